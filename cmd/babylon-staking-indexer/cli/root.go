@@ -1,22 +1,308 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 )
 
 const (
 	defaultConfigFileName = "config.yml"
+	resyncBbnHeightFlag   = "resync-bbn-height"
+	skipParamsCheckFlag   = "skip-params-verification"
+	skipNetworkCheckFlag  = "skip-network-verification"
+	dryRunFlag            = "dry-run"
+	forceResyncFlag       = "force-resync"
+	allowGapFlag          = "allow-gap"
+	// operatorEnvVar names the operator recorded with the admin actions if
+	// the --operator flag is not set
+	operatorEnvVar = "INDEXER_OPERATOR"
 )
 
 var (
-	cfgPath string
-	rootCmd = &cobra.Command{
+	cfgPath                  string
+	resyncBbnHeight          uint64
+	reconcileRequested       bool
+	reconcileFix             bool
+	cleanupRequested         bool
+	skipParamsCheck          bool
+	skipNetworkCheck         bool
+	dryRun                   bool
+	forceResync              bool
+	allowGap                 bool
+	recalculateRequested     bool
+	recalculateParamsVersion uint32
+	outboxPoisonRequested    bool
+	outboxPoisonRequeue      bool
+	deadLettersRequested     bool
+	deadLettersQueue         string
+	deadLettersLimit         int
+	deadLettersRequeue       bool
+	republishRequested       bool
+	republishStakingTxHash   string
+	republishFromHeight      int64
+	republishToHeight        int64
+	republishRate            float64
+	backfillRequested        bool
+	backfillFromHeight       uint64
+	backfillToHeight         uint64
+	backfillConcurrency      int
+	backfillCheckpointFile   string
+	verifyRequested          bool
+	verifySample             uint64
+	verifyAll                bool
+	verifyStates             []string
+	verifyFpBtcPks           []string
+	verifyFixSafe            bool
+	setStateRequested        bool
+	setStateStakingTxHash    string
+	setStateState            string
+	setStateSubState         string
+	setStateReason           string
+	setStateOperator         string
+	reportRequested          bool
+	reportFrom               string
+	reportTo                 string
+	reportGranularity        string
+	reportFormat             string
+	reportOutput             string
+	paramsSyncRequested      bool
+	paramsSyncVerifyOnly     bool
+	replayRequested          bool
+	replayStakingTxHash      string
+	replayReset              bool
+	covenantSigsRequested    bool
+	covenantSigsStakingTx    string
+	covenantSigsMark         bool
+	pruneRequested           bool
+	pruneOlderThan           string
+	pruneCollections         []string
+	pruneBatchSize           int64
+	pruneProgressEvery       uint64
+	captureEventsRequested   bool
+	captureEventsHeight      int64
+	captureEventsVersion     string
+	captureEventsOutput      string
+	rootCmd                  = &cobra.Command{
 		Use: "start-server",
 	}
+	cleanupTimeLocksCmd = &cobra.Command{
+		Use:   "cleanup-timelocks",
+		Short: "Delete the timelock documents of delegations that can no longer expire",
+		Run: func(cmd *cobra.Command, args []string) {
+			cleanupRequested = true
+		},
+	}
+	recalculateTimeLocksCmd = &cobra.Command{
+		Use:   "recalculate-timelocks",
+		Short: "Recompute the timelock expire heights after the staking params of a version changed",
+		Run: func(cmd *cobra.Command, args []string) {
+			recalculateRequested = true
+		},
+	}
+	outboxPoisonCmd = &cobra.Command{
+		Use:   "outbox-poison",
+		Short: "List the outbox events no longer pushed to the queue after too many failures",
+		Run: func(cmd *cobra.Command, args []string) {
+			outboxPoisonRequested = true
+		},
+	}
+	deadLettersCmd = &cobra.Command{
+		Use:   "dlq",
+		Short: "Inspect the messages rejected by the queue consumers, or requeue them",
+		Run: func(cmd *cobra.Command, args []string) {
+			deadLettersRequested = true
+		},
+	}
+	republishCmd = &cobra.Command{
+		Use:   "republish",
+		Short: "Publish again the events of a delegation, or of the delegations created in a BBN height range, flagged as replayed",
+		Args: func(cmd *cobra.Command, args []string) error {
+			byHash := cmd.Flags().Changed("staking-tx-hash")
+			byHeight := cmd.Flags().Changed("from-height") || cmd.Flags().Changed("to-height")
+			if byHash == byHeight {
+				return errors.New("either --staking-tx-hash or --from-height and --to-height must be set")
+			}
+			if byHeight && !(cmd.Flags().Changed("from-height") && cmd.Flags().Changed("to-height")) {
+				return errors.New("both --from-height and --to-height must be set")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			republishRequested = true
+		},
+	}
+	backfillCmd = &cobra.Command{
+		Use:   "backfill",
+		Short: "Process again the BBN blocks of a height range, e.g. the gaps found by the processed height audit",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if backfillFromHeight == 0 || backfillFromHeight > backfillToHeight {
+				return errors.New("--from must be positive and not above --to")
+			}
+			if backfillConcurrency <= 0 {
+				return errors.New("--concurrency must be positive")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			backfillRequested = true
+		},
+	}
+	verifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Compare a sample of the indexed delegations and the finality providers with the BBN chain state, exiting with 1 on mismatches",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if verifyAll && cmd.Flags().Changed("sample") {
+				return errors.New("--sample and --all are mutually exclusive")
+			}
+			if !verifyAll && verifySample == 0 {
+				return errors.New("--sample must be positive")
+			}
+			for _, state := range verifyStates {
+				if !utils.Contains(types.AllDelegationStates(), types.DelegationState(strings.ToUpper(state))) {
+					return fmt.Errorf("%s is not a valid delegation state", state)
+				}
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			verifyRequested = true
+		},
+	}
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Report the state transitions and the staked amounts they concern by period, e.g. the new, unbonded and withdrawn stake by week",
+		Args: func(cmd *cobra.Command, args []string) error {
+			from, err := time.Parse(time.DateOnly, reportFrom)
+			if err != nil {
+				return fmt.Errorf("invalid --from date: %w", err)
+			}
+			to, err := time.Parse(time.DateOnly, reportTo)
+			if err != nil {
+				return fmt.Errorf("invalid --to date: %w", err)
+			}
+			if !from.Before(to) {
+				return errors.New("--to must be after --from")
+			}
+			switch reportGranularity {
+			case "day", "week", "month":
+			default:
+				return errors.New("--granularity must be day, week or month")
+			}
+			switch reportFormat {
+			case "csv", "json":
+			default:
+				return errors.New("--format must be csv or json")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			reportRequested = true
+		},
+	}
+	replayDelegationCmd = &cobra.Command{
+		Use:   "replay-delegation",
+		Short: "Apply again the BBN events of a delegation in order, printing its state after each of them",
+		Run: func(cmd *cobra.Command, args []string) {
+			replayRequested = true
+		},
+	}
+	verifyCovenantSigsCmd = &cobra.Command{
+		Use:   "verify-covenant-sigs",
+		Short: "Check the covenant signatures of a delegation against the covenant pks of its params",
+		Run: func(cmd *cobra.Command, args []string) {
+			covenantSigsRequested = true
+		},
+	}
+	pruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Archive the delegations in a terminal state for long enough and delete the old archived timelocks",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if _, err := parseRetention(pruneOlderThan); err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+			for _, collection := range pruneCollections {
+				switch collection {
+				case "delegations", "timelock_archive":
+				default:
+					return fmt.Errorf("%s cannot be pruned, only delegations and timelock_archive", collection)
+				}
+			}
+			if pruneBatchSize <= 0 {
+				return errors.New("--batch-size must be positive")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			pruneRequested = true
+		},
+	}
+	paramsCmd = &cobra.Command{
+		Use:   "params",
+		Short: "Manage the stored staking and checkpoint params",
+	}
+	paramsSyncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Save the params versions of the BBN chain missing locally and verify the stored ones, exiting with 1 on mismatches",
+		Run: func(cmd *cobra.Command, args []string) {
+			paramsSyncRequested = true
+		},
+	}
+	adminCmd = &cobra.Command{
+		Use:   "admin",
+		Short: "Operator actions recorded in the indexed data",
+	}
+	setStateCmd = &cobra.Command{
+		Use:   "set-state",
+		Short: "Override the state of a delegation, recording the reason and the operator in its state history",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(setStateReason) == "" {
+				return errors.New("--reason must not be empty")
+			}
+			if strings.TrimSpace(setStateOperator) == "" {
+				return fmt.Errorf("either --operator or %s must be set", operatorEnvVar)
+			}
+			if !utils.Contains(types.AllDelegationStates(), types.DelegationState(strings.ToUpper(setStateState))) {
+				return fmt.Errorf("%s is not a valid delegation state", setStateState)
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			setStateRequested = true
+		},
+	}
+	captureEventsCmd = &cobra.Command{
+		Use:   "capture-events",
+		Short: "Dump the handled events of a BBN block in the format of the event parsing fixtures",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if captureEventsHeight <= 0 {
+				return errors.New("--height must be positive")
+			}
+			if strings.TrimSpace(captureEventsVersion) == "" {
+				return errors.New("--babylon-version must not be empty")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			captureEventsRequested = true
+		},
+	}
+	reconcileCmd = &cobra.Command{
+		Use:   "reconcile",
+		Short: "Reconcile the indexed delegations and finality providers against the BBN chain state",
+		Run: func(cmd *cobra.Command, args []string) {
+			reconcileRequested = true
+		},
+	}
 )
 
 func Setup() error {
@@ -28,6 +314,92 @@ func Setup() error {
 	defaultConfigPath := getDefaultConfigFile(homePath, defaultConfigFileName)
 
 	rootCmd.PersistentFlags().StringVar(&cfgPath, "config", defaultConfigPath, fmt.Sprintf("config file (default %s)", defaultConfigPath))
+	rootCmd.PersistentFlags().Uint64Var(&resyncBbnHeight, resyncBbnHeightFlag, 0, "resume a halted BBN block processing after the given height")
+	rootCmd.PersistentFlags().BoolVar(&skipParamsCheck, skipParamsCheckFlag, false, "start even if the stored params differ from the BBN chain ones")
+	rootCmd.PersistentFlags().BoolVar(&skipNetworkCheck, skipNetworkCheckFlag, false, "start even if the BTC network differs from the BTC backend or the BBN chain one, e.g. on a custom network")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, dryRunFlag, false, "process the BBN blocks, logging the database writes and the queue events instead of applying them")
+	rootCmd.PersistentFlags().BoolVar(&forceResync, forceResyncFlag, false, "process the BBN blocks again from the configured start height if below the last processed one")
+	rootCmd.PersistentFlags().BoolVar(&allowGap, allowGapFlag, false, "skip the BBN blocks up to the configured start height if above the last processed one")
+	reconcileCmd.Flags().BoolVar(&reconcileFix, "fix", false, "apply safe corrections to the discrepancies found")
+	recalculateTimeLocksCmd.Flags().Uint32Var(&recalculateParamsVersion, "params-version", 0, "the staking params version that changed")
+	if err := recalculateTimeLocksCmd.MarkFlagRequired("params-version"); err != nil {
+		return err
+	}
+	outboxPoisonCmd.Flags().BoolVar(&outboxPoisonRequeue, "requeue", false, "requeue the poison events so that they are pushed again")
+	deadLettersCmd.Flags().StringVar(&deadLettersQueue, "queue", "", "the queue whose dead letters are handled (default all the staking queues)")
+	deadLettersCmd.Flags().IntVar(&deadLettersLimit, "limit", 100, "the maximum number of dead letters listed per queue")
+	deadLettersCmd.Flags().BoolVar(&deadLettersRequeue, "requeue", false, "publish the dead letters back to their queue")
+	republishCmd.Flags().StringVar(&republishStakingTxHash, "staking-tx-hash", "", "the staking tx hash of the delegation whose events are republished")
+	republishCmd.Flags().Int64Var(&republishFromHeight, "from-height", 0, "the first BBN height of the range the republished delegations were created in")
+	republishCmd.Flags().Int64Var(&republishToHeight, "to-height", 0, "the last BBN height of the range the republished delegations were created in")
+	republishCmd.Flags().Float64Var(&republishRate, "events-per-second", 10, "the maximum number of events published per second")
+	backfillCmd.Flags().Uint64Var(&backfillFromHeight, "from", 0, "the first BBN height to backfill")
+	backfillCmd.Flags().Uint64Var(&backfillToHeight, "to", 0, "the last BBN height to backfill")
+	backfillCmd.Flags().IntVar(&backfillConcurrency, "concurrency", 4, "the number of BBN blocks fetched in parallel")
+	backfillCmd.Flags().StringVar(&backfillCheckpointFile, "checkpoint-file", "backfill-checkpoint.json", "the file recording the progress, from which an interrupted backfill resumes")
+	for _, flag := range []string{"from", "to"} {
+		if err := backfillCmd.MarkFlagRequired(flag); err != nil {
+			return err
+		}
+	}
+	verifyCmd.Flags().Uint64Var(&verifySample, "sample", 1000, "the number of delegations checked on each side")
+	verifyCmd.Flags().BoolVar(&verifyAll, "all", false, "check all the delegations instead of a sample")
+	verifyCmd.Flags().StringSliceVar(&verifyStates, "state", nil, "check only the delegations in the states")
+	verifyCmd.Flags().StringSliceVar(&verifyFpBtcPks, "fp", nil, "check only the finality providers, and the delegations to them, of the BTC public keys")
+	verifyCmd.Flags().BoolVar(&verifyFixSafe, "fix-safe", false, "apply the unambiguous corrections of the mismatches found")
+	setStateCmd.Flags().StringVar(&setStateStakingTxHash, "staking-tx-hash", "", "the staking tx hash of the delegation whose state is overridden")
+	setStateCmd.Flags().StringVar(&setStateState, "state", "", "the state the delegation is set to")
+	setStateCmd.Flags().StringVar(&setStateSubState, "sub-state", "", "the sub state of the UNBONDING, WITHDRAWABLE and WITHDRAWN states")
+	setStateCmd.Flags().StringVar(&setStateReason, "reason", "", "why the state is overridden, recorded in the state history")
+	setStateCmd.Flags().StringVar(&setStateOperator, "operator", os.Getenv(operatorEnvVar), fmt.Sprintf("who overrides the state, recorded in the state history (default $%s)", operatorEnvVar))
+	for _, flag := range []string{"staking-tx-hash", "state", "reason"} {
+		if err := setStateCmd.MarkFlagRequired(flag); err != nil {
+			return err
+		}
+	}
+	adminCmd.AddCommand(setStateCmd)
+	replayDelegationCmd.Flags().StringVar(&replayStakingTxHash, "staking-tx-hash", "", "the staking tx hash of the delegation whose events are replayed")
+	replayDelegationCmd.Flags().BoolVar(&replayReset, "reset", false, "delete the delegation, its state history and its timelocks before the replay")
+	if err := replayDelegationCmd.MarkFlagRequired("staking-tx-hash"); err != nil {
+		return err
+	}
+	verifyCovenantSigsCmd.Flags().StringVar(&covenantSigsStakingTx, "staking-tx-hash", "", "the staking tx hash of the delegation whose covenant signatures are checked")
+	verifyCovenantSigsCmd.Flags().BoolVar(&covenantSigsMark, "mark", false, "record whether each signature is valid in the database")
+	if err := verifyCovenantSigsCmd.MarkFlagRequired("staking-tx-hash"); err != nil {
+		return err
+	}
+	pruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "how long the documents must have been terminal or archived, e.g. 365d or 720h")
+	pruneCmd.Flags().StringSliceVar(&pruneCollections, "collections", []string{"delegations", "timelock_archive"}, "the collections pruned")
+	pruneCmd.Flags().Int64Var(&pruneBatchSize, "batch-size", 1000, "the number of documents pruned at once")
+	pruneCmd.Flags().Uint64Var(&pruneProgressEvery, "progress-every", 10000, "the number of documents pruned between progress logs")
+	if err := pruneCmd.MarkFlagRequired("older-than"); err != nil {
+		return err
+	}
+	paramsSyncCmd.Flags().BoolVar(&paramsSyncVerifyOnly, "verify-only", false, "only compare the stored params with the BBN chain ones, without saving anything")
+	paramsCmd.AddCommand(paramsSyncCmd)
+	reportCmd.Flags().StringVar(&reportFrom, "from", "", "the first UTC date of the report, formatted as 2006-01-02")
+	reportCmd.Flags().StringVar(&reportTo, "to", "", "the UTC date the report ends on, excluded, formatted as 2006-01-02")
+	reportCmd.Flags().StringVar(&reportGranularity, "granularity", "week", "the period the transitions are aggregated by: day, week or month")
+	reportCmd.Flags().StringVar(&reportFormat, "format", "csv", "the format of the report: csv or json")
+	reportCmd.Flags().StringVar(&reportOutput, "output", "", "the file the report is written to (default stdout)")
+	for _, flag := range []string{"from", "to"} {
+		if err := reportCmd.MarkFlagRequired(flag); err != nil {
+			return err
+		}
+	}
+	captureEventsCmd.Flags().Int64Var(&captureEventsHeight, "height", 0, "the BBN height of the block whose events are captured")
+	captureEventsCmd.Flags().StringVar(&captureEventsVersion, "babylon-version", "", "the version of the Babylon node the events are captured from, e.g. v1.0.0")
+	captureEventsCmd.Flags().StringVar(&captureEventsOutput, "output", "", "the fixture file the events are written to (default stdout)")
+	for _, flag := range []string{"height", "babylon-version"} {
+		if err := captureEventsCmd.MarkFlagRequired(flag); err != nil {
+			return err
+		}
+	}
+	rootCmd.AddCommand(
+		reconcileCmd, cleanupTimeLocksCmd, recalculateTimeLocksCmd, outboxPoisonCmd, deadLettersCmd, republishCmd,
+		backfillCmd, verifyCmd, adminCmd, reportCmd, paramsCmd, replayDelegationCmd, pruneCmd,
+		verifyCovenantSigsCmd, captureEventsCmd,
+	)
 	if err := rootCmd.Execute(); err != nil {
 		return err
 	}
@@ -42,3 +414,274 @@ func getDefaultConfigFile(homePath, filename string) string {
 func GetConfigPath() string {
 	return cfgPath
 }
+
+// GetResyncBbnHeight returns the BBN height to resync from and whether a
+// resync was requested
+func GetResyncBbnHeight() (uint64, bool) {
+	return resyncBbnHeight, rootCmd.PersistentFlags().Changed(resyncBbnHeightFlag)
+}
+
+// GetStartHeightOverrides returns whether the configured BBN start height may
+// process the blocks again, or skip blocks, on a database with processed
+// blocks
+func GetStartHeightOverrides() (forceResync bool, allowGap bool) {
+	return forceResync, allowGap
+}
+
+// GetReconcileCommand returns whether the reconcile command was requested and
+// whether it should apply safe corrections
+func GetReconcileCommand() (bool, bool) {
+	return reconcileRequested, reconcileFix
+}
+
+// IsCleanupTimeLocksCommand returns whether the cleanup-timelocks command was requested
+func IsCleanupTimeLocksCommand() bool {
+	return cleanupRequested
+}
+
+// GetRecalculateTimeLocksCommand returns whether the recalculate-timelocks
+// command was requested and the staking params version it applies to
+func GetRecalculateTimeLocksCommand() (bool, uint32) {
+	return recalculateRequested, recalculateParamsVersion
+}
+
+// GetOutboxPoisonCommand returns whether the outbox-poison command was
+// requested and whether the poison events should be requeued
+func GetOutboxPoisonCommand() (bool, bool) {
+	return outboxPoisonRequested, outboxPoisonRequeue
+}
+
+// DeadLettersCommand holds the options of the dlq command
+type DeadLettersCommand struct {
+	// Queue is the queue whose dead letters are handled, all if empty
+	Queue   string
+	Limit   int
+	Requeue bool
+}
+
+// GetDeadLettersCommand returns whether the dlq command was requested and its
+// options
+func GetDeadLettersCommand() (bool, DeadLettersCommand) {
+	return deadLettersRequested, DeadLettersCommand{
+		Queue:   deadLettersQueue,
+		Limit:   deadLettersLimit,
+		Requeue: deadLettersRequeue,
+	}
+}
+
+// RepublishCommand holds the options of the republish command
+type RepublishCommand struct {
+	// StakingTxHashHex selects a single delegation, the height range being
+	// used otherwise
+	StakingTxHashHex string
+	FromHeight       int64
+	ToHeight         int64
+	EventsPerSecond  float64
+}
+
+// GetRepublishCommand returns whether the republish command was requested and
+// its options
+func GetRepublishCommand() (bool, RepublishCommand) {
+	return republishRequested, RepublishCommand{
+		StakingTxHashHex: republishStakingTxHash,
+		FromHeight:       republishFromHeight,
+		ToHeight:         republishToHeight,
+		EventsPerSecond:  republishRate,
+	}
+}
+
+// IsParamsVerificationSkipped returns whether the stored params should not be
+// verified against the BBN chain at startup
+func IsParamsVerificationSkipped() bool {
+	return skipParamsCheck
+}
+
+// IsNetworkVerificationSkipped returns whether the configured BTC network
+// should not be verified against the BTC backend and the BBN chain at startup
+func IsNetworkVerificationSkipped() bool {
+	return skipNetworkCheck
+}
+
+// IsDryRun returns whether the database writes and the queue events should be
+// logged instead of applied. The backfill and the prune report what they would
+// write in a dry run.
+func IsDryRun() bool {
+	return dryRun
+}
+
+// BackfillCommand holds the options of the backfill command
+type BackfillCommand struct {
+	FromHeight     uint64
+	ToHeight       uint64
+	DryRun         bool
+	Concurrency    int
+	CheckpointFile string
+}
+
+// GetBackfillCommand returns whether the backfill command was requested and
+// its options
+func GetBackfillCommand() (bool, BackfillCommand) {
+	return backfillRequested, BackfillCommand{
+		FromHeight:     backfillFromHeight,
+		ToHeight:       backfillToHeight,
+		DryRun:         dryRun,
+		Concurrency:    backfillConcurrency,
+		CheckpointFile: backfillCheckpointFile,
+	}
+}
+
+// VerifyCommand holds the options of the verify command
+type VerifyCommand struct {
+	// Sample is the number of delegations checked on each side, zero to
+	// check all of them
+	Sample      uint64
+	States      []types.DelegationState
+	FpBtcPksHex []string
+	FixSafe     bool
+}
+
+// GetVerifyCommand returns whether the verify command was requested and its
+// options
+func GetVerifyCommand() (bool, VerifyCommand) {
+	cmd := VerifyCommand{
+		Sample:      verifySample,
+		FpBtcPksHex: verifyFpBtcPks,
+		FixSafe:     verifyFixSafe,
+	}
+	if verifyAll {
+		cmd.Sample = 0
+	}
+	for _, state := range verifyStates {
+		cmd.States = append(cmd.States, types.DelegationState(strings.ToUpper(state)))
+	}
+	return verifyRequested, cmd
+}
+
+// SetStateCommand holds the options of the admin set-state command
+type SetStateCommand struct {
+	StakingTxHashHex string
+	State            types.DelegationState
+	SubState         types.DelegationSubState
+	Reason           string
+	Operator         string
+}
+
+// GetSetStateCommand returns whether the admin set-state command was
+// requested and its options
+func GetSetStateCommand() (bool, SetStateCommand) {
+	return setStateRequested, SetStateCommand{
+		StakingTxHashHex: setStateStakingTxHash,
+		State:            types.DelegationState(strings.ToUpper(setStateState)),
+		SubState:         types.DelegationSubState(strings.ToUpper(setStateSubState)),
+		Reason:           setStateReason,
+		Operator:         setStateOperator,
+	}
+}
+
+// ReportCommand holds the options of the report command
+type ReportCommand struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Format      string
+	// Output is the file the report is written to, stdout if empty
+	Output string
+}
+
+// GetReportCommand returns whether the report command was requested and its
+// options
+func GetReportCommand() (bool, ReportCommand) {
+	// the dates are validated along with the command arguments
+	from, _ := time.Parse(time.DateOnly, reportFrom)
+	to, _ := time.Parse(time.DateOnly, reportTo)
+	return reportRequested, ReportCommand{
+		From:        from,
+		To:          to,
+		Granularity: reportGranularity,
+		Format:      reportFormat,
+		Output:      reportOutput,
+	}
+}
+
+// GetParamsSyncCommand returns whether the params sync command was requested
+// and whether it should only verify the stored params
+func GetParamsSyncCommand() (bool, bool) {
+	return paramsSyncRequested, paramsSyncVerifyOnly
+}
+
+// GetReplayDelegationCommand returns whether the replay-delegation command was
+// requested, the staking tx hash of the delegation and whether it should be
+// reset first
+func GetReplayDelegationCommand() (bool, string, bool) {
+	return replayRequested, replayStakingTxHash, replayReset
+}
+
+// GetVerifyCovenantSigsCommand returns whether the verify-covenant-sigs
+// command was requested, the staking tx hash of the delegation and whether
+// the outcome should be recorded
+func GetVerifyCovenantSigsCommand() (bool, string, bool) {
+	return covenantSigsRequested, covenantSigsStakingTx, covenantSigsMark
+}
+
+// CaptureEventsCommand holds the options of the capture-events command
+type CaptureEventsCommand struct {
+	Height         int64
+	BabylonVersion string
+	// Output is the file the events are written to, stdout if empty
+	Output string
+}
+
+// GetCaptureEventsCommand returns whether the capture-events command was
+// requested and its options
+func GetCaptureEventsCommand() (bool, CaptureEventsCommand) {
+	return captureEventsRequested, CaptureEventsCommand{
+		Height:         captureEventsHeight,
+		BabylonVersion: captureEventsVersion,
+		Output:         captureEventsOutput,
+	}
+}
+
+// PruneCommand holds the options of the prune command
+type PruneCommand struct {
+	OlderThan     time.Duration
+	Collections   []string
+	DryRun        bool
+	BatchSize     int64
+	ProgressEvery uint64
+}
+
+// GetPruneCommand returns whether the prune command was requested and its
+// options
+func GetPruneCommand() (bool, PruneCommand) {
+	// the retention is validated along with the command arguments
+	olderThan, _ := parseRetention(pruneOlderThan)
+	return pruneRequested, PruneCommand{
+		OlderThan:     olderThan,
+		Collections:   pruneCollections,
+		DryRun:        dryRun,
+		BatchSize:     pruneBatchSize,
+		ProgressEvery: pruneProgressEvery,
+	}
+}
+
+// parseRetention parses a positive duration, which may be given in days with
+// the d unit
+func parseRetention(value string) (time.Duration, error) {
+	var retention time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseUint(days, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %s", days)
+		}
+		retention = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if retention, err = time.ParseDuration(value); err != nil {
+			return 0, err
+		}
+	}
+	if retention <= 0 {
+		return 0, errors.New("the retention must be positive")
+	}
+	return retention, nil
+}
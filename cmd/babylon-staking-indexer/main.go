@@ -2,22 +2,45 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
 	"go.uber.org/zap"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/cmd/babylon-staking-indexer/cli"
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/api"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/errorreporting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/tracing"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
-	"github.com/babylonlabs-io/staking-queue-client/queuemngr"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/shutdown"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/supervisor"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 )
 
+// tracingShutdownTimeout bounds the flush of the spans left on shutdown
+const tracingShutdownTimeout = 5 * time.Second
+
 func init() {
 	if err := godotenv.Load(); err != nil {
 		log.Debug().Msg("failed to load .env file")
@@ -25,7 +48,9 @@ func init() {
 }
 
 func main() {
-	ctx := context.Background()
+	// cancelled on SIGINT or SIGTERM to shut the indexer down gracefully
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// setup cli commands and flags
 	if err := cli.Setup(); err != nil {
@@ -39,14 +64,67 @@ func main() {
 		log.Fatal().Err(err).Msg(fmt.Sprintf("error while loading config file: %s", cfgPath))
 	}
 
+	// set the configured log levels, the component ones adjustable at runtime
+	if err := logging.Init(&cfg.Log); err != nil {
+		log.Fatal().Err(err).Msg("error while setting the log levels")
+	}
+
+	// register the metrics before any component records them
+	metrics.Init()
+
+	// dump the handled events of a BBN block as a parsing fixture if
+	// requested, which only needs the BBN node
+	if capture, cmd := cli.GetCaptureEventsCommand(); capture {
+		if err := captureBbnEvents(ctx, cfg, cmd); err != nil {
+			log.Fatal().Err(err).Msg("error while capturing BBN events")
+		}
+		return
+	}
+
+	// export the traces if a collector is configured
+	shutdownTracing, err := tracing.InitTracerProvider(ctx, &cfg.Tracing)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while initializing tracing")
+	}
+	defer func() {
+		// flush the spans left with a context of its own, the main one being
+		// cancelled by then
+		flushCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			log.Error().Err(err).Msg("failed to flush the traces")
+		}
+	}()
+
+	// write the audit records of the mutations to the configured output
+	closeAudit, err := audit.Init(&cfg.Audit)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while opening the audit output")
+	}
+	defer func() {
+		if err := closeAudit(); err != nil {
+			log.Error().Err(err).Msg("failed to close the audit output")
+		}
+	}()
+
 	// create new db client
-	dbClient, err := db.New(ctx, cfg.Db)
+	database, err := db.New(ctx, cfg.Db)
 	if err != nil {
 		log.Fatal().Err(err).Msg("error while creating db client")
 	}
+	// log the writes instead of applying them in a dry run
+	if cli.IsDryRun() {
+		log.Warn().Msg("dry run: the database writes and the queue events are logged instead of applied")
+	}
+	dbClient, err := db.NewIndexerDatabase(ctx, database, &cfg.Db, cli.IsDryRun())
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while wrapping db client")
+	}
 
-	// Create a basic zap logger
-	zapLogger, err := zap.NewProduction()
+	// Create a basic zap logger, at the level of the emitter
+	zapCfg := zap.NewProductionConfig()
+	zapCfg.Level = logging.Emitter.ZapLevel()
+	zapLogger, err := zapCfg.Build()
 	if err != nil {
 		log.Fatal().Err(err).Msg("error while creating zap logger")
 	}
@@ -56,11 +134,53 @@ func main() {
 		}
 	}()
 
-	queueConsumer, err := queuemngr.NewQueueManager(&cfg.Queue, zapLogger)
+	// report the errors to the exception aggregation service if configured
+	if err := errorreporting.Init(&cfg.ErrorReporting); err != nil {
+		log.Fatal().Err(err).Msg("error while initializing error reporting")
+	}
+
+	// push the alerts on critical conditions if configured
+	alerter := alerting.New(&cfg.Alerting)
+
+	var queueConsumer consumer.EventConsumer
+	switch {
+	case cli.IsDryRun():
+		queueConsumer = consumer.NewDryRunEmitter()
+	case cfg.Emitter.IsKafka():
+		queueConsumer, err = consumer.NewKafkaEmitter(&cfg.Emitter.Kafka, cfg.Emitter.SchemaVersions)
+	case cfg.Emitter.IsWebhook():
+		queueConsumer, err = consumer.NewWebhookEmitter(
+			&cfg.Emitter.Webhook, cfg.Emitter.SchemaVersions, func(url string) {
+				metrics.RecordWebhookEndpointDisabled(url)
+				// disabled until a restart, the events are no longer
+				// delivered to the endpoint
+				alerter.Alert(ctx, alerting.SeverityCritical, "Webhook endpoint disabled: "+url, map[string]string{
+					"url":                url,
+					"max_failure_streak": strconv.Itoa(cfg.Emitter.Webhook.MaxFailureStreak),
+				})
+			},
+		)
+	default:
+		queueConsumer, err = consumer.NewQueueManager(
+			&cfg.Queue, &cfg.Emitter.RabbitMQ, cfg.Emitter.SchemaVersions, zapLogger,
+		)
+	}
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize event consumer")
 	}
 
+	// inspect or requeue the dead letters of the staking queues if requested
+	if deadLetters, cmd := cli.GetDeadLettersCommand(); deadLetters {
+		queueManager, ok := queueConsumer.(*consumer.QueueManager)
+		if !ok {
+			log.Fatal().Msg("the dlq command requires the rabbitmq emitter")
+		}
+		if err := handleDeadLetters(queueManager, cmd); err != nil {
+			log.Fatal().Err(err).Msg("error while handling dead letters")
+		}
+		return
+	}
+
 	btcClient, err := btcclient.NewBTCClient(&cfg.BTC)
 	if err != nil {
 		log.Fatal().Err(err).Msg("error while creating btc client")
@@ -76,14 +196,501 @@ func main() {
 		log.Fatal().Err(err).Msg("error while creating btc notifier")
 	}
 
-	service := services.NewService(cfg, dbClient, btcClient, btcNotifier, bbnClient, queueConsumer)
+	// the background components of the indexer and the api run under a
+	// single supervisor, restarting them on failure
+	sup := supervisor.New()
+	service := services.New(cfg, services.Dependencies{
+		Db:          dbClient,
+		Btc:         btcClient,
+		BtcNotifier: btcNotifier,
+		Bbn:         bbnClient,
+		Emitter:     queueConsumer,
+		Alerter:     alerter,
+		Supervisor:  sup,
+	})
+
+	// the one-off commands are audited as admin operations of the command line
+	commandCtx := audit.WithTrigger(ctx, audit.Trigger{Type: audit.TriggerAdmin, Operator: "cli"})
+
+	// run a one-off reconciliation instead of the indexer if requested
+	if reconcile, fix := cli.GetReconcileCommand(); reconcile {
+		if err := bbnClient.Start(); err != nil {
+			log.Fatal().Err(err).Msg("error while starting BBN client")
+		}
+		reconcileCtx := commandCtx
+		if fix {
+			var release func()
+			reconcileCtx, release = holdLeaderLease(commandCtx, service, "reconcile")
+			defer release()
+		}
+		if err := service.RunReconciliation(reconcileCtx, fix); err != nil {
+			log.Fatal().Err(err).Msg("error while running reconciliation")
+		}
+		return
+	}
+
+	// process again a BBN height range if requested
+	if backfill, cmd := cli.GetBackfillCommand(); backfill {
+		if err := bbnClient.Start(); err != nil {
+			log.Fatal().Err(err).Msg("error while starting BBN client")
+		}
+		backfillCtx := commandCtx
+		if !cmd.DryRun {
+			var release func()
+			backfillCtx, release = holdLeaderLease(commandCtx, service, "backfill")
+			defer release()
+		}
+		summary, err := service.Backfill(backfillCtx, services.BackfillRequest{
+			FromHeight:     cmd.FromHeight,
+			ToHeight:       cmd.ToHeight,
+			DryRun:         cmd.DryRun,
+			Concurrency:    cmd.Concurrency,
+			CheckpointFile: cmd.CheckpointFile,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while backfilling BBN heights")
+		}
+		for collection, writes := range summary.Writes {
+			log.Info().
+				Str("collection", collection).
+				Uint64("created", writes.Created).
+				Uint64("updated", writes.Updated).
+				Bool("dry_run", cmd.DryRun).
+				Msg("backfill summary")
+		}
+		log.Info().
+			Uint64("processed_heights", summary.ProcessedHeights).
+			Bool("dry_run", cmd.DryRun).
+			Msg("backfill completed")
+		return
+	}
+
+	// compare the indexed data with the BBN chain state if requested, the
+	// exit code telling whether mismatches were found
+	if verify, cmd := cli.GetVerifyCommand(); verify {
+		if err := bbnClient.Start(); err != nil {
+			log.Fatal().Err(err).Msg("error while starting BBN client")
+		}
+		report, err := service.VerifyConsistency(commandCtx, services.VerifyRequest{
+			Sample:                 cmd.Sample,
+			States:                 cmd.States,
+			FinalityProviderBtcPks: cmd.FpBtcPksHex,
+			FixSafe:                cmd.FixSafe,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while verifying consistency with the BBN chain")
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(report); err != nil {
+			log.Fatal().Err(err).Msg("error while writing consistency report")
+		}
+		if report.MismatchCount() > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// prune the old terminal delegations and archived timelocks if requested
+	if prune, cmd := cli.GetPruneCommand(); prune {
+		summary, err := service.Prune(commandCtx, services.PruneRequest{
+			OlderThan:     cmd.OlderThan,
+			Collections:   cmd.Collections,
+			DryRun:        cmd.DryRun,
+			BatchSize:     cmd.BatchSize,
+			ProgressEvery: cmd.ProgressEvery,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while pruning")
+		}
+		for collection, counts := range summary.Collections {
+			log.Info().
+				Str("collection", collection).
+				Uint64("moved", counts.Moved).
+				Uint64("deleted", counts.Deleted).
+				Bool("dry_run", summary.DryRun).
+				Msg("prune summary")
+		}
+		return
+	}
+
+	// replay the BBN events of a delegation if requested
+	if replay, stakingTxHash, reset := cli.GetReplayDelegationCommand(); replay {
+		if err := bbnClient.Start(); err != nil {
+			log.Fatal().Err(err).Msg("error while starting BBN client")
+		}
+		leaseCtx, release := holdLeaderLease(commandCtx, service, "replay-delegation")
+		defer release()
+		events, err := service.ReplayDelegation(leaseCtx, services.ReplayDelegationRequest{
+			StakingTxHashHex: parseStakingTxHash(stakingTxHash),
+			Reset:            reset,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while replaying delegation events")
+		}
+		if err := writeReplayedEvents(os.Stdout, events); err != nil {
+			log.Fatal().Err(err).Msg("error while writing replayed events")
+		}
+		return
+	}
+
+	// check the covenant signatures of a delegation if requested, the exit
+	// code telling whether invalid signatures were found
+	if verifySigs, stakingTxHash, mark := cli.GetVerifyCovenantSigsCommand(); verifySigs {
+		report, err := service.VerifyCovenantSignatures(commandCtx, stakingTxHash, mark)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while verifying covenant signatures")
+		}
+		if err := writeCovenantSignatures(os.Stdout, report); err != nil {
+			log.Fatal().Err(err).Msg("error while writing covenant signatures")
+		}
+		if report.InvalidCount() > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// save the missing params versions and verify the stored ones if
+	// requested, the exit code telling whether mismatches were found
+	if paramsSync, verifyOnly := cli.GetParamsSyncCommand(); paramsSync {
+		if err := bbnClient.Start(); err != nil {
+			log.Fatal().Err(err).Msg("error while starting BBN client")
+		}
+		statuses, err := service.SyncParams(commandCtx, verifyOnly)
+		if err != nil {
+			log.Fatal().Err(err).Msg("error while syncing params")
+		}
+		mismatch, writeErr := writeParamsStatuses(os.Stdout, statuses)
+		if writeErr != nil {
+			log.Fatal().Err(writeErr).Msg("error while writing params versions")
+		}
+		if mismatch {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// write the staking statistics report if requested
+	if report, cmd := cli.GetReportCommand(); report {
+		if err := writeStakingReport(commandCtx, service, cmd); err != nil {
+			log.Fatal().Err(err).Msg("error while writing staking report")
+		}
+		return
+	}
+
+	// override the state of a delegation if requested
+	if setState, cmd := cli.GetSetStateCommand(); setState {
+		leaseCtx, release := holdLeaderLease(commandCtx, service, "set-state")
+		defer release()
+		if err := service.SetDelegationState(leaseCtx, services.SetStateRequest{
+			StakingTxHashHex: parseStakingTxHash(cmd.StakingTxHashHex),
+			State:            cmd.State,
+			SubState:         cmd.SubState,
+			Reason:           cmd.Reason,
+			Operator:         cmd.Operator,
+		}); err != nil {
+			log.Fatal().Err(err).Msg("error while overriding delegation state")
+		}
+		return
+	}
+
+	// run a one-off cleanup of the orphaned timelocks if requested
+	if cli.IsCleanupTimeLocksCommand() {
+		if _, err := service.CleanupOrphanedTimeLocks(commandCtx); err != nil {
+			log.Fatal().Err(err).Msg("error while cleaning up orphaned timelocks")
+		}
+		return
+	}
+
+	// run a one-off recalculation of the timelock expire heights if requested
+	if recalculate, paramsVersion := cli.GetRecalculateTimeLocksCommand(); recalculate {
+		leaseCtx, release := holdLeaderLease(commandCtx, service, "recalculate-timelocks")
+		defer release()
+		if _, err := service.RecalculateTimeLockExpiry(leaseCtx, paramsVersion); err != nil {
+			log.Fatal().Err(err).Msg("error while recalculating timelock expire heights")
+		}
+		return
+	}
+
+	// list or requeue the poison outbox events if requested
+	if poison, requeue := cli.GetOutboxPoisonCommand(); poison {
+		if err := service.ReportPoisonOutboxEvents(commandCtx, requeue); err != nil {
+			log.Fatal().Err(err).Msg("error while handling poison outbox events")
+		}
+		return
+	}
+
+	// republish the events of delegations if requested
+	if republish, cmd := cli.GetRepublishCommand(); republish {
+		req := services.RepublishRequest{
+			FromHeight:      cmd.FromHeight,
+			ToHeight:        cmd.ToHeight,
+			EventsPerSecond: cmd.EventsPerSecond,
+		}
+		if cmd.StakingTxHashHex != "" {
+			req.StakingTxHashHex = parseStakingTxHash(cmd.StakingTxHashHex)
+		}
+		leaseCtx, release := holdLeaderLease(commandCtx, service, "republish")
+		defer release()
+		if err := service.RepublishEvents(leaseCtx, req); err != nil {
+			log.Fatal().Err(err).Msg("error while republishing events")
+		}
+		return
+	}
+
+	// refuse to start against a BTC network other than the BBN chain one
+	if cli.IsNetworkVerificationSkipped() {
+		log.Warn().Msg("skipping the verification of the BTC network")
+	} else if err := service.VerifyBtcNetwork(ctx); err != nil {
+		log.Fatal().Err(err).Msg("BTC network does not match the BBN chain")
+	}
+
+	// refuse to start on top of params that differ from the BBN chain ones
+	if cli.IsParamsVerificationSkipped() {
+		log.Warn().Msg("skipping the verification of the stored params")
+	} else if err := service.VerifyStoredParams(ctx); err != nil {
+		log.Fatal().Err(err).Msg("stored params do not match the BBN chain")
+	}
+
+	// serve the metrics on a listener of their own if configured
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled {
+		metricsServer = metrics.StartServer(cfg.Metrics.GetMetricsAddress())
+	}
+
+	// the indexer and the api run on a context of their own, cancelled once
+	// the shutdown sequence stopped them, so that a signal does not cancel
+	// the block in flight
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+
+	// serve the api alongside the indexer if configured
+	apiCtx, stopAPI := context.WithCancel(runCtx)
+	defer stopAPI()
+	var apiStopped <-chan struct{}
+	if cfg.API.IsEnabled() {
+		apiServer := api.New(&cfg.API, dbClient, service, service, service, service, service)
+		// a server failing to listen keeps failing, the process exits
+		apiPolicy := supervisor.WithBackoff(time.Second, 10*time.Second).GiveUpAfter(5)
+		apiComponent := sup.Go(apiCtx, "api_server", apiPolicy, func(ctx context.Context) error {
+			err := apiServer.Run(ctx)
+			if err != nil && ctx.Err() != nil {
+				log.Error().Err(err).Msg("error while stopping api server")
+			}
+			return err
+		})
+		apiStopped = apiComponent.Done()
+	} else {
+		stopped := make(chan struct{})
+		close(stopped)
+		apiStopped = stopped
+	}
+
+	// the BBN height the block processing starts from is resolved by the
+	// leader once elected, resyncing the processing if explicitly requested
+	forceResync, allowGap := cli.GetStartHeightOverrides()
+	startHeightOverrides := &services.StartHeightOverrides{
+		ForceResync: forceResync,
+		AllowGap:    allowGap,
+	}
+	if resyncHeight, ok := cli.GetResyncBbnHeight(); ok {
+		startHeightOverrides.ResyncHeight = &resyncHeight
+	}
+
+	indexerStopped := make(chan struct{})
+	go func() {
+		defer close(indexerStopped)
+		service.StartIndexerSync(runCtx, startHeightOverrides)
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Info().Msg("shutdown signal received")
+	case <-indexerStopped:
+	}
+	// a second signal kills the process without waiting for the shutdown
+	stop()
+
+	// stop the processing first, then the listeners, then the connections
+	// the processing and the api use
+	coordinator := shutdown.New(cfg.Shutdown.GetGracePeriod())
+	coordinator.Add("pollers", service.StopPollers)
+	coordinator.Add("block_processor", service.StopBbnBlockProcessor)
+	coordinator.Add("outbox", service.FlushOutbox)
+	coordinator.Add("leadership", service.ReleaseLeadership)
+	coordinator.Add("http", func(ctx context.Context) error {
+		stopAPI()
+		var metricsErr error
+		if metricsServer != nil {
+			metricsErr = metricsServer.Shutdown(ctx)
+		}
+		select {
+		case <-apiStopped:
+			return metricsErr
+		case <-ctx.Done():
+			return errors.Join(errors.New("api server abandoned with requests in flight"), metricsErr)
+		}
+	})
+	coordinator.Add("mongo", database.Disconnect)
+	coordinator.Add("emitter", func(context.Context) error {
+		return queueConsumer.Stop()
+	})
+	coordinator.Shutdown()
+}
+
+// holdLeaderLease holds the leader lease for the one-off command writing to
+// the database until released, exiting while an indexer leads. A command
+// exiting on an error leaves the lease to expire.
+func holdLeaderLease(
+	ctx context.Context, service *services.Service, command string,
+) (context.Context, func()) {
+	leaseCtx, release, err := service.HoldLeaderLease(ctx, command)
+	if err != nil {
+		log.Fatal().Err(err).Str("command", command).Msg("error while acquiring the leader lease")
+	}
+	return leaseCtx, release
+}
+
+// parseStakingTxHash returns the normalized staking tx hash given to a
+// command, exiting on an invalid one
+func parseStakingTxHash(stakingTxHashHex string) types.StakingTxHash {
+	stakingTxHash, err := types.NewStakingTxHash(stakingTxHashHex)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid staking tx hash")
+	}
+	return stakingTxHash
+}
+
+// writeCovenantSignatures writes the status of the signature of each covenant
+// member as a table, followed by whether the quorum is met
+func writeCovenantSignatures(out io.Writer, report *services.CovenantSignaturesReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COVENANT PK\tSTATUS\tREASON")
+	for _, signature := range report.Signatures {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", signature.CovenantBtcPkHex, signature.Status, signature.Reason)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(
+		out, "valid signatures: %d/%d, quorum met: %t\n",
+		report.ValidSignatures, report.CovenantQuorum, report.QuorumMet,
+	)
+	return err
+}
+
+// writeReplayedEvents writes the replayed events as a table
+func writeReplayedEvents(out io.Writer, events []services.ReplayedEvent) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BBN HEIGHT\tEVENT\tSTATE\tSUB STATE")
+	for _, event := range events {
+		state := event.State.String()
+		if state == "" {
+			state = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", event.BbnHeight, event.EventType, state, event.SubState)
+	}
+	return w.Flush()
+}
+
+// writeParamsStatuses writes the params versions as a table and returns
+// whether any of them mismatches the BBN chain
+func writeParamsStatuses(out io.Writer, statuses []services.ParamsVersionStatus) (bool, error) {
+	mismatch := false
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tVERSION\tBTC ACTIVATION HEIGHT\tSTATUS\tMISMATCHED FIELDS")
+	for _, status := range statuses {
+		activationHeight := "-"
+		if status.Type == db.STAKING_PARAMS_TYPE {
+			activationHeight = strconv.FormatUint(uint64(status.BtcActivationHeight), 10)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n",
+			status.Type, status.Version, activationHeight, status.Status, strings.Join(status.MismatchedFields, ", "))
+		mismatch = mismatch || status.IsMismatch()
+	}
+	return mismatch, w.Flush()
+}
+
+// captureBbnEvents writes the handled events of the BBN block of the command,
+// indented, to its output file or stdout
+func captureBbnEvents(ctx context.Context, cfg *config.Config, cmd cli.CaptureEventsCommand) error {
+	bbnClient := bbnclient.NewBBNClient(&cfg.BBN)
+	if err := bbnClient.Start(); err != nil {
+		return fmt.Errorf("failed to start BBN client: %w", err)
+	}
+	service := services.NewService(cfg, nil, nil, nil, bbnClient, nil)
+	fixture, captureErr := service.CaptureBbnEvents(ctx, cmd.Height, cmd.BabylonVersion)
+	if captureErr != nil {
+		return captureErr
+	}
+
+	out, err := json.MarshalIndent(fixture, "", "  ")
 	if err != nil {
-		log.Fatal().Err(err).Msg("error while creating service")
+		return fmt.Errorf("failed to encode the events: %w", err)
+	}
+	out = append(out, '\n')
+	if cmd.Output == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(cmd.Output, out, 0o644)
+}
+
+// writeStakingReport writes the report to the output file of the command, or
+// stdout
+func writeStakingReport(ctx context.Context, service *services.Service, cmd cli.ReportCommand) error {
+	req := services.StakingReportRequest{
+		From:        cmd.From,
+		To:          cmd.To,
+		Granularity: cmd.Granularity,
+		Format:      cmd.Format,
+	}
+	if cmd.Output == "" {
+		if err := service.WriteStakingReport(ctx, req, os.Stdout); err != nil {
+			return err
+		}
+		return nil
 	}
 
-	// initialize metrics with the metrics port from config
-	metricsPort := cfg.Metrics.GetMetricsPort()
-	metrics.Init(metricsPort)
+	file, err := os.Create(cmd.Output)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	if err := service.WriteStakingReport(ctx, req, file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func handleDeadLetters(queueManager *consumer.QueueManager, cmd cli.DeadLettersCommand) error {
+	queueNames := queueManager.QueueNames()
+	if cmd.Queue != "" {
+		queueNames = []string{cmd.Queue}
+	}
+
+	for _, queueName := range queueNames {
+		if cmd.Requeue {
+			requeued, err := queueManager.RequeueDeadLetters(queueName)
+			if err != nil {
+				return err
+			}
+			log.Info().Str("queue", queueName).Int("messages", requeued).Msg("requeued dead letters")
+			continue
+		}
+
+		deadLetters, err := queueManager.InspectDeadLetters(queueName, cmd.Limit)
+		if err != nil {
+			return err
+		}
+		for _, deadLetter := range deadLetters {
+			log.Warn().
+				Str("queue", queueName).
+				Str("reason", deadLetter.Reason).
+				Int64("count", deadLetter.Count).
+				Str("body", deadLetter.Body).
+				Msg("dead letter")
+		}
+		log.Info().Str("queue", queueName).Int("messages", len(deadLetters)).Msg("found dead letters")
+	}
 
-	service.StartIndexerSync(ctx)
+	return nil
 }
@@ -0,0 +1,95 @@
+package consumer
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetter is a message rejected by the consumers of a queue
+type DeadLetter struct {
+	Body string
+	// Reason is why the message got dead-lettered, such as rejected
+	Reason string
+	// Count is the number of times the message got dead-lettered
+	Count int64
+}
+
+// InspectDeadLetters returns up to limit messages of the dead-letter queue of
+// the given queue, leaving them in place
+func (qc *QueueManager) InspectDeadLetters(queueName string, limit int) ([]DeadLetter, error) {
+	var deadLetters []DeadLetter
+	// The messages got but not acked return to the queue with the channel
+	err := qc.publisher.withChannel(func(channel *amqp.Channel) error {
+		for len(deadLetters) < limit {
+			delivery, ok, err := channel.Get(queueName+DeadLetterQueuePostfix, false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+			deadLetters = append(deadLetters, newDeadLetter(delivery))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect the dead letters of queue %s: %w", queueName, err)
+	}
+
+	return deadLetters, nil
+}
+
+// RequeueDeadLetters publishes the messages of the dead-letter queue of the
+// given queue back to it and returns their number
+func (qc *QueueManager) RequeueDeadLetters(queueName string) (int, error) {
+	requeued := 0
+	err := qc.publisher.withChannel(func(channel *amqp.Channel) error {
+		for {
+			delivery, ok, err := channel.Get(queueName+DeadLetterQueuePostfix, false)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+
+			// A message is only removed once published again, so that a
+			// failure leaves it in the dead-letter queue
+			if err := qc.publisher.publish(queueName, delivery.Body); err != nil {
+				return err
+			}
+			if err := delivery.Ack(false); err != nil {
+				return err
+			}
+			requeued++
+		}
+	})
+	if err != nil {
+		return requeued, fmt.Errorf("failed to requeue the dead letters of queue %s: %w", queueName, err)
+	}
+
+	return requeued, nil
+}
+
+func newDeadLetter(delivery amqp.Delivery) DeadLetter {
+	deadLetter := DeadLetter{Body: string(delivery.Body)}
+
+	// The most recent dead-lettering comes first
+	deaths, ok := delivery.Headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return deadLetter
+	}
+	death, ok := deaths[0].(amqp.Table)
+	if !ok {
+		return deadLetter
+	}
+	if reason, ok := death["reason"].(string); ok {
+		deadLetter.Reason = reason
+	}
+	if count, ok := death["count"].(int64); ok {
+		deadLetter.Count = count
+	}
+
+	return deadLetter
+}
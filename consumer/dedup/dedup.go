@@ -0,0 +1,96 @@
+// Package dedup implements the delivery contract of the indexer events on the
+// consumer side.
+//
+// The events are delivered at least once: a message can be delivered again
+// after the indexer crashed before recording its publication, or when it is
+// republished on request. A message delivered again always carries the same
+// idempotency key and sequence number as the first delivery. The sequence
+// number increases by one with each event of a delegation, starting at 1.
+//
+// A consumer either records the idempotency keys of the messages it applied,
+// e.g. under a unique index next to their effect, or the last sequence number
+// applied per delegation, as the Tracker does. A gap in the sequence numbers
+// of a delegation means that events were missed, which can be republished.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// IdempotencyKey derives the key of the event of a delegation transition from
+// the staking tx hash, the transition and the height it happened at
+func IdempotencyKey(stakingTxHashHex string, transition string, height uint64) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", stakingTxHashHex, transition, height)))
+	return hex.EncodeToString(hash[:])
+}
+
+// Result classifies a message against the ones already applied
+type Result int
+
+const (
+	// InOrder is the next message of the delegation, to be applied
+	InOrder Result = iota
+	// Duplicate was already applied and must be skipped
+	Duplicate
+	// Gap follows missed messages of the delegation, which should be
+	// republished before applying it
+	Gap
+)
+
+func (r Result) String() string {
+	switch r {
+	case InOrder:
+		return "in_order"
+	case Duplicate:
+		return "duplicate"
+	case Gap:
+		return "gap"
+	default:
+		return fmt.Sprintf("result(%d)", int(r))
+	}
+}
+
+// Tracker tracks the last sequence number applied per delegation. It is not
+// safe for concurrent use.
+type Tracker struct {
+	last map[string]uint64
+}
+
+// NewTracker creates a tracker resuming from the last sequence numbers
+// applied, as persisted by the consumer, which may be nil
+func NewTracker(last map[string]uint64) *Tracker {
+	tracker := &Tracker{last: make(map[string]uint64, len(last))}
+	for stakingTxHashHex, sequence := range last {
+		tracker.last[stakingTxHashHex] = sequence
+	}
+	return tracker
+}
+
+// Check classifies the message of the delegation with the given sequence
+// number, without recording it
+func (t *Tracker) Check(stakingTxHashHex string, sequence uint64) Result {
+	last := t.last[stakingTxHashHex]
+	switch {
+	case sequence <= last:
+		return Duplicate
+	case sequence == last+1:
+		return InOrder
+	default:
+		return Gap
+	}
+}
+
+// Applied records the message of the delegation with the given sequence number
+// as applied
+func (t *Tracker) Applied(stakingTxHashHex string, sequence uint64) {
+	if sequence > t.last[stakingTxHashHex] {
+		t.last[stakingTxHashHex] = sequence
+	}
+}
+
+// Last returns the last sequence number applied for the delegation, 0 if none
+func (t *Tracker) Last(stakingTxHashHex string) uint64 {
+	return t.last[stakingTxHashHex]
+}
@@ -0,0 +1,34 @@
+package dedup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKeyIsDeterministic(t *testing.T) {
+	key := IdempotencyKey("staking-tx", "withdrawn_staking", 110)
+
+	require.Equal(t, key, IdempotencyKey("staking-tx", "withdrawn_staking", 110))
+	require.Len(t, key, 64)
+	require.NotEqual(t, key, IdempotencyKey("staking-tx", "withdrawn_staking", 111))
+	require.NotEqual(t, key, IdempotencyKey("staking-tx", "withdrawable_staking", 110))
+	require.NotEqual(t, key, IdempotencyKey("other-tx", "withdrawn_staking", 110))
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(map[string]uint64{"tx-a": 2})
+
+	require.Equal(t, Duplicate, tracker.Check("tx-a", 2))
+	require.Equal(t, InOrder, tracker.Check("tx-a", 3))
+	require.Equal(t, Gap, tracker.Check("tx-a", 4))
+	require.Equal(t, InOrder, tracker.Check("tx-b", 1))
+
+	tracker.Applied("tx-a", 3)
+	require.Equal(t, Duplicate, tracker.Check("tx-a", 3))
+	require.Equal(t, uint64(3), tracker.Last("tx-a"))
+
+	// A redelivered message does not move the tracker back
+	tracker.Applied("tx-a", 1)
+	require.Equal(t, uint64(3), tracker.Last("tx-a"))
+}
@@ -0,0 +1,59 @@
+package consumer
+
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+)
+
+// DryRunEmitter logs the staking events instead of publishing them, so that
+// a dry run of the indexer emits nothing
+type DryRunEmitter struct {
+	replay bool
+}
+
+func NewDryRunEmitter() *DryRunEmitter {
+	return &DryRunEmitter{}
+}
+
+func (e *DryRunEmitter) Start() error {
+	return nil
+}
+
+func (e *DryRunEmitter) Replaying() EventConsumer {
+	return &DryRunEmitter{replay: true}
+}
+
+func (e *DryRunEmitter) Stop() error {
+	return nil
+}
+
+func (e *DryRunEmitter) PushActiveStakingEvent(ev *StakingEvent) error {
+	e.record(ev)
+	return nil
+}
+
+func (e *DryRunEmitter) PushUnbondingStakingEvent(ev *StakingEvent) error {
+	e.record(ev)
+	return nil
+}
+
+func (e *DryRunEmitter) PushWithdrawableStakingEvent(ev *WithdrawalStakingEvent) error {
+	e.record(ev)
+	return nil
+}
+
+func (e *DryRunEmitter) PushWithdrawnStakingEvent(ev *WithdrawalStakingEvent) error {
+	e.record(ev)
+	return nil
+}
+
+func (e *DryRunEmitter) PushSlashedFundsStakingEvent(ev *WithdrawalStakingEvent) error {
+	e.record(ev)
+	return nil
+}
+
+func (e *DryRunEmitter) record(ev interface{}) {
+	logging.Emitter.Logger().Info().
+		Bool("replay", e.replay).
+		Interface("event", ev).
+		Msg("dry run: event push skipped")
+}
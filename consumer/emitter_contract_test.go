@@ -0,0 +1,374 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	queuecfg "github.com/babylonlabs-io/staking-queue-client/config"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// The contract suite runs against live brokers when their address is set,
+// e.g. with the ones of docker-compose
+const (
+	rabbitMQAddrEnv   = "TEST_RABBITMQ_ADDR"
+	kafkaBrokersEnv   = "TEST_KAFKA_BROKERS"
+	receiveTimeout    = 10 * time.Second
+	contractTxHashHex = "contract-staking-tx"
+)
+
+// emitterHarness wires an emitter to the broker it publishes to
+type emitterHarness struct {
+	emitter EventConsumer
+	// receive returns the body of the next published event of the given type
+	receive func(t *testing.T, eventType client.EventType) []byte
+	// reject makes the broker reject the next push, nil if it cannot
+	reject func()
+}
+
+// runEmitterContractTests checks the behavior the outbox relies on, as
+// documented by EventConsumer: an event reported pushed is delivered once and
+// unchanged along with its delivery identifiers, the events of a delegation
+// keep their order, and a push that cannot be delivered fails synchronously.
+func runEmitterContractTests(t *testing.T, newHarness func(t *testing.T) *emitterHarness) {
+	t.Run("delivers each event type", func(t *testing.T) {
+		h := newHarness(t)
+		active := NewActiveStakingEvent(contractTxHashHex, "staker", []string{"fp"}, 1000, 90, 1)
+		active.Delivery = contractDelivery(1)
+		unbonding := NewUnbondingStakingEvent(
+			contractTxHashHex, "staker", []string{"fp"}, 1000, 90, 1, "EARLY_UNBONDING", "unbonding-tx", 100,
+		)
+		unbonding.Delivery = contractDelivery(2)
+		withdrawable := NewWithdrawableStakingEvent(contractTxHashHex, "TIMELOCK", 100)
+		withdrawable.Delivery = contractDelivery(3)
+		withdrawn := NewWithdrawnStakingEvent(contractTxHashHex, "TIMELOCK", 110, "spending-tx")
+		withdrawn.Delivery = contractDelivery(4)
+		slashedFunds := NewSlashedFundsStakingEvent(contractTxHashHex, "SLASHING_CONFIRMED", 120, "slashing-tx")
+		slashedFunds.Delivery = contractDelivery(5)
+
+		require.NoError(t, h.emitter.PushActiveStakingEvent(&active))
+		require.NoError(t, h.emitter.PushUnbondingStakingEvent(&unbonding))
+		require.NoError(t, h.emitter.PushWithdrawableStakingEvent(&withdrawable))
+		require.NoError(t, h.emitter.PushWithdrawnStakingEvent(&withdrawn))
+		require.NoError(t, h.emitter.PushSlashedFundsStakingEvent(&slashedFunds))
+
+		requireEventBody(t, active, h.receive(t, client.ActiveStakingEventType))
+		requireEventBody(t, unbonding, h.receive(t, client.UnbondingStakingEventType))
+		requireEventBody(t, withdrawable, h.receive(t, WithdrawableStakingEventType))
+		requireEventBody(t, withdrawn, h.receive(t, WithdrawnStakingEventType))
+		requireEventBody(t, slashedFunds, h.receive(t, SlashedFundsStakingEventType))
+	})
+
+	t.Run("keeps the order of a delegation events", func(t *testing.T) {
+		h := newHarness(t)
+		// The events of two delegations are interleaved, only the order of
+		// the events of each delegation being guaranteed
+		txHashes := []string{contractTxHashHex + "-a", contractTxHashHex + "-b"}
+		pushed := make(map[string][]string)
+		for height := uint32(100); height < 105; height++ {
+			for _, txHash := range txHashes {
+				event := NewWithdrawableStakingEvent(txHash, "TIMELOCK", height)
+				require.NoError(t, h.emitter.PushWithdrawableStakingEvent(&event))
+				body, err := json.Marshal(event)
+				require.NoError(t, err)
+				pushed[txHash] = append(pushed[txHash], string(body))
+			}
+		}
+
+		received := make(map[string][]string)
+		for range 5 * len(txHashes) {
+			body := h.receive(t, WithdrawableStakingEventType)
+			var event WithdrawalStakingEvent
+			require.NoError(t, json.Unmarshal(body, &event))
+			received[event.StakingTxHashHex] = append(received[event.StakingTxHashHex], string(body))
+		}
+		for _, txHash := range txHashes {
+			require.Len(t, received[txHash], len(pushed[txHash]))
+			for i := range pushed[txHash] {
+				require.JSONEq(t, pushed[txHash][i], received[txHash][i])
+			}
+		}
+	})
+
+	t.Run("fails a rejected push synchronously", func(t *testing.T) {
+		h := newHarness(t)
+		if h.reject == nil {
+			t.Skip("the broker cannot be made to reject a push")
+		}
+		rejected := NewWithdrawableStakingEvent(contractTxHashHex, "TIMELOCK", 100)
+		h.reject()
+		require.Error(t, h.emitter.PushWithdrawableStakingEvent(&rejected))
+
+		// The rejected event is not delivered, its push again is
+		pushedAgain := NewWithdrawableStakingEvent(contractTxHashHex, "TIMELOCK", 101)
+		require.NoError(t, h.emitter.PushWithdrawableStakingEvent(&pushedAgain))
+		requireEventBody(t, pushedAgain, h.receive(t, WithdrawableStakingEventType))
+	})
+
+	t.Run("fails to push once stopped", func(t *testing.T) {
+		h := newHarness(t)
+		require.NoError(t, h.emitter.Stop())
+
+		event := NewWithdrawableStakingEvent(contractTxHashHex, "TIMELOCK", 100)
+		require.Error(t, h.emitter.PushWithdrawableStakingEvent(&event))
+	})
+}
+
+func contractDelivery(sequence uint64) schema.Delivery {
+	return schema.Delivery{
+		IdempotencyKey: fmt.Sprintf("%s:%d", contractTxHashHex, sequence),
+		Sequence:       sequence,
+	}
+}
+
+func requireEventBody(t *testing.T, expected any, body []byte) {
+	expectedBody, err := json.Marshal(expected)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedBody), string(body))
+}
+
+func TestKafkaEmitterContractInMemory(t *testing.T) {
+	runEmitterContractTests(t, func(t *testing.T) *emitterHarness {
+		writer := &fakeMessageWriter{}
+		emitter, err := newKafkaEmitter(writer, &config.KafkaConfig{WriteTimeout: time.Second}, nil)
+		require.NoError(t, err)
+
+		return &emitterHarness{
+			emitter: emitter,
+			reject:  func() { writer.failures++ },
+			receive: func(t *testing.T, eventType client.EventType) []byte {
+				for i, msg := range writer.messages {
+					if messageEventType(t, msg) == eventType {
+						writer.messages = append(writer.messages[:i], writer.messages[i+1:]...)
+						return msg.Value
+					}
+				}
+				t.Fatalf("no event of type %d published", eventType)
+				return nil
+			},
+		}
+	})
+}
+
+func TestMemoryEmitterContract(t *testing.T) {
+	runEmitterContractTests(t, func(t *testing.T) *emitterHarness {
+		emitter := NewMemoryEmitter()
+		received := 0
+		return &emitterHarness{
+			emitter: emitter,
+			reject:  func() { emitter.FailPushes(1, errors.New("event rejected")) },
+			receive: func(t *testing.T, eventType client.EventType) []byte {
+				events := emitter.Events()
+				for ; received < len(events); received++ {
+					if events[received].Event.GetEventType() == eventType {
+						body, err := json.Marshal(events[received].Event)
+						require.NoError(t, err)
+						received++
+						return body
+					}
+				}
+				t.Fatalf("no event of type %d pushed", eventType)
+				return nil
+			},
+		}
+	})
+}
+
+func TestKafkaEmitterContract(t *testing.T) {
+	brokers := os.Getenv(kafkaBrokersEnv)
+	if brokers == "" {
+		t.Skipf("%s not set", kafkaBrokersEnv)
+	}
+
+	runEmitterContractTests(t, func(t *testing.T) *emitterHarness {
+		cfg := &config.KafkaConfig{
+			Brokers:      strings.Split(brokers, ","),
+			Topic:        fmt.Sprintf("indexer-contract-%d", time.Now().UnixNano()),
+			WriteTimeout: receiveTimeout,
+		}
+		emitter, err := NewKafkaEmitter(cfg, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = emitter.Stop() })
+
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     cfg.Brokers,
+			Topic:       cfg.Topic,
+			StartOffset: kafka.FirstOffset,
+		})
+		t.Cleanup(func() { _ = reader.Close() })
+
+		// events read while waiting for another type
+		pending := make(map[client.EventType][][]byte)
+		return &emitterHarness{
+			emitter: emitter,
+			receive: func(t *testing.T, eventType client.EventType) []byte {
+				ctx, cancel := context.WithTimeout(context.Background(), receiveTimeout)
+				defer cancel()
+
+				for len(pending[eventType]) == 0 {
+					msg, err := reader.ReadMessage(ctx)
+					require.NoError(t, err)
+					msgType := messageEventType(t, msg)
+					pending[msgType] = append(pending[msgType], msg.Value)
+				}
+				body := pending[eventType][0]
+				pending[eventType] = pending[eventType][1:]
+				return body
+			},
+		}
+	})
+}
+
+func TestRabbitMQEmitterContract(t *testing.T) {
+	addr := os.Getenv(rabbitMQAddrEnv)
+	if addr == "" {
+		t.Skipf("%s not set", rabbitMQAddrEnv)
+	}
+
+	queueNames := map[client.EventType]string{
+		client.ActiveStakingEventType:    client.ActiveStakingQueueName,
+		client.UnbondingStakingEventType: client.UnbondingStakingQueueName,
+		WithdrawableStakingEventType:     WithdrawableStakingQueueName,
+		WithdrawnStakingEventType:        WithdrawnStakingQueueName,
+		SlashedFundsStakingEventType:     SlashedFundsStakingQueueName,
+	}
+	// The events are published to renamed queues through an exchange of
+	// their own as well
+	renamed := make(map[string]string)
+	for _, queueName := range queueNames {
+		renamed[queueName] = "contract_" + queueName
+	}
+	publishCfgs := map[string]*config.RabbitMQConfig{
+		"default exchange": {},
+		"custom topology": {
+			Exchange:   "contract_staking_events",
+			QueueNames: renamed,
+			Transient:  true,
+		},
+	}
+
+	for name, publishCfg := range publishCfgs {
+		t.Run(name, func(t *testing.T) {
+			runEmitterContractTests(t, func(t *testing.T) *emitterHarness {
+				cfg := &queuecfg.QueueConfig{
+					QueueUser:              "user",
+					QueuePassword:          "password",
+					Url:                    addr,
+					QueueType:              queuecfg.QuorumQueueType,
+					QueueProcessingTimeout: 5 * time.Second,
+					MsgMaxRetryAttempts:    10,
+					ReQueueDelayTime:       5 * time.Second,
+				}
+				emitter, err := NewQueueManager(cfg, publishCfg, nil, zap.NewNop())
+				require.NoError(t, err)
+				t.Cleanup(func() { _ = emitter.Stop() })
+
+				receivers := make(map[client.EventType]client.QueueClient)
+				messages := make(map[client.EventType]<-chan client.QueueMessage)
+				for eventType, queueName := range queueNames {
+					if renamedTo, ok := publishCfg.QueueNames[queueName]; ok {
+						queueName = renamedTo
+					}
+					purgeRabbitMQQueue(t, cfg, queueName)
+					receiver, err := client.NewQueueClient(cfg, queueName)
+					require.NoError(t, err)
+					t.Cleanup(func() { _ = receiver.Stop() })
+
+					receivers[eventType] = receiver
+					messages[eventType], err = receiver.ReceiveMessages()
+					require.NoError(t, err)
+				}
+
+				return &emitterHarness{
+					emitter: emitter,
+					receive: func(t *testing.T, eventType client.EventType) []byte {
+						select {
+						case msg := <-messages[eventType]:
+							require.NoError(t, receivers[eventType].DeleteMessage(msg.Receipt))
+							return []byte(msg.Body)
+						case <-time.After(receiveTimeout):
+							t.Fatalf("no event of type %d published", eventType)
+							return nil
+						}
+					},
+				}
+			})
+		})
+	}
+}
+
+func purgeRabbitMQQueue(t *testing.T, cfg *queuecfg.QueueConfig, queueName string) {
+	conn, err := amqp091.Dial(fmt.Sprintf("amqp://%s:%s@%s", cfg.QueueUser, cfg.QueuePassword, cfg.Url))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	defer ch.Close()
+
+	// The queue does not exist before the first run
+	_, _ = ch.QueuePurge(queueName, false)
+}
+
+func messageEventType(t *testing.T, msg kafka.Message) client.EventType {
+	for _, header := range msg.Headers {
+		if header.Key == EventTypeHeader {
+			eventType, err := strconv.Atoi(string(header.Value))
+			require.NoError(t, err)
+			return client.EventType(eventType)
+		}
+	}
+	t.Fatalf("message without %s header", EventTypeHeader)
+	return 0
+}
+
+// TestWebhookEmitterOutboxContract checks the contract of the webhook
+// emitter, which publishes the outbox events as recorded: each event is
+// delivered as marshalled, the events of a delegation keep their order, and a
+// push not accepted by an enabled endpoint fails synchronously
+func TestWebhookEmitterOutboxContract(t *testing.T) {
+	receiver, server := newWebhookReceiver(t)
+	cfg := newTestWebhookConfig(server.URL)
+	emitter, err := NewWebhookEmitter(cfg, nil, nil)
+	require.NoError(t, err)
+
+	var expected [][]byte
+	for sequence := uint64(1); sequence <= 3; sequence++ {
+		for _, txHash := range []string{"tx-a", "tx-b"} {
+			event := testWebhookOutboxEvent(sequence)
+			event.Id = model.OutboxEventTypeWithdrawable + ":" + txHash
+			event.StakingTxHashHex = txHash
+			require.NoError(t, emitter.PushOutboxEvent(event))
+
+			webhookEvent := NewWebhookEvent(event)
+			body, err := schema.Encode(&webhookEvent, 0, false)
+			require.NoError(t, err)
+			expected = append(expected, body)
+		}
+	}
+	require.Len(t, receiver.bodies, len(expected))
+	for i := range expected {
+		require.JSONEq(t, string(expected[i]), string(receiver.bodies[i]))
+	}
+
+	// Every retry of the delivery is rejected
+	receiver.failures = int(cfg.MaxRetries) + 1
+	require.Error(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(4)))
+	require.Len(t, receiver.bodies, len(expected))
+	require.NoError(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(4)))
+	require.Len(t, receiver.bodies, len(expected)+1)
+}
@@ -1,12 +1,23 @@
 package consumer
 
-import (
-	"github.com/babylonlabs-io/staking-queue-client/client"
-)
-
+// EventConsumer publishes the staking events. The pushes are synchronous: a
+// push returning nil means the event was accepted by the broker, the emitters
+// waiting for its confirmation, and a push returning an error means the event
+// has to be pushed again. The events of a delegation are delivered in push
+// order, each of them as marshalled. The RabbitMQ emitter configured to skip
+// the publisher confirms reports an event pushed once sent. The webhook
+// emitter, which accepts the outbox events only, reports the endpoints it
+// disables through its callback rather than through an error, failing the
+// push only while an enabled endpoint did not accept the event.
 type EventConsumer interface {
 	Start() error
-	PushActiveStakingEvent(ev *client.StakingEvent) error
-	PushUnbondingStakingEvent(ev *client.StakingEvent) error
+	PushActiveStakingEvent(ev *StakingEvent) error
+	PushUnbondingStakingEvent(ev *StakingEvent) error
+	PushWithdrawableStakingEvent(ev *WithdrawalStakingEvent) error
+	PushWithdrawnStakingEvent(ev *WithdrawalStakingEvent) error
+	PushSlashedFundsStakingEvent(ev *WithdrawalStakingEvent) error
+	// Replaying returns an emitter sharing the connection of this one, which
+	// publishes the events flagged as replayed
+	Replaying() EventConsumer
 	Stop() error
 }
@@ -0,0 +1,145 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka message headers carrying the event type and its schema version
+const (
+	EventTypeHeader     = "event_type"
+	SchemaVersionHeader = "schema_version"
+)
+
+// messageWriter is the part of the Kafka writer used by the emitter
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaEmitter publishes the staking events to Kafka. The messages are keyed
+// by staking tx hash, so that the events of a delegation land on the same
+// partition and keep their order.
+type KafkaEmitter struct {
+	writer       messageWriter
+	topic        string
+	writeTimeout time.Duration
+	// schemaVersions are the versions each event is published in, by queue
+	schemaVersions map[string][]int
+	// replay flags the published events as replayed
+	replay bool
+}
+
+func NewKafkaEmitter(cfg *config.KafkaConfig, schemaVersions map[string][]int) (*KafkaEmitter, error) {
+	writer := &kafka.Writer{
+		Addr:     kafka.TCP(cfg.Brokers...),
+		Balancer: &kafka.Hash{},
+		// Each event is written on its own and only reported delivered once
+		// acknowledged by all the in-sync replicas
+		BatchSize:              1,
+		RequiredAcks:           kafka.RequireAll,
+		WriteTimeout:           cfg.WriteTimeout,
+		AllowAutoTopicCreation: true,
+	}
+	return newKafkaEmitter(writer, cfg, schemaVersions)
+}
+
+func newKafkaEmitter(
+	writer messageWriter, cfg *config.KafkaConfig, schemaVersions map[string][]int,
+) (*KafkaEmitter, error) {
+	versions, err := resolveSchemaVersions(schemaVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KafkaEmitter{
+		writer:         writer,
+		topic:          cfg.Topic,
+		writeTimeout:   cfg.WriteTimeout,
+		schemaVersions: versions,
+	}, nil
+}
+
+func (e *KafkaEmitter) Start() error {
+	return nil
+}
+
+func (e *KafkaEmitter) PushActiveStakingEvent(ev *StakingEvent) error {
+	return e.publish(client.ActiveStakingQueueName, ev.EventType, ev.StakingTxHashHex, ev)
+}
+
+func (e *KafkaEmitter) PushUnbondingStakingEvent(ev *StakingEvent) error {
+	return e.publish(client.UnbondingStakingQueueName, ev.EventType, ev.StakingTxHashHex, ev)
+}
+
+func (e *KafkaEmitter) PushWithdrawableStakingEvent(ev *WithdrawalStakingEvent) error {
+	return e.publish(WithdrawableStakingQueueName, ev.EventType, ev.StakingTxHashHex, ev)
+}
+
+func (e *KafkaEmitter) PushWithdrawnStakingEvent(ev *WithdrawalStakingEvent) error {
+	return e.publish(WithdrawnStakingQueueName, ev.EventType, ev.StakingTxHashHex, ev)
+}
+
+func (e *KafkaEmitter) PushSlashedFundsStakingEvent(ev *WithdrawalStakingEvent) error {
+	return e.publish(SlashedFundsStakingQueueName, ev.EventType, ev.StakingTxHashHex, ev)
+}
+
+func (e *KafkaEmitter) Replaying() EventConsumer {
+	replaying := *e
+	replaying.replay = true
+	return &replaying
+}
+
+func (e *KafkaEmitter) Stop() error {
+	return e.writer.Close()
+}
+
+// publish writes the event in each configured schema version and waits for
+// the delivery reports, so that an error means the event has to be pushed
+// again
+func (e *KafkaEmitter) publish(
+	queueName string, eventType client.EventType, stakingTxHashHex string, ev any,
+) error {
+	versions := e.schemaVersions[queueName]
+	msgs := make([]kafka.Message, 0, len(versions))
+	for _, version := range versions {
+		body, err := schema.Encode(ev, version, e.replay)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, kafka.Message{
+			Key:   []byte(stakingTxHashHex),
+			Value: body,
+			Headers: []kafka.Header{
+				{Key: EventTypeHeader, Value: []byte(strconv.Itoa(int(eventType)))},
+				{Key: SchemaVersionHeader, Value: []byte(strconv.Itoa(version))},
+			},
+		})
+	}
+
+	topic := queueName
+	if e.topic != "" {
+		topic = e.topic
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.writeTimeout)
+	defer cancel()
+
+	for i := range msgs {
+		msgs[i].Topic = topic
+	}
+
+	err := e.writer.WriteMessages(ctx, msgs...)
+	if err != nil {
+		return fmt.Errorf("failed to publish event of type %d to kafka topic %s: %w", eventType, topic, err)
+	}
+
+	return nil
+}
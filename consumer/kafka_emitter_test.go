@@ -0,0 +1,105 @@
+package consumer
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMessageWriter records the written messages as acknowledged, failing
+// the configured number of writes first
+type fakeMessageWriter struct {
+	messages []kafka.Message
+	closed   bool
+	failures int
+}
+
+func (w *fakeMessageWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+	if w.failures > 0 {
+		w.failures--
+		return kafka.LeaderNotAvailable
+	}
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *fakeMessageWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestKafkaEmitterTopicPerEventType(t *testing.T) {
+	writer := &fakeMessageWriter{}
+	emitter, err := newKafkaEmitter(writer, &config.KafkaConfig{WriteTimeout: time.Second}, nil)
+	require.NoError(t, err)
+
+	active := NewActiveStakingEvent("tx-a", "staker", []string{"fp"}, 1000, 90, 1)
+	withdrawn := NewWithdrawnStakingEvent("tx-b", "TIMELOCK", 110, "spending-tx")
+	require.NoError(t, emitter.PushActiveStakingEvent(&active))
+	require.NoError(t, emitter.PushWithdrawnStakingEvent(&withdrawn))
+
+	require.Len(t, writer.messages, 2)
+	require.Equal(t, client.ActiveStakingQueueName, writer.messages[0].Topic)
+	require.Equal(t, []byte("tx-a"), writer.messages[0].Key)
+	require.Equal(t, client.ActiveStakingEventType, messageEventType(t, writer.messages[0]))
+	require.Equal(t, WithdrawnStakingQueueName, writer.messages[1].Topic)
+	require.Equal(t, []byte("tx-b"), writer.messages[1].Key)
+	require.Equal(t, WithdrawnStakingEventType, messageEventType(t, writer.messages[1]))
+}
+
+func TestKafkaEmitterSingleTopic(t *testing.T) {
+	writer := &fakeMessageWriter{}
+	emitter, err := newKafkaEmitter(writer, &config.KafkaConfig{
+		Topic:        "staking-events",
+		WriteTimeout: time.Second,
+	}, nil)
+	require.NoError(t, err)
+
+	unbonding := NewUnbondingStakingEvent("tx-a", "staker", []string{"fp"}, 1000, 90, 1, "EARLY_UNBONDING", "unbonding-tx", 100)
+	withdrawable := NewWithdrawableStakingEvent("tx-a", "EARLY_UNBONDING", 100)
+	require.NoError(t, emitter.PushUnbondingStakingEvent(&unbonding))
+	require.NoError(t, emitter.PushWithdrawableStakingEvent(&withdrawable))
+
+	require.Len(t, writer.messages, 2)
+	for _, msg := range writer.messages {
+		require.Equal(t, "staking-events", msg.Topic)
+		require.Equal(t, []byte("tx-a"), msg.Key)
+	}
+	require.Equal(t, client.UnbondingStakingEventType, messageEventType(t, writer.messages[0]))
+	require.Equal(t, WithdrawableStakingEventType, messageEventType(t, writer.messages[1]))
+}
+
+func TestKafkaEmitterSchemaVersions(t *testing.T) {
+	writer := &fakeMessageWriter{}
+	emitter, err := newKafkaEmitter(writer, &config.KafkaConfig{WriteTimeout: time.Second}, map[string][]int{
+		WithdrawnStakingQueueName: {0},
+	})
+	require.NoError(t, err)
+
+	withdrawn := NewWithdrawnStakingEvent("tx-a", "TIMELOCK", 110, "spending-tx")
+	require.NoError(t, emitter.PushWithdrawnStakingEvent(&withdrawn))
+
+	require.Len(t, writer.messages, 1)
+	require.Contains(t, writer.messages[0].Headers, kafka.Header{Key: SchemaVersionHeader, Value: []byte("0")})
+}
+
+func TestKafkaEmitterRejectsUnsupportedSchemaVersion(t *testing.T) {
+	_, err := newKafkaEmitter(&fakeMessageWriter{}, &config.KafkaConfig{}, map[string][]int{
+		WithdrawnStakingQueueName: {0, 1},
+	})
+	require.Error(t, err)
+
+	_, err = newKafkaEmitter(&fakeMessageWriter{}, &config.KafkaConfig{}, map[string][]int{
+		"unknown_queue": {0},
+	})
+	require.Error(t, err)
+}
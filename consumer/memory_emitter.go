@@ -0,0 +1,150 @@
+package consumer
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+)
+
+// errMemoryEmitterStopped is returned by the pushes to a stopped MemoryEmitter
+var errMemoryEmitterStopped = errors.New("memory emitter stopped")
+
+// EmittedEvent is a staking event pushed to a MemoryEmitter
+type EmittedEvent struct {
+	// Queue is the name of the queue the event is published to
+	Queue string
+	// Event is the *StakingEvent or *WithdrawalStakingEvent pushed
+	Event client.EventMessage
+	// Replay flags an event pushed through the replaying emitter
+	Replay bool
+}
+
+// Delivery returns the delivery identifiers the event was pushed with
+func (e EmittedEvent) Delivery() schema.Delivery {
+	switch ev := e.Event.(type) {
+	case *StakingEvent:
+		return ev.Delivery
+	case *WithdrawalStakingEvent:
+		return ev.Delivery
+	default:
+		return schema.Delivery{}
+	}
+}
+
+// MemoryEmitter records the pushed staking events in memory in push order,
+// for the tests to assert the events the indexer emits without a broker. Its
+// pushes can be made to fail, the error being returned by the push as the
+// other emitters do, and the failed events not being recorded.
+type MemoryEmitter struct {
+	replay bool
+	// events are shared with the replaying emitter
+	events *memoryEvents
+}
+
+type memoryEvents struct {
+	mu      sync.Mutex
+	events  []EmittedEvent
+	stopped bool
+	// failures fail the next pushes of any delegation
+	failures memoryFailures
+	// delegationFailures fail the next pushes of the delegations, by staking
+	// tx hash
+	delegationFailures map[string]*memoryFailures
+}
+
+// memoryFailures is a number of pushes to fail with the error
+type memoryFailures struct {
+	remaining int
+	err       error
+}
+
+// fail consumes one of the failures, returning its error if any is left
+func (f *memoryFailures) fail() error {
+	if f == nil || f.remaining == 0 {
+		return nil
+	}
+	f.remaining--
+	return f.err
+}
+
+func NewMemoryEmitter() *MemoryEmitter {
+	return &MemoryEmitter{events: &memoryEvents{
+		delegationFailures: make(map[string]*memoryFailures),
+	}}
+}
+
+// Events returns the events pushed so far, in push order
+func (e *MemoryEmitter) Events() []EmittedEvent {
+	e.events.mu.Lock()
+	defer e.events.mu.Unlock()
+	return append([]EmittedEvent{}, e.events.events...)
+}
+
+// FailPushes makes the next n pushes fail with the error
+func (e *MemoryEmitter) FailPushes(n int, err error) {
+	e.events.mu.Lock()
+	defer e.events.mu.Unlock()
+	e.events.failures = memoryFailures{remaining: n, err: err}
+}
+
+// FailDelegationPushes makes the next n pushes of the events of the
+// delegation fail with the error
+func (e *MemoryEmitter) FailDelegationPushes(stakingTxHashHex string, n int, err error) {
+	e.events.mu.Lock()
+	defer e.events.mu.Unlock()
+	e.events.delegationFailures[stakingTxHashHex] = &memoryFailures{remaining: n, err: err}
+}
+
+func (e *MemoryEmitter) Start() error {
+	return nil
+}
+
+func (e *MemoryEmitter) Replaying() EventConsumer {
+	return &MemoryEmitter{replay: true, events: e.events}
+}
+
+// Stop makes the later pushes fail, the events pushed so far being kept
+func (e *MemoryEmitter) Stop() error {
+	e.events.mu.Lock()
+	defer e.events.mu.Unlock()
+	e.events.stopped = true
+	return nil
+}
+
+func (e *MemoryEmitter) PushActiveStakingEvent(ev *StakingEvent) error {
+	return e.push(client.ActiveStakingQueueName, ev)
+}
+
+func (e *MemoryEmitter) PushUnbondingStakingEvent(ev *StakingEvent) error {
+	return e.push(client.UnbondingStakingQueueName, ev)
+}
+
+func (e *MemoryEmitter) PushWithdrawableStakingEvent(ev *WithdrawalStakingEvent) error {
+	return e.push(WithdrawableStakingQueueName, ev)
+}
+
+func (e *MemoryEmitter) PushWithdrawnStakingEvent(ev *WithdrawalStakingEvent) error {
+	return e.push(WithdrawnStakingQueueName, ev)
+}
+
+func (e *MemoryEmitter) PushSlashedFundsStakingEvent(ev *WithdrawalStakingEvent) error {
+	return e.push(SlashedFundsStakingQueueName, ev)
+}
+
+func (e *MemoryEmitter) push(queueName string, ev client.EventMessage) error {
+	e.events.mu.Lock()
+	defer e.events.mu.Unlock()
+	if e.events.stopped {
+		return errMemoryEmitterStopped
+	}
+	if err := e.events.failures.fail(); err != nil {
+		return err
+	}
+	if err := e.events.delegationFailures[ev.GetStakingTxHashHex()].fail(); err != nil {
+		return err
+	}
+	e.events.events = append(e.events.events, EmittedEvent{Queue: queueName, Event: ev, Replay: e.replay})
+	return nil
+}
@@ -0,0 +1,216 @@
+package consumer
+
+import (
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	indexerconfig "github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	"github.com/babylonlabs-io/staking-queue-client/config"
+	"go.uber.org/zap"
+)
+
+// StakingQueueNames are the default names of the queues the staking events
+// are published to
+var StakingQueueNames = []string{
+	client.ActiveStakingQueueName,
+	client.UnbondingStakingQueueName,
+	WithdrawableStakingQueueName,
+	WithdrawnStakingQueueName,
+	SlashedFundsStakingQueueName,
+}
+
+// QueueManager publishes the staking events to their queues through a
+// publisher confirming their delivery, unless configured otherwise, and
+// recovering from broker restarts. The queue clients are kept to consume the
+// queues.
+type QueueManager struct {
+	ActiveStakingQueue       client.QueueClient
+	UnbondingStakingQueue    client.QueueClient
+	WithdrawableStakingQueue client.QueueClient
+	WithdrawnStakingQueue    client.QueueClient
+	SlashedFundsStakingQueue client.QueueClient
+	publisher                *rabbitMQPublisher
+	// queueNames are the names of the queues the events are published to,
+	// keyed by their default name
+	queueNames map[string]string
+	// schemaVersions are the versions each event is published in, by queue
+	schemaVersions map[string][]int
+	// replay flags the published events as replayed
+	replay bool
+	logger *zap.Logger
+}
+
+func NewQueueManager(
+	cfg *config.QueueConfig,
+	publishCfg *indexerconfig.RabbitMQConfig,
+	schemaVersions map[string][]int,
+	logger *zap.Logger,
+) (*QueueManager, error) {
+	versions, err := resolveSchemaVersions(schemaVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	queueNames, err := resolveQueueNames(publishCfg.QueueNames)
+	if err != nil {
+		return nil, err
+	}
+
+	queues := make(map[string]client.QueueClient, len(StakingQueueNames))
+	for _, queueName := range StakingQueueNames {
+		queue, err := client.NewQueueClient(cfg, queueNames[queueName])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create queue %s: %w", queueNames[queueName], err)
+		}
+		queues[queueName] = queue
+	}
+
+	qc := &QueueManager{
+		ActiveStakingQueue:       queues[client.ActiveStakingQueueName],
+		UnbondingStakingQueue:    queues[client.UnbondingStakingQueueName],
+		WithdrawableStakingQueue: queues[WithdrawableStakingQueueName],
+		WithdrawnStakingQueue:    queues[WithdrawnStakingQueueName],
+		SlashedFundsStakingQueue: queues[SlashedFundsStakingQueueName],
+		queueNames:               queueNames,
+		schemaVersions:           versions,
+		logger:                   logger.With(zap.String("module", "queue manager")),
+	}
+	// Created after the queue clients, which bind the delay queues to the
+	// common DLX in place of the dead-letter queues
+	qc.publisher, err = newRabbitMQPublisher(cfg, publishCfg, qc.QueueNames(), logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue publisher: %w", err)
+	}
+
+	return qc, nil
+}
+
+// resolveQueueNames returns the names of the queues the events are published
+// to, keyed by their default name, renamed as configured
+func resolveQueueNames(renamed map[string]string) (map[string]string, error) {
+	queueNames := make(map[string]string, len(StakingQueueNames))
+	for _, queueName := range StakingQueueNames {
+		queueNames[queueName] = queueName
+	}
+	for queueName, renamedTo := range renamed {
+		if _, ok := queueNames[queueName]; !ok {
+			return nil, fmt.Errorf("unknown queue %s renamed", queueName)
+		}
+		queueNames[queueName] = renamedTo
+	}
+
+	named := make(map[string]bool, len(queueNames))
+	for _, queueName := range StakingQueueNames {
+		if named[queueNames[queueName]] {
+			return nil, fmt.Errorf("several queues named %s", queueNames[queueName])
+		}
+		named[queueNames[queueName]] = true
+	}
+	return queueNames, nil
+}
+
+// QueueNames returns the names of the queues the events are published to, in
+// the order of StakingQueueNames
+func (qc *QueueManager) QueueNames() []string {
+	queueNames := make([]string, len(StakingQueueNames))
+	for i, queueName := range StakingQueueNames {
+		queueNames[i] = qc.queueNames[queueName]
+	}
+	return queueNames
+}
+
+func (qc *QueueManager) Start() error {
+	return nil
+}
+
+func (qc *QueueManager) PushActiveStakingEvent(ev *StakingEvent) error {
+	if err := qc.push(client.ActiveStakingQueueName, ev); err != nil {
+		return fmt.Errorf("failed to push staking event: %w", err)
+	}
+	return nil
+}
+
+func (qc *QueueManager) PushUnbondingStakingEvent(ev *StakingEvent) error {
+	if err := qc.push(client.UnbondingStakingQueueName, ev); err != nil {
+		return fmt.Errorf("failed to push unbonding staking event: %w", err)
+	}
+	return nil
+}
+
+func (qc *QueueManager) PushWithdrawableStakingEvent(ev *WithdrawalStakingEvent) error {
+	if err := qc.push(WithdrawableStakingQueueName, ev); err != nil {
+		return fmt.Errorf("failed to push withdrawable staking event: %w", err)
+	}
+	return nil
+}
+
+func (qc *QueueManager) PushWithdrawnStakingEvent(ev *WithdrawalStakingEvent) error {
+	if err := qc.push(WithdrawnStakingQueueName, ev); err != nil {
+		return fmt.Errorf("failed to push withdrawn staking event: %w", err)
+	}
+	return nil
+}
+
+func (qc *QueueManager) PushSlashedFundsStakingEvent(ev *WithdrawalStakingEvent) error {
+	if err := qc.push(SlashedFundsStakingQueueName, ev); err != nil {
+		return fmt.Errorf("failed to push slashed funds staking event: %w", err)
+	}
+	return nil
+}
+
+// push publishes the event in each schema version configured for the queue
+func (qc *QueueManager) push(queueName string, ev client.EventMessage) error {
+	for _, version := range qc.schemaVersions[queueName] {
+		body, err := schema.Encode(ev, version, qc.replay)
+		if err != nil {
+			return err
+		}
+
+		if err := qc.publisher.publish(qc.queueNames[queueName], body); err != nil {
+			return err
+		}
+		qc.logger.Debug("pushed staking event",
+			zap.String("queue", qc.queueNames[queueName]),
+			zap.String("staking_tx_hash", ev.GetStakingTxHashHex()),
+			zap.Int("schema_version", version),
+			zap.Bool("replay", qc.replay),
+		)
+	}
+
+	return nil
+}
+
+func (qc *QueueManager) Replaying() EventConsumer {
+	replaying := *qc
+	replaying.replay = true
+	return &replaying
+}
+
+func (qc *QueueManager) Stop() error {
+	if err := qc.publisher.stop(); err != nil {
+		return err
+	}
+
+	if err := qc.ActiveStakingQueue.Stop(); err != nil {
+		return err
+	}
+
+	if err := qc.UnbondingStakingQueue.Stop(); err != nil {
+		return err
+	}
+
+	if err := qc.WithdrawableStakingQueue.Stop(); err != nil {
+		return err
+	}
+
+	if err := qc.WithdrawnStakingQueue.Stop(); err != nil {
+		return err
+	}
+
+	if err := qc.SlashedFundsStakingQueue.Stop(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveQueueNames(t *testing.T) {
+	queueNames, err := resolveQueueNames(map[string]string{
+		client.ActiveStakingQueueName: "active_staking",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "active_staking", queueNames[client.ActiveStakingQueueName])
+	require.Equal(t, WithdrawnStakingQueueName, queueNames[WithdrawnStakingQueueName])
+	require.Len(t, queueNames, len(StakingQueueNames))
+
+	_, err = resolveQueueNames(map[string]string{"unknown_queue": "queue"})
+	require.EqualError(t, err, "unknown queue unknown_queue renamed")
+
+	// A queue renamed after another one not renamed
+	_, err = resolveQueueNames(map[string]string{
+		client.ActiveStakingQueueName: client.UnbondingStakingQueueName,
+	})
+	require.EqualError(t, err, "several queues named "+client.UnbondingStakingQueueName)
+}
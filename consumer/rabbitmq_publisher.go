@@ -0,0 +1,341 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	indexerconfig "github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/staking-queue-client/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// DeadLetterQueuePostfix names the queue where the messages rejected by the
+// consumers of a queue land
+const DeadLetterQueuePostfix = "_dlq"
+
+// Topology of the staking queue client, which the publisher declares the same
+// way so that the client consumers keep working
+const (
+	commonDlxName       = "common_dlx"
+	dlxRoutingPostfix   = "_routing_key"
+	delayedQueuePostfix = "_delay"
+)
+
+// ErrPublishNacked is returned when the broker refuses a published message
+var ErrPublishNacked = errors.New("message nacked by the broker")
+
+const (
+	publishTimeout       = 5 * time.Second
+	initialReconnectWait = time.Second
+	maxReconnectWait     = 30 * time.Second
+	returnsBufferSize    = 16
+)
+
+// rabbitMQPublisher publishes to the staking queues with publisher confirms
+// and mandatory routing, so that a message is only reported published once
+// the broker routed it to a queue, unless the confirms are skipped. It
+// reconnects on its own after the connection or its channel gets closed,
+// publishing failing meanwhile.
+type rabbitMQPublisher struct {
+	cfg        *config.QueueConfig
+	publishCfg *indexerconfig.RabbitMQConfig
+	queueNames []string
+	logger     *zap.Logger
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+	returns chan amqp.Return
+	// published numbers the messages to match them with their returns
+	published uint64
+	stopCh    chan struct{}
+}
+
+func newRabbitMQPublisher(
+	cfg *config.QueueConfig, publishCfg *indexerconfig.RabbitMQConfig, queueNames []string, logger *zap.Logger,
+) (*rabbitMQPublisher, error) {
+	p := &rabbitMQPublisher{
+		cfg:        cfg,
+		publishCfg: publishCfg,
+		queueNames: queueNames,
+		logger:     logger,
+		stopCh:     make(chan struct{}),
+	}
+	if err := p.connect(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *rabbitMQPublisher) connect() error {
+	conn, err := amqp.Dial(amqpURI(p.cfg))
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+
+	for _, queueName := range p.queueNames {
+		if err := declareQueueTopology(channel, p.cfg, queueName); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to declare the topology of queue %s: %w", queueName, err)
+		}
+	}
+
+	if p.publishCfg.Exchange != "" {
+		if err := declareExchange(channel, p.publishCfg, p.queueNames); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to declare exchange %s: %w", p.publishCfg.Exchange, err)
+		}
+	}
+
+	if !p.publishCfg.SkipPublisherConfirms {
+		if err := channel.Confirm(false); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to enable publisher confirms: %w", err)
+		}
+	}
+
+	returns := channel.NotifyReturn(make(chan amqp.Return, returnsBufferSize))
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	p.mu.Lock()
+	p.conn = conn
+	p.channel = channel
+	p.returns = returns
+	p.mu.Unlock()
+
+	go p.watch(conn, connClosed, channelClosed)
+	return nil
+}
+
+// watch reconnects once the connection or the channel gets closed by the
+// broker or the network
+func (p *rabbitMQPublisher) watch(
+	conn *amqp.Connection, connClosed, channelClosed chan *amqp.Error,
+) {
+	var closeErr *amqp.Error
+	select {
+	case <-p.stopCh:
+		return
+	case closeErr = <-connClosed:
+	case closeErr = <-channelClosed:
+	}
+
+	// A graceful close reports no error
+	if closeErr == nil {
+		return
+	}
+
+	p.logger.Warn("RabbitMQ connection lost, reconnecting", zap.Error(closeErr))
+	p.mu.Lock()
+	p.channel = nil
+	p.mu.Unlock()
+	conn.Close()
+
+	wait := initialReconnectWait
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-time.After(wait):
+		}
+
+		err := p.connect()
+		if err == nil {
+			p.logger.Info("RabbitMQ connection recovered")
+			return
+		}
+		p.logger.Warn("failed to reconnect to RabbitMQ", zap.Error(err))
+		wait = min(wait*2, maxReconnectWait)
+	}
+}
+
+// publish sends the message to the queue and waits for the broker to confirm
+// it. A nacked or returned message fails to be published. Without the
+// confirms, the message is published once sent.
+func (p *rabbitMQPublisher) publish(queueName string, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.channel == nil {
+		return errors.New("not connected to RabbitMQ")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	p.published++
+	messageId := strconv.FormatUint(p.published, 10)
+	deliveryMode := amqp.Persistent
+	if p.publishCfg.Transient {
+		deliveryMode = amqp.Transient
+	}
+	confirmation, err := p.channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		p.publishCfg.Exchange, // the default one if unset, routing to the queue named by the key
+		queueName,             // routing key
+		// mandatory: return the message if no queue is bound, only noticed
+		// along with the confirms
+		!p.publishCfg.SkipPublisherConfirms,
+		false, // immediate
+		amqp.Publishing{
+			DeliveryMode: deliveryMode,
+			ContentType:  "text/plain",
+			MessageId:    messageId,
+			Body:         body,
+			Headers:      amqp.Table{"x-processing-attempts": int32(0)},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish to queue %s: %w", queueName, err)
+	}
+	// The confirmation is nil on a channel not in confirm mode
+	if confirmation == nil {
+		return nil
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to confirm the message published to queue %s: %w", queueName, err)
+	}
+	if !acked {
+		return fmt.Errorf("%w when publishing to queue %s", ErrPublishNacked, queueName)
+	}
+
+	// The broker returns an unroutable message before confirming it, so its
+	// return is already buffered. Returns of earlier messages given up on
+	// are dropped along the way.
+	for {
+		select {
+		case ret := <-p.returns:
+			if ret.MessageId == messageId {
+				return fmt.Errorf(
+					"message returned by the broker when publishing to queue %s: %d %s",
+					queueName, ret.ReplyCode, ret.ReplyText,
+				)
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// withChannel runs f on a dedicated channel, closed afterwards
+func (p *rabbitMQPublisher) withChannel(f func(channel *amqp.Channel) error) error {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+
+	if conn == nil || conn.IsClosed() {
+		return errors.New("not connected to RabbitMQ")
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open RabbitMQ channel: %w", err)
+	}
+	defer channel.Close()
+
+	return f(channel)
+}
+
+func (p *rabbitMQPublisher) stop() error {
+	close(p.stopCh)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.channel = nil
+	if p.conn == nil || p.conn.IsClosed() {
+		return nil
+	}
+	return p.conn.Close()
+}
+
+// declareQueueTopology declares the queue and its delay queue with the
+// arguments of the staking queue client, together with the dead-letter queue.
+// The messages rejected by the consumers are dead-lettered through the common
+// DLX, which routes them to the dead-letter queue instead of the delay queue,
+// as the delayed requeues are published to the latter directly.
+func declareQueueTopology(channel *amqp.Channel, cfg *config.QueueConfig, queueName string) error {
+	err := channel.ExchangeDeclare(
+		commonDlxName, "direct", true, false, false, false,
+		amqp.Table{"x-queue-type": cfg.QueueType},
+	)
+	if err != nil {
+		return err
+	}
+
+	delayQueueName := queueName + delayedQueuePostfix
+	_, err = channel.QueueDeclare(
+		delayQueueName, true, false, false, false,
+		amqp.Table{
+			"x-queue-type":              cfg.QueueType,
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queueName,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	dlxRoutingKey := queueName + dlxRoutingPostfix
+	_, err = channel.QueueDeclare(
+		queueName, true, false, false, false,
+		amqp.Table{
+			"x-queue-type":              cfg.QueueType,
+			"x-dead-letter-exchange":    commonDlxName,
+			"x-dead-letter-routing-key": dlxRoutingKey,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	deadLetterQueueName := queueName + DeadLetterQueuePostfix
+	_, err = channel.QueueDeclare(
+		deadLetterQueueName, true, false, false, false,
+		amqp.Table{"x-queue-type": cfg.QueueType},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := channel.QueueBind(deadLetterQueueName, dlxRoutingKey, commonDlxName, false, nil); err != nil {
+		return err
+	}
+
+	return channel.QueueUnbind(delayQueueName, dlxRoutingKey, commonDlxName, nil)
+}
+
+// declareExchange declares the direct exchange the messages are published to,
+// bound to each queue under its name
+func declareExchange(channel *amqp.Channel, publishCfg *indexerconfig.RabbitMQConfig, queueNames []string) error {
+	err := channel.ExchangeDeclare(
+		publishCfg.Exchange, "direct", !publishCfg.Transient, false, false, false, nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, queueName := range queueNames {
+		if err := channel.QueueBind(queueName, queueName, publishCfg.Exchange, false, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func amqpURI(cfg *config.QueueConfig) string {
+	return fmt.Sprintf("amqp://%s:%s@%s", cfg.QueueUser, cfg.QueuePassword, cfg.Url)
+}
@@ -0,0 +1,121 @@
+package consumer
+
+import (
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+)
+
+const (
+	WithdrawableStakingQueueName string = "v2_withdrawable_staking_queue"
+	WithdrawnStakingQueueName    string = "v2_withdrawn_staking_queue"
+	SlashedFundsStakingQueueName string = "v2_slashed_funds_staking_queue"
+)
+
+// WebhookSchemaKey configures the schema versions of the webhook payloads,
+// the other keys of the schema versions being queue names
+const WebhookSchemaKey = "webhook"
+
+const (
+	WithdrawableStakingEventType = schema.WithdrawableStakingEventType
+	WithdrawnStakingEventType    = schema.WithdrawnStakingEventType
+	SlashedFundsStakingEventType = schema.SlashedFundsStakingEventType
+)
+
+// StakingEvent is the latest version of the active and unbonding events,
+// converted to the older versions configured when emitted
+type StakingEvent = schema.StakingEventV0
+
+func NewActiveStakingEvent(
+	stakingTxHashHex string,
+	stakerBtcPkHex string,
+	finalityProviderBtcPksHex []string,
+	stakingAmount uint64,
+	stakingStartHeight uint32,
+	paramsVersion uint32,
+) StakingEvent {
+	return schema.NewActiveStakingEventV0(
+		stakingTxHashHex, stakerBtcPkHex, finalityProviderBtcPksHex, stakingAmount, stakingStartHeight, paramsVersion,
+	)
+}
+
+func NewUnbondingStakingEvent(
+	stakingTxHashHex string,
+	stakerBtcPkHex string,
+	finalityProviderBtcPksHex []string,
+	stakingAmount uint64,
+	stakingStartHeight uint32,
+	paramsVersion uint32,
+	subState string,
+	unbondingTxHashHex string,
+	withdrawableHeight uint32,
+) StakingEvent {
+	return schema.NewUnbondingStakingEventV0(
+		stakingTxHashHex, stakerBtcPkHex, finalityProviderBtcPksHex, stakingAmount, stakingStartHeight, paramsVersion,
+		subState, unbondingTxHashHex, withdrawableHeight,
+	)
+}
+
+// WithdrawalStakingEvent is the latest version of the withdrawal events,
+// converted to the older versions configured when emitted
+type WithdrawalStakingEvent = schema.WithdrawalStakingEventV0
+
+func NewWithdrawableStakingEvent(
+	stakingTxHashHex string, subState string, btcHeight uint32,
+) WithdrawalStakingEvent {
+	return schema.NewWithdrawableStakingEventV0(stakingTxHashHex, subState, btcHeight)
+}
+
+func NewWithdrawnStakingEvent(
+	stakingTxHashHex string, subState string, btcHeight uint32, spendingTxHashHex string,
+) WithdrawalStakingEvent {
+	return schema.NewWithdrawnStakingEventV0(stakingTxHashHex, subState, btcHeight, spendingTxHashHex)
+}
+
+func NewSlashedFundsStakingEvent(
+	stakingTxHashHex string, subState string, btcHeight uint32, spendingTxHashHex string,
+) WithdrawalStakingEvent {
+	return schema.NewSlashedFundsStakingEventV0(stakingTxHashHex, subState, btcHeight, spendingTxHashHex)
+}
+
+// schemaKinds maps the keys of the schema versions to the kind of events
+// they configure
+var schemaKinds = map[string]string{
+	client.ActiveStakingQueueName:    schema.KindStaking,
+	client.UnbondingStakingQueueName: schema.KindStaking,
+	WithdrawableStakingQueueName:     schema.KindWithdrawal,
+	WithdrawnStakingQueueName:        schema.KindWithdrawal,
+	SlashedFundsStakingQueueName:     schema.KindWithdrawal,
+	WebhookSchemaKey:                 schema.KindWebhook,
+}
+
+// resolveSchemaVersions checks the configured schema versions and fills in
+// the default version for the queues not configured
+func resolveSchemaVersions(configured map[string][]int) (map[string][]int, error) {
+	for key := range configured {
+		if _, ok := schemaKinds[key]; !ok {
+			return nil, fmt.Errorf("schema versions configured for unknown queue %s", key)
+		}
+	}
+
+	versions := make(map[string][]int, len(schemaKinds))
+	for key, kind := range schemaKinds {
+		keyVersions, ok := configured[key]
+		if !ok {
+			versions[key] = []int{schema.DefaultVersion}
+			continue
+		}
+		if len(keyVersions) == 0 {
+			return nil, fmt.Errorf("no schema version configured for %s", key)
+		}
+		for _, version := range keyVersions {
+			if !schema.IsSupported(kind, version) {
+				return nil, fmt.Errorf("unsupported schema version %d for %s", version, key)
+			}
+		}
+		versions[key] = keyVersions
+	}
+
+	return versions, nil
+}
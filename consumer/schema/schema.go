@@ -0,0 +1,81 @@
+// Package schema defines the versions of the event payloads emitted by the
+// indexer. Every payload carries its version in its schema_version field.
+//
+// Schemas evolve under the following policy:
+//   - fields may be added to an existing version, consumers ignoring the
+//     fields they do not know
+//   - removing or renaming a field, or changing its type, requires a new
+//     version, emitted alongside the previous one during its deprecation
+//     window so that consumers can move over
+//
+// The golden files under testdata pin the payload of each version, and
+// updating them is refused for a change that is not an addition.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Kinds of events, each one versioned on its own
+const (
+	KindStaking    = "staking"
+	KindWithdrawal = "withdrawal"
+	KindWebhook    = "webhook"
+)
+
+// DefaultVersion is emitted when no version is configured
+const DefaultVersion = 0
+
+// supportedVersions lists the versions each kind of event can be emitted in
+var supportedVersions = map[string][]int{
+	KindStaking:    {0},
+	KindWithdrawal: {0},
+	KindWebhook:    {0},
+}
+
+// IsSupported returns whether events of the given kind can be emitted in the
+// given version
+func IsSupported(kind string, version int) bool {
+	return slices.Contains(supportedVersions[kind], version)
+}
+
+// Encode marshals the event in the given version, flagged as replayed if
+// requested. The event is in the latest version of its kind, and gets
+// converted to the older ones still supported.
+func Encode(event any, version int, replay bool) ([]byte, error) {
+	payload, err := convert(event, version, replay)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(payload)
+}
+
+func convert(event any, version int, replay bool) (any, error) {
+	switch ev := event.(type) {
+	case *StakingEventV0:
+		if version == 0 {
+			payload := *ev
+			payload.Replay = replay
+			return &payload, nil
+		}
+	case *WithdrawalStakingEventV0:
+		if version == 0 {
+			payload := *ev
+			payload.Replay = replay
+			return &payload, nil
+		}
+	case *WebhookEventV0:
+		if version == 0 {
+			payload := *ev
+			payload.Replay = replay
+			return &payload, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown event %T", event)
+	}
+
+	return nil, fmt.Errorf("unsupported schema version %d of event %T", version, event)
+}
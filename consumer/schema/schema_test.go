@@ -0,0 +1,210 @@
+package schema
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden files, which is refused for a change breaking
+// the compatibility policy
+var update = flag.Bool("update-golden", false, "update the golden files")
+
+// goldenEvents are the events pinned by the golden files, each one encoded in
+// the given version
+var goldenEvents = []struct {
+	name    string
+	kind    string
+	event   any
+	version int
+	replay  bool
+}{
+	{
+		name:    "active_staking_v0",
+		kind:    KindStaking,
+		event:   stakingEvent(NewActiveStakingEventV0("staking-tx", "staker-pk", []string{"fp-pk"}, 1000, 90, 1)),
+		version: 0,
+	},
+	{
+		name: "unbonding_staking_v0",
+		kind: KindStaking,
+		event: stakingEvent(NewUnbondingStakingEventV0(
+			"staking-tx", "staker-pk", []string{"fp-pk"}, 1000, 90, 1, "EARLY_UNBONDING", "unbonding-tx", 100,
+		)),
+		version: 0,
+	},
+	{
+		name:    "withdrawable_staking_v0",
+		kind:    KindWithdrawal,
+		event:   withdrawalEvent(NewWithdrawableStakingEventV0("staking-tx", "TIMELOCK", 100)),
+		version: 0,
+	},
+	{
+		name:    "withdrawn_staking_v0",
+		kind:    KindWithdrawal,
+		event:   withdrawalEvent(NewWithdrawnStakingEventV0("staking-tx", "TIMELOCK", 110, "spending-tx")),
+		version: 0,
+	},
+	{
+		name:    "slashed_funds_staking_v0",
+		kind:    KindWithdrawal,
+		event:   withdrawalEvent(NewSlashedFundsStakingEventV0("staking-tx", "SLASHING_CONFIRMED", 120, "slashing-tx")),
+		version: 0,
+	},
+	{
+		name:    "active_staking_v0_replay",
+		kind:    KindStaking,
+		event:   stakingEvent(NewActiveStakingEventV0("staking-tx", "staker-pk", []string{"fp-pk"}, 1000, 90, 1)),
+		version: 0,
+		replay:  true,
+	},
+	{
+		name:    "withdrawn_staking_v0_replay",
+		kind:    KindWithdrawal,
+		event:   withdrawalEvent(NewWithdrawnStakingEventV0("staking-tx", "TIMELOCK", 110, "spending-tx")),
+		version: 0,
+		replay:  true,
+	},
+	{
+		name: "webhook_v0",
+		kind: KindWebhook,
+		event: webhookEvent(NewWebhookEventV0(&model.OutboxEvent{
+			Id:                        "withdrawn:staking-tx",
+			EventType:                 model.OutboxEventTypeWithdrawn,
+			StakingTxHashHex:          "staking-tx",
+			IdempotencyKey:            testDelivery.IdempotencyKey,
+			Sequence:                  testDelivery.Sequence,
+			StakerBtcPkHex:            "staker-pk",
+			FinalityProviderBtcPksHex: []string{"fp-pk"},
+			StakingAmount:             1000,
+			StakingStartHeight:        90,
+			ParamsVersion:             1,
+			SubState:                  "TIMELOCK",
+			UnbondingTxHashHex:        "unbonding-tx",
+			WithdrawableHeight:        100,
+			BtcHeight:                 110,
+			SpendingTxHashHex:         "spending-tx",
+			CreatedAt:                 1700000000000000000,
+		})),
+		version: 0,
+	},
+}
+
+var testDelivery = Delivery{IdempotencyKey: "idempotency-key", Sequence: 4}
+
+func stakingEvent(event StakingEventV0) *StakingEventV0 {
+	event.Delivery = testDelivery
+	return &event
+}
+
+func withdrawalEvent(event WithdrawalStakingEventV0) *WithdrawalStakingEventV0 {
+	event.Delivery = testDelivery
+	return &event
+}
+
+func webhookEvent(event WebhookEventV0) *WebhookEventV0 {
+	return &event
+}
+
+func TestGoldenPayloads(t *testing.T) {
+	for _, golden := range goldenEvents {
+		t.Run(golden.name, func(t *testing.T) {
+			payload, err := Encode(golden.event, golden.version, golden.replay)
+			require.NoError(t, err)
+
+			path := filepath.Join("testdata", golden.name+".json")
+			expected, err := os.ReadFile(path)
+			if *update {
+				if err == nil {
+					require.NoError(t, checkCompatible(expected, payload))
+				}
+				require.NoError(t, os.WriteFile(path, append(payload, '\n'), 0o644))
+				return
+			}
+			require.NoError(t, err)
+
+			// A breaking change needs a new version, an addition only to
+			// update the golden file
+			require.NoError(t, checkCompatible(expected, payload))
+			require.JSONEq(t, string(expected), string(payload),
+				"payload changed, run the tests with -update-golden to accept the added fields")
+		})
+	}
+}
+
+func TestGoldenPayloadsCoverSupportedVersions(t *testing.T) {
+	pinned := make(map[string]bool)
+	for _, golden := range goldenEvents {
+		pinned[fmt.Sprintf("%s:%d", golden.kind, golden.version)] = true
+	}
+
+	for kind, versions := range supportedVersions {
+		for _, version := range versions {
+			require.True(t, pinned[fmt.Sprintf("%s:%d", kind, version)],
+				"no golden payload for version %d of %s events", version, kind)
+		}
+	}
+}
+
+func TestEncodeSetsSchemaVersion(t *testing.T) {
+	for _, golden := range goldenEvents {
+		payload, err := Encode(golden.event, golden.version, golden.replay)
+		require.NoError(t, err)
+
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(payload, &fields))
+		require.Equal(t, float64(golden.version), fields["schema_version"], golden.name)
+	}
+}
+
+func TestEncodeRejectsUnsupportedVersion(t *testing.T) {
+	event := NewWithdrawableStakingEventV0("staking-tx", "TIMELOCK", 100)
+	_, err := Encode(&event, 1, false)
+	require.Error(t, err)
+
+	_, err = Encode(struct{}{}, 0, false)
+	require.Error(t, err)
+
+	require.True(t, IsSupported(KindWithdrawal, 0))
+	require.False(t, IsSupported(KindWithdrawal, 1))
+	require.False(t, IsSupported("unknown", 0))
+}
+
+func TestCheckCompatible(t *testing.T) {
+	previous := []byte(`{"a": 1, "b": "x", "c": ["y"]}`)
+
+	require.NoError(t, checkCompatible(previous, []byte(`{"a": 2, "b": "z", "c": [], "d": true}`)))
+	require.Error(t, checkCompatible(previous, []byte(`{"a": 1, "c": ["y"]}`)))
+	require.Error(t, checkCompatible(previous, []byte(`{"a": "1", "b": "x", "c": ["y"]}`)))
+}
+
+// checkCompatible returns an error if the payload removes or changes the type
+// of a field of the previous one, which the compatibility policy only allows
+// in a new version
+func checkCompatible(previous, payload []byte) error {
+	var previousFields, fields map[string]any
+	if err := json.Unmarshal(previous, &previousFields); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return err
+	}
+
+	for name, previousValue := range previousFields {
+		value, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("field %s removed or renamed, which requires a new schema version", name)
+		}
+		if fmt.Sprintf("%T", value) != fmt.Sprintf("%T", previousValue) {
+			return fmt.Errorf("type of field %s changed, which requires a new schema version", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+)
+
+// Event types following the ones of the staking queue client
+const (
+	WithdrawableStakingEventType client.EventType = 3
+	WithdrawnStakingEventType    client.EventType = 4
+	SlashedFundsStakingEventType client.EventType = 5
+)
+
+// Delivery identifies the deliveries of an event, which the consumers
+// deduplicate following the contract of the dedup package
+type Delivery struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	// Sequence increases by one with each event of the delegation
+	Sequence uint64 `json:"sequence"`
+	// Replay flags an event republished on request, already emitted before
+	Replay bool `json:"replay,omitempty"`
+}
+
+// StakingEventV0 is the active and unbonding event, extending the one of the
+// staking queue client
+type StakingEventV0 struct {
+	client.StakingEvent
+	// StakingStartHeight is the BTC height from which the delegation is active
+	StakingStartHeight uint32 `json:"staking_start_height"`
+	// ParamsVersion is the version of the staking params of the delegation
+	ParamsVersion uint32 `json:"params_version"`
+	// SubState tells early unbonding from the natural expiry of an unbonding
+	// event, unset for a slashed delegation
+	SubState string `json:"sub_state,omitempty"`
+	// UnbondingTxHashHex is the tx unbonding the delegation early
+	UnbondingTxHashHex string `json:"unbonding_tx_hash_hex,omitempty"`
+	// WithdrawableHeight is the BTC height at which the unbonding delegation
+	// is expected to become withdrawable
+	WithdrawableHeight uint32 `json:"withdrawable_height,omitempty"`
+	Delivery
+}
+
+func NewActiveStakingEventV0(
+	stakingTxHashHex string,
+	stakerBtcPkHex string,
+	finalityProviderBtcPksHex []string,
+	stakingAmount uint64,
+	stakingStartHeight uint32,
+	paramsVersion uint32,
+) StakingEventV0 {
+	return StakingEventV0{
+		StakingEvent: client.NewActiveStakingEvent(
+			stakingTxHashHex, stakerBtcPkHex, finalityProviderBtcPksHex, stakingAmount,
+		),
+		StakingStartHeight: stakingStartHeight,
+		ParamsVersion:      paramsVersion,
+	}
+}
+
+func NewUnbondingStakingEventV0(
+	stakingTxHashHex string,
+	stakerBtcPkHex string,
+	finalityProviderBtcPksHex []string,
+	stakingAmount uint64,
+	stakingStartHeight uint32,
+	paramsVersion uint32,
+	subState string,
+	unbondingTxHashHex string,
+	withdrawableHeight uint32,
+) StakingEventV0 {
+	return StakingEventV0{
+		StakingEvent: client.NewUnbondingStakingEvent(
+			stakingTxHashHex, stakerBtcPkHex, finalityProviderBtcPksHex, stakingAmount,
+		),
+		StakingStartHeight: stakingStartHeight,
+		ParamsVersion:      paramsVersion,
+		SubState:           subState,
+		UnbondingTxHashHex: unbondingTxHashHex,
+		WithdrawableHeight: withdrawableHeight,
+	}
+}
+
+// WithdrawalStakingEventV0 is emitted when a delegation becomes withdrawable
+// and once it is withdrawn, and along the lifecycle of the slashed funds of a
+// slashed delegation
+type WithdrawalStakingEventV0 struct {
+	SchemaVersion    int              `json:"schema_version"`
+	EventType        client.EventType `json:"event_type"`
+	StakingTxHashHex string           `json:"staking_tx_hash_hex"`
+	// SubState tells the timelock path from the early unbonding one, and
+	// whether the delegation got slashed. For a slashed funds event it tells
+	// whether the slashing tx got confirmed or its slashed output swept.
+	SubState string `json:"sub_state"`
+	// BtcHeight is the height at which the timelock expired for a withdrawable
+	// event and the height of the spending tx for a withdrawn or slashed funds
+	// event
+	BtcHeight uint32 `json:"btc_height"`
+	// SpendingTxHashHex is the hash of the tx withdrawing the delegation for a
+	// withdrawn event, of the slashing tx or of the tx sweeping its slashed
+	// output for a slashed funds event
+	SpendingTxHashHex string `json:"spending_tx_hash_hex,omitempty"`
+	Delivery
+}
+
+func (e WithdrawalStakingEventV0) GetEventType() client.EventType {
+	return e.EventType
+}
+
+func (e WithdrawalStakingEventV0) GetStakingTxHashHex() string {
+	return e.StakingTxHashHex
+}
+
+func NewWithdrawableStakingEventV0(
+	stakingTxHashHex string, subState string, btcHeight uint32,
+) WithdrawalStakingEventV0 {
+	return WithdrawalStakingEventV0{
+		SchemaVersion:    0,
+		EventType:        WithdrawableStakingEventType,
+		StakingTxHashHex: stakingTxHashHex,
+		SubState:         subState,
+		BtcHeight:        btcHeight,
+	}
+}
+
+func NewWithdrawnStakingEventV0(
+	stakingTxHashHex string, subState string, btcHeight uint32, spendingTxHashHex string,
+) WithdrawalStakingEventV0 {
+	return WithdrawalStakingEventV0{
+		SchemaVersion:     0,
+		EventType:         WithdrawnStakingEventType,
+		StakingTxHashHex:  stakingTxHashHex,
+		SubState:          subState,
+		BtcHeight:         btcHeight,
+		SpendingTxHashHex: spendingTxHashHex,
+	}
+}
+
+func NewSlashedFundsStakingEventV0(
+	stakingTxHashHex string, subState string, btcHeight uint32, spendingTxHashHex string,
+) WithdrawalStakingEventV0 {
+	return WithdrawalStakingEventV0{
+		SchemaVersion:     0,
+		EventType:         SlashedFundsStakingEventType,
+		StakingTxHashHex:  stakingTxHashHex,
+		SubState:          subState,
+		BtcHeight:         btcHeight,
+		SpendingTxHashHex: spendingTxHashHex,
+	}
+}
+
+// WebhookEventV0 is the payload POSTed to the webhook endpoints
+type WebhookEventV0 struct {
+	SchemaVersion             int      `json:"schema_version"`
+	Id                        string   `json:"id"`
+	EventType                 string   `json:"event_type"`
+	StakingTxHashHex          string   `json:"staking_tx_hash_hex"`
+	StakerBtcPkHex            string   `json:"staker_btc_pk_hex,omitempty"`
+	FinalityProviderBtcPksHex []string `json:"finality_provider_btc_pks_hex,omitempty"`
+	StakingAmount             uint64   `json:"staking_amount,omitempty"`
+	StakingStartHeight        uint32   `json:"staking_start_height,omitempty"`
+	ParamsVersion             uint32   `json:"params_version"`
+	SubState                  string   `json:"sub_state,omitempty"`
+	UnbondingTxHashHex        string   `json:"unbonding_tx_hash_hex,omitempty"`
+	WithdrawableHeight        uint32   `json:"withdrawable_height,omitempty"`
+	BtcHeight                 uint32   `json:"btc_height,omitempty"`
+	SpendingTxHashHex         string   `json:"spending_tx_hash_hex,omitempty"`
+	CreatedAt                 int64    `json:"created_at"` // epoch time in nanoseconds
+	Delivery
+}
+
+func NewWebhookEventV0(event *model.OutboxEvent) WebhookEventV0 {
+	return WebhookEventV0{
+		SchemaVersion:             0,
+		Id:                        event.Id,
+		EventType:                 event.EventType,
+		StakingTxHashHex:          event.StakingTxHashHex,
+		StakerBtcPkHex:            event.StakerBtcPkHex,
+		FinalityProviderBtcPksHex: event.FinalityProviderBtcPksHex,
+		StakingAmount:             event.StakingAmount,
+		StakingStartHeight:        event.StakingStartHeight,
+		ParamsVersion:             event.ParamsVersion,
+		SubState:                  event.SubState,
+		UnbondingTxHashHex:        event.UnbondingTxHashHex,
+		WithdrawableHeight:        event.WithdrawableHeight,
+		BtcHeight:                 event.BtcHeight,
+		SpendingTxHashHex:         event.SpendingTxHashHex,
+		CreatedAt:                 event.CreatedAt,
+		Delivery: Delivery{
+			IdempotencyKey: event.IdempotencyKey,
+			Sequence:       event.Sequence,
+		},
+	}
+}
@@ -0,0 +1,231 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the webhook payload,
+// keyed by the secret of the endpoint
+const SignatureHeader = "X-Signature"
+
+// ErrOutboxEventsOnly is returned by the emitters only publishing the outbox
+// events as recorded
+var ErrOutboxEventsOnly = errors.New("emitter only publishes outbox events")
+
+// OutboxEventEmitter is implemented by the emitters publishing the outbox
+// events as recorded rather than through the queue event schemas
+type OutboxEventEmitter interface {
+	PushOutboxEvent(event *model.OutboxEvent) error
+}
+
+// WebhookEvent is the latest version of the webhook payloads, converted to
+// the older versions configured when delivered
+type WebhookEvent = schema.WebhookEventV0
+
+func NewWebhookEvent(event *model.OutboxEvent) WebhookEvent {
+	return schema.NewWebhookEventV0(event)
+}
+
+type webhookEndpoint struct {
+	url    string
+	secret []byte
+	// failureStreak is the number of consecutive failed deliveries
+	failureStreak int
+	disabled      bool
+}
+
+// WebhookEmitter POSTs the outbox events to the configured endpoints
+type WebhookEmitter struct {
+	cfg        *config.WebhookConfig
+	httpClient *http.Client
+	endpoints  []*webhookEndpoint
+	// schemaVersions are the versions each event is delivered in
+	schemaVersions []int
+	// replay flags the delivered events as replayed
+	replay bool
+	// onEndpointDisabled is called once an endpoint gets disabled
+	onEndpointDisabled func(url string)
+}
+
+func NewWebhookEmitter(
+	cfg *config.WebhookConfig, schemaVersions map[string][]int, onEndpointDisabled func(url string),
+) (*WebhookEmitter, error) {
+	versions, err := resolveSchemaVersions(schemaVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]*webhookEndpoint, 0, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		endpoints = append(endpoints, &webhookEndpoint{
+			url:    endpoint.URL,
+			secret: []byte(endpoint.Secret),
+		})
+	}
+
+	return &WebhookEmitter{
+		cfg:                cfg,
+		httpClient:         &http.Client{Timeout: cfg.Timeout},
+		endpoints:          endpoints,
+		schemaVersions:     versions[WebhookSchemaKey],
+		onEndpointDisabled: onEndpointDisabled,
+	}, nil
+}
+
+func (e *WebhookEmitter) Start() error {
+	return nil
+}
+
+func (e *WebhookEmitter) Replaying() EventConsumer {
+	replaying := *e
+	replaying.replay = true
+	return &replaying
+}
+
+func (e *WebhookEmitter) Stop() error {
+	e.httpClient.CloseIdleConnections()
+	return nil
+}
+
+func (e *WebhookEmitter) PushActiveStakingEvent(ev *StakingEvent) error {
+	return ErrOutboxEventsOnly
+}
+
+func (e *WebhookEmitter) PushUnbondingStakingEvent(ev *StakingEvent) error {
+	return ErrOutboxEventsOnly
+}
+
+func (e *WebhookEmitter) PushWithdrawableStakingEvent(ev *WithdrawalStakingEvent) error {
+	return ErrOutboxEventsOnly
+}
+
+func (e *WebhookEmitter) PushWithdrawnStakingEvent(ev *WithdrawalStakingEvent) error {
+	return ErrOutboxEventsOnly
+}
+
+func (e *WebhookEmitter) PushSlashedFundsStakingEvent(ev *WithdrawalStakingEvent) error {
+	return ErrOutboxEventsOnly
+}
+
+// PushOutboxEvent delivers the event to every enabled endpoint, once per
+// configured schema version. It fails if any of them did not accept it, in
+// which case the event is pushed again to all of them. An endpoint failing
+// MaxFailureStreak deliveries in a row is disabled so that it no longer holds
+// the other ones back.
+func (e *WebhookEmitter) PushOutboxEvent(event *model.OutboxEvent) error {
+	webhookEvent := NewWebhookEvent(event)
+	bodies := make([][]byte, 0, len(e.schemaVersions))
+	for _, version := range e.schemaVersions {
+		body, err := schema.Encode(&webhookEvent, version, e.replay)
+		if err != nil {
+			return err
+		}
+		bodies = append(bodies, body)
+	}
+
+	var errs []error
+	delivered := 0
+	for _, endpoint := range e.endpoints {
+		if endpoint.disabled {
+			continue
+		}
+
+		if err := e.deliverAll(endpoint, bodies); err != nil {
+			endpoint.failureStreak++
+			if endpoint.failureStreak >= e.cfg.MaxFailureStreak {
+				e.disable(endpoint, err)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to deliver event %s to %s: %w", event.Id, endpoint.url, err))
+			continue
+		}
+		endpoint.failureStreak = 0
+		delivered++
+	}
+
+	if delivered == 0 && len(errs) == 0 {
+		return fmt.Errorf("failed to deliver event %s: all the webhook endpoints are disabled", event.Id)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *WebhookEmitter) deliverAll(endpoint *webhookEndpoint, bodies [][]byte) error {
+	for _, body := range bodies {
+		if err := e.deliver(endpoint, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliver POSTs the payload to the endpoint, retrying with an exponential
+// backoff until it answers with a 2xx status
+func (e *WebhookEmitter) deliver(endpoint *webhookEndpoint, body []byte) error {
+	return retry.Do(
+		func() error {
+			return e.post(endpoint, body)
+		},
+		retry.Attempts(e.cfg.MaxRetries+1),
+		retry.Delay(e.cfg.RetryBackoff),
+		retry.DelayType(retry.BackOffDelay),
+		retry.LastErrorOnly(true),
+	)
+}
+
+func (e *WebhookEmitter) post(endpoint *webhookEndpoint, body []byte) error {
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodPost, endpoint.url, bytes.NewReader(body),
+	)
+	if err != nil {
+		return retry.Unrecoverable(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signWebhookPayload(endpoint.secret, body))
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (e *WebhookEmitter) disable(endpoint *webhookEndpoint, err error) {
+	endpoint.disabled = true
+	logging.Emitter.Logger().Error().Err(err).
+		Str("url", endpoint.url).
+		Int("failureStreak", endpoint.failureStreak).
+		Msg("webhook endpoint disabled after too many failed deliveries")
+
+	if e.onEndpointDisabled != nil {
+		e.onEndpointDisabled(endpoint.url)
+	}
+}
+
+func signWebhookPayload(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,136 @@
+package consumer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver answers the configured number of requests with an error
+// status, then records the delivered payloads
+type webhookReceiver struct {
+	mu       sync.Mutex
+	failures int
+	bodies   [][]byte
+	// signatures are the signature headers of the delivered payloads
+	signatures []string
+}
+
+func newWebhookReceiver(t *testing.T) (*webhookReceiver, *httptest.Server) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+
+		if receiver.failures > 0 {
+			receiver.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		receiver.bodies = append(receiver.bodies, body)
+		receiver.signatures = append(receiver.signatures, r.Header.Get(SignatureHeader))
+	}))
+	t.Cleanup(server.Close)
+
+	return receiver, server
+}
+
+func newTestWebhookConfig(urls ...string) *config.WebhookConfig {
+	cfg := &config.WebhookConfig{
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     time.Millisecond,
+		MaxFailureStreak: 2,
+	}
+	for _, url := range urls {
+		cfg.Endpoints = append(cfg.Endpoints, config.WebhookEndpointConfig{URL: url, Secret: "secret"})
+	}
+	return cfg
+}
+
+func testWebhookOutboxEvent(sequence uint64) *model.OutboxEvent {
+	return &model.OutboxEvent{
+		Id:               model.OutboxEventTypeWithdrawable + ":tx-a",
+		EventType:        model.OutboxEventTypeWithdrawable,
+		StakingTxHashHex: "tx-a",
+		Sequence:         sequence,
+		SubState:         "TIMELOCK",
+		BtcHeight:        100,
+	}
+}
+
+func TestWebhookEmitterDeliversSignedEvent(t *testing.T) {
+	receiver, server := newWebhookReceiver(t)
+	emitter, err := NewWebhookEmitter(newTestWebhookConfig(server.URL), nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(3)))
+
+	require.Len(t, receiver.bodies, 1)
+	require.Equal(t, signWebhookPayload([]byte("secret"), receiver.bodies[0]), receiver.signatures[0])
+
+	var event WebhookEvent
+	require.NoError(t, json.Unmarshal(receiver.bodies[0], &event))
+	require.Equal(t, "tx-a", event.StakingTxHashHex)
+	require.Equal(t, uint64(3), event.Sequence)
+	require.Equal(t, model.OutboxEventTypeWithdrawable, event.EventType)
+	require.Equal(t, uint32(100), event.BtcHeight)
+	require.Equal(t, 0, event.SchemaVersion)
+}
+
+func TestWebhookEmitterRetriesFailedDelivery(t *testing.T) {
+	receiver, server := newWebhookReceiver(t)
+	receiver.failures = 2
+	emitter, err := NewWebhookEmitter(newTestWebhookConfig(server.URL), nil, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(1)))
+	require.Len(t, receiver.bodies, 1)
+}
+
+func TestWebhookEmitterDisablesFailingEndpoint(t *testing.T) {
+	failing, failingServer := newWebhookReceiver(t)
+	failing.failures = 100
+	healthy, healthyServer := newWebhookReceiver(t)
+
+	var disabled []string
+	emitter, err := NewWebhookEmitter(
+		newTestWebhookConfig(failingServer.URL, healthyServer.URL),
+		nil,
+		func(url string) { disabled = append(disabled, url) },
+	)
+	require.NoError(t, err)
+
+	// The failing endpoint holds the event back until it gets disabled
+	require.Error(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(1)))
+	require.Empty(t, disabled)
+	require.NoError(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(1)))
+	require.Equal(t, []string{failingServer.URL}, disabled)
+
+	require.NoError(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(2)))
+	require.Len(t, healthy.bodies, 3)
+	require.Empty(t, failing.bodies)
+}
+
+func TestWebhookEmitterFailsWithAllEndpointsDisabled(t *testing.T) {
+	failing, server := newWebhookReceiver(t)
+	failing.failures = 100
+	cfg := newTestWebhookConfig(server.URL)
+	cfg.MaxFailureStreak = 1
+	emitter, err := NewWebhookEmitter(cfg, nil, nil)
+	require.NoError(t, err)
+
+	// The last enabled endpoint getting disabled leaves the event unsent
+	require.Error(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(1)))
+	require.Error(t, emitter.PushOutboxEvent(testWebhookOutboxEvent(1)))
+}
@@ -0,0 +1,13 @@
+// Package e2e runs the whole indexer, its pollers, BBN block processor,
+// expiry checker and outbox relay, against the fakes of its dependencies: the
+// scripted BbnClient and the BTC chain of the fixtures, and an in-memory
+// emitter. The scenarios append the BBN blocks and mine the BTC blocks of a
+// delegation lifecycle, asserting the stored state, the emitted events and
+// the metrics at each step:
+//
+//	go test ./e2e/...
+//
+// They run against the in-memory database, or against the Mongo of
+// E2E_MONGO_ADDRESS, authenticated as E2E_MONGO_USERNAME and
+// E2E_MONGO_PASSWORD, if set.
+package e2e
@@ -0,0 +1,147 @@
+package e2e
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+const (
+	// e2ePollingInterval is the interval of the pollers the scenarios wait
+	// on: the params, the expiry checker and the outbox relay
+	e2ePollingInterval = 50 * time.Millisecond
+	// e2eTimeout is the time given to the indexer to reach a step
+	e2eTimeout = 10 * time.Second
+	// e2eBtcTipHeight is the tip of the BTC chain when the indexer starts
+	e2eBtcTipHeight = 100
+)
+
+// e2eEnv is an indexer running against the fakes of its dependencies
+type e2eEnv struct {
+	db      db.DbInterface
+	btc     *fixtures.BtcChain
+	bbn     *fixtures.BbnClient
+	emitter *consumer.MemoryEmitter
+}
+
+// newE2EEnv starts the indexer with the local config, its polling intervals
+// shortened, serving the staking params of the fixture delegations, and stops
+// it once the test is done. It returns once the indexer listens to the new
+// BBN blocks.
+func newE2EEnv(t *testing.T) *e2eEnv {
+	metrics.Init()
+	previousLevel := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	t.Cleanup(func() { zerolog.SetGlobalLevel(previousLevel) })
+
+	cfg, err := config.New("../config/config-local.yml")
+	require.NoError(t, err)
+	cfg.Poller.ParamPollingInterval = e2ePollingInterval
+	cfg.Poller.ExpiryCheckerPollingInterval = e2ePollingInterval
+	cfg.Poller.OutboxRelayInterval = e2ePollingInterval
+	cfg.Poller.OutboxRelayRetryBackoff = e2ePollingInterval
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dbClient, err := db.NewIndexerDatabase(ctx, newE2EDatabase(t, cfg), &cfg.Db, false)
+	require.NoError(t, err)
+
+	env := &e2eEnv{
+		db:      dbClient,
+		btc:     fixtures.NewBtcChain(1, e2eBtcTipHeight),
+		bbn:     fixtures.NewBbnClient(),
+		emitter: consumer.NewMemoryEmitter(),
+	}
+	env.bbn.StakingParams[fixtures.StakingParamsVersion] = fixtures.NewStakingParams()
+
+	service := services.New(cfg, services.Dependencies{
+		Db:          env.db,
+		Btc:         env.btc,
+		BtcNotifier: env.btc,
+		Bbn:         env.bbn,
+		Emitter:     env.emitter,
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		service.StartIndexerSync(ctx, nil)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	require.Eventually(t, func() bool {
+		_, err := env.db.GetStakingParams(ctx, fixtures.StakingParamsVersion)
+		return err == nil && env.bbn.HasSubscribers()
+	}, e2eTimeout, 10*time.Millisecond, "the indexer did not start")
+	return env
+}
+
+// newE2EDatabase returns the database of the Mongo of E2E_MONGO_ADDRESS,
+// dropped once the test is done, or an in-memory database if unset
+func newE2EDatabase(t *testing.T, cfg *config.Config) db.DbInterface {
+	address := os.Getenv("E2E_MONGO_ADDRESS")
+	if address == "" {
+		return inmemory.New()
+	}
+
+	ctx := context.Background()
+	cfg.Db.Address = address
+	cfg.Db.Username = os.Getenv("E2E_MONGO_USERNAME")
+	cfg.Db.Password = os.Getenv("E2E_MONGO_PASSWORD")
+	cfg.Db.DbName = "indexer-e2e"
+	require.NoError(t, model.Setup(ctx, cfg))
+	database, err := db.New(ctx, cfg.Db)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(address).SetAuth(options.Credential{
+		Username: cfg.Db.Username,
+		Password: cfg.Db.Password,
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Database(cfg.Db.DbName).Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return database
+}
+
+// counterValue returns the value of the counter series, as exposed to
+// Prometheus, or 0 if not exposed yet. The series is the metric name followed
+// by its labels, e.g. `outbox_events_published_total{event_type="active_staking"}`.
+func counterValue(t *testing.T, series string) float64 {
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	for _, line := range strings.Split(string(body), "\n") {
+		value, ok := strings.CutPrefix(line, series+" ")
+		if !ok {
+			continue
+		}
+		count, err := strconv.ParseFloat(value, 64)
+		require.NoError(t, err)
+		return count
+	}
+	return 0
+}
@@ -0,0 +1,145 @@
+package e2e
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+)
+
+// TestDelegationEarlyUnbondingLifecycle drives a delegation from its creation
+// to the withdrawal of its unbonded stake, each step waiting for the indexer
+// to store the state reached, emit the event of the step and count it in the
+// metrics
+func TestDelegationEarlyUnbondingLifecycle(t *testing.T) {
+	env := newE2EEnv(t)
+	ctx := context.Background()
+	d := fixtures.NewDelegation(1, 1)
+	stakingTxHash := d.StakingTxHashHex()
+
+	processed := func(eventType string) string {
+		return `indexer_bbn_events_processed_total{event_type="` + eventType + `",outcome="success"}`
+	}
+	published := func(eventType string) string {
+		return `outbox_events_published_total{event_type="` + eventType + `"}`
+	}
+	baseline := map[string]float64{}
+	for _, series := range []string{
+		processed(string(services.EventFinalityProviderCreatedType)),
+		processed(string(services.EventBTCDelegationCreated)),
+		processed(string(services.EventCovenantQuorumReached)),
+		processed(string(services.EventBTCDelegationInclusionProofReceived)),
+		processed(string(services.EventBTCDelgationUnbondedEarly)),
+		published("active_staking"),
+		published("unbonding_staking"),
+		published("withdrawable_staking"),
+		published("withdrawn_staking"),
+		`indexer_expiry_withdrawable_total{sub_state="EARLY_UNBONDING"}`,
+	} {
+		baseline[series] = counterValue(t, series)
+	}
+	// requireCounted waits for the counter series to be incremented once
+	// since the scenario started
+	requireCounted := func(series string) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			return counterValue(t, series)-baseline[series] == 1
+		}, e2eTimeout, 10*time.Millisecond, "%s not incremented", series)
+	}
+	requireState := func(state types.DelegationState, subState types.DelegationSubState) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			delegation, err := env.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+			return err == nil && delegation.State == state && delegation.SubState == subState
+		}, e2eTimeout, 10*time.Millisecond, "the delegation did not reach %s %s", state, subState)
+	}
+	// requireEmitted waits for the event of the delegation to be emitted on
+	// the queue, the events emitted so far being the expected ones
+	requireEmitted := func(queues ...string) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			return len(env.emitter.Events()) == len(queues)
+		}, e2eTimeout, 10*time.Millisecond, "%s not emitted", queues[len(queues)-1])
+		for i, event := range env.emitter.Events() {
+			assert.Equal(t, queues[i], event.Queue)
+			assert.Equal(t, stakingTxHash, event.Event.GetStakingTxHashHex())
+			assert.False(t, event.Replay)
+		}
+	}
+
+	// The finality provider registers
+	env.bbn.AppendBlocks(fixtures.NewBlockResults(fixtures.FpCreatedEvent(1)))
+	require.Eventually(t, func() bool {
+		_, err := env.db.GetFinalityProviderByBtcPk(ctx, d.FpBtcPkHex)
+		return err == nil
+	}, e2eTimeout, 10*time.Millisecond, "the finality provider is not stored")
+	requireCounted(processed(string(services.EventFinalityProviderCreatedType)))
+
+	// The delegation is created, then verified by the covenant committee
+	env.bbn.AppendBlocks(fixtures.NewBlockResults(d.CreatedEvent()))
+	requireState(types.StatePending, "")
+	requireCounted(processed(string(services.EventBTCDelegationCreated)))
+
+	env.bbn.AppendBlocks(fixtures.NewBlockResults(d.CovenantQuorumReachedEvent()))
+	requireState(types.StateVerified, "")
+	requireCounted(processed(string(services.EventCovenantQuorumReached)))
+	assert.Empty(t, env.emitter.Events())
+
+	// The staking tx is included, activating the delegation
+	stakingBlock := env.btc.MineBlock(d.StakingTx)
+	env.bbn.AppendBlocks(fixtures.NewBlockResults(d.InclusionProofReceivedEvent(uint32(stakingBlock.Height))))
+	requireState(types.StateActive, "")
+	requireCounted(processed(string(services.EventBTCDelegationInclusionProofReceived)))
+	requireEmitted(client.ActiveStakingQueueName)
+	requireCounted(published("active_staking"))
+
+	// The unbonding tx is included, unbonding the delegation until its
+	// timelock expires
+	unbondingBlock := env.btc.MineBlock(d.UnbondingTx)
+	env.bbn.AppendBlocks(fixtures.NewBlockResults(d.UnbondedEarlyEvent(uint32(unbondingBlock.Height))))
+	requireState(types.StateUnbonding, types.SubStateEarlyUnbonding)
+	requireCounted(processed(string(services.EventBTCDelgationUnbondedEarly)))
+	requireEmitted(client.ActiveStakingQueueName, client.UnbondingStakingQueueName)
+	requireCounted(published("unbonding_staking"))
+
+	timeLocks, err := env.db.GetTimeLocks(ctx, stakingTxHash)
+	require.NoError(t, err)
+	require.Len(t, timeLocks, 1)
+	expireHeight := uint32(unbondingBlock.Height) + uint32(fixtures.UnbondingTime)
+	assert.Equal(t, expireHeight, timeLocks[0].ExpireHeight)
+	assert.Equal(t, types.SubStateEarlyUnbonding, timeLocks[0].DelegationSubState)
+
+	// The BTC tip reaches the expiry of the timelock, the unbonded stake
+	// becoming withdrawable
+	env.btc.Extend(int32(expireHeight))
+	requireState(types.StateWithdrawable, types.SubStateEarlyUnbonding)
+	requireEmitted(client.ActiveStakingQueueName, client.UnbondingStakingQueueName,
+		consumer.WithdrawableStakingQueueName)
+	requireCounted(`indexer_expiry_withdrawable_total{sub_state="EARLY_UNBONDING"}`)
+	requireCounted(published("withdrawable_staking"))
+
+	// The staker withdraws the unbonded stake
+	env.btc.MineBlock(d.UnbondingWithdrawalTx)
+	requireState(types.StateWithdrawn, types.SubStateEarlyUnbonding)
+	requireEmitted(client.ActiveStakingQueueName, client.UnbondingStakingQueueName,
+		consumer.WithdrawableStakingQueueName, consumer.WithdrawnStakingQueueName)
+	requireCounted(published("withdrawn_staking"))
+
+	transitions, err := env.db.GetDelegationStateTransitions(ctx, stakingTxHash)
+	require.NoError(t, err)
+	var states []types.DelegationState
+	for _, transition := range transitions {
+		states = append(states, transition.ToState)
+	}
+	assert.Equal(t, []types.DelegationState{
+		types.StatePending, types.StateVerified, types.StateActive, types.StateUnbonding, types.StateWithdrawable, types.StateWithdrawn,
+	}, states)
+}
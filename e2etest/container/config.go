@@ -14,6 +14,8 @@ type ImageConfig struct {
 	BitcoindVersion    string
 	BabylonRepository  string
 	BabylonVersion     string
+	RabbitMQRepository string
+	RabbitMQVersion    string
 }
 
 //nolint:deadcode
@@ -21,6 +23,8 @@ const (
 	dockerBitcoindRepository = "lncm/bitcoind"
 	dockerBitcoindVersionTag = "v27.0"
 	dockerBabylondRepository = "babylonlabs/babylond"
+	dockerRabbitMQRepository = "rabbitmq"
+	dockerRabbitMQVersionTag = "3-management"
 )
 
 // NewImageConfig returns ImageConfig needed for running e2e test.
@@ -33,5 +37,7 @@ func NewImageConfig(t *testing.T) ImageConfig {
 		BitcoindVersion:    dockerBitcoindVersionTag,
 		BabylonRepository:  dockerBabylondRepository,
 		BabylonVersion:     babylonVersion,
+		RabbitMQRepository: dockerRabbitMQRepository,
+		RabbitMQVersion:    dockerRabbitMQVersionTag,
 	}
 }
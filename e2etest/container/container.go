@@ -21,6 +21,7 @@ import (
 const (
 	bitcoindContainerName = "bitcoind"
 	babylondContainerName = "babylond"
+	rabbitMQContainerName = "rabbitmq"
 )
 
 var (
@@ -234,6 +235,46 @@ func (m *Manager) RunBabylondResource(
 	return resource, nil
 }
 
+// RunRabbitMQResource starts a RabbitMQ container with the user and password
+// of the default queue config
+func (m *Manager) RunRabbitMQResource(t *testing.T) (*dockertest.Resource, error) {
+	resource, err := m.pool.RunWithOptions(
+		&dockertest.RunOptions{
+			Name:       fmt.Sprintf("%s-%s", rabbitMQContainerName, t.Name()),
+			Repository: m.cfg.RabbitMQRepository,
+			Tag:        m.cfg.RabbitMQVersion,
+			Labels: map[string]string{
+				"e2e": "rabbitmq",
+			},
+			Env: []string{
+				"RABBITMQ_DEFAULT_USER=user",
+				"RABBITMQ_DEFAULT_PASS=password",
+			},
+			ExposedPorts: []string{
+				"5672/tcp",
+			},
+		},
+		func(config *docker.HostConfig) {
+			config.PortBindings = map[docker.Port][]docker.PortBinding{
+				"5672/tcp": {{HostIP: "", HostPort: strconv.Itoa(testutil.AllocateUniquePort(t))}},
+			}
+		},
+		noRestart,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	m.resources[rabbitMQContainerName] = resource
+
+	return resource, nil
+}
+
+// RestartResource restarts the container of the resource, keeping its state
+func (m *Manager) RestartResource(resource *dockertest.Resource) error {
+	return m.pool.Client.RestartContainer(resource.Container.ID, 10)
+}
+
 // ClearResources removes all outstanding Docker resources created by the Manager.
 func (m *Manager) ClearResources() error {
 	for _, resource := range m.resources {
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	bbndatagen "github.com/babylonlabs-io/babylon/testutil/datagen"
 	bstypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
@@ -39,13 +40,15 @@ func TestQueueConsumer(t *testing.T) {
 	n := rand.Intn(10) + 1
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	stakingEventList := make([]*queuecli.StakingEvent, 0)
+	stakingEventList := make([]*consumer.StakingEvent, 0)
 	for i := 0; i < n; i++ {
-		stakingEvent := queuecli.NewActiveStakingEvent(
+		stakingEvent := consumer.NewActiveStakingEvent(
 			hex.EncodeToString(bbndatagen.GenRandomByteArray(r, 10)),
 			hex.EncodeToString(bbndatagen.GenRandomByteArray(r, 10)),
 			[]string{hex.EncodeToString(bbndatagen.GenRandomByteArray(r, 10))},
 			1000,
+			100,
+			0,
 		)
 		err = queueConsumer.PushActiveStakingEvent(&stakingEvent)
 		require.NoError(t, err)
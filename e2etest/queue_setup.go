@@ -5,8 +5,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	indexerconfig "github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
 	"github.com/babylonlabs-io/staking-queue-client/client"
-	"github.com/babylonlabs-io/staking-queue-client/queuemngr"
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -14,7 +15,7 @@ import (
 	"github.com/babylonlabs-io/staking-queue-client/config"
 )
 
-func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*queuemngr.QueueManager, error) {
+func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*consumer.QueueManager, error) {
 	amqpURI := fmt.Sprintf("amqp://%s:%s@%s", cfg.QueueUser, cfg.QueuePassword, cfg.Url)
 	conn, err := amqp091.Dial(amqpURI)
 	if err != nil {
@@ -23,6 +24,9 @@ func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*queuemngr.Q
 	defer conn.Close()
 	err = purgeQueues(conn, []string{
 		client.ActiveStakingQueueName,
+		consumer.WithdrawableStakingQueueName,
+		consumer.WithdrawnStakingQueueName,
+		consumer.SlashedFundsStakingQueueName,
 	})
 	if err != nil {
 		return nil, err
@@ -32,7 +36,7 @@ func setupTestQueueConsumer(t *testing.T, cfg *config.QueueConfig) (*queuemngr.Q
 
 	err = cfg.Validate()
 	require.NoError(t, err)
-	queues, err := queuemngr.NewQueueManager(cfg, zap.NewNop())
+	queues, err := consumer.NewQueueManager(cfg, &indexerconfig.RabbitMQConfig{}, nil, zap.NewNop())
 	require.NoError(t, err)
 
 	return queues, nil
@@ -0,0 +1,85 @@
+package e2etest
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/e2etest/container"
+	indexerconfig "github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	"github.com/babylonlabs-io/staking-queue-client/config"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRabbitMQPublisherRecoversAfterBrokerRestart(t *testing.T) {
+	manager, err := container.NewManager(t)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, manager.ClearResources())
+	}()
+
+	resource, err := manager.RunRabbitMQResource(t)
+	require.NoError(t, err)
+
+	queueCfg := config.DefaultQueueConfig()
+	queueCfg.Url = fmt.Sprintf("localhost:%s", resource.GetPort("5672/tcp"))
+
+	// The broker takes a while to accept connections
+	var queueManager *consumer.QueueManager
+	require.Eventually(t, func() bool {
+		queueManager, err = consumer.NewQueueManager(queueCfg, &indexerconfig.RabbitMQConfig{}, nil, zap.NewNop())
+		return err == nil
+	}, time.Minute, time.Second)
+	defer queueManager.Stop() //nolint:errcheck
+
+	event := consumer.NewWithdrawableStakingEvent("staking-tx", "TIMELOCK", 100)
+	require.NoError(t, queueManager.PushWithdrawableStakingEvent(&event))
+
+	// A message no queue is bound for is returned and reported failed
+	amqpURI := fmt.Sprintf("amqp://%s:%s@%s", queueCfg.QueueUser, queueCfg.QueuePassword, queueCfg.Url)
+	conn, err := amqp091.Dial(amqpURI)
+	require.NoError(t, err)
+	ch, err := conn.Channel()
+	require.NoError(t, err)
+	_, err = ch.QueueDelete(consumer.WithdrawnStakingQueueName, false, false, false)
+	require.NoError(t, err)
+	conn.Close()
+
+	withdrawn := consumer.NewWithdrawnStakingEvent("staking-tx", "TIMELOCK", 110, "spending-tx")
+	require.Error(t, queueManager.PushWithdrawnStakingEvent(&withdrawn))
+
+	require.NoError(t, manager.RestartResource(resource))
+
+	// The publisher reconnects and declares the queues again on its own
+	recovered := consumer.NewWithdrawableStakingEvent("staking-tx", "TIMELOCK", 101)
+	require.Eventually(t, func() bool {
+		return queueManager.PushWithdrawableStakingEvent(&recovered) == nil
+	}, 2*time.Minute, time.Second)
+	require.NoError(t, queueManager.PushWithdrawnStakingEvent(&withdrawn))
+
+	receiver, err := client.NewQueueClient(queueCfg, consumer.WithdrawableStakingQueueName)
+	require.NoError(t, err)
+	defer receiver.Stop() //nolint:errcheck
+	messages, err := receiver.ReceiveMessages()
+	require.NoError(t, err)
+
+	// The persistent message published before the restart is kept
+	var heights []uint32
+	for len(heights) < 2 {
+		select {
+		case message := <-messages:
+			var received consumer.WithdrawalStakingEvent
+			require.NoError(t, json.Unmarshal([]byte(message.Body), &received))
+			heights = append(heights, received.BtcHeight)
+			require.NoError(t, receiver.DeleteMessage(message.Receipt))
+		case <-time.After(10 * time.Second):
+			t.Fatalf("received %d of the 2 withdrawable events", len(heights))
+		}
+	}
+	require.Equal(t, []uint32{100, 101}, heights)
+}
@@ -12,6 +12,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
 	"github.com/babylonlabs-io/babylon-staking-indexer/e2etest/container"
 	indexerbbnclient "github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
@@ -28,7 +29,6 @@ import (
 	btclctypes "github.com/babylonlabs-io/babylon/x/btclightclient/types"
 	queuecli "github.com/babylonlabs-io/staking-queue-client/client"
 	queuecfg "github.com/babylonlabs-io/staking-queue-client/config"
-	"github.com/babylonlabs-io/staking-queue-client/queuemngr"
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/chaincfg"
@@ -57,7 +57,7 @@ type TestManager struct {
 	Config                    *config.Config
 	manager                   *container.Manager
 	DbClient                  *db.Database
-	QueueConsumer             *queuemngr.QueueManager
+	QueueConsumer             *consumer.QueueManager
 	ActiveStakingEventChan    <-chan queuecli.QueueMessage
 	UnbondingStakingEventChan <-chan queuecli.QueueMessage
 }
@@ -139,7 +139,9 @@ func StartManager(t *testing.T, numMatureOutputsInWallet uint32, epochInterval u
 	dbClient, err := db.New(ctx, cfg.Db)
 	require.NoError(t, err)
 
-	queueConsumer, err := queuemngr.NewQueueManager(&cfg.Queue, zap.NewNop())
+	queueConsumer, err := consumer.NewQueueManager(
+		&cfg.Queue, &cfg.Emitter.RabbitMQ, cfg.Emitter.SchemaVersions, zap.NewNop(),
+	)
 	require.NoError(t, err)
 
 	btcNotifier, err := btcclient.NewBTCNotifier(
@@ -154,9 +156,11 @@ func StartManager(t *testing.T, numMatureOutputsInWallet uint32, epochInterval u
 	service := services.NewService(cfg, dbClient, btcClient, btcNotifier, bbnClient, queueConsumer)
 	require.NoError(t, err)
 
-	// initialize metrics with the metrics port from config
-	metricsPort := cfg.Metrics.GetMetricsPort()
-	metrics.Init(metricsPort)
+	// initialize metrics and serve them if configured
+	metrics.Init()
+	if cfg.Metrics.Enabled {
+		metrics.StartServer(cfg.Metrics.GetMetricsAddress())
+	}
 
 	activeStakingEventChan, err := queueConsumer.ActiveStakingQueue.ReceiveMessages()
 	require.NoError(t, err)
@@ -168,7 +172,7 @@ func StartManager(t *testing.T, numMatureOutputsInWallet uint32, epochInterval u
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		service.StartIndexerSync(ctx)
+		service.StartIndexerSync(ctx, nil)
 	}()
 	// Wait for the server to start
 	time.Sleep(3 * time.Second)
@@ -242,6 +246,7 @@ func TestConfig(t *testing.T) *config.Config {
 			MaxRetryTimes:        5,
 			RetryInterval:        500 * time.Millisecond,
 			NetParams:            "regtest",
+			MaxReorgDepth:        10,
 		},
 		Db: config.DbConfig{
 			Address:  "mongodb://localhost:27019/?replicaSet=RS&directConnection=true",
@@ -256,15 +261,41 @@ func TestConfig(t *testing.T) *config.Config {
 			RetryInterval: 1 * time.Second,
 		},
 		Poller: config.PollerConfig{
-			ParamPollingInterval:         1 * time.Second,
-			ExpiryCheckerPollingInterval: 1 * time.Second,
-			ExpiredDelegationsLimit:      1000,
+			ParamPollingInterval:           1 * time.Second,
+			ExpiryCheckerPollingInterval:   1 * time.Second,
+			ExpiredDelegationsLimit:        1000,
+			FpActiveSetPollingInterval:     1 * time.Second,
+			BtcReorgCheckerPollingInterval: 1 * time.Second,
+			FpVotingPowerChangeThreshold:   0.05,
+			ProcessedHeightAuditInterval:   24 * time.Hour,
+			TimeLockCleanupInterval:        7 * 24 * time.Hour,
+			StuckDelegationCheckerInterval: 1 * time.Hour,
+			OutboxRelayInterval:            1 * time.Second,
+			StatsUpdateInterval:            30 * time.Second,
+			BbnLagPollingInterval:          1 * time.Second,
+			DelegationMetricsInterval:      1 * time.Minute,
+			BtcBackendMonitorInterval:      1 * time.Minute,
+			FpStakeMetricsInterval:         1 * time.Minute,
+			FpStakeMetricsTopN:             20,
+			OutboxRelayMaxAttempts:         10,
+			OutboxRelayRetryBackoff:        1 * time.Second,
+			StuckDelegationThresholds: map[string]time.Duration{
+				"pending":  72 * time.Hour,
+				"verified": 6 * time.Hour,
+			},
+			StuckDelegationsReportLimit: 50,
 		},
 		Queue: *queuecfg.DefaultQueueConfig(),
 		Metrics: config.MetricsConfig{
 			Host: "0.0.0.0",
 			Port: 2112,
 		},
+		Reconciliation: config.ReconciliationConfig{
+			Interval:          24 * time.Hour,
+			BatchSize:         100,
+			RequestsPerSecond: 10,
+		},
+		Log: config.LogConfig{Level: "debug"},
 	}
 	cfg.Queue.QueueProcessingTimeout = time.Duration(50) * time.Second
 	cfg.Queue.ReQueueDelayTime = time.Duration(100) * time.Second
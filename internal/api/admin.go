@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// DelegationReprocessor corrects an indexed delegation from its BBN chain
+// state
+type DelegationReprocessor interface {
+	ReprocessDelegation(ctx context.Context, stakingTxHash types.StakingTxHash) ([]types.DelegationCorrection, *types.Error)
+}
+
+// IndexingController pauses the indexing for maintenance and resumes it
+type IndexingController interface {
+	PauseIndexing(ctx context.Context) *types.Error
+	ResumeIndexing(ctx context.Context) *types.Error
+}
+
+// LeadershipChecker tells whether the instance holds the leader lease, the
+// only one applying the admin actions when several replicas run
+type LeadershipChecker interface {
+	IsLeader() bool
+}
+
+type adminContextKey struct{}
+
+type ReprocessDelegationRequest struct {
+	StakingTxHashHex string `json:"staking_tx_hash_hex"`
+}
+
+type DelegationCorrectionPublic struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type ReprocessDelegationPublic struct {
+	StakingTxHashHex string                       `json:"staking_tx_hash_hex"`
+	Changes          []DelegationCorrectionPublic `json:"changes"`
+}
+
+type IndexingStatusPublic struct {
+	Paused bool `json:"paused"`
+}
+
+type SetLogLevelRequest struct {
+	Component string `json:"component"`
+	Level     string `json:"level"`
+}
+
+type LogLevelsPublic struct {
+	Levels map[string]string `json:"levels"`
+}
+
+// requireAdmin only lets through the requests bearing one of the admin
+// tokens, and passes the name of the authenticated admin in the context
+func (h *handler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, r, types.NewErrorWithMsg(
+				http.StatusUnauthorized, types.Unauthorized, "missing bearer token",
+			))
+			return
+		}
+
+		// Compare every token so that the time taken does not tell which
+		// one matched
+		var admin string
+		for name, adminToken := range h.cfg.AdminTokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+				admin = name
+			}
+		}
+		if admin == "" {
+			logging.FromContext(r.Context()).Warn().
+				Str("remote_addr", r.RemoteAddr).
+				Str("path", r.URL.Path).
+				Msg("rejected admin request with an invalid token")
+			writeError(w, r, types.NewErrorWithMsg(
+				http.StatusUnauthorized, types.Unauthorized, "invalid bearer token",
+			))
+			return
+		}
+
+		// The mutations of the admin action are audited with the admin
+		ctx := audit.WithTrigger(r.Context(), audit.Trigger{Type: audit.TriggerAdmin, Operator: admin})
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, adminContextKey{}, admin)))
+	})
+}
+
+// requireLeader refuses the requests with a 503 unless the instance holds the
+// leader lease, for the admin actions not to be applied by a standby
+// alongside the indexing of the leader. The refusal is not a failure of the
+// instance, so it is neither logged as one nor does it hide its message.
+func (h *handler) requireLeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.leadership.IsLeader() {
+			writeResponse(w, http.StatusServiceUnavailable, types.ErrorEnvelope{
+				ErrorCode: types.ServiceUnavailable.String(),
+				Message:   "the instance is a standby, the admin actions are applied by the leader",
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *handler) reprocessDelegation(w http.ResponseWriter, r *http.Request) error {
+	var req ReprocessDelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return types.NewValidationFailedError(fmt.Errorf("invalid request body: %w", err))
+	}
+	stakingTxHash, err := parseTxHash("staking_tx_hash_hex", req.StakingTxHashHex)
+	if err != nil {
+		return err
+	}
+
+	corrections, err := h.admin.ReprocessDelegation(r.Context(), stakingTxHash)
+
+	// Audit record of the admin action, whatever its outcome
+	audit := logging.FromContext(r.Context()).Info()
+	if err != nil {
+		audit = logging.FromContext(r.Context()).Warn().Err(err)
+	}
+	audit.
+		Str("admin", r.Context().Value(adminContextKey{}).(string)).
+		Str("remote_addr", r.RemoteAddr).
+		Str("action", "reprocess_delegation").
+		Str("staking_tx_hash_hex", stakingTxHash.String()).
+		Interface("changes", corrections).
+		Msg("admin action")
+
+	if err != nil {
+		return err
+	}
+
+	changes := make([]DelegationCorrectionPublic, 0, len(corrections))
+	for _, correction := range corrections {
+		changes = append(changes, DelegationCorrectionPublic{
+			Field: correction.Field,
+			From:  correction.From,
+			To:    correction.To,
+		})
+	}
+	writeData(w, ReprocessDelegationPublic{
+		StakingTxHashHex: stakingTxHash.String(),
+		Changes:          changes,
+	})
+	return nil
+}
+
+// pauseIndexing pauses the indexing, responding once the block or the poller
+// runs in flight completed
+func (h *handler) pauseIndexing(w http.ResponseWriter, r *http.Request) error {
+	return h.setIndexingPaused(w, r, true)
+}
+
+func (h *handler) resumeIndexing(w http.ResponseWriter, r *http.Request) error {
+	return h.setIndexingPaused(w, r, false)
+}
+
+func (h *handler) setIndexingPaused(w http.ResponseWriter, r *http.Request, paused bool) error {
+	action := "resume_indexing"
+	change := h.indexing.ResumeIndexing
+	if paused {
+		action = "pause_indexing"
+		change = h.indexing.PauseIndexing
+	}
+	err := change(r.Context())
+
+	// Audit record of the admin action, whatever its outcome
+	audit := logging.FromContext(r.Context()).Info()
+	if err != nil {
+		audit = logging.FromContext(r.Context()).Warn().Err(err)
+	}
+	audit.
+		Str("admin", r.Context().Value(adminContextKey{}).(string)).
+		Str("remote_addr", r.RemoteAddr).
+		Str("action", action).
+		Msg("admin action")
+
+	if err != nil {
+		return err
+	}
+	writeData(w, IndexingStatusPublic{Paused: paused})
+	return nil
+}
+
+// setLogLevel changes the log level of a component until the next restart,
+// leaving the other components at theirs
+func (h *handler) setLogLevel(w http.ResponseWriter, r *http.Request) error {
+	var req SetLogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return types.NewValidationFailedError(fmt.Errorf("invalid request body: %w", err))
+	}
+
+	var err *types.Error
+	if setErr := logging.SetLevel(req.Component, req.Level); setErr != nil {
+		err = types.NewValidationFailedError(setErr)
+	}
+
+	// Audit record of the admin action, whatever its outcome
+	audit := logging.FromContext(r.Context()).Info()
+	if err != nil {
+		audit = logging.FromContext(r.Context()).Warn().Err(err)
+	}
+	audit.
+		Str("admin", r.Context().Value(adminContextKey{}).(string)).
+		Str("remote_addr", r.RemoteAddr).
+		Str("action", "set_log_level").
+		Str("component", req.Component).
+		Str("level", req.Level).
+		Msg("admin action")
+
+	if err != nil {
+		return err
+	}
+	writeData(w, LogLevelsPublic{Levels: logging.CurrentLevels()})
+	return nil
+}
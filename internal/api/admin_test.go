@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+type fakeDelegationReprocessor struct {
+	reprocessed []types.StakingTxHash
+	corrections []types.DelegationCorrection
+	err         *types.Error
+}
+
+func (f *fakeDelegationReprocessor) ReprocessDelegation(
+	_ context.Context, stakingTxHash types.StakingTxHash,
+) ([]types.DelegationCorrection, *types.Error) {
+	f.reprocessed = append(f.reprocessed, stakingTxHash)
+	return f.corrections, f.err
+}
+
+// fakeLeadership tells whether the instance holds the leader lease
+type fakeLeadership bool
+
+func (f fakeLeadership) IsLeader() bool {
+	return bool(f)
+}
+
+func serveAdmin(
+	t *testing.T, admin DelegationReprocessor, adminTokens map[string]string, token, body string,
+) (*httptest.ResponseRecorder, types.ErrorEnvelope) {
+	cfg := newTestConfig()
+	cfg.AdminTokens = adminTokens
+	server := New(cfg, mocks.NewDbInterface(t), nil, nil, admin, nil, fakeLeadership(true))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/v1/delegation/reprocess", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(rec, req)
+
+	var errResp types.ErrorEnvelope
+	if rec.Code != http.StatusOK && rec.Code != http.StatusNotFound {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	}
+	return rec, errResp
+}
+
+func TestReprocessDelegation(t *testing.T) {
+	admin := &fakeDelegationReprocessor{
+		corrections: []types.DelegationCorrection{{Field: "state", From: "ACTIVE", To: "UNBONDING"}},
+	}
+	body := `{"staking_tx_hash_hex":"` + strings.ToUpper(testStakingTxHashHex) + `"}`
+
+	rec, _ := serveAdmin(t, admin, map[string]string{"alice": "secret"}, "secret", body)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":{
+		"staking_tx_hash_hex":"`+testStakingTxHashHex+`",
+		"changes":[{"field":"state","from":"ACTIVE","to":"UNBONDING"}]
+	}}`, rec.Body.String())
+	require.Equal(t, []types.StakingTxHash{types.StakingTxHash(testStakingTxHashHex)}, admin.reprocessed)
+}
+
+func TestReprocessDelegationRefused(t *testing.T) {
+	admin := &fakeDelegationReprocessor{
+		err: types.NewErrorWithMsg(http.StatusConflict, types.Conflict, "block being processed"),
+	}
+	body := `{"staking_tx_hash_hex":"` + testStakingTxHashHex + `"}`
+
+	rec, errResp := serveAdmin(t, admin, map[string]string{"alice": "secret"}, "secret", body)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Equal(t, types.Conflict.String(), errResp.ErrorCode)
+}
+
+func TestReprocessDelegationStateTransitionRejected(t *testing.T) {
+	// a rejected state transition conflicts whatever the error wrapping it
+	admin := &fakeDelegationReprocessor{
+		err: types.NewInternalServiceError(fmt.Errorf("failed to update state: %w", &db.StateTransitionError{
+			StakingTxHash: testStakingTxHashHex,
+			CurrentState:  types.StateWithdrawn,
+			TargetState:   types.StateUnbonding,
+		})),
+	}
+	body := `{"staking_tx_hash_hex":"` + testStakingTxHashHex + `"}`
+
+	rec, errResp := serveAdmin(t, admin, map[string]string{"alice": "secret"}, "secret", body)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Equal(t, types.Conflict.String(), errResp.ErrorCode)
+}
+
+func TestReprocessDelegationAuthentication(t *testing.T) {
+	adminTokens := map[string]string{"alice": "secret"}
+	body := `{"staking_tx_hash_hex":"` + testStakingTxHashHex + `"}`
+
+	for name, token := range map[string]string{"missing token": "", "invalid token": "guess"} {
+		t.Run(name, func(t *testing.T) {
+			admin := &fakeDelegationReprocessor{}
+			rec, errResp := serveAdmin(t, admin, adminTokens, token, body)
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+			require.Equal(t, types.Unauthorized.String(), errResp.ErrorCode)
+			require.Empty(t, admin.reprocessed)
+		})
+	}
+
+	t.Run("admin disabled", func(t *testing.T) {
+		rec, _ := serveAdmin(t, &fakeDelegationReprocessor{}, nil, "secret", body)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestReprocessDelegationValidation(t *testing.T) {
+	for name, body := range map[string]string{
+		"malformed body":    `{`,
+		"missing tx hash":   `{}`,
+		"invalid tx hash":   `{"staking_tx_hash_hex":"zz"}`,
+		"tx hash too short": `{"staking_tx_hash_hex":"abcd"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			admin := &fakeDelegationReprocessor{}
+			rec, errResp := serveAdmin(t, admin, map[string]string{"alice": "secret"}, "secret", body)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+			require.Empty(t, admin.reprocessed)
+		})
+	}
+}
+
+type fakeIndexingController struct {
+	paused bool
+	err    *types.Error
+}
+
+func (f *fakeIndexingController) PauseIndexing(_ context.Context) *types.Error {
+	if f.err == nil {
+		f.paused = true
+	}
+	return f.err
+}
+
+func (f *fakeIndexingController) ResumeIndexing(_ context.Context) *types.Error {
+	f.paused = false
+	return nil
+}
+
+func TestPauseAndResumeIndexing(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AdminTokens = map[string]string{"alice": "secret"}
+	indexing := &fakeIndexingController{}
+	server := New(cfg, mocks.NewDbInterface(t), nil, nil, nil, indexing, fakeLeadership(true))
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/admin/v1/indexing/pause")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":{"paused":true}}`, rec.Body.String())
+	require.True(t, indexing.paused)
+
+	rec = post("/admin/v1/indexing/resume")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":{"paused":false}}`, rec.Body.String())
+	require.False(t, indexing.paused)
+
+	indexing.err = types.NewErrorWithMsg(http.StatusRequestTimeout, types.RequestTimeout, "work in flight")
+	rec = post("/admin/v1/indexing/pause")
+	require.Equal(t, http.StatusRequestTimeout, rec.Code)
+}
+
+func TestSetLogLevel(t *testing.T) {
+	require.NoError(t, logging.Init(&config.LogConfig{Level: "info"}))
+	t.Cleanup(func() {
+		require.NoError(t, logging.Init(&config.LogConfig{Level: "trace"}))
+	})
+	cfg := newTestConfig()
+	cfg.AdminTokens = map[string]string{"alice": "secret"}
+	server := New(cfg, mocks.NewDbInterface(t), nil, nil, nil, nil, fakeLeadership(true))
+	put := func(token, body string) (*httptest.ResponseRecorder, types.ErrorEnvelope) {
+		req := httptest.NewRequest(http.MethodPut, "/admin/v1/log-level", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(rec, req)
+		var errResp types.ErrorEnvelope
+		if rec.Code != http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+		}
+		return rec, errResp
+	}
+
+	rec, _ := put("secret", `{"component":"db","level":"debug"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":{"levels":{
+		"block-processor":"info","expiry":"info","db":"debug",
+		"bbnclient":"info","btcclient":"info","emitter":"info"
+	}}}`, rec.Body.String())
+	require.Equal(t, zerolog.DebugLevel, logging.DB.Level())
+	require.Equal(t, zerolog.InfoLevel, logging.BlockProcessor.Level())
+
+	rec, errResp := put("secret", `{"component":"api","level":"debug"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+	require.Contains(t, errResp.Message, "block-processor, expiry, db, bbnclient, btcclient, emitter")
+
+	rec, errResp = put("secret", `{"component":"db","level":"verbose"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, errResp.Message, "trace, debug, info, warn, error, fatal, panic, disabled")
+	require.Equal(t, zerolog.DebugLevel, logging.DB.Level())
+
+	rec, _ = put("wrong", `{"component":"db","level":"error"}`)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Equal(t, zerolog.DebugLevel, logging.DB.Level())
+}
+
+func TestAdminActionsRefusedByStandby(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AdminTokens = map[string]string{"alice": "secret"}
+	admin := &fakeDelegationReprocessor{}
+	indexing := &fakeIndexingController{}
+	server := New(cfg, mocks.NewDbInterface(t), nil, nil, admin, indexing, fakeLeadership(false))
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/admin/v1/delegation/reprocess", `{"staking_tx_hash_hex":"` + testStakingTxHashHex + `"}`},
+		{http.MethodPost, "/admin/v1/indexing/pause", ""},
+		{http.MethodPost, "/admin/v1/indexing/resume", ""},
+		{http.MethodPut, "/admin/v1/log-level", `{"component":"db","level":"debug"}`},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			server.httpServer.Handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			var errResp types.ErrorEnvelope
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+			require.Equal(t, types.ServiceUnavailable.String(), errResp.ErrorCode)
+			require.Contains(t, errResp.Message, "standby")
+		})
+	}
+	require.Empty(t, admin.reprocessed)
+	require.False(t, indexing.paused)
+
+	// The admin token is checked first
+	req := httptest.NewRequest(http.MethodPost, "/admin/v1/indexing/pause", nil)
+	rec := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestPprof(t *testing.T) {
+	get := func(pprof bool, token, target string) int {
+		cfg := newTestConfig()
+		cfg.AdminTokens = map[string]string{"alice": "secret"}
+		cfg.Pprof = pprof
+		server := New(cfg, mocks.NewDbInterface(t), nil, nil, nil, nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, get(true, "secret", "/debug/pprof/"))
+	require.Equal(t, http.StatusOK, get(true, "secret", "/debug/pprof/heap"))
+	require.Equal(t, http.StatusOK, get(true, "secret", "/debug/pprof/cmdline"))
+	// the profiles are guarded as the admin endpoints
+	require.Equal(t, http.StatusUnauthorized, get(true, "", "/debug/pprof/heap"))
+	require.Equal(t, http.StatusUnauthorized, get(true, "wrong", "/debug/pprof/heap"))
+	// and not served unless enabled
+	require.Equal(t, http.StatusNotFound, get(false, "secret", "/debug/pprof/heap"))
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+type handler struct {
+	cfg        *config.APIConfig
+	db         db.DbInterface
+	stats      GlobalStatsComputer
+	health     HealthChecker
+	admin      DelegationReprocessor
+	indexing   IndexingController
+	leadership LeadershipChecker
+}
+
+type DelegationPublic struct {
+	StakingTxHashHex          string   `json:"staking_tx_hash_hex"`
+	StakerBtcPkHex            string   `json:"staker_btc_pk_hex"`
+	StakerBabylonAddress      string   `json:"staker_babylon_address,omitempty"`
+	FinalityProviderBtcPksHex []string `json:"finality_provider_btc_pks_hex"`
+	State                     string   `json:"state"`
+	SubState                  string   `json:"sub_state,omitempty"`
+	StakingAmount             uint64   `json:"staking_amount"`
+	StakingTime               uint32   `json:"staking_time"`
+	UnbondingTime             uint32   `json:"unbonding_time"`
+	StartHeight               uint32   `json:"start_height"`
+	EndHeight                 uint32   `json:"end_height"`
+	ParamsVersion             uint32   `json:"params_version"`
+	// CreatedBbnHeight is the BBN height the delegation got created at
+	CreatedBbnHeight                 int64 `json:"created_bbn_height"`
+	CovenantUnbondingSignaturesCount int   `json:"covenant_unbonding_signatures_count"`
+}
+
+func newDelegationPublic(delegation *model.BTCDelegationSummary) DelegationPublic {
+	fpBtcPksHex := delegation.FinalityProviderBtcPksHex
+	if fpBtcPksHex == nil {
+		fpBtcPksHex = []string{}
+	}
+
+	return DelegationPublic{
+		StakingTxHashHex:                 delegation.StakingTxHashHex,
+		StakerBtcPkHex:                   delegation.StakerBtcPkHex,
+		StakerBabylonAddress:             delegation.StakerBabylonAddress,
+		FinalityProviderBtcPksHex:        fpBtcPksHex,
+		State:                            delegation.State.String(),
+		SubState:                         string(delegation.SubState),
+		StakingAmount:                    delegation.StakingAmount,
+		StakingTime:                      delegation.StakingTime,
+		UnbondingTime:                    delegation.UnbondingTime,
+		StartHeight:                      delegation.StartHeight,
+		EndHeight:                        delegation.EndHeight,
+		ParamsVersion:                    delegation.ParamsVersion,
+		CreatedBbnHeight:                 delegation.BTCDelegationCreatedBlock.Height,
+		CovenantUnbondingSignaturesCount: delegation.CovenantUnbondingSignaturesCount,
+	}
+}
+
+// getDelegation returns the delegation of the staking tx hash given by the
+// staking_tx_hash_hex query parameter
+func (h *handler) getDelegation(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r, "staking_tx_hash_hex"); err != nil {
+		return err
+	}
+
+	stakingTxHash, err := parseTxHashQuery(r, "staking_tx_hash_hex")
+	if err != nil {
+		return err
+	}
+
+	delegation, dbErr := h.db.GetBTCDelegationByStakingTxHash(r.Context(), stakingTxHash.String())
+	if dbErr != nil {
+		if db.IsNotFoundError(dbErr) {
+			return types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, "delegation not found",
+			)
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get delegation %s: %w", stakingTxHash, dbErr),
+		)
+	}
+
+	writeData(w, newDelegationPublic(delegation.Summary()))
+	return nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+const (
+	timeLockStatusActive   = "active"
+	timeLockStatusArchived = "archived"
+)
+
+type DelegationHistoryPublic struct {
+	StakingTxHashHex string                  `json:"staking_tx_hash_hex"`
+	State            string                  `json:"state"`
+	SubState         string                  `json:"sub_state,omitempty"`
+	Transitions      []StateTransitionPublic `json:"transitions"`
+	TimeLocks        []TimeLockPublic        `json:"timelocks"`
+}
+
+type StateTransitionPublic struct {
+	FromState string `json:"from_state,omitempty"`
+	ToState   string `json:"to_state"`
+	SubState  string `json:"sub_state,omitempty"`
+	Trigger   string `json:"trigger"`
+	BbnHeight uint64 `json:"bbn_height,omitempty"`
+	BtcHeight uint64 `json:"btc_height,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Operator  string `json:"operator,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type TimeLockPublic struct {
+	ExpireHeight  uint32 `json:"expire_height"`
+	SubState      string `json:"sub_state"`
+	Status        string `json:"status"`
+	ArchiveReason string `json:"archive_reason,omitempty"`
+	ArchivedAt    int64  `json:"archived_at,omitempty"`
+}
+
+func newStateTransitionPublic(transition *model.DelegationStateTransition) StateTransitionPublic {
+	return StateTransitionPublic{
+		FromState: transition.FromState.String(),
+		ToState:   transition.ToState.String(),
+		SubState:  transition.SubState.String(),
+		Trigger:   transition.Trigger,
+		BbnHeight: transition.BbnHeight,
+		BtcHeight: transition.BtcHeight,
+		Reason:    transition.Reason,
+		Operator:  transition.Operator,
+		Timestamp: transition.CreatedAt,
+	}
+}
+
+// getDelegationHistory returns the state transitions and the timelocks,
+// archived ones included, of the delegation of the staking tx hash given by
+// the staking_tx_hash_hex query parameter
+func (h *handler) getDelegationHistory(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r, "staking_tx_hash_hex"); err != nil {
+		return err
+	}
+
+	stakingTxHash, err := parseTxHashQuery(r, "staking_tx_hash_hex")
+	if err != nil {
+		return err
+	}
+	stakingTxHashHex := stakingTxHash.String()
+
+	ctx := r.Context()
+	delegation, dbErr := h.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if dbErr != nil {
+		if db.IsNotFoundError(dbErr) {
+			return types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, "delegation not found",
+			)
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get delegation %s: %w", stakingTxHashHex, dbErr),
+		)
+	}
+
+	transitions, dbErr := h.db.GetDelegationStateTransitions(ctx, stakingTxHashHex)
+	if dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get state transitions of delegation %s: %w", stakingTxHashHex, dbErr),
+		)
+	}
+
+	timeLocks, dbErr := h.db.GetTimeLocks(ctx, stakingTxHashHex)
+	if dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get timelocks of delegation %s: %w", stakingTxHashHex, dbErr),
+		)
+	}
+
+	archivedTimeLocks, dbErr := h.db.GetArchivedTimeLocks(ctx, stakingTxHashHex)
+	if dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get archived timelocks of delegation %s: %w", stakingTxHashHex, dbErr),
+		)
+	}
+
+	history := DelegationHistoryPublic{
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		State:            delegation.State.String(),
+		SubState:         delegation.SubState.String(),
+		Transitions:      make([]StateTransitionPublic, 0, len(transitions)),
+		TimeLocks:        make([]TimeLockPublic, 0, len(archivedTimeLocks)+len(timeLocks)),
+	}
+	for _, transition := range transitions {
+		history.Transitions = append(history.Transitions, newStateTransitionPublic(transition))
+	}
+	for _, timeLock := range archivedTimeLocks {
+		history.TimeLocks = append(history.TimeLocks, TimeLockPublic{
+			ExpireHeight:  timeLock.ExpireHeight,
+			SubState:      timeLock.DelegationSubState.String(),
+			Status:        timeLockStatusArchived,
+			ArchiveReason: timeLock.ArchiveReason,
+			ArchivedAt:    timeLock.ArchivedAt,
+		})
+	}
+	for _, timeLock := range timeLocks {
+		history.TimeLocks = append(history.TimeLocks, TimeLockPublic{
+			ExpireHeight: timeLock.ExpireHeight,
+			SubState:     timeLock.DelegationSubState.String(),
+			Status:       timeLockStatusActive,
+		})
+	}
+
+	writeData(w, history)
+	return nil
+}
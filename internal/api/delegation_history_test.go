@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+func TestGetDelegationHistory(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHashHex).Return(
+		&model.BTCDelegationDetails{
+			StakingTxHashHex: testStakingTxHashHex,
+			State:            types.StateWithdrawable,
+			SubState:         types.SubStateTimelock,
+		}, nil,
+	)
+	dbMock.On("GetDelegationStateTransitions", mock.Anything, testStakingTxHashHex).Return(
+		[]*model.DelegationStateTransition{
+			model.NewBbnStateTransition(
+				testStakingTxHashHex, "", types.StatePending, "",
+				"babylon.btcstaking.v1.EventBTCDelegationCreated", 10, 1000,
+			),
+			model.NewBbnStateTransition(
+				testStakingTxHashHex, types.StatePending, types.StateActive, "",
+				"babylon.btcstaking.v1.EventCovenantQuorumReached", 12, 1100,
+			),
+			model.NewBbnStateTransition(
+				testStakingTxHashHex, types.StateActive, types.StateUnbonding, types.SubStateTimelock,
+				"babylon.btcstaking.v1.EventBTCDelegationExpired", 50, 1500,
+			),
+			model.NewBtcStateTransition(
+				testStakingTxHashHex, types.StateUnbonding, types.StateWithdrawable, types.SubStateTimelock,
+				model.StateTransitionTriggerExpiry, 900, 1600,
+			),
+		}, nil,
+	)
+	dbMock.On("GetTimeLocks", mock.Anything, testStakingTxHashHex).Return(nil, nil)
+	dbMock.On("GetArchivedTimeLocks", mock.Anything, testStakingTxHashHex).Return(
+		[]*model.ArchivedTimeLockDocument{{
+			TimeLockDocument: *model.NewTimeLockDocument(testStakingTxHashHex, 900, types.SubStateTimelock),
+			ArchiveReason:    model.TimeLockArchiveReasonExpired,
+			ArchivedAt:       1600,
+		}}, nil,
+	)
+
+	rec, _ := serve(t, dbMock, "/v1/delegation/history?staking_tx_hash_hex="+testStakingTxHashHex)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":{
+		"staking_tx_hash_hex":"`+testStakingTxHashHex+`",
+		"state":"WITHDRAWABLE",
+		"sub_state":"TIMELOCK",
+		"transitions":[
+			{"to_state":"PENDING","trigger":"babylon.btcstaking.v1.EventBTCDelegationCreated","bbn_height":10,"timestamp":1000},
+			{"from_state":"PENDING","to_state":"ACTIVE","trigger":"babylon.btcstaking.v1.EventCovenantQuorumReached","bbn_height":12,"timestamp":1100},
+			{"from_state":"ACTIVE","to_state":"UNBONDING","sub_state":"TIMELOCK","trigger":"babylon.btcstaking.v1.EventBTCDelegationExpired","bbn_height":50,"timestamp":1500},
+			{"from_state":"UNBONDING","to_state":"WITHDRAWABLE","sub_state":"TIMELOCK","trigger":"expiry","btc_height":900,"timestamp":1600}
+		],
+		"timelocks":[
+			{"expire_height":900,"sub_state":"TIMELOCK","status":"archived","archive_reason":"expired","archived_at":1600}
+		]
+	}}`, rec.Body.String())
+}
+
+func TestGetDelegationHistoryNotFound(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHashHex).Return(
+		nil, &db.NotFoundError{Key: testStakingTxHashHex, Message: "not found"},
+	)
+
+	rec, errResp := serve(t, dbMock, "/v1/delegation/history?staking_tx_hash_hex="+testStakingTxHashHex)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, types.NotFound.String(), errResp.ErrorCode)
+}
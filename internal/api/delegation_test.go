@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+const testMaxPageSize = 10
+
+var testStakingTxHashHex = strings.Repeat("ab", 32)
+
+func newTestConfig() *config.APIConfig {
+	return &config.APIConfig{
+		ListenAddress:      "127.0.0.1:0",
+		RequestTimeout:     time.Second,
+		ShutdownTimeout:    time.Second,
+		MaxPageSize:        testMaxPageSize,
+		MaxBtcHeightWindow: 10000,
+		StatsMaxAge:        time.Minute,
+		StatsCacheMaxAge:   5 * time.Second,
+	}
+}
+
+func serve(t *testing.T, dbMock *mocks.DbInterface, target string) (*httptest.ResponseRecorder, types.ErrorEnvelope) {
+	return serveWithStats(t, dbMock, nil, target)
+}
+
+func serveWithStats(
+	t *testing.T, dbMock *mocks.DbInterface, stats GlobalStatsComputer, target string,
+) (*httptest.ResponseRecorder, types.ErrorEnvelope) {
+	server := New(newTestConfig(), dbMock, stats, nil, nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var errResp types.ErrorEnvelope
+	if rec.Code != http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	}
+	return rec, errResp
+}
+
+func TestGetDelegation(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHashHex).Return(
+		&model.BTCDelegationDetails{
+			StakingTxHashHex:          testStakingTxHashHex,
+			StakerBtcPkHex:            "staker",
+			FinalityProviderBtcPksHex: []string{"fp"},
+			State:                     types.StateUnbonding,
+			SubState:                  types.SubStateEarlyUnbonding,
+			StakingAmount:             1000,
+			StakingTime:               100,
+			UnbondingTime:             10,
+			StartHeight:               200,
+			EndHeight:                 300,
+			ParamsVersion:             1,
+			CovenantUnbondingSignatures: []model.CovenantSignature{
+				{CovenantBtcPkHex: "cov-1", SignatureHex: "sig-1"},
+				{CovenantBtcPkHex: "cov-2", SignatureHex: "sig-2"},
+			},
+			BTCDelegationCreatedBlock: model.BTCDelegationCreatedBbnBlock{Height: 50},
+		}, nil,
+	)
+
+	// The hash is looked up lowercase
+	rec, _ := serve(t, dbMock, "/v1/delegation?staking_tx_hash_hex="+strings.ToUpper(testStakingTxHashHex))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp publicResponse[DelegationPublic]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, DelegationPublic{
+		StakingTxHashHex:                 testStakingTxHashHex,
+		StakerBtcPkHex:                   "staker",
+		FinalityProviderBtcPksHex:        []string{"fp"},
+		State:                            types.StateUnbonding.String(),
+		SubState:                         string(types.SubStateEarlyUnbonding),
+		StakingAmount:                    1000,
+		StakingTime:                      100,
+		UnbondingTime:                    10,
+		StartHeight:                      200,
+		EndHeight:                        300,
+		ParamsVersion:                    1,
+		CreatedBbnHeight:                 50,
+		CovenantUnbondingSignaturesCount: 2,
+	}, resp.Data)
+}
+
+func TestGetDelegationNotFound(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHashHex).Return(
+		nil, &db.NotFoundError{Key: testStakingTxHashHex, Message: "not found"},
+	)
+
+	rec, errResp := serve(t, dbMock, "/v1/delegation?staking_tx_hash_hex="+testStakingTxHashHex)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, types.NotFound.String(), errResp.ErrorCode)
+}
+
+func TestGetDelegationDbError(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHashHex).Return(
+		nil, errors.New("connection refused"),
+	)
+
+	rec, errResp := serve(t, dbMock, "/v1/delegation?staking_tx_hash_hex="+testStakingTxHashHex)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, types.InternalServiceError.String(), errResp.ErrorCode)
+	require.NotContains(t, errResp.Message, "connection refused")
+}
+
+func TestGetDelegationValidation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"missing", ""},
+		{"not hex", "?staking_tx_hash_hex=" + strings.Repeat("zz", 32)},
+		{"too short", "?staking_tx_hash_hex=" + strings.Repeat("ab", 31)},
+		{"too long", "?staking_tx_hash_hex=" + strings.Repeat("ab", 33)},
+		{"unknown parameter", "?staking_tx_hash_hex=" + testStakingTxHashHex + "&staking_tx_hash=x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The db is not queried
+			rec, errResp := serve(t, mocks.NewDbInterface(t), "/v1/delegation"+tc.query)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	bbn "github.com/babylonlabs-io/babylon/types"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+type DescriptionPublic struct {
+	Moniker         string `json:"moniker"`
+	Identity        string `json:"identity"`
+	Website         string `json:"website"`
+	SecurityContact string `json:"security_contact"`
+	Details         string `json:"details"`
+}
+
+type FinalityProviderPublic struct {
+	BtcPk          string            `json:"btc_pk"`
+	BabylonAddress string            `json:"babylon_address"`
+	Commission     string            `json:"commission"`
+	State          string            `json:"state"`
+	Description    DescriptionPublic `json:"description"`
+	BsnId          string            `json:"bsn_id"`
+}
+
+type FinalityProviderStatsPublic struct {
+	ActiveDelegations   uint64 `json:"active_delegations"`
+	ActiveStakingAmount uint64 `json:"active_staking_amount"`
+}
+
+type FinalityProviderWithStatsPublic struct {
+	FinalityProviderPublic
+	Stats FinalityProviderStatsPublic `json:"stats"`
+}
+
+func newFinalityProviderPublic(fp *model.FinalityProviderDetails) FinalityProviderPublic {
+	return FinalityProviderPublic{
+		BtcPk:          fp.BtcPk,
+		BabylonAddress: fp.BabylonAddress,
+		Commission:     fp.Commission,
+		State:          strings.TrimPrefix(fp.State, finalityProviderStatePrefix),
+		Description: DescriptionPublic{
+			Moniker:         fp.Description.Moniker,
+			Identity:        fp.Description.Identity,
+			Website:         fp.Description.Website,
+			SecurityContact: fp.Description.SecurityContact,
+			Details:         fp.Description.Details,
+		},
+		BsnId: fp.BsnId,
+	}
+}
+
+// getFinalityProviders returns a page of the finality providers, sorted by
+// BTC public key, optionally filtered by state, BSN id and moniker
+func (h *handler) getFinalityProviders(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r, "state", "bsn_id", "search", "pagination_key"); err != nil {
+		return err
+	}
+
+	state, err := parseFinalityProviderStateQuery(r, "state")
+	if err != nil {
+		return err
+	}
+	filter := db.FinalityProvidersFilter{
+		State:         state,
+		MonikerSearch: r.URL.Query().Get("search"),
+	}
+	// An empty BSN id selects the finality providers of the Babylon chain
+	if r.URL.Query().Has("bsn_id") {
+		bsnId := r.URL.Query().Get("bsn_id")
+		filter.BsnId = &bsnId
+	}
+
+	paginationKey := r.URL.Query().Get("pagination_key")
+	result, dbErr := h.db.GetFinalityProviders(r.Context(), filter, paginationKey, h.cfg.MaxPageSize)
+	if dbErr != nil {
+		if db.IsInvalidPaginationTokenError(dbErr) {
+			return types.NewValidationFailedError(errors.New("pagination_key is invalid"))
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get finality providers: %w", dbErr),
+		)
+	}
+
+	fps := make([]FinalityProviderPublic, 0, len(result.Data))
+	for _, fp := range result.Data {
+		fps = append(fps, newFinalityProviderPublic(fp))
+	}
+
+	writePage(w, fps, result.PaginationToken)
+	return nil
+}
+
+// getFinalityProvider returns the finality provider of the BTC public key
+// together with the stats of its active delegations
+func (h *handler) getFinalityProvider(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r); err != nil {
+		return err
+	}
+
+	pk, parseErr := bbn.NewBIP340PubKeyFromHex(chi.URLParam(r, "btc_pk"))
+	if parseErr != nil {
+		return types.NewValidationFailedError(errors.New("btc_pk is not a valid BTC public key"))
+	}
+	btcPk := pk.MarshalHex()
+
+	fp, dbErr := h.db.GetFinalityProviderByBtcPk(r.Context(), btcPk)
+	if dbErr != nil {
+		if db.IsNotFoundError(dbErr) {
+			return types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, "finality provider not found",
+			)
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get finality provider %s: %w", btcPk, dbErr),
+		)
+	}
+
+	stats, dbErr := h.db.GetFinalityProviderStats(r.Context(), btcPk)
+	if dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get stats of finality provider %s: %w", btcPk, dbErr),
+		)
+	}
+
+	writeData(w, FinalityProviderWithStatsPublic{
+		FinalityProviderPublic: newFinalityProviderPublic(fp),
+		Stats: FinalityProviderStatsPublic{
+			ActiveDelegations:   stats.ActiveDelegations,
+			ActiveStakingAmount: stats.ActiveStakingAmount,
+		},
+	})
+	return nil
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+// testFpBtcPk is the x-only key of the secp256k1 generator
+const testFpBtcPk = testStakerBtcPkHex
+
+func testFinalityProvider() *model.FinalityProviderDetails {
+	return &model.FinalityProviderDetails{
+		BtcPk:          testFpBtcPk,
+		BabylonAddress: "bbn-address",
+		Commission:     "0.05",
+		State:          "FINALITY_PROVIDER_STATUS_ACTIVE",
+		Description:    model.Description{Moniker: "Provider"},
+	}
+}
+
+func TestGetFinalityProviders(t *testing.T) {
+	babylonBsnId := model.BabylonBsnId
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetFinalityProviders", mock.Anything, db.FinalityProvidersFilter{
+		State:         "FINALITY_PROVIDER_STATUS_ACTIVE",
+		BsnId:         &babylonBsnId,
+		MonikerSearch: "prov",
+	}, "page-2", int64(testMaxPageSize)).Return(
+		&db.DbResultMap[*model.FinalityProviderDetails]{
+			Data:            []*model.FinalityProviderDetails{testFinalityProvider()},
+			PaginationToken: "page-3",
+		}, nil,
+	)
+
+	rec, _ := serve(t, dbMock, "/v1/finality-providers?state=active&bsn_id=&search=prov&pagination_key=page-2")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp publicResponse[[]FinalityProviderPublic]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "page-3", resp.Pagination.NextKey)
+	require.Equal(t, []FinalityProviderPublic{{
+		BtcPk:          testFpBtcPk,
+		BabylonAddress: "bbn-address",
+		Commission:     "0.05",
+		State:          "ACTIVE",
+		Description:    DescriptionPublic{Moniker: "Provider"},
+	}}, resp.Data)
+}
+
+func TestGetFinalityProvidersWithoutFilters(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetFinalityProviders", mock.Anything, db.FinalityProvidersFilter{}, "", int64(testMaxPageSize)).Return(
+		&db.DbResultMap[*model.FinalityProviderDetails]{}, nil,
+	)
+
+	rec, _ := serve(t, dbMock, "/v1/finality-providers")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":[],"pagination":{"next_key":""}}`, rec.Body.String())
+}
+
+func TestGetFinalityProvidersValidation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid state", "?state=bonded"},
+		{"unknown parameter", "?moniker=prov"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The db is not queried
+			rec, errResp := serve(t, mocks.NewDbInterface(t), "/v1/finality-providers"+tc.query)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+		})
+	}
+}
+
+func TestGetFinalityProvider(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetFinalityProviderByBtcPk", mock.Anything, testFpBtcPk).Return(testFinalityProvider(), nil)
+	dbMock.On("GetFinalityProviderStats", mock.Anything, testFpBtcPk).Return(
+		&model.FinalityProviderStats{ActiveDelegations: 2, ActiveStakingAmount: 3000}, nil,
+	)
+
+	rec, _ := serve(t, dbMock, "/v1/finality-providers/"+testFpBtcPk)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp publicResponse[FinalityProviderWithStatsPublic]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, testFpBtcPk, resp.Data.BtcPk)
+	require.Equal(t, FinalityProviderStatsPublic{ActiveDelegations: 2, ActiveStakingAmount: 3000}, resp.Data.Stats)
+}
+
+func TestGetFinalityProviderNotFound(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetFinalityProviderByBtcPk", mock.Anything, testFpBtcPk).Return(
+		nil, &db.NotFoundError{Key: testFpBtcPk, Message: "not found"},
+	)
+
+	rec, errResp := serve(t, dbMock, "/v1/finality-providers/"+testFpBtcPk)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, types.NotFound.String(), errResp.ErrorCode)
+}
+
+func TestGetFinalityProviderValidation(t *testing.T) {
+	for name, target := range map[string]string{
+		"invalid btc pk":    "/v1/finality-providers/abcd",
+		"unknown parameter": "/v1/finality-providers/" + testFpBtcPk + "?stats=true",
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec, errResp := serve(t, mocks.NewDbInterface(t), target)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+type StakingParamsPublic struct {
+	Version                      uint32   `json:"version"`
+	BtcActivationHeight          uint32   `json:"btc_activation_height"`
+	CovenantPks                  []string `json:"covenant_pks"`
+	CovenantQuorum               uint32   `json:"covenant_quorum"`
+	MinStakingValueSat           int64    `json:"min_staking_value_sat"`
+	MaxStakingValueSat           int64    `json:"max_staking_value_sat"`
+	MinStakingTimeBlocks         uint32   `json:"min_staking_time_blocks"`
+	MaxStakingTimeBlocks         uint32   `json:"max_staking_time_blocks"`
+	SlashingPkScript             string   `json:"slashing_pk_script"`
+	MinSlashingTxFeeSat          int64    `json:"min_slashing_tx_fee_sat"`
+	SlashingRate                 string   `json:"slashing_rate"`
+	UnbondingTimeBlocks          uint32   `json:"unbonding_time_blocks"`
+	UnbondingFeeSat              int64    `json:"unbonding_fee_sat"`
+	MinCommissionRate            string   `json:"min_commission_rate"`
+	DelegationCreationBaseGasFee uint64   `json:"delegation_creation_base_gas_fee"`
+	AllowListExpirationHeight    uint64   `json:"allow_list_expiration_height"`
+}
+
+type StakingParamsVersionPublic struct {
+	Version             uint32 `json:"version"`
+	BtcActivationHeight uint32 `json:"btc_activation_height"`
+}
+
+type CheckpointParamsPublic struct {
+	Version                       uint32 `json:"version"`
+	BtcConfirmationDepth          uint32 `json:"btc_confirmation_depth"`
+	CheckpointFinalizationTimeout uint32 `json:"checkpoint_finalization_timeout"`
+	CheckpointTag                 string `json:"checkpoint_tag"`
+}
+
+func newStakingParamsPublic(version uint32, params *bbnclient.StakingParams) StakingParamsPublic {
+	covenantPks := params.CovenantPks
+	if covenantPks == nil {
+		covenantPks = []string{}
+	}
+
+	return StakingParamsPublic{
+		Version:                      version,
+		BtcActivationHeight:          params.BtcActivationHeight,
+		CovenantPks:                  covenantPks,
+		CovenantQuorum:               params.CovenantQuorum,
+		MinStakingValueSat:           params.MinStakingValueSat,
+		MaxStakingValueSat:           params.MaxStakingValueSat,
+		MinStakingTimeBlocks:         params.MinStakingTimeBlocks,
+		MaxStakingTimeBlocks:         params.MaxStakingTimeBlocks,
+		SlashingPkScript:             params.SlashingPkScript,
+		MinSlashingTxFeeSat:          params.MinSlashingTxFeeSat,
+		SlashingRate:                 params.SlashingRate,
+		UnbondingTimeBlocks:          params.UnbondingTimeBlocks,
+		UnbondingFeeSat:              params.UnbondingFeeSat,
+		MinCommissionRate:            params.MinCommissionRate,
+		DelegationCreationBaseGasFee: params.DelegationCreationBaseGasFee,
+		AllowListExpirationHeight:    params.AllowListExpirationHeight,
+	}
+}
+
+// getStakingParams returns the staking params of the version, or the ones in
+// effect at the BTC height
+func (h *handler) getStakingParams(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r, "version", "btc_height"); err != nil {
+		return err
+	}
+
+	version, err := parseUint32Query(r, "version")
+	if err != nil {
+		return err
+	}
+	btcHeight, err := parseUint32Query(r, "btc_height")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version == nil && btcHeight == nil:
+		return types.NewValidationFailedError(errors.New("either version or btc_height is required"))
+	case version != nil && btcHeight != nil:
+		return types.NewValidationFailedError(errors.New("version and btc_height are mutually exclusive"))
+	case version != nil:
+		return h.getStakingParamsByVersion(w, r, *version)
+	default:
+		return h.getStakingParamsByBtcHeight(w, r, *btcHeight)
+	}
+}
+
+func (h *handler) getStakingParamsByVersion(w http.ResponseWriter, r *http.Request, version uint32) error {
+	params, dbErr := h.db.GetStakingParams(r.Context(), version)
+	if dbErr != nil {
+		if db.IsNotFoundError(dbErr) {
+			return types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, fmt.Sprintf("staking params version %d not found", version),
+			)
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get staking params version %d: %w", version, dbErr),
+		)
+	}
+
+	writeData(w, newStakingParamsPublic(version, params))
+	return nil
+}
+
+// getStakingParamsByBtcHeight returns the staking params with the highest
+// activation height not above the BTC height
+func (h *handler) getStakingParamsByBtcHeight(w http.ResponseWriter, r *http.Request, btcHeight uint32) error {
+	allParams, dbErr := h.db.GetAllStakingParams(r.Context())
+	if dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get staking params: %w", dbErr),
+		)
+	}
+
+	var (
+		selected        *bbnclient.StakingParams
+		selectedVersion uint32
+	)
+	for version, params := range allParams {
+		if params.BtcActivationHeight > btcHeight {
+			continue
+		}
+		if selected == nil ||
+			params.BtcActivationHeight > selected.BtcActivationHeight ||
+			(params.BtcActivationHeight == selected.BtcActivationHeight && version > selectedVersion) {
+			selected, selectedVersion = params, version
+		}
+	}
+	if selected == nil {
+		return types.NewErrorWithMsg(
+			http.StatusNotFound, types.NotFound, fmt.Sprintf("no staking params active at BTC height %d", btcHeight),
+		)
+	}
+
+	writeData(w, newStakingParamsPublic(selectedVersion, selected))
+	return nil
+}
+
+// getStakingParamsVersions lists the stored staking params versions with
+// their activation height, sorted by version
+func (h *handler) getStakingParamsVersions(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r); err != nil {
+		return err
+	}
+
+	allParams, dbErr := h.db.GetAllStakingParams(r.Context())
+	if dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get staking params: %w", dbErr),
+		)
+	}
+
+	versions := make([]StakingParamsVersionPublic, 0, len(allParams))
+	for version, params := range allParams {
+		versions = append(versions, StakingParamsVersionPublic{
+			Version:             version,
+			BtcActivationHeight: params.BtcActivationHeight,
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	writeData(w, versions)
+	return nil
+}
+
+func (h *handler) getCheckpointParams(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r); err != nil {
+		return err
+	}
+
+	params, dbErr := h.db.GetCheckpointParams(r.Context())
+	if dbErr != nil {
+		if db.IsNotFoundError(dbErr) {
+			return types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, "checkpoint params not found",
+			)
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get checkpoint params: %w", dbErr),
+		)
+	}
+
+	writeData(w, CheckpointParamsPublic{
+		Version:                       db.CHECKPOINT_PARAMS_VERSION,
+		BtcConfirmationDepth:          params.BtcConfirmationDepth,
+		CheckpointFinalizationTimeout: params.CheckpointFinalizationTimeout,
+		CheckpointTag:                 params.CheckpointTag,
+	})
+	return nil
+}
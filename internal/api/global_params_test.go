@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+func testAllStakingParams() map[uint32]*bbnclient.StakingParams {
+	return map[uint32]*bbnclient.StakingParams{
+		0: {BtcActivationHeight: 100, CovenantQuorum: 3},
+		1: {BtcActivationHeight: 200, CovenantQuorum: 4},
+		2: {BtcActivationHeight: 300, CovenantQuorum: 5},
+	}
+}
+
+func TestGetStakingParamsByVersion(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetStakingParams", mock.Anything, uint32(1)).Return(testAllStakingParams()[1], nil)
+
+	rec, _ := serve(t, dbMock, "/v1/params/staking?version=1")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp publicResponse[StakingParamsPublic]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, uint32(1), resp.Data.Version)
+	require.Equal(t, uint32(200), resp.Data.BtcActivationHeight)
+	require.Equal(t, uint32(4), resp.Data.CovenantQuorum)
+}
+
+func TestGetStakingParamsUnknownVersion(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetStakingParams", mock.Anything, uint32(9)).Return(
+		nil, &db.NotFoundError{Key: "9", Message: "staking params not found"},
+	)
+
+	rec, errResp := serve(t, dbMock, "/v1/params/staking?version=9")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, types.NotFound.String(), errResp.ErrorCode)
+}
+
+func TestGetStakingParamsByBtcHeight(t *testing.T) {
+	testCases := []struct {
+		btcHeight       string
+		expectedVersion uint32
+	}{
+		{"100", 0},
+		{"299", 1},
+		{"1000", 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.btcHeight, func(t *testing.T) {
+			dbMock := mocks.NewDbInterface(t)
+			dbMock.On("GetAllStakingParams", mock.Anything).Return(testAllStakingParams(), nil)
+
+			rec, _ := serve(t, dbMock, "/v1/params/staking?btc_height="+tc.btcHeight)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var resp publicResponse[StakingParamsPublic]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Equal(t, tc.expectedVersion, resp.Data.Version)
+		})
+	}
+}
+
+func TestGetStakingParamsBeforeFirstActivation(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetAllStakingParams", mock.Anything).Return(testAllStakingParams(), nil)
+
+	rec, errResp := serve(t, dbMock, "/v1/params/staking?btc_height=99")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, types.NotFound.String(), errResp.ErrorCode)
+}
+
+func TestGetStakingParamsValidation(t *testing.T) {
+	for name, query := range map[string]string{
+		"missing":           "",
+		"both":              "?version=1&btc_height=100",
+		"invalid version":   "?version=-1",
+		"invalid height":    "?btc_height=abc",
+		"unknown parameter": "?versions=1",
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec, errResp := serve(t, mocks.NewDbInterface(t), "/v1/params/staking"+query)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+		})
+	}
+}
+
+func TestGetStakingParamsVersions(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetAllStakingParams", mock.Anything).Return(testAllStakingParams(), nil)
+
+	rec, _ := serve(t, dbMock, "/v1/params/staking/versions")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp publicResponse[[]StakingParamsVersionPublic]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, []StakingParamsVersionPublic{
+		{Version: 0, BtcActivationHeight: 100},
+		{Version: 1, BtcActivationHeight: 200},
+		{Version: 2, BtcActivationHeight: 300},
+	}, resp.Data)
+}
+
+func TestGetCheckpointParams(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetCheckpointParams", mock.Anything).Return(&bbnclient.CheckpointParams{
+		BtcConfirmationDepth:          10,
+		CheckpointFinalizationTimeout: 100,
+		CheckpointTag:                 "bbn0",
+	}, nil)
+
+	rec, _ := serve(t, dbMock, "/v1/params/checkpoint")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":{
+		"version":0,
+		"btc_confirmation_depth":10,
+		"checkpoint_finalization_timeout":100,
+		"checkpoint_tag":"bbn0"
+	}}`, rec.Body.String())
+}
+
+func TestGetCheckpointParamsNotFound(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetCheckpointParams", mock.Anything).Return(
+		nil, &db.NotFoundError{Key: db.CHECKPOINT_PARAMS_TYPE, Message: "checkpoint params not found"},
+	)
+
+	rec, errResp := serve(t, dbMock, "/v1/params/checkpoint")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, types.NotFound.String(), errResp.ErrorCode)
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// HealthChecker runs the checks of the liveness and readiness probes
+type HealthChecker interface {
+	CheckLiveness(ctx context.Context) *types.HealthReport
+	CheckReadiness(ctx context.Context) *types.HealthReport
+}
+
+const (
+	healthStatusOk          = "ok"
+	healthStatusUnavailable = "unavailable"
+)
+
+type HealthCheckPublic struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type HealthReportPublic struct {
+	Status string              `json:"status"`
+	Checks []HealthCheckPublic `json:"checks"`
+}
+
+func healthStatus(healthy bool) string {
+	if healthy {
+		return healthStatusOk
+	}
+	return healthStatusUnavailable
+}
+
+func newHealthReportPublic(report *types.HealthReport) HealthReportPublic {
+	checks := make([]HealthCheckPublic, 0, len(report.Checks))
+	for _, check := range report.Checks {
+		checks = append(checks, HealthCheckPublic{
+			Name:       check.Name,
+			Status:     healthStatus(check.Healthy),
+			Message:    check.Message,
+			DurationMs: check.Duration.Milliseconds(),
+		})
+	}
+
+	return HealthReportPublic{
+		Status: healthStatus(report.Healthy),
+		Checks: checks,
+	}
+}
+
+// getLiveness serves the liveness probe, failing when the process is
+// unresponsive
+func (h *handler) getLiveness(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, r, "liveness", h.health.CheckLiveness(r.Context()))
+}
+
+// getReadiness serves the readiness probe, failing when a dependency is
+// unavailable or the indexed data is not up to date
+func (h *handler) getReadiness(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, r, "readiness", h.health.CheckReadiness(r.Context()))
+}
+
+// writeHealthReport writes the report with a 200 status if healthy and a 503
+// otherwise, as expected by the probes
+func writeHealthReport(w http.ResponseWriter, r *http.Request, probe string, report *types.HealthReport) {
+	if !report.Healthy {
+		logging.FromContext(r.Context()).Warn().
+			Str("probe", probe).
+			Interface("checks", report.Checks).
+			Msg("health probe failed")
+		writeResponse(w, http.StatusServiceUnavailable, newHealthReportPublic(report))
+		return
+	}
+	writeResponse(w, http.StatusOK, newHealthReportPublic(report))
+}
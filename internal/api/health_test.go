@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+type fakeHealthChecker struct {
+	liveness  *types.HealthReport
+	readiness *types.HealthReport
+}
+
+func (f *fakeHealthChecker) CheckLiveness(context.Context) *types.HealthReport {
+	return f.liveness
+}
+
+func (f *fakeHealthChecker) CheckReadiness(context.Context) *types.HealthReport {
+	return f.readiness
+}
+
+func serveHealth(t *testing.T, health HealthChecker, target string) *httptest.ResponseRecorder {
+	server := New(newTestConfig(), mocks.NewDbInterface(t), nil, health, nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	return rec
+}
+
+func TestHealthProbes(t *testing.T) {
+	health := &fakeHealthChecker{
+		liveness: types.NewHealthReport([]types.HealthCheck{
+			{Name: "block_processor", Healthy: true, Message: "idle", Duration: time.Millisecond},
+		}),
+		readiness: types.NewHealthReport([]types.HealthCheck{
+			{Name: "mongodb", Healthy: true, Message: "reachable", Duration: 3 * time.Millisecond},
+			{Name: "bbn", Healthy: false, Message: "timed out after 2s", Duration: 2 * time.Second},
+		}),
+	}
+
+	rec := serveHealth(t, health, "/healthz")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"status":"ok","checks":[
+		{"name":"block_processor","status":"ok","message":"idle","duration_ms":1}
+	]}`, rec.Body.String())
+
+	rec = serveHealth(t, health, "/readyz")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"status":"unavailable","checks":[
+		{"name":"mongodb","status":"ok","message":"reachable","duration_ms":3},
+		{"name":"bbn","status":"unavailable","message":"timed out after 2s","duration_ms":2000}
+	]}`, rec.Body.String())
+}
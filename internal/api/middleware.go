@@ -0,0 +1,149 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/errorreporting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+const (
+	requestIDHeader = "X-Request-Id"
+	// maxRequestIDLength bounds the incoming request IDs, a longer one being
+	// replaced rather than logged
+	maxRequestIDLength = 128
+)
+
+// withRequestID assigns the request its ID, the incoming X-Request-Id if
+// valid, returns it in the response header and opens the logging scope of
+// the request, so that the lines logged while serving it carry the ID
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// isValidRequestID only accepts the IDs of printable ASCII characters, not
+// to let a client inject anything into the logs
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] <= ' ' || requestID[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	// crypto/rand does not fail on the supported platforms
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// accessLogWriter records the status and size of a response
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(body []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(body)
+	w.size += n
+	return n, err
+}
+
+// logAccess logs every request with the status, size and latency of its
+// response
+func logAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		recorder := &accessLogWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		logging.FromContext(r.Context()).Info().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(started)).
+			Int("size", recorder.size).
+			Msg("api request")
+	})
+}
+
+// recoverPanic turns a panic of a handler into a 500 with the error
+// envelope, logged with its stack, rather than a dropped connection
+func recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// The server aborts the response on purpose with this one
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			logging.FromContext(r.Context()).Error().
+				Interface("panic", recovered).
+				Bytes("stack", debug.Stack()).
+				Msg("api handler panicked")
+			errorreporting.Report(r.Context(), "api", fmt.Errorf("api handler panicked: %v", recovered), map[string]string{
+				"path": r.URL.Path, errorreporting.PanicTag: "true",
+			})
+			// The envelope can only be written if the handler did not start
+			// its response
+			if recorder, ok := w.(*accessLogWriter); ok && recorder.status != 0 {
+				return
+			}
+			writeResponse(w, http.StatusInternalServerError, errorEnvelope(w, types.NewErrorWithMsg(
+				http.StatusInternalServerError, types.InternalServiceError, "api handler panicked",
+			)))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// errorHandlerFunc is a handler returning its error rather than writing it
+type errorHandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// handleErrors writes the error envelope of the error returned by the
+// handler, a 500 unless a service error is found in its chain, so that every
+// handler fails with the same envelope
+func handleErrors(next errorHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := next(w, r); err != nil {
+			writeError(w, r, err)
+		}
+	}
+}
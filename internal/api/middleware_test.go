@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// serveWithMiddlewares serves the request through the middlewares of the
+// server and returns the response along with the lines logged
+func serveWithMiddlewares(
+	t *testing.T, handler http.HandlerFunc, requestID string,
+) (*httptest.ResponseRecorder, []map[string]any) {
+	var logs bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { log.Logger = previousLogger })
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/delegation", nil)
+	if requestID != "" {
+		req.Header.Set(requestIDHeader, requestID)
+	}
+	rec := httptest.NewRecorder()
+	withRequestID(logAccess(recoverPanic(handler))).ServeHTTP(rec, req)
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return rec, lines
+}
+
+func TestAccessLog(t *testing.T) {
+	rec, lines := serveWithMiddlewares(t, func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info().Msg("handling")
+		writeData(w, "ok")
+	}, "req-1")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "req-1", rec.Header().Get(requestIDHeader))
+	require.Len(t, lines, 2)
+	// the handler logs correlate with the access log line
+	require.Equal(t, "req-1", lines[0][logging.RequestIDField])
+	access := lines[1]
+	require.Equal(t, "req-1", access[logging.RequestIDField])
+	require.Equal(t, "api request", access["message"])
+	require.Equal(t, http.MethodGet, access["method"])
+	require.Equal(t, "/v1/delegation", access["path"])
+	require.Equal(t, float64(http.StatusOK), access["status"])
+	require.Equal(t, float64(rec.Body.Len()), access["size"])
+	require.Contains(t, access, "latency")
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	for _, incoming := range []string{"", "with space", strings.Repeat("a", maxRequestIDLength+1)} {
+		rec, lines := serveWithMiddlewares(t, func(w http.ResponseWriter, r *http.Request) {
+			writeData(w, "ok")
+		}, incoming)
+
+		requestID := rec.Header().Get(requestIDHeader)
+		require.Len(t, requestID, 32)
+		require.Equal(t, requestID, lines[0][logging.RequestIDField])
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	rec, lines := serveWithMiddlewares(t, func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}, "req-2")
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	var errResp types.ErrorEnvelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	require.Equal(t, types.ErrorEnvelope{
+		ErrorCode: types.InternalServiceError.String(),
+		Message:   "Internal service error",
+		RequestID: "req-2",
+	}, errResp)
+
+	panicked := lines[0]
+	require.Equal(t, "api handler panicked", panicked["message"])
+	require.Equal(t, "boom", panicked["panic"])
+	require.Equal(t, "req-2", panicked[logging.RequestIDField])
+	require.Contains(t, panicked["stack"], "TestRecoverPanic")
+	access := lines[len(lines)-1]
+	require.Equal(t, float64(http.StatusInternalServerError), access["status"])
+}
+
+func TestHandleErrorsEnvelope(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{
+			name:   "not_found",
+			err:    types.NewErrorWithMsg(http.StatusNotFound, types.NotFound, "delegation not found"),
+			status: http.StatusNotFound,
+		},
+		{
+			name: "wrapped_validation",
+			err: fmt.Errorf("parse query: %w",
+				types.NewValidationFailedError(errors.New("state is invalid")),
+			),
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "state_transition",
+			err: types.NewInternalServiceError(&db.StateTransitionError{
+				StakingTxHash: "tx", CurrentState: types.StateWithdrawn, TargetState: types.StateWithdrawable,
+			}),
+			status: http.StatusConflict,
+		},
+		{
+			name:   "internal",
+			err:    types.NewInternalServiceError(errors.New("connection refused")),
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "unknown",
+			err:    errors.New("connection refused"),
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, _ := serveWithMiddlewares(t, handleErrors(func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			}), "req-3")
+
+			require.Equal(t, tt.status, rec.Code)
+			golden, err := os.ReadFile(filepath.Join("testdata", "error_envelopes", tt.name+".json"))
+			require.NoError(t, err)
+			require.Equal(t, string(golden), rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	appparams "github.com/babylonlabs-io/babylon/app/params"
+	bbn "github.com/babylonlabs-io/babylon/types"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// finalityProviderStatePrefix prefixes the stored finality provider states,
+// left out of the API ones
+const finalityProviderStatePrefix = "FINALITY_PROVIDER_STATUS_"
+
+// checkQueryParams rejects the query parameters other than the allowed ones,
+// so that the typos of the clients do not go unnoticed
+func checkQueryParams(r *http.Request, allowed ...string) *types.Error {
+	for param := range r.URL.Query() {
+		if !slices.Contains(allowed, param) {
+			return types.NewValidationFailedError(fmt.Errorf("unknown query parameter %s", param))
+		}
+	}
+	return nil
+}
+
+// parseTxHashQuery returns the staking tx hash of the query parameter, which
+// must be set
+func parseTxHashQuery(r *http.Request, param string) (types.StakingTxHash, *types.Error) {
+	return parseTxHash(param, r.URL.Query().Get(param))
+}
+
+// parseTxHash validates the staking tx hash of the named argument and returns
+// it normalized
+func parseTxHash(param, txHashHex string) (types.StakingTxHash, *types.Error) {
+	if txHashHex == "" {
+		return "", types.NewValidationFailedError(fmt.Errorf("%s is required", param))
+	}
+
+	stakingTxHash, err := types.NewStakingTxHash(txHashHex)
+	if err != nil {
+		return "", types.NewValidationFailedError(fmt.Errorf("%s is invalid: %w", param, err))
+	}
+	return stakingTxHash, nil
+}
+
+// parseBtcPkQuery returns the BTC pk of the query parameter, empty if unset
+func parseBtcPkQuery(r *http.Request, param string) (string, *types.Error) {
+	pkHex := r.URL.Query().Get(param)
+	if pkHex == "" {
+		return "", nil
+	}
+
+	pk, err := bbn.NewBIP340PubKeyFromHex(pkHex)
+	if err != nil {
+		return "", types.NewValidationFailedError(fmt.Errorf("%s is not a valid BTC public key", param))
+	}
+
+	return pk.MarshalHex(), nil
+}
+
+// parseBabylonAddressQuery returns the BBN address of the query parameter,
+// empty if unset
+func parseBabylonAddressQuery(r *http.Request, param string) (string, *types.Error) {
+	address := r.URL.Query().Get(param)
+	if address == "" {
+		return "", nil
+	}
+
+	hrp, _, err := bech32.DecodeAndConvert(address)
+	if err != nil || hrp != appparams.Bech32PrefixAccAddr {
+		return "", types.NewValidationFailedError(fmt.Errorf("%s is not a valid Babylon address", param))
+	}
+
+	return address, nil
+}
+
+// parseStateQuery returns the delegation state of the query parameter, which
+// is case insensitive, empty if unset
+func parseStateQuery(r *http.Request, param string) (types.DelegationState, *types.Error) {
+	stateStr := r.URL.Query().Get(param)
+	if stateStr == "" {
+		return "", nil
+	}
+
+	state := types.DelegationState(strings.ToUpper(stateStr))
+	switch state {
+	case types.StatePending, types.StateVerified, types.StateActive, types.StateUnbonding,
+		types.StateWithdrawable, types.StateWithdrawn, types.StateSlashed:
+		return state, nil
+	default:
+		return "", types.NewValidationFailedError(fmt.Errorf("%s is not a valid delegation state", param))
+	}
+}
+
+// parseUint32Query returns the number of the query parameter, nil if unset
+func parseUint32Query(r *http.Request, param string) (*uint32, *types.Error) {
+	value := r.URL.Query().Get(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, types.NewValidationFailedError(fmt.Errorf("%s is not a valid number", param))
+	}
+
+	n32 := uint32(n)
+	return &n32, nil
+}
+
+// parseBoolQuery returns the boolean of the query parameter, false if unset
+func parseBoolQuery(r *http.Request, param string) (bool, *types.Error) {
+	value := r.URL.Query().Get(param)
+	if value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, types.NewValidationFailedError(fmt.Errorf("%s is not a valid boolean", param))
+	}
+
+	return b, nil
+}
+
+// parseFinalityProviderStateQuery returns the stored finality provider state
+// of the query parameter, which is case insensitive, empty if unset
+func parseFinalityProviderStateQuery(r *http.Request, param string) (string, *types.Error) {
+	stateStr := r.URL.Query().Get(param)
+	if stateStr == "" {
+		return "", nil
+	}
+
+	state := finalityProviderStatePrefix + strings.ToUpper(stateStr)
+	if _, ok := bbntypes.FinalityProviderStatus_value[state]; !ok {
+		return "", types.NewValidationFailedError(fmt.Errorf("%s is not a valid finality provider state", param))
+	}
+
+	return state, nil
+}
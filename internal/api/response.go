@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/errorreporting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// publicResponse wraps the data of a successful response
+type publicResponse[T any] struct {
+	Data       T                   `json:"data"`
+	Pagination *paginationResponse `json:"pagination,omitempty"`
+}
+
+// paginationResponse holds the key of the next page, empty on the last one
+type paginationResponse struct {
+	NextKey string `json:"next_key"`
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error().Err(err).Msg("failed to write api response")
+	}
+}
+
+func writeData[T any](w http.ResponseWriter, data T) {
+	writeResponse(w, http.StatusOK, publicResponse[T]{Data: data})
+}
+
+func writePage[T any](w http.ResponseWriter, data []T, nextKey string) {
+	writeResponse(w, http.StatusOK, publicResponse[[]T]{
+		Data:       data,
+		Pagination: &paginationResponse{NextKey: nextKey},
+	})
+}
+
+// writeError writes the error envelope of the error, a service error found
+// in its chain or a 500 otherwise. The internal errors are logged with the
+// logger of the request and reported rather than returned, their envelope
+// carrying the request ID instead.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var serviceErr *types.Error
+	if !errors.As(err, &serviceErr) {
+		serviceErr = types.NewInternalServiceError(err)
+	}
+	// A state update rejected by the delegation state conflicts with it,
+	// whatever the error the handler wrapped it in
+	if db.IsStateTransitionError(err) && serviceErr.StatusCode != http.StatusConflict {
+		serviceErr = types.NewError(http.StatusConflict, types.Conflict, serviceErr.Err)
+	}
+
+	if serviceErr.StatusCode >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error().Err(err).Msg("api request failed")
+		errorreporting.Report(r.Context(), "api", err, map[string]string{"path": r.URL.Path})
+	}
+	writeResponse(w, serviceErr.StatusCode, errorEnvelope(w, serviceErr))
+}
+
+// errorEnvelope returns the envelope of the error, along with the ID of the
+// request answered with a 5xx
+func errorEnvelope(w http.ResponseWriter, err *types.Error) types.ErrorEnvelope {
+	envelope := err.Envelope()
+	if err.StatusCode >= http.StatusInternalServerError {
+		envelope.RequestID = w.Header().Get(requestIDHeader)
+	}
+	return envelope
+}
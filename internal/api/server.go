@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+)
+
+// Server serves the indexed data over HTTP, so that it does not need to be
+// queried from the database directly
+type Server struct {
+	cfg        *config.APIConfig
+	httpServer *http.Server
+}
+
+func New(
+	cfg *config.APIConfig,
+	db db.DbInterface,
+	stats GlobalStatsComputer,
+	health HealthChecker,
+	admin DelegationReprocessor,
+	indexing IndexingController,
+	leadership LeadershipChecker,
+) *Server {
+	handler := &handler{
+		cfg: cfg, db: db, stats: stats, health: health, admin: admin, indexing: indexing, leadership: leadership,
+	}
+
+	router := chi.NewRouter()
+	// the panics are recovered within the access log, so that it records
+	// the 500 they turn into
+	router.Use(withRequestID, logAccess, recoverPanic)
+	router.Get("/healthz", handler.getLiveness)
+	router.Get("/readyz", handler.getReadiness)
+	router.Get("/v1/delegation", handleErrors(handler.getDelegation))
+	router.Get("/v1/delegation/history", handleErrors(handler.getDelegationHistory))
+	router.Get("/v1/staker/delegations", handleErrors(handler.getStakerDelegations))
+	router.Get("/v1/finality-providers", handleErrors(handler.getFinalityProviders))
+	router.Get("/v1/finality-providers/{btc_pk}", handleErrors(handler.getFinalityProvider))
+	router.Get("/v1/withdrawable", handleErrors(handler.getWithdrawableDelegations))
+	router.Get("/v1/stats", handleErrors(handler.getGlobalStats))
+	router.Get("/v1/params/staking", handleErrors(handler.getStakingParams))
+	router.Get("/v1/params/staking/versions", handleErrors(handler.getStakingParamsVersions))
+	router.Get("/v1/params/checkpoint", handleErrors(handler.getCheckpointParams))
+	if cfg.IsAdminEnabled() {
+		router.Group(func(router chi.Router) {
+			router.Use(handler.requireAdmin)
+			// The admin actions are applied by the leader, a standby
+			// refusing them
+			router.Group(func(router chi.Router) {
+				router.Use(handler.requireLeader)
+				router.Post("/admin/v1/delegation/reprocess", handleErrors(handler.reprocessDelegation))
+				router.Post("/admin/v1/indexing/pause", handleErrors(handler.pauseIndexing))
+				router.Post("/admin/v1/indexing/resume", handleErrors(handler.resumeIndexing))
+				router.Put("/admin/v1/log-level", handleErrors(handler.setLogLevel))
+			})
+			// The profiles are served at the path the pprof handlers expect,
+			// a CPU profile or trace longer than the request timeout being
+			// refused by them
+			if cfg.Pprof {
+				router.HandleFunc("/debug/pprof/*", pprof.Index)
+				router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+				router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+				router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+				router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+			}
+		})
+	}
+
+	return &Server{
+		cfg: cfg,
+		httpServer: &http.Server{
+			Addr:         cfg.ListenAddress,
+			Handler:      router,
+			ReadTimeout:  cfg.RequestTimeout,
+			WriteTimeout: cfg.RequestTimeout,
+			IdleTimeout:  2 * cfg.RequestTimeout,
+		},
+	}
+}
+
+// Run serves the requests until the context is cancelled, then shuts the
+// server down, waiting for the in-flight requests up to the shutdown timeout
+func (s *Server) Run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info().Str("address", s.cfg.ListenAddress).Msg("starting api server")
+		serveErr <- s.httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("api server exited: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down api server: %w", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("api server exited: %w", err)
+	}
+
+	log.Info().Msg("api server stopped")
+	return nil
+}
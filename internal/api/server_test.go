@@ -0,0 +1,27 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+func TestServerStopsWithContext(t *testing.T) {
+	server := New(newTestConfig(), mocks.NewDbInterface(t), nil, nil, nil, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Run(ctx) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("api server did not stop")
+	}
+}
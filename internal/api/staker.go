@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// DelegationTxHexesPublic holds the transactions of a delegation, which are
+// only returned on request as they make up most of its size
+type DelegationTxHexesPublic struct {
+	StakingTxHex           string `json:"staking_tx_hex"`
+	UnbondingTxHex         string `json:"unbonding_tx_hex"`
+	SlashingTxHex          string `json:"slashing_tx_hex,omitempty"`
+	UnbondingSlashingTxHex string `json:"unbonding_slashing_tx_hex,omitempty"`
+}
+
+type StakerDelegationPublic struct {
+	DelegationPublic
+	*DelegationTxHexesPublic
+}
+
+// newStakerDelegationPublic returns the delegation along with its
+// transactions
+func newStakerDelegationPublic(delegation *model.BTCDelegationDetails) StakerDelegationPublic {
+	return StakerDelegationPublic{
+		DelegationPublic: newDelegationPublic(delegation.Summary()),
+		DelegationTxHexesPublic: &DelegationTxHexesPublic{
+			StakingTxHex:           delegation.StakingTxHex,
+			UnbondingTxHex:         delegation.UnbondingTx,
+			SlashingTxHex:          delegation.SlashingTx.SlashingTxHex,
+			UnbondingSlashingTxHex: delegation.SlashingTx.UnbondingSlashingTxHex,
+		},
+	}
+}
+
+// getStakerDelegations returns a page of the delegations of the staker given
+// by either its BTC pk or its Babylon address, newest first
+func (h *handler) getStakerDelegations(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(
+		r, "staker_btc_pk", "staker_babylon_address", "state", "include_tx_hex", "pagination_key",
+	); err != nil {
+		return err
+	}
+
+	filter, includeTxHex, err := h.parseStakerDelegationsQuery(r)
+	if err != nil {
+		return err
+	}
+
+	paginationKey := r.URL.Query().Get("pagination_key")
+	if !includeTxHex {
+		// The delegations are read without their transactions, which make
+		// up most of their size
+		result, dbErr := h.db.GetStakerDelegationSummaries(r.Context(), filter, paginationKey, h.cfg.MaxPageSize)
+		if dbErr != nil {
+			return stakerDelegationsError(dbErr)
+		}
+		delegations := make([]StakerDelegationPublic, 0, len(result.Data))
+		for _, summary := range result.Data {
+			delegations = append(delegations, StakerDelegationPublic{DelegationPublic: newDelegationPublic(summary)})
+		}
+		writePage(w, delegations, result.PaginationToken)
+		return nil
+	}
+
+	result, dbErr := h.db.GetStakerDelegations(r.Context(), filter, paginationKey, h.cfg.MaxPageSize)
+	if dbErr != nil {
+		return stakerDelegationsError(dbErr)
+	}
+	delegations := make([]StakerDelegationPublic, 0, len(result.Data))
+	for _, delegation := range result.Data {
+		delegations = append(delegations, newStakerDelegationPublic(delegation))
+	}
+	writePage(w, delegations, result.PaginationToken)
+	return nil
+}
+
+func stakerDelegationsError(dbErr error) *types.Error {
+	if db.IsInvalidPaginationTokenError(dbErr) {
+		return types.NewValidationFailedError(errors.New("pagination_key is invalid"))
+	}
+	return types.NewInternalServiceError(
+		fmt.Errorf("failed to get staker delegations: %w", dbErr),
+	)
+}
+
+func (h *handler) parseStakerDelegationsQuery(
+	r *http.Request,
+) (db.StakerDelegationsFilter, bool, *types.Error) {
+	var filter db.StakerDelegationsFilter
+
+	stakerBtcPkHex, err := parseBtcPkQuery(r, "staker_btc_pk")
+	if err != nil {
+		return filter, false, err
+	}
+	stakerBabylonAddress, err := parseBabylonAddressQuery(r, "staker_babylon_address")
+	if err != nil {
+		return filter, false, err
+	}
+	switch {
+	case stakerBtcPkHex == "" && stakerBabylonAddress == "":
+		return filter, false, types.NewValidationFailedError(
+			errors.New("either staker_btc_pk or staker_babylon_address is required"),
+		)
+	case stakerBtcPkHex != "" && stakerBabylonAddress != "":
+		return filter, false, types.NewValidationFailedError(
+			errors.New("staker_btc_pk and staker_babylon_address are mutually exclusive"),
+		)
+	}
+
+	state, err := parseStateQuery(r, "state")
+	if err != nil {
+		return filter, false, err
+	}
+	includeTxHex, err := parseBoolQuery(r, "include_tx_hex")
+	if err != nil {
+		return filter, false, err
+	}
+
+	filter = db.StakerDelegationsFilter{
+		StakerBtcPkHex:       stakerBtcPkHex,
+		StakerBabylonAddress: stakerBabylonAddress,
+		State:                state,
+	}
+	return filter, includeTxHex, nil
+}
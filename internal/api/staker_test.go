@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+// testStakerBtcPkHex is the x-only key of the secp256k1 generator
+const testStakerBtcPkHex = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+func testStakerBabylonAddress(t *testing.T) string {
+	address, err := bech32.ConvertAndEncode("bbn", make([]byte, 20))
+	require.NoError(t, err)
+	return address
+}
+
+func testStakerDelegation() *model.BTCDelegationDetails {
+	return &model.BTCDelegationDetails{
+		StakingTxHashHex: testStakingTxHashHex,
+		StakingTxHex:     "staking-tx",
+		StakerBtcPkHex:   testStakerBtcPkHex,
+		State:            types.StateActive,
+		UnbondingTx:      "unbonding-tx",
+		SlashingTx:       model.SlashingTx{SlashingTxHex: "slashing-tx"},
+	}
+}
+
+func TestGetStakerDelegations(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	// the delegations are read without their transactions by default
+	dbMock.On("GetStakerDelegationSummaries", mock.Anything, db.StakerDelegationsFilter{
+		StakerBtcPkHex: testStakerBtcPkHex,
+		State:          types.StateActive,
+	}, "page-2", int64(testMaxPageSize)).Return(
+		&db.DbResultMap[*model.BTCDelegationSummary]{
+			Data:            []*model.BTCDelegationSummary{testStakerDelegation().Summary()},
+			PaginationToken: "page-3",
+		}, nil,
+	)
+
+	rec, _ := serve(t, dbMock,
+		"/v1/staker/delegations?staker_btc_pk="+testStakerBtcPkHex+"&state=active&pagination_key=page-2",
+	)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp publicResponse[[]map[string]any]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "page-3", resp.Pagination.NextKey)
+	require.Len(t, resp.Data, 1)
+	require.Equal(t, testStakingTxHashHex, resp.Data[0]["staking_tx_hash_hex"])
+	require.Equal(t, types.StateActive.String(), resp.Data[0]["state"])
+	// The tx hexes are left out by default
+	require.NotContains(t, resp.Data[0], "staking_tx_hex")
+	require.NotContains(t, resp.Data[0], "unbonding_tx_hex")
+}
+
+func TestGetStakerDelegationsWithTxHex(t *testing.T) {
+	address := testStakerBabylonAddress(t)
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetStakerDelegations", mock.Anything, db.StakerDelegationsFilter{
+		StakerBabylonAddress: address,
+	}, "", int64(testMaxPageSize)).Return(
+		&db.DbResultMap[*model.BTCDelegationDetails]{
+			Data: []*model.BTCDelegationDetails{testStakerDelegation()},
+		}, nil,
+	)
+
+	rec, _ := serve(t, dbMock, "/v1/staker/delegations?staker_babylon_address="+address+"&include_tx_hex=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp publicResponse[[]StakerDelegationPublic]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Empty(t, resp.Pagination.NextKey)
+	require.Len(t, resp.Data, 1)
+	require.Equal(t, &DelegationTxHexesPublic{
+		StakingTxHex:   "staking-tx",
+		UnbondingTxHex: "unbonding-tx",
+		SlashingTxHex:  "slashing-tx",
+	}, resp.Data[0].DelegationTxHexesPublic)
+}
+
+func TestGetStakerDelegationsInvalidPaginationKey(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetStakerDelegationSummaries", mock.Anything, mock.Anything, "garbage", mock.Anything).Return(
+		nil, &db.InvalidPaginationTokenError{Message: "invalid pagination token"},
+	)
+
+	rec, errResp := serve(t, dbMock,
+		"/v1/staker/delegations?staker_btc_pk="+testStakerBtcPkHex+"&pagination_key=garbage",
+	)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+}
+
+func TestGetStakerDelegationsValidation(t *testing.T) {
+	address := testStakerBabylonAddress(t)
+	otherPrefixAddress, err := bech32.ConvertAndEncode("cosmos", make([]byte, 20))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"no staker", ""},
+		{"both stakers", "?staker_btc_pk=" + testStakerBtcPkHex + "&staker_babylon_address=" + address},
+		{"invalid btc pk", "?staker_btc_pk=" + strings.Repeat("ab", 31)},
+		{"invalid address", "?staker_babylon_address=bbn1invalid"},
+		{"other chain address", "?staker_babylon_address=" + otherPrefixAddress},
+		{"invalid state", "?staker_btc_pk=" + testStakerBtcPkHex + "&state=bonded"},
+		{"invalid include_tx_hex", "?staker_btc_pk=" + testStakerBtcPkHex + "&include_tx_hex=maybe"},
+		{"unknown parameter", "?staker_btc_pk=" + testStakerBtcPkHex + "&page_size=5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The db is not queried
+			rec, errResp := serve(t, mocks.NewDbInterface(t), "/v1/staker/delegations"+tc.query)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// GlobalStatsComputer computes the global stats live, when the maintained
+// document cannot be served
+type GlobalStatsComputer interface {
+	ComputeGlobalStats(ctx context.Context) (*model.GlobalStats, *types.Error)
+}
+
+type GlobalStatsPublic struct {
+	ActiveTvl                uint64            `json:"active_tvl"`
+	DelegationsByState       map[string]uint64 `json:"delegations_by_state"`
+	FinalityProvidersByState map[string]uint64 `json:"finality_providers_by_state"`
+	LastProcessedBbnHeight   uint64            `json:"last_processed_bbn_height"`
+	BbnTipHeight             uint64            `json:"bbn_tip_height"`
+	BbnLag                   uint64            `json:"bbn_lag"`
+	LastProcessedBtcHeight   uint64            `json:"last_processed_btc_height"`
+	BtcTipHeight             uint64            `json:"btc_tip_height"`
+	BtcLag                   uint64            `json:"btc_lag"`
+	UpdatedAt                int64             `json:"updated_at"`
+}
+
+func newGlobalStatsPublic(stats *model.GlobalStats) GlobalStatsPublic {
+	fpsByState := make(map[string]uint64, len(stats.FinalityProvidersByState))
+	for state, count := range stats.FinalityProvidersByState {
+		fpsByState[strings.TrimPrefix(state, finalityProviderStatePrefix)] = count
+	}
+	delegationsByState := stats.DelegationsByState
+	if delegationsByState == nil {
+		delegationsByState = map[string]uint64{}
+	}
+
+	return GlobalStatsPublic{
+		ActiveTvl:                stats.ActiveTvl,
+		DelegationsByState:       delegationsByState,
+		FinalityProvidersByState: fpsByState,
+		LastProcessedBbnHeight:   stats.LastProcessedBbnHeight,
+		BbnTipHeight:             stats.BbnTipHeight,
+		BbnLag:                   stats.BbnLag,
+		LastProcessedBtcHeight:   stats.LastProcessedBtcHeight,
+		BtcTipHeight:             stats.BtcTipHeight,
+		BtcLag:                   stats.BtcLag,
+		UpdatedAt:                stats.UpdatedAt,
+	}
+}
+
+// getGlobalStats returns the global stats document, cacheable for a few
+// seconds as dashboards poll it
+func (h *handler) getGlobalStats(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r); err != nil {
+		return err
+	}
+
+	stats, err := h.getGlobalStatsDocument(r.Context())
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.cfg.StatsCacheMaxAge.Seconds())))
+	writeData(w, newGlobalStatsPublic(stats))
+	return nil
+}
+
+// getGlobalStatsDocument returns the maintained global stats document,
+// falling back to a live aggregation if it is missing or stale
+func (h *handler) getGlobalStatsDocument(ctx context.Context) (*model.GlobalStats, *types.Error) {
+	stats, dbErr := h.db.GetGlobalStats(ctx)
+	switch {
+	case db.IsNotFoundError(dbErr):
+		logging.FromContext(ctx).Warn().Msg("global stats document missing, aggregating the stats live")
+	case dbErr != nil:
+		return nil, types.NewInternalServiceError(fmt.Errorf("failed to get global stats: %w", dbErr))
+	default:
+		age := time.Since(time.Unix(stats.UpdatedAt, 0))
+		if age <= h.cfg.StatsMaxAge {
+			return stats, nil
+		}
+		logging.FromContext(ctx).Warn().
+			Dur("age", age).
+			Msg("global stats document stale, aggregating the stats live")
+	}
+
+	return h.stats.ComputeGlobalStats(ctx)
+}
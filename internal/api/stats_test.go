@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+// fakeStatsComputer counts the live aggregations
+type fakeStatsComputer struct {
+	stats *model.GlobalStats
+	calls int
+}
+
+func (c *fakeStatsComputer) ComputeGlobalStats(ctx context.Context) (*model.GlobalStats, *types.Error) {
+	c.calls++
+	return c.stats, nil
+}
+
+func testGlobalStats(updatedAt time.Time) *model.GlobalStats {
+	return &model.GlobalStats{
+		Id:                       model.GlobalStatsId,
+		ActiveTvl:                5000,
+		DelegationsByState:       map[string]uint64{"ACTIVE": 2, "PENDING": 1},
+		FinalityProvidersByState: map[string]uint64{"FINALITY_PROVIDER_STATUS_ACTIVE": 3},
+		LastProcessedBbnHeight:   90,
+		BbnTipHeight:             100,
+		BbnLag:                   10,
+		LastProcessedBtcHeight:   800,
+		BtcTipHeight:             801,
+		BtcLag:                   1,
+		UpdatedAt:                updatedAt.Unix(),
+	}
+}
+
+func TestGetGlobalStats(t *testing.T) {
+	stats := testGlobalStats(time.Now())
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetGlobalStats", mock.Anything).Return(stats, nil)
+	computer := &fakeStatsComputer{}
+
+	rec, _ := serveWithStats(t, dbMock, computer, "/v1/stats")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "public, max-age=5", rec.Header().Get("Cache-Control"))
+	require.Zero(t, computer.calls)
+
+	var resp publicResponse[GlobalStatsPublic]
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, GlobalStatsPublic{
+		ActiveTvl:                5000,
+		DelegationsByState:       map[string]uint64{"ACTIVE": 2, "PENDING": 1},
+		FinalityProvidersByState: map[string]uint64{"ACTIVE": 3},
+		LastProcessedBbnHeight:   90,
+		BbnTipHeight:             100,
+		BbnLag:                   10,
+		LastProcessedBtcHeight:   800,
+		BtcTipHeight:             801,
+		BtcLag:                   1,
+		UpdatedAt:                stats.UpdatedAt,
+	}, resp.Data)
+}
+
+func TestGetGlobalStatsFallsBackToLiveAggregation(t *testing.T) {
+	testCases := []struct {
+		name   string
+		stored *model.GlobalStats
+		err    error
+	}{
+		{"missing", nil, &db.NotFoundError{Key: model.GlobalStatsId, Message: "not found"}},
+		{"stale", testGlobalStats(time.Now().Add(-time.Hour)), nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dbMock := mocks.NewDbInterface(t)
+			dbMock.On("GetGlobalStats", mock.Anything).Return(tc.stored, tc.err)
+			live := testGlobalStats(time.Now())
+			live.BbnLag = 42
+			computer := &fakeStatsComputer{stats: live}
+
+			rec, _ := serveWithStats(t, dbMock, computer, "/v1/stats")
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, 1, computer.calls)
+
+			var resp publicResponse[GlobalStatsPublic]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Equal(t, uint64(42), resp.Data.BbnLag)
+		})
+	}
+}
+
+func TestGetGlobalStatsDbError(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetGlobalStats", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	rec, errResp := serveWithStats(t, dbMock, &fakeStatsComputer{}, "/v1/stats")
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Empty(t, rec.Header().Get("Cache-Control"))
+	require.Equal(t, types.InternalServiceError.String(), errResp.ErrorCode)
+}
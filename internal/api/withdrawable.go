@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+type WithdrawableDelegationPublic struct {
+	StakingTxHashHex string `json:"staking_tx_hash_hex"`
+	StakerBtcPkHex   string `json:"staker_btc_pk_hex"`
+	StakingAmount    uint64 `json:"staking_amount"`
+	SubState         string `json:"sub_state"`
+	// WithdrawableHeight is the BTC height from which the delegation can be
+	// withdrawn
+	WithdrawableHeight uint32 `json:"withdrawable_height"`
+}
+
+func newWithdrawableDelegationPublic(timeLock *model.ExpiringTimeLock) WithdrawableDelegationPublic {
+	return WithdrawableDelegationPublic{
+		StakingTxHashHex:   timeLock.StakingTxHashHex,
+		StakerBtcPkHex:     timeLock.StakerBtcPkHex,
+		StakingAmount:      timeLock.StakingAmount,
+		SubState:           timeLock.DelegationSubState.String(),
+		WithdrawableHeight: timeLock.ExpireHeight,
+	}
+}
+
+// getWithdrawableDelegations returns a page of the delegations becoming
+// withdrawable in the BTC height range given by the from_btc_height and
+// to_btc_height query parameters, sorted by withdrawable height
+func (h *handler) getWithdrawableDelegations(w http.ResponseWriter, r *http.Request) error {
+	if err := checkQueryParams(r, "from_btc_height", "to_btc_height", "pagination_key"); err != nil {
+		return err
+	}
+
+	fromHeight, err := parseUint32Query(r, "from_btc_height")
+	if err != nil {
+		return err
+	}
+	toHeight, err := parseUint32Query(r, "to_btc_height")
+	if err != nil {
+		return err
+	}
+	if fromHeight == nil || toHeight == nil {
+		return types.NewValidationFailedError(
+			errors.New("from_btc_height and to_btc_height are required"),
+		)
+	}
+	if *fromHeight > *toHeight {
+		return types.NewValidationFailedError(
+			errors.New("from_btc_height must not be above to_btc_height"),
+		)
+	}
+	// Bounded so that the whole collection cannot be listed at once
+	if *toHeight-*fromHeight >= h.cfg.MaxBtcHeightWindow {
+		return types.NewValidationFailedError(
+			fmt.Errorf("the BTC height range must not exceed %d blocks", h.cfg.MaxBtcHeightWindow),
+		)
+	}
+
+	paginationKey := r.URL.Query().Get("pagination_key")
+	result, dbErr := h.db.GetTimeLocksExpiringBetween(
+		r.Context(), *fromHeight, *toHeight, paginationKey, h.cfg.MaxPageSize,
+	)
+	if dbErr != nil {
+		if db.IsInvalidPaginationTokenError(dbErr) {
+			return types.NewValidationFailedError(errors.New("pagination_key is invalid"))
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get the timelocks expiring between %d and %d: %w", *fromHeight, *toHeight, dbErr),
+		)
+	}
+
+	delegations := make([]WithdrawableDelegationPublic, 0, len(result.Data))
+	for _, timeLock := range result.Data {
+		delegations = append(delegations, newWithdrawableDelegationPublic(timeLock))
+	}
+
+	writePage(w, delegations, result.PaginationToken)
+	return nil
+}
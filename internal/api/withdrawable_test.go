@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+func TestGetWithdrawableDelegations(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetTimeLocksExpiringBetween", mock.Anything, uint32(100), uint32(200), "page-2", int64(testMaxPageSize)).Return(
+		&db.DbResultMap[*model.ExpiringTimeLock]{
+			Data: []*model.ExpiringTimeLock{{
+				StakingTxHashHex:   testStakingTxHashHex,
+				ExpireHeight:       150,
+				DelegationSubState: types.SubStateEarlyUnbonding,
+				StakerBtcPkHex:     "staker",
+				StakingAmount:      1000,
+			}},
+			PaginationToken: "page-3",
+		}, nil,
+	)
+
+	rec, _ := serve(t, dbMock, "/v1/withdrawable?from_btc_height=100&to_btc_height=200&pagination_key=page-2")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{
+		"data":[{
+			"staking_tx_hash_hex":"`+testStakingTxHashHex+`",
+			"staker_btc_pk_hex":"staker",
+			"staking_amount":1000,
+			"sub_state":"EARLY_UNBONDING",
+			"withdrawable_height":150
+		}],
+		"pagination":{"next_key":"page-3"}
+	}`, rec.Body.String())
+}
+
+func TestGetWithdrawableDelegationsMaxWindow(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetTimeLocksExpiringBetween", mock.Anything, uint32(0), uint32(9999), "", int64(testMaxPageSize)).Return(
+		&db.DbResultMap[*model.ExpiringTimeLock]{}, nil,
+	)
+
+	rec, _ := serve(t, dbMock, "/v1/withdrawable?from_btc_height=0&to_btc_height=9999")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"data":[],"pagination":{"next_key":""}}`, rec.Body.String())
+}
+
+func TestGetWithdrawableDelegationsValidation(t *testing.T) {
+	for name, query := range map[string]string{
+		"missing from":     "?to_btc_height=200",
+		"missing to":       "?from_btc_height=100",
+		"reversed range":   "?from_btc_height=200&to_btc_height=100",
+		"range too wide":   "?from_btc_height=0&to_btc_height=10000",
+		"invalid height":   "?from_btc_height=abc&to_btc_height=100",
+		"unknown argument": "?from_btc_height=100&to_btc_height=200&limit=5",
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec, errResp := serve(t, mocks.NewDbInterface(t), "/v1/withdrawable"+query)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+		})
+	}
+}
+
+func TestGetWithdrawableDelegationsInvalidPaginationKey(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetTimeLocksExpiringBetween", mock.Anything, uint32(100), uint32(200), "bad", int64(testMaxPageSize)).Return(
+		nil, &db.InvalidPaginationTokenError{Message: "invalid pagination token"},
+	)
+
+	rec, errResp := serve(t, dbMock, "/v1/withdrawable?from_btc_height=100&to_btc_height=200&pagination_key=bad")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, types.ValidationError.String(), errResp.ErrorCode)
+}
@@ -0,0 +1,101 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	proto "github.com/cosmos/gogoproto/proto"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+const (
+	benchBlockHeight = 1000
+	// benchBlockDelegations are the delegations signed by the covenant
+	// committee in the fixture block, each by benchCovenantMembers members
+	benchBlockDelegations = 100
+	benchCovenantMembers  = 3
+	// benchBlockFinalityProviders are the finality providers edited in the
+	// fixture block
+	benchBlockFinalityProviders = 50
+)
+
+// fixtureBlock returns the results of a BBN block of finality provider edits
+// and of covenant signatures of the delegations, a tx of the covenant
+// committee carrying the signatures of a member for every delegation
+func fixtureBlock(b *testing.B, stakingTxHashes, fpBtcPks []string) *ctypes.ResultBlockResults {
+	newTx := func(events ...proto.Message) *abcitypes.ExecTxResult {
+		tx := &abcitypes.ExecTxResult{}
+		for _, event := range events {
+			sdkEvent, err := sdk.TypedEventToEvent(event)
+			require.NoError(b, err)
+			tx.Events = append(tx.Events, abcitypes.Event(sdkEvent))
+		}
+		return tx
+	}
+
+	var txs []*abcitypes.ExecTxResult
+	for _, fpBtcPk := range fpBtcPks {
+		txs = append(txs, newTx(&bbntypes.EventFinalityProviderEdited{BtcPkHex: fpBtcPk, Moniker: "edited"}))
+	}
+	for member := 0; member < benchCovenantMembers; member++ {
+		var events []proto.Message
+		for _, stakingTxHash := range stakingTxHashes {
+			events = append(events, &bbntypes.EventCovenantSignatureReceived{
+				StakingTxHash:                 stakingTxHash,
+				CovenantBtcPkHex:              fmt.Sprintf("covenant-%d", member),
+				CovenantUnbondingSignatureHex: fmt.Sprintf("sig-%d", member),
+			})
+		}
+		txs = append(txs, newTx(events...))
+	}
+	return &ctypes.ResultBlockResults{Height: benchBlockHeight, TxsResults: txs}
+}
+
+// BenchmarkBbnBlock processes the events of the fixture block through the
+// backfill of its height, which applies them as the block processor does
+func BenchmarkBbnBlock(b *testing.B) {
+	env := newBenchEnv(b)
+	ctx := context.Background()
+	stakingTxHashes := benchStakingTxHashes()[:benchBlockDelegations]
+	fpBtcPks := make([]string, benchBlockFinalityProviders)
+	fps := make([]interface{}, benchBlockFinalityProviders)
+	for i := range fpBtcPks {
+		fpBtcPks[i] = fmt.Sprintf("fp-%d", i)
+		fps[i] = &model.FinalityProviderDetails{BtcPk: fpBtcPks[i], Description: model.Description{Moniker: "fp"}}
+	}
+	block := fixtureBlock(b, stakingTxHashes, fpBtcPks)
+
+	bbnMock := mocks.NewBbnInterface(b)
+	bbnMock.On("GetBlockResults", mock.Anything, mock.Anything).Return(block, nil)
+	service := services.NewService(
+		&config.Config{BBN: config.BBNConfig{CovenantSignatureWorkers: config.DefaultBbnCovenantSignatureWorkers}},
+		env.database, nil, nil, bbnMock, nil,
+	)
+	req := services.BackfillRequest{FromHeight: benchBlockHeight, ToHeight: benchBlockHeight, Concurrency: 1}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		env.seed(b, map[string][]interface{}{
+			model.BTCDelegationDetailsCollection:    benchDelegations(stakingTxHashes, types.StateVerified),
+			model.FinalityProviderDetailsCollection: fps,
+		})
+		b.StartTimer()
+		summary, err := service.Backfill(ctx, req)
+		require.Nil(b, err)
+		require.Equal(b, uint64(1), summary.ProcessedHeights)
+	}
+}
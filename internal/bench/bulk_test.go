@@ -0,0 +1,93 @@
+package bench
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// seedPendingDelegations replaces the stored delegations with pending ones
+func seedPendingDelegations(b *testing.B, env *benchEnv, stakingTxHashes []string) {
+	env.seed(b, map[string][]interface{}{
+		model.BTCDelegationDetailsCollection: benchDelegations(stakingTxHashes, types.StatePending),
+	})
+}
+
+func BenchmarkUpdateDelegationStates(b *testing.B) {
+	env := newBenchEnv(b)
+	stakingTxHashes := benchStakingTxHashes()
+	ctx := context.Background()
+	pending := []types.DelegationState{types.StatePending}
+
+	b.Run("one_by_one", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			seedPendingDelegations(b, env, stakingTxHashes)
+			b.StartTimer()
+			for _, stakingTxHash := range stakingTxHashes {
+				err := env.database.UpdateBTCDelegationState(ctx, stakingTxHash, pending, types.StateVerified, nil)
+				require.NoError(b, err)
+			}
+		}
+	})
+
+	b.Run("bulk", func(b *testing.B) {
+		b.ReportAllocs()
+		updates := make([]db.DelegationStateUpdate, len(stakingTxHashes))
+		for i, stakingTxHash := range stakingTxHashes {
+			updates[i] = db.DelegationStateUpdate{
+				StakingTxHash: stakingTxHash, QualifiedPreviousStates: pending, NewState: types.StateVerified,
+			}
+		}
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			seedPendingDelegations(b, env, stakingTxHashes)
+			b.StartTimer()
+			require.NoError(b, env.database.BulkUpdateDelegationStates(ctx, updates))
+		}
+	})
+}
+
+func BenchmarkSaveCovenantSignatures(b *testing.B) {
+	env := newBenchEnv(b)
+	stakingTxHashes := benchStakingTxHashes()
+	ctx := context.Background()
+	const covenantBtcPkHex = "covenant"
+
+	b.Run("one_by_one", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			seedPendingDelegations(b, env, stakingTxHashes)
+			b.StartTimer()
+			for _, stakingTxHash := range stakingTxHashes {
+				err := env.database.SaveBTCDelegationUnbondingCovenantSignature(
+					ctx, stakingTxHash, covenantBtcPkHex, "sig",
+				)
+				require.NoError(b, err)
+			}
+		}
+	})
+
+	b.Run("bulk", func(b *testing.B) {
+		b.ReportAllocs()
+		sigs := make([]db.CovenantSigRecord, len(stakingTxHashes))
+		for i, stakingTxHash := range stakingTxHashes {
+			sigs[i] = db.CovenantSigRecord{
+				StakingTxHash: stakingTxHash, CovenantBtcPkHex: covenantBtcPkHex, SignatureHex: "sig",
+			}
+		}
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			seedPendingDelegations(b, env, stakingTxHashes)
+			b.StartTimer()
+			require.NoError(b, env.database.BulkSaveCovenantSignatures(ctx, sigs))
+		}
+	})
+}
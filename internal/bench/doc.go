@@ -0,0 +1,11 @@
+// Package bench holds the benchmarks of the hot paths of the indexer: the
+// processing of the events of a BBN block, a batch of the expiry checker and
+// the bulk writes to the delegations. They run against the Mongo of
+// BENCH_MONGO_ADDRESS, authenticated as BENCH_MONGO_USERNAME and
+// BENCH_MONGO_PASSWORD, and are skipped if it is unset:
+//
+//	make bench
+//
+// The documents read and written by a benchmark are seeded again before each
+// iteration, out of the timing, so that every iteration does the same work.
+package bench
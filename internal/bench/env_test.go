@@ -0,0 +1,119 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// benchBatchSize is the number of delegations of the benchmarked batches,
+// about the largest seen in a BBN block
+const benchBatchSize = 1000
+
+// benchEnv is a database of the bench Mongo, with its indexes, and a client
+// seeding its documents
+type benchEnv struct {
+	cfg      *config.Config
+	database *db.Database
+	mongo    *mongo.Database
+}
+
+// newBenchEnv connects to the Mongo of BENCH_MONGO_ADDRESS, skipping the
+// benchmark if unset, and drops the database once done. The logs are disabled
+// not to be measured.
+func newBenchEnv(b *testing.B) *benchEnv {
+	address := os.Getenv("BENCH_MONGO_ADDRESS")
+	if address == "" {
+		b.Skip("BENCH_MONGO_ADDRESS is not set")
+	}
+	metrics.Init()
+	previousLevel := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	b.Cleanup(func() { zerolog.SetGlobalLevel(previousLevel) })
+
+	ctx := context.Background()
+	cfg := &config.Config{
+		Db: config.DbConfig{
+			Address:  address,
+			Username: os.Getenv("BENCH_MONGO_USERNAME"),
+			Password: os.Getenv("BENCH_MONGO_PASSWORD"),
+			DbName:   "indexer-bench",
+		},
+	}
+	require.NoError(b, model.Setup(ctx, cfg))
+	database, err := db.New(ctx, cfg.Db)
+	require.NoError(b, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(address).SetAuth(options.Credential{
+		Username: cfg.Db.Username,
+		Password: cfg.Db.Password,
+	}))
+	require.NoError(b, err)
+	b.Cleanup(func() {
+		_ = client.Database(cfg.Db.DbName).Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+
+	return &benchEnv{cfg: cfg, database: database, mongo: client.Database(cfg.Db.DbName)}
+}
+
+// seed replaces the documents of the collections with the given ones, the
+// collections left out being emptied, so that the indexes are kept
+func (env *benchEnv) seed(b *testing.B, docs map[string][]interface{}) {
+	ctx := context.Background()
+	for _, collection := range []string{
+		model.BTCDelegationDetailsCollection,
+		model.FinalityProviderDetailsCollection,
+		model.TimeLockCollection,
+		model.BTCDerivedChangesCollection,
+		model.DelegationStateHistoryCollection,
+		model.OutboxEventsCollection,
+		model.ProcessedBbnHeightsCollection,
+		model.BbnEventDeadLettersCollection,
+	} {
+		_, err := env.mongo.Collection(collection).DeleteMany(ctx, bson.M{})
+		require.NoError(b, err)
+		if len(docs[collection]) == 0 {
+			continue
+		}
+		_, err = env.mongo.Collection(collection).InsertMany(ctx, docs[collection])
+		require.NoError(b, err)
+	}
+}
+
+// benchStakingTxHashes returns the staking tx hashes of the delegations of a
+// batch
+func benchStakingTxHashes() []string {
+	stakingTxHashes := make([]string, benchBatchSize)
+	for i := range stakingTxHashes {
+		stakingTxHashes[i] = fmt.Sprintf("%064x", i)
+	}
+	return stakingTxHashes
+}
+
+// benchDelegations returns the delegations of the staking tx hashes in the
+// state, without covenant signature
+func benchDelegations(stakingTxHashes []string, state types.DelegationState) []interface{} {
+	docs := make([]interface{}, len(stakingTxHashes))
+	for i, stakingTxHash := range stakingTxHashes {
+		docs[i] = &model.BTCDelegationDetails{
+			StakingTxHashHex:            stakingTxHash,
+			State:                       state,
+			CovenantUnbondingSignatures: []model.CovenantSignature{},
+		}
+	}
+	return docs
+}
@@ -0,0 +1,50 @@
+package bench
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/services"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+const (
+	benchBtcTip       = 900_000
+	benchExpireHeight = benchBtcTip - 10
+)
+
+// BenchmarkExpiryBatch runs the expiry checker over a batch of active
+// delegations whose timelocks expired, each turning withdrawable
+func BenchmarkExpiryBatch(b *testing.B) {
+	env := newBenchEnv(b)
+	ctx := context.Background()
+	stakingTxHashes := benchStakingTxHashes()
+	timeLocks := make([]interface{}, len(stakingTxHashes))
+	for i, stakingTxHash := range stakingTxHashes {
+		timeLocks[i] = model.NewTimeLockDocument(stakingTxHash, benchExpireHeight, types.SubStateTimelock)
+	}
+
+	btcMock := mocks.NewBtcInterface(b)
+	btcMock.On("GetTipHeight").Return(uint64(benchBtcTip), nil)
+	service := services.NewService(
+		&config.Config{Poller: config.PollerConfig{ExpiredDelegationsLimit: benchBatchSize}},
+		env.database, btcMock, nil, nil, nil,
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		env.seed(b, map[string][]interface{}{
+			model.BTCDelegationDetailsCollection: benchDelegations(stakingTxHashes, types.StateActive),
+			model.TimeLockCollection:             timeLocks,
+		})
+		b.StartTimer()
+		require.Nil(b, service.RunExpiryCheck(ctx))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -58,7 +59,8 @@ func sendRequest[I any, R any](
 	if input != nil && (method == http.MethodPost || method == http.MethodPut) {
 		body, err := json.Marshal(input)
 		if err != nil {
-			return nil, types.NewErrorWithMsg(
+			return nil, types.Wrap(
+				err,
 				http.StatusInternalServerError,
 				types.InternalServiceError,
 				"failed to marshal request body",
@@ -69,8 +71,8 @@ func sendRequest[I any, R any](
 		req, requestError = http.NewRequestWithContext(ctxWithTimeout, method, url, nil)
 	}
 	if requestError != nil {
-		return nil, types.NewErrorWithMsg(
-			http.StatusInternalServerError, types.InternalServiceError, requestError.Error(),
+		return nil, types.Wrap(
+			requestError, http.StatusInternalServerError, types.InternalServiceError, "failed to create request",
 		)
 	}
 	// Set headers
@@ -80,14 +82,16 @@ func sendRequest[I any, R any](
 
 	resp, err := client.GetHttpClient().Do(req)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded || err.Error() == "context canceled" {
-			return nil, types.NewErrorWithMsg(
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, types.Wrap(
+				err,
 				http.StatusRequestTimeout,
 				types.RequestTimeout,
 				fmt.Sprintf("request timeout after %d ms at %s", timeout, url),
 			)
 		}
-		return nil, types.NewErrorWithMsg(
+		return nil, types.Wrap(
+			err,
 			http.StatusInternalServerError,
 			types.InternalServiceError,
 			fmt.Sprintf("failed to send request to %s", url),
@@ -111,7 +115,8 @@ func sendRequest[I any, R any](
 
 	var output R
 	if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
-		return nil, types.NewErrorWithMsg(
+		return nil, types.Wrap(
+			err,
 			http.StatusInternalServerError,
 			types.InternalServiceError,
 			fmt.Sprintf("failed to decode response from %s", url),
@@ -2,17 +2,26 @@ package bbnclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/avast/retry-go/v4"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/tracing"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 	bbncfg "github.com/babylonlabs-io/babylon/client/config"
 	"github.com/babylonlabs-io/babylon/client/query"
 	btcctypes "github.com/babylonlabs-io/babylon/x/btccheckpoint/types"
+	btclctypes "github.com/babylonlabs-io/babylon/x/btclightclient/types"
 	btcstakingtypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	finalitytypes "github.com/babylonlabs-io/babylon/x/finality/types"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	sdkquerytypes "github.com/cosmos/cosmos-sdk/types/query"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type BBNClient struct {
@@ -42,13 +51,43 @@ func (c *BBNClient) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 		return status, nil
 	}
 
-	status, err := clientCallWithRetry(callForStatus, c.cfg)
+	status, err := clientCallWithRetry(ctx, callForStatus, c.cfg)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get latest block number by fetching status: %w", err)
 	}
 	return status.SyncInfo.LatestBlockHeight, nil
 }
 
+func (c *BBNClient) GetChainID(ctx context.Context) (string, error) {
+	callForStatus := func() (*ctypes.ResultStatus, error) {
+		status, err := c.queryClient.RPCClient.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return status, nil
+	}
+
+	status, err := clientCallWithRetry(ctx, callForStatus, c.cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get chain id by fetching status: %w", err)
+	}
+	return status.NodeInfo.Network, nil
+}
+
+// GetBTCLightClientTipHeight returns the height of the BTC tip known to the
+// btclightclient module of the BBN chain
+func (c *BBNClient) GetBTCLightClientTipHeight(ctx context.Context) (uint64, error) {
+	callForTip := func() (*btclctypes.QueryTipResponse, error) {
+		return c.queryClient.BTCHeaderChainTip()
+	}
+
+	tip, err := clientCallWithRetry(ctx, callForTip, c.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the btclightclient tip: %w", err)
+	}
+	return uint64(tip.Header.Height), nil
+}
+
 func (c *BBNClient) GetCheckpointParams(ctx context.Context) (*CheckpointParams, error) {
 	callForCheckpointParams := func() (*btcctypes.QueryParamsResponse, error) {
 		params, err := c.queryClient.BTCCheckpointParams()
@@ -58,7 +97,7 @@ func (c *BBNClient) GetCheckpointParams(ctx context.Context) (*CheckpointParams,
 		return params, nil
 	}
 
-	params, err := clientCallWithRetry(callForCheckpointParams, c.cfg)
+	params, err := clientCallWithRetry(ctx, callForCheckpointParams, c.cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -85,7 +124,7 @@ func (c *BBNClient) GetAllStakingParams(ctx context.Context) (map[uint32]*Stakin
 				return c.queryClient.BTCStakingParamsByVersion(version)
 			}
 
-			params, err = clientCallWithRetry(callForStakingParams, c.cfg)
+			params, err = clientCallWithRetry(ctx, callForStakingParams, c.cfg)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get staking params for version %d: %w", version, err)
 			}
@@ -106,6 +145,104 @@ func (c *BBNClient) GetAllStakingParams(ctx context.Context) (map[uint32]*Stakin
 	return allParams, nil
 }
 
+func (c *BBNClient) GetActiveFinalityProvidersAtHeight(
+	ctx context.Context, height uint64,
+) ([]*FinalityProviderVotingPower, error) {
+	var activeFps []*FinalityProviderVotingPower
+	pagination := &sdkquerytypes.PageRequest{}
+
+	for {
+		callForActiveFps := func() (*finalitytypes.QueryActiveFinalityProvidersAtHeightResponse, error) {
+			return c.queryClient.ActiveFinalityProvidersAtHeight(height, pagination)
+		}
+
+		resp, err := clientCallWithRetry(ctx, callForActiveFps, c.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get active finality providers at height %d: %w", height, err)
+		}
+
+		for _, fp := range resp.FinalityProviders {
+			activeFps = append(activeFps, FromBbnActiveFinalityProvider(fp))
+		}
+
+		if resp.Pagination == nil || len(resp.Pagination.NextKey) == 0 {
+			break
+		}
+		pagination = &sdkquerytypes.PageRequest{Key: resp.Pagination.NextKey}
+	}
+
+	return activeFps, nil
+}
+
+func (c *BBNClient) GetBTCDelegation(
+	ctx context.Context, stakingTxHashHex string,
+) (*BTCDelegation, error) {
+	callForDelegation := func() (*btcstakingtypes.QueryBTCDelegationResponse, error) {
+		resp, err := c.queryClient.BTCDelegation(stakingTxHashHex)
+		if err != nil && isBTCDelegationNotFoundError(err) {
+			// Retrying won't make the delegation appear
+			return nil, retry.Unrecoverable(err)
+		}
+		return resp, err
+	}
+
+	resp, err := clientCallWithRetry(ctx, callForDelegation, c.cfg)
+	if err != nil {
+		if isBTCDelegationNotFoundError(err) {
+			return nil, ErrBTCDelegationNotFound
+		}
+		return nil, fmt.Errorf("failed to get BTC delegation %s: %w", stakingTxHashHex, err)
+	}
+
+	return FromBbnBTCDelegation(resp.BtcDelegation)
+}
+
+func (c *BBNClient) GetBTCDelegations(
+	ctx context.Context, pageKey []byte, limit uint64,
+) ([]*BTCDelegation, []byte, error) {
+	pagination := &sdkquerytypes.PageRequest{Key: pageKey, Limit: limit}
+	callForDelegations := func() (*btcstakingtypes.QueryBTCDelegationsResponse, error) {
+		return c.queryClient.BTCDelegations(btcstakingtypes.BTCDelegationStatus_ANY, pagination)
+	}
+
+	resp, err := clientCallWithRetry(ctx, callForDelegations, c.cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get BTC delegations: %w", err)
+	}
+
+	delegations := make([]*BTCDelegation, 0, len(resp.BtcDelegations))
+	for _, del := range resp.BtcDelegations {
+		delegation, err := FromBbnBTCDelegation(del)
+		if err != nil {
+			return nil, nil, err
+		}
+		delegations = append(delegations, delegation)
+	}
+
+	return delegations, nextPageKey(resp.Pagination), nil
+}
+
+func (c *BBNClient) GetFinalityProviders(
+	ctx context.Context, pageKey []byte, limit uint64,
+) ([]*FinalityProvider, []byte, error) {
+	pagination := &sdkquerytypes.PageRequest{Key: pageKey, Limit: limit}
+	callForFps := func() (*btcstakingtypes.QueryFinalityProvidersResponse, error) {
+		return c.queryClient.FinalityProviders(pagination)
+	}
+
+	resp, err := clientCallWithRetry(ctx, callForFps, c.cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get finality providers: %w", err)
+	}
+
+	fps := make([]*FinalityProvider, 0, len(resp.FinalityProviders))
+	for _, fp := range resp.FinalityProviders {
+		fps = append(fps, FromBbnFinalityProvider(fp))
+	}
+
+	return fps, nextPageKey(resp.Pagination), nil
+}
+
 func (c *BBNClient) GetBlockResults(
 	ctx context.Context, blockHeight *int64,
 ) (*ctypes.ResultBlockResults, error) {
@@ -117,7 +254,7 @@ func (c *BBNClient) GetBlockResults(
 		return resp, nil
 	}
 
-	blockResults, err := clientCallWithRetry(callForBlockResults, c.cfg)
+	blockResults, err := clientCallWithRetry(ctx, callForBlockResults, c.cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -133,13 +270,96 @@ func (c *BBNClient) GetBlock(ctx context.Context, blockHeight *int64) (*ctypes.R
 		return resp, nil
 	}
 
-	block, err := clientCallWithRetry(callForBlock, c.cfg)
+	block, err := clientCallWithRetry(ctx, callForBlock, c.cfg)
 	if err != nil {
 		return nil, err
 	}
 	return block, nil
 }
 
+// searchPageSize is the number of results per page of the event searches
+const searchPageSize = 100
+
+// SearchEventHeights returns the heights of the blocks with a tx or finalize
+// block event matching the query, in ascending order. The node must index the
+// events searched.
+func (c *BBNClient) SearchEventHeights(ctx context.Context, query string) ([]int64, error) {
+	heights := make(map[int64]struct{})
+
+	for page := 1; ; page++ {
+		perPage := searchPageSize
+		callForTxSearch := func() (*ctypes.ResultTxSearch, error) {
+			return c.queryClient.RPCClient.TxSearch(ctx, query, false, &page, &perPage, "asc")
+		}
+		resp, err := clientCallWithRetry(ctx, callForTxSearch, c.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search txs with events %s: %w", query, err)
+		}
+		for _, tx := range resp.Txs {
+			heights[tx.Height] = struct{}{}
+		}
+		if page*perPage >= resp.TotalCount {
+			break
+		}
+	}
+
+	for page := 1; ; page++ {
+		perPage := searchPageSize
+		callForBlockSearch := func() (*ctypes.ResultBlockSearch, error) {
+			return c.queryClient.RPCClient.BlockSearch(ctx, query, &page, &perPage, "asc")
+		}
+		resp, err := clientCallWithRetry(ctx, callForBlockSearch, c.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search blocks with events %s: %w", query, err)
+		}
+		for _, block := range resp.Blocks {
+			heights[block.Block.Height] = struct{}{}
+		}
+		if page*perPage >= resp.TotalCount {
+			break
+		}
+	}
+
+	sortedHeights := make([]int64, 0, len(heights))
+	for height := range heights {
+		sortedHeights = append(sortedHeights, height)
+	}
+	sort.Slice(sortedHeights, func(i, j int) bool { return sortedHeights[i] < sortedHeights[j] })
+	return sortedHeights, nil
+}
+
+// SearchFirstEventHeight returns the height of the first block with a tx or
+// finalize block event matching the query, zero if none. The node must index
+// the events searched.
+func (c *BBNClient) SearchFirstEventHeight(ctx context.Context, query string) (int64, error) {
+	page, perPage := 1, 1
+	callForTxSearch := func() (*ctypes.ResultTxSearch, error) {
+		return c.queryClient.RPCClient.TxSearch(ctx, query, false, &page, &perPage, "asc")
+	}
+	txResp, err := clientCallWithRetry(ctx, callForTxSearch, c.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search txs with events %s: %w", query, err)
+	}
+	var first int64
+	if len(txResp.Txs) > 0 {
+		first = txResp.Txs[0].Height
+	}
+
+	callForBlockSearch := func() (*ctypes.ResultBlockSearch, error) {
+		return c.queryClient.RPCClient.BlockSearch(ctx, query, &page, &perPage, "asc")
+	}
+	blockResp, err := clientCallWithRetry(ctx, callForBlockSearch, c.cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search blocks with events %s: %w", query, err)
+	}
+	if len(blockResp.Blocks) > 0 {
+		if height := blockResp.Blocks[0].Block.Height; first == 0 || height < first {
+			first = height
+		}
+	}
+	return first, nil
+}
+
 func (c *BBNClient) Subscribe(subscriber, query string, outCapacity ...int) (out <-chan ctypes.ResultEvent, err error) {
 	return c.queryClient.RPCClient.Subscribe(context.Background(), subscriber, query, outCapacity...)
 }
@@ -156,12 +376,38 @@ func (c *BBNClient) Start() error {
 	return c.queryClient.RPCClient.Start()
 }
 
+// ErrBTCDelegationNotFound the BTC delegation does not exist on the BBN chain
+var ErrBTCDelegationNotFound = errors.New("BTC delegation not found")
+
+func isBTCDelegationNotFoundError(err error) bool {
+	// The registered error is only preserved as text once through the RPC
+	return strings.Contains(err.Error(), btcstakingtypes.ErrBTCDelegationNotFound.Error())
+}
+
+func nextPageKey(pagination *sdkquerytypes.PageResponse) []byte {
+	if pagination == nil || len(pagination.NextKey) == 0 {
+		return nil
+	}
+	return pagination.NextKey
+}
+
+// clientCallWithRetry runs the call, retrying it on failure, within a span
+// named after the calling client method
 func clientCallWithRetry[T any](
-	call retry.RetryableFuncWithData[*T], cfg *config.BBNConfig,
-) (*T, error) {
-	result, err := retry.DoWithData(call, retry.Attempts(cfg.MaxRetryTimes), retry.Delay(cfg.RetryInterval), retry.LastErrorOnly(true),
+	ctx context.Context, call retry.RetryableFuncWithData[*T], cfg *config.BBNConfig,
+) (result *T, err error) {
+	method := utils.GetFunctionName(1)
+	if idx := strings.LastIndex(method, "."); idx >= 0 {
+		method = method[idx+1:]
+	}
+	_, span := tracing.StartSpan(ctx, "bbn."+method, attribute.String("rpc.method", method))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	result, err = retry.DoWithData(call, retry.Attempts(cfg.MaxRetryTimes), retry.Delay(cfg.RetryInterval), retry.LastErrorOnly(true),
 		retry.OnRetry(func(n uint, err error) {
-			log.Debug().
+			logging.BbnClient.FromContext(ctx).Debug().
 				Uint("attempt", n+1).
 				Uint("max_attempts", cfg.MaxRetryTimes).
 				Err(err).
@@ -10,8 +10,16 @@ type BbnInterface interface {
 	GetCheckpointParams(ctx context.Context) (*CheckpointParams, error)
 	GetAllStakingParams(ctx context.Context) (map[uint32]*StakingParams, error)
 	GetLatestBlockNumber(ctx context.Context) (int64, error)
+	GetChainID(ctx context.Context) (string, error)
+	GetBTCLightClientTipHeight(ctx context.Context) (uint64, error)
+	GetActiveFinalityProvidersAtHeight(ctx context.Context, height uint64) ([]*FinalityProviderVotingPower, error)
+	GetBTCDelegation(ctx context.Context, stakingTxHashHex string) (*BTCDelegation, error)
+	GetBTCDelegations(ctx context.Context, pageKey []byte, limit uint64) ([]*BTCDelegation, []byte, error)
+	GetFinalityProviders(ctx context.Context, pageKey []byte, limit uint64) ([]*FinalityProvider, []byte, error)
 	GetBlock(ctx context.Context, blockHeight *int64) (*ctypes.ResultBlock, error)
 	GetBlockResults(ctx context.Context, blockHeight *int64) (*ctypes.ResultBlockResults, error)
+	SearchEventHeights(ctx context.Context, query string) ([]int64, error)
+	SearchFirstEventHeight(ctx context.Context, query string) (int64, error)
 	Subscribe(subscriber, query string, outCapacity ...int) (out <-chan ctypes.ResultEvent, err error)
 	UnsubscribeAll(subscriber string) error
 	IsRunning() bool
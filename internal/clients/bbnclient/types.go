@@ -2,9 +2,14 @@ package bbnclient
 
 import (
 	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 
 	checkpointtypes "github.com/babylonlabs-io/babylon/x/btccheckpoint/types"
 	stakingtypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	finalitytypes "github.com/babylonlabs-io/babylon/x/finality/types"
 )
 
 // StakingParams represents the staking parameters of the BBN chain
@@ -33,6 +38,81 @@ type CheckpointParams struct {
 	CheckpointTag                 string `bson:"checkpoint_tag"`
 }
 
+// FinalityProviderVotingPower represents a finality provider of the active set
+// at a given BBN height together with its voting power
+type FinalityProviderVotingPower struct {
+	BtcPk       string
+	VotingPower uint64
+	Jailed      bool
+}
+
+// BTCDelegation represents the state of a BTC delegation on the BBN chain
+type BTCDelegation struct {
+	StakingTxHashHex            string
+	StakingTxHex                string
+	StakerBtcPkHex              string
+	StakerBabylonAddress        string
+	FinalityProviderBtcPksHex   []string
+	StakingTime                 uint32
+	StartHeight                 uint32
+	EndHeight                   uint32
+	TotalSat                    uint64
+	StakingOutputIdx            uint32
+	UnbondingTime               uint32
+	UnbondingTxHex              string
+	ParamsVersion               uint32
+	Status                      string
+	CovenantUnbondingSignatures []CovenantUnbondingSignature
+}
+
+type CovenantUnbondingSignature struct {
+	CovenantBtcPkHex string
+	SignatureHex     string
+}
+
+// FinalityProvider represents the state of a finality provider on the BBN chain
+type FinalityProvider struct {
+	BtcPk           string
+	BabylonAddress  string
+	Commission      string
+	Moniker         string
+	Identity        string
+	Website         string
+	SecurityContact string
+	Details         string
+	Jailed          bool
+	Slashed         bool
+}
+
+// Diff returns the names of the fields that differ from the other params
+func (p *StakingParams) Diff(other *StakingParams) []string {
+	return diffFields(p, other)
+}
+
+// Diff returns the names of the fields that differ from the other params
+func (p *CheckpointParams) Diff(other *CheckpointParams) []string {
+	return diffFields(p, other)
+}
+
+// diffFields returns the names of the fields that differ between both structs.
+// Nil and empty slices are considered equal as they are not told apart once
+// stored.
+func diffFields[T any](a, b *T) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		fa, fb := va.Field(i), vb.Field(i)
+		if fa.Kind() == reflect.Slice && fa.Len() == 0 && fb.Len() == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			fields = append(fields, va.Type().Field(i).Name)
+		}
+	}
+	return fields
+}
+
 func FromBbnStakingParams(params stakingtypes.Params) *StakingParams {
 	return &StakingParams{
 		CovenantPks:                  params.CovenantPksHex(),
@@ -60,3 +140,76 @@ func FromBbnCheckpointParams(params checkpointtypes.Params) *CheckpointParams {
 		CheckpointTag:                 params.CheckpointTag,
 	}
 }
+
+func FromBbnActiveFinalityProvider(
+	fp *finalitytypes.ActiveFinalityProvidersAtHeightResponse,
+) *FinalityProviderVotingPower {
+	return &FinalityProviderVotingPower{
+		BtcPk:       fp.BtcPkHex.MarshalHex(),
+		VotingPower: fp.VotingPower,
+		Jailed:      fp.Jailed,
+	}
+}
+
+func FromBbnBTCDelegation(del *stakingtypes.BTCDelegationResponse) (*BTCDelegation, error) {
+	stakingTx, err := utils.DeserializeBtcTransactionFromHex(del.StakingTxHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize staking tx: %w", err)
+	}
+
+	fpBtcPksHex := make([]string, len(del.FpBtcPkList))
+	for i, fpBtcPk := range del.FpBtcPkList {
+		fpBtcPksHex[i] = fpBtcPk.MarshalHex()
+	}
+
+	delegation := &BTCDelegation{
+		StakingTxHashHex:          stakingTx.TxHash().String(),
+		StakingTxHex:              del.StakingTxHex,
+		StakerBtcPkHex:            del.BtcPk.MarshalHex(),
+		StakerBabylonAddress:      del.StakerAddr,
+		FinalityProviderBtcPksHex: fpBtcPksHex,
+		StakingTime:               del.StakingTime,
+		StartHeight:               del.StartHeight,
+		EndHeight:                 del.EndHeight,
+		TotalSat:                  del.TotalSat,
+		StakingOutputIdx:          del.StakingOutputIdx,
+		UnbondingTime:             del.UnbondingTime,
+		ParamsVersion:             del.ParamsVersion,
+		Status:                    del.StatusDesc,
+	}
+
+	if del.UndelegationResponse != nil {
+		delegation.UnbondingTxHex = del.UndelegationResponse.UnbondingTxHex
+		for _, sig := range del.UndelegationResponse.CovenantUnbondingSigList {
+			delegation.CovenantUnbondingSignatures = append(
+				delegation.CovenantUnbondingSignatures,
+				CovenantUnbondingSignature{
+					CovenantBtcPkHex: sig.Pk.MarshalHex(),
+					SignatureHex:     sig.Sig.ToHexStr(),
+				},
+			)
+		}
+	}
+
+	return delegation, nil
+}
+
+func FromBbnFinalityProvider(fp *stakingtypes.FinalityProviderResponse) *FinalityProvider {
+	finalityProvider := &FinalityProvider{
+		BtcPk:          fp.BtcPk.MarshalHex(),
+		BabylonAddress: fp.Addr,
+		Jailed:         fp.Jailed,
+		Slashed:        fp.SlashedBabylonHeight > 0 || fp.SlashedBtcHeight > 0,
+	}
+	if fp.Commission != nil {
+		finalityProvider.Commission = fp.Commission.String()
+	}
+	if fp.Description != nil {
+		finalityProvider.Moniker = fp.Description.Moniker
+		finalityProvider.Identity = fp.Description.Identity
+		finalityProvider.Website = fp.Description.Website
+		finalityProvider.SecurityContact = fp.Description.SecurityContact
+		finalityProvider.Details = fp.Description.Details
+	}
+	return finalityProvider
+}
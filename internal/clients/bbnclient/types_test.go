@@ -0,0 +1,39 @@
+package bbnclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStakingParamsDiff(t *testing.T) {
+	params := &StakingParams{
+		CovenantPks:         []string{"pk1", "pk2"},
+		CovenantQuorum:      2,
+		MinStakingValueSat:  1000,
+		UnbondingTimeBlocks: 101,
+	}
+
+	same := *params
+	same.CovenantPks = []string{"pk1", "pk2"}
+	require.Empty(t, params.Diff(&same))
+
+	other := *params
+	other.CovenantPks = []string{"pk1", "pk3"}
+	other.UnbondingTimeBlocks = 1008
+	require.Equal(t, []string{"CovenantPks", "UnbondingTimeBlocks"}, params.Diff(&other))
+
+	// Nil and empty slices are not told apart once stored
+	empty := StakingParams{CovenantPks: []string{}}
+	require.Empty(t, empty.Diff(&StakingParams{}))
+}
+
+func TestCheckpointParamsDiff(t *testing.T) {
+	params := &CheckpointParams{BtcConfirmationDepth: 10, CheckpointTag: "01020304"}
+
+	other := *params
+	require.Empty(t, params.Diff(&other))
+
+	other.CheckpointTag = "62627434"
+	require.Equal(t, []string{"CheckpointTag"}, params.Diff(&other))
+}
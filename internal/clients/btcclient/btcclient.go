@@ -4,10 +4,13 @@ import (
 	"fmt"
 
 	"github.com/avast/retry-go/v4"
+	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/rpcclient"
-	"github.com/rs/zerolog/log"
+	"github.com/btcsuite/btcd/wire"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 )
 
 type BTCClient struct {
@@ -54,12 +57,60 @@ func (c *BTCClient) GetTipHeight() (uint64, error) {
 	return uint64(blockCount.count), nil
 }
 
+func (c *BTCClient) GetBlockHeaderByHeight(height uint64) (*wire.BlockHeader, error) {
+	callForBlockHeader := func() (*wire.BlockHeader, error) {
+		hash, err := c.client.GetBlockHash(int64(height))
+		if err != nil {
+			return nil, err
+		}
+
+		return c.client.GetBlockHeader(hash)
+	}
+
+	header, err := clientCallWithRetry(callForBlockHeader, c.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block header at height %d: %w", height, err)
+	}
+
+	return header, nil
+}
+
+// backendNetworks maps the chain names served by getblockchaininfo, the
+// bitcoind and the btcd ones, to the BTC network names of the config
+var backendNetworks = map[string]utils.SupportedBtcNetwork{
+	"main":     utils.BtcMainnet,
+	"mainnet":  utils.BtcMainnet,
+	"test":     utils.BtcTestnet,
+	"testnet3": utils.BtcTestnet,
+	"signet":   utils.BtcSignet,
+	"regtest":  utils.BtcRegtest,
+	"simnet":   utils.BtcSimnet,
+}
+
+// GetNetwork returns the BTC network the backend serves, by its name in the
+// config, or the chain name served by getblockchaininfo if unknown
+func (c *BTCClient) GetNetwork() (string, error) {
+	callForBlockChainInfo := func() (*btcjson.GetBlockChainInfoResult, error) {
+		return c.client.GetBlockChainInfo()
+	}
+
+	info, err := clientCallWithRetry(callForBlockChainInfo, c.cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to get blockchain info: %w", err)
+	}
+
+	if network, ok := backendNetworks[info.Chain]; ok {
+		return network.String(), nil
+	}
+	return info.Chain, nil
+}
+
 func clientCallWithRetry[T any](
 	call retry.RetryableFuncWithData[*T], cfg *config.BTCConfig,
 ) (*T, error) {
 	result, err := retry.DoWithData(call, retry.Attempts(cfg.MaxRetryTimes), retry.Delay(cfg.RetryInterval), retry.LastErrorOnly(true),
 		retry.OnRetry(func(n uint, err error) {
-			log.Debug().
+			logging.BtcClient.Logger().Debug().
 				Uint("attempt", n+1).
 				Uint("max_attempts", cfg.MaxRetryTimes).
 				Err(err).
@@ -1,5 +1,9 @@
 package btcclient
 
+import "github.com/btcsuite/btcd/wire"
+
 type BtcInterface interface {
 	GetTipHeight() (uint64, error)
+	GetBlockHeaderByHeight(height uint64) (*wire.BlockHeader, error)
+	GetNetwork() (string, error)
 }
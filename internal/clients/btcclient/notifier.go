@@ -13,6 +13,11 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 )
 
+// BlockNotificationSourcePoll is the source of the block notifications of the
+// BTC notifier, which polls bitcoind rather than subscribing to its ZMQ
+// notifications
+const BlockNotificationSourcePoll = "poll"
+
 type BTCNotifier struct {
 	*bitcoindnotify.BitcoindNotifier
 }
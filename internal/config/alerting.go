@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	AlerterTypeNone    = "none"
+	AlerterTypeWebhook = "webhook"
+)
+
+// AlertingConfig defines where the alerts on critical indexer conditions are
+// pushed, ahead of the metrics based alerting
+type AlertingConfig struct {
+	// Type is either none, the alerts being only logged, or webhook. None is
+	// used if unset.
+	Type string `mapstructure:"type"`
+	// WebhookURL is the Slack compatible endpoint the alerts are POSTed to
+	WebhookURL string `mapstructure:"webhook-url"`
+	// Timeout bounds each alert request
+	Timeout time.Duration `mapstructure:"timeout"`
+	// MinInterval is the minimum delay between two alerts on the same
+	// condition, the ones in between being dropped
+	MinInterval time.Duration `mapstructure:"min-interval"`
+}
+
+func (cfg *AlertingConfig) IsWebhook() bool {
+	return cfg.Type == AlerterTypeWebhook
+}
+
+func (cfg *AlertingConfig) Validate() error {
+	switch cfg.Type {
+	case "", AlerterTypeNone:
+		return nil
+	case AlerterTypeWebhook:
+	default:
+		return fmt.Errorf("unknown alerter type %s", cfg.Type)
+	}
+
+	webhookURL, err := url.Parse(cfg.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("invalid alerting webhook-url %s: %w", cfg.WebhookURL, err)
+	}
+	if webhookURL.Scheme != "https" {
+		return fmt.Errorf("alerting webhook-url %s must use https", cfg.WebhookURL)
+	}
+
+	if cfg.Timeout <= 0 {
+		return errors.New("alerting timeout must be positive")
+	}
+
+	if cfg.MinInterval < 0 {
+		return errors.New("alerting min-interval must not be negative")
+	}
+
+	return nil
+}
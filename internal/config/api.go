@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// APIConfig defines the configuration of the HTTP API serving the indexed
+// data. The server is disabled when no listen address is set.
+type APIConfig struct {
+	// ListenAddress is the host:port the server listens on
+	ListenAddress string `mapstructure:"listen-address"`
+	// RequestTimeout bounds the reading and writing of a request
+	RequestTimeout time.Duration `mapstructure:"request-timeout"`
+	// ShutdownTimeout is how long the in-flight requests are waited for on
+	// shutdown
+	ShutdownTimeout time.Duration `mapstructure:"shutdown-timeout"`
+	// MaxPageSize is the maximum number of items of a paginated response
+	MaxPageSize int64 `mapstructure:"max-page-size"`
+	// MaxBtcHeightWindow is the maximum number of BTC blocks of a queried
+	// height range
+	MaxBtcHeightWindow uint32 `mapstructure:"max-btc-height-window"`
+	// StatsMaxAge is the age after which the global stats document is
+	// considered stale
+	StatsMaxAge time.Duration `mapstructure:"stats-max-age"`
+	// StatsCacheMaxAge is how long the clients may cache the global stats
+	StatsCacheMaxAge time.Duration `mapstructure:"stats-cache-max-age"`
+	// HealthCheckTimeout bounds each check of the health probes, so that a
+	// hung dependency fails its check instead of hanging the probe
+	HealthCheckTimeout time.Duration `mapstructure:"health-check-timeout"`
+	// BtcTipMaxAge is the age of the BTC tip block after which the BTC
+	// backend is considered stale
+	BtcTipMaxAge time.Duration `mapstructure:"btc-tip-max-age"`
+	// PollerStallTolerance is how long a poller may overrun its interval
+	// before it is considered stalled
+	PollerStallTolerance time.Duration `mapstructure:"poller-stall-tolerance"`
+	// BlockProcessorTimeout is how long the BBN block processor may spend on
+	// a single block before the process is considered unresponsive
+	BlockProcessorTimeout time.Duration `mapstructure:"block-processor-timeout"`
+	// PollerHeartbeatTimeout is how long a poller may go without completing
+	// a run, on top of its interval, before the process is considered
+	// unresponsive. It is generous, as the restart of the process it leads
+	// to interrupts the other pollers.
+	PollerHeartbeatTimeout time.Duration `mapstructure:"poller-heartbeat-timeout"`
+	// MaxBbnLag is the number of BBN blocks the last processed height may lag
+	// behind the chain tip before the indexer is reported not ready
+	MaxBbnLag uint64 `mapstructure:"max-bbn-lag"`
+	// AdminTokens maps the name of each admin to the bearer token
+	// authenticating them on the admin endpoints, which are disabled if empty
+	AdminTokens map[string]string `mapstructure:"admin-tokens"`
+	// Pprof serves the net/http/pprof profiles at /debug/pprof to the
+	// admins, along with the admin endpoints
+	Pprof bool `mapstructure:"pprof"`
+}
+
+func (cfg *APIConfig) IsEnabled() bool {
+	return cfg.ListenAddress != ""
+}
+
+// IsAdminEnabled returns whether the admin endpoints are served
+func (cfg *APIConfig) IsAdminEnabled() bool {
+	return len(cfg.AdminTokens) > 0
+}
+
+func (cfg *APIConfig) Validate() error {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.ListenAddress); err != nil {
+		return fmt.Errorf("invalid api listen-address %s: %w", cfg.ListenAddress, err)
+	}
+
+	if cfg.RequestTimeout <= 0 {
+		return errors.New("api request-timeout must be positive")
+	}
+
+	if cfg.ShutdownTimeout <= 0 {
+		return errors.New("api shutdown-timeout must be positive")
+	}
+
+	if cfg.MaxPageSize <= 0 {
+		return errors.New("api max-page-size must be positive")
+	}
+
+	if cfg.MaxBtcHeightWindow == 0 {
+		return errors.New("api max-btc-height-window must be positive")
+	}
+
+	if cfg.StatsMaxAge <= 0 {
+		return errors.New("api stats-max-age must be positive")
+	}
+
+	if cfg.StatsCacheMaxAge < 0 {
+		return errors.New("api stats-cache-max-age must not be negative")
+	}
+
+	if cfg.HealthCheckTimeout <= 0 {
+		return errors.New("api health-check-timeout must be positive")
+	}
+
+	if cfg.BtcTipMaxAge <= 0 {
+		return errors.New("api btc-tip-max-age must be positive")
+	}
+
+	if cfg.PollerStallTolerance <= 0 {
+		return errors.New("api poller-stall-tolerance must be positive")
+	}
+
+	if cfg.BlockProcessorTimeout <= 0 {
+		return errors.New("api block-processor-timeout must be positive")
+	}
+
+	if cfg.PollerHeartbeatTimeout < cfg.PollerStallTolerance {
+		return errors.New("api poller-heartbeat-timeout must be at least the poller-stall-tolerance")
+	}
+
+	if cfg.MaxBbnLag == 0 {
+		return errors.New("api max-bbn-lag must be positive")
+	}
+
+	for name, token := range cfg.AdminTokens {
+		if token == "" {
+			return fmt.Errorf("api admin-tokens of %s must not be empty", name)
+		}
+	}
+
+	return nil
+}
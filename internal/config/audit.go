@@ -0,0 +1,13 @@
+package config
+
+// AuditConfig defines where the audit stream of the mutations of the indexed
+// state is written
+type AuditConfig struct {
+	// Output is stdout, stderr or the path of a file the audit records are
+	// appended to, the mutations are not audited if empty
+	Output string `mapstructure:"output"`
+}
+
+func (cfg *AuditConfig) IsEnabled() bool {
+	return cfg.Output != ""
+}
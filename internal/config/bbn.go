@@ -11,6 +11,60 @@ type BBNConfig struct {
 	Timeout       time.Duration `mapstructure:"timeout"`
 	MaxRetryTimes uint          `mapstructure:"maxretrytimes"`
 	RetryInterval time.Duration `mapstructure:"retryinterval"`
+	// CheckpointTag is the checkpoint tag the BBN chain is expected to use on
+	// the configured BTC network. It is not verified if left empty.
+	CheckpointTag string `mapstructure:"checkpoint-tag"`
+	// ChainId is the chain id the BBN node is expected to serve, checked by
+	// the readiness probe. It is not verified if left empty.
+	ChainId string `mapstructure:"chain-id"`
+	// PrefetchConcurrency is the number of block results fetched at a time
+	// ahead of their processing, DefaultBbnPrefetchConcurrency if unset
+	PrefetchConcurrency int `mapstructure:"prefetch-concurrency"`
+	// PrefetchBufferSize is the number of blocks ahead of the processed one
+	// whose results are prefetched and held, DefaultBbnPrefetchBufferSize if
+	// unset
+	PrefetchBufferSize uint64 `mapstructure:"prefetch-buffer-size"`
+	// CovenantSignatureWorkers is the number of delegations read at a time
+	// for their covenant signatures of a block,
+	// DefaultBbnCovenantSignatureWorkers if unset
+	CovenantSignatureWorkers int `mapstructure:"covenant-signature-workers"`
+	// StartHeight is the first BBN height processed on a fresh database,
+	// the activation height of the staking module if unset. Once blocks are
+	// processed, a different start height only applies with --force-resync
+	// or --allow-gap.
+	StartHeight uint64 `mapstructure:"start-height"`
+	// StakingActivationHeight is the BBN height the staking module was
+	// activated at, below which no block is processed
+	StakingActivationHeight uint64 `mapstructure:"staking-activation-height"`
+}
+
+const (
+	DefaultBbnPrefetchConcurrency = 3
+	DefaultBbnPrefetchBufferSize  = 32
+	// DefaultBbnCovenantSignatureWorkers is the default number of delegations
+	// read at a time for their covenant signatures
+	DefaultBbnCovenantSignatureWorkers = 4
+)
+
+func (cfg *BBNConfig) GetPrefetchConcurrency() int {
+	if cfg.PrefetchConcurrency == 0 {
+		return DefaultBbnPrefetchConcurrency
+	}
+	return cfg.PrefetchConcurrency
+}
+
+func (cfg *BBNConfig) GetPrefetchBufferSize() uint64 {
+	if cfg.PrefetchBufferSize == 0 {
+		return DefaultBbnPrefetchBufferSize
+	}
+	return cfg.PrefetchBufferSize
+}
+
+func (cfg *BBNConfig) GetCovenantSignatureWorkers() int {
+	if cfg.CovenantSignatureWorkers == 0 {
+		return DefaultBbnCovenantSignatureWorkers
+	}
+	return cfg.CovenantSignatureWorkers
 }
 
 func (cfg *BBNConfig) Validate() error {
@@ -30,5 +84,13 @@ func (cfg *BBNConfig) Validate() error {
 		return fmt.Errorf("cfg.RetryInterval must be positive")
 	}
 
+	if cfg.PrefetchConcurrency < 0 {
+		return fmt.Errorf("cfg.PrefetchConcurrency must not be negative")
+	}
+
+	if cfg.CovenantSignatureWorkers < 0 {
+		return fmt.Errorf("cfg.CovenantSignatureWorkers must not be negative")
+	}
+
 	return nil
 }
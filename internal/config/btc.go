@@ -21,6 +21,7 @@ type BTCConfig struct {
 	MaxRetryTimes           uint          `mapstructure:"maxretrytimes"`
 	RetryInterval           time.Duration `mapstructure:"retryinterval"`
 	NetParams               string        `mapstructure:"netparams"`
+	MaxReorgDepth           uint64        `mapstructure:"maxreorgdepth"`
 }
 
 func (cfg *BTCConfig) ToConnConfig() (*rpcclient.ConnConfig, error) {
@@ -76,6 +77,10 @@ func (cfg *BTCConfig) Validate() error {
 		return fmt.Errorf("retry interval should be positive")
 	}
 
+	if cfg.MaxReorgDepth <= 0 {
+		return fmt.Errorf("max reorg depth should be positive")
+	}
+
 	if _, ok := utils.GetValidNetParams()[cfg.NetParams]; !ok {
 		return fmt.Errorf("invalid net params")
 	}
@@ -10,12 +10,23 @@ import (
 )
 
 type Config struct {
-	Db      DbConfig          `mapstructure:"db"`
-	BTC     BTCConfig         `mapstructure:"btc"`
-	BBN     BBNConfig         `mapstructure:"bbn"`
-	Poller  PollerConfig      `mapstructure:"poller"`
-	Queue   queue.QueueConfig `mapstructure:"queue"`
-	Metrics MetricsConfig     `mapstructure:"metrics"`
+	Db             DbConfig             `mapstructure:"db"`
+	BTC            BTCConfig            `mapstructure:"btc"`
+	BBN            BBNConfig            `mapstructure:"bbn"`
+	Poller         PollerConfig         `mapstructure:"poller"`
+	Queue          queue.QueueConfig    `mapstructure:"queue"`
+	Emitter        EmitterConfig        `mapstructure:"emitter"`
+	Metrics        MetricsConfig        `mapstructure:"metrics"`
+	Reconciliation ReconciliationConfig `mapstructure:"reconciliation"`
+	API            APIConfig            `mapstructure:"api"`
+	Alerting       AlertingConfig       `mapstructure:"alerting"`
+	Tracing        TracingConfig        `mapstructure:"tracing"`
+	Log            LogConfig            `mapstructure:"log"`
+	Audit          AuditConfig          `mapstructure:"audit"`
+	EventCapture   EventCaptureConfig   `mapstructure:"event-capture"`
+	ErrorReporting ErrorReportingConfig `mapstructure:"error-reporting"`
+	Shutdown       ShutdownConfig       `mapstructure:"shutdown"`
+	LeaderElection LeaderElectionConfig `mapstructure:"leader-election"`
 }
 
 func (cfg *Config) Validate() error {
@@ -35,14 +46,57 @@ func (cfg *Config) Validate() error {
 		return err
 	}
 
-	if err := cfg.Queue.Validate(); err != nil {
+	if err := cfg.Emitter.Validate(); err != nil {
 		return err
 	}
 
+	// The queue section is only used when publishing to RabbitMQ
+	if cfg.Emitter.IsRabbitMQ() {
+		if err := cfg.Queue.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if err := cfg.Poller.Validate(); err != nil {
 		return err
 	}
 
+	if err := cfg.Reconciliation.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.API.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Alerting.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Tracing.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Log.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.EventCapture.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.ErrorReporting.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Shutdown.Validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.LeaderElection.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -4,6 +4,14 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"time"
+)
+
+const (
+	DefaultSlowQueryThreshold   = 500 * time.Millisecond
+	DefaultSlowQueryLogInterval = time.Minute
+	DefaultFpCacheSize          = 1000
+	DefaultFpCacheTTL           = 5 * time.Minute
 )
 
 type DbConfig struct {
@@ -11,6 +19,46 @@ type DbConfig struct {
 	Password string `mapstructure:"password"`
 	DbName   string `mapstructure:"db-name"`
 	Address  string `mapstructure:"address"`
+	// SlowQueryThreshold is the duration above which an operation is logged
+	// and counted as slow, DefaultSlowQueryThreshold if unset
+	SlowQueryThreshold time.Duration `mapstructure:"slow-query-threshold"`
+	// SlowQueryLogInterval is the minimum delay between two slow query logs
+	// of the same method, DefaultSlowQueryLogInterval if unset
+	SlowQueryLogInterval time.Duration `mapstructure:"slow-query-log-interval"`
+	// FpCacheSize is the number of finality providers cached by BTC pk,
+	// DefaultFpCacheSize if unset
+	FpCacheSize int `mapstructure:"fp-cache-size"`
+	// FpCacheTTL is the duration a cached finality provider is served for,
+	// in case an update missed invalidating it, DefaultFpCacheTTL if unset
+	FpCacheTTL time.Duration `mapstructure:"fp-cache-ttl"`
+}
+
+func (cfg *DbConfig) GetSlowQueryThreshold() time.Duration {
+	if cfg.SlowQueryThreshold == 0 {
+		return DefaultSlowQueryThreshold
+	}
+	return cfg.SlowQueryThreshold
+}
+
+func (cfg *DbConfig) GetSlowQueryLogInterval() time.Duration {
+	if cfg.SlowQueryLogInterval == 0 {
+		return DefaultSlowQueryLogInterval
+	}
+	return cfg.SlowQueryLogInterval
+}
+
+func (cfg *DbConfig) GetFpCacheSize() int {
+	if cfg.FpCacheSize == 0 {
+		return DefaultFpCacheSize
+	}
+	return cfg.FpCacheSize
+}
+
+func (cfg *DbConfig) GetFpCacheTTL() time.Duration {
+	if cfg.FpCacheTTL == 0 {
+		return DefaultFpCacheTTL
+	}
+	return cfg.FpCacheTTL
 }
 
 func (cfg *DbConfig) Validate() error {
@@ -57,5 +105,21 @@ func (cfg *DbConfig) Validate() error {
 		return fmt.Errorf("port number must be between 1024 and 65535 (inclusive)")
 	}
 
+	if cfg.SlowQueryThreshold < 0 {
+		return fmt.Errorf("db slow-query-threshold must not be negative")
+	}
+
+	if cfg.SlowQueryLogInterval < 0 {
+		return fmt.Errorf("db slow-query-log-interval must not be negative")
+	}
+
+	if cfg.FpCacheSize < 0 {
+		return fmt.Errorf("db fp-cache-size must not be negative")
+	}
+
+	if cfg.FpCacheTTL < 0 {
+		return fmt.Errorf("db fp-cache-ttl must not be negative")
+	}
+
 	return nil
 }
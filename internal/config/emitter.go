@@ -0,0 +1,167 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	EmitterTypeRabbitMQ = "rabbitmq"
+	EmitterTypeKafka    = "kafka"
+	EmitterTypeWebhook  = "webhook"
+)
+
+// EmitterConfig defines the broker the staking events are published to
+type EmitterConfig struct {
+	// Type is either rabbitmq, configured by the queue section, kafka or
+	// webhook. RabbitMQ is used if unset.
+	Type     string         `mapstructure:"type"`
+	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
+	Kafka    KafkaConfig    `mapstructure:"kafka"`
+	Webhook  WebhookConfig  `mapstructure:"webhook"`
+	// SchemaVersions are the event schema versions emitted, keyed by queue
+	// name or webhook. Several versions get emitted side by side while
+	// consumers move to the latest one, version 0 being emitted if unset.
+	SchemaVersions map[string][]int `mapstructure:"schema-versions"`
+}
+
+// RabbitMQConfig defines how the staking events are published to the RabbitMQ
+// broker of the queue section
+type RabbitMQConfig struct {
+	// Exchange is the direct exchange the events are published to, declared
+	// and bound to each queue under its name. The events are published
+	// through the default exchange if empty.
+	Exchange string `mapstructure:"exchange"`
+	// QueueNames renames the queues the events are published to, keyed by
+	// their default name. The schema versions and the dead letter commands
+	// keep using the default names and the new ones respectively.
+	QueueNames map[string]string `mapstructure:"queue-names"`
+	// Transient publishes the events as transient messages, lost on a broker
+	// restart, and declares the exchange non durable. The queues are durable
+	// either way, as their consumers declare them.
+	Transient bool `mapstructure:"transient"`
+	// SkipPublisherConfirms publishes the events without waiting for the
+	// broker to confirm them, an event the broker drops going unnoticed
+	SkipPublisherConfirms bool `mapstructure:"skip-publisher-confirms"`
+}
+
+// KafkaConfig defines the Kafka cluster the staking events are published to
+type KafkaConfig struct {
+	Brokers []string `mapstructure:"brokers"`
+	// Topic publishes all the events to a single topic, their type being
+	// carried by a header. Otherwise each event type is published to the
+	// topic named after its RabbitMQ queue.
+	Topic string `mapstructure:"topic"`
+	// WriteTimeout bounds the wait for the acknowledgement of an event
+	WriteTimeout time.Duration `mapstructure:"write-timeout"`
+}
+
+// WebhookConfig defines the HTTPS endpoints the outbox events are POSTed to
+type WebhookConfig struct {
+	Endpoints []WebhookEndpointConfig `mapstructure:"endpoints"`
+	// Timeout bounds each delivery request
+	Timeout time.Duration `mapstructure:"timeout"`
+	// MaxRetries is the number of retries of a failed delivery, RetryBackoff
+	// being the delay before the first one and doubled for the next ones
+	MaxRetries   uint          `mapstructure:"max-retries"`
+	RetryBackoff time.Duration `mapstructure:"retry-backoff"`
+	// MaxFailureStreak is the number of consecutive failed deliveries after
+	// which an endpoint is disabled until the indexer restarts
+	MaxFailureStreak int `mapstructure:"max-failure-streak"`
+}
+
+type WebhookEndpointConfig struct {
+	URL string `mapstructure:"url"`
+	// Secret is the HMAC-SHA256 key signing the payloads of the endpoint
+	Secret string `mapstructure:"secret"`
+}
+
+// GetType returns the type of the emitter, rabbitmq if unset
+func (cfg *EmitterConfig) GetType() string {
+	if cfg.Type == "" {
+		return EmitterTypeRabbitMQ
+	}
+	return cfg.Type
+}
+
+func (cfg *EmitterConfig) IsRabbitMQ() bool {
+	return cfg.Type == "" || cfg.Type == EmitterTypeRabbitMQ
+}
+
+func (cfg *EmitterConfig) IsKafka() bool {
+	return cfg.Type == EmitterTypeKafka
+}
+
+func (cfg *EmitterConfig) IsWebhook() bool {
+	return cfg.Type == EmitterTypeWebhook
+}
+
+func (cfg *EmitterConfig) Validate() error {
+	switch cfg.Type {
+	case "", EmitterTypeRabbitMQ:
+		return cfg.RabbitMQ.Validate()
+	case EmitterTypeKafka:
+		return cfg.Kafka.Validate()
+	case EmitterTypeWebhook:
+		return cfg.Webhook.Validate()
+	default:
+		return fmt.Errorf("unknown emitter type %s", cfg.Type)
+	}
+}
+
+func (cfg *RabbitMQConfig) Validate() error {
+	for queueName, renamedTo := range cfg.QueueNames {
+		if renamedTo == "" {
+			return fmt.Errorf("rabbitmq queue-names renames queue %s to an empty name", queueName)
+		}
+	}
+
+	return nil
+}
+
+func (cfg *KafkaConfig) Validate() error {
+	if len(cfg.Brokers) == 0 {
+		return errors.New("kafka brokers must be set")
+	}
+
+	if cfg.WriteTimeout <= 0 {
+		return errors.New("kafka write-timeout must be positive")
+	}
+
+	return nil
+}
+
+func (cfg *WebhookConfig) Validate() error {
+	if len(cfg.Endpoints) == 0 {
+		return errors.New("webhook endpoints must be set")
+	}
+
+	for _, endpoint := range cfg.Endpoints {
+		endpointURL, err := url.Parse(endpoint.URL)
+		if err != nil {
+			return fmt.Errorf("invalid webhook endpoint url %s: %w", endpoint.URL, err)
+		}
+		if endpointURL.Scheme != "https" {
+			return fmt.Errorf("webhook endpoint %s must use https", endpoint.URL)
+		}
+		if endpoint.Secret == "" {
+			return fmt.Errorf("webhook endpoint %s is missing its secret", endpoint.URL)
+		}
+	}
+
+	if cfg.Timeout <= 0 {
+		return errors.New("webhook timeout must be positive")
+	}
+
+	if cfg.RetryBackoff <= 0 {
+		return errors.New("webhook retry-backoff must be positive")
+	}
+
+	if cfg.MaxFailureStreak <= 0 {
+		return errors.New("webhook max-failure-streak must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrorReportingConfig defines the Sentry compatible service the indexer
+// errors are reported to, for their aggregation
+type ErrorReportingConfig struct {
+	// DSN is the Sentry DSN of the project, e.g.
+	// https://<key>@<host>/<project id>, the errors are not reported if empty
+	DSN string `mapstructure:"dsn"`
+	// Environment tells the deployment the errors come from
+	Environment string `mapstructure:"environment"`
+	// Timeout bounds each report request
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func (cfg *ErrorReportingConfig) IsEnabled() bool {
+	return cfg.DSN != ""
+}
+
+func (cfg *ErrorReportingConfig) Validate() error {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return fmt.Errorf("invalid error-reporting dsn: %w", err)
+	}
+	if dsn.Scheme != "https" && dsn.Scheme != "http" {
+		return fmt.Errorf("error-reporting dsn must use https or http")
+	}
+	if dsn.User.Username() == "" {
+		return errors.New("error-reporting dsn is missing the public key")
+	}
+	if strings.Trim(dsn.Path, "/") == "" {
+		return errors.New("error-reporting dsn is missing the project id")
+	}
+
+	if cfg.Timeout <= 0 {
+		return errors.New("error-reporting timeout must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import "errors"
+
+// EventCaptureConfig defines the capture of the raw attributes of the BBN
+// events, for debugging their parsing
+type EventCaptureConfig struct {
+	// Enabled captures the events failing their parsing or handling, and a
+	// sample of the others
+	Enabled bool `mapstructure:"enabled"`
+	// SampleRate is the share of the successfully processed events captured,
+	// from 0 to 1
+	SampleRate float64 `mapstructure:"sample-rate"`
+	// MaxSizeBytes is the size of the capped collection of the captures, the
+	// oldest ones being overwritten once it is full
+	MaxSizeBytes int64 `mapstructure:"max-size-bytes"`
+	// RedactedAttributes are the keys of the event attributes whose value is
+	// not captured
+	RedactedAttributes []string `mapstructure:"redacted-attributes"`
+}
+
+func (cfg *EventCaptureConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return errors.New("event-capture sample-rate must be between 0 and 1")
+	}
+
+	if cfg.MaxSizeBytes <= 0 {
+		return errors.New("event-capture max-size-bytes must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// LeaderElectionConfig defines the election of the replica processing the BBN
+// blocks, when several indexer replicas run against the same database
+type LeaderElectionConfig struct {
+	// Enabled runs the indexing only while holding the leader lease, the
+	// standby replicas serving the api
+	Enabled bool `mapstructure:"enabled"`
+	// LeaseTTL is the time after which the lease of a leader that stopped
+	// renewing it is taken over by a standby
+	LeaseTTL time.Duration `mapstructure:"lease-ttl"`
+	// RenewInterval is the interval at which the leader renews its lease and
+	// the standby replicas try to acquire it
+	RenewInterval time.Duration `mapstructure:"renew-interval"`
+	// ClockSkewMargin is the clock skew tolerated between the replicas, the
+	// leader stopping the indexing that long before its lease expires
+	ClockSkewMargin time.Duration `mapstructure:"clock-skew-margin"`
+}
+
+func (cfg *LeaderElectionConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if cfg.RenewInterval <= 0 {
+		return errors.New("leader-election renew-interval must be positive")
+	}
+
+	if cfg.ClockSkewMargin < 0 {
+		return errors.New("leader-election clock-skew-margin must not be negative")
+	}
+
+	// the lease survives a failed renewal within the clock skew margin, and
+	// is stored with a precision of a second
+	if cfg.LeaseTTL < 2*cfg.RenewInterval+cfg.ClockSkewMargin || cfg.LeaseTTL < 2*time.Second {
+		return errors.New(
+			"leader-election lease-ttl must be at least 2 renew intervals plus the clock-skew-margin, and 2s",
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import "errors"
+
+// LogConfig defines the levels the indexer logs at on startup, those of the
+// components being adjustable at runtime through the admin API
+type LogConfig struct {
+	// Level is the level of the logs outside of the components and of the
+	// components without a level of their own
+	Level string `mapstructure:"level"`
+	// ComponentLevels are the levels of the components, by component name
+	ComponentLevels map[string]string `mapstructure:"component-levels"`
+	// BlockAllocations adds the heap allocations of the process during each
+	// BBN block to the block summaries. It is meant for debugging, reading
+	// the allocations stopping the world twice a block.
+	BlockAllocations bool `mapstructure:"block-allocations"`
+}
+
+func (cfg *LogConfig) Validate() error {
+	if cfg.Level == "" {
+		return errors.New("log level is required")
+	}
+
+	return nil
+}
@@ -3,10 +3,13 @@ package config
 import (
 	"fmt"
 	"net"
+	"strconv"
 )
 
 // MetricsConfig defines the server's metric configuration
 type MetricsConfig struct {
+	// Enabled starts the prometheus server serving /metrics
+	Enabled bool `mapstructure:"enabled"`
 	// IP of the prometheus server
 	Host string `mapstructure:"host"`
 	// Port of the prometheus server
@@ -14,6 +17,10 @@ type MetricsConfig struct {
 }
 
 func (cfg *MetricsConfig) Validate() error {
+	if !cfg.Enabled {
+		return nil
+	}
+
 	if cfg.Port < 1024 || cfg.Port > 65535 {
 		return fmt.Errorf("metrics server port must be between 1024 and 65535 (inclusive)")
 	}
@@ -26,6 +33,7 @@ func (cfg *MetricsConfig) Validate() error {
 	return nil
 }
 
-func (cfg *MetricsConfig) GetMetricsPort() int {
-	return cfg.Port
+// GetMetricsAddress returns the address the prometheus server listens on
+func (cfg *MetricsConfig) GetMetricsAddress() string {
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
 }
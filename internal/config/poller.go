@@ -2,15 +2,75 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 )
 
+// stuckDelegationStates are the delegation states a delegation may get stuck in
+var stuckDelegationStates = []types.DelegationState{
+	types.StatePending,
+	types.StateVerified,
+	types.StateActive,
+	types.StateUnbonding,
+	types.StateWithdrawable,
+	types.StateSlashed,
+}
+
 type PollerConfig struct {
-	ParamPollingInterval         time.Duration `mapstructure:"param-polling-interval"`
-	ExpiryCheckerPollingInterval time.Duration `mapstructure:"expiry-checker-polling-interval"`
-	ExpiredDelegationsLimit      uint64        `mapstructure:"expired-delegations-limit"`
+	ParamPollingInterval           time.Duration `mapstructure:"param-polling-interval"`
+	ExpiryCheckerPollingInterval   time.Duration `mapstructure:"expiry-checker-polling-interval"`
+	ExpiredDelegationsLimit        uint64        `mapstructure:"expired-delegations-limit"`
+	FpActiveSetPollingInterval     time.Duration `mapstructure:"fp-active-set-polling-interval"`
+	BtcReorgCheckerPollingInterval time.Duration `mapstructure:"btc-reorg-checker-polling-interval"`
+	ProcessedHeightAuditInterval   time.Duration `mapstructure:"processed-height-audit-interval"`
+	TimeLockCleanupInterval        time.Duration `mapstructure:"timelock-cleanup-interval"`
+	StuckDelegationCheckerInterval time.Duration `mapstructure:"stuck-delegation-checker-polling-interval"`
+	OutboxRelayInterval            time.Duration `mapstructure:"outbox-relay-interval"`
+	// OutboxRelayMaxAttempts is the number of failed pushes after which an
+	// outbox event is flagged poison
+	OutboxRelayMaxAttempts int `mapstructure:"outbox-relay-max-attempts"`
+	// OutboxRelayRetryBackoff is the delay before the first retry of a failed
+	// push, doubled on every further failure
+	OutboxRelayRetryBackoff time.Duration `mapstructure:"outbox-relay-retry-backoff"`
+	// StuckDelegationThresholds is the time after which a delegation is
+	// considered stuck in a state, by state
+	StuckDelegationThresholds map[string]time.Duration `mapstructure:"stuck-delegation-thresholds"`
+	// StuckDelegationsReportLimit is the number of the longest stuck
+	// delegations listed in a stuck delegation report
+	StuckDelegationsReportLimit uint64 `mapstructure:"stuck-delegations-report-limit"`
+	// FpVotingPowerChangeThreshold is the relative change of a finality
+	// provider's voting power (e.g. 0.05 for 5%) above which a new voting
+	// power change is recorded
+	FpVotingPowerChangeThreshold float64 `mapstructure:"fp-voting-power-change-threshold"`
+	// StatsUpdateInterval is the interval between the updates of the global
+	// stats document
+	StatsUpdateInterval time.Duration `mapstructure:"stats-update-interval"`
+	// BbnLagPollingInterval is the interval between the updates of the BBN
+	// processed height, chain tip and lag metrics
+	BbnLagPollingInterval time.Duration `mapstructure:"bbn-lag-polling-interval"`
+	// DelegationMetricsInterval is the interval between the refreshes of the
+	// delegation count and staking amount metrics by state
+	DelegationMetricsInterval time.Duration `mapstructure:"delegation-metrics-interval"`
+	// BtcBackendMonitorInterval is the interval between the comparisons of
+	// the tracked BTC tip with the BBN btclightclient one
+	BtcBackendMonitorInterval time.Duration `mapstructure:"btc-backend-monitor-interval"`
+	// FpStakeMetricsInterval is the interval between the refreshes of the
+	// top finality providers by active stake metrics, an aggregation over
+	// all the active delegations
+	FpStakeMetricsInterval time.Duration `mapstructure:"fp-stake-metrics-interval"`
+	// FpStakeMetricsTopN is the number of finality providers holding the
+	// most active stake exported by btc pk
+	FpStakeMetricsTopN int64 `mapstructure:"fp-stake-metrics-top-n"`
 }
 
+// maxFpStakeMetricsTopN bounds the finality providers exported by btc pk, so
+// that the label cardinality stays reasonable
+const maxFpStakeMetricsTopN = 100
+
 func (cfg *PollerConfig) Validate() error {
 	if cfg.ParamPollingInterval <= 0 {
 		return errors.New("param-polling-interval must be positive")
@@ -24,5 +84,88 @@ func (cfg *PollerConfig) Validate() error {
 		return errors.New("expired-delegations-limit must be positive")
 	}
 
+	if cfg.FpActiveSetPollingInterval <= 0 {
+		return errors.New("fp-active-set-polling-interval must be positive")
+	}
+
+	if cfg.BtcReorgCheckerPollingInterval <= 0 {
+		return errors.New("btc-reorg-checker-polling-interval must be positive")
+	}
+
+	if cfg.ProcessedHeightAuditInterval <= 0 {
+		return errors.New("processed-height-audit-interval must be positive")
+	}
+
+	if cfg.TimeLockCleanupInterval <= 0 {
+		return errors.New("timelock-cleanup-interval must be positive")
+	}
+
+	if cfg.StuckDelegationCheckerInterval <= 0 {
+		return errors.New("stuck-delegation-checker-polling-interval must be positive")
+	}
+
+	if cfg.OutboxRelayInterval <= 0 {
+		return errors.New("outbox-relay-interval must be positive")
+	}
+
+	if cfg.OutboxRelayMaxAttempts <= 0 {
+		return errors.New("outbox-relay-max-attempts must be positive")
+	}
+
+	if cfg.OutboxRelayRetryBackoff <= 0 {
+		return errors.New("outbox-relay-retry-backoff must be positive")
+	}
+
+	for state, threshold := range cfg.StuckDelegationThresholds {
+		if !utils.Contains(stuckDelegationStates, types.DelegationState(strings.ToUpper(state))) {
+			return fmt.Errorf("stuck-delegation-thresholds: %s is not a non-terminal delegation state", state)
+		}
+		if threshold <= 0 {
+			return fmt.Errorf("stuck-delegation-thresholds: threshold of %s must be positive", state)
+		}
+	}
+
+	if cfg.StuckDelegationsReportLimit <= 0 {
+		return errors.New("stuck-delegations-report-limit must be positive")
+	}
+
+	if cfg.FpVotingPowerChangeThreshold < 0 {
+		return errors.New("fp-voting-power-change-threshold must not be negative")
+	}
+
+	if cfg.StatsUpdateInterval <= 0 {
+		return errors.New("stats-update-interval must be positive")
+	}
+
+	if cfg.BbnLagPollingInterval <= 0 {
+		return errors.New("bbn-lag-polling-interval must be positive")
+	}
+
+	if cfg.DelegationMetricsInterval <= 0 {
+		return errors.New("delegation-metrics-interval must be positive")
+	}
+
+	if cfg.BtcBackendMonitorInterval <= 0 {
+		return errors.New("btc-backend-monitor-interval must be positive")
+	}
+
+	if cfg.FpStakeMetricsInterval <= 0 {
+		return errors.New("fp-stake-metrics-interval must be positive")
+	}
+
+	if cfg.FpStakeMetricsTopN <= 0 || cfg.FpStakeMetricsTopN > maxFpStakeMetricsTopN {
+		return fmt.Errorf("fp-stake-metrics-top-n must be between 1 and %d", maxFpStakeMetricsTopN)
+	}
+
 	return nil
 }
+
+// GetStuckDelegationThresholds returns the stuck delegation thresholds by
+// delegation state. Config keys are case insensitive.
+func (cfg *PollerConfig) GetStuckDelegationThresholds() map[types.DelegationState]time.Duration {
+	thresholds := make(map[types.DelegationState]time.Duration, len(cfg.StuckDelegationThresholds))
+	for state, threshold := range cfg.StuckDelegationThresholds {
+		thresholds[types.DelegationState(strings.ToUpper(state))] = threshold
+	}
+	return thresholds
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// ReconciliationConfig defines configuration for the reconciliation of the
+// indexed delegations and finality providers against the BBN chain state
+type ReconciliationConfig struct {
+	Interval time.Duration `mapstructure:"interval"`
+	// BatchSize is the number of items checked between two cursor saves
+	BatchSize uint64 `mapstructure:"batch-size"`
+	// RequestsPerSecond limits the BBN queries issued by a reconciliation run
+	RequestsPerSecond float64 `mapstructure:"requests-per-second"`
+	// Fix applies safe corrections in the scheduled runs
+	Fix bool `mapstructure:"fix"`
+}
+
+func (cfg *ReconciliationConfig) Validate() error {
+	if cfg.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	if cfg.BatchSize <= 0 {
+		return errors.New("batch-size must be positive")
+	}
+
+	if cfg.RequestsPerSecond <= 0 {
+		return errors.New("requests-per-second must be positive")
+	}
+
+	return nil
+}
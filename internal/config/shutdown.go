@@ -0,0 +1,32 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultShutdownGracePeriod is the default time the indexer is given to stop
+// on SIGINT or SIGTERM
+const DefaultShutdownGracePeriod = 30 * time.Second
+
+// ShutdownConfig defines the graceful shutdown of the indexer
+type ShutdownConfig struct {
+	// GracePeriod bounds the whole shutdown sequence, the stages not done by
+	// then being abandoned
+	GracePeriod time.Duration `mapstructure:"grace-period"`
+}
+
+func (cfg *ShutdownConfig) Validate() error {
+	if cfg.GracePeriod < 0 {
+		return errors.New("shutdown grace-period must not be negative")
+	}
+
+	return nil
+}
+
+func (cfg *ShutdownConfig) GetGracePeriod() time.Duration {
+	if cfg.GracePeriod == 0 {
+		return DefaultShutdownGracePeriod
+	}
+	return cfg.GracePeriod
+}
@@ -0,0 +1,31 @@
+package config
+
+import "errors"
+
+// TracingConfig defines the OTLP collector the traces of the block
+// processing are exported to
+type TracingConfig struct {
+	// OtlpEndpoint is the host:port of the OTLP gRPC collector, the traces
+	// are not exported if empty
+	OtlpEndpoint string `mapstructure:"otlp-endpoint"`
+	// Insecure connects to the collector without TLS
+	Insecure bool `mapstructure:"insecure"`
+	// SampleRatio is the share of the traces exported, from 0 to 1
+	SampleRatio float64 `mapstructure:"sample-ratio"`
+}
+
+func (cfg *TracingConfig) IsEnabled() bool {
+	return cfg.OtlpEndpoint != ""
+}
+
+func (cfg *TracingConfig) Validate() error {
+	if !cfg.IsEnabled() {
+		return nil
+	}
+
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		return errors.New("tracing sample-ratio must be above 0 and at most 1")
+	}
+
+	return nil
+}
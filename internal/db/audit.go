@@ -0,0 +1,357 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditDatabase writes an audit record of every mutation of the indexed
+// delegations, finality providers, timelocks and params, with the trigger of
+// the scope it is made in, so that no handler can forget to audit. The
+// bookkeeping writes, e.g. of the processing progress, the outbox or the
+// state transition history, pass through unaudited.
+type AuditDatabase struct {
+	DbInterface
+}
+
+func NewAuditDatabase(dbClient DbInterface) *AuditDatabase {
+	return &AuditDatabase{DbInterface: dbClient}
+}
+
+func (d *AuditDatabase) SaveNewFinalityProvider(
+	ctx context.Context, fpDoc *model.FinalityProviderDetails,
+) error {
+	err := d.DbInterface.SaveNewFinalityProvider(ctx, fpDoc)
+	audit.Record(ctx, "SaveNewFinalityProvider", err, map[string]any{
+		logging.FpBtcPkField: fpDoc.BtcPk,
+		"state":              fpDoc.State,
+	})
+	return err
+}
+
+func (d *AuditDatabase) UpdateFinalityProviderState(
+	ctx context.Context, btcPk string, newState string,
+) error {
+	err := d.DbInterface.UpdateFinalityProviderState(ctx, btcPk, newState)
+	audit.Record(ctx, "UpdateFinalityProviderState", err, map[string]any{
+		logging.FpBtcPkField: btcPk,
+		"state":              newState,
+	})
+	return err
+}
+
+func (d *AuditDatabase) UpdateFinalityProviderDetailsFromEvent(
+	ctx context.Context, detailsToUpdate *model.FinalityProviderDetails,
+) error {
+	err := d.DbInterface.UpdateFinalityProviderDetailsFromEvent(ctx, detailsToUpdate)
+	audit.Record(ctx, "UpdateFinalityProviderDetailsFromEvent", err, map[string]any{
+		logging.FpBtcPkField: detailsToUpdate.BtcPk,
+	})
+	return err
+}
+
+func (d *AuditDatabase) SaveFinalityProviderVotingPowerChange(
+	ctx context.Context, change *model.FinalityProviderVotingPowerChange,
+) error {
+	err := d.DbInterface.SaveFinalityProviderVotingPowerChange(ctx, change)
+	audit.Record(ctx, "SaveFinalityProviderVotingPowerChange", err, map[string]any{
+		logging.FpBtcPkField: change.FinalityProviderBtcPkHex,
+		"active":             change.Active,
+		"voting_power":       change.VotingPower,
+	})
+	return err
+}
+
+func (d *AuditDatabase) SaveStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	err := d.DbInterface.SaveStakingParams(ctx, version, params)
+	audit.Record(ctx, "SaveStakingParams", err, map[string]any{"version": version})
+	return err
+}
+
+func (d *AuditDatabase) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	err := d.DbInterface.ReplaceStakingParams(ctx, version, params)
+	audit.Record(ctx, "ReplaceStakingParams", err, map[string]any{"version": version})
+	return err
+}
+
+func (d *AuditDatabase) SaveCheckpointParams(
+	ctx context.Context, params *bbnclient.CheckpointParams,
+) error {
+	err := d.DbInterface.SaveCheckpointParams(ctx, params)
+	audit.Record(ctx, "SaveCheckpointParams", err, nil)
+	return err
+}
+
+func (d *AuditDatabase) SaveNewBTCDelegation(
+	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
+) error {
+	err := d.DbInterface.SaveNewBTCDelegation(ctx, delegationDoc)
+	audit.Record(ctx, "SaveNewBTCDelegation", err, map[string]any{
+		logging.StakingTxHashField: delegationDoc.StakingTxHashHex,
+		"state":                    delegationDoc.State,
+	})
+	return err
+}
+
+func (d *AuditDatabase) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	err := d.DbInterface.UpdateBTCDelegationState(ctx, stakingTxHash, qualifiedPreviousStates, newState, newSubState)
+	fields := map[string]any{logging.StakingTxHashField: stakingTxHash, "state": newState}
+	if newSubState != nil {
+		fields["sub_state"] = *newSubState
+	}
+	audit.Record(ctx, "UpdateBTCDelegationState", err, fields)
+	return err
+}
+
+func (d *AuditDatabase) ApplyBTCDelegationStateChange(
+	ctx context.Context, change DelegationStateChange,
+) error {
+	err := d.DbInterface.ApplyBTCDelegationStateChange(ctx, change)
+	fields := map[string]any{logging.StakingTxHashField: change.StakingTxHash, "state": change.NewState}
+	if change.NewSubState != nil {
+		fields["sub_state"] = *change.NewSubState
+	}
+	audit.Record(ctx, "ApplyBTCDelegationStateChange", err, fields)
+	return err
+}
+
+func (d *AuditDatabase) OverrideBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	currentState types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	err := d.DbInterface.OverrideBTCDelegationState(ctx, stakingTxHash, currentState, newState, newSubState)
+	fields := map[string]any{
+		logging.StakingTxHashField: stakingTxHash, "previous_state": currentState, "state": newState,
+	}
+	if newSubState != nil {
+		fields["sub_state"] = *newSubState
+	}
+	audit.Record(ctx, "OverrideBTCDelegationState", err, fields)
+	return err
+}
+
+func (d *AuditDatabase) UpdateBTCDelegationSubState(
+	ctx context.Context,
+	stakingTxHash string,
+	state types.DelegationState,
+	newSubState types.DelegationSubState,
+) error {
+	err := d.DbInterface.UpdateBTCDelegationSubState(ctx, stakingTxHash, state, newSubState)
+	audit.Record(ctx, "UpdateBTCDelegationSubState", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHash, "state": state, "sub_state": newSubState,
+	})
+	return err
+}
+
+func (d *AuditDatabase) SaveBTCDelegationUnbondingCovenantSignature(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
+) error {
+	err := d.DbInterface.SaveBTCDelegationUnbondingCovenantSignature(ctx, stakingTxHash, covenantBtcPkHex, signatureHex)
+	audit.Record(ctx, "SaveBTCDelegationUnbondingCovenantSignature", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHash,
+		"covenant_btc_pk":          covenantBtcPkHex,
+	})
+	return err
+}
+
+func (d *AuditDatabase) SetCovenantSignatureVerified(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+) error {
+	err := d.DbInterface.SetCovenantSignatureVerified(ctx, stakingTxHash, covenantBtcPkHex, verified)
+	audit.Record(ctx, "SetCovenantSignatureVerified", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHash,
+		"covenant_btc_pk":          covenantBtcPkHex,
+		"verified":                 verified,
+	})
+	return err
+}
+
+func (d *AuditDatabase) UpdateBTCDelegationDetails(
+	ctx context.Context, stakingTxHash string, details *model.BTCDelegationDetails,
+) error {
+	err := d.DbInterface.UpdateBTCDelegationDetails(ctx, stakingTxHash, details)
+	audit.Record(ctx, "UpdateBTCDelegationDetails", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHash,
+		"state":                    details.State,
+	})
+	return err
+}
+
+func (d *AuditDatabase) BulkUpdateDelegationStates(
+	ctx context.Context, updates []DelegationStateUpdate,
+) error {
+	err := d.DbInterface.BulkUpdateDelegationStates(ctx, updates)
+	for i, update := range updates {
+		audit.Record(ctx, "BulkUpdateDelegationStates", bulkItemError(err, i), map[string]any{
+			logging.StakingTxHashField: update.StakingTxHash,
+			"state":                    update.NewState,
+		})
+	}
+	return err
+}
+
+func (d *AuditDatabase) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []CovenantSigRecord,
+) error {
+	err := d.DbInterface.BulkSaveCovenantSignatures(ctx, sigs)
+	for i, sig := range sigs {
+		audit.Record(ctx, "BulkSaveCovenantSignatures", bulkItemError(err, i), map[string]any{
+			logging.StakingTxHashField: sig.StakingTxHash,
+			"covenant_btc_pk":          sig.CovenantBtcPkHex,
+		})
+	}
+	return err
+}
+
+// bulkItemError returns the error of the item of a batched write, the error
+// of the whole write if it failed before reporting the items
+func bulkItemError(err error, item int) error {
+	var bulkErr *BulkWriteError
+	if !errors.As(err, &bulkErr) {
+		return err
+	}
+	for _, failure := range bulkErr.Failures {
+		if failure.Index == item {
+			return failure.Err
+		}
+	}
+	return nil
+}
+
+func (d *AuditDatabase) UpdateDelegationsStateByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, newState types.DelegationState,
+) error {
+	err := d.DbInterface.UpdateDelegationsStateByFinalityProvider(ctx, fpBtcPkHex, newState)
+	audit.Record(ctx, "UpdateDelegationsStateByFinalityProvider", err, map[string]any{
+		logging.FpBtcPkField: fpBtcPkHex,
+		"state":              newState,
+	})
+	return err
+}
+
+func (d *AuditDatabase) SaveBTCDelegationSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, slashingTxHex string, spendingHeight uint32,
+) error {
+	err := d.DbInterface.SaveBTCDelegationSlashingTxHex(ctx, stakingTxHashHex, slashingTxHex, spendingHeight)
+	audit.Record(ctx, "SaveBTCDelegationSlashingTxHex", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHashHex,
+		"spending_height":          spendingHeight,
+	})
+	return err
+}
+
+func (d *AuditDatabase) SaveBTCDelegationUnbondingSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, unbondingSlashingTxHex string, spendingHeight uint32,
+) error {
+	err := d.DbInterface.SaveBTCDelegationUnbondingSlashingTxHex(ctx, stakingTxHashHex, unbondingSlashingTxHex, spendingHeight)
+	audit.Record(ctx, "SaveBTCDelegationUnbondingSlashingTxHex", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHashHex,
+		"spending_height":          spendingHeight,
+	})
+	return err
+}
+
+func (d *AuditDatabase) DeleteBTCDelegation(
+	ctx context.Context, stakingTxHashHex string,
+) error {
+	err := d.DbInterface.DeleteBTCDelegation(ctx, stakingTxHashHex)
+	audit.Record(ctx, "DeleteBTCDelegation", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHashHex,
+	})
+	return err
+}
+
+func (d *AuditDatabase) SaveNewTimeLockExpire(
+	ctx context.Context, stakingTxHashHex string, expireHeight uint32, subState types.DelegationSubState,
+) error {
+	err := d.DbInterface.SaveNewTimeLockExpire(ctx, stakingTxHashHex, expireHeight, subState)
+	audit.Record(ctx, "SaveNewTimeLockExpire", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHashHex,
+		"expire_height":            expireHeight,
+		"sub_state":                subState,
+	})
+	return err
+}
+
+func (d *AuditDatabase) DeleteExpiredDelegation(
+	ctx context.Context, stakingTxHashHex string,
+) error {
+	err := d.DbInterface.DeleteExpiredDelegation(ctx, stakingTxHashHex)
+	audit.Record(ctx, "DeleteExpiredDelegation", err, map[string]any{
+		logging.StakingTxHashField: stakingTxHashHex,
+	})
+	return err
+}
+
+func (d *AuditDatabase) UpdateTimeLockExpireHeight(
+	ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32,
+) error {
+	err := d.DbInterface.UpdateTimeLockExpireHeight(ctx, timeLock, newExpireHeight)
+	audit.Record(ctx, "UpdateTimeLockExpireHeight", err, map[string]any{
+		logging.StakingTxHashField: timeLock.StakingTxHashHex,
+		"expire_height":            newExpireHeight,
+	})
+	return err
+}
+
+func (d *AuditDatabase) RollbackBTCDerivedChanges(
+	ctx context.Context, forkHeight uint64,
+) ([]*model.BTCDerivedChange, error) {
+	changes, err := d.DbInterface.RollbackBTCDerivedChanges(ctx, forkHeight)
+	for _, change := range changes {
+		audit.Record(ctx, "RollbackBTCDerivedChanges", err, map[string]any{
+			logging.StakingTxHashField: change.StakingTxHashHex,
+			"fork_height":              forkHeight,
+		})
+	}
+	if err != nil {
+		audit.Record(ctx, "RollbackBTCDerivedChanges", err, map[string]any{
+			"fork_height": forkHeight,
+		})
+	}
+	return changes, err
+}
+
+func (d *AuditDatabase) DeleteTimeLocks(
+	ctx context.Context, ids []primitive.ObjectID,
+) (uint64, error) {
+	deleted, err := d.DbInterface.DeleteTimeLocks(ctx, ids)
+	audit.Record(ctx, "DeleteTimeLocks", err, map[string]any{"deleted": deleted})
+	return deleted, err
+}
+
+func (d *AuditDatabase) ArchivePrunableBTCDelegations(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	archived, err := d.DbInterface.ArchivePrunableBTCDelegations(ctx, before, limit)
+	audit.Record(ctx, "ArchivePrunableBTCDelegations", err, map[string]any{"archived": archived})
+	return archived, err
+}
+
+func (d *AuditDatabase) DeletePrunableArchivedTimeLocks(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	deleted, err := d.DbInterface.DeletePrunableArchivedTimeLocks(ctx, before, limit)
+	audit.Record(ctx, "DeletePrunableArchivedTimeLocks", err, map[string]any{"deleted": deleted})
+	return deleted, err
+}
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (db *Database) SaveBbnEventDeadLetter(
+	ctx context.Context, deadLetter *model.BbnEventDeadLetter,
+) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.BbnEventDeadLettersCollection).
+		ReplaceOne(ctx, bson.M{"_id": deadLetter.Id}, deadLetter, options.Replace().SetUpsert(true))
+	return err
+}
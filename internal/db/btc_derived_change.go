@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Database) SaveBTCDerivedChange(
+	ctx context.Context, change *model.BTCDerivedChange,
+) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.BTCDerivedChangesCollection).
+		InsertOne(ctx, change)
+	return err
+}
+
+func (db *Database) RollbackBTCDerivedChanges(
+	ctx context.Context, forkHeight uint64,
+) ([]*model.BTCDerivedChange, error) {
+	changesCollection := db.client.Database(db.dbName).
+		Collection(model.BTCDerivedChangesCollection)
+
+	// Undo the most recent changes first, so that each delegation ends up in
+	// the state it had at the fork height
+	filter := bson.M{"btc_height": bson.M{"$gt": forkHeight}}
+	opts := options.Find().SetSort(bson.D{{Key: "btc_height", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := changesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*model.BTCDerivedChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	// Each change is undone and deleted in a transaction of its own, so that
+	// a change is never undone twice nor deleted without being undone
+	for _, change := range changes {
+		_, err := db.withTransaction(ctx, "RollbackBTCDerivedChanges", func(sessCtx mongo.SessionContext) (interface{}, error) {
+			if err := db.undoBTCDerivedChange(sessCtx, change); err != nil {
+				return nil, fmt.Errorf(
+					"failed to undo BTC derived change of delegation %s at height %d: %w",
+					change.StakingTxHashHex, change.BtcHeight, err,
+				)
+			}
+			_, err := changesCollection.DeleteOne(sessCtx, bson.M{"_id": change.Id})
+			return nil, err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
+
+func (db *Database) undoBTCDerivedChange(
+	ctx context.Context, change *model.BTCDerivedChange,
+) error {
+	set := bson.M{}
+	unset := bson.M{}
+	if change.PreviousState != "" {
+		set["state"] = change.PreviousState.String()
+		set["state_updated_at"] = time.Now().Unix()
+		if change.PreviousSubState != "" {
+			set["sub_state"] = change.PreviousSubState.String()
+		} else {
+			unset["sub_state"] = ""
+		}
+	}
+	if change.PreviousSlashingTx != nil {
+		set["slashing_tx"] = change.PreviousSlashingTx
+	}
+
+	delegationUpdate := bson.M{}
+	if len(set) > 0 {
+		delegationUpdate["$set"] = set
+	}
+	if len(unset) > 0 {
+		delegationUpdate["$unset"] = unset
+	}
+
+	if len(delegationUpdate) > 0 {
+		res, err := db.client.Database(db.dbName).
+			Collection(model.BTCDelegationDetailsCollection).
+			UpdateOne(ctx, bson.M{"_id": change.StakingTxHashHex}, delegationUpdate)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return &NotFoundError{
+				Key:     change.StakingTxHashHex,
+				Message: "BTC delegation not found when rolling back BTC derived change",
+			}
+		}
+	}
+
+	timeLockCollection := db.client.Database(db.dbName).Collection(model.TimeLockCollection)
+	if change.CreatedTimeLock != nil {
+		if _, err := timeLockCollection.DeleteOne(ctx, bson.M{
+			"staking_tx_hash_hex":  change.CreatedTimeLock.StakingTxHashHex,
+			"expire_height":        change.CreatedTimeLock.ExpireHeight,
+			"delegation_sub_state": change.CreatedTimeLock.DelegationSubState,
+		}); err != nil {
+			return err
+		}
+	}
+	if change.DeletedTimeLock != nil {
+		deletedTimeLockFilter := bson.M{
+			"staking_tx_hash_hex":  change.DeletedTimeLock.StakingTxHashHex,
+			"expire_height":        change.DeletedTimeLock.ExpireHeight,
+			"delegation_sub_state": change.DeletedTimeLock.DelegationSubState,
+		}
+		// Upsert as the timelock document might not have been deleted
+		if _, err := timeLockCollection.ReplaceOne(
+			ctx, deletedTimeLockFilter, change.DeletedTimeLock, options.Replace().SetUpsert(true),
+		); err != nil {
+			return err
+		}
+		// The restored timelock is active again
+		if _, err := db.client.Database(db.dbName).
+			Collection(model.TimeLockArchiveCollection).
+			DeleteMany(ctx, deletedTimeLockFilter); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) DeleteBTCDerivedChangesBelow(ctx context.Context, height uint64) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.BTCDerivedChangesCollection).
+		DeleteMany(ctx, bson.M{"btc_height": bson.M{"$lt": height}})
+	return err
+}
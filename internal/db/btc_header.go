@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Database) SaveBTCHeader(ctx context.Context, header *model.BTCHeader) error {
+	filter := bson.M{"_id": header.Height}
+	opts := options.Replace().SetUpsert(true)
+	_, err := db.client.Database(db.dbName).
+		Collection(model.BTCHeadersCollection).
+		ReplaceOne(ctx, filter, header, opts)
+	return err
+}
+
+func (db *Database) GetBTCHeaderByHeight(
+	ctx context.Context, height uint64,
+) (*model.BTCHeader, error) {
+	var header model.BTCHeader
+	err := db.client.Database(db.dbName).
+		Collection(model.BTCHeadersCollection).
+		FindOne(ctx, bson.M{"_id": height}).
+		Decode(&header)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     strconv.FormatUint(height, 10),
+				Message: "BTC header not found",
+			}
+		}
+		return nil, err
+	}
+
+	return &header, nil
+}
+
+func (db *Database) GetLatestBTCHeader(ctx context.Context) (*model.BTCHeader, error) {
+	opts := options.FindOne().SetSort(bson.M{"_id": -1})
+
+	var header model.BTCHeader
+	err := db.client.Database(db.dbName).
+		Collection(model.BTCHeadersCollection).
+		FindOne(ctx, bson.M{}, opts).
+		Decode(&header)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     "latest",
+				Message: "no BTC header has been processed yet",
+			}
+		}
+		return nil, err
+	}
+
+	return &header, nil
+}
+
+func (db *Database) DeleteBTCHeadersAbove(ctx context.Context, height uint64) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.BTCHeadersCollection).
+		DeleteMany(ctx, bson.M{"_id": bson.M{"$gt": height}})
+	return err
+}
+
+func (db *Database) DeleteBTCHeadersBelow(ctx context.Context, height uint64) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.BTCHeadersCollection).
+		DeleteMany(ctx, bson.M{"_id": bson.M{"$lt": height}})
+	return err
+}
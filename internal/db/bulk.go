@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DelegationStateUpdate is a state update of a delegation, as applied by
+// UpdateBTCDelegationState
+type DelegationStateUpdate struct {
+	StakingTxHash           string
+	QualifiedPreviousStates []types.DelegationState
+	NewState                types.DelegationState
+	NewSubState             *types.DelegationSubState
+}
+
+// DelegationStateChange is a state update of a delegation along with the
+// records of the change, saved only if the update applies
+type DelegationStateChange struct {
+	DelegationStateUpdate
+	DelegationStateRecords
+}
+
+// DelegationStateRecords are the records of a delegation state change, any of
+// them optional
+type DelegationStateRecords struct {
+	// BTCDerivedChange journals an update derived from a BTC block, for it to
+	// be undone if the block is reorged out. Its previous state and sub state
+	// are set to the ones the delegation is updated from.
+	BTCDerivedChange *model.BTCDerivedChange
+	// Transition records the transition in the state history of the
+	// delegation. Its from state is set to the one the delegation is updated
+	// from.
+	Transition *model.DelegationStateTransition
+	// OutboxEvent is the event of the change to relay to the queue, left as
+	// is if saved already
+	OutboxEvent *model.OutboxEvent
+}
+
+// CovenantSigRecord is an unbonding covenant signature of a delegation
+type CovenantSigRecord struct {
+	StakingTxHash    string
+	CovenantBtcPkHex string
+	SignatureHex     string
+}
+
+// bulkWrite is the write of a batch item, the item rejected before the batch
+// is sent having a nil model and an error
+type bulkWrite struct {
+	item  int
+	key   string
+	model mongo.WriteModel
+	err   error
+}
+
+func (db *Database) BulkUpdateDelegationStates(
+	ctx context.Context, updates []DelegationStateUpdate,
+) error {
+	writes := make([]bulkWrite, len(updates))
+	for i, update := range updates {
+		writes[i] = bulkWrite{item: i, key: update.StakingTxHash}
+
+		var qualifiedStates []string
+		for _, state := range update.QualifiedPreviousStates {
+			if state.CanTransitionTo(update.NewState) {
+				qualifiedStates = append(qualifiedStates, state.String())
+			}
+		}
+		if len(qualifiedStates) == 0 {
+			writes[i].err = &InvalidStateTransitionError{
+				Key: update.StakingTxHash, From: update.QualifiedPreviousStates, To: update.NewState,
+			}
+			continue
+		}
+
+		updateFields := bson.M{
+			"state":            update.NewState.String(),
+			"state_updated_at": time.Now().Unix(),
+		}
+		if update.NewSubState != nil {
+			updateFields["sub_state"] = update.NewSubState.String()
+		}
+		writes[i].model = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": update.StakingTxHash, "state": bson.M{"$in": qualifiedStates}}).
+			SetUpdate(bson.M{"$set": updateFields})
+	}
+
+	// The update not matching its delegation is told apart from the others
+	// by the state of the delegation after the batch, the one not in the new
+	// state having been either missing or in a state not qualified
+	return db.bulkWrite(ctx, writes, func(delegation *model.BTCDelegationDetails, i int) error {
+		update := updates[i]
+		if delegation == nil {
+			return &NotFoundError{
+				Key:     update.StakingTxHash,
+				Message: "BTC delegation not found when updating its state",
+			}
+		}
+		if delegation.State == update.NewState {
+			return nil
+		}
+		return &StateTransitionError{
+			StakingTxHash: update.StakingTxHash,
+			CurrentState:  delegation.State,
+			TargetState:   update.NewState,
+		}
+	})
+}
+
+func (db *Database) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []CovenantSigRecord,
+) error {
+	writes := make([]bulkWrite, len(sigs))
+	for i, sig := range sigs {
+		// A covenant member signs the unbonding of a delegation once, so
+		// that its signature is not pushed twice on a replay of the batch
+		writes[i] = bulkWrite{
+			item: i,
+			key:  sig.StakingTxHash,
+			model: mongo.NewUpdateOneModel().
+				SetFilter(bson.M{
+					"_id": sig.StakingTxHash,
+					"covenant_unbonding_signatures.covenant_btc_pk_hex": bson.M{"$ne": sig.CovenantBtcPkHex},
+				}).
+				SetUpdate(bson.M{"$push": bson.M{
+					"covenant_unbonding_signatures": bson.M{
+						"covenant_btc_pk_hex": sig.CovenantBtcPkHex,
+						"signature_hex":       sig.SignatureHex,
+					},
+				}}),
+		}
+	}
+
+	return db.bulkWrite(ctx, writes, func(delegation *model.BTCDelegationDetails, i int) error {
+		sig := sigs[i]
+		if delegation == nil {
+			return &NotFoundError{
+				Key:     sig.StakingTxHash,
+				Message: "BTC delegation not found when saving covenant signature",
+			}
+		}
+		for _, saved := range delegation.CovenantUnbondingSignatures {
+			if saved.CovenantBtcPkHex == sig.CovenantBtcPkHex && saved.SignatureHex != sig.SignatureHex {
+				return &DuplicateKeyError{
+					Key:     sig.StakingTxHash,
+					Message: "covenant signature already saved: " + sig.CovenantBtcPkHex,
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// bulkWrite applies the writes of a batch in a single unordered BulkWrite to
+// the delegations and returns a BulkWriteError with the items which failed.
+// As the result of a BulkWrite does not tell which writes matched no
+// document, if fewer matched than sent, the delegations are read back and
+// checkUnmatched tells whether the write of the item applied, given its
+// delegation, nil if missing.
+func (db *Database) bulkWrite(
+	ctx context.Context,
+	writes []bulkWrite,
+	checkUnmatched func(delegation *model.BTCDelegationDetails, item int) error,
+) error {
+	var failures []BulkWriteFailure
+	var sent []bulkWrite
+	var models []mongo.WriteModel
+	for _, write := range writes {
+		if write.err != nil {
+			failures = append(failures, BulkWriteFailure{Index: write.item, Key: write.key, Err: write.err})
+			continue
+		}
+		sent = append(sent, write)
+		models = append(models, write.model)
+	}
+	if len(models) == 0 {
+		return newBulkWriteError(failures)
+	}
+
+	collection := db.client.Database(db.dbName).Collection(model.BTCDelegationDetailsCollection)
+	result, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+
+	// The writes failing on the server are reported by their index in the
+	// models sent
+	failedWrites := make(map[int]bool)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil || len(bulkErr.WriteErrors) == 0 {
+			return err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			write := sent[writeErr.Index]
+			failedWrites[writeErr.Index] = true
+			var itemErr error = writeErr.WriteError
+			if mongo.IsDuplicateKeyError(writeErr.WriteError) {
+				itemErr = &DuplicateKeyError{Key: write.key, Message: writeErr.Message}
+			}
+			failures = append(failures, BulkWriteFailure{Index: write.item, Key: write.key, Err: itemErr})
+		}
+	}
+
+	applied := len(models) - len(failedWrites)
+	if result != nil && int(result.MatchedCount) < applied {
+		keys := make([]string, 0, len(sent))
+		for i, write := range sent {
+			if !failedWrites[i] {
+				keys = append(keys, write.key)
+			}
+		}
+		delegations, err := db.GetBTCDelegationsByStakingTxHashes(ctx, keys)
+		if err != nil {
+			return err
+		}
+		for i, write := range sent {
+			if failedWrites[i] {
+				continue
+			}
+			if itemErr := checkUnmatched(delegations[write.key], write.item); itemErr != nil {
+				failures = append(failures, BulkWriteFailure{Index: write.item, Key: write.key, Err: itemErr})
+			}
+		}
+	}
+
+	return newBulkWriteError(failures)
+}
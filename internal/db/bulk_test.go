@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+)
+
+func TestBulkWriteError(t *testing.T) {
+	require.NoError(t, newBulkWriteError(nil))
+
+	err := newBulkWriteError([]BulkWriteFailure{
+		{Index: 3, Key: "b", Err: &DuplicateKeyError{Key: "b"}},
+		{Index: 1, Key: "a", Err: &NotFoundError{Key: "a"}},
+	})
+	var bulkErr *BulkWriteError
+	require.True(t, errors.As(err, &bulkErr))
+	require.Equal(t, 1, bulkErr.Failures[0].Index)
+	require.True(t, IsNotFoundError(err))
+	require.True(t, IsDuplicateKeyError(err))
+	require.False(t, IsStateTransitionError(err))
+
+	require.Equal(t, &NotFoundError{Key: "a"}, bulkItemError(err, 1))
+	require.NoError(t, bulkItemError(err, 2))
+	whole := errors.New("connection reset")
+	require.Equal(t, whole, bulkItemError(whole, 2))
+}
+
+// mongoTestDatabase connects to the Mongo of the <envPrefix>_MONGO_ADDRESS,
+// skipping the test if unset, and drops its database once done
+func mongoTestDatabase(tb testing.TB, envPrefix string, dbName string) *Database {
+	if os.Getenv(envPrefix+"_MONGO_ADDRESS") == "" {
+		tb.Skip(envPrefix + "_MONGO_ADDRESS is not set")
+	}
+	ctx := context.Background()
+	database, err := New(ctx, mongoTestConfig(envPrefix, dbName))
+	require.NoError(tb, err)
+	tb.Cleanup(func() {
+		_ = database.client.Database(database.dbName).Drop(ctx)
+		_ = database.client.Disconnect(ctx)
+	})
+	return database
+}
+
+// mongoTestConfig is the config of the database of the Mongo of the
+// <envPrefix>_MONGO_ADDRESS
+func mongoTestConfig(envPrefix string, dbName string) config.DbConfig {
+	return config.DbConfig{
+		Address:  os.Getenv(envPrefix + "_MONGO_ADDRESS"),
+		Username: os.Getenv(envPrefix + "_MONGO_USERNAME"),
+		Password: os.Getenv(envPrefix + "_MONGO_PASSWORD"),
+		DbName:   dbName,
+	}
+}
@@ -0,0 +1,981 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// primarySteppedDownCode is the code of the mongo error returned by a primary
+// stepping down
+const primarySteppedDownCode = 189
+
+// ChaosDatabase injects the faults programmed per method by the resilience
+// tests into the calls to the wrapped database: latency, errors such as the
+// transient ones of a primary stepdown or duplicate keys, and crashes. Every
+// method of DbInterface is wrapped explicitly, so that any of them can fail.
+type ChaosDatabase struct {
+	next DbInterface
+
+	mu sync.Mutex
+	// calls are the numbers of calls to every method so far
+	calls map[string]int
+	// faults are the faults programmed by method
+	faults map[string]Fault
+}
+
+// Fault is a failure injected into the calls to a method of ChaosDatabase
+type Fault struct {
+	// After is the number of calls to the method let through before the
+	// fault applies
+	After int
+	// Times is the number of calls the fault applies to, every later call if 0
+	Times int
+	// Latency delays the faulty calls
+	Latency time.Duration
+	// Err fails the faulty calls
+	Err error
+	// Panic crashes the faulty calls, as the indexer being killed during them
+	Panic bool
+	// Applied lets the faulty calls reach the wrapped database before
+	// failing or crashing, as a write applied but not acknowledged
+	Applied bool
+}
+
+// ChaosPanic is the value the calls crashed by ChaosDatabase panic with
+type ChaosPanic struct {
+	Method string
+	// Call is the number of the crashed call to the method
+	Call int
+}
+
+func (p ChaosPanic) String() string {
+	return fmt.Sprintf("chaos: crashed call %d to %s", p.Call, p.Method)
+}
+
+func NewChaosDatabase(next DbInterface) *ChaosDatabase {
+	return &ChaosDatabase{
+		next:   next,
+		calls:  make(map[string]int),
+		faults: make(map[string]Fault),
+	}
+}
+
+// NewSteppedDownError returns the error of a call to a primary stepping down,
+// which the driver labels as transient
+func NewSteppedDownError() error {
+	return mongo.CommandError{
+		Code:    primarySteppedDownCode,
+		Name:    "PrimarySteppedDown",
+		Message: "chaos: primary stepped down",
+		Labels:  []string{transientTransactionErrorLabel},
+	}
+}
+
+// Inject programs the fault into the calls to the method of DbInterface,
+// replacing the one programmed before, if any. The calls made so far count
+// towards the calls let through.
+func (d *ChaosDatabase) Inject(method string, fault Fault) {
+	if _, ok := reflect.TypeOf((*DbInterface)(nil)).Elem().MethodByName(method); !ok {
+		panic(fmt.Sprintf("chaos: DbInterface has no method %s", method))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.faults[method] = fault
+}
+
+// Heal removes the faults of every method
+func (d *ChaosDatabase) Heal() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.faults = make(map[string]Fault)
+}
+
+// Calls returns the number of calls to the method so far
+func (d *ChaosDatabase) Calls(method string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls[method]
+}
+
+// fault counts a call to the method and returns the fault applying to it, if
+// any, along with the number of the call
+func (d *ChaosDatabase) fault(method string) (*Fault, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls[method]++
+	call := d.calls[method]
+	fault, ok := d.faults[method]
+	if !ok || call <= fault.After || (fault.Times > 0 && call > fault.After+fault.Times) {
+		return nil, call
+	}
+	return &fault, call
+}
+
+// call runs fn, the call to the method of the wrapped database, through the
+// fault applying to it, if any
+func (d *ChaosDatabase) call(ctx context.Context, method string, fn func() error) error {
+	fault, call := d.fault(method)
+	if fault == nil {
+		return fn()
+	}
+
+	if fault.Latency > 0 {
+		select {
+		case <-time.After(fault.Latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var err error
+	if fault.Applied {
+		err = fn()
+	}
+	if fault.Panic {
+		panic(ChaosPanic{Method: method, Call: call})
+	}
+	switch {
+	case fault.Err != nil:
+		return fault.Err
+	case fault.Applied:
+		return err
+	default:
+		return fn()
+	}
+}
+
+// chaosCall runs fn, the call to the method of the wrapped database returning
+// a result, through the fault applying to it, if any
+func chaosCall[T any](ctx context.Context, d *ChaosDatabase, method string, fn func() (T, error)) (T, error) {
+	var result T
+	err := d.call(ctx, method, func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+func (d *ChaosDatabase) Ping(ctx context.Context) error {
+	return d.call(ctx, "Ping", func() error {
+		return d.next.Ping(ctx)
+	})
+}
+
+func (d *ChaosDatabase) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return d.call(ctx, "RunInTransaction", func() error {
+		return d.next.RunInTransaction(ctx, fn)
+	})
+}
+
+func (d *ChaosDatabase) SaveNewFinalityProvider(
+	ctx context.Context, fpDoc *model.FinalityProviderDetails,
+) error {
+	return d.call(ctx, "SaveNewFinalityProvider", func() error {
+		return d.next.SaveNewFinalityProvider(ctx, fpDoc)
+	})
+}
+
+func (d *ChaosDatabase) UpdateFinalityProviderState(
+	ctx context.Context, btcPk string, newState string,
+) error {
+	return d.call(ctx, "UpdateFinalityProviderState", func() error {
+		return d.next.UpdateFinalityProviderState(ctx, btcPk, newState)
+	})
+}
+
+func (d *ChaosDatabase) UpdateFinalityProviderDetailsFromEvent(
+	ctx context.Context, detailsToUpdate *model.FinalityProviderDetails,
+) error {
+	return d.call(ctx, "UpdateFinalityProviderDetailsFromEvent", func() error {
+		return d.next.UpdateFinalityProviderDetailsFromEvent(ctx, detailsToUpdate)
+	})
+}
+
+func (d *ChaosDatabase) GetFinalityProviderByBtcPk(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderDetails, error) {
+	return chaosCall(ctx, d, "GetFinalityProviderByBtcPk", func() (*model.FinalityProviderDetails, error) {
+		return d.next.GetFinalityProviderByBtcPk(ctx, btcPk)
+	})
+}
+
+func (d *ChaosDatabase) GetFinalityProvidersByBsnId(
+	ctx context.Context, bsnId string,
+) ([]*model.FinalityProviderDetails, error) {
+	return chaosCall(ctx, d, "GetFinalityProvidersByBsnId", func() ([]*model.FinalityProviderDetails, error) {
+		return d.next.GetFinalityProvidersByBsnId(ctx, bsnId)
+	})
+}
+
+func (d *ChaosDatabase) GetFinalityProviders(
+	ctx context.Context, filter FinalityProvidersFilter, paginationToken string, limit int64,
+) (*DbResultMap[*model.FinalityProviderDetails], error) {
+	return chaosCall(ctx, d, "GetFinalityProviders", func() (*DbResultMap[*model.FinalityProviderDetails], error) {
+		return d.next.GetFinalityProviders(ctx, filter, paginationToken, limit)
+	})
+}
+
+func (d *ChaosDatabase) GetFinalityProviderStats(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderStats, error) {
+	return chaosCall(ctx, d, "GetFinalityProviderStats", func() (*model.FinalityProviderStats, error) {
+		return d.next.GetFinalityProviderStats(ctx, btcPk)
+	})
+}
+
+func (d *ChaosDatabase) GetFinalityProviderStakeDistribution(
+	ctx context.Context, limit int64,
+) (*model.FinalityProviderStakeDistribution, error) {
+	return chaosCall(ctx, d, "GetFinalityProviderStakeDistribution", func() (*model.FinalityProviderStakeDistribution, error) {
+		return d.next.GetFinalityProviderStakeDistribution(ctx, limit)
+	})
+}
+
+func (d *ChaosDatabase) CountFinalityProvidersByState(
+	ctx context.Context,
+) (map[string]uint64, error) {
+	return chaosCall(ctx, d, "CountFinalityProvidersByState", func() (map[string]uint64, error) {
+		return d.next.CountFinalityProvidersByState(ctx)
+	})
+}
+
+func (d *ChaosDatabase) SaveStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	return d.call(ctx, "SaveStakingParams", func() error {
+		return d.next.SaveStakingParams(ctx, version, params)
+	})
+}
+
+func (d *ChaosDatabase) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	return d.call(ctx, "ReplaceStakingParams", func() error {
+		return d.next.ReplaceStakingParams(ctx, version, params)
+	})
+}
+
+func (d *ChaosDatabase) GetStakingParams(
+	ctx context.Context, version uint32,
+) (*bbnclient.StakingParams, error) {
+	return chaosCall(ctx, d, "GetStakingParams", func() (*bbnclient.StakingParams, error) {
+		return d.next.GetStakingParams(ctx, version)
+	})
+}
+
+func (d *ChaosDatabase) GetAllStakingParams(
+	ctx context.Context,
+) (map[uint32]*bbnclient.StakingParams, error) {
+	return chaosCall(ctx, d, "GetAllStakingParams", func() (map[uint32]*bbnclient.StakingParams, error) {
+		return d.next.GetAllStakingParams(ctx)
+	})
+}
+
+func (d *ChaosDatabase) SaveCheckpointParams(
+	ctx context.Context, params *bbnclient.CheckpointParams,
+) error {
+	return d.call(ctx, "SaveCheckpointParams", func() error {
+		return d.next.SaveCheckpointParams(ctx, params)
+	})
+}
+
+func (d *ChaosDatabase) GetCheckpointParams(
+	ctx context.Context,
+) (*bbnclient.CheckpointParams, error) {
+	return chaosCall(ctx, d, "GetCheckpointParams", func() (*bbnclient.CheckpointParams, error) {
+		return d.next.GetCheckpointParams(ctx)
+	})
+}
+
+func (d *ChaosDatabase) SaveNewBTCDelegation(
+	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
+) error {
+	return d.call(ctx, "SaveNewBTCDelegation", func() error {
+		return d.next.SaveNewBTCDelegation(ctx, delegationDoc)
+	})
+}
+
+func (d *ChaosDatabase) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	return d.call(ctx, "UpdateBTCDelegationState", func() error {
+		return d.next.UpdateBTCDelegationState(ctx, stakingTxHash, qualifiedPreviousStates, newState, newSubState)
+	})
+}
+
+func (d *ChaosDatabase) ApplyBTCDelegationStateChange(
+	ctx context.Context, change DelegationStateChange,
+) error {
+	return d.call(ctx, "ApplyBTCDelegationStateChange", func() error {
+		return d.next.ApplyBTCDelegationStateChange(ctx, change)
+	})
+}
+
+func (d *ChaosDatabase) OverrideBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	currentState types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	return d.call(ctx, "OverrideBTCDelegationState", func() error {
+		return d.next.OverrideBTCDelegationState(ctx, stakingTxHash, currentState, newState, newSubState)
+	})
+}
+
+func (d *ChaosDatabase) UpdateBTCDelegationSubState(
+	ctx context.Context,
+	stakingTxHash string,
+	state types.DelegationState,
+	newSubState types.DelegationSubState,
+) error {
+	return d.call(ctx, "UpdateBTCDelegationSubState", func() error {
+		return d.next.UpdateBTCDelegationSubState(ctx, stakingTxHash, state, newSubState)
+	})
+}
+
+func (d *ChaosDatabase) SaveBTCDelegationUnbondingCovenantSignature(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
+) error {
+	return d.call(ctx, "SaveBTCDelegationUnbondingCovenantSignature", func() error {
+		return d.next.SaveBTCDelegationUnbondingCovenantSignature(ctx, stakingTxHash, covenantBtcPkHex, signatureHex)
+	})
+}
+
+func (d *ChaosDatabase) SetCovenantSignatureVerified(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+) error {
+	return d.call(ctx, "SetCovenantSignatureVerified", func() error {
+		return d.next.SetCovenantSignatureVerified(ctx, stakingTxHash, covenantBtcPkHex, verified)
+	})
+}
+
+func (d *ChaosDatabase) BulkUpdateDelegationStates(
+	ctx context.Context, updates []DelegationStateUpdate,
+) error {
+	return d.call(ctx, "BulkUpdateDelegationStates", func() error {
+		return d.next.BulkUpdateDelegationStates(ctx, updates)
+	})
+}
+
+func (d *ChaosDatabase) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []CovenantSigRecord,
+) error {
+	return d.call(ctx, "BulkSaveCovenantSignatures", func() error {
+		return d.next.BulkSaveCovenantSignatures(ctx, sigs)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCDelegationState(
+	ctx context.Context, stakingTxHash string,
+) (*types.DelegationState, error) {
+	return chaosCall(ctx, d, "GetBTCDelegationState", func() (*types.DelegationState, error) {
+		return d.next.GetBTCDelegationState(ctx, stakingTxHash)
+	})
+}
+
+func (d *ChaosDatabase) UpdateBTCDelegationDetails(
+	ctx context.Context, stakingTxHash string, details *model.BTCDelegationDetails,
+) error {
+	return d.call(ctx, "UpdateBTCDelegationDetails", func() error {
+		return d.next.UpdateBTCDelegationDetails(ctx, stakingTxHash, details)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCDelegationByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "GetBTCDelegationByStakingTxHash", func() (*model.BTCDelegationDetails, error) {
+		return d.next.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCDelegationSummaryByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationSummary, error) {
+	return chaosCall(ctx, d, "GetBTCDelegationSummaryByStakingTxHash", func() (*model.BTCDelegationSummary, error) {
+		return d.next.GetBTCDelegationSummaryByStakingTxHash(ctx, stakingTxHash)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCDelegationsByStakingTxHashes(
+	ctx context.Context, stakingTxHashes []string,
+) (map[string]*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "GetBTCDelegationsByStakingTxHashes", func() (map[string]*model.BTCDelegationDetails, error) {
+		return d.next.GetBTCDelegationsByStakingTxHashes(ctx, stakingTxHashes)
+	})
+}
+
+func (d *ChaosDatabase) UpdateDelegationsStateByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, newState types.DelegationState,
+) error {
+	return d.call(ctx, "UpdateDelegationsStateByFinalityProvider", func() error {
+		return d.next.UpdateDelegationsStateByFinalityProvider(ctx, fpBtcPkHex, newState)
+	})
+}
+
+func (d *ChaosDatabase) GetDelegationsByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string,
+) ([]*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "GetDelegationsByFinalityProvider", func() ([]*model.BTCDelegationDetails, error) {
+		return d.next.GetDelegationsByFinalityProvider(ctx, fpBtcPkHex)
+	})
+}
+
+func (d *ChaosDatabase) SaveNewTimeLockExpire(
+	ctx context.Context, stakingTxHashHex string, expireHeight uint32, subState types.DelegationSubState,
+) error {
+	return d.call(ctx, "SaveNewTimeLockExpire", func() error {
+		return d.next.SaveNewTimeLockExpire(ctx, stakingTxHashHex, expireHeight, subState)
+	})
+}
+
+func (d *ChaosDatabase) FindExpiredDelegations(
+	ctx context.Context, btcTipHeight, limit uint64,
+) ([]model.TimeLockDocument, error) {
+	return chaosCall(ctx, d, "FindExpiredDelegations", func() ([]model.TimeLockDocument, error) {
+		return d.next.FindExpiredDelegations(ctx, btcTipHeight, limit)
+	})
+}
+
+func (d *ChaosDatabase) ForEachExpiredDelegation(
+	ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error,
+) error {
+	return d.call(ctx, "ForEachExpiredDelegation", func() error {
+		return d.next.ForEachExpiredDelegation(ctx, btcTipHeight, fn)
+	})
+}
+
+func (d *ChaosDatabase) GetExpiryBacklogStats(
+	ctx context.Context, btcTipHeight uint64,
+) ([]*model.ExpiryBacklogStats, error) {
+	return chaosCall(ctx, d, "GetExpiryBacklogStats", func() ([]*model.ExpiryBacklogStats, error) {
+		return d.next.GetExpiryBacklogStats(ctx, btcTipHeight)
+	})
+}
+
+func (d *ChaosDatabase) DeleteExpiredDelegation(
+	ctx context.Context, stakingTxHashHex string,
+) error {
+	return d.call(ctx, "DeleteExpiredDelegation", func() error {
+		return d.next.DeleteExpiredDelegation(ctx, stakingTxHashHex)
+	})
+}
+
+func (d *ChaosDatabase) FindOrphanedTimeLocks(
+	ctx context.Context, terminalStates []types.DelegationState, limit uint64,
+) ([]*model.OrphanedTimeLock, error) {
+	return chaosCall(ctx, d, "FindOrphanedTimeLocks", func() ([]*model.OrphanedTimeLock, error) {
+		return d.next.FindOrphanedTimeLocks(ctx, terminalStates, limit)
+	})
+}
+
+func (d *ChaosDatabase) DeleteTimeLocks(
+	ctx context.Context, ids []primitive.ObjectID,
+) (uint64, error) {
+	return chaosCall(ctx, d, "DeleteTimeLocks", func() (uint64, error) {
+		return d.next.DeleteTimeLocks(ctx, ids)
+	})
+}
+
+func (d *ChaosDatabase) GetTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]model.TimeLockDocument, error) {
+	return chaosCall(ctx, d, "GetTimeLocks", func() ([]model.TimeLockDocument, error) {
+		return d.next.GetTimeLocks(ctx, stakingTxHashHex)
+	})
+}
+
+func (d *ChaosDatabase) GetArchivedTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.ArchivedTimeLockDocument, error) {
+	return chaosCall(ctx, d, "GetArchivedTimeLocks", func() ([]*model.ArchivedTimeLockDocument, error) {
+		return d.next.GetArchivedTimeLocks(ctx, stakingTxHashHex)
+	})
+}
+
+func (d *ChaosDatabase) GetTimeLocksExpiringBetween(
+	ctx context.Context, fromHeight, toHeight uint32, paginationToken string, limit int64,
+) (*DbResultMap[*model.ExpiringTimeLock], error) {
+	return chaosCall(ctx, d, "GetTimeLocksExpiringBetween", func() (*DbResultMap[*model.ExpiringTimeLock], error) {
+		return d.next.GetTimeLocksExpiringBetween(ctx, fromHeight, toHeight, paginationToken, limit)
+	})
+}
+
+func (d *ChaosDatabase) DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error {
+	return d.call(ctx, "DeleteBTCDelegation", func() error {
+		return d.next.DeleteBTCDelegation(ctx, stakingTxHashHex)
+	})
+}
+
+func (d *ChaosDatabase) SaveDelegationStateTransition(
+	ctx context.Context, transition *model.DelegationStateTransition,
+) error {
+	return d.call(ctx, "SaveDelegationStateTransition", func() error {
+		return d.next.SaveDelegationStateTransition(ctx, transition)
+	})
+}
+
+func (d *ChaosDatabase) GetDelegationStateTransitions(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.DelegationStateTransition, error) {
+	return chaosCall(ctx, d, "GetDelegationStateTransitions", func() ([]*model.DelegationStateTransition, error) {
+		return d.next.GetDelegationStateTransitions(ctx, stakingTxHashHex)
+	})
+}
+
+func (d *ChaosDatabase) AggregateStateTransitions(
+	ctx context.Context,
+	fromTime, toTime int64,
+	periodUnit string,
+	visit func(stats *model.StateTransitionPeriodStats) error,
+) error {
+	return d.call(ctx, "AggregateStateTransitions", func() error {
+		return d.next.AggregateStateTransitions(ctx, fromTime, toTime, periodUnit, visit)
+	})
+}
+
+func (d *ChaosDatabase) FindTimeLocksByParamsVersion(
+	ctx context.Context, subStates []types.DelegationSubState, paramsVersion uint32,
+) ([]model.TimeLockDocument, error) {
+	return chaosCall(ctx, d, "FindTimeLocksByParamsVersion", func() ([]model.TimeLockDocument, error) {
+		return d.next.FindTimeLocksByParamsVersion(ctx, subStates, paramsVersion)
+	})
+}
+
+func (d *ChaosDatabase) UpdateTimeLockExpireHeight(
+	ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32,
+) error {
+	return d.call(ctx, "UpdateTimeLockExpireHeight", func() error {
+		return d.next.UpdateTimeLockExpireHeight(ctx, timeLock, newExpireHeight)
+	})
+}
+
+func (d *ChaosDatabase) GetLastProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	return chaosCall(ctx, d, "GetLastProcessedBbnHeight", func() (uint64, error) {
+		return d.next.GetLastProcessedBbnHeight(ctx)
+	})
+}
+
+func (d *ChaosDatabase) GetLastProcessedBbnBlock(
+	ctx context.Context,
+) (*model.LastProcessedHeight, error) {
+	return chaosCall(ctx, d, "GetLastProcessedBbnBlock", func() (*model.LastProcessedHeight, error) {
+		return d.next.GetLastProcessedBbnBlock(ctx)
+	})
+}
+
+func (d *ChaosDatabase) UpdateLastProcessedBbnHeight(
+	ctx context.Context, height uint64, blockHash string,
+) error {
+	return d.call(ctx, "UpdateLastProcessedBbnHeight", func() error {
+		return d.next.UpdateLastProcessedBbnHeight(ctx, height, blockHash)
+	})
+}
+
+func (d *ChaosDatabase) HaltBbnProcessing(ctx context.Context, reason string) error {
+	return d.call(ctx, "HaltBbnProcessing", func() error {
+		return d.next.HaltBbnProcessing(ctx, reason)
+	})
+}
+
+func (d *ChaosDatabase) ResyncLastProcessedBbnHeight(ctx context.Context, height uint64) error {
+	return d.call(ctx, "ResyncLastProcessedBbnHeight", func() error {
+		return d.next.ResyncLastProcessedBbnHeight(ctx, height)
+	})
+}
+
+func (d *ChaosDatabase) StartBbnBlockProcessing(
+	ctx context.Context, marker *model.BbnProcessingMarker,
+) error {
+	return d.call(ctx, "StartBbnBlockProcessing", func() error {
+		return d.next.StartBbnBlockProcessing(ctx, marker)
+	})
+}
+
+func (d *ChaosDatabase) MarkBbnEventProcessed(
+	ctx context.Context, height uint64, eventIndex int,
+) error {
+	return d.call(ctx, "MarkBbnEventProcessed", func() error {
+		return d.next.MarkBbnEventProcessed(ctx, height, eventIndex)
+	})
+}
+
+func (d *ChaosDatabase) SaveBTCDelegationSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, slashingTxHex string, spendingHeight uint32,
+) error {
+	return d.call(ctx, "SaveBTCDelegationSlashingTxHex", func() error {
+		return d.next.SaveBTCDelegationSlashingTxHex(ctx, stakingTxHashHex, slashingTxHex, spendingHeight)
+	})
+}
+
+func (d *ChaosDatabase) SaveBTCDelegationUnbondingSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, unbondingSlashingTxHex string, spendingHeight uint32,
+) error {
+	return d.call(ctx, "SaveBTCDelegationUnbondingSlashingTxHex", func() error {
+		return d.next.SaveBTCDelegationUnbondingSlashingTxHex(ctx, stakingTxHashHex, unbondingSlashingTxHex, spendingHeight)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCDelegationsByStates(
+	ctx context.Context, states []types.DelegationState,
+) ([]*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "GetBTCDelegationsByStates", func() ([]*model.BTCDelegationDetails, error) {
+		return d.next.GetBTCDelegationsByStates(ctx, states)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCDelegationsAfter(
+	ctx context.Context, filter BTCDelegationsFilter, stakingTxHashHex string, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "GetBTCDelegationsAfter", func() ([]*model.BTCDelegationDetails, error) {
+		return d.next.GetBTCDelegationsAfter(ctx, filter, stakingTxHashHex, limit)
+	})
+}
+
+func (d *ChaosDatabase) SampleBTCDelegations(
+	ctx context.Context, filter BTCDelegationsFilter, size uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "SampleBTCDelegations", func() ([]*model.BTCDelegationDetails, error) {
+		return d.next.SampleBTCDelegations(ctx, filter, size)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCDelegationsCreatedBetween(
+	ctx context.Context, fromHeight, toHeight int64,
+) ([]*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "GetBTCDelegationsCreatedBetween", func() ([]*model.BTCDelegationDetails, error) {
+		return d.next.GetBTCDelegationsCreatedBetween(ctx, fromHeight, toHeight)
+	})
+}
+
+func (d *ChaosDatabase) GetStakerDelegations(
+	ctx context.Context, filter StakerDelegationsFilter, paginationToken string, limit int64,
+) (*DbResultMap[*model.BTCDelegationDetails], error) {
+	return chaosCall(ctx, d, "GetStakerDelegations", func() (*DbResultMap[*model.BTCDelegationDetails], error) {
+		return d.next.GetStakerDelegations(ctx, filter, paginationToken, limit)
+	})
+}
+
+func (d *ChaosDatabase) GetStakerDelegationSummaries(
+	ctx context.Context, filter StakerDelegationsFilter, paginationToken string, limit int64,
+) (*DbResultMap[*model.BTCDelegationSummary], error) {
+	return chaosCall(ctx, d, "GetStakerDelegationSummaries", func() (*DbResultMap[*model.BTCDelegationSummary], error) {
+		return d.next.GetStakerDelegationSummaries(ctx, filter, paginationToken, limit)
+	})
+}
+
+func (d *ChaosDatabase) SaveFinalityProviderVotingPowerChange(
+	ctx context.Context, change *model.FinalityProviderVotingPowerChange,
+) error {
+	return d.call(ctx, "SaveFinalityProviderVotingPowerChange", func() error {
+		return d.next.SaveFinalityProviderVotingPowerChange(ctx, change)
+	})
+}
+
+func (d *ChaosDatabase) GetLatestFinalityProviderVotingPowerChanges(
+	ctx context.Context, fpBtcPks []string,
+) (map[string]*model.FinalityProviderVotingPowerChange, error) {
+	return chaosCall(ctx, d, "GetLatestFinalityProviderVotingPowerChanges", func() (map[string]*model.FinalityProviderVotingPowerChange, error) {
+		return d.next.GetLatestFinalityProviderVotingPowerChanges(ctx, fpBtcPks)
+	})
+}
+
+func (d *ChaosDatabase) GetFinalityProviderActivationPeriods(
+	ctx context.Context, fpBtcPk string,
+) ([]*model.FinalityProviderActivationPeriod, error) {
+	return chaosCall(ctx, d, "GetFinalityProviderActivationPeriods", func() ([]*model.FinalityProviderActivationPeriod, error) {
+		return d.next.GetFinalityProviderActivationPeriods(ctx, fpBtcPk)
+	})
+}
+
+func (d *ChaosDatabase) SaveBTCHeader(ctx context.Context, header *model.BTCHeader) error {
+	return d.call(ctx, "SaveBTCHeader", func() error {
+		return d.next.SaveBTCHeader(ctx, header)
+	})
+}
+
+func (d *ChaosDatabase) GetBTCHeaderByHeight(
+	ctx context.Context, height uint64,
+) (*model.BTCHeader, error) {
+	return chaosCall(ctx, d, "GetBTCHeaderByHeight", func() (*model.BTCHeader, error) {
+		return d.next.GetBTCHeaderByHeight(ctx, height)
+	})
+}
+
+func (d *ChaosDatabase) GetLatestBTCHeader(ctx context.Context) (*model.BTCHeader, error) {
+	return chaosCall(ctx, d, "GetLatestBTCHeader", func() (*model.BTCHeader, error) {
+		return d.next.GetLatestBTCHeader(ctx)
+	})
+}
+
+func (d *ChaosDatabase) DeleteBTCHeadersAbove(ctx context.Context, height uint64) error {
+	return d.call(ctx, "DeleteBTCHeadersAbove", func() error {
+		return d.next.DeleteBTCHeadersAbove(ctx, height)
+	})
+}
+
+func (d *ChaosDatabase) DeleteBTCHeadersBelow(ctx context.Context, height uint64) error {
+	return d.call(ctx, "DeleteBTCHeadersBelow", func() error {
+		return d.next.DeleteBTCHeadersBelow(ctx, height)
+	})
+}
+
+func (d *ChaosDatabase) SaveBTCDerivedChange(
+	ctx context.Context, change *model.BTCDerivedChange,
+) error {
+	return d.call(ctx, "SaveBTCDerivedChange", func() error {
+		return d.next.SaveBTCDerivedChange(ctx, change)
+	})
+}
+
+func (d *ChaosDatabase) RollbackBTCDerivedChanges(
+	ctx context.Context, forkHeight uint64,
+) ([]*model.BTCDerivedChange, error) {
+	return chaosCall(ctx, d, "RollbackBTCDerivedChanges", func() ([]*model.BTCDerivedChange, error) {
+		return d.next.RollbackBTCDerivedChanges(ctx, forkHeight)
+	})
+}
+
+func (d *ChaosDatabase) DeleteBTCDerivedChangesBelow(ctx context.Context, height uint64) error {
+	return d.call(ctx, "DeleteBTCDerivedChangesBelow", func() error {
+		return d.next.DeleteBTCDerivedChangesBelow(ctx, height)
+	})
+}
+
+func (d *ChaosDatabase) GetUnfinishedReconciliationRun(
+	ctx context.Context,
+) (*model.ReconciliationRun, error) {
+	return chaosCall(ctx, d, "GetUnfinishedReconciliationRun", func() (*model.ReconciliationRun, error) {
+		return d.next.GetUnfinishedReconciliationRun(ctx)
+	})
+}
+
+func (d *ChaosDatabase) SaveReconciliationRun(
+	ctx context.Context, run *model.ReconciliationRun,
+) error {
+	return d.call(ctx, "SaveReconciliationRun", func() error {
+		return d.next.SaveReconciliationRun(ctx, run)
+	})
+}
+
+func (d *ChaosDatabase) SaveReconciliationDiscrepancy(
+	ctx context.Context, discrepancy *model.ReconciliationDiscrepancy,
+) error {
+	return d.call(ctx, "SaveReconciliationDiscrepancy", func() error {
+		return d.next.SaveReconciliationDiscrepancy(ctx, discrepancy)
+	})
+}
+
+func (d *ChaosDatabase) MarkBbnHeightProcessed(ctx context.Context, height uint64) error {
+	return d.call(ctx, "MarkBbnHeightProcessed", func() error {
+		return d.next.MarkBbnHeightProcessed(ctx, height)
+	})
+}
+
+func (d *ChaosDatabase) GetLowestProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	return chaosCall(ctx, d, "GetLowestProcessedBbnHeight", func() (uint64, error) {
+		return d.next.GetLowestProcessedBbnHeight(ctx)
+	})
+}
+
+func (d *ChaosDatabase) DetectProcessedHeightGaps(
+	ctx context.Context, from, to uint64,
+) ([]*model.BbnHeightRange, error) {
+	return chaosCall(ctx, d, "DetectProcessedHeightGaps", func() ([]*model.BbnHeightRange, error) {
+		return d.next.DetectProcessedHeightGaps(ctx, from, to)
+	})
+}
+
+func (d *ChaosDatabase) CountStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64,
+) (uint64, error) {
+	return chaosCall(ctx, d, "CountStuckDelegations", func() (uint64, error) {
+		return d.next.CountStuckDelegations(ctx, state, before)
+	})
+}
+
+func (d *ChaosDatabase) FindStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	return chaosCall(ctx, d, "FindStuckDelegations", func() ([]*model.BTCDelegationDetails, error) {
+		return d.next.FindStuckDelegations(ctx, state, before, limit)
+	})
+}
+
+func (d *ChaosDatabase) SaveStuckDelegationReport(
+	ctx context.Context, report *model.StuckDelegationReport,
+) error {
+	return d.call(ctx, "SaveStuckDelegationReport", func() error {
+		return d.next.SaveStuckDelegationReport(ctx, report)
+	})
+}
+
+func (d *ChaosDatabase) SaveRawEventCapture(ctx context.Context, capture *model.RawEventCapture) error {
+	return d.call(ctx, "SaveRawEventCapture", func() error {
+		return d.next.SaveRawEventCapture(ctx, capture)
+	})
+}
+
+func (d *ChaosDatabase) SaveBbnEventDeadLetter(ctx context.Context, deadLetter *model.BbnEventDeadLetter) error {
+	return d.call(ctx, "SaveBbnEventDeadLetter", func() error {
+		return d.next.SaveBbnEventDeadLetter(ctx, deadLetter)
+	})
+}
+
+func (d *ChaosDatabase) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	return d.call(ctx, "SaveOutboxEvent", func() error {
+		return d.next.SaveOutboxEvent(ctx, event)
+	})
+}
+
+func (d *ChaosDatabase) GetUnsentOutboxEvents(
+	ctx context.Context, createdAfter int64, afterId string, limit uint64,
+) ([]*model.OutboxEvent, error) {
+	return chaosCall(ctx, d, "GetUnsentOutboxEvents", func() ([]*model.OutboxEvent, error) {
+		return d.next.GetUnsentOutboxEvents(ctx, createdAfter, afterId, limit)
+	})
+}
+
+func (d *ChaosDatabase) MarkOutboxEventSent(ctx context.Context, id string, sentAt int64) error {
+	return d.call(ctx, "MarkOutboxEventSent", func() error {
+		return d.next.MarkOutboxEventSent(ctx, id, sentAt)
+	})
+}
+
+func (d *ChaosDatabase) MarkOutboxEventFailed(
+	ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool,
+) error {
+	return d.call(ctx, "MarkOutboxEventFailed", func() error {
+		return d.next.MarkOutboxEventFailed(ctx, id, lastError, nextAttemptAt, poison)
+	})
+}
+
+func (d *ChaosDatabase) GetPoisonOutboxEvents(ctx context.Context) ([]*model.OutboxEvent, error) {
+	return chaosCall(ctx, d, "GetPoisonOutboxEvents", func() ([]*model.OutboxEvent, error) {
+		return d.next.GetPoisonOutboxEvents(ctx)
+	})
+}
+
+func (d *ChaosDatabase) RequeuePoisonOutboxEvents(ctx context.Context) (uint64, error) {
+	return chaosCall(ctx, d, "RequeuePoisonOutboxEvents", func() (uint64, error) {
+		return d.next.RequeuePoisonOutboxEvents(ctx)
+	})
+}
+
+func (d *ChaosDatabase) GetOutboxStats(ctx context.Context) (*model.OutboxStats, error) {
+	return chaosCall(ctx, d, "GetOutboxStats", func() (*model.OutboxStats, error) {
+		return d.next.GetOutboxStats(ctx)
+	})
+}
+
+func (d *ChaosDatabase) GetDelegationOutboxEvents(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.OutboxEvent, error) {
+	return chaosCall(ctx, d, "GetDelegationOutboxEvents", func() ([]*model.OutboxEvent, error) {
+		return d.next.GetDelegationOutboxEvents(ctx, stakingTxHashHex)
+	})
+}
+
+func (d *ChaosDatabase) GetOutboxSequence(
+	ctx context.Context, stakingTxHashHex string,
+) (uint64, error) {
+	return chaosCall(ctx, d, "GetOutboxSequence", func() (uint64, error) {
+		return d.next.GetOutboxSequence(ctx, stakingTxHashHex)
+	})
+}
+
+func (d *ChaosDatabase) GetDelegationStatsByState(
+	ctx context.Context,
+) ([]*model.DelegationStateStats, error) {
+	return chaosCall(ctx, d, "GetDelegationStatsByState", func() ([]*model.DelegationStateStats, error) {
+		return d.next.GetDelegationStatsByState(ctx)
+	})
+}
+
+func (d *ChaosDatabase) SaveGlobalStats(ctx context.Context, stats *model.GlobalStats) error {
+	return d.call(ctx, "SaveGlobalStats", func() error {
+		return d.next.SaveGlobalStats(ctx, stats)
+	})
+}
+
+func (d *ChaosDatabase) GetGlobalStats(ctx context.Context) (*model.GlobalStats, error) {
+	return chaosCall(ctx, d, "GetGlobalStats", func() (*model.GlobalStats, error) {
+		return d.next.GetGlobalStats(ctx)
+	})
+}
+
+func (d *ChaosDatabase) SaveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) error {
+	return d.call(ctx, "SaveBootstrap", func() error {
+		return d.next.SaveBootstrap(ctx, bootstrap)
+	})
+}
+
+func (d *ChaosDatabase) GetBootstrap(ctx context.Context) (*model.Bootstrap, error) {
+	return chaosCall(ctx, d, "GetBootstrap", func() (*model.Bootstrap, error) {
+		return d.next.GetBootstrap(ctx)
+	})
+}
+
+func (d *ChaosDatabase) AcquireLock(
+	ctx context.Context, name, owner string, ttl time.Duration,
+) error {
+	return d.call(ctx, "AcquireLock", func() error {
+		return d.next.AcquireLock(ctx, name, owner, ttl)
+	})
+}
+
+func (d *ChaosDatabase) ReleaseLock(ctx context.Context, name, owner string) error {
+	return d.call(ctx, "ReleaseLock", func() error {
+		return d.next.ReleaseLock(ctx, name, owner)
+	})
+}
+
+func (d *ChaosDatabase) CountPrunableBTCDelegations(
+	ctx context.Context, before int64,
+) (uint64, error) {
+	return chaosCall(ctx, d, "CountPrunableBTCDelegations", func() (uint64, error) {
+		return d.next.CountPrunableBTCDelegations(ctx, before)
+	})
+}
+
+func (d *ChaosDatabase) ArchivePrunableBTCDelegations(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	return chaosCall(ctx, d, "ArchivePrunableBTCDelegations", func() (uint64, error) {
+		return d.next.ArchivePrunableBTCDelegations(ctx, before, limit)
+	})
+}
+
+func (d *ChaosDatabase) CountPrunableArchivedTimeLocks(
+	ctx context.Context, before int64,
+) (uint64, error) {
+	return chaosCall(ctx, d, "CountPrunableArchivedTimeLocks", func() (uint64, error) {
+		return d.next.CountPrunableArchivedTimeLocks(ctx, before)
+	})
+}
+
+func (d *ChaosDatabase) DeletePrunableArchivedTimeLocks(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	return chaosCall(ctx, d, "DeletePrunableArchivedTimeLocks", func() (uint64, error) {
+		return d.next.DeletePrunableArchivedTimeLocks(ctx, before, limit)
+	})
+}
@@ -0,0 +1,85 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+func saveFinalityProvider(database db.DbInterface, btcPk string) error {
+	return database.SaveNewFinalityProvider(context.Background(), &model.FinalityProviderDetails{BtcPk: btcPk})
+}
+
+func requireFinalityProviderSaved(t *testing.T, database db.DbInterface, btcPk string, saved bool) {
+	_, err := database.GetFinalityProviderByBtcPk(context.Background(), btcPk)
+	if saved {
+		require.NoError(t, err)
+	} else {
+		require.True(t, db.IsNotFoundError(err))
+	}
+}
+
+func TestChaosDatabaseFaultsCallsInRange(t *testing.T) {
+	chaos := db.NewChaosDatabase(inmemory.New())
+	chaos.Inject("SaveNewFinalityProvider", db.Fault{After: 1, Times: 2, Err: db.NewSteppedDownError()})
+
+	require.NoError(t, saveFinalityProvider(chaos, "fp-1"))
+	for _, btcPk := range []string{"fp-2", "fp-3"} {
+		err := saveFinalityProvider(chaos, btcPk)
+		require.Error(t, err)
+		// The stepdown is worth retrying
+		require.True(t, types.IsRetryable(err))
+	}
+	require.NoError(t, saveFinalityProvider(chaos, "fp-2"))
+	require.Equal(t, 4, chaos.Calls("SaveNewFinalityProvider"))
+
+	// The faulty calls did not reach the wrapped database
+	requireFinalityProviderSaved(t, chaos, "fp-3", false)
+}
+
+func TestChaosDatabaseAppliesWriteBeforeFailing(t *testing.T) {
+	chaos := db.NewChaosDatabase(inmemory.New())
+	chaos.Inject("SaveNewFinalityProvider", db.Fault{Times: 1, Applied: true, Err: db.NewSteppedDownError()})
+
+	require.Error(t, saveFinalityProvider(chaos, "fp"))
+	// The write landed, its retry finding the finality provider saved
+	require.True(t, db.IsDuplicateKeyError(saveFinalityProvider(chaos, "fp")))
+}
+
+func TestChaosDatabaseCrashesCall(t *testing.T) {
+	chaos := db.NewChaosDatabase(inmemory.New())
+	chaos.Inject("SaveNewFinalityProvider", db.Fault{After: 1, Panic: true, Applied: true})
+
+	require.NoError(t, saveFinalityProvider(chaos, "fp-1"))
+	require.PanicsWithValue(t, db.ChaosPanic{Method: "SaveNewFinalityProvider", Call: 2}, func() {
+		_ = saveFinalityProvider(chaos, "fp-2")
+	})
+
+	// The crashed write landed before the crash
+	chaos.Heal()
+	requireFinalityProviderSaved(t, chaos, "fp-2", true)
+}
+
+func TestChaosDatabaseDelaysCall(t *testing.T) {
+	chaos := db.NewChaosDatabase(inmemory.New())
+	chaos.Inject("Ping", db.Fault{Latency: time.Hour})
+
+	// The delay gives way to the cancellation of the call
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, chaos.Ping(ctx), context.DeadlineExceeded)
+}
+
+func TestChaosDatabaseRefusesUnknownMethod(t *testing.T) {
+	chaos := db.NewChaosDatabase(inmemory.New())
+	require.Panics(t, func() {
+		chaos.Inject("SaveFinalityProvider", db.Fault{Panic: true})
+	})
+}
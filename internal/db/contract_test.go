@@ -0,0 +1,87 @@
+//go:build integration
+
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/dbtest"
+)
+
+// TestMongoContract runs the contract suite against a Mongo replica set
+// started in docker, so that the transactions are exercised, each test in a
+// database of its own
+func TestMongoContract(t *testing.T) {
+	startMongo(t, "INTEGRATION")
+
+	databases := 0
+	dbtest.RunDbInterfaceTests(t, func() db.DbInterface {
+		databases++
+		return db.MongoTestDatabase(t, "INTEGRATION", fmt.Sprintf("indexer-contract-%d", databases))
+	})
+}
+
+// startMongo runs the Mongo of the local setup, a single node replica set
+// initiated by bin/init-mongo.sh, and points <envPrefix>_MONGO_ADDRESS at it
+// once it accepts the root user
+func startMongo(t *testing.T, envPrefix string) {
+	initScript, err := filepath.Abs("../../bin/init-mongo.sh")
+	require.NoError(t, err)
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+	resource, err := pool.RunWithOptions(
+		&dockertest.RunOptions{
+			Repository: "mongo",
+			Tag:        "latest",
+			Env: []string{
+				"MONGO_INITDB_ROOT_USERNAME=root",
+				"MONGO_INITDB_ROOT_PASSWORD=example",
+			},
+			Mounts:       []string{initScript + ":/init-mongo.sh"},
+			Entrypoint:   []string{"/init-mongo.sh"},
+			ExposedPorts: []string{"27017/tcp"},
+		},
+		func(config *docker.HostConfig) {
+			config.AutoRemove = true
+			config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = pool.Purge(resource)
+	})
+
+	address := fmt.Sprintf(
+		"mongodb://localhost:%s/?replicaSet=RS&directConnection=true", resource.GetPort("27017/tcp"),
+	)
+	t.Setenv(envPrefix+"_MONGO_ADDRESS", address)
+	t.Setenv(envPrefix+"_MONGO_USERNAME", "root")
+	t.Setenv(envPrefix+"_MONGO_PASSWORD", "example")
+
+	// The root user is created once the replica set is initiated
+	require.Eventually(t, func() bool {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(address).SetAuth(options.Credential{
+			Username: "root",
+			Password: "example",
+		}))
+		if err != nil {
+			return false
+		}
+		defer client.Disconnect(ctx)
+		return client.Ping(ctx, nil) == nil
+	}, 2*time.Minute, time.Second, "mongo did not start")
+}
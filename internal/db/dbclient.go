@@ -31,6 +31,12 @@ func New(ctx context.Context, cfg config.DbConfig) (*Database, error) {
 	}, nil
 }
 
+// Disconnect closes the connections of the Mongo client, waiting for the
+// operations in use up to the context deadline
+func (db *Database) Disconnect(ctx context.Context) error {
+	return db.client.Disconnect(ctx)
+}
+
 func (db *Database) Ping(ctx context.Context) error {
 	err := db.client.Ping(ctx, nil)
 	if err != nil {
@@ -0,0 +1,113 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+var bbnHeightTests = []contractTest{
+	{
+		name: "LastProcessedBbnHeight",
+		methods: []string{
+			"GetLastProcessedBbnHeight", "GetLastProcessedBbnBlock", "UpdateLastProcessedBbnHeight",
+			"HaltBbnProcessing", "ResyncLastProcessedBbnHeight",
+		},
+		run: testLastProcessedBbnHeight,
+	},
+	{
+		name:    "BbnProcessingMarker",
+		methods: []string{"StartBbnBlockProcessing", "MarkBbnEventProcessed"},
+		run:     testBbnProcessingMarker,
+	},
+	{
+		name:    "ProcessedHeights",
+		methods: []string{"MarkBbnHeightProcessed", "GetLowestProcessedBbnHeight", "DetectProcessedHeightGaps"},
+		run:     testProcessedHeights,
+	},
+}
+
+func testLastProcessedBbnHeight(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	// Nothing processed yet
+	height, err := database.GetLastProcessedBbnHeight(ctx)
+	require.NoError(t, err)
+	require.Zero(t, height)
+	block, err := database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.LastProcessedHeight{}, block)
+
+	require.NoError(t, database.UpdateLastProcessedBbnHeight(ctx, 10, "hash-10"))
+	require.NoError(t, database.HaltBbnProcessing(ctx, "fork"))
+	block, err = database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.LastProcessedHeight{Height: 10, BlockHash: "hash-10", HaltReason: "fork"}, block)
+
+	// The resync clears the block hash and the halt
+	require.NoError(t, database.ResyncLastProcessedBbnHeight(ctx, 5))
+	block, err = database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.LastProcessedHeight{Height: 5}, block)
+	height, err = database.GetLastProcessedBbnHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), height)
+}
+
+func testBbnProcessingMarker(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.True(t, db.IsNotFoundError(database.MarkBbnEventProcessed(ctx, 11, 0)))
+
+	require.NoError(t, database.UpdateLastProcessedBbnHeight(ctx, 10, "hash-10"))
+	require.NoError(t, database.StartBbnBlockProcessing(ctx, model.NewBbnProcessingMarker(11, "hash-11", 100)))
+	require.NoError(t, database.MarkBbnEventProcessed(ctx, 11, 2))
+	require.NoError(t, database.MarkBbnEventProcessed(ctx, 11, 0))
+	// An event is recorded once
+	require.NoError(t, database.MarkBbnEventProcessed(ctx, 11, 2))
+	require.True(t, db.IsNotFoundError(database.MarkBbnEventProcessed(ctx, 12, 0)))
+
+	block, err := database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.BbnProcessingMarker{
+		Height:          11,
+		BlockHash:       "hash-11",
+		StartedAt:       100,
+		ProcessedEvents: []int{2, 0},
+	}, block.ProcessingMarker)
+
+	// Completing the block clears its marker
+	require.NoError(t, database.UpdateLastProcessedBbnHeight(ctx, 11, "hash-11"))
+	block, err = database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.LastProcessedHeight{Height: 11, BlockHash: "hash-11"}, block)
+}
+
+func testProcessedHeights(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	_, err := database.GetLowestProcessedBbnHeight(ctx)
+	require.True(t, db.IsNotFoundError(err))
+
+	for _, height := range []uint64{2, 3, 6, 5, 3} {
+		require.NoError(t, database.MarkBbnHeightProcessed(ctx, height))
+	}
+	gaps, err := database.DetectProcessedHeightGaps(ctx, 1, 7)
+	require.NoError(t, err)
+	require.Equal(t, []*model.BbnHeightRange{{Start: 1, End: 1}, {Start: 4, End: 4}, {Start: 7, End: 7}}, gaps)
+
+	// The height filling a gap merges its ranges
+	require.NoError(t, database.MarkBbnHeightProcessed(ctx, 4))
+	require.NoError(t, database.MarkBbnHeightProcessed(ctx, 1))
+	gaps, err = database.DetectProcessedHeightGaps(ctx, 1, 6)
+	require.NoError(t, err)
+	require.Empty(t, gaps)
+	lowest, err := database.GetLowestProcessedBbnHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), lowest)
+
+	gaps, err = database.DetectProcessedHeightGaps(ctx, 7, 6)
+	require.NoError(t, err)
+	require.Empty(t, gaps)
+}
@@ -0,0 +1,279 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+var btcTests = []contractTest{
+	{
+		name: "BTCHeaders",
+		methods: []string{
+			"SaveBTCHeader", "GetBTCHeaderByHeight", "GetLatestBTCHeader", "DeleteBTCHeadersAbove",
+			"DeleteBTCHeadersBelow",
+		},
+		run: testBTCHeaders,
+	},
+	{
+		name:    "BTCDerivedChangesRollback",
+		methods: []string{"SaveBTCDerivedChange", "RollbackBTCDerivedChanges", "DeleteBTCDerivedChangesBelow"},
+		run:     testBTCDerivedChangesRollback,
+	},
+	{
+		name:    "BTCDelegationStateChange",
+		methods: []string{"ApplyBTCDelegationStateChange"},
+		run:     testBTCDelegationStateChange,
+	},
+	{
+		name:    "BTCDelegationStateChangeRecords",
+		methods: []string{"ApplyBTCDelegationStateChange"},
+		run:     testBTCDelegationStateChangeRecords,
+	},
+}
+
+func testBTCHeaders(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	_, err := database.GetLatestBTCHeader(ctx)
+	require.True(t, db.IsNotFoundError(err))
+
+	for height := uint64(1); height <= 5; height++ {
+		require.NoError(t, database.SaveBTCHeader(ctx, &model.BTCHeader{Height: height, Hash: "hash"}))
+	}
+	// The header of a height is replaced
+	reorged := &model.BTCHeader{Height: 3, Hash: "other hash", PrevHash: "hash"}
+	require.NoError(t, database.SaveBTCHeader(ctx, reorged))
+	header, err := database.GetBTCHeaderByHeight(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, reorged, header)
+
+	latest, err := database.GetLatestBTCHeader(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), latest.Height)
+
+	require.NoError(t, database.DeleteBTCHeadersAbove(ctx, 3))
+	require.NoError(t, database.DeleteBTCHeadersBelow(ctx, 2))
+	latest, err = database.GetLatestBTCHeader(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), latest.Height)
+	for _, height := range []uint64{1, 4} {
+		_, err = database.GetBTCHeaderByHeight(ctx, height)
+		require.True(t, db.IsNotFoundError(err))
+	}
+	_, err = database.GetBTCHeaderByHeight(ctx, 2)
+	require.NoError(t, err)
+}
+
+func testBTCDerivedChangesRollback(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateActive, 1)))
+
+	// The delegation is unbonded at height 10, then withdrawable at height 12
+	unbonding := types.SubStateEarlyUnbonding
+	require.NoError(t, database.UpdateBTCDelegationState(
+		ctx, "aa", []types.DelegationState{types.StateActive}, types.StateUnbonding, &unbonding,
+	))
+	timeLock := model.NewTimeLockDocument("aa", 20, types.SubStateEarlyUnbonding)
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "aa", 20, types.SubStateEarlyUnbonding))
+	require.NoError(t, database.SaveBTCDerivedChange(ctx, &model.BTCDerivedChange{
+		StakingTxHashHex: "aa",
+		BtcHeight:        10,
+		PreviousState:    types.StateActive,
+		CreatedTimeLock:  timeLock,
+	}))
+	require.NoError(t, database.DeleteExpiredDelegation(ctx, "aa"))
+	require.NoError(t, database.UpdateBTCDelegationState(
+		ctx, "aa", []types.DelegationState{types.StateUnbonding}, types.StateWithdrawable, nil,
+	))
+	require.NoError(t, database.SaveBTCDerivedChange(ctx, &model.BTCDerivedChange{
+		StakingTxHashHex: "aa",
+		BtcHeight:        12,
+		PreviousState:    types.StateUnbonding,
+		PreviousSubState: types.SubStateEarlyUnbonding,
+		DeletedTimeLock:  timeLock,
+	}))
+
+	// Rolling back above 10 restores the unbonding delegation and its timelock
+	changes, err := database.RollbackBTCDerivedChanges(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, uint64(12), changes[0].BtcHeight)
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateUnbonding, delegation.State)
+	require.Equal(t, types.SubStateEarlyUnbonding, delegation.SubState)
+	timeLocks, err := database.GetTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, []model.TimeLockDocument{*timeLock}, timeLocks)
+	archived, err := database.GetArchivedTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, archived)
+
+	// Rolling back again is a no-op
+	changes, err = database.RollbackBTCDerivedChanges(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	// The change at the height is kept, and rolling it back restores the
+	// active delegation without the timelock it created
+	require.NoError(t, database.DeleteBTCDerivedChangesBelow(ctx, 10))
+	changes, err = database.RollbackBTCDerivedChanges(ctx, 9)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	delegation, err = database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, delegation.State)
+	require.Empty(t, delegation.SubState)
+	timeLocks, err = database.GetTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, timeLocks)
+
+	// The changes below the height can no longer be rolled back
+	require.NoError(t, database.SaveBTCDerivedChange(ctx, &model.BTCDerivedChange{
+		StakingTxHashHex: "aa",
+		BtcHeight:        5,
+		PreviousState:    types.StatePending,
+	}))
+	require.NoError(t, database.DeleteBTCDerivedChangesBelow(ctx, 6))
+	changes, err = database.RollbackBTCDerivedChanges(ctx, 0)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+	state, err := database.GetBTCDelegationState(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, *state)
+}
+
+func testBTCDelegationStateChange(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	delegation := newDelegation("aa", "staker", types.StateUnbonding, 1)
+	delegation.SubState = types.SubStateEarlyUnbonding
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+
+	// The journal entry is saved along with the update, from the state left
+	timelock := types.SubStateTimelock
+	journal := &model.BTCDerivedChange{StakingTxHashHex: "aa", BtcHeight: 12}
+	require.NoError(t, database.ApplyBTCDelegationStateChange(ctx, db.DelegationStateChange{
+		DelegationStateUpdate: db.DelegationStateUpdate{
+			StakingTxHash:           "aa",
+			QualifiedPreviousStates: []types.DelegationState{types.StateUnbonding},
+			NewState:                types.StateWithdrawable,
+			NewSubState:             &timelock,
+		},
+		DelegationStateRecords: db.DelegationStateRecords{BTCDerivedChange: journal},
+	}))
+	require.Equal(t, types.StateUnbonding, journal.PreviousState)
+	require.Equal(t, types.SubStateEarlyUnbonding, journal.PreviousSubState)
+
+	// Nothing is journaled for an update which does not apply
+	err := database.ApplyBTCDelegationStateChange(ctx, db.DelegationStateChange{
+		DelegationStateUpdate: db.DelegationStateUpdate{
+			StakingTxHash:           "aa",
+			QualifiedPreviousStates: []types.DelegationState{types.StateUnbonding},
+			NewState:                types.StateWithdrawable,
+		},
+		DelegationStateRecords: db.DelegationStateRecords{
+			BTCDerivedChange: &model.BTCDerivedChange{StakingTxHashHex: "aa", BtcHeight: 13},
+		},
+	})
+	var transitionErr *db.StateTransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	require.Equal(t, types.StateWithdrawable, transitionErr.CurrentState)
+	err = database.ApplyBTCDelegationStateChange(ctx, db.DelegationStateChange{
+		DelegationStateUpdate: db.DelegationStateUpdate{
+			StakingTxHash:           "bb",
+			QualifiedPreviousStates: []types.DelegationState{types.StateUnbonding},
+			NewState:                types.StateWithdrawable,
+		},
+		DelegationStateRecords: db.DelegationStateRecords{
+			BTCDerivedChange: &model.BTCDerivedChange{StakingTxHashHex: "bb", BtcHeight: 13},
+		},
+	})
+	require.True(t, db.IsNotFoundError(err))
+
+	changes, err := database.RollbackBTCDerivedChanges(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, uint64(12), changes[0].BtcHeight)
+	delegation, err = database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateUnbonding, delegation.State)
+	require.Equal(t, types.SubStateEarlyUnbonding, delegation.SubState)
+}
+
+func testBTCDelegationStateChangeRecords(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	delegation := newDelegation("aa", "staker", types.StateUnbonding, 1)
+	delegation.SubState = types.SubStateEarlyUnbonding
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	timelock := types.SubStateTimelock
+	update := db.DelegationStateUpdate{
+		StakingTxHash:           "aa",
+		QualifiedPreviousStates: []types.DelegationState{types.StateUnbonding},
+		NewState:                types.StateWithdrawable,
+		NewSubState:             &timelock,
+	}
+
+	// The outbox event failing to be saved after the update, as the one of
+	// a delegation not found, leaves nothing of the change
+	err := database.ApplyBTCDelegationStateChange(ctx, db.DelegationStateChange{
+		DelegationStateUpdate: update,
+		DelegationStateRecords: db.DelegationStateRecords{
+			BTCDerivedChange: &model.BTCDerivedChange{StakingTxHashHex: "aa", BtcHeight: 12},
+			Transition: model.NewBtcStateTransition(
+				"aa", "", types.StateWithdrawable, timelock, model.StateTransitionTriggerExpiry, 12, 100,
+			),
+			OutboxEvent: &model.OutboxEvent{
+				Id: "withdrawable:bb", EventType: model.OutboxEventTypeWithdrawable, StakingTxHashHex: "bb",
+			},
+		},
+	})
+	require.True(t, db.IsNotFoundError(err))
+	state, err := database.GetBTCDelegationState(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateUnbonding, *state)
+	transitions, err := database.GetDelegationStateTransitions(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, transitions)
+	changes, err := database.RollbackBTCDerivedChanges(ctx, 0)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	// The transition is recorded from the state left, along with the event
+	transition := model.NewBtcStateTransition(
+		"aa", "", types.StateWithdrawable, timelock, model.StateTransitionTriggerExpiry, 12, 100,
+	)
+	event := model.NewWithdrawableStakingOutboxEvent(delegation.Summary(), timelock, 12, 100)
+	require.NoError(t, database.ApplyBTCDelegationStateChange(ctx, db.DelegationStateChange{
+		DelegationStateUpdate:  update,
+		DelegationStateRecords: db.DelegationStateRecords{Transition: transition, OutboxEvent: event},
+	}))
+	require.Equal(t, types.StateUnbonding, transition.FromState)
+	transitions, err = database.GetDelegationStateTransitions(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	require.Equal(t, types.StateUnbonding, transitions[0].FromState)
+	require.Equal(t, types.StateWithdrawable, transitions[0].ToState)
+	events, err := database.GetDelegationOutboxEvents(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, uint64(1), events[0].Sequence)
+
+	// An event saved already is left as is, the update applying
+	update.QualifiedPreviousStates = []types.DelegationState{types.StateWithdrawable}
+	update.NewState = types.StateWithdrawn
+	require.NoError(t, database.ApplyBTCDelegationStateChange(ctx, db.DelegationStateChange{
+		DelegationStateUpdate:  update,
+		DelegationStateRecords: db.DelegationStateRecords{OutboxEvent: event},
+	}))
+	state, err = database.GetBTCDelegationState(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateWithdrawn, *state)
+	events, err = database.GetDelegationOutboxEvents(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+}
@@ -0,0 +1,93 @@
+// Package dbtest holds the contract suite of the DbInterface, run against
+// every implementation of it so that they do not drift apart.
+package dbtest
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// contractTest is a test of the contract along with the DbInterface methods
+// it covers
+type contractTest struct {
+	name    string
+	methods []string
+	run     func(t *testing.T, database db.DbInterface)
+}
+
+// contractTests are the tests of every area of the DbInterface
+var contractTests = slices.Concat(
+	finalityProviderTests,
+	paramsTests,
+	delegationTests,
+	timeLockTests,
+	bbnHeightTests,
+	btcTests,
+	outboxTests,
+	maintenanceTests,
+)
+
+// RunDbInterfaceTests runs the contract suite against the implementation,
+// the factory returning an empty database for each test. The suite fails if
+// a method of the DbInterface is covered by none of its tests.
+func RunDbInterfaceTests(t *testing.T, factory func() db.DbInterface) {
+	t.Run("Completeness", func(t *testing.T) {
+		uncovered, unknown := checkCoverage(contractTests)
+		require.Empty(t, uncovered, "DbInterface methods without contract tests")
+		require.Empty(t, unknown, "contract tests covering no DbInterface method")
+	})
+	for _, test := range contractTests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory())
+		})
+	}
+}
+
+// checkCoverage returns the DbInterface methods none of the tests covers, and
+// the methods the tests cover that the DbInterface does not have
+func checkCoverage(tests []contractTest) (uncovered []string, unknown []string) {
+	covered := make(map[string]bool)
+	for _, test := range tests {
+		for _, method := range test.methods {
+			covered[method] = true
+		}
+	}
+
+	dbInterface := reflect.TypeOf((*db.DbInterface)(nil)).Elem()
+	for i := range dbInterface.NumMethod() {
+		method := dbInterface.Method(i).Name
+		if !covered[method] {
+			uncovered = append(uncovered, method)
+		}
+		delete(covered, method)
+	}
+	for method := range covered {
+		unknown = append(unknown, method)
+	}
+	slices.Sort(unknown)
+	return uncovered, unknown
+}
+
+// newDelegation returns a delegation of the staker created at the BBN height
+func newDelegation(
+	stakingTxHash string, staker string, state types.DelegationState, bbnHeight int64,
+) *model.BTCDelegationDetails {
+	return &model.BTCDelegationDetails{
+		StakingTxHashHex:          stakingTxHash,
+		StakerBtcPkHex:            staker,
+		StakingAmount:             1000,
+		State:                     state,
+		FinalityProviderBtcPksHex: []string{"fp"},
+		BTCDelegationCreatedBlock: model.BTCDelegationCreatedBbnBlock{
+			Height:    bbnHeight,
+			Timestamp: bbnHeight,
+		},
+	}
+}
@@ -0,0 +1,500 @@
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+var delegationTests = []contractTest{
+	{
+		name: "DelegationKeys",
+		methods: []string{
+			"SaveNewBTCDelegation", "GetBTCDelegationByStakingTxHash", "GetBTCDelegationSummaryByStakingTxHash",
+			"GetBTCDelegationsByStakingTxHashes",
+		},
+		run: testDelegationKeys,
+	},
+	{
+		name:    "DelegationStateTransitions",
+		methods: []string{"UpdateBTCDelegationState", "GetBTCDelegationState"},
+		run:     testDelegationStateTransitions,
+	},
+	{
+		name:    "DelegationStateOverride",
+		methods: []string{"OverrideBTCDelegationState"},
+		run:     testDelegationStateOverride,
+	},
+	{
+		name:    "DelegationSubState",
+		methods: []string{"UpdateBTCDelegationSubState"},
+		run:     testDelegationSubState,
+	},
+	{
+		name:    "DelegationDetailsUpdate",
+		methods: []string{"UpdateBTCDelegationDetails"},
+		run:     testDelegationDetailsUpdate,
+	},
+	{
+		name: "CovenantSignatures",
+		methods: []string{
+			"SaveBTCDelegationUnbondingCovenantSignature", "SetCovenantSignatureVerified", "BulkSaveCovenantSignatures",
+		},
+		run: testCovenantSignatures,
+	},
+	{
+		name:    "BulkUpdateDelegationStates",
+		methods: []string{"BulkUpdateDelegationStates"},
+		run:     testBulkUpdateDelegationStates,
+	},
+	{
+		name:    "DelegationsByFinalityProvider",
+		methods: []string{"UpdateDelegationsStateByFinalityProvider", "GetDelegationsByFinalityProvider"},
+		run:     testDelegationsByFinalityProvider,
+	},
+	{
+		name:    "SlashingTxHex",
+		methods: []string{"SaveBTCDelegationSlashingTxHex", "SaveBTCDelegationUnbondingSlashingTxHex"},
+		run:     testSlashingTxHex,
+	},
+	{
+		name: "DelegationScans",
+		methods: []string{
+			"GetBTCDelegationsByStates", "GetBTCDelegationsAfter", "SampleBTCDelegations",
+			"GetBTCDelegationsCreatedBetween",
+		},
+		run: testDelegationScans,
+	},
+	{
+		name:    "StakerDelegationsPagination",
+		methods: []string{"GetStakerDelegations", "GetStakerDelegationSummaries"},
+		run:     testStakerDelegationsPagination,
+	},
+	{
+		name:    "DeleteDelegation",
+		methods: []string{"DeleteBTCDelegation"},
+		run:     testDeleteDelegation,
+	},
+}
+
+func testDelegationKeys(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	delegation := newDelegation("aa", "staker", types.StatePending, 1)
+	delegation.StakingTxHex = "staking tx"
+	delegation.CovenantUnbondingSignatures = []model.CovenantSignature{{CovenantBtcPkHex: "covenant", SignatureHex: "sig"}}
+
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	require.True(t, db.IsDuplicateKeyError(database.SaveNewBTCDelegation(ctx, delegation)))
+
+	saved, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, delegation, saved)
+	summary, err := database.GetBTCDelegationSummaryByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, delegation.Summary(), summary)
+
+	_, err = database.GetBTCDelegationByStakingTxHash(ctx, "missing")
+	require.True(t, db.IsNotFoundError(err))
+	_, err = database.GetBTCDelegationSummaryByStakingTxHash(ctx, "missing")
+	require.True(t, db.IsNotFoundError(err))
+
+	// The missing delegations are left out
+	delegations, err := database.GetBTCDelegationsByStakingTxHashes(ctx, []string{"aa", "missing"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]*model.BTCDelegationDetails{"aa": delegation}, delegations)
+}
+
+func testDelegationStateTransitions(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateActive, 1)))
+
+	// The qualified states the state machine does not allow are ignored
+	subState := types.SubStateEarlyUnbonding
+	err := database.UpdateBTCDelegationState(
+		ctx, "aa", []types.DelegationState{types.StateWithdrawn, types.StateActive}, types.StateUnbonding, &subState,
+	)
+	require.NoError(t, err)
+	state, err := database.GetBTCDelegationState(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateUnbonding, *state)
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.SubStateEarlyUnbonding, delegation.SubState)
+	require.NotZero(t, delegation.StateUpdatedAt)
+
+	err = database.UpdateBTCDelegationState(
+		ctx, "aa", []types.DelegationState{types.StateWithdrawn}, types.StateActive, nil,
+	)
+	require.True(t, db.IsInvalidStateTransitionError(err))
+
+	err = database.UpdateBTCDelegationState(
+		ctx, "aa", []types.DelegationState{types.StatePending, types.StateVerified}, types.StateActive, nil,
+	)
+	require.True(t, db.IsStateTransitionError(err))
+	var transitionErr *db.StateTransitionError
+	require.True(t, errors.As(err, &transitionErr))
+	require.Equal(t, types.StateUnbonding, transitionErr.CurrentState)
+
+	err = database.UpdateBTCDelegationState(
+		ctx, "missing", types.QualifiedStatesForWithdrawable(), types.StateWithdrawable, nil,
+	)
+	require.True(t, db.IsNotFoundError(err))
+	_, err = database.GetBTCDelegationState(ctx, "missing")
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func testDelegationStateOverride(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateWithdrawn, 1)))
+
+	// The override is not bound to the state machine
+	subState := types.SubStateTimelock
+	require.NoError(t, database.OverrideBTCDelegationState(ctx, "aa", types.StateWithdrawn, types.StateActive, &subState))
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, delegation.State)
+	require.Equal(t, types.SubStateTimelock, delegation.SubState)
+
+	// but to the state the override was decided on
+	err = database.OverrideBTCDelegationState(ctx, "aa", types.StateWithdrawn, types.StatePending, nil)
+	require.True(t, db.IsNotFoundError(err))
+	err = database.OverrideBTCDelegationState(ctx, "missing", types.StateActive, types.StatePending, nil)
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func testDelegationSubState(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	// Constants, as the string literals passed to a sub state update are taken
+	// for sub states by TestDelegationSubStateLiterals
+	const stakingTxHash, missing = "aa", "missing"
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation(stakingTxHash, "staker", types.StateSlashed, 1)))
+
+	require.NoError(t, database.UpdateBTCDelegationSubState(ctx, stakingTxHash, types.StateSlashed, types.SubStateSlashingConfirmed))
+	err := database.UpdateBTCDelegationSubState(ctx, stakingTxHash, types.StateSlashed, types.SubStateSlashingConfirmed)
+	require.True(t, db.IsNotFoundError(err))
+	require.NoError(t, database.UpdateBTCDelegationSubState(ctx, stakingTxHash, types.StateSlashed, types.SubStateSlashedOutputSwept))
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	require.NoError(t, err)
+	require.Equal(t, types.StateSlashed, delegation.State)
+	require.Equal(t, types.SubStateSlashedOutputSwept, delegation.SubState)
+
+	// The delegation must be in the state
+	err = database.UpdateBTCDelegationSubState(ctx, stakingTxHash, types.StateActive, types.SubStateSlashingConfirmed)
+	require.Error(t, err)
+	err = database.UpdateBTCDelegationSubState(ctx, missing, types.StateSlashed, types.SubStateSlashingConfirmed)
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func testDelegationDetailsUpdate(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	delegation := newDelegation("aa", "staker", types.StateVerified, 1)
+	delegation.StartHeight = 10
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+
+	// Only the fields set are updated
+	require.NoError(t, database.UpdateBTCDelegationDetails(ctx, "aa", &model.BTCDelegationDetails{
+		State:     types.StateActive,
+		EndHeight: 110,
+	}))
+	saved, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, saved.State)
+	require.Equal(t, uint32(10), saved.StartHeight)
+	require.Equal(t, uint32(110), saved.EndHeight)
+	require.Equal(t, "staker", saved.StakerBtcPkHex)
+
+	require.NoError(t, database.UpdateBTCDelegationDetails(ctx, "missing", &model.BTCDelegationDetails{}))
+	err = database.UpdateBTCDelegationDetails(ctx, "missing", &model.BTCDelegationDetails{StartHeight: 1})
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func testCovenantSignatures(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StatePending, 1)))
+	require.NoError(t, database.SaveBTCDelegationUnbondingCovenantSignature(ctx, "aa", "covenant-1", "sig-1"))
+	// A signature of a missing delegation is dropped
+	require.NoError(t, database.SaveBTCDelegationUnbondingCovenantSignature(ctx, "missing", "covenant-1", "sig-1"))
+
+	err := database.BulkSaveCovenantSignatures(ctx, []db.CovenantSigRecord{
+		{StakingTxHash: "aa", CovenantBtcPkHex: "covenant-2", SignatureHex: "sig-2"},
+		{StakingTxHash: "missing", CovenantBtcPkHex: "covenant-1", SignatureHex: "sig-1"},
+		// A signature already saved is skipped
+		{StakingTxHash: "aa", CovenantBtcPkHex: "covenant-1", SignatureHex: "sig-1"},
+		{StakingTxHash: "aa", CovenantBtcPkHex: "covenant-1", SignatureHex: "other sig"},
+	})
+	var bulkErr *db.BulkWriteError
+	require.True(t, errors.As(err, &bulkErr))
+	require.Len(t, bulkErr.Failures, 2)
+	require.Equal(t, 1, bulkErr.Failures[0].Index)
+	require.True(t, db.IsNotFoundError(bulkErr.Failures[0].Err))
+	require.Equal(t, 3, bulkErr.Failures[1].Index)
+	require.True(t, db.IsDuplicateKeyError(bulkErr.Failures[1].Err))
+	require.NoError(t, database.BulkSaveCovenantSignatures(ctx, []db.CovenantSigRecord{
+		{StakingTxHash: "aa", CovenantBtcPkHex: "covenant-2", SignatureHex: "sig-2"},
+	}))
+
+	require.NoError(t, database.SetCovenantSignatureVerified(ctx, "aa", "covenant-2", false))
+	err = database.SetCovenantSignatureVerified(ctx, "aa", "covenant-3", true)
+	require.True(t, db.IsNotFoundError(err))
+
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	verified := false
+	require.Equal(t, []model.CovenantSignature{
+		{CovenantBtcPkHex: "covenant-1", SignatureHex: "sig-1"},
+		{CovenantBtcPkHex: "covenant-2", SignatureHex: "sig-2", Verified: &verified},
+	}, delegation.CovenantUnbondingSignatures)
+}
+
+func testBulkUpdateDelegationStates(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, stakingTxHash := range []string{"aa", "bb", "cc"} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation(stakingTxHash, "staker", types.StateActive, 1)))
+	}
+	require.NoError(t, database.BulkUpdateDelegationStates(ctx, nil))
+
+	subState := types.SubStateEarlyUnbonding
+	err := database.BulkUpdateDelegationStates(ctx, []db.DelegationStateUpdate{
+		{
+			StakingTxHash:           "aa",
+			QualifiedPreviousStates: []types.DelegationState{types.StateActive},
+			NewState:                types.StateUnbonding,
+			NewSubState:             &subState,
+		},
+		{
+			StakingTxHash:           "missing",
+			QualifiedPreviousStates: []types.DelegationState{types.StateActive},
+			NewState:                types.StateUnbonding,
+		},
+		{
+			StakingTxHash:           "bb",
+			QualifiedPreviousStates: []types.DelegationState{types.StateWithdrawn},
+			NewState:                types.StateActive,
+		},
+		{
+			StakingTxHash:           "cc",
+			QualifiedPreviousStates: []types.DelegationState{types.StateUnbonding},
+			NewState:                types.StateWithdrawable,
+		},
+	})
+	var bulkErr *db.BulkWriteError
+	require.True(t, errors.As(err, &bulkErr))
+	require.Len(t, bulkErr.Failures, 3)
+	require.Equal(t, 1, bulkErr.Failures[0].Index)
+	require.True(t, db.IsNotFoundError(bulkErr.Failures[0].Err))
+	require.Equal(t, 2, bulkErr.Failures[1].Index)
+	require.True(t, db.IsInvalidStateTransitionError(bulkErr.Failures[1].Err))
+	require.Equal(t, 3, bulkErr.Failures[2].Index)
+	require.True(t, db.IsStateTransitionError(bulkErr.Failures[2].Err))
+
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateUnbonding, delegation.State)
+	require.Equal(t, types.SubStateEarlyUnbonding, delegation.SubState)
+	for _, stakingTxHash := range []string{"bb", "cc"} {
+		state, err := database.GetBTCDelegationState(ctx, stakingTxHash)
+		require.NoError(t, err)
+		require.Equal(t, types.StateActive, *state)
+	}
+}
+
+func testDelegationsByFinalityProvider(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, delegation := range []*model.BTCDelegationDetails{
+		{StakingTxHashHex: "aa", State: types.StateActive, FinalityProviderBtcPksHex: []string{"fp-1"}},
+		{StakingTxHashHex: "bb", State: types.StateActive, FinalityProviderBtcPksHex: []string{"fp-2", "fp-1"}},
+		{StakingTxHashHex: "cc", State: types.StateActive, FinalityProviderBtcPksHex: []string{"fp-2"}},
+		{StakingTxHashHex: "dd", State: types.StateWithdrawn, FinalityProviderBtcPksHex: []string{"fp-1"}},
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+
+	require.NoError(t, database.UpdateDelegationsStateByFinalityProvider(ctx, "fp-1", types.StateSlashed))
+	delegations, err := database.GetDelegationsByFinalityProvider(ctx, "fp-1")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"aa", "bb", "dd"}, stakingTxHashes(delegations))
+	for _, delegation := range delegations {
+		// a withdrawn delegation can no longer be slashed
+		if delegation.StakingTxHashHex == "dd" {
+			require.Equal(t, types.StateWithdrawn, delegation.State)
+			continue
+		}
+		require.Equal(t, types.StateSlashed, delegation.State)
+		require.NotZero(t, delegation.StateUpdatedAt)
+	}
+	state, err := database.GetBTCDelegationState(ctx, "cc")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, *state)
+
+	require.NoError(t, database.UpdateDelegationsStateByFinalityProvider(ctx, "missing", types.StateSlashed))
+	delegations, err = database.GetDelegationsByFinalityProvider(ctx, "missing")
+	require.NoError(t, err)
+	require.Empty(t, delegations)
+}
+
+func testSlashingTxHex(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateSlashed, 1)))
+
+	require.NoError(t, database.SaveBTCDelegationSlashingTxHex(ctx, "aa", "slashing tx", 100))
+	require.NoError(t, database.SaveBTCDelegationUnbondingSlashingTxHex(ctx, "aa", "unbonding slashing tx", 110))
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, model.SlashingTx{
+		SlashingTxHex:          "slashing tx",
+		UnbondingSlashingTxHex: "unbonding slashing tx",
+		SpendingHeight:         110,
+	}, delegation.SlashingTx)
+
+	require.True(t, db.IsNotFoundError(database.SaveBTCDelegationSlashingTxHex(ctx, "missing", "slashing tx", 100)))
+	err = database.SaveBTCDelegationUnbondingSlashingTxHex(ctx, "missing", "unbonding slashing tx", 100)
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func testDelegationScans(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, delegation := range []*model.BTCDelegationDetails{
+		newDelegation("aa", "staker", types.StateActive, 3),
+		newDelegation("bb", "staker", types.StateUnbonding, 1),
+		newDelegation("cc", "staker", types.StateActive, 2),
+		newDelegation("dd", "staker", types.StateWithdrawn, 1),
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+	otherFp := newDelegation("ee", "staker", types.StateActive, 5)
+	otherFp.FinalityProviderBtcPksHex = []string{"other fp"}
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, otherFp))
+
+	delegations, err := database.GetBTCDelegationsByStates(ctx, []types.DelegationState{types.StateUnbonding, types.StateWithdrawn})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"bb", "dd"}, stakingTxHashes(delegations))
+
+	// Scanned by staking tx hash
+	filter := db.BTCDelegationsFilter{States: []types.DelegationState{types.StateActive}, FinalityProviderBtcPks: []string{"fp"}}
+	delegations, err = database.GetBTCDelegationsAfter(ctx, filter, "", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"aa"}, stakingTxHashes(delegations))
+	delegations, err = database.GetBTCDelegationsAfter(ctx, filter, "aa", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cc"}, stakingTxHashes(delegations))
+	delegations, err = database.GetBTCDelegationsAfter(ctx, db.BTCDelegationsFilter{}, "cc", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"dd", "ee"}, stakingTxHashes(delegations))
+
+	delegations, err = database.SampleBTCDelegations(ctx, filter, 10)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"aa", "cc"}, stakingTxHashes(delegations))
+	delegations, err = database.SampleBTCDelegations(ctx, db.BTCDelegationsFilter{}, 2)
+	require.NoError(t, err)
+	require.Len(t, delegations, 2)
+
+	// Sorted by creation height, then staking tx hash
+	delegations, err = database.GetBTCDelegationsCreatedBetween(ctx, 1, 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bb", "dd", "cc", "aa"}, stakingTxHashes(delegations))
+	delegations, err = database.GetBTCDelegationsCreatedBetween(ctx, 2, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cc"}, stakingTxHashes(delegations))
+}
+
+func testStakerDelegationsPagination(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for i, height := range []int64{3, 1, 3} {
+		delegation := newDelegation(fmt.Sprintf("%02x", i), "staker", types.StateActive, height)
+		delegation.StakerBabylonAddress = "bbn staker"
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("ff", "other staker", types.StateActive, 2)))
+
+	// The newest delegations come first
+	filter := db.StakerDelegationsFilter{StakerBtcPkHex: "staker"}
+	page, err := database.GetStakerDelegations(ctx, filter, "", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"02", "00"}, stakingTxHashes(page.Data))
+	require.NotEmpty(t, page.PaginationToken)
+
+	page, err = database.GetStakerDelegations(ctx, filter, page.PaginationToken, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"01"}, stakingTxHashes(page.Data))
+	require.Empty(t, page.PaginationToken)
+
+	_, err = database.GetStakerDelegations(ctx, filter, "not a token", 2)
+	require.True(t, db.IsInvalidPaginationTokenError(err))
+
+	// The summaries are paginated alike
+	require.NoError(t, database.UpdateBTCDelegationDetails(ctx, "00", &model.BTCDelegationDetails{State: types.StateUnbonding}))
+	summaries, err := database.GetStakerDelegationSummaries(ctx, db.StakerDelegationsFilter{
+		StakerBabylonAddress: "bbn staker",
+		State:                types.StateActive,
+	}, "", 1)
+	require.NoError(t, err)
+	require.Len(t, summaries.Data, 1)
+	require.Equal(t, "02", summaries.Data[0].StakingTxHashHex)
+	summaries, err = database.GetStakerDelegationSummaries(ctx, db.StakerDelegationsFilter{
+		StakerBabylonAddress: "bbn staker",
+		State:                types.StateActive,
+	}, summaries.PaginationToken, 1)
+	require.NoError(t, err)
+	require.Len(t, summaries.Data, 1)
+	require.Equal(t, "01", summaries.Data[0].StakingTxHashHex)
+	require.Empty(t, summaries.PaginationToken)
+
+	_, err = database.GetStakerDelegationSummaries(ctx, filter, "not a token", 2)
+	require.True(t, db.IsInvalidPaginationTokenError(err))
+}
+
+func testDeleteDelegation(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, stakingTxHash := range []string{"aa", "bb"} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation(stakingTxHash, "staker", types.StateActive, 1)))
+		require.NoError(t, database.SaveNewTimeLockExpire(ctx, stakingTxHash, 10, types.SubStateTimelock))
+		require.NoError(t, database.SaveDelegationStateTransition(ctx, &model.DelegationStateTransition{
+			StakingTxHashHex: stakingTxHash,
+			ToState:          types.StateActive,
+			CreatedAt:        1,
+		}))
+	}
+
+	// The state history and timelocks of the delegation go along with it
+	require.NoError(t, database.DeleteBTCDelegation(ctx, "aa"))
+	_, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.True(t, db.IsNotFoundError(err))
+	timeLocks, err := database.GetTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, timeLocks)
+	transitions, err := database.GetDelegationStateTransitions(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, transitions)
+
+	timeLocks, err = database.GetTimeLocks(ctx, "bb")
+	require.NoError(t, err)
+	require.Len(t, timeLocks, 1)
+	transitions, err = database.GetDelegationStateTransitions(ctx, "bb")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+
+	require.True(t, db.IsNotFoundError(database.DeleteBTCDelegation(ctx, "aa")))
+}
+
+// stakingTxHashes returns the staking tx hashes of the delegations
+func stakingTxHashes[T interface {
+	*model.BTCDelegationDetails | *model.BTCDelegationSummary
+}](delegations []T) []string {
+	hashes := make([]string, len(delegations))
+	for i, delegation := range delegations {
+		switch delegation := any(delegation).(type) {
+		case *model.BTCDelegationDetails:
+			hashes[i] = delegation.StakingTxHashHex
+		case *model.BTCDelegationSummary:
+			hashes[i] = delegation.StakingTxHashHex
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,253 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+const (
+	fpStateActive = "FINALITY_PROVIDER_STATUS_ACTIVE"
+	fpStateJailed = "FINALITY_PROVIDER_STATUS_JAILED"
+)
+
+var finalityProviderTests = []contractTest{
+	{
+		name:    "FinalityProviderKeys",
+		methods: []string{"SaveNewFinalityProvider", "GetFinalityProviderByBtcPk", "UpdateFinalityProviderState"},
+		run:     testFinalityProviderKeys,
+	},
+	{
+		name:    "FinalityProviderDetailsUpdate",
+		methods: []string{"UpdateFinalityProviderDetailsFromEvent"},
+		run:     testFinalityProviderDetailsUpdate,
+	},
+	{
+		name:    "FinalityProvidersByBsnId",
+		methods: []string{"GetFinalityProvidersByBsnId"},
+		run:     testFinalityProvidersByBsnId,
+	},
+	{
+		name:    "FinalityProvidersPagination",
+		methods: []string{"GetFinalityProviders"},
+		run:     testFinalityProvidersPagination,
+	},
+	{
+		name: "FinalityProviderStats",
+		methods: []string{
+			"GetFinalityProviderStats", "GetFinalityProviderStakeDistribution", "CountFinalityProvidersByState",
+		},
+		run: testFinalityProviderStats,
+	},
+	{
+		name: "FinalityProviderVotingPower",
+		methods: []string{
+			"SaveFinalityProviderVotingPowerChange", "GetLatestFinalityProviderVotingPowerChanges",
+			"GetFinalityProviderActivationPeriods",
+		},
+		run: testFinalityProviderVotingPower,
+	},
+}
+
+func testFinalityProviderKeys(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	fp := &model.FinalityProviderDetails{BtcPk: "fp", State: fpStateActive, BsnId: "bsn"}
+
+	require.NoError(t, database.SaveNewFinalityProvider(ctx, fp))
+	require.True(t, db.IsDuplicateKeyError(database.SaveNewFinalityProvider(ctx, fp)))
+	saved, err := database.GetFinalityProviderByBtcPk(ctx, "fp")
+	require.NoError(t, err)
+	require.Equal(t, fp, saved)
+
+	require.NoError(t, database.UpdateFinalityProviderState(ctx, "fp", fpStateJailed))
+	saved, err = database.GetFinalityProviderByBtcPk(ctx, "fp")
+	require.NoError(t, err)
+	require.Equal(t, fpStateJailed, saved.State)
+
+	_, err = database.GetFinalityProviderByBtcPk(ctx, "missing")
+	require.True(t, db.IsNotFoundError(err))
+	err = database.UpdateFinalityProviderState(ctx, "missing", fpStateJailed)
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func testFinalityProviderDetailsUpdate(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewFinalityProvider(ctx, &model.FinalityProviderDetails{
+		BtcPk:       "fp",
+		Commission:  "0.1",
+		Description: model.Description{Moniker: "moniker", Website: "website"},
+		BsnId:       "bsn",
+	}))
+
+	// Only the fields set in the event are updated
+	require.NoError(t, database.UpdateFinalityProviderDetailsFromEvent(ctx, &model.FinalityProviderDetails{
+		BtcPk:       "fp",
+		Description: model.Description{Website: "other website"},
+	}))
+	fp, err := database.GetFinalityProviderByBtcPk(ctx, "fp")
+	require.NoError(t, err)
+	require.Equal(t, "0.1", fp.Commission)
+	require.Equal(t, model.Description{Moniker: "moniker", Website: "other website"}, fp.Description)
+
+	// An event setting nothing does not look the finality provider up
+	require.NoError(t, database.UpdateFinalityProviderDetailsFromEvent(ctx, &model.FinalityProviderDetails{BtcPk: "missing"}))
+	err = database.UpdateFinalityProviderDetailsFromEvent(ctx, &model.FinalityProviderDetails{
+		BtcPk:      "missing",
+		Commission: "0.2",
+	})
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func testFinalityProvidersByBsnId(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, fp := range []*model.FinalityProviderDetails{
+		{BtcPk: "fp-1", BsnId: model.BabylonBsnId},
+		{BtcPk: "fp-2", BsnId: "bsn"},
+		{BtcPk: "fp-3", BsnId: model.BabylonBsnId},
+	} {
+		require.NoError(t, database.SaveNewFinalityProvider(ctx, fp))
+	}
+
+	fps, err := database.GetFinalityProvidersByBsnId(ctx, model.BabylonBsnId)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"fp-1", "fp-3"}, fpBtcPks(fps))
+	fps, err = database.GetFinalityProvidersByBsnId(ctx, "bsn")
+	require.NoError(t, err)
+	require.Equal(t, []string{"fp-2"}, fpBtcPks(fps))
+	fps, err = database.GetFinalityProvidersByBsnId(ctx, "other bsn")
+	require.NoError(t, err)
+	require.Empty(t, fps)
+}
+
+func testFinalityProvidersPagination(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, fp := range []*model.FinalityProviderDetails{
+		{BtcPk: "fp-4", State: fpStateActive, Description: model.Description{Moniker: "Alpha (4)"}},
+		{BtcPk: "fp-2", State: fpStateActive, Description: model.Description{Moniker: "beta"}, BsnId: "bsn"},
+		{BtcPk: "fp-1", State: fpStateJailed, Description: model.Description{Moniker: "ALPHA"}},
+		{BtcPk: "fp-3", State: fpStateActive, Description: model.Description{Moniker: "alphabet"}},
+	} {
+		require.NoError(t, database.SaveNewFinalityProvider(ctx, fp))
+	}
+
+	// Sorted by BTC public key
+	page, err := database.GetFinalityProviders(ctx, db.FinalityProvidersFilter{}, "", 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fp-1", "fp-2", "fp-3"}, fpBtcPks(page.Data))
+	require.NotEmpty(t, page.PaginationToken)
+	page, err = database.GetFinalityProviders(ctx, db.FinalityProvidersFilter{}, page.PaginationToken, 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fp-4"}, fpBtcPks(page.Data))
+	require.Empty(t, page.PaginationToken)
+
+	babylon := model.BabylonBsnId
+	page, err = database.GetFinalityProviders(ctx, db.FinalityProvidersFilter{
+		State:         fpStateActive,
+		BsnId:         &babylon,
+		MonikerSearch: "alpha",
+	}, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fp-3", "fp-4"}, fpBtcPks(page.Data))
+
+	// The search is not a regular expression
+	page, err = database.GetFinalityProviders(ctx, db.FinalityProvidersFilter{MonikerSearch: "(4)"}, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fp-4"}, fpBtcPks(page.Data))
+
+	_, err = database.GetFinalityProviders(ctx, db.FinalityProvidersFilter{}, "not a token", 10)
+	require.True(t, db.IsInvalidPaginationTokenError(err))
+}
+
+func testFinalityProviderStats(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, fp := range []*model.FinalityProviderDetails{
+		{BtcPk: "fp-1", State: fpStateActive},
+		{BtcPk: "fp-2", State: fpStateActive},
+		{BtcPk: "fp-3", State: fpStateJailed},
+	} {
+		require.NoError(t, database.SaveNewFinalityProvider(ctx, fp))
+	}
+	for _, delegation := range []*model.BTCDelegationDetails{
+		{StakingTxHashHex: "aa", StakingAmount: 100, State: types.StateActive, FinalityProviderBtcPksHex: []string{"fp-1"}},
+		{StakingTxHashHex: "bb", StakingAmount: 200, State: types.StateActive, FinalityProviderBtcPksHex: []string{"fp-1", "fp-2"}},
+		{StakingTxHashHex: "cc", StakingAmount: 400, State: types.StateUnbonding, FinalityProviderBtcPksHex: []string{"fp-1"}},
+		{StakingTxHashHex: "dd", StakingAmount: 300, State: types.StateActive, FinalityProviderBtcPksHex: []string{"fp-3"}},
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+
+	// Only the active delegations count
+	stats, err := database.GetFinalityProviderStats(ctx, "fp-1")
+	require.NoError(t, err)
+	require.Equal(t, &model.FinalityProviderStats{ActiveDelegations: 2, ActiveStakingAmount: 300}, stats)
+	stats, err = database.GetFinalityProviderStats(ctx, "missing")
+	require.NoError(t, err)
+	require.Equal(t, &model.FinalityProviderStats{}, stats)
+
+	// The ties are sorted by BTC public key
+	distribution, err := database.GetFinalityProviderStakeDistribution(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, &model.FinalityProviderStakeDistribution{
+		Top: []*model.FinalityProviderActiveStake{
+			{BtcPk: "fp-1", ActiveStakingAmount: 300},
+			{BtcPk: "fp-3", ActiveStakingAmount: 300},
+		},
+		TotalActiveStakingAmount: 800,
+	}, distribution)
+
+	counts, err := database.CountFinalityProvidersByState(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{fpStateActive: 2, fpStateJailed: 1}, counts)
+}
+
+func testFinalityProviderVotingPower(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	latest, err := database.GetLatestFinalityProviderVotingPowerChanges(ctx, []string{"fp"})
+	require.NoError(t, err)
+	require.Empty(t, latest)
+
+	for _, change := range []*model.FinalityProviderVotingPowerChange{
+		model.NewFinalityProviderVotingPowerChange("fp", 30, true, 50),
+		model.NewFinalityProviderVotingPowerChange("fp", 10, true, 100),
+		model.NewFinalityProviderVotingPowerChange("fp", 20, false, 0),
+		model.NewFinalityProviderVotingPowerChange("other fp", 40, true, 10),
+	} {
+		require.NoError(t, database.SaveFinalityProviderVotingPowerChange(ctx, change))
+	}
+
+	// Only the requested finality providers with a recorded change are returned
+	latest, err = database.GetLatestFinalityProviderVotingPowerChanges(ctx, []string{"fp", "no change fp"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]*model.FinalityProviderVotingPowerChange{
+		"fp": model.NewFinalityProviderVotingPowerChange("fp", 30, true, 50),
+	}, latest)
+
+	latest, err = database.GetLatestFinalityProviderVotingPowerChanges(ctx, []string{"fp", "other fp"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]*model.FinalityProviderVotingPowerChange{
+		"fp":       model.NewFinalityProviderVotingPowerChange("fp", 30, true, 50),
+		"other fp": model.NewFinalityProviderVotingPowerChange("other fp", 40, true, 10),
+	}, latest)
+
+	// The changes are read by BBN height, whatever the order they were saved in
+	periods, err := database.GetFinalityProviderActivationPeriods(ctx, "fp")
+	require.NoError(t, err)
+	require.Equal(t, []*model.FinalityProviderActivationPeriod{
+		{StartHeight: 10, StartVotingPower: 100, EndHeight: 20, EndVotingPower: 100},
+		{StartHeight: 30, StartVotingPower: 50, EndVotingPower: 50},
+	}, periods)
+}
+
+// fpBtcPks returns the BTC public keys of the finality providers
+func fpBtcPks(fps []*model.FinalityProviderDetails) []string {
+	btcPks := make([]string, len(fps))
+	for i, fp := range fps {
+		btcPks[i] = fp.BtcPk
+	}
+	return btcPks
+}
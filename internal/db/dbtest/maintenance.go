@@ -0,0 +1,426 @@
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+var maintenanceTests = []contractTest{
+	{
+		name: "StateTransitionHistory",
+		methods: []string{
+			"SaveDelegationStateTransition", "GetDelegationStateTransitions", "AggregateStateTransitions",
+		},
+		run: testStateTransitionHistory,
+	},
+	{
+		name: "ReconciliationRuns",
+		methods: []string{
+			"SaveReconciliationRun", "GetUnfinishedReconciliationRun", "SaveReconciliationDiscrepancy",
+		},
+		run: testReconciliationRuns,
+	},
+	{
+		name:    "StuckDelegations",
+		methods: []string{"CountStuckDelegations", "FindStuckDelegations", "SaveStuckDelegationReport"},
+		run:     testStuckDelegations,
+	},
+	{
+		name:    "EventCaptures",
+		methods: []string{"SaveRawEventCapture", "SaveBbnEventDeadLetter"},
+		run:     testEventCaptures,
+	},
+	{
+		name:    "GlobalStats",
+		methods: []string{"GetDelegationStatsByState", "SaveGlobalStats", "GetGlobalStats"},
+		run:     testGlobalStats,
+	},
+	{
+		name:    "Bootstrap",
+		methods: []string{"SaveBootstrap", "GetBootstrap"},
+		run:     testBootstrap,
+	},
+	{
+		name:    "Locks",
+		methods: []string{"AcquireLock", "ReleaseLock"},
+		run:     testLocks,
+	},
+	{
+		name: "Pruning",
+		methods: []string{
+			"CountPrunableBTCDelegations", "ArchivePrunableBTCDelegations", "CountPrunableArchivedTimeLocks",
+			"DeletePrunableArchivedTimeLocks",
+		},
+		run: testPruning,
+	},
+	{
+		name:    "Ping",
+		methods: []string{"Ping"},
+		run:     testPing,
+	},
+	{
+		name:    "Transactions",
+		methods: []string{"RunInTransaction"},
+		run:     testTransactions,
+	},
+}
+
+func testStateTransitionHistory(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateActive, 1)))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("bb", "staker", types.StateActive, 1)))
+
+	// Monday, January 1st 2024
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	day := int64(24 * 60 * 60)
+	for _, transition := range []*model.DelegationStateTransition{
+		{StakingTxHashHex: "aa", FromState: types.StateActive, ToState: types.StateUnbonding, CreatedAt: monday + day},
+		{StakingTxHashHex: "aa", FromState: types.StateVerified, ToState: types.StateActive, CreatedAt: monday + 10},
+		{StakingTxHashHex: "bb", FromState: types.StateVerified, ToState: types.StateActive, CreatedAt: monday + 20},
+		// The staked amount of a missing delegation is not known
+		{StakingTxHashHex: "cc", FromState: types.StateVerified, ToState: types.StateActive, CreatedAt: monday + 7*day},
+	} {
+		require.NoError(t, database.SaveDelegationStateTransition(ctx, transition))
+	}
+
+	// Read in the order they were recorded
+	transitions, err := database.GetDelegationStateTransitions(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	require.Equal(t, types.StateActive, transitions[0].ToState)
+	require.Equal(t, types.StateUnbonding, transitions[1].ToState)
+	require.NotEqual(t, transitions[0].Id, transitions[1].Id)
+	transitions, err = database.GetDelegationStateTransitions(ctx, "missing")
+	require.NoError(t, err)
+	require.Empty(t, transitions)
+
+	aggregate := func(fromTime, toTime int64, periodUnit string) []*model.StateTransitionPeriodStats {
+		var stats []*model.StateTransitionPeriodStats
+		require.NoError(t, database.AggregateStateTransitions(
+			ctx, fromTime, toTime, periodUnit, func(periodStats *model.StateTransitionPeriodStats) error {
+				stats = append(stats, periodStats)
+				return nil
+			},
+		))
+		return stats
+	}
+	require.Equal(t, []*model.StateTransitionPeriodStats{
+		{PeriodStart: monday, ToState: types.StateActive, TransitionCount: 2, TotalSat: 2000},
+		{PeriodStart: monday + day, ToState: types.StateUnbonding, TransitionCount: 1, TotalSat: 1000},
+	}, aggregate(monday, monday+7*day, "day"))
+	require.Equal(t, []*model.StateTransitionPeriodStats{
+		{PeriodStart: monday, ToState: types.StateActive, TransitionCount: 2, TotalSat: 2000},
+		{PeriodStart: monday, ToState: types.StateUnbonding, TransitionCount: 1, TotalSat: 1000},
+		{PeriodStart: monday + 7*day, ToState: types.StateActive, TransitionCount: 1},
+	}, aggregate(monday, monday+14*day, "week"))
+	// The end of the range is exclusive
+	require.Empty(t, aggregate(monday, monday+10, "day"))
+
+	// The error of the visitor stops the aggregation
+	stop := errors.New("stop")
+	visited := 0
+	err = database.AggregateStateTransitions(
+		ctx, monday, monday+14*day, "week", func(*model.StateTransitionPeriodStats) error {
+			visited++
+			return stop
+		},
+	)
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, 1, visited)
+}
+
+func testReconciliationRuns(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	_, err := database.GetUnfinishedReconciliationRun(ctx)
+	require.True(t, db.IsNotFoundError(err))
+
+	completed := model.NewReconciliationRun(false, 100)
+	completed.Status = model.ReconciliationCompleted
+	completed.FinishedAt = 110
+	older := model.NewReconciliationRun(false, 200)
+	latest := model.NewReconciliationRun(true, 300)
+	for _, run := range []*model.ReconciliationRun{completed, latest, older} {
+		require.NoError(t, database.SaveReconciliationRun(ctx, run))
+	}
+	require.NoError(t, database.SaveReconciliationDiscrepancy(ctx, &model.ReconciliationDiscrepancy{
+		Kind:       model.ReconciliationDiscrepancyKind,
+		RunId:      latest.Id,
+		EntityType: model.ReconciliationDelegationEntity,
+		EntityId:   "aa",
+	}))
+
+	// The latest run started is resumed
+	run, err := database.GetUnfinishedReconciliationRun(ctx)
+	require.NoError(t, err)
+	require.Equal(t, latest, run)
+
+	// Saving a run again replaces it
+	latest.Status = model.ReconciliationCompleted
+	latest.Checked = 10
+	require.NoError(t, database.SaveReconciliationRun(ctx, latest))
+	run, err = database.GetUnfinishedReconciliationRun(ctx)
+	require.NoError(t, err)
+	require.Equal(t, older, run)
+}
+
+func testStuckDelegations(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	updated := newDelegation("aa", "staker", types.StatePending, 1)
+	updated.StateUpdatedAt = 8
+	for _, delegation := range []*model.BTCDelegationDetails{
+		updated,
+		// The delegations never updated are pending since their creation
+		newDelegation("bb", "staker", types.StatePending, 5),
+		newDelegation("cc", "staker", types.StatePending, 3),
+		newDelegation("dd", "staker", types.StatePending, 20),
+		newDelegation("ee", "staker", types.StateActive, 1),
+		// pending since exactly the threshold time, not stuck yet
+		newDelegation("ff", "staker", types.StatePending, 10),
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+
+	count, err := database.CountStuckDelegations(ctx, types.StatePending, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), count)
+	count, err = database.CountStuckDelegations(ctx, types.StateVerified, 10)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	// The delegations never updated come first, the longest stuck first
+	stuck, err := database.FindStuckDelegations(ctx, types.StatePending, 10, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cc", "bb", "aa"}, stakingTxHashes(stuck))
+	stuck, err = database.FindStuckDelegations(ctx, types.StatePending, 10, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"cc"}, stakingTxHashes(stuck))
+
+	report := &model.StuckDelegationReport{
+		CreatedAt: 10,
+		Counts:    map[string]uint64{types.StatePending.String(): 3},
+		Offenders: []*model.StuckDelegation{{StakingTxHashHex: "cc", State: types.StatePending.String(), StateSince: 3}},
+	}
+	require.NoError(t, database.SaveStuckDelegationReport(ctx, report))
+	require.NoError(t, database.SaveStuckDelegationReport(ctx, report))
+}
+
+func testEventCaptures(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	attributes := []model.RawEventAttribute{{Key: "staking_tx_hash", Value: "aa"}}
+
+	// The captures are appended
+	capture := &model.RawEventCapture{CapturedAt: 1, BbnHeight: 10, EventType: "event", Attributes: attributes}
+	require.NoError(t, database.SaveRawEventCapture(ctx, capture))
+	require.NoError(t, database.SaveRawEventCapture(ctx, capture))
+
+	// The dead letter of an event failing again is replaced
+	deadLetter := &model.BbnEventDeadLetter{
+		Id:         "10-0",
+		BbnHeight:  10,
+		EventType:  "event",
+		Attributes: attributes,
+		Error:      "invalid event",
+	}
+	require.NoError(t, database.SaveBbnEventDeadLetter(ctx, deadLetter))
+	deadLetter.Error = "still invalid"
+	require.NoError(t, database.SaveBbnEventDeadLetter(ctx, deadLetter))
+}
+
+func testGlobalStats(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	stats, err := database.GetDelegationStatsByState(ctx)
+	require.NoError(t, err)
+	require.Empty(t, stats)
+	_, err = database.GetGlobalStats(ctx)
+	require.True(t, db.IsNotFoundError(err))
+
+	withdrawn := newDelegation("cc", "staker", types.StateWithdrawn, 1)
+	withdrawn.StakingAmount = 500
+	for _, delegation := range []*model.BTCDelegationDetails{
+		newDelegation("aa", "staker", types.StateActive, 1),
+		newDelegation("bb", "staker", types.StateActive, 1),
+		withdrawn,
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+	stats, err = database.GetDelegationStatsByState(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []*model.DelegationStateStats{
+		{State: types.StateActive.String(), Count: 2, StakingAmount: 2000},
+		{State: types.StateWithdrawn.String(), Count: 1, StakingAmount: 500},
+	}, stats)
+
+	// The global stats document is replaced
+	require.NoError(t, database.SaveGlobalStats(ctx, &model.GlobalStats{ActiveTvl: 1000, BbnLag: 5}))
+	saved := &model.GlobalStats{
+		ActiveTvl:          2000,
+		DelegationsByState: map[string]uint64{types.StateActive.String(): 2},
+	}
+	require.NoError(t, database.SaveGlobalStats(ctx, saved))
+	globalStats, err := database.GetGlobalStats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, saved, globalStats)
+	require.Zero(t, globalStats.BbnLag)
+}
+
+func testBootstrap(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	_, err := database.GetBootstrap(ctx)
+	require.True(t, db.IsNotFoundError(err))
+
+	// The bootstrap document is replaced
+	require.NoError(t, database.SaveBootstrap(ctx, &model.Bootstrap{
+		ParamsSynced: true, FinalityProvidersCursor: "0a", FinalityProvidersSynced: 10, StartedAt: 1,
+	}))
+	saved := &model.Bootstrap{
+		ParamsSynced: true, FinalityProvidersSynced: 15, StartedAt: 1, CompletedAt: 2,
+		StartHeight: 100, StartHeightSource: "configured",
+	}
+	require.NoError(t, database.SaveBootstrap(ctx, saved))
+	bootstrap, err := database.GetBootstrap(ctx)
+	require.NoError(t, err)
+	require.Equal(t, saved, bootstrap)
+	require.Empty(t, bootstrap.FinalityProvidersCursor)
+	require.True(t, bootstrap.IsCompleted())
+}
+
+func testLocks(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.AcquireLock(ctx, "lock", "owner-1", time.Minute))
+	// The owner renews its lock
+	require.NoError(t, database.AcquireLock(ctx, "lock", "owner-1", time.Minute))
+
+	err := database.AcquireLock(ctx, "lock", "owner-2", time.Minute)
+	require.True(t, db.IsLockHeldError(err))
+	var heldErr *db.LockHeldError
+	require.True(t, errors.As(err, &heldErr))
+	require.Equal(t, "owner-1", heldErr.Owner)
+
+	// Only the owner releases its lock
+	require.NoError(t, database.ReleaseLock(ctx, "lock", "owner-2"))
+	require.True(t, db.IsLockHeldError(database.AcquireLock(ctx, "lock", "owner-2", time.Minute)))
+	require.NoError(t, database.ReleaseLock(ctx, "lock", "owner-1"))
+	require.NoError(t, database.AcquireLock(ctx, "lock", "owner-2", time.Minute))
+
+	// An expired lock is taken over
+	require.NoError(t, database.AcquireLock(ctx, "expired", "owner-1", -time.Minute))
+	require.NoError(t, database.AcquireLock(ctx, "expired", "owner-2", time.Minute))
+	require.True(t, db.IsLockHeldError(database.AcquireLock(ctx, "expired", "owner-1", time.Minute)))
+}
+
+func testPruning(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	for _, delegation := range []*model.BTCDelegationDetails{
+		newDelegation("aa", "staker", types.StateWithdrawn, 1),
+		newDelegation("bb", "staker", types.StateWithdrawn, 1),
+		newDelegation("cc", "staker", types.StateWithdrawn, 1),
+		newDelegation("dd", "staker", types.StateActive, 1),
+	} {
+		delegation.StateUpdatedAt = 5
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+	recent := newDelegation("ee", "staker", types.StateWithdrawn, 1)
+	recent.StateUpdatedAt = 20
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, recent))
+
+	count, err := database.CountPrunableBTCDelegations(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), count)
+
+	// Archived by batch
+	moved, err := database.ArchivePrunableBTCDelegations(ctx, 10, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), moved)
+	moved, err = database.ArchivePrunableBTCDelegations(ctx, 10, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), moved)
+	moved, err = database.ArchivePrunableBTCDelegations(ctx, 10, 2)
+	require.NoError(t, err)
+	require.Zero(t, moved)
+	for _, stakingTxHash := range []string{"aa", "bb", "cc"} {
+		_, err := database.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+		require.True(t, db.IsNotFoundError(err))
+	}
+	for _, stakingTxHash := range []string{"dd", "ee"} {
+		_, err := database.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+		require.NoError(t, err)
+	}
+
+	// The archived timelocks of the pruned delegations and of those in a
+	// terminal state are prunable, the others are kept
+	for _, stakingTxHash := range []string{"aa", "dd", "ee"} {
+		require.NoError(t, database.SaveNewTimeLockExpire(ctx, stakingTxHash, 10, types.SubStateTimelock))
+		require.NoError(t, database.DeleteExpiredDelegation(ctx, stakingTxHash))
+	}
+	archivedBefore := time.Now().Unix() + 60
+	count, err = database.CountPrunableArchivedTimeLocks(ctx, archivedBefore)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), count)
+	count, err = database.CountPrunableArchivedTimeLocks(ctx, 10)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	deleted, err := database.DeletePrunableArchivedTimeLocks(ctx, archivedBefore, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), deleted)
+	deleted, err = database.DeletePrunableArchivedTimeLocks(ctx, archivedBefore, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), deleted)
+	count, err = database.CountPrunableArchivedTimeLocks(ctx, archivedBefore)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	archived, err := database.GetArchivedTimeLocks(ctx, "dd")
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+}
+
+func testPing(t *testing.T, database db.DbInterface) {
+	require.NoError(t, database.Ping(context.Background()))
+}
+
+func testTransactions(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateActive, 1)))
+
+	// The writes of a failed transaction are rolled back, along with the ones
+	// of the transactions run within it
+	errFailed := errors.New("failed")
+	err := database.RunInTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("bb", "staker", types.StateActive, 1)))
+		require.NoError(t, database.RunInTransaction(ctx, func(ctx context.Context) error {
+			return database.UpdateBTCDelegationState(
+				ctx, "aa", []types.DelegationState{types.StateActive}, types.StateUnbonding, nil,
+			)
+		}))
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, delegation.State)
+	_, err = database.GetBTCDelegationByStakingTxHash(ctx, "bb")
+	require.True(t, db.IsNotFoundError(err))
+
+	// The writes of a successful one are committed
+	require.NoError(t, database.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := database.SaveNewBTCDelegation(ctx, newDelegation("bb", "staker", types.StateActive, 1)); err != nil {
+			return err
+		}
+		return database.UpdateBTCDelegationState(
+			ctx, "aa", []types.DelegationState{types.StateActive}, types.StateUnbonding, nil,
+		)
+	}))
+	delegation, err = database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateUnbonding, delegation.State)
+	_, err = database.GetBTCDelegationByStakingTxHash(ctx, "bb")
+	require.NoError(t, err)
+}
@@ -0,0 +1,210 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+var outboxTests = []contractTest{
+	{
+		name: "OutboxSequence",
+		methods: []string{
+			"SaveOutboxEvent", "GetOutboxSequence", "GetDelegationOutboxEvents", "GetUnsentOutboxEvents",
+			"MarkOutboxEventSent",
+		},
+		run: testOutboxSequence,
+	},
+	{
+		name:    "UnsentOutboxEventsPaging",
+		methods: []string{"GetUnsentOutboxEvents"},
+		run:     testUnsentOutboxEventsPaging,
+	},
+	{
+		name:    "OutboxEventWithoutDelegation",
+		methods: []string{"SaveOutboxEvent"},
+		run:     testOutboxEventWithoutDelegation,
+	},
+	{
+		name: "PoisonOutboxEvents",
+		methods: []string{
+			"MarkOutboxEventFailed", "GetPoisonOutboxEvents", "RequeuePoisonOutboxEvents", "GetOutboxStats",
+		},
+		run: testPoisonOutboxEvents,
+	},
+}
+
+func testOutboxSequence(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateActive, 1)))
+	_, err := database.GetOutboxSequence(ctx, "aa")
+	require.True(t, db.IsNotFoundError(err))
+
+	for i, eventType := range []string{model.OutboxEventTypeActiveStaking, model.OutboxEventTypeUnbondingStaking} {
+		event := newOutboxEvent(eventType, "aa", int64(i+1))
+		require.NoError(t, database.SaveOutboxEvent(ctx, event))
+		require.Equal(t, uint64(i+1), event.Sequence)
+	}
+
+	// Saving an event again does not consume a sequence number
+	duplicate := &model.OutboxEvent{Id: model.OutboxEventTypeActiveStaking + ":aa", StakingTxHashHex: "aa"}
+	require.True(t, db.IsDuplicateKeyError(database.SaveOutboxEvent(ctx, duplicate)))
+	sequence, err := database.GetOutboxSequence(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), sequence)
+
+	unsent, err := database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+	require.NoError(t, err)
+	require.Len(t, unsent, 2)
+	require.NoError(t, database.MarkOutboxEventSent(ctx, unsent[0].Id, 1))
+	require.True(t, db.IsNotFoundError(database.MarkOutboxEventSent(ctx, "missing", 1)))
+	unsent, err = database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+	require.NoError(t, err)
+	require.Len(t, unsent, 1)
+	require.Equal(t, model.OutboxEventTypeUnbondingStaking, unsent[0].EventType)
+	unsent, err = database.GetUnsentOutboxEvents(ctx, unsent[0].CreatedAt, unsent[0].Id, 10)
+	require.NoError(t, err)
+	require.Empty(t, unsent)
+
+	// The sent events are kept
+	events, err := database.GetDelegationOutboxEvents(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(1), events[0].Sequence)
+	require.Equal(t, int64(1), events[0].SentAt)
+	require.Equal(t, uint64(2), events[1].Sequence)
+
+	// The sequence outlives the delegation, so that indexing it again does
+	// not reuse its numbers
+	require.NoError(t, database.DeleteBTCDelegation(ctx, "aa"))
+	sequence, err = database.GetOutboxSequence(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), sequence)
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateActive, 1)))
+	event := newOutboxEvent(model.OutboxEventTypeWithdrawn, "aa", 3)
+	require.NoError(t, database.SaveOutboxEvent(ctx, event))
+	require.Equal(t, uint64(3), event.Sequence)
+}
+
+func testUnsentOutboxEventsPaging(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	// The events of a block are created at the same time
+	for stakingTxHash, createdAt := range map[string]int64{"cc": 5, "aa": 5, "bb": 5, "dd": 6} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation(stakingTxHash, "staker", types.StateActive, 1)))
+		require.NoError(t, database.SaveOutboxEvent(ctx, newOutboxEvent(model.OutboxEventTypeActiveStaking, stakingTxHash, createdAt)))
+	}
+
+	// A page resumes right after the last event of the previous one, among
+	// those created at the same time
+	var ids []string
+	var createdAfter int64
+	var afterId string
+	for {
+		page, err := database.GetUnsentOutboxEvents(ctx, createdAfter, afterId, 2)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		for _, event := range page {
+			ids = append(ids, event.Id)
+		}
+		last := page[len(page)-1]
+		createdAfter, afterId = last.CreatedAt, last.Id
+	}
+	require.Equal(t, []string{
+		model.OutboxEventTypeActiveStaking + ":aa",
+		model.OutboxEventTypeActiveStaking + ":bb",
+		model.OutboxEventTypeActiveStaking + ":cc",
+		model.OutboxEventTypeActiveStaking + ":dd",
+	}, ids)
+}
+
+func testOutboxEventWithoutDelegation(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+
+	// The event is not saved without the sequence of its delegation
+	event := newOutboxEvent(model.OutboxEventTypeActiveStaking, "missing", 1)
+	require.True(t, db.IsNotFoundError(database.SaveOutboxEvent(ctx, event)))
+	events, err := database.GetDelegationOutboxEvents(ctx, "missing")
+	require.NoError(t, err)
+	require.Empty(t, events)
+	unsent, err := database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+	require.NoError(t, err)
+	require.Empty(t, unsent)
+
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("missing", "staker", types.StateActive, 1)))
+	require.NoError(t, database.SaveOutboxEvent(ctx, event))
+	require.Equal(t, uint64(1), event.Sequence)
+}
+
+func testPoisonOutboxEvents(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	stats, err := database.GetOutboxStats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.OutboxStats{}, stats)
+
+	for i, stakingTxHash := range []string{"aa", "bb", "cc"} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation(stakingTxHash, "staker", types.StateActive, 1)))
+		event := newOutboxEvent(model.OutboxEventTypeActiveStaking, stakingTxHash, int64(10+i))
+		require.NoError(t, database.SaveOutboxEvent(ctx, event))
+	}
+	aa := model.OutboxEventTypeActiveStaking + ":aa"
+	bb := model.OutboxEventTypeActiveStaking + ":bb"
+	cc := model.OutboxEventTypeActiveStaking + ":cc"
+
+	require.NoError(t, database.MarkOutboxEventFailed(ctx, aa, "timeout", 100, false))
+	require.NoError(t, database.MarkOutboxEventFailed(ctx, aa, "closed", 200, true))
+	require.NoError(t, database.MarkOutboxEventSent(ctx, cc, 1))
+	require.True(t, db.IsNotFoundError(database.MarkOutboxEventFailed(ctx, "missing", "timeout", 100, true)))
+
+	poison, err := database.GetPoisonOutboxEvents(ctx)
+	require.NoError(t, err)
+	require.Len(t, poison, 1)
+	require.Equal(t, aa, poison[0].Id)
+	require.Equal(t, 2, poison[0].Attempts)
+	require.Equal(t, int64(200), poison[0].NextAttemptAt)
+	require.Equal(t, "closed", poison[0].LastError)
+
+	// The poison events are not pushed, nor counted as unsent
+	unsent, err := database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+	require.NoError(t, err)
+	require.Len(t, unsent, 1)
+	require.Equal(t, bb, unsent[0].Id)
+	stats, err = database.GetOutboxStats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.OutboxStats{Unsent: 1, OldestUnsentAt: 11, Poison: 1}, stats)
+
+	requeued, err := database.RequeuePoisonOutboxEvents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), requeued)
+	requeued, err = database.RequeuePoisonOutboxEvents(ctx)
+	require.NoError(t, err)
+	require.Zero(t, requeued)
+
+	unsent, err = database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+	require.NoError(t, err)
+	require.Len(t, unsent, 2)
+	require.Equal(t, aa, unsent[0].Id)
+	require.Zero(t, unsent[0].Attempts)
+	require.Zero(t, unsent[0].NextAttemptAt)
+	require.False(t, unsent[0].Poison)
+	stats, err = database.GetOutboxStats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.OutboxStats{Unsent: 2, OldestUnsentAt: 10}, stats)
+}
+
+// newOutboxEvent returns an event of the type for the delegation, created at
+// the given time
+func newOutboxEvent(eventType, stakingTxHash string, createdAt int64) *model.OutboxEvent {
+	return &model.OutboxEvent{
+		Id:               eventType + ":" + stakingTxHash,
+		EventType:        eventType,
+		StakingTxHashHex: stakingTxHash,
+		CreatedAt:        createdAt,
+	}
+}
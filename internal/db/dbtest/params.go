@@ -0,0 +1,70 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+)
+
+var paramsTests = []contractTest{
+	{
+		name: "StakingParams",
+		methods: []string{
+			"SaveStakingParams", "ReplaceStakingParams", "GetStakingParams", "GetAllStakingParams",
+		},
+		run: testStakingParams,
+	},
+	{
+		name:    "CheckpointParams",
+		methods: []string{"SaveCheckpointParams", "GetCheckpointParams"},
+		run:     testCheckpointParams,
+	},
+}
+
+func testStakingParams(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	_, err := database.GetStakingParams(ctx, 0)
+	require.True(t, db.IsNotFoundError(err))
+	all, err := database.GetAllStakingParams(ctx)
+	require.NoError(t, err)
+	require.Empty(t, all)
+
+	v0 := &bbnclient.StakingParams{CovenantPks: []string{"covenant"}, CovenantQuorum: 1, UnbondingTimeBlocks: 100}
+	v1 := &bbnclient.StakingParams{CovenantPks: []string{"covenant"}, CovenantQuorum: 1, UnbondingTimeBlocks: 200}
+	require.NoError(t, database.SaveStakingParams(ctx, 0, v0))
+	require.NoError(t, database.SaveStakingParams(ctx, 1, v1))
+
+	// Saving a version again keeps the params first saved
+	require.NoError(t, database.SaveStakingParams(ctx, 0, v1))
+	params, err := database.GetStakingParams(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, v0, params)
+
+	// Replacing them does not
+	fixed := &bbnclient.StakingParams{CovenantPks: []string{"covenant"}, CovenantQuorum: 1, UnbondingTimeBlocks: 101}
+	require.NoError(t, database.ReplaceStakingParams(ctx, 0, fixed))
+	all, err = database.GetAllStakingParams(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[uint32]*bbnclient.StakingParams{0: fixed, 1: v1}, all)
+
+	require.True(t, db.IsNotFoundError(database.ReplaceStakingParams(ctx, 2, fixed)))
+}
+
+func testCheckpointParams(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	_, err := database.GetCheckpointParams(ctx)
+	require.True(t, db.IsNotFoundError(err))
+
+	saved := &bbnclient.CheckpointParams{BtcConfirmationDepth: 6, CheckpointFinalizationTimeout: 100, CheckpointTag: "bbn"}
+	require.NoError(t, database.SaveCheckpointParams(ctx, saved))
+	// Saving them again keeps the params first saved
+	require.NoError(t, database.SaveCheckpointParams(ctx, &bbnclient.CheckpointParams{BtcConfirmationDepth: 10}))
+
+	params, err := database.GetCheckpointParams(ctx)
+	require.NoError(t, err)
+	require.Equal(t, saved, params)
+}
@@ -0,0 +1,273 @@
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+var timeLockTests = []contractTest{
+	{
+		name:    "ExpiredTimeLocksOrder",
+		methods: []string{"SaveNewTimeLockExpire", "FindExpiredDelegations", "ForEachExpiredDelegation"},
+		run:     testExpiredTimeLocksOrder,
+	},
+	{
+		name:    "ExpiryBacklogStats",
+		methods: []string{"GetExpiryBacklogStats"},
+		run:     testExpiryBacklogStats,
+	},
+	{
+		name:    "ExpiredTimeLocksArchive",
+		methods: []string{"DeleteExpiredDelegation", "GetTimeLocks", "GetArchivedTimeLocks"},
+		run:     testExpiredTimeLocksArchive,
+	},
+	{
+		name:    "OrphanedTimeLocks",
+		methods: []string{"FindOrphanedTimeLocks", "DeleteTimeLocks"},
+		run:     testOrphanedTimeLocks,
+	},
+	{
+		name:    "TimeLocksExpiringBetween",
+		methods: []string{"GetTimeLocksExpiringBetween"},
+		run:     testTimeLocksExpiringBetween,
+	},
+	{
+		name:    "TimeLockExpireHeightUpdate",
+		methods: []string{"FindTimeLocksByParamsVersion", "UpdateTimeLockExpireHeight"},
+		run:     testTimeLockExpireHeightUpdate,
+	},
+}
+
+func testExpiredTimeLocksOrder(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	timeLocks := []*model.TimeLockDocument{
+		model.NewTimeLockDocument("bb", 5, types.SubStateTimelock),
+		model.NewTimeLockDocument("aa", 7, types.SubStateTimelock),
+		model.NewTimeLockDocument("aa", 5, types.SubStateTimelock),
+		model.NewTimeLockDocument("aa", 5, types.SubStateEarlyUnbonding),
+		model.NewTimeLockDocument("cc", 11, types.SubStateTimelock),
+	}
+	for _, tl := range timeLocks {
+		require.NoError(t, database.SaveNewTimeLockExpire(ctx, tl.StakingTxHashHex, tl.ExpireHeight, tl.DelegationSubState))
+	}
+
+	// Sorted by expire height, then staking tx hash and sub state
+	expected := []model.TimeLockDocument{*timeLocks[3], *timeLocks[2], *timeLocks[0], *timeLocks[1]}
+	expired, err := database.FindExpiredDelegations(ctx, 10, 10)
+	require.NoError(t, err)
+	require.Equal(t, expected, expired)
+	expired, err = database.FindExpiredDelegations(ctx, 10, 2)
+	require.NoError(t, err)
+	require.Equal(t, expected[:2], expired)
+
+	var visited []model.TimeLockDocument
+	require.NoError(t, database.ForEachExpiredDelegation(ctx, 10, func(tl model.TimeLockDocument) error {
+		visited = append(visited, tl)
+		return nil
+	}))
+	require.Equal(t, expected, visited)
+
+	// The error of the function stops the iteration as is
+	stop := errors.New("stop")
+	visited = nil
+	err = database.ForEachExpiredDelegation(ctx, 10, func(tl model.TimeLockDocument) error {
+		visited = append(visited, tl)
+		return stop
+	})
+	require.ErrorIs(t, err, stop)
+	require.Len(t, visited, 1)
+}
+
+func testExpiryBacklogStats(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	stats, err := database.GetExpiryBacklogStats(ctx, 10)
+	require.NoError(t, err)
+	require.Empty(t, stats)
+
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "aa", 8, types.SubStateTimelock))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "bb", 3, types.SubStateTimelock))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "cc", 5, types.SubStateTimelockSlashing))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "dd", 11, types.SubStateEarlyUnbonding))
+
+	stats, err = database.GetExpiryBacklogStats(ctx, 10)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []*model.ExpiryBacklogStats{
+		{SubState: types.SubStateTimelock, Count: 2, OldestExpireHeight: 3},
+		{SubState: types.SubStateTimelockSlashing, Count: 1, OldestExpireHeight: 5},
+	}, stats)
+}
+
+func testExpiredTimeLocksArchive(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "aa", 5, types.SubStateTimelock))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "aa", 7, types.SubStateEarlyUnbonding))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "bb", 5, types.SubStateTimelock))
+
+	// Read in the order they were saved
+	timeLocks, err := database.GetTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, []model.TimeLockDocument{
+		*model.NewTimeLockDocument("aa", 5, types.SubStateTimelock),
+		*model.NewTimeLockDocument("aa", 7, types.SubStateEarlyUnbonding),
+	}, timeLocks)
+	archived, err := database.GetArchivedTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, archived)
+
+	// Every timelock of the delegation moves to the archive
+	require.NoError(t, database.DeleteExpiredDelegation(ctx, "aa"))
+	timeLocks, err = database.GetTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Empty(t, timeLocks)
+	archived, err = database.GetArchivedTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, archived, 2)
+	for i, expected := range []*model.TimeLockDocument{
+		model.NewTimeLockDocument("aa", 5, types.SubStateTimelock),
+		model.NewTimeLockDocument("aa", 7, types.SubStateEarlyUnbonding),
+	} {
+		require.Equal(t, *expected, archived[i].TimeLockDocument)
+		require.Equal(t, model.TimeLockArchiveReasonExpired, archived[i].ArchiveReason)
+		require.NotZero(t, archived[i].ArchivedAt)
+	}
+
+	timeLocks, err = database.GetTimeLocks(ctx, "bb")
+	require.NoError(t, err)
+	require.Len(t, timeLocks, 1)
+
+	require.Error(t, database.DeleteExpiredDelegation(ctx, "aa"))
+}
+
+func testOrphanedTimeLocks(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker", types.StateActive, 1)))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("bb", "staker", types.StateWithdrawn, 1)))
+	for _, stakingTxHash := range []string{"aa", "bb", "cc"} {
+		require.NoError(t, database.SaveNewTimeLockExpire(ctx, stakingTxHash, 5, types.SubStateTimelock))
+	}
+
+	// The timelocks of a delegation in a terminal state or missing are orphaned
+	terminalStates := []types.DelegationState{types.StateWithdrawn, types.StateSlashed}
+	orphaned, err := database.FindOrphanedTimeLocks(ctx, terminalStates, 10)
+	require.NoError(t, err)
+	require.Len(t, orphaned, 2)
+	states := make(map[string]types.DelegationState)
+	ids := make([]primitive.ObjectID, len(orphaned))
+	for i, timeLock := range orphaned {
+		states[timeLock.StakingTxHashHex] = timeLock.DelegationState
+		ids[i] = timeLock.Id
+	}
+	require.Equal(t, map[string]types.DelegationState{"bb": types.StateWithdrawn, "cc": ""}, states)
+
+	orphaned, err = database.FindOrphanedTimeLocks(ctx, terminalStates, 1)
+	require.NoError(t, err)
+	require.Len(t, orphaned, 1)
+
+	deleted, err := database.DeleteTimeLocks(ctx, append(ids, primitive.NewObjectID()))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), deleted)
+	orphaned, err = database.FindOrphanedTimeLocks(ctx, terminalStates, 10)
+	require.NoError(t, err)
+	require.Empty(t, orphaned)
+
+	archived, err := database.GetArchivedTimeLocks(ctx, "cc")
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	require.Equal(t, model.TimeLockArchiveReasonOrphaned, archived[0].ArchiveReason)
+	timeLocks, err := database.GetTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, timeLocks, 1)
+
+	deleted, err = database.DeleteTimeLocks(ctx, ids)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+}
+
+func testTimeLocksExpiringBetween(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("aa", "staker-a", types.StateUnbonding, 1)))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("bb", "staker-b", types.StateSlashed, 1)))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, newDelegation("cc", "staker-c", types.StateWithdrawn, 1)))
+	for _, timeLock := range []*model.TimeLockDocument{
+		model.NewTimeLockDocument("bb", 20, types.SubStateEarlyUnbonding),
+		model.NewTimeLockDocument("aa", 10, types.SubStateTimelock),
+		model.NewTimeLockDocument("cc", 15, types.SubStateTimelock),
+		model.NewTimeLockDocument("aa", 20, types.SubStateTimelock),
+		model.NewTimeLockDocument("missing", 12, types.SubStateTimelock),
+		model.NewTimeLockDocument("aa", 31, types.SubStateTimelock),
+	} {
+		require.NoError(t, database.SaveNewTimeLockExpire(
+			ctx, timeLock.StakingTxHashHex, timeLock.ExpireHeight, timeLock.DelegationSubState,
+		))
+	}
+
+	// Only the timelocks of the delegations which can become withdrawable,
+	// sorted by expire height and then in the order they were saved
+	page, err := database.GetTimeLocksExpiringBetween(ctx, 10, 30, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Data, 2)
+	require.Equal(t, "aa", page.Data[0].StakingTxHashHex)
+	require.Equal(t, uint32(10), page.Data[0].ExpireHeight)
+	require.Equal(t, "staker-a", page.Data[0].StakerBtcPkHex)
+	require.Equal(t, uint64(1000), page.Data[0].StakingAmount)
+	require.Equal(t, "bb", page.Data[1].StakingTxHashHex)
+	require.Equal(t, types.SubStateEarlyUnbonding, page.Data[1].DelegationSubState)
+	require.NotEmpty(t, page.PaginationToken)
+
+	page, err = database.GetTimeLocksExpiringBetween(ctx, 10, 30, page.PaginationToken, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Data, 1)
+	require.Equal(t, "aa", page.Data[0].StakingTxHashHex)
+	require.Equal(t, uint32(20), page.Data[0].ExpireHeight)
+	require.Empty(t, page.PaginationToken)
+
+	_, err = database.GetTimeLocksExpiringBetween(ctx, 10, 30, "not a token", 2)
+	require.True(t, db.IsInvalidPaginationTokenError(err))
+}
+
+func testTimeLockExpireHeightUpdate(t *testing.T, database db.DbInterface) {
+	ctx := context.Background()
+	v1 := newDelegation("aa", "staker", types.StateActive, 1)
+	v1.ParamsVersion = 1
+	v2 := newDelegation("bb", "staker", types.StateActive, 1)
+	v2.ParamsVersion = 2
+	withdrawn := newDelegation("cc", "staker", types.StateWithdrawn, 1)
+	withdrawn.ParamsVersion = 1
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, v1))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, v2))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, withdrawn))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "aa", 10, types.SubStateTimelock))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "aa", 12, types.SubStateEarlyUnbonding))
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "bb", 10, types.SubStateTimelock))
+	// left behind by a delegation in a terminal state
+	require.NoError(t, database.SaveNewTimeLockExpire(ctx, "cc", 10, types.SubStateTimelock))
+
+	subStates := []types.DelegationSubState{types.SubStateTimelock}
+	timeLocks, err := database.FindTimeLocksByParamsVersion(ctx, subStates, 1)
+	require.NoError(t, err)
+	require.Equal(t, []model.TimeLockDocument{*model.NewTimeLockDocument("aa", 10, types.SubStateTimelock)}, timeLocks)
+	timeLocks, err = database.FindTimeLocksByParamsVersion(ctx, subStates, 3)
+	require.NoError(t, err)
+	require.Empty(t, timeLocks)
+
+	timeLock := model.NewTimeLockDocument("aa", 10, types.SubStateTimelock)
+	require.NoError(t, database.UpdateTimeLockExpireHeight(ctx, timeLock, 15))
+	timeLocks, err = database.GetTimeLocks(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, []model.TimeLockDocument{
+		*model.NewTimeLockDocument("aa", 15, types.SubStateTimelock),
+		*model.NewTimeLockDocument("aa", 12, types.SubStateEarlyUnbonding),
+	}, timeLocks)
+
+	// The timelock is matched by its former expire height
+	err = database.UpdateTimeLockExpireHeight(ctx, timeLock, 20)
+	require.True(t, db.IsNotFoundError(err))
+}
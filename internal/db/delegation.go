@@ -4,20 +4,35 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func (db *Database) SaveNewBTCDelegation(
 	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
 ) error {
-	_, err := db.client.Database(db.dbName).
-		Collection(model.BTCDelegationDetailsCollection).
-		InsertOne(ctx, delegationDoc)
+	collection := db.client.Database(db.dbName).Collection(model.BTCDelegationDetailsCollection)
+
+	// A duplicate key error would abort the transaction the insert runs in
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": delegationDoc.StakingTxHashHex})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &DuplicateKeyError{
+			Key:     delegationDoc.StakingTxHashHex,
+			Message: "delegation already exists",
+		}
+	}
+
+	_, err = collection.InsertOne(ctx, delegationDoc)
 	if err != nil {
 		var writeErr mongo.WriteException
 		if errors.As(err, &writeErr) {
@@ -42,12 +57,158 @@ func (db *Database) UpdateBTCDelegationState(
 	newState types.DelegationState,
 	newSubState *types.DelegationSubState,
 ) error {
-	if len(qualifiedPreviousStates) == 0 {
-		return fmt.Errorf("qualified previous states array cannot be empty")
+	_, err := db.updateBTCDelegationState(ctx, DelegationStateUpdate{
+		StakingTxHash:           stakingTxHash,
+		QualifiedPreviousStates: qualifiedPreviousStates,
+		NewState:                newState,
+		NewSubState:             newSubState,
+	})
+	return err
+}
+
+// updateBTCDelegationState applies the update as UpdateBTCDelegationState,
+// returning the delegation as it was before it
+func (db *Database) updateBTCDelegationState(
+	ctx context.Context, update DelegationStateUpdate,
+) (*model.BTCDelegationSummary, error) {
+	if len(update.QualifiedPreviousStates) == 0 {
+		return nil, fmt.Errorf("qualified previous states array cannot be empty")
+	}
+
+	var qualifiedStates []types.DelegationState
+	for _, state := range update.QualifiedPreviousStates {
+		if state.CanTransitionTo(update.NewState) {
+			qualifiedStates = append(qualifiedStates, state)
+		}
+	}
+	if len(qualifiedStates) == 0 {
+		return nil, &InvalidStateTransitionError{
+			Key: update.StakingTxHash, From: update.QualifiedPreviousStates, To: update.NewState,
+		}
 	}
 
-	qualifiedStateStrs := make([]string, len(qualifiedPreviousStates))
-	for i, state := range qualifiedPreviousStates {
+	previous, err := db.setBTCDelegationState(
+		ctx, update.StakingTxHash, qualifiedStates, update.NewState, update.NewSubState,
+	)
+	if !IsNotFoundError(err) {
+		return previous, err
+	}
+	// Tell a missing delegation apart from one in another state
+	currentState, stateErr := db.GetBTCDelegationState(ctx, update.StakingTxHash)
+	if stateErr != nil {
+		return nil, err
+	}
+	return nil, &StateTransitionError{
+		StakingTxHash: update.StakingTxHash, CurrentState: *currentState, TargetState: update.NewState,
+	}
+}
+
+// ApplyBTCDelegationStateChange applies the state update and saves the records
+// of the change in a single transaction, so that neither is left without the
+// other
+func (db *Database) ApplyBTCDelegationStateChange(ctx context.Context, change DelegationStateChange) error {
+	method := "ApplyBTCDelegationStateChange"
+	_, err := db.withTransaction(ctx, method, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		previous, err := db.updateBTCDelegationState(sessCtx, change.DelegationStateUpdate)
+		if err != nil {
+			return nil, err
+		}
+
+		if journal := change.BTCDerivedChange; journal != nil {
+			journal.PreviousState = previous.State
+			journal.PreviousSubState = previous.SubState
+			if _, err := db.client.Database(db.dbName).
+				Collection(model.BTCDerivedChangesCollection).
+				InsertOne(sessCtx, journal); err != nil {
+				return nil, err
+			}
+		}
+		if transition := change.Transition; transition != nil {
+			transition.FromState = previous.State
+			if err := db.SaveDelegationStateTransition(sessCtx, transition); err != nil {
+				return nil, err
+			}
+		}
+		if event := change.OutboxEvent; event != nil {
+			// An event saved already is found before any write, which leaves
+			// the transaction to commit
+			if err := db.saveOutboxEvent(sessCtx, event); err != nil && !IsDuplicateKeyError(err) {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func (db *Database) OverrideBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	currentState types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	_, err := db.setBTCDelegationState(
+		ctx, stakingTxHash, []types.DelegationState{currentState}, newState, newSubState,
+	)
+	return err
+}
+
+func (db *Database) UpdateBTCDelegationSubState(
+	ctx context.Context,
+	stakingTxHash string,
+	state types.DelegationState,
+	newSubState types.DelegationSubState,
+) error {
+	qualifiedSubStates := types.QualifiedPreviousSubStates(state, newSubState)
+	if len(qualifiedSubStates) == 0 {
+		return fmt.Errorf("no sub state of %s can move to %s", state, newSubState)
+	}
+
+	// The delegations without sub state have none stored
+	qualifiedSubStateValues := make([]any, len(qualifiedSubStates))
+	for i, subState := range qualifiedSubStates {
+		if subState == "" {
+			qualifiedSubStateValues[i] = nil
+			continue
+		}
+		qualifiedSubStateValues[i] = subState.String()
+	}
+
+	filter := bson.M{
+		"_id":       stakingTxHash,
+		"state":     state.String(),
+		"sub_state": bson.M{"$in": qualifiedSubStateValues},
+	}
+	update := bson.M{"$set": bson.M{"sub_state": newSubState.String()}}
+
+	result, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found or current sub state is not qualified",
+		}
+	}
+
+	return nil
+}
+
+// setBTCDelegationState sets the state of the delegation if in one of the
+// qualified states
+func (db *Database) setBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) (*model.BTCDelegationSummary, error) {
+	qualifiedStateStrs := make([]string, len(qualifiedStates))
+	for i, state := range qualifiedStates {
 		qualifiedStateStrs[i] = state.String()
 	}
 
@@ -57,7 +218,8 @@ func (db *Database) UpdateBTCDelegationState(
 	}
 
 	updateFields := bson.M{
-		"state": newState.String(),
+		"state":            newState.String(),
+		"state_updated_at": time.Now().Unix(),
 	}
 
 	if newSubState != nil {
@@ -68,21 +230,24 @@ func (db *Database) UpdateBTCDelegationState(
 		"$set": updateFields,
 	}
 
-	res := db.client.Database(db.dbName).
+	// The delegation is returned as it was before the update
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"state": 1, "sub_state": 1})
+	var previous model.BTCDelegationSummary
+	err := db.client.Database(db.dbName).
 		Collection(model.BTCDelegationDetailsCollection).
-		FindOneAndUpdate(ctx, filter, update)
-
-	if res.Err() != nil {
-		if errors.Is(res.Err(), mongo.ErrNoDocuments) {
-			return &NotFoundError{
+		FindOneAndUpdate(ctx, filter, update, opts).
+		Decode(&previous)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
 				Key:     stakingTxHash,
 				Message: "BTC delegation not found or current state is not qualified states",
 			}
 		}
-		return res.Err()
+		return nil, err
 	}
 
-	return nil
+	return &previous, nil
 }
 
 func (db *Database) GetBTCDelegationState(
@@ -105,6 +270,7 @@ func (db *Database) UpdateBTCDelegationDetails(
 	// Only add fields to updateFields if they are not empty
 	if details.State.String() != "" {
 		updateFields["state"] = details.State.String()
+		updateFields["state_updated_at"] = time.Now().Unix()
 	}
 	if details.StartHeight != 0 {
 		updateFields["start_height"] = details.StartHeight
@@ -155,28 +321,104 @@ func (db *Database) SaveBTCDelegationUnbondingCovenantSignature(
 	return err
 }
 
+func (db *Database) SetCovenantSignatureVerified(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+) error {
+	filter := bson.M{
+		"_id": stakingTxHash,
+		"covenant_unbonding_signatures.covenant_btc_pk_hex": covenantBtcPkHex,
+	}
+	update := bson.M{
+		"$set": bson.M{"covenant_unbonding_signatures.$.verified": verified},
+	}
+	result, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation or covenant signature not found",
+		}
+	}
+
+	return nil
+}
+
 func (db *Database) GetBTCDelegationByStakingTxHash(
 	ctx context.Context, stakingTxHash string,
 ) (*model.BTCDelegationDetails, error) {
-	filter := bson.M{"_id": stakingTxHash}
+	var delegationDoc model.BTCDelegationDetails
+	if err := db.findDelegation(
+		ctx, "GetBTCDelegationByStakingTxHash", stakingTxHash, nil, &delegationDoc,
+	); err != nil {
+		return nil, err
+	}
+	return &delegationDoc, nil
+}
 
-	res := db.client.Database(db.dbName).
-		Collection(model.BTCDelegationDetailsCollection).
-		FindOne(ctx, filter)
+func (db *Database) GetBTCDelegationSummaryByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationSummary, error) {
+	var summary model.BTCDelegationSummary
+	if err := db.findDelegation(
+		ctx, "GetBTCDelegationSummaryByStakingTxHash", stakingTxHash, model.BTCDelegationSummaryProjection, &summary,
+	); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
 
-	var delegationDoc model.BTCDelegationDetails
-	err := res.Decode(&delegationDoc)
+// findDelegation decodes the delegation of the staking tx hash, read with
+// the projection unless nil, into doc and counts its size as read by the
+// method
+func (db *Database) findDelegation(
+	ctx context.Context, method string, stakingTxHash string, projection bson.M, doc any,
+) error {
+	opts := options.FindOne()
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
+	raw, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		FindOne(ctx, bson.M{"_id": stakingTxHash}, opts).
+		Raw()
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, &NotFoundError{
+			return &NotFoundError{
 				Key:     stakingTxHash,
 				Message: "BTC delegation not found when getting by staking tx hash",
 			}
 		}
+		return err
+	}
+	metrics.RecordDbReadBytes(method, len(raw))
+	return bson.Unmarshal(raw, doc)
+}
+
+func (db *Database) GetBTCDelegationsByStakingTxHashes(
+	ctx context.Context, stakingTxHashes []string,
+) (map[string]*model.BTCDelegationDetails, error) {
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Find(ctx, bson.M{"_id": bson.M{"$in": stakingTxHashes}})
+	if err != nil {
 		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	return &delegationDoc, nil
+	var delegations []*model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		return nil, err
+	}
+
+	byStakingTxHash := make(map[string]*model.BTCDelegationDetails, len(delegations))
+	for _, delegation := range delegations {
+		byStakingTxHash[delegation.StakingTxHashHex] = delegation
+	}
+	return byStakingTxHash, nil
 }
 
 func (db *Database) UpdateDelegationsStateByFinalityProvider(
@@ -184,13 +426,21 @@ func (db *Database) UpdateDelegationsStateByFinalityProvider(
 	fpBTCPKHex string,
 	newState types.DelegationState,
 ) error {
+	qualifiedStates := types.QualifiedPreviousStates(newState)
+	qualifiedStateStrs := make([]string, len(qualifiedStates))
+	for i, state := range qualifiedStates {
+		qualifiedStateStrs[i] = state.String()
+	}
+
 	filter := bson.M{
 		"finality_provider_btc_pks_hex": fpBTCPKHex,
+		"state":                         bson.M{"$in": qualifiedStateStrs},
 	}
 
 	update := bson.M{
 		"$set": bson.M{
-			"state": newState.String(),
+			"state":            newState.String(),
+			"state_updated_at": time.Now().Unix(),
 		},
 	}
 
@@ -201,11 +451,10 @@ func (db *Database) UpdateDelegationsStateByFinalityProvider(
 		return fmt.Errorf("failed to update delegations: %w", err)
 	}
 
-	log.Printf("Updated %d delegations for finality provider %s to state %s",
-		result.ModifiedCount,
-		fpBTCPKHex,
-		newState.String(),
-	)
+	logging.DB.FromContext(logging.WithFpBtcPk(ctx, fpBTCPKHex)).Debug().
+		Int64("updated", result.ModifiedCount).
+		Str("state", newState.String()).
+		Msg("updated the delegations of the finality provider")
 	return nil
 }
 
@@ -230,10 +479,9 @@ func (db *Database) GetDelegationsByFinalityProvider(
 		return nil, fmt.Errorf("failed to decode delegations: %w", err)
 	}
 
-	log.Printf("Found %d delegations for finality provider %s",
-		len(delegations),
-		fpBTCPKHex,
-	)
+	logging.DB.FromContext(logging.WithFpBtcPk(ctx, fpBTCPKHex)).Debug().
+		Int("found", len(delegations)).
+		Msg("found the delegations of the finality provider")
 	return delegations, nil
 }
 
@@ -324,3 +572,288 @@ func (db *Database) GetBTCDelegationsByStates(
 
 	return delegations, nil
 }
+
+// BTCDelegationsFilter selects BTC delegations, all of them if empty
+type BTCDelegationsFilter struct {
+	// States restricts the delegations to the ones in any of the states
+	States []types.DelegationState
+	// FinalityProviderBtcPks restricts the delegations to the ones delegated
+	// to any of the finality providers
+	FinalityProviderBtcPks []string
+}
+
+func (f BTCDelegationsFilter) query() bson.M {
+	query := bson.M{}
+	if len(f.States) > 0 {
+		states := make([]string, len(f.States))
+		for i, state := range f.States {
+			states[i] = state.String()
+		}
+		query["state"] = bson.M{"$in": states}
+	}
+	if len(f.FinalityProviderBtcPks) > 0 {
+		query["finality_provider_btc_pks_hex"] = bson.M{"$in": f.FinalityProviderBtcPks}
+	}
+	return query
+}
+
+func (db *Database) GetBTCDelegationsAfter(
+	ctx context.Context, filter BTCDelegationsFilter, stakingTxHashHex string, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	query := filter.query()
+	query["_id"] = bson.M{"$gt": stakingTxHashHex}
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(int64(limit))
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []*model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		return nil, err
+	}
+
+	return delegations, nil
+}
+
+func (db *Database) SampleBTCDelegations(
+	ctx context.Context, filter BTCDelegationsFilter, size uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.query()}},
+		{{Key: "$sample", Value: bson.M{"size": size}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []*model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		return nil, err
+	}
+
+	return delegations, nil
+}
+
+func (db *Database) GetBTCDelegationsCreatedBetween(
+	ctx context.Context, fromHeight, toHeight int64,
+) ([]*model.BTCDelegationDetails, error) {
+	filter := bson.M{"btc_delegation_created_bbn_block.height": bson.M{
+		"$gte": fromHeight,
+		"$lte": toHeight,
+	}}
+	opts := options.Find().SetSort(bson.D{
+		{Key: "btc_delegation_created_bbn_block.height", Value: 1},
+		{Key: "_id", Value: 1},
+	})
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []*model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		return nil, err
+	}
+
+	return delegations, nil
+}
+
+// StakerDelegationsFilter selects the delegations of a staker, by either its
+// BTC pk or its BBN address
+type StakerDelegationsFilter struct {
+	StakerBtcPkHex       string
+	StakerBabylonAddress string
+	// State restricts the delegations to the ones in the state, if set
+	State types.DelegationState
+}
+
+// stakerDelegationsPagination is the position of the last delegation of a
+// page, the delegations being sorted by creation height then staking tx hash,
+// newest first
+type stakerDelegationsPagination struct {
+	CreatedBbnHeight int64  `json:"created_bbn_height"`
+	StakingTxHashHex string `json:"staking_tx_hash_hex"`
+}
+
+func (db *Database) GetStakerDelegations(
+	ctx context.Context,
+	filter StakerDelegationsFilter,
+	paginationToken string,
+	limit int64,
+) (*DbResultMap[*model.BTCDelegationDetails], error) {
+	return getStakerDelegations(
+		ctx, db, "GetStakerDelegations", filter, paginationToken, limit, nil,
+		func(delegation *model.BTCDelegationDetails) stakerDelegationsPagination {
+			return stakerDelegationsPagination{
+				CreatedBbnHeight: delegation.BTCDelegationCreatedBlock.Height,
+				StakingTxHashHex: delegation.StakingTxHashHex,
+			}
+		},
+	)
+}
+
+func (db *Database) GetStakerDelegationSummaries(
+	ctx context.Context,
+	filter StakerDelegationsFilter,
+	paginationToken string,
+	limit int64,
+) (*DbResultMap[*model.BTCDelegationSummary], error) {
+	return getStakerDelegations(
+		ctx, db, "GetStakerDelegationSummaries", filter, paginationToken, limit,
+		model.BTCDelegationSummaryProjection,
+		func(summary *model.BTCDelegationSummary) stakerDelegationsPagination {
+			return stakerDelegationsPagination{
+				CreatedBbnHeight: summary.BTCDelegationCreatedBlock.Height,
+				StakingTxHashHex: summary.StakingTxHashHex,
+			}
+		},
+	)
+}
+
+// getStakerDelegations returns a page of the delegations of the staker
+// decoded as T, read with the projection unless nil, the pagination of a
+// delegation telling where the next page starts
+func getStakerDelegations[T any](
+	ctx context.Context,
+	db *Database,
+	method string,
+	filter StakerDelegationsFilter,
+	paginationToken string,
+	limit int64,
+	projection bson.M,
+	paginationOf func(T) stakerDelegationsPagination,
+) (*DbResultMap[T], error) {
+	query := bson.M{}
+	if filter.StakerBtcPkHex != "" {
+		query["staker_btc_pk_hex"] = filter.StakerBtcPkHex
+	}
+	if filter.StakerBabylonAddress != "" {
+		query["staker_babylon_address"] = filter.StakerBabylonAddress
+	}
+	if filter.State != "" {
+		query["state"] = filter.State
+	}
+
+	if paginationToken != "" {
+		var pagination stakerDelegationsPagination
+		if err := decodePaginationToken(paginationToken, &pagination); err != nil {
+			return nil, err
+		}
+		query["$or"] = []bson.M{
+			{"btc_delegation_created_bbn_block.height": bson.M{"$lt": pagination.CreatedBbnHeight}},
+			{
+				"btc_delegation_created_bbn_block.height": pagination.CreatedBbnHeight,
+				"_id": bson.M{"$lt": pagination.StakingTxHashHex},
+			},
+		}
+	}
+
+	// One more delegation than the limit tells whether there is a next page
+	opts := options.Find().SetSort(bson.D{
+		{Key: "btc_delegation_created_bbn_block.height", Value: -1},
+		{Key: "_id", Value: -1},
+	}).SetLimit(limit + 1)
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	delegations, err := decodeAll[T](ctx, cursor, method)
+	if err != nil {
+		return nil, err
+	}
+
+	return toResultMapWithPaginationToken(
+		delegations, limit,
+		func(delegation T) (string, error) {
+			return encodePaginationToken(paginationOf(delegation))
+		},
+	)
+}
+
+// decodeAll decodes the documents of the cursor and counts their size as
+// read by the method
+func decodeAll[T any](ctx context.Context, cursor *mongo.Cursor, method string) ([]T, error) {
+	var results []T
+	readBytes := 0
+	for cursor.Next(ctx) {
+		var result T
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		readBytes += len(cursor.Current)
+		results = append(results, result)
+	}
+	metrics.RecordDbReadBytes(method, readBytes)
+	return results, cursor.Err()
+}
+
+// DeleteBTCDelegation deletes the delegation along with its state history and
+// timelocks, active and archived, in a single transaction. Its event sequence
+// is kept as the legacy sequence of the delegation, so that the events of a
+// delegation created again do not reuse the sequence numbers.
+func (db *Database) DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error {
+	_, err := db.withTransaction(ctx, "DeleteBTCDelegation", func(sessCtx mongo.SessionContext) (interface{}, error) {
+		database := db.client.Database(db.dbName)
+
+		var delegation model.BTCDelegationDetails
+		err := database.Collection(model.BTCDelegationDetailsCollection).
+			FindOneAndDelete(sessCtx, bson.M{"_id": stakingTxHashHex}).
+			Decode(&delegation)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, &NotFoundError{
+					Key:     stakingTxHashHex,
+					Message: "BTC delegation not found when deleting it",
+				}
+			}
+			return nil, err
+		}
+
+		if delegation.EventSequence > 0 {
+			_, err := database.Collection(model.OutboxSequencesCollection).UpdateOne(
+				sessCtx,
+				bson.M{"_id": stakingTxHashHex},
+				bson.M{"$max": bson.M{"sequence": delegation.EventSequence}},
+				options.Update().SetUpsert(true),
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		filter := bson.M{"staking_tx_hash_hex": stakingTxHashHex}
+		for _, collection := range []string{
+			model.DelegationStateHistoryCollection,
+			model.TimeLockCollection,
+			model.TimeLockArchiveCollection,
+		} {
+			if _, err := database.Collection(collection).DeleteMany(sessCtx, filter); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
@@ -0,0 +1,444 @@
+package db
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// delegationMemoName labels the lookups of the delegation memo
+const delegationMemoName = "delegation_memo"
+
+// DelegationMemo holds the delegations read with a context, e.g. during the
+// processing of a BBN block, so that the handlers reading the same delegation
+// read it once. A delegation written with the context is dropped from it.
+// Within a transaction, the delegations are held by a memo of the transaction
+// attempt, kept only once the transaction commits.
+type DelegationMemo struct {
+	mu sync.Mutex
+	// delegations are the delegations read by staking tx hash, nil for the
+	// ones found missing
+	delegations map[string]*model.BTCDelegationDetails
+	// epoch is the count of the delegation writes made out of the memo when
+	// the delegations were read, the memo being dropped once it changes
+	epoch uint64
+	// drops counts the delegations dropped, and forkDrops is the count of
+	// the memo a transaction attempt memo was forked from
+	drops, forkDrops uint64
+	// reads are the delegations read by staking tx hash, and memoized the
+	// ones among them served from the memo
+	reads, memoized uint64
+}
+
+type delegationMemoKey struct{}
+
+// WithDelegationMemo returns a context whose delegation reads are memoized,
+// along with the contexts derived from it, by the DelegationMemoDatabase
+func WithDelegationMemo(ctx context.Context) (context.Context, *DelegationMemo) {
+	memo := &DelegationMemo{delegations: make(map[string]*model.BTCDelegationDetails)}
+	return context.WithValue(ctx, delegationMemoKey{}, memo), memo
+}
+
+func delegationMemoFromContext(ctx context.Context) (*DelegationMemo, bool) {
+	memo, ok := ctx.Value(delegationMemoKey{}).(*DelegationMemo)
+	return memo, ok && !isCacheBypassed(ctx)
+}
+
+// Reads returns the number of delegations read by staking tx hash with the
+// memo, and the number of them served from it
+func (m *DelegationMemo) Reads() (reads, memoized uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reads, m.memoized
+}
+
+// sync drops the delegations read before the delegation writes made out of
+// the memo, as of the given count
+func (m *DelegationMemo) sync(epoch uint64) {
+	if m.epoch != epoch {
+		clear(m.delegations)
+		m.epoch = epoch
+		m.drops++
+	}
+}
+
+func (m *DelegationMemo) drop(stakingTxHashes ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, stakingTxHash := range stakingTxHashes {
+		delete(m.delegations, stakingTxHash)
+	}
+	m.drops++
+}
+
+func (m *DelegationMemo) dropAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.delegations)
+	m.drops++
+}
+
+// fork returns the memo of a transaction attempt, holding the delegations of
+// the memo
+func (m *DelegationMemo) fork() *DelegationMemo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &DelegationMemo{
+		delegations: maps.Clone(m.delegations),
+		epoch:       m.epoch,
+		forkDrops:   m.drops,
+	}
+}
+
+// join counts the reads of the transaction attempt memo, and takes its
+// delegations once the transaction committed. The memo is dropped instead
+// if delegations were dropped from it meanwhile, as the attempt memo may
+// still hold them.
+func (m *DelegationMemo) join(attempt *DelegationMemo, committed bool) {
+	attempt.mu.Lock()
+	defer attempt.mu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads += attempt.reads
+	m.memoized += attempt.memoized
+	if !committed {
+		return
+	}
+	if m.drops != attempt.forkDrops {
+		clear(m.delegations)
+	} else {
+		m.delegations = attempt.delegations
+		m.epoch = attempt.epoch
+	}
+	// the memos forked meanwhile may hold the delegations written in the
+	// transaction as they were before
+	m.drops++
+}
+
+// DelegationMemoDatabase serves the delegations read with a context
+// WithDelegationMemo from its memo, reading them through on a miss. The
+// delegations written with the context are dropped from the memo, and the
+// whole memo is dropped on a delegation write made without it, e.g. by the
+// BTC processing, so that its delegations are never older than the last
+// write.
+type DelegationMemoDatabase struct {
+	DbInterface
+
+	// outOfMemoWrites counts the delegation writes made without a memo
+	outOfMemoWrites atomic.Uint64
+}
+
+func NewDelegationMemoDatabase(dbClient DbInterface) *DelegationMemoDatabase {
+	return &DelegationMemoDatabase{DbInterface: dbClient}
+}
+
+func (d *DelegationMemoDatabase) GetBTCDelegationByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationDetails, error) {
+	memo, ok := delegationMemoFromContext(ctx)
+	if !ok {
+		return d.DbInterface.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	}
+
+	memo.mu.Lock()
+	memo.sync(d.outOfMemoWrites.Load())
+	memo.reads++
+	delegation, found := memo.delegations[stakingTxHash]
+	if found {
+		memo.memoized++
+	}
+	memo.mu.Unlock()
+	metrics.RecordDbCacheLookup(delegationMemoName, found)
+	if found {
+		if delegation == nil {
+			return nil, &NotFoundError{
+				Key:     stakingTxHash,
+				Message: "BTC delegation not found when getting by staking tx hash",
+			}
+		}
+		return cloneDelegation(delegation), nil
+	}
+
+	stored, err := d.DbInterface.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	if err != nil && !IsNotFoundError(err) {
+		return nil, err
+	}
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	if stored == nil {
+		memo.delegations[stakingTxHash] = nil
+		return nil, err
+	}
+	memo.delegations[stakingTxHash] = cloneDelegation(stored)
+	return stored, nil
+}
+
+// GetBTCDelegationsByStakingTxHashes reads the delegations missing from the
+// memo in a single read, filling the memo, so that it can be filled ahead of
+// the reads of the delegations one by one
+func (d *DelegationMemoDatabase) GetBTCDelegationsByStakingTxHashes(
+	ctx context.Context, stakingTxHashes []string,
+) (map[string]*model.BTCDelegationDetails, error) {
+	memo, ok := delegationMemoFromContext(ctx)
+	if !ok {
+		return d.DbInterface.GetBTCDelegationsByStakingTxHashes(ctx, stakingTxHashes)
+	}
+
+	delegations := make(map[string]*model.BTCDelegationDetails, len(stakingTxHashes))
+	var missing []string
+	memo.mu.Lock()
+	memo.sync(d.outOfMemoWrites.Load())
+	for _, stakingTxHash := range stakingTxHashes {
+		delegation, found := memo.delegations[stakingTxHash]
+		if !found {
+			missing = append(missing, stakingTxHash)
+		} else if delegation != nil {
+			delegations[stakingTxHash] = cloneDelegation(delegation)
+		}
+	}
+	memo.mu.Unlock()
+	if len(missing) == 0 {
+		return delegations, nil
+	}
+
+	stored, err := d.DbInterface.GetBTCDelegationsByStakingTxHashes(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	for _, stakingTxHash := range missing {
+		delegation, found := stored[stakingTxHash]
+		if !found {
+			memo.delegations[stakingTxHash] = nil
+			continue
+		}
+		memo.delegations[stakingTxHash] = cloneDelegation(delegation)
+		delegations[stakingTxHash] = delegation
+	}
+	return delegations, nil
+}
+
+// written drops the written delegations from the memo of the context, or
+// the memos of all the contexts if written without one
+func (d *DelegationMemoDatabase) written(ctx context.Context, stakingTxHashes ...string) {
+	memo, ok := delegationMemoFromContext(ctx)
+	if !ok {
+		d.outOfMemoWrites.Add(1)
+		return
+	}
+	memo.drop(stakingTxHashes...)
+}
+
+// writtenAll drops every delegation from the memo of the context, or the
+// memos of all the contexts if written without one, for the writes to an
+// unknown set of delegations
+func (d *DelegationMemoDatabase) writtenAll(ctx context.Context) {
+	memo, ok := delegationMemoFromContext(ctx)
+	if !ok {
+		d.outOfMemoWrites.Add(1)
+		return
+	}
+	memo.dropAll()
+}
+
+// RunInTransaction runs every attempt of the transaction with a memo of its
+// own, forked from the memo of the context, as the delegations read after
+// being written in an attempt are not stored unless it commits. The memo of
+// the last attempt replaces the memo of the context once committed, and is
+// dropped otherwise.
+func (d *DelegationMemoDatabase) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	memo, ok := delegationMemoFromContext(ctx)
+	if !ok {
+		return d.DbInterface.RunInTransaction(ctx, fn)
+	}
+
+	var attempt *DelegationMemo
+	err := d.DbInterface.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if attempt != nil {
+			memo.join(attempt, false)
+		}
+		attempt = memo.fork()
+		return fn(context.WithValue(txCtx, delegationMemoKey{}, attempt))
+	})
+	if attempt != nil {
+		memo.join(attempt, err == nil)
+	}
+	return err
+}
+
+func (d *DelegationMemoDatabase) SaveNewBTCDelegation(
+	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
+) error {
+	err := d.DbInterface.SaveNewBTCDelegation(ctx, delegationDoc)
+	d.written(ctx, delegationDoc.StakingTxHashHex)
+	return err
+}
+
+func (d *DelegationMemoDatabase) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	err := d.DbInterface.UpdateBTCDelegationState(ctx, stakingTxHash, qualifiedPreviousStates, newState, newSubState)
+	d.written(ctx, stakingTxHash)
+	return err
+}
+
+func (d *DelegationMemoDatabase) ApplyBTCDelegationStateChange(
+	ctx context.Context, change DelegationStateChange,
+) error {
+	err := d.DbInterface.ApplyBTCDelegationStateChange(ctx, change)
+	d.written(ctx, change.StakingTxHash)
+	return err
+}
+
+func (d *DelegationMemoDatabase) OverrideBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	currentState types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	err := d.DbInterface.OverrideBTCDelegationState(ctx, stakingTxHash, currentState, newState, newSubState)
+	d.written(ctx, stakingTxHash)
+	return err
+}
+
+func (d *DelegationMemoDatabase) UpdateBTCDelegationSubState(
+	ctx context.Context,
+	stakingTxHash string,
+	state types.DelegationState,
+	newSubState types.DelegationSubState,
+) error {
+	err := d.DbInterface.UpdateBTCDelegationSubState(ctx, stakingTxHash, state, newSubState)
+	d.written(ctx, stakingTxHash)
+	return err
+}
+
+func (d *DelegationMemoDatabase) SaveBTCDelegationUnbondingCovenantSignature(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
+) error {
+	err := d.DbInterface.SaveBTCDelegationUnbondingCovenantSignature(ctx, stakingTxHash, covenantBtcPkHex, signatureHex)
+	d.written(ctx, stakingTxHash)
+	return err
+}
+
+func (d *DelegationMemoDatabase) SetCovenantSignatureVerified(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+) error {
+	err := d.DbInterface.SetCovenantSignatureVerified(ctx, stakingTxHash, covenantBtcPkHex, verified)
+	d.written(ctx, stakingTxHash)
+	return err
+}
+
+func (d *DelegationMemoDatabase) BulkUpdateDelegationStates(
+	ctx context.Context, updates []DelegationStateUpdate,
+) error {
+	err := d.DbInterface.BulkUpdateDelegationStates(ctx, updates)
+	stakingTxHashes := make([]string, len(updates))
+	for i, update := range updates {
+		stakingTxHashes[i] = update.StakingTxHash
+	}
+	d.written(ctx, stakingTxHashes...)
+	return err
+}
+
+func (d *DelegationMemoDatabase) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []CovenantSigRecord,
+) error {
+	err := d.DbInterface.BulkSaveCovenantSignatures(ctx, sigs)
+	stakingTxHashes := make([]string, len(sigs))
+	for i, sig := range sigs {
+		stakingTxHashes[i] = sig.StakingTxHash
+	}
+	d.written(ctx, stakingTxHashes...)
+	return err
+}
+
+func (d *DelegationMemoDatabase) UpdateBTCDelegationDetails(
+	ctx context.Context, stakingTxHash string, details *model.BTCDelegationDetails,
+) error {
+	err := d.DbInterface.UpdateBTCDelegationDetails(ctx, stakingTxHash, details)
+	d.written(ctx, stakingTxHash)
+	return err
+}
+
+func (d *DelegationMemoDatabase) UpdateDelegationsStateByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, newState types.DelegationState,
+) error {
+	err := d.DbInterface.UpdateDelegationsStateByFinalityProvider(ctx, fpBtcPkHex, newState)
+	d.writtenAll(ctx)
+	return err
+}
+
+func (d *DelegationMemoDatabase) SaveBTCDelegationSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, slashingTxHex string, spendingHeight uint32,
+) error {
+	err := d.DbInterface.SaveBTCDelegationSlashingTxHex(ctx, stakingTxHashHex, slashingTxHex, spendingHeight)
+	d.written(ctx, stakingTxHashHex)
+	return err
+}
+
+func (d *DelegationMemoDatabase) SaveBTCDelegationUnbondingSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, unbondingSlashingTxHex string, spendingHeight uint32,
+) error {
+	err := d.DbInterface.SaveBTCDelegationUnbondingSlashingTxHex(
+		ctx, stakingTxHashHex, unbondingSlashingTxHex, spendingHeight,
+	)
+	d.written(ctx, stakingTxHashHex)
+	return err
+}
+
+func (d *DelegationMemoDatabase) DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error {
+	err := d.DbInterface.DeleteBTCDelegation(ctx, stakingTxHashHex)
+	d.written(ctx, stakingTxHashHex)
+	return err
+}
+
+// SaveOutboxEvent increments the event sequence of the delegation of the
+// event
+func (d *DelegationMemoDatabase) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	err := d.DbInterface.SaveOutboxEvent(ctx, event)
+	d.written(ctx, event.StakingTxHashHex)
+	return err
+}
+
+func (d *DelegationMemoDatabase) RollbackBTCDerivedChanges(
+	ctx context.Context, forkHeight uint64,
+) ([]*model.BTCDerivedChange, error) {
+	changes, err := d.DbInterface.RollbackBTCDerivedChanges(ctx, forkHeight)
+	d.writtenAll(ctx)
+	return changes, err
+}
+
+func (d *DelegationMemoDatabase) ArchivePrunableBTCDelegations(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	archived, err := d.DbInterface.ArchivePrunableBTCDelegations(ctx, before, limit)
+	d.writtenAll(ctx)
+	return archived, err
+}
+
+// cloneDelegation copies the delegation along with its slices, so that the
+// callers modifying theirs do not modify the memoized one
+func cloneDelegation(delegation *model.BTCDelegationDetails) *model.BTCDelegationDetails {
+	clone := *delegation
+	clone.FinalityProviderBtcPksHex = slices.Clone(delegation.FinalityProviderBtcPksHex)
+	clone.CovenantUnbondingSignatures = slices.Clone(delegation.CovenantUnbondingSignatures)
+	for i, sig := range clone.CovenantUnbondingSignatures {
+		if sig.Verified != nil {
+			verified := *sig.Verified
+			clone.CovenantUnbondingSignatures[i].Verified = &verified
+		}
+	}
+	return &clone
+}
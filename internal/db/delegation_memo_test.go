@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// delegationDatabase stores the delegations in memory and counts the reads
+type delegationDatabase struct {
+	DbInterface
+	delegations map[string]model.BTCDelegationDetails
+	reads       int
+}
+
+func (d *delegationDatabase) GetBTCDelegationByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationDetails, error) {
+	d.reads++
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok {
+		return nil, &NotFoundError{Key: stakingTxHash, Message: "BTC delegation not found"}
+	}
+	return &delegation, nil
+}
+
+func (d *delegationDatabase) GetBTCDelegationsByStakingTxHashes(
+	ctx context.Context, stakingTxHashes []string,
+) (map[string]*model.BTCDelegationDetails, error) {
+	d.reads++
+	delegations := make(map[string]*model.BTCDelegationDetails)
+	for _, stakingTxHash := range stakingTxHashes {
+		if delegation, ok := d.delegations[stakingTxHash]; ok {
+			delegations[stakingTxHash] = &delegation
+		}
+	}
+	return delegations, nil
+}
+
+func (d *delegationDatabase) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	delegation := d.delegations[stakingTxHash]
+	delegation.State = newState
+	d.delegations[stakingTxHash] = delegation
+	return nil
+}
+
+// transactionalDatabase rolls back the delegations written in a failed
+// transaction, running it again once on a transient error as the driver does
+type transactionalDatabase struct {
+	*delegationDatabase
+}
+
+// errTransient fails a transaction attempt, run again
+var errTransient = errors.New("transient transaction error")
+
+func (d transactionalDatabase) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	for attempt := 0; ; attempt++ {
+		snapshot := maps.Clone(d.delegations)
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		d.delegations = snapshot
+		if !errors.Is(err, errTransient) || attempt > 0 {
+			return err
+		}
+	}
+}
+
+func TestDelegationMemoDatabase(t *testing.T) {
+	metrics.Init()
+	stored := &delegationDatabase{delegations: map[string]model.BTCDelegationDetails{
+		"a": {StakingTxHashHex: "a", State: types.StatePending, FinalityProviderBtcPksHex: []string{"fp"}},
+		"b": {StakingTxHashHex: "b", State: types.StateActive},
+	}}
+	memoDb := NewDelegationMemoDatabase(stored)
+	ctx, memo := WithDelegationMemo(context.Background())
+
+	// the delegations are read at once, the missing ones included
+	delegations, err := memoDb.GetBTCDelegationsByStakingTxHashes(ctx, []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	require.Len(t, delegations, 2)
+	delegation, err := memoDb.GetBTCDelegationByStakingTxHash(ctx, "a")
+	require.NoError(t, err)
+	// modifying the delegation read does not modify the memoized one
+	delegation.FinalityProviderBtcPksHex[0] = "modified"
+	delegation, err = memoDb.GetBTCDelegationByStakingTxHash(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"fp"}, delegation.FinalityProviderBtcPksHex)
+	_, err = memoDb.GetBTCDelegationByStakingTxHash(ctx, "missing")
+	require.True(t, IsNotFoundError(err))
+	require.Equal(t, 1, stored.reads)
+
+	// a delegation written with the memo is read again
+	require.NoError(t, memoDb.UpdateBTCDelegationState(
+		ctx, "a", []types.DelegationState{types.StatePending}, types.StateActive, nil,
+	))
+	delegation, err = memoDb.GetBTCDelegationByStakingTxHash(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, delegation.State)
+	require.Equal(t, 2, stored.reads)
+	_, err = memoDb.GetBTCDelegationByStakingTxHash(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, 2, stored.reads)
+
+	// a delegation written without the memo drops the memo
+	require.NoError(t, memoDb.UpdateBTCDelegationState(
+		context.Background(), "b", []types.DelegationState{types.StateActive}, types.StateUnbonding, nil,
+	))
+	delegation, err = memoDb.GetBTCDelegationByStakingTxHash(ctx, "b")
+	require.NoError(t, err)
+	require.Equal(t, types.StateUnbonding, delegation.State)
+	require.Equal(t, 3, stored.reads)
+
+	reads, memoized := memo.Reads()
+	require.Equal(t, uint64(6), reads)
+	require.Equal(t, uint64(4), memoized)
+
+	// the reads without a memo always read the stored delegation
+	_, err = memoDb.GetBTCDelegationByStakingTxHash(context.Background(), "b")
+	require.NoError(t, err)
+	require.Equal(t, 4, stored.reads)
+}
+
+func TestDelegationMemoDatabaseTransaction(t *testing.T) {
+	metrics.Init()
+	stored := &delegationDatabase{delegations: map[string]model.BTCDelegationDetails{
+		"a": {StakingTxHashHex: "a", State: types.StatePending},
+	}}
+	memoDb := NewDelegationMemoDatabase(transactionalDatabase{stored})
+	ctx, _ := WithDelegationMemo(context.Background())
+
+	activate := func(txCtx context.Context) {
+		require.NoError(t, memoDb.UpdateBTCDelegationState(
+			txCtx, "a", []types.DelegationState{types.StatePending}, types.StateActive, nil,
+		))
+		delegation, err := memoDb.GetBTCDelegationByStakingTxHash(txCtx, "a")
+		require.NoError(t, err)
+		require.Equal(t, types.StateActive, delegation.State)
+	}
+
+	// the delegation read after being written in an aborted transaction is
+	// not served once rolled back
+	err := memoDb.RunInTransaction(ctx, func(txCtx context.Context) error {
+		activate(txCtx)
+		return errors.New("aborted")
+	})
+	require.EqualError(t, err, "aborted")
+	delegation, err := memoDb.GetBTCDelegationByStakingTxHash(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, types.StatePending, delegation.State)
+
+	// nor is it served to the attempt running the transaction again
+	attempts := 0
+	require.NoError(t, memoDb.RunInTransaction(ctx, func(txCtx context.Context) error {
+		attempts++
+		delegation, err := memoDb.GetBTCDelegationByStakingTxHash(txCtx, "a")
+		require.NoError(t, err)
+		require.Equal(t, types.StatePending, delegation.State)
+		activate(txCtx)
+		if attempts == 1 {
+			return errTransient
+		}
+		return nil
+	}))
+	require.Equal(t, 2, attempts)
+
+	// the delegation read in a committed transaction is served after it
+	reads := stored.reads
+	delegation, err = memoDb.GetBTCDelegationByStakingTxHash(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, delegation.State)
+	require.Equal(t, reads, stored.reads)
+}
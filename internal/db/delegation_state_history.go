@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Database) SaveDelegationStateTransition(
+	ctx context.Context, transition *model.DelegationStateTransition,
+) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.DelegationStateHistoryCollection).
+		InsertOne(ctx, transition)
+	return err
+}
+
+func (db *Database) GetDelegationStateTransitions(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.DelegationStateTransition, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.DelegationStateHistoryCollection).
+		Find(ctx, bson.M{"staking_tx_hash_hex": stakingTxHashHex}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var transitions []*model.DelegationStateTransition
+	if err := cursor.All(ctx, &transitions); err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
+func (db *Database) AggregateStateTransitions(
+	ctx context.Context,
+	fromTime, toTime int64,
+	periodUnit string,
+	visit func(stats *model.StateTransitionPeriodStats) error,
+) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"created_at": bson.M{"$gte": fromTime, "$lt": toTime},
+		}}},
+		// the staked amount is only held by the delegation document
+		{{Key: "$lookup", Value: bson.M{
+			"from":         model.BTCDelegationDetailsCollection,
+			"localField":   "staking_tx_hash_hex",
+			"foreignField": "_id",
+			"as":           "delegation",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"period": bson.M{"$dateTrunc": bson.M{
+					"date":        bson.M{"$toDate": bson.M{"$multiply": bson.A{"$created_at", 1000}}},
+					"unit":        periodUnit,
+					"startOfWeek": "monday",
+				}},
+				"to_state": "$to_state",
+			},
+			"transition_count": bson.M{"$sum": 1},
+			"total_sat": bson.M{"$sum": bson.M{
+				"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$delegation.staking_amount", 0}}, 0},
+			}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":              0,
+			"period_start":     bson.M{"$toLong": bson.M{"$divide": bson.A{bson.M{"$toLong": "$_id.period"}, 1000}}},
+			"to_state":         "$_id.to_state",
+			"transition_count": 1,
+			"total_sat":        1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "period_start", Value: 1}, {Key: "to_state", Value: 1}}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.DelegationStateHistoryCollection).
+		Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var stats model.StateTransitionPeriodStats
+		if err := cursor.Decode(&stats); err != nil {
+			return err
+		}
+		if err := visit(&stats); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// TestDelegationSummaryReads checks against the Mongo of
+// INTEGRATION_MONGO_ADDRESS that the summaries of the delegations match
+// the delegations, read without their transactions
+func TestDelegationSummaryReads(t *testing.T) {
+	database := mongoTestDatabase(t, "INTEGRATION", "indexer-integration")
+	metrics.Init()
+	ctx := context.Background()
+
+	verified := true
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: strings.Repeat("ab", 32),
+		StakingTxHex:     strings.Repeat("00", 2048),
+		StakingAmount:    100000,
+		StakerBtcPkHex:   "staker",
+		State:            types.StateActive,
+		UnbondingTx:      strings.Repeat("00", 2048),
+		CovenantUnbondingSignatures: []model.CovenantSignature{
+			{CovenantBtcPkHex: "covenant-1", SignatureHex: "sig-1", Verified: &verified},
+			{CovenantBtcPkHex: "covenant-2", SignatureHex: "sig-2"},
+		},
+		FinalityProviderBtcPksHex: []string{"fp"},
+		BTCDelegationCreatedBlock: model.BTCDelegationCreatedBbnBlock{Height: 10, Timestamp: 1000},
+	}
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+
+	full, err := database.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+	require.NoError(t, err)
+	summary, err := database.GetBTCDelegationSummaryByStakingTxHash(ctx, delegation.StakingTxHashHex)
+	require.NoError(t, err)
+	require.Equal(t, full.Summary(), summary)
+
+	page, err := database.GetStakerDelegationSummaries(ctx, StakerDelegationsFilter{StakerBtcPkHex: "staker"}, "", 10)
+	require.NoError(t, err)
+	require.Equal(t, []*model.BTCDelegationSummary{summary}, page.Data)
+
+	_, err = database.GetBTCDelegationSummaryByStakingTxHash(ctx, strings.Repeat("cd", 32))
+	require.True(t, IsNotFoundError(err))
+
+	// the summary reads skip the transactions
+	fullBytes := scrapeReadBytes(t, "GetBTCDelegationByStakingTxHash")
+	summaryBytes := scrapeReadBytes(t, "GetBTCDelegationSummaryByStakingTxHash")
+	require.Less(t, summaryBytes*10, fullBytes)
+}
+
+// scrapeReadBytes returns the bytes read by the method, as exported
+func scrapeReadBytes(t *testing.T, method string) float64 {
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	prefix := fmt.Sprintf(`indexer_db_read_bytes_total{method=%q} `, method)
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if value, ok := strings.CutPrefix(line, prefix); ok {
+			bytes, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return bytes
+		}
+	}
+	t.Fatalf("no bytes read by %s", method)
+	return 0
+}
@@ -0,0 +1,503 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DryRunDatabase logs the writes to the database instead of applying them,
+// the reads passing through to the wrapped database. The logged writes of two
+// runs over the same BBN heights can be diffed to validate a change of the
+// event processing.
+// The last processed BBN height is kept in memory, so that the block
+// processor moves on without advancing the stored one.
+type DryRunDatabase struct {
+	DbInterface
+
+	mu            sync.Mutex
+	lastProcessed *model.LastProcessedHeight
+}
+
+func NewDryRunDatabase(dbClient DbInterface) *DryRunDatabase {
+	return &DryRunDatabase{DbInterface: dbClient}
+}
+
+// RunInTransaction runs fn right away, as none of its writes is applied
+func (d *DryRunDatabase) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// record logs the write the method would have applied, the filter selecting
+// the documents written and the update applied to them
+func (d *DryRunDatabase) record(method string, filter, update interface{}) {
+	logging.DB.Logger().Info().
+		Str("method", method).
+		Interface("filter", filter).
+		Interface("update", update).
+		Msg("dry run: database write skipped")
+}
+
+func (d *DryRunDatabase) GetLastProcessedBbnBlock(ctx context.Context) (*model.LastProcessedHeight, error) {
+	d.mu.Lock()
+	lastProcessed := d.lastProcessed
+	d.mu.Unlock()
+	if lastProcessed != nil {
+		copied := *lastProcessed
+		return &copied, nil
+	}
+	return d.DbInterface.GetLastProcessedBbnBlock(ctx)
+}
+
+func (d *DryRunDatabase) GetLastProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	lastProcessed, err := d.GetLastProcessedBbnBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return lastProcessed.Height, nil
+}
+
+func (d *DryRunDatabase) UpdateLastProcessedBbnHeight(
+	ctx context.Context, height uint64, blockHash string,
+) error {
+	d.record("UpdateLastProcessedBbnHeight", bson.M{}, bson.M{"height": height, "block_hash": blockHash})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastProcessed = &model.LastProcessedHeight{Height: height, BlockHash: blockHash}
+	return nil
+}
+
+func (d *DryRunDatabase) ResyncLastProcessedBbnHeight(ctx context.Context, height uint64) error {
+	d.record("ResyncLastProcessedBbnHeight", bson.M{}, bson.M{"height": height})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastProcessed = &model.LastProcessedHeight{Height: height}
+	return nil
+}
+
+func (d *DryRunDatabase) HaltBbnProcessing(ctx context.Context, reason string) error {
+	d.record("HaltBbnProcessing", bson.M{}, bson.M{"halt_reason": reason})
+	return nil
+}
+
+func (d *DryRunDatabase) StartBbnBlockProcessing(
+	ctx context.Context, marker *model.BbnProcessingMarker,
+) error {
+	d.record("StartBbnBlockProcessing", bson.M{}, bson.M{"processing_marker": marker})
+	return nil
+}
+
+func (d *DryRunDatabase) MarkBbnEventProcessed(
+	ctx context.Context, height uint64, eventIndex int,
+) error {
+	d.record(
+		"MarkBbnEventProcessed",
+		bson.M{"processing_marker.height": height},
+		bson.M{"processing_marker.processed_events": eventIndex},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) MarkBbnHeightProcessed(ctx context.Context, height uint64) error {
+	d.record("MarkBbnHeightProcessed", bson.M{"_id": height}, nil)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveNewFinalityProvider(
+	ctx context.Context, fpDoc *model.FinalityProviderDetails,
+) error {
+	d.record("SaveNewFinalityProvider", bson.M{"_id": fpDoc.BtcPk}, fpDoc)
+	return nil
+}
+
+func (d *DryRunDatabase) UpdateFinalityProviderState(
+	ctx context.Context, btcPk string, newState string,
+) error {
+	d.record("UpdateFinalityProviderState", bson.M{"_id": btcPk}, bson.M{"state": newState})
+	return nil
+}
+
+func (d *DryRunDatabase) UpdateFinalityProviderDetailsFromEvent(
+	ctx context.Context, detailsToUpdate *model.FinalityProviderDetails,
+) error {
+	d.record("UpdateFinalityProviderDetailsFromEvent", bson.M{"_id": detailsToUpdate.BtcPk}, detailsToUpdate)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveFinalityProviderVotingPowerChange(
+	ctx context.Context, change *model.FinalityProviderVotingPowerChange,
+) error {
+	d.record("SaveFinalityProviderVotingPowerChange", nil, change)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	d.record("SaveStakingParams", bson.M{"type": STAKING_PARAMS_TYPE, "version": version}, params)
+	return nil
+}
+
+func (d *DryRunDatabase) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	d.record("ReplaceStakingParams", bson.M{"type": STAKING_PARAMS_TYPE, "version": version}, params)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveCheckpointParams(
+	ctx context.Context, params *bbnclient.CheckpointParams,
+) error {
+	d.record("SaveCheckpointParams", bson.M{"type": CHECKPOINT_PARAMS_TYPE}, params)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveNewBTCDelegation(
+	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
+) error {
+	d.record("SaveNewBTCDelegation", bson.M{"_id": delegationDoc.StakingTxHashHex}, delegationDoc)
+	return nil
+}
+
+func (d *DryRunDatabase) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	update := bson.M{"state": newState}
+	if newSubState != nil {
+		update["sub_state"] = *newSubState
+	}
+	d.record(
+		"UpdateBTCDelegationState",
+		bson.M{"_id": stakingTxHash, "state": bson.M{"$in": qualifiedPreviousStates}},
+		update,
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) ApplyBTCDelegationStateChange(
+	ctx context.Context, change DelegationStateChange,
+) error {
+	update := bson.M{"state": change.NewState}
+	if change.NewSubState != nil {
+		update["sub_state"] = *change.NewSubState
+	}
+	d.record(
+		"ApplyBTCDelegationStateChange",
+		bson.M{"_id": change.StakingTxHash, "state": bson.M{"$in": change.QualifiedPreviousStates}},
+		update,
+	)
+	if change.BTCDerivedChange != nil {
+		d.record("ApplyBTCDelegationStateChange", nil, change.BTCDerivedChange)
+	}
+	if change.Transition != nil {
+		d.record("ApplyBTCDelegationStateChange", nil, change.Transition)
+	}
+	if change.OutboxEvent != nil {
+		d.record("ApplyBTCDelegationStateChange", bson.M{"_id": change.OutboxEvent.Id}, change.OutboxEvent)
+	}
+	return nil
+}
+
+func (d *DryRunDatabase) OverrideBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	currentState types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	update := bson.M{"state": newState}
+	if newSubState != nil {
+		update["sub_state"] = *newSubState
+	}
+	d.record("OverrideBTCDelegationState", bson.M{"_id": stakingTxHash, "state": currentState}, update)
+	return nil
+}
+
+func (d *DryRunDatabase) UpdateBTCDelegationSubState(
+	ctx context.Context,
+	stakingTxHash string,
+	state types.DelegationState,
+	newSubState types.DelegationSubState,
+) error {
+	d.record(
+		"UpdateBTCDelegationSubState",
+		bson.M{"_id": stakingTxHash, "state": state},
+		bson.M{"sub_state": newSubState},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveBTCDelegationUnbondingCovenantSignature(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
+) error {
+	d.record(
+		"SaveBTCDelegationUnbondingCovenantSignature",
+		bson.M{"_id": stakingTxHash},
+		bson.M{"covenant_btc_pk_hex": covenantBtcPkHex, "signature_hex": signatureHex},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) SetCovenantSignatureVerified(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+) error {
+	d.record(
+		"SetCovenantSignatureVerified",
+		bson.M{"_id": stakingTxHash, "covenant_unbonding_signatures.covenant_btc_pk_hex": covenantBtcPkHex},
+		bson.M{"covenant_unbonding_signatures.$.verified": verified},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) BulkUpdateDelegationStates(
+	ctx context.Context, updates []DelegationStateUpdate,
+) error {
+	for _, update := range updates {
+		d.record(
+			"BulkUpdateDelegationStates",
+			bson.M{"_id": update.StakingTxHash, "state": update.QualifiedPreviousStates},
+			bson.M{"state": update.NewState, "sub_state": update.NewSubState},
+		)
+	}
+	return nil
+}
+
+func (d *DryRunDatabase) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []CovenantSigRecord,
+) error {
+	for _, sig := range sigs {
+		d.record(
+			"BulkSaveCovenantSignatures",
+			bson.M{"_id": sig.StakingTxHash},
+			bson.M{"covenant_btc_pk_hex": sig.CovenantBtcPkHex, "signature_hex": sig.SignatureHex},
+		)
+	}
+	return nil
+}
+
+func (d *DryRunDatabase) UpdateBTCDelegationDetails(
+	ctx context.Context, stakingTxHash string, details *model.BTCDelegationDetails,
+) error {
+	d.record("UpdateBTCDelegationDetails", bson.M{"_id": stakingTxHash}, details)
+	return nil
+}
+
+func (d *DryRunDatabase) UpdateDelegationsStateByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, newState types.DelegationState,
+) error {
+	d.record(
+		"UpdateDelegationsStateByFinalityProvider",
+		bson.M{
+			"finality_provider_btc_pks_hex": fpBtcPkHex,
+			"state":                         bson.M{"$in": types.QualifiedPreviousStates(newState)},
+		},
+		bson.M{"state": newState},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveBTCDelegationSlashingTxHex(
+	ctx context.Context, stakingTxHash string, slashingTxHex string, spendingHeight uint32,
+) error {
+	d.record(
+		"SaveBTCDelegationSlashingTxHex",
+		bson.M{"_id": stakingTxHash},
+		bson.M{"slashing_tx_hex": slashingTxHex, "spending_height": spendingHeight},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveBTCDelegationUnbondingSlashingTxHex(
+	ctx context.Context, stakingTxHash string, unbondingSlashingTxHex string, spendingHeight uint32,
+) error {
+	d.record(
+		"SaveBTCDelegationUnbondingSlashingTxHex",
+		bson.M{"_id": stakingTxHash},
+		bson.M{"unbonding_slashing_tx_hex": unbondingSlashingTxHex, "spending_height": spendingHeight},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error {
+	d.record("DeleteBTCDelegation", bson.M{"_id": stakingTxHashHex}, nil)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveDelegationStateTransition(
+	ctx context.Context, transition *model.DelegationStateTransition,
+) error {
+	d.record("SaveDelegationStateTransition", nil, transition)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveNewTimeLockExpire(
+	ctx context.Context, stakingTxHashHex string, expireHeight uint32, subState types.DelegationSubState,
+) error {
+	d.record("SaveNewTimeLockExpire", nil, bson.M{
+		"staking_tx_hash_hex":  stakingTxHashHex,
+		"expire_height":        expireHeight,
+		"delegation_sub_state": subState,
+	})
+	return nil
+}
+
+func (d *DryRunDatabase) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
+	d.record("DeleteExpiredDelegation", bson.M{"staking_tx_hash_hex": stakingTxHashHex}, nil)
+	return nil
+}
+
+func (d *DryRunDatabase) DeleteTimeLocks(ctx context.Context, ids []primitive.ObjectID) (uint64, error) {
+	d.record("DeleteTimeLocks", bson.M{"_id": bson.M{"$in": ids}}, nil)
+	return uint64(len(ids)), nil
+}
+
+func (d *DryRunDatabase) UpdateTimeLockExpireHeight(
+	ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32,
+) error {
+	d.record(
+		"UpdateTimeLockExpireHeight",
+		bson.M{
+			"staking_tx_hash_hex":  timeLock.StakingTxHashHex,
+			"delegation_sub_state": timeLock.DelegationSubState,
+			"expire_height":        timeLock.ExpireHeight,
+		},
+		bson.M{"expire_height": newExpireHeight},
+	)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveBTCHeader(ctx context.Context, header *model.BTCHeader) error {
+	d.record("SaveBTCHeader", nil, header)
+	return nil
+}
+
+func (d *DryRunDatabase) DeleteBTCHeadersAbove(ctx context.Context, height uint64) error {
+	d.record("DeleteBTCHeadersAbove", bson.M{"_id": bson.M{"$gt": height}}, nil)
+	return nil
+}
+
+func (d *DryRunDatabase) DeleteBTCHeadersBelow(ctx context.Context, height uint64) error {
+	d.record("DeleteBTCHeadersBelow", bson.M{"_id": bson.M{"$lt": height}}, nil)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveBTCDerivedChange(ctx context.Context, change *model.BTCDerivedChange) error {
+	d.record("SaveBTCDerivedChange", nil, change)
+	return nil
+}
+
+func (d *DryRunDatabase) RollbackBTCDerivedChanges(
+	ctx context.Context, forkHeight uint64,
+) ([]*model.BTCDerivedChange, error) {
+	d.record("RollbackBTCDerivedChanges", bson.M{"btc_height": bson.M{"$gte": forkHeight}}, nil)
+	return nil, nil
+}
+
+func (d *DryRunDatabase) DeleteBTCDerivedChangesBelow(ctx context.Context, height uint64) error {
+	d.record("DeleteBTCDerivedChangesBelow", bson.M{"btc_height": bson.M{"$lt": height}}, nil)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveReconciliationRun(ctx context.Context, run *model.ReconciliationRun) error {
+	d.record("SaveReconciliationRun", nil, run)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveReconciliationDiscrepancy(
+	ctx context.Context, discrepancy *model.ReconciliationDiscrepancy,
+) error {
+	d.record("SaveReconciliationDiscrepancy", nil, discrepancy)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveStuckDelegationReport(
+	ctx context.Context, report *model.StuckDelegationReport,
+) error {
+	d.record("SaveStuckDelegationReport", nil, report)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveRawEventCapture(ctx context.Context, capture *model.RawEventCapture) error {
+	d.record("SaveRawEventCapture", nil, capture)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveBbnEventDeadLetter(
+	ctx context.Context, deadLetter *model.BbnEventDeadLetter,
+) error {
+	d.record("SaveBbnEventDeadLetter", bson.M{"_id": deadLetter.Id}, deadLetter)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	d.record("SaveOutboxEvent", bson.M{"_id": event.Id}, event)
+	return nil
+}
+
+func (d *DryRunDatabase) MarkOutboxEventSent(ctx context.Context, id string, sentAt int64) error {
+	d.record("MarkOutboxEventSent", bson.M{"_id": id}, bson.M{"sent_at": sentAt})
+	return nil
+}
+
+func (d *DryRunDatabase) MarkOutboxEventFailed(
+	ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool,
+) error {
+	d.record("MarkOutboxEventFailed", bson.M{"_id": id}, bson.M{
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+		"poison":          poison,
+	})
+	return nil
+}
+
+func (d *DryRunDatabase) RequeuePoisonOutboxEvents(ctx context.Context) (uint64, error) {
+	d.record("RequeuePoisonOutboxEvents", bson.M{"poison": true}, bson.M{"poison": false})
+	return 0, nil
+}
+
+func (d *DryRunDatabase) SaveGlobalStats(ctx context.Context, stats *model.GlobalStats) error {
+	d.record("SaveGlobalStats", nil, stats)
+	return nil
+}
+
+func (d *DryRunDatabase) SaveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) error {
+	d.record("SaveBootstrap", nil, bootstrap)
+	return nil
+}
+
+func (d *DryRunDatabase) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) error {
+	d.record("AcquireLock", bson.M{"_id": name}, bson.M{"owner": owner, "ttl": ttl.String()})
+	return nil
+}
+
+func (d *DryRunDatabase) ReleaseLock(ctx context.Context, name, owner string) error {
+	d.record("ReleaseLock", bson.M{"_id": name, "owner": owner}, nil)
+	return nil
+}
+
+// ArchivePrunableBTCDelegations and DeletePrunableArchivedTimeLocks report no
+// document pruned, as a prune runs until none is left
+func (d *DryRunDatabase) ArchivePrunableBTCDelegations(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	d.record("ArchivePrunableBTCDelegations", bson.M{"state_updated_at": bson.M{"$lt": before}}, nil)
+	return 0, nil
+}
+
+func (d *DryRunDatabase) DeletePrunableArchivedTimeLocks(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	d.record("DeletePrunableArchivedTimeLocks", bson.M{"archived_at": bson.M{"$lt": before}}, nil)
+	return 0, nil
+}
@@ -0,0 +1,44 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunDatabase(t *testing.T) {
+	ctx := context.Background()
+	// the mock fails on any write reaching it
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetLastProcessedBbnBlock", ctx).Return(&model.LastProcessedHeight{Height: 10}, nil).Once()
+	dbMock.On("GetBTCDelegationByStakingTxHash", ctx, "tx").Return(&model.BTCDelegationDetails{
+		StakingTxHashHex: "tx",
+		State:            types.StateActive,
+	}, nil).Once()
+
+	dryRun := db.NewDryRunDatabase(dbMock)
+	require.NoError(t, dryRun.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{StakingTxHashHex: "tx"}))
+	require.NoError(t, dryRun.UpdateBTCDelegationState(
+		ctx, "tx", []types.DelegationState{types.StateActive}, types.StateUnbonding, nil,
+	))
+	require.NoError(t, dryRun.SaveOutboxEvent(ctx, &model.OutboxEvent{Id: "event"}))
+
+	// the reads pass through
+	delegation, err := dryRun.GetBTCDelegationByStakingTxHash(ctx, "tx")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, delegation.State)
+
+	// the last processed height is only advanced in memory
+	height, err := dryRun.GetLastProcessedBbnHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), height)
+	require.NoError(t, dryRun.UpdateLastProcessedBbnHeight(ctx, 11, "hash"))
+	lastProcessed, err := dryRun.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &model.LastProcessedHeight{Height: 11, BlockHash: "hash"}, lastProcessed)
+}
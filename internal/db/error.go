@@ -1,10 +1,30 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// Classes of the database errors, as counted by MetricsDatabase
+const (
+	ErrorClassDuplicateKey     = "duplicate_key"
+	ErrorClassNotFound         = "not_found"
+	ErrorClassStateTransition  = "state_transition"
+	ErrorClassTransientNetwork = "transient_network"
+	ErrorClassTimeout          = "timeout"
+	ErrorClassOther            = "other"
 )
 
+// transientTransactionErrorLabel labels the errors on which the driver retries
+// a transaction
+const transientTransactionErrorLabel = "TransientTransactionError"
+
 // DuplicateKeyError is an error type for duplicate key errors
 type DuplicateKeyError struct {
 	Key     string
@@ -20,6 +40,11 @@ func (e *DuplicateKeyError) Is(target error) bool {
 	return ok
 }
 
+// Retryable tells that writing the same document again is of no use
+func (e *DuplicateKeyError) Retryable() bool {
+	return false
+}
+
 func IsDuplicateKeyError(err error) bool {
 	return errors.Is(err, &DuplicateKeyError{})
 }
@@ -33,6 +58,11 @@ func (e *InvalidPaginationTokenError) Error() string {
 	return e.Message
 }
 
+func (e *InvalidPaginationTokenError) Is(target error) bool {
+	_, ok := target.(*InvalidPaginationTokenError)
+	return ok
+}
+
 func IsInvalidPaginationTokenError(err error) bool {
 	return errors.Is(err, &InvalidPaginationTokenError{})
 }
@@ -47,6 +77,145 @@ func (e *NotFoundError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Message, e.Key)
 }
 
+func (e *NotFoundError) Is(target error) bool {
+	_, ok := target.(*NotFoundError)
+	return ok
+}
+
 func IsNotFoundError(err error) bool {
 	return errors.Is(err, &NotFoundError{})
 }
+
+// LockHeldError is returned when acquiring a lock held by another owner
+type LockHeldError struct {
+	Name  string
+	Owner string
+}
+
+func (e *LockHeldError) Error() string {
+	return fmt.Sprintf("lock %s is held by %s", e.Name, e.Owner)
+}
+
+func (e *LockHeldError) Is(target error) bool {
+	_, ok := target.(*LockHeldError)
+	return ok
+}
+
+func IsLockHeldError(err error) bool {
+	return errors.Is(err, &LockHeldError{})
+}
+
+// InvalidStateTransitionError is returned on a delegation state update the
+// delegation state machine does not allow, from any of the qualified previous
+// states or from the current state of the delegation
+type InvalidStateTransitionError struct {
+	Key  string
+	From []types.DelegationState
+	To   types.DelegationState
+}
+
+func (e *InvalidStateTransitionError) Error() string {
+	return fmt.Sprintf("delegation %s cannot move from %v to %s", e.Key, e.From, e.To)
+}
+
+func (e *InvalidStateTransitionError) Is(target error) bool {
+	_, ok := target.(*InvalidStateTransitionError)
+	return ok
+}
+
+func IsInvalidStateTransitionError(err error) bool {
+	return errors.Is(err, &InvalidStateTransitionError{})
+}
+
+// StateTransitionError is returned on a delegation state update finding the
+// delegation in a state not qualified for it, e.g. already moved on by
+// another update
+type StateTransitionError struct {
+	StakingTxHash string
+	CurrentState  types.DelegationState
+	TargetState   types.DelegationState
+}
+
+func (e *StateTransitionError) Error() string {
+	return fmt.Sprintf(
+		"delegation %s in state %s cannot move to %s", e.StakingTxHash, e.CurrentState, e.TargetState,
+	)
+}
+
+// Retryable tells that the update is rejected again on a retry, the
+// delegation never moving back to a qualified state
+func (e *StateTransitionError) Retryable() bool {
+	return false
+}
+
+func (e *StateTransitionError) Is(target error) bool {
+	_, ok := target.(*StateTransitionError)
+	return ok
+}
+
+func IsStateTransitionError(err error) bool {
+	return errors.Is(err, &StateTransitionError{})
+}
+
+// BulkWriteFailure is the failure of an item of a batched write
+type BulkWriteFailure struct {
+	// Index is the index of the item in the batch
+	Index int
+	Key   string
+	Err   error
+}
+
+// BulkWriteError is returned by the batched writes with the items which
+// failed, the others being applied. The errors of the items are typed as the
+// ones of the writes of a single item, e.g. NotFoundError or
+// DuplicateKeyError, and are matched by errors.Is and errors.As.
+type BulkWriteError struct {
+	Failures []BulkWriteFailure
+}
+
+// newBulkWriteError returns a BulkWriteError with the failures, nil if none
+func newBulkWriteError(failures []BulkWriteFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	return &BulkWriteError{Failures: failures}
+}
+
+func (e *BulkWriteError) Error() string {
+	first := e.Failures[0]
+	return fmt.Sprintf(
+		"%d items of the batch failed, first item %d (%s): %v",
+		len(e.Failures), first.Index, first.Key, first.Err,
+	)
+}
+
+func (e *BulkWriteError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure.Err
+	}
+	return errs
+}
+
+// errorClass returns the class of a database error. The timeouts are told
+// apart from the other network errors, the driver reporting them as both.
+func errorClass(err error) string {
+	var labeledErr mongo.LabeledError
+	switch {
+	case IsDuplicateKeyError(err) || mongo.IsDuplicateKeyError(err):
+		return ErrorClassDuplicateKey
+	case IsNotFoundError(err) || errors.Is(err, mongo.ErrNoDocuments):
+		return ErrorClassNotFound
+	case IsStateTransitionError(err):
+		return ErrorClassStateTransition
+	case mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case mongo.IsNetworkError(err):
+		return ErrorClassTransientNetwork
+	case errors.As(err, &labeledErr) && labeledErr.HasErrorLabel(transientTransactionErrorLabel):
+		return ErrorClassTransientNetwork
+	default:
+		return ErrorClassOther
+	}
+}
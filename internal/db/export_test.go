@@ -0,0 +1,20 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+// MongoTestDatabase is the mongoTestDatabase, with its collections and
+// indexes set up, for the tests of the db_test package
+func MongoTestDatabase(tb testing.TB, envPrefix string, dbName string) *Database {
+	database := mongoTestDatabase(tb, envPrefix, dbName)
+	cfg := &config.Config{Db: mongoTestConfig(envPrefix, dbName)}
+	require.NoError(tb, model.Setup(context.Background(), cfg))
+	return database
+}
@@ -3,18 +3,33 @@ package db
 import (
 	"context"
 	"errors"
+	"regexp"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func (db *Database) SaveNewFinalityProvider(
 	ctx context.Context, fpDoc *model.FinalityProviderDetails,
 ) error {
-	_, err := db.client.Database(db.dbName).
-		Collection(model.FinalityProviderDetailsCollection).
-		InsertOne(ctx, fpDoc)
+	collection := db.client.Database(db.dbName).Collection(model.FinalityProviderDetailsCollection)
+
+	// A duplicate key error would abort the transaction the insert runs in
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": fpDoc.BtcPk})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &DuplicateKeyError{
+			Key:     fpDoc.BtcPk,
+			Message: "finality provider already exists",
+		}
+	}
+
+	_, err = collection.InsertOne(ctx, fpDoc)
 	if err != nil {
 		var writeErr mongo.WriteException
 		if errors.As(err, &writeErr) {
@@ -126,3 +141,181 @@ func (db *Database) GetFinalityProviderByBtcPk(
 
 	return &fpDoc, nil
 }
+
+func (db *Database) GetFinalityProvidersByBsnId(
+	ctx context.Context, bsnId string,
+) ([]*model.FinalityProviderDetails, error) {
+	filter := bson.M{"bsn_id": bsnId}
+	if bsnId == model.BabylonBsnId {
+		// Finality providers indexed before BSN support have no bsn_id field,
+		// all of them belong to the Babylon chain.
+		filter = bson.M{"bsn_id": bson.M{"$in": []interface{}{model.BabylonBsnId, nil}}}
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.FinalityProviderDetailsCollection).
+		Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var fps []*model.FinalityProviderDetails
+	if err := cursor.All(ctx, &fps); err != nil {
+		return nil, err
+	}
+
+	return fps, nil
+}
+
+// FinalityProvidersFilter selects finality providers, all of them if empty
+type FinalityProvidersFilter struct {
+	State string
+	// BsnId restricts the finality providers to the ones of the BSN, if set
+	BsnId *string
+	// MonikerSearch restricts the finality providers to the ones whose moniker
+	// contains it, case insensitively
+	MonikerSearch string
+}
+
+// finalityProvidersPagination is the BTC public key of the last finality
+// provider of a page
+type finalityProvidersPagination struct {
+	BtcPk string `json:"btc_pk"`
+}
+
+func (db *Database) GetFinalityProviders(
+	ctx context.Context,
+	filter FinalityProvidersFilter,
+	paginationToken string,
+	limit int64,
+) (*DbResultMap[*model.FinalityProviderDetails], error) {
+	query := bson.M{}
+	if filter.State != "" {
+		query["state"] = filter.State
+	}
+	if filter.BsnId != nil {
+		query["bsn_id"] = *filter.BsnId
+		if *filter.BsnId == model.BabylonBsnId {
+			// Finality providers indexed before BSN support have no bsn_id field
+			query["bsn_id"] = bson.M{"$in": []interface{}{model.BabylonBsnId, nil}}
+		}
+	}
+	if filter.MonikerSearch != "" {
+		query["description.moniker"] = bson.M{
+			"$regex":   regexp.QuoteMeta(filter.MonikerSearch),
+			"$options": "i",
+		}
+	}
+
+	if paginationToken != "" {
+		var pagination finalityProvidersPagination
+		if err := decodePaginationToken(paginationToken, &pagination); err != nil {
+			return nil, err
+		}
+		query["_id"] = bson.M{"$gt": pagination.BtcPk}
+	}
+
+	// One more finality provider than the limit tells whether there is a
+	// next page
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit + 1)
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.FinalityProviderDetailsCollection).
+		Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var fps []*model.FinalityProviderDetails
+	if err := cursor.All(ctx, &fps); err != nil {
+		return nil, err
+	}
+
+	return toResultMapWithPaginationToken(
+		fps, limit,
+		func(fp *model.FinalityProviderDetails) (string, error) {
+			return encodePaginationToken(finalityProvidersPagination{BtcPk: fp.BtcPk})
+		},
+	)
+}
+
+func (db *Database) GetFinalityProviderStats(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"finality_provider_btc_pks_hex": btcPk,
+			"state":                         types.StateActive,
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":                   nil,
+			"active_delegations":    bson.M{"$sum": 1},
+			"active_staking_amount": bson.M{"$sum": "$staking_amount"},
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []*model.FinalityProviderStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return &model.FinalityProviderStats{}, nil
+	}
+	return stats[0], nil
+}
+
+func (db *Database) GetFinalityProviderStakeDistribution(
+	ctx context.Context, limit int64,
+) (*model.FinalityProviderStakeDistribution, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"state": types.StateActive}}},
+		{{Key: "$unwind", Value: "$finality_provider_btc_pks_hex"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":                   "$finality_provider_btc_pks_hex",
+			"active_staking_amount": bson.M{"$sum": "$staking_amount"},
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"top": bson.A{
+				// sorted by pk too, so that ties do not reorder between runs
+				bson.M{"$sort": bson.D{{Key: "active_staking_amount", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": limit},
+			},
+			"total": bson.A{
+				bson.M{"$group": bson.M{"_id": nil, "amount": bson.M{"$sum": "$active_staking_amount"}}},
+			},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"top": 1,
+			"total_active_staking_amount": bson.M{
+				"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$total.amount", 0}}, 0},
+			},
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var distributions []*model.FinalityProviderStakeDistribution
+	if err := cursor.All(ctx, &distributions); err != nil {
+		return nil, err
+	}
+	if len(distributions) == 0 {
+		return &model.FinalityProviderStakeDistribution{}, nil
+	}
+	return distributions[0], nil
+}
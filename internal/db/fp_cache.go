@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// fpCacheName labels the lookups of the finality provider cache
+const fpCacheName = "finality_providers"
+
+type cacheBypassKey struct{}
+
+// WithoutCache returns a context whose reads skip the caches in front of the
+// database, e.g. to compare the stored documents with the chain
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func isCacheBypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypassed
+}
+
+// FpCacheDatabase caches the finality providers read by BTC pk in a LRU
+// cache. A finality provider is dropped from the cache whenever written
+// through it, and expires after the TTL in case it was written otherwise.
+// The reads of a context WithoutCache neither use nor fill the cache.
+type FpCacheDatabase struct {
+	DbInterface
+
+	fps *expirable.LRU[string, model.FinalityProviderDetails]
+}
+
+func NewFpCacheDatabase(dbClient DbInterface, cfg *config.DbConfig) *FpCacheDatabase {
+	return &FpCacheDatabase{
+		DbInterface: dbClient,
+		fps: expirable.NewLRU[string, model.FinalityProviderDetails](
+			cfg.GetFpCacheSize(), nil, cfg.GetFpCacheTTL(),
+		),
+	}
+}
+
+func (d *FpCacheDatabase) GetFinalityProviderByBtcPk(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderDetails, error) {
+	if isCacheBypassed(ctx) {
+		return d.DbInterface.GetFinalityProviderByBtcPk(ctx, btcPk)
+	}
+
+	// The cached finality provider is copied out, so that the callers
+	// modifying theirs do not modify it
+	fp, ok := d.fps.Get(btcPk)
+	metrics.RecordDbCacheLookup(fpCacheName, ok)
+	if ok {
+		return &fp, nil
+	}
+
+	stored, err := d.DbInterface.GetFinalityProviderByBtcPk(ctx, btcPk)
+	if err != nil {
+		return nil, err
+	}
+	d.fps.Add(btcPk, *stored)
+	return stored, nil
+}
+
+// RunInTransaction empties the cache if the transaction fails, as it may
+// hold the finality providers read after being written in it
+func (d *FpCacheDatabase) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := d.DbInterface.RunInTransaction(ctx, fn)
+	if err != nil {
+		d.fps.Purge()
+	}
+	return err
+}
+
+func (d *FpCacheDatabase) SaveNewFinalityProvider(
+	ctx context.Context, fpDoc *model.FinalityProviderDetails,
+) error {
+	err := d.DbInterface.SaveNewFinalityProvider(ctx, fpDoc)
+	d.fps.Remove(fpDoc.BtcPk)
+	return err
+}
+
+func (d *FpCacheDatabase) UpdateFinalityProviderState(
+	ctx context.Context, btcPk string, newState string,
+) error {
+	err := d.DbInterface.UpdateFinalityProviderState(ctx, btcPk, newState)
+	d.fps.Remove(btcPk)
+	return err
+}
+
+func (d *FpCacheDatabase) UpdateFinalityProviderDetailsFromEvent(
+	ctx context.Context, detailsToUpdate *model.FinalityProviderDetails,
+) error {
+	err := d.DbInterface.UpdateFinalityProviderDetailsFromEvent(ctx, detailsToUpdate)
+	d.fps.Remove(detailsToUpdate.BtcPk)
+	return err
+}
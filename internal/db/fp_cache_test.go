@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// fpDatabase stores the finality providers in memory and counts the reads
+type fpDatabase struct {
+	DbInterface
+	fps   map[string]model.FinalityProviderDetails
+	reads int
+}
+
+func (d *fpDatabase) GetFinalityProviderByBtcPk(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderDetails, error) {
+	d.reads++
+	fp, ok := d.fps[btcPk]
+	if !ok {
+		return nil, &NotFoundError{Key: btcPk, Message: "finality provider not found"}
+	}
+	return &fp, nil
+}
+
+func (d *fpDatabase) UpdateFinalityProviderState(ctx context.Context, btcPk string, newState string) error {
+	fp := d.fps[btcPk]
+	fp.State = newState
+	d.fps[btcPk] = fp
+	return nil
+}
+
+func TestFpCacheDatabase(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	stored := &fpDatabase{fps: map[string]model.FinalityProviderDetails{
+		"pk": {BtcPk: "pk", State: "FINALITY_PROVIDER_STATUS_ACTIVE"},
+	}}
+	cache := NewFpCacheDatabase(stored, &config.DbConfig{FpCacheSize: 10, FpCacheTTL: time.Hour})
+
+	fp, err := cache.GetFinalityProviderByBtcPk(ctx, "pk")
+	require.NoError(t, err)
+	// modifying the finality provider read does not modify the cached one
+	fp.State = "modified"
+	fp, err = cache.GetFinalityProviderByBtcPk(ctx, "pk")
+	require.NoError(t, err)
+	require.Equal(t, "FINALITY_PROVIDER_STATUS_ACTIVE", fp.State)
+	require.Equal(t, 1, stored.reads)
+
+	// a state update drops the cached finality provider
+	require.NoError(t, cache.UpdateFinalityProviderState(ctx, "pk", "FINALITY_PROVIDER_STATUS_JAILED"))
+	fp, err = cache.GetFinalityProviderByBtcPk(ctx, "pk")
+	require.NoError(t, err)
+	require.Equal(t, "FINALITY_PROVIDER_STATUS_JAILED", fp.State)
+	require.Equal(t, 2, stored.reads)
+
+	// the reads without cache always read the stored one
+	_, err = cache.GetFinalityProviderByBtcPk(WithoutCache(ctx), "pk")
+	require.NoError(t, err)
+	require.Equal(t, 3, stored.reads)
+
+	_, err = cache.GetFinalityProviderByBtcPk(ctx, "missing")
+	require.True(t, IsNotFoundError(err))
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Database) SaveFinalityProviderVotingPowerChange(
+	ctx context.Context, change *model.FinalityProviderVotingPowerChange,
+) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.FpVotingPowerChangesCollection).
+		InsertOne(ctx, change)
+	return err
+}
+
+func (db *Database) GetLatestFinalityProviderVotingPowerChanges(
+	ctx context.Context, fpBtcPks []string,
+) (map[string]*model.FinalityProviderVotingPowerChange, error) {
+	latest := make(map[string]*model.FinalityProviderVotingPowerChange, len(fpBtcPks))
+	if len(fpBtcPks) == 0 {
+		return latest, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"fp_btc_pk_hex": bson.M{"$in": fpBtcPks}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "fp_btc_pk_hex", Value: 1}, {Key: "bbn_height", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    "$fp_btc_pk_hex",
+			"latest": bson.M{"$first": "$$ROOT"},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$latest"}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.FpVotingPowerChangesCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*model.FinalityProviderVotingPowerChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	for _, change := range changes {
+		latest[change.FinalityProviderBtcPkHex] = change
+	}
+	return latest, nil
+}
+
+func (db *Database) GetFinalityProviderActivationPeriods(
+	ctx context.Context, fpBtcPk string,
+) ([]*model.FinalityProviderActivationPeriod, error) {
+	filter := bson.M{"fp_btc_pk_hex": fpBtcPk}
+	opts := options.Find().SetSort(bson.M{"bbn_height": 1})
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.FpVotingPowerChangesCollection).
+		Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*model.FinalityProviderVotingPowerChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return model.ToActivationPeriods(changes), nil
+}
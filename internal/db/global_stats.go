@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Database) GetDelegationStatsByState(
+	ctx context.Context,
+) ([]*model.DelegationStateStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$state",
+			"count":          bson.M{"$sum": 1},
+			"staking_amount": bson.M{"$sum": "$staking_amount"},
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []*model.DelegationStateStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (db *Database) CountFinalityProvidersByState(
+	ctx context.Context,
+) (map[string]uint64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$state",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.FinalityProviderDetailsCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		State string `bson:"_id"`
+		Count uint64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]uint64, len(results))
+	for _, result := range results {
+		counts[result.State] = result.Count
+	}
+	return counts, nil
+}
+
+func (db *Database) SaveGlobalStats(ctx context.Context, stats *model.GlobalStats) error {
+	stats.Id = model.GlobalStatsId
+	_, err := db.client.Database(db.dbName).
+		Collection(model.GlobalStatsCollection).
+		ReplaceOne(ctx, bson.M{"_id": model.GlobalStatsId}, stats, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (db *Database) GetGlobalStats(ctx context.Context) (*model.GlobalStats, error) {
+	var stats model.GlobalStats
+	err := db.client.Database(db.dbName).
+		Collection(model.GlobalStatsCollection).
+		FindOne(ctx, bson.M{"_id": model.GlobalStatsId}).
+		Decode(&stats)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     model.GlobalStatsId,
+				Message: "global stats not found",
+			}
+		}
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+func (db *Database) SaveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) error {
+	bootstrap.Id = model.BootstrapId
+	_, err := db.client.Database(db.dbName).
+		Collection(model.BootstrapCollection).
+		ReplaceOne(ctx, bson.M{"_id": model.BootstrapId}, bootstrap, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (db *Database) GetBootstrap(ctx context.Context) (*model.Bootstrap, error) {
+	var bootstrap model.Bootstrap
+	err := db.client.Database(db.dbName).
+		Collection(model.BootstrapCollection).
+		FindOne(ctx, bson.M{"_id": model.BootstrapId}).
+		Decode(&bootstrap)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     model.BootstrapId,
+				Message: "bootstrap not found",
+			}
+		}
+		return nil, err
+	}
+
+	return &bootstrap, nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+)
+
+// NewIndexerDatabase wraps the database in the decorators the indexer runs
+// with, from the innermost:
+//   - the metrics, counting the database errors by method and error class
+//   - the audit of the mutations of the indexed state, those skipped by a dry
+//     run excepted
+//   - the cache of the staking params, warmed with the stored versions
+//   - the cache of the finality providers read by BTC pk until written
+//   - the memo of the delegations read again while processing a BBN block
+//   - in a dry run, the logging of the writes instead of applying them
+func NewIndexerDatabase(
+	ctx context.Context, database DbInterface, cfg *config.DbConfig, dryRun bool,
+) (DbInterface, error) {
+	var dbClient DbInterface = NewMetricsDatabase(database, cfg)
+	dbClient = NewAuditDatabase(dbClient)
+	paramsCache := NewParamsCacheDatabase(dbClient)
+	if err := paramsCache.Warm(ctx); err != nil {
+		return nil, fmt.Errorf("failed to warm the staking params cache: %w", err)
+	}
+	dbClient = paramsCache
+	dbClient = NewFpCacheDatabase(dbClient, cfg)
+	dbClient = NewDelegationMemoDatabase(dbClient)
+	if dryRun {
+		dbClient = NewDryRunDatabase(dbClient)
+	}
+	return dbClient, nil
+}
@@ -0,0 +1,21 @@
+package inmemory
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) SaveBbnEventDeadLetter(
+	ctx context.Context, deadLetter *model.BbnEventDeadLetter,
+) error {
+	copied, err := clone(deadLetter)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deadLetters[deadLetter.Id] = copied
+	return nil
+}
@@ -0,0 +1,127 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) SaveBTCDerivedChange(
+	ctx context.Context, change *model.BTCDerivedChange,
+) error {
+	copied, err := clone(change)
+	if err != nil {
+		return err
+	}
+	if copied.Id.IsZero() {
+		copied.Id = primitive.NewObjectID()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.btcDerivedChanges = append(d.btcDerivedChanges, copied)
+	return nil
+}
+
+func (d *Database) RollbackBTCDerivedChanges(
+	ctx context.Context, forkHeight uint64,
+) ([]*model.BTCDerivedChange, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Undo the most recent changes first, so that each delegation ends up in
+	// the state it had at the fork height
+	var changes []*model.BTCDerivedChange
+	for _, change := range d.btcDerivedChanges {
+		if change.BtcHeight > forkHeight {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].BtcHeight != changes[j].BtcHeight {
+			return changes[i].BtcHeight > changes[j].BtcHeight
+		}
+		return isObjectIdBefore(changes[j].Id, changes[i].Id)
+	})
+
+	// As in Mongo, the changes undone before a failure stay undone
+	for _, change := range changes {
+		if err := d.undoBTCDerivedChange(change); err != nil {
+			return nil, fmt.Errorf(
+				"failed to undo BTC derived change of delegation %s at height %d: %w",
+				change.StakingTxHashHex, change.BtcHeight, err,
+			)
+		}
+		d.btcDerivedChanges = deleteWhere(d.btcDerivedChanges, func(saved *model.BTCDerivedChange) bool {
+			return saved == change
+		})
+	}
+
+	return cloneAll(changes)
+}
+
+func (d *Database) undoBTCDerivedChange(change *model.BTCDerivedChange) error {
+	if change.PreviousState != "" || change.PreviousSlashingTx != nil {
+		delegation, ok := d.delegations[change.StakingTxHashHex]
+		if !ok {
+			return &db.NotFoundError{
+				Key:     change.StakingTxHashHex,
+				Message: "BTC delegation not found when rolling back BTC derived change",
+			}
+		}
+		if change.PreviousState != "" {
+			delegation.State = change.PreviousState
+			delegation.StateUpdatedAt = time.Now().Unix()
+			delegation.SubState = change.PreviousSubState
+		}
+		if change.PreviousSlashingTx != nil {
+			delegation.SlashingTx = *change.PreviousSlashingTx
+		}
+	}
+
+	if created := change.CreatedTimeLock; created != nil {
+		deleted := false
+		d.timeLocks = deleteWhere(d.timeLocks, func(tl *timeLock) bool {
+			if deleted || tl.TimeLockDocument != *created {
+				return false
+			}
+			deleted = true
+			return true
+		})
+	}
+	if restored := change.DeletedTimeLock; restored != nil {
+		// The timelock document might not have been deleted
+		found := false
+		for _, tl := range d.timeLocks {
+			if tl.TimeLockDocument == *restored {
+				found = true
+				break
+			}
+		}
+		if !found {
+			d.timeLocks = append(d.timeLocks, &timeLock{Id: primitive.NewObjectID(), TimeLockDocument: *restored})
+		}
+		// The restored timelock is active again
+		d.archivedTimeLocks = deleteWhere(d.archivedTimeLocks, func(tl *model.ArchivedTimeLockDocument) bool {
+			return tl.TimeLockDocument == *restored
+		})
+	}
+
+	return nil
+}
+
+func (d *Database) DeleteBTCDerivedChangesBelow(ctx context.Context, height uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.btcDerivedChanges = deleteWhere(d.btcDerivedChanges, func(change *model.BTCDerivedChange) bool {
+		return change.BtcHeight < height
+	})
+	return nil
+}
@@ -0,0 +1,74 @@
+package inmemory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) SaveBTCHeader(ctx context.Context, header *model.BTCHeader) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	copied := *header
+	d.btcHeaders[header.Height] = &copied
+	return nil
+}
+
+func (d *Database) GetBTCHeaderByHeight(
+	ctx context.Context, height uint64,
+) (*model.BTCHeader, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	header, ok := d.btcHeaders[height]
+	if !ok {
+		return nil, &db.NotFoundError{
+			Key:     strconv.FormatUint(height, 10),
+			Message: "BTC header not found",
+		}
+	}
+	copied := *header
+	return &copied, nil
+}
+
+func (d *Database) GetLatestBTCHeader(ctx context.Context) (*model.BTCHeader, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	heights := sortedKeys(d.btcHeaders)
+	if len(heights) == 0 {
+		return nil, &db.NotFoundError{
+			Key:     "latest",
+			Message: "no BTC header has been processed yet",
+		}
+	}
+	copied := *d.btcHeaders[heights[len(heights)-1]]
+	return &copied, nil
+}
+
+func (d *Database) DeleteBTCHeadersAbove(ctx context.Context, height uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for headerHeight := range d.btcHeaders {
+		if headerHeight > height {
+			delete(d.btcHeaders, headerHeight)
+		}
+	}
+	return nil
+}
+
+func (d *Database) DeleteBTCHeadersBelow(ctx context.Context, height uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for headerHeight := range d.btcHeaders {
+		if headerHeight < height {
+			delete(d.btcHeaders, headerHeight)
+		}
+	}
+	return nil
+}
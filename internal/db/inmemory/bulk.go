@@ -0,0 +1,160 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// bulkWrite is the write of a batch item, applying it if its filter matches
+// the delegation and telling whether it did. The item rejected before the
+// batch is sent has a nil apply and an error.
+type bulkWrite struct {
+	item  int
+	key   string
+	apply func(delegation *model.BTCDelegationDetails) bool
+	err   error
+}
+
+func (d *Database) BulkUpdateDelegationStates(
+	ctx context.Context, updates []db.DelegationStateUpdate,
+) error {
+	writes := make([]bulkWrite, len(updates))
+	for i, update := range updates {
+		writes[i] = bulkWrite{item: i, key: update.StakingTxHash}
+
+		var qualifiedStates []types.DelegationState
+		for _, state := range update.QualifiedPreviousStates {
+			if state.CanTransitionTo(update.NewState) {
+				qualifiedStates = append(qualifiedStates, state)
+			}
+		}
+		if len(qualifiedStates) == 0 {
+			writes[i].err = &db.InvalidStateTransitionError{
+				Key: update.StakingTxHash, From: update.QualifiedPreviousStates, To: update.NewState,
+			}
+			continue
+		}
+
+		writes[i].apply = func(delegation *model.BTCDelegationDetails) bool {
+			if !utils.Contains(qualifiedStates, delegation.State) {
+				return false
+			}
+			delegation.State = update.NewState
+			delegation.StateUpdatedAt = time.Now().Unix()
+			if update.NewSubState != nil {
+				delegation.SubState = *update.NewSubState
+			}
+			return true
+		}
+	}
+
+	return d.bulkWrite(writes, func(delegation *model.BTCDelegationDetails, i int) error {
+		update := updates[i]
+		if delegation == nil {
+			return &db.NotFoundError{
+				Key:     update.StakingTxHash,
+				Message: "BTC delegation not found when updating its state",
+			}
+		}
+		if delegation.State == update.NewState {
+			return nil
+		}
+		return &db.StateTransitionError{
+			StakingTxHash: update.StakingTxHash,
+			CurrentState:  delegation.State,
+			TargetState:   update.NewState,
+		}
+	})
+}
+
+func (d *Database) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []db.CovenantSigRecord,
+) error {
+	writes := make([]bulkWrite, len(sigs))
+	for i, sig := range sigs {
+		// A covenant member signs the unbonding of a delegation once, so
+		// that its signature is not pushed twice on a replay of the batch
+		writes[i] = bulkWrite{
+			item: i,
+			key:  sig.StakingTxHash,
+			apply: func(delegation *model.BTCDelegationDetails) bool {
+				for _, saved := range delegation.CovenantUnbondingSignatures {
+					if saved.CovenantBtcPkHex == sig.CovenantBtcPkHex {
+						return false
+					}
+				}
+				delegation.CovenantUnbondingSignatures = append(
+					delegation.CovenantUnbondingSignatures,
+					model.CovenantSignature{CovenantBtcPkHex: sig.CovenantBtcPkHex, SignatureHex: sig.SignatureHex},
+				)
+				return true
+			},
+		}
+	}
+
+	return d.bulkWrite(writes, func(delegation *model.BTCDelegationDetails, i int) error {
+		sig := sigs[i]
+		if delegation == nil {
+			return &db.NotFoundError{
+				Key:     sig.StakingTxHash,
+				Message: "BTC delegation not found when saving covenant signature",
+			}
+		}
+		for _, saved := range delegation.CovenantUnbondingSignatures {
+			if saved.CovenantBtcPkHex == sig.CovenantBtcPkHex && saved.SignatureHex != sig.SignatureHex {
+				return &db.DuplicateKeyError{
+					Key:     sig.StakingTxHash,
+					Message: "covenant signature already saved: " + sig.CovenantBtcPkHex,
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// bulkWrite applies the writes of a batch to the delegations and returns a
+// BulkWriteError with the items which failed. As the Mongo implementation,
+// if fewer writes matched than sent, checkUnmatched tells for every write
+// sent whether it applied, given its delegation after the batch, nil if
+// missing.
+func (d *Database) bulkWrite(
+	writes []bulkWrite,
+	checkUnmatched func(delegation *model.BTCDelegationDetails, item int) error,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var failures []db.BulkWriteFailure
+	var sent []bulkWrite
+	matched := 0
+	for _, write := range writes {
+		if write.err != nil {
+			failures = append(failures, db.BulkWriteFailure{Index: write.item, Key: write.key, Err: write.err})
+			continue
+		}
+		sent = append(sent, write)
+		if delegation, ok := d.delegations[write.key]; ok && write.apply(delegation) {
+			matched++
+		}
+	}
+
+	if matched < len(sent) {
+		for _, write := range sent {
+			if itemErr := checkUnmatched(d.delegations[write.key], write.item); itemErr != nil {
+				failures = append(failures, db.BulkWriteFailure{Index: write.item, Key: write.key, Err: itemErr})
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	return &db.BulkWriteError{Failures: failures}
+}
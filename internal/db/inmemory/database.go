@@ -0,0 +1,128 @@
+// Package inmemory implements the DbInterface in memory, with the semantics
+// of the Mongo implementation, for the tests of its users. Both pass the
+// contract suite of the dbtest package.
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+// Database holds the documents of the collections in memory. It is safe for
+// concurrent use, every method running under a single lock. The documents
+// are copied in and out, so that the ones of the callers are never shared.
+type Database struct {
+	mu sync.Mutex
+
+	collections
+}
+
+// collections are the documents of the database
+type collections struct {
+	finalityProviders  map[string]*model.FinalityProviderDetails
+	votingPowerChanges []*model.FinalityProviderVotingPowerChange
+	stakingParams      map[uint32]*bbnclient.StakingParams
+	checkpointParams   *bbnclient.CheckpointParams
+
+	delegations         map[string]*model.BTCDelegationDetails
+	archivedDelegations map[string]*model.ArchivedBTCDelegation
+	stateTransitions    []*model.DelegationStateTransition
+	// timeLocks are the active timelock documents, in insertion order
+	timeLocks         []*timeLock
+	archivedTimeLocks []*model.ArchivedTimeLockDocument
+
+	lastProcessedHeight *model.LastProcessedHeight
+	processedHeights    []*model.BbnHeightRange
+
+	btcHeaders        map[uint64]*model.BTCHeader
+	btcDerivedChanges []*model.BTCDerivedChange
+
+	// outboxEvents are in insertion order
+	outboxEvents    []*model.OutboxEvent
+	outboxSequences map[string]uint64
+
+	reconciliationRuns          map[primitive.ObjectID]*model.ReconciliationRun
+	reconciliationDiscrepancies []*model.ReconciliationDiscrepancy
+	stuckDelegationReports      []*model.StuckDelegationReport
+	rawEventCaptures            []*model.RawEventCapture
+	deadLetters                 map[string]*model.BbnEventDeadLetter
+	globalStats                 *model.GlobalStats
+	bootstrap                   *model.Bootstrap
+	locks                       map[string]*model.Lock
+}
+
+var _ db.DbInterface = (*Database)(nil)
+
+func New() *Database {
+	return &Database{collections: collections{
+		finalityProviders:   make(map[string]*model.FinalityProviderDetails),
+		stakingParams:       make(map[uint32]*bbnclient.StakingParams),
+		delegations:         make(map[string]*model.BTCDelegationDetails),
+		archivedDelegations: make(map[string]*model.ArchivedBTCDelegation),
+		btcHeaders:          make(map[uint64]*model.BTCHeader),
+		outboxSequences:     make(map[string]uint64),
+		reconciliationRuns:  make(map[primitive.ObjectID]*model.ReconciliationRun),
+		deadLetters:         make(map[string]*model.BbnEventDeadLetter),
+		locks:               make(map[string]*model.Lock),
+	}}
+}
+
+func (d *Database) Ping(ctx context.Context) error {
+	return nil
+}
+
+// clone returns a copy of the document encoded and decoded as by the driver,
+// so that a document failing to encode is refused as by Mongo and the copy
+// reads back as the stored document would
+func clone[T any](doc *T) (*T, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var copied T
+	if err := bson.Unmarshal(raw, &copied); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// cloneAll returns copies of the documents
+func cloneAll[T any](docs []*T) ([]*T, error) {
+	var copied []*T
+	for _, doc := range docs {
+		c, err := clone(doc)
+		if err != nil {
+			return nil, err
+		}
+		copied = append(copied, c)
+	}
+	return copied, nil
+}
+
+// sortedKeys returns the keys of the documents in ascending order, the order
+// of their _id index
+func sortedKeys[K string | uint64, V any](docs map[K]V) []K {
+	keys := make([]K, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// limited returns the first limit results, all of them if the limit is not
+// positive, as the limit of a Find
+func limited[T any](results []T, limit int64) []T {
+	if limit > 0 && int64(len(results)) > limit {
+		return results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,671 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+func (d *Database) SaveNewBTCDelegation(
+	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.delegations[delegationDoc.StakingTxHashHex]; ok {
+		return &db.DuplicateKeyError{
+			Key:     delegationDoc.StakingTxHashHex,
+			Message: "delegation already exists",
+		}
+	}
+	delegation, err := clone(delegationDoc)
+	if err != nil {
+		return err
+	}
+	d.delegations[delegation.StakingTxHashHex] = delegation
+	return nil
+}
+
+func (d *Database) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, _, err := d.updateBTCDelegationState(db.DelegationStateUpdate{
+		StakingTxHash:           stakingTxHash,
+		QualifiedPreviousStates: qualifiedPreviousStates,
+		NewState:                newState,
+		NewSubState:             newSubState,
+	})
+	return err
+}
+
+// updateBTCDelegationState applies the update as UpdateBTCDelegationState,
+// returning the state and sub state of the delegation before it
+func (d *Database) updateBTCDelegationState(
+	update db.DelegationStateUpdate,
+) (types.DelegationState, types.DelegationSubState, error) {
+	if len(update.QualifiedPreviousStates) == 0 {
+		return "", "", fmt.Errorf("qualified previous states array cannot be empty")
+	}
+
+	var qualifiedStates []types.DelegationState
+	for _, state := range update.QualifiedPreviousStates {
+		if state.CanTransitionTo(update.NewState) {
+			qualifiedStates = append(qualifiedStates, state)
+		}
+	}
+	if len(qualifiedStates) == 0 {
+		return "", "", &db.InvalidStateTransitionError{
+			Key: update.StakingTxHash, From: update.QualifiedPreviousStates, To: update.NewState,
+		}
+	}
+
+	delegation, ok := d.delegations[update.StakingTxHash]
+	if !ok {
+		return "", "", &db.NotFoundError{
+			Key:     update.StakingTxHash,
+			Message: "BTC delegation not found or current state is not qualified states",
+		}
+	}
+	previousState, previousSubState := delegation.State, delegation.SubState
+	err := d.setBTCDelegationState(update.StakingTxHash, qualifiedStates, update.NewState, update.NewSubState)
+	if !db.IsNotFoundError(err) {
+		return previousState, previousSubState, err
+	}
+	// The delegation is in another state
+	return "", "", &db.StateTransitionError{
+		StakingTxHash: update.StakingTxHash, CurrentState: delegation.State, TargetState: update.NewState,
+	}
+}
+
+func (d *Database) ApplyBTCDelegationStateChange(ctx context.Context, change db.DelegationStateChange) error {
+	var journal *model.BTCDerivedChange
+	if change.BTCDerivedChange != nil {
+		var err error
+		if journal, err = clone(change.BTCDerivedChange); err != nil {
+			return err
+		}
+		if journal.Id.IsZero() {
+			journal.Id = primitive.NewObjectID()
+		}
+	}
+	var transition *model.DelegationStateTransition
+	if change.Transition != nil {
+		var err error
+		if transition, err = clone(change.Transition); err != nil {
+			return err
+		}
+		if transition.Id.IsZero() {
+			transition.Id = primitive.NewObjectID()
+		}
+	}
+	var event *model.OutboxEvent
+	if change.OutboxEvent != nil {
+		var err error
+		if event, err = clone(change.OutboxEvent); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The delegation is restored if its event fails to be saved, the only
+	// record which can fail
+	delegation, err := cloneDoc(d.delegations[change.StakingTxHash])
+	if err != nil {
+		return err
+	}
+	previousState, previousSubState, err := d.updateBTCDelegationState(change.DelegationStateUpdate)
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		if err := d.saveOutboxEvent(change.OutboxEvent, event); err != nil && !db.IsDuplicateKeyError(err) {
+			d.delegations[change.StakingTxHash] = delegation
+			return err
+		}
+	}
+	if journal != nil {
+		change.BTCDerivedChange.PreviousState = previousState
+		change.BTCDerivedChange.PreviousSubState = previousSubState
+		journal.PreviousState = previousState
+		journal.PreviousSubState = previousSubState
+		d.btcDerivedChanges = append(d.btcDerivedChanges, journal)
+	}
+	if transition != nil {
+		change.Transition.FromState = previousState
+		transition.FromState = previousState
+		d.stateTransitions = append(d.stateTransitions, transition)
+	}
+	return nil
+}
+
+func (d *Database) OverrideBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	currentState types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.setBTCDelegationState(
+		stakingTxHash, []types.DelegationState{currentState}, newState, newSubState,
+	)
+}
+
+func (d *Database) UpdateBTCDelegationSubState(
+	ctx context.Context,
+	stakingTxHash string,
+	state types.DelegationState,
+	newSubState types.DelegationSubState,
+) error {
+	qualifiedSubStates := types.QualifiedPreviousSubStates(state, newSubState)
+	if len(qualifiedSubStates) == 0 {
+		return fmt.Errorf("no sub state of %s can move to %s", state, newSubState)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok || delegation.State != state || !utils.Contains(qualifiedSubStates, delegation.SubState) {
+		return &db.NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found or current sub state is not qualified",
+		}
+	}
+	delegation.SubState = newSubState
+	return nil
+}
+
+// setBTCDelegationState sets the state of the delegation if in one of the
+// qualified states
+func (d *Database) setBTCDelegationState(
+	stakingTxHash string,
+	qualifiedStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok || !utils.Contains(qualifiedStates, delegation.State) {
+		return &db.NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found or current state is not qualified states",
+		}
+	}
+
+	delegation.State = newState
+	delegation.StateUpdatedAt = time.Now().Unix()
+	if newSubState != nil {
+		delegation.SubState = *newSubState
+	}
+	return nil
+}
+
+func (d *Database) GetBTCDelegationState(
+	ctx context.Context, stakingTxHash string,
+) (*types.DelegationState, error) {
+	delegation, err := d.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	if err != nil {
+		return nil, err
+	}
+	return &delegation.State, nil
+}
+
+func (d *Database) UpdateBTCDelegationDetails(
+	ctx context.Context,
+	stakingTxHash string,
+	details *model.BTCDelegationDetails,
+) error {
+	// Only the fields which are not empty are updated, if any
+	if details.State == "" && details.StartHeight == 0 && details.EndHeight == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok {
+		return &db.NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found when updating details",
+		}
+	}
+	if details.State != "" {
+		delegation.State = details.State
+		delegation.StateUpdatedAt = time.Now().Unix()
+	}
+	if details.StartHeight != 0 {
+		delegation.StartHeight = details.StartHeight
+	}
+	if details.EndHeight != 0 {
+		delegation.EndHeight = details.EndHeight
+	}
+	return nil
+}
+
+func (d *Database) SaveBTCDelegationUnbondingCovenantSignature(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// As the update of Mongo, a missing delegation is not an error
+	if delegation, ok := d.delegations[stakingTxHash]; ok {
+		delegation.CovenantUnbondingSignatures = append(
+			delegation.CovenantUnbondingSignatures,
+			model.CovenantSignature{CovenantBtcPkHex: covenantBtcPkHex, SignatureHex: signatureHex},
+		)
+	}
+	return nil
+}
+
+func (d *Database) SetCovenantSignatureVerified(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if delegation, ok := d.delegations[stakingTxHash]; ok {
+		for i, sig := range delegation.CovenantUnbondingSignatures {
+			if sig.CovenantBtcPkHex == covenantBtcPkHex {
+				delegation.CovenantUnbondingSignatures[i].Verified = &verified
+				return nil
+			}
+		}
+	}
+	return &db.NotFoundError{
+		Key:     stakingTxHash,
+		Message: "BTC delegation or covenant signature not found",
+	}
+}
+
+func (d *Database) GetBTCDelegationByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok {
+		return nil, &db.NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found when getting by staking tx hash",
+		}
+	}
+	return clone(delegation)
+}
+
+func (d *Database) GetBTCDelegationSummaryByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationSummary, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok {
+		return nil, &db.NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found when getting by staking tx hash",
+		}
+	}
+	return toSummary(delegation)
+}
+
+// toSummary returns the delegation read as its summary, as with the
+// BTCDelegationSummaryProjection
+func toSummary(delegation *model.BTCDelegationDetails) (*model.BTCDelegationSummary, error) {
+	raw, err := bson.Marshal(delegation)
+	if err != nil {
+		return nil, err
+	}
+	var summary model.BTCDelegationSummary
+	if err := bson.Unmarshal(raw, &summary); err != nil {
+		return nil, err
+	}
+	summary.CovenantUnbondingSignaturesCount = len(delegation.CovenantUnbondingSignatures)
+	return &summary, nil
+}
+
+func (d *Database) GetBTCDelegationsByStakingTxHashes(
+	ctx context.Context, stakingTxHashes []string,
+) (map[string]*model.BTCDelegationDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	byStakingTxHash := make(map[string]*model.BTCDelegationDetails)
+	for _, stakingTxHash := range stakingTxHashes {
+		delegation, ok := d.delegations[stakingTxHash]
+		if !ok {
+			continue
+		}
+		copied, err := clone(delegation)
+		if err != nil {
+			return nil, err
+		}
+		byStakingTxHash[stakingTxHash] = copied
+	}
+	return byStakingTxHash, nil
+}
+
+func (d *Database) UpdateDelegationsStateByFinalityProvider(
+	ctx context.Context,
+	fpBTCPKHex string,
+	newState types.DelegationState,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, delegation := range d.delegations {
+		if utils.Contains(delegation.FinalityProviderBtcPksHex, fpBTCPKHex) &&
+			delegation.State.CanTransitionTo(newState) {
+			delegation.State = newState
+			delegation.StateUpdatedAt = time.Now().Unix()
+		}
+	}
+	return nil
+}
+
+func (d *Database) GetDelegationsByFinalityProvider(
+	ctx context.Context,
+	fpBTCPKHex string,
+) ([]*model.BTCDelegationDetails, error) {
+	return d.findDelegations(func(delegation *model.BTCDelegationDetails) bool {
+		return utils.Contains(delegation.FinalityProviderBtcPksHex, fpBTCPKHex)
+	})
+}
+
+func (d *Database) SaveBTCDelegationSlashingTxHex(
+	ctx context.Context,
+	stakingTxHash string,
+	slashingTxHex string,
+	spendingHeight uint32,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok {
+		return &db.NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found when updating slashing tx hex",
+		}
+	}
+	delegation.SlashingTx.SlashingTxHex = slashingTxHex
+	delegation.SlashingTx.SpendingHeight = spendingHeight
+	return nil
+}
+
+func (d *Database) SaveBTCDelegationUnbondingSlashingTxHex(
+	ctx context.Context,
+	stakingTxHash string,
+	unbondingSlashingTxHex string,
+	spendingHeight uint32,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delegation, ok := d.delegations[stakingTxHash]
+	if !ok {
+		return &db.NotFoundError{
+			Key:     stakingTxHash,
+			Message: "BTC delegation not found when updating unbonding slashing tx hex",
+		}
+	}
+	delegation.SlashingTx.UnbondingSlashingTxHex = unbondingSlashingTxHex
+	delegation.SlashingTx.SpendingHeight = spendingHeight
+	return nil
+}
+
+func (d *Database) GetBTCDelegationsByStates(
+	ctx context.Context,
+	states []types.DelegationState,
+) ([]*model.BTCDelegationDetails, error) {
+	return d.findDelegations(func(delegation *model.BTCDelegationDetails) bool {
+		return utils.Contains(states, delegation.State)
+	})
+}
+
+// matchesFilter tells whether the delegation is selected by the filter
+func matchesFilter(filter db.BTCDelegationsFilter, delegation *model.BTCDelegationDetails) bool {
+	if len(filter.States) > 0 && !utils.Contains(filter.States, delegation.State) {
+		return false
+	}
+	if len(filter.FinalityProviderBtcPks) == 0 {
+		return true
+	}
+	for _, fpBtcPk := range delegation.FinalityProviderBtcPksHex {
+		if utils.Contains(filter.FinalityProviderBtcPks, fpBtcPk) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Database) GetBTCDelegationsAfter(
+	ctx context.Context, filter db.BTCDelegationsFilter, stakingTxHashHex string, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	delegations, err := d.findDelegations(func(delegation *model.BTCDelegationDetails) bool {
+		return delegation.StakingTxHashHex > stakingTxHashHex && matchesFilter(filter, delegation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return limited(delegations, int64(limit)), nil
+}
+
+func (d *Database) SampleBTCDelegations(
+	ctx context.Context, filter db.BTCDelegationsFilter, size uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	delegations, err := d.findDelegations(func(delegation *model.BTCDelegationDetails) bool {
+		return matchesFilter(filter, delegation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	rand.Shuffle(len(delegations), func(i, j int) {
+		delegations[i], delegations[j] = delegations[j], delegations[i]
+	})
+	if uint64(len(delegations)) > size {
+		delegations = delegations[:size]
+	}
+	return delegations, nil
+}
+
+func (d *Database) GetBTCDelegationsCreatedBetween(
+	ctx context.Context, fromHeight, toHeight int64,
+) ([]*model.BTCDelegationDetails, error) {
+	delegations, err := d.findDelegations(func(delegation *model.BTCDelegationDetails) bool {
+		height := delegation.BTCDelegationCreatedBlock.Height
+		return height >= fromHeight && height <= toHeight
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Sorted by staking tx hash first, the sort keeps it among equal heights
+	sort.SliceStable(delegations, func(i, j int) bool {
+		return delegations[i].BTCDelegationCreatedBlock.Height < delegations[j].BTCDelegationCreatedBlock.Height
+	})
+	return delegations, nil
+}
+
+// stakerDelegationsPagination is the position of the last delegation of a
+// page, the delegations being sorted by creation height then staking tx hash,
+// newest first
+type stakerDelegationsPagination struct {
+	CreatedBbnHeight int64  `json:"created_bbn_height"`
+	StakingTxHashHex string `json:"staking_tx_hash_hex"`
+}
+
+func (d *Database) GetStakerDelegations(
+	ctx context.Context,
+	filter db.StakerDelegationsFilter,
+	paginationToken string,
+	limit int64,
+) (*db.DbResultMap[*model.BTCDelegationDetails], error) {
+	return getStakerDelegations(d, filter, paginationToken, limit, clone[model.BTCDelegationDetails])
+}
+
+func (d *Database) GetStakerDelegationSummaries(
+	ctx context.Context,
+	filter db.StakerDelegationsFilter,
+	paginationToken string,
+	limit int64,
+) (*db.DbResultMap[*model.BTCDelegationSummary], error) {
+	return getStakerDelegations(d, filter, paginationToken, limit, toSummary)
+}
+
+// getStakerDelegations returns a page of the delegations of the staker read
+// as T
+func getStakerDelegations[T any](
+	d *Database,
+	filter db.StakerDelegationsFilter,
+	paginationToken string,
+	limit int64,
+	read func(*model.BTCDelegationDetails) (*T, error),
+) (*db.DbResultMap[*T], error) {
+	var pagination *stakerDelegationsPagination
+	if paginationToken != "" {
+		pagination = &stakerDelegationsPagination{}
+		if err := decodePaginationToken(paginationToken, pagination); err != nil {
+			return nil, err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var delegations []*model.BTCDelegationDetails
+	for _, delegation := range d.delegations {
+		switch {
+		case filter.StakerBtcPkHex != "" && delegation.StakerBtcPkHex != filter.StakerBtcPkHex,
+			filter.StakerBabylonAddress != "" && delegation.StakerBabylonAddress != filter.StakerBabylonAddress,
+			filter.State != "" && delegation.State != filter.State:
+			continue
+		}
+		if pagination != nil && !isBeforePagination(delegation, pagination) {
+			continue
+		}
+		delegations = append(delegations, delegation)
+	}
+	sort.Slice(delegations, func(i, j int) bool {
+		return isBeforePagination(delegations[j], &stakerDelegationsPagination{
+			CreatedBbnHeight: delegations[i].BTCDelegationCreatedBlock.Height,
+			StakingTxHashHex: delegations[i].StakingTxHashHex,
+		})
+	})
+
+	// One more delegation than the limit tells whether there is a next page
+	page, err := toResultMapWithPaginationToken(
+		limited(delegations, limit+1), limit,
+		func(delegation *model.BTCDelegationDetails) any {
+			return stakerDelegationsPagination{
+				CreatedBbnHeight: delegation.BTCDelegationCreatedBlock.Height,
+				StakingTxHashHex: delegation.StakingTxHashHex,
+			}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*T
+	for _, delegation := range page.Data {
+		result, err := read(delegation)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return &db.DbResultMap[*T]{Data: results, PaginationToken: page.PaginationToken}, nil
+}
+
+// isBeforePagination tells whether the delegation comes after the position
+// of the pagination, newest first
+func isBeforePagination(
+	delegation *model.BTCDelegationDetails, pagination *stakerDelegationsPagination,
+) bool {
+	height := delegation.BTCDelegationCreatedBlock.Height
+	return height < pagination.CreatedBbnHeight ||
+		(height == pagination.CreatedBbnHeight && delegation.StakingTxHashHex < pagination.StakingTxHashHex)
+}
+
+// findDelegations returns copies of the delegations matching, sorted by
+// staking tx hash
+func (d *Database) findDelegations(
+	match func(delegation *model.BTCDelegationDetails) bool,
+) ([]*model.BTCDelegationDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var delegations []*model.BTCDelegationDetails
+	for _, stakingTxHash := range sortedKeys(d.delegations) {
+		if delegation := d.delegations[stakingTxHash]; match(delegation) {
+			delegations = append(delegations, delegation)
+		}
+	}
+	return cloneAll(delegations)
+}
+
+// DeleteBTCDelegation deletes the delegation along with its state history and
+// timelocks, active and archived. Its event sequence is kept as the legacy
+// sequence of the delegation, so that the events of a delegation created
+// again do not reuse the sequence numbers.
+func (d *Database) DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delegation, ok := d.delegations[stakingTxHashHex]
+	if !ok {
+		return &db.NotFoundError{
+			Key:     stakingTxHashHex,
+			Message: "BTC delegation not found when deleting it",
+		}
+	}
+	delete(d.delegations, stakingTxHashHex)
+
+	if delegation.EventSequence > d.outboxSequences[stakingTxHashHex] {
+		d.outboxSequences[stakingTxHashHex] = delegation.EventSequence
+	}
+	d.stateTransitions = deleteWhere(d.stateTransitions, func(transition *model.DelegationStateTransition) bool {
+		return transition.StakingTxHashHex == stakingTxHashHex
+	})
+	d.timeLocks = deleteWhere(d.timeLocks, func(tl *timeLock) bool {
+		return tl.StakingTxHashHex == stakingTxHashHex
+	})
+	d.archivedTimeLocks = deleteWhere(d.archivedTimeLocks, func(tl *model.ArchivedTimeLockDocument) bool {
+		return tl.StakingTxHashHex == stakingTxHashHex
+	})
+	return nil
+}
+
+// deleteWhere returns the documents not matching
+func deleteWhere[T any](docs []*T, match func(*T) bool) []*T {
+	kept := docs[:0]
+	for _, doc := range docs {
+		if !match(doc) {
+			kept = append(kept, doc)
+		}
+	}
+	return kept
+}
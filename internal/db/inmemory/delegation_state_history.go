@@ -0,0 +1,135 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+func (d *Database) SaveDelegationStateTransition(
+	ctx context.Context, transition *model.DelegationStateTransition,
+) error {
+	copied, err := clone(transition)
+	if err != nil {
+		return err
+	}
+	if copied.Id.IsZero() {
+		copied.Id = primitive.NewObjectID()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stateTransitions = append(d.stateTransitions, copied)
+	return nil
+}
+
+func (d *Database) GetDelegationStateTransitions(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.DelegationStateTransition, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var transitions []*model.DelegationStateTransition
+	for _, transition := range d.stateTransitions {
+		if transition.StakingTxHashHex == stakingTxHashHex {
+			transitions = append(transitions, transition)
+		}
+	}
+	sort.Slice(transitions, func(i, j int) bool {
+		if transitions[i].CreatedAt != transitions[j].CreatedAt {
+			return transitions[i].CreatedAt < transitions[j].CreatedAt
+		}
+		return isObjectIdBefore(transitions[i].Id, transitions[j].Id)
+	})
+	return cloneAll(transitions)
+}
+
+func (d *Database) AggregateStateTransitions(
+	ctx context.Context,
+	fromTime, toTime int64,
+	periodUnit string,
+	visit func(stats *model.StateTransitionPeriodStats) error,
+) error {
+	stats, err := d.stateTransitionPeriodStats(fromTime, toTime, periodUnit)
+	if err != nil {
+		return err
+	}
+	// visit is run without the lock, as it may read the database
+	for _, periodStats := range stats {
+		if err := visit(periodStats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stateTransitionPeriodStats aggregates the state transitions recorded in
+// the time range by period and target state, sorted by period then state
+func (d *Database) stateTransitionPeriodStats(
+	fromTime, toTime int64, periodUnit string,
+) ([]*model.StateTransitionPeriodStats, error) {
+	type periodKey struct {
+		periodStart int64
+		toState     types.DelegationState
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	byPeriod := make(map[periodKey]*model.StateTransitionPeriodStats)
+	var stats []*model.StateTransitionPeriodStats
+	for _, transition := range d.stateTransitions {
+		if transition.CreatedAt < fromTime || transition.CreatedAt >= toTime {
+			continue
+		}
+		periodStart, err := truncateToPeriod(transition.CreatedAt, periodUnit)
+		if err != nil {
+			return nil, err
+		}
+		key := periodKey{periodStart: periodStart, toState: transition.ToState}
+		periodStats, ok := byPeriod[key]
+		if !ok {
+			periodStats = &model.StateTransitionPeriodStats{PeriodStart: periodStart, ToState: transition.ToState}
+			byPeriod[key] = periodStats
+			stats = append(stats, periodStats)
+		}
+		periodStats.TransitionCount++
+		// the staked amount is only held by the delegation document
+		if delegation, ok := d.delegations[transition.StakingTxHashHex]; ok {
+			periodStats.TotalSat += delegation.StakingAmount
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].PeriodStart != stats[j].PeriodStart {
+			return stats[i].PeriodStart < stats[j].PeriodStart
+		}
+		return stats[i].ToState < stats[j].ToState
+	})
+	return stats, nil
+}
+
+// truncateToPeriod returns the start of the period of the unit the time in
+// epoch seconds is in, in UTC and the weeks starting on monday, as the
+// $dateTrunc of the Mongo aggregation
+func truncateToPeriod(epochSeconds int64, unit string) (int64, error) {
+	t := time.Unix(epochSeconds, 0).UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch unit {
+	case "day":
+		return day.Unix(), nil
+	case "week":
+		daysSinceMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -daysSinceMonday).Unix(), nil
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Unix(), nil
+	default:
+		return 0, fmt.Errorf("unsupported period unit: %s", unit)
+	}
+}
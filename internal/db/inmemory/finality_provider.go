@@ -0,0 +1,305 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+func (d *Database) SaveNewFinalityProvider(
+	ctx context.Context, fpDoc *model.FinalityProviderDetails,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.finalityProviders[fpDoc.BtcPk]; ok {
+		return &db.DuplicateKeyError{
+			Key:     fpDoc.BtcPk,
+			Message: "finality provider already exists",
+		}
+	}
+	fp, err := clone(fpDoc)
+	if err != nil {
+		return err
+	}
+	d.finalityProviders[fp.BtcPk] = fp
+	return nil
+}
+
+func (d *Database) UpdateFinalityProviderDetailsFromEvent(
+	ctx context.Context, detailsToUpdate *model.FinalityProviderDetails,
+) error {
+	// Only the fields which are not empty are updated, if any
+	details := detailsToUpdate.Description
+	if detailsToUpdate.Commission == "" && details == (model.Description{}) {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fp, ok := d.finalityProviders[detailsToUpdate.BtcPk]
+	if !ok {
+		return &db.NotFoundError{
+			Key:     detailsToUpdate.BtcPk,
+			Message: "finality provider not found when updating details",
+		}
+	}
+	if detailsToUpdate.Commission != "" {
+		fp.Commission = detailsToUpdate.Commission
+	}
+	if details.Moniker != "" {
+		fp.Description.Moniker = details.Moniker
+	}
+	if details.Identity != "" {
+		fp.Description.Identity = details.Identity
+	}
+	if details.Website != "" {
+		fp.Description.Website = details.Website
+	}
+	if details.SecurityContact != "" {
+		fp.Description.SecurityContact = details.SecurityContact
+	}
+	if details.Details != "" {
+		fp.Description.Details = details.Details
+	}
+	return nil
+}
+
+func (d *Database) UpdateFinalityProviderState(
+	ctx context.Context, btcPk string, newState string,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fp, ok := d.finalityProviders[btcPk]
+	if !ok {
+		return &db.NotFoundError{
+			Key:     btcPk,
+			Message: "finality provider not found when updating state",
+		}
+	}
+	fp.State = newState
+	return nil
+}
+
+func (d *Database) GetFinalityProviderByBtcPk(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fp, ok := d.finalityProviders[btcPk]
+	if !ok {
+		return nil, &db.NotFoundError{
+			Key:     btcPk,
+			Message: "finality provider not found when getting by btc public key",
+		}
+	}
+	return clone(fp)
+}
+
+func (d *Database) GetFinalityProvidersByBsnId(
+	ctx context.Context, bsnId string,
+) ([]*model.FinalityProviderDetails, error) {
+	// The finality providers saved always hold a bsn_id, unlike the ones
+	// indexed in Mongo before BSN support, found along with the Babylon ones
+	return d.findFinalityProviders(func(fp *model.FinalityProviderDetails) bool {
+		return fp.BsnId == bsnId
+	})
+}
+
+// finalityProvidersPagination is the BTC public key of the last finality
+// provider of a page
+type finalityProvidersPagination struct {
+	BtcPk string `json:"btc_pk"`
+}
+
+func (d *Database) GetFinalityProviders(
+	ctx context.Context,
+	filter db.FinalityProvidersFilter,
+	paginationToken string,
+	limit int64,
+) (*db.DbResultMap[*model.FinalityProviderDetails], error) {
+	var pagination finalityProvidersPagination
+	if paginationToken != "" {
+		if err := decodePaginationToken(paginationToken, &pagination); err != nil {
+			return nil, err
+		}
+	}
+	monikerSearch := strings.ToLower(filter.MonikerSearch)
+
+	fps, err := d.findFinalityProviders(func(fp *model.FinalityProviderDetails) bool {
+		switch {
+		case filter.State != "" && fp.State != filter.State,
+			// as in GetFinalityProvidersByBsnId, every finality provider
+			// holds a bsn_id
+			filter.BsnId != nil && fp.BsnId != *filter.BsnId,
+			!strings.Contains(strings.ToLower(fp.Description.Moniker), monikerSearch):
+			return false
+		}
+		return paginationToken == "" || fp.BtcPk > pagination.BtcPk
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// One more finality provider than the limit tells whether there is a
+	// next page
+	return toResultMapWithPaginationToken(
+		limited(fps, limit+1), limit,
+		func(fp *model.FinalityProviderDetails) any {
+			return finalityProvidersPagination{BtcPk: fp.BtcPk}
+		},
+	)
+}
+
+// findFinalityProviders returns copies of the finality providers matching,
+// sorted by BTC public key
+func (d *Database) findFinalityProviders(
+	match func(fp *model.FinalityProviderDetails) bool,
+) ([]*model.FinalityProviderDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var fps []*model.FinalityProviderDetails
+	for _, btcPk := range sortedKeys(d.finalityProviders) {
+		if fp := d.finalityProviders[btcPk]; match(fp) {
+			fps = append(fps, fp)
+		}
+	}
+	return cloneAll(fps)
+}
+
+func (d *Database) GetFinalityProviderStats(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := &model.FinalityProviderStats{}
+	for _, delegation := range d.delegations {
+		if delegation.State == types.StateActive &&
+			utils.Contains(delegation.FinalityProviderBtcPksHex, btcPk) {
+			stats.ActiveDelegations++
+			stats.ActiveStakingAmount += delegation.StakingAmount
+		}
+	}
+	return stats, nil
+}
+
+func (d *Database) GetFinalityProviderStakeDistribution(
+	ctx context.Context, limit int64,
+) (*model.FinalityProviderStakeDistribution, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The stake of a delegation counts for each of its finality providers
+	stakes := make(map[string]uint64)
+	distribution := &model.FinalityProviderStakeDistribution{Top: []*model.FinalityProviderActiveStake{}}
+	for _, delegation := range d.delegations {
+		if delegation.State != types.StateActive {
+			continue
+		}
+		for _, fpBtcPk := range delegation.FinalityProviderBtcPksHex {
+			stakes[fpBtcPk] += delegation.StakingAmount
+			distribution.TotalActiveStakingAmount += delegation.StakingAmount
+		}
+	}
+
+	for _, btcPk := range sortedKeys(stakes) {
+		distribution.Top = append(distribution.Top, &model.FinalityProviderActiveStake{
+			BtcPk: btcPk, ActiveStakingAmount: stakes[btcPk],
+		})
+	}
+	// sorted by pk too, so that ties do not reorder between runs
+	sort.SliceStable(distribution.Top, func(i, j int) bool {
+		return distribution.Top[i].ActiveStakingAmount > distribution.Top[j].ActiveStakingAmount
+	})
+	distribution.Top = limited(distribution.Top, limit)
+	return distribution, nil
+}
+
+func (d *Database) CountFinalityProvidersByState(
+	ctx context.Context,
+) (map[string]uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	counts := make(map[string]uint64)
+	for _, fp := range d.finalityProviders {
+		counts[fp.State]++
+	}
+	return counts, nil
+}
+
+func (d *Database) SaveFinalityProviderVotingPowerChange(
+	ctx context.Context, change *model.FinalityProviderVotingPowerChange,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	copied, err := clone(change)
+	if err != nil {
+		return err
+	}
+	d.votingPowerChanges = append(d.votingPowerChanges, copied)
+	return nil
+}
+
+func (d *Database) GetLatestFinalityProviderVotingPowerChanges(
+	ctx context.Context, fpBtcPks []string,
+) (map[string]*model.FinalityProviderVotingPowerChange, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	wanted := make(map[string]struct{}, len(fpBtcPks))
+	for _, fpBtcPk := range fpBtcPks {
+		wanted[fpBtcPk] = struct{}{}
+	}
+
+	latest := make(map[string]*model.FinalityProviderVotingPowerChange)
+	for _, change := range d.votingPowerChanges {
+		if _, ok := wanted[change.FinalityProviderBtcPkHex]; !ok {
+			continue
+		}
+		last, ok := latest[change.FinalityProviderBtcPkHex]
+		if !ok || change.BbnHeight >= last.BbnHeight {
+			latest[change.FinalityProviderBtcPkHex] = change
+		}
+	}
+	return cloneMap(latest)
+}
+
+func (d *Database) GetFinalityProviderActivationPeriods(
+	ctx context.Context, fpBtcPk string,
+) ([]*model.FinalityProviderActivationPeriod, error) {
+	changes, err := d.votingPowerChangesOf(fpBtcPk)
+	if err != nil {
+		return nil, err
+	}
+	return model.ToActivationPeriods(changes), nil
+}
+
+// votingPowerChangesOf returns copies of the voting power changes of the
+// finality provider sorted by BBN height
+func (d *Database) votingPowerChangesOf(
+	fpBtcPk string,
+) ([]*model.FinalityProviderVotingPowerChange, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var changes []*model.FinalityProviderVotingPowerChange
+	for _, change := range d.votingPowerChanges {
+		if change.FinalityProviderBtcPkHex == fpBtcPk {
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].BbnHeight < changes[j].BbnHeight })
+	return cloneAll(changes)
+}
@@ -0,0 +1,84 @@
+package inmemory
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) GetDelegationStatsByState(
+	ctx context.Context,
+) ([]*model.DelegationStateStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	byState := make(map[string]*model.DelegationStateStats)
+	for _, delegation := range d.delegations {
+		stats, ok := byState[delegation.State.String()]
+		if !ok {
+			stats = &model.DelegationStateStats{State: delegation.State.String()}
+			byState[stats.State] = stats
+		}
+		stats.Count++
+		stats.StakingAmount += delegation.StakingAmount
+	}
+
+	stats := make([]*model.DelegationStateStats, 0, len(byState))
+	for _, state := range sortedKeys(byState) {
+		stats = append(stats, byState[state])
+	}
+	return stats, nil
+}
+
+func (d *Database) SaveGlobalStats(ctx context.Context, stats *model.GlobalStats) error {
+	stats.Id = model.GlobalStatsId
+	copied, err := clone(stats)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.globalStats = copied
+	return nil
+}
+
+func (d *Database) GetGlobalStats(ctx context.Context) (*model.GlobalStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.globalStats == nil {
+		return nil, &db.NotFoundError{
+			Key:     model.GlobalStatsId,
+			Message: "global stats not found",
+		}
+	}
+	return clone(d.globalStats)
+}
+
+func (d *Database) SaveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) error {
+	bootstrap.Id = model.BootstrapId
+	copied, err := clone(bootstrap)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bootstrap = copied
+	return nil
+}
+
+func (d *Database) GetBootstrap(ctx context.Context) (*model.Bootstrap, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.bootstrap == nil {
+		return nil, &db.NotFoundError{
+			Key:     model.BootstrapId,
+			Message: "bootstrap not found",
+		}
+	}
+	return clone(d.bootstrap)
+}
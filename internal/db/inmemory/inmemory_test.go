@@ -0,0 +1,70 @@
+package inmemory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/dbtest"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+func TestContract(t *testing.T) {
+	dbtest.RunDbInterfaceTests(t, func() db.DbInterface {
+		return inmemory.New()
+	})
+}
+
+func TestConcurrentOutboxEvents(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+		StakingTxHashHex: "aa",
+		State:            types.StateActive,
+	}))
+
+	// Every event of the delegation gets a sequence number of its own
+	const events = 100
+	var wg sync.WaitGroup
+	for i := range events {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			event := &model.OutboxEvent{Id: fmt.Sprintf("event:%d", i), StakingTxHashHex: "aa"}
+			require.NoError(t, database.SaveOutboxEvent(ctx, event))
+			_, err := database.GetDelegationOutboxEvents(ctx, "aa")
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	saved, err := database.GetDelegationOutboxEvents(ctx, "aa")
+	require.NoError(t, err)
+	require.Len(t, saved, events)
+	for i, event := range saved {
+		require.Equal(t, uint64(i+1), event.Sequence)
+	}
+}
+
+func TestReadsReturnCopies(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	delegation := &model.BTCDelegationDetails{StakingTxHashHex: "aa", State: types.StateActive}
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+
+	delegation.State = types.StateWithdrawn
+	read, err := database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	read.StakerBtcPkHex = "staker"
+
+	read, err = database.GetBTCDelegationByStakingTxHash(ctx, "aa")
+	require.NoError(t, err)
+	require.Equal(t, types.StateActive, read.State)
+	require.Empty(t, read.StakerBtcPkHex)
+}
@@ -0,0 +1,99 @@
+package inmemory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) GetLastProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	result, err := d.GetLastProcessedBbnBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return result.Height, nil
+}
+
+func (d *Database) GetLastProcessedBbnBlock(ctx context.Context) (*model.LastProcessedHeight, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.lastProcessedHeight == nil {
+		// If no document exists, start from height 0
+		return &model.LastProcessedHeight{}, nil
+	}
+	return clone(d.lastProcessedHeight)
+}
+
+// updateLastProcessedHeight applies the update to the last processed height
+// document, created if missing
+func (d *Database) updateLastProcessedHeight(update func(lastProcessed *model.LastProcessedHeight)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.lastProcessedHeight == nil {
+		d.lastProcessedHeight = &model.LastProcessedHeight{}
+	}
+	update(d.lastProcessedHeight)
+}
+
+func (d *Database) UpdateLastProcessedBbnHeight(
+	ctx context.Context, height uint64, blockHash string,
+) error {
+	// Advancing the height completes the block under processing, if any
+	d.updateLastProcessedHeight(func(lastProcessed *model.LastProcessedHeight) {
+		lastProcessed.Height = height
+		lastProcessed.BlockHash = blockHash
+		lastProcessed.ProcessingMarker = nil
+	})
+	return nil
+}
+
+func (d *Database) HaltBbnProcessing(ctx context.Context, reason string) error {
+	d.updateLastProcessedHeight(func(lastProcessed *model.LastProcessedHeight) {
+		lastProcessed.HaltReason = reason
+	})
+	return nil
+}
+
+func (d *Database) ResyncLastProcessedBbnHeight(ctx context.Context, height uint64) error {
+	d.updateLastProcessedHeight(func(lastProcessed *model.LastProcessedHeight) {
+		*lastProcessed = model.LastProcessedHeight{Height: height}
+	})
+	return nil
+}
+
+func (d *Database) StartBbnBlockProcessing(
+	ctx context.Context, marker *model.BbnProcessingMarker,
+) error {
+	copied, err := clone(marker)
+	if err != nil {
+		return err
+	}
+	d.updateLastProcessedHeight(func(lastProcessed *model.LastProcessedHeight) {
+		lastProcessed.ProcessingMarker = copied
+	})
+	return nil
+}
+
+func (d *Database) MarkBbnEventProcessed(
+	ctx context.Context, height uint64, eventIndex int,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.lastProcessedHeight == nil || d.lastProcessedHeight.ProcessingMarker == nil ||
+		d.lastProcessedHeight.ProcessingMarker.Height != height {
+		return &db.NotFoundError{
+			Key:     strconv.FormatUint(height, 10),
+			Message: "no BBN block is being processed at the height",
+		}
+	}
+	marker := d.lastProcessedHeight.ProcessingMarker
+	if !marker.IsEventProcessed(eventIndex) {
+		marker.ProcessedEvents = append(marker.ProcessedEvents, eventIndex)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package inmemory
+
+import (
+	"context"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	// The lock is taken over once expired, and renewed by its owner
+	if lock, ok := d.locks[name]; ok && lock.Owner != owner && lock.ExpiresAt >= now.Unix() {
+		return &db.LockHeldError{Name: name, Owner: lock.Owner}
+	}
+	d.locks[name] = &model.Lock{
+		Name:      name,
+		Owner:     owner,
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	return nil
+}
+
+func (d *Database) ReleaseLock(ctx context.Context, name, owner string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if lock, ok := d.locks[name]; ok && lock.Owner == owner {
+		delete(d.locks, name)
+	}
+	return nil
+}
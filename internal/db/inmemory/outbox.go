@@ -0,0 +1,207 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+// isUnsentOutboxEvent matches the events to be pushed to the queue
+func isUnsentOutboxEvent(event *model.OutboxEvent) bool {
+	return event.SentAt == 0 && !event.Poison
+}
+
+func (d *Database) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	copied, err := clone(event)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.saveOutboxEvent(event, copied)
+}
+
+// saveOutboxEvent saves the copy of the event, setting the sequence of both.
+// The caller holds the lock.
+func (d *Database) saveOutboxEvent(event, copied *model.OutboxEvent) error {
+	// Reprocessing a transition must not consume a sequence number
+	if d.findOutboxEvent(event.Id) != nil {
+		return &db.DuplicateKeyError{
+			Key:     event.Id,
+			Message: "outbox event already exists",
+		}
+	}
+
+	delegation, ok := d.delegations[event.StakingTxHashHex]
+	if !ok {
+		return &db.NotFoundError{
+			Key:     event.StakingTxHashHex,
+			Message: "BTC delegation not found when incrementing its event sequence",
+		}
+	}
+	// The legacy sequences carry on, as in Mongo
+	delegation.EventSequence = max(delegation.EventSequence, d.outboxSequences[event.StakingTxHashHex]) + 1
+	event.Sequence = delegation.EventSequence
+	copied.Sequence = delegation.EventSequence
+
+	d.outboxEvents = append(d.outboxEvents, copied)
+	return nil
+}
+
+// findOutboxEvent returns the saved event of the id, or nil
+func (d *Database) findOutboxEvent(id string) *model.OutboxEvent {
+	for _, event := range d.outboxEvents {
+		if event.Id == id {
+			return event
+		}
+	}
+	return nil
+}
+
+// findOutboxEvents returns the events matching, sorted by the less function
+func (d *Database) findOutboxEvents(
+	match func(*model.OutboxEvent) bool, less func(a, b *model.OutboxEvent) bool,
+) []*model.OutboxEvent {
+	var events []*model.OutboxEvent
+	for _, event := range d.outboxEvents {
+		if match(event) {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return less(events[i], events[j]) })
+	return events
+}
+
+// createdBefore orders the events by creation time, and by id those created
+// at the same time
+func createdBefore(a, b *model.OutboxEvent) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt < b.CreatedAt
+	}
+	return a.Id < b.Id
+}
+
+func (d *Database) GetUnsentOutboxEvents(
+	ctx context.Context, createdAfter int64, afterId string, limit uint64,
+) ([]*model.OutboxEvent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	after := &model.OutboxEvent{Id: afterId, CreatedAt: createdAfter}
+	events := d.findOutboxEvents(func(event *model.OutboxEvent) bool {
+		return createdBefore(after, event) && isUnsentOutboxEvent(event)
+	}, createdBefore)
+	return cloneAll(limited(events, int64(limit)))
+}
+
+func (d *Database) MarkOutboxEventSent(ctx context.Context, id string, sentAt int64) error {
+	return d.updateOutboxEvent(id, func(event *model.OutboxEvent) {
+		event.SentAt = sentAt
+	})
+}
+
+func (d *Database) MarkOutboxEventFailed(
+	ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool,
+) error {
+	return d.updateOutboxEvent(id, func(event *model.OutboxEvent) {
+		event.Attempts++
+		event.LastError = lastError
+		event.NextAttemptAt = nextAttemptAt
+		event.Poison = poison
+	})
+}
+
+func (d *Database) updateOutboxEvent(id string, update func(event *model.OutboxEvent)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	event := d.findOutboxEvent(id)
+	if event == nil {
+		return &db.NotFoundError{
+			Key:     id,
+			Message: "outbox event not found",
+		}
+	}
+	update(event)
+	return nil
+}
+
+func (d *Database) GetPoisonOutboxEvents(ctx context.Context) ([]*model.OutboxEvent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	events := d.findOutboxEvents(func(event *model.OutboxEvent) bool {
+		return event.Poison
+	}, createdBefore)
+	return cloneAll(events)
+}
+
+func (d *Database) RequeuePoisonOutboxEvents(ctx context.Context) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var requeued uint64
+	for _, event := range d.outboxEvents {
+		if event.Poison {
+			event.Poison = false
+			event.Attempts = 0
+			event.NextAttemptAt = 0
+			requeued++
+		}
+	}
+	return requeued, nil
+}
+
+func (d *Database) GetOutboxStats(ctx context.Context) (*model.OutboxStats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := &model.OutboxStats{}
+	for _, event := range d.outboxEvents {
+		switch {
+		case event.SentAt != 0:
+		case event.Poison:
+			stats.Poison++
+		default:
+			if stats.Unsent == 0 || event.CreatedAt < stats.OldestUnsentAt {
+				stats.OldestUnsentAt = event.CreatedAt
+			}
+			stats.Unsent++
+		}
+	}
+	return stats, nil
+}
+
+func (d *Database) GetDelegationOutboxEvents(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.OutboxEvent, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	events := d.findOutboxEvents(func(event *model.OutboxEvent) bool {
+		return event.StakingTxHashHex == stakingTxHashHex
+	}, func(a, b *model.OutboxEvent) bool {
+		return a.Sequence < b.Sequence
+	})
+	return cloneAll(events)
+}
+
+func (d *Database) GetOutboxSequence(ctx context.Context, stakingTxHashHex string) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sequence := d.outboxSequences[stakingTxHashHex]
+	if delegation, ok := d.delegations[stakingTxHashHex]; ok {
+		sequence = max(sequence, delegation.EventSequence)
+	}
+	if sequence == 0 {
+		return 0, &db.NotFoundError{
+			Key:     stakingTxHashHex,
+			Message: "outbox sequence not found",
+		}
+	}
+	return sequence, nil
+}
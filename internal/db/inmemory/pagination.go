@@ -0,0 +1,43 @@
+package inmemory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+)
+
+// The pagination tokens are encoded as the ones of the Mongo implementation,
+// so that a token of either is read by both
+
+// toResultMapWithPaginationToken returns the page of the first limit
+// results, the extra one telling whether there is a next page
+func toResultMapWithPaginationToken[T any](
+	results []T, limit int64, paginationKeyBuilder func(T) any,
+) (*db.DbResultMap[T], error) {
+	if int64(len(results)) <= limit {
+		return &db.DbResultMap[T]{Data: results}, nil
+	}
+
+	results = results[:limit]
+	tokenBytes, err := json.Marshal(paginationKeyBuilder(results[len(results)-1]))
+	if err != nil {
+		return nil, err
+	}
+
+	return &db.DbResultMap[T]{
+		Data:            results,
+		PaginationToken: base64.URLEncoding.EncodeToString(tokenBytes),
+	}, nil
+}
+
+func decodePaginationToken(token string, value any) error {
+	tokenBytes, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return &db.InvalidPaginationTokenError{Message: "invalid pagination token"}
+	}
+	if err := json.Unmarshal(tokenBytes, value); err != nil {
+		return &db.InvalidPaginationTokenError{Message: "invalid pagination token"}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package inmemory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+)
+
+func (d *Database) SaveStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// The params of a version are saved once
+	if _, ok := d.stakingParams[version]; ok {
+		return nil
+	}
+	copied, err := clone(params)
+	if err != nil {
+		return err
+	}
+	d.stakingParams[version] = copied
+	return nil
+}
+
+func (d *Database) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.stakingParams[version]; !ok {
+		return &db.NotFoundError{
+			Key:     strconv.FormatUint(uint64(version), 10),
+			Message: "staking params not found when replacing them",
+		}
+	}
+	copied, err := clone(params)
+	if err != nil {
+		return err
+	}
+	d.stakingParams[version] = copied
+	return nil
+}
+
+func (d *Database) SaveCheckpointParams(
+	ctx context.Context, params *bbnclient.CheckpointParams,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.checkpointParams != nil {
+		return nil
+	}
+	copied, err := clone(params)
+	if err != nil {
+		return err
+	}
+	d.checkpointParams = copied
+	return nil
+}
+
+func (d *Database) GetStakingParams(ctx context.Context, version uint32) (*bbnclient.StakingParams, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	params, ok := d.stakingParams[version]
+	if !ok {
+		return nil, &db.NotFoundError{
+			Key:     strconv.FormatUint(uint64(version), 10),
+			Message: "staking params not found",
+		}
+	}
+	return clone(params)
+}
+
+func (d *Database) GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	allParams := make(map[uint32]*bbnclient.StakingParams, len(d.stakingParams))
+	for version, params := range d.stakingParams {
+		copied, err := clone(params)
+		if err != nil {
+			return nil, err
+		}
+		allParams[version] = copied
+	}
+	return allParams, nil
+}
+
+func (d *Database) GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.checkpointParams == nil {
+		return nil, &db.NotFoundError{
+			Key:     db.CHECKPOINT_PARAMS_TYPE,
+			Message: "checkpoint params not found",
+		}
+	}
+	return clone(d.checkpointParams)
+}
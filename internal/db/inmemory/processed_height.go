@@ -0,0 +1,82 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) MarkBbnHeightProcessed(ctx context.Context, height uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var prev, next *model.BbnHeightRange
+	for _, heightRange := range d.processedHeights {
+		switch {
+		case heightRange.Start <= height && heightRange.End >= height:
+			// Already recorded, e.g. when reprocessing a height
+			return nil
+		case heightRange.End == height-1:
+			prev = heightRange
+		case heightRange.Start == height+1:
+			next = heightRange
+		}
+	}
+
+	switch {
+	case prev != nil && next != nil:
+		// The height fills a gap, merge both ranges
+		prev.End = next.End
+		d.processedHeights = deleteWhere(d.processedHeights, func(heightRange *model.BbnHeightRange) bool {
+			return heightRange == next
+		})
+	case prev != nil:
+		prev.End = height
+	case next != nil:
+		next.Start = height
+	default:
+		d.processedHeights = append(d.processedHeights, &model.BbnHeightRange{Start: height, End: height})
+	}
+	return nil
+}
+
+func (d *Database) GetLowestProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.processedHeights) == 0 {
+		return 0, &db.NotFoundError{
+			Key:     "lowest",
+			Message: "no processed BBN height recorded",
+		}
+	}
+	lowest := d.processedHeights[0].Start
+	for _, heightRange := range d.processedHeights {
+		lowest = min(lowest, heightRange.Start)
+	}
+	return lowest, nil
+}
+
+func (d *Database) DetectProcessedHeightGaps(
+	ctx context.Context, from, to uint64,
+) ([]*model.BbnHeightRange, error) {
+	if from > to {
+		return nil, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var processed []*model.BbnHeightRange
+	for _, heightRange := range d.processedHeights {
+		if heightRange.Start <= to && heightRange.End >= from {
+			copied := *heightRange
+			processed = append(processed, &copied)
+		}
+	}
+	sort.Slice(processed, func(i, j int) bool { return processed[i].Start < processed[j].Start })
+
+	return model.FindBbnHeightGaps(processed, from, to), nil
+}
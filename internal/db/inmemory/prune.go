@@ -0,0 +1,98 @@
+package inmemory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// isPrunableDelegation matches the delegations in a terminal state since
+// before the given time. The delegations missing the time they entered their
+// state are never pruned.
+func isPrunableDelegation(delegation *model.BTCDelegationDetails, before int64) bool {
+	return slices.Contains(types.TerminalDelegationStates(), delegation.State) &&
+		delegation.StateUpdatedAt != 0 && delegation.StateUpdatedAt < before
+}
+
+// isPrunableArchivedTimeLock matches the archived timelocks archived before
+// the given time whose delegation is in a terminal state or pruned
+func (d *Database) isPrunableArchivedTimeLock(tl *model.ArchivedTimeLockDocument, before int64) bool {
+	if tl.ArchivedAt >= before {
+		return false
+	}
+	delegation, ok := d.delegations[tl.StakingTxHashHex]
+	return !ok || slices.Contains(types.TerminalDelegationStates(), delegation.State)
+}
+
+func (d *Database) CountPrunableBTCDelegations(ctx context.Context, before int64) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var count uint64
+	for _, delegation := range d.delegations {
+		if isPrunableDelegation(delegation, before) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (d *Database) ArchivePrunableBTCDelegations(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var prunable []string
+	for _, stakingTxHash := range sortedKeys(d.delegations) {
+		if isPrunableDelegation(d.delegations[stakingTxHash], before) {
+			prunable = append(prunable, stakingTxHash)
+		}
+	}
+
+	// A delegation indexed again after being pruned replaces its previous
+	// archive
+	prunable = limited(prunable, limit)
+	archivedAt := time.Now().Unix()
+	for _, stakingTxHash := range prunable {
+		d.archivedDelegations[stakingTxHash] = &model.ArchivedBTCDelegation{
+			BTCDelegationDetails: *d.delegations[stakingTxHash],
+			ArchivedAt:           archivedAt,
+		}
+		delete(d.delegations, stakingTxHash)
+	}
+	return uint64(len(prunable)), nil
+}
+
+func (d *Database) CountPrunableArchivedTimeLocks(ctx context.Context, before int64) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var count uint64
+	for _, tl := range d.archivedTimeLocks {
+		if d.isPrunableArchivedTimeLock(tl, before) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (d *Database) DeletePrunableArchivedTimeLocks(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var deleted int64
+	d.archivedTimeLocks = deleteWhere(d.archivedTimeLocks, func(tl *model.ArchivedTimeLockDocument) bool {
+		if (limit > 0 && deleted >= limit) || !d.isPrunableArchivedTimeLock(tl, before) {
+			return false
+		}
+		deleted++
+		return true
+	})
+	return uint64(deleted), nil
+}
@@ -0,0 +1,24 @@
+package inmemory
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) SaveRawEventCapture(ctx context.Context, capture *model.RawEventCapture) error {
+	copied, err := clone(capture)
+	if err != nil {
+		return err
+	}
+	if copied.Id.IsZero() {
+		copied.Id = primitive.NewObjectID()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rawEventCaptures = append(d.rawEventCaptures, copied)
+	return nil
+}
@@ -0,0 +1,65 @@
+package inmemory
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (d *Database) GetUnfinishedReconciliationRun(
+	ctx context.Context,
+) (*model.ReconciliationRun, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var latest *model.ReconciliationRun
+	for _, run := range d.reconciliationRuns {
+		if run.Kind != model.ReconciliationSummaryKind || run.Status != model.ReconciliationRunning {
+			continue
+		}
+		if latest == nil || run.StartedAt > latest.StartedAt {
+			latest = run
+		}
+	}
+	if latest == nil {
+		return nil, &db.NotFoundError{
+			Key:     model.ReconciliationRunning,
+			Message: "no unfinished reconciliation run found",
+		}
+	}
+	return clone(latest)
+}
+
+func (d *Database) SaveReconciliationRun(
+	ctx context.Context, run *model.ReconciliationRun,
+) error {
+	copied, err := clone(run)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reconciliationRuns[run.Id] = copied
+	return nil
+}
+
+func (d *Database) SaveReconciliationDiscrepancy(
+	ctx context.Context, discrepancy *model.ReconciliationDiscrepancy,
+) error {
+	copied, err := clone(discrepancy)
+	if err != nil {
+		return err
+	}
+	if copied.Id.IsZero() {
+		copied.Id = primitive.NewObjectID()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reconciliationDiscrepancies = append(d.reconciliationDiscrepancies, copied)
+	return nil
+}
@@ -0,0 +1,73 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// isStuckDelegation matches the delegations in the given state since before
+// the given time
+func isStuckDelegation(
+	delegation *model.BTCDelegationDetails, state types.DelegationState, before int64,
+) bool {
+	return delegation.State == state && delegation.StateSince() < before
+}
+
+func (d *Database) CountStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64,
+) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var count uint64
+	for _, delegation := range d.delegations {
+		if isStuckDelegation(delegation, state, before) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (d *Database) FindStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var delegations []*model.BTCDelegationDetails
+	for _, stakingTxHash := range sortedKeys(d.delegations) {
+		if delegation := d.delegations[stakingTxHash]; isStuckDelegation(delegation, state, before) {
+			delegations = append(delegations, delegation)
+		}
+	}
+	// Missing state times sort first, as in Mongo
+	sort.SliceStable(delegations, func(i, j int) bool {
+		if delegations[i].StateUpdatedAt != delegations[j].StateUpdatedAt {
+			return delegations[i].StateUpdatedAt < delegations[j].StateUpdatedAt
+		}
+		return delegations[i].BTCDelegationCreatedBlock.Timestamp < delegations[j].BTCDelegationCreatedBlock.Timestamp
+	})
+	return cloneAll(limited(delegations, int64(limit)))
+}
+
+func (d *Database) SaveStuckDelegationReport(
+	ctx context.Context, report *model.StuckDelegationReport,
+) error {
+	copied, err := clone(report)
+	if err != nil {
+		return err
+	}
+	if copied.Id.IsZero() {
+		copied.Id = primitive.NewObjectID()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stuckDelegationReports = append(d.stuckDelegationReports, copied)
+	return nil
+}
@@ -0,0 +1,325 @@
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// timeLock is a timelock document along with the id Mongo generates for it
+type timeLock struct {
+	Id                     primitive.ObjectID `bson:"_id"`
+	model.TimeLockDocument `bson:",inline"`
+}
+
+// isObjectIdBefore tells whether the id sorts before the other
+func isObjectIdBefore(id, other primitive.ObjectID) bool {
+	return bytes.Compare(id[:], other[:]) < 0
+}
+
+func (d *Database) SaveNewTimeLockExpire(
+	ctx context.Context,
+	stakingTxHashHex string,
+	expireHeight uint32,
+	subState types.DelegationSubState,
+) error {
+	tl, err := clone(&timeLock{
+		Id:               primitive.NewObjectID(),
+		TimeLockDocument: *model.NewTimeLockDocument(stakingTxHashHex, expireHeight, subState),
+	})
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timeLocks = append(d.timeLocks, tl)
+	return nil
+}
+
+func (d *Database) FindExpiredDelegations(ctx context.Context, btcTipHeight, limit uint64) ([]model.TimeLockDocument, error) {
+	return limited(d.expiredTimeLocks(btcTipHeight), int64(limit)), nil
+}
+
+func (d *Database) ForEachExpiredDelegation(
+	ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error,
+) error {
+	// fn is run without the lock, as it may write the timelocks
+	for _, tlDoc := range d.expiredTimeLocks(btcTipHeight) {
+		if err := fn(tlDoc); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expiredTimeLocks returns the timelock documents expired at the BTC tip
+// height, oldest first, in the order of the expiry index the Mongo scan is
+// hinted to: by expire height, staking tx hash, then sub state
+func (d *Database) expiredTimeLocks(btcTipHeight uint64) []model.TimeLockDocument {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var expired []model.TimeLockDocument
+	for _, tl := range d.timeLocks {
+		if uint64(tl.ExpireHeight) <= btcTipHeight {
+			expired = append(expired, tl.TimeLockDocument)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		a, b := expired[i], expired[j]
+		if a.ExpireHeight != b.ExpireHeight {
+			return a.ExpireHeight < b.ExpireHeight
+		}
+		if a.StakingTxHashHex != b.StakingTxHashHex {
+			return a.StakingTxHashHex < b.StakingTxHashHex
+		}
+		return a.DelegationSubState < b.DelegationSubState
+	})
+	return expired
+}
+
+func (d *Database) GetExpiryBacklogStats(
+	ctx context.Context, btcTipHeight uint64,
+) ([]*model.ExpiryBacklogStats, error) {
+	bySubState := make(map[types.DelegationSubState]*model.ExpiryBacklogStats)
+	var stats []*model.ExpiryBacklogStats
+	for _, tl := range d.expiredTimeLocks(btcTipHeight) {
+		subStateStats, ok := bySubState[tl.DelegationSubState]
+		if !ok {
+			subStateStats = &model.ExpiryBacklogStats{
+				SubState: tl.DelegationSubState, OldestExpireHeight: tl.ExpireHeight,
+			}
+			bySubState[tl.DelegationSubState] = subStateStats
+			stats = append(stats, subStateStats)
+		}
+		subStateStats.Count++
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].SubState < stats[j].SubState })
+	return stats, nil
+}
+
+func (d *Database) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Once the delegation expired none of its timelocks is of use anymore
+	deletedCount := d.archiveTimeLocks(func(tl *timeLock) bool {
+		return tl.StakingTxHashHex == stakingTxHashHex
+	}, model.TimeLockArchiveReasonExpired)
+	if deletedCount == 0 {
+		return fmt.Errorf("no expired delegation found with stakingTxHashHex %v", stakingTxHashHex)
+	}
+	return nil
+}
+
+func (d *Database) FindOrphanedTimeLocks(
+	ctx context.Context, terminalStates []types.DelegationState, limit uint64,
+) ([]*model.OrphanedTimeLock, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var timeLocks []*model.OrphanedTimeLock
+	for _, tl := range d.timeLocks {
+		orphaned := &model.OrphanedTimeLock{Id: tl.Id, StakingTxHashHex: tl.StakingTxHashHex}
+		if delegation, ok := d.delegations[tl.StakingTxHashHex]; ok {
+			if !utils.Contains(terminalStates, delegation.State) {
+				continue
+			}
+			orphaned.DelegationState = delegation.State
+		}
+		timeLocks = append(timeLocks, orphaned)
+	}
+	return limited(timeLocks, int64(limit)), nil
+}
+
+func (d *Database) DeleteTimeLocks(ctx context.Context, ids []primitive.ObjectID) (uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.archiveTimeLocks(func(tl *timeLock) bool {
+		return utils.Contains(ids, tl.Id)
+	}, model.TimeLockArchiveReasonOrphaned), nil
+}
+
+// archiveTimeLocks moves the matching timelock documents to the archive and
+// returns their number
+func (d *Database) archiveTimeLocks(match func(tl *timeLock) bool, reason string) uint64 {
+	archivedAt := time.Now().Unix()
+	var archivedCount uint64
+	d.timeLocks = deleteWhere(d.timeLocks, func(tl *timeLock) bool {
+		if !match(tl) {
+			return false
+		}
+		d.archivedTimeLocks = append(d.archivedTimeLocks, &model.ArchivedTimeLockDocument{
+			Id:               tl.Id,
+			TimeLockDocument: tl.TimeLockDocument,
+			ArchiveReason:    reason,
+			ArchivedAt:       archivedAt,
+		})
+		archivedCount++
+		return true
+	})
+	return archivedCount
+}
+
+// GetTimeLocks returns the active timelock documents of the delegation
+func (d *Database) GetTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]model.TimeLockDocument, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var timeLocks []*timeLock
+	for _, tl := range d.timeLocks {
+		if tl.StakingTxHashHex == stakingTxHashHex {
+			timeLocks = append(timeLocks, tl)
+		}
+	}
+	sort.Slice(timeLocks, func(i, j int) bool { return isObjectIdBefore(timeLocks[i].Id, timeLocks[j].Id) })
+
+	var tlDocs []model.TimeLockDocument
+	for _, tl := range timeLocks {
+		tlDocs = append(tlDocs, tl.TimeLockDocument)
+	}
+	return tlDocs, nil
+}
+
+func (d *Database) GetArchivedTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.ArchivedTimeLockDocument, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var timeLocks []*model.ArchivedTimeLockDocument
+	for _, tl := range d.archivedTimeLocks {
+		if tl.StakingTxHashHex == stakingTxHashHex {
+			timeLocks = append(timeLocks, tl)
+		}
+	}
+	sort.Slice(timeLocks, func(i, j int) bool { return isObjectIdBefore(timeLocks[i].Id, timeLocks[j].Id) })
+	return cloneAll(timeLocks)
+}
+
+func (d *Database) FindTimeLocksByParamsVersion(
+	ctx context.Context, subStates []types.DelegationSubState, paramsVersion uint32,
+) ([]model.TimeLockDocument, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var timeLocks []model.TimeLockDocument
+	for _, tl := range d.timeLocks {
+		if !utils.Contains(subStates, tl.DelegationSubState) {
+			continue
+		}
+		delegation, ok := d.delegations[tl.StakingTxHashHex]
+		if !ok || delegation.ParamsVersion != paramsVersion ||
+			utils.Contains(types.TerminalStatesForTimeLock(), delegation.State) {
+			continue
+		}
+		timeLocks = append(timeLocks, tl.TimeLockDocument)
+	}
+	return timeLocks, nil
+}
+
+func (d *Database) UpdateTimeLockExpireHeight(
+	ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32,
+) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, tl := range d.timeLocks {
+		if tl.TimeLockDocument == *timeLock {
+			tl.ExpireHeight = newExpireHeight
+			return nil
+		}
+	}
+	return &db.NotFoundError{
+		Key:     timeLock.StakingTxHashHex,
+		Message: "timelock not found when updating its expire height",
+	}
+}
+
+type expiringTimeLocksPagination struct {
+	ExpireHeight uint32 `json:"expire_height"`
+	Id           string `json:"id"`
+}
+
+// GetTimeLocksExpiringBetween returns a page of the timelock documents
+// expiring in the BTC height range, inclusive, whose delegation is qualified
+// to become withdrawable, sorted by expire height
+func (d *Database) GetTimeLocksExpiringBetween(
+	ctx context.Context,
+	fromHeight, toHeight uint32,
+	paginationToken string,
+	limit int64,
+) (*db.DbResultMap[*model.ExpiringTimeLock], error) {
+	var after *model.ExpiringTimeLock
+	if paginationToken != "" {
+		var pagination expiringTimeLocksPagination
+		if err := decodePaginationToken(paginationToken, &pagination); err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(pagination.Id)
+		if err != nil {
+			return nil, &db.InvalidPaginationTokenError{Message: "invalid pagination token"}
+		}
+		after = &model.ExpiringTimeLock{Id: id, ExpireHeight: pagination.ExpireHeight}
+	}
+	isBefore := func(a, b *model.ExpiringTimeLock) bool {
+		if a.ExpireHeight != b.ExpireHeight {
+			return a.ExpireHeight < b.ExpireHeight
+		}
+		return isObjectIdBefore(a.Id, b.Id)
+	}
+	qualifiedStates := types.QualifiedStatesForWithdrawable()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var timeLocks []*model.ExpiringTimeLock
+	for _, tl := range d.timeLocks {
+		if tl.ExpireHeight < fromHeight || tl.ExpireHeight > toHeight {
+			continue
+		}
+		delegation, ok := d.delegations[tl.StakingTxHashHex]
+		if !ok || !utils.Contains(qualifiedStates, delegation.State) {
+			continue
+		}
+		expiring := &model.ExpiringTimeLock{
+			Id:                 tl.Id,
+			StakingTxHashHex:   tl.StakingTxHashHex,
+			ExpireHeight:       tl.ExpireHeight,
+			DelegationSubState: tl.DelegationSubState,
+			StakerBtcPkHex:     delegation.StakerBtcPkHex,
+			StakingAmount:      delegation.StakingAmount,
+		}
+		if after == nil || isBefore(after, expiring) {
+			timeLocks = append(timeLocks, expiring)
+		}
+	}
+	sort.Slice(timeLocks, func(i, j int) bool { return isBefore(timeLocks[i], timeLocks[j]) })
+
+	// One more timelock than the limit tells whether there is a next page
+	return toResultMapWithPaginationToken(
+		limited(timeLocks, limit+1), limit,
+		func(timeLock *model.ExpiringTimeLock) any {
+			return expiringTimeLocksPagination{
+				ExpireHeight: timeLock.ExpireHeight,
+				Id:           timeLock.Id.Hex(),
+			}
+		},
+	)
+}
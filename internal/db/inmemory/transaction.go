@@ -0,0 +1,138 @@
+package inmemory
+
+import (
+	"context"
+	"maps"
+)
+
+type transactionKey struct{}
+
+// RunInTransaction runs fn with the database as it was restored if fn fails
+// or panics, as a transaction aborted. Unlike Mongo, the writes of the other
+// callers during the transaction are undone along with it, the tests running
+// the writers of a transaction alone. A call made within a transaction runs
+// in it.
+func (d *Database) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(transactionKey{}) != nil {
+		return fn(ctx)
+	}
+
+	d.mu.Lock()
+	snapshot, err := d.collections.copy()
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		d.mu.Lock()
+		d.collections = *snapshot
+		d.mu.Unlock()
+	}()
+
+	if err := fn(context.WithValue(ctx, transactionKey{}, true)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// copy returns a copy of the documents of the collections
+func (c *collections) copy() (*collections, error) {
+	copied := &collections{
+		outboxSequences: maps.Clone(c.outboxSequences),
+	}
+	var err error
+	if copied.finalityProviders, err = cloneMap(c.finalityProviders); err != nil {
+		return nil, err
+	}
+	if copied.votingPowerChanges, err = cloneAll(c.votingPowerChanges); err != nil {
+		return nil, err
+	}
+	if copied.stakingParams, err = cloneMap(c.stakingParams); err != nil {
+		return nil, err
+	}
+	if copied.checkpointParams, err = cloneDoc(c.checkpointParams); err != nil {
+		return nil, err
+	}
+	if copied.delegations, err = cloneMap(c.delegations); err != nil {
+		return nil, err
+	}
+	if copied.archivedDelegations, err = cloneMap(c.archivedDelegations); err != nil {
+		return nil, err
+	}
+	if copied.stateTransitions, err = cloneAll(c.stateTransitions); err != nil {
+		return nil, err
+	}
+	if copied.timeLocks, err = cloneAll(c.timeLocks); err != nil {
+		return nil, err
+	}
+	if copied.archivedTimeLocks, err = cloneAll(c.archivedTimeLocks); err != nil {
+		return nil, err
+	}
+	if copied.lastProcessedHeight, err = cloneDoc(c.lastProcessedHeight); err != nil {
+		return nil, err
+	}
+	if copied.processedHeights, err = cloneAll(c.processedHeights); err != nil {
+		return nil, err
+	}
+	if copied.btcHeaders, err = cloneMap(c.btcHeaders); err != nil {
+		return nil, err
+	}
+	if copied.btcDerivedChanges, err = cloneAll(c.btcDerivedChanges); err != nil {
+		return nil, err
+	}
+	if copied.outboxEvents, err = cloneAll(c.outboxEvents); err != nil {
+		return nil, err
+	}
+	if copied.reconciliationRuns, err = cloneMap(c.reconciliationRuns); err != nil {
+		return nil, err
+	}
+	if copied.reconciliationDiscrepancies, err = cloneAll(c.reconciliationDiscrepancies); err != nil {
+		return nil, err
+	}
+	if copied.stuckDelegationReports, err = cloneAll(c.stuckDelegationReports); err != nil {
+		return nil, err
+	}
+	if copied.rawEventCaptures, err = cloneAll(c.rawEventCaptures); err != nil {
+		return nil, err
+	}
+	if copied.deadLetters, err = cloneMap(c.deadLetters); err != nil {
+		return nil, err
+	}
+	if copied.globalStats, err = cloneDoc(c.globalStats); err != nil {
+		return nil, err
+	}
+	if copied.bootstrap, err = cloneDoc(c.bootstrap); err != nil {
+		return nil, err
+	}
+	if copied.locks, err = cloneMap(c.locks); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// cloneDoc returns a copy of the document, if any
+func cloneDoc[T any](doc *T) (*T, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	return clone(doc)
+}
+
+// cloneMap returns copies of the documents by key
+func cloneMap[K comparable, V any](docs map[K]*V) (map[K]*V, error) {
+	copied := make(map[K]*V, len(docs))
+	for key, doc := range docs {
+		c, err := clone(doc)
+		if err != nil {
+			return nil, err
+		}
+		copied[key] = c
+	}
+	return copied, nil
+}
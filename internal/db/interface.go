@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type DbInterface interface {
@@ -15,6 +17,16 @@ type DbInterface interface {
 	 * @return An error if the operation failed
 	 */
 	Ping(ctx context.Context) error
+	/**
+	 * RunInTransaction runs fn in a transaction, the writes of the methods
+	 * called with the context given to fn being applied together once fn
+	 * returns nil, and none of them otherwise. fn may be run again on a
+	 * transient error. The transactions run within one are part of it.
+	 * @param ctx The context
+	 * @param fn The function writing in the transaction
+	 * @return The error of fn, or an error if the transaction failed
+	 */
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 	/**
 	 * SaveNewFinalityProvider saves a new finality provider to the database.
 	 * If the finality provider already exists, DuplicateKeyError will be returned.
@@ -55,6 +67,60 @@ type DbInterface interface {
 	GetFinalityProviderByBtcPk(
 		ctx context.Context, btcPk string,
 	) (*model.FinalityProviderDetails, error)
+	/**
+	 * GetFinalityProvidersByBsnId retrieves all finality providers registered
+	 * for the given BSN (consumer chain). Use model.BabylonBsnId to retrieve
+	 * the finality providers of the Babylon chain itself.
+	 * @param ctx The context
+	 * @param bsnId The BSN id
+	 * @return The finality providers or an error
+	 */
+	GetFinalityProvidersByBsnId(
+		ctx context.Context, bsnId string,
+	) ([]*model.FinalityProviderDetails, error)
+	/**
+	 * GetFinalityProviders retrieves a page of the finality providers matching
+	 * the filter, sorted by BTC public key.
+	 * If the pagination token is invalid, an InvalidPaginationTokenError will
+	 * be returned.
+	 * @param ctx The context
+	 * @param filter The optional state, BSN id and moniker search
+	 * @param paginationToken The token of the page, empty for the first one
+	 * @param limit The maximum number of finality providers of the page
+	 * @return The page of finality providers with the token of the next one or an error
+	 */
+	GetFinalityProviders(
+		ctx context.Context, filter FinalityProvidersFilter, paginationToken string, limit int64,
+	) (*DbResultMap[*model.FinalityProviderDetails], error)
+	/**
+	 * GetFinalityProviderStats aggregates the active delegations of the
+	 * finality provider.
+	 * @param ctx The context
+	 * @param btcPk The BTC public key of the finality provider
+	 * @return The stats of the finality provider or an error
+	 */
+	GetFinalityProviderStats(
+		ctx context.Context, btcPk string,
+	) (*model.FinalityProviderStats, error)
+	/**
+	 * GetFinalityProviderStakeDistribution aggregates the active stake of
+	 * each finality provider.
+	 * @param ctx The context
+	 * @param limit The number of finality providers holding the most active
+	 * stake to return
+	 * @return The top finality providers by active stake along with the
+	 * total active stake of all of them, or an error
+	 */
+	GetFinalityProviderStakeDistribution(
+		ctx context.Context, limit int64,
+	) (*model.FinalityProviderStakeDistribution, error)
+	/**
+	 * CountFinalityProvidersByState counts the finality providers in each
+	 * state.
+	 * @param ctx The context
+	 * @return The number of finality providers by state or an error
+	 */
+	CountFinalityProvidersByState(ctx context.Context) (map[string]uint64, error)
 	/**
 	 * SaveStakingParams saves the staking parameters to the database.
 	 * @param ctx The context
@@ -65,13 +131,32 @@ type DbInterface interface {
 	SaveStakingParams(
 		ctx context.Context, version uint32, params *bbnclient.StakingParams,
 	) error
+	/**
+	 * ReplaceStakingParams replaces the stored staking parameters of the
+	 * version, e.g. after a params fix landed on the BBN chain.
+	 * If the version is not stored, NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param version The version of the staking parameters
+	 * @param params The new staking parameters
+	 * @return An error if the operation failed
+	 */
+	ReplaceStakingParams(
+		ctx context.Context, version uint32, params *bbnclient.StakingParams,
+	) error
 	/**
 	 * GetStakingParams retrieves the staking parameters by the version.
+	 * If the version does not exist, a NotFoundError will be returned.
 	 * @param ctx The context
 	 * @param version The version of the staking parameters
 	 * @return The staking parameters or an error
 	 */
 	GetStakingParams(ctx context.Context, version uint32) (*bbnclient.StakingParams, error)
+	/**
+	 * GetAllStakingParams retrieves all the stored staking parameters.
+	 * @param ctx The context
+	 * @return The staking parameters keyed by version or an error
+	 */
+	GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error)
 	/**
 	 * SaveCheckpointParams saves the checkpoint parameters to the database.
 	 * @param ctx The context
@@ -81,6 +166,13 @@ type DbInterface interface {
 	SaveCheckpointParams(
 		ctx context.Context, params *bbnclient.CheckpointParams,
 	) error
+	/**
+	 * GetCheckpointParams retrieves the stored checkpoint parameters.
+	 * If none are stored yet, NotFoundError will be returned.
+	 * @param ctx The context
+	 * @return The checkpoint parameters or an error
+	 */
+	GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error)
 	/**
 	 * SaveNewBTCDelegation saves a new BTC delegation to the database.
 	 * If the BTC delegation already exists, DuplicateKeyError will be returned.
@@ -92,10 +184,20 @@ type DbInterface interface {
 		ctx context.Context, delegationDoc *model.BTCDelegationDetails,
 	) error
 	/**
-	 * SaveBTCDelegationStateUpdate saves a BTC delegation state update to the database.
+	 * UpdateBTCDelegationState moves a BTC delegation in one of the qualified
+	 * previous states to the new state, along the delegation state machine.
+	 * The qualified previous states the state machine does not allow to move
+	 * to the new state are ignored.
 	 * @param ctx The context
-	 * @param delegationDoc The BTC delegation details
-	 * @return An error if the operation failed
+	 * @param stakingTxHash The staking tx hash
+	 * @param qualifiedPreviousStates The states the delegation can be moved from
+	 * @param newState The new state
+	 * @param newSubState The new sub state, if any
+	 * @return An InvalidStateTransitionError if none of the qualified previous
+	 * states can move to the new state, a StateTransitionError if the
+	 * delegation is in a state not qualified or not allowed to move to the new
+	 * state, a NotFoundError if the delegation is not found, or any other
+	 * error if the operation failed
 	 */
 	UpdateBTCDelegationState(
 		ctx context.Context,
@@ -104,6 +206,55 @@ type DbInterface interface {
 		newState types.DelegationState,
 		newSubState *types.DelegationSubState,
 	) error
+	/**
+	 * ApplyBTCDelegationStateChange applies the state update as
+	 * UpdateBTCDelegationState and, in the same transaction, saves the
+	 * records of the change given: its journal entry, its transition and its
+	 * outbox event, the latter left as is if saved already. Nothing is saved
+	 * if the update does not apply or any record fails to be saved.
+	 * @param ctx The context
+	 * @param change The state update along with the records of the change
+	 * @return An error typed as the one of UpdateBTCDelegationState, or any
+	 * other error if the operation failed
+	 */
+	ApplyBTCDelegationStateChange(ctx context.Context, change DelegationStateChange) error
+	/**
+	 * OverrideBTCDelegationState moves a BTC delegation in the current state
+	 * to the new state, whether or not the delegation state machine allows it.
+	 * It is meant for the manual overrides only.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param currentState The state of the delegation the override was decided on
+	 * @param newState The new state
+	 * @param newSubState The new sub state, if any
+	 * @return A NotFoundError if the delegation is not found or no longer in
+	 * the current state, or any other error if the operation failed
+	 */
+	OverrideBTCDelegationState(
+		ctx context.Context,
+		stakingTxHash string,
+		currentState types.DelegationState,
+		newState types.DelegationState,
+		newSubState *types.DelegationSubState,
+	) error
+	/**
+	 * UpdateBTCDelegationSubState moves a BTC delegation in the given state
+	 * to the new sub state, without leaving the state, if the sub state
+	 * transitions of the state allow it from its current sub state.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param state The state of the delegation
+	 * @param newSubState The new sub state
+	 * @return A NotFoundError if the delegation is not found, not in the state
+	 * or not in a sub state that can move to the new one, or any other error
+	 * if the operation failed
+	 */
+	UpdateBTCDelegationSubState(
+		ctx context.Context,
+		stakingTxHash string,
+		state types.DelegationState,
+		newSubState types.DelegationSubState,
+	) error
 	/**
 	 * SaveBTCDelegationUnbondingCovenantSignature saves a BTC delegation
 	 * unbonding covenant signature to the database.
@@ -116,6 +267,42 @@ type DbInterface interface {
 	SaveBTCDelegationUnbondingCovenantSignature(
 		ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
 	) error
+	/**
+	 * SetCovenantSignatureVerified records whether the unbonding signature of
+	 * the covenant member was found valid.
+	 * If the delegation has no signature of the covenant member, a
+	 * NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @param covenantBtcPkHex The covenant BTC public key
+	 * @param verified Whether the signature is valid
+	 * @return An error if the operation failed
+	 */
+	SetCovenantSignatureVerified(
+		ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+	) error
+	/**
+	 * BulkUpdateDelegationStates applies the state updates of a batch, e.g.
+	 * of a BBN block, in a single write. Each update is applied as by
+	 * UpdateBTCDelegationState, the updates which do not apply being skipped.
+	 * @param ctx The context
+	 * @param updates The state updates
+	 * @return A BulkWriteError with the updates which failed and the error of
+	 * each, typed as the one of UpdateBTCDelegationState, or any other error
+	 * if the write failed as a whole
+	 */
+	BulkUpdateDelegationStates(ctx context.Context, updates []DelegationStateUpdate) error
+	/**
+	 * BulkSaveCovenantSignatures saves the unbonding covenant signatures of a
+	 * batch in a single write. A signature already saved is skipped.
+	 * @param ctx The context
+	 * @param sigs The covenant signatures
+	 * @return A BulkWriteError with the signatures which failed, with a
+	 * NotFoundError if the delegation is not found or a DuplicateKeyError if
+	 * another signature of the covenant member is saved, or any other error
+	 * if the write failed as a whole
+	 */
+	BulkSaveCovenantSignatures(ctx context.Context, sigs []CovenantSigRecord) error
 	/**
 	 * GetBTCDelegationState retrieves the BTC delegation state.
 	 * @param ctx The context
@@ -143,12 +330,35 @@ type DbInterface interface {
 	GetBTCDelegationByStakingTxHash(
 		ctx context.Context, stakingTxHash string,
 	) (*model.BTCDelegationDetails, error)
+	/**
+	 * GetBTCDelegationSummaryByStakingTxHash retrieves the BTC delegation by
+	 * the staking tx hash without its transactions.
+	 * If the BTC delegation does not exist, a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param stakingTxHash The staking tx hash
+	 * @return The BTC delegation summary or an error
+	 */
+	GetBTCDelegationSummaryByStakingTxHash(
+		ctx context.Context, stakingTxHash string,
+	) (*model.BTCDelegationSummary, error)
+	/**
+	 * GetBTCDelegationsByStakingTxHashes retrieves the BTC delegations of the
+	 * staking tx hashes in a single read.
+	 * @param ctx The context
+	 * @param stakingTxHashes The staking tx hashes
+	 * @return The BTC delegations found keyed by staking tx hash, the ones not
+	 * found missing, or an error
+	 */
+	GetBTCDelegationsByStakingTxHashes(
+		ctx context.Context, stakingTxHashes []string,
+	) (map[string]*model.BTCDelegationDetails, error)
 	/**
 	 * UpdateDelegationsStateByFinalityProvider updates the BTC delegation state by the finality provider public key.
+	 * Only the delegations in a state qualified for the new state are updated,
+	 * the others being left as they are.
 	 * @param ctx The context
 	 * @param fpBtcPkHex The finality provider public key
 	 * @param newState The new state
-	 * @param qualifiedStates The qualified states
 	 * @return An error if the operation failed
 	 */
 	UpdateDelegationsStateByFinalityProvider(
@@ -184,12 +394,153 @@ type DbInterface interface {
 	 */
 	FindExpiredDelegations(ctx context.Context, btcTipHeight, limit uint64) ([]model.TimeLockDocument, error)
 	/**
-	 * DeleteExpiredDelegation deletes an expired delegation.
+	 * ForEachExpiredDelegation calls fn with every expired delegation as it
+	 * is read, without loading them all first.
+	 * @param ctx The context, its cancellation stopping the iteration
+	 * @param btcTipHeight The BTC tip height
+	 * @param fn The function called with every expired delegation, its error
+	 * stopping the iteration
+	 * @return The error of fn as is, the error of the context if cancelled, or
+	 * any other error if the operation failed
+	 */
+	ForEachExpiredDelegation(
+		ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error,
+	) error
+	/**
+	 * GetExpiryBacklogStats aggregates the number and the oldest expire
+	 * height of the expired timelock documents of each sub state.
+	 * @param ctx The context
+	 * @param btcTipHeight The BTC tip height
+	 * @return The stats of each sub state holding expired timelocks or an error
+	 */
+	GetExpiryBacklogStats(ctx context.Context, btcTipHeight uint64) ([]*model.ExpiryBacklogStats, error)
+	/**
+	 * DeleteExpiredDelegation deletes an expired delegation. Its timelock
+	 * documents are moved to the archive.
 	 * @param ctx The context
 	 * @param id The ID of the expired delegation
 	 * @return An error if the operation failed
 	 */
 	DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error
+	/**
+	 * FindOrphanedTimeLocks finds timelock documents whose delegation is in
+	 * one of the terminal states or does not exist.
+	 * @param ctx The context
+	 * @param terminalStates The states in which a delegation can no longer expire
+	 * @param limit The maximum number of timelock documents to return
+	 * @return The orphaned timelock documents or an error
+	 */
+	FindOrphanedTimeLocks(
+		ctx context.Context, terminalStates []types.DelegationState, limit uint64,
+	) ([]*model.OrphanedTimeLock, error)
+	/**
+	 * DeleteTimeLocks deletes timelock documents by ID, moving them to the
+	 * archive.
+	 * @param ctx The context
+	 * @param ids The IDs of the timelock documents
+	 * @return The number of deleted documents or an error
+	 */
+	DeleteTimeLocks(ctx context.Context, ids []primitive.ObjectID) (uint64, error)
+	/**
+	 * GetTimeLocks retrieves the active timelock documents of a delegation.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash hex
+	 * @return The timelock documents or an error
+	 */
+	GetTimeLocks(ctx context.Context, stakingTxHashHex string) ([]model.TimeLockDocument, error)
+	/**
+	 * GetArchivedTimeLocks retrieves the archived timelock documents of a
+	 * delegation.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash hex
+	 * @return The archived timelock documents or an error
+	 */
+	GetArchivedTimeLocks(ctx context.Context, stakingTxHashHex string) ([]*model.ArchivedTimeLockDocument, error)
+	/**
+	 * GetTimeLocksExpiringBetween retrieves a page of the timelock documents
+	 * expiring in the BTC height range, whose delegation is qualified to
+	 * become withdrawable, sorted by expire height.
+	 * If the pagination token is invalid, InvalidPaginationTokenError will be returned.
+	 * @param ctx The context
+	 * @param fromHeight The first BTC height of the range
+	 * @param toHeight The last BTC height of the range
+	 * @param paginationToken The token of the page, empty for the first one
+	 * @param limit The maximum number of timelocks of the page
+	 * @return The timelocks joined with their delegation or an error
+	 */
+	GetTimeLocksExpiringBetween(
+		ctx context.Context, fromHeight, toHeight uint32, paginationToken string, limit int64,
+	) (*DbResultMap[*model.ExpiringTimeLock], error)
+	/**
+	 * DeleteBTCDelegation deletes the BTC delegation along with its state
+	 * history and timelocks, so that it can be indexed again from scratch.
+	 * Its outbox events are kept. If the BTC delegation does not exist,
+	 * NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash hex
+	 * @return An error if the operation failed
+	 */
+	DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error
+	/**
+	 * SaveDelegationStateTransition records a change of a delegation's state.
+	 * @param ctx The context
+	 * @param transition The state transition
+	 * @return An error if the operation failed
+	 */
+	SaveDelegationStateTransition(ctx context.Context, transition *model.DelegationStateTransition) error
+	/**
+	 * GetDelegationStateTransitions retrieves the state transitions of a
+	 * delegation in the order they were recorded.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash hex
+	 * @return The state transitions or an error
+	 */
+	GetDelegationStateTransitions(
+		ctx context.Context, stakingTxHashHex string,
+	) ([]*model.DelegationStateTransition, error)
+	/**
+	 * AggregateStateTransitions aggregates the state transitions recorded in
+	 * the time range by period and state reached, sorted by period. The
+	 * aggregates are passed to the visitor as they are read, so that a long
+	 * range is not held in memory.
+	 * @param ctx The context
+	 * @param fromTime The start of the range, inclusive, in epoch seconds
+	 * @param toTime The end of the range, exclusive, in epoch seconds
+	 * @param periodUnit The unit of the periods: day, week or month
+	 * @param visit The function called with each aggregate, whose error stops
+	 * the aggregation
+	 * @return An error if the operation failed
+	 */
+	AggregateStateTransitions(
+		ctx context.Context,
+		fromTime, toTime int64,
+		periodUnit string,
+		visit func(stats *model.StateTransitionPeriodStats) error,
+	) error
+	/**
+	 * FindTimeLocksByParamsVersion retrieves the timelock documents of the given
+	 * sub states whose delegation uses the given staking params version and
+	 * can still expire, leaving out those of the delegations in a terminal
+	 * state.
+	 * @param ctx The context
+	 * @param subStates The sub states of the timelock documents
+	 * @param paramsVersion The staking params version of the delegations
+	 * @return The timelock documents or an error
+	 */
+	FindTimeLocksByParamsVersion(
+		ctx context.Context, subStates []types.DelegationSubState, paramsVersion uint32,
+	) ([]model.TimeLockDocument, error)
+	/**
+	 * UpdateTimeLockExpireHeight updates the expire height of a timelock document.
+	 * If the timelock document does not exist, NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param timeLock The timelock document with its current expire height
+	 * @param newExpireHeight The new expire height
+	 * @return An error if the operation failed
+	 */
+	UpdateTimeLockExpireHeight(
+		ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32,
+	) error
 	/**
 	 * GetLastProcessedBbnHeight retrieves the last processed BBN height.
 	 * @param ctx The context
@@ -197,12 +548,55 @@ type DbInterface interface {
 	 */
 	GetLastProcessedBbnHeight(ctx context.Context) (uint64, error)
 	/**
-	 * UpdateLastProcessedBbnHeight updates the last processed BBN height.
+	 * GetLastProcessedBbnBlock retrieves the last processed BBN height along
+	 * with its block hash and halt reason.
+	 * @param ctx The context
+	 * @return The last processed block or an error
+	 */
+	GetLastProcessedBbnBlock(ctx context.Context) (*model.LastProcessedHeight, error)
+	/**
+	 * UpdateLastProcessedBbnHeight updates the last processed BBN height and
+	 * clears the processing marker in the same write.
 	 * @param ctx The context
 	 * @param height The last processed height
+	 * @param blockHash The hash of the block at the last processed height
+	 * @return An error if the operation failed
+	 */
+	UpdateLastProcessedBbnHeight(ctx context.Context, height uint64, blockHash string) error
+	/**
+	 * HaltBbnProcessing halts the BBN block processing until an explicit resync.
+	 * @param ctx The context
+	 * @param reason The reason for halting
 	 * @return An error if the operation failed
 	 */
-	UpdateLastProcessedBbnHeight(ctx context.Context, height uint64) error
+	HaltBbnProcessing(ctx context.Context, reason string) error
+	/**
+	 * ResyncLastProcessedBbnHeight resets the last processed BBN height, clears
+	 * the stored block hash and processing marker, and resumes a halted BBN
+	 * block processing.
+	 * @param ctx The context
+	 * @param height The height to resume processing after
+	 * @return An error if the operation failed
+	 */
+	ResyncLastProcessedBbnHeight(ctx context.Context, height uint64) error
+	/**
+	 * StartBbnBlockProcessing writes the processing marker of the BBN block
+	 * about to be processed, replacing any previous marker.
+	 * @param ctx The context
+	 * @param marker The processing marker
+	 * @return An error if the operation failed
+	 */
+	StartBbnBlockProcessing(ctx context.Context, marker *model.BbnProcessingMarker) error
+	/**
+	 * MarkBbnEventProcessed records an event of the BBN block under processing
+	 * as applied. If no block is being processed at the height, NotFoundError
+	 * will be returned.
+	 * @param ctx The context
+	 * @param height The height of the block under processing
+	 * @param eventIndex The index of the event in the block
+	 * @return An error if the operation failed
+	 */
+	MarkBbnEventProcessed(ctx context.Context, height uint64, eventIndex int) error
 	/**
 	 * SaveBTCDelegationSlashingTxHex saves the BTC delegation slashing tx hex.
 	 * @param ctx The context
@@ -238,4 +632,437 @@ type DbInterface interface {
 	 * @return The BTC delegations or an error
 	 */
 	GetBTCDelegationsByStates(ctx context.Context, states []types.DelegationState) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * GetBTCDelegationsAfter retrieves the BTC delegations matching the
+	 * filter whose staking tx hash is greater than the given one, sorted by
+	 * staking tx hash.
+	 * @param ctx The context
+	 * @param filter The optional states and finality providers
+	 * @param stakingTxHashHex The staking tx hash to start after
+	 * @param limit The maximum number of delegations to return
+	 * @return The BTC delegations or an error
+	 */
+	GetBTCDelegationsAfter(
+		ctx context.Context, filter BTCDelegationsFilter, stakingTxHashHex string, limit uint64,
+	) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * SampleBTCDelegations retrieves random BTC delegations matching the
+	 * filter.
+	 * @param ctx The context
+	 * @param filter The optional states and finality providers
+	 * @param size The maximum number of delegations to return
+	 * @return The BTC delegations or an error
+	 */
+	SampleBTCDelegations(
+		ctx context.Context, filter BTCDelegationsFilter, size uint64,
+	) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * GetBTCDelegationsCreatedBetween retrieves the BTC delegations created in
+	 * the BBN blocks of the given height range, sorted by creation height.
+	 * @param ctx The context
+	 * @param fromHeight The first BBN height of the range
+	 * @param toHeight The last BBN height of the range
+	 * @return The BTC delegations or an error
+	 */
+	GetBTCDelegationsCreatedBetween(
+		ctx context.Context, fromHeight, toHeight int64,
+	) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * GetStakerDelegations retrieves a page of the BTC delegations of a
+	 * staker, newest first.
+	 * If the pagination token is invalid, an InvalidPaginationTokenError will
+	 * be returned.
+	 * @param ctx The context
+	 * @param filter The staker and the optional state of the delegations
+	 * @param paginationToken The token of the page, empty for the first one
+	 * @param limit The maximum number of delegations of the page
+	 * @return The page of BTC delegations with the token of the next one or an error
+	 */
+	GetStakerDelegations(
+		ctx context.Context, filter StakerDelegationsFilter, paginationToken string, limit int64,
+	) (*DbResultMap[*model.BTCDelegationDetails], error)
+	/**
+	 * GetStakerDelegationSummaries retrieves a page of the BTC delegations of
+	 * a staker without their transactions, newest first.
+	 * If the pagination token is invalid, an InvalidPaginationTokenError will
+	 * be returned.
+	 * @param ctx The context
+	 * @param filter The staker and the optional state of the delegations
+	 * @param paginationToken The token of the page, empty for the first one
+	 * @param limit The maximum number of delegations of the page
+	 * @return The page of BTC delegation summaries with the token of the next one or an error
+	 */
+	GetStakerDelegationSummaries(
+		ctx context.Context, filter StakerDelegationsFilter, paginationToken string, limit int64,
+	) (*DbResultMap[*model.BTCDelegationSummary], error)
+	/**
+	 * SaveFinalityProviderVotingPowerChange appends a change of a finality
+	 * provider's active set membership or voting power.
+	 * @param ctx The context
+	 * @param change The voting power change
+	 * @return An error if the operation failed
+	 */
+	SaveFinalityProviderVotingPowerChange(
+		ctx context.Context, change *model.FinalityProviderVotingPowerChange,
+	) error
+	/**
+	 * GetLatestFinalityProviderVotingPowerChanges retrieves the most recent
+	 * voting power change of each of the finality providers in one query.
+	 * Finality providers with no change recorded yet are not in the result.
+	 * @param ctx The context
+	 * @param fpBtcPks The finality provider BTC public keys
+	 * @return The latest voting power changes by finality provider BTC public key or an error
+	 */
+	GetLatestFinalityProviderVotingPowerChanges(
+		ctx context.Context, fpBtcPks []string,
+	) (map[string]*model.FinalityProviderVotingPowerChange, error)
+	/**
+	 * GetFinalityProviderActivationPeriods retrieves the BBN height intervals
+	 * during which the finality provider was part of the active set, in
+	 * ascending order.
+	 * @param ctx The context
+	 * @param fpBtcPk The finality provider BTC public key
+	 * @return The activation periods or an error
+	 */
+	GetFinalityProviderActivationPeriods(
+		ctx context.Context, fpBtcPk string,
+	) ([]*model.FinalityProviderActivationPeriod, error)
+	/**
+	 * SaveBTCHeader saves the processed BTC header, replacing the header
+	 * previously stored at the same height.
+	 * @param ctx The context
+	 * @param header The BTC header
+	 * @return An error if the operation failed
+	 */
+	SaveBTCHeader(ctx context.Context, header *model.BTCHeader) error
+	/**
+	 * GetBTCHeaderByHeight retrieves the processed BTC header at the given height.
+	 * If the header does not exist, a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param height The BTC height
+	 * @return The BTC header or an error
+	 */
+	GetBTCHeaderByHeight(ctx context.Context, height uint64) (*model.BTCHeader, error)
+	/**
+	 * GetLatestBTCHeader retrieves the processed BTC header with the highest height.
+	 * If no header has been processed yet, a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @return The BTC header or an error
+	 */
+	GetLatestBTCHeader(ctx context.Context) (*model.BTCHeader, error)
+	/**
+	 * DeleteBTCHeadersAbove deletes the processed BTC headers above the given height.
+	 * @param ctx The context
+	 * @param height The BTC height
+	 * @return An error if the operation failed
+	 */
+	DeleteBTCHeadersAbove(ctx context.Context, height uint64) error
+	/**
+	 * DeleteBTCHeadersBelow deletes the processed BTC headers below the given height.
+	 * @param ctx The context
+	 * @param height The BTC height
+	 * @return An error if the operation failed
+	 */
+	DeleteBTCHeadersBelow(ctx context.Context, height uint64) error
+	/**
+	 * SaveBTCDerivedChange records a change of a delegation derived from a BTC
+	 * block so that it can be rolled back if the block is reorged out.
+	 * @param ctx The context
+	 * @param change The BTC derived change
+	 * @return An error if the operation failed
+	 */
+	SaveBTCDerivedChange(ctx context.Context, change *model.BTCDerivedChange) error
+	/**
+	 * RollbackBTCDerivedChanges undoes all BTC derived changes recorded above
+	 * the fork height, most recent first, and deletes them. Each change is
+	 * undone and deleted at once, the changes undone before a failure staying
+	 * undone.
+	 * @param ctx The context
+	 * @param forkHeight The height of the last BTC block shared with the new chain
+	 * @return The rolled back changes or an error
+	 */
+	RollbackBTCDerivedChanges(
+		ctx context.Context, forkHeight uint64,
+	) ([]*model.BTCDerivedChange, error)
+	/**
+	 * DeleteBTCDerivedChangesBelow deletes the BTC derived changes recorded
+	 * below the given height, which can no longer be rolled back.
+	 * @param ctx The context
+	 * @param height The BTC height
+	 * @return An error if the operation failed
+	 */
+	DeleteBTCDerivedChangesBelow(ctx context.Context, height uint64) error
+	/**
+	 * GetUnfinishedReconciliationRun retrieves the latest reconciliation run
+	 * that has not completed yet.
+	 * @param ctx The context
+	 * @return The reconciliation run or an error
+	 */
+	GetUnfinishedReconciliationRun(ctx context.Context) (*model.ReconciliationRun, error)
+	/**
+	 * SaveReconciliationRun upserts the summary of a reconciliation run.
+	 * @param ctx The context
+	 * @param run The reconciliation run
+	 * @return An error if the operation failed
+	 */
+	SaveReconciliationRun(ctx context.Context, run *model.ReconciliationRun) error
+	/**
+	 * SaveReconciliationDiscrepancy saves a discrepancy found by a
+	 * reconciliation run.
+	 * @param ctx The context
+	 * @param discrepancy The discrepancy
+	 * @return An error if the operation failed
+	 */
+	SaveReconciliationDiscrepancy(
+		ctx context.Context, discrepancy *model.ReconciliationDiscrepancy,
+	) error
+	/**
+	 * MarkBbnHeightProcessed records that the BBN height has been processed.
+	 * @param ctx The context
+	 * @param height The processed BBN height
+	 * @return An error if the operation failed
+	 */
+	MarkBbnHeightProcessed(ctx context.Context, height uint64) error
+	/**
+	 * GetLowestProcessedBbnHeight retrieves the lowest recorded processed BBN
+	 * height. If no height is recorded, a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @return The lowest processed BBN height or an error
+	 */
+	GetLowestProcessedBbnHeight(ctx context.Context) (uint64, error)
+	/**
+	 * DetectProcessedHeightGaps compares the expected BBN heights in the given
+	 * range with the recorded processed heights.
+	 * @param ctx The context
+	 * @param from The first expected height
+	 * @param to The last expected height
+	 * @return The ranges of heights that were not processed or an error
+	 */
+	DetectProcessedHeightGaps(
+		ctx context.Context, from, to uint64,
+	) ([]*model.BbnHeightRange, error)
+	/**
+	 * CountStuckDelegations counts the delegations that have been in the given
+	 * state since before the given time.
+	 * @param ctx The context
+	 * @param state The delegation state
+	 * @param before The time in epoch seconds
+	 * @return The number of delegations or an error
+	 */
+	CountStuckDelegations(
+		ctx context.Context, state types.DelegationState, before int64,
+	) (uint64, error)
+	/**
+	 * FindStuckDelegations retrieves the delegations that have been in the
+	 * given state since before the given time, the longest stuck first.
+	 * @param ctx The context
+	 * @param state The delegation state
+	 * @param before The time in epoch seconds
+	 * @param limit The maximum number of delegations to return
+	 * @return The delegations or an error
+	 */
+	FindStuckDelegations(
+		ctx context.Context, state types.DelegationState, before int64, limit uint64,
+	) ([]*model.BTCDelegationDetails, error)
+	/**
+	 * SaveStuckDelegationReport saves a stuck delegation report.
+	 * @param ctx The context
+	 * @param report The report
+	 * @return An error if the operation failed
+	 */
+	SaveStuckDelegationReport(ctx context.Context, report *model.StuckDelegationReport) error
+	/**
+	 * SaveRawEventCapture saves the raw attributes of a captured BBN event
+	 * into the capped collection of the captures.
+	 * @param ctx The context
+	 * @param capture The captured event
+	 * @return An error if the operation failed
+	 */
+	SaveRawEventCapture(ctx context.Context, capture *model.RawEventCapture) error
+	/**
+	 * SaveBbnEventDeadLetter saves a BBN event set aside as its processing
+	 * failed with a permanent error, replacing the dead letter of the same
+	 * event if any.
+	 * @param ctx The context
+	 * @param deadLetter The dead letter of the event
+	 * @return An error if the operation failed
+	 */
+	SaveBbnEventDeadLetter(ctx context.Context, deadLetter *model.BbnEventDeadLetter) error
+	/**
+	 * SaveOutboxEvent records a queue event to be relayed to the queue, and
+	 * assigns it the next event sequence number of its delegation, in the
+	 * same transaction.
+	 * If the event already exists, DuplicateKeyError will be returned.
+	 * If the delegation does not exist, NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param event The outbox event
+	 * @return An error if the operation failed
+	 */
+	SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error
+	/**
+	 * GetUnsentOutboxEvents retrieves the outbox events not pushed to the queue
+	 * yet and not flagged poison, in insertion order. Events created at the
+	 * same time are ordered by id, so that a page resumes right after the last
+	 * event of the previous one without skipping or repeating any.
+	 * @param ctx The context
+	 * @param createdAfter Only events created after this time, or at this
+	 * time with an id greater than afterId, are retrieved
+	 * @param afterId The id of the last event retrieved at createdAfter
+	 * @param limit The maximum number of events to retrieve
+	 * @return The outbox events or an error
+	 */
+	GetUnsentOutboxEvents(
+		ctx context.Context, createdAfter int64, afterId string, limit uint64,
+	) ([]*model.OutboxEvent, error)
+	/**
+	 * MarkOutboxEventSent marks an outbox event as pushed to the queue.
+	 * If the event does not exist, NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param id The id of the outbox event
+	 * @param sentAt The push time
+	 * @return An error if the operation failed
+	 */
+	MarkOutboxEventSent(ctx context.Context, id string, sentAt int64) error
+	/**
+	 * MarkOutboxEventFailed records a failed push of an outbox event.
+	 * If the event does not exist, NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param id The id of the outbox event
+	 * @param lastError The push error
+	 * @param nextAttemptAt The time before which the push is not retried
+	 * @param poison Whether the event is no longer pushed
+	 * @return An error if the operation failed
+	 */
+	MarkOutboxEventFailed(
+		ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool,
+	) error
+	/**
+	 * GetPoisonOutboxEvents retrieves the outbox events flagged poison.
+	 * @param ctx The context
+	 * @return The outbox events or an error
+	 */
+	GetPoisonOutboxEvents(ctx context.Context) ([]*model.OutboxEvent, error)
+	/**
+	 * RequeuePoisonOutboxEvents clears the poison flag and the failed attempts
+	 * of the poison outbox events, so that they are pushed again.
+	 * @param ctx The context
+	 * @return The number of requeued events or an error
+	 */
+	RequeuePoisonOutboxEvents(ctx context.Context) (uint64, error)
+	/**
+	 * GetOutboxStats retrieves the number of unsent and poison outbox events
+	 * and the creation time of the oldest unsent one.
+	 * @param ctx The context
+	 * @return The outbox stats or an error
+	 */
+	GetOutboxStats(ctx context.Context) (*model.OutboxStats, error)
+	/**
+	 * GetDelegationOutboxEvents retrieves all the outbox events recorded for
+	 * a delegation, sent or not, sorted by sequence number.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash of the delegation
+	 * @return The outbox events or an error
+	 */
+	GetDelegationOutboxEvents(
+		ctx context.Context, stakingTxHashHex string,
+	) ([]*model.OutboxEvent, error)
+	/**
+	 * GetOutboxSequence retrieves the sequence number of the last outbox event
+	 * recorded for a delegation.
+	 * If no event was recorded, NotFoundError will be returned.
+	 * @param ctx The context
+	 * @param stakingTxHashHex The staking tx hash of the delegation
+	 * @return The sequence number or an error
+	 */
+	GetOutboxSequence(ctx context.Context, stakingTxHashHex string) (uint64, error)
+	/**
+	 * GetDelegationStatsByState aggregates the number and staking amount of
+	 * the delegations in each state.
+	 * @param ctx The context
+	 * @return The stats of each state holding delegations or an error
+	 */
+	GetDelegationStatsByState(ctx context.Context) ([]*model.DelegationStateStats, error)
+	/**
+	 * SaveGlobalStats saves the global stats document, replacing the previous one.
+	 * @param ctx The context
+	 * @param stats The global stats
+	 * @return An error if the operation failed
+	 */
+	SaveGlobalStats(ctx context.Context, stats *model.GlobalStats) error
+	/**
+	 * GetGlobalStats retrieves the global stats document.
+	 * If it was never saved, a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @return The global stats or an error
+	 */
+	GetGlobalStats(ctx context.Context) (*model.GlobalStats, error)
+	/**
+	 * SaveBootstrap saves the bootstrap document, replacing the previous one.
+	 * @param ctx The context
+	 * @param bootstrap The bootstrap progress
+	 * @return An error if the operation failed
+	 */
+	SaveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) error
+	/**
+	 * GetBootstrap retrieves the bootstrap document.
+	 * If the bootstrap never started, a NotFoundError will be returned.
+	 * @param ctx The context
+	 * @return The bootstrap progress or an error
+	 */
+	GetBootstrap(ctx context.Context) (*model.Bootstrap, error)
+	/**
+	 * AcquireLock acquires the named lock for the owner, or renews it if
+	 * already held by the owner. An expired lock is taken over.
+	 * If another owner holds the lock, LockHeldError will be returned.
+	 * @param ctx The context
+	 * @param name The name of the lock
+	 * @param owner The owner acquiring the lock
+	 * @param ttl The time after which the lock expires unless renewed
+	 * @return An error if the operation failed
+	 */
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) error
+	/**
+	 * ReleaseLock releases the named lock if held by the owner.
+	 * @param ctx The context
+	 * @param name The name of the lock
+	 * @param owner The owner releasing the lock
+	 * @return An error if the operation failed
+	 */
+	ReleaseLock(ctx context.Context, name, owner string) error
+	/**
+	 * CountPrunableBTCDelegations counts the delegations in a terminal state
+	 * since before the given time.
+	 * @param ctx The context
+	 * @param before The time in epoch seconds
+	 * @return The number of delegations or an error
+	 */
+	CountPrunableBTCDelegations(ctx context.Context, before int64) (uint64, error)
+	/**
+	 * ArchivePrunableBTCDelegations moves a batch of the delegations in a
+	 * terminal state since before the given time to the archive, in a single
+	 * transaction.
+	 * @param ctx The context
+	 * @param before The time in epoch seconds
+	 * @param limit The maximum number of delegations moved
+	 * @return The number of moved delegations or an error
+	 */
+	ArchivePrunableBTCDelegations(ctx context.Context, before int64, limit int64) (uint64, error)
+	/**
+	 * CountPrunableArchivedTimeLocks counts the archived timelocks archived
+	 * before the given time whose delegation is in a terminal state or pruned.
+	 * @param ctx The context
+	 * @param before The time in epoch seconds
+	 * @return The number of archived timelocks or an error
+	 */
+	CountPrunableArchivedTimeLocks(ctx context.Context, before int64) (uint64, error)
+	/**
+	 * DeletePrunableArchivedTimeLocks deletes a batch of the archived
+	 * timelocks archived before the given time whose delegation is in a
+	 * terminal state or pruned.
+	 * @param ctx The context
+	 * @param before The time in epoch seconds
+	 * @param limit The maximum number of archived timelocks deleted
+	 * @return The number of deleted archived timelocks or an error
+	 */
+	DeletePrunableArchivedTimeLocks(ctx context.Context, before int64, limit int64) (uint64, error)
 }
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"go.mongodb.org/mongo-driver/bson"
@@ -10,25 +11,91 @@ import (
 )
 
 func (db *Database) GetLastProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	result, err := db.GetLastProcessedBbnBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return result.Height, nil
+}
+
+func (db *Database) GetLastProcessedBbnBlock(ctx context.Context) (*model.LastProcessedHeight, error) {
 	var result model.LastProcessedHeight
 	err := db.client.Database(db.dbName).
 		Collection(model.LastProcessedHeightCollection).
 		FindOne(ctx, bson.M{}).Decode(&result)
 	if err == mongo.ErrNoDocuments {
-		// If no document exists, return 0
-		return 0, nil
+		// If no document exists, start from height 0
+		return &model.LastProcessedHeight{}, nil
 	}
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return result.Height, nil
+	return &result, nil
 }
 
-func (db *Database) UpdateLastProcessedBbnHeight(ctx context.Context, height uint64) error {
-	update := bson.M{"$set": bson.M{"height": height}}
+func (db *Database) UpdateLastProcessedBbnHeight(
+	ctx context.Context, height uint64, blockHash string,
+) error {
+	// Advancing the height completes the block under processing, if any
+	update := bson.M{
+		"$set":   bson.M{"height": height, "block_hash": blockHash},
+		"$unset": bson.M{"processing_marker": ""},
+	}
 	opts := options.Update().SetUpsert(true)
 	_, err := db.client.Database(db.dbName).
 		Collection(model.LastProcessedHeightCollection).
 		UpdateOne(ctx, bson.M{}, update, opts)
 	return err
 }
+
+func (db *Database) HaltBbnProcessing(ctx context.Context, reason string) error {
+	update := bson.M{"$set": bson.M{"halt_reason": reason}}
+	opts := options.Update().SetUpsert(true)
+	_, err := db.client.Database(db.dbName).
+		Collection(model.LastProcessedHeightCollection).
+		UpdateOne(ctx, bson.M{}, update, opts)
+	return err
+}
+
+func (db *Database) ResyncLastProcessedBbnHeight(ctx context.Context, height uint64) error {
+	update := bson.M{
+		"$set":   bson.M{"height": height},
+		"$unset": bson.M{"block_hash": "", "halt_reason": "", "processing_marker": ""},
+	}
+	opts := options.Update().SetUpsert(true)
+	_, err := db.client.Database(db.dbName).
+		Collection(model.LastProcessedHeightCollection).
+		UpdateOne(ctx, bson.M{}, update, opts)
+	return err
+}
+
+func (db *Database) StartBbnBlockProcessing(
+	ctx context.Context, marker *model.BbnProcessingMarker,
+) error {
+	update := bson.M{"$set": bson.M{"processing_marker": marker}}
+	opts := options.Update().SetUpsert(true)
+	_, err := db.client.Database(db.dbName).
+		Collection(model.LastProcessedHeightCollection).
+		UpdateOne(ctx, bson.M{}, update, opts)
+	return err
+}
+
+func (db *Database) MarkBbnEventProcessed(
+	ctx context.Context, height uint64, eventIndex int,
+) error {
+	filter := bson.M{"processing_marker.height": height}
+	update := bson.M{"$addToSet": bson.M{"processing_marker.processed_events": eventIndex}}
+	result, err := db.client.Database(db.dbName).
+		Collection(model.LastProcessedHeightCollection).
+		UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{
+			Key:     strconv.FormatUint(height, 10),
+			Message: "no BBN block is being processed at the height",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationLockName is the lock of the schema migrations, held by the
+// operations that must not run alongside one
+const MigrationLockName = "migration"
+
+// LeaderLockName is the lease of the leader replica, the only one processing
+// the BBN blocks
+const LeaderLockName = "leader"
+
+func (db *Database) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) error {
+	collection := db.client.Database(db.dbName).Collection(model.LocksCollection)
+	now := time.Now()
+
+	// The lock is taken over once expired, and renewed by its owner. The
+	// expiry is compared with the clock of the acquiring instance, the
+	// holders allowing for the skew with the other instances.
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"owner": owner},
+			{"expires_at": bson.M{"$lt": now.Unix()}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":      owner,
+			"expires_at": now.Add(ttl).Unix(),
+		},
+	}
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err == nil {
+		return nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	// The upsert conflicts with the lock held by another owner
+	var lock model.Lock
+	if err := collection.FindOne(ctx, bson.M{"_id": name}).Decode(&lock); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &LockHeldError{Name: name, Owner: "an owner that just released it"}
+		}
+		return err
+	}
+	return &LockHeldError{Name: name, Owner: lock.Owner}
+}
+
+func (db *Database) ReleaseLock(ctx context.Context, name, owner string) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.LocksCollection).
+		DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	return err
+}
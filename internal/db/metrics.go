@@ -0,0 +1,961 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/tracing"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MetricsDatabase counts the errors returned by the wrapped database, by
+// method and error class, logs them with the logger of the calling scope and
+// traces every call in a span of its own. The calls slower than the slow query
+// threshold are counted and logged, at most once per method every log
+// interval. The successful writes are counted in the write stats of the
+// context, if any. Every method of DbInterface is wrapped explicitly, so that
+// a new one cannot bypass the counting.
+type MetricsDatabase struct {
+	next                 DbInterface
+	slowQueryThreshold   time.Duration
+	slowQueryLogInterval time.Duration
+
+	mu sync.Mutex
+	// slowQueryLogs tracks the slow query logs of every method
+	slowQueryLogs map[string]*slowQueryLog
+}
+
+// slowQueryLog tracks the slow query logs of a method, to rate limit them
+type slowQueryLog struct {
+	lastLogged time.Time
+	// suppressed is the number of slow calls not logged since the last log
+	suppressed int
+}
+
+func NewMetricsDatabase(dbClient DbInterface, cfg *config.DbConfig) *MetricsDatabase {
+	return &MetricsDatabase{
+		next:                 dbClient,
+		slowQueryThreshold:   cfg.GetSlowQueryThreshold(),
+		slowQueryLogInterval: cfg.GetSlowQueryLogInterval(),
+		slowQueryLogs:        make(map[string]*slowQueryLog),
+	}
+}
+
+// dbCall is a call to a method of the wrapped database
+type dbCall struct {
+	method string
+	// args are the names of the arguments of the call, a rough shape of its
+	// filter which does not dump the documents
+	args    string
+	span    trace.Span
+	started time.Time
+	retries *atomic.Int32
+}
+
+// start starts the span of a call to the method and the tracking of its
+// duration and retries
+func (d *MetricsDatabase) start(ctx context.Context, method string, args string) (context.Context, *dbCall) {
+	ctx, span := tracing.StartSpan(ctx, "db."+method, attribute.String("db.method", method))
+	ctx, retries := withRetryCount(ctx)
+	return ctx, &dbCall{method: method, args: args, span: span, started: time.Now(), retries: retries}
+}
+
+// record counts the error returned by the call, if any, logs the call if
+// slow, ends its span and returns the error
+func (d *MetricsDatabase) record(ctx context.Context, call *dbCall, err error) error {
+	d.recordDuration(ctx, call, time.Since(call.started))
+
+	method := call.method
+	if err != nil {
+		metrics.RecordDbError(method, errorClass(err))
+	} else {
+		recordWrite(ctx, method)
+	}
+	// A document not found, or not in the state to update, is an answer
+	// rather than a failure of the call
+	if err == nil || IsNotFoundError(err) || IsStateTransitionError(err) {
+		tracing.EndSpan(call.span, nil)
+		return err
+	}
+	// A duplicate key is how the idempotent writes learn they were applied
+	// already, which their callers handle
+	if !IsDuplicateKeyError(err) {
+		logging.DB.FromContext(ctx).Error().Err(err).Str("db_method", method).Msg("db operation failed")
+	}
+	tracing.EndSpan(call.span, err)
+	return err
+}
+
+// recordDuration counts and logs the call if it took longer than the slow
+// query threshold
+func (d *MetricsDatabase) recordDuration(ctx context.Context, call *dbCall, duration time.Duration) {
+	if duration < d.slowQueryThreshold {
+		return
+	}
+	metrics.RecordDbSlowQuery(call.method)
+
+	suppressed, ok := d.allowSlowQueryLog(call.method, time.Now())
+	if !ok {
+		return
+	}
+	logging.DB.FromContext(ctx).Warn().
+		Str("db_method", call.method).
+		Str("db_args", call.args).
+		Dur("duration", duration).
+		Int32("retries", call.retries.Load()).
+		Int("suppressed", suppressed).
+		Msg("slow db operation")
+}
+
+// allowSlowQueryLog tells whether a slow call to the method can be logged at
+// the time, along with the number of slow calls not logged since the last log
+func (d *MetricsDatabase) allowSlowQueryLog(method string, now time.Time) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.slowQueryLogs[method]
+	if !ok {
+		entry = &slowQueryLog{}
+		d.slowQueryLogs[method] = entry
+	} else if now.Sub(entry.lastLogged) < d.slowQueryLogInterval {
+		entry.suppressed++
+		return 0, false
+	}
+
+	suppressed := entry.suppressed
+	entry.lastLogged = now
+	entry.suppressed = 0
+	return suppressed, true
+}
+
+func (d *MetricsDatabase) Ping(ctx context.Context) error {
+	ctx, call := d.start(ctx, "Ping", "")
+	err := d.next.Ping(ctx)
+	return d.record(ctx, call, err)
+}
+
+// RunInTransaction is not measured itself, the calls made by fn are
+func (d *MetricsDatabase) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return d.next.RunInTransaction(ctx, fn)
+}
+
+func (d *MetricsDatabase) SaveNewFinalityProvider(
+	ctx context.Context, fpDoc *model.FinalityProviderDetails,
+) error {
+	ctx, call := d.start(ctx, "SaveNewFinalityProvider", "fpDoc")
+	err := d.next.SaveNewFinalityProvider(ctx, fpDoc)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateFinalityProviderState(
+	ctx context.Context, btcPk string, newState string,
+) error {
+	ctx, call := d.start(ctx, "UpdateFinalityProviderState", "btcPk, newState")
+	err := d.next.UpdateFinalityProviderState(ctx, btcPk, newState)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateFinalityProviderDetailsFromEvent(
+	ctx context.Context, detailsToUpdate *model.FinalityProviderDetails,
+) error {
+	ctx, call := d.start(ctx, "UpdateFinalityProviderDetailsFromEvent", "detailsToUpdate")
+	err := d.next.UpdateFinalityProviderDetailsFromEvent(ctx, detailsToUpdate)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetFinalityProviderByBtcPk(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderDetails, error) {
+	ctx, call := d.start(ctx, "GetFinalityProviderByBtcPk", "btcPk")
+	result, err := d.next.GetFinalityProviderByBtcPk(ctx, btcPk)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetFinalityProvidersByBsnId(
+	ctx context.Context, bsnId string,
+) ([]*model.FinalityProviderDetails, error) {
+	ctx, call := d.start(ctx, "GetFinalityProvidersByBsnId", "bsnId")
+	result, err := d.next.GetFinalityProvidersByBsnId(ctx, bsnId)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetFinalityProviders(
+	ctx context.Context, filter FinalityProvidersFilter, paginationToken string, limit int64,
+) (*DbResultMap[*model.FinalityProviderDetails], error) {
+	ctx, call := d.start(ctx, "GetFinalityProviders", "filter, paginationToken, limit")
+	result, err := d.next.GetFinalityProviders(ctx, filter, paginationToken, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetFinalityProviderStats(
+	ctx context.Context, btcPk string,
+) (*model.FinalityProviderStats, error) {
+	ctx, call := d.start(ctx, "GetFinalityProviderStats", "btcPk")
+	result, err := d.next.GetFinalityProviderStats(ctx, btcPk)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetFinalityProviderStakeDistribution(
+	ctx context.Context, limit int64,
+) (*model.FinalityProviderStakeDistribution, error) {
+	ctx, call := d.start(ctx, "GetFinalityProviderStakeDistribution", "limit")
+	result, err := d.next.GetFinalityProviderStakeDistribution(ctx, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) CountFinalityProvidersByState(
+	ctx context.Context,
+) (map[string]uint64, error) {
+	ctx, call := d.start(ctx, "CountFinalityProvidersByState", "")
+	result, err := d.next.CountFinalityProvidersByState(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	ctx, call := d.start(ctx, "SaveStakingParams", "version, params")
+	err := d.next.SaveStakingParams(ctx, version, params)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	ctx, call := d.start(ctx, "ReplaceStakingParams", "version, params")
+	err := d.next.ReplaceStakingParams(ctx, version, params)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetStakingParams(
+	ctx context.Context, version uint32,
+) (*bbnclient.StakingParams, error) {
+	ctx, call := d.start(ctx, "GetStakingParams", "version")
+	result, err := d.next.GetStakingParams(ctx, version)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetAllStakingParams(
+	ctx context.Context,
+) (map[uint32]*bbnclient.StakingParams, error) {
+	ctx, call := d.start(ctx, "GetAllStakingParams", "")
+	result, err := d.next.GetAllStakingParams(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveCheckpointParams(
+	ctx context.Context, params *bbnclient.CheckpointParams,
+) error {
+	ctx, call := d.start(ctx, "SaveCheckpointParams", "params")
+	err := d.next.SaveCheckpointParams(ctx, params)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetCheckpointParams(
+	ctx context.Context,
+) (*bbnclient.CheckpointParams, error) {
+	ctx, call := d.start(ctx, "GetCheckpointParams", "")
+	result, err := d.next.GetCheckpointParams(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveNewBTCDelegation(
+	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
+) error {
+	ctx, call := d.start(ctx, "SaveNewBTCDelegation", "delegationDoc")
+	err := d.next.SaveNewBTCDelegation(ctx, delegationDoc)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	ctx, call := d.start(ctx, "UpdateBTCDelegationState", "stakingTxHash, qualifiedPreviousStates, newState, newSubState")
+	err := d.next.UpdateBTCDelegationState(ctx, stakingTxHash, qualifiedPreviousStates, newState, newSubState)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) ApplyBTCDelegationStateChange(
+	ctx context.Context, change DelegationStateChange,
+) error {
+	ctx, call := d.start(ctx, "ApplyBTCDelegationStateChange", "change")
+	err := d.next.ApplyBTCDelegationStateChange(ctx, change)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) OverrideBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	currentState types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	ctx, call := d.start(ctx, "OverrideBTCDelegationState", "stakingTxHash, currentState, newState, newSubState")
+	err := d.next.OverrideBTCDelegationState(ctx, stakingTxHash, currentState, newState, newSubState)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateBTCDelegationSubState(
+	ctx context.Context,
+	stakingTxHash string,
+	state types.DelegationState,
+	newSubState types.DelegationSubState,
+) error {
+	ctx, call := d.start(ctx, "UpdateBTCDelegationSubState", "stakingTxHash, state, newSubState")
+	err := d.next.UpdateBTCDelegationSubState(ctx, stakingTxHash, state, newSubState)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveBTCDelegationUnbondingCovenantSignature(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
+) error {
+	ctx, call := d.start(ctx, "SaveBTCDelegationUnbondingCovenantSignature", "stakingTxHash, covenantBtcPkHex, signatureHex")
+	err := d.next.SaveBTCDelegationUnbondingCovenantSignature(ctx, stakingTxHash, covenantBtcPkHex, signatureHex)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SetCovenantSignatureVerified(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool,
+) error {
+	ctx, call := d.start(ctx, "SetCovenantSignatureVerified", "stakingTxHash, covenantBtcPkHex, verified")
+	err := d.next.SetCovenantSignatureVerified(ctx, stakingTxHash, covenantBtcPkHex, verified)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) BulkUpdateDelegationStates(
+	ctx context.Context, updates []DelegationStateUpdate,
+) error {
+	ctx, call := d.start(ctx, "BulkUpdateDelegationStates", "updates")
+	err := d.next.BulkUpdateDelegationStates(ctx, updates)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []CovenantSigRecord,
+) error {
+	ctx, call := d.start(ctx, "BulkSaveCovenantSignatures", "sigs")
+	err := d.next.BulkSaveCovenantSignatures(ctx, sigs)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCDelegationState(
+	ctx context.Context, stakingTxHash string,
+) (*types.DelegationState, error) {
+	ctx, call := d.start(ctx, "GetBTCDelegationState", "stakingTxHash")
+	result, err := d.next.GetBTCDelegationState(ctx, stakingTxHash)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateBTCDelegationDetails(
+	ctx context.Context, stakingTxHash string, details *model.BTCDelegationDetails,
+) error {
+	ctx, call := d.start(ctx, "UpdateBTCDelegationDetails", "stakingTxHash, details")
+	err := d.next.UpdateBTCDelegationDetails(ctx, stakingTxHash, details)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCDelegationByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "GetBTCDelegationByStakingTxHash", "stakingTxHash")
+	result, err := d.next.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCDelegationSummaryByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationSummary, error) {
+	ctx, call := d.start(ctx, "GetBTCDelegationSummaryByStakingTxHash", "stakingTxHash")
+	result, err := d.next.GetBTCDelegationSummaryByStakingTxHash(ctx, stakingTxHash)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCDelegationsByStakingTxHashes(
+	ctx context.Context, stakingTxHashes []string,
+) (map[string]*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "GetBTCDelegationsByStakingTxHashes", "stakingTxHashes")
+	result, err := d.next.GetBTCDelegationsByStakingTxHashes(ctx, stakingTxHashes)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateDelegationsStateByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, newState types.DelegationState,
+) error {
+	ctx, call := d.start(ctx, "UpdateDelegationsStateByFinalityProvider", "fpBtcPkHex, newState")
+	err := d.next.UpdateDelegationsStateByFinalityProvider(ctx, fpBtcPkHex, newState)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetDelegationsByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string,
+) ([]*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "GetDelegationsByFinalityProvider", "fpBtcPkHex")
+	result, err := d.next.GetDelegationsByFinalityProvider(ctx, fpBtcPkHex)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveNewTimeLockExpire(
+	ctx context.Context, stakingTxHashHex string, expireHeight uint32, subState types.DelegationSubState,
+) error {
+	ctx, call := d.start(ctx, "SaveNewTimeLockExpire", "stakingTxHashHex, expireHeight, subState")
+	err := d.next.SaveNewTimeLockExpire(ctx, stakingTxHashHex, expireHeight, subState)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) FindExpiredDelegations(
+	ctx context.Context, btcTipHeight, limit uint64,
+) ([]model.TimeLockDocument, error) {
+	ctx, call := d.start(ctx, "FindExpiredDelegations", "btcTipHeight, limit")
+	result, err := d.next.FindExpiredDelegations(ctx, btcTipHeight, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) ForEachExpiredDelegation(
+	ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error,
+) error {
+	ctx, call := d.start(ctx, "ForEachExpiredDelegation", "btcTipHeight")
+	// The time spent in fn is not the database's, nor are its errors
+	var fnErr error
+	err := d.next.ForEachExpiredDelegation(ctx, btcTipHeight, func(tlDoc model.TimeLockDocument) error {
+		started := time.Now()
+		fnErr = fn(tlDoc)
+		call.started = call.started.Add(time.Since(started))
+		return fnErr
+	})
+	if err != nil && err == fnErr {
+		d.record(ctx, call, nil)
+		return err
+	}
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetExpiryBacklogStats(
+	ctx context.Context, btcTipHeight uint64,
+) ([]*model.ExpiryBacklogStats, error) {
+	ctx, call := d.start(ctx, "GetExpiryBacklogStats", "btcTipHeight")
+	result, err := d.next.GetExpiryBacklogStats(ctx, btcTipHeight)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DeleteExpiredDelegation(
+	ctx context.Context, stakingTxHashHex string,
+) error {
+	ctx, call := d.start(ctx, "DeleteExpiredDelegation", "stakingTxHashHex")
+	err := d.next.DeleteExpiredDelegation(ctx, stakingTxHashHex)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) FindOrphanedTimeLocks(
+	ctx context.Context, terminalStates []types.DelegationState, limit uint64,
+) ([]*model.OrphanedTimeLock, error) {
+	ctx, call := d.start(ctx, "FindOrphanedTimeLocks", "terminalStates, limit")
+	result, err := d.next.FindOrphanedTimeLocks(ctx, terminalStates, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DeleteTimeLocks(
+	ctx context.Context, ids []primitive.ObjectID,
+) (uint64, error) {
+	ctx, call := d.start(ctx, "DeleteTimeLocks", "ids")
+	result, err := d.next.DeleteTimeLocks(ctx, ids)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]model.TimeLockDocument, error) {
+	ctx, call := d.start(ctx, "GetTimeLocks", "stakingTxHashHex")
+	result, err := d.next.GetTimeLocks(ctx, stakingTxHashHex)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetArchivedTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.ArchivedTimeLockDocument, error) {
+	ctx, call := d.start(ctx, "GetArchivedTimeLocks", "stakingTxHashHex")
+	result, err := d.next.GetArchivedTimeLocks(ctx, stakingTxHashHex)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetTimeLocksExpiringBetween(
+	ctx context.Context, fromHeight, toHeight uint32, paginationToken string, limit int64,
+) (*DbResultMap[*model.ExpiringTimeLock], error) {
+	ctx, call := d.start(ctx, "GetTimeLocksExpiringBetween", "fromHeight, toHeight, paginationToken, limit")
+	result, err := d.next.GetTimeLocksExpiringBetween(ctx, fromHeight, toHeight, paginationToken, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error {
+	ctx, call := d.start(ctx, "DeleteBTCDelegation", "stakingTxHashHex")
+	err := d.next.DeleteBTCDelegation(ctx, stakingTxHashHex)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveDelegationStateTransition(
+	ctx context.Context, transition *model.DelegationStateTransition,
+) error {
+	ctx, call := d.start(ctx, "SaveDelegationStateTransition", "transition")
+	err := d.next.SaveDelegationStateTransition(ctx, transition)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetDelegationStateTransitions(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.DelegationStateTransition, error) {
+	ctx, call := d.start(ctx, "GetDelegationStateTransitions", "stakingTxHashHex")
+	result, err := d.next.GetDelegationStateTransitions(ctx, stakingTxHashHex)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) AggregateStateTransitions(
+	ctx context.Context,
+	fromTime, toTime int64,
+	periodUnit string,
+	visit func(stats *model.StateTransitionPeriodStats) error,
+) error {
+	ctx, call := d.start(ctx, "AggregateStateTransitions", "fromTime, toTime, periodUnit, visit")
+	err := d.next.AggregateStateTransitions(ctx, fromTime, toTime, periodUnit, visit)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) FindTimeLocksByParamsVersion(
+	ctx context.Context, subStates []types.DelegationSubState, paramsVersion uint32,
+) ([]model.TimeLockDocument, error) {
+	ctx, call := d.start(ctx, "FindTimeLocksByParamsVersion", "subStates, paramsVersion")
+	result, err := d.next.FindTimeLocksByParamsVersion(ctx, subStates, paramsVersion)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateTimeLockExpireHeight(
+	ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32,
+) error {
+	ctx, call := d.start(ctx, "UpdateTimeLockExpireHeight", "timeLock, newExpireHeight")
+	err := d.next.UpdateTimeLockExpireHeight(ctx, timeLock, newExpireHeight)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetLastProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	ctx, call := d.start(ctx, "GetLastProcessedBbnHeight", "")
+	result, err := d.next.GetLastProcessedBbnHeight(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetLastProcessedBbnBlock(
+	ctx context.Context,
+) (*model.LastProcessedHeight, error) {
+	ctx, call := d.start(ctx, "GetLastProcessedBbnBlock", "")
+	result, err := d.next.GetLastProcessedBbnBlock(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) UpdateLastProcessedBbnHeight(
+	ctx context.Context, height uint64, blockHash string,
+) error {
+	ctx, call := d.start(ctx, "UpdateLastProcessedBbnHeight", "height, blockHash")
+	err := d.next.UpdateLastProcessedBbnHeight(ctx, height, blockHash)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) HaltBbnProcessing(ctx context.Context, reason string) error {
+	ctx, call := d.start(ctx, "HaltBbnProcessing", "reason")
+	err := d.next.HaltBbnProcessing(ctx, reason)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) ResyncLastProcessedBbnHeight(ctx context.Context, height uint64) error {
+	ctx, call := d.start(ctx, "ResyncLastProcessedBbnHeight", "height")
+	err := d.next.ResyncLastProcessedBbnHeight(ctx, height)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) StartBbnBlockProcessing(
+	ctx context.Context, marker *model.BbnProcessingMarker,
+) error {
+	ctx, call := d.start(ctx, "StartBbnBlockProcessing", "marker")
+	err := d.next.StartBbnBlockProcessing(ctx, marker)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) MarkBbnEventProcessed(
+	ctx context.Context, height uint64, eventIndex int,
+) error {
+	ctx, call := d.start(ctx, "MarkBbnEventProcessed", "height, eventIndex")
+	err := d.next.MarkBbnEventProcessed(ctx, height, eventIndex)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveBTCDelegationSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, slashingTxHex string, spendingHeight uint32,
+) error {
+	ctx, call := d.start(ctx, "SaveBTCDelegationSlashingTxHex", "stakingTxHashHex, slashingTxHex, spendingHeight")
+	err := d.next.SaveBTCDelegationSlashingTxHex(ctx, stakingTxHashHex, slashingTxHex, spendingHeight)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveBTCDelegationUnbondingSlashingTxHex(
+	ctx context.Context, stakingTxHashHex string, unbondingSlashingTxHex string, spendingHeight uint32,
+) error {
+	ctx, call := d.start(ctx, "SaveBTCDelegationUnbondingSlashingTxHex", "stakingTxHashHex, unbondingSlashingTxHex, spendingHeight")
+	err := d.next.SaveBTCDelegationUnbondingSlashingTxHex(ctx, stakingTxHashHex, unbondingSlashingTxHex, spendingHeight)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCDelegationsByStates(
+	ctx context.Context, states []types.DelegationState,
+) ([]*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "GetBTCDelegationsByStates", "states")
+	result, err := d.next.GetBTCDelegationsByStates(ctx, states)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCDelegationsAfter(
+	ctx context.Context, filter BTCDelegationsFilter, stakingTxHashHex string, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "GetBTCDelegationsAfter", "filter, stakingTxHashHex, limit")
+	result, err := d.next.GetBTCDelegationsAfter(ctx, filter, stakingTxHashHex, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SampleBTCDelegations(
+	ctx context.Context, filter BTCDelegationsFilter, size uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "SampleBTCDelegations", "filter, size")
+	result, err := d.next.SampleBTCDelegations(ctx, filter, size)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCDelegationsCreatedBetween(
+	ctx context.Context, fromHeight, toHeight int64,
+) ([]*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "GetBTCDelegationsCreatedBetween", "fromHeight, toHeight")
+	result, err := d.next.GetBTCDelegationsCreatedBetween(ctx, fromHeight, toHeight)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetStakerDelegations(
+	ctx context.Context, filter StakerDelegationsFilter, paginationToken string, limit int64,
+) (*DbResultMap[*model.BTCDelegationDetails], error) {
+	ctx, call := d.start(ctx, "GetStakerDelegations", "filter, paginationToken, limit")
+	result, err := d.next.GetStakerDelegations(ctx, filter, paginationToken, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetStakerDelegationSummaries(
+	ctx context.Context, filter StakerDelegationsFilter, paginationToken string, limit int64,
+) (*DbResultMap[*model.BTCDelegationSummary], error) {
+	ctx, call := d.start(ctx, "GetStakerDelegationSummaries", "filter, paginationToken, limit")
+	result, err := d.next.GetStakerDelegationSummaries(ctx, filter, paginationToken, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveFinalityProviderVotingPowerChange(
+	ctx context.Context, change *model.FinalityProviderVotingPowerChange,
+) error {
+	ctx, call := d.start(ctx, "SaveFinalityProviderVotingPowerChange", "change")
+	err := d.next.SaveFinalityProviderVotingPowerChange(ctx, change)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetLatestFinalityProviderVotingPowerChanges(
+	ctx context.Context, fpBtcPks []string,
+) (map[string]*model.FinalityProviderVotingPowerChange, error) {
+	ctx, call := d.start(ctx, "GetLatestFinalityProviderVotingPowerChanges", "fpBtcPks")
+	result, err := d.next.GetLatestFinalityProviderVotingPowerChanges(ctx, fpBtcPks)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetFinalityProviderActivationPeriods(
+	ctx context.Context, fpBtcPk string,
+) ([]*model.FinalityProviderActivationPeriod, error) {
+	ctx, call := d.start(ctx, "GetFinalityProviderActivationPeriods", "fpBtcPk")
+	result, err := d.next.GetFinalityProviderActivationPeriods(ctx, fpBtcPk)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveBTCHeader(ctx context.Context, header *model.BTCHeader) error {
+	ctx, call := d.start(ctx, "SaveBTCHeader", "header")
+	err := d.next.SaveBTCHeader(ctx, header)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBTCHeaderByHeight(
+	ctx context.Context, height uint64,
+) (*model.BTCHeader, error) {
+	ctx, call := d.start(ctx, "GetBTCHeaderByHeight", "height")
+	result, err := d.next.GetBTCHeaderByHeight(ctx, height)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetLatestBTCHeader(ctx context.Context) (*model.BTCHeader, error) {
+	ctx, call := d.start(ctx, "GetLatestBTCHeader", "")
+	result, err := d.next.GetLatestBTCHeader(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DeleteBTCHeadersAbove(ctx context.Context, height uint64) error {
+	ctx, call := d.start(ctx, "DeleteBTCHeadersAbove", "height")
+	err := d.next.DeleteBTCHeadersAbove(ctx, height)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DeleteBTCHeadersBelow(ctx context.Context, height uint64) error {
+	ctx, call := d.start(ctx, "DeleteBTCHeadersBelow", "height")
+	err := d.next.DeleteBTCHeadersBelow(ctx, height)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveBTCDerivedChange(
+	ctx context.Context, change *model.BTCDerivedChange,
+) error {
+	ctx, call := d.start(ctx, "SaveBTCDerivedChange", "change")
+	err := d.next.SaveBTCDerivedChange(ctx, change)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) RollbackBTCDerivedChanges(
+	ctx context.Context, forkHeight uint64,
+) ([]*model.BTCDerivedChange, error) {
+	ctx, call := d.start(ctx, "RollbackBTCDerivedChanges", "forkHeight")
+	result, err := d.next.RollbackBTCDerivedChanges(ctx, forkHeight)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DeleteBTCDerivedChangesBelow(ctx context.Context, height uint64) error {
+	ctx, call := d.start(ctx, "DeleteBTCDerivedChangesBelow", "height")
+	err := d.next.DeleteBTCDerivedChangesBelow(ctx, height)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetUnfinishedReconciliationRun(
+	ctx context.Context,
+) (*model.ReconciliationRun, error) {
+	ctx, call := d.start(ctx, "GetUnfinishedReconciliationRun", "")
+	result, err := d.next.GetUnfinishedReconciliationRun(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveReconciliationRun(
+	ctx context.Context, run *model.ReconciliationRun,
+) error {
+	ctx, call := d.start(ctx, "SaveReconciliationRun", "run")
+	err := d.next.SaveReconciliationRun(ctx, run)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveReconciliationDiscrepancy(
+	ctx context.Context, discrepancy *model.ReconciliationDiscrepancy,
+) error {
+	ctx, call := d.start(ctx, "SaveReconciliationDiscrepancy", "discrepancy")
+	err := d.next.SaveReconciliationDiscrepancy(ctx, discrepancy)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) MarkBbnHeightProcessed(ctx context.Context, height uint64) error {
+	ctx, call := d.start(ctx, "MarkBbnHeightProcessed", "height")
+	err := d.next.MarkBbnHeightProcessed(ctx, height)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetLowestProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	ctx, call := d.start(ctx, "GetLowestProcessedBbnHeight", "")
+	result, err := d.next.GetLowestProcessedBbnHeight(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DetectProcessedHeightGaps(
+	ctx context.Context, from, to uint64,
+) ([]*model.BbnHeightRange, error) {
+	ctx, call := d.start(ctx, "DetectProcessedHeightGaps", "from, to")
+	result, err := d.next.DetectProcessedHeightGaps(ctx, from, to)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) CountStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64,
+) (uint64, error) {
+	ctx, call := d.start(ctx, "CountStuckDelegations", "state, before")
+	result, err := d.next.CountStuckDelegations(ctx, state, before)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) FindStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	ctx, call := d.start(ctx, "FindStuckDelegations", "state, before, limit")
+	result, err := d.next.FindStuckDelegations(ctx, state, before, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveStuckDelegationReport(
+	ctx context.Context, report *model.StuckDelegationReport,
+) error {
+	ctx, call := d.start(ctx, "SaveStuckDelegationReport", "report")
+	err := d.next.SaveStuckDelegationReport(ctx, report)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveRawEventCapture(ctx context.Context, capture *model.RawEventCapture) error {
+	ctx, call := d.start(ctx, "SaveRawEventCapture", "capture")
+	err := d.next.SaveRawEventCapture(ctx, capture)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveBbnEventDeadLetter(ctx context.Context, deadLetter *model.BbnEventDeadLetter) error {
+	ctx, call := d.start(ctx, "SaveBbnEventDeadLetter", "dead_letter")
+	err := d.next.SaveBbnEventDeadLetter(ctx, deadLetter)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	ctx, call := d.start(ctx, "SaveOutboxEvent", "event")
+	err := d.next.SaveOutboxEvent(ctx, event)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetUnsentOutboxEvents(
+	ctx context.Context, createdAfter int64, afterId string, limit uint64,
+) ([]*model.OutboxEvent, error) {
+	ctx, call := d.start(ctx, "GetUnsentOutboxEvents", "createdAfter, afterId, limit")
+	result, err := d.next.GetUnsentOutboxEvents(ctx, createdAfter, afterId, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) MarkOutboxEventSent(ctx context.Context, id string, sentAt int64) error {
+	ctx, call := d.start(ctx, "MarkOutboxEventSent", "id, sentAt")
+	err := d.next.MarkOutboxEventSent(ctx, id, sentAt)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) MarkOutboxEventFailed(
+	ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool,
+) error {
+	ctx, call := d.start(ctx, "MarkOutboxEventFailed", "id, lastError, nextAttemptAt, poison")
+	err := d.next.MarkOutboxEventFailed(ctx, id, lastError, nextAttemptAt, poison)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetPoisonOutboxEvents(ctx context.Context) ([]*model.OutboxEvent, error) {
+	ctx, call := d.start(ctx, "GetPoisonOutboxEvents", "")
+	result, err := d.next.GetPoisonOutboxEvents(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) RequeuePoisonOutboxEvents(ctx context.Context) (uint64, error) {
+	ctx, call := d.start(ctx, "RequeuePoisonOutboxEvents", "")
+	result, err := d.next.RequeuePoisonOutboxEvents(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetOutboxStats(ctx context.Context) (*model.OutboxStats, error) {
+	ctx, call := d.start(ctx, "GetOutboxStats", "")
+	result, err := d.next.GetOutboxStats(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetDelegationOutboxEvents(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.OutboxEvent, error) {
+	ctx, call := d.start(ctx, "GetDelegationOutboxEvents", "stakingTxHashHex")
+	result, err := d.next.GetDelegationOutboxEvents(ctx, stakingTxHashHex)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetOutboxSequence(
+	ctx context.Context, stakingTxHashHex string,
+) (uint64, error) {
+	ctx, call := d.start(ctx, "GetOutboxSequence", "stakingTxHashHex")
+	result, err := d.next.GetOutboxSequence(ctx, stakingTxHashHex)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetDelegationStatsByState(
+	ctx context.Context,
+) ([]*model.DelegationStateStats, error) {
+	ctx, call := d.start(ctx, "GetDelegationStatsByState", "")
+	result, err := d.next.GetDelegationStatsByState(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveGlobalStats(ctx context.Context, stats *model.GlobalStats) error {
+	ctx, call := d.start(ctx, "SaveGlobalStats", "stats")
+	err := d.next.SaveGlobalStats(ctx, stats)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetGlobalStats(ctx context.Context) (*model.GlobalStats, error) {
+	ctx, call := d.start(ctx, "GetGlobalStats", "")
+	result, err := d.next.GetGlobalStats(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) SaveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) error {
+	ctx, call := d.start(ctx, "SaveBootstrap", "bootstrap")
+	err := d.next.SaveBootstrap(ctx, bootstrap)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) GetBootstrap(ctx context.Context) (*model.Bootstrap, error) {
+	ctx, call := d.start(ctx, "GetBootstrap", "")
+	result, err := d.next.GetBootstrap(ctx)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) AcquireLock(
+	ctx context.Context, name, owner string, ttl time.Duration,
+) error {
+	ctx, call := d.start(ctx, "AcquireLock", "name, owner, ttl")
+	err := d.next.AcquireLock(ctx, name, owner, ttl)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) ReleaseLock(ctx context.Context, name, owner string) error {
+	ctx, call := d.start(ctx, "ReleaseLock", "name, owner")
+	err := d.next.ReleaseLock(ctx, name, owner)
+	return d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) CountPrunableBTCDelegations(
+	ctx context.Context, before int64,
+) (uint64, error) {
+	ctx, call := d.start(ctx, "CountPrunableBTCDelegations", "before")
+	result, err := d.next.CountPrunableBTCDelegations(ctx, before)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) ArchivePrunableBTCDelegations(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	ctx, call := d.start(ctx, "ArchivePrunableBTCDelegations", "before, limit")
+	result, err := d.next.ArchivePrunableBTCDelegations(ctx, before, limit)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) CountPrunableArchivedTimeLocks(
+	ctx context.Context, before int64,
+) (uint64, error) {
+	ctx, call := d.start(ctx, "CountPrunableArchivedTimeLocks", "before")
+	result, err := d.next.CountPrunableArchivedTimeLocks(ctx, before)
+	return result, d.record(ctx, call, err)
+}
+
+func (d *MetricsDatabase) DeletePrunableArchivedTimeLocks(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	ctx, call := d.start(ctx, "DeletePrunableArchivedTimeLocks", "before, limit")
+	result, err := d.next.DeletePrunableArchivedTimeLocks(ctx, before, limit)
+	return result, d.record(ctx, call, err)
+}
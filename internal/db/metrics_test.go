@@ -0,0 +1,161 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{&DuplicateKeyError{Key: "key"}, ErrorClassDuplicateKey},
+		{mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, ErrorClassDuplicateKey},
+		{fmt.Errorf("wrapped: %w", &NotFoundError{Key: "key"}), ErrorClassNotFound},
+		{mongo.ErrNoDocuments, ErrorClassNotFound},
+		{&StateTransitionError{StakingTxHash: "key"}, ErrorClassStateTransition},
+		{context.DeadlineExceeded, ErrorClassTimeout},
+		{mongo.CommandError{Labels: []string{"NetworkError"}}, ErrorClassTransientNetwork},
+		{mongo.CommandError{Labels: []string{"TransientTransactionError"}}, ErrorClassTransientNetwork},
+		{errors.New("invalid"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.class, errorClass(tt.err), tt.err.Error())
+	}
+}
+
+// retryingDatabase retries every call once, as a transaction hitting a
+// transient error does
+type retryingDatabase struct {
+	DbInterface
+}
+
+func (d *retryingDatabase) Ping(ctx context.Context) error {
+	recordRetry(ctx)
+	return nil
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	metrics.Init()
+	var logs bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { log.Logger = previousLogger })
+
+	// every call is slow with the smallest threshold
+	dbClient := NewMetricsDatabase(&retryingDatabase{}, &config.DbConfig{
+		SlowQueryThreshold:   time.Nanosecond,
+		SlowQueryLogInterval: time.Hour,
+	})
+	for i := 0; i < 3; i++ {
+		require.NoError(t, dbClient.Ping(context.Background()))
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 1)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "warn", entry["level"])
+	require.Equal(t, "Ping", entry["db_method"])
+	require.Equal(t, float64(1), entry["retries"])
+	require.Equal(t, float64(0), entry["suppressed"])
+	require.Contains(t, entry, "duration")
+
+	// the calls not logged are reported by the next log of the method
+	suppressed, ok := dbClient.allowSlowQueryLog("Ping", time.Now().Add(2*time.Hour))
+	require.True(t, ok)
+	require.Equal(t, 2, suppressed)
+	_, ok = dbClient.allowSlowQueryLog("GetLatestBTCHeader", time.Now())
+	require.True(t, ok)
+
+	// a call below the threshold is neither logged nor counted
+	logs.Reset()
+	dbClient = NewMetricsDatabase(&retryingDatabase{}, &config.DbConfig{})
+	require.NoError(t, dbClient.Ping(context.Background()))
+	require.Empty(t, logs.String())
+}
+
+// writingDatabase succeeds in marking the heights and fails to save the outbox
+// events
+type writingDatabase struct {
+	DbInterface
+}
+
+func (d *writingDatabase) MarkBbnHeightProcessed(context.Context, uint64) error {
+	return nil
+}
+
+func (d *writingDatabase) SaveOutboxEvent(context.Context, *model.OutboxEvent) error {
+	return &DuplicateKeyError{Key: "key"}
+}
+
+func TestWriteStats(t *testing.T) {
+	metrics.Init()
+	dbClient := NewMetricsDatabase(&writingDatabase{}, &config.DbConfig{})
+
+	ctx, stats := WithWriteStats(context.Background())
+	require.NoError(t, dbClient.MarkBbnHeightProcessed(ctx, 1))
+	require.NoError(t, dbClient.MarkBbnHeightProcessed(ctx, 2))
+	require.Error(t, dbClient.SaveOutboxEvent(ctx, &model.OutboxEvent{}))
+	// a write without stats in its context is not counted
+	require.NoError(t, dbClient.MarkBbnHeightProcessed(context.Background(), 3))
+
+	require.Equal(t, map[string]uint64{model.ProcessedBbnHeightsCollection: 2}, stats.Writes())
+}
+
+func TestWriteCollectionsMethods(t *testing.T) {
+	dbInterface := reflect.TypeOf((*DbInterface)(nil)).Elem()
+	for method := range writeCollections {
+		_, ok := dbInterface.MethodByName(method)
+		require.True(t, ok, method)
+	}
+}
+
+// expiredDatabase streams a single expired delegation
+type expiredDatabase struct {
+	DbInterface
+}
+
+func (d *expiredDatabase) ForEachExpiredDelegation(
+	ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error,
+) error {
+	return fn(model.TimeLockDocument{StakingTxHashHex: "key"})
+}
+
+func TestForEachExpiredDelegationExcludesCallback(t *testing.T) {
+	metrics.Init()
+	var logs bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { log.Logger = previousLogger })
+
+	dbClient := NewMetricsDatabase(&expiredDatabase{}, &config.DbConfig{
+		SlowQueryThreshold: 50 * time.Millisecond,
+	})
+
+	// a slow callback failing is neither a slow nor a failed db operation
+	fnErr := errors.New("failed to expire")
+	err := dbClient.ForEachExpiredDelegation(context.Background(), 1, func(model.TimeLockDocument) error {
+		time.Sleep(100 * time.Millisecond)
+		return fnErr
+	})
+	require.Equal(t, fnErr, err)
+	require.Empty(t, logs.String())
+}
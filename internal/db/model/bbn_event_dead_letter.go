@@ -0,0 +1,43 @@
+package model
+
+import "fmt"
+
+// BbnEventDeadLetter is a BBN event set aside by the block processor as its
+// processing failed with a permanent error, retrying it being of no use. The
+// writes of the event made before the failure are not rolled back.
+type BbnEventDeadLetter struct {
+	// Id is the BBN height and the index of the event in the block
+	Id               string `bson:"_id"`
+	BbnHeight        uint64 `bson:"bbn_height"`
+	EventIndex       int    `bson:"event_index"`
+	EventType        string `bson:"event_type"`
+	StakingTxHashHex string `bson:"staking_tx_hash_hex,omitempty"`
+	// Attributes are the raw attributes of the event, in order, the values
+	// redacted by the event capture redacted
+	Attributes []RawEventAttribute `bson:"attributes"`
+	Error      string              `bson:"error"`
+	CreatedAt  int64               `bson:"created_at"` // epoch time in seconds
+}
+
+// NewBbnEventDeadLetter returns the dead letter of the event at the index of
+// the block at the given height, failed with the error
+func NewBbnEventDeadLetter(
+	height uint64,
+	index int,
+	eventType string,
+	stakingTxHashHex string,
+	attributes []RawEventAttribute,
+	processErr error,
+	createdAt int64,
+) *BbnEventDeadLetter {
+	return &BbnEventDeadLetter{
+		Id:               fmt.Sprintf("%d:%d", height, index),
+		BbnHeight:        height,
+		EventIndex:       index,
+		EventType:        eventType,
+		StakingTxHashHex: stakingTxHashHex,
+		Attributes:       attributes,
+		Error:            processErr.Error(),
+		CreatedAt:        createdAt,
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+// BootstrapId is the id of the single bootstrap document
+const BootstrapId = "bootstrap"
+
+// Bootstrap is the progress of the bootstrap the indexer runs at startup,
+// syncing the finality providers and params registered before the processed
+// BBN heights, so that a restarted bootstrap resumes where it stopped
+type Bootstrap struct {
+	Id string `bson:"_id"`
+	// ParamsSynced is set once the staking and checkpoint params are saved
+	ParamsSynced bool `bson:"params_synced"`
+	// FinalityProvidersCursor is the hex encoded key of the next page of the
+	// chain finality providers to sync
+	FinalityProvidersCursor string `bson:"finality_providers_cursor"`
+	// FinalityProvidersSynced is the number of chain finality providers
+	// synced so far
+	FinalityProvidersSynced uint64 `bson:"finality_providers_synced"`
+	StartedAt               int64  `bson:"started_at"` // epoch time in seconds
+	// CompletedAt is zero until the bootstrap completes, in epoch seconds
+	CompletedAt int64 `bson:"completed_at"`
+	// StartHeight is the BBN height the block processing of the fresh
+	// database starts from, decided once for the restarts before the first
+	// processed block to start from the same height
+	StartHeight uint64 `bson:"start_height"`
+	// StartHeightSource tells how the start height was decided
+	StartHeightSource string `bson:"start_height_source"`
+}
+
+// IsCompleted returns whether the bootstrap completed
+func (b *Bootstrap) IsCompleted() bool {
+	return b.CompletedAt != 0
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BTCDerivedChange records a change of a delegation that the indexer derived
+// from a BTC block (spends of staking, unbonding and slashing outputs and
+// timelock expiries) together with what is needed to undo it if the block
+// gets reorged out.
+type BTCDerivedChange struct {
+	Id               primitive.ObjectID       `bson:"_id,omitempty"`
+	StakingTxHashHex string                   `bson:"staking_tx_hash_hex"`
+	BtcHeight        uint64                   `bson:"btc_height"`
+	PreviousState    types.DelegationState    `bson:"previous_state,omitempty"`
+	PreviousSubState types.DelegationSubState `bson:"previous_sub_state,omitempty"`
+	// PreviousSlashingTx is set if the change recorded a slashing tx
+	PreviousSlashingTx *SlashingTx `bson:"previous_slashing_tx,omitempty"`
+	// CreatedTimeLock is set if the change saved a new timelock document
+	CreatedTimeLock *TimeLockDocument `bson:"created_timelock,omitempty"`
+	// DeletedTimeLock is set if the change deleted a timelock document
+	DeletedTimeLock *TimeLockDocument `bson:"deleted_timelock,omitempty"`
+}
+
+// NewBTCStateChange returns the change of a delegation's state derived from
+// the BTC block at the given height.
+func NewBTCStateChange(
+	delegation *BTCDelegationSummary, btcHeight uint64,
+) *BTCDerivedChange {
+	return &BTCDerivedChange{
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		BtcHeight:        btcHeight,
+		PreviousState:    delegation.State,
+		PreviousSubState: delegation.SubState,
+	}
+}
@@ -0,0 +1,19 @@
+package model
+
+import "github.com/btcsuite/btcd/wire"
+
+// BTCHeader is a BTC block header processed by the indexer. The stored headers
+// form the chain used to detect BTC reorgs.
+type BTCHeader struct {
+	Height   uint64 `bson:"_id"` // Primary key
+	Hash     string `bson:"hash"`
+	PrevHash string `bson:"prev_hash"`
+}
+
+func NewBTCHeader(height uint64, header *wire.BlockHeader) *BTCHeader {
+	return &BTCHeader{
+		Height:   height,
+		Hash:     header.BlockHash().String(),
+		PrevHash: header.PrevBlock.String(),
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
 	"github.com/btcsuite/btcd/btcutil"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type CovenantSignature struct {
 	CovenantBtcPkHex string `bson:"covenant_btc_pk_hex"`
 	SignatureHex     string `bson:"signature_hex"`
+	// Verified tells whether the signature was found valid once checked
+	// against the covenant pk, and is missing until checked
+	Verified *bool `bson:"verified,omitempty"`
 }
 
 type BTCDelegationCreatedBbnBlock struct {
@@ -28,12 +32,15 @@ type SlashingTx struct {
 }
 
 type BTCDelegationDetails struct {
-	StakingTxHashHex            string                       `bson:"_id"` // Primary key
-	StakingTxHex                string                       `bson:"staking_tx_hex"`
-	StakingTime                 uint32                       `bson:"staking_time"`
-	StakingAmount               uint64                       `bson:"staking_amount"`
-	StakingOutputIdx            uint32                       `bson:"staking_output_idx"`
-	StakerBtcPkHex              string                       `bson:"staker_btc_pk_hex"`
+	StakingTxHashHex string `bson:"_id"` // Primary key
+	StakingTxHex     string `bson:"staking_tx_hex"`
+	StakingTime      uint32 `bson:"staking_time"`
+	StakingAmount    uint64 `bson:"staking_amount"`
+	StakingOutputIdx uint32 `bson:"staking_output_idx"`
+	StakerBtcPkHex   string `bson:"staker_btc_pk_hex"`
+	// StakerBabylonAddress is the BBN address of the staker. It is missing on
+	// delegations indexed before it existed.
+	StakerBabylonAddress        string                       `bson:"staker_babylon_address,omitempty"`
 	FinalityProviderBtcPksHex   []string                     `bson:"finality_provider_btc_pks_hex"`
 	StartHeight                 uint32                       `bson:"start_height"`
 	EndHeight                   uint32                       `bson:"end_height"`
@@ -45,6 +52,67 @@ type BTCDelegationDetails struct {
 	CovenantUnbondingSignatures []CovenantSignature          `bson:"covenant_unbonding_signatures"`
 	BTCDelegationCreatedBlock   BTCDelegationCreatedBbnBlock `bson:"btc_delegation_created_bbn_block"`
 	SlashingTx                  SlashingTx                   `bson:"slashing_tx"`
+	// StateUpdatedAt is the time the delegation entered its current state,
+	// in epoch seconds. It is missing on delegations indexed before it existed.
+	StateUpdatedAt int64 `bson:"state_updated_at,omitempty"`
+	// EventSequence is the sequence number of the last event recorded in the
+	// outbox for the delegation
+	EventSequence uint64 `bson:"event_sequence,omitempty"`
+}
+
+// BTCDelegationSummary holds the fields of a delegation but its
+// transactions, which make up most of its size, for the reads listing
+// delegations without using them
+type BTCDelegationSummary struct {
+	StakingTxHashHex          string                       `bson:"_id"`
+	StakingTime               uint32                       `bson:"staking_time"`
+	StakingAmount             uint64                       `bson:"staking_amount"`
+	StakingOutputIdx          uint32                       `bson:"staking_output_idx"`
+	StakerBtcPkHex            string                       `bson:"staker_btc_pk_hex"`
+	StakerBabylonAddress      string                       `bson:"staker_babylon_address,omitempty"`
+	FinalityProviderBtcPksHex []string                     `bson:"finality_provider_btc_pks_hex"`
+	StartHeight               uint32                       `bson:"start_height"`
+	EndHeight                 uint32                       `bson:"end_height"`
+	State                     types.DelegationState        `bson:"state"`
+	SubState                  types.DelegationSubState     `bson:"sub_state,omitempty"`
+	ParamsVersion             uint32                       `bson:"params_version"`
+	UnbondingTime             uint32                       `bson:"unbonding_time"`
+	BTCDelegationCreatedBlock BTCDelegationCreatedBbnBlock `bson:"btc_delegation_created_bbn_block"`
+	StateUpdatedAt            int64                        `bson:"state_updated_at,omitempty"`
+	EventSequence             uint64                       `bson:"event_sequence,omitempty"`
+	// CovenantUnbondingSignaturesCount is the number of unbonding covenant
+	// signatures of the delegation, read without the signatures
+	CovenantUnbondingSignaturesCount int `bson:"covenant_unbonding_signatures_count"`
+}
+
+// BTCDelegationSummaryProjection is the projection of the delegation
+// documents read as BTCDelegationSummary
+var BTCDelegationSummaryProjection = bson.M{
+	"staking_time":                     1,
+	"staking_amount":                   1,
+	"staking_output_idx":               1,
+	"staker_btc_pk_hex":                1,
+	"staker_babylon_address":           1,
+	"finality_provider_btc_pks_hex":    1,
+	"start_height":                     1,
+	"end_height":                       1,
+	"state":                            1,
+	"sub_state":                        1,
+	"params_version":                   1,
+	"unbonding_time":                   1,
+	"btc_delegation_created_bbn_block": 1,
+	"state_updated_at":                 1,
+	"event_sequence":                   1,
+	"covenant_unbonding_signatures_count": bson.M{
+		"$size": bson.M{"$ifNull": bson.A{"$covenant_unbonding_signatures", bson.A{}}},
+	},
+}
+
+// ArchivedBTCDelegation is a delegation pruned once in a terminal state for
+// long enough, kept under its staking tx hash
+type ArchivedBTCDelegation struct {
+	BTCDelegationDetails `bson:",inline"`
+	ArchivedAt           int64 `bson:"archived_at"`
 }
 
 func FromEventBTCDelegationCreated(
@@ -97,10 +165,20 @@ func FromEventBTCDelegationCreated(
 		)
 	}
 
-	stakingValue := btcutil.Amount(stakingTx.TxOut[stakingOutputIdx].Value)
+	stakingOutput, err := utils.GetTxOutput(stakingTx, uint32(stakingOutputIdx))
+	if err != nil {
+		return nil, types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("%w: %w", types.ErrInvalidStakingTx, err),
+		)
+	}
+
+	stakingValue := btcutil.Amount(stakingOutput.Value)
+	stakingTxHash := stakingTx.TxHash()
 
 	return &BTCDelegationDetails{
-		StakingTxHashHex:            stakingTx.TxHash().String(),
+		StakingTxHashHex:            types.StakingTxHashFromChainHash(&stakingTxHash).String(),
 		StakingTxHex:                event.StakingTxHex,
 		StakingTime:                 uint32(stakingTime),
 		StakingAmount:               uint64(stakingValue),
@@ -118,9 +196,12 @@ func FromEventBTCDelegationCreated(
 			Height:    bbnBlockHeight,
 			Timestamp: bbnBlockTime,
 		},
+		StateUpdatedAt: bbnBlockTime,
 	}, nil
 }
 
+// FromEventBTCDelegationInclusionProofReceived returns the staking heights the
+// inclusion proof sets, the state moving along the event transition
 func FromEventBTCDelegationInclusionProofReceived(
 	event *bbntypes.EventBTCDelegationInclusionProofReceived,
 ) *BTCDelegationDetails {
@@ -129,7 +210,39 @@ func FromEventBTCDelegationInclusionProofReceived(
 	return &BTCDelegationDetails{
 		StartHeight: uint32(startHeight),
 		EndHeight:   uint32(endHeight),
-		State:       types.DelegationState(event.NewState),
+	}
+}
+
+// StateSince returns the time the delegation entered its current state, in
+// epoch seconds. Delegations indexed before the state time was recorded fall
+// back to their creation time.
+func (d *BTCDelegationDetails) StateSince() int64 {
+	if d.StateUpdatedAt != 0 {
+		return d.StateUpdatedAt
+	}
+	return d.BTCDelegationCreatedBlock.Timestamp
+}
+
+// Summary returns the summary of the delegation
+func (d *BTCDelegationDetails) Summary() *BTCDelegationSummary {
+	return &BTCDelegationSummary{
+		StakingTxHashHex:                 d.StakingTxHashHex,
+		StakingTime:                      d.StakingTime,
+		StakingAmount:                    d.StakingAmount,
+		StakingOutputIdx:                 d.StakingOutputIdx,
+		StakerBtcPkHex:                   d.StakerBtcPkHex,
+		StakerBabylonAddress:             d.StakerBabylonAddress,
+		FinalityProviderBtcPksHex:        d.FinalityProviderBtcPksHex,
+		StartHeight:                      d.StartHeight,
+		EndHeight:                        d.EndHeight,
+		State:                            d.State,
+		SubState:                         d.SubState,
+		ParamsVersion:                    d.ParamsVersion,
+		UnbondingTime:                    d.UnbondingTime,
+		BTCDelegationCreatedBlock:        d.BTCDelegationCreatedBlock,
+		StateUpdatedAt:                   d.StateUpdatedAt,
+		EventSequence:                    d.EventSequence,
+		CovenantUnbondingSignaturesCount: len(d.CovenantUnbondingSignatures),
 	}
 }
 
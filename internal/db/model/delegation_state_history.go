@@ -0,0 +1,109 @@
+package model
+
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Triggers of the state transitions not caused by a BBN event, which are
+// recorded with the event type
+const (
+	// StateTransitionTriggerBtcSpend is a spend of a staking, unbonding or
+	// slashing change output
+	StateTransitionTriggerBtcSpend = "btc_spend"
+	// StateTransitionTriggerExpiry is the expiry of a timelock
+	StateTransitionTriggerExpiry = "expiry"
+	// StateTransitionTriggerBtcReorg is the rollback of the changes derived
+	// from reorged BTC blocks
+	StateTransitionTriggerBtcReorg = "btc_reorg"
+	// StateTransitionTriggerAdmin is an operator action, e.g. a state override
+	StateTransitionTriggerAdmin = "admin"
+	// StateTransitionTriggerReconciliation is a fix applied by the
+	// reconciliation with the BBN chain state
+	StateTransitionTriggerReconciliation = "reconciliation"
+)
+
+// DelegationStateTransition records a change of a delegation's state. The
+// from state is empty for the creation of the delegation.
+type DelegationStateTransition struct {
+	Id               primitive.ObjectID       `bson:"_id,omitempty"`
+	StakingTxHashHex string                   `bson:"staking_tx_hash_hex"`
+	FromState        types.DelegationState    `bson:"from_state,omitempty"`
+	ToState          types.DelegationState    `bson:"to_state"`
+	SubState         types.DelegationSubState `bson:"sub_state,omitempty"`
+	Trigger          string                   `bson:"trigger"`
+	BbnHeight        uint64                   `bson:"bbn_height,omitempty"`
+	BtcHeight        uint64                   `bson:"btc_height,omitempty"`
+	// Reason and Operator document a manual override of the state, by whom
+	// and why it was made
+	Reason    string `bson:"reason,omitempty"`
+	Operator  string `bson:"operator,omitempty"`
+	CreatedAt int64  `bson:"created_at"`
+}
+
+// NewBbnStateTransition returns the transition of a delegation's state caused
+// by an event of the BBN block at the given height
+func NewBbnStateTransition(
+	stakingTxHashHex string,
+	fromState, toState types.DelegationState,
+	subState types.DelegationSubState,
+	eventType string,
+	bbnHeight uint64,
+	createdAt int64,
+) *DelegationStateTransition {
+	return &DelegationStateTransition{
+		StakingTxHashHex: stakingTxHashHex,
+		FromState:        fromState,
+		ToState:          toState,
+		SubState:         subState,
+		Trigger:          eventType,
+		BbnHeight:        bbnHeight,
+		CreatedAt:        createdAt,
+	}
+}
+
+// NewBtcStateTransition returns the transition of a delegation's state derived
+// from the BTC block at the given height
+func NewBtcStateTransition(
+	stakingTxHashHex string,
+	fromState, toState types.DelegationState,
+	subState types.DelegationSubState,
+	trigger string,
+	btcHeight uint64,
+	createdAt int64,
+) *DelegationStateTransition {
+	return &DelegationStateTransition{
+		StakingTxHashHex: stakingTxHashHex,
+		FromState:        fromState,
+		ToState:          toState,
+		SubState:         subState,
+		Trigger:          trigger,
+		BtcHeight:        btcHeight,
+		CreatedAt:        createdAt,
+	}
+}
+
+// ArchivedTimeLockDocument is a timelock document deleted once of no use,
+// kept for the history of its delegation under its original id
+type ArchivedTimeLockDocument struct {
+	Id               primitive.ObjectID `bson:"_id"`
+	TimeLockDocument `bson:",inline"`
+	// ArchiveReason is why the timelock was deleted, e.g. expired
+	ArchiveReason string `bson:"archive_reason"`
+	ArchivedAt    int64  `bson:"archived_at"`
+}
+
+const (
+	TimeLockArchiveReasonExpired  = "expired"
+	TimeLockArchiveReasonOrphaned = "orphaned"
+)
+
+// StateTransitionPeriodStats aggregates the transitions of the delegations to
+// a state within a period, along with the staked amounts they concern
+type StateTransitionPeriodStats struct {
+	// PeriodStart is the start of the period, in epoch seconds
+	PeriodStart     int64                 `bson:"period_start"`
+	ToState         types.DelegationState `bson:"to_state"`
+	TransitionCount uint64                `bson:"transition_count"`
+	TotalSat        uint64                `bson:"total_sat"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// bsonFields returns the bson names of the fields of the struct type
+func bsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("bson"), ",")
+		fields[name] = true
+	}
+	return fields
+}
+
+func TestBTCDelegationSummaryProjection(t *testing.T) {
+	detailsFields := bsonFields(reflect.TypeOf(BTCDelegationDetails{}))
+	for field := range bsonFields(reflect.TypeOf(BTCDelegationSummary{})) {
+		if field == "_id" {
+			continue
+		}
+		// every field of the summary is projected, under its name in the
+		// delegation documents but the computed count
+		require.Contains(t, BTCDelegationSummaryProjection, field)
+		if field != "covenant_unbonding_signatures_count" {
+			require.True(t, detailsFields[field], field)
+		}
+	}
+	require.Len(t, BTCDelegationSummaryProjection, len(bsonFields(reflect.TypeOf(BTCDelegationSummary{})))-1)
+}
@@ -4,12 +4,40 @@ import (
 	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
 )
 
+// BabylonBsnId is the BSN id of finality providers securing the Babylon chain
+// itself. Registration events of Babylon finality providers carry no consumer
+// id, hence the empty value.
+const BabylonBsnId = ""
+
 type FinalityProviderDetails struct {
 	BtcPk          string      `bson:"_id"` // Primary key
 	BabylonAddress string      `bson:"babylon_address"`
 	Commission     string      `bson:"commission"`
 	State          string      `bson:"state"`
 	Description    Description `bson:"description"`
+	BsnId          string      `bson:"bsn_id"`
+}
+
+// FinalityProviderStats aggregates the active delegations of a finality
+// provider
+type FinalityProviderStats struct {
+	ActiveDelegations   uint64 `bson:"active_delegations"`
+	ActiveStakingAmount uint64 `bson:"active_staking_amount"`
+}
+
+// FinalityProviderActiveStake is the staking amount of the active
+// delegations of a finality provider
+type FinalityProviderActiveStake struct {
+	BtcPk               string `bson:"_id"`
+	ActiveStakingAmount uint64 `bson:"active_staking_amount"`
+}
+
+// FinalityProviderStakeDistribution lists the finality providers holding the
+// most active stake, along with the active stake of all of them. A delegation
+// to several finality providers counts for each of them.
+type FinalityProviderStakeDistribution struct {
+	Top                      []*FinalityProviderActiveStake `bson:"top"`
+	TotalActiveStakingAmount uint64                         `bson:"total_active_staking_amount"`
 }
 
 // Description represents the nested description field
@@ -23,6 +51,7 @@ type Description struct {
 
 func FromEventFinalityProviderCreated(
 	event *bbntypes.EventFinalityProviderCreated,
+	bsnId string,
 ) *FinalityProviderDetails {
 	return &FinalityProviderDetails{
 		BtcPk:          event.BtcPkHex,
@@ -36,6 +65,7 @@ func FromEventFinalityProviderCreated(
 		},
 		Commission: event.Commission,
 		State:      bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE.String(),
+		BsnId:      bsnId,
 	}
 }
 
@@ -0,0 +1,64 @@
+package model
+
+// FinalityProviderVotingPowerChange records a change of a finality provider's
+// active set membership or voting power observed at a given BBN height
+type FinalityProviderVotingPowerChange struct {
+	FinalityProviderBtcPkHex string `bson:"fp_btc_pk_hex"`
+	BbnHeight                uint64 `bson:"bbn_height"`
+	Active                   bool   `bson:"active"`
+	VotingPower              uint64 `bson:"voting_power"`
+}
+
+// FinalityProviderActivationPeriod represents an interval of BBN heights during
+// which a finality provider was part of the active set. EndHeight is zero if the
+// finality provider is still active.
+type FinalityProviderActivationPeriod struct {
+	StartHeight      uint64 `json:"start_height"`
+	StartVotingPower uint64 `json:"start_voting_power"`
+	EndHeight        uint64 `json:"end_height"`
+	EndVotingPower   uint64 `json:"end_voting_power"`
+}
+
+func NewFinalityProviderVotingPowerChange(
+	fpBtcPkHex string, bbnHeight uint64, active bool, votingPower uint64,
+) *FinalityProviderVotingPowerChange {
+	return &FinalityProviderVotingPowerChange{
+		FinalityProviderBtcPkHex: fpBtcPkHex,
+		BbnHeight:                bbnHeight,
+		Active:                   active,
+		VotingPower:              votingPower,
+	}
+}
+
+// ToActivationPeriods folds voting power changes sorted by ascending height
+// into the periods during which the finality provider was active.
+func ToActivationPeriods(
+	changes []*FinalityProviderVotingPowerChange,
+) []*FinalityProviderActivationPeriod {
+	var periods []*FinalityProviderActivationPeriod
+	var current *FinalityProviderActivationPeriod
+
+	for _, change := range changes {
+		switch {
+		case change.Active && current == nil:
+			current = &FinalityProviderActivationPeriod{
+				StartHeight:      change.BbnHeight,
+				StartVotingPower: change.VotingPower,
+				EndVotingPower:   change.VotingPower,
+			}
+		case change.Active:
+			// Voting power changed while staying in the active set
+			current.EndVotingPower = change.VotingPower
+		case current != nil:
+			current.EndHeight = change.BbnHeight
+			periods = append(periods, current)
+			current = nil
+		}
+	}
+
+	if current != nil {
+		periods = append(periods, current)
+	}
+
+	return periods
+}
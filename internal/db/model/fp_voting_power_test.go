@@ -0,0 +1,71 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToActivationPeriods(t *testing.T) {
+	testCases := []struct {
+		name     string
+		changes  []*FinalityProviderVotingPowerChange
+		expected []*FinalityProviderActivationPeriod
+	}{
+		{
+			name:     "no change",
+			changes:  nil,
+			expected: nil,
+		},
+		{
+			name: "still active",
+			changes: []*FinalityProviderVotingPowerChange{
+				{BbnHeight: 10, Active: true, VotingPower: 100},
+			},
+			expected: []*FinalityProviderActivationPeriod{
+				{StartHeight: 10, StartVotingPower: 100, EndVotingPower: 100},
+			},
+		},
+		{
+			name: "voting power changes within a period",
+			changes: []*FinalityProviderVotingPowerChange{
+				{BbnHeight: 10, Active: true, VotingPower: 100},
+				{BbnHeight: 15, Active: true, VotingPower: 150},
+				{BbnHeight: 20, Active: false},
+			},
+			expected: []*FinalityProviderActivationPeriod{
+				{StartHeight: 10, StartVotingPower: 100, EndHeight: 20, EndVotingPower: 150},
+			},
+		},
+		{
+			name: "several periods",
+			changes: []*FinalityProviderVotingPowerChange{
+				{BbnHeight: 10, Active: true, VotingPower: 100},
+				{BbnHeight: 20, Active: false},
+				// leaving the set again while inactive opens no period
+				{BbnHeight: 25, Active: false},
+				{BbnHeight: 30, Active: true, VotingPower: 50},
+			},
+			expected: []*FinalityProviderActivationPeriod{
+				{StartHeight: 10, StartVotingPower: 100, EndHeight: 20, EndVotingPower: 100},
+				{StartHeight: 30, StartVotingPower: 50, EndVotingPower: 50},
+			},
+		},
+		{
+			name: "history starting inactive",
+			changes: []*FinalityProviderVotingPowerChange{
+				{BbnHeight: 5, Active: false},
+				{BbnHeight: 10, Active: true, VotingPower: 100},
+			},
+			expected: []*FinalityProviderActivationPeriod{
+				{StartHeight: 10, StartVotingPower: 100, EndVotingPower: 100},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, ToActivationPeriods(tc.changes))
+		})
+	}
+}
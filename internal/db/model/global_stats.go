@@ -0,0 +1,30 @@
+package model
+
+// GlobalStatsId is the id of the single global stats document
+const GlobalStatsId = "global"
+
+// GlobalStats summarizes the indexed delegations and finality providers,
+// together with how far the indexing lags behind the BBN and BTC chains
+type GlobalStats struct {
+	Id string `bson:"_id"`
+	// ActiveTvl is the total staking amount of the active delegations, in sats
+	ActiveTvl                uint64            `bson:"active_tvl"`
+	DelegationsByState       map[string]uint64 `bson:"delegations_by_state"`
+	FinalityProvidersByState map[string]uint64 `bson:"finality_providers_by_state"`
+	LastProcessedBbnHeight   uint64            `bson:"last_processed_bbn_height"`
+	BbnTipHeight             uint64            `bson:"bbn_tip_height"`
+	// BbnLag is the number of BBN blocks left to process
+	BbnLag                 uint64 `bson:"bbn_lag"`
+	LastProcessedBtcHeight uint64 `bson:"last_processed_btc_height"`
+	BtcTipHeight           uint64 `bson:"btc_tip_height"`
+	// BtcLag is the number of BTC blocks left to process
+	BtcLag    uint64 `bson:"btc_lag"`
+	UpdatedAt int64  `bson:"updated_at"` // epoch time in seconds
+}
+
+// DelegationStateStats aggregates the delegations in a state
+type DelegationStateStats struct {
+	State         string `bson:"_id"`
+	Count         uint64 `bson:"count"`
+	StakingAmount uint64 `bson:"staking_amount"`
+}
@@ -2,4 +2,41 @@ package model
 
 type LastProcessedHeight struct {
 	Height uint64 `bson:"height"`
+	// BlockHash is the hash of the BBN block at Height, used to detect that
+	// the RPC node serves a different chain than the one processed so far
+	BlockHash string `bson:"block_hash,omitempty"`
+	// HaltReason is set once a BBN fork is detected, processing stays halted
+	// until an explicit resync
+	HaltReason string `bson:"halt_reason,omitempty"`
+	// ProcessingMarker is set while the block after Height is being processed,
+	// so that a crash mid-block can be told apart from a completed block
+	ProcessingMarker *BbnProcessingMarker `bson:"processing_marker,omitempty"`
+}
+
+// BbnProcessingMarker marks a BBN block whose processing started, along with
+// the indexes of its events applied so far
+type BbnProcessingMarker struct {
+	Height          uint64 `bson:"height"`
+	BlockHash       string `bson:"block_hash"`
+	StartedAt       int64  `bson:"started_at"`
+	ProcessedEvents []int  `bson:"processed_events,omitempty"`
+}
+
+func NewBbnProcessingMarker(height uint64, blockHash string, startedAt int64) *BbnProcessingMarker {
+	return &BbnProcessingMarker{
+		Height:    height,
+		BlockHash: blockHash,
+		StartedAt: startedAt,
+	}
+}
+
+// IsEventProcessed returns whether the event at the given index of the block
+// was already applied
+func (m *BbnProcessingMarker) IsEventProcessed(eventIndex int) bool {
+	for _, index := range m.ProcessedEvents {
+		if index == eventIndex {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,11 @@
+package model
+
+// Lock is held by a process for an operation that must not run alongside
+// others, until released or expired
+type Lock struct {
+	Name  string `bson:"_id"`
+	Owner string `bson:"owner"`
+	// ExpiresAt is when the lock is released if its owner did not renew it,
+	// in epoch seconds
+	ExpiresAt int64 `bson:"expires_at"`
+}
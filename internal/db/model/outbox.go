@@ -0,0 +1,213 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/dedup"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// OutboxEvent is a queue event recorded along with the delegation state
+// transition that triggers it, and relayed to the queue afterwards, so that
+// a crash or queue outage in between does not lose it
+type OutboxEvent struct {
+	// Id is unique per event type and delegation, so that reprocessing the
+	// transition does not record the event twice
+	Id               string `bson:"_id"`
+	EventType        string `bson:"event_type"`
+	StakingTxHashHex string `bson:"staking_tx_hash_hex"`
+	// IdempotencyKey is derived from the delegation, the transition and its
+	// height, so that consumers can deduplicate the deliveries of the event
+	IdempotencyKey string `bson:"idempotency_key"`
+	// Sequence increases with each event of the delegation, so that consumers
+	// can detect missed events
+	Sequence                  uint64   `bson:"sequence"`
+	StakerBtcPkHex            string   `bson:"staker_btc_pk_hex"`
+	FinalityProviderBtcPksHex []string `bson:"finality_provider_btc_pks_hex"`
+	StakingAmount             uint64   `bson:"staking_amount"`
+	StakingStartHeight        uint32   `bson:"staking_start_height"`
+	ParamsVersion             uint32   `bson:"params_version"`
+	// SubState tells early unbonding from the natural expiry of an unbonding event
+	SubState           string `bson:"sub_state,omitempty"`
+	UnbondingTxHashHex string `bson:"unbonding_tx_hash_hex,omitempty"`
+	// WithdrawableHeight is the BTC height at which an unbonding delegation
+	// is expected to become withdrawable
+	WithdrawableHeight uint32 `bson:"withdrawable_height,omitempty"`
+	// BtcHeight is the BTC height of a withdrawal event, at which the timelock
+	// expired or the delegation got withdrawn, and of the tx of a slashed
+	// funds event
+	BtcHeight uint32 `bson:"btc_height,omitempty"`
+	// SpendingTxHashHex is the tx withdrawing the delegation, or the tx of a
+	// slashed funds event
+	SpendingTxHashHex string `bson:"spending_tx_hash_hex,omitempty"`
+	// CreatedAt orders the events of the outbox
+	CreatedAt int64 `bson:"created_at"`        // epoch time in nanoseconds
+	SentAt    int64 `bson:"sent_at,omitempty"` // epoch time in seconds
+	// Attempts is the number of failed pushes of the event
+	Attempts      int    `bson:"attempts,omitempty"`
+	NextAttemptAt int64  `bson:"next_attempt_at,omitempty"` // epoch time in seconds
+	LastError     string `bson:"last_error,omitempty"`
+	// Poison events failed to be pushed too many times and are no longer
+	// retried until requeued
+	Poison bool `bson:"poison,omitempty"`
+}
+
+// OutboxSequence holds the sequence number of the last outbox event recorded
+// for a delegation before the sequence moved to the delegation document
+type OutboxSequence struct {
+	StakingTxHashHex string `bson:"_id"`
+	Sequence         uint64 `bson:"sequence"`
+}
+
+// OutboxStats summarizes the events of the outbox not pushed to the queue
+type OutboxStats struct {
+	Unsent         uint64 `bson:"unsent"`
+	OldestUnsentAt int64  `bson:"oldest_unsent_at"` // epoch time in nanoseconds
+	Poison         uint64 `bson:"poison"`
+}
+
+const (
+	OutboxEventTypeActiveStaking    = "active_staking"
+	OutboxEventTypeUnbondingStaking = "unbonding_staking"
+	OutboxEventTypeWithdrawable     = "withdrawable_staking"
+	OutboxEventTypeWithdrawn        = "withdrawn_staking"
+	// OutboxEventTypeSlashedStaking is relayed to the queue as an unbonding
+	// event
+	OutboxEventTypeSlashedStaking = "slashed_staking"
+	// OutboxEventTypeSlashedFunds follows the slashed output of a slashed
+	// delegation, emitted once per sub state of the slashed funds lifecycle
+	OutboxEventTypeSlashedFunds = "slashed_funds_staking"
+)
+
+func NewActiveStakingOutboxEvent(
+	delegation *BTCDelegationDetails, stakingStartHeight uint32, createdAt int64,
+) *OutboxEvent {
+	return &OutboxEvent{
+		Id:               fmt.Sprintf("%s:%s", OutboxEventTypeActiveStaking, delegation.StakingTxHashHex),
+		EventType:        OutboxEventTypeActiveStaking,
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		IdempotencyKey: dedup.IdempotencyKey(
+			delegation.StakingTxHashHex, OutboxEventTypeActiveStaking, uint64(stakingStartHeight),
+		),
+		StakerBtcPkHex:            delegation.StakerBtcPkHex,
+		FinalityProviderBtcPksHex: delegation.FinalityProviderBtcPksHex,
+		StakingAmount:             delegation.StakingAmount,
+		StakingStartHeight:        stakingStartHeight,
+		ParamsVersion:             delegation.ParamsVersion,
+		CreatedAt:                 createdAt,
+	}
+}
+
+func NewUnbondingStakingOutboxEvent(
+	delegation *BTCDelegationDetails,
+	subState types.DelegationSubState,
+	unbondingTxHashHex string,
+	withdrawableHeight uint32,
+	createdAt int64,
+) *OutboxEvent {
+	return &OutboxEvent{
+		Id:               fmt.Sprintf("%s:%s", OutboxEventTypeUnbondingStaking, delegation.StakingTxHashHex),
+		EventType:        OutboxEventTypeUnbondingStaking,
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		IdempotencyKey: dedup.IdempotencyKey(
+			delegation.StakingTxHashHex, OutboxEventTypeUnbondingStaking, uint64(withdrawableHeight),
+		),
+		StakerBtcPkHex:            delegation.StakerBtcPkHex,
+		FinalityProviderBtcPksHex: delegation.FinalityProviderBtcPksHex,
+		StakingAmount:             delegation.StakingAmount,
+		StakingStartHeight:        delegation.StartHeight,
+		ParamsVersion:             delegation.ParamsVersion,
+		SubState:                  subState.String(),
+		UnbondingTxHashHex:        unbondingTxHashHex,
+		WithdrawableHeight:        withdrawableHeight,
+		CreatedAt:                 createdAt,
+	}
+}
+
+func NewWithdrawableStakingOutboxEvent(
+	delegation *BTCDelegationSummary,
+	subState types.DelegationSubState,
+	btcHeight uint32,
+	createdAt int64,
+) *OutboxEvent {
+	return &OutboxEvent{
+		Id:               fmt.Sprintf("%s:%s", OutboxEventTypeWithdrawable, delegation.StakingTxHashHex),
+		EventType:        OutboxEventTypeWithdrawable,
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		IdempotencyKey: dedup.IdempotencyKey(
+			delegation.StakingTxHashHex, OutboxEventTypeWithdrawable, uint64(btcHeight),
+		),
+		SubState:  subState.String(),
+		BtcHeight: btcHeight,
+		CreatedAt: createdAt,
+	}
+}
+
+func NewWithdrawnStakingOutboxEvent(
+	delegation *BTCDelegationDetails,
+	subState types.DelegationSubState,
+	spendingTxHashHex string,
+	spendingHeight uint32,
+	createdAt int64,
+) *OutboxEvent {
+	return &OutboxEvent{
+		Id:               fmt.Sprintf("%s:%s", OutboxEventTypeWithdrawn, delegation.StakingTxHashHex),
+		EventType:        OutboxEventTypeWithdrawn,
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		IdempotencyKey: dedup.IdempotencyKey(
+			delegation.StakingTxHashHex, OutboxEventTypeWithdrawn, uint64(spendingHeight),
+		),
+		SubState:          subState.String(),
+		BtcHeight:         spendingHeight,
+		SpendingTxHashHex: spendingTxHashHex,
+		CreatedAt:         createdAt,
+	}
+}
+
+// NewSlashedStakingOutboxEvent creates the event of a delegation slashed along
+// with its finality provider in the BBN block of the given height
+func NewSlashedStakingOutboxEvent(
+	delegation *BTCDelegationDetails, bbnHeight int64, createdAt int64,
+) *OutboxEvent {
+	return &OutboxEvent{
+		Id:               fmt.Sprintf("%s:%s", OutboxEventTypeSlashedStaking, delegation.StakingTxHashHex),
+		EventType:        OutboxEventTypeSlashedStaking,
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		IdempotencyKey: dedup.IdempotencyKey(
+			delegation.StakingTxHashHex, OutboxEventTypeSlashedStaking, uint64(bbnHeight),
+		),
+		StakerBtcPkHex:            delegation.StakerBtcPkHex,
+		FinalityProviderBtcPksHex: delegation.FinalityProviderBtcPksHex,
+		StakingAmount:             delegation.StakingAmount,
+		StakingStartHeight:        delegation.StartHeight,
+		ParamsVersion:             delegation.ParamsVersion,
+		CreatedAt:                 createdAt,
+	}
+}
+
+// NewSlashedFundsOutboxEvent creates the event of a slashed delegation moving
+// to the given sub state of the slashed funds lifecycle, on the BTC tx of the
+// given height: the slashing tx once confirmed, the tx sweeping the slashed
+// output once swept
+func NewSlashedFundsOutboxEvent(
+	delegation *BTCDelegationDetails,
+	subState types.DelegationSubState,
+	txHashHex string,
+	btcHeight uint32,
+	createdAt int64,
+) *OutboxEvent {
+	return &OutboxEvent{
+		Id: fmt.Sprintf(
+			"%s:%s:%s", OutboxEventTypeSlashedFunds, subState, delegation.StakingTxHashHex,
+		),
+		EventType:        OutboxEventTypeSlashedFunds,
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		IdempotencyKey: dedup.IdempotencyKey(
+			delegation.StakingTxHashHex, OutboxEventTypeSlashedFunds+":"+subState.String(), uint64(btcHeight),
+		),
+		SubState:          subState.String(),
+		BtcHeight:         btcHeight,
+		SpendingTxHashHex: txHashHex,
+		CreatedAt:         createdAt,
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// BbnHeightRange is an inclusive range of BBN heights. Processed heights are
+// recorded as ranges of contiguous heights to keep the record lightweight.
+type BbnHeightRange struct {
+	Id    primitive.ObjectID `bson:"_id,omitempty"`
+	Start uint64             `bson:"start"`
+	End   uint64             `bson:"end"`
+}
+
+// Len returns the number of heights in the range
+func (r *BbnHeightRange) Len() uint64 {
+	return r.End - r.Start + 1
+}
+
+// FindBbnHeightGaps returns the ranges of heights between from and to that are
+// not covered by the processed ranges, which must be sorted by start height.
+func FindBbnHeightGaps(processed []*BbnHeightRange, from, to uint64) []*BbnHeightRange {
+	var gaps []*BbnHeightRange
+	expected := from
+	for _, r := range processed {
+		if r.End < expected {
+			continue
+		}
+		if r.Start > to {
+			break
+		}
+		if r.Start > expected {
+			gaps = append(gaps, &BbnHeightRange{Start: expected, End: r.Start - 1})
+		}
+		if r.End >= to {
+			return gaps
+		}
+		expected = r.End + 1
+	}
+	if expected <= to {
+		gaps = append(gaps, &BbnHeightRange{Start: expected, End: to})
+	}
+	return gaps
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBbnHeightGaps(t *testing.T) {
+	testCases := []struct {
+		name      string
+		processed []*BbnHeightRange
+		from, to  uint64
+		expected  []*BbnHeightRange
+	}{
+		{
+			name:      "fully processed",
+			processed: []*BbnHeightRange{{Start: 1, End: 100}},
+			from:      10,
+			to:        20,
+			expected:  nil,
+		},
+		{
+			name:      "nothing processed",
+			processed: nil,
+			from:      10,
+			to:        20,
+			expected:  []*BbnHeightRange{{Start: 10, End: 20}},
+		},
+		{
+			name: "gaps between ranges",
+			processed: []*BbnHeightRange{
+				{Start: 1, End: 4},
+				{Start: 6, End: 9},
+				{Start: 15, End: 20},
+			},
+			from: 1,
+			to:   20,
+			expected: []*BbnHeightRange{
+				{Start: 5, End: 5},
+				{Start: 10, End: 14},
+			},
+		},
+		{
+			name: "gaps at both ends",
+			processed: []*BbnHeightRange{
+				{Start: 1, End: 12},
+				{Start: 15, End: 16},
+			},
+			from: 10,
+			to:   20,
+			expected: []*BbnHeightRange{
+				{Start: 13, End: 14},
+				{Start: 17, End: 20},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, FindBbnHeightGaps(tc.processed, tc.from, tc.to))
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// RawEventCapture is a BBN event as received from the chain, captured when its
+// processing failed or sampled otherwise, for debugging the parsing of its
+// attributes across chain upgrades
+type RawEventCapture struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`
+	CapturedAt int64              `bson:"captured_at"` // epoch time in seconds
+	BbnHeight  uint64             `bson:"bbn_height"`
+	EventIndex int                `bson:"event_index"`
+	EventType  string             `bson:"event_type"`
+	Category   string             `bson:"category"`
+	// Attributes are the raw attributes of the event, in order, the sensitive
+	// values redacted
+	Attributes []RawEventAttribute `bson:"attributes"`
+	// Error is the error of the processing of the event, empty when sampled
+	Error string `bson:"error,omitempty"`
+}
+
+type RawEventAttribute struct {
+	Key      string `bson:"key"`
+	Value    string `bson:"value"`
+	Redacted bool   `bson:"redacted,omitempty"`
+}
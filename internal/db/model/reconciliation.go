@@ -0,0 +1,159 @@
+package model
+
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of the documents stored in the reconciliation reports collection
+const (
+	ReconciliationSummaryKind     = "summary"
+	ReconciliationDiscrepancyKind = "discrepancy"
+)
+
+const (
+	ReconciliationRunning   = "running"
+	ReconciliationCompleted = "completed"
+)
+
+// Phases of a reconciliation run, in the order they are executed
+const (
+	ReconciliationPhaseLocalDelegations = "local_delegations"
+	ReconciliationPhaseChainDelegations = "chain_delegations"
+	ReconciliationPhaseChainFps         = "chain_finality_providers"
+)
+
+// Types of discrepancies between the indexed data and the BBN chain state
+const (
+	DiscrepancyStateMismatch             = "state_mismatch"
+	DiscrepancyMissingLocally            = "missing_locally"
+	DiscrepancyMissingOnChain            = "missing_on_chain"
+	DiscrepancyCovenantSignatureMismatch = "covenant_signature_count_mismatch"
+)
+
+const (
+	ReconciliationDelegationEntity = "delegation"
+	ReconciliationFpEntity         = "finality_provider"
+)
+
+// ReconciliationRun is the summary document of a reconciliation run. Phase and
+// Cursor are saved after each batch so that an interrupted run is resumed.
+type ReconciliationRun struct {
+	Id            primitive.ObjectID `bson:"_id"`
+	Kind          string             `bson:"kind"`
+	Status        string             `bson:"status"`
+	Fix           bool               `bson:"fix"`
+	StartedAt     int64              `bson:"started_at"` // epoch time in seconds
+	FinishedAt    int64              `bson:"finished_at,omitempty"`
+	Phase         string             `bson:"phase"`
+	Cursor        string             `bson:"cursor"`
+	Checked       uint64             `bson:"checked"`
+	Fixed         uint64             `bson:"fixed"`
+	Discrepancies map[string]uint64  `bson:"discrepancies"` // count by discrepancy type
+}
+
+// ReconciliationDiscrepancy is the detail of a single discrepancy found by a
+// reconciliation run
+type ReconciliationDiscrepancy struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty"`
+	Kind       string             `bson:"kind"`
+	RunId      primitive.ObjectID `bson:"run_id"`
+	Type       string             `bson:"type"`
+	EntityType string             `bson:"entity_type"`
+	EntityId   string             `bson:"entity_id"`
+	LocalValue string             `bson:"local_value,omitempty"`
+	ChainValue string             `bson:"chain_value,omitempty"`
+	Fixed      bool               `bson:"fixed"`
+}
+
+func NewReconciliationRun(fix bool, startedAt int64) *ReconciliationRun {
+	return &ReconciliationRun{
+		Id:            primitive.NewObjectID(),
+		Kind:          ReconciliationSummaryKind,
+		Status:        ReconciliationRunning,
+		Fix:           fix,
+		StartedAt:     startedAt,
+		Phase:         ReconciliationPhaseLocalDelegations,
+		Discrepancies: make(map[string]uint64),
+	}
+}
+
+func NewReconciliationDiscrepancy(
+	runId primitive.ObjectID, discrepancyType, entityType, entityId, localValue, chainValue string,
+) *ReconciliationDiscrepancy {
+	return &ReconciliationDiscrepancy{
+		Kind:       ReconciliationDiscrepancyKind,
+		RunId:      runId,
+		Type:       discrepancyType,
+		EntityType: entityType,
+		EntityId:   entityId,
+		LocalValue: localValue,
+		ChainValue: chainValue,
+	}
+}
+
+// FromBbnBTCDelegation replays the creation of a delegation from its chain
+// state. The BBN block it was created in is not known from the chain state.
+func FromBbnBTCDelegation(
+	del *bbnclient.BTCDelegation, state types.DelegationState,
+) *BTCDelegationDetails {
+	covenantSignatures := make([]CovenantSignature, len(del.CovenantUnbondingSignatures))
+	for i, sig := range del.CovenantUnbondingSignatures {
+		covenantSignatures[i] = CovenantSignature{
+			CovenantBtcPkHex: sig.CovenantBtcPkHex,
+			SignatureHex:     sig.SignatureHex,
+		}
+	}
+
+	return &BTCDelegationDetails{
+		StakingTxHashHex:            del.StakingTxHashHex,
+		StakingTxHex:                del.StakingTxHex,
+		StakingTime:                 del.StakingTime,
+		StakingAmount:               del.TotalSat,
+		StakingOutputIdx:            del.StakingOutputIdx,
+		StakerBtcPkHex:              del.StakerBtcPkHex,
+		FinalityProviderBtcPksHex:   del.FinalityProviderBtcPksHex,
+		StartHeight:                 del.StartHeight,
+		EndHeight:                   del.EndHeight,
+		State:                       state,
+		ParamsVersion:               del.ParamsVersion,
+		UnbondingTime:               del.UnbondingTime,
+		UnbondingTx:                 del.UnbondingTxHex,
+		CovenantUnbondingSignatures: covenantSignatures,
+	}
+}
+
+// FromBbnFinalityProvider replays the creation of a Babylon finality provider
+// from its chain state
+func FromBbnFinalityProvider(fp *bbnclient.FinalityProvider) *FinalityProviderDetails {
+	return &FinalityProviderDetails{
+		BtcPk:          fp.BtcPk,
+		BabylonAddress: fp.BabylonAddress,
+		Commission:     fp.Commission,
+		State:          FinalityProviderStateFromChain(fp),
+		Description: Description{
+			Moniker:         fp.Moniker,
+			Identity:        fp.Identity,
+			Website:         fp.Website,
+			SecurityContact: fp.SecurityContact,
+			Details:         fp.Details,
+		},
+		BsnId: BabylonBsnId,
+	}
+}
+
+// FinalityProviderStateFromChain returns the state a finality provider is
+// expected to have locally given its chain state. The chain state does not
+// tell active and inactive finality providers apart, hence inactive.
+func FinalityProviderStateFromChain(fp *bbnclient.FinalityProvider) string {
+	switch {
+	case fp.Slashed:
+		return bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_SLASHED.String()
+	case fp.Jailed:
+		return bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_JAILED.String()
+	default:
+		return bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE.String()
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
-	"github.com/rs/zerolog/log"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,19 +19,106 @@ const (
 	TimeLockCollection                = "timelock"
 	GlobalParamsCollection            = "global_params"
 	LastProcessedHeightCollection     = "last_processed_height"
+	FpVotingPowerChangesCollection    = "fp_voting_power_changes"
+	BTCHeadersCollection              = "btc_headers"
+	BTCDerivedChangesCollection       = "btc_derived_changes"
+	ReconciliationReportsCollection   = "reconciliation_reports"
+	ProcessedBbnHeightsCollection     = "processed_bbn_heights"
+	StuckDelegationReportsCollection  = "stuck_delegation_reports"
+	OutboxEventsCollection            = "outbox_events"
+	GlobalStatsCollection             = "global_stats"
+	DelegationStateHistoryCollection  = "delegation_state_history"
+	TimeLockArchiveCollection         = "timelock_archive"
+	// OutboxSequencesCollection holds the event sequences recorded before they
+	// moved to the delegation documents
+	OutboxSequencesCollection = "outbox_sequences"
+	// BTCDelegationArchiveCollection holds the delegations pruned once in a
+	// terminal state for long enough
+	BTCDelegationArchiveCollection = "btc_delegation_archive"
+	LocksCollection                = "locks"
+	// RawEventCapturesCollection is the capped collection of the raw BBN
+	// events captured, only created when the capture is enabled
+	RawEventCapturesCollection = "raw_event_captures"
+	// BbnEventDeadLettersCollection holds the BBN events set aside as their
+	// processing failed with a permanent error
+	BbnEventDeadLettersCollection = "bbn_event_dead_letters"
+	// BootstrapCollection holds the progress of the startup bootstrap
+	BootstrapCollection = "bootstrap"
 )
 
 type index struct {
 	Indexes map[string]int
-	Unique  bool
+	// Keys are the keys of a compound index in order, as the order of
+	// Indexes is not kept
+	Keys   bson.D
+	Name   string
+	Unique bool
+}
+
+// TimeLockExpiryIndexName is the index of the timelock documents the expiry
+// checker scans, hinted so that the planner does not pick another one. It
+// holds the fields the checker reads, for the scan to be covered by it.
+const TimeLockExpiryIndexName = "expire_height_1_staking_tx_hash_hex_1_delegation_sub_state_1"
+
+// TimeLockExpiryIndexKeys are the keys of the TimeLockExpiryIndexName index
+var TimeLockExpiryIndexKeys = bson.D{
+	{Key: "expire_height", Value: 1},
+	{Key: "staking_tx_hash_hex", Value: 1},
+	{Key: "delegation_sub_state", Value: 1},
 }
 
 var collections = map[string][]index{
-	FinalityProviderDetailsCollection: {{Indexes: map[string]int{}}},
-	BTCDelegationDetailsCollection:    {{Indexes: map[string]int{}}},
-	TimeLockCollection:                {{Indexes: map[string]int{}}},
-	GlobalParamsCollection:            {{Indexes: map[string]int{}}},
-	LastProcessedHeightCollection:     {{Indexes: map[string]int{}}},
+	FinalityProviderDetailsCollection: {
+		{Indexes: map[string]int{"bsn_id": 1}},
+		{Indexes: map[string]int{"state": 1}},
+	},
+	BTCDelegationDetailsCollection: {
+		{Indexes: map[string]int{"state": 1}},
+		{Indexes: map[string]int{"btc_delegation_created_bbn_block.height": 1}},
+		{Indexes: map[string]int{"staker_btc_pk_hex": 1}},
+		{Indexes: map[string]int{"staker_babylon_address": 1}},
+		{Indexes: map[string]int{"finality_provider_btc_pks_hex": 1}},
+		{Indexes: map[string]int{"state_updated_at": 1}},
+	},
+	TimeLockCollection: {
+		{Indexes: map[string]int{"staking_tx_hash_hex": 1}},
+		{Indexes: map[string]int{"expire_height": 1}},
+		{Keys: TimeLockExpiryIndexKeys, Name: TimeLockExpiryIndexName},
+	},
+	GlobalParamsCollection:        {{Indexes: map[string]int{}}},
+	LastProcessedHeightCollection: {{Indexes: map[string]int{}}},
+	// the history of a finality provider is read in height order
+	FpVotingPowerChangesCollection: {{Keys: bson.D{{Key: "fp_btc_pk_hex", Value: 1}, {Key: "bbn_height", Value: 1}}}},
+	BTCHeadersCollection:           {{Indexes: map[string]int{}}},
+	BTCDerivedChangesCollection:    {{Indexes: map[string]int{"btc_height": 1}}},
+	ProcessedBbnHeightsCollection: {
+		{Indexes: map[string]int{"start": 1}, Unique: true},
+		{Indexes: map[string]int{"end": 1}, Unique: true},
+	},
+	StuckDelegationReportsCollection: {{Indexes: map[string]int{"created_at": 1}}},
+	// the relay pages through the outbox on the creation time and the id
+	OutboxEventsCollection: {
+		{Keys: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Indexes: map[string]int{"staking_tx_hash_hex": 1}},
+	},
+	OutboxSequencesCollection: {{Indexes: map[string]int{}}},
+	GlobalStatsCollection:     {{Indexes: map[string]int{}}},
+	BootstrapCollection:       {{Indexes: map[string]int{}}},
+	DelegationStateHistoryCollection: {
+		{Indexes: map[string]int{"staking_tx_hash_hex": 1}},
+		{Indexes: map[string]int{"created_at": 1}},
+	},
+	TimeLockArchiveCollection: {
+		{Indexes: map[string]int{"staking_tx_hash_hex": 1}},
+		{Indexes: map[string]int{"archived_at": 1}},
+	},
+	BTCDelegationArchiveCollection: {{Indexes: map[string]int{}}},
+	LocksCollection:                {{Indexes: map[string]int{}}},
+	ReconciliationReportsCollection: {
+		{Indexes: map[string]int{"kind": 1, "status": 1}},
+		{Indexes: map[string]int{"run_id": 1}},
+	},
+	BbnEventDeadLettersCollection: {{Indexes: map[string]int{"bbn_height": 1}}},
 }
 
 func Setup(ctx context.Context, cfg *config.Config) error {
@@ -63,45 +150,68 @@ func Setup(ctx context.Context, cfg *config.Config) error {
 		}
 	}
 
-	log.Info().Msg("Collections and Indexes created successfully.")
+	if cfg.EventCapture.Enabled {
+		createCappedCollection(ctx, database, RawEventCapturesCollection, cfg.EventCapture.MaxSizeBytes)
+		createIndex(ctx, database, RawEventCapturesCollection, index{Indexes: map[string]int{"bbn_height": 1}})
+	}
+
+	logging.DB.FromContext(ctx).Info().Msg("Collections and Indexes created successfully.")
 	return nil
 }
 
 func createCollection(ctx context.Context, database *mongo.Database, collectionName string) {
 	// Check if the collection already exists.
 	if _, err := database.Collection(collectionName).Indexes().CreateOne(ctx, mongo.IndexModel{}); err != nil {
-		log.Debug().Msg(fmt.Sprintf("Collection maybe already exists: %s, skip the rest. info: %s", collectionName, err))
+		logging.DB.FromContext(ctx).Debug().Msg(fmt.Sprintf("Collection maybe already exists: %s, skip the rest. info: %s", collectionName, err))
 		return
 	}
 
 	// Create the collection.
 	if err := database.CreateCollection(ctx, collectionName); err != nil {
-		log.Ctx(ctx).Error().Err(err).Msg("Failed to create collection: " + collectionName)
+		logging.DB.FromContext(ctx).Error().Err(err).Msg("Failed to create collection: " + collectionName)
 		return
 	}
 
-	log.Debug().Msg("Collection created successfully: " + collectionName)
+	logging.DB.FromContext(ctx).Debug().Msg("Collection created successfully: " + collectionName)
 }
 
-func createIndex(ctx context.Context, database *mongo.Database, collectionName string, idx index) {
-	if len(idx.Indexes) == 0 {
+// createCappedCollection creates a collection of a fixed size, the oldest
+// documents being overwritten once it is full. An existing collection is left
+// as it is.
+func createCappedCollection(ctx context.Context, database *mongo.Database, collectionName string, sizeBytes int64) {
+	err := database.CreateCollection(
+		ctx, collectionName, options.CreateCollection().SetCapped(true).SetSizeInBytes(sizeBytes),
+	)
+	if err != nil {
+		logging.DB.FromContext(ctx).Debug().Msg(fmt.Sprintf("Collection maybe already exists: %s, info: %s", collectionName, err))
 		return
 	}
 
-	indexKeys := bson.D{}
+	logging.DB.FromContext(ctx).Debug().Msg("Capped collection created successfully: " + collectionName)
+}
+
+func createIndex(ctx context.Context, database *mongo.Database, collectionName string, idx index) {
+	indexKeys := append(bson.D{}, idx.Keys...)
 	for k, v := range idx.Indexes {
 		indexKeys = append(indexKeys, bson.E{Key: k, Value: v})
 	}
+	if len(indexKeys) == 0 {
+		return
+	}
 
+	indexOpts := options.Index().SetUnique(idx.Unique)
+	if idx.Name != "" {
+		indexOpts.SetName(idx.Name)
+	}
 	index := mongo.IndexModel{
 		Keys:    indexKeys,
-		Options: options.Index().SetUnique(idx.Unique),
+		Options: indexOpts,
 	}
 
 	if _, err := database.Collection(collectionName).Indexes().CreateOne(ctx, index); err != nil {
-		log.Debug().Msg(fmt.Sprintf("Failed to create index on collection '%s': %v", collectionName, err))
+		logging.DB.FromContext(ctx).Debug().Msg(fmt.Sprintf("Failed to create index on collection '%s': %v", collectionName, err))
 		return
 	}
 
-	log.Debug().Msg("Index created successfully on collection: " + collectionName)
+	logging.DB.FromContext(ctx).Debug().Msg("Index created successfully on collection: " + collectionName)
 }
@@ -0,0 +1,21 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// StuckDelegationReport lists the delegations that stayed in a non-terminal
+// state longer than the configured threshold of that state
+type StuckDelegationReport struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt int64              `bson:"created_at"` // epoch time in seconds
+	// Counts is the number of stuck delegations by state
+	Counts map[string]uint64 `bson:"counts"`
+	// Offenders are the delegations stuck for the longest time
+	Offenders []*StuckDelegation `bson:"offenders"`
+}
+
+type StuckDelegation struct {
+	StakingTxHashHex string `bson:"staking_tx_hash_hex"`
+	State            string `bson:"state"`
+	StateSince       int64  `bson:"state_since"` // epoch time in seconds
+	StuckForSeconds  int64  `bson:"stuck_for_seconds"`
+}
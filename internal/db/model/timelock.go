@@ -1,6 +1,9 @@
 package model
 
-import "github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+import (
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type TimeLockDocument struct {
 	StakingTxHashHex   string                   `bson:"staking_tx_hash_hex"`
@@ -8,6 +11,33 @@ type TimeLockDocument struct {
 	DelegationSubState types.DelegationSubState `bson:"delegation_sub_state"`
 }
 
+// OrphanedTimeLock is a timelock document whose delegation is in a terminal
+// state or no longer exists, in which case DelegationState is empty
+type OrphanedTimeLock struct {
+	Id               primitive.ObjectID    `bson:"_id"`
+	StakingTxHashHex string                `bson:"staking_tx_hash_hex"`
+	DelegationState  types.DelegationState `bson:"delegation_state,omitempty"`
+}
+
+// ExpiringTimeLock is a timelock document joined with the delegation it makes
+// withdrawable once expired
+type ExpiringTimeLock struct {
+	Id                 primitive.ObjectID       `bson:"_id"`
+	StakingTxHashHex   string                   `bson:"staking_tx_hash_hex"`
+	ExpireHeight       uint32                   `bson:"expire_height"`
+	DelegationSubState types.DelegationSubState `bson:"delegation_sub_state"`
+	StakerBtcPkHex     string                   `bson:"staker_btc_pk_hex"`
+	StakingAmount      uint64                   `bson:"staking_amount"`
+}
+
+// ExpiryBacklogStats aggregates the expired timelock documents of a sub state
+// waiting for the expiry checker
+type ExpiryBacklogStats struct {
+	SubState           types.DelegationSubState `bson:"_id"`
+	Count              uint64                   `bson:"count"`
+	OldestExpireHeight uint32                   `bson:"oldest_expire_height"`
+}
+
 func NewTimeLockDocument(
 	stakingTxHashHex string, expireHeight uint32, subState types.DelegationSubState,
 ) *TimeLockDocument {
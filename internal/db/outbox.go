@@ -0,0 +1,296 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// unsentOutboxEventsFilter matches the events to be pushed to the queue
+var unsentOutboxEventsFilter = bson.M{
+	"sent_at": bson.M{"$exists": false},
+	"poison":  bson.M{"$ne": true},
+}
+
+// SaveOutboxEvent records the event and increments the event sequence of its
+// delegation in a single transaction, so that a crash in between neither
+// loses a sequence number nor assigns it twice
+func (db *Database) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	_, err := db.withTransaction(ctx, "SaveOutboxEvent", func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, db.saveOutboxEvent(sessCtx, event)
+	})
+	return err
+}
+
+func (db *Database) saveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	collection := db.client.Database(db.dbName).Collection(model.OutboxEventsCollection)
+
+	// Reprocessing a transition must not consume a sequence number, which
+	// consumers would take for a missed event
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": event.Id})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &DuplicateKeyError{
+			Key:     event.Id,
+			Message: "outbox event already exists",
+		}
+	}
+
+	sequence, err := db.nextEventSequence(ctx, event.StakingTxHashHex)
+	if err != nil {
+		return err
+	}
+	event.Sequence = sequence
+
+	_, err = collection.InsertOne(ctx, event)
+	if err != nil {
+		var writeErr mongo.WriteException
+		if errors.As(err, &writeErr) {
+			for _, e := range writeErr.WriteErrors {
+				if mongo.IsDuplicateKeyError(e) {
+					return &DuplicateKeyError{
+						Key:     event.Id,
+						Message: "outbox event already exists",
+					}
+				}
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (db *Database) GetUnsentOutboxEvents(
+	ctx context.Context, createdAfter int64, afterId string, limit uint64,
+) ([]*model.OutboxEvent, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"created_at": bson.M{"$gt": createdAfter}},
+		bson.M{"created_at": createdAfter, "_id": bson.M{"$gt": afterId}},
+	}}
+	for key, value := range unsentOutboxEventsFilter {
+		filter[key] = value
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.OutboxEventsCollection).
+		Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*model.OutboxEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (db *Database) MarkOutboxEventSent(ctx context.Context, id string, sentAt int64) error {
+	update := bson.M{"$set": bson.M{"sent_at": sentAt}}
+	return db.updateOutboxEvent(ctx, id, update)
+}
+
+func (db *Database) MarkOutboxEventFailed(
+	ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool,
+) error {
+	update := bson.M{
+		"$inc": bson.M{"attempts": 1},
+		"$set": bson.M{
+			"last_error":      lastError,
+			"next_attempt_at": nextAttemptAt,
+			"poison":          poison,
+		},
+	}
+	return db.updateOutboxEvent(ctx, id, update)
+}
+
+func (db *Database) GetPoisonOutboxEvents(ctx context.Context) ([]*model.OutboxEvent, error) {
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.OutboxEventsCollection).
+		Find(ctx, bson.M{"poison": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*model.OutboxEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (db *Database) RequeuePoisonOutboxEvents(ctx context.Context) (uint64, error) {
+	update := bson.M{
+		"$unset": bson.M{"poison": "", "attempts": "", "next_attempt_at": ""},
+	}
+	result, err := db.client.Database(db.dbName).
+		Collection(model.OutboxEventsCollection).
+		UpdateMany(ctx, bson.M{"poison": true}, update)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(result.ModifiedCount), nil
+}
+
+func (db *Database) GetOutboxStats(ctx context.Context) (*model.OutboxStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"sent_at": bson.M{"$exists": false}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": nil,
+			"unsent": bson.M{"$sum": bson.M{
+				"$cond": bson.A{bson.M{"$eq": bson.A{"$poison", true}}, 0, 1},
+			}},
+			"oldest_unsent_at": bson.M{"$min": bson.M{
+				"$cond": bson.A{bson.M{"$eq": bson.A{"$poison", true}}, nil, "$created_at"},
+			}},
+			"poison": bson.M{"$sum": bson.M{
+				"$cond": bson.A{bson.M{"$eq": bson.A{"$poison", true}}, 1, 0},
+			}},
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.OutboxEventsCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []*model.OutboxStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return &model.OutboxStats{}, nil
+	}
+	return stats[0], nil
+}
+
+func (db *Database) updateOutboxEvent(ctx context.Context, id string, update bson.M) error {
+	result, err := db.client.Database(db.dbName).
+		Collection(model.OutboxEventsCollection).
+		UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{
+			Key:     id,
+			Message: "outbox event not found",
+		}
+	}
+	return nil
+}
+
+// nextEventSequence increments and returns the event sequence of the
+// delegation, starting at 1. The sequences recorded in the outbox sequences
+// collection before they moved to the delegation documents carry on.
+func (db *Database) nextEventSequence(ctx context.Context, stakingTxHashHex string) (uint64, error) {
+	legacySequence, err := db.getLegacyOutboxSequence(ctx, stakingTxHashHex)
+	if err != nil {
+		return 0, err
+	}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"event_sequence": bson.M{"$add": bson.A{
+				bson.M{"$max": bson.A{bson.M{"$ifNull": bson.A{"$event_sequence", 0}}, legacySequence}},
+				1,
+			}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var delegation model.BTCDelegationDetails
+	err = db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		FindOneAndUpdate(ctx, bson.M{"_id": stakingTxHashHex}, update, opts).
+		Decode(&delegation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, &NotFoundError{
+				Key:     stakingTxHashHex,
+				Message: "BTC delegation not found when incrementing its event sequence",
+			}
+		}
+		return 0, err
+	}
+
+	return delegation.EventSequence, nil
+}
+
+func (db *Database) getLegacyOutboxSequence(ctx context.Context, stakingTxHashHex string) (uint64, error) {
+	var sequence model.OutboxSequence
+	err := db.client.Database(db.dbName).
+		Collection(model.OutboxSequencesCollection).
+		FindOne(ctx, bson.M{"_id": stakingTxHashHex}).
+		Decode(&sequence)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return sequence.Sequence, nil
+}
+
+func (db *Database) GetDelegationOutboxEvents(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.OutboxEvent, error) {
+	opts := options.Find().SetSort(bson.M{"sequence": 1})
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.OutboxEventsCollection).
+		Find(ctx, bson.M{"staking_tx_hash_hex": stakingTxHashHex}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []*model.OutboxEvent
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (db *Database) GetOutboxSequence(ctx context.Context, stakingTxHashHex string) (uint64, error) {
+	legacySequence, err := db.getLegacyOutboxSequence(ctx, stakingTxHashHex)
+	if err != nil {
+		return 0, err
+	}
+
+	var delegation model.BTCDelegationDetails
+	err = db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		FindOne(ctx, bson.M{"_id": stakingTxHashHex}).
+		Decode(&delegation)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, err
+	}
+
+	sequence := max(delegation.EventSequence, legacySequence)
+	if sequence == 0 {
+		return 0, &NotFoundError{
+			Key:     stakingTxHashHex,
+			Message: "outbox sequence not found",
+		}
+	}
+	return sequence, nil
+}
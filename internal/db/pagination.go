@@ -0,0 +1,49 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// DbResultMap is a page of results, with the token of the next page if any
+type DbResultMap[T any] struct {
+	Data            []T    `json:"data"`
+	PaginationToken string `json:"paginationToken"`
+}
+
+// toResultMapWithPaginationToken returns the page of the first limit
+// results, the extra one telling whether there is a next page
+func toResultMapWithPaginationToken[T any](
+	results []T, limit int64, paginationKeyBuilder func(T) (string, error),
+) (*DbResultMap[T], error) {
+	if int64(len(results)) <= limit {
+		return &DbResultMap[T]{Data: results}, nil
+	}
+
+	results = results[:limit]
+	paginationToken, err := paginationKeyBuilder(results[len(results)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &DbResultMap[T]{Data: results, PaginationToken: paginationToken}, nil
+}
+
+func encodePaginationToken(value any) (string, error) {
+	tokenBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(tokenBytes), nil
+}
+
+func decodePaginationToken(token string, value any) error {
+	tokenBytes, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return &InvalidPaginationTokenError{Message: "invalid pagination token"}
+	}
+	if err := json.Unmarshal(tokenBytes, value); err != nil {
+		return &InvalidPaginationTokenError{Message: "invalid pagination token"}
+	}
+	return nil
+}
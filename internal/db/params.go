@@ -2,11 +2,14 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -43,6 +46,30 @@ func (db *Database) SaveStakingParams(
 	return err
 }
 
+func (db *Database) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	collection := db.client.Database(db.dbName).Collection(model.GlobalParamsCollection)
+
+	filter := bson.M{
+		"type":    STAKING_PARAMS_TYPE,
+		"version": version,
+	}
+	update := bson.M{"$set": bson.M{"params": params}}
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{
+			Key:     strconv.FormatUint(uint64(version), 10),
+			Message: "staking params not found when replacing them",
+		}
+	}
+	return nil
+}
+
 func (db *Database) SaveCheckpointParams(
 	ctx context.Context, params *bbnclient.CheckpointParams,
 ) error {
@@ -78,8 +105,61 @@ func (db *Database) GetStakingParams(ctx context.Context, version uint32) (*bbnc
 	var params model.StakingParamsDocument
 	err := collection.FindOne(ctx, filter).Decode(&params)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     strconv.FormatUint(uint64(version), 10),
+				Message: "staking params not found",
+			}
+		}
 		return nil, fmt.Errorf("failed to get staking params: %w", err)
 	}
 
 	return params.Params, nil
 }
+
+func (db *Database) GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error) {
+	collection := db.client.Database(db.dbName).
+		Collection(model.GlobalParamsCollection)
+
+	cursor, err := collection.Find(ctx, bson.M{"type": STAKING_PARAMS_TYPE})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staking params: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []model.StakingParamsDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode staking params: %w", err)
+	}
+
+	allParams := make(map[uint32]*bbnclient.StakingParams, len(docs))
+	for _, doc := range docs {
+		allParams[doc.Version] = doc.Params
+	}
+
+	return allParams, nil
+}
+
+func (db *Database) GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error) {
+	collection := db.client.Database(db.dbName).
+		Collection(model.GlobalParamsCollection)
+
+	filter := bson.M{
+		"type":    CHECKPOINT_PARAMS_TYPE,
+		"version": CHECKPOINT_PARAMS_VERSION,
+	}
+
+	var params model.CheckpointParamsDocument
+	err := collection.FindOne(ctx, filter).Decode(&params)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     CHECKPOINT_PARAMS_TYPE,
+				Message: "checkpoint params not found",
+			}
+		}
+		return nil, fmt.Errorf("failed to get checkpoint params: %w", err)
+	}
+
+	return params.Params, nil
+}
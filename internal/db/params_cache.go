@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// stakingParamsCacheName labels the lookups of the staking params cache
+const stakingParamsCacheName = "staking_params"
+
+// ParamsCacheDatabase caches the staking params by version, the params of a
+// version not changing once saved. The cache only holds params read back
+// from the database, so that params which failed to be saved are never
+// served. A version is dropped from the cache whenever its params are saved
+// or replaced, and read again on the next lookup. The cached params are
+// shared by the callers and must not be modified.
+type ParamsCacheDatabase struct {
+	DbInterface
+
+	mu            sync.RWMutex
+	stakingParams map[uint32]*bbnclient.StakingParams
+	// generation is increased on every invalidation, so that the params read
+	// before it are not cached after it
+	generation uint64
+}
+
+func NewParamsCacheDatabase(dbClient DbInterface) *ParamsCacheDatabase {
+	return &ParamsCacheDatabase{
+		DbInterface:   dbClient,
+		stakingParams: make(map[uint32]*bbnclient.StakingParams),
+	}
+}
+
+// Warm fills the cache with all the stored staking params
+func (d *ParamsCacheDatabase) Warm(ctx context.Context) error {
+	_, err := d.GetAllStakingParams(ctx)
+	return err
+}
+
+func (d *ParamsCacheDatabase) GetStakingParams(
+	ctx context.Context, version uint32,
+) (*bbnclient.StakingParams, error) {
+	d.mu.RLock()
+	params, ok := d.stakingParams[version]
+	generation := d.generation
+	d.mu.RUnlock()
+	metrics.RecordDbCacheLookup(stakingParamsCacheName, ok)
+	if ok {
+		return params, nil
+	}
+
+	params, err := d.DbInterface.GetStakingParams(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	d.store(generation, map[uint32]*bbnclient.StakingParams{version: params})
+	return params, nil
+}
+
+func (d *ParamsCacheDatabase) GetAllStakingParams(
+	ctx context.Context,
+) (map[uint32]*bbnclient.StakingParams, error) {
+	d.mu.RLock()
+	generation := d.generation
+	d.mu.RUnlock()
+
+	allParams, err := d.DbInterface.GetAllStakingParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d.store(generation, allParams)
+	return allParams, nil
+}
+
+func (d *ParamsCacheDatabase) SaveStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	err := d.DbInterface.SaveStakingParams(ctx, version, params)
+	d.invalidate(version)
+	return err
+}
+
+func (d *ParamsCacheDatabase) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	err := d.DbInterface.ReplaceStakingParams(ctx, version, params)
+	d.invalidate(version)
+	return err
+}
+
+// store caches the params read at the generation, unless invalidated since
+func (d *ParamsCacheDatabase) store(generation uint64, allParams map[uint32]*bbnclient.StakingParams) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.generation != generation {
+		return
+	}
+	for version, params := range allParams {
+		if params != nil {
+			d.stakingParams[version] = params
+		}
+	}
+}
+
+func (d *ParamsCacheDatabase) invalidate(version uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.stakingParams, version)
+	d.generation++
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// paramsDatabase stores the staking params in memory and counts the reads
+type paramsDatabase struct {
+	DbInterface
+	stakingParams map[uint32]*bbnclient.StakingParams
+	reads         int
+}
+
+func (d *paramsDatabase) GetStakingParams(ctx context.Context, version uint32) (*bbnclient.StakingParams, error) {
+	d.reads++
+	params, ok := d.stakingParams[version]
+	if !ok {
+		return nil, &NotFoundError{Message: "staking params not found"}
+	}
+	return params, nil
+}
+
+func (d *paramsDatabase) GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error) {
+	d.reads++
+	allParams := make(map[uint32]*bbnclient.StakingParams, len(d.stakingParams))
+	for version, params := range d.stakingParams {
+		allParams[version] = params
+	}
+	return allParams, nil
+}
+
+func (d *paramsDatabase) ReplaceStakingParams(
+	ctx context.Context, version uint32, params *bbnclient.StakingParams,
+) error {
+	d.stakingParams[version] = params
+	return nil
+}
+
+func TestParamsCacheDatabase(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	stored := &paramsDatabase{stakingParams: map[uint32]*bbnclient.StakingParams{
+		0: {MinStakingTimeBlocks: 10},
+		1: {MinStakingTimeBlocks: 20},
+	}}
+	cache := NewParamsCacheDatabase(stored)
+
+	require.NoError(t, cache.Warm(ctx))
+	require.Equal(t, 1, stored.reads)
+	params, err := cache.GetStakingParams(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(20), params.MinStakingTimeBlocks)
+	require.Equal(t, 1, stored.reads)
+
+	// a missing version is read on every lookup until stored
+	_, err = cache.GetStakingParams(ctx, 2)
+	require.True(t, IsNotFoundError(err))
+	_, err = cache.GetStakingParams(ctx, 2)
+	require.True(t, IsNotFoundError(err))
+	require.Equal(t, 3, stored.reads)
+
+	// a replaced version is read again
+	require.NoError(t, cache.ReplaceStakingParams(ctx, 1, &bbnclient.StakingParams{MinStakingTimeBlocks: 30}))
+	params, err = cache.GetStakingParams(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(30), params.MinStakingTimeBlocks)
+	require.Equal(t, 4, stored.reads)
+	_, err = cache.GetStakingParams(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, 4, stored.reads)
+}
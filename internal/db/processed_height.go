@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Database) MarkBbnHeightProcessed(ctx context.Context, height uint64) error {
+	collection := db.client.Database(db.dbName).Collection(model.ProcessedBbnHeightsCollection)
+
+	// Already recorded, e.g. when reprocessing a height
+	covered, err := collection.CountDocuments(ctx, bson.M{
+		"start": bson.M{"$lte": height},
+		"end":   bson.M{"$gte": height},
+	})
+	if err != nil {
+		return err
+	}
+	if covered > 0 {
+		return nil
+	}
+
+	prev, err := findBbnHeightRange(ctx, collection, bson.M{"end": height - 1})
+	if err != nil {
+		return err
+	}
+	next, err := findBbnHeightRange(ctx, collection, bson.M{"start": height + 1})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case prev != nil && next != nil:
+		// The height fills a gap, merge both ranges. The next range is deleted
+		// first as range ends are unique.
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": next.Id}); err != nil {
+			return err
+		}
+		_, err = collection.UpdateOne(
+			ctx, bson.M{"_id": prev.Id}, bson.M{"$set": bson.M{"end": next.End}},
+		)
+	case prev != nil:
+		_, err = collection.UpdateOne(
+			ctx, bson.M{"_id": prev.Id}, bson.M{"$set": bson.M{"end": height}},
+		)
+	case next != nil:
+		_, err = collection.UpdateOne(
+			ctx, bson.M{"_id": next.Id}, bson.M{"$set": bson.M{"start": height}},
+		)
+	default:
+		_, err = collection.InsertOne(ctx, &model.BbnHeightRange{Start: height, End: height})
+	}
+	return err
+}
+
+func (db *Database) GetLowestProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	opts := options.FindOne().SetSort(bson.M{"start": 1})
+
+	var heightRange model.BbnHeightRange
+	err := db.client.Database(db.dbName).
+		Collection(model.ProcessedBbnHeightsCollection).
+		FindOne(ctx, bson.M{}, opts).
+		Decode(&heightRange)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, &NotFoundError{
+				Key:     "lowest",
+				Message: "no processed BBN height recorded",
+			}
+		}
+		return 0, err
+	}
+
+	return heightRange.Start, nil
+}
+
+func (db *Database) DetectProcessedHeightGaps(
+	ctx context.Context, from, to uint64,
+) ([]*model.BbnHeightRange, error) {
+	if from > to {
+		return nil, nil
+	}
+
+	filter := bson.M{
+		"start": bson.M{"$lte": to},
+		"end":   bson.M{"$gte": from},
+	}
+	opts := options.Find().SetSort(bson.M{"start": 1})
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.ProcessedBbnHeightsCollection).
+		Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var processed []*model.BbnHeightRange
+	if err := cursor.All(ctx, &processed); err != nil {
+		return nil, err
+	}
+
+	return model.FindBbnHeightGaps(processed, from, to), nil
+}
+
+func findBbnHeightRange(
+	ctx context.Context, collection *mongo.Collection, filter bson.M,
+) (*model.BbnHeightRange, error) {
+	var heightRange model.BbnHeightRange
+	err := collection.FindOne(ctx, filter).Decode(&heightRange)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &heightRange, nil
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// prunableDelegationsFilter matches the delegations in a terminal state since
+// before the given time. The delegations missing the time they entered their
+// state are never pruned.
+func prunableDelegationsFilter(before int64) bson.M {
+	terminalStates := types.TerminalDelegationStates()
+	stateStrs := make([]string, len(terminalStates))
+	for i, state := range terminalStates {
+		stateStrs[i] = state.String()
+	}
+	return bson.M{
+		"state":            bson.M{"$in": stateStrs},
+		"state_updated_at": bson.M{"$lt": before},
+	}
+}
+
+// prunableArchivedTimeLocksPipeline matches the archived timelocks archived
+// before the given time whose delegation is in a terminal state or pruned
+func prunableArchivedTimeLocksPipeline(before int64) mongo.Pipeline {
+	terminalStates := types.TerminalDelegationStates()
+	stateStrs := make([]string, len(terminalStates))
+	for i, state := range terminalStates {
+		stateStrs[i] = state.String()
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"archived_at": bson.M{"$lt": before}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         model.BTCDelegationDetailsCollection,
+			"localField":   "staking_tx_hash_hex",
+			"foreignField": "_id",
+			"as":           "delegation",
+		}}},
+		{{Key: "$match", Value: bson.M{
+			"$or": []bson.M{
+				{"delegation": bson.M{"$size": 0}},
+				{"delegation.state": bson.M{"$in": stateStrs}},
+			},
+		}}},
+	}
+}
+
+func (db *Database) CountPrunableBTCDelegations(ctx context.Context, before int64) (uint64, error) {
+	count, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		CountDocuments(ctx, prunableDelegationsFilter(before))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(count), nil
+}
+
+func (db *Database) ArchivePrunableBTCDelegations(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	method := "ArchivePrunableBTCDelegations"
+	movedCount, err := db.withTransaction(ctx, method, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		delegationCollection := db.client.Database(db.dbName).Collection(model.BTCDelegationDetailsCollection)
+		cursor, err := delegationCollection.Find(
+			sessCtx, prunableDelegationsFilter(before), options.Find().SetLimit(limit),
+		)
+		if err != nil {
+			return uint64(0), err
+		}
+		var delegations []model.BTCDelegationDetails
+		if err := cursor.All(sessCtx, &delegations); err != nil {
+			return uint64(0), err
+		}
+		if len(delegations) == 0 {
+			return uint64(0), nil
+		}
+
+		// A delegation indexed again after being pruned replaces its
+		// previous archive
+		archivedAt := time.Now().Unix()
+		writes := make([]mongo.WriteModel, len(delegations))
+		ids := make([]string, len(delegations))
+		for i, delegation := range delegations {
+			writes[i] = mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": delegation.StakingTxHashHex}).
+				SetReplacement(&model.ArchivedBTCDelegation{
+					BTCDelegationDetails: delegation,
+					ArchivedAt:           archivedAt,
+				}).
+				SetUpsert(true)
+			ids[i] = delegation.StakingTxHashHex
+		}
+		if _, err := db.client.Database(db.dbName).
+			Collection(model.BTCDelegationArchiveCollection).
+			BulkWrite(sessCtx, writes); err != nil {
+			return uint64(0), err
+		}
+
+		result, err := delegationCollection.DeleteMany(sessCtx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return uint64(0), err
+		}
+		return uint64(result.DeletedCount), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return movedCount.(uint64), nil
+}
+
+func (db *Database) CountPrunableArchivedTimeLocks(ctx context.Context, before int64) (uint64, error) {
+	pipeline := append(
+		prunableArchivedTimeLocksPipeline(before),
+		bson.D{{Key: "$count", Value: "count"}},
+	)
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockArchiveCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Count int64 `bson:"count"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return uint64(result[0].Count), nil
+}
+
+func (db *Database) DeletePrunableArchivedTimeLocks(
+	ctx context.Context, before int64, limit int64,
+) (uint64, error) {
+	pipeline := append(
+		prunableArchivedTimeLocksPipeline(before),
+		bson.D{{Key: "$limit", Value: limit}},
+		bson.D{{Key: "$project", Value: bson.M{"_id": 1}}},
+	)
+	collection := db.client.Database(db.dbName).Collection(model.TimeLockArchiveCollection)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var timeLocks []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &timeLocks); err != nil {
+		return 0, err
+	}
+	if len(timeLocks) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(timeLocks))
+	for i, timeLock := range timeLocks {
+		ids[i] = timeLock.Id
+	}
+	result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(result.DeletedCount), nil
+}
@@ -0,0 +1,14 @@
+package db
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+func (db *Database) SaveRawEventCapture(ctx context.Context, capture *model.RawEventCapture) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.RawEventCapturesCollection).
+		InsertOne(ctx, capture)
+	return err
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (db *Database) GetUnfinishedReconciliationRun(
+	ctx context.Context,
+) (*model.ReconciliationRun, error) {
+	filter := bson.M{
+		"kind":   model.ReconciliationSummaryKind,
+		"status": model.ReconciliationRunning,
+	}
+	opts := options.FindOne().SetSort(bson.M{"started_at": -1})
+
+	var run model.ReconciliationRun
+	err := db.client.Database(db.dbName).
+		Collection(model.ReconciliationReportsCollection).
+		FindOne(ctx, filter, opts).
+		Decode(&run)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &NotFoundError{
+				Key:     model.ReconciliationRunning,
+				Message: "no unfinished reconciliation run found",
+			}
+		}
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+func (db *Database) SaveReconciliationRun(
+	ctx context.Context, run *model.ReconciliationRun,
+) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := db.client.Database(db.dbName).
+		Collection(model.ReconciliationReportsCollection).
+		ReplaceOne(ctx, bson.M{"_id": run.Id}, run, opts)
+	return err
+}
+
+func (db *Database) SaveReconciliationDiscrepancy(
+	ctx context.Context, discrepancy *model.ReconciliationDiscrepancy,
+) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.ReconciliationReportsCollection).
+		InsertOne(ctx, discrepancy)
+	return err
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// stuckDelegationsFilter matches the delegations in the given state since
+// before the given time. Delegations indexed before the state time was
+// recorded fall back to their creation time.
+func stuckDelegationsFilter(state types.DelegationState, before int64) bson.M {
+	return bson.M{
+		"state": state.String(),
+		"$expr": bson.M{
+			"$lt": bson.A{
+				bson.M{"$ifNull": bson.A{"$state_updated_at", "$btc_delegation_created_bbn_block.timestamp"}},
+				before,
+			},
+		},
+	}
+}
+
+func (db *Database) CountStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64,
+) (uint64, error) {
+	count, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		CountDocuments(ctx, stuckDelegationsFilter(state, before))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(count), nil
+}
+
+func (db *Database) FindStuckDelegations(
+	ctx context.Context, state types.DelegationState, before int64, limit uint64,
+) ([]*model.BTCDelegationDetails, error) {
+	opts := options.Find().
+		SetSort(bson.D{
+			{Key: "state_updated_at", Value: 1},
+			{Key: "btc_delegation_created_bbn_block.timestamp", Value: 1},
+		}).
+		SetLimit(int64(limit))
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.BTCDelegationDetailsCollection).
+		Find(ctx, stuckDelegationsFilter(state, before), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var delegations []*model.BTCDelegationDetails
+	if err := cursor.All(ctx, &delegations); err != nil {
+		return nil, err
+	}
+
+	return delegations, nil
+}
+
+func (db *Database) SaveStuckDelegationReport(
+	ctx context.Context, report *model.StuckDelegationReport,
+) error {
+	_, err := db.client.Database(db.dbName).
+		Collection(model.StuckDelegationReportsCollection).
+		InsertOne(ctx, report)
+	return err
+}
@@ -2,14 +2,23 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// badValueErrorCode is the code of the Mongo errors of an invalid argument
+const badValueErrorCode = 2
+
 func (db *Database) SaveNewTimeLockExpire(
 	ctx context.Context,
 	stakingTxHashHex string,
@@ -24,11 +33,7 @@ func (db *Database) SaveNewTimeLockExpire(
 }
 
 func (db *Database) FindExpiredDelegations(ctx context.Context, btcTipHeight, limit uint64) ([]model.TimeLockDocument, error) {
-	client := db.client.Database(db.dbName).Collection(model.TimeLockCollection)
-	filter := bson.M{"expire_height": bson.M{"$lte": btcTipHeight}}
-
-	opts := options.Find().SetLimit(int64(limit))
-	cursor, err := client.Find(ctx, filter, opts)
+	cursor, err := db.findExpiredTimeLocks(ctx, btcTipHeight, int64(limit))
 	if err != nil {
 		return nil, err
 	}
@@ -42,19 +47,403 @@ func (db *Database) FindExpiredDelegations(ctx context.Context, btcTipHeight, li
 	return delegations, nil
 }
 
+func (db *Database) ForEachExpiredDelegation(
+	ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error,
+) error {
+	cursor, err := db.findExpiredTimeLocks(ctx, btcTipHeight, 0)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var tlDoc model.TimeLockDocument
+		if err := cursor.Decode(&tlDoc); err != nil {
+			return err
+		}
+		if err := fn(tlDoc); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// expiredTimeLocksQuery is the scan of the timelock documents expired at the
+// BTC tip height, oldest first. It is hinted to the expiry index and only
+// reads the fields held by the index, so that it is covered by it whatever
+// plan the planner would pick.
+func expiredTimeLocksQuery(btcTipHeight uint64) (bson.M, *options.FindOptions) {
+	filter := bson.M{"expire_height": bson.M{"$lte": btcTipHeight}}
+	opts := options.Find().
+		SetHint(model.TimeLockExpiryIndexName).
+		SetSort(bson.D{{Key: "expire_height", Value: 1}}).
+		SetProjection(bson.M{
+			"_id":                  0,
+			"staking_tx_hash_hex":  1,
+			"expire_height":        1,
+			"delegation_sub_state": 1,
+		})
+	return filter, opts
+}
+
+// findExpiredTimeLocks runs the expiredTimeLocksQuery, limited unless the
+// limit is 0. The index of the hint is created along with the collections,
+// so that if missing, the scan is run without the hint rather than failing.
+func (db *Database) findExpiredTimeLocks(
+	ctx context.Context, btcTipHeight uint64, limit int64,
+) (*mongo.Cursor, error) {
+	collection := db.client.Database(db.dbName).Collection(model.TimeLockCollection)
+	filter, opts := expiredTimeLocksQuery(btcTipHeight)
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if isBadHintError(err) {
+		logging.DB.FromContext(ctx).Warn().Err(err).
+			Str("index", model.TimeLockExpiryIndexName).
+			Msg("expiry index missing, scanning the timelocks without hint")
+		opts.Hint = nil
+		cursor, err = collection.Find(ctx, filter, opts)
+	}
+	return cursor, err
+}
+
+// isBadHintError tells whether the error is the one of a query hinted to an
+// index which does not exist
+func isBadHintError(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == badValueErrorCode &&
+		strings.Contains(cmdErr.Message, "hint")
+}
+
+func (db *Database) GetExpiryBacklogStats(
+	ctx context.Context, btcTipHeight uint64,
+) ([]*model.ExpiryBacklogStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"expire_height": bson.M{"$lte": btcTipHeight}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":                  "$delegation_sub_state",
+			"count":                bson.M{"$sum": 1},
+			"oldest_expire_height": bson.M{"$min": "$expire_height"},
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats []*model.ExpiryBacklogStats
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 func (db *Database) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
-	client := db.client.Database(db.dbName).Collection(model.TimeLockCollection)
-	filter := bson.M{"_id": stakingTxHashHex}
+	// Timelock documents are keyed by an auto generated id, and once the
+	// delegation expired none of its timelocks is of use anymore
+	filter := bson.M{"staking_tx_hash_hex": stakingTxHashHex}
 
-	result, err := client.DeleteOne(ctx, filter)
+	deletedCount, err := db.archiveTimeLocks(ctx, "DeleteExpiredDelegation", filter, model.TimeLockArchiveReasonExpired)
 	if err != nil {
 		return fmt.Errorf("failed to delete expired delegation with stakingTxHashHex %v: %w", stakingTxHashHex, err)
 	}
 
 	// Check if any document was deleted
-	if result.DeletedCount == 0 {
+	if deletedCount == 0 {
 		return fmt.Errorf("no expired delegation found with stakingTxHashHex %v", stakingTxHashHex)
 	}
 
 	return nil
 }
+
+func (db *Database) FindOrphanedTimeLocks(
+	ctx context.Context, terminalStates []types.DelegationState, limit uint64,
+) ([]*model.OrphanedTimeLock, error) {
+	stateStrs := make([]string, len(terminalStates))
+	for i, state := range terminalStates {
+		stateStrs[i] = state.String()
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$lookup", Value: delegationLookup(bson.M{"state": 1})}},
+		{{Key: "$match", Value: bson.M{
+			"$or": []bson.M{
+				{"delegation": bson.M{"$size": 0}},
+				{"delegation.state": bson.M{"$in": stateStrs}},
+			},
+		}}},
+		{{Key: "$limit", Value: int64(limit)}},
+		{{Key: "$project", Value: bson.M{
+			"staking_tx_hash_hex": 1,
+			"delegation_state":    bson.M{"$arrayElemAt": bson.A{"$delegation.state", 0}},
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var timeLocks []*model.OrphanedTimeLock
+	if err := cursor.All(ctx, &timeLocks); err != nil {
+		return nil, err
+	}
+
+	return timeLocks, nil
+}
+
+// delegationLookup joins the timelock documents with the fields of the
+// projection of their delegation, as "delegation", so that the transactions
+// of the delegations are not read by the joins not using them
+func delegationLookup(projection bson.M) bson.M {
+	return bson.M{
+		"from":         model.BTCDelegationDetailsCollection,
+		"localField":   "staking_tx_hash_hex",
+		"foreignField": "_id",
+		"pipeline":     bson.A{bson.M{"$project": projection}},
+		"as":           "delegation",
+	}
+}
+
+func (db *Database) DeleteTimeLocks(ctx context.Context, ids []primitive.ObjectID) (uint64, error) {
+	return db.archiveTimeLocks(
+		ctx, "DeleteTimeLocks", bson.M{"_id": bson.M{"$in": ids}}, model.TimeLockArchiveReasonOrphaned,
+	)
+}
+
+// archiveTimeLocks moves the matching timelock documents to the archive in a
+// single transaction and returns their number. The method is the one the
+// transaction retries are counted for.
+func (db *Database) archiveTimeLocks(
+	ctx context.Context, method string, filter bson.M, reason string,
+) (uint64, error) {
+	deletedCount, err := db.withTransaction(ctx, method, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		timeLockCollection := db.client.Database(db.dbName).Collection(model.TimeLockCollection)
+		cursor, err := timeLockCollection.Find(sessCtx, filter)
+		if err != nil {
+			return uint64(0), err
+		}
+		var timeLocks []*model.ArchivedTimeLockDocument
+		if err := cursor.All(sessCtx, &timeLocks); err != nil {
+			return uint64(0), err
+		}
+		if len(timeLocks) == 0 {
+			return uint64(0), nil
+		}
+
+		archivedAt := time.Now().Unix()
+		archived := make([]interface{}, len(timeLocks))
+		ids := make([]primitive.ObjectID, len(timeLocks))
+		for i, timeLock := range timeLocks {
+			timeLock.ArchiveReason = reason
+			timeLock.ArchivedAt = archivedAt
+			archived[i] = timeLock
+			ids[i] = timeLock.Id
+		}
+		if _, err := db.client.Database(db.dbName).
+			Collection(model.TimeLockArchiveCollection).
+			InsertMany(sessCtx, archived); err != nil {
+			return uint64(0), err
+		}
+
+		result, err := timeLockCollection.DeleteMany(sessCtx, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return uint64(0), err
+		}
+		return uint64(result.DeletedCount), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deletedCount.(uint64), nil
+}
+
+// GetTimeLocks returns the active timelock documents of the delegation
+func (db *Database) GetTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]model.TimeLockDocument, error) {
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockCollection).
+		Find(ctx, bson.M{"staking_tx_hash_hex": stakingTxHashHex},
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var timeLocks []model.TimeLockDocument
+	if err := cursor.All(ctx, &timeLocks); err != nil {
+		return nil, err
+	}
+
+	return timeLocks, nil
+}
+
+func (db *Database) GetArchivedTimeLocks(
+	ctx context.Context, stakingTxHashHex string,
+) ([]*model.ArchivedTimeLockDocument, error) {
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockArchiveCollection).
+		Find(ctx, bson.M{"staking_tx_hash_hex": stakingTxHashHex},
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var timeLocks []*model.ArchivedTimeLockDocument
+	if err := cursor.All(ctx, &timeLocks); err != nil {
+		return nil, err
+	}
+
+	return timeLocks, nil
+}
+
+func (db *Database) FindTimeLocksByParamsVersion(
+	ctx context.Context, subStates []types.DelegationSubState, paramsVersion uint32,
+) ([]model.TimeLockDocument, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"delegation_sub_state": bson.M{"$in": subStates},
+		}}},
+		{{Key: "$lookup", Value: delegationLookup(bson.M{"params_version": 1, "state": 1})}},
+		{{Key: "$match", Value: bson.M{
+			"delegation.params_version": paramsVersion,
+			"delegation.state":          bson.M{"$nin": types.TerminalStatesForTimeLock()},
+		}}},
+		{{Key: "$project", Value: bson.M{"delegation": 0}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var timeLocks []model.TimeLockDocument
+	if err := cursor.All(ctx, &timeLocks); err != nil {
+		return nil, err
+	}
+
+	return timeLocks, nil
+}
+
+func (db *Database) UpdateTimeLockExpireHeight(
+	ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32,
+) error {
+	filter := bson.M{
+		"staking_tx_hash_hex":  timeLock.StakingTxHashHex,
+		"delegation_sub_state": timeLock.DelegationSubState,
+		"expire_height":        timeLock.ExpireHeight,
+	}
+	update := bson.M{"$set": bson.M{"expire_height": newExpireHeight}}
+
+	result, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockCollection).
+		UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return &NotFoundError{
+			Key:     timeLock.StakingTxHashHex,
+			Message: "timelock not found when updating its expire height",
+		}
+	}
+	return nil
+}
+
+type expiringTimeLocksPagination struct {
+	ExpireHeight uint32 `json:"expire_height"`
+	Id           string `json:"id"`
+}
+
+// GetTimeLocksExpiringBetween returns a page of the timelock documents
+// expiring in the BTC height range, inclusive, whose delegation is qualified
+// to become withdrawable, sorted by expire height
+func (db *Database) GetTimeLocksExpiringBetween(
+	ctx context.Context,
+	fromHeight, toHeight uint32,
+	paginationToken string,
+	limit int64,
+) (*DbResultMap[*model.ExpiringTimeLock], error) {
+	match := bson.M{"expire_height": bson.M{"$gte": fromHeight, "$lte": toHeight}}
+	if paginationToken != "" {
+		var pagination expiringTimeLocksPagination
+		if err := decodePaginationToken(paginationToken, &pagination); err != nil {
+			return nil, err
+		}
+		id, err := primitive.ObjectIDFromHex(pagination.Id)
+		if err != nil {
+			return nil, &InvalidPaginationTokenError{Message: "invalid pagination token"}
+		}
+		match["$or"] = []bson.M{
+			{"expire_height": bson.M{"$gt": pagination.ExpireHeight}},
+			{"expire_height": pagination.ExpireHeight, "_id": bson.M{"$gt": id}},
+		}
+	}
+
+	qualifiedStates := types.QualifiedStatesForWithdrawable()
+	stateStrs := make([]string, len(qualifiedStates))
+	for i, state := range qualifiedStates {
+		stateStrs[i] = state.String()
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "expire_height", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$lookup", Value: delegationLookup(bson.M{
+			"state": 1, "staker_btc_pk_hex": 1, "staking_amount": 1,
+		})}},
+		{{Key: "$unwind", Value: "$delegation"}},
+		{{Key: "$match", Value: bson.M{"delegation.state": bson.M{"$in": stateStrs}}}},
+		// One more timelock than the limit tells whether there is a next page
+		{{Key: "$limit", Value: limit + 1}},
+		{{Key: "$project", Value: bson.M{
+			"staking_tx_hash_hex":  1,
+			"expire_height":        1,
+			"delegation_sub_state": 1,
+			"staker_btc_pk_hex":    "$delegation.staker_btc_pk_hex",
+			"staking_amount":       "$delegation.staking_amount",
+		}}},
+	}
+
+	cursor, err := db.client.Database(db.dbName).
+		Collection(model.TimeLockCollection).
+		Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var timeLocks []*model.ExpiringTimeLock
+	if err := cursor.All(ctx, &timeLocks); err != nil {
+		return nil, err
+	}
+
+	return toResultMapWithPaginationToken(
+		timeLocks, limit,
+		func(timeLock *model.ExpiringTimeLock) (string, error) {
+			return encodePaginationToken(expiringTimeLocksPagination{
+				ExpireHeight: timeLock.ExpireHeight,
+				Id:           timeLock.Id.Hex(),
+			})
+		},
+	)
+}
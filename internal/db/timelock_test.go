@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// TestExpiredTimeLocksQueryCovered checks against the Mongo of
+// INTEGRATION_MONGO_ADDRESS that the expiry scan is served by the expiry
+// index alone, whatever the other indexes of the timelocks
+func TestExpiredTimeLocksQueryCovered(t *testing.T) {
+	database := mongoTestDatabase(t, "INTEGRATION", "indexer-integration")
+	ctx := context.Background()
+	collection := database.client.Database(database.dbName).Collection(model.TimeLockCollection)
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expire_height", Value: 1}}},
+		{Keys: bson.D{{Key: "staking_tx_hash_hex", Value: 1}}},
+		{Keys: model.TimeLockExpiryIndexKeys, Options: options.Index().SetName(model.TimeLockExpiryIndexName)},
+	})
+	require.NoError(t, err)
+	const timeLocks, btcTipHeight = 1000, 99
+	docs := make([]interface{}, timeLocks)
+	for i := range docs {
+		docs[i] = model.NewTimeLockDocument(fmt.Sprintf("%064x", i), uint32(i), types.SubStateTimelock)
+	}
+	_, err = collection.InsertMany(ctx, docs)
+	require.NoError(t, err)
+
+	filter, opts := expiredTimeLocksQuery(btcTipHeight)
+	var explained bson.M
+	err = database.client.Database(database.dbName).RunCommand(ctx, bson.D{
+		{Key: "explain", Value: bson.D{
+			{Key: "find", Value: model.TimeLockCollection},
+			{Key: "filter", Value: filter},
+			{Key: "sort", Value: opts.Sort},
+			{Key: "projection", Value: opts.Projection},
+			{Key: "hint", Value: opts.Hint},
+		}},
+		{Key: "verbosity", Value: "executionStats"},
+	}).Decode(&explained)
+	require.NoError(t, err)
+
+	queryPlanner := explained["queryPlanner"].(bson.M)
+	stages := planStages(queryPlanner["winningPlan"])
+	require.Contains(t, stages, "IXSCAN")
+	require.NotContains(t, stages, "COLLSCAN")
+	require.NotContains(t, stages, "FETCH")
+	require.NotContains(t, stages, "SORT")
+
+	executionStats := explained["executionStats"].(bson.M)
+	require.EqualValues(t, btcTipHeight+1, executionStats["nReturned"])
+	require.EqualValues(t, 0, executionStats["totalDocsExamined"])
+
+	// the scan reads the fields the expiry checker needs, oldest first
+	var expired []model.TimeLockDocument
+	require.NoError(t, database.ForEachExpiredDelegation(ctx, btcTipHeight, func(tlDoc model.TimeLockDocument) error {
+		expired = append(expired, tlDoc)
+		return nil
+	}))
+	require.Len(t, expired, btcTipHeight+1)
+	require.Equal(t, *model.NewTimeLockDocument(fmt.Sprintf("%064x", 0), 0, types.SubStateTimelock), expired[0])
+	require.Equal(t, uint32(btcTipHeight), expired[btcTipHeight].ExpireHeight)
+}
+
+// planStages returns the stages of the explained plan, the nested ones
+// included
+func planStages(plan interface{}) []string {
+	var stages []string
+	switch plan := plan.(type) {
+	case bson.M:
+		for key, value := range plan {
+			if stage, ok := value.(string); ok && key == "stage" {
+				stages = append(stages, stage)
+				continue
+			}
+			stages = append(stages, planStages(value)...)
+		}
+	case bson.A:
+		for _, value := range plan {
+			stages = append(stages, planStages(value)...)
+		}
+	}
+	return stages
+}
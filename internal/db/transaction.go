@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// RunInTransaction runs fn in a transaction, the writes made with the context
+// given to fn being committed together once it returns nil
+func (db *Database) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := db.withTransaction(ctx, "RunInTransaction", func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+// withTransaction runs fn in a transaction of a session of its own. The driver
+// runs fn again on a transient error, every such run being counted as a retry
+// of the method. Within a transaction already running, fn runs in it, so
+// that the methods writing in a transaction of their own compose.
+func (db *Database) withTransaction(
+	ctx context.Context,
+	method string,
+	fn func(sessCtx mongo.SessionContext) (interface{}, error),
+) (interface{}, error) {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(mongo.NewSessionContext(ctx, session))
+	}
+
+	session, err := db.client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	metrics.RecordDbTransactionStarted()
+	defer metrics.RecordDbTransactionEnded()
+
+	attempts := 0
+	return session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		attempts++
+		if attempts > 1 {
+			metrics.RecordDbRetry(method)
+			recordRetry(ctx)
+		}
+		return fn(sessCtx)
+	})
+}
+
+type retryCountKey struct{}
+
+// withRetryCount returns a context counting the retries of the transactions
+// run with it
+func withRetryCount(ctx context.Context) (context.Context, *atomic.Int32) {
+	retries := &atomic.Int32{}
+	return context.WithValue(ctx, retryCountKey{}, retries), retries
+}
+
+// recordRetry counts a retry in the count of the context, if any
+func recordRetry(ctx context.Context) {
+	if retries, ok := ctx.Value(retryCountKey{}).(*atomic.Int32); ok {
+		retries.Add(1)
+	}
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+)
+
+// writeCollections maps the methods of DbInterface writing documents to the
+// collection they write to, the one of their main document for the methods
+// writing to several collections
+var writeCollections = map[string]string{
+	"SaveNewFinalityProvider":                     model.FinalityProviderDetailsCollection,
+	"UpdateFinalityProviderState":                 model.FinalityProviderDetailsCollection,
+	"UpdateFinalityProviderDetailsFromEvent":      model.FinalityProviderDetailsCollection,
+	"SaveStakingParams":                           model.GlobalParamsCollection,
+	"ReplaceStakingParams":                        model.GlobalParamsCollection,
+	"SaveCheckpointParams":                        model.GlobalParamsCollection,
+	"SaveNewBTCDelegation":                        model.BTCDelegationDetailsCollection,
+	"UpdateBTCDelegationState":                    model.BTCDelegationDetailsCollection,
+	"ApplyBTCDelegationStateChange":               model.BTCDelegationDetailsCollection,
+	"OverrideBTCDelegationState":                  model.BTCDelegationDetailsCollection,
+	"UpdateBTCDelegationSubState":                 model.BTCDelegationDetailsCollection,
+	"UpdateBTCDelegationDetails":                  model.BTCDelegationDetailsCollection,
+	"SaveBTCDelegationUnbondingCovenantSignature": model.BTCDelegationDetailsCollection,
+	"SetCovenantSignatureVerified":                model.BTCDelegationDetailsCollection,
+	"BulkUpdateDelegationStates":                  model.BTCDelegationDetailsCollection,
+	"BulkSaveCovenantSignatures":                  model.BTCDelegationDetailsCollection,
+	"UpdateDelegationsStateByFinalityProvider":    model.BTCDelegationDetailsCollection,
+	"SaveBTCDelegationSlashingTxHex":              model.BTCDelegationDetailsCollection,
+	"SaveBTCDelegationUnbondingSlashingTxHex":     model.BTCDelegationDetailsCollection,
+	"DeleteBTCDelegation":                         model.BTCDelegationDetailsCollection,
+	"SaveDelegationStateTransition":               model.DelegationStateHistoryCollection,
+	"SaveNewTimeLockExpire":                       model.TimeLockCollection,
+	"DeleteExpiredDelegation":                     model.TimeLockCollection,
+	"DeleteTimeLocks":                             model.TimeLockCollection,
+	"UpdateTimeLockExpireHeight":                  model.TimeLockCollection,
+	"UpdateLastProcessedBbnHeight":                model.LastProcessedHeightCollection,
+	"HaltBbnProcessing":                           model.LastProcessedHeightCollection,
+	"ResyncLastProcessedBbnHeight":                model.LastProcessedHeightCollection,
+	"StartBbnBlockProcessing":                     model.LastProcessedHeightCollection,
+	"MarkBbnEventProcessed":                       model.LastProcessedHeightCollection,
+	"MarkBbnHeightProcessed":                      model.ProcessedBbnHeightsCollection,
+	"SaveFinalityProviderVotingPowerChange":       model.FpVotingPowerChangesCollection,
+	"SaveBTCHeader":                               model.BTCHeadersCollection,
+	"DeleteBTCHeadersAbove":                       model.BTCHeadersCollection,
+	"DeleteBTCHeadersBelow":                       model.BTCHeadersCollection,
+	"SaveBTCDerivedChange":                        model.BTCDerivedChangesCollection,
+	"RollbackBTCDerivedChanges":                   model.BTCDerivedChangesCollection,
+	"DeleteBTCDerivedChangesBelow":                model.BTCDerivedChangesCollection,
+	"SaveReconciliationRun":                       model.ReconciliationReportsCollection,
+	"SaveReconciliationDiscrepancy":               model.ReconciliationReportsCollection,
+	"SaveStuckDelegationReport":                   model.StuckDelegationReportsCollection,
+	"SaveOutboxEvent":                             model.OutboxEventsCollection,
+	"MarkOutboxEventSent":                         model.OutboxEventsCollection,
+	"MarkOutboxEventFailed":                       model.OutboxEventsCollection,
+	"RequeuePoisonOutboxEvents":                   model.OutboxEventsCollection,
+	"SaveGlobalStats":                             model.GlobalStatsCollection,
+	"SaveBootstrap":                               model.BootstrapCollection,
+	"ArchivePrunableBTCDelegations":               model.BTCDelegationArchiveCollection,
+	"DeletePrunableArchivedTimeLocks":             model.TimeLockArchiveCollection,
+	"SaveRawEventCapture":                         model.RawEventCapturesCollection,
+	"SaveBbnEventDeadLetter":                      model.BbnEventDeadLettersCollection,
+	"AcquireLock":                                 model.LocksCollection,
+	"ReleaseLock":                                 model.LocksCollection,
+}
+
+// WriteStats counts the successful write calls made with a context, by
+// collection, as recorded by the MetricsDatabase
+type WriteStats struct {
+	mu     sync.Mutex
+	writes map[string]uint64
+}
+
+type writeStatsKey struct{}
+
+// WithWriteStats returns a context counting the writes made with it, and
+// with the contexts derived from it
+func WithWriteStats(ctx context.Context) (context.Context, *WriteStats) {
+	stats := &WriteStats{writes: make(map[string]uint64)}
+	return context.WithValue(ctx, writeStatsKey{}, stats), stats
+}
+
+// Writes returns the number of writes by collection
+func (s *WriteStats) Writes() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writes := make(map[string]uint64, len(s.writes))
+	for collection, count := range s.writes {
+		writes[collection] = count
+	}
+	return writes
+}
+
+// recordWrite counts a successful call to the method in the write stats of
+// the context, if any and if the method writes
+func recordWrite(ctx context.Context, method string) {
+	stats, ok := ctx.Value(writeStatsKey{}).(*WriteStats)
+	if !ok {
+		return
+	}
+	collection, ok := writeCollections[method]
+	if !ok {
+		return
+	}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	stats.writes[collection]++
+}
@@ -0,0 +1,102 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+func (s Severity) String() string {
+	return string(s)
+}
+
+// Alerter pushes alerts on critical indexer conditions, ahead of the metrics
+// based alerting. The title identifies the condition, along with what it
+// affects if several can be affected at once, so it must not carry the values
+// varying between two alerts on the same condition, which go in the details.
+type Alerter interface {
+	Alert(ctx context.Context, severity Severity, title string, details map[string]string)
+}
+
+// New returns the alerter selected by the config, dropping the alerts on a
+// condition already alerted on within the configured min interval
+func New(cfg *config.AlertingConfig) Alerter {
+	var alerter Alerter = NewNoopAlerter()
+	if cfg.IsWebhook() {
+		alerter = NewWebhookAlerter(cfg.WebhookURL, cfg.Timeout)
+	}
+	return NewRateLimitedAlerter(alerter, cfg.MinInterval)
+}
+
+// NoopAlerter only logs the alerts
+type NoopAlerter struct{}
+
+func NewNoopAlerter() *NoopAlerter {
+	return &NoopAlerter{}
+}
+
+func (a *NoopAlerter) Alert(_ context.Context, severity Severity, title string, details map[string]string) {
+	log.Warn().
+		Str("severity", severity.String()).
+		Str("title", title).
+		Interface("details", details).
+		Msg("alert not pushed, no alerter configured")
+}
+
+// RateLimitedAlerter drops the alerts on a condition, identified by its
+// severity and title, already alerted on within the min interval, so that a
+// flapping condition does not spam
+type RateLimitedAlerter struct {
+	next        Alerter
+	minInterval time.Duration
+	now         func() time.Time
+
+	mu sync.Mutex
+	// alertedAt is when each condition was last alerted on
+	alertedAt map[string]time.Time
+}
+
+func NewRateLimitedAlerter(next Alerter, minInterval time.Duration) *RateLimitedAlerter {
+	return &RateLimitedAlerter{
+		next:        next,
+		minInterval: minInterval,
+		now:         time.Now,
+		alertedAt:   make(map[string]time.Time),
+	}
+}
+
+func (a *RateLimitedAlerter) Alert(
+	ctx context.Context, severity Severity, title string, details map[string]string,
+) {
+	if !a.allow(fmt.Sprintf("%s/%s", severity, title)) {
+		log.Debug().
+			Str("severity", severity.String()).
+			Str("title", title).
+			Msg("alert dropped, already alerted on recently")
+		return
+	}
+	a.next.Alert(ctx, severity, title, details)
+}
+
+func (a *RateLimitedAlerter) allow(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if alertedAt, ok := a.alertedAt[key]; ok && now.Sub(alertedAt) < a.minInterval {
+		return false
+	}
+	a.alertedAt[key] = now
+	return true
+}
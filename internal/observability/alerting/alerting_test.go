@@ -0,0 +1,60 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingAlerter struct {
+	titles []string
+}
+
+func (a *recordingAlerter) Alert(_ context.Context, _ Severity, title string, _ map[string]string) {
+	a.titles = append(a.titles, title)
+}
+
+func TestRateLimitedAlerter(t *testing.T) {
+	ctx := context.Background()
+	recorder := &recordingAlerter{}
+	alerter := NewRateLimitedAlerter(recorder, time.Minute)
+	now := time.Now()
+	alerter.now = func() time.Time { return now }
+
+	alerter.Alert(ctx, SeverityCritical, "a", nil)
+	alerter.Alert(ctx, SeverityCritical, "a", map[string]string{"height": "2"})
+	// another condition is not held back
+	alerter.Alert(ctx, SeverityCritical, "b", nil)
+	alerter.Alert(ctx, SeverityWarning, "a", nil)
+	require.Equal(t, []string{"a", "b", "a"}, recorder.titles)
+
+	now = now.Add(time.Minute)
+	alerter.Alert(ctx, SeverityCritical, "a", nil)
+	require.Equal(t, []string{"a", "b", "a", "a"}, recorder.titles)
+}
+
+func TestWebhookAlerter(t *testing.T) {
+	payloads := make(chan WebhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	alerter := NewWebhookAlerter(server.URL, time.Second)
+	alerter.Alert(context.Background(), SeverityCritical, "BTC reorg too deep", map[string]string{
+		"max_reorg_depth": "6",
+		"latest_height":   "800",
+	})
+
+	payload := <-payloads
+	require.Equal(t, "[CRITICAL] BTC reorg too deep\nlatest_height: 800\nmax_reorg_depth: 6", payload.Text)
+	require.Equal(t, SeverityCritical, payload.Severity)
+	require.Equal(t, "800", payload.Details["latest_height"])
+}
@@ -0,0 +1,98 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WebhookPayload is the body of the alerts POSTed by the WebhookAlerter. Text
+// is the message displayed by the Slack incoming webhooks, the other fields
+// being for the endpoints parsing the alert.
+type WebhookPayload struct {
+	Text     string            `json:"text"`
+	Severity Severity          `json:"severity"`
+	Title    string            `json:"title"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+// WebhookAlerter POSTs the alerts to a Slack compatible webhook. A failed
+// alert is logged and not retried, the metrics based alerting covering the
+// condition too.
+type WebhookAlerter struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewWebhookAlerter(url string, timeout time.Duration) *WebhookAlerter {
+	return &WebhookAlerter{
+		url:        url,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (a *WebhookAlerter) Alert(ctx context.Context, severity Severity, title string, details map[string]string) {
+	if err := a.post(ctx, newWebhookPayload(severity, title, details)); err != nil {
+		log.Error().Err(err).
+			Str("severity", severity.String()).
+			Str("title", title).
+			Interface("details", details).
+			Msg("failed to push the alert")
+	}
+}
+
+func (a *WebhookAlerter) post(ctx context.Context, payload WebhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func newWebhookPayload(severity Severity, title string, details map[string]string) WebhookPayload {
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "[%s] %s", strings.ToUpper(severity.String()), title)
+	for _, key := range keys {
+		fmt.Fprintf(&text, "\n%s: %s", key, details[key])
+	}
+
+	return WebhookPayload{
+		Text:     text.String(),
+		Severity: severity,
+		Title:    title,
+		Details:  details,
+	}
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+)
+
+// Triggers of the mutations, besides those of the state transitions
+const (
+	// TriggerBbnEvent is the processing of a BBN event
+	TriggerBbnEvent = "bbn_event"
+	// TriggerPoller is a run of a poller, e.g. the params poller
+	TriggerPoller = "poller"
+	// TriggerBootstrap is the sync of the chain state at startup
+	TriggerBootstrap = "bootstrap"
+	// TriggerUnknown is a mutation made outside of any triggered scope
+	TriggerUnknown = "unknown"
+
+	TriggerBtcSpend       = model.StateTransitionTriggerBtcSpend
+	TriggerExpiry         = model.StateTransitionTriggerExpiry
+	TriggerBtcReorg       = model.StateTransitionTriggerBtcReorg
+	TriggerAdmin          = model.StateTransitionTriggerAdmin
+	TriggerReconciliation = model.StateTransitionTriggerReconciliation
+)
+
+// Trigger is what caused the mutations made in its scope
+type Trigger struct {
+	Type string
+	// BbnEventType and BbnHeight identify the BBN event of a bbn_event
+	BbnEventType string
+	BbnHeight    uint64
+	// BtcHeight is the BTC height of a btc_spend, expiry or btc_reorg
+	BtcHeight uint64
+	// TxHash is the staking tx of a BBN event or the spending tx of a spend
+	TxHash string
+	// Operator is the admin of an admin command
+	Operator string
+	// Poller is the poller of a poller run
+	Poller string
+}
+
+type triggerKey struct{}
+
+// WithTrigger opens the scope of the trigger, replacing the enclosing one
+func WithTrigger(ctx context.Context, trigger Trigger) context.Context {
+	return context.WithValue(ctx, triggerKey{}, trigger)
+}
+
+// TriggerFromContext returns the trigger of the scope carried by the context
+func TriggerFromContext(ctx context.Context) Trigger {
+	if trigger, ok := ctx.Value(triggerKey{}).(Trigger); ok {
+		return trigger
+	}
+	return Trigger{Type: TriggerUnknown}
+}
+
+var logger atomic.Pointer[zerolog.Logger]
+
+func init() {
+	SetWriter(io.Discard)
+}
+
+// Init opens the configured output of the audit stream and returns the
+// function closing it
+func Init(cfg *config.AuditConfig) (func() error, error) {
+	switch cfg.Output {
+	case "":
+		SetWriter(io.Discard)
+		return func() error { return nil }, nil
+	case "stdout":
+		SetWriter(os.Stdout)
+		return func() error { return nil }, nil
+	case "stderr":
+		SetWriter(os.Stderr)
+		return func() error { return nil }, nil
+	}
+
+	file, err := os.OpenFile(cfg.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the audit output: %w", err)
+	}
+	SetWriter(file)
+	return file.Close, nil
+}
+
+// SetWriter sends the audit records to the writer
+func SetWriter(w io.Writer) {
+	auditLogger := zerolog.New(w).With().Timestamp().Str("log", "audit").Logger()
+	logger.Store(&auditLogger)
+}
+
+// Record writes the audit record of a mutation, with the trigger of its
+// scope and the fields identifying what it mutated
+func Record(ctx context.Context, method string, err error, fields map[string]any) {
+	trigger := TriggerFromContext(ctx)
+	event := logger.Load().Log().
+		Str("method", method).
+		Str("trigger", trigger.Type)
+	if trigger.BbnEventType != "" {
+		event = event.Str(logging.EventTypeField, trigger.BbnEventType)
+	}
+	if trigger.BbnHeight != 0 {
+		event = event.Uint64(logging.BbnHeightField, trigger.BbnHeight)
+	}
+	if trigger.BtcHeight != 0 {
+		event = event.Uint64("btc_height", trigger.BtcHeight)
+	}
+	if trigger.TxHash != "" {
+		event = event.Str("tx_hash", trigger.TxHash)
+	}
+	if trigger.Operator != "" {
+		event = event.Str("operator", trigger.Operator)
+	}
+	if trigger.Poller != "" {
+		event = event.Str("poller", trigger.Poller)
+	}
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		event = event.Err(err)
+	}
+	event.Str("outcome", outcome).Fields(fields).Send()
+}
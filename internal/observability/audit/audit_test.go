@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	var records bytes.Buffer
+	SetWriter(&records)
+	t.Cleanup(func() { SetWriter(&bytes.Buffer{}) })
+
+	decode := func() map[string]any {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(records.Bytes(), &record))
+		require.NotEmpty(t, record["time"])
+		delete(record, "time")
+		records.Reset()
+		return record
+	}
+
+	Record(context.Background(), "DeleteTimeLocks", nil, nil)
+	require.Equal(t, map[string]any{
+		"log":     "audit",
+		"method":  "DeleteTimeLocks",
+		"trigger": TriggerUnknown,
+		"outcome": "success",
+	}, decode())
+
+	ctx := WithTrigger(context.Background(), Trigger{Type: TriggerPoller, Poller: "params"})
+	// an inner scope replaces the enclosing one
+	ctx = WithTrigger(ctx, Trigger{Type: TriggerBtcSpend, BtcHeight: 120, TxHash: "spend"})
+	Record(ctx, "UpdateBTCDelegationState", errors.New("boom"), map[string]any{"state": "UNBONDING"})
+	require.Equal(t, map[string]any{
+		"log":        "audit",
+		"method":     "UpdateBTCDelegationState",
+		"trigger":    TriggerBtcSpend,
+		"btc_height": float64(120),
+		"tx_hash":    "spend",
+		"outcome":    "error",
+		"error":      "boom",
+		"state":      "UNBONDING",
+	}, decode())
+}
@@ -0,0 +1,70 @@
+package errorreporting
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+)
+
+// The tags grouping the reported errors, besides the correlation fields of
+// the logging scope of the error, e.g. bbn_height and staking_tx_hash
+const (
+	ComponentTag = "component"
+	// PanicTag is set on the errors of a recovered panic
+	PanicTag = "panic"
+)
+
+// ErrorReporter reports the indexer errors to an exception aggregation
+// service. The error message groups the reports, so it must not carry the
+// values varying between two occurrences of the same error, which go in the
+// tags when not already in the logging scope.
+type ErrorReporter interface {
+	Report(ctx context.Context, err error, tags map[string]string)
+}
+
+// NoopReporter drops the errors, which are logged by their callers anyway
+type NoopReporter struct{}
+
+func (r *NoopReporter) Report(context.Context, error, map[string]string) {}
+
+var reporter atomic.Pointer[ErrorReporter]
+
+func init() {
+	SetReporter(&NoopReporter{})
+}
+
+// Init sets the reporter selected by the config
+func Init(cfg *config.ErrorReportingConfig) error {
+	if !cfg.IsEnabled() {
+		SetReporter(&NoopReporter{})
+		return nil
+	}
+
+	sentryReporter, err := NewSentryReporter(cfg.DSN, cfg.Environment, cfg.Timeout)
+	if err != nil {
+		return err
+	}
+	SetReporter(sentryReporter)
+	return nil
+}
+
+// SetReporter sets the reporter the errors are reported to
+func SetReporter(r ErrorReporter) {
+	reporter.Store(&r)
+}
+
+// Report reports the error of the component with the correlation fields of
+// the logging scope carried by the context and the given tags
+func Report(ctx context.Context, component string, err error, tags map[string]string) {
+	if err == nil {
+		return
+	}
+	scopeTags := logging.ScopeFields(ctx)
+	for key, value := range tags {
+		scopeTags[key] = value
+	}
+	scopeTags[ComponentTag] = component
+	(*reporter.Load()).Report(ctx, err, scopeTags)
+}
@@ -0,0 +1,86 @@
+package errorreporting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+type report struct {
+	err  error
+	tags map[string]string
+}
+
+type recordingReporter struct {
+	reports []report
+}
+
+func (r *recordingReporter) Report(_ context.Context, err error, tags map[string]string) {
+	r.reports = append(r.reports, report{err: err, tags: tags})
+}
+
+func TestReportTagsScope(t *testing.T) {
+	recorder := &recordingReporter{}
+	SetReporter(recorder)
+	t.Cleanup(func() { SetReporter(&NoopReporter{}) })
+
+	ctx := logging.WithBbnEvent(logging.WithBbnHeight(context.Background(), 100), "created", "tx")
+	Report(ctx, "block-processor", errors.New("boom"), map[string]string{"poller": "expiry"})
+	Report(ctx, "block-processor", nil, nil)
+
+	require.Len(t, recorder.reports, 1)
+	require.Equal(t, map[string]string{
+		ComponentTag:               "block-processor",
+		logging.BbnHeightField:     "100",
+		logging.EventTypeField:     "created",
+		logging.StakingTxHashField: "tx",
+		"poller":                   "expiry",
+	}, recorder.reports[0].tags)
+}
+
+func TestSentryReporter(t *testing.T) {
+	var (
+		path  string
+		auth  string
+		event SentryEvent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("X-Sentry-Auth")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "://", "://public-key@", 1) + "/42"
+	reporter, err := NewSentryReporter(dsn, "test", time.Second)
+	require.NoError(t, err)
+
+	serviceErr := types.NewInternalServiceError(fmt.Errorf("failed to save: %w", context.DeadlineExceeded))
+	reporter.Report(context.Background(), serviceErr, map[string]string{ComponentTag: "api"})
+
+	require.Equal(t, "/api/42/store/", path)
+	require.Contains(t, auth, "sentry_key=public-key")
+	require.Len(t, event.EventId, 32)
+	require.Equal(t, "error", event.Level)
+	require.Equal(t, "test", event.Environment)
+	require.Equal(t, map[string]string{ComponentTag: "api"}, event.Tags)
+	require.Equal(t, []string{"{{ default }}", "api"}, event.Fingerprint)
+	// the exception type is the one of the innermost error
+	require.Equal(t, []SentryException{
+		{Type: "context.deadlineExceededError", Value: serviceErr.Error()},
+	}, event.Exception.Values)
+
+	_, err = NewSentryReporter("https://sentry.example.com/42", "test", time.Second)
+	require.Error(t, err)
+}
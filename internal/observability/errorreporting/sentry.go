@@ -0,0 +1,152 @@
+package errorreporting
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// sentryClient identifies the reporter to the Sentry compatible services
+const sentryClient = "babylon-staking-indexer/1.0"
+
+// SentryEvent is the body of the events stored by the SentryReporter, as
+// expected by the store endpoint of the Sentry protocol
+type SentryEvent struct {
+	EventId     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Environment string            `json:"environment,omitempty"`
+	Message     string            `json:"message"`
+	Exception   *SentryExceptions `json:"exception"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	// Fingerprint groups the events by component on top of the default
+	// grouping by exception
+	Fingerprint []string `json:"fingerprint"`
+}
+
+type SentryExceptions struct {
+	Values []SentryException `json:"values"`
+}
+
+type SentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// SentryReporter stores the errors as events of a Sentry compatible project.
+// A failed report is logged and not retried.
+type SentryReporter struct {
+	storeURL    string
+	auth        string
+	environment string
+	httpClient  *http.Client
+}
+
+// NewSentryReporter returns the reporter to the project of the DSN, e.g.
+// https://<key>@<host>/<project id>
+func NewSentryReporter(dsn string, environment string, timeout time.Duration) (*SentryReporter, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	key := parsed.User.Username()
+	projectId := strings.Trim(parsed.Path, "/")
+	if key == "" || projectId == "" {
+		return nil, errors.New("sentry dsn must hold the public key and the project id")
+	}
+
+	return &SentryReporter{
+		storeURL: fmt.Sprintf("%s://%s/api/%s/store/", parsed.Scheme, parsed.Host, projectId),
+		auth: fmt.Sprintf(
+			"Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, key,
+		),
+		environment: environment,
+		httpClient:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (r *SentryReporter) Report(ctx context.Context, err error, tags map[string]string) {
+	if postErr := r.post(ctx, r.newEvent(err, tags)); postErr != nil {
+		log.Error().Err(postErr).
+			AnErr("reported_error", err).
+			Interface("tags", tags).
+			Msg("failed to report the error")
+	}
+}
+
+func (r *SentryReporter) newEvent(err error, tags map[string]string) SentryEvent {
+	return SentryEvent{
+		EventId:     newEventId(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Logger:      "babylon-staking-indexer",
+		Environment: r.environment,
+		Message:     err.Error(),
+		Exception: &SentryExceptions{Values: []SentryException{
+			{Type: errorType(err), Value: err.Error()},
+		}},
+		Tags:        tags,
+		Fingerprint: []string{"{{ default }}", tags[ComponentTag]},
+	}
+}
+
+func (r *SentryReporter) post(ctx context.Context, event SentryEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// errorType returns the type of the innermost error wrapped, the one telling
+// what failed
+func errorType(err error) string {
+	for {
+		unwrapped := errors.Unwrap(err)
+		if unwrapped == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = unwrapped
+	}
+}
+
+func newEventId() string {
+	id := make([]byte, 16)
+	// crypto/rand does not fail on the supported platforms
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,182 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+)
+
+// Component is a part of the indexer whose log level can be changed at
+// runtime independently of the others
+type Component string
+
+const (
+	BlockProcessor Component = "block-processor"
+	Expiry         Component = "expiry"
+	DB             Component = "db"
+	BbnClient      Component = "bbnclient"
+	BtcClient      Component = "btcclient"
+	Emitter        Component = "emitter"
+)
+
+// Components are the components whose log level can be changed
+var Components = []Component{BlockProcessor, Expiry, DB, BbnClient, BtcClient, Emitter}
+
+// Levels are the names of the levels a component can be set to
+var Levels = []string{
+	zerolog.LevelTraceValue,
+	zerolog.LevelDebugValue,
+	zerolog.LevelInfoValue,
+	zerolog.LevelWarnValue,
+	zerolog.LevelErrorValue,
+	zerolog.LevelFatalValue,
+	zerolog.LevelPanicValue,
+	"disabled",
+}
+
+// componentLevels holds the current level of every component. The map is
+// filled once and never changes, the levels are swapped atomically.
+var componentLevels = func() map[Component]*atomic.Int32 {
+	levels := make(map[Component]*atomic.Int32, len(Components))
+	for _, component := range Components {
+		levels[component] = &atomic.Int32{}
+		levels[component].Store(int32(zerolog.TraceLevel))
+	}
+	return levels
+}()
+
+// zapLevels follow the levels of the components logging through zap
+var zapLevels = map[Component]zap.AtomicLevel{
+	Emitter: zap.NewAtomicLevelAt(zapcore.DebugLevel),
+}
+
+// Init sets the level of the logs outside of any component and the levels
+// of the components, those not configured following the default level
+func Init(cfg *config.LogConfig) error {
+	defaultLevel, err := parseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	for name := range cfg.ComponentLevels {
+		if _, ok := componentLevels[Component(name)]; !ok {
+			return unknownComponentError(name)
+		}
+	}
+
+	log.Logger = log.Logger.Level(defaultLevel)
+	for _, component := range Components {
+		componentLevel := defaultLevel
+		if name, ok := cfg.ComponentLevels[string(component)]; ok {
+			if componentLevel, err = parseLevel(name); err != nil {
+				return fmt.Errorf("invalid log level of %s: %w", component, err)
+			}
+		}
+		component.setLevel(componentLevel)
+	}
+
+	return nil
+}
+
+// SetLevel changes the level of the component, leaving the others as they are
+func SetLevel(component, level string) error {
+	if _, ok := componentLevels[Component(component)]; !ok {
+		return unknownComponentError(component)
+	}
+	parsed, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	Component(component).setLevel(parsed)
+	return nil
+}
+
+// CurrentLevels returns the current level of every component by name
+func CurrentLevels() map[string]string {
+	levels := make(map[string]string, len(Components))
+	for _, component := range Components {
+		levels[string(component)] = component.Level().String()
+	}
+	return levels
+}
+
+// Level returns the current level of the component
+func (c Component) Level() zerolog.Level {
+	return zerolog.Level(componentLevels[c].Load())
+}
+
+// FromContext returns the logger of the scope carried by the context at the
+// current level of the component, its records carrying the component
+func (c Component) FromContext(ctx context.Context) *zerolog.Logger {
+	logger := FromContext(ctx).With().
+		Ctx(componentContexts[c]).
+		Str(ComponentField, string(c)).
+		Logger().
+		Level(c.Level())
+	return &logger
+}
+
+// Logger returns the logger of the component outside of any scope
+func (c Component) Logger() *zerolog.Logger {
+	return c.FromContext(context.Background())
+}
+
+// ZapLevel returns the level of the zap loggers of the component, following
+// its changes
+func (c Component) ZapLevel() zap.AtomicLevel {
+	return zapLevels[c]
+}
+
+func (c Component) setLevel(level zerolog.Level) {
+	componentLevels[c].Store(int32(level))
+	if zapLevel, ok := zapLevels[c]; ok {
+		zapLevel.SetLevel(toZapLevel(level))
+	}
+}
+
+func parseLevel(level string) (zerolog.Level, error) {
+	parsed, err := zerolog.ParseLevel(level)
+	// An empty level parses as no level, which would log everything
+	if err != nil || level == "" || parsed == zerolog.NoLevel {
+		return zerolog.NoLevel, fmt.Errorf(
+			"invalid log level %q, valid levels: %s", level, strings.Join(Levels, ", "),
+		)
+	}
+	return parsed, nil
+}
+
+func unknownComponentError(component string) error {
+	names := make([]string, 0, len(Components))
+	for _, c := range Components {
+		names = append(names, string(c))
+	}
+	return fmt.Errorf(
+		"unknown log component %q, valid components: %s", component, strings.Join(names, ", "),
+	)
+}
+
+// toZapLevel returns the zap level letting through the same logs as the
+// zerolog one
+func toZapLevel(level zerolog.Level) zapcore.Level {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return zapcore.DebugLevel
+	case zerolog.InfoLevel:
+		return zapcore.InfoLevel
+	case zerolog.WarnLevel:
+		return zapcore.WarnLevel
+	case zerolog.ErrorLevel:
+		return zapcore.ErrorLevel
+	case zerolog.FatalLevel, zerolog.PanicLevel:
+		return zapcore.PanicLevel
+	default:
+		return zapcore.InvalidLevel
+	}
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+)
+
+func TestComponentLevels(t *testing.T) {
+	var logs bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() {
+		log.Logger = previousLogger
+		require.NoError(t, Init(&config.LogConfig{Level: "trace"}))
+	})
+
+	require.NoError(t, Init(&config.LogConfig{
+		Level:           "info",
+		ComponentLevels: map[string]string{"expiry": "error"},
+	}))
+	require.Equal(t, zerolog.InfoLevel, DB.Level())
+	require.Equal(t, zerolog.ErrorLevel, Expiry.Level())
+
+	ctx := WithStakingTxHash(context.Background(), "tx")
+	DB.FromContext(ctx).Debug().Msg("db debug")
+	Expiry.FromContext(ctx).Warn().Msg("expiry warn")
+	require.Empty(t, logs.String())
+
+	// raising one component leaves the others and the scope fields as they are
+	require.NoError(t, SetLevel("db", "debug"))
+	DB.FromContext(ctx).Debug().Msg("db debug")
+	BlockProcessor.FromContext(ctx).Debug().Msg("block processor debug")
+	require.JSONEq(t, `{"level":"debug","staking_tx_hash":"tx","component":"db","message":"db debug"}`, logs.String())
+	require.Equal(t, zerolog.ErrorLevel, Expiry.Level())
+
+	require.NoError(t, SetLevel("emitter", "warn"))
+	require.False(t, Emitter.ZapLevel().Enabled(zapcore.InfoLevel))
+	require.True(t, Emitter.ZapLevel().Enabled(zapcore.WarnLevel))
+
+	require.ErrorContains(t, SetLevel("api", "debug"), "valid components: block-processor, expiry, db")
+	require.ErrorContains(t, SetLevel("db", ""), "valid levels: trace, debug")
+	require.Error(t, Init(&config.LogConfig{Level: "info", ComponentLevels: map[string]string{"api": "info"}}))
+	require.Equal(t, zerolog.DebugLevel, DB.Level())
+}
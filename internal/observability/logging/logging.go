@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// The correlation fields set on the logger of a scope, so that every line
+// logged about a block, an event, a delegation, a finality provider or an api
+// request can be found by the value of the field
+const (
+	BbnHeightField     = "bbn_height"
+	EventTypeField     = "event_type"
+	StakingTxHashField = "staking_tx_hash"
+	FpBtcPkField       = "fp_btc_pk"
+	RequestIDField     = "request_id"
+)
+
+// ComponentField is the field set on the loggers of the components
+const ComponentField = "component"
+
+// FromContext returns the logger of the scope carried by the context, the
+// global logger outside of any scope
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &log.Logger
+}
+
+// WithBbnHeight opens the scope of the processing of a BBN block
+func WithBbnHeight(ctx context.Context, height uint64) context.Context {
+	return with(ctx, BbnHeightField, height)
+}
+
+// WithBbnEvent opens the scope of the handling of a BBN event, along with
+// the delegation it is about if any
+func WithBbnEvent(ctx context.Context, eventType, stakingTxHash string) context.Context {
+	ctx = with(ctx, EventTypeField, eventType)
+	if stakingTxHash == "" {
+		return ctx
+	}
+	return WithStakingTxHash(ctx, stakingTxHash)
+}
+
+// WithStakingTxHash opens the scope of the handling of a delegation
+func WithStakingTxHash(ctx context.Context, stakingTxHash string) context.Context {
+	return with(ctx, StakingTxHashField, stakingTxHash)
+}
+
+// WithFpBtcPk opens the scope of the handling of a finality provider
+func WithFpBtcPk(ctx context.Context, fpBtcPk string) context.Context {
+	return with(ctx, FpBtcPkField, fpBtcPk)
+}
+
+// WithRequestID opens the scope of the serving of an api request
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return with(ctx, RequestIDField, requestID)
+}
+
+// ScopeFields returns the correlation fields of the scope carried by the
+// context, formatted
+func ScopeFields(ctx context.Context) map[string]string {
+	fields := make(map[string]string)
+	for _, field := range []string{
+		BbnHeightField, EventTypeField, StakingTxHashField, FpBtcPkField, RequestIDField,
+	} {
+		if value := ctx.Value(fieldKey(field)); value != nil {
+			fields[field] = fmt.Sprint(value)
+		}
+	}
+	return fields
+}
+
+type fieldKey string
+
+// with sets the field on the logger of the context. A field already set to
+// the value by an enclosing scope is not repeated, so that the functions
+// handling a delegation can open its scope whether or not their caller did.
+func with(ctx context.Context, field string, value any) context.Context {
+	if ctx.Value(fieldKey(field)) == value {
+		return ctx
+	}
+	logger := FromContext(ctx).With().Interface(field, value).Logger()
+	return context.WithValue(logger.WithContext(ctx), fieldKey(field), value)
+}
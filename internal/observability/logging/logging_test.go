@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopes(t *testing.T) {
+	var logs bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { log.Logger = previousLogger })
+
+	FromContext(context.Background()).Info().Msg("outside")
+	require.Equal(t, `{"level":"info","message":"outside"}`, strings.TrimSpace(logs.String()))
+	logs.Reset()
+
+	ctx := WithBbnEvent(WithBbnHeight(context.Background(), 100), "created", "tx")
+	// a delegation scope opened again for the same delegation adds nothing
+	ctx = WithStakingTxHash(ctx, "tx")
+	FromContext(WithFpBtcPk(ctx, "fp")).Info().Msg("inside")
+
+	line := logs.String()
+	require.Equal(t, 1, strings.Count(line, StakingTxHashField))
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &entry))
+	require.Equal(t, map[string]any{
+		"level":            "info",
+		"message":          "inside",
+		BbnHeightField:     float64(100),
+		EventTypeField:     "created",
+		StakingTxHashField: "tx",
+		FpBtcPkField:       "fp",
+	}, entry)
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// noComponent is the component label of the records logged outside of any
+// component
+const noComponent = "none"
+
+type componentKey struct{}
+
+// componentContexts are set on the loggers of every component, for the
+// metrics hook to tell their records apart, a hook not being able to read
+// the fields of a record. They are built once, not to allocate on every log.
+var componentContexts = func() map[Component]context.Context {
+	contexts := make(map[Component]context.Context, len(Components))
+	for _, component := range Components {
+		contexts[component] = context.WithValue(context.Background(), componentKey{}, component)
+	}
+	return contexts
+}()
+
+// metricsHook counts the warn, error, fatal and panic records by level and
+// component, as they are logged rather than once shipped. It runs on every
+// record, so it does not allocate, and it never logs, so that a record logged
+// by the metrics layer is counted like any other without recursing.
+type metricsHook struct {
+	record func(level string, component string)
+}
+
+func (h metricsHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	if level < zerolog.WarnLevel || level > zerolog.PanicLevel {
+		return
+	}
+	component := noComponent
+	if c, ok := e.GetCtx().Value(componentKey{}).(Component); ok {
+		component = string(c)
+	}
+	h.record(level.String(), component)
+}
+
+// The hook is installed with the global logger every other logger derives
+// from, so that the records are counted from the first one
+func init() {
+	log.Logger = log.Logger.Hook(metricsHook{record: metrics.RecordLogRecord})
+}
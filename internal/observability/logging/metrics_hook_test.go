@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsHook(t *testing.T) {
+	counts := make(map[[2]string]int)
+	hook := metricsHook{record: func(level string, component string) {
+		counts[[2]string{level, component}]++
+	}}
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs).Level(zerolog.InfoLevel).Hook(hook)
+	ctx := logger.WithContext(context.Background())
+
+	FromContext(ctx).Warn().Msg("warn")
+	FromContext(ctx).Debug().Msg("filtered out")
+	DB.FromContext(ctx).Error().Msg("error")
+	DB.FromContext(ctx).Info().Msg("info")
+	Expiry.FromContext(WithBbnHeight(ctx, 1)).Warn().Msg("warn")
+
+	require.Equal(t, map[[2]string]int{
+		{"warn", noComponent}:    1,
+		{"error", string(DB)}:    1,
+		{"warn", string(Expiry)}: 1,
+	}, counts)
+
+	// the records of a component logger are counted without allocating
+	event := DB.FromContext(ctx).Warn()
+	allocs := testing.AllocsPerRun(100, func() {
+		hook.Run(event, zerolog.WarnLevel, "")
+	})
+	require.Zero(t, allocs)
+}
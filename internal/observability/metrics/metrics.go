@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
 
@@ -19,6 +21,10 @@ type Outcome string
 const (
 	Success                  Outcome       = "success"
 	Error                    Outcome       = "error"
+	Skipped                  Outcome       = "skipped"
+	Failure                  Outcome       = "failure"
+	Nack                     Outcome       = "nack"
+	DeadLettered             Outcome       = "dead_lettered"
 	MetricRequestTimeout     time.Duration = 5 * time.Second
 	MetricRequestIdleTimeout time.Duration = 10 * time.Second
 )
@@ -28,29 +34,107 @@ func (O Outcome) String() string {
 }
 
 var (
-	once                           sync.Once
-	metricsRouter                  *chi.Mux
-	btcClientDurationHistogram     *prometheus.HistogramVec
-	queueSendErrorCounter          prometheus.Counter
-	clientRequestDurationHistogram *prometheus.HistogramVec
+	once sync.Once
+	// registry is the process wide registry every metric registers with, and
+	// the one served by the metrics server
+	registry                        = prometheus.NewRegistry()
+	btcClientDurationHistogram      *prometheus.HistogramVec
+	queueSendErrorCounter           prometheus.Counter
+	bbnBlockProcessorHaltedGauge    prometheus.Gauge
+	indexingPausedGauge             prometheus.Gauge
+	bbnProcessedHeightGapsGauge     prometheus.Gauge
+	stuckDelegationsGauge           *prometheus.GaugeVec
+	outboxPublishedCounter          *prometheus.CounterVec
+	outboxDepthGauge                prometheus.Gauge
+	outboxOldestUnsentAgeGauge      prometheus.Gauge
+	outboxPoisonEventsGauge         prometheus.Gauge
+	webhookEndpointDisabledGauge    *prometheus.GaugeVec
+	indexingLagGauge                *prometheus.GaugeVec
+	lastProcessedBbnHeightGauge     prometheus.Gauge
+	bbnChainTipHeightGauge          prometheus.Gauge
+	bbnLagBlocksGauge               prometheus.Gauge
+	bbnLastAdvancedGauge            prometheus.Gauge
+	delegationsGauge                *prometheus.GaugeVec
+	delegationsSatsGauge            *prometheus.GaugeVec
+	bbnEventDurationHistogram       *prometheus.HistogramVec
+	bbnEventsProcessedCounter       *prometheus.CounterVec
+	bbnBlockDurationHistogram       prometheus.Histogram
+	bbnBlockEventsHistogram         prometheus.Histogram
+	dbErrorsCounter                 *prometheus.CounterVec
+	dbRetriesCounter                *prometheus.CounterVec
+	dbSlowQueriesCounter            *prometheus.CounterVec
+	dbOpenTransactionsGauge         prometheus.Gauge
+	dbCacheLookupsCounter           *prometheus.CounterVec
+	dbReadBytesCounter              *prometheus.CounterVec
+	expiryBacklogGauge              *prometheus.GaugeVec
+	expiryBacklogOldestAgeGauge     *prometheus.GaugeVec
+	expiryWithdrawableCounter       *prometheus.CounterVec
+	expiryCycleDurationHistogram    prometheus.Histogram
+	stateTransitionsRejectedCounter *prometheus.CounterVec
+	btcTrackedTipHeightGauge        prometheus.Gauge
+	btcBlockNotificationsCounter    *prometheus.CounterVec
+	btcSpendWatchesGauge            prometheus.Gauge
+	btcLightClientTipHeightGauge    prometheus.Gauge
+	btcTipLeadGauge                 prometheus.Gauge
+	outboxPublishDurationHistogram  *prometheus.HistogramVec
+	outboxPublishCounter            *prometheus.CounterVec
+	pollerLastSuccessGauge          *prometheus.GaugeVec
+	pollerConsecutiveFailuresGauge  *prometheus.GaugeVec
+	pollerRunDurationHistogram      *prometheus.HistogramVec
+	pollerSkippedTicksCounter       *prometheus.CounterVec
+	pollerPanicsCounter             *prometheus.CounterVec
+	fpActiveStakeGauge              *prometheus.GaugeVec
+	fpTop3StakeShareGauge           prometheus.Gauge
+	clientRequestDurationHistogram  *prometheus.HistogramVec
+	leaderGauge                     prometheus.Gauge
+	leadershipTransitionsCounter    *prometheus.CounterVec
+	supervisedRestartsCounter       *prometheus.CounterVec
+	// logRecordsCounter is created with the package rather than by Init, as
+	// the log records are counted from the first one, logged before the
+	// metrics are registered
+	logRecordsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_log_records_total",
+			Help: "The total number of warn, error, fatal and panic log records, by level and component",
+		},
+		[]string{"level", "component"},
+	)
+	// delegationsRefreshedAt is the unix time of the last refresh of the
+	// delegation gauges, the process start time until the first one
+	delegationsRefreshedAt atomic.Int64
+	// btcTipAdvancedAt is the unix time the tracked BTC tip last advanced, the
+	// process start time until it first does
+	btcTipAdvancedAt atomic.Int64
 )
 
-// Init initializes the metrics package.
-func Init(metricsPort int) {
+// Init creates the metrics and registers them, along with the Go runtime and
+// process collectors, with the process wide registry. It must be called
+// before any metric is recorded, calling it again is a no-op.
+func Init() {
 	once.Do(func() {
-		initMetricsRouter(metricsPort)
 		registerMetrics()
 	})
 }
 
-// initMetricsRouter initializes the metrics router.
-func initMetricsRouter(metricsPort int) {
-	metricsRouter = chi.NewRouter()
-	metricsRouter.Get("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		promhttp.Handler().ServeHTTP(w, r)
-	})
+// MustRegister registers the collectors with the process wide registry. It
+// panics if a collector is already registered.
+func MustRegister(collectors ...prometheus.Collector) {
+	registry.MustRegister(collectors...)
+}
+
+// Handler serves the metrics of the process wide registry in the Prometheus
+// exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// StartServer serves the metrics at /metrics on the given address, on a
+// listener of its own separate from the api server, and returns the server to
+// shut down
+func StartServer(metricsAddr string) *http.Server {
+	metricsRouter := chi.NewRouter()
+	metricsRouter.Method(http.MethodGet, "/metrics", Handler())
 	// Create a custom server with timeout settings
-	metricsAddr := fmt.Sprintf(":%d", metricsPort)
 	server := &http.Server{
 		Addr:         metricsAddr,
 		Handler:      metricsRouter,
@@ -66,6 +150,7 @@ func initMetricsRouter(metricsPort int) {
 			log.Fatal().Err(err).Msgf("Error starting metrics server on %s", metricsAddr)
 		}
 	}()
+	return server
 }
 
 // registerMetrics initializes and register the Prometheus metrics.
@@ -99,10 +184,486 @@ func registerMetrics() {
 		},
 	)
 
-	prometheus.MustRegister(
+	// set to 1 while the BBN block processing is halted waiting for a resync
+	bbnBlockProcessorHaltedGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "bbn_block_processor_halted",
+			Help: "Whether the BBN block processing is halted due to a detected fork",
+		},
+	)
+
+	// set to 1 while the indexing is paused by an admin
+	indexingPausedGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexing_paused",
+			Help: "Whether the indexing is paused for maintenance",
+		},
+	)
+
+	// number of BBN heights found unprocessed by the last processed height audit
+	bbnProcessedHeightGapsGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "bbn_processed_height_gaps",
+			Help: "The number of BBN heights found unprocessed by the last audit",
+		},
+	)
+
+	// number of delegations stuck in a state longer than the state threshold
+	stuckDelegationsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "stuck_delegations",
+			Help: "The number of delegations stuck in a state longer than its threshold",
+		},
+		[]string{"state"},
+	)
+
+	// number of outbox events pushed to the queue, its rate being the throughput
+	outboxPublishedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_events_published_total",
+			Help: "The total number of outbox events pushed to the queue",
+		},
+		[]string{"event_type"},
+	)
+
+	outboxDepthGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_depth",
+			Help: "The number of outbox events waiting to be pushed to the queue",
+		},
+	)
+
+	outboxOldestUnsentAgeGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_oldest_unsent_age_seconds",
+			Help: "The age of the oldest outbox event waiting to be pushed to the queue",
+		},
+	)
+
+	outboxPoisonEventsGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_poison_events",
+			Help: "The number of outbox events no longer pushed after failing too many times",
+		},
+	)
+
+	webhookEndpointDisabledGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "webhook_endpoint_disabled",
+			Help: "Whether a webhook endpoint got disabled after too many failed deliveries",
+		},
+		[]string{"url"},
+	)
+
+	// number of blocks the indexing lags behind the chain tip, by chain
+	indexingLagGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexing_lag_blocks",
+			Help: "The number of blocks the indexing lags behind the chain tip",
+		},
+		[]string{"chain"},
+	)
+
+	lastProcessedBbnHeightGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_last_processed_bbn_height",
+			Help: "The last BBN height processed by the indexer",
+		},
+	)
+
+	bbnChainTipHeightGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_bbn_chain_tip_height",
+			Help: "The height of the BBN chain tip",
+		},
+	)
+
+	// number of BBN blocks between the last processed height and the chain tip
+	bbnLagBlocksGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_bbn_lag_blocks",
+			Help: "The number of blocks the BBN indexing lags behind the chain tip",
+		},
+	)
+
+	// unix time of the last BBN block processed, its age telling how long the
+	// processing has not advanced
+	bbnLastAdvancedGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_bbn_last_advanced_timestamp_seconds",
+			Help: "The unix time at which the last processed BBN height last advanced",
+		},
+	)
+
+	// number of delegations and their total staking amount, by state
+	delegationsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexer_delegations_total",
+			Help: "The number of delegations in a state",
+		},
+		[]string{"state"},
+	)
+
+	delegationsSatsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexer_delegations_sats_total",
+			Help: "The total staking amount of the delegations in a state, in sats",
+		},
+		[]string{"state"},
+	)
+
+	// computed on scrape, so that it keeps growing if the refreshes stop
+	delegationsRefreshedAt.Store(time.Now().Unix())
+	delegationsStaleGauge := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "indexer_delegations_stale_seconds",
+			Help: "The age of the last refresh of the delegation counts by state",
+		},
+		func() float64 {
+			return float64(time.Now().Unix() - delegationsRefreshedAt.Load())
+		},
+	)
+
+	// duration of the handling of a BBN event, by event type
+	bbnEventDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "indexer_bbn_event_processing_duration_seconds",
+			Help:    "Histogram of the BBN event handling durations in seconds, by event type.",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+		},
+		[]string{"event_type"},
+	)
+
+	bbnEventsProcessedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_bbn_events_processed_total",
+			Help: "The total number of BBN events processed, by event type and outcome",
+		},
+		[]string{"event_type", "outcome"},
+	)
+
+	bbnBlockDurationHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "indexer_bbn_block_processing_duration_seconds",
+			Help:    "Histogram of the BBN block processing durations in seconds.",
+			Buckets: defaultHistogramBucketsSeconds,
+		},
+	)
+
+	bbnBlockEventsHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "indexer_bbn_block_events",
+			Help:    "Histogram of the number of events of the processed BBN blocks.",
+			Buckets: []float64{0, 1, 5, 10, 50, 100, 500, 1000},
+		},
+	)
+
+	// errors returned by the database, by method and error class
+	dbErrorsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_db_errors_total",
+			Help: "The total number of errors returned by the database, by method and error class",
+		},
+		[]string{"method", "class"},
+	)
+
+	// transactions retried on a transient error, the errors absorbed rather
+	// than returned
+	dbRetriesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_db_retries_total",
+			Help: "The total number of database transactions retried on a transient error, by method",
+		},
+		[]string{"method"},
+	)
+
+	// calls to the database slower than the slow query threshold
+	dbSlowQueriesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_db_slow_queries_total",
+			Help: "The total number of database operations slower than the slow query threshold, by method",
+		},
+		[]string{"method"},
+	)
+
+	// lookups of the caches in front of the database
+	dbCacheLookupsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_db_cache_lookups_total",
+			Help: "The total number of lookups of the database caches, by cache and result (hit or miss)",
+		},
+		[]string{"cache", "result"},
+	)
+
+	dbReadBytesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_db_read_bytes_total",
+			Help: "The total size in bytes of the documents read from the database, by method",
+		},
+		[]string{"method"},
+	)
+
+	dbOpenTransactionsGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_db_open_transactions",
+			Help: "The number of database transactions in progress",
+		},
+	)
+
+	// number of expired timelocks waiting for the expiry checker, by sub state
+	expiryBacklogGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexer_expiry_backlog",
+			Help: "The number of expired timelocks waiting for the expiry checker, by sub state",
+		},
+		[]string{"sub_state"},
+	)
+
+	expiryBacklogOldestAgeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexer_expiry_backlog_oldest_age_blocks",
+			Help: "The number of BTC blocks since the oldest waiting timelock expired, by sub state",
+		},
+		[]string{"sub_state"},
+	)
+
+	expiryWithdrawableCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_expiry_withdrawable_total",
+			Help: "The total number of delegations made withdrawable by their timelock expiry, by sub state",
+		},
+		[]string{"sub_state"},
+	)
+
+	stateTransitionsRejectedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_state_transitions_rejected_total",
+			Help: "The total number of delegation state updates skipped as the delegation was found in a state not qualified for them, by current and target state",
+		},
+		[]string{"current_state", "target_state"},
+	)
+
+	expiryCycleDurationHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "indexer_expiry_cycle_duration_seconds",
+			Help:    "Histogram of the expiry checker cycle durations in seconds.",
+			Buckets: defaultHistogramBucketsSeconds,
+		},
+	)
+
+	// BTC tip height notified by the BTC notifier
+	btcTrackedTipHeightGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_btc_tracked_tip_height",
+			Help: "The height of the BTC tip notified by the BTC notifier",
+		},
+	)
+
+	btcTipAdvancedAt.Store(time.Now().Unix())
+	btcTipAgeGauge := prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "indexer_btc_tip_last_advanced_seconds",
+			Help: "The time since the tracked BTC tip last advanced",
+		},
+		func() float64 {
+			return float64(time.Now().Unix() - btcTipAdvancedAt.Load())
+		},
+	)
+
+	btcBlockNotificationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_btc_block_notifications_total",
+			Help: "The total number of BTC block notifications received, by source",
+		},
+		[]string{"source"},
+	)
+
+	btcSpendWatchesGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_btc_outstanding_spend_watches",
+			Help: "The number of BTC spend notifications registered and not yet received",
+		},
+	)
+
+	btcLightClientTipHeightGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_bbn_btc_light_client_tip_height",
+			Help: "The height of the BTC tip known to the btclightclient module of the BBN chain",
+		},
+	)
+
+	// positive when the BBN light client lags behind the tracked BTC tip,
+	// negative when the BTC notifier does
+	btcTipLeadGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_btc_tip_lead_over_light_client_blocks",
+			Help: "The number of blocks the tracked BTC tip is ahead of the BBN btclightclient tip",
+		},
+	)
+
+	// duration of the push of an outbox event, by emitter type
+	outboxPublishDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "indexer_outbox_publish_duration_seconds",
+			Help:    "Histogram of the outbox event push durations in seconds, by emitter type.",
+			Buckets: defaultHistogramBucketsSeconds,
+		},
+		[]string{"emitter"},
+	)
+
+	// pushes of outbox events by emitter type and outcome: success, failure,
+	// or nack when the broker refused the event
+	outboxPublishCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_outbox_publish_total",
+			Help: "The total number of outbox event pushes, by emitter type and outcome",
+		},
+		[]string{"emitter", "outcome"},
+	)
+
+	// the poller metrics are labeled by the name the poller is created with,
+	// every poller created through the service is monitored
+	pollerLastSuccessGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexer_poller_last_success_timestamp_seconds",
+			Help: "The unix time of the last successful run of the poller, its start time until the first one",
+		},
+		[]string{"poller"},
+	)
+
+	pollerConsecutiveFailuresGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexer_poller_consecutive_failures",
+			Help: "The number of runs of the poller that failed since its last successful one",
+		},
+		[]string{"poller"},
+	)
+
+	pollerRunDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "indexer_poller_run_duration_seconds",
+			Help:    "Histogram of the poller run durations in seconds.",
+			Buckets: defaultHistogramBucketsSeconds,
+		},
+		[]string{"poller"},
+	)
+
+	// the ticker of a poller drops the ticks falling while a run overruns
+	// its interval
+	pollerSkippedTicksCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_poller_skipped_ticks_total",
+			Help: "The total number of poller ticks dropped while a run overran its interval",
+		},
+		[]string{"poller"},
+	)
+
+	pollerPanicsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_poller_panics_total",
+			Help: "The total number of panics recovered from the poller runs",
+		},
+		[]string{"poller"},
+	)
+
+	// only the top finality providers by active stake are exported, so that
+	// the label cardinality stays bounded
+	fpActiveStakeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "indexer_fp_active_stake_sats",
+			Help: "The active stake of the finality providers holding the most of it, by btc pk",
+		},
+		[]string{"fp_btc_pk"},
+	)
+
+	fpTop3StakeShareGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_fp_top3_stake_share",
+			Help: "The share of the active stake of all the finality providers held by the top 3",
+		},
+	)
+
+	// only the leader replica processes the BBN blocks and relays the outbox
+	leaderGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "indexer_leader",
+			Help: "Whether the indexer instance holds the leader lease",
+		},
+	)
+
+	leadershipTransitionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_leadership_transitions_total",
+			Help: "The total number of leadership transitions of the indexer instance, by transition",
+		},
+		[]string{"transition"},
+	)
+
+	supervisedRestartsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "indexer_supervised_restarts_total",
+			Help: "The total number of restarts of the supervised background components, by component",
+		},
+		[]string{"component"},
+	)
+
+	MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		btcClientDurationHistogram,
 		queueSendErrorCounter,
+		bbnBlockProcessorHaltedGauge,
+		indexingPausedGauge,
+		bbnProcessedHeightGapsGauge,
+		stuckDelegationsGauge,
+		outboxPublishedCounter,
+		outboxDepthGauge,
+		outboxOldestUnsentAgeGauge,
+		outboxPoisonEventsGauge,
+		webhookEndpointDisabledGauge,
+		indexingLagGauge,
+		lastProcessedBbnHeightGauge,
+		bbnChainTipHeightGauge,
+		bbnLagBlocksGauge,
+		bbnLastAdvancedGauge,
+		delegationsGauge,
+		delegationsSatsGauge,
+		delegationsStaleGauge,
+		bbnEventDurationHistogram,
+		bbnEventsProcessedCounter,
+		bbnBlockDurationHistogram,
+		bbnBlockEventsHistogram,
+		dbErrorsCounter,
+		dbRetriesCounter,
+		dbSlowQueriesCounter,
+		dbOpenTransactionsGauge,
+		dbCacheLookupsCounter,
+		dbReadBytesCounter,
+		expiryBacklogGauge,
+		expiryBacklogOldestAgeGauge,
+		expiryWithdrawableCounter,
+		expiryCycleDurationHistogram,
+		stateTransitionsRejectedCounter,
+		btcTrackedTipHeightGauge,
+		btcTipAgeGauge,
+		btcBlockNotificationsCounter,
+		btcSpendWatchesGauge,
+		btcLightClientTipHeightGauge,
+		btcTipLeadGauge,
+		outboxPublishDurationHistogram,
+		outboxPublishCounter,
+		pollerLastSuccessGauge,
+		pollerConsecutiveFailuresGauge,
+		pollerRunDurationHistogram,
+		pollerSkippedTicksCounter,
+		pollerPanicsCounter,
+		fpActiveStakeGauge,
+		fpTop3StakeShareGauge,
 		clientRequestDurationHistogram,
+		leaderGauge,
+		leadershipTransitionsCounter,
+		supervisedRestartsCounter,
+		logRecordsCounter,
 	)
 }
 
@@ -146,3 +707,213 @@ func StartClientRequestDurationTimer(baseUrl, method, path string) func(statusCo
 func RecordQueueSendError() {
 	queueSendErrorCounter.Inc()
 }
+
+func RecordBbnBlockProcessorHalted() {
+	bbnBlockProcessorHaltedGauge.Set(1)
+}
+
+func RecordIndexingPaused(paused bool) {
+	if paused {
+		indexingPausedGauge.Set(1)
+	} else {
+		indexingPausedGauge.Set(0)
+	}
+}
+
+func RecordBbnProcessedHeightGaps(count uint64) {
+	bbnProcessedHeightGapsGauge.Set(float64(count))
+}
+
+func RecordStuckDelegations(state string, count uint64) {
+	stuckDelegationsGauge.WithLabelValues(state).Set(float64(count))
+}
+
+func RecordOutboxEventsPublished(eventType string, count uint64) {
+	outboxPublishedCounter.WithLabelValues(eventType).Add(float64(count))
+}
+
+func RecordOutboxStats(depth uint64, oldestUnsentAge time.Duration, poison uint64) {
+	outboxDepthGauge.Set(float64(depth))
+	outboxOldestUnsentAgeGauge.Set(oldestUnsentAge.Seconds())
+	outboxPoisonEventsGauge.Set(float64(poison))
+}
+
+func RecordWebhookEndpointDisabled(url string) {
+	webhookEndpointDisabledGauge.WithLabelValues(url).Set(1)
+}
+
+func RecordIndexingLag(chain string, lag uint64) {
+	indexingLagGauge.WithLabelValues(chain).Set(float64(lag))
+}
+
+func RecordBbnHeights(lastProcessedHeight, tipHeight, lag uint64) {
+	lastProcessedBbnHeightGauge.Set(float64(lastProcessedHeight))
+	bbnChainTipHeightGauge.Set(float64(tipHeight))
+	bbnLagBlocksGauge.Set(float64(lag))
+}
+
+func RecordBbnHeightAdvanced(at time.Time) {
+	bbnLastAdvancedGauge.Set(float64(at.Unix()))
+}
+
+// RecordDelegationsByState sets the delegation count and staking amount of
+// every state, and marks the delegation gauges refreshed
+func RecordDelegationsByState(counts, stakingAmounts map[string]uint64, refreshedAt time.Time) {
+	for state, count := range counts {
+		delegationsGauge.WithLabelValues(state).Set(float64(count))
+	}
+	for state, amount := range stakingAmounts {
+		delegationsSatsGauge.WithLabelValues(state).Set(float64(amount))
+	}
+	delegationsRefreshedAt.Store(refreshedAt.Unix())
+}
+
+// RecordBbnEventProcessed counts a processed BBN event, and observes its
+// handling duration unless skipped
+func RecordBbnEventProcessed(eventType string, outcome Outcome, duration time.Duration) {
+	bbnEventsProcessedCounter.WithLabelValues(eventType, outcome.String()).Inc()
+	if outcome != Skipped {
+		bbnEventDurationHistogram.WithLabelValues(eventType).Observe(duration.Seconds())
+	}
+}
+
+func RecordBbnBlockProcessed(duration time.Duration, eventCount int) {
+	bbnBlockDurationHistogram.Observe(duration.Seconds())
+	bbnBlockEventsHistogram.Observe(float64(eventCount))
+}
+
+func RecordDbError(method, class string) {
+	dbErrorsCounter.WithLabelValues(method, class).Inc()
+}
+
+func RecordDbRetry(method string) {
+	dbRetriesCounter.WithLabelValues(method).Inc()
+}
+
+func RecordDbSlowQuery(method string) {
+	dbSlowQueriesCounter.WithLabelValues(method).Inc()
+}
+
+func RecordDbCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	dbCacheLookupsCounter.WithLabelValues(cache, result).Inc()
+}
+
+// RecordDbReadBytes counts the size of the documents read by the method
+func RecordDbReadBytes(method string, bytes int) {
+	dbReadBytesCounter.WithLabelValues(method).Add(float64(bytes))
+}
+
+func RecordDbTransactionStarted() {
+	dbOpenTransactionsGauge.Inc()
+}
+
+func RecordDbTransactionEnded() {
+	dbOpenTransactionsGauge.Dec()
+}
+
+func RecordExpiryBacklog(subState string, count uint64, oldestAgeBlocks uint64) {
+	expiryBacklogGauge.WithLabelValues(subState).Set(float64(count))
+	expiryBacklogOldestAgeGauge.WithLabelValues(subState).Set(float64(oldestAgeBlocks))
+}
+
+func RecordExpiryWithdrawable(subState string) {
+	expiryWithdrawableCounter.WithLabelValues(subState).Inc()
+}
+
+func RecordStateTransitionRejected(currentState string, targetState string) {
+	stateTransitionsRejectedCounter.WithLabelValues(currentState, targetState).Inc()
+}
+
+func RecordExpiryCycle(duration time.Duration) {
+	expiryCycleDurationHistogram.Observe(duration.Seconds())
+}
+
+// RecordBtcBlockNotification counts a BTC block notification, and records the
+// tip advancing if the block is above the tracked tip
+func RecordBtcBlockNotification(source string, height uint64, advanced bool) {
+	btcBlockNotificationsCounter.WithLabelValues(source).Inc()
+	if advanced {
+		btcTrackedTipHeightGauge.Set(float64(height))
+		btcTipAdvancedAt.Store(time.Now().Unix())
+	}
+}
+
+func RecordBtcSpendWatchStarted() {
+	btcSpendWatchesGauge.Inc()
+}
+
+func RecordBtcSpendWatchEnded() {
+	btcSpendWatchesGauge.Dec()
+}
+
+func RecordBtcLightClientTip(lightClientTipHeight uint64, trackedTipLead int64) {
+	btcLightClientTipHeightGauge.Set(float64(lightClientTipHeight))
+	btcTipLeadGauge.Set(float64(trackedTipLead))
+}
+
+func RecordOutboxPublish(emitter string, outcome Outcome, duration time.Duration) {
+	outboxPublishCounter.WithLabelValues(emitter, outcome.String()).Inc()
+	outboxPublishDurationHistogram.WithLabelValues(emitter).Observe(duration.Seconds())
+}
+
+// RecordPollerStarted initializes the metrics of a poller, whose last success
+// is its start time until its first successful run
+func RecordPollerStarted(poller string, at time.Time) {
+	pollerLastSuccessGauge.WithLabelValues(poller).Set(float64(at.Unix()))
+	pollerConsecutiveFailuresGauge.WithLabelValues(poller).Set(0)
+	pollerSkippedTicksCounter.WithLabelValues(poller).Add(0)
+	pollerPanicsCounter.WithLabelValues(poller).Add(0)
+}
+
+func RecordPollerRun(
+	poller string, duration time.Duration, lastSuccessAt time.Time, consecutiveFailures int, skippedTicks int,
+) {
+	pollerRunDurationHistogram.WithLabelValues(poller).Observe(duration.Seconds())
+	pollerLastSuccessGauge.WithLabelValues(poller).Set(float64(lastSuccessAt.Unix()))
+	pollerConsecutiveFailuresGauge.WithLabelValues(poller).Set(float64(consecutiveFailures))
+	if skippedTicks > 0 {
+		pollerSkippedTicksCounter.WithLabelValues(poller).Add(float64(skippedTicks))
+	}
+}
+
+func RecordPollerPanic(poller string) {
+	pollerPanicsCounter.WithLabelValues(poller).Inc()
+}
+
+// RecordFpStakeDistribution replaces the exported top finality providers by
+// active stake, so that the ones leaving the top do not keep their former
+// stake
+func RecordFpStakeDistribution(activeStakes map[string]uint64, top3Share float64) {
+	fpActiveStakeGauge.Reset()
+	for btcPk, amount := range activeStakes {
+		fpActiveStakeGauge.WithLabelValues(btcPk).Set(float64(amount))
+	}
+	fpTop3StakeShareGauge.Set(top3Share)
+}
+
+// RecordLeadershipTransition records the instance acquiring, losing or
+// releasing the leader lease
+func RecordLeadershipTransition(transition string, leader bool) {
+	leadershipTransitionsCounter.WithLabelValues(transition).Inc()
+	if leader {
+		leaderGauge.Set(1)
+	} else {
+		leaderGauge.Set(0)
+	}
+}
+
+// RecordSupervisedRestart records the restart of a failed supervised
+// component
+func RecordSupervisedRestart(component string) {
+	supervisedRestartsCounter.WithLabelValues(component).Inc()
+}
+
+// RecordLogRecord counts a log record of the level and component. It is called
+// for every record logged, so it must neither allocate nor log.
+func RecordLogRecord(level string, component string) {
+	logRecordsCounter.WithLabelValues(level, component).Inc()
+}
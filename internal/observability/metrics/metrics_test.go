@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordOutboxStats(t *testing.T) {
+	Init()
+
+	RecordOutboxStats(3, 90*time.Second, 1)
+
+	require.Equal(t, float64(3), testutil.ToFloat64(outboxDepthGauge))
+	require.Equal(t, float64(90), testutil.ToFloat64(outboxOldestUnsentAgeGauge))
+	require.Equal(t, float64(1), testutil.ToFloat64(outboxPoisonEventsGauge))
+}
+
+func TestRecordBbnHeights(t *testing.T) {
+	Init()
+
+	RecordBbnHeights(90, 100, 10)
+	RecordBbnHeightAdvanced(time.Unix(1700000000, 0))
+
+	require.Equal(t, float64(90), testutil.ToFloat64(lastProcessedBbnHeightGauge))
+	require.Equal(t, float64(100), testutil.ToFloat64(bbnChainTipHeightGauge))
+	require.Equal(t, float64(10), testutil.ToFloat64(bbnLagBlocksGauge))
+	require.Equal(t, float64(1700000000), testutil.ToFloat64(bbnLastAdvancedGauge))
+}
+
+func TestRecordDelegationsByState(t *testing.T) {
+	Init()
+
+	RecordDelegationsByState(
+		map[string]uint64{"active": 2}, map[string]uint64{"active": 3000}, time.Now().Add(-time.Minute),
+	)
+
+	require.Equal(t, float64(2), testutil.ToFloat64(delegationsGauge.WithLabelValues("active")))
+	require.Equal(t, float64(3000), testutil.ToFloat64(delegationsSatsGauge.WithLabelValues("active")))
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(), "indexer_delegations_stale_seconds 60")
+}
+
+func TestRecordBbnEventProcessed(t *testing.T) {
+	Init()
+
+	RecordBbnEventProcessed("created", Success, 20*time.Millisecond)
+	RecordBbnEventProcessed("created", Skipped, 0)
+	RecordBbnBlockProcessed(time.Second, 2)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(bbnEventsProcessedCounter.WithLabelValues("created", "success")))
+	require.Equal(t, float64(1), testutil.ToFloat64(bbnEventsProcessedCounter.WithLabelValues("created", "skipped")))
+	// the skipped event is not observed
+	require.Equal(t, 1, testutil.CollectAndCount(bbnEventDurationHistogram))
+	require.Equal(t, 1, testutil.CollectAndCount(bbnBlockEventsHistogram))
+}
+
+func TestHandler(t *testing.T) {
+	Init()
+	RecordIndexingLag("bbn", 7)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `indexing_lag_blocks{chain="bbn"} 7`)
+	// runtime and process collectors are served along the indexer metrics
+	require.Contains(t, string(body), "go_goroutines")
+	require.Contains(t, string(body), "process_start_time_seconds")
+}
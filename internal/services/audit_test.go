@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// captureAuditRecords sends the audit records to a buffer for the duration of
+// the test and returns the function decoding those written so far
+func captureAuditRecords(t *testing.T) func() []map[string]any {
+	var records bytes.Buffer
+	audit.SetWriter(&records)
+	t.Cleanup(func() { audit.SetWriter(&bytes.Buffer{}) })
+
+	return func() []map[string]any {
+		var decoded []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(records.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			delete(record, "time")
+			decoded = append(decoded, record)
+		}
+		return decoded
+	}
+}
+
+func TestAuditAdminOverride(t *testing.T) {
+	records := captureAuditRecords(t)
+	dbMock := mocks.NewDbInterface(t)
+
+	subState := types.SubStateTimelock
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testReprocessTxHash).Return(
+		&model.BTCDelegationDetails{StakingTxHashHex: testReprocessTxHash, State: types.StateWithdrawn}, nil,
+	)
+	dbMock.On(
+		"OverrideBTCDelegationState", mock.Anything, testReprocessTxHash,
+		types.StateWithdrawn, types.StateWithdrawable, &subState,
+	).Return(nil).Once()
+	dbMock.On("SaveDelegationStateTransition", mock.Anything, mock.Anything).Return(nil).Once()
+	dbMock.On("GetTimeLocks", mock.Anything, testReprocessTxHash).Return([]model.TimeLockDocument{
+		{StakingTxHashHex: testReprocessTxHash, ExpireHeight: 210, DelegationSubState: subState},
+	}, nil)
+	dbMock.On("SaveOutboxEvent", mock.Anything, mock.Anything).Return(nil).Once()
+
+	service := NewService(&config.Config{}, db.NewAuditDatabase(dbMock), nil, nil, nil, nil)
+	require.Nil(t, service.SetDelegationState(context.Background(), SetStateRequest{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateWithdrawable,
+		SubState:         subState,
+		Reason:           "spend reported on a reorged block",
+		Operator:         "alice",
+	}))
+
+	// the state transition and the outbox event are bookkeeping, not audited
+	require.Equal(t, []map[string]any{{
+		"log":             "audit",
+		"method":          "OverrideBTCDelegationState",
+		"trigger":         audit.TriggerAdmin,
+		"operator":        "alice",
+		"outcome":         "success",
+		"staking_tx_hash": testReprocessTxHash,
+		"previous_state":  types.StateWithdrawn.String(),
+		"state":           types.StateWithdrawable.String(),
+		"sub_state":       subState.String(),
+	}}, records())
+}
+
+func TestAuditBbnEvent(t *testing.T) {
+	metrics.Init()
+	records := captureAuditRecords(t)
+	env := newMarkerTestEnv(t)
+	env.service.db = db.NewAuditDatabase(env.service.db)
+
+	require.NoError(t, env.processBlock(context.Background()))
+
+	audited := records()
+	require.Len(t, audited, testMarkerEventsLen)
+	for i, record := range audited {
+		require.Equal(t, map[string]any{
+			"log":        "audit",
+			"method":     "UpdateFinalityProviderDetailsFromEvent",
+			"trigger":    audit.TriggerBbnEvent,
+			"event_type": "babylon.btcstaking.v1.EventFinalityProviderEdited",
+			"bbn_height": float64(testMarkerHeight),
+			"outcome":    "success",
+			"fp_btc_pk":  fpBtcPkForEvent(i),
+		}, record)
+	}
+}
+
+func TestAuditExpiry(t *testing.T) {
+	metrics.Init()
+	records := captureAuditRecords(t)
+	dbMock := mocks.NewDbInterface(t)
+
+	tlDoc := model.TimeLockDocument{
+		StakingTxHashHex:   testReprocessTxHash,
+		ExpireHeight:       110,
+		DelegationSubState: types.SubStateTimelock,
+	}
+	dbMock.On("GetBTCDelegationSummaryByStakingTxHash", mock.Anything, testReprocessTxHash).Return(
+		&model.BTCDelegationSummary{StakingTxHashHex: testReprocessTxHash, State: types.StateUnbonding}, nil,
+	)
+	dbMock.On("ApplyBTCDelegationStateChange", mock.Anything, mock.MatchedBy(func(change db.DelegationStateChange) bool {
+		return change.StakingTxHash == testReprocessTxHash && change.NewState == types.StateWithdrawable &&
+			change.BTCDerivedChange != nil && change.Transition != nil && change.OutboxEvent != nil
+	})).Return(nil).Once()
+	dbMock.On("DeleteExpiredDelegation", mock.Anything, testReprocessTxHash).Return(nil).Once()
+
+	service := NewService(&config.Config{}, db.NewAuditDatabase(dbMock), nil, nil, nil, nil)
+	require.Nil(t, service.expireTimeLock(context.Background(), tlDoc, 112))
+
+	trigger := map[string]any{
+		"log":        "audit",
+		"trigger":    audit.TriggerExpiry,
+		"btc_height": float64(112),
+		"outcome":    "success",
+	}
+	withTrigger := func(fields map[string]any) map[string]any {
+		for key, value := range trigger {
+			fields[key] = value
+		}
+		return fields
+	}
+	require.Equal(t, []map[string]any{
+		withTrigger(map[string]any{
+			"method":          "ApplyBTCDelegationStateChange",
+			"staking_tx_hash": testReprocessTxHash,
+			"state":           types.StateWithdrawable.String(),
+			"sub_state":       types.SubStateTimelock.String(),
+		}),
+		withTrigger(map[string]any{
+			"method":          "DeleteExpiredDelegation",
+			"staking_tx_hash": testReprocessTxHash,
+		}),
+	}, records())
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// BackfillRequest selects the BBN height range processed again by a backfill
+type BackfillRequest struct {
+	FromHeight uint64
+	ToHeight   uint64
+	// DryRun counts the documents the backfill would write without writing
+	// them
+	DryRun bool
+	// Concurrency is the number of blocks fetched in parallel, their events
+	// being applied in height order
+	Concurrency int
+	// CheckpointFile records the progress of the backfill so that an
+	// interrupted one resumes where it stopped, unused in a dry run
+	CheckpointFile string
+}
+
+// BackfillSummary reports the documents written by a backfill, by collection
+type BackfillSummary struct {
+	ProcessedHeights uint64
+	Writes           map[string]*BackfillWrites
+}
+
+// backfillCheckpoint is the progress of a backfill saved in its checkpoint
+// file
+type backfillCheckpoint struct {
+	FromHeight uint64                     `json:"from_height"`
+	ToHeight   uint64                     `json:"to_height"`
+	NextHeight uint64                     `json:"next_height"`
+	Writes     map[string]*BackfillWrites `json:"writes"`
+}
+
+// fetchedBbnBlock holds the events of a block fetched by a backfill
+type fetchedBbnBlock struct {
+	events []BbnEvent
+	err    *types.Error
+}
+
+// Backfill processes again the BBN blocks of the height range through the
+// normal pipeline, to repair the heights the processed height audit reports
+// missing. The event handlers ignore the events already applied, so the range
+// may overlap the indexed data. The last processed height is left untouched,
+// so that the backfill can run alongside the indexer, and the BTC spends are
+// not watched.
+func (s *Service) Backfill(ctx context.Context, req BackfillRequest) (*BackfillSummary, *types.Error) {
+	if req.FromHeight == 0 || req.FromHeight > req.ToHeight {
+		return nil, types.NewValidationFailedError(
+			fmt.Errorf("invalid BBN height range %d-%d", req.FromHeight, req.ToHeight),
+		)
+	}
+	if req.Concurrency <= 0 {
+		return nil, types.NewValidationFailedError(errors.New("concurrency must be positive"))
+	}
+
+	checkpoint := &backfillCheckpoint{
+		FromHeight: req.FromHeight,
+		ToHeight:   req.ToHeight,
+		NextHeight: req.FromHeight,
+		Writes:     make(map[string]*BackfillWrites),
+	}
+	if !req.DryRun && req.CheckpointFile != "" {
+		var err error
+		checkpoint, err = loadBackfillCheckpoint(req.CheckpointFile, checkpoint)
+		if err != nil {
+			return nil, types.NewInternalServiceError(err)
+		}
+		if checkpoint.NextHeight > req.FromHeight {
+			logging.BlockProcessor.FromContext(ctx).Info().
+				Uint64("next_height", checkpoint.NextHeight).
+				Str("checkpoint_file", req.CheckpointFile).
+				Msg("resuming backfill")
+		}
+	}
+
+	// The backfill writes through its own service so that its writes are
+	// counted
+	backfillDb := newBackfillDb(s.db, req.DryRun, checkpoint.Writes)
+	backfill := NewService(s.cfg, backfillDb, s.btc, nil, s.bbn, s.queueManager)
+	ctx = withBbnBlockMode(ctx, bbnBlockBackfill)
+
+	for checkpoint.NextHeight <= req.ToHeight {
+		batchEnd := min(checkpoint.NextHeight+uint64(req.Concurrency)-1, req.ToHeight)
+		blocks := backfill.fetchBbnBlocks(ctx, checkpoint.NextHeight, batchEnd)
+
+		for i, block := range blocks {
+			height := checkpoint.NextHeight + uint64(i)
+			if block.err != nil {
+				return nil, block.err
+			}
+			blockCtx, span := startBbnBlockSpan(logging.WithBbnHeight(ctx, height), height)
+			err := backfill.applyBbnBlockEvents(blockCtx, height, block.events, nil)
+			endSpan(span, err)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		checkpoint.NextHeight = batchEnd + 1
+		if !req.DryRun && req.CheckpointFile != "" {
+			if err := saveBackfillCheckpoint(req.CheckpointFile, checkpoint); err != nil {
+				return nil, types.NewInternalServiceError(err)
+			}
+		}
+		logging.BlockProcessor.FromContext(ctx).Info().
+			Uint64("height", batchEnd).
+			Uint64("to_height", req.ToHeight).
+			Bool("dry_run", req.DryRun).
+			Msg("backfilled BBN heights")
+	}
+
+	return &BackfillSummary{
+		ProcessedHeights: req.ToHeight - req.FromHeight + 1,
+		Writes:           checkpoint.Writes,
+	}, nil
+}
+
+// fetchBbnBlocks fetches the events of the blocks of the height range in
+// parallel, returned in height order
+func (s *Service) fetchBbnBlocks(ctx context.Context, fromHeight, toHeight uint64) []fetchedBbnBlock {
+	blocks := make([]fetchedBbnBlock, toHeight-fromHeight+1)
+	var wg sync.WaitGroup
+	for i := range blocks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			blocks[i].events, blocks[i].err = s.getEventsFromBlock(ctx, int64(fromHeight)+int64(i))
+		}()
+	}
+	wg.Wait()
+	return blocks
+}
+
+// loadBackfillCheckpoint returns the checkpoint saved in the file, or the
+// initial one if there is none. A checkpoint of another height range is
+// refused, so that a stale file does not skip heights.
+func loadBackfillCheckpoint(path string, initial *backfillCheckpoint) (*backfillCheckpoint, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return initial, nil
+		}
+		return nil, fmt.Errorf("failed to read backfill checkpoint: %w", err)
+	}
+
+	var checkpoint backfillCheckpoint
+	if err := json.Unmarshal(content, &checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid backfill checkpoint %s: %w", path, err)
+	}
+	if checkpoint.FromHeight != initial.FromHeight || checkpoint.ToHeight != initial.ToHeight {
+		return nil, fmt.Errorf(
+			"backfill checkpoint %s is for heights %d-%d, remove it to backfill %d-%d",
+			path, checkpoint.FromHeight, checkpoint.ToHeight, initial.FromHeight, initial.ToHeight,
+		)
+	}
+	if checkpoint.Writes == nil {
+		checkpoint.Writes = make(map[string]*BackfillWrites)
+	}
+	return &checkpoint, nil
+}
+
+// saveBackfillCheckpoint replaces the checkpoint file, through a rename so that
+// a crash does not leave it truncated
+func saveBackfillCheckpoint(path string, checkpoint *backfillCheckpoint) error {
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to encode backfill checkpoint: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o600); err != nil {
+		return fmt.Errorf("failed to write backfill checkpoint: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace backfill checkpoint: %w", err)
+	}
+	return nil
+}
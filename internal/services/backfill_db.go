@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// BackfillWrites counts the documents of a collection written by a backfill
+type BackfillWrites struct {
+	Created uint64 `json:"created"`
+	Updated uint64 `json:"updated"`
+}
+
+// backfillDb counts the documents written by the BBN event processing of a
+// backfill, by collection. In a dry run the writes are counted but not
+// applied, the delegations written being kept in memory so that the later
+// events of the range see them.
+type backfillDb struct {
+	db.DbInterface
+	dryRun bool
+
+	mu     sync.Mutex
+	writes map[string]*BackfillWrites
+	// delegations holds the delegations written in a dry run
+	delegations map[string]*model.BTCDelegationDetails
+}
+
+func newBackfillDb(dbClient db.DbInterface, dryRun bool, writes map[string]*BackfillWrites) *backfillDb {
+	return &backfillDb{
+		DbInterface: dbClient,
+		dryRun:      dryRun,
+		writes:      writes,
+		delegations: make(map[string]*model.BTCDelegationDetails),
+	}
+}
+
+// record counts a successful write of a document of the collection
+func (b *backfillDb) record(collection string, created bool, err error) error {
+	if err != nil {
+		return err
+	}
+	b.count(collection, created, 1)
+	return nil
+}
+
+// count counts the documents of the collection written
+func (b *backfillDb) count(collection string, created bool, documents uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	writes, ok := b.writes[collection]
+	if !ok {
+		writes = &BackfillWrites{}
+		b.writes[collection] = writes
+	}
+	if created {
+		writes.Created += documents
+	} else {
+		writes.Updated += documents
+	}
+}
+
+// apply runs the write unless in a dry run
+func (b *backfillDb) apply(write func() error) error {
+	if b.dryRun {
+		return nil
+	}
+	return write()
+}
+
+// RunInTransaction runs fn right away in a dry run, as none of its writes is
+// applied
+func (b *backfillDb) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if b.dryRun {
+		return fn(ctx)
+	}
+	return b.DbInterface.RunInTransaction(ctx, fn)
+}
+
+func (b *backfillDb) GetBTCDelegationByStakingTxHash(
+	ctx context.Context, stakingTxHash string,
+) (*model.BTCDelegationDetails, error) {
+	b.mu.Lock()
+	delegation, ok := b.delegations[stakingTxHash]
+	b.mu.Unlock()
+	if ok {
+		copied := *delegation
+		return &copied, nil
+	}
+	return b.DbInterface.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+}
+
+func (b *backfillDb) GetBTCDelegationState(
+	ctx context.Context, stakingTxHash string,
+) (*types.DelegationState, error) {
+	delegation, err := b.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	if err != nil {
+		return nil, err
+	}
+	return &delegation.State, nil
+}
+
+// updateDryRunDelegation applies the update to the in memory copy of the
+// delegation, failing like the database if it does not exist
+func (b *backfillDb) updateDryRunDelegation(
+	ctx context.Context, stakingTxHash string, update func(delegation *model.BTCDelegationDetails) error,
+) error {
+	delegation, err := b.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash)
+	if err != nil {
+		return err
+	}
+	if err := update(delegation); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.delegations[stakingTxHash] = delegation
+	return nil
+}
+
+func (b *backfillDb) SaveNewBTCDelegation(
+	ctx context.Context, delegationDoc *model.BTCDelegationDetails,
+) error {
+	if !b.dryRun {
+		return b.record(
+			model.BTCDelegationDetailsCollection, true,
+			b.DbInterface.SaveNewBTCDelegation(ctx, delegationDoc),
+		)
+	}
+
+	_, err := b.GetBTCDelegationByStakingTxHash(ctx, delegationDoc.StakingTxHashHex)
+	if err == nil {
+		return &db.DuplicateKeyError{
+			Key:     delegationDoc.StakingTxHashHex,
+			Message: "BTC delegation already exists",
+		}
+	}
+	if !db.IsNotFoundError(err) {
+		return err
+	}
+
+	b.mu.Lock()
+	copied := *delegationDoc
+	b.delegations[delegationDoc.StakingTxHashHex] = &copied
+	b.mu.Unlock()
+	return b.record(model.BTCDelegationDetailsCollection, true, nil)
+}
+
+func (b *backfillDb) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	if !b.dryRun {
+		return b.record(
+			model.BTCDelegationDetailsCollection, false,
+			b.DbInterface.UpdateBTCDelegationState(
+				ctx, stakingTxHash, qualifiedPreviousStates, newState, newSubState,
+			),
+		)
+	}
+
+	return b.record(model.BTCDelegationDetailsCollection, false, b.updateDryRunDelegation(
+		ctx, stakingTxHash, func(delegation *model.BTCDelegationDetails) error {
+			if !delegation.State.CanTransitionTo(newState) ||
+				!utils.Contains(qualifiedPreviousStates, delegation.State) {
+				return &db.StateTransitionError{
+					StakingTxHash: stakingTxHash, CurrentState: delegation.State, TargetState: newState,
+				}
+			}
+			delegation.State = newState
+			if newSubState != nil {
+				delegation.SubState = *newSubState
+			}
+			return nil
+		},
+	))
+}
+
+func (b *backfillDb) ApplyBTCDelegationStateChange(
+	ctx context.Context, change db.DelegationStateChange,
+) error {
+	if !b.dryRun {
+		return b.record(
+			model.BTCDelegationDetailsCollection, false,
+			b.DbInterface.ApplyBTCDelegationStateChange(ctx, change),
+		)
+	}
+	return b.UpdateBTCDelegationState(
+		ctx, change.StakingTxHash, change.QualifiedPreviousStates, change.NewState, change.NewSubState,
+	)
+}
+
+func (b *backfillDb) UpdateBTCDelegationDetails(
+	ctx context.Context, stakingTxHash string, details *model.BTCDelegationDetails,
+) error {
+	if !b.dryRun {
+		return b.record(
+			model.BTCDelegationDetailsCollection, false,
+			b.DbInterface.UpdateBTCDelegationDetails(ctx, stakingTxHash, details),
+		)
+	}
+
+	return b.record(model.BTCDelegationDetailsCollection, false, b.updateDryRunDelegation(
+		ctx, stakingTxHash, func(delegation *model.BTCDelegationDetails) error {
+			if details.State != "" {
+				delegation.State = details.State
+			}
+			if details.StartHeight != 0 {
+				delegation.StartHeight = details.StartHeight
+			}
+			if details.EndHeight != 0 {
+				delegation.EndHeight = details.EndHeight
+			}
+			return nil
+		},
+	))
+}
+
+func (b *backfillDb) SaveBTCDelegationUnbondingCovenantSignature(
+	ctx context.Context, stakingTxHash string, covenantBtcPkHex string, signatureHex string,
+) error {
+	if !b.dryRun {
+		return b.record(
+			model.BTCDelegationDetailsCollection, false,
+			b.DbInterface.SaveBTCDelegationUnbondingCovenantSignature(
+				ctx, stakingTxHash, covenantBtcPkHex, signatureHex,
+			),
+		)
+	}
+
+	return b.record(model.BTCDelegationDetailsCollection, false, b.updateDryRunDelegation(
+		ctx, stakingTxHash, func(delegation *model.BTCDelegationDetails) error {
+			delegation.CovenantUnbondingSignatures = append(
+				delegation.CovenantUnbondingSignatures,
+				model.CovenantSignature{CovenantBtcPkHex: covenantBtcPkHex, SignatureHex: signatureHex},
+			)
+			return nil
+		},
+	))
+}
+
+func (b *backfillDb) BulkSaveCovenantSignatures(
+	ctx context.Context, sigs []db.CovenantSigRecord,
+) error {
+	if !b.dryRun {
+		err := b.DbInterface.BulkSaveCovenantSignatures(ctx, sigs)
+		var bulkErr *db.BulkWriteError
+		if err != nil && !errors.As(err, &bulkErr) {
+			return err
+		}
+		// The items not failed are applied
+		saved := len(sigs)
+		if bulkErr != nil {
+			saved -= len(bulkErr.Failures)
+		}
+		b.count(model.BTCDelegationDetailsCollection, false, uint64(saved))
+		return err
+	}
+
+	var failures []db.BulkWriteFailure
+	for i, sig := range sigs {
+		err := b.SaveBTCDelegationUnbondingCovenantSignature(
+			ctx, sig.StakingTxHash, sig.CovenantBtcPkHex, sig.SignatureHex,
+		)
+		if err != nil {
+			failures = append(failures, db.BulkWriteFailure{Index: i, Key: sig.StakingTxHash, Err: err})
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &db.BulkWriteError{Failures: failures}
+}
+
+func (b *backfillDb) UpdateDelegationsStateByFinalityProvider(
+	ctx context.Context, fpBtcPkHex string, newState types.DelegationState,
+) error {
+	return b.record(model.BTCDelegationDetailsCollection, false, b.apply(func() error {
+		return b.DbInterface.UpdateDelegationsStateByFinalityProvider(ctx, fpBtcPkHex, newState)
+	}))
+}
+
+func (b *backfillDb) SaveNewFinalityProvider(
+	ctx context.Context, fpDoc *model.FinalityProviderDetails,
+) error {
+	return b.record(model.FinalityProviderDetailsCollection, true, b.apply(func() error {
+		return b.DbInterface.SaveNewFinalityProvider(ctx, fpDoc)
+	}))
+}
+
+func (b *backfillDb) UpdateFinalityProviderState(
+	ctx context.Context, btcPk string, newState string,
+) error {
+	return b.record(model.FinalityProviderDetailsCollection, false, b.apply(func() error {
+		return b.DbInterface.UpdateFinalityProviderState(ctx, btcPk, newState)
+	}))
+}
+
+func (b *backfillDb) UpdateFinalityProviderDetailsFromEvent(
+	ctx context.Context, detailsToUpdate *model.FinalityProviderDetails,
+) error {
+	return b.record(model.FinalityProviderDetailsCollection, false, b.apply(func() error {
+		return b.DbInterface.UpdateFinalityProviderDetailsFromEvent(ctx, detailsToUpdate)
+	}))
+}
+
+func (b *backfillDb) SaveNewTimeLockExpire(
+	ctx context.Context,
+	stakingTxHashHex string,
+	expireHeight uint32,
+	subState types.DelegationSubState,
+) error {
+	return b.record(model.TimeLockCollection, true, b.apply(func() error {
+		return b.DbInterface.SaveNewTimeLockExpire(ctx, stakingTxHashHex, expireHeight, subState)
+	}))
+}
+
+func (b *backfillDb) SaveDelegationStateTransition(
+	ctx context.Context, transition *model.DelegationStateTransition,
+) error {
+	return b.record(model.DelegationStateHistoryCollection, true, b.apply(func() error {
+		return b.DbInterface.SaveDelegationStateTransition(ctx, transition)
+	}))
+}
+
+func (b *backfillDb) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	return b.record(model.OutboxEventsCollection, true, b.apply(func() error {
+		return b.DbInterface.SaveOutboxEvent(ctx, event)
+	}))
+}
+
+func (b *backfillDb) MarkBbnHeightProcessed(ctx context.Context, height uint64) error {
+	return b.record(model.ProcessedBbnHeightsCollection, false, b.apply(func() error {
+		return b.DbInterface.MarkBbnHeightProcessed(ctx, height)
+	}))
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newBackfillTestBbn serves BBN blocks each holding the edit of the finality
+// provider named after the block height
+func newBackfillTestBbn(t *testing.T) *mocks.BbnInterface {
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetBlockResults", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+			event, err := sdk.TypedEventToEvent(&bbntypes.EventFinalityProviderEdited{
+				BtcPkHex: fpBtcPkForEvent(int(*height)),
+				Moniker:  "moniker",
+			})
+			require.NoError(t, err)
+			return &ctypes.ResultBlockResults{
+				Height: *height,
+				TxsResults: []*abcitypes.ExecTxResult{
+					{Events: []abcitypes.Event{abcitypes.Event(event)}},
+				},
+			}, nil
+		},
+	).Maybe()
+	return bbnMock
+}
+
+func TestBackfill(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	dbMock := mocks.NewDbInterface(t)
+	var edited []string
+	dbMock.On("UpdateFinalityProviderDetailsFromEvent", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, details *model.FinalityProviderDetails) error {
+			edited = append(edited, details.BtcPk)
+			return nil
+		},
+	).Times(5)
+	for height := uint64(1); height <= 5; height++ {
+		dbMock.On("MarkBbnHeightProcessed", mock.Anything, height).Return(nil).Once()
+	}
+	expectTransactions(dbMock)
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, newBackfillTestBbn(t), nil)
+	req := BackfillRequest{FromHeight: 1, ToHeight: 5, Concurrency: 2, CheckpointFile: checkpointFile}
+	summary, err := service.Backfill(ctx, req)
+	require.Nil(t, err)
+
+	// The blocks are applied in height order
+	require.Equal(t, []string{
+		fpBtcPkForEvent(1), fpBtcPkForEvent(2), fpBtcPkForEvent(3), fpBtcPkForEvent(4), fpBtcPkForEvent(5),
+	}, edited)
+	expectedWrites := map[string]*BackfillWrites{
+		model.FinalityProviderDetailsCollection: {Updated: 5},
+		model.ProcessedBbnHeightsCollection:     {Updated: 5},
+	}
+	require.Equal(t, &BackfillSummary{ProcessedHeights: 5, Writes: expectedWrites}, summary)
+
+	var checkpoint backfillCheckpoint
+	content, readErr := os.ReadFile(checkpointFile)
+	require.NoError(t, readErr)
+	require.NoError(t, json.Unmarshal(content, &checkpoint))
+	require.Equal(t, uint64(6), checkpoint.NextHeight)
+
+	// A completed backfill resumes to nothing, keeping its summary
+	summary, err = service.Backfill(ctx, req)
+	require.Nil(t, err)
+	require.Equal(t, expectedWrites, summary.Writes)
+
+	// The checkpoint of another range is refused
+	_, err = service.Backfill(ctx, BackfillRequest{
+		FromHeight: 1, ToHeight: 6, Concurrency: 2, CheckpointFile: checkpointFile,
+	})
+	require.NotNil(t, err)
+}
+
+func TestBackfillDryRun(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	checkpointFile := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	// Nothing is written
+	dbMock := mocks.NewDbInterface(t)
+	service := NewService(&config.Config{}, dbMock, nil, nil, newBackfillTestBbn(t), nil)
+	summary, err := service.Backfill(ctx, BackfillRequest{
+		FromHeight: 3, ToHeight: 4, DryRun: true, Concurrency: 4, CheckpointFile: checkpointFile,
+	})
+	require.Nil(t, err)
+	require.Equal(t, &BackfillSummary{
+		ProcessedHeights: 2,
+		Writes: map[string]*BackfillWrites{
+			model.FinalityProviderDetailsCollection: {Updated: 2},
+			model.ProcessedBbnHeightsCollection:     {Updated: 2},
+		},
+	}, summary)
+	require.NoFileExists(t, checkpointFile)
+}
+
+func TestBackfillDbDryRunDelegations(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", ctx, testReprocessTxHash).Return(
+		nil, &db.NotFoundError{},
+	).Once()
+
+	backfillDb := newBackfillDb(dbMock, true, make(map[string]*BackfillWrites))
+	require.NoError(t, backfillDb.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StatePending,
+	}))
+	err := backfillDb.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{StakingTxHashHex: testReprocessTxHash})
+	require.True(t, db.IsDuplicateKeyError(err))
+
+	// The later events see the delegations written in the dry run
+	require.NoError(t, backfillDb.UpdateBTCDelegationState(
+		ctx, testReprocessTxHash, []types.DelegationState{types.StatePending}, types.StateVerified, nil,
+	))
+	err = backfillDb.UpdateBTCDelegationState(
+		ctx, testReprocessTxHash, []types.DelegationState{types.StatePending}, types.StateActive, nil,
+	)
+	require.True(t, db.IsStateTransitionError(err))
+
+	state, err := backfillDb.GetBTCDelegationState(ctx, testReprocessTxHash)
+	require.NoError(t, err)
+	require.Equal(t, types.StateVerified, *state)
+	require.Equal(t, map[string]*BackfillWrites{
+		model.BTCDelegationDetailsCollection: {Created: 1, Updated: 1},
+	}, backfillDb.writes)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// bbnTipMaxAge is the age after which the cached BBN chain tip is queried
+// again from the BBN node
+const bbnTipMaxAge = 5 * time.Second
+
+// bbnTipCache caches the BBN chain tip height, fed by the new block events.
+// While the block processor catches up or is paused the events are not
+// consumed, so the tip gets stale and is queried instead.
+type bbnTipCache struct {
+	mu        sync.Mutex
+	height    int64
+	updatedAt time.Time
+}
+
+func (c *bbnTipCache) observe(height int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.height = height
+	c.updatedAt = time.Now()
+}
+
+func (c *bbnTipCache) get(maxAge time.Duration) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.updatedAt.IsZero() || time.Since(c.updatedAt) > maxAge {
+		return 0, false
+	}
+	return c.height, true
+}
+
+// bbnTipHeight returns the BBN chain tip height, only querying the BBN node
+// when the cached one is stale
+func (s *Service) bbnTipHeight(ctx context.Context) (int64, error) {
+	if height, ok := s.bbnTip.get(bbnTipMaxAge); ok {
+		return height, nil
+	}
+	height, err := s.bbn.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.bbnTip.observe(height)
+	return height, nil
+}
+
+// StartBbnLagMonitor publishes the last processed BBN height, the chain tip
+// and the lag between them. It keeps running while the indexing is paused,
+// the lag then growing with the chain.
+func (s *Service) StartBbnLagMonitor(ctx context.Context) {
+	bbnLagPoller := s.newPoller(
+		"bbn_lag",
+		s.cfg.Poller.BbnLagPollingInterval,
+		s.recordBbnLag,
+	)
+	s.startPoller(ctx, bbnLagPoller)
+}
+
+func (s *Service) recordBbnLag(ctx context.Context) *types.Error {
+	lastProcessedHeight, err := s.db.GetLastProcessedBbnHeight(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get last processed BBN height: %w", err),
+		)
+	}
+	tipHeight, err := s.bbnTipHeight(ctx)
+	if err != nil {
+		return types.NewError(
+			http.StatusInternalServerError,
+			types.ClientRequestError,
+			fmt.Errorf("failed to get BBN tip height: %w", err),
+		)
+	}
+
+	metrics.RecordBbnHeights(
+		lastProcessedHeight, uint64(tipHeight), lag(uint64(tipHeight), lastProcessedHeight),
+	)
+	return nil
+}
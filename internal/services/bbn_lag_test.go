@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordBbnLag(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetLastProcessedBbnHeight", ctx).Return(uint64(90), nil).Twice()
+	bbnMock := mocks.NewBbnInterface(t)
+	// the tip is queried once, then served from the cache
+	bbnMock.On("GetLatestBlockNumber", ctx).Return(int64(100), nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+	require.Nil(t, service.recordBbnLag(ctx))
+	require.Nil(t, service.recordBbnLag(ctx))
+
+	// a new block event refreshes the cached tip
+	service.bbnTip.observe(105)
+	tipHeight, err := service.bbnTipHeight(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(105), tipHeight)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+)
+
+// bbnBlockPrefetcher fetches the results of the BBN blocks ahead of their
+// processing, so that the RPC latency of a block is not added to its
+// indexing delay. At most inFlight fetches run at a time and at most capacity
+// results are held, the heights beyond being fetched once the processor gets
+// closer. A failed fetch is not retried by the prefetcher, the processor
+// fetching the block itself when it reaches it.
+// The results are fetched before the processor checks that their block
+// extends the last processed one, so they are only handed over for the block
+// the processor verified, which it fetches again otherwise.
+type bbnBlockPrefetcher struct {
+	bbn      bbnclient.BbnInterface
+	capacity uint64
+	inFlight chan struct{}
+
+	mu sync.Mutex
+	// ctx is the one of the latest prefetch, cancelled with the processor
+	ctx context.Context
+	// next is the next height the processor will take, and target the
+	// highest height to prefetch
+	next, target uint64
+	// results holds the fetched blocks by height, and fetching the heights
+	// being fetched, closing their channel once fetched
+	results  map[uint64]*prefetchedBbnBlock
+	fetching map[uint64]chan struct{}
+	// failed holds the heights which failed to be fetched, left to the
+	// processor
+	failed map[uint64]bool
+}
+
+// prefetchedBbnBlock is the hash of a prefetched block along with its results
+type prefetchedBbnBlock struct {
+	hash    string
+	results *ctypes.ResultBlockResults
+}
+
+func newBbnBlockPrefetcher(bbn bbnclient.BbnInterface, inFlight int, capacity uint64) *bbnBlockPrefetcher {
+	return &bbnBlockPrefetcher{
+		bbn:      bbn,
+		capacity: capacity,
+		inFlight: make(chan struct{}, inFlight),
+		results:  make(map[uint64]*prefetchedBbnBlock),
+		fetching: make(map[uint64]chan struct{}),
+		failed:   make(map[uint64]bool),
+	}
+}
+
+// prefetch starts fetching the results of the blocks from the height to the
+// target one, the processor taking them from the height on
+func (p *bbnBlockPrefetcher) prefetch(ctx context.Context, from, target uint64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ctx = ctx
+	p.target = target
+	p.advance(from)
+}
+
+// take returns the prefetched results of the block at the height, waiting
+// for them if being fetched, if any. The results of another block than the
+// verified one of the given hash are not returned, as the chain moved since
+// they were fetched. The processor taking the heights in order, the results
+// of the lower ones are dropped. The heights out of the prefetched ones, e.g.
+// backfilled, are left alone.
+func (p *bbnBlockPrefetcher) take(
+	ctx context.Context, height uint64, blockHash string,
+) (*ctypes.ResultBlockResults, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	if p.ctx == nil || height < p.next || height >= p.next+p.capacity {
+		p.mu.Unlock()
+		return nil, false
+	}
+	fetched, ok := p.fetching[height]
+	p.mu.Unlock()
+	if ok {
+		select {
+		case <-fetched:
+		case <-ctx.Done():
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	block, ok := p.results[height]
+	p.advance(height + 1)
+	if !ok {
+		return nil, false
+	}
+	if block.hash != blockHash || block.results.Height != int64(height) {
+		logging.BlockProcessor.FromContext(ctx).Warn().
+			Uint64("height", height).
+			Str("block_hash", blockHash).
+			Str("prefetched_block_hash", block.hash).
+			Msg("prefetched BBN block differs from the verified one, fetching it again")
+		return nil, false
+	}
+	return block.results, true
+}
+
+// advance moves the next height to take, drops the results below it and
+// starts fetching the heights within capacity of it. It must be called with
+// the lock held.
+func (p *bbnBlockPrefetcher) advance(next uint64) {
+	p.next = next
+	for height := range p.results {
+		if height < next {
+			delete(p.results, height)
+		}
+	}
+	for height := range p.failed {
+		if height < next {
+			delete(p.failed, height)
+		}
+	}
+	if p.ctx == nil || p.ctx.Err() != nil {
+		return
+	}
+	for height := next; height <= p.target && height < next+p.capacity; height++ {
+		if _, ok := p.results[height]; ok {
+			continue
+		}
+		if _, ok := p.fetching[height]; ok || p.failed[height] {
+			continue
+		}
+		fetched := make(chan struct{})
+		p.fetching[height] = fetched
+		go p.fetch(p.ctx, height, fetched)
+	}
+}
+
+func (p *bbnBlockPrefetcher) fetch(ctx context.Context, height uint64, fetched chan struct{}) {
+	var result *prefetchedBbnBlock
+	defer func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.fetching, height)
+		close(fetched)
+		// The processor may have moved past the height, or restarted below
+		// it, while fetching
+		if height < p.next || height >= p.next+p.capacity {
+			return
+		}
+		if result == nil {
+			p.failed[height] = true
+			return
+		}
+		p.results[height] = result
+	}()
+
+	select {
+	case p.inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-p.inFlight }()
+
+	// Skip the heights taken while waiting for a fetch slot
+	p.mu.Lock()
+	taken := height < p.next
+	p.mu.Unlock()
+	if taken {
+		return
+	}
+
+	blockHeight := int64(height)
+	block, err := p.bbn.GetBlock(ctx, &blockHeight)
+	if err != nil {
+		logging.BlockProcessor.FromContext(ctx).Debug().Err(err).
+			Uint64("height", height).
+			Msg("failed to prefetch BBN block, left to the processor")
+		return
+	}
+	blockResults, err := p.bbn.GetBlockResults(ctx, &blockHeight)
+	if err != nil {
+		logging.BlockProcessor.FromContext(ctx).Debug().Err(err).
+			Uint64("height", height).
+			Msg("failed to prefetch BBN block results, left to the processor")
+		return
+	}
+	result = &prefetchedBbnBlock{hash: block.BlockID.Hash.String(), results: blockResults}
+}
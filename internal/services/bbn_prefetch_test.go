@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+// prefetchTestBlock returns the block at the height of the fork of the seed
+func prefetchTestBlock(height int64, fork byte) *ctypes.ResultBlock {
+	return &ctypes.ResultBlock{BlockID: cmttypes.BlockID{Hash: []byte{fork, byte(height)}}}
+}
+
+func prefetchTestBlockHash(height int64, fork byte) string {
+	return prefetchTestBlock(height, fork).BlockID.Hash.String()
+}
+
+func TestBbnBlockPrefetcher(t *testing.T) {
+	var mu sync.Mutex
+	fetches := make(map[int64]int)
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetBlock", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *int64) (*ctypes.ResultBlock, error) {
+			return prefetchTestBlock(*height, 0), nil
+		},
+	)
+	bbnMock.On("GetBlockResults", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			fetches[*height]++
+			if *height == 12 {
+				return nil, errors.New("unavailable")
+			}
+			return &ctypes.ResultBlockResults{Height: *height}, nil
+		},
+	)
+
+	ctx := context.Background()
+	prefetcher := newBbnBlockPrefetcher(bbnMock, 2, 3)
+	prefetcher.prefetch(ctx, 10, 20)
+
+	result, ok := prefetcher.take(ctx, 10, prefetchTestBlockHash(10, 0))
+	require.True(t, ok)
+	require.Equal(t, int64(10), result.Height)
+	result, ok = prefetcher.take(ctx, 11, prefetchTestBlockHash(11, 0))
+	require.True(t, ok)
+	require.Equal(t, int64(11), result.Height)
+	// a failed fetch is left to the processor
+	_, ok = prefetcher.take(ctx, 12, prefetchTestBlockHash(12, 0))
+	require.False(t, ok)
+	// the heights out of the prefetched ones are not taken
+	_, ok = prefetcher.take(ctx, 5, prefetchTestBlockHash(5, 0))
+	require.False(t, ok)
+	_, ok = prefetcher.take(ctx, 30, prefetchTestBlockHash(30, 0))
+	require.False(t, ok)
+
+	for height := uint64(13); height <= 20; height++ {
+		result, ok = prefetcher.take(ctx, height, prefetchTestBlockHash(int64(height), 0))
+		require.True(t, ok)
+		require.Equal(t, int64(height), result.Height)
+	}
+
+	// every height is fetched once, and no more than the buffer holds
+	// ahead of the processor
+	mu.Lock()
+	defer mu.Unlock()
+	for height := int64(10); height <= 20; height++ {
+		require.Equal(t, 1, fetches[height], height)
+	}
+	require.Len(t, fetches, 11)
+	require.Empty(t, prefetcher.results)
+}
+
+func TestBbnBlockPrefetcherNil(t *testing.T) {
+	var prefetcher *bbnBlockPrefetcher
+	prefetcher.prefetch(context.Background(), 1, 2)
+	_, ok := prefetcher.take(context.Background(), 1, "")
+	require.False(t, ok)
+}
+
+// TestBbnBlockPrefetcherVerifiedBlock prefetches blocks of a fork the
+// processor does not verify, whose results are not taken
+func TestBbnBlockPrefetcherVerifiedBlock(t *testing.T) {
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetBlock", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *int64) (*ctypes.ResultBlock, error) {
+			return prefetchTestBlock(*height, 1), nil
+		},
+	)
+	bbnMock.On("GetBlockResults", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+			return &ctypes.ResultBlockResults{Height: *height}, nil
+		},
+	)
+
+	ctx := context.Background()
+	prefetcher := newBbnBlockPrefetcher(bbnMock, 2, 3)
+	prefetcher.prefetch(ctx, 10, 12)
+
+	// the block verified by the processor is not the prefetched one, left to
+	// the processor to fetch
+	_, ok := prefetcher.take(ctx, 10, prefetchTestBlockHash(10, 0))
+	require.False(t, ok)
+	result, ok := prefetcher.take(ctx, 11, prefetchTestBlockHash(11, 1))
+	require.True(t, ok)
+	require.Equal(t, int64(11), result.Height)
+	// nor are the results of a block taken without its verified hash
+	_, ok = prefetcher.take(ctx, 12, "")
+	require.False(t, ok)
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+// TestBlockProcessorFixtureSequence drives the block processor over three
+// captured blocks, a finality provider and its delegation created, then
+// covenant signed and the finality provider edited, then slashed
+func TestBlockProcessorFixtureSequence(t *testing.T) {
+	metrics.Init()
+	bbnClient := fixtures.NewBbnClient(
+		fixtures.MergeBlockResults(
+			fixtures.MustLoadBlockResults(fixtures.FpCreated),
+			fixtures.MustLoadBlockResults(fixtures.DelegationCreated),
+		),
+		fixtures.MergeBlockResults(
+			fixtures.MustLoadBlockResults(fixtures.CovenantSignature),
+			fixtures.MustLoadBlockResults(fixtures.FpEdited),
+		),
+		fixtures.MustLoadBlockResults(fixtures.FpSlashed),
+	)
+	database := inmemory.New()
+	service := NewService(&config.Config{}, database, nil, nil, bbnClient, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = service.processBlocksSequentially(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	service.latestHeightChan <- 3
+	require.Eventually(t, func() bool {
+		lastProcessed, err := database.GetLastProcessedBbnBlock(ctx)
+		return err == nil && lastProcessed.Height == 3
+	}, 10*time.Second, 10*time.Millisecond)
+
+	lastProcessed, err := database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, fixtures.BlockHash(3).String(), lastProcessed.BlockHash)
+	require.Nil(t, lastProcessed.ProcessingMarker)
+
+	fp, err := database.GetFinalityProviderByBtcPk(ctx, fixtures.FpBtcPkHex)
+	require.NoError(t, err)
+	require.Equal(t, "Fixture FP renamed", fp.Description.Moniker)
+	require.Equal(t, "0.100000000000000000", fp.Commission)
+
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, fixtures.StakingTxHashHex)
+	require.NoError(t, err)
+	require.Equal(t, types.StateSlashed, delegation.State)
+	require.Equal(t, []string{fixtures.FpBtcPkHex}, delegation.FinalityProviderBtcPksHex)
+	require.Equal(t, uint64(500000), delegation.StakingAmount)
+	require.Equal(t, int64(1), delegation.BTCDelegationCreatedBlock.Height)
+	require.Equal(t, fixtures.BlockTime(1).Unix(), delegation.BTCDelegationCreatedBlock.Timestamp)
+	require.Len(t, delegation.CovenantUnbondingSignatures, 1)
+
+	transitions, err := database.GetDelegationStateTransitions(ctx, fixtures.StakingTxHashHex)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	require.Equal(t, types.StatePending, transitions[0].ToState)
+	require.Equal(t, uint64(1), transitions[0].BbnHeight)
+	require.Equal(t, types.StatePending, transitions[1].FromState)
+	require.Equal(t, types.StateSlashed, transitions[1].ToState)
+	require.Equal(t, uint64(3), transitions[1].BbnHeight)
+}
+
+// TestBbnForkHaltsProcessing feeds a block not extending the last processed
+// one, which halts the processing and raises a critical alert
+func TestBbnForkHaltsProcessing(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.UpdateLastProcessedBbnHeight(ctx, 1, "stored hash"))
+	alerter := &fakeAlerter{}
+	service := New(&config.Config{}, Dependencies{
+		Db:      database,
+		Bbn:     fixtures.NewBbnClient(fixtures.NewBlockResults(), fixtures.NewBlockResults()),
+		Alerter: alerter,
+	})
+
+	_, err := service.processNextBbnBlock(ctx, 2, "stored hash")
+	require.ErrorIs(t, err, types.ErrBbnForkDetected)
+
+	lastProcessed, dbErr := database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, dbErr)
+	require.Equal(t, uint64(1), lastProcessed.Height)
+	require.Equal(t, fmt.Sprintf(
+		"block at height 2 has parent hash %s, expected stored hash", fixtures.BlockHash(1),
+	), lastProcessed.HaltReason)
+
+	require.Equal(t, []string{"BBN fork detected, block processing halted"}, alerter.titles)
+	require.Equal(t, []alerting.Severity{alerting.SeverityCritical}, alerter.severities)
+	require.Equal(t, []map[string]string{{
+		"height":              "2",
+		"parent_hash":         fixtures.BlockHash(1).String(),
+		"last_processed_hash": "stored hash",
+	}}, alerter.fields)
+}
+
+// TestBbnForkResync halts the processing on a fork, which a restart does not
+// resume, until a resync from a given height restarts the processing there
+func TestBbnForkResync(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.UpdateLastProcessedBbnHeight(ctx, 1, "stored hash"))
+	service := New(&config.Config{}, Dependencies{
+		Db:      database,
+		Bbn:     fixtures.NewBbnClient(fixtures.NewBlockResults(), fixtures.NewBlockResults(), fixtures.NewBlockResults()),
+		Alerter: &fakeAlerter{},
+	})
+
+	_, err := service.processNextBbnBlock(ctx, 2, "stored hash")
+	require.ErrorIs(t, err, types.ErrBbnForkDetected)
+
+	// The halt survives restarts
+	err = service.processBlocksSequentially(ctx)
+	require.ErrorIs(t, err, types.ErrBbnForkDetected)
+
+	resyncHeight := uint64(1)
+	service.applyStartHeight(ctx, StartHeightOverrides{ResyncHeight: &resyncHeight})
+	lastProcessed, dbErr := database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, dbErr)
+	require.Equal(t, &model.LastProcessedHeight{Height: resyncHeight}, lastProcessed)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = service.processBlocksSequentially(runCtx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	service.latestHeightChan <- 3
+	require.Eventually(t, func() bool {
+		lastProcessed, err := database.GetLastProcessedBbnBlock(ctx)
+		return err == nil && lastProcessed.Height == 3
+	}, 10*time.Second, 10*time.Millisecond)
+
+	lastProcessed, dbErr = database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, dbErr)
+	require.Equal(t, fixtures.BlockHash(3).String(), lastProcessed.BlockHash)
+	require.Empty(t, lastProcessed.HaltReason)
+}
+
+// TestBbnHeightRegressionIgnored feeds a latest height below the last
+// processed one, as from a lagging node, which is ignored rather than
+// stopping the processing
+func TestBbnHeightRegressionIgnored(t *testing.T) {
+	metrics.Init()
+	bbnClient := fixtures.NewBbnClient(fixtures.NewBlockResults(), fixtures.NewBlockResults())
+	database := inmemory.New()
+	service := NewService(&config.Config{}, database, nil, nil, bbnClient, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- service.processBlocksSequentially(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	waitForHeight := func(height uint64) {
+		require.Eventually(t, func() bool {
+			lastProcessed, err := database.GetLastProcessedBbnBlock(ctx)
+			return err == nil && lastProcessed.Height == height
+		}, 10*time.Second, 10*time.Millisecond)
+	}
+
+	service.latestHeightChan <- 2
+	waitForHeight(2)
+
+	service.latestHeightChan <- 1
+	bbnClient.AppendBlocks(fixtures.NewBlockResults())
+	service.latestHeightChan <- 3
+	waitForHeight(3)
+	require.Empty(t, done)
+}
+
+// TestRestartRechecksLastProcessedBbnBlock restarts the processing after a
+// block creating a finality provider, the finality provider left out of the
+// database for its creation to tell whether the block is processed again
+func TestRestartRechecksLastProcessedBbnBlock(t *testing.T) {
+	testCases := []struct {
+		name        string
+		storedHash  string
+		reprocessed bool
+	}{
+		{
+			name:        "unchanged block skipped",
+			storedHash:  fixtures.BlockHash(1).String(),
+			reprocessed: false,
+		},
+		{
+			name:        "changed block reprocessed",
+			storedHash:  "hash of a forked block",
+			reprocessed: true,
+		},
+		{
+			name:        "hash recorded before the check",
+			storedHash:  "",
+			reprocessed: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics.Init()
+			database := inmemory.New()
+			require.NoError(t, database.UpdateLastProcessedBbnHeight(context.Background(), 1, tc.storedHash))
+			bbnClient := fixtures.NewBbnClient(
+				fixtures.MustLoadBlockResults(fixtures.FpCreated),
+				fixtures.NewBlockResults(),
+			)
+			service := NewService(&config.Config{}, database, nil, nil, bbnClient, nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_ = service.processBlocksSequentially(ctx)
+			}()
+			t.Cleanup(func() {
+				cancel()
+				<-done
+			})
+
+			service.latestHeightChan <- 2
+			require.Eventually(t, func() bool {
+				lastProcessed, err := database.GetLastProcessedBbnBlock(ctx)
+				return err == nil && lastProcessed.Height == 2
+			}, 10*time.Second, 10*time.Millisecond)
+
+			_, err := database.GetFinalityProviderByBtcPk(ctx, fixtures.FpBtcPkHex)
+			if tc.reprocessed {
+				require.NoError(t, err)
+			} else {
+				require.True(t, db.IsNotFoundError(err))
+			}
+		})
+	}
+}
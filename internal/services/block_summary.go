@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+)
+
+// bbnBlockSummarySchemaVersion is the version of the fields of the block
+// summary log, to be bumped on any change of their names or meaning
+const bbnBlockSummarySchemaVersion = 3
+
+// bbnBlockMode tells why a BBN block is processed
+type bbnBlockMode string
+
+const (
+	// bbnBlockLive is a new block processed at the tip of the chain
+	bbnBlockLive bbnBlockMode = "live"
+	// bbnBlockCatchUp is a new block processed behind the tip of the chain
+	bbnBlockCatchUp bbnBlockMode = "catch_up"
+	// bbnBlockReplay is a block processed again, after it changed or after its
+	// processing got interrupted
+	bbnBlockReplay bbnBlockMode = "replay"
+	// bbnBlockBackfill is a past block processed by a backfill, of a requested
+	// range or of the unprocessed heights found by the audit
+	bbnBlockBackfill bbnBlockMode = "backfill"
+)
+
+type bbnBlockModeKey struct{}
+
+// withBbnBlockMode returns a context processing the blocks in the given mode
+func withBbnBlockMode(ctx context.Context, mode bbnBlockMode) context.Context {
+	return context.WithValue(ctx, bbnBlockModeKey{}, mode)
+}
+
+// bbnBlockModeFromContext returns the mode of the blocks processed with the
+// context, live by default
+func bbnBlockModeFromContext(ctx context.Context) bbnBlockMode {
+	if mode, ok := ctx.Value(bbnBlockModeKey{}).(bbnBlockMode); ok {
+		return mode
+	}
+	return bbnBlockLive
+}
+
+// bbnBlockSummary sums up the processing of a BBN block
+type bbnBlockSummary struct {
+	blockHash string
+	mode      bbnBlockMode
+	started   time.Time
+	// eventCounts are the events of the block by event type, "other" for the
+	// ones not processed
+	eventCounts map[string]int
+	// skippedEvents are the events applied before an interruption of the
+	// processing, skipped on its resumption
+	skippedEvents int
+	// delegationReads are the delegations read by staking tx hash by the
+	// handlers of the events, and memoizedDelegationReads the ones among them
+	// served from the delegation memo of the block instead of the database
+	delegationReads, memoizedDelegationReads uint64
+	// startMemStats are the memory statistics of the process when the block
+	// started, nil unless its allocations are summed up
+	startMemStats *runtime.MemStats
+}
+
+// newBbnBlockSummary starts the summary of a block, summing up the heap
+// allocations of the process during the block if allocations is set
+func newBbnBlockSummary(
+	ctx context.Context, marker *model.BbnProcessingMarker, allocations bool,
+) *bbnBlockSummary {
+	summary := &bbnBlockSummary{
+		mode:        bbnBlockModeFromContext(ctx),
+		started:     time.Now(),
+		eventCounts: make(map[string]int),
+	}
+	// The hash is only known to the blocks processed under a marker
+	if marker != nil {
+		summary.blockHash = marker.BlockHash
+	}
+	if allocations {
+		summary.startMemStats = &runtime.MemStats{}
+		runtime.ReadMemStats(summary.startMemStats)
+	}
+	return summary
+}
+
+// log logs the summary of the processed block along with the writes of its
+// processing by collection, as a single line of a stable schema parsed by the
+// dashboards. The line is logged whatever the level of the block processor.
+// The allocations, when summed up, are the ones of the whole process, the
+// pollers included, during the block.
+func (b *bbnBlockSummary) log(ctx context.Context, writes map[string]uint64) {
+	events := 0
+	for _, count := range b.eventCounts {
+		events += count
+	}
+
+	line := logging.BlockProcessor.FromContext(ctx).Log()
+	if b.startMemStats != nil {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		line = line.
+			Uint64("allocs", memStats.Mallocs-b.startMemStats.Mallocs).
+			Uint64("alloc_bytes", memStats.TotalAlloc-b.startMemStats.TotalAlloc)
+	}
+	line.
+		Str("log", "bbn_block_summary").
+		Int("schema_version", bbnBlockSummarySchemaVersion).
+		Str("block_hash", b.blockHash).
+		Str("mode", string(b.mode)).
+		Int("events", events).
+		Interface("event_counts", b.eventCounts).
+		Int("skipped_events", b.skippedEvents).
+		Interface("writes", writes).
+		Uint64("outbox_events", writes[model.OutboxEventsCollection]).
+		Uint64("delegation_reads", b.delegationReads).
+		Uint64("delegation_reads_memoized", b.memoizedDelegationReads).
+		Float64("duration_seconds", time.Since(b.started).Seconds()).
+		Msg("bbn block summary")
+}
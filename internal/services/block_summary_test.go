@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// captureBlockSummaries returns the block summaries logged by the test
+func captureBlockSummaries(t *testing.T) func() []map[string]any {
+	var logs bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { log.Logger = previousLogger })
+
+	return func() []map[string]any {
+		var summaries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+			if entry["log"] == "bbn_block_summary" {
+				summaries = append(summaries, entry)
+			}
+		}
+		return summaries
+	}
+}
+
+func TestBbnBlockSummary(t *testing.T) {
+	metrics.Init()
+	summaries := captureBlockSummaries(t)
+	ctx := context.Background()
+	env := newMarkerTestEnv(t)
+	// the writes are counted by the metrics database
+	env.service.db = db.NewMetricsDatabase(env.service.db, &config.DbConfig{})
+
+	env.killAt = 2
+	require.Error(t, env.processBlock(withBbnBlockMode(ctx, bbnBlockCatchUp)))
+	// a failed block is not summed up
+	require.Empty(t, summaries())
+
+	env.killAt = -1
+	_, err := env.service.recoverBbnBlockProcessing(ctx, env.lastProcessed)
+	require.Nil(t, err)
+
+	logged := summaries()
+	require.Len(t, logged, 1)
+	summary := logged[0]
+	require.Equal(t, "bbn block summary", summary["message"])
+	require.Equal(t, float64(bbnBlockSummarySchemaVersion), summary["schema_version"])
+	require.Equal(t, float64(testMarkerHeight), summary[logging.BbnHeightField])
+	require.Equal(t, env.blockHash.String(), summary["block_hash"])
+	require.Equal(t, string(bbnBlockReplay), summary["mode"])
+	require.Equal(t, float64(testMarkerEventsLen), summary["events"])
+	require.Equal(t, map[string]any{
+		string(EventFinalityProviderEditedType): float64(testMarkerEventsLen),
+	}, summary["event_counts"])
+	require.Equal(t, float64(2), summary["skipped_events"])
+	// the events left are applied and marked as processed, then the height
+	require.Equal(t, map[string]any{
+		model.FinalityProviderDetailsCollection: float64(3),
+		model.LastProcessedHeightCollection:     float64(3),
+		model.ProcessedBbnHeightsCollection:     float64(1),
+	}, summary["writes"])
+	require.Equal(t, float64(0), summary["outbox_events"])
+	require.Equal(t, float64(0), summary["delegation_reads"])
+	require.Equal(t, float64(0), summary["delegation_reads_memoized"])
+	require.Contains(t, summary, "duration_seconds")
+	// the allocations are only summed up when enabled
+	require.NotContains(t, summary, "allocs")
+}
+
+func TestBbnBlockSummaryAllocations(t *testing.T) {
+	summaries := captureBlockSummaries(t)
+	ctx := context.Background()
+	env := newMarkerTestEnv(t)
+	env.service.blockAllocations = true
+
+	require.NoError(t, env.processBlock(ctx))
+
+	logged := summaries()
+	require.Len(t, logged, 1)
+	require.Greater(t, logged[0]["allocs"], float64(0))
+	require.Greater(t, logged[0]["alloc_bytes"], float64(0))
+}
@@ -2,11 +2,24 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/errorreporting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TODO: To be replaced by the actual values later and moved to a config file
@@ -16,10 +29,44 @@ const (
 
 // StartBbnBlockProcessor initiates the BBN blockchain block processing in a separate goroutine.
 // It continuously processes new blocks and their events sequentially, maintaining the chain order.
-// If an error occurs, it logs the error and terminates the program.
+// If an error occurs, it logs the error and terminates the program. If the BBN node serves a
+// chain that differs from the processed one, it halts the processing until an explicit resync.
 // The method runs asynchronously to allow non-blocking operation.
 func (s *Service) StartBbnBlockProcessor(ctx context.Context) {
-	if err := s.processBlocksSequentially(ctx); err != nil {
+	s.blockProcessorStarted.Store(true)
+	defer close(s.blockProcessorDone)
+
+	// The block in flight is cancelled once aborted, on the loss of the
+	// leader lease
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.blockProcessorAbort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := s.processBlocksSequentially(ctx)
+	if err != nil && errors.Is(err, types.ErrBbnForkDetected) {
+		metrics.RecordBbnBlockProcessorHalted()
+		s.health.setBlockProcessorHalted()
+		logging.BlockProcessor.FromContext(ctx).Error().Err(err).
+			Msg("BBN block processing halted, restart with --resync-bbn-height once the BBN node is healthy")
+		// Keep the other processes and the metrics server running
+		select {
+		case <-ctx.Done():
+		case <-s.blockProcessorStop:
+		}
+		return
+	}
+	if err != nil && (ctx.Err() != nil || errors.Is(err, errBbnBlockProcessorStopped)) {
+		// The indexer is shutting down
+		logging.BlockProcessor.FromContext(ctx).Info().Msg("BBN block processor stopped")
+		return
+	}
+	if err != nil {
 		log.Fatal().Msgf("BBN block processor exited with error: %v", err)
 	}
 }
@@ -29,7 +76,7 @@ func (s *Service) StartBbnBlockProcessor(ctx context.Context) {
 // It extracts events from each block and forwards them to the event processor.
 // Returns an error if it fails to get block results or process events.
 func (s *Service) processBlocksSequentially(ctx context.Context) *types.Error {
-	lastProcessedHeight, dbErr := s.db.GetLastProcessedBbnHeight(ctx)
+	lastProcessed, dbErr := s.db.GetLastProcessedBbnBlock(ctx)
 	if dbErr != nil {
 		return types.NewError(
 			http.StatusInternalServerError,
@@ -37,6 +84,20 @@ func (s *Service) processBlocksSequentially(ctx context.Context) *types.Error {
 			fmt.Errorf("failed to get last processed height: %w", dbErr),
 		)
 	}
+	if lastProcessed.HaltReason != "" {
+		return types.NewInternalServiceError(
+			fmt.Errorf("%w: %s", types.ErrBbnForkDetected, lastProcessed.HaltReason),
+		)
+	}
+	lastProcessed, err := s.recoverBbnBlockProcessing(ctx, lastProcessed)
+	if err != nil {
+		return err
+	}
+	lastProcessedHeight := lastProcessed.Height
+	lastProcessedHash, err := s.recheckLastProcessedBbnBlock(ctx, lastProcessed)
+	if err != nil {
+		return err
+	}
 
 	for {
 		select {
@@ -46,20 +107,34 @@ func (s *Service) processBlocksSequentially(ctx context.Context) *types.Error {
 				types.InternalServiceError,
 				fmt.Errorf("context cancelled during BBN block processor"),
 			)
+		case <-s.blockProcessorStop:
+			return types.NewInternalServiceError(errBbnBlockProcessorStopped)
 
 		case height := <-s.latestHeightChan:
 			// Drain channel to get the most recent height
 			latestHeight := s.getLatestHeight(height)
 
-			log.Debug().
+			logging.BlockProcessor.FromContext(ctx).Debug().
 				Uint64("last_processed_height", lastProcessedHeight).
 				Int64("latest_height", latestHeight).
 				Msg("Received new block height")
 
-			if uint64(latestHeight) <= lastProcessedHeight {
+			if uint64(latestHeight) < lastProcessedHeight {
+				// Typically the RPC provider failing over to a lagging node
+				logging.BlockProcessor.FromContext(ctx).Warn().
+					Uint64("last_processed_height", lastProcessedHeight).
+					Int64("latest_height", latestHeight).
+					Msg("BBN latest height went backwards, ignoring it")
+				continue
+			}
+			if uint64(latestHeight) == lastProcessedHeight {
+				s.health.setBootstrapped()
 				continue
 			}
 
+			// Fetch the blocks ahead while processing them
+			s.bbnPrefetcher.prefetch(ctx, lastProcessedHeight+1, uint64(latestHeight))
+
 			// Process blocks from lastProcessedHeight + 1 to latestHeight
 			for i := lastProcessedHeight + 1; i <= uint64(latestHeight); i++ {
 				select {
@@ -69,31 +144,456 @@ func (s *Service) processBlocksSequentially(ctx context.Context) *types.Error {
 						types.InternalServiceError,
 						fmt.Errorf("context cancelled during block processing"),
 					)
+				case <-s.blockProcessorStop:
+					return types.NewInternalServiceError(errBbnBlockProcessorStopped)
 				default:
-					events, err := s.getEventsFromBlock(ctx, int64(i))
+					mode := bbnBlockLive
+					if i < uint64(latestHeight) {
+						mode = bbnBlockCatchUp
+					}
+					blockHash, err := s.processNextBbnBlock(withBbnBlockMode(ctx, mode), i, lastProcessedHash)
 					if err != nil {
 						return err
 					}
-
-					for _, event := range events {
-						if err := s.processEvent(ctx, event, int64(i)); err != nil {
-							return err
-						}
-					}
-
-					if dbErr := s.db.UpdateLastProcessedBbnHeight(ctx, uint64(i)); dbErr != nil {
-						return types.NewError(
-							http.StatusInternalServerError,
-							types.InternalServiceError,
-							fmt.Errorf("failed to update last processed height in database: %w", dbErr),
-						)
-					}
 					lastProcessedHeight = i
+					lastProcessedHash = blockHash
 				}
-				log.Info().Msgf("Processed blocks up to height %d", lastProcessedHeight)
+				logging.BlockProcessor.FromContext(ctx).Info().Msgf("Processed blocks up to height %d", lastProcessedHeight)
+			}
+			s.health.setBootstrapped()
+		}
+	}
+}
+
+// processNextBbnBlock processes the block at the given height, once the
+// indexing is resumed if paused, and returns its hash
+func (s *Service) processNextBbnBlock(
+	ctx context.Context, height uint64, lastProcessedHash string,
+) (string, *types.Error) {
+	if err := s.pause.enter(ctx, s.blockProcessorStop); err != nil {
+		if errors.Is(err, errPauseWaitStopped) {
+			return "", types.NewInternalServiceError(errBbnBlockProcessorStopped)
+		}
+		return "", types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("context cancelled while the indexing is paused"),
+		)
+	}
+	defer s.pause.exit()
+
+	s.health.startBlockProcessing(height)
+	blockHash, err := s.verifyBbnBlock(ctx, int64(height), lastProcessedHash)
+	if err != nil {
+		return "", err
+	}
+
+	marker := model.NewBbnProcessingMarker(height, blockHash, time.Now().Unix())
+	if dbErr := s.db.StartBbnBlockProcessing(ctx, marker); dbErr != nil {
+		return "", types.NewInternalServiceError(
+			fmt.Errorf("failed to write BBN block processing marker: %w", dbErr),
+		)
+	}
+
+	if err := s.processMarkedBbnBlock(ctx, marker); err != nil {
+		return "", err
+	}
+	s.health.completeBlockProcessing()
+	metrics.RecordBbnHeightAdvanced(time.Now())
+
+	return blockHash, nil
+}
+
+// recheckLastProcessedBbnBlock compares the hash of the block at the last
+// processed height with the stored one and returns the current hash. The height
+// is skipped if the hashes match, and reprocessed otherwise as its events may
+// differ from the ones processed, relying on the event handlers ignoring the
+// events already applied. Without a stored hash, the current one is recorded.
+func (s *Service) recheckLastProcessedBbnBlock(
+	ctx context.Context, lastProcessed *model.LastProcessedHeight,
+) (string, *types.Error) {
+	if lastProcessed.Height == 0 {
+		return "", nil
+	}
+
+	height := int64(lastProcessed.Height)
+	block, err := s.bbn.GetBlock(ctx, &height)
+	if err != nil {
+		return "", types.NewError(
+			http.StatusInternalServerError,
+			types.ClientRequestError,
+			fmt.Errorf("failed to get block: %w", err),
+		)
+	}
+	blockHash := block.BlockID.Hash.String()
+
+	if blockHash == lastProcessed.BlockHash {
+		logging.BlockProcessor.FromContext(ctx).Debug().
+			Uint64("height", lastProcessed.Height).
+			Msg("last processed BBN block unchanged, skipping it")
+		return blockHash, nil
+	}
+
+	if lastProcessed.BlockHash != "" {
+		logging.BlockProcessor.FromContext(ctx).Warn().
+			Uint64("height", lastProcessed.Height).
+			Str("block_hash", blockHash).
+			Str("last_processed_hash", lastProcessed.BlockHash).
+			Msg("last processed BBN block changed, reprocessing it")
+
+		if err := s.processBbnBlock(withBbnBlockMode(ctx, bbnBlockReplay), lastProcessed.Height, nil); err != nil {
+			return "", err
+		}
+	}
+
+	if dbErr := s.db.UpdateLastProcessedBbnHeight(ctx, lastProcessed.Height, blockHash); dbErr != nil {
+		return "", types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("failed to update last processed height in database: %w", dbErr),
+		)
+	}
+
+	return blockHash, nil
+}
+
+// verifyBbnBlock checks that the block at the given height extends the last
+// processed block and returns its hash. On mismatch the processing is halted
+// until an explicit resync, as its events would be processed on top of state
+// derived from a different chain. The check is skipped if no hash is stored yet.
+func (s *Service) verifyBbnBlock(
+	ctx context.Context, blockHeight int64, lastProcessedHash string,
+) (string, *types.Error) {
+	block, err := s.bbn.GetBlock(ctx, &blockHeight)
+	if err != nil {
+		return "", types.NewError(
+			http.StatusInternalServerError,
+			types.ClientRequestError,
+			fmt.Errorf("failed to get block: %w", err),
+		)
+	}
+
+	parentHash := block.Block.Header.LastBlockID.Hash.String()
+	if lastProcessedHash != "" && parentHash != lastProcessedHash {
+		reason := fmt.Sprintf(
+			"block at height %d has parent hash %s, expected %s",
+			blockHeight, parentHash, lastProcessedHash,
+		)
+		logging.BlockProcessor.FromContext(ctx).Error().
+			Int64("height", blockHeight).
+			Str("parent_hash", parentHash).
+			Str("last_processed_hash", lastProcessedHash).
+			Msg("BBN block does not extend the last processed block")
+		s.alerter.Alert(ctx, alerting.SeverityCritical, "BBN fork detected, block processing halted", map[string]string{
+			"height":              strconv.FormatInt(blockHeight, 10),
+			"parent_hash":         parentHash,
+			"last_processed_hash": lastProcessedHash,
+		})
+
+		if dbErr := s.db.HaltBbnProcessing(ctx, reason); dbErr != nil {
+			return "", types.NewInternalServiceError(
+				fmt.Errorf("failed to halt BBN block processing: %w", dbErr),
+			)
+		}
+		return "", types.NewInternalServiceError(
+			fmt.Errorf("%w: %s", types.ErrBbnForkDetected, reason),
+		)
+	}
+
+	return block.BlockID.Hash.String(), nil
+}
+
+// recoverBbnBlockProcessing completes the block left half processed by a crash,
+// as recorded by the processing marker, before the normal processing resumes.
+// Only the events not applied yet are processed, unless the block changed in
+// the meantime, in which case it is processed again as a whole. It returns the
+// last processed block once recovered.
+func (s *Service) recoverBbnBlockProcessing(
+	ctx context.Context, lastProcessed *model.LastProcessedHeight,
+) (*model.LastProcessedHeight, *types.Error) {
+	marker := lastProcessed.ProcessingMarker
+	if marker == nil {
+		return lastProcessed, nil
+	}
+
+	height := int64(marker.Height)
+	block, err := s.bbn.GetBlock(ctx, &height)
+	if err != nil {
+		return nil, types.NewError(
+			http.StatusInternalServerError,
+			types.ClientRequestError,
+			fmt.Errorf("failed to get block: %w", err),
+		)
+	}
+
+	blockHash := block.BlockID.Hash.String()
+	if blockHash != marker.BlockHash {
+		logging.BlockProcessor.FromContext(ctx).Warn().
+			Uint64("height", marker.Height).
+			Str("block_hash", blockHash).
+			Str("marker_block_hash", marker.BlockHash).
+			Msg("BBN block under processing changed, processing it again as a whole")
+
+		marker = model.NewBbnProcessingMarker(marker.Height, blockHash, time.Now().Unix())
+		if dbErr := s.db.StartBbnBlockProcessing(ctx, marker); dbErr != nil {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to write BBN block processing marker: %w", dbErr),
+			)
+		}
+	}
+
+	logging.BlockProcessor.FromContext(ctx).Info().
+		Uint64("height", marker.Height).
+		Int("processed_events", len(marker.ProcessedEvents)).
+		Msg("resuming the interrupted processing of a BBN block")
+
+	if err := s.processMarkedBbnBlock(withBbnBlockMode(ctx, bbnBlockReplay), marker); err != nil {
+		return nil, err
+	}
+
+	return &model.LastProcessedHeight{Height: marker.Height, BlockHash: marker.BlockHash}, nil
+}
+
+// processMarkedBbnBlock processes the block under the given processing marker
+// and advances the last processed height, which clears the marker in the same
+// write.
+func (s *Service) processMarkedBbnBlock(
+	ctx context.Context, marker *model.BbnProcessingMarker,
+) *types.Error {
+	if err := s.processBbnBlock(ctx, marker.Height, marker); err != nil {
+		return err
+	}
+
+	if dbErr := s.db.UpdateLastProcessedBbnHeight(ctx, marker.Height, marker.BlockHash); dbErr != nil {
+		return types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("failed to update last processed height in database: %w", dbErr),
+		)
+	}
+
+	return nil
+}
+
+// processBbnBlock processes the events of the block at the given height and
+// records the height as processed.
+// Given a processing marker, the events it records as applied are skipped and
+// every applied event is recorded in it, so that no event is applied twice if
+// the processing gets interrupted.
+func (s *Service) processBbnBlock(
+	ctx context.Context, height uint64, marker *model.BbnProcessingMarker,
+) (err *types.Error) {
+	ctx, span := startBbnBlockSpan(logging.WithBbnHeight(ctx, height), height)
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	// The prefetched results are only taken for the block verified by the
+	// processor, recorded by the marker
+	var events []BbnEvent
+	if blockResult, ok := s.takePrefetchedBbnBlock(ctx, marker); ok {
+		events = eventsFromBlockResults(ctx, int64(height), blockResult)
+	} else if events, err = s.getEventsFromBlock(ctx, int64(height)); err != nil {
+		return err
+	}
+
+	return s.applyBbnBlockEvents(ctx, height, events, marker)
+}
+
+// applyBbnBlockEvents applies the fetched events of the block at the given
+// height and records the height as processed. Blocks are applied one at a
+// time, so that the backfill of missed heights does not interleave with the
+// block processor.
+func (s *Service) applyBbnBlockEvents(
+	ctx context.Context, height uint64, events []BbnEvent, marker *model.BbnProcessingMarker,
+) *types.Error {
+	s.bbnBlockMu.Lock()
+	defer s.bbnBlockMu.Unlock()
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("bbn.event_count", len(events)))
+	blockStart := time.Now()
+	summary := newBbnBlockSummary(ctx, marker, s.blockAllocations)
+	ctx, writeStats := db.WithWriteStats(ctx)
+	ctx, delegationMemo := db.WithDelegationMemo(ctx)
+	s.primeDelegationMemo(ctx, events)
+	for _, event := range events {
+		summary.eventCounts[eventTypeLabel(event.Event.Type)]++
+	}
+	for i := 0; i < len(events); i++ {
+		event := events[i]
+		eventType := eventTypeLabel(event.Event.Type)
+		if marker != nil && marker.IsEventProcessed(i) {
+			logging.BlockProcessor.FromContext(ctx).Debug().
+				Int("event_index", i).
+				Msg("skipping BBN event already applied")
+			metrics.RecordBbnEventProcessed(eventType, metrics.Skipped, 0)
+			summary.skippedEvents++
+			continue
+		}
+		if eventType == "other" {
+			metrics.RecordBbnEventProcessed(eventType, metrics.Skipped, 0)
+			continue
+		}
+
+		if isCovenantEvent(event) {
+			end := covenantEventRunEnd(events, i, marker)
+			if err := s.applyCovenantEvents(ctx, height, events, i, end, marker); err != nil {
+				return err
 			}
+			i = end - 1
+			continue
 		}
+
+		if err := s.applyBbnEvent(ctx, height, i, event, marker, 0, func(ctx context.Context) *types.Error {
+			return s.processEvent(ctx, event, int64(height))
+		}); err != nil {
+			return err
+		}
+	}
+
+	if dbErr := s.db.MarkBbnHeightProcessed(ctx, height); dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to mark BBN height %d as processed: %w", height, dbErr),
+		)
+	}
+	metrics.RecordBbnBlockProcessed(time.Since(blockStart), len(events))
+	summary.delegationReads, summary.memoizedDelegationReads = delegationMemo.Reads()
+	summary.log(ctx, writeStats.Writes())
+
+	return nil
+}
+
+// applyBbnEvent applies the event at the index of the block with process,
+// accounting for it and marking it as processed. The writes of process and
+// the marker run in a single transaction, so that an event is never applied
+// twice nor marked processed without being applied. A failed event stops the
+// block processing to be retried, unless it failed with a permanent error, in
+// which case it is set aside. The elapsed duration is the one spent on the
+// event before, e.g. in a batch, added to the one of process.
+func (s *Service) applyBbnEvent(
+	ctx context.Context, height uint64, index int, event BbnEvent, marker *model.BbnProcessingMarker,
+	elapsed time.Duration, process func(ctx context.Context) *types.Error,
+) *types.Error {
+	eventType := eventTypeLabel(event.Event.Type)
+	eventStart := time.Now().Add(-elapsed)
+	stakingTxHash := eventStakingTxHash(event.Event)
+	eventCtx := audit.WithTrigger(logging.WithBbnEvent(ctx, event.Event.Type, stakingTxHash), audit.Trigger{
+		Type:         audit.TriggerBbnEvent,
+		BbnEventType: event.Event.Type,
+		BbnHeight:    height,
+		TxHash:       stakingTxHash,
+	})
+	eventCtx, eventSpan := startBbnEventSpan(eventCtx, event, index, stakingTxHash)
+	var err *types.Error
+	txErr := s.db.RunInTransaction(eventCtx, func(txCtx context.Context) error {
+		if err = process(txCtx); err != nil {
+			return err
+		}
+		return s.markBbnEventProcessed(txCtx, height, index, marker)
+	})
+	endSpan(eventSpan, err)
+	if err != nil {
+		s.captureBbnEvent(eventCtx, height, index, event, err)
+		errorreporting.Report(eventCtx, string(logging.BlockProcessor), err, nil)
+		if types.IsRetryable(err) {
+			metrics.RecordBbnEventProcessed(eventType, metrics.Error, time.Since(eventStart))
+			return err
+		}
+		if dlErr := s.deadLetterBbnEvent(eventCtx, height, index, event, stakingTxHash, marker, err); dlErr != nil {
+			metrics.RecordBbnEventProcessed(eventType, metrics.Error, time.Since(eventStart))
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to dead-letter BBN event %d at height %d: %w", index, height, dlErr),
+			)
+		}
+		metrics.RecordBbnEventProcessed(eventType, metrics.DeadLettered, time.Since(eventStart))
+		return nil
+	}
+	if txErr != nil {
+		metrics.RecordBbnEventProcessed(eventType, metrics.Error, time.Since(eventStart))
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to mark BBN event %d at height %d as processed: %w", index, height, txErr),
+		)
+	}
+
+	metrics.RecordBbnEventProcessed(eventType, metrics.Success, time.Since(eventStart))
+	s.captureBbnEvent(eventCtx, height, index, event, nil)
+	return nil
+}
+
+// markBbnEventProcessed marks the event at the index of the block as
+// processed, if the block is processed by event
+func (s *Service) markBbnEventProcessed(
+	ctx context.Context, height uint64, index int, marker *model.BbnProcessingMarker,
+) error {
+	if marker == nil {
+		return nil
+	}
+	return s.db.MarkBbnEventProcessed(ctx, height, index)
+}
+
+// primeDelegationMemo reads the delegations of the events of the block in a
+// single read into the delegation memo of the context, for their handlers to
+// read them from it. As the handlers read them anyway, failing to do so is
+// not an error.
+func (s *Service) primeDelegationMemo(ctx context.Context, events []BbnEvent) {
+	seen := make(map[string]bool)
+	var stakingTxHashes []string
+	for _, event := range events {
+		stakingTxHash, err := types.NewStakingTxHash(eventStakingTxHash(event.Event))
+		if err != nil || seen[stakingTxHash.String()] {
+			continue
+		}
+		seen[stakingTxHash.String()] = true
+		stakingTxHashes = append(stakingTxHashes, stakingTxHash.String())
+	}
+	if len(stakingTxHashes) == 0 {
+		return
+	}
+
+	if _, err := s.db.GetBTCDelegationsByStakingTxHashes(ctx, stakingTxHashes); err != nil {
+		logging.BlockProcessor.FromContext(ctx).Warn().Err(err).
+			Int("delegations", len(stakingTxHashes)).
+			Msg("failed to prime the delegation memo of the BBN block")
+	}
+}
+
+// deadLetterBbnEvent sets aside the event at the index of the block, failed
+// with a permanent error, for the block processing to go on. The event is
+// marked processed along with its dead letter, and an alert is raised once
+// both are saved.
+func (s *Service) deadLetterBbnEvent(
+	ctx context.Context,
+	height uint64,
+	index int,
+	event BbnEvent,
+	stakingTxHash string,
+	marker *model.BbnProcessingMarker,
+	processErr error,
+) error {
+	deadLetter := model.NewBbnEventDeadLetter(
+		height, index, event.Event.Type, stakingTxHash,
+		s.eventCapture.rawAttributes(event), processErr, time.Now().Unix(),
+	)
+	if err := s.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.db.SaveBbnEventDeadLetter(txCtx, deadLetter); err != nil {
+			return err
+		}
+		return s.markBbnEventProcessed(txCtx, height, index, marker)
+	}); err != nil {
+		return err
 	}
+
+	logging.BlockProcessor.FromContext(ctx).Error().Err(processErr).
+		Int("event_index", index).
+		Msg("BBN event failed with a permanent error, dead-lettered")
+	s.alerter.Alert(ctx, alerting.SeverityCritical, "BBN event dead-lettered", map[string]string{
+		"height":      strconv.FormatUint(height, 10),
+		"event_index": strconv.Itoa(index),
+		"event_type":  event.Event.Type,
+		"staking_tx":  stakingTxHash,
+		"error":       processErr.Error(),
+	})
+	return nil
 }
 
 // getEventsFromBlock fetches the events for a given block by its block height
@@ -103,7 +603,6 @@ func (s *Service) processBlocksSequentially(ctx context.Context) *types.Error {
 func (s *Service) getEventsFromBlock(
 	ctx context.Context, blockHeight int64,
 ) ([]BbnEvent, *types.Error) {
-	events := make([]BbnEvent, 0)
 	blockResult, err := s.bbn.GetBlockResults(ctx, &blockHeight)
 	if err != nil {
 		return nil, types.NewError(
@@ -112,6 +611,26 @@ func (s *Service) getEventsFromBlock(
 			fmt.Errorf("failed to get block results: %w", err),
 		)
 	}
+	return eventsFromBlockResults(ctx, blockHeight, blockResult), nil
+}
+
+// takePrefetchedBbnBlock returns the prefetched results of the block of the
+// processing marker, if any
+func (s *Service) takePrefetchedBbnBlock(
+	ctx context.Context, marker *model.BbnProcessingMarker,
+) (*ctypes.ResultBlockResults, bool) {
+	if marker == nil {
+		return nil, false
+	}
+	return s.bbnPrefetcher.take(ctx, marker.Height, marker.BlockHash)
+}
+
+// eventsFromBlockResults returns the transaction-level and then the
+// finalize-block-level events of the block results
+func eventsFromBlockResults(
+	ctx context.Context, blockHeight int64, blockResult *ctypes.ResultBlockResults,
+) []BbnEvent {
+	events := make([]BbnEvent, 0)
 	// Append transaction-level events
 	for _, txResult := range blockResult.TxsResults {
 		for _, event := range txResult.Events {
@@ -122,8 +641,8 @@ func (s *Service) getEventsFromBlock(
 	for _, event := range blockResult.FinalizeBlockEvents {
 		events = append(events, NewBbnEvent(BlockCategory, event))
 	}
-	log.Debug().Msgf("Fetched %d events from block %d", len(events), blockHeight)
-	return events, nil
+	logging.BlockProcessor.FromContext(ctx).Debug().Msgf("Fetched %d events from block %d", len(events), blockHeight)
+	return events
 }
 
 func (s *Service) getLatestHeight(initialHeight int64) int64 {
@@ -132,6 +651,14 @@ func (s *Service) getLatestHeight(initialHeight int64) int64 {
 	for {
 		select {
 		case newHeight := <-s.latestHeightChan:
+			// A lower height comes from a lagging node, keep the highest one
+			if newHeight < latestHeight {
+				logging.BlockProcessor.Logger().Warn().
+					Int64("height", newHeight).
+					Int64("latest_height", latestHeight).
+					Msg("BBN latest height went backwards, ignoring it")
+				continue
+			}
 			latestHeight = newHeight
 		default:
 			// No more values in channel, return the latest height
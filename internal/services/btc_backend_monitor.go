@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// btcTipTracker tracks the height of the BTC tip notified by the BTC notifier
+type btcTipTracker struct {
+	mu     sync.Mutex
+	height uint64
+}
+
+// observe records the notified block height and returns whether it advanced
+// the tip
+func (t *btcTipTracker) observe(height uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if height <= t.height {
+		return false
+	}
+	t.height = height
+	return true
+}
+
+func (t *btcTipTracker) get() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.height
+}
+
+// StartBtcBackendMonitor tracks the BTC tip through the block notifications
+// of the BTC notifier, and periodically compares it with the BTC tip known to
+// the BBN light client, telling which of the BTC backend or the BBN chain is
+// stalled when the expiries stop.
+func (s *Service) StartBtcBackendMonitor(ctx context.Context) {
+	s.supervisor.Go(ctx, "btc_block_epochs", listenerPolicy, s.watchBtcBlockEpochs)
+
+	btcBackendPoller := s.newPoller(
+		"btc_backend_monitor",
+		s.cfg.Poller.BtcBackendMonitorInterval,
+		s.checkBtcLightClientTip,
+	)
+	s.startPoller(ctx, btcBackendPoller)
+}
+
+// watchBtcBlockEpochs registers for the BTC block notifications and tracks
+// the notified tip until the service quits. The notifications closing fail
+// the watch, to register again.
+func (s *Service) watchBtcBlockEpochs(ctx context.Context) error {
+	blockEpochs, err := s.btcNotifier.RegisterBlockEpochNtfn(nil)
+	if err != nil {
+		return fmt.Errorf("failed to register for BTC block notifications: %w", err)
+	}
+	s.wg.Add(1)
+	defer s.wg.Done()
+	defer blockEpochs.Cancel()
+
+	for {
+		select {
+		case epoch, ok := <-blockEpochs.Epochs:
+			if !ok {
+				select {
+				case <-s.quit:
+					return nil
+				default:
+					return errors.New("BTC block notifications closed")
+				}
+			}
+			height := uint64(epoch.Height)
+			metrics.RecordBtcBlockNotification(
+				btcclient.BlockNotificationSourcePoll, height, s.btcTip.observe(height),
+			)
+		case <-s.quit:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// checkBtcLightClientTip publishes the BTC tip known to the BBN light client
+// and how far the tracked BTC tip is ahead of it
+func (s *Service) checkBtcLightClientTip(ctx context.Context) *types.Error {
+	lightClientTip, err := s.bbn.GetBTCLightClientTipHeight(ctx)
+	if err != nil {
+		return types.NewError(
+			http.StatusInternalServerError,
+			types.ClientRequestError,
+			fmt.Errorf("failed to get the BBN btclightclient tip: %w", err),
+		)
+	}
+	trackedTip := s.btcTip.get()
+	if trackedTip == 0 {
+		// No block notified yet
+		return nil
+	}
+
+	metrics.RecordBtcLightClientTip(lightClientTip, int64(trackedTip)-int64(lightClientTip))
+	return nil
+}
+
+// startSpendWatch runs the watch of a registered spend notification, counted
+// as outstanding until it returns
+func (s *Service) startSpendWatch(watch func()) {
+	s.wg.Add(1)
+	metrics.RecordBtcSpendWatchStarted()
+	go func() {
+		defer metrics.RecordBtcSpendWatchEnded()
+		watch()
+	}()
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBtcLightClientTip(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetBTCLightClientTipHeight", ctx).Return(uint64(805), nil)
+	service := NewService(&config.Config{}, nil, nil, nil, bbnMock, nil)
+
+	require.True(t, service.btcTip.observe(800))
+	// a notification of a lower block, as on a reorg, does not move the tip
+	require.False(t, service.btcTip.observe(799))
+	require.Nil(t, service.checkBtcLightClientTip(ctx))
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(), "indexer_bbn_btc_light_client_tip_height 805")
+	// the BTC notifier lags behind the BBN light client
+	require.Contains(t, rec.Body.String(), "indexer_btc_tip_lead_over_light_client_blocks -5")
+}
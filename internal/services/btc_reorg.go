@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *Service) StartBtcReorgChecker(ctx context.Context) {
+	btcReorgCheckerPoller := s.newPoller(
+		"btc_reorg_checker",
+		s.cfg.Poller.BtcReorgCheckerPollingInterval,
+		s.checkBtcReorg,
+		criticalPoller(5*s.cfg.Poller.BtcReorgCheckerPollingInterval),
+	)
+	s.startPoller(ctx, btcReorgCheckerPoller)
+}
+
+// checkBtcReorg extends the processed BTC header chain up to the BTC tip. If
+// the stored chain no longer matches the BTC node, it walks back to the fork
+// point and rolls back the delegation changes derived from the orphaned blocks.
+// Staking tx confirmations are not rolled back here as they are reported by
+// Babylon only once k-deep in its BTC light client.
+func (s *Service) checkBtcReorg(ctx context.Context) *types.Error {
+	tipHeight, err := s.btc.GetTipHeight()
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get BTC tip height: %w", err),
+		)
+	}
+
+	maxReorgDepth := s.cfg.BTC.MaxReorgDepth
+	latestHeader, err := s.db.GetLatestBTCHeader(ctx)
+	if err != nil {
+		if !db.IsNotFoundError(err) {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get latest BTC header: %w", err),
+			)
+		}
+		// Nothing processed yet, start tracking the reorg window below the tip
+		startHeight := uint64(0)
+		if tipHeight > maxReorgDepth {
+			startHeight = tipHeight - maxReorgDepth
+		}
+		return s.saveBtcHeaders(ctx, startHeight, tipHeight)
+	}
+
+	forkHeight, err := s.findBtcForkHeight(ctx, latestHeader.Height, tipHeight)
+	if err != nil {
+		return types.NewInternalServiceError(err)
+	}
+
+	if forkHeight < latestHeader.Height {
+		reorgDepth := latestHeader.Height - forkHeight
+		log.Warn().
+			Uint64("fork_height", forkHeight).
+			Uint64("reorg_depth", reorgDepth).
+			Msg("BTC reorg detected")
+
+		if err := s.rollbackBtcReorg(ctx, forkHeight); err != nil {
+			return err
+		}
+	}
+
+	if err := s.saveBtcHeaders(ctx, forkHeight+1, tipHeight); err != nil {
+		return err
+	}
+
+	// Headers and changes deeper than the reorg limit are never rolled back
+	if tipHeight > maxReorgDepth {
+		pruneHeight := tipHeight - maxReorgDepth
+		if err := s.db.DeleteBTCHeadersBelow(ctx, pruneHeight); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to prune BTC headers: %w", err),
+			)
+		}
+		if err := s.db.DeleteBTCDerivedChangesBelow(ctx, pruneHeight); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to prune BTC derived changes: %w", err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// findBtcForkHeight returns the highest height at which the stored header
+// matches the BTC node. It returns an error if the fork point is deeper than
+// the configured reorg limit.
+func (s *Service) findBtcForkHeight(
+	ctx context.Context, latestHeight, tipHeight uint64,
+) (uint64, error) {
+	maxReorgDepth := s.cfg.BTC.MaxReorgDepth
+	height := min(latestHeight, tipHeight)
+
+	for {
+		if latestHeight-height > maxReorgDepth {
+			log.Error().
+				Uint64("latest_height", latestHeight).
+				Uint64("max_reorg_depth", maxReorgDepth).
+				Msg("BTC reorg is deeper than the configured limit, refusing to roll back")
+			s.alerter.Alert(ctx, alerting.SeverityCritical, "BTC reorg deeper than the limit", map[string]string{
+				"latest_height":   strconv.FormatUint(latestHeight, 10),
+				"tip_height":      strconv.FormatUint(tipHeight, 10),
+				"max_reorg_depth": strconv.FormatUint(maxReorgDepth, 10),
+			})
+			return 0, fmt.Errorf(
+				"%w: reorg below height %d exceeds max depth %d",
+				types.ErrBtcReorgTooDeep, latestHeight, maxReorgDepth,
+			)
+		}
+
+		storedHeader, err := s.db.GetBTCHeaderByHeight(ctx, height)
+		if err != nil {
+			if db.IsNotFoundError(err) {
+				// Nothing stored at or below this height, so there is no
+				// processed block left that could have been reorged out
+				return height, nil
+			}
+			return 0, fmt.Errorf("failed to get BTC header at height %d: %w", height, err)
+		}
+
+		chainHeader, err := s.btc.GetBlockHeaderByHeight(height)
+		if err != nil {
+			return 0, err
+		}
+
+		if chainHeader.BlockHash().String() == storedHeader.Hash {
+			return height, nil
+		}
+
+		if height == 0 {
+			return 0, fmt.Errorf("BTC genesis block does not match the stored header")
+		}
+		height--
+	}
+}
+
+// rollbackBtcReorg undoes the delegation changes derived from BTC blocks above
+// the fork height and re-registers the spend notifications of the affected
+// delegations, so that spends included again in the new chain are picked up.
+func (s *Service) rollbackBtcReorg(ctx context.Context, forkHeight uint64) *types.Error {
+	ctx = audit.WithTrigger(ctx, audit.Trigger{Type: audit.TriggerBtcReorg, BtcHeight: forkHeight})
+	changes, err := s.db.RollbackBTCDerivedChanges(ctx, forkHeight)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to roll back BTC derived changes: %w", err),
+		)
+	}
+
+	if err := s.db.DeleteBTCHeadersAbove(ctx, forkHeight); err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to delete orphaned BTC headers: %w", err),
+		)
+	}
+
+	rolledBack := make(map[string]struct{})
+	for _, change := range changes {
+		if _, ok := rolledBack[change.StakingTxHashHex]; ok {
+			continue
+		}
+		rolledBack[change.StakingTxHashHex] = struct{}{}
+
+		delegationCtx := logging.WithStakingTxHash(ctx, change.StakingTxHashHex)
+		delegation, err := s.db.GetBTCDelegationByStakingTxHash(delegationCtx, change.StakingTxHashHex)
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
+			)
+		}
+
+		logging.FromContext(delegationCtx).Info().
+			Str("state", delegation.State.String()).
+			Uint64("fork_height", forkHeight).
+			Msg("rolled back BTC derived changes of delegation")
+
+		if err := s.recordRollbackTransition(delegationCtx, delegation, forkHeight); err != nil {
+			return types.NewInternalServiceError(err)
+		}
+
+		if !utils.Contains(types.QualifiedStatesForWithdrawn(), delegation.State) {
+			continue
+		}
+
+		if err := s.registerStakingSpendNotification(
+			delegationCtx,
+			delegation.StakingTxHashHex,
+			delegation.StakingTxHex,
+			delegation.StakingOutputIdx,
+			delegation.StartHeight,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveBtcHeaders stores the BTC headers in the given height range, checking
+// that each of them extends the previously stored header.
+func (s *Service) saveBtcHeaders(ctx context.Context, fromHeight, toHeight uint64) *types.Error {
+	for height := fromHeight; height <= toHeight; height++ {
+		header, err := s.btc.GetBlockHeaderByHeight(height)
+		if err != nil {
+			return types.NewInternalServiceError(err)
+		}
+
+		if height > 0 {
+			prevHeader, err := s.db.GetBTCHeaderByHeight(ctx, height-1)
+			if err != nil && !db.IsNotFoundError(err) {
+				return types.NewInternalServiceError(
+					fmt.Errorf("failed to get BTC header at height %d: %w", height-1, err),
+				)
+			}
+			if prevHeader != nil && prevHeader.Hash != header.PrevBlock.String() {
+				// The chain changed while we were reading it, the next poll
+				// will detect the reorg and roll it back
+				return types.NewInternalServiceError(
+					fmt.Errorf("BTC header at height %d does not extend the stored chain", height),
+				)
+			}
+		}
+
+		if err := s.db.SaveBTCHeader(ctx, model.NewBTCHeader(height, header)); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to save BTC header at height %d: %w", height, err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// recordBTCDerivedChange journals a delegation change derived from a BTC block
+// before it is applied, so that it can be undone if the block is reorged out.
+func (s *Service) recordBTCDerivedChange(
+	ctx context.Context, change *model.BTCDerivedChange,
+) error {
+	if err := s.db.SaveBTCDerivedChange(ctx, change); err != nil {
+		return fmt.Errorf("failed to record BTC derived change: %w", err)
+	}
+	return nil
+}
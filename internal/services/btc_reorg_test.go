@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testMaxReorgDepth = 5
+
+// reorgTestEnv wires a service to a simulated BTC chain and an in-memory header store
+type reorgTestEnv struct {
+	service          *Service
+	chain            *fixtures.BtcChain
+	headers          map[uint64]*model.BTCHeader
+	rolledBackHeight []uint64
+	alerter          *fakeAlerter
+}
+
+func newReorgTestEnv(t *testing.T, chain *fixtures.BtcChain) *reorgTestEnv {
+	env := &reorgTestEnv{
+		chain:   chain,
+		headers: make(map[uint64]*model.BTCHeader),
+		alerter: &fakeAlerter{},
+	}
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetLatestBTCHeader", mock.Anything).Return(
+		func(ctx context.Context) (*model.BTCHeader, error) {
+			var latest *model.BTCHeader
+			for _, header := range env.headers {
+				if latest == nil || header.Height > latest.Height {
+					latest = header
+				}
+			}
+			if latest == nil {
+				return nil, &db.NotFoundError{Key: "latest", Message: "no BTC header"}
+			}
+			return latest, nil
+		},
+	).Maybe()
+	dbMock.On("GetBTCHeaderByHeight", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height uint64) (*model.BTCHeader, error) {
+			header, ok := env.headers[height]
+			if !ok {
+				return nil, &db.NotFoundError{Key: strconv.FormatUint(height, 10), Message: "no BTC header"}
+			}
+			return header, nil
+		},
+	).Maybe()
+	dbMock.On("SaveBTCHeader", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, header *model.BTCHeader) error {
+			env.headers[header.Height] = header
+			return nil
+		},
+	).Maybe()
+	dbMock.On("DeleteBTCHeadersAbove", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height uint64) error {
+			for h := range env.headers {
+				if h > height {
+					delete(env.headers, h)
+				}
+			}
+			return nil
+		},
+	).Maybe()
+	dbMock.On("DeleteBTCHeadersBelow", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height uint64) error {
+			for h := range env.headers {
+				if h < height {
+					delete(env.headers, h)
+				}
+			}
+			return nil
+		},
+	).Maybe()
+	dbMock.On("DeleteBTCDerivedChangesBelow", mock.Anything, mock.Anything).Return(nil).Maybe()
+	dbMock.On("RollbackBTCDerivedChanges", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, forkHeight uint64) ([]*model.BTCDerivedChange, error) {
+			env.rolledBackHeight = append(env.rolledBackHeight, forkHeight)
+			return nil, nil
+		},
+	).Maybe()
+
+	env.service = &Service{
+		cfg: &config.Config{
+			BTC: config.BTCConfig{MaxReorgDepth: testMaxReorgDepth},
+		},
+		db:      dbMock,
+		btc:     chain,
+		alerter: env.alerter,
+	}
+
+	return env
+}
+
+// reorg replaces the blocks of the simulated chain above the fork height by
+// blocks of another branch up to the new tip height
+func (env *reorgTestEnv) reorg(forkHeight, tipHeight uint64) {
+	env.chain = env.chain.Fork(int32(forkHeight), 1)
+	env.chain.Extend(int32(tipHeight))
+	env.service.btc = env.chain
+}
+
+// requireHeadersMatchChain checks the stored headers belong to the simulated
+// chain, reach its tip and do not go deeper than the reorg window
+func (env *reorgTestEnv) requireHeadersMatchChain(t *testing.T) {
+	tip := uint64(env.chain.TipHeight())
+	require.Contains(t, env.headers, tip)
+	for height, header := range env.headers {
+		require.LessOrEqual(t, height, tip)
+		require.GreaterOrEqual(t, height, tip-testMaxReorgDepth)
+		require.Equal(t, env.chain.Block(int32(height)).BlockHash().String(), header.Hash)
+	}
+}
+
+func TestCheckBtcReorgInitialSync(t *testing.T) {
+	env := newReorgTestEnv(t, fixtures.NewBtcChain(0, 20))
+	require.Nil(t, env.service.checkBtcReorg(context.Background()))
+
+	env.requireHeadersMatchChain(t)
+	require.Len(t, env.headers, testMaxReorgDepth+1)
+	require.Empty(t, env.rolledBackHeight)
+}
+
+func TestCheckBtcReorgExtendsChain(t *testing.T) {
+	env := newReorgTestEnv(t, fixtures.NewBtcChain(0, 20))
+	require.Nil(t, env.service.checkBtcReorg(context.Background()))
+
+	env.chain.Extend(23)
+	require.Nil(t, env.service.checkBtcReorg(context.Background()))
+
+	env.requireHeadersMatchChain(t)
+	require.Empty(t, env.rolledBackHeight)
+}
+
+func TestCheckBtcReorgRollsBackFork(t *testing.T) {
+	testCases := []struct {
+		name          string
+		forkHeight    uint64
+		newTipHeight  uint64
+		expectedFork  uint64
+		expectedError error
+	}{
+		{"longer chain", 17, 22, 17, nil},
+		{"same length chain", 19, 20, 19, nil},
+		{"shorter chain", 18, 19, 18, nil},
+		{"reorg at the depth limit", 15, 21, 15, nil},
+		{"reorg deeper than the limit", 14, 21, 0, types.ErrBtcReorgTooDeep},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newReorgTestEnv(t, fixtures.NewBtcChain(0, 20))
+			require.Nil(t, env.service.checkBtcReorg(context.Background()))
+			storedBeforeReorg := len(env.headers)
+
+			env.reorg(tc.forkHeight, tc.newTipHeight)
+			err := env.service.checkBtcReorg(context.Background())
+
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.ErrorIs(t, err, tc.expectedError)
+				require.Empty(t, env.rolledBackHeight)
+				require.Len(t, env.headers, storedBeforeReorg)
+				require.Equal(t, []string{"BTC reorg deeper than the limit"}, env.alerter.titles)
+				return
+			}
+
+			require.Nil(t, err)
+			require.Equal(t, []uint64{tc.expectedFork}, env.rolledBackHeight)
+			env.requireHeadersMatchChain(t)
+		})
+	}
+}
+
+func TestCheckBtcReorgIsIdempotentAfterRollback(t *testing.T) {
+	env := newReorgTestEnv(t, fixtures.NewBtcChain(0, 20))
+	require.Nil(t, env.service.checkBtcReorg(context.Background()))
+
+	env.reorg(18, 21)
+	require.Nil(t, env.service.checkBtcReorg(context.Background()))
+	require.Nil(t, env.service.checkBtcReorg(context.Background()))
+
+	// The second poll must not roll back again
+	require.Equal(t, []uint64{18}, env.rolledBackHeight)
+	env.requireHeadersMatchChain(t)
+}
+
+// TestCheckBtcReorgReappliesOrphanedWithdrawal confirms the withdrawal of a
+// delegation in a block that a 3-block reorg orphans, the withdrawal being
+// included again higher on the new branch
+func TestCheckBtcReorgReappliesOrphanedWithdrawal(t *testing.T) {
+	ctx := context.Background()
+	spends := newSpendPathFixture(t)
+	delegation := spends.delegation
+	delegation.State = types.StateWithdrawable
+	delegation.SubState = types.SubStateTimelock
+	withdrawalTx := spends.stakingWithdrawalTx
+
+	database := inmemory.New()
+	require.NoError(t, database.SaveStakingParams(ctx, delegation.ParamsVersion, spends.params))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+
+	chain := fixtures.NewBtcChain(0, 100)
+	service := newSpendPathService()
+	service.db = database
+	service.btc = chain
+	service.cfg.BTC.MaxReorgDepth = testMaxReorgDepth
+	require.Nil(t, service.checkBtcReorg(ctx))
+
+	// confirmSpend processes the spend of the staking output by the withdrawal
+	// tx at the height the chain includes it
+	confirmSpend := func(chain *fixtures.BtcChain) uint32 {
+		height, ok := chain.TxHeight(withdrawalTx.TxHash())
+		require.True(t, ok)
+		require.NoError(t, service.handleSpendingStakingTransaction(
+			ctx, withdrawalTx, 0, uint32(height), delegation.StakingTxHashHex,
+		))
+		stored, err := database.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+		require.NoError(t, err)
+		require.Equal(t, types.StateWithdrawn, stored.State)
+		require.Equal(t, types.SubStateTimelock, stored.SubState)
+		return uint32(height)
+	}
+
+	chain.MineBlock(withdrawalTx)
+	chain.Extend(103)
+	require.Nil(t, service.checkBtcReorg(ctx))
+	require.Equal(t, uint32(101), confirmSpend(chain))
+
+	// The new branch forks below the block of the withdrawal, which it
+	// includes two blocks higher
+	newChain := chain.Fork(100, 1)
+	newChain.Extend(102)
+	newChain.MineBlock(withdrawalTx)
+	newChain.Extend(104)
+	service.btc = newChain
+	require.Nil(t, service.checkBtcReorg(ctx))
+
+	stored, err := database.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
+	require.NoError(t, err)
+	require.Equal(t, types.StateWithdrawable, stored.State)
+	require.Equal(t, types.SubStateTimelock, stored.SubState)
+	header, err := database.GetBTCHeaderByHeight(ctx, 103)
+	require.NoError(t, err)
+	require.Equal(t, newChain.Block(103).BlockHash().String(), header.Hash)
+
+	require.Equal(t, uint32(103), confirmSpend(newChain))
+
+	transitions, err := database.GetDelegationStateTransitions(ctx, delegation.StakingTxHashHex)
+	require.NoError(t, err)
+	require.Len(t, transitions, 3)
+	for i, expected := range []struct {
+		from, to  types.DelegationState
+		trigger   string
+		btcHeight uint64
+	}{
+		{types.StateWithdrawable, types.StateWithdrawn, model.StateTransitionTriggerBtcSpend, 101},
+		{types.StateWithdrawn, types.StateWithdrawable, model.StateTransitionTriggerBtcReorg, 100},
+		{types.StateWithdrawable, types.StateWithdrawn, model.StateTransitionTriggerBtcSpend, 103},
+	} {
+		require.Equal(t, expected.from, transitions[i].FromState)
+		require.Equal(t, expected.to, transitions[i].ToState)
+		require.Equal(t, expected.trigger, transitions[i].Trigger)
+		require.Equal(t, expected.btcHeight, transitions[i].BtcHeight)
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+const (
+	// bootstrapFpPageLimit is the number of chain finality providers synced
+	// per page
+	bootstrapFpPageLimit = 100
+	// bootstrapRetryInterval is the delay before retrying a failed bootstrap
+	bootstrapRetryInterval = 10 * time.Second
+)
+
+// bootstrapChainState syncs the finality providers and params of the BBN
+// chain before any BBN block is processed, as the delegations of the
+// processed blocks may reference finality providers and params versions
+// registered below the start height. A failed bootstrap is retried until it
+// completes, resuming from its saved progress. It returns false if the
+// context is cancelled first.
+func (s *Service) bootstrapChainState(ctx context.Context) bool {
+	for {
+		err := s.runBootstrap(ctx)
+		if err == nil {
+			s.health.setChainStateBootstrapped()
+			return true
+		}
+		log.Error().Err(err).
+			Dur("retry_in", bootstrapRetryInterval).
+			Msg("chain state bootstrap failed, will resume")
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(bootstrapRetryInterval):
+		}
+	}
+}
+
+// runBootstrap runs the bootstrap from its saved progress: the params first,
+// then the pages of the chain finality providers, the progress being saved
+// after each step
+func (s *Service) runBootstrap(ctx context.Context) *types.Error {
+	ctx = audit.WithTrigger(ctx, audit.Trigger{Type: audit.TriggerBootstrap})
+	bootstrap, err := s.db.GetBootstrap(ctx)
+	if err != nil {
+		if !db.IsNotFoundError(err) {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get bootstrap progress: %w", err),
+			)
+		}
+		bootstrap = &model.Bootstrap{StartedAt: time.Now().Unix()}
+		log.Info().Msg("starting the chain state bootstrap")
+	}
+	if bootstrap.IsCompleted() {
+		log.Info().
+			Time("completed_at", time.Unix(bootstrap.CompletedAt, 0)).
+			Msg("chain state bootstrap already completed")
+		return nil
+	}
+
+	if !bootstrap.ParamsSynced {
+		if err := s.fetchAndSaveParams(ctx); err != nil {
+			return err
+		}
+		bootstrap.ParamsSynced = true
+		if err := s.saveBootstrap(ctx, bootstrap); err != nil {
+			return err
+		}
+		log.Info().Msg("bootstrap synced the staking and checkpoint params")
+	}
+
+	for {
+		done, err := s.bootstrapFinalityProviders(ctx, bootstrap)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+
+	bootstrap.CompletedAt = time.Now().Unix()
+	if err := s.saveBootstrap(ctx, bootstrap); err != nil {
+		return err
+	}
+	log.Info().
+		Uint64("finality_providers", bootstrap.FinalityProvidersSynced).
+		Dur("duration", time.Since(time.Unix(bootstrap.StartedAt, 0))).
+		Msg("chain state bootstrap completed")
+	return nil
+}
+
+// bootstrapFinalityProviders saves the missing finality providers of the
+// page of the chain ones at the cursor of the bootstrap, and moves the cursor
+// to the next page. The finality providers stored already are left as they
+// are, their BBN events applying the later changes. It returns true once the
+// last page is synced.
+func (s *Service) bootstrapFinalityProviders(ctx context.Context, bootstrap *model.Bootstrap) (bool, *types.Error) {
+	pageKey, decodeErr := hex.DecodeString(bootstrap.FinalityProvidersCursor)
+	if decodeErr != nil {
+		return false, types.NewInternalServiceError(
+			fmt.Errorf("invalid bootstrap cursor %s: %w", bootstrap.FinalityProvidersCursor, decodeErr),
+		)
+	}
+	chainFps, nextKey, err := s.bbn.GetFinalityProviders(ctx, pageKey, bootstrapFpPageLimit)
+	if err != nil {
+		return false, types.NewInternalServiceError(
+			fmt.Errorf("failed to get finality providers: %w", err),
+		)
+	}
+
+	for _, chainFp := range chainFps {
+		if err := s.db.SaveNewFinalityProvider(
+			ctx, model.FromBbnFinalityProvider(chainFp),
+		); err != nil && !db.IsDuplicateKeyError(err) {
+			return false, types.NewInternalServiceError(
+				fmt.Errorf("failed to save new finality provider: %w", err),
+			)
+		}
+	}
+
+	bootstrap.FinalityProvidersSynced += uint64(len(chainFps))
+	bootstrap.FinalityProvidersCursor = hex.EncodeToString(nextKey)
+	if err := s.saveBootstrap(ctx, bootstrap); err != nil {
+		return false, err
+	}
+	log.Info().
+		Uint64("finality_providers", bootstrap.FinalityProvidersSynced).
+		Msg("bootstrap synced a page of finality providers")
+	return len(nextKey) == 0, nil
+}
+
+func (s *Service) saveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) *types.Error {
+	if err := s.db.SaveBootstrap(ctx, bootstrap); err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to save bootstrap progress: %w", err),
+		)
+	}
+	return nil
+}
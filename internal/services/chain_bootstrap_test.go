@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+// TestBootstrapResumesAfterFailure fails the bootstrap midway through the
+// finality providers, and checks that it resumes from the page it stopped at
+// and reports the indexer ready only once completed
+func TestBootstrapResumesAfterFailure(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := inmemory.New()
+	bbnClient := fixtures.NewBbnClient()
+	bbnClient.StakingParams[fixtures.StakingParamsVersion] = fixtures.NewStakingParams()
+	// Three pages, the last one partial
+	const fps = 2*bootstrapFpPageLimit + 50
+	for i := range fps {
+		bbnClient.FinalityProviders = append(bbnClient.FinalityProviders, &bbnclient.FinalityProvider{
+			BtcPk: fmt.Sprintf("%064x", i),
+		})
+	}
+
+	chaos := db.NewChaosDatabase(database)
+	// Fails in the middle of the second page
+	chaos.Inject("SaveNewFinalityProvider", db.Fault{After: bootstrapFpPageLimit + 50, Times: 1, Err: db.NewSteppedDownError()})
+	service := NewService(&config.Config{}, chaos, nil, nil, bbnClient, nil)
+
+	require.NotNil(t, service.runBootstrap(ctx))
+	_, err := service.checkBootstrap(ctx)
+	require.EqualError(t, err, "finality providers and params bootstrap in progress")
+	bootstrap, err := database.GetBootstrap(ctx)
+	require.NoError(t, err)
+	require.True(t, bootstrap.ParamsSynced)
+	require.Equal(t, uint64(bootstrapFpPageLimit), bootstrap.FinalityProvidersSynced)
+	require.False(t, bootstrap.IsCompleted())
+	_, err = database.GetStakingParams(ctx, fixtures.StakingParamsVersion)
+	require.NoError(t, err)
+
+	// The second page is synced again, the finality providers saved before
+	// the failure being skipped
+	require.True(t, service.bootstrapChainState(ctx))
+	require.Equal(t, bootstrapFpPageLimit+51+bootstrapFpPageLimit+50, chaos.Calls("SaveNewFinalityProvider"))
+	bootstrap, err = database.GetBootstrap(ctx)
+	require.NoError(t, err)
+	require.True(t, bootstrap.IsCompleted())
+	require.Equal(t, uint64(fps), bootstrap.FinalityProvidersSynced)
+	require.Empty(t, bootstrap.FinalityProvidersCursor)
+	for _, fp := range bbnClient.FinalityProviders {
+		_, err := database.GetFinalityProviderByBtcPk(ctx, fp.BtcPk)
+		require.NoError(t, err)
+	}
+	// Ready once the block processor catches up
+	_, err = service.checkBootstrap(ctx)
+	require.EqualError(t, err, "BBN block processor has not caught up with the chain tip yet")
+
+	// A completed bootstrap is not run again on restart
+	saves := chaos.Calls("SaveBootstrap")
+	chaos.Inject("SaveBootstrap", db.Fault{Err: errors.New("not saved again")})
+	restarted := NewService(&config.Config{}, chaos, nil, nil, bbnClient, nil)
+	require.True(t, restarted.bootstrapChainState(ctx))
+	require.Equal(t, saves, chaos.Calls("SaveBootstrap"))
+}
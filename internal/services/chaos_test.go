@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cosmos/gogoproto/proto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/dedup"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+// The resilience tests run the services over a ChaosDatabase wrapping the
+// in-memory database, injecting the failures of a crashing indexer or of a
+// Mongo primary stepping down, and check that the state reached once the
+// services resume matches the one of a run without failures.
+
+// chaosDelegations is the number of delegations of the resilience tests
+const chaosDelegations = 3
+
+// crashes runs fn, telling whether it crashed on a call to a ChaosDatabase
+func crashes(t *testing.T, fn func()) (crashed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			_, ok := r.(db.ChaosPanic)
+			require.True(t, ok, "unexpected panic: %v", r)
+			crashed = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// TestBbnBlockProcessingResumesAfterCrash crashes the processing of a BBN block
+// at various calls of its events, and checks that once restarted the indexer
+// completes the block, every event being applied once
+func TestBbnBlockProcessingResumesAfterCrash(t *testing.T) {
+	metrics.Init()
+	tests := []struct {
+		name   string
+		method string
+		fault  db.Fault
+	}{
+		{"before a delegation is saved", "SaveNewBTCDelegation", db.Fault{After: 1}},
+		{"after a delegation is saved", "SaveNewBTCDelegation", db.Fault{After: 1, Applied: true}},
+		{"before a transition is recorded", "SaveDelegationStateTransition", db.Fault{After: 2}},
+		{"after a covenant quorum is applied", "UpdateBTCDelegationState", db.Fault{After: 1, Applied: true}},
+		{"before an event is marked processed", "MarkBbnEventProcessed", db.Fault{After: 3}},
+		{"after an event is marked processed", "MarkBbnEventProcessed", db.Fault{After: 3, Applied: true}},
+		{"before the height is advanced", "UpdateLastProcessedBbnHeight", db.Fault{}},
+		{"after the height is advanced", "UpdateLastProcessedBbnHeight", db.Fault{Applied: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			database := inmemory.New()
+			require.NoError(t, database.SaveStakingParams(ctx, fixtures.StakingParamsVersion, fixtures.NewStakingParams()))
+
+			events := []proto.Message{fixtures.FpCreatedEvent(1)}
+			var delegations []*fixtures.Delegation
+			for i := 1; i <= chaosDelegations; i++ {
+				d := fixtures.NewDelegation(i, 1)
+				delegations = append(delegations, d)
+				events = append(events, d.CreatedEvent())
+			}
+			for _, d := range delegations {
+				events = append(events, d.CovenantQuorumReachedEvent())
+			}
+			bbnClient := fixtures.NewBbnClient(fixtures.NewBlockResults(events...))
+
+			chaos := db.NewChaosDatabase(database)
+			fault := tt.fault
+			fault.Panic = true
+			chaos.Inject(tt.method, fault)
+			service := NewService(&config.Config{}, chaos, nil, nil, bbnClient, nil)
+			require.True(t, crashes(t, func() {
+				_, _ = service.processNextBbnBlock(ctx, 1, "")
+			}))
+
+			// The indexer restarts over the database
+			restarted := NewService(&config.Config{}, database, nil, nil, bbnClient, nil)
+			lastProcessed, err := database.GetLastProcessedBbnBlock(ctx)
+			require.NoError(t, err)
+			recovered, rerr := restarted.recoverBbnBlockProcessing(ctx, lastProcessed)
+			require.Nil(t, rerr)
+			require.Equal(t, uint64(1), recovered.Height)
+
+			lastProcessed, err = database.GetLastProcessedBbnBlock(ctx)
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), lastProcessed.Height)
+			require.Nil(t, lastProcessed.ProcessingMarker)
+
+			_, err = database.GetFinalityProviderByBtcPk(ctx, fixtures.FpBtcPkHexOf(1))
+			require.NoError(t, err)
+			for _, d := range delegations {
+				delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, d.StakingTxHashHex())
+				require.NoError(t, err)
+				require.Equal(t, types.StateVerified, delegation.State)
+
+				// A transition applied right before the crash but not recorded
+				// yet is missing from the history, its replay finding the
+				// delegation moved already; none is recorded twice
+				transitions, err := database.GetDelegationStateTransitions(ctx, d.StakingTxHashHex())
+				require.NoError(t, err)
+				var states []types.DelegationState
+				for _, transition := range transitions {
+					states = append(states, transition.ToState)
+				}
+				require.Contains(t, [][]types.DelegationState{
+					{types.StatePending, types.StateVerified},
+					{types.StatePending},
+					{types.StateVerified},
+				}, states)
+			}
+		})
+	}
+}
+
+// TestExpiryCheckerSurvivesStepdown steps the primary down in the middle of a
+// batch of the expiry checker, and checks that its poller carries on to make
+// every delegation of the batch withdrawable once, with its withdrawable event
+func TestExpiryCheckerSurvivesStepdown(t *testing.T) {
+	metrics.Init()
+	tests := []struct {
+		name   string
+		method string
+		fault  db.Fault
+	}{
+		{"while scanning the timelocks", "ForEachExpiredDelegation", db.Fault{Times: 2}},
+		{"while updating the states", "ApplyBTCDelegationStateChange", db.Fault{After: 5, Times: 3}},
+		{"after updating the states", "ApplyBTCDelegationStateChange", db.Fault{After: 5, Times: 3, Applied: true}},
+		{"while deleting the timelocks", "DeleteExpiredDelegation", db.Fault{After: 5, Times: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			database := inmemory.New()
+			delegations := saveRaceDelegations(t, database, types.StateUnbonding, types.SubStateEarlyUnbonding)
+			for _, d := range delegations {
+				require.NoError(t, database.SaveNewTimeLockExpire(
+					ctx, d.StakingTxHashHex(), 110, types.SubStateEarlyUnbonding,
+				))
+				require.NoError(t, database.SaveDelegationStateTransition(ctx, model.NewBtcStateTransition(
+					d.StakingTxHashHex(), types.StateActive, types.StateUnbonding, types.SubStateEarlyUnbonding,
+					model.StateTransitionTriggerBtcSpend, 105, time.Now().Unix(),
+				)))
+			}
+
+			chaos := db.NewChaosDatabase(database)
+			fault := tt.fault
+			fault.Err = db.NewSteppedDownError()
+			fault.Latency = time.Millisecond
+			chaos.Inject(tt.method, fault)
+			service := NewService(&config.Config{Poller: config.PollerConfig{
+				ExpiryCheckerPollingInterval: 10 * time.Millisecond,
+				ExpiredDelegationsLimit:      raceDelegations,
+			}}, chaos, fixtures.NewBtcChain(0, 120), nil, nil, nil)
+			service.StartExpiryChecker(ctx)
+
+			require.Eventually(t, func() bool {
+				expired, err := database.FindExpiredDelegations(ctx, 120, raceDelegations)
+				return err == nil && len(expired) == 0
+			}, 10*time.Second, 10*time.Millisecond, "the expired timelocks are not all processed")
+			cancel()
+			// The stepdown was hit
+			require.Greater(t, chaos.Calls(tt.method), tt.fault.After)
+
+			for _, d := range delegations {
+				requireStateHistory(t, database, d, types.StateActive,
+					types.StateWithdrawable, types.SubStateEarlyUnbonding,
+					[]types.DelegationState{types.StateUnbonding, types.StateWithdrawable},
+				)
+				events, err := database.GetDelegationOutboxEvents(ctx, d.StakingTxHashHex())
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+				require.Equal(t, model.OutboxEventTypeWithdrawable, events[0].EventType)
+			}
+		})
+	}
+}
+
+// TestOutboxNeverDoubleSends fails the outbox between the write of the events
+// and their marking as sent, and checks that once the relay carries on every
+// event is sent, a consumer deduplicating on the delivery identifiers
+// applying each of them once
+func TestOutboxNeverDoubleSends(t *testing.T) {
+	metrics.Init()
+	tests := []struct {
+		name   string
+		method string
+		fault  db.Fault
+	}{
+		{"the write of an event acknowledged as failed", "ApplyBTCDelegationStateChange",
+			db.Fault{After: 1, Times: 1, Applied: true, Err: db.NewSteppedDownError()}},
+		{"the mark sent stepped down", "MarkOutboxEventSent",
+			db.Fault{After: 1, Times: 1, Err: db.NewSteppedDownError()}},
+		{"the mark sent acknowledged as failed", "MarkOutboxEventSent",
+			db.Fault{After: 1, Times: 1, Applied: true, Err: db.NewSteppedDownError()}},
+		{"a crash between the push and the mark sent", "MarkOutboxEventSent",
+			db.Fault{After: 1, Times: 1, Panic: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			database := inmemory.New()
+			delegations := saveRaceDelegations(t, database, types.StateUnbonding, types.SubStateEarlyUnbonding)
+			for _, d := range delegations {
+				require.NoError(t, database.SaveNewTimeLockExpire(
+					ctx, d.StakingTxHashHex(), 110, types.SubStateEarlyUnbonding,
+				))
+			}
+
+			chaos := db.NewChaosDatabase(database)
+			chaos.Inject(tt.method, tt.fault)
+			emitter := consumer.NewMemoryEmitter()
+			service := NewService(&config.Config{Poller: config.PollerConfig{
+				ExpiredDelegationsLimit: raceDelegations,
+				OutboxRelayMaxAttempts:  3,
+			}}, chaos, fixtures.NewBtcChain(0, 120), nil, nil, emitter)
+
+			// The expiry checker and the relay run until done, the indexer
+			// restarting after a crash
+			for run := 0; run < 5; run++ {
+				if crashes(t, func() {
+					_ = service.checkExpiry(ctx)
+					_, _ = service.relayOutboxEvents(ctx)
+				}) {
+					chaos.Heal()
+				}
+			}
+			require.Greater(t, chaos.Calls(tt.method), tt.fault.After)
+
+			unsent, err := database.GetUnsentOutboxEvents(ctx, 0, "", 2*raceDelegations)
+			require.NoError(t, err)
+			require.Empty(t, unsent)
+			for _, d := range delegations {
+				events, err := database.GetDelegationOutboxEvents(ctx, d.StakingTxHashHex())
+				require.NoError(t, err)
+				require.Len(t, events, 1)
+			}
+
+			// An event pushed again carries the identifiers of its first push,
+			// for the consumer to drop it
+			tracker := dedup.NewTracker(nil)
+			applied := 0
+			for _, emitted := range emitter.Events() {
+				delivery := emitted.Delivery()
+				require.NotEmpty(t, delivery.IdempotencyKey)
+				switch result := tracker.Check(emitted.Event.GetStakingTxHashHex(), delivery.Sequence); result {
+				case dedup.InOrder:
+					tracker.Applied(emitted.Event.GetStakingTxHashHex(), delivery.Sequence)
+					applied++
+				case dedup.Duplicate:
+				default:
+					t.Fatalf("event %s delivered %s", delivery.IdempotencyKey, result)
+				}
+			}
+			require.Equal(t, raceDelegations, applied)
+			require.LessOrEqual(t, len(emitter.Events()), raceDelegations+1)
+		})
+	}
+}
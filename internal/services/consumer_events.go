@@ -3,41 +3,123 @@ package services
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
-	queuecli "github.com/babylonlabs-io/staking-queue-client/client"
 )
 
+// emitActiveDelegationEvent records the active staking event of the delegation
+// in the outbox, from which it is relayed to the queue. An event already
+// recorded by a previous processing of the transition is not recorded twice.
 func (s *Service) emitActiveDelegationEvent(
 	ctx context.Context,
-	stakingTxHashHex string,
-	stakerBtcPkHex string,
-	finalityProviderBtcPksHex []string,
-	stakingAmount uint64,
+	delegation *model.BTCDelegationDetails,
+	stakingStartHeight uint32,
 ) *types.Error {
-	stakingEvent := queuecli.NewActiveStakingEvent(
-		stakingTxHashHex,
-		stakerBtcPkHex,
-		finalityProviderBtcPksHex,
-		stakingAmount,
+	event := model.NewActiveStakingOutboxEvent(delegation, stakingStartHeight, time.Now().UnixNano())
+	if err := s.db.SaveOutboxEvent(ctx, event); err != nil && !db.IsDuplicateKeyError(err) {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to record the staking event in the outbox: %w", err),
+		)
+	}
+	return nil
+}
+
+// emitUnbondingDelegationEvent records the unbonding staking event of the
+// delegation in the outbox, from which it is relayed to the queue. It must only
+// be called once the transition to UNBONDING is applied, so that a replayed
+// transition does not emit the event again.
+func (s *Service) emitUnbondingDelegationEvent(
+	ctx context.Context,
+	delegation *model.BTCDelegationDetails,
+	subState types.DelegationSubState,
+	unbondingTxHashHex string,
+	withdrawableHeight uint32,
+) *types.Error {
+	event := model.NewUnbondingStakingOutboxEvent(
+		delegation, subState, unbondingTxHashHex, withdrawableHeight, time.Now().UnixNano(),
+	)
+	if err := s.db.SaveOutboxEvent(ctx, event); err != nil && !db.IsDuplicateKeyError(err) {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to record the unbonding event in the outbox: %w", err),
+		)
+	}
+	return nil
+}
+
+// emitWithdrawableDelegationEvent records the withdrawable staking event of the
+// delegation in the outbox once its transition to WITHDRAWABLE is applied
+func (s *Service) emitWithdrawableDelegationEvent(
+	ctx context.Context,
+	delegation *model.BTCDelegationSummary,
+	subState types.DelegationSubState,
+	expireHeight uint32,
+) *types.Error {
+	event := model.NewWithdrawableStakingOutboxEvent(delegation, subState, expireHeight, time.Now().UnixNano())
+	if err := s.db.SaveOutboxEvent(ctx, event); err != nil && !db.IsDuplicateKeyError(err) {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to record the withdrawable event in the outbox: %w", err),
+		)
+	}
+	return nil
+}
+
+// emitWithdrawnDelegationEvent records the withdrawn staking event of the
+// delegation in the outbox once its transition to WITHDRAWN is applied
+func (s *Service) emitWithdrawnDelegationEvent(
+	ctx context.Context,
+	delegation *model.BTCDelegationDetails,
+	subState types.DelegationSubState,
+	spendingTxHashHex string,
+	spendingHeight uint32,
+) *types.Error {
+	event := model.NewWithdrawnStakingOutboxEvent(
+		delegation, subState, spendingTxHashHex, spendingHeight, time.Now().UnixNano(),
 	)
+	if err := s.db.SaveOutboxEvent(ctx, event); err != nil && !db.IsDuplicateKeyError(err) {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to record the withdrawn event in the outbox: %w", err),
+		)
+	}
+	return nil
+}
 
-	if err := s.queueManager.PushActiveStakingEvent(&stakingEvent); err != nil {
-		return types.NewInternalServiceError(fmt.Errorf("failed to push the staking event to the queue: %w", err))
+// emitSlashedDelegationEvent records the event of a delegation slashed along
+// with its finality provider in the outbox, relayed to the queue as an
+// unbonding event
+func (s *Service) emitSlashedDelegationEvent(
+	ctx context.Context,
+	delegation *model.BTCDelegationDetails,
+	bbnHeight int64,
+) *types.Error {
+	event := model.NewSlashedStakingOutboxEvent(delegation, bbnHeight, time.Now().UnixNano())
+	if err := s.db.SaveOutboxEvent(ctx, event); err != nil && !db.IsDuplicateKeyError(err) {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to record the slashed event in the outbox: %w", err),
+		)
 	}
 	return nil
 }
 
-func (s *Service) emitUnbondingDelegationEvent(ctx context.Context, delegation *model.BTCDelegationDetails) *types.Error {
-	ev := queuecli.NewUnbondingStakingEvent(
-		delegation.StakingTxHashHex,
-		delegation.StakerBtcPkHex,
-		delegation.FinalityProviderBtcPksHex,
-		delegation.StakingAmount,
+// emitSlashedFundsDelegationEvent records the event of a slashed delegation
+// moving to a sub state of the slashed funds lifecycle in the outbox, once
+// the sub state is applied
+func (s *Service) emitSlashedFundsDelegationEvent(
+	ctx context.Context,
+	delegation *model.BTCDelegationDetails,
+	subState types.DelegationSubState,
+	txHashHex string,
+	btcHeight uint32,
+) *types.Error {
+	event := model.NewSlashedFundsOutboxEvent(
+		delegation, subState, txHashHex, btcHeight, time.Now().UnixNano(),
 	)
-	if err := s.queueManager.PushUnbondingStakingEvent(&ev); err != nil {
-		return types.NewInternalServiceError(fmt.Errorf("failed to push the unbonding event to the queue: %w", err))
+	if err := s.db.SaveOutboxEvent(ctx, event); err != nil && !db.IsDuplicateKeyError(err) {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to record the slashed funds event in the outbox: %w", err),
+		)
 	}
 	return nil
 }
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/tracing"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// covenantSignatureBatch is the covenant signatures received for a delegation
+// in a run of covenant events, with the indexes of their events in the block
+type covenantSignatureBatch struct {
+	stakingTxHash string
+	indexes       []int
+	events        []*bbntypes.EventCovenantSignatureReceived
+
+	// sigs is the signatures of the batch not yet saved, sigEvents holding
+	// the index in the batch of the event of each, unless reading the
+	// delegation failed with readErr, elapsed being the duration of the read
+	sigs      []db.CovenantSigRecord
+	sigEvents []int
+	readErr   *types.Error
+	elapsed   time.Duration
+
+	// errs is the error of each event of the batch once its signatures are
+	// saved, nil until then
+	errs []*types.Error
+}
+
+// isCovenantEvent tells whether the event is one of the covenant committee on
+// a delegation, applied by runs of consecutive ones
+func isCovenantEvent(event BbnEvent) bool {
+	eventType := EventTypes(event.Event.Type)
+	return eventType == EventCovenantSignatureReceived || eventType == EventCovenantQuorumReached
+}
+
+// covenantEventRunEnd returns the index following the run of consecutive
+// covenant events, not yet applied, starting at the index
+func covenantEventRunEnd(events []BbnEvent, start int, marker *model.BbnProcessingMarker) int {
+	end := start
+	for end < len(events) && isCovenantEvent(events[end]) {
+		if marker != nil && marker.IsEventProcessed(end) {
+			break
+		}
+		end++
+	}
+	return end
+}
+
+// applyCovenantEvents applies the run of covenant events of the block from
+// start to end. The signatures are batched by delegation, the delegations
+// being read concurrently by a bounded number of workers. The signatures of a
+// batch are then saved in a single write in the transaction of its first
+// event, so that they are saved along with the event being marked processed,
+// the following events of the batch having nothing left to write. Should the
+// first event fail, its transaction saves none of them and the following
+// events save their own signature. The quorum events are processed in order,
+// after the signatures of their delegation received before them, so that the
+// quorum transition of a delegation does not race its signatures. A
+// retryable failure stops the block processing at its event.
+func (s *Service) applyCovenantEvents(
+	ctx context.Context, height uint64, events []BbnEvent, start, end int, marker *model.BbnProcessingMarker,
+) *types.Error {
+	batches := s.prepareCovenantSignatures(ctx, events, start, end)
+	for i := start; i < end; i++ {
+		event := events[i]
+		process := func(ctx context.Context) *types.Error {
+			return s.processEvent(ctx, event, int64(height))
+		}
+		var elapsed time.Duration
+		// The signatures failing to parse are not batched, processEvent
+		// failing them the same way
+		if batch, ok := batches[i]; ok {
+			switch {
+			case i == batch.indexes[0]:
+				process = func(ctx context.Context) *types.Error {
+					errs := s.saveCovenantSignatureBatch(ctx, height, batch)
+					if errs[0] != nil {
+						return errs[0]
+					}
+					batch.errs = errs
+					return nil
+				}
+				elapsed = batch.elapsed
+			case batch.errs != nil:
+				err := batch.errs[slices.Index(batch.indexes, i)]
+				process = func(context.Context) *types.Error { return err }
+			}
+		}
+		if err := s.applyBbnEvent(ctx, height, i, event, marker, elapsed, process); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareCovenantSignatures batches the covenant signatures of the run of
+// events from start to end by delegation, reading the signatures not yet
+// saved of each, and returns the batch of their events by index in the block
+func (s *Service) prepareCovenantSignatures(
+	ctx context.Context, events []BbnEvent, start, end int,
+) map[int]*covenantSignatureBatch {
+	var batches []*covenantSignatureBatch
+	byDelegation := make(map[string]*covenantSignatureBatch)
+	byIndex := make(map[int]*covenantSignatureBatch)
+	for i := start; i < end; i++ {
+		if EventTypes(events[i].Event.Type) != EventCovenantSignatureReceived {
+			continue
+		}
+		signatureEvent, err := parseEvent[*bbntypes.EventCovenantSignatureReceived](
+			EventCovenantSignatureReceived, events[i].Event,
+		)
+		if err != nil {
+			continue
+		}
+		batch, ok := byDelegation[signatureEvent.StakingTxHash]
+		if !ok {
+			batch = &covenantSignatureBatch{stakingTxHash: signatureEvent.StakingTxHash}
+			byDelegation[signatureEvent.StakingTxHash] = batch
+			batches = append(batches, batch)
+		}
+		batch.indexes = append(batch.indexes, i)
+		batch.events = append(batch.events, signatureEvent)
+		byIndex[i] = batch
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, s.cfg.BBN.GetCovenantSignatureWorkers())
+	for _, batch := range batches {
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(batch *covenantSignatureBatch) {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			batchStart := time.Now()
+			s.prepareCovenantSignatureBatch(ctx, batch)
+			batch.elapsed = time.Since(batchStart)
+		}(batch)
+	}
+	wg.Wait()
+	return byIndex
+}
+
+// prepareCovenantSignatureBatch reads the delegation of the batch for the
+// signatures of the batch not yet saved. The signatures of a covenant member
+// already saved, or received twice, are ignored.
+func (s *Service) prepareCovenantSignatureBatch(ctx context.Context, batch *covenantSignatureBatch) {
+	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, batch.stakingTxHash)
+	if dbErr != nil {
+		batch.readErr = types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr),
+		)
+		return
+	}
+
+	saved := make(map[string]bool)
+	for _, signature := range delegation.CovenantUnbondingSignatures {
+		saved[signature.CovenantBtcPkHex] = true
+	}
+	for k, event := range batch.events {
+		if saved[event.CovenantBtcPkHex] {
+			continue
+		}
+		saved[event.CovenantBtcPkHex] = true
+		batch.sigs = append(batch.sigs, db.CovenantSigRecord{
+			StakingTxHash:    batch.stakingTxHash,
+			CovenantBtcPkHex: event.CovenantBtcPkHex,
+			SignatureHex:     event.CovenantUnbondingSignatureHex,
+		})
+		batch.sigEvents = append(batch.sigEvents, k)
+	}
+}
+
+// saveCovenantSignatureBatch saves the signatures of the batch not yet saved
+// for its delegation in a single write, and returns the error of each event of
+// the batch
+func (s *Service) saveCovenantSignatureBatch(
+	ctx context.Context, height uint64, batch *covenantSignatureBatch,
+) []*types.Error {
+	ctx = audit.WithTrigger(
+		logging.WithBbnEvent(ctx, EventCovenantSignatureReceived.String(), batch.stakingTxHash),
+		audit.Trigger{
+			Type:         audit.TriggerBbnEvent,
+			BbnEventType: EventCovenantSignatureReceived.String(),
+			BbnHeight:    height,
+			TxHash:       batch.stakingTxHash,
+		},
+	)
+	ctx, span := tracing.StartSpan(ctx, "bbn.save_covenant_signatures",
+		attribute.String("bbn.staking_tx_hash", batch.stakingTxHash),
+		attribute.Int("bbn.covenant_signatures", len(batch.events)),
+	)
+
+	errs := make([]*types.Error, len(batch.events))
+	var firstErr *types.Error
+	defer func() { endSpan(span, firstErr) }()
+
+	if batch.readErr != nil {
+		firstErr = batch.readErr
+		for k := range errs {
+			errs[k] = firstErr
+		}
+		return errs
+	}
+	if len(batch.sigs) == 0 {
+		return errs
+	}
+
+	dbErr := s.db.BulkSaveCovenantSignatures(ctx, batch.sigs)
+	if dbErr == nil {
+		return errs
+	}
+	var bulkErr *db.BulkWriteError
+	if !errors.As(dbErr, &bulkErr) {
+		firstErr = covenantSignatureSaveError(batch.stakingTxHash, dbErr)
+		for _, k := range batch.sigEvents {
+			errs[k] = firstErr
+		}
+		return errs
+	}
+	for _, failure := range bulkErr.Failures {
+		err := covenantSignatureSaveError(batch.stakingTxHash, failure.Err)
+		if firstErr == nil {
+			firstErr = err
+		}
+		errs[batch.sigEvents[failure.Index]] = err
+	}
+	return errs
+}
+
+func covenantSignatureSaveError(stakingTxHash string, dbErr error) *types.Error {
+	return types.NewError(
+		http.StatusInternalServerError,
+		types.InternalServiceError,
+		fmt.Errorf(
+			"failed to save BTC delegation unbonding covenant signature: %w for staking tx hash %s",
+			dbErr, stakingTxHash,
+		),
+	)
+}
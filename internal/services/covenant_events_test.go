@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testCovenantTxHashA = strings.Repeat("a", 64)
+	testCovenantTxHashB = strings.Repeat("b", 64)
+)
+
+// covenantSignatureBbnEvent returns the event of the signature of the covenant
+// member received for the delegation
+func covenantSignatureBbnEvent(t *testing.T, stakingTxHash, covenantBtcPkHex string) BbnEvent {
+	return NewBbnEvent(TxCategory, covenantSignatureEvent(t, stakingTxHash, covenantBtcPkHex))
+}
+
+// covenantTestEnv wires a service to delegations A, with no signature, and B,
+// signed by pk-2, recording the batched signature writes
+type covenantTestEnv struct {
+	service *Service
+	dbMock  *mocks.DbInterface
+
+	mu     sync.Mutex
+	writes map[string][]db.CovenantSigRecord
+}
+
+func newCovenantTestEnv(t *testing.T, saveErr func(sigs []db.CovenantSigRecord) error) *covenantTestEnv {
+	metrics.Init()
+	env := &covenantTestEnv{writes: make(map[string][]db.CovenantSigRecord)}
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationsByStakingTxHashes", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testCovenantTxHashA).Return(
+		&model.BTCDelegationDetails{StakingTxHashHex: testCovenantTxHashA}, nil,
+	).Once()
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testCovenantTxHashB).Return(
+		&model.BTCDelegationDetails{
+			StakingTxHashHex: testCovenantTxHashB,
+			CovenantUnbondingSignatures: []model.CovenantSignature{
+				{CovenantBtcPkHex: "pk-2", SignatureHex: "sig-pk-2"},
+			},
+		}, nil,
+	).Once()
+	dbMock.On("BulkSaveCovenantSignatures", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, sigs []db.CovenantSigRecord) error {
+			env.mu.Lock()
+			defer env.mu.Unlock()
+			_, ok := env.writes[sigs[0].StakingTxHash]
+			require.False(t, ok, "signatures of a delegation written twice")
+			env.writes[sigs[0].StakingTxHash] = sigs
+			return saveErr(sigs)
+		},
+	)
+	dbMock.On("MarkBbnHeightProcessed", mock.Anything, mock.Anything).Return(nil).Maybe()
+	expectTransactions(dbMock)
+
+	env.dbMock = dbMock
+	env.service = &Service{
+		cfg:     &config.Config{BBN: config.BBNConfig{CovenantSignatureWorkers: 2}},
+		db:      dbMock,
+		alerter: alerting.NewNoopAlerter(),
+	}
+	return env
+}
+
+func TestApplyCovenantSignaturesBatchedByDelegation(t *testing.T) {
+	env := newCovenantTestEnv(t, func([]db.CovenantSigRecord) error { return nil })
+	events := []BbnEvent{
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashB, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-2"),
+		// received twice, or already saved, the signature is ignored
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashB, "pk-2"),
+	}
+
+	require.Nil(t, env.service.applyBbnBlockEvents(context.Background(), 10, events, nil))
+
+	// a single write per delegation, with its new signatures in event order
+	require.Equal(t, map[string][]db.CovenantSigRecord{
+		testCovenantTxHashA: {
+			{StakingTxHash: testCovenantTxHashA, CovenantBtcPkHex: "pk-1", SignatureHex: "sig-pk-1"},
+			{StakingTxHash: testCovenantTxHashA, CovenantBtcPkHex: "pk-2", SignatureHex: "sig-pk-2"},
+		},
+		testCovenantTxHashB: {
+			{StakingTxHash: testCovenantTxHashB, CovenantBtcPkHex: "pk-1", SignatureHex: "sig-pk-1"},
+		},
+	}, env.writes)
+}
+
+func TestApplyCovenantSignaturesItemFailure(t *testing.T) {
+	env := newCovenantTestEnv(t, func(sigs []db.CovenantSigRecord) error {
+		if sigs[0].StakingTxHash != testCovenantTxHashA {
+			return nil
+		}
+		return &db.BulkWriteError{Failures: []db.BulkWriteFailure{{
+			Index: 1, Key: testCovenantTxHashA, Err: types.NewPermanentError(errors.New("corrupt signature")),
+		}}}
+	})
+	var deadLetters []*model.BbnEventDeadLetter
+	env.dbMock.On("SaveBbnEventDeadLetter", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, deadLetter *model.BbnEventDeadLetter) error {
+			deadLetters = append(deadLetters, deadLetter)
+			return nil
+		},
+	)
+	events := []BbnEvent{
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashB, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-3"),
+	}
+
+	require.Nil(t, env.service.applyBbnBlockEvents(context.Background(), 10, events, nil))
+
+	// the failed item of the batch is the event of its signature
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, 2, deadLetters[0].EventIndex)
+	require.Len(t, env.writes, 2)
+}
+
+func TestApplyCovenantSignaturesFirstEventFailure(t *testing.T) {
+	env := newCovenantTestEnv(t, func(sigs []db.CovenantSigRecord) error {
+		if sigs[0].StakingTxHash != testCovenantTxHashA {
+			return nil
+		}
+		return &db.BulkWriteError{Failures: []db.BulkWriteFailure{{
+			Index: 0, Key: testCovenantTxHashA, Err: types.NewPermanentError(errors.New("corrupt signature")),
+		}}}
+	})
+	var deadLetters []*model.BbnEventDeadLetter
+	env.dbMock.On("SaveBbnEventDeadLetter", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, deadLetter *model.BbnEventDeadLetter) error {
+			deadLetters = append(deadLetters, deadLetter)
+			return nil
+		},
+	)
+	env.dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testCovenantTxHashA).Return(
+		&model.BTCDelegationDetails{StakingTxHashHex: testCovenantTxHashA}, nil,
+	).Once()
+	env.dbMock.On(
+		"SaveBTCDelegationUnbondingCovenantSignature", mock.Anything, testCovenantTxHashA, "pk-3", "sig-pk-3",
+	).Return(nil).Once()
+	events := []BbnEvent{
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashB, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-3"),
+	}
+
+	require.Nil(t, env.service.applyBbnBlockEvents(context.Background(), 10, events, nil))
+
+	// the transaction of the failed first event saved none of the batch, the
+	// following event of the batch saving its own signature
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, 0, deadLetters[0].EventIndex)
+}
+
+// failingEventMarkerDb fails marking the event at the index as processed
+type failingEventMarkerDb struct {
+	db.DbInterface
+	failIndex int
+	fail      bool
+}
+
+func (d *failingEventMarkerDb) MarkBbnEventProcessed(ctx context.Context, height uint64, eventIndex int) error {
+	if d.fail && eventIndex == d.failIndex {
+		return errors.New("marker write failed")
+	}
+	return d.DbInterface.MarkBbnEventProcessed(ctx, height, eventIndex)
+}
+
+func TestApplyCovenantSignaturesInEventTransaction(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := &failingEventMarkerDb{DbInterface: inmemory.New(), failIndex: 0, fail: true}
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+		StakingTxHashHex: testCovenantTxHashA,
+	}))
+	service := &Service{
+		cfg:     &config.Config{BBN: config.BBNConfig{CovenantSignatureWorkers: 2}},
+		db:      database,
+		alerter: alerting.NewNoopAlerter(),
+	}
+	marker := model.NewBbnProcessingMarker(10, "block-hash", 0)
+	require.NoError(t, database.StartBbnBlockProcessing(ctx, marker))
+	events := []BbnEvent{
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-1"),
+		covenantSignatureBbnEvent(t, testCovenantTxHashA, "pk-2"),
+	}
+
+	// the signatures are rolled back with the marker of the first event
+	require.NotNil(t, service.applyBbnBlockEvents(ctx, 10, events, marker))
+	delegation, err := database.GetBTCDelegationByStakingTxHash(ctx, testCovenantTxHashA)
+	require.NoError(t, err)
+	require.Empty(t, delegation.CovenantUnbondingSignatures)
+
+	database.fail = false
+	require.Nil(t, service.applyBbnBlockEvents(ctx, 10, events, marker))
+	delegation, err = database.GetBTCDelegationByStakingTxHash(ctx, testCovenantTxHashA)
+	require.NoError(t, err)
+	require.Equal(t, []model.CovenantSignature{
+		{CovenantBtcPkHex: "pk-1", SignatureHex: "sig-pk-1"},
+		{CovenantBtcPkHex: "pk-2", SignatureHex: "sig-pk-2"},
+	}, delegation.CovenantUnbondingSignatures)
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/babylonlabs-io/babylon/btcstaking"
+	bbn "github.com/babylonlabs-io/babylon/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Statuses of the signature of a covenant member
+const (
+	CovenantSignatureValid   = "valid"
+	CovenantSignatureInvalid = "invalid"
+	// CovenantSignatureMissing is the status of the covenant members that did
+	// not sign
+	CovenantSignatureMissing = "missing"
+)
+
+// CovenantSignatureStatus is the outcome of the check of the signature of a
+// covenant member
+type CovenantSignatureStatus struct {
+	CovenantBtcPkHex string `json:"covenant_btc_pk_hex"`
+	Status           string `json:"status"`
+	// Reason tells why the signature is invalid
+	Reason string `json:"reason,omitempty"`
+}
+
+// CovenantSignaturesReport reports the checks of the covenant signatures of a
+// delegation, by covenant member
+type CovenantSignaturesReport struct {
+	StakingTxHashHex string                    `json:"staking_tx_hash_hex"`
+	ParamsVersion    uint32                    `json:"params_version"`
+	CovenantQuorum   uint32                    `json:"covenant_quorum"`
+	ValidSignatures  uint32                    `json:"valid_signatures"`
+	QuorumMet        bool                      `json:"quorum_met"`
+	Signatures       []CovenantSignatureStatus `json:"signatures"`
+}
+
+// InvalidCount returns the number of invalid signatures
+func (r *CovenantSignaturesReport) InvalidCount() int {
+	count := 0
+	for _, signature := range r.Signatures {
+		if signature.Status == CovenantSignatureInvalid {
+			count++
+		}
+	}
+	return count
+}
+
+// VerifyCovenantSignatures checks the stored covenant signatures of the
+// delegation against the covenant pks of its params version. The covenant
+// members sign the unbonding tx spending the staking output through its
+// unbonding path, whose sighash is rebuilt from the stored txs. The adaptor
+// signatures of the slashing txs are not indexed and so not checked.
+// With mark, whether each signature is valid is recorded along with it.
+func (s *Service) VerifyCovenantSignatures(
+	ctx context.Context, stakingTxHashHex string, mark bool,
+) (*CovenantSignaturesReport, *types.Error) {
+	ctx = logging.WithStakingTxHash(ctx, stakingTxHashHex)
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if err != nil {
+		if db.IsNotFoundError(err) {
+			return nil, types.NewErrorWithMsg(http.StatusNotFound, types.NotFound, "delegation not found")
+		}
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
+		)
+	}
+	params, err := s.db.GetStakingParams(ctx, delegation.ParamsVersion)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get staking params of version %d: %w", delegation.ParamsVersion, err),
+		)
+	}
+
+	unbondingTx, stakingOutput, unbondingScript, err := s.rebuildUnbondingSpend(delegation, params)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to rebuild the unbonding spend of the delegation: %w", err),
+		)
+	}
+
+	report := &CovenantSignaturesReport{
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		ParamsVersion:    delegation.ParamsVersion,
+		CovenantQuorum:   params.CovenantQuorum,
+	}
+	signed := make(map[string]bool, len(delegation.CovenantUnbondingSignatures))
+	for _, signature := range delegation.CovenantUnbondingSignatures {
+		status := CovenantSignatureStatus{
+			CovenantBtcPkHex: signature.CovenantBtcPkHex,
+			Status:           CovenantSignatureValid,
+		}
+		if verifyErr := verifyCovenantSignature(
+			unbondingTx, stakingOutput, unbondingScript, params.CovenantPks, signature,
+		); verifyErr != nil {
+			status.Status = CovenantSignatureInvalid
+			status.Reason = verifyErr.Error()
+		} else {
+			report.ValidSignatures++
+		}
+		signed[strings.ToLower(signature.CovenantBtcPkHex)] = true
+		report.Signatures = append(report.Signatures, status)
+
+		if mark {
+			valid := status.Status == CovenantSignatureValid
+			if err := s.db.SetCovenantSignatureVerified(
+				ctx, delegation.StakingTxHashHex, signature.CovenantBtcPkHex, valid,
+			); err != nil {
+				return nil, types.NewInternalServiceError(
+					fmt.Errorf("failed to mark the covenant signature verified: %w", err),
+				)
+			}
+		}
+	}
+	for _, covenantPk := range params.CovenantPks {
+		if !signed[strings.ToLower(covenantPk)] {
+			report.Signatures = append(report.Signatures, CovenantSignatureStatus{
+				CovenantBtcPkHex: covenantPk,
+				Status:           CovenantSignatureMissing,
+			})
+		}
+	}
+	report.QuorumMet = report.ValidSignatures >= params.CovenantQuorum
+
+	logging.FromContext(ctx).Info().
+		Uint32("valid_signatures", report.ValidSignatures).
+		Int("invalid_signatures", report.InvalidCount()).
+		Bool("quorum_met", report.QuorumMet).
+		Bool("marked", mark).
+		Msg("verified the covenant signatures")
+
+	return report, nil
+}
+
+// rebuildUnbondingSpend returns the unbonding tx of the delegation along with
+// the staking output it spends and the unbonding path script it spends it
+// through
+func (s *Service) rebuildUnbondingSpend(
+	delegation *model.BTCDelegationDetails, params *bbnclient.StakingParams,
+) (*wire.MsgTx, *wire.TxOut, []byte, error) {
+	stakerPk, err := bbn.NewBIP340PubKeyFromHex(delegation.StakerBtcPkHex)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to convert staker btc pkh to a public key: %w", err)
+	}
+
+	finalityProviderPks := make([]*btcec.PublicKey, len(delegation.FinalityProviderBtcPksHex))
+	for i, fpPkHex := range delegation.FinalityProviderBtcPksHex {
+		fpPk, err := bbn.NewBIP340PubKeyFromHex(fpPkHex)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to convert finality provider pk hex to a public key: %w", err)
+		}
+		finalityProviderPks[i] = fpPk.MustToBTCPK()
+	}
+
+	covPks := make([]*btcec.PublicKey, len(params.CovenantPks))
+	for i, covPkHex := range params.CovenantPks {
+		covPk, err := bbn.NewBIP340PubKeyFromHex(covPkHex)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to convert covenant pk hex to a public key: %w", err)
+		}
+		covPks[i] = covPk.MustToBTCPK()
+	}
+
+	btcParams, err := utils.GetBTCParams(s.cfg.BTC.NetParams)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to deserialize staking tx: %w", err)
+	}
+	if int(delegation.StakingOutputIdx) >= len(stakingTx.TxOut) {
+		return nil, nil, nil, fmt.Errorf("staking tx has no output %d", delegation.StakingOutputIdx)
+	}
+	unbondingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.UnbondingTx)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to deserialize unbonding tx: %w", err)
+	}
+
+	stakingInfo, err := btcstaking.BuildStakingInfo(
+		stakerPk.MustToBTCPK(),
+		finalityProviderPks,
+		covPks,
+		params.CovenantQuorum,
+		uint16(delegation.StakingTime),
+		btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value),
+		btcParams,
+	)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to rebuild the staking info: %w", err)
+	}
+
+	unbondingPathInfo, err := stakingInfo.UnbondingPathSpendInfo()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get the unbonding path spend info: %w", err)
+	}
+
+	return unbondingTx, stakingInfo.StakingOutput, unbondingPathInfo.GetPkScriptPath(), nil
+}
+
+// verifyCovenantSignature checks that the signature is a valid Schnorr
+// signature of the unbonding tx by a covenant member
+func verifyCovenantSignature(
+	unbondingTx *wire.MsgTx,
+	stakingOutput *wire.TxOut,
+	unbondingScript []byte,
+	covenantPks []string,
+	signature model.CovenantSignature,
+) error {
+	isMember := false
+	for _, covenantPk := range covenantPks {
+		isMember = isMember || strings.EqualFold(covenantPk, signature.CovenantBtcPkHex)
+	}
+	if !isMember {
+		return errors.New("not a covenant member of the params version")
+	}
+
+	covPk, err := bbn.NewBIP340PubKeyFromHex(signature.CovenantBtcPkHex)
+	if err != nil {
+		return fmt.Errorf("invalid covenant pk: %w", err)
+	}
+	sig, err := hex.DecodeString(signature.SignatureHex)
+	if err != nil {
+		return fmt.Errorf("invalid signature hex: %w", err)
+	}
+
+	return btcstaking.VerifyTransactionSigWithOutput(
+		unbondingTx, stakingOutput, unbondingScript, covPk.MustToBTCPK(), sig,
+	)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/babylonlabs-io/babylon/btcstaking"
+	bbn "github.com/babylonlabs-io/babylon/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func txHex(t testing.TB, tx *wire.MsgTx) string {
+	var buf bytes.Buffer
+	require.NoError(t, tx.Serialize(&buf))
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestVerifyCovenantSignatures(t *testing.T) {
+	ctx := context.Background()
+	newKey := func() *btcec.PrivateKey {
+		key, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		return key
+	}
+	pkHex := func(key *btcec.PrivateKey) string {
+		return bbn.NewBIP340PubKeyFromBTCPK(key.PubKey()).MarshalHex()
+	}
+	stakerKey, fpKey := newKey(), newKey()
+	covenantKeys := []*btcec.PrivateKey{newKey(), newKey(), newKey()}
+	params := &bbnclient.StakingParams{CovenantQuorum: 2}
+	var covenantPks []*btcec.PublicKey
+	for _, key := range covenantKeys {
+		params.CovenantPks = append(params.CovenantPks, pkHex(key))
+		covenantPks = append(covenantPks, key.PubKey())
+	}
+
+	stakingInfo, err := btcstaking.BuildStakingInfo(
+		stakerKey.PubKey(), []*btcec.PublicKey{fpKey.PubKey()}, covenantPks,
+		params.CovenantQuorum, 1000, 100000, &chaincfg.SigNetParams,
+	)
+	require.NoError(t, err)
+	stakingTx := wire.NewMsgTx(2)
+	stakingTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, nil, nil))
+	stakingTx.AddTxOut(stakingInfo.StakingOutput)
+	stakingTxHash := stakingTx.TxHash()
+	unbondingTx := wire.NewMsgTx(2)
+	unbondingTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&stakingTxHash, 0), nil, nil))
+	unbondingTx.AddTxOut(wire.NewTxOut(99000, stakingInfo.StakingOutput.PkScript))
+
+	unbondingPathInfo, err := stakingInfo.UnbondingPathSpendInfo()
+	require.NoError(t, err)
+	sign := func(key *btcec.PrivateKey) string {
+		sig, err := btcstaking.SignTxWithOneScriptSpendInputFromScript(
+			unbondingTx, stakingInfo.StakingOutput, key, unbondingPathInfo.GetPkScriptPath(),
+		)
+		require.NoError(t, err)
+		return hex.EncodeToString(sig.Serialize())
+	}
+
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex:          stakingTxHash.String(),
+		StakingTxHex:              txHex(t, stakingTx),
+		StakingTime:               1000,
+		StakerBtcPkHex:            pkHex(stakerKey),
+		FinalityProviderBtcPksHex: []string{pkHex(fpKey)},
+		ParamsVersion:             1,
+		UnbondingTx:               txHex(t, unbondingTx),
+		CovenantUnbondingSignatures: []model.CovenantSignature{
+			{CovenantBtcPkHex: params.CovenantPks[0], SignatureHex: sign(covenantKeys[0])},
+			// signed by another key than the one of the covenant member
+			{CovenantBtcPkHex: params.CovenantPks[1], SignatureHex: sign(covenantKeys[0])},
+		},
+	}
+	cfg := &config.Config{BTC: config.BTCConfig{NetParams: "signet"}}
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, delegation.StakingTxHashHex).Return(delegation, nil)
+	dbMock.On("GetStakingParams", mock.Anything, uint32(1)).Return(params, nil)
+	dbMock.On("SetCovenantSignatureVerified", mock.Anything, delegation.StakingTxHashHex, params.CovenantPks[0], true).
+		Return(nil).Once()
+	dbMock.On("SetCovenantSignatureVerified", mock.Anything, delegation.StakingTxHashHex, params.CovenantPks[1], false).
+		Return(nil).Once()
+
+	service := NewService(cfg, dbMock, nil, nil, nil, nil)
+	report, verifyErr := service.VerifyCovenantSignatures(ctx, delegation.StakingTxHashHex, true)
+	require.Nil(t, verifyErr)
+	require.Equal(t, uint32(1), report.ValidSignatures)
+	require.False(t, report.QuorumMet)
+	require.Equal(t, 1, report.InvalidCount())
+	require.Len(t, report.Signatures, 3)
+	require.Equal(t, CovenantSignatureValid, report.Signatures[0].Status)
+	require.Equal(t, CovenantSignatureInvalid, report.Signatures[1].Status)
+	require.Equal(t, CovenantSignatureStatus{
+		CovenantBtcPkHex: params.CovenantPks[2],
+		Status:           CovenantSignatureMissing,
+	}, report.Signatures[2])
+}
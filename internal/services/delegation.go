@@ -5,14 +5,16 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
 	ftypes "github.com/babylonlabs-io/babylon/x/finality/types"
 	abcitypes "github.com/cometbft/cometbft/abci/types"
-	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -55,6 +57,13 @@ func (s *Service) processNewBTCDelegationEvent(
 		return err
 	}
 
+	// The event does not carry the BBN address of the staker
+	stakerBabylonAddress, err := s.getStakerBabylonAddress(ctx, delegationDoc.StakingTxHashHex)
+	if err != nil {
+		return err
+	}
+	delegationDoc.StakerBabylonAddress = stakerBabylonAddress
+
 	if dbErr := s.db.SaveNewBTCDelegation(
 		ctx, delegationDoc,
 	); dbErr != nil {
@@ -69,6 +78,13 @@ func (s *Service) processNewBTCDelegationEvent(
 		)
 	}
 
+	if err := s.recordStateTransition(ctx, model.NewBbnStateTransition(
+		delegationDoc.StakingTxHashHex, "", delegationDoc.State, delegationDoc.SubState,
+		EventBTCDelegationCreated.String(), uint64(bbnBlockHeight), time.Now().Unix(),
+	)); err != nil {
+		return types.NewInternalServiceError(err)
+	}
+
 	// TODO: start watching for BTC confirmation if we need PendingBTCConfirmation state
 
 	return nil
@@ -108,21 +124,14 @@ func (s *Service) processCovenantSignatureReceivedEvent(
 		covenantBtcPkHex,
 		signatureHex,
 	); dbErr != nil {
-		return types.NewError(
-			http.StatusInternalServerError,
-			types.InternalServiceError,
-			fmt.Errorf(
-				"failed to save BTC delegation unbonding covenant signature: %w for staking tx hash %s",
-				dbErr, stakingTxHash,
-			),
-		)
+		return covenantSignatureSaveError(stakingTxHash, dbErr)
 	}
 
 	return nil
 }
 
 func (s *Service) processCovenantQuorumReachedEvent(
-	ctx context.Context, event abcitypes.Event,
+	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
 ) *types.Error {
 	covenantQuorumReachedEvent, err := parseEvent[*bbntypes.EventCovenantQuorumReached](
 		EventCovenantQuorumReached, event,
@@ -140,7 +149,6 @@ func (s *Service) processCovenantQuorumReachedEvent(
 		return nil
 	}
 
-	// Emit event and register spend notification
 	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, covenantQuorumReachedEvent.StakingTxHash)
 	if dbErr != nil {
 		return types.NewError(
@@ -150,21 +158,27 @@ func (s *Service) processCovenantQuorumReachedEvent(
 		)
 	}
 
-	newState := types.DelegationState(covenantQuorumReachedEvent.NewState)
+	newState, parseErr := types.ParseDelegationState(covenantQuorumReachedEvent.NewState)
+	if parseErr != nil {
+		return types.NewValidationFailedError(parseErr)
+	}
+
+	// Update delegation state
+	applied, err := s.applyEventTransition(
+		ctx, EventCovenantQuorumReached, delegation, newState, bbnBlockHeight,
+	)
+	if err != nil || !applied {
+		return err
+	}
+
+	// Emit event and register spend notification once the transition is
+	// applied
 	if newState == types.StateActive {
-		log.Debug().
-			Str("staking_tx", covenantQuorumReachedEvent.StakingTxHash).
+		logging.BlockProcessor.FromContext(ctx).Debug().
 			Str("staking_start_height", strconv.FormatUint(uint64(delegation.StartHeight), 10)).
-			Str("event_type", EventCovenantQuorumReached.String()).
 			Msg("handling active state")
 
-		err = s.emitActiveDelegationEvent(
-			ctx,
-			delegation.StakingTxHashHex,
-			delegation.StakerBtcPkHex,
-			delegation.FinalityProviderBtcPksHex,
-			delegation.StakingAmount,
-		)
+		err = s.emitActiveDelegationEvent(ctx, delegation, delegation.StartHeight)
 		if err != nil {
 			return err
 		}
@@ -180,26 +194,11 @@ func (s *Service) processCovenantQuorumReachedEvent(
 		}
 	}
 
-	// Update delegation state
-	if dbErr := s.db.UpdateBTCDelegationState(
-		ctx,
-		covenantQuorumReachedEvent.StakingTxHash,
-		types.QualifiedStatesForCovenantQuorumReached(covenantQuorumReachedEvent.NewState),
-		newState,
-		nil,
-	); dbErr != nil {
-		return types.NewError(
-			http.StatusInternalServerError,
-			types.InternalServiceError,
-			fmt.Errorf("failed to update BTC delegation state: %w", dbErr),
-		)
-	}
-
 	return nil
 }
 
 func (s *Service) processBTCDelegationInclusionProofReceivedEvent(
-	ctx context.Context, event abcitypes.Event,
+	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
 ) *types.Error {
 	inclusionProofEvent, err := parseEvent[*bbntypes.EventBTCDelegationInclusionProofReceived](
 		EventBTCDelegationInclusionProofReceived, event,
@@ -217,7 +216,6 @@ func (s *Service) processBTCDelegationInclusionProofReceivedEvent(
 		return nil
 	}
 
-	// Emit event and register spend notification
 	delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, inclusionProofEvent.StakingTxHash)
 	if dbErr != nil {
 		return types.NewError(
@@ -226,23 +224,47 @@ func (s *Service) processBTCDelegationInclusionProofReceivedEvent(
 			fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr),
 		)
 	}
-	newState := types.DelegationState(inclusionProofEvent.NewState)
+	newState, parseErr := types.ParseDelegationState(inclusionProofEvent.NewState)
+	if parseErr != nil {
+		return types.NewValidationFailedError(parseErr)
+	}
+
+	// Update delegation details
+	if dbErr := s.db.UpdateBTCDelegationDetails(
+		ctx,
+		inclusionProofEvent.StakingTxHash,
+		model.FromEventBTCDelegationInclusionProofReceived(inclusionProofEvent),
+	); dbErr != nil {
+		return types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("failed to update BTC delegation details: %w", dbErr),
+		)
+	}
+
+	// The inclusion proof of a pending delegation does not change its state
+	if newState == delegation.State {
+		return nil
+	}
+
+	// Update delegation state
+	applied, err := s.applyEventTransition(
+		ctx, EventBTCDelegationInclusionProofReceived, delegation, newState, bbnBlockHeight,
+	)
+	if err != nil || !applied {
+		return err
+	}
+
+	// Emit event and register spend notification once the transition is
+	// applied
 	if newState == types.StateActive {
 		stakingStartHeight, _ := strconv.ParseUint(inclusionProofEvent.StartHeight, 10, 32)
 
-		log.Debug().
-			Str("staking_tx", inclusionProofEvent.StakingTxHash).
+		logging.BlockProcessor.FromContext(ctx).Debug().
 			Str("staking_start_height", inclusionProofEvent.StartHeight).
-			Str("event_type", EventBTCDelegationInclusionProofReceived.String()).
 			Msg("handling active state")
 
-		err = s.emitActiveDelegationEvent(
-			ctx,
-			inclusionProofEvent.StakingTxHash,
-			delegation.StakerBtcPkHex,
-			delegation.FinalityProviderBtcPksHex,
-			delegation.StakingAmount,
-		)
+		err = s.emitActiveDelegationEvent(ctx, delegation, uint32(stakingStartHeight))
 		if err != nil {
 			return err
 		}
@@ -257,24 +279,11 @@ func (s *Service) processBTCDelegationInclusionProofReceivedEvent(
 		}
 	}
 
-	// Update delegation details
-	if dbErr := s.db.UpdateBTCDelegationDetails(
-		ctx,
-		inclusionProofEvent.StakingTxHash,
-		model.FromEventBTCDelegationInclusionProofReceived(inclusionProofEvent),
-	); dbErr != nil {
-		return types.NewError(
-			http.StatusInternalServerError,
-			types.InternalServiceError,
-			fmt.Errorf("failed to update BTC delegation details: %w", dbErr),
-		)
-	}
-
 	return nil
 }
 
 func (s *Service) processBTCDelegationUnbondedEarlyEvent(
-	ctx context.Context, event abcitypes.Event,
+	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
 ) *types.Error {
 	unbondedEarlyEvent, err := parseEvent[*bbntypes.EventBTCDelgationUnbondedEarly](
 		EventBTCDelgationUnbondedEarly,
@@ -302,11 +311,6 @@ func (s *Service) processBTCDelegationUnbondedEarlyEvent(
 		)
 	}
 
-	// Emit consumer event
-	if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
-		return err
-	}
-
 	unbondingStartHeight, parseErr := strconv.ParseUint(unbondedEarlyEvent.StartHeight, 10, 32)
 	if parseErr != nil {
 		return types.NewError(
@@ -316,53 +320,58 @@ func (s *Service) processBTCDelegationUnbondedEarlyEvent(
 		)
 	}
 
-	subState := types.SubStateEarlyUnbonding
+	subState := eventTransitions[EventBTCDelgationUnbondedEarly].subState
 
-	// Save timelock expire
 	unbondingExpireHeight := uint32(unbondingStartHeight) + delegation.UnbondingTime
-	if err := s.db.SaveNewTimeLockExpire(
-		ctx,
-		delegation.StakingTxHashHex,
-		unbondingExpireHeight,
-		subState,
-	); err != nil {
-		return types.NewError(
-			http.StatusInternalServerError,
-			types.InternalServiceError,
-			fmt.Errorf("failed to save timelock expire: %w", err),
-		)
-	}
 
-	log.Debug().
-		Str("staking_tx", unbondedEarlyEvent.StakingTxHash).
+	logging.BlockProcessor.FromContext(ctx).Debug().
 		Str("new_state", types.StateUnbonding.String()).
 		Str("early_unbonding_start_height", unbondedEarlyEvent.StartHeight).
 		Str("unbonding_time", strconv.FormatUint(uint64(delegation.UnbondingTime), 10)).
 		Str("unbonding_expire_height", strconv.FormatUint(uint64(unbondingExpireHeight), 10)).
 		Str("sub_state", subState.String()).
-		Str("event_type", EventBTCDelgationUnbondedEarly.String()).
 		Msg("updating delegation state")
 
 	// Update delegation state
-	if err := s.db.UpdateBTCDelegationState(
+	applied, err := s.applyEventTransition(
+		ctx, EventBTCDelgationUnbondedEarly, delegation, types.StateUnbonding, bbnBlockHeight,
+	)
+	if err != nil || !applied {
+		return err
+	}
+
+	// Save timelock expire
+	if err := s.db.SaveNewTimeLockExpire(
 		ctx,
-		unbondedEarlyEvent.StakingTxHash,
-		types.QualifiedStatesForUnbondedEarly(),
-		types.StateUnbonding,
-		&subState,
+		delegation.StakingTxHashHex,
+		unbondingExpireHeight,
+		subState,
 	); err != nil {
 		return types.NewError(
 			http.StatusInternalServerError,
 			types.InternalServiceError,
-			fmt.Errorf("failed to update BTC delegation state: %w", err),
+			fmt.Errorf("failed to save timelock expire: %w", err),
 		)
 	}
 
+	// Emit consumer event once the transition is applied
+	unbondingTx, parseErr := utils.DeserializeBtcTransactionFromHex(delegation.UnbondingTx)
+	if parseErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to deserialize unbonding tx: %w", parseErr),
+		)
+	}
+	if err := s.emitUnbondingDelegationEvent(
+		ctx, delegation, subState, unbondingTx.TxHash().String(), unbondingExpireHeight,
+	); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (s *Service) processBTCDelegationExpiredEvent(
-	ctx context.Context, event abcitypes.Event,
+	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
 ) *types.Error {
 	expiredEvent, err := parseEvent[*bbntypes.EventBTCDelegationExpired](
 		EventBTCDelegationExpired,
@@ -390,13 +399,16 @@ func (s *Service) processBTCDelegationExpiredEvent(
 		)
 	}
 
-	// Emit consumer event
-	if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
+	subState := eventTransitions[EventBTCDelegationExpired].subState
+
+	// Update delegation state
+	applied, err := s.applyEventTransition(
+		ctx, EventBTCDelegationExpired, delegation, types.StateUnbonding, bbnBlockHeight,
+	)
+	if err != nil || !applied {
 		return err
 	}
 
-	subState := types.SubStateTimelock
-
 	// Save timelock expire
 	if err := s.db.SaveNewTimeLockExpire(
 		ctx,
@@ -411,26 +423,18 @@ func (s *Service) processBTCDelegationExpiredEvent(
 		)
 	}
 
-	// Update delegation state
-	if err := s.db.UpdateBTCDelegationState(
-		ctx,
-		delegation.StakingTxHashHex,
-		types.QualifiedStatesForExpired(),
-		types.StateUnbonding,
-		&subState,
+	// Emit consumer event once the transition is applied
+	if err := s.emitUnbondingDelegationEvent(
+		ctx, delegation, subState, "", delegation.EndHeight,
 	); err != nil {
-		return types.NewError(
-			http.StatusInternalServerError,
-			types.InternalServiceError,
-			fmt.Errorf("failed to update BTC delegation state: %w", err),
-		)
+		return err
 	}
 
 	return nil
 }
 
 func (s *Service) processSlashedFinalityProviderEvent(
-	ctx context.Context, event abcitypes.Event,
+	ctx context.Context, event abcitypes.Event, bbnBlockHeight int64,
 ) *types.Error {
 	slashedFinalityProviderEvent, err := parseEvent[*ftypes.EventSlashedFinalityProvider](
 		EventSlashedFinalityProvider,
@@ -451,36 +455,62 @@ func (s *Service) processSlashedFinalityProviderEvent(
 
 	evidence := slashedFinalityProviderEvent.Evidence
 	fpBTCPKHex := evidence.FpBtcPk.MarshalHex()
+	ctx = logging.WithFpBtcPk(ctx, fpBTCPKHex)
 
-	if dbErr := s.db.UpdateDelegationsStateByFinalityProvider(
-		ctx, fpBTCPKHex, types.StateSlashed,
-	); dbErr != nil {
+	// Read before the update, for the states the delegations are slashed from
+	delegations, dbErr := s.db.GetDelegationsByFinalityProvider(ctx, fpBTCPKHex)
+	if dbErr != nil {
 		return types.NewError(
 			http.StatusInternalServerError,
 			types.InternalServiceError,
-			fmt.Errorf("failed to update BTC delegation state: %w", dbErr),
+			fmt.Errorf("failed to get BTC delegations by finality provider: %w", dbErr),
 		)
 	}
 
-	delegations, dbErr := s.db.GetDelegationsByFinalityProvider(ctx, fpBTCPKHex)
-	if dbErr != nil {
+	if dbErr := s.db.UpdateDelegationsStateByFinalityProvider(
+		ctx, fpBTCPKHex, types.StateSlashed,
+	); dbErr != nil {
 		return types.NewError(
 			http.StatusInternalServerError,
 			types.InternalServiceError,
-			fmt.Errorf("failed to get BTC delegations by finality provider: %w", dbErr),
+			fmt.Errorf("failed to update BTC delegation state: %w", dbErr),
 		)
 	}
 
+	slashed := make([]*model.BTCDelegationDetails, 0, len(delegations))
 	for _, delegation := range delegations {
+		// Already slashed on a previous processing of the event, whose events
+		// are emitted again in case it stopped before
+		if delegation.State == types.StateSlashed {
+			slashed = append(slashed, delegation)
+			continue
+		}
+		// Left as they are by the update, as they can no longer be slashed
+		if !delegation.State.CanTransitionTo(types.StateSlashed) {
+			continue
+		}
+		if err := s.recordStateTransition(ctx, model.NewBbnStateTransition(
+			delegation.StakingTxHashHex, delegation.State, types.StateSlashed, "",
+			EventSlashedFinalityProvider.String(), uint64(bbnBlockHeight), time.Now().Unix(),
+		)); err != nil {
+			return types.NewInternalServiceError(err)
+		}
+		if err := s.catchUpSlashingConfirmed(ctx, delegation); err != nil {
+			return err
+		}
+		slashed = append(slashed, delegation)
+	}
+
+	for _, delegation := range slashed {
+		delegationCtx := logging.WithStakingTxHash(ctx, delegation.StakingTxHashHex)
 		if !delegation.HasInclusionProof() {
-			log.Debug().
-				Str("staking_tx", delegation.StakingTxHashHex).
+			logging.BlockProcessor.FromContext(delegationCtx).Debug().
 				Str("reason", "missing_inclusion_proof").
 				Msg("skipping slashed delegation event")
 			continue
 		}
 
-		if err := s.emitUnbondingDelegationEvent(ctx, delegation); err != nil {
+		if err := s.emitSlashedDelegationEvent(delegationCtx, delegation, bbnBlockHeight); err != nil {
 			return err
 		}
 	}
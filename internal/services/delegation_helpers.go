@@ -3,22 +3,29 @@ package services
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 	bbn "github.com/babylonlabs-io/babylon/types"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/wire"
-	"github.com/rs/zerolog/log"
 )
 
 func (s *Service) registerUnbondingSpendNotification(
 	ctx context.Context,
 	delegation *model.BTCDelegationDetails,
 ) *types.Error {
+	// A backfill does not watch BTC
+	if s.btcNotifier == nil {
+		return nil
+	}
+
 	unbondingTxBytes, parseErr := hex.DecodeString(delegation.UnbondingTx)
 	if parseErr != nil {
 		return types.NewError(
@@ -37,8 +44,8 @@ func (s *Service) registerUnbondingSpendNotification(
 		)
 	}
 
-	log.Debug().
-		Str("staking_tx", delegation.StakingTxHashHex).
+	ctx = logging.WithStakingTxHash(ctx, delegation.StakingTxHashHex)
+	logging.BlockProcessor.FromContext(ctx).Debug().
 		Str("unbonding_tx", unbondingTx.TxHash().String()).
 		Msg("registering early unbonding spend notification")
 
@@ -47,9 +54,18 @@ func (s *Service) registerUnbondingSpendNotification(
 		Index: 0, // unbonding tx has only 1 output
 	}
 
+	unbondingOutput, btcErr := utils.GetTxOutput(unbondingTx, unbondingOutpoint.Index)
+	if btcErr != nil {
+		return types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("%w: %w", types.ErrInvalidUnbondingTx, btcErr),
+		)
+	}
+
 	spendEv, btcErr := s.btcNotifier.RegisterSpendNtfn(
 		&unbondingOutpoint,
-		unbondingTx.TxOut[0].PkScript,
+		unbondingOutput.PkScript,
 		delegation.StartHeight,
 	)
 	if btcErr != nil {
@@ -60,8 +76,7 @@ func (s *Service) registerUnbondingSpendNotification(
 		)
 	}
 
-	s.wg.Add(1)
-	go s.watchForSpendUnbondingTx(spendEv, delegation)
+	s.startSpendWatch(func() { s.watchForSpendUnbondingTx(spendEv, delegation) })
 
 	return nil
 }
@@ -73,6 +88,12 @@ func (s *Service) registerStakingSpendNotification(
 	stakingOutputIdx uint32,
 	stakingStartHeight uint32,
 ) *types.Error {
+	// A backfill does not watch BTC, the indexer resubscribes to the missed
+	// spends on restart
+	if s.btcNotifier == nil {
+		return nil
+	}
+
 	stakingTxHash, err := chainhash.NewHashFromStr(stakingTxHashHex)
 	if err != nil {
 		return types.NewError(
@@ -91,6 +112,15 @@ func (s *Service) registerStakingSpendNotification(
 		)
 	}
 
+	stakingOutput, err := utils.GetTxOutput(stakingTx, stakingOutputIdx)
+	if err != nil {
+		return types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("%w: %w", types.ErrInvalidStakingTx, err),
+		)
+	}
+
 	stakingOutpoint := wire.OutPoint{
 		Hash:  *stakingTxHash,
 		Index: stakingOutputIdx,
@@ -98,7 +128,7 @@ func (s *Service) registerStakingSpendNotification(
 
 	spendEv, err := s.btcNotifier.RegisterSpendNtfn(
 		&stakingOutpoint,
-		stakingTx.TxOut[stakingOutputIdx].PkScript,
+		stakingOutput.PkScript,
 		stakingStartHeight,
 	)
 	if err != nil {
@@ -109,8 +139,30 @@ func (s *Service) registerStakingSpendNotification(
 		)
 	}
 
-	s.wg.Add(1)
-	go s.watchForSpendStakingTx(spendEv, stakingTxHashHex)
+	s.startSpendWatch(func() { s.watchForSpendStakingTx(spendEv, stakingTxHashHex) })
 
 	return nil
 }
+
+// getStakerBabylonAddress returns the BBN address of the staker of the
+// delegation, as known by the BBN chain. It is empty if the chain does not
+// know the delegation, which does not hold back the indexing.
+func (s *Service) getStakerBabylonAddress(
+	ctx context.Context, stakingTxHashHex string,
+) (string, *types.Error) {
+	delegation, err := s.bbn.GetBTCDelegation(ctx, stakingTxHashHex)
+	if err != nil {
+		if errors.Is(err, bbnclient.ErrBTCDelegationNotFound) {
+			logging.BlockProcessor.FromContext(logging.WithStakingTxHash(ctx, stakingTxHashHex)).Warn().
+				Msg("BTC delegation not found on the BBN chain, indexing it without the staker address")
+			return "", nil
+		}
+		return "", types.NewError(
+			http.StatusInternalServerError,
+			types.ClientRequestError,
+			fmt.Errorf("failed to get BTC delegation %s: %w", stakingTxHashHex, err),
+		)
+	}
+
+	return delegation.StakerBabylonAddress, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+func (s *Service) StartDelegationMetricsUpdater(ctx context.Context) {
+	delegationMetricsPoller := s.newPoller(
+		"delegation_metrics",
+		s.cfg.Poller.DelegationMetricsInterval,
+		s.updateDelegationMetrics,
+	)
+	s.startPoller(ctx, delegationMetricsPoller)
+}
+
+// updateDelegationMetrics publishes the number of delegations and their total
+// staking amount by state. The states without any delegation are published
+// as zero, so that a state left by its last delegation does not keep its
+// former count.
+func (s *Service) updateDelegationMetrics(ctx context.Context) *types.Error {
+	delegationStats, err := s.db.GetDelegationStatsByState(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to aggregate delegations by state: %w", err),
+		)
+	}
+
+	counts := make(map[string]uint64)
+	stakingAmounts := make(map[string]uint64)
+	for _, state := range types.AllDelegationStates() {
+		counts[strings.ToLower(state.String())] = 0
+		stakingAmounts[strings.ToLower(state.String())] = 0
+	}
+	for _, stateStats := range delegationStats {
+		counts[strings.ToLower(stateStats.State)] = stateStats.Count
+		stakingAmounts[strings.ToLower(stateStats.State)] = stateStats.StakingAmount
+	}
+	metrics.RecordDelegationsByState(counts, stakingAmounts, time.Now())
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateDelegationMetrics(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetDelegationStatsByState", ctx).Return([]*model.DelegationStateStats{
+		{State: types.StateActive.String(), Count: 2, StakingAmount: 3000},
+	}, nil).Once()
+	dbMock.On("GetDelegationStatsByState", ctx).Return([]*model.DelegationStateStats{
+		{State: types.StateUnbonding.String(), Count: 2, StakingAmount: 3000},
+	}, nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	require.Nil(t, service.updateDelegationMetrics(ctx))
+	require.Nil(t, service.updateDelegationMetrics(ctx))
+
+	// the delegations left the active state
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	require.Contains(t, body, `indexer_delegations_total{state="active"} 0`)
+	require.Contains(t, body, `indexer_delegations_total{state="unbonding"} 2`)
+	require.Contains(t, body, `indexer_delegations_sats_total{state="unbonding"} 3000`)
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// SetStateRequest is a manual override of the state of a delegation
+type SetStateRequest struct {
+	StakingTxHashHex types.StakingTxHash
+	State            types.DelegationState
+	// SubState is required by the UNBONDING, WITHDRAWABLE and WITHDRAWN
+	// states, and refused by the others
+	SubState types.DelegationSubState
+	// Reason and Operator are recorded in the state history of the
+	// delegation, both are required
+	Reason   string
+	Operator string
+}
+
+// overrideSubStates lists the sub states allowed in the states having one
+var overrideSubStates = map[types.DelegationState][]types.DelegationSubState{
+	types.StateUnbonding: {types.SubStateTimelock, types.SubStateEarlyUnbonding},
+	types.StateWithdrawable: {
+		types.SubStateTimelock, types.SubStateEarlyUnbonding,
+		types.SubStateTimelockSlashing, types.SubStateEarlyUnbondingSlashing,
+	},
+	types.StateWithdrawn: {
+		types.SubStateTimelock, types.SubStateEarlyUnbonding,
+		types.SubStateTimelockSlashing, types.SubStateEarlyUnbondingSlashing,
+	},
+}
+
+// SetDelegationState overrides the state of a delegation whatever its current
+// state, bypassing the delegation state machine, to work around chain bugs the
+// event processing cannot handle. The override is recorded in the state
+// history with the reason and the operator, and the event of the new state is
+// emitted. Nothing else is derived from the new state, e.g. no timelock is
+// saved nor BTC spend watched.
+func (s *Service) SetDelegationState(ctx context.Context, req SetStateRequest) *types.Error {
+	if strings.TrimSpace(req.Reason) == "" {
+		return types.NewValidationFailedError(errors.New("a reason is required to override a delegation state"))
+	}
+	if strings.TrimSpace(req.Operator) == "" {
+		return types.NewValidationFailedError(errors.New("an operator is required to override a delegation state"))
+	}
+	if !utils.Contains(types.AllDelegationStates(), req.State) {
+		return types.NewValidationFailedError(fmt.Errorf("%s is not a valid delegation state", req.State))
+	}
+	if req.SubState != "" {
+		if err := req.SubState.Validate(); err != nil {
+			return types.NewValidationFailedError(err)
+		}
+	}
+	subStates, hasSubState := overrideSubStates[req.State]
+	if hasSubState && !utils.Contains(subStates, req.SubState) {
+		return types.NewValidationFailedError(
+			fmt.Errorf("%q is not a valid sub state of %s", req.SubState, req.State),
+		)
+	}
+	if !hasSubState && req.SubState != "" {
+		return types.NewValidationFailedError(fmt.Errorf("%s has no sub state", req.State))
+	}
+
+	ctx = logging.WithStakingTxHash(ctx, req.StakingTxHashHex.String())
+	ctx = audit.WithTrigger(ctx, audit.Trigger{Type: audit.TriggerAdmin, Operator: req.Operator})
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, req.StakingTxHashHex.String())
+	if err != nil {
+		if db.IsNotFoundError(err) {
+			return types.NewErrorWithMsg(http.StatusNotFound, types.NotFound, "delegation not found")
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
+		)
+	}
+
+	var subState *types.DelegationSubState
+	if hasSubState {
+		subState = &req.SubState
+	}
+	// The override bypasses the state machine, only a concurrent change of
+	// the state is refused
+	if err := s.db.OverrideBTCDelegationState(
+		ctx, delegation.StakingTxHashHex, delegation.State, req.State, subState,
+	); err != nil {
+		if db.IsNotFoundError(err) {
+			return types.NewErrorWithMsg(
+				http.StatusConflict, types.Conflict, "delegation state changed during the override",
+			)
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to update BTC delegation state: %w", err),
+		)
+	}
+
+	if err := s.recordStateTransition(ctx, &model.DelegationStateTransition{
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		FromState:        delegation.State,
+		ToState:          req.State,
+		SubState:         req.SubState,
+		Trigger:          model.StateTransitionTriggerAdmin,
+		Reason:           req.Reason,
+		Operator:         req.Operator,
+		CreatedAt:        time.Now().Unix(),
+	}); err != nil {
+		return types.NewInternalServiceError(err)
+	}
+
+	logging.FromContext(ctx).Info().
+		Str("from_state", delegation.State.String()).
+		Str("to_state", req.State.String()).
+		Str("sub_state", req.SubState.String()).
+		Str("operator", req.Operator).
+		Str("reason", req.Reason).
+		Msg("delegation state overridden")
+
+	return s.emitOverriddenStateEvent(ctx, delegation, req.State, req.SubState)
+}
+
+// emitOverriddenStateEvent emits the event of the state a delegation was set
+// to, the heights the event would carry being taken from the delegation
+func (s *Service) emitOverriddenStateEvent(
+	ctx context.Context,
+	delegation *model.BTCDelegationDetails,
+	state types.DelegationState,
+	subState types.DelegationSubState,
+) *types.Error {
+	switch state {
+	case types.StateActive:
+		return s.emitActiveDelegationEvent(ctx, delegation, delegation.StartHeight)
+	case types.StateUnbonding:
+		expireHeight, err := s.overriddenStateExpireHeight(ctx, delegation, subState)
+		if err != nil {
+			return err
+		}
+		unbondingTxHashHex := ""
+		if subState == types.SubStateEarlyUnbonding {
+			unbondingTx, parseErr := utils.DeserializeBtcTransactionFromHex(delegation.UnbondingTx)
+			if parseErr != nil {
+				return types.NewInternalServiceError(
+					fmt.Errorf("failed to deserialize unbonding tx: %w", parseErr),
+				)
+			}
+			unbondingTxHashHex = unbondingTx.TxHash().String()
+		}
+		return s.emitUnbondingDelegationEvent(ctx, delegation, subState, unbondingTxHashHex, expireHeight)
+	case types.StateWithdrawable:
+		expireHeight, err := s.overriddenStateExpireHeight(ctx, delegation, subState)
+		if err != nil {
+			return err
+		}
+		return s.emitWithdrawableDelegationEvent(ctx, delegation.Summary(), subState, expireHeight)
+	case types.StateWithdrawn:
+		// The spending tx is unknown to a manual override
+		return s.emitWithdrawnDelegationEvent(ctx, delegation, subState, "", 0)
+	case types.StateSlashed:
+		return s.emitSlashedDelegationEvent(ctx, delegation, 0)
+	default:
+		// No event is emitted for the states preceding the activation
+		return nil
+	}
+}
+
+// overriddenStateExpireHeight returns the expire height of the timelock of the
+// sub state, or the end height of the delegation if it has none
+func (s *Service) overriddenStateExpireHeight(
+	ctx context.Context, delegation *model.BTCDelegationDetails, subState types.DelegationSubState,
+) (uint32, *types.Error) {
+	timeLocks, err := s.db.GetTimeLocks(ctx, delegation.StakingTxHashHex)
+	if err != nil {
+		return 0, types.NewInternalServiceError(
+			fmt.Errorf("failed to get timelocks of delegation: %w", err),
+		)
+	}
+	for _, timeLock := range timeLocks {
+		if timeLock.DelegationSubState == subState {
+			return timeLock.ExpireHeight, nil
+		}
+	}
+	return delegation.EndHeight, nil
+}
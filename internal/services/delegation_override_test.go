@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetDelegationState(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateWithdrawn,
+		SubState:         types.SubStateTimelock,
+		EndHeight:        200,
+	}
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testReprocessTxHash).Return(delegation, nil)
+	subState := types.SubStateTimelock
+	dbMock.On(
+		"OverrideBTCDelegationState", mock.Anything, testReprocessTxHash,
+		types.StateWithdrawn, types.StateWithdrawable, &subState,
+	).Return(nil).Once()
+	dbMock.On("SaveDelegationStateTransition", mock.Anything, mock.MatchedBy(func(transition *model.DelegationStateTransition) bool {
+		return transition.FromState == types.StateWithdrawn &&
+			transition.ToState == types.StateWithdrawable &&
+			transition.SubState == subState &&
+			transition.Trigger == model.StateTransitionTriggerAdmin &&
+			transition.Reason == "spend reported on a reorged block" &&
+			transition.Operator == "alice"
+	})).Return(nil).Once()
+	dbMock.On("GetTimeLocks", mock.Anything, testReprocessTxHash).Return([]model.TimeLockDocument{
+		{StakingTxHashHex: testReprocessTxHash, ExpireHeight: 210, DelegationSubState: subState},
+	}, nil)
+	dbMock.On("SaveOutboxEvent", mock.Anything, mock.MatchedBy(func(event *model.OutboxEvent) bool {
+		return event.EventType == model.OutboxEventTypeWithdrawable
+	})).Return(nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	err := service.SetDelegationState(ctx, SetStateRequest{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateWithdrawable,
+		SubState:         subState,
+		Reason:           "spend reported on a reorged block",
+		Operator:         "alice",
+	})
+	require.Nil(t, err)
+}
+
+func TestSetDelegationStateInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		req  SetStateRequest
+	}{
+		{"empty reason", SetStateRequest{State: types.StateActive, Reason: "  ", Operator: "alice"}},
+		{"no operator", SetStateRequest{State: types.StateActive, Reason: "fix"}},
+		{"unknown state", SetStateRequest{State: "EXPIRED", Reason: "fix", Operator: "alice"}},
+		{"missing sub state", SetStateRequest{State: types.StateUnbonding, Reason: "fix", Operator: "alice"}},
+		{"unknown sub state", SetStateRequest{
+			State: types.StateWithdrawn, SubState: "SWEPT", Reason: "fix", Operator: "alice",
+		}},
+		{"unexpected sub state", SetStateRequest{
+			State: types.StateActive, SubState: types.SubStateTimelock, Reason: "fix", Operator: "alice",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// no db call is expected as the request is refused upfront
+			service := NewService(&config.Config{}, mocks.NewDbInterface(t), nil, nil, nil, nil)
+			tc.req.StakingTxHashHex = testReprocessTxHash
+			err := service.SetDelegationState(context.Background(), tc.req)
+			require.NotNil(t, err)
+			require.Equal(t, http.StatusBadRequest, err.StatusCode)
+		})
+	}
+}
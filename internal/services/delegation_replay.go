@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+)
+
+// ReplayDelegationRequest selects the delegation whose BBN events are replayed
+type ReplayDelegationRequest struct {
+	StakingTxHashHex types.StakingTxHash
+	// Reset deletes the delegation, its state history and its timelocks
+	// before the replay, so that the replay indexes it from scratch
+	Reset bool
+}
+
+// ReplayedEvent is a BBN event of a replayed delegation, along with the state
+// of the delegation once the event is applied
+type ReplayedEvent struct {
+	BbnHeight int64
+	EventType string
+	// State and SubState are empty if the delegation does not exist once the
+	// event is applied
+	State    types.DelegationState
+	SubState types.DelegationSubState
+}
+
+// delegationEventSearch is the attribute of a delegation event referencing the
+// delegation, searched on the BBN chain
+type delegationEventSearch struct {
+	eventType EventTypes
+	attribute string
+	// byStakingTx tells whether the attribute holds the staking tx, instead
+	// of its hash
+	byStakingTx bool
+}
+
+var delegationEventSearches = []delegationEventSearch{
+	{eventType: EventBTCDelegationCreated, attribute: "staking_tx_hex", byStakingTx: true},
+	{eventType: EventCovenantSignatureReceived, attribute: "staking_tx_hash"},
+	{eventType: EventCovenantQuorumReached, attribute: "staking_tx_hash"},
+	{eventType: EventBTCDelegationInclusionProofReceived, attribute: "staking_tx_hash"},
+	{eventType: EventBTCDelgationUnbondedEarly, attribute: "staking_tx_hash"},
+	{eventType: EventBTCDelegationExpired, attribute: "staking_tx_hash"},
+}
+
+// ReplayDelegation searches the BBN chain for the events referencing the
+// delegation and applies them again in order through the event handlers,
+// which ignore the ones already applied. The events of the finality providers
+// of the delegation, e.g. their slashing, are not replayed. As in a backfill,
+// the BTC spends are not watched.
+func (s *Service) ReplayDelegation(
+	ctx context.Context, req ReplayDelegationRequest,
+) ([]ReplayedEvent, *types.Error) {
+	ctx = logging.WithStakingTxHash(ctx, req.StakingTxHashHex.String())
+	chainDelegation, err := s.bbn.GetBTCDelegation(ctx, req.StakingTxHashHex.String())
+	if err != nil {
+		if errors.Is(err, bbnclient.ErrBTCDelegationNotFound) {
+			return nil, types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, "delegation not found on the BBN chain",
+			)
+		}
+		return nil, types.NewInternalServiceError(err)
+	}
+
+	heights, searchErr := s.searchDelegationEventHeights(ctx, req.StakingTxHashHex.String(), chainDelegation.StakingTxHex)
+	if searchErr != nil {
+		return nil, searchErr
+	}
+
+	if req.Reset {
+		if err := s.db.DeleteBTCDelegation(ctx, req.StakingTxHashHex.String()); err != nil && !db.IsNotFoundError(err) {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to delete BTC delegation: %w", err),
+			)
+		}
+		logging.FromContext(ctx).Info().Msg("deleted delegation before the replay")
+	}
+
+	// The replay applies the events through a service of its own so that no
+	// BTC spend is watched
+	replay := NewService(s.cfg, s.db, s.btc, nil, s.bbn, s.queueManager)
+
+	var replayed []ReplayedEvent
+	for _, height := range heights {
+		events, err := s.getEventsFromBlock(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if !eventReferencesDelegation(event.Event, req.StakingTxHashHex.String(), chainDelegation.StakingTxHex) {
+				continue
+			}
+			eventCtx := logging.WithBbnEvent(logging.WithBbnHeight(ctx, uint64(height)), event.Event.Type, "")
+			if err := replay.processEvent(eventCtx, event, height); err != nil {
+				return nil, err
+			}
+
+			replayedEvent := ReplayedEvent{BbnHeight: height, EventType: event.Event.Type}
+			delegation, dbErr := s.db.GetBTCDelegationByStakingTxHash(ctx, req.StakingTxHashHex.String())
+			if dbErr != nil && !db.IsNotFoundError(dbErr) {
+				return nil, types.NewInternalServiceError(
+					fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", dbErr),
+				)
+			}
+			if delegation != nil {
+				replayedEvent.State = delegation.State
+				replayedEvent.SubState = delegation.SubState
+			}
+			replayed = append(replayed, replayedEvent)
+		}
+	}
+
+	return replayed, nil
+}
+
+// searchDelegationEventHeights returns the BBN heights of the blocks with an
+// event referencing the delegation, in ascending order
+func (s *Service) searchDelegationEventHeights(
+	ctx context.Context, stakingTxHashHex, stakingTxHex string,
+) ([]int64, *types.Error) {
+	heights := make(map[int64]struct{})
+	for _, search := range delegationEventSearches {
+		value := stakingTxHashHex
+		if search.byStakingTx {
+			value = stakingTxHex
+		}
+		// The attributes of the typed events hold JSON values
+		query := fmt.Sprintf(`%s.%s='"%s"'`, search.eventType, search.attribute, value)
+		found, err := s.bbn.SearchEventHeights(ctx, query)
+		if err != nil {
+			return nil, types.NewError(
+				http.StatusInternalServerError,
+				types.ClientRequestError,
+				fmt.Errorf("failed to search %s events: %w", search.eventType, err),
+			)
+		}
+		for _, height := range found {
+			heights[height] = struct{}{}
+		}
+	}
+
+	sorted := make([]int64, 0, len(heights))
+	for height := range heights {
+		sorted = append(sorted, height)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted, nil
+}
+
+// eventReferencesDelegation returns whether the event is a delegation event of
+// the staking tx
+func eventReferencesDelegation(event abcitypes.Event, stakingTxHashHex, stakingTxHex string) bool {
+	for _, search := range delegationEventSearches {
+		if EventTypes(event.Type) != search.eventType {
+			continue
+		}
+		value := stakingTxHashHex
+		if search.byStakingTx {
+			value = stakingTxHex
+		}
+		for _, attr := range event.Attributes {
+			if attr.Key == search.attribute && strings.EqualFold(strings.Trim(attr.Value, `"`), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
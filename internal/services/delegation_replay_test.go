@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testReplayOtherTxHash = "0000000000000000000000000000000000000000000000000000000000000002"
+
+func covenantSignatureEvent(t *testing.T, stakingTxHash, covenantBtcPkHex string) abcitypes.Event {
+	event, err := sdk.TypedEventToEvent(&bbntypes.EventCovenantSignatureReceived{
+		StakingTxHash:                 stakingTxHash,
+		CovenantBtcPkHex:              covenantBtcPkHex,
+		CovenantUnbondingSignatureHex: "sig-" + covenantBtcPkHex,
+	})
+	require.NoError(t, err)
+	return abcitypes.Event(event)
+}
+
+func TestReplayDelegation(t *testing.T) {
+	ctx := context.Background()
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetBTCDelegation", mock.Anything, testReprocessTxHash).Return(&bbnclient.BTCDelegation{
+		StakingTxHashHex: testReprocessTxHash,
+		StakingTxHex:     "aabb",
+	}, nil)
+	bbnMock.On("SearchEventHeights", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, query string) ([]int64, error) {
+			if query == string(EventCovenantSignatureReceived)+`.staking_tx_hash='"`+testReprocessTxHash+`"'` {
+				return []int64{12, 10}, nil
+			}
+			return nil, nil
+		},
+	).Times(len(delegationEventSearches))
+	blocks := map[int64][]abcitypes.Event{
+		10: {
+			covenantSignatureEvent(t, testReplayOtherTxHash, "covenant1"),
+			covenantSignatureEvent(t, testReprocessTxHash, "covenant1"),
+		},
+		12: {covenantSignatureEvent(t, testReprocessTxHash, "covenant2")},
+	}
+	bbnMock.On("GetBlockResults", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+			return &ctypes.ResultBlockResults{
+				Height:     *height,
+				TxsResults: []*abcitypes.ExecTxResult{{Events: blocks[*height]}},
+			}, nil
+		},
+	).Twice()
+
+	dbMock := mocks.NewDbInterface(t)
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateActive,
+		CovenantUnbondingSignatures: []model.CovenantSignature{
+			{CovenantBtcPkHex: "covenant1", SignatureHex: "sig-covenant1"},
+		},
+	}
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testReprocessTxHash).Return(delegation, nil)
+	// the signature already indexed is ignored, the event of the other
+	// delegation is not replayed
+	dbMock.On(
+		"SaveBTCDelegationUnbondingCovenantSignature", mock.Anything, testReprocessTxHash, "covenant2", "sig-covenant2",
+	).Return(nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+	events, err := service.ReplayDelegation(ctx, ReplayDelegationRequest{StakingTxHashHex: testReprocessTxHash})
+	require.Nil(t, err)
+	require.Equal(t, []ReplayedEvent{
+		{BbnHeight: 10, EventType: string(EventCovenantSignatureReceived), State: types.StateActive},
+		{BbnHeight: 12, EventType: string(EventCovenantSignatureReceived), State: types.StateActive},
+	}, events)
+}
+
+func TestReplayDelegationReset(t *testing.T) {
+	ctx := context.Background()
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetBTCDelegation", mock.Anything, testReprocessTxHash).Return(&bbnclient.BTCDelegation{
+		StakingTxHashHex: testReprocessTxHash,
+		StakingTxHex:     "aabb",
+	}, nil)
+	bbnMock.On("SearchEventHeights", mock.Anything, mock.Anything).Return(nil, nil).Times(len(delegationEventSearches))
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("DeleteBTCDelegation", mock.Anything, testReprocessTxHash).Return(nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+	events, err := service.ReplayDelegation(ctx, ReplayDelegationRequest{
+		StakingTxHashHex: testReprocessTxHash,
+		Reset:            true,
+	})
+	require.Nil(t, err)
+	require.Empty(t, events)
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+)
+
+// ReprocessDelegation re-derives the state of an indexed delegation from its
+// BBN chain state and applies the correction the way the BBN events would, so
+// that the state transition and the consumer events are recorded. It returns
+// the corrected fields, none if the delegation matches the chain state.
+// It refuses to act while a BBN block is being processed, as the block may
+// be transitioning the delegation.
+func (s *Service) ReprocessDelegation(
+	ctx context.Context, stakingTxHash types.StakingTxHash,
+) ([]types.DelegationCorrection, *types.Error) {
+	lastProcessed, err := s.db.GetLastProcessedBbnBlock(ctx)
+	if err != nil && !db.IsNotFoundError(err) {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get last processed BBN block: %w", err),
+		)
+	}
+	if lastProcessed != nil && lastProcessed.ProcessingMarker != nil {
+		return nil, types.NewErrorWithMsg(
+			http.StatusConflict, types.Conflict,
+			fmt.Sprintf(
+				"BBN block %d is being processed, retry once it is applied",
+				lastProcessed.ProcessingMarker.Height,
+			),
+		)
+	}
+
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHash.String())
+	if err != nil {
+		if db.IsNotFoundError(err) {
+			return nil, types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, "delegation not found",
+			)
+		}
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
+		)
+	}
+
+	chainDelegation, err := s.bbn.GetBTCDelegation(ctx, stakingTxHash.String())
+	if err != nil {
+		if errors.Is(err, bbnclient.ErrBTCDelegationNotFound) {
+			return nil, types.NewErrorWithMsg(
+				http.StatusNotFound, types.NotFound, "delegation not found on the BBN chain",
+			)
+		}
+		return nil, types.NewInternalServiceError(err)
+	}
+
+	// Derive the state before correcting anything, so that a delegation whose
+	// state cannot be derived is left untouched
+	stateMatches := utils.Contains(chainStatusesForState(delegation.State), chainDelegation.Status)
+	var newState types.DelegationState
+	var newSubState types.DelegationSubState
+	if !stateMatches {
+		var stateErr *types.Error
+		newState, newSubState, stateErr = stateFromChainStatus(chainDelegation.Status)
+		if stateErr != nil {
+			return nil, stateErr
+		}
+		// e.g. an active delegation pending on chain, left to a manual
+		// override
+		if !delegation.State.CanTransitionTo(newState) {
+			return nil, types.NewErrorWithMsg(
+				http.StatusUnprocessableEntity, types.UnprocessableEntity,
+				fmt.Sprintf("a delegation cannot move from %s to %s", delegation.State, newState),
+			)
+		}
+	}
+
+	var corrections []types.DelegationCorrection
+
+	heightsCorrections := correctDelegationHeights(delegation, chainDelegation)
+	if len(heightsCorrections) > 0 {
+		if err := s.db.UpdateBTCDelegationDetails(ctx, stakingTxHash.String(), &model.BTCDelegationDetails{
+			StartHeight: delegation.StartHeight,
+			EndHeight:   delegation.EndHeight,
+		}); err != nil {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to update BTC delegation details: %w", err),
+			)
+		}
+		corrections = append(corrections, heightsCorrections...)
+	}
+
+	signaturesCorrection, sigErr := s.saveMissingCovenantSignatures(ctx, delegation, chainDelegation)
+	if sigErr != nil {
+		return nil, sigErr
+	}
+	if signaturesCorrection != nil {
+		corrections = append(corrections, *signaturesCorrection)
+	}
+
+	if !stateMatches {
+		if err := s.correctDelegationState(ctx, delegation, newState, newSubState); err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, types.DelegationCorrection{
+			Field: "state", From: delegation.State.String(), To: newState.String(),
+		})
+		if newSubState != delegation.SubState {
+			corrections = append(corrections, types.DelegationCorrection{
+				Field: "sub_state", From: delegation.SubState.String(), To: newSubState.String(),
+			})
+		}
+	}
+
+	return corrections, nil
+}
+
+// stateFromChainStatus returns the state a delegation enters on the BBN event
+// leading to the chain status. The early unbonding cannot be derived, as its
+// start height is only carried by the event.
+func stateFromChainStatus(
+	status string,
+) (types.DelegationState, types.DelegationSubState, *types.Error) {
+	switch status {
+	case bbntypes.BTCDelegationStatus_PENDING.String():
+		return types.StatePending, "", nil
+	case bbntypes.BTCDelegationStatus_VERIFIED.String():
+		return types.StateVerified, "", nil
+	case bbntypes.BTCDelegationStatus_ACTIVE.String():
+		return types.StateActive, "", nil
+	case bbntypes.BTCDelegationStatus_EXPIRED.String():
+		return types.StateUnbonding, types.SubStateTimelock, nil
+	default:
+		return "", "", types.NewErrorWithMsg(
+			http.StatusUnprocessableEntity, types.UnprocessableEntity,
+			fmt.Sprintf("the state of a delegation with chain status %s cannot be derived", status),
+		)
+	}
+}
+
+// correctDelegationHeights sets the staking heights of the delegation to the
+// chain ones and returns the changed fields
+func correctDelegationHeights(
+	delegation *model.BTCDelegationDetails, chainDelegation *bbnclient.BTCDelegation,
+) []types.DelegationCorrection {
+	var corrections []types.DelegationCorrection
+	// The heights are unset on chain until the inclusion proof is received
+	if chainDelegation.StartHeight != 0 && chainDelegation.StartHeight != delegation.StartHeight {
+		corrections = append(corrections, types.DelegationCorrection{
+			Field: "start_height",
+			From:  strconv.FormatUint(uint64(delegation.StartHeight), 10),
+			To:    strconv.FormatUint(uint64(chainDelegation.StartHeight), 10),
+		})
+		delegation.StartHeight = chainDelegation.StartHeight
+	}
+	if chainDelegation.EndHeight != 0 && chainDelegation.EndHeight != delegation.EndHeight {
+		corrections = append(corrections, types.DelegationCorrection{
+			Field: "end_height",
+			From:  strconv.FormatUint(uint64(delegation.EndHeight), 10),
+			To:    strconv.FormatUint(uint64(chainDelegation.EndHeight), 10),
+		})
+		delegation.EndHeight = chainDelegation.EndHeight
+	}
+	return corrections
+}
+
+// saveMissingCovenantSignatures saves the covenant unbonding signatures of the
+// chain missing locally, returning the correction of their count if any
+func (s *Service) saveMissingCovenantSignatures(
+	ctx context.Context, delegation *model.BTCDelegationDetails, chainDelegation *bbnclient.BTCDelegation,
+) (*types.DelegationCorrection, *types.Error) {
+	localSigs := make(map[string]struct{}, len(delegation.CovenantUnbondingSignatures))
+	for _, sig := range delegation.CovenantUnbondingSignatures {
+		localSigs[sig.CovenantBtcPkHex] = struct{}{}
+	}
+
+	saved := 0
+	for _, sig := range chainDelegation.CovenantUnbondingSignatures {
+		if _, ok := localSigs[sig.CovenantBtcPkHex]; ok {
+			continue
+		}
+		if err := s.db.SaveBTCDelegationUnbondingCovenantSignature(
+			ctx, delegation.StakingTxHashHex, sig.CovenantBtcPkHex, sig.SignatureHex,
+		); err != nil {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to save BTC delegation unbonding covenant signature: %w", err),
+			)
+		}
+		saved++
+	}
+	if saved == 0 {
+		return nil, nil
+	}
+
+	return &types.DelegationCorrection{
+		Field: "covenant_unbonding_signatures",
+		From:  strconv.Itoa(len(localSigs)),
+		To:    strconv.Itoa(len(localSigs) + saved),
+	}, nil
+}
+
+// correctDelegationState moves the delegation to the new state along with the
+// side effects of the BBN event leading to it
+func (s *Service) correctDelegationState(
+	ctx context.Context,
+	delegation *model.BTCDelegationDetails,
+	newState types.DelegationState,
+	newSubState types.DelegationSubState,
+) *types.Error {
+	switch newState {
+	case types.StateActive:
+		if err := s.emitActiveDelegationEvent(ctx, delegation, delegation.StartHeight); err != nil {
+			return err
+		}
+		if err := s.registerStakingSpendNotification(
+			ctx,
+			delegation.StakingTxHashHex,
+			delegation.StakingTxHex,
+			delegation.StakingOutputIdx,
+			delegation.StartHeight,
+		); err != nil {
+			return err
+		}
+	case types.StateUnbonding:
+		if err := s.db.SaveNewTimeLockExpire(
+			ctx, delegation.StakingTxHashHex, delegation.EndHeight, newSubState,
+		); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to save timelock expire: %w", err),
+			)
+		}
+	}
+
+	var subState *types.DelegationSubState
+	if newSubState != "" {
+		subState = &newSubState
+	}
+	if err := s.db.UpdateBTCDelegationState(
+		ctx,
+		delegation.StakingTxHashHex,
+		[]types.DelegationState{delegation.State},
+		newState,
+		subState,
+	); err != nil {
+		if db.IsStateTransitionError(err) {
+			return types.Wrap(
+				err, http.StatusConflict, types.Conflict, "delegation state changed during the reprocessing",
+			)
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to update BTC delegation state: %w", err),
+		)
+	}
+
+	if err := s.recordStateTransition(ctx, &model.DelegationStateTransition{
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		FromState:        delegation.State,
+		ToState:          newState,
+		SubState:         newSubState,
+		Trigger:          model.StateTransitionTriggerAdmin,
+		CreatedAt:        time.Now().Unix(),
+	}); err != nil {
+		return types.NewInternalServiceError(err)
+	}
+
+	// Emit consumer event once the transition is applied
+	if newState == types.StateUnbonding {
+		return s.emitUnbondingDelegationEvent(ctx, delegation, newSubState, "", delegation.EndHeight)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testReprocessTxHash = "0000000000000000000000000000000000000000000000000000000000000001"
+
+func TestReprocessDelegationExpired(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	bbnMock := mocks.NewBbnInterface(t)
+
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateActive,
+		StartHeight:      100,
+		EndHeight:        190,
+		CovenantUnbondingSignatures: []model.CovenantSignature{
+			{CovenantBtcPkHex: "covenant1", SignatureHex: "sig1"},
+		},
+	}
+	dbMock.On("GetLastProcessedBbnBlock", ctx).Return(&model.LastProcessedHeight{Height: 10}, nil)
+	dbMock.On("GetBTCDelegationByStakingTxHash", ctx, testReprocessTxHash).Return(delegation, nil)
+	bbnMock.On("GetBTCDelegation", ctx, testReprocessTxHash).Return(&bbnclient.BTCDelegation{
+		StakingTxHashHex: testReprocessTxHash,
+		StartHeight:      100,
+		EndHeight:        200,
+		Status:           bbntypes.BTCDelegationStatus_EXPIRED.String(),
+		CovenantUnbondingSignatures: []bbnclient.CovenantUnbondingSignature{
+			{CovenantBtcPkHex: "covenant1", SignatureHex: "sig1"},
+			{CovenantBtcPkHex: "covenant2", SignatureHex: "sig2"},
+		},
+	}, nil)
+
+	dbMock.On("UpdateBTCDelegationDetails", ctx, testReprocessTxHash, &model.BTCDelegationDetails{
+		StartHeight: 100,
+		EndHeight:   200,
+	}).Return(nil).Once()
+	dbMock.On(
+		"SaveBTCDelegationUnbondingCovenantSignature", ctx, testReprocessTxHash, "covenant2", "sig2",
+	).Return(nil).Once()
+	subState := types.SubStateTimelock
+	dbMock.On("SaveNewTimeLockExpire", ctx, testReprocessTxHash, uint32(200), subState).Return(nil).Once()
+	dbMock.On(
+		"UpdateBTCDelegationState", ctx, testReprocessTxHash,
+		[]types.DelegationState{types.StateActive}, types.StateUnbonding, &subState,
+	).Return(nil).Once()
+	dbMock.On("SaveDelegationStateTransition", ctx, mock.MatchedBy(func(transition *model.DelegationStateTransition) bool {
+		return transition.FromState == types.StateActive &&
+			transition.ToState == types.StateUnbonding &&
+			transition.SubState == subState &&
+			transition.Trigger == model.StateTransitionTriggerAdmin
+	})).Return(nil).Once()
+	dbMock.On("SaveOutboxEvent", ctx, mock.Anything).Return(nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+	corrections, err := service.ReprocessDelegation(ctx, testReprocessTxHash)
+	require.Nil(t, err)
+	require.Equal(t, []types.DelegationCorrection{
+		{Field: "end_height", From: "190", To: "200"},
+		{Field: "covenant_unbonding_signatures", From: "1", To: "2"},
+		{Field: "state", From: "ACTIVE", To: "UNBONDING"},
+		{Field: "sub_state", From: "", To: "TIMELOCK"},
+	}, corrections)
+}
+
+func TestReprocessDelegationConsistent(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	bbnMock := mocks.NewBbnInterface(t)
+
+	dbMock.On("GetLastProcessedBbnBlock", ctx).Return(nil, &db.NotFoundError{})
+	dbMock.On("GetBTCDelegationByStakingTxHash", ctx, testReprocessTxHash).Return(&model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateWithdrawn,
+		StartHeight:      100,
+		EndHeight:        200,
+	}, nil)
+	bbnMock.On("GetBTCDelegation", ctx, testReprocessTxHash).Return(&bbnclient.BTCDelegation{
+		StakingTxHashHex: testReprocessTxHash,
+		StartHeight:      100,
+		EndHeight:        200,
+		Status:           bbntypes.BTCDelegationStatus_UNBONDED.String(),
+	}, nil)
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+	corrections, err := service.ReprocessDelegation(ctx, testReprocessTxHash)
+	require.Nil(t, err)
+	require.Empty(t, corrections)
+}
+
+func TestReprocessDelegationRefused(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("block being processed", func(t *testing.T) {
+		dbMock := mocks.NewDbInterface(t)
+		dbMock.On("GetLastProcessedBbnBlock", ctx).Return(&model.LastProcessedHeight{
+			Height:           10,
+			ProcessingMarker: model.NewBbnProcessingMarker(11, "hash", 0),
+		}, nil)
+
+		service := NewService(&config.Config{}, dbMock, nil, nil, mocks.NewBbnInterface(t), nil)
+		_, err := service.ReprocessDelegation(ctx, testReprocessTxHash)
+		require.NotNil(t, err)
+		require.Equal(t, http.StatusConflict, err.StatusCode)
+	})
+
+	t.Run("early unbonding", func(t *testing.T) {
+		dbMock := mocks.NewDbInterface(t)
+		bbnMock := mocks.NewBbnInterface(t)
+		dbMock.On("GetLastProcessedBbnBlock", ctx).Return(&model.LastProcessedHeight{Height: 10}, nil)
+		dbMock.On("GetBTCDelegationByStakingTxHash", ctx, testReprocessTxHash).Return(&model.BTCDelegationDetails{
+			StakingTxHashHex: testReprocessTxHash,
+			State:            types.StateActive,
+		}, nil)
+		bbnMock.On("GetBTCDelegation", ctx, testReprocessTxHash).Return(&bbnclient.BTCDelegation{
+			StakingTxHashHex: testReprocessTxHash,
+			StartHeight:      100,
+			EndHeight:        200,
+			Status:           bbntypes.BTCDelegationStatus_UNBONDED.String(),
+		}, nil)
+
+		// Nothing is corrected, not even the heights
+		service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+		_, err := service.ReprocessDelegation(ctx, testReprocessTxHash)
+		require.NotNil(t, err)
+		require.Equal(t, http.StatusUnprocessableEntity, err.StatusCode)
+	})
+	t.Run("transition not allowed", func(t *testing.T) {
+		dbMock := mocks.NewDbInterface(t)
+		bbnMock := mocks.NewBbnInterface(t)
+		dbMock.On("GetLastProcessedBbnBlock", ctx).Return(&model.LastProcessedHeight{Height: 10}, nil)
+		dbMock.On("GetBTCDelegationByStakingTxHash", ctx, testReprocessTxHash).Return(&model.BTCDelegationDetails{
+			StakingTxHashHex: testReprocessTxHash,
+			State:            types.StateActive,
+		}, nil)
+		bbnMock.On("GetBTCDelegation", ctx, testReprocessTxHash).Return(&bbnclient.BTCDelegation{
+			StakingTxHashHex: testReprocessTxHash,
+			Status:           bbntypes.BTCDelegationStatus_PENDING.String(),
+		}, nil)
+
+		// An active delegation does not go back to pending
+		service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+		_, err := service.ReprocessDelegation(ctx, testReprocessTxHash)
+		require.NotNil(t, err)
+		require.Equal(t, http.StatusUnprocessableEntity, err.StatusCode)
+	})
+}
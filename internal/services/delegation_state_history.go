@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// isRejectedStateTransition tells whether a state update failed as the
+// delegation was found in a state not qualified for it, e.g. moved on by a
+// concurrent update. Such an update is a benign skip, counted and logged
+// rather than failed.
+func isRejectedStateTransition(ctx context.Context, err error) bool {
+	var transitionErr *db.StateTransitionError
+	if !errors.As(err, &transitionErr) {
+		return false
+	}
+
+	metrics.RecordStateTransitionRejected(
+		transitionErr.CurrentState.String(), transitionErr.TargetState.String(),
+	)
+	logging.FromContext(ctx).Info().
+		Str("current_state", transitionErr.CurrentState.String()).
+		Str("target_state", transitionErr.TargetState.String()).
+		Msg("skipping state transition rejected by the delegation state")
+	return true
+}
+
+// updateStateFrom moves the delegation from the state it was read in to the
+// target state. If a concurrent update moved it first, it is moved from its
+// new state instead as long as that one is qualified, so that the transition
+// is recorded from the state actually left. It returns false if the
+// delegation is no longer in a qualified state. The records of the change are
+// saved along with the update, only if it applies.
+func (s *Service) updateStateFrom(
+	ctx context.Context,
+	stakingTxHashHex string,
+	from types.DelegationState,
+	qualifiedStates []types.DelegationState,
+	target types.DelegationState,
+	subState *types.DelegationSubState,
+	records db.DelegationStateRecords,
+) (bool, error) {
+	// Every retry follows a concurrent transition of the delegation, which
+	// moves through each state at most once before reaching a terminal one
+	for range types.AllDelegationStates() {
+		err := s.db.ApplyBTCDelegationStateChange(ctx, db.DelegationStateChange{
+			DelegationStateUpdate: db.DelegationStateUpdate{
+				StakingTxHash:           stakingTxHashHex,
+				QualifiedPreviousStates: []types.DelegationState{from},
+				NewState:                target,
+				NewSubState:             subState,
+			},
+			DelegationStateRecords: records,
+		})
+		if err == nil {
+			return true, nil
+		}
+		var transitionErr *db.StateTransitionError
+		if errors.As(err, &transitionErr) && utils.Contains(qualifiedStates, transitionErr.CurrentState) {
+			from = transitionErr.CurrentState
+			continue
+		}
+		if isRejectedStateTransition(ctx, err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return false, fmt.Errorf(
+		"delegation %s kept moving while being updated to %s", stakingTxHashHex, target,
+	)
+}
+
+// recordStateTransition records a delegation's state transition once it is
+// applied. A transition is only applied once, as its replays are ignored by the
+// state checks, so it is recorded once.
+func (s *Service) recordStateTransition(
+	ctx context.Context, transition *model.DelegationStateTransition,
+) error {
+	if err := s.db.SaveDelegationStateTransition(ctx, transition); err != nil {
+		return fmt.Errorf(
+			"failed to record the transition of delegation %s to %s: %w",
+			transition.StakingTxHashHex, transition.ToState, err,
+		)
+	}
+	return nil
+}
+
+// recordRollbackTransition records the transition of a delegation whose BTC
+// derived changes above the fork height were rolled back, from the state of
+// its last recorded transition
+func (s *Service) recordRollbackTransition(
+	ctx context.Context, delegation *model.BTCDelegationDetails, forkHeight uint64,
+) error {
+	transitions, err := s.db.GetDelegationStateTransitions(ctx, delegation.StakingTxHashHex)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to get the state transitions of delegation %s: %w", delegation.StakingTxHashHex, err,
+		)
+	}
+
+	var fromState types.DelegationState
+	if len(transitions) > 0 {
+		fromState = transitions[len(transitions)-1].ToState
+	}
+	// Only the slashing tx was rolled back
+	if fromState == delegation.State {
+		return nil
+	}
+
+	return s.recordStateTransition(ctx, model.NewBtcStateTransition(
+		delegation.StakingTxHashHex, fromState, delegation.State, delegation.SubState,
+		model.StateTransitionTriggerBtcReorg, forkHeight, time.Now().Unix(),
+	))
+}
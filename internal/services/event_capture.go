@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+)
+
+// redactedValue replaces the value of a redacted attribute
+const redactedValue = "[redacted]"
+
+// EventRedactor is a hook telling whether the value of an attribute of a
+// captured BBN event is sensitive and must not be captured
+type EventRedactor func(eventType string, attribute abcitypes.EventAttribute) bool
+
+// RedactEventAttributes returns the redactor of the attributes of the given
+// keys, whatever the event
+func RedactEventAttributes(keys ...string) EventRedactor {
+	redacted := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		redacted[key] = struct{}{}
+	}
+	return func(eventType string, attribute abcitypes.EventAttribute) bool {
+		_, ok := redacted[attribute.Key]
+		return ok
+	}
+}
+
+// eventCapture captures the raw attributes of the BBN events failing their
+// processing and of a sample of the others
+type eventCapture struct {
+	sampleRate float64
+	redactors  []EventRedactor
+	// sample draws a number in [0, 1) against the sample rate
+	sample func() float64
+}
+
+// newEventCapture returns the capture of the config, nil if it is disabled
+func newEventCapture(cfg *config.EventCaptureConfig) *eventCapture {
+	if !cfg.Enabled {
+		return nil
+	}
+	capture := &eventCapture{sampleRate: cfg.SampleRate, sample: rand.Float64}
+	if len(cfg.RedactedAttributes) > 0 {
+		capture.redactors = append(capture.redactors, RedactEventAttributes(cfg.RedactedAttributes...))
+	}
+	return capture
+}
+
+// AddEventRedactor adds a hook redacting the sensitive attributes of the
+// captured BBN events, on top of the configured ones
+func (s *Service) AddEventRedactor(redactor EventRedactor) {
+	if s.eventCapture != nil {
+		s.eventCapture.redactors = append(s.eventCapture.redactors, redactor)
+	}
+}
+
+// captureBbnEvent captures the event at the index of the block if its
+// processing failed, or if it is sampled otherwise. A failure to capture the
+// event is only logged, not to hold up the processing.
+func (s *Service) captureBbnEvent(
+	ctx context.Context, height uint64, index int, event BbnEvent, processErr error,
+) {
+	capture := s.eventCapture
+	if capture == nil || (processErr == nil && capture.sample() >= capture.sampleRate) {
+		return
+	}
+
+	attributes := capture.rawAttributes(event)
+	rawEvent := &model.RawEventCapture{
+		CapturedAt: time.Now().Unix(),
+		BbnHeight:  height,
+		EventIndex: index,
+		EventType:  event.Event.Type,
+		Category:   string(event.Category),
+		Attributes: attributes,
+	}
+	if processErr != nil {
+		rawEvent.Error = processErr.Error()
+	}
+	if err := s.db.SaveRawEventCapture(ctx, rawEvent); err != nil {
+		logging.BlockProcessor.FromContext(ctx).Warn().Err(err).Msg("failed to capture the raw BBN event")
+	}
+}
+
+// rawAttributes returns the raw attributes of the event, the sensitive values
+// redacted. A nil capture redacts nothing.
+func (c *eventCapture) rawAttributes(event BbnEvent) []model.RawEventAttribute {
+	attributes := make([]model.RawEventAttribute, 0, len(event.Event.Attributes))
+	for _, attribute := range event.Event.Attributes {
+		raw := model.RawEventAttribute{Key: attribute.Key, Value: attribute.Value}
+		if c != nil {
+			for _, redactor := range c.redactors {
+				if redactor(event.Event.Type, attribute) {
+					raw.Value = redactedValue
+					raw.Redacted = true
+					break
+				}
+			}
+		}
+		attributes = append(attributes, raw)
+	}
+	return attributes
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// captureTestEnv wires the event capture to the marker test env, recording
+// the captured events
+func captureTestEnv(t *testing.T, sampleRate float64) (*markerTestEnv, *[]*model.RawEventCapture) {
+	env := newMarkerTestEnv(t)
+	env.service.eventCapture = newEventCapture(&config.EventCaptureConfig{
+		Enabled:            true,
+		SampleRate:         sampleRate,
+		MaxSizeBytes:       1 << 20,
+		RedactedAttributes: []string{"moniker"},
+	})
+
+	var captured []*model.RawEventCapture
+	env.service.db.(*mocks.DbInterface).On("SaveRawEventCapture", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, capture *model.RawEventCapture) error {
+			captured = append(captured, capture)
+			return nil
+		},
+	).Maybe()
+	return env, &captured
+}
+
+func TestCaptureFailedBbnEvent(t *testing.T) {
+	metrics.Init()
+	env, captured := captureTestEnv(t, 0)
+	env.killAt = 2
+
+	require.Error(t, env.processBlock(context.Background()))
+
+	// the successful events are not sampled at a rate of 0
+	require.Len(t, *captured, 1)
+	capture := (*captured)[0]
+	require.Equal(t, uint64(testMarkerHeight), capture.BbnHeight)
+	require.Equal(t, 2, capture.EventIndex)
+	require.Equal(t, "babylon.btcstaking.v1.EventFinalityProviderEdited", capture.EventType)
+	require.Equal(t, string(TxCategory), capture.Category)
+	require.Contains(t, capture.Error, "killed")
+
+	attributes := make(map[string]model.RawEventAttribute)
+	for _, attribute := range capture.Attributes {
+		attributes[attribute.Key] = attribute
+	}
+	require.Equal(t, strconv.Quote(fpBtcPkForEvent(2)), attributes["btc_pk_hex"].Value)
+	require.False(t, attributes["btc_pk_hex"].Redacted)
+	require.Equal(t, model.RawEventAttribute{Key: "moniker", Value: redactedValue, Redacted: true}, attributes["moniker"])
+}
+
+func TestCaptureSampledBbnEvents(t *testing.T) {
+	metrics.Init()
+	env, captured := captureTestEnv(t, 0.5)
+	// sample every other event
+	draws := 0
+	env.service.eventCapture.sample = func() float64 {
+		draws++
+		return float64(draws%2) * 0.9
+	}
+	// a redaction hook on top of the configured keys
+	env.service.AddEventRedactor(func(eventType string, attribute abcitypes.EventAttribute) bool {
+		return attribute.Key == "btc_pk_hex"
+	})
+
+	require.NoError(t, env.processBlock(context.Background()))
+
+	require.Len(t, *captured, 2)
+	for i, capture := range *captured {
+		require.Equal(t, 2*i+1, capture.EventIndex)
+		require.Empty(t, capture.Error)
+		for _, attribute := range capture.Attributes {
+			if attribute.Key == "btc_pk_hex" || attribute.Key == "moniker" {
+				require.True(t, attribute.Redacted, attribute.Key)
+				require.Equal(t, redactedValue, attribute.Value)
+			}
+		}
+	}
+}
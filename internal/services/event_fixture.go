@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// BbnEventFixture holds the raw events of a BBN block handled by the indexer,
+// in the format of the fixtures the parsing of the events is tested against
+type BbnEventFixture struct {
+	// BabylonVersion is the version of the Babylon node the events were
+	// captured from
+	BabylonVersion string                 `json:"babylon_version"`
+	ChainId        string                 `json:"chain_id"`
+	Height         int64                  `json:"height"`
+	Events         []BbnEventFixtureEvent `json:"events"`
+}
+
+// BbnEventFixtureEvent is a raw event of a fixture, its attributes as emitted
+type BbnEventFixtureEvent struct {
+	Category   EventCategory              `json:"category"`
+	Type       string                     `json:"type"`
+	Attributes []abcitypes.EventAttribute `json:"attributes"`
+}
+
+// BbnEvent returns the event as fetched from the block results
+func (e BbnEventFixtureEvent) BbnEvent() BbnEvent {
+	return NewBbnEvent(e.Category, abcitypes.Event{Type: e.Type, Attributes: e.Attributes})
+}
+
+// CaptureBbnEvents returns the events of the block at the height of the types
+// the indexer handles, the others being left out, as a fixture of the given
+// Babylon version
+func (s *Service) CaptureBbnEvents(
+	ctx context.Context, height int64, babylonVersion string,
+) (*BbnEventFixture, *types.Error) {
+	chainId, err := s.bbn.GetChainID(ctx)
+	if err != nil {
+		return nil, types.NewError(
+			http.StatusInternalServerError,
+			types.ClientRequestError,
+			fmt.Errorf("failed to get chain id: %w", err),
+		)
+	}
+
+	events, typesErr := s.getEventsFromBlock(ctx, height)
+	if typesErr != nil {
+		return nil, typesErr
+	}
+
+	fixture := &BbnEventFixture{
+		BabylonVersion: babylonVersion,
+		ChainId:        chainId,
+		Height:         height,
+		Events:         []BbnEventFixtureEvent{},
+	}
+	for _, event := range events {
+		if !processedEventTypes[EventTypes(event.Event.Type)] {
+			continue
+		}
+		fixture.Events = append(fixture.Events, BbnEventFixtureEvent{
+			Category:   event.Category,
+			Type:       event.Event.Type,
+			Attributes: event.Event.Attributes,
+		})
+	}
+	return fixture, nil
+}
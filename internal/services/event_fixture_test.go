@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+func TestCaptureBbnEventsKeepsHandledEvents(t *testing.T) {
+	bbnClient := fixtures.NewBbnClient(fixtures.MergeBlockResults(
+		fixtures.MustLoadBlockResults(fixtures.FpCreated),
+		fixtures.MustLoadBlockResults(fixtures.ParamsUpgrade),
+	))
+	service := NewService(&config.Config{}, nil, nil, nil, bbnClient, nil)
+
+	fixture, err := service.CaptureBbnEvents(context.Background(), 1, "v1.0.0-rc.2")
+	require.Nil(t, err)
+	require.Equal(t, "v1.0.0-rc.2", fixture.BabylonVersion)
+	require.Equal(t, "bbn-fixtures", fixture.ChainId)
+	require.Equal(t, int64(1), fixture.Height)
+	require.Len(t, fixture.Events, 1)
+	require.Equal(t, TxCategory, fixture.Events[0].Category)
+	require.Equal(t, EventFinalityProviderCreatedType.String(), fixture.Events[0].Type)
+
+	// The captured events parse back as they were fetched
+	raw, jsonErr := json.Marshal(fixture)
+	require.NoError(t, jsonErr)
+	var captured BbnEventFixture
+	require.NoError(t, json.Unmarshal(raw, &captured))
+	parsed := eventParsers[EventFinalityProviderCreatedType](captured.Events[0].BbnEvent().Event)
+	require.Empty(t, parsed.Error)
+}
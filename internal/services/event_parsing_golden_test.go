@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	ftypes "github.com/babylonlabs-io/babylon/x/finality/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	proto "github.com/cosmos/gogoproto/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the golden files of the event parsing from the
+// fixtures, to be reviewed as the parsing changes they reflect
+var updateGolden = flag.Bool("update-golden", false, "update the golden files")
+
+// goldenEventsDir holds a directory of fixtures by Babylon version, each
+// fixture next to the golden file of its parsed events
+const goldenEventsDir = "testdata/bbn_events"
+
+// parsedEvent is the outcome of the parsing of a fixture event, pinned by the
+// golden files
+type parsedEvent struct {
+	Type string `json:"type"`
+	// BsnId is the BSN id of a finality provider created
+	BsnId  string `json:"bsn_id,omitempty"`
+	Parsed any    `json:"parsed,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// eventParsers parse each handled event type the way its handler does
+var eventParsers = map[EventTypes]func(event abcitypes.Event) parsedEvent{
+	EventFinalityProviderCreatedType: func(event abcitypes.Event) parsedEvent {
+		bsnId, event := extractBsnIdFromEvent(event)
+		parsed := parseGoldenEvent[*bbntypes.EventFinalityProviderCreated](EventFinalityProviderCreatedType, event)
+		parsed.BsnId = bsnId
+		return parsed
+	},
+	EventFinalityProviderEditedType: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventFinalityProviderEdited](EventFinalityProviderEditedType, event)
+	},
+	EventFinalityProviderStatusChange: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventFinalityProviderStatusChange](EventFinalityProviderStatusChange, event)
+	},
+	EventBTCDelegationCreated: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventBTCDelegationCreated](EventBTCDelegationCreated, event)
+	},
+	EventCovenantQuorumReached: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventCovenantQuorumReached](EventCovenantQuorumReached, event)
+	},
+	EventCovenantSignatureReceived: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventCovenantSignatureReceived](EventCovenantSignatureReceived, event)
+	},
+	EventBTCDelegationInclusionProofReceived: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventBTCDelegationInclusionProofReceived](
+			EventBTCDelegationInclusionProofReceived, event,
+		)
+	},
+	EventBTCDelgationUnbondedEarly: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventBTCDelgationUnbondedEarly](EventBTCDelgationUnbondedEarly, event)
+	},
+	EventBTCDelegationExpired: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*bbntypes.EventBTCDelegationExpired](EventBTCDelegationExpired, event)
+	},
+	EventSlashedFinalityProvider: func(event abcitypes.Event) parsedEvent {
+		return parseGoldenEvent[*ftypes.EventSlashedFinalityProvider](EventSlashedFinalityProvider, event)
+	},
+}
+
+func parseGoldenEvent[T proto.Message](eventType EventTypes, event abcitypes.Event) parsedEvent {
+	parsed, err := parseEvent[T](eventType, event)
+	if err != nil {
+		return parsedEvent{Type: event.Type, Error: err.Error()}
+	}
+	return parsedEvent{Type: event.Type, Parsed: parsed}
+}
+
+func TestEventParsersCoverHandledTypes(t *testing.T) {
+	for eventType := range processedEventTypes {
+		require.Contains(t, eventParsers, eventType)
+	}
+	require.Len(t, eventParsers, len(processedEventTypes))
+}
+
+// TestGoldenEventParsing parses the events captured from each Babylon version
+// and compares the outcome with the golden files, so that a change of the
+// attribute encodings of a chain upgrade shows up as a golden diff
+func TestGoldenEventParsing(t *testing.T) {
+	fixturePaths, err := filepath.Glob(filepath.Join(goldenEventsDir, "*", "*.json"))
+	require.NoError(t, err)
+	versions := make(map[string]bool)
+
+	for _, fixturePath := range fixturePaths {
+		if strings.HasSuffix(fixturePath, ".golden.json") {
+			continue
+		}
+		versions[filepath.Base(filepath.Dir(fixturePath))] = true
+
+		t.Run(strings.TrimPrefix(fixturePath, goldenEventsDir+"/"), func(t *testing.T) {
+			raw, err := os.ReadFile(fixturePath)
+			require.NoError(t, err)
+			var fixture BbnEventFixture
+			require.NoError(t, json.Unmarshal(raw, &fixture))
+			require.Equal(t, filepath.Base(filepath.Dir(fixturePath)), fixture.BabylonVersion)
+			require.NotEmpty(t, fixture.Events)
+
+			parsedEvents := make([]parsedEvent, 0, len(fixture.Events))
+			for _, event := range fixture.Events {
+				parse, ok := eventParsers[EventTypes(event.Type)]
+				require.True(t, ok, "no parser of %s", event.Type)
+				parsedEvents = append(parsedEvents, parse(event.BbnEvent().Event))
+			}
+			got, err := json.MarshalIndent(parsedEvents, "", "  ")
+			require.NoError(t, err)
+			got = append(got, '\n')
+
+			goldenPath := strings.TrimSuffix(fixturePath, ".json") + ".golden.json"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, got, 0o644))
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			require.NoError(t, err, "missing golden file, run the test with -update-golden")
+			require.Equal(t, string(want), string(got))
+		})
+	}
+
+	// The parsing is pinned across chain upgrades
+	require.GreaterOrEqual(t, len(versions), 2)
+}
+
+// TestGoldenEventsParseAcrossVersions checks that the events of every
+// captured version parse without error, whatever their encoding
+func TestGoldenEventsParseAcrossVersions(t *testing.T) {
+	goldenPaths, err := filepath.Glob(filepath.Join(goldenEventsDir, "*", "*.golden.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, goldenPaths)
+
+	for _, goldenPath := range goldenPaths {
+		raw, err := os.ReadFile(goldenPath)
+		require.NoError(t, err)
+		var parsedEvents []parsedEvent
+		require.NoError(t, json.Unmarshal(raw, &parsedEvents))
+		for _, event := range parsedEvents {
+			require.Empty(t, event.Error, "%s: %s", goldenPath, event.Type)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// eventTransition is the delegation state transition a BBN event leads to
+type eventTransition struct {
+	// targets are the qualified previous states of every state the event
+	// moves a delegation to, the target being the state of the chain status
+	// the event carries. A target qualified from itself keeps the state.
+	targets map[types.DelegationState][]types.DelegationState
+	// subState is the sub state the delegation enters along, if any
+	subState types.DelegationSubState
+}
+
+// eventTransitions are the delegation state transitions of the BBN events
+// moving a single delegation, applied by applyEventTransition. The created
+// delegations are saved as pending, and the delegations of a slashed finality
+// provider are slashed from any state the state machine allows.
+var eventTransitions = map[EventTypes]eventTransition{
+	// The covenant quorum verifies a delegation, or activates it if its
+	// inclusion proof was received already
+	EventCovenantQuorumReached: {
+		targets: map[types.DelegationState][]types.DelegationState{
+			types.StateVerified: {types.StatePending},
+			types.StateActive:   {types.StatePending},
+		},
+	},
+	// The inclusion proof activates a verified delegation, while a pending one
+	// waits for the covenant quorum
+	EventBTCDelegationInclusionProofReceived: {
+		targets: map[types.DelegationState][]types.DelegationState{
+			types.StateActive:  {types.StateVerified},
+			types.StatePending: {types.StatePending},
+		},
+	},
+	EventBTCDelgationUnbondedEarly: {
+		targets: map[types.DelegationState][]types.DelegationState{
+			types.StateUnbonding: {types.StateActive},
+		},
+		subState: types.SubStateEarlyUnbonding,
+	},
+	EventBTCDelegationExpired: {
+		targets: map[types.DelegationState][]types.DelegationState{
+			types.StateUnbonding: {types.StateActive},
+		},
+		subState: types.SubStateTimelock,
+	},
+}
+
+// qualifiedStatesForEvent returns the states the event moves a delegation to
+// the target state from, none if the event does not lead to the target
+func qualifiedStatesForEvent(
+	eventType EventTypes, target types.DelegationState,
+) []types.DelegationState {
+	return eventTransitions[eventType].targets[target]
+}
+
+// applyEventTransition moves the delegation to the target state of the
+// event, along with the sub state of its transition, and records the
+// transition. It returns false if the update was rejected as the delegation
+// is no longer in a qualified state, in which case nothing is recorded.
+func (s *Service) applyEventTransition(
+	ctx context.Context,
+	eventType EventTypes,
+	delegation *model.BTCDelegationDetails,
+	target types.DelegationState,
+	bbnBlockHeight int64,
+) (bool, *types.Error) {
+	transition := eventTransitions[eventType]
+	var subState *types.DelegationSubState
+	if transition.subState != "" {
+		subState = &transition.subState
+	}
+
+	if err := s.db.UpdateBTCDelegationState(
+		ctx,
+		delegation.StakingTxHashHex,
+		qualifiedStatesForEvent(eventType, target),
+		target,
+		subState,
+	); err != nil {
+		if isRejectedStateTransition(ctx, err) {
+			return false, nil
+		}
+		return false, types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("failed to update BTC delegation state: %w", err),
+		)
+	}
+
+	if err := s.recordStateTransition(ctx, model.NewBbnStateTransition(
+		delegation.StakingTxHashHex, delegation.State, target, transition.subState,
+		eventType.String(), uint64(bbnBlockHeight), time.Now().Unix(),
+	)); err != nil {
+		return false, types.NewInternalServiceError(err)
+	}
+	return true, nil
+}
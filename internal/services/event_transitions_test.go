@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"github.com/btcsuite/btcd/wire"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventTransitionsFollowStateMachine(t *testing.T) {
+	for eventType, transition := range eventTransitions {
+		require.True(t, processedEventTypes[eventType], eventType.String())
+		require.NotEmpty(t, transition.targets, eventType.String())
+		if transition.subState != "" {
+			require.NoError(t, transition.subState.Validate(), eventType.String())
+		}
+
+		for target, qualified := range transition.targets {
+			require.NotEmpty(t, qualified, "%s: %s", eventType, target)
+			for _, state := range qualified {
+				// a target qualified from itself keeps the state
+				require.True(t, state == target || state.CanTransitionTo(target),
+					"%s: %s -> %s", eventType, state, target)
+			}
+		}
+	}
+
+	// only active delegations unbond, early or on expiry
+	for _, eventType := range []EventTypes{EventBTCDelgationUnbondedEarly, EventBTCDelegationExpired} {
+		require.Equal(t, []types.DelegationState{types.StateActive},
+			qualifiedStatesForEvent(eventType, types.StateUnbonding), eventType.String())
+		require.NotContains(t, qualifiedStatesForEvent(eventType, types.StateUnbonding), types.StateVerified)
+	}
+	require.Empty(t, qualifiedStatesForEvent(EventCovenantQuorumReached, types.StateUnbonding))
+	require.Empty(t, qualifiedStatesForEvent(EventCovenantSignatureReceived, types.StateActive))
+}
+
+func TestApplyEventTransition(t *testing.T) {
+	ctx := context.Background()
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateActive,
+	}
+	subState := types.SubStateTimelock
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On(
+		"UpdateBTCDelegationState", ctx, testReprocessTxHash,
+		[]types.DelegationState{types.StateActive}, types.StateUnbonding, &subState,
+	).Return(nil).Once()
+	dbMock.On("SaveDelegationStateTransition", ctx, mock.MatchedBy(func(transition *model.DelegationStateTransition) bool {
+		return transition.FromState == types.StateActive &&
+			transition.ToState == types.StateUnbonding &&
+			transition.SubState == subState &&
+			transition.Trigger == EventBTCDelegationExpired.String() &&
+			transition.BbnHeight == 42
+	})).Return(nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	applied, err := service.applyEventTransition(
+		ctx, EventBTCDelegationExpired, delegation, types.StateUnbonding, 42,
+	)
+	require.Nil(t, err)
+	require.True(t, applied)
+}
+
+func TestApplyEventTransitionRejected(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StatePending,
+	}
+
+	// nothing is recorded for a delegation verified in the meantime
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On(
+		"UpdateBTCDelegationState", ctx, testReprocessTxHash,
+		[]types.DelegationState{types.StatePending}, types.StateVerified, (*types.DelegationSubState)(nil),
+	).Return(&db.StateTransitionError{
+		StakingTxHash: testReprocessTxHash,
+		CurrentState:  types.StateVerified,
+		TargetState:   types.StateVerified,
+	}).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	applied, err := service.applyEventTransition(
+		ctx, EventCovenantQuorumReached, delegation, types.StateVerified, 42,
+	)
+	require.Nil(t, err)
+	require.False(t, applied)
+}
+
+// rejectingTransitionDb rejects every state update, as if the delegation was
+// slashed between the validation of an event and its transition
+type rejectingTransitionDb struct {
+	db.DbInterface
+}
+
+func (d rejectingTransitionDb) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	return &db.StateTransitionError{
+		StakingTxHash: stakingTxHash,
+		CurrentState:  types.StateSlashed,
+		TargetState:   newState,
+	}
+}
+
+// unexpectedSpendNotifier fails the test on any spend registration
+type unexpectedSpendNotifier struct {
+	notifier.ChainNotifier
+	t *testing.T
+}
+
+func (n unexpectedSpendNotifier) RegisterSpendNtfn(
+	*wire.OutPoint, []byte, uint32,
+) (*notifier.SpendEvent, error) {
+	n.t.Error("unexpected spend registration")
+	return nil, errors.New("unexpected spend registration")
+}
+
+func TestEventSideEffectsFollowAppliedTransition(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	d := fixtures.NewDelegation(1, 1)
+
+	for _, tc := range []struct {
+		name  string
+		state types.DelegationState
+		event sdk.Msg
+	}{
+		{
+			name:  "covenant quorum",
+			state: types.StatePending,
+			event: &bbntypes.EventCovenantQuorumReached{
+				StakingTxHash: d.StakingTxHashHex(),
+				NewState:      bbntypes.BTCDelegationStatus_ACTIVE.String(),
+			},
+		},
+		{
+			name:  "inclusion proof",
+			state: types.StateVerified,
+			event: d.InclusionProofReceivedEvent(100),
+		},
+		{
+			name:  "unbonded early",
+			state: types.StateActive,
+			event: d.UnbondedEarlyEvent(200),
+		},
+		{
+			name:  "expired",
+			state: types.StateActive,
+			event: &bbntypes.EventBTCDelegationExpired{
+				StakingTxHash: d.StakingTxHashHex(),
+				NewState:      types.SubStateTimelock.String(),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			database := inmemory.New()
+			details := fixtureDelegationDetails(t, d)
+			details.State = tc.state
+			details.StartHeight = 100
+			details.EndHeight = 100 + uint32(fixtures.StakingTime)
+			require.NoError(t, database.SaveNewBTCDelegation(ctx, details))
+
+			service := NewService(&config.Config{}, rejectingTransitionDb{database},
+				nil, unexpectedSpendNotifier{t: t}, nil, nil)
+			event, err := sdk.TypedEventToEvent(tc.event)
+			require.NoError(t, err)
+			require.Nil(t, service.processEvent(ctx, NewBbnEvent(BlockCategory, abcitypes.Event(event)), 10))
+
+			// Nothing follows the rejected transition
+			outboxEvents, err := database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+			require.NoError(t, err)
+			require.Empty(t, outboxEvents)
+			timeLocks, err := database.GetTimeLocks(ctx, d.StakingTxHashHex())
+			require.NoError(t, err)
+			require.Empty(t, timeLocks)
+			transitions, err := database.GetDelegationStateTransitions(ctx, d.StakingTxHashHex())
+			require.NoError(t, err)
+			require.Empty(t, transitions)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 	bstypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
@@ -14,7 +15,6 @@ import (
 	abcitypes "github.com/cometbft/cometbft/abci/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	proto "github.com/cosmos/gogoproto/proto"
-	"github.com/rs/zerolog/log"
 )
 
 type EventTypes string
@@ -43,6 +43,30 @@ func NewBbnEvent(category EventCategory, event abcitypes.Event) BbnEvent {
 	}
 }
 
+// processedEventTypes are the BBN event types processEvent handles, the bounded
+// set of event type labels of the event processing metrics
+var processedEventTypes = map[EventTypes]bool{
+	EventFinalityProviderCreatedType:         true,
+	EventFinalityProviderEditedType:          true,
+	EventFinalityProviderStatusChange:        true,
+	EventBTCDelegationCreated:                true,
+	EventCovenantQuorumReached:               true,
+	EventCovenantSignatureReceived:           true,
+	EventBTCDelegationInclusionProofReceived: true,
+	EventBTCDelgationUnbondedEarly:           true,
+	EventBTCDelegationExpired:                true,
+	EventSlashedFinalityProvider:             true,
+}
+
+// eventTypeLabel returns the event type label of the event processing metrics,
+// "other" for the event types not processed
+func eventTypeLabel(eventType string) string {
+	if processedEventTypes[EventTypes(eventType)] {
+		return eventType
+	}
+	return "other"
+}
+
 // Entry point for processing events
 func (s *Service) processEvent(
 	ctx context.Context,
@@ -57,39 +81,39 @@ func (s *Service) processEvent(
 
 	switch EventTypes(bbnEvent.Type) {
 	case EventFinalityProviderCreatedType:
-		log.Debug().Msg("Processing new finality provider event")
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing new finality provider event")
 		err = s.processNewFinalityProviderEvent(ctx, bbnEvent)
 	case EventFinalityProviderEditedType:
-		log.Debug().Msg("Processing finality provider edited event")
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing finality provider edited event")
 		err = s.processFinalityProviderEditedEvent(ctx, bbnEvent)
 	case EventFinalityProviderStatusChange:
-		log.Debug().Msg("Processing finality provider status change event")
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing finality provider status change event")
 		err = s.processFinalityProviderStateChangeEvent(ctx, bbnEvent)
 	case EventBTCDelegationCreated:
-		log.Debug().Msg("Processing new BTC delegation event")
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing new BTC delegation event")
 		err = s.processNewBTCDelegationEvent(ctx, bbnEvent, blockHeight)
 	case EventCovenantQuorumReached:
-		log.Debug().Msg("Processing covenant quorum reached event")
-		err = s.processCovenantQuorumReachedEvent(ctx, bbnEvent)
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing covenant quorum reached event")
+		err = s.processCovenantQuorumReachedEvent(ctx, bbnEvent, blockHeight)
 	case EventCovenantSignatureReceived:
-		log.Debug().Msg("Processing covenant signature received event")
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing covenant signature received event")
 		err = s.processCovenantSignatureReceivedEvent(ctx, bbnEvent)
 	case EventBTCDelegationInclusionProofReceived:
-		log.Debug().Msg("Processing BTC delegation inclusion proof received event")
-		err = s.processBTCDelegationInclusionProofReceivedEvent(ctx, bbnEvent)
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing BTC delegation inclusion proof received event")
+		err = s.processBTCDelegationInclusionProofReceivedEvent(ctx, bbnEvent, blockHeight)
 	case EventBTCDelgationUnbondedEarly:
-		log.Debug().Msg("Processing BTC delegation unbonded early event")
-		err = s.processBTCDelegationUnbondedEarlyEvent(ctx, bbnEvent)
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing BTC delegation unbonded early event")
+		err = s.processBTCDelegationUnbondedEarlyEvent(ctx, bbnEvent, blockHeight)
 	case EventBTCDelegationExpired:
-		log.Debug().Msg("Processing BTC delegation expired event")
-		err = s.processBTCDelegationExpiredEvent(ctx, bbnEvent)
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing BTC delegation expired event")
+		err = s.processBTCDelegationExpiredEvent(ctx, bbnEvent, blockHeight)
 	case EventSlashedFinalityProvider:
-		log.Debug().Msg("Processing slashed finality provider event")
-		err = s.processSlashedFinalityProviderEvent(ctx, bbnEvent)
+		logging.BlockProcessor.FromContext(ctx).Debug().Msg("Processing slashed finality provider event")
+		err = s.processSlashedFinalityProviderEvent(ctx, bbnEvent, blockHeight)
 	}
 
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to process event")
+		logging.BlockProcessor.FromContext(ctx).Error().Err(err).Msg("Failed to process event")
 		return err
 	}
 
@@ -117,13 +141,13 @@ func parseEvent[T proto.Message](
 
 	// Check if the event has attributes
 	if len(event.Attributes) == 0 {
-		return result, types.NewErrorWithMsg(
+		return result, types.NewError(
 			http.StatusInternalServerError,
 			types.InternalServiceError,
-			fmt.Sprintf(
+			types.NewPermanentError(fmt.Errorf(
 				"no attributes found in the %s event",
 				expectedType,
-			),
+			)),
 		)
 	}
 
@@ -133,11 +157,11 @@ func parseEvent[T proto.Message](
 	// Use the SDK's ParseTypedEvent function
 	protoMsg, err := sdk.ParseTypedEvent(sanitizedEvent)
 	if err != nil {
-		log.Debug().Interface("raw_event", event).Msg("Raw event data")
+		logging.BlockProcessor.Logger().Debug().Interface("raw_event", event).Msg("Raw event data")
 		return result, types.NewError(
 			http.StatusInternalServerError,
 			types.InternalServiceError,
-			fmt.Errorf("failed to parse typed event: %w", err),
+			types.NewPermanentError(fmt.Errorf("failed to parse typed event: %w", err)),
 		)
 	}
 
@@ -147,13 +171,53 @@ func parseEvent[T proto.Message](
 		return result, types.NewError(
 			http.StatusInternalServerError,
 			types.InternalServiceError,
-			fmt.Errorf("parsed event type %T does not match expected type %T", protoMsg, result),
+			types.NewPermanentError(
+				fmt.Errorf("parsed event type %T does not match expected type %T", protoMsg, result),
+			),
 		)
 	}
 
+	if err := normalizeEventStakingTxHash(concreteMsg); err != nil {
+		return result, types.NewValidationFailedError(types.NewPermanentError(
+			fmt.Errorf("invalid staking tx hash in the %s event: %w", expectedType, err),
+		))
+	}
+
 	return concreteMsg, nil
 }
 
+// normalizeEventStakingTxHash normalizes the staking tx hash of the delegation
+// events, so that the delegation is looked up the way it is stored whatever
+// the casing the chain emitted. A missing hash is left to the event
+// validation.
+func normalizeEventStakingTxHash(event proto.Message) error {
+	var stakingTxHashHex *string
+	switch event := event.(type) {
+	case *bstypes.EventCovenantSignatureReceived:
+		stakingTxHashHex = &event.StakingTxHash
+	case *bstypes.EventCovenantQuorumReached:
+		stakingTxHashHex = &event.StakingTxHash
+	case *bstypes.EventBTCDelegationInclusionProofReceived:
+		stakingTxHashHex = &event.StakingTxHash
+	case *bstypes.EventBTCDelgationUnbondedEarly:
+		stakingTxHashHex = &event.StakingTxHash
+	case *bstypes.EventBTCDelegationExpired:
+		stakingTxHashHex = &event.StakingTxHash
+	default:
+		return nil
+	}
+	if *stakingTxHashHex == "" {
+		return nil
+	}
+
+	stakingTxHash, err := types.NewStakingTxHash(*stakingTxHashHex)
+	if err != nil {
+		return err
+	}
+	*stakingTxHashHex = stakingTxHash.String()
+	return nil
+}
+
 func (s *Service) validateBTCDelegationCreatedEvent(event *bstypes.EventBTCDelegationCreated) *types.Error {
 	// Check if the staking tx hex is present
 	if event.StakingTxHex == "" {
@@ -169,7 +233,13 @@ func (s *Service) validateBTCDelegationCreatedEvent(event *bstypes.EventBTCDeleg
 	}
 
 	// Validate the event state
-	if event.NewState != bstypes.BTCDelegationStatus_PENDING.String() {
+	newState, err := types.ParseDelegationState(event.NewState)
+	if err != nil {
+		return types.NewValidationFailedError(
+			fmt.Errorf("invalid delegation state from Babylon when processing EventBTCDelegationCreated: %w", err),
+		)
+	}
+	if newState != types.StatePending {
 		return types.NewValidationFailedError(
 			fmt.Errorf("invalid delegation state from Babylon when processing EventBTCDelegationCreated: expected PENDING, got %s", event.NewState),
 		)
@@ -198,8 +268,15 @@ func (s *Service) validateCovenantQuorumReachedEvent(ctx context.Context, event
 		)
 	}
 
+	newState, err := types.ParseDelegationState(event.NewState)
+	if err != nil {
+		return false, types.NewValidationFailedError(
+			fmt.Errorf("invalid delegation state from Babylon: %w", err),
+		)
+	}
+
 	// Retrieve the qualified states for the intended transition
-	qualifiedStates := types.QualifiedStatesForCovenantQuorumReached(event.NewState)
+	qualifiedStates := qualifiedStatesForEvent(EventCovenantQuorumReached, newState)
 	if qualifiedStates == nil {
 		return false, types.NewValidationFailedError(
 			fmt.Errorf("invalid delegation state from Babylon: %s", event.NewState),
@@ -208,34 +285,31 @@ func (s *Service) validateCovenantQuorumReachedEvent(ctx context.Context, event
 
 	// Check if the current state is qualified for the transition
 	if !utils.Contains(qualifiedStates, delegation.State) {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
+		logging.BlockProcessor.FromContext(ctx).Debug().
 			Str("currentState", delegation.State.String()).
 			Str("newState", event.NewState).
 			Msg("Ignoring EventCovenantQuorumReached because current state is not qualified for transition")
 		return false, nil // Ignore the event silently
 	}
 
-	if event.NewState == bstypes.BTCDelegationStatus_VERIFIED.String() {
+	if newState == types.StateVerified {
 		// This will only happen if the staker is following the new pre-approval flow.
 		// For more info read https://github.com/babylonlabs-io/pm/blob/main/rfc/rfc-008-staking-transaction-pre-approval.md#handling-of-the-modified--msgcreatebtcdelegation-message
 
 		// Delegation should not have the inclusion proof yet
 		if delegation.HasInclusionProof() {
-			log.Debug().
-				Str("stakingTxHashHex", event.StakingTxHash).
+			logging.BlockProcessor.FromContext(ctx).Debug().
 				Str("currentState", delegation.State.String()).
 				Str("newState", event.NewState).
 				Msg("Ignoring EventCovenantQuorumReached because inclusion proof already received")
 			return false, nil
 		}
-	} else if event.NewState == bstypes.BTCDelegationStatus_ACTIVE.String() {
+	} else if newState == types.StateActive {
 		// This will happen if the inclusion proof is received in MsgCreateBTCDelegation, i.e the staker is following the old flow
 
 		// Delegation should have the inclusion proof
 		if !delegation.HasInclusionProof() {
-			log.Debug().
-				Str("stakingTxHashHex", event.StakingTxHash).
+			logging.BlockProcessor.FromContext(ctx).Debug().
 				Str("currentState", delegation.State.String()).
 				Str("newState", event.NewState).
 				Msg("Ignoring EventCovenantQuorumReached because inclusion proof not received")
@@ -266,8 +340,15 @@ func (s *Service) validateBTCDelegationInclusionProofReceivedEvent(ctx context.C
 		)
 	}
 
+	newState, err := types.ParseDelegationState(event.NewState)
+	if err != nil {
+		return false, types.NewValidationFailedError(
+			fmt.Errorf("invalid delegation state from Babylon: %w", err),
+		)
+	}
+
 	// Retrieve the qualified states for the intended transition
-	qualifiedStates := types.QualifiedStatesForInclusionProofReceived(event.NewState)
+	qualifiedStates := qualifiedStatesForEvent(EventBTCDelegationInclusionProofReceived, newState)
 	if qualifiedStates == nil {
 		return false, types.NewValidationFailedError(
 			fmt.Errorf("no qualified states defined for new state: %s", event.NewState),
@@ -276,8 +357,7 @@ func (s *Service) validateBTCDelegationInclusionProofReceivedEvent(ctx context.C
 
 	// Check if the current state is qualified for the transition
 	if !utils.Contains(qualifiedStates, delegation.State) {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
+		logging.BlockProcessor.FromContext(ctx).Debug().
 			Str("currentState", delegation.State.String()).
 			Str("newState", event.NewState).
 			Msg("Ignoring EventBTCDelegationInclusionProofReceived because current state is not qualified for transition")
@@ -287,8 +367,7 @@ func (s *Service) validateBTCDelegationInclusionProofReceivedEvent(ctx context.C
 	// Delegation should not have the inclusion proof yet
 	// After this event is processed, the inclusion proof will be set
 	if delegation.HasInclusionProof() {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
+		logging.BlockProcessor.FromContext(ctx).Debug().
 			Str("currentState", delegation.State.String()).
 			Str("newState", event.NewState).
 			Msg("Ignoring EventBTCDelegationInclusionProofReceived because inclusion proof already received")
@@ -309,7 +388,7 @@ func (s *Service) validateBTCDelegationUnbondedEarlyEvent(ctx context.Context, e
 	}
 
 	// Validate the event state
-	if event.NewState != bstypes.BTCDelegationStatus_UNBONDED.String() {
+	if subState, err := types.ParseDelegationSubState(event.NewState); err != nil || subState != eventTransitions[EventBTCDelgationUnbondedEarly].subState {
 		return false, types.NewValidationFailedError(
 			fmt.Errorf("invalid delegation state from Babylon when processing EventBTCDelgationUnbondedEarly: expected UNBONDED, got %s", event.NewState),
 		)
@@ -326,9 +405,8 @@ func (s *Service) validateBTCDelegationUnbondedEarlyEvent(ctx context.Context, e
 	}
 
 	// Check if the current state is qualified for the transition
-	if !utils.Contains(types.QualifiedStatesForUnbondedEarly(), delegation.State) {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
+	if !utils.Contains(qualifiedStatesForEvent(EventBTCDelgationUnbondedEarly, types.StateUnbonding), delegation.State) {
+		logging.BlockProcessor.FromContext(ctx).Debug().
 			Str("currentState", delegation.State.String()).
 			Msg("Ignoring EventBTCDelgationUnbondedEarly because current state is not qualified for transition")
 		return false, nil
@@ -348,7 +426,7 @@ func (s *Service) validateBTCDelegationExpiredEvent(ctx context.Context, event *
 	}
 
 	// Validate the event state
-	if event.NewState != bstypes.BTCDelegationStatus_EXPIRED.String() {
+	if subState, err := types.ParseDelegationSubState(event.NewState); err != nil || subState != eventTransitions[EventBTCDelegationExpired].subState {
 		return false, types.NewValidationFailedError(
 			fmt.Errorf("invalid delegation state from Babylon when processing EventBTCDelegationExpired: expected EXPIRED, got %s", event.NewState),
 		)
@@ -365,9 +443,8 @@ func (s *Service) validateBTCDelegationExpiredEvent(ctx context.Context, event *
 	}
 
 	// Check if the current state is qualified for the transition
-	if !utils.Contains(types.QualifiedStatesForExpired(), delegation.State) {
-		log.Debug().
-			Str("stakingTxHashHex", event.StakingTxHash).
+	if !utils.Contains(qualifiedStatesForEvent(EventBTCDelegationExpired, types.StateUnbonding), delegation.State) {
+		logging.BlockProcessor.FromContext(ctx).Debug().
 			Str("currentState", delegation.State.String()).
 			Msg("Ignoring EventBTCDelegationExpired because current state is not qualified for transition")
 		return false, nil
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEventNormalizesStakingTxHash(t *testing.T) {
+	toEvent := func(stakingTxHash string) abcitypes.Event {
+		event, err := sdk.TypedEventToEvent(&bbntypes.EventBTCDelegationExpired{
+			StakingTxHash: stakingTxHash,
+			NewState:      bbntypes.BTCDelegationStatus_EXPIRED.String(),
+		})
+		require.NoError(t, err)
+		return abcitypes.Event(event)
+	}
+
+	// the delegation is looked up lowercase whatever the casing of the chain
+	expired, err := parseEvent[*bbntypes.EventBTCDelegationExpired](
+		EventBTCDelegationExpired, toEvent(strings.ToUpper(testReprocessTxHash)),
+	)
+	require.Nil(t, err)
+	require.Equal(t, testReprocessTxHash, expired.StakingTxHash)
+
+	// the missing hash is left to the event validation
+	expired, err = parseEvent[*bbntypes.EventBTCDelegationExpired](EventBTCDelegationExpired, toEvent(""))
+	require.Nil(t, err)
+	require.Empty(t, expired.StakingTxHash)
+
+	_, err = parseEvent[*bbntypes.EventBTCDelegationExpired](EventBTCDelegationExpired, toEvent("0d0a"))
+	require.NotNil(t, err)
+	require.False(t, types.IsRetryable(err))
+}
@@ -2,25 +2,47 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
-	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
-	"github.com/rs/zerolog/log"
 )
 
+// errExpiryLimitReached stops the iteration over the expired delegations of
+// a run once the limit of them is processed
+var errExpiryLimitReached = errors.New("expired delegations limit reached")
+
 func (s *Service) StartExpiryChecker(ctx context.Context) {
-	expiryCheckerPoller := poller.NewPoller(
+	expiryCheckerPoller := s.newPoller(
+		"expiry_checker",
 		s.cfg.Poller.ExpiryCheckerPollingInterval,
-		s.checkExpiry,
+		s.pausable(s.checkExpiry),
+		// withdrawable delegations are not reported while the runs fail
+		criticalPoller(5*s.cfg.Poller.ExpiryCheckerPollingInterval),
 	)
-	go expiryCheckerPoller.Start(ctx)
+	s.startPoller(ctx, expiryCheckerPoller)
+}
+
+// RunExpiryCheck runs the expiry checker once, outside of its poller, e.g. to
+// benchmark a batch of expired delegations
+func (s *Service) RunExpiryCheck(ctx context.Context) *types.Error {
+	return s.checkExpiry(ctx)
 }
 
 func (s *Service) checkExpiry(ctx context.Context) *types.Error {
+	defer func(start time.Time) {
+		metrics.RecordExpiryCycle(time.Since(start))
+	}(time.Now())
+
 	btcTip, err := s.btc.GetTipHeight()
 	if err != nil {
 		return types.NewInternalServiceError(
@@ -28,63 +50,185 @@ func (s *Service) checkExpiry(ctx context.Context) *types.Error {
 		)
 	}
 
-	expiredDelegations, err := s.db.FindExpiredDelegations(ctx, uint64(btcTip), s.cfg.Poller.ExpiredDelegationsLimit)
-	if err != nil {
+	// Recorded before processing, so that a backlog failing to be processed
+	// still shows
+	if err := s.recordExpiryBacklog(ctx, btcTip); err != nil {
+		return err
+	}
+
+	// The expired delegations are processed as read, at most the limit of
+	// them a run
+	var expired uint64
+	var expireErr *types.Error
+	err = s.db.ForEachExpiredDelegation(ctx, uint64(btcTip), func(tlDoc model.TimeLockDocument) error {
+		if expired == s.cfg.Poller.ExpiredDelegationsLimit {
+			return errExpiryLimitReached
+		}
+		expired++
+		if expireErr = s.expireTimeLock(ctx, tlDoc, btcTip); expireErr != nil {
+			return expireErr
+		}
+		return nil
+	})
+	switch {
+	case expireErr != nil:
+		return expireErr
+	case err != nil && !errors.Is(err, errExpiryLimitReached):
 		return types.NewInternalServiceError(
 			fmt.Errorf("failed to find expired delegations: %w", err),
 		)
 	}
 
-	for _, tlDoc := range expiredDelegations {
-		delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, tlDoc.StakingTxHashHex)
-		if err != nil {
-			return types.NewError(
-				http.StatusInternalServerError,
-				types.InternalServiceError,
-				fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
-			)
-		}
+	return nil
+}
 
-		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
-			Str("current_state", delegation.State.String()).
-			Str("new_sub_state", tlDoc.DelegationSubState.String()).
-			Str("expire_height", strconv.FormatUint(uint64(tlDoc.ExpireHeight), 10)).
-			Msg("checking if delegation is expired")
-
-		// Check if the delegation is in a qualified state to transition to Withdrawable
-		if !utils.Contains(types.QualifiedStatesForWithdrawable(), delegation.State) {
-			log.Debug().
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Str("current_state", delegation.State.String()).
-				Msg("current state is not qualified for withdrawable")
+// recordExpiryBacklog publishes the number of expired timelocks and the age
+// of the oldest one by sub state, as zero for the sub states without any
+func (s *Service) recordExpiryBacklog(ctx context.Context, btcTip uint64) *types.Error {
+	backlog, err := s.db.GetExpiryBacklogStats(ctx, btcTip)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to aggregate the expiry backlog: %w", err),
+		)
+	}
+
+	bySubState := make(map[types.DelegationSubState]*model.ExpiryBacklogStats, len(backlog))
+	for _, stats := range backlog {
+		bySubState[stats.SubState] = stats
+	}
+	for _, subState := range types.TimeLockSubStates() {
+		stats, ok := bySubState[subState]
+		if !ok {
+			metrics.RecordExpiryBacklog(subState.String(), 0, 0)
 			continue
 		}
+		metrics.RecordExpiryBacklog(
+			subState.String(), stats.Count, lag(btcTip, uint64(stats.OldestExpireHeight)),
+		)
+	}
 
-		if err := s.db.UpdateBTCDelegationState(
-			ctx,
-			delegation.StakingTxHashHex,
-			types.QualifiedStatesForWithdrawable(),
-			types.StateWithdrawable,
-			&tlDoc.DelegationSubState,
-		); err != nil {
-			log.Error().
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Msg("failed to update BTC delegation state to withdrawable")
-			return types.NewInternalServiceError(
-				fmt.Errorf("failed to update BTC delegation state to withdrawable: %w", err),
-			)
-		}
+	return nil
+}
+
+// expireTimeLock transitions the delegation of the expired timelock document
+// to Withdrawable and deletes its timelock documents
+func (s *Service) expireTimeLock(
+	ctx context.Context, tlDoc model.TimeLockDocument, btcTip uint64,
+) *types.Error {
+	ctx = logging.WithStakingTxHash(ctx, tlDoc.StakingTxHashHex)
+	ctx = audit.WithTrigger(ctx, audit.Trigger{Type: audit.TriggerExpiry, BtcHeight: btcTip})
+	// The transactions of the delegation are not of use to its expiry
+	delegation, err := s.db.GetBTCDelegationSummaryByStakingTxHash(ctx, tlDoc.StakingTxHashHex)
+	if err != nil {
+		return types.NewError(
+			http.StatusInternalServerError,
+			types.InternalServiceError,
+			fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
+		)
+	}
 
-		if err := s.db.DeleteExpiredDelegation(ctx, delegation.StakingTxHashHex); err != nil {
-			log.Error().
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Msg("failed to delete expired delegation")
-			return types.NewInternalServiceError(
-				fmt.Errorf("failed to delete expired delegation: %w", err),
-			)
+	logging.Expiry.FromContext(ctx).Debug().
+		Str("current_state", delegation.State.String()).
+		Str("new_sub_state", tlDoc.DelegationSubState.String()).
+		Str("expire_height", strconv.FormatUint(uint64(tlDoc.ExpireHeight), 10)).
+		Msg("checking if delegation is expired")
+
+	// A run failing after the transition leaves the timelock behind
+	if delegation.State == types.StateWithdrawable && delegation.SubState == tlDoc.DelegationSubState {
+		return s.completeTimeLockExpiry(ctx, delegation, tlDoc, btcTip)
+	}
+
+	// Check if the delegation is in a qualified state to transition to Withdrawable
+	if !utils.Contains(types.QualifiedStatesForWithdrawable(), delegation.State) {
+		logging.Expiry.FromContext(ctx).Debug().
+			Str("current_state", delegation.State.String()).
+			Msg("current state is not qualified for withdrawable")
+		return nil
+	}
+
+	change := model.NewBTCStateChange(delegation, btcTip)
+	change.DeletedTimeLock = &tlDoc
+	applied, err := s.updateStateFrom(
+		ctx,
+		delegation.StakingTxHashHex,
+		delegation.State,
+		types.QualifiedStatesForWithdrawable(),
+		types.StateWithdrawable,
+		&tlDoc.DelegationSubState,
+		db.DelegationStateRecords{
+			BTCDerivedChange: change,
+			Transition: model.NewBtcStateTransition(
+				delegation.StakingTxHashHex, delegation.State, types.StateWithdrawable, tlDoc.DelegationSubState,
+				model.StateTransitionTriggerExpiry, btcTip, time.Now().Unix(),
+			),
+			OutboxEvent: model.NewWithdrawableStakingOutboxEvent(
+				delegation, tlDoc.DelegationSubState, tlDoc.ExpireHeight, time.Now().UnixNano(),
+			),
+		},
+	)
+	if err != nil {
+		logging.Expiry.FromContext(ctx).Error().
+			Msg("failed to update BTC delegation state to withdrawable")
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to update BTC delegation state to withdrawable: %w", err),
+		)
+	}
+	if !applied {
+		return nil
+	}
+
+	return s.deleteExpiredTimeLock(ctx, delegation, tlDoc)
+}
+
+// completeTimeLockExpiry completes the expiry of a timelock whose delegation
+// was made withdrawable by a run which failed before deleting the timelock,
+// or by a former indexer saving the transition and the event apart from the
+// update. The transition is recorded unless it was already, and the
+// withdrawable event, recorded once whatever the retries, and the deletion of
+// the timelock are applied again.
+func (s *Service) completeTimeLockExpiry(
+	ctx context.Context, delegation *model.BTCDelegationSummary, tlDoc model.TimeLockDocument, btcTip uint64,
+) *types.Error {
+	logging.Expiry.FromContext(ctx).Info().
+		Msg("completing the expiry of a delegation already withdrawable")
+
+	transitions, err := s.db.GetDelegationStateTransitions(ctx, delegation.StakingTxHashHex)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get the state transitions of the delegation: %w", err),
+		)
+	}
+	// Without a history, the transition predates its recording
+	if n := len(transitions); n > 0 && transitions[n-1].ToState != types.StateWithdrawable {
+		if err := s.recordStateTransition(ctx, model.NewBtcStateTransition(
+			delegation.StakingTxHashHex, transitions[n-1].ToState, types.StateWithdrawable,
+			tlDoc.DelegationSubState, model.StateTransitionTriggerExpiry, btcTip, time.Now().Unix(),
+		)); err != nil {
+			return types.NewInternalServiceError(err)
 		}
 	}
 
+	if err := s.emitWithdrawableDelegationEvent(
+		ctx, delegation, tlDoc.DelegationSubState, tlDoc.ExpireHeight,
+	); err != nil {
+		return err
+	}
+	return s.deleteExpiredTimeLock(ctx, delegation, tlDoc)
+}
+
+// deleteExpiredTimeLock deletes the timelocks of the delegation made
+// withdrawable by the expiry of the timelock
+func (s *Service) deleteExpiredTimeLock(
+	ctx context.Context, delegation *model.BTCDelegationSummary, tlDoc model.TimeLockDocument,
+) *types.Error {
+	if err := s.db.DeleteExpiredDelegation(ctx, delegation.StakingTxHashHex); err != nil {
+		logging.Expiry.FromContext(ctx).Error().
+			Msg("failed to delete expired delegation")
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to delete expired delegation: %w", err),
+		)
+	}
+	metrics.RecordExpiryWithdrawable(tlDoc.DelegationSubState.String())
+
 	return nil
 }
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordExpiryBacklog(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetExpiryBacklogStats", ctx, uint64(110)).Return([]*model.ExpiryBacklogStats{
+		{SubState: types.SubStateTimelock, Count: 3, OldestExpireHeight: 104},
+	}, nil)
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	require.Nil(t, service.recordExpiryBacklog(ctx, 110))
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	require.Contains(t, body, `indexer_expiry_backlog{sub_state="TIMELOCK"} 3`)
+	require.Contains(t, body, `indexer_expiry_backlog_oldest_age_blocks{sub_state="TIMELOCK"} 6`)
+	require.Contains(t, body, `indexer_expiry_backlog{sub_state="EARLY_UNBONDING"} 0`)
+}
+
+func TestExpireTimeLockRejectedTransition(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	tlDoc := model.TimeLockDocument{
+		StakingTxHashHex:   testReprocessTxHash,
+		ExpireHeight:       100,
+		DelegationSubState: types.SubStateTimelock,
+	}
+
+	// the delegation is withdrawn by a concurrent spend before the update
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationSummaryByStakingTxHash", mock.Anything, testReprocessTxHash).Return(
+		&model.BTCDelegationSummary{StakingTxHashHex: testReprocessTxHash, State: types.StateUnbonding}, nil,
+	)
+	// Nothing is journaled for the update not applied
+	dbMock.On("ApplyBTCDelegationStateChange", mock.Anything, mock.MatchedBy(func(change db.DelegationStateChange) bool {
+		return change.StakingTxHash == testReprocessTxHash &&
+			slices.Equal(change.QualifiedPreviousStates, []types.DelegationState{types.StateUnbonding}) &&
+			change.NewState == types.StateWithdrawable && *change.NewSubState == tlDoc.DelegationSubState
+	})).Return(&db.StateTransitionError{
+		StakingTxHash: testReprocessTxHash,
+		CurrentState:  types.StateWithdrawn,
+		TargetState:   types.StateWithdrawable,
+	}).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	require.Nil(t, service.expireTimeLock(ctx, tlDoc, 110))
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(),
+		`indexer_state_transitions_rejected_total{current_state="WITHDRAWN",target_state="WITHDRAWABLE"}`)
+}
+
+func TestExpireTimeLockOutboxFailure(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	tlDoc := model.TimeLockDocument{
+		StakingTxHashHex:   testReprocessTxHash,
+		ExpireHeight:       100,
+		DelegationSubState: types.SubStateTimelock,
+	}
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationSummaryByStakingTxHash", mock.Anything, testReprocessTxHash).Return(
+		&model.BTCDelegationSummary{StakingTxHashHex: testReprocessTxHash, State: types.StateUnbonding}, nil,
+	)
+	// The transition and the event are saved along with the update
+	withRecords := mock.MatchedBy(func(change db.DelegationStateChange) bool {
+		return change.NewState == types.StateWithdrawable &&
+			change.Transition != nil && change.Transition.ToState == types.StateWithdrawable &&
+			change.OutboxEvent != nil && change.OutboxEvent.EventType == model.OutboxEventTypeWithdrawable
+	})
+	// The outbox insert failing after the update undoes it, the timelock
+	// being kept for the next run
+	dbMock.On("ApplyBTCDelegationStateChange", mock.Anything, withRecords).
+		Return(errors.New("outbox insert failed")).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	require.NotNil(t, service.expireTimeLock(ctx, tlDoc, 110))
+
+	dbMock.On("ApplyBTCDelegationStateChange", mock.Anything, withRecords).Return(nil).Once()
+	dbMock.On("DeleteExpiredDelegation", mock.Anything, testReprocessTxHash).Return(nil).Once()
+	require.Nil(t, service.expireTimeLock(ctx, tlDoc, 110))
+}
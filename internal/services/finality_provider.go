@@ -4,13 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
 	abcitypes "github.com/cometbft/cometbft/abci/types"
-	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -19,9 +20,17 @@ const (
 	EventFinalityProviderStatusChange EventTypes = "babylon.btcstaking.v1.EventFinalityProviderStatusChange"
 )
 
+// bsnIdAttributeKey is the attribute key under which Babylon versions with
+// BSN support emit the consumer chain id of a finality provider, after the
+// bsn_id field of EventFinalityProviderCreated
+const bsnIdAttributeKey = "bsn_id"
+
 func (s *Service) processNewFinalityProviderEvent(
 	ctx context.Context, event abcitypes.Event,
 ) *types.Error {
+	// The BSN id is not part of the typed event, so it has to be taken out of
+	// the raw attributes before parsing
+	bsnId, event := extractBsnIdFromEvent(event)
 	newFinalityProvider, err := parseEvent[*bbntypes.EventFinalityProviderCreated](
 		EventFinalityProviderCreatedType, event,
 	)
@@ -32,14 +41,14 @@ func (s *Service) processNewFinalityProviderEvent(
 	if validationErr := s.validateFinalityProviderCreatedEvent(newFinalityProvider); validationErr != nil {
 		return validationErr
 	}
+	ctx = logging.WithFpBtcPk(ctx, newFinalityProvider.BtcPkHex)
 
 	if dbErr := s.db.SaveNewFinalityProvider(
-		ctx, model.FromEventFinalityProviderCreated(newFinalityProvider),
+		ctx, model.FromEventFinalityProviderCreated(newFinalityProvider, bsnId),
 	); dbErr != nil {
 		if db.IsDuplicateKeyError(dbErr) {
 			// Finality provider already exists, ignore the event
-			log.Debug().
-				Str("btcPk", newFinalityProvider.BtcPkHex).
+			logging.BlockProcessor.FromContext(ctx).Debug().
 				Msg("Ignoring EventFinalityProviderCreated because finality provider already exists")
 			return nil
 		}
@@ -66,6 +75,7 @@ func (s *Service) processFinalityProviderEditedEvent(
 	if validationErr := s.validateFinalityProviderEditedEvent(finalityProviderEdited); validationErr != nil {
 		return validationErr
 	}
+	ctx = logging.WithFpBtcPk(ctx, finalityProviderEdited.BtcPkHex)
 
 	if dbErr := s.db.UpdateFinalityProviderDetailsFromEvent(
 		ctx, model.FromEventFinalityProviderEdited(finalityProviderEdited),
@@ -90,11 +100,14 @@ func (s *Service) processFinalityProviderStateChangeEvent(
 		return err
 	}
 
+	ctx = logging.WithFpBtcPk(ctx, finalityProviderStateChange.BtcPk)
 	if validationErr := s.validateFinalityProviderStateChangeEvent(ctx, finalityProviderStateChange); validationErr != nil {
 		return validationErr
 	}
 
-	// If all validations pass, update the finality provider state
+	// If all validations pass, update the finality provider state. Consumer
+	// chain finality providers are updated as well, only the active set
+	// reconciliation is limited to the Babylon ones.
 	if dbErr := s.db.UpdateFinalityProviderState(
 		ctx, finalityProviderStateChange.BtcPk, finalityProviderStateChange.NewState,
 	); dbErr != nil {
@@ -169,3 +182,24 @@ func (s *Service) validateFinalityProviderStateChangeEvent(
 
 	return nil
 }
+
+// extractBsnIdFromEvent returns the BSN (consumer chain) id carried by a
+// finality provider registration event together with the event stripped of
+// that attribute, so it can be parsed into the typed Babylon event.
+// Finality providers registered for Babylon itself carry no such attribute.
+func extractBsnIdFromEvent(event abcitypes.Event) (string, abcitypes.Event) {
+	bsnId := model.BabylonBsnId
+	attrs := make([]abcitypes.EventAttribute, 0, len(event.Attributes))
+	for _, attr := range event.Attributes {
+		if attr.Key == bsnIdAttributeKey {
+			bsnId = strings.Trim(attr.Value, "\"")
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+
+	return bsnId, abcitypes.Event{
+		Type:       event.Type,
+		Attributes: attrs,
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testBsnFpBtcPk = "05c74e3d7c224c1fa5772e0aded029f2727eb5197cca85873ea2e53602f1ed57"
+
+func finalityProviderCreatedEvent(t *testing.T, bsnId string) abcitypes.Event {
+	event, err := sdk.TypedEventToEvent(&bbntypes.EventFinalityProviderCreated{
+		BtcPkHex:   testBsnFpBtcPk,
+		Addr:       "bbn1u8nds3xnkf07c3ze5r62jj8r6c8sgnqd33h050",
+		Commission: "0.050000000000000000",
+		Moniker:    "BSN FP",
+	})
+	require.NoError(t, err)
+	if bsnId != "" {
+		event.Attributes = append(event.Attributes, abcitypes.EventAttribute{
+			Key: bsnIdAttributeKey, Value: `"` + bsnId + `"`,
+		})
+	}
+	return abcitypes.Event(event)
+}
+
+func TestExtractBsnIdFromEvent(t *testing.T) {
+	t.Run("bsn_id attribute", func(t *testing.T) {
+		bsnId, event := extractBsnIdFromEvent(finalityProviderCreatedEvent(t, "bsn-cosmos-1"))
+		require.Equal(t, "bsn-cosmos-1", bsnId)
+		for _, attr := range event.Attributes {
+			require.NotEqual(t, bsnIdAttributeKey, attr.Key)
+		}
+	})
+
+	t.Run("babylon finality provider", func(t *testing.T) {
+		bsnId, _ := extractBsnIdFromEvent(finalityProviderCreatedEvent(t, ""))
+		require.Equal(t, model.BabylonBsnId, bsnId)
+	})
+}
+
+func TestProcessFinalityProviderStateChangeEventUpdatesBsnFinalityProvider(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	service := NewService(&config.Config{}, database, nil, nil, nil, nil)
+
+	require.Nil(t, service.processNewFinalityProviderEvent(ctx, finalityProviderCreatedEvent(t, "bsn-cosmos-1")))
+	fp, err := database.GetFinalityProviderByBtcPk(ctx, testBsnFpBtcPk)
+	require.NoError(t, err)
+	require.Equal(t, "bsn-cosmos-1", fp.BsnId)
+
+	for _, state := range []bbntypes.FinalityProviderStatus{
+		bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE,
+		bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_JAILED,
+		bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_SLASHED,
+	} {
+		event, err := sdk.TypedEventToEvent(&bbntypes.EventFinalityProviderStatusChange{
+			BtcPk:    testBsnFpBtcPk,
+			NewState: state.String(),
+		})
+		require.NoError(t, err)
+		require.Nil(t, service.processFinalityProviderStateChangeEvent(ctx, abcitypes.Event(event)))
+
+		fp, err := database.GetFinalityProviderByBtcPk(ctx, testBsnFpBtcPk)
+		require.NoError(t, err)
+		require.Equal(t, state.String(), fp.State)
+	}
+
+	// The BSN finality provider stays out of the Babylon active set
+	babylonFps, err := database.GetFinalityProvidersByBsnId(ctx, model.BabylonBsnId)
+	require.NoError(t, err)
+	require.Empty(t, babylonFps)
+}
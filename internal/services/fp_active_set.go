@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *Service) StartFpActiveSetPoller(ctx context.Context) {
+	fpActiveSetPoller := s.newPoller(
+		"fp_active_set",
+		s.cfg.Poller.FpActiveSetPollingInterval,
+		s.checkFpActiveSet,
+	)
+	s.startPoller(ctx, fpActiveSetPoller)
+}
+
+// checkFpActiveSet compares the active set at the latest BBN height with the
+// last recorded voting power of each Babylon finality provider and records
+// a change for those that entered or left the active set or whose voting
+// power changed beyond the configured threshold.
+func (s *Service) checkFpActiveSet(ctx context.Context) *types.Error {
+	height, err := s.bbn.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get latest BBN block height: %w", err),
+		)
+	}
+
+	activeFps, err := s.bbn.GetActiveFinalityProvidersAtHeight(ctx, uint64(height))
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get active finality providers: %w", err),
+		)
+	}
+	activeFpsByPk := make(map[string]*bbnclient.FinalityProviderVotingPower, len(activeFps))
+	for _, fp := range activeFps {
+		activeFpsByPk[fp.BtcPk] = fp
+	}
+
+	fps, err := s.db.GetFinalityProvidersByBsnId(ctx, model.BabylonBsnId)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get finality providers: %w", err),
+		)
+	}
+
+	fpBtcPks := make([]string, len(fps))
+	for i, fp := range fps {
+		fpBtcPks[i] = fp.BtcPk
+	}
+	lastChanges, err := s.db.GetLatestFinalityProviderVotingPowerChanges(ctx, fpBtcPks)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get latest voting power changes: %w", err),
+		)
+	}
+
+	for _, fp := range fps {
+		var votingPower uint64
+		activeFp, active := activeFpsByPk[fp.BtcPk]
+		if active && !activeFp.Jailed && activeFp.VotingPower > 0 {
+			votingPower = activeFp.VotingPower
+		} else {
+			active = false
+		}
+
+		if !shouldRecordVotingPowerChange(
+			lastChanges[fp.BtcPk], active, votingPower, s.cfg.Poller.FpVotingPowerChangeThreshold,
+		) {
+			continue
+		}
+
+		log.Debug().
+			Str("btcPk", fp.BtcPk).
+			Int64("height", height).
+			Bool("active", active).
+			Uint64("votingPower", votingPower).
+			Msg("recording finality provider voting power change")
+
+		if err := s.db.SaveFinalityProviderVotingPowerChange(
+			ctx, model.NewFinalityProviderVotingPowerChange(fp.BtcPk, uint64(height), active, votingPower),
+		); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to save voting power change: %w", err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// shouldRecordVotingPowerChange returns true if the finality provider entered
+// or left the active set, or if its voting power changed relative to the last
+// recorded value by more than the threshold.
+func shouldRecordVotingPowerChange(
+	lastChange *model.FinalityProviderVotingPowerChange,
+	active bool,
+	votingPower uint64,
+	threshold float64,
+) bool {
+	if lastChange == nil {
+		// Nothing to record for finality providers that never were active
+		return active
+	}
+	if lastChange.Active != active {
+		return true
+	}
+	if !active {
+		return false
+	}
+	if lastChange.VotingPower == 0 {
+		return votingPower != 0
+	}
+
+	relativeChange := math.Abs(float64(votingPower)-float64(lastChange.VotingPower)) /
+		float64(lastChange.VotingPower)
+	return relativeChange > threshold
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+func TestShouldRecordVotingPowerChange(t *testing.T) {
+	active := &model.FinalityProviderVotingPowerChange{Active: true, VotingPower: 1000}
+	testCases := []struct {
+		name        string
+		lastChange  *model.FinalityProviderVotingPowerChange
+		active      bool
+		votingPower uint64
+		expected    bool
+	}{
+		{name: "never active, still inactive", lastChange: nil, active: false, expected: false},
+		{name: "entering the set for the first time", lastChange: nil, active: true, votingPower: 10, expected: true},
+		{name: "leaving the set", lastChange: active, active: false, expected: true},
+		{
+			name:       "entering the set again",
+			lastChange: &model.FinalityProviderVotingPowerChange{Active: false},
+			active:     true, votingPower: 1000, expected: true,
+		},
+		{name: "staying inactive", lastChange: &model.FinalityProviderVotingPowerChange{Active: false}, active: false},
+		{name: "change within the threshold", lastChange: active, active: true, votingPower: 1050, expected: false},
+		{name: "change at the threshold", lastChange: active, active: true, votingPower: 1100, expected: false},
+		{name: "increase above the threshold", lastChange: active, active: true, votingPower: 1101, expected: true},
+		{name: "decrease above the threshold", lastChange: active, active: true, votingPower: 899, expected: true},
+		{
+			name:       "from zero voting power",
+			lastChange: &model.FinalityProviderVotingPowerChange{Active: true},
+			active:     true, votingPower: 1, expected: true,
+		},
+		{
+			name:       "staying at zero voting power",
+			lastChange: &model.FinalityProviderVotingPowerChange{Active: true},
+			active:     true, votingPower: 0, expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, shouldRecordVotingPowerChange(tc.lastChange, tc.active, tc.votingPower, 0.1))
+		})
+	}
+}
+
+func TestCheckFpActiveSet(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	for _, fp := range []*model.FinalityProviderDetails{
+		{BtcPk: "entering fp"},
+		{BtcPk: "leaving fp"},
+		{BtcPk: "steady fp"},
+		{BtcPk: "jailed fp"},
+		{BtcPk: "bsn fp", BsnId: "bsn-cosmos-1"},
+	} {
+		require.NoError(t, database.SaveNewFinalityProvider(ctx, fp))
+	}
+	for _, change := range []*model.FinalityProviderVotingPowerChange{
+		model.NewFinalityProviderVotingPowerChange("leaving fp", 10, true, 100),
+		model.NewFinalityProviderVotingPowerChange("steady fp", 5, false, 0),
+		model.NewFinalityProviderVotingPowerChange("steady fp", 10, true, 1000),
+	} {
+		require.NoError(t, database.SaveFinalityProviderVotingPowerChange(ctx, change))
+	}
+
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetLatestBlockNumber", mock.Anything).Return(int64(20), nil)
+	bbnMock.On("GetActiveFinalityProvidersAtHeight", mock.Anything, uint64(20)).Return(
+		[]*bbnclient.FinalityProviderVotingPower{
+			{BtcPk: "entering fp", VotingPower: 200},
+			{BtcPk: "steady fp", VotingPower: 1010},
+			{BtcPk: "jailed fp", VotingPower: 300, Jailed: true},
+			{BtcPk: "bsn fp", VotingPower: 400},
+		}, nil,
+	)
+
+	chaos := db.NewChaosDatabase(database)
+	cfg := &config.Config{Poller: config.PollerConfig{FpVotingPowerChangeThreshold: 0.05}}
+	service := NewService(cfg, chaos, nil, nil, bbnMock, nil)
+	require.Nil(t, service.checkFpActiveSet(ctx))
+
+	// The last changes of all the finality providers are read at once
+	require.Equal(t, 1, chaos.Calls("GetLatestFinalityProviderVotingPowerChanges"))
+
+	latest, err := database.GetLatestFinalityProviderVotingPowerChanges(
+		ctx, []string{"entering fp", "leaving fp", "steady fp", "jailed fp", "bsn fp"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, map[string]*model.FinalityProviderVotingPowerChange{
+		"entering fp": model.NewFinalityProviderVotingPowerChange("entering fp", 20, true, 200),
+		"leaving fp":  model.NewFinalityProviderVotingPowerChange("leaving fp", 20, false, 0),
+		// a change within the threshold is not recorded
+		"steady fp": model.NewFinalityProviderVotingPowerChange("steady fp", 10, true, 1000),
+	}, latest)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// fpStakeShareTopN is the number of top finality providers whose share of the
+// active stake is exported
+const fpStakeShareTopN = 3
+
+func (s *Service) StartFpStakeMetricsUpdater(ctx context.Context) {
+	fpStakeMetricsPoller := s.newPoller(
+		"fp_stake_metrics",
+		s.cfg.Poller.FpStakeMetricsInterval,
+		s.updateFpStakeMetrics,
+	)
+	s.startPoller(ctx, fpStakeMetricsPoller)
+}
+
+// updateFpStakeMetrics publishes the active stake of the finality providers
+// holding the most of it, and the share of the active stake of all of them
+// held by the top 3, to monitor the stake concentration
+func (s *Service) updateFpStakeMetrics(ctx context.Context) *types.Error {
+	topN := s.cfg.Poller.FpStakeMetricsTopN
+	distribution, err := s.db.GetFinalityProviderStakeDistribution(ctx, max(topN, fpStakeShareTopN))
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to aggregate the active stake by finality provider: %w", err),
+		)
+	}
+
+	activeStakes := make(map[string]uint64, topN)
+	var topStake uint64
+	for i, fp := range distribution.Top {
+		if int64(i) < topN {
+			activeStakes[fp.BtcPk] = fp.ActiveStakingAmount
+		}
+		if i < fpStakeShareTopN {
+			topStake += fp.ActiveStakingAmount
+		}
+	}
+	var topShare float64
+	if distribution.TotalActiveStakingAmount > 0 {
+		topShare = float64(topStake) / float64(distribution.TotalActiveStakingAmount)
+	}
+	metrics.RecordFpStakeDistribution(activeStakes, topShare)
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateFpStakeMetrics(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+
+	dbMock := mocks.NewDbInterface(t)
+	// the top 3 are fetched for their share even below a top 3
+	dbMock.On("GetFinalityProviderStakeDistribution", ctx, int64(3)).Return(
+		&model.FinalityProviderStakeDistribution{
+			Top: []*model.FinalityProviderActiveStake{
+				{BtcPk: "fp1", ActiveStakingAmount: 4000},
+				{BtcPk: "fp2", ActiveStakingAmount: 2000},
+				{BtcPk: "fp3", ActiveStakingAmount: 2000},
+			},
+			TotalActiveStakingAmount: 10000,
+		}, nil,
+	).Once()
+	dbMock.On("GetFinalityProviderStakeDistribution", ctx, int64(3)).Return(
+		&model.FinalityProviderStakeDistribution{
+			Top: []*model.FinalityProviderActiveStake{
+				{BtcPk: "fp3", ActiveStakingAmount: 5000},
+			},
+			TotalActiveStakingAmount: 5000,
+		}, nil,
+	).Once()
+
+	cfg := &config.Config{Poller: config.PollerConfig{FpStakeMetricsTopN: 2}}
+	service := NewService(cfg, dbMock, nil, nil, nil, nil)
+
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec.Body.String()
+	}
+
+	require.Nil(t, service.updateFpStakeMetrics(ctx))
+	body := scrape()
+	require.Contains(t, body, `indexer_fp_active_stake_sats{fp_btc_pk="fp1"} 4000`)
+	require.Contains(t, body, `indexer_fp_active_stake_sats{fp_btc_pk="fp2"} 2000`)
+	require.NotContains(t, body, `fp_btc_pk="fp3"`)
+	require.Contains(t, body, `indexer_fp_top3_stake_share 0.8`)
+
+	// the finality providers leaving the top are no longer exported
+	require.Nil(t, service.updateFpStakeMetrics(ctx))
+	body = scrape()
+	require.NotContains(t, body, `fp_btc_pk="fp1"`)
+	require.NotContains(t, body, `fp_btc_pk="fp2"`)
+	require.Contains(t, body, `indexer_fp_active_stake_sats{fp_btc_pk="fp3"} 5000`)
+	require.Contains(t, body, `indexer_fp_top3_stake_share 1`)
+}
@@ -5,15 +5,18 @@ import (
 	"fmt"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
-	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
 )
 
 func (s *Service) SyncGlobalParams(ctx context.Context) {
-	paramsPoller := poller.NewPoller(
+	paramsPoller := s.newPoller(
+		"params",
 		s.cfg.Poller.ParamPollingInterval,
 		s.fetchAndSaveParams,
+		// the delegations of a new params version fail to be processed until
+		// it is saved
+		criticalPoller(3*s.cfg.Poller.ParamPollingInterval),
 	)
-	go paramsPoller.Start(ctx)
+	s.startPoller(ctx, paramsPoller)
 }
 
 func (s *Service) fetchAndSaveParams(ctx context.Context) *types.Error {
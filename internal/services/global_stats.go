@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+func (s *Service) StartGlobalStatsUpdater(ctx context.Context) {
+	globalStatsPoller := s.newPoller(
+		"global_stats",
+		s.cfg.Poller.StatsUpdateInterval,
+		s.updateGlobalStats,
+	)
+	s.startPoller(ctx, globalStatsPoller)
+}
+
+// updateGlobalStats saves the global stats document and publishes the
+// indexing lags
+func (s *Service) updateGlobalStats(ctx context.Context) *types.Error {
+	stats, err := s.ComputeGlobalStats(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.RecordIndexingLag("bbn", stats.BbnLag)
+	metrics.RecordIndexingLag("btc", stats.BtcLag)
+
+	if dbErr := s.db.SaveGlobalStats(ctx, stats); dbErr != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to save global stats: %w", dbErr),
+		)
+	}
+
+	return nil
+}
+
+// ComputeGlobalStats aggregates the indexed delegations and finality
+// providers, and compares the processed heights with the chain tips
+func (s *Service) ComputeGlobalStats(ctx context.Context) (*model.GlobalStats, *types.Error) {
+	stats := &model.GlobalStats{
+		Id:                 model.GlobalStatsId,
+		DelegationsByState: make(map[string]uint64),
+		UpdatedAt:          time.Now().Unix(),
+	}
+
+	delegationStats, err := s.db.GetDelegationStatsByState(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to aggregate delegations by state: %w", err),
+		)
+	}
+	for _, stateStats := range delegationStats {
+		stats.DelegationsByState[stateStats.State] = stateStats.Count
+		if stateStats.State == types.StateActive.String() {
+			stats.ActiveTvl = stateStats.StakingAmount
+		}
+	}
+
+	stats.FinalityProvidersByState, err = s.db.CountFinalityProvidersByState(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to count finality providers by state: %w", err),
+		)
+	}
+
+	lastProcessed, err := s.db.GetLastProcessedBbnBlock(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get last processed BBN height: %w", err),
+		)
+	}
+	bbnTipHeight, err := s.bbnTipHeight(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get BBN tip height: %w", err),
+		)
+	}
+	stats.LastProcessedBbnHeight = lastProcessed.Height
+	stats.BbnTipHeight = uint64(bbnTipHeight)
+	stats.BbnLag = lag(stats.BbnTipHeight, stats.LastProcessedBbnHeight)
+
+	latestHeader, err := s.db.GetLatestBTCHeader(ctx)
+	if err != nil && !db.IsNotFoundError(err) {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get latest BTC header: %w", err),
+		)
+	}
+	if latestHeader != nil {
+		stats.LastProcessedBtcHeight = latestHeader.Height
+	}
+	stats.BtcTipHeight, err = s.btc.GetTipHeight()
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get BTC tip height: %w", err),
+		)
+	}
+	stats.BtcLag = lag(stats.BtcTipHeight, stats.LastProcessedBtcHeight)
+
+	return stats, nil
+}
+
+// lag returns the number of blocks between the processed height and the tip,
+// zero if the tip is behind as when the RPC node lags
+func lag(tipHeight, processedHeight uint64) uint64 {
+	if tipHeight < processedHeight {
+		return 0
+	}
+	return tipHeight - processedHeight
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeGlobalStats(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetDelegationStatsByState", mock.Anything).Return([]*model.DelegationStateStats{
+		{State: types.StateActive.String(), Count: 2, StakingAmount: 3000},
+		{State: types.StateWithdrawn.String(), Count: 1, StakingAmount: 500},
+	}, nil)
+	dbMock.On("CountFinalityProvidersByState", mock.Anything).Return(
+		map[string]uint64{"FINALITY_PROVIDER_STATUS_ACTIVE": 4}, nil,
+	)
+	dbMock.On("GetLastProcessedBbnBlock", mock.Anything).Return(&model.LastProcessedHeight{Height: 90}, nil)
+	dbMock.On("GetLatestBTCHeader", mock.Anything).Return(&model.BTCHeader{Height: 800}, nil)
+
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetLatestBlockNumber", mock.Anything).Return(int64(100), nil)
+	btcMock := mocks.NewBtcInterface(t)
+	btcMock.On("GetTipHeight").Return(uint64(803), nil)
+
+	service := NewService(&config.Config{}, dbMock, btcMock, nil, bbnMock, nil)
+	stats, err := service.ComputeGlobalStats(context.Background())
+	require.Nil(t, err)
+
+	require.Equal(t, uint64(3000), stats.ActiveTvl)
+	require.Equal(t, map[string]uint64{"ACTIVE": 2, "WITHDRAWN": 1}, stats.DelegationsByState)
+	require.Equal(t, map[string]uint64{"FINALITY_PROVIDER_STATUS_ACTIVE": 4}, stats.FinalityProvidersByState)
+	require.Equal(t, uint64(90), stats.LastProcessedBbnHeight)
+	require.Equal(t, uint64(10), stats.BbnLag)
+	require.Equal(t, uint64(800), stats.LastProcessedBtcHeight)
+	require.Equal(t, uint64(3), stats.BtcLag)
+}
+
+func TestComputeGlobalStatsWithoutProcessedBtcHeader(t *testing.T) {
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetDelegationStatsByState", mock.Anything).Return(nil, nil)
+	dbMock.On("CountFinalityProvidersByState", mock.Anything).Return(map[string]uint64{}, nil)
+	dbMock.On("GetLastProcessedBbnBlock", mock.Anything).Return(&model.LastProcessedHeight{Height: 100}, nil)
+	dbMock.On("GetLatestBTCHeader", mock.Anything).Return(
+		nil, &db.NotFoundError{Key: "latest", Message: "no BTC header"},
+	)
+
+	// A lagging BBN node does not make the lag negative
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetLatestBlockNumber", mock.Anything).Return(int64(95), nil)
+	btcMock := mocks.NewBtcInterface(t)
+	btcMock.On("GetTipHeight").Return(uint64(803), nil)
+
+	service := NewService(&config.Config{}, dbMock, btcMock, nil, bbnMock, nil)
+	stats, err := service.ComputeGlobalStats(context.Background())
+	require.Nil(t, err)
+
+	require.Zero(t, stats.ActiveTvl)
+	require.Zero(t, stats.BbnLag)
+	require.Zero(t, stats.LastProcessedBtcHeight)
+	require.Equal(t, uint64(803), stats.BtcLag)
+}
@@ -0,0 +1,487 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/errorreporting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/supervisor"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
+	"github.com/rs/zerolog/log"
+)
+
+// healthState tracks the progress of the indexer processes checked by the
+// health probes
+type healthState struct {
+	mu sync.Mutex
+	// chainStateBootstrapped is set once the finality providers and params
+	// of the BBN chain are synced, before any BBN block is processed
+	chainStateBootstrapped bool
+	// bootstrapped is set once the BBN block processor caught up with the
+	// chain tip
+	bootstrapped bool
+	// blockProcessorHalted is set once the BBN block processing is halted
+	// until an explicit resync
+	blockProcessorHalted bool
+	// blockProcessingSince is when the processing of the current BBN block
+	// started, zero while the processor waits for new blocks
+	blockProcessingSince time.Time
+	// blockProcessingHeight is the height of the BBN block being processed
+	blockProcessingHeight uint64
+	pollers               map[string]*pollerHeartbeat
+}
+
+type pollerHeartbeat struct {
+	interval time.Duration
+	// completedAt is when the last run completed, or the poller started
+	completedAt time.Time
+	// succeededAt is when the last successful run completed, or the poller
+	// started
+	succeededAt         time.Time
+	consecutiveFailures int
+	// successThreshold is how long a critical poller may go without a
+	// successful run before the indexer is reported not ready, zero for the
+	// pollers that are not critical
+	successThreshold time.Duration
+}
+
+func newHealthState() *healthState {
+	return &healthState{pollers: make(map[string]*pollerHeartbeat)}
+}
+
+func (h *healthState) setBootstrapped() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.bootstrapped = true
+}
+
+func (h *healthState) setChainStateBootstrapped() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.chainStateBootstrapped = true
+}
+
+func (h *healthState) setBlockProcessorHalted() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.blockProcessorHalted = true
+}
+
+func (h *healthState) startBlockProcessing(height uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.blockProcessingSince = time.Now()
+	h.blockProcessingHeight = height
+}
+
+func (h *healthState) completeBlockProcessing() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.blockProcessingSince = time.Time{}
+	h.blockProcessingHeight = 0
+}
+
+// blockInFlight returns the height of the BBN block being processed, if any
+func (h *healthState) blockInFlight() (uint64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.blockProcessingHeight, !h.blockProcessingSince.IsZero()
+}
+
+func (h *healthState) registerPoller(name string, interval, successThreshold time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	h.pollers[name] = &pollerHeartbeat{
+		interval:         interval,
+		completedAt:      now,
+		succeededAt:      now,
+		successThreshold: successThreshold,
+	}
+	metrics.RecordPollerStarted(name, now)
+}
+
+// completePollerRun records the completion of a run of the poller, which
+// started at the given time
+func (h *healthState) completePollerRun(name string, startedAt time.Time, succeeded bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	heartbeat, ok := h.pollers[name]
+	if !ok {
+		return
+	}
+	heartbeat.completedAt = time.Now()
+	if succeeded {
+		heartbeat.succeededAt = heartbeat.completedAt
+		heartbeat.consecutiveFailures = 0
+	} else {
+		heartbeat.consecutiveFailures++
+	}
+
+	// The ticker buffers a single tick, the other ones falling within the
+	// run are dropped
+	duration := heartbeat.completedAt.Sub(startedAt)
+	skippedTicks := int(duration/heartbeat.interval) - 1
+	metrics.RecordPollerRun(
+		name, duration, heartbeat.succeededAt, heartbeat.consecutiveFailures, max(skippedTicks, 0),
+	)
+}
+
+// pollerOption customizes the monitoring of a poller created by newPoller
+type pollerOption func(*pollerOptions)
+
+type pollerOptions struct {
+	successThreshold time.Duration
+}
+
+// criticalPoller reports the indexer not ready once the poller did not
+// complete a successful run for longer than the threshold
+func criticalPoller(successThreshold time.Duration) pollerOption {
+	return func(opts *pollerOptions) {
+		opts.successThreshold = successThreshold
+	}
+}
+
+// newPoller creates a poller whose runs are tracked by the readiness probe
+// and exported as metrics under the given name. A panic in a run is recovered
+// and fails the run.
+func (s *Service) newPoller(
+	name string,
+	interval time.Duration,
+	pollMethod func(ctx context.Context) *types.Error,
+	opts ...pollerOption,
+) namedPoller {
+	var options pollerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	s.health.registerPoller(name, interval, options.successThreshold)
+	p := poller.NewPoller(interval, func(ctx context.Context) (err *types.Error) {
+		startedAt := time.Now()
+		defer func() {
+			if r := recover(); r != nil {
+				metrics.RecordPollerPanic(name)
+				log.Error().Str("poller", name).Interface("panic", r).
+					Bytes("stack", debug.Stack()).Msg("recovered from a poller panic")
+				err = types.NewInternalServiceError(fmt.Errorf("poller %s panicked: %v", name, r))
+				errorreporting.Report(ctx, "poller", err, map[string]string{
+					"poller": name, errorreporting.PanicTag: "true",
+				})
+			} else if err != nil {
+				errorreporting.Report(ctx, "poller", err, map[string]string{"poller": name})
+			}
+			s.health.completePollerRun(name, startedAt, err == nil)
+		}()
+		return pollMethod(audit.WithTrigger(ctx, audit.Trigger{Type: audit.TriggerPoller, Poller: name}))
+	})
+	return s.registerForShutdown(name, p)
+}
+
+// CheckLiveness reports whether the indexer process is responsive, i.e. its
+// main loops are scheduled: the BBN block processor is not stuck on a block
+// and every poller completes its runs. A halted processor is live, as a
+// restart does not resume it. Failing liveness gets the process restarted.
+func (s *Service) CheckLiveness(ctx context.Context) *types.HealthReport {
+	return types.NewHealthReport([]types.HealthCheck{
+		s.runHealthCheck(ctx, "block_processor", s.checkBlockProcessor),
+		s.runHealthCheck(ctx, "poller_heartbeats", s.checkPollerHeartbeats),
+	})
+}
+
+// CheckReadiness reports whether the indexer serves up to date data: its
+// dependencies are reachable, the bootstrap completed, the processing lags
+// the BBN chain by less than the threshold, no poller stalled, no background
+// component restarts after a failure, and the instance leads the indexing or
+// stands by read-only. The checks run
+// concurrently, each bounded by the health check timeout.
+func (s *Service) CheckReadiness(ctx context.Context) *types.HealthReport {
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) (string, error)
+	}{
+		{"mongodb", s.checkDb},
+		{"bbn", s.checkBbn},
+		{"btc", s.checkBtc},
+		{"bootstrap", s.checkBootstrap},
+		{"pollers", s.checkPollers},
+		{"critical_pollers", s.checkCriticalPollers},
+		{"indexing", s.checkIndexingPaused},
+		{"bbn_lag", s.checkBbnLag},
+		{"leadership", s.checkLeadership},
+		{"components", s.checkSupervisedComponents},
+	}
+
+	results := make([]types.HealthCheck, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = s.runHealthCheck(ctx, c.name, c.check)
+		}()
+	}
+	wg.Wait()
+
+	return types.NewHealthReport(results)
+}
+
+// runHealthCheck runs the check in the background, so that it fails on
+// timeout even if the checked dependency does not honor the context
+func (s *Service) runHealthCheck(
+	ctx context.Context, name string, check func(ctx context.Context) (string, error),
+) types.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.API.HealthCheckTimeout)
+	defer cancel()
+
+	type checkResult struct {
+		message string
+		err     error
+	}
+	// buffered so that a check outliving the timeout does not leak
+	resultChan := make(chan checkResult, 1)
+	start := time.Now()
+	go func() {
+		message, err := check(ctx)
+		resultChan <- checkResult{message, err}
+	}()
+
+	var result checkResult
+	select {
+	case result = <-resultChan:
+	case <-ctx.Done():
+		result.err = fmt.Errorf("timed out after %s", s.cfg.API.HealthCheckTimeout)
+	}
+
+	healthCheck := types.HealthCheck{
+		Name:     name,
+		Healthy:  result.err == nil,
+		Message:  result.message,
+		Duration: time.Since(start),
+	}
+	if result.err != nil {
+		healthCheck.Message = result.err.Error()
+	}
+	return healthCheck
+}
+
+func (s *Service) checkBlockProcessor(_ context.Context) (string, error) {
+	s.health.mu.Lock()
+	halted := s.health.blockProcessorHalted
+	processingSince := s.health.blockProcessingSince
+	s.health.mu.Unlock()
+
+	if halted {
+		return "halted until resync", nil
+	}
+	if processingSince.IsZero() {
+		return "idle", nil
+	}
+	processingTime := time.Since(processingSince)
+	if processingTime > s.cfg.API.BlockProcessorTimeout {
+		return "", fmt.Errorf(
+			"processing a block for %s, above %s",
+			processingTime.Round(time.Second), s.cfg.API.BlockProcessorTimeout,
+		)
+	}
+	return "processing", nil
+}
+
+func (s *Service) checkDb(ctx context.Context) (string, error) {
+	if err := s.db.Ping(ctx); err != nil {
+		return "", err
+	}
+	return "reachable", nil
+}
+
+func (s *Service) checkBbn(ctx context.Context) (string, error) {
+	chainId, err := s.bbn.GetChainID(ctx)
+	if err != nil {
+		return "", err
+	}
+	expectedChainId := s.cfg.BBN.ChainId
+	if expectedChainId != "" && chainId != expectedChainId {
+		return "", fmt.Errorf("node serves chain %s, expected %s", chainId, expectedChainId)
+	}
+	return chainId, nil
+}
+
+func (s *Service) checkBtc(_ context.Context) (string, error) {
+	tipHeight, err := s.btc.GetTipHeight()
+	if err != nil {
+		return "", err
+	}
+	header, err := s.btc.GetBlockHeaderByHeight(tipHeight)
+	if err != nil {
+		return "", err
+	}
+
+	tipAge := time.Since(header.Timestamp)
+	if tipAge > s.cfg.API.BtcTipMaxAge {
+		return "", fmt.Errorf(
+			"tip %d is %s old, above %s", tipHeight, tipAge.Round(time.Second), s.cfg.API.BtcTipMaxAge,
+		)
+	}
+	return fmt.Sprintf("tip %d", tipHeight), nil
+}
+
+func (s *Service) checkBootstrap(_ context.Context) (string, error) {
+	if s.isStandby() {
+		return "standby, serving reads", nil
+	}
+
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	if s.health.blockProcessorHalted {
+		return "", errors.New("BBN block processing halted until resync")
+	}
+	if !s.health.chainStateBootstrapped {
+		return "", errors.New("finality providers and params bootstrap in progress")
+	}
+	if !s.health.bootstrapped {
+		return "", errors.New("BBN block processor has not caught up with the chain tip yet")
+	}
+	return "completed", nil
+}
+
+// checkPollers fails if a poller run did not complete within twice its
+// interval plus the stall tolerance, i.e. a run overran its interval by more
+// than the tolerance
+func (s *Service) checkPollers(_ context.Context) (string, error) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	var stalled []string
+	for name, heartbeat := range s.health.pollers {
+		if time.Since(heartbeat.completedAt) > 2*heartbeat.interval+s.cfg.API.PollerStallTolerance {
+			stalled = append(stalled, name)
+		}
+	}
+	if len(stalled) > 0 {
+		sort.Strings(stalled)
+		return "", fmt.Errorf("stalled: %s", strings.Join(stalled, ", "))
+	}
+	return fmt.Sprintf("%d running", len(s.health.pollers)), nil
+}
+
+// checkPollerHeartbeats fails if a poller did not complete a run for its
+// interval plus the heartbeat timeout, i.e. its loop is wedged on a run
+func (s *Service) checkPollerHeartbeats(_ context.Context) (string, error) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	var wedged []string
+	for name, heartbeat := range s.health.pollers {
+		sinceHeartbeat := time.Since(heartbeat.completedAt)
+		if sinceHeartbeat > heartbeat.interval+s.cfg.API.PollerHeartbeatTimeout {
+			wedged = append(wedged, fmt.Sprintf("%s (no heartbeat for %s)", name, sinceHeartbeat.Round(time.Second)))
+		}
+	}
+	if len(wedged) > 0 {
+		sort.Strings(wedged)
+		return "", fmt.Errorf("wedged: %s", strings.Join(wedged, ", "))
+	}
+	return fmt.Sprintf("%d beating", len(s.health.pollers)), nil
+}
+
+// checkCriticalPollers fails if a critical poller did not complete a
+// successful run within its threshold, e.g. because its runs keep failing
+func (s *Service) checkCriticalPollers(_ context.Context) (string, error) {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	var critical int
+	var failing []string
+	for name, heartbeat := range s.health.pollers {
+		if heartbeat.successThreshold == 0 {
+			continue
+		}
+		critical++
+		if sinceSuccess := time.Since(heartbeat.succeededAt); sinceSuccess > heartbeat.successThreshold {
+			failing = append(failing, fmt.Sprintf(
+				"%s (no success for %s, above %s)",
+				name, sinceSuccess.Round(time.Second), heartbeat.successThreshold,
+			))
+		}
+	}
+	if len(failing) > 0 {
+		sort.Strings(failing)
+		return "", fmt.Errorf("failing: %s", strings.Join(failing, ", "))
+	}
+	return fmt.Sprintf("%d succeeding", critical), nil
+}
+
+func (s *Service) checkIndexingPaused(_ context.Context) (string, error) {
+	if s.pause.isPaused() {
+		return "", errors.New("paused by an admin")
+	}
+	return "running", nil
+}
+
+// checkBbnLag fails once the last processed BBN height lags the chain tip by
+// more than the threshold, e.g. as the new blocks are no longer notified
+func (s *Service) checkBbnLag(ctx context.Context) (string, error) {
+	lastProcessedHeight, err := s.db.GetLastProcessedBbnHeight(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the last processed height: %w", err)
+	}
+	tipHeight, err := s.bbnTipHeight(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the chain tip: %w", err)
+	}
+
+	blocks := lag(uint64(tipHeight), lastProcessedHeight)
+	if blocks > s.cfg.API.MaxBbnLag {
+		return "", fmt.Errorf("%d blocks behind the tip %d, above %d", blocks, tipHeight, s.cfg.API.MaxBbnLag)
+	}
+	return fmt.Sprintf("%d blocks behind the tip %d", blocks, tipHeight), nil
+}
+
+// checkLeadership fails once the instance lost or released the leader lease,
+// as it then neither indexes nor stands by
+func (s *Service) checkLeadership(_ context.Context) (string, error) {
+	switch {
+	case !s.cfg.LeaderElection.Enabled:
+		return "leader, leader election disabled", nil
+	case s.IsLeader():
+		return "leader", nil
+	case s.isStandby():
+		return "standby, read-only", nil
+	default:
+		return "", errors.New("stepped down from the leader lease, stopping")
+	}
+}
+
+// checkSupervisedComponents fails while a background component waits to be
+// restarted after a failure, or once one gave up, listing the status of each
+// of them
+func (s *Service) checkSupervisedComponents(_ context.Context) (string, error) {
+	var running int
+	var failing []string
+	for _, status := range s.supervisor.Statuses() {
+		switch status.State {
+		case supervisor.StateRunning:
+			running++
+		case supervisor.StateRestarting, supervisor.StateGaveUp:
+			failing = append(failing, fmt.Sprintf(
+				"%s (%s, %d restarts, last error: %s)",
+				status.Name, status.State, status.Restarts, status.LastError,
+			))
+		}
+	}
+	if len(failing) > 0 {
+		sort.Strings(failing)
+		return "", fmt.Errorf("failing: %s", strings.Join(failing, ", "))
+	}
+	return fmt.Sprintf("%d running", running), nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/supervisor"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newHealthTestConfig() *config.Config {
+	return &config.Config{
+		BBN: config.BBNConfig{ChainId: "bbn-test"},
+		API: config.APIConfig{
+			HealthCheckTimeout:     100 * time.Millisecond,
+			BtcTipMaxAge:           time.Hour,
+			PollerStallTolerance:   time.Minute,
+			BlockProcessorTimeout:  time.Minute,
+			PollerHeartbeatTimeout: time.Hour,
+			MaxBbnLag:              10,
+		},
+	}
+}
+
+func healthChecksByName(report *types.HealthReport) map[string]types.HealthCheck {
+	checks := make(map[string]types.HealthCheck, len(report.Checks))
+	for _, check := range report.Checks {
+		checks[check.Name] = check
+	}
+	return checks
+}
+
+func TestCheckReadiness(t *testing.T) {
+	metrics.Init()
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("Ping", mock.Anything).Return(nil)
+	dbMock.On("GetLastProcessedBbnHeight", mock.Anything).Return(uint64(100), nil)
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetChainID", mock.Anything).Return("bbn-test", nil)
+	bbnMock.On("GetLatestBlockNumber", mock.Anything).Return(int64(105), nil)
+	btcMock := mocks.NewBtcInterface(t)
+	btcMock.On("GetTipHeight").Return(uint64(800), nil)
+	btcMock.On("GetBlockHeaderByHeight", uint64(800)).Return(
+		&wire.BlockHeader{Timestamp: time.Now().Add(-10 * time.Minute)}, nil,
+	)
+
+	service := NewService(newHealthTestConfig(), dbMock, btcMock, nil, bbnMock, nil)
+	service.newPoller("test", time.Second, func(ctx context.Context) *types.Error { return nil })
+	service.health.setChainStateBootstrapped()
+	service.health.setBootstrapped()
+
+	report := service.CheckReadiness(context.Background())
+	require.True(t, report.Healthy, report.Checks)
+	checks := healthChecksByName(report)
+	require.Len(t, checks, 10)
+	require.Equal(t, "bbn-test", checks["bbn"].Message)
+	require.Equal(t, "tip 800", checks["btc"].Message)
+	require.Equal(t, "5 blocks behind the tip 105", checks["bbn_lag"].Message)
+	require.Equal(t, "leader, leader election disabled", checks["leadership"].Message)
+	require.Equal(t, "0 running", checks["components"].Message)
+}
+
+func TestCheckReadinessFailures(t *testing.T) {
+	metrics.Init()
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("Ping", mock.Anything).Return(errors.New("connection refused"))
+	dbMock.On("GetLastProcessedBbnHeight", mock.Anything).Return(uint64(100), nil)
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetChainID", mock.Anything).Return("bbn-other", nil)
+	bbnMock.On("GetLatestBlockNumber", mock.Anything).Return(int64(200), nil)
+	btcMock := mocks.NewBtcInterface(t)
+	btcMock.On("GetTipHeight").Return(uint64(800), nil)
+	btcMock.On("GetBlockHeaderByHeight", uint64(800)).Return(
+		&wire.BlockHeader{Timestamp: time.Now().Add(-2 * time.Hour)}, nil,
+	)
+
+	cfg := newHealthTestConfig()
+	cfg.LeaderElection.Enabled = true
+	service := NewService(cfg, dbMock, btcMock, nil, bbnMock, nil)
+	// The instance lost the leader lease
+	service.leader.steppedDown.Store(true)
+	service.newPoller(
+		"test", time.Second, func(ctx context.Context) *types.Error { return nil }, criticalPoller(time.Minute),
+	)
+	service.health.pollers["test"].completedAt = time.Now().Add(-time.Hour)
+	service.health.pollers["test"].succeededAt = time.Now().Add(-time.Hour)
+	service.pause.pause()
+	// A background component waits to be restarted
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	service.supervisor.Go(ctx, "bbn_new_blocks", supervisor.WithBackoff(time.Hour, time.Hour),
+		func(context.Context) error { return errors.New("subscription closed") },
+	)
+	require.Eventually(t, func() bool {
+		return service.supervisor.Statuses()[0].State == supervisor.StateRestarting
+	}, time.Second, time.Millisecond)
+
+	report := service.CheckReadiness(context.Background())
+	require.False(t, report.Healthy)
+	for name, check := range healthChecksByName(report) {
+		require.False(t, check.Healthy, name)
+	}
+	checks := healthChecksByName(report)
+	require.Equal(t, "connection refused", checks["mongodb"].Message)
+	require.Equal(t, "node serves chain bbn-other, expected bbn-test", checks["bbn"].Message)
+	require.Equal(t, "finality providers and params bootstrap in progress", checks["bootstrap"].Message)
+	require.Equal(t, "stalled: test", checks["pollers"].Message)
+	require.Equal(t, "failing: test (no success for 1h0m0s, above 1m0s)", checks["critical_pollers"].Message)
+	require.Equal(t, "paused by an admin", checks["indexing"].Message)
+	require.Equal(t, "100 blocks behind the tip 200, above 10", checks["bbn_lag"].Message)
+	require.Equal(t, "stepped down from the leader lease, stopping", checks["leadership"].Message)
+	require.Equal(t,
+		"failing: bbn_new_blocks (restarting, 1 restarts, last error: subscription closed)",
+		checks["components"].Message,
+	)
+}
+
+func TestCheckReadinessTimesOutHungDependency(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("Ping", mock.Anything).Return(nil)
+	dbMock.On("GetLastProcessedBbnHeight", mock.Anything).Return(uint64(100), nil)
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetChainID", mock.Anything).Return("bbn-test", nil)
+	bbnMock.On("GetLatestBlockNumber", mock.Anything).Return(int64(100), nil)
+	btcMock := mocks.NewBtcInterface(t)
+	// The BTC client calls do not take a context
+	btcMock.On("GetTipHeight").Run(func(mock.Arguments) { <-release }).Return(uint64(0), errors.New("released")).Maybe()
+
+	service := NewService(newHealthTestConfig(), dbMock, btcMock, nil, bbnMock, nil)
+	service.health.setChainStateBootstrapped()
+	service.health.setBootstrapped()
+
+	start := time.Now()
+	report := service.CheckReadiness(context.Background())
+	require.Less(t, time.Since(start), time.Second)
+
+	require.False(t, report.Healthy)
+	checks := healthChecksByName(report)
+	require.False(t, checks["btc"].Healthy)
+	require.Equal(t, "timed out after 100ms", checks["btc"].Message)
+	require.True(t, checks["mongodb"].Healthy)
+}
+
+func TestPollerMonitoring(t *testing.T) {
+	metrics.Init()
+	service := NewService(newHealthTestConfig(), nil, nil, nil, nil, nil)
+
+	var runs atomic.Int32
+	p := service.newPoller("test_monitoring", 10*time.Millisecond, func(ctx context.Context) *types.Error {
+		if runs.Add(1) == 1 {
+			panic("boom")
+		}
+		return types.NewInternalServiceError(errors.New("failed"))
+	}, criticalPoller(time.Minute))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Start(ctx)
+
+	// the panic is recovered and counted as a failed run
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, 5*time.Millisecond)
+	p.Stop()
+	service.health.mu.Lock()
+	heartbeat := *service.health.pollers["test_monitoring"]
+	service.health.mu.Unlock()
+	require.GreaterOrEqual(t, heartbeat.consecutiveFailures, 3)
+
+	// failing runs do not fail readiness until the threshold
+	message, err := service.checkCriticalPollers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "1 succeeding", message)
+
+	service.health.mu.Lock()
+	service.health.pollers["test_monitoring"].succeededAt = time.Now().Add(-2 * time.Minute)
+	service.health.mu.Unlock()
+	_, err = service.checkCriticalPollers(ctx)
+	require.ErrorContains(t, err, "failing: test_monitoring (no success for 2m0s, above 1m0s)")
+}
+
+func TestCheckLiveness(t *testing.T) {
+	service := NewService(newHealthTestConfig(), nil, nil, nil, nil, nil)
+	require.True(t, service.CheckLiveness(context.Background()).Healthy)
+
+	service.health.startBlockProcessing(1)
+	require.True(t, service.CheckLiveness(context.Background()).Healthy)
+
+	// Stuck on a block
+	service.health.blockProcessingSince = time.Now().Add(-time.Hour)
+	require.False(t, service.CheckLiveness(context.Background()).Healthy)
+
+	service.health.completeBlockProcessing()
+	require.True(t, service.CheckLiveness(context.Background()).Healthy)
+
+	// A halted processor is not restarted by the probe
+	service.health.startBlockProcessing(1)
+	service.health.blockProcessingSince = time.Now().Add(-time.Hour)
+	service.health.setBlockProcessorHalted()
+	require.True(t, service.CheckLiveness(context.Background()).Healthy)
+}
+
+// TestCheckLivenessWedgedPoller wedges a poller on a run, liveness failing
+// once its heartbeat is older than the bound for the process to be restarted
+func TestCheckLivenessWedgedPoller(t *testing.T) {
+	metrics.Init()
+	cfg := newHealthTestConfig()
+	cfg.API.PollerHeartbeatTimeout = 50 * time.Millisecond
+	service := NewService(cfg, nil, nil, nil, nil, nil)
+
+	release := make(chan struct{})
+	var runs atomic.Int32
+	p := service.newPoller("wedged", 10*time.Millisecond, func(ctx context.Context) *types.Error {
+		// The second run hangs until released
+		if runs.Add(1) == 2 {
+			<-release
+		}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Start(ctx)
+	require.Eventually(t, func() bool { return runs.Load() == 2 }, time.Second, time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return !service.CheckLiveness(ctx).Healthy
+	}, time.Second, 5*time.Millisecond, "liveness did not fail on the wedged poller")
+	checks := healthChecksByName(service.CheckLiveness(ctx))
+	require.True(t, checks["block_processor"].Healthy)
+	require.Contains(t, checks["poller_heartbeats"].Message, "wedged: wedged (no heartbeat for")
+
+	// The poller beats again once unwedged
+	close(release)
+	require.Eventually(t, func() bool {
+		return service.CheckLiveness(ctx).Healthy
+	}, time.Second, 5*time.Millisecond)
+	p.Stop()
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/supervisor"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// Leadership transitions of the instance
+const (
+	LeadershipAcquired = "acquired"
+	LeadershipLost     = "lost"
+	LeadershipReleased = "released"
+)
+
+// leaderState is the leader lease of the instance, when several replicas run
+// against the same database
+type leaderState struct {
+	owner  string
+	leader atomic.Bool
+	// steppedDown is set once the instance lost or released the lease it
+	// held, stopping its indexing
+	steppedDown atomic.Bool
+
+	mu sync.Mutex
+	// expiresAt is when the lease held expires unless renewed
+	expiresAt time.Time
+
+	// stop is closed to stop renewing the lease
+	stop     chan struct{}
+	stopOnce sync.Once
+	// renewal is the supervised renewal of the lease, once started
+	renewal atomic.Pointer[supervisor.Component]
+}
+
+func newLeaderState() *leaderState {
+	return &leaderState{
+		owner: lockOwner("indexer"),
+		stop:  make(chan struct{}),
+	}
+}
+
+func (l *leaderState) leaseExpiresAt() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.expiresAt
+}
+
+// leaseValidUntil returns when the lease held is no longer to be relied on,
+// the clock skew margin before its expiry, as a standby with a clock ahead
+// may take it over by then
+func (s *Service) leaseValidUntil() time.Time {
+	return s.leader.leaseExpiresAt().Add(-s.cfg.LeaderElection.ClockSkewMargin)
+}
+
+// IsLeader returns whether the instance holds the leader lease. Without
+// leader election, the instance is always the leader.
+func (s *Service) IsLeader() bool {
+	if !s.cfg.LeaderElection.Enabled {
+		return true
+	}
+	return s.leader.leader.Load()
+}
+
+// isStandby returns whether the instance waits for the leader lease, serving
+// the api read-only, as opposed to leading or having stepped down
+func (s *Service) isStandby() bool {
+	return s.cfg.LeaderElection.Enabled && !s.leader.leader.Load() && !s.leader.steppedDown.Load()
+}
+
+// acquireLeadership acquires or renews the leader lease, failing with a
+// LockHeldError if another instance holds it
+func (s *Service) acquireLeadership(ctx context.Context) error {
+	ttl := s.cfg.LeaderElection.LeaseTTL
+	attemptAt := time.Now()
+	if err := s.db.AcquireLock(ctx, db.LeaderLockName, s.leader.owner, ttl); err != nil {
+		return err
+	}
+
+	s.leader.mu.Lock()
+	s.leader.expiresAt = attemptAt.Add(ttl)
+	s.leader.mu.Unlock()
+	if s.leader.leader.CompareAndSwap(false, true) {
+		metrics.RecordLeadershipTransition(LeadershipAcquired, true)
+		log.Info().Str("owner", s.leader.owner).Msg("acquired the leader lease, starting the indexing")
+	}
+	return nil
+}
+
+// awaitLeadership waits as a standby for the instance to acquire the leader
+// lease, returning false if the indexer stops first
+func (s *Service) awaitLeadership(ctx context.Context) bool {
+	ticker := time.NewTicker(s.cfg.LeaderElection.RenewInterval)
+	defer ticker.Stop()
+
+	var leader string
+	for {
+		err := s.acquireLeadership(ctx)
+		if err == nil {
+			return true
+		}
+		var held *db.LockHeldError
+		if errors.As(err, &held) {
+			if held.Owner != leader {
+				leader = held.Owner
+				log.Info().Str("leader", leader).Msg("standing by, the indexing runs on the leader")
+			}
+		} else {
+			log.Error().Err(err).Msg("failed to acquire the leader lease")
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.blockProcessorStop:
+			return false
+		case <-s.leader.stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// startKeepingLeadership renews the leader lease in the background. A
+// renewal that keeps panicking exits the process, a standby taking over once
+// the lease expires.
+func (s *Service) startKeepingLeadership(ctx context.Context) {
+	s.leader.renewal.Store(s.supervisor.Go(
+		ctx, "leader_lease", supervisor.Immediate().GiveUpAfter(3),
+		func(ctx context.Context) error {
+			s.keepLeadership(ctx)
+			return nil
+		},
+	))
+}
+
+// keepLeadership renews the leader lease until released. Once the lease is
+// taken over by another instance, or about to be no longer valid without
+// being renewed, the instance steps down.
+func (s *Service) keepLeadership(ctx context.Context) {
+	renewInterval := s.cfg.LeaderElection.RenewInterval
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.leader.stop:
+			return
+		case <-ticker.C:
+		}
+
+		err := s.acquireLeadership(ctx)
+		if err == nil {
+			continue
+		}
+		validUntil := s.leaseValidUntil()
+		if !db.IsLockHeldError(err) && time.Now().Add(renewInterval).Before(validUntil) {
+			log.Warn().Err(err).Time("valid_until", validUntil).Msg("failed to renew the leader lease")
+			continue
+		}
+		s.stepDown(ctx, err, validUntil)
+		return
+	}
+}
+
+// stepDown stops the indexing once the leader lease is lost, the block in
+// flight being given until the lease is no longer valid to commit. Past it,
+// the block is cancelled, its writes being rolled back, and the instance only
+// gives the lease up once the block processor returned. The indexer then
+// exits, to be restarted as a standby.
+func (s *Service) stepDown(ctx context.Context, cause error, validUntil time.Time) {
+	s.leader.steppedDown.Store(true)
+	s.leader.leader.Store(false)
+	metrics.RecordLeadershipTransition(LeadershipLost, false)
+	log.Error().Err(cause).Str("owner", s.leader.owner).
+		Msg("lost the leader lease, stopping the indexing")
+
+	stopCtx, cancel := context.WithDeadline(ctx, validUntil)
+	defer cancel()
+	if err := s.StopBbnBlockProcessor(stopCtx); err != nil {
+		log.Error().Err(err).Msg("cancelling the BBN block in flight, the leader lease being no longer valid")
+		if err := s.abortBbnBlockProcessor(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to wait for the cancelled BBN block")
+		}
+	}
+	if err := s.StopPollers(stopCtx); err != nil {
+		log.Error().Err(err).Msg("failed to stop the pollers before the lease expiry")
+	}
+}
+
+// ReleaseLeadership stops renewing the leader lease and releases it, for a
+// standby to take over right away. It is a no-op unless the instance is the
+// leader.
+func (s *Service) ReleaseLeadership(ctx context.Context) error {
+	if !s.cfg.LeaderElection.Enabled {
+		return nil
+	}
+	s.leader.stopOnce.Do(func() { close(s.leader.stop) })
+	if renewal := s.leader.renewal.Load(); renewal != nil {
+		select {
+		case <-renewal.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if !s.leader.leader.Load() {
+		return nil
+	}
+
+	if err := s.db.ReleaseLock(ctx, db.LeaderLockName, s.leader.owner); err != nil {
+		return err
+	}
+	s.leader.steppedDown.Store(true)
+	s.leader.leader.Store(false)
+	metrics.RecordLeadershipTransition(LeadershipReleased, false)
+	log.Info().Str("owner", s.leader.owner).Msg("released the leader lease")
+	return nil
+}
+
+// HoldLeaderLease acquires the leader lease for the one-off operation writing
+// to the database, failing with a conflict while an indexer leads, and renews
+// it until released, so that no standby starts indexing alongside it. The
+// returned context is cancelled once the lease is lost, or about to be no
+// longer valid without being renewed. Without leader election, or in a dry
+// run writing nothing, there is no lease to hold.
+func (s *Service) HoldLeaderLease(
+	ctx context.Context, operation string,
+) (context.Context, func(), *types.Error) {
+	if !s.cfg.LeaderElection.Enabled || s.isDryRun() {
+		return ctx, func() {}, nil
+	}
+
+	owner := lockOwner(operation)
+	ttl := s.cfg.LeaderElection.LeaseTTL
+	acquire := func(ctx context.Context) (time.Time, error) {
+		attemptAt := time.Now()
+		if err := s.db.AcquireLock(ctx, db.LeaderLockName, owner, ttl); err != nil {
+			return time.Time{}, err
+		}
+		return attemptAt.Add(ttl - s.cfg.LeaderElection.ClockSkewMargin), nil
+	}
+	validUntil, err := acquire(ctx)
+	if err != nil {
+		if db.IsLockHeldError(err) {
+			return nil, nil, types.NewErrorWithMsg(
+				http.StatusConflict, types.Conflict, err.Error()+", the indexer must be stopped first",
+			)
+		}
+		return nil, nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to acquire the leader lease: %w", err),
+		)
+	}
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		renewInterval := s.cfg.LeaderElection.RenewInterval
+		ticker := time.NewTicker(renewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-leaseCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			renewedUntil, err := acquire(leaseCtx)
+			if err == nil {
+				validUntil = renewedUntil
+				continue
+			}
+			if !db.IsLockHeldError(err) && time.Now().Add(renewInterval).Before(validUntil) {
+				log.Warn().Err(err).Time("valid_until", validUntil).Msg("failed to renew the leader lease")
+				continue
+			}
+			log.Error().Err(err).Str("owner", owner).Msg("lost the leader lease, stopping the operation")
+			cancel()
+			return
+		}
+	}()
+
+	release := func() {
+		cancel()
+		<-renewed
+		if err := s.db.ReleaseLock(context.WithoutCancel(ctx), db.LeaderLockName, owner); err != nil {
+			log.Error().Err(err).Msg("failed to release the leader lease")
+		}
+	}
+	return leaseCtx, release, nil
+}
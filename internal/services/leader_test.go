@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+// newReplica returns an indexer replica electing its leader over the
+// database, each replica owning the lease under its own name
+func newReplica(database db.DbInterface, name string) *Service {
+	metrics.Init()
+	service := NewService(&config.Config{LeaderElection: config.LeaderElectionConfig{
+		Enabled:       true,
+		LeaseTTL:      2 * time.Second,
+		RenewInterval: 50 * time.Millisecond,
+	}}, database, nil, nil, nil, nil)
+	service.leader.owner = name
+	return service
+}
+
+// awaitLeadershipAsync runs awaitLeadership in the background, returning the
+// channel its result is sent on
+func awaitLeadershipAsync(ctx context.Context, service *Service) <-chan bool {
+	acquired := make(chan bool, 1)
+	go func() { acquired <- service.awaitLeadership(ctx) }()
+	return acquired
+}
+
+func TestStandbyTakesOverOnceLeaderDies(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	database := inmemory.New()
+	leader := newReplica(database, "leader")
+	standby := newReplica(database, "standby")
+
+	require.True(t, leader.awaitLeadership(ctx))
+	require.True(t, leader.IsLeader())
+
+	// The standby waits while the leader renews its lease
+	leaderCtx, killLeader := context.WithCancel(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		leader.keepLeadership(leaderCtx)
+	}()
+	acquired := awaitLeadershipAsync(ctx, standby)
+	time.Sleep(3 * time.Second)
+	require.False(t, standby.IsLeader())
+	msg, err := standby.checkBootstrap(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "standby, serving reads", msg)
+
+	// The leader dies without releasing its lease, taken over once expired
+	killLeader()
+	<-renewed
+	select {
+	case ok := <-acquired:
+		require.True(t, ok)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the standby did not take over the expired lease")
+	}
+	require.True(t, standby.IsLeader())
+}
+
+func TestLeaderStepsDownOnceLeaseTakenOver(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	leader := newReplica(database, "leader")
+	require.True(t, leader.awaitLeadership(ctx))
+
+	// Another replica takes the lease over, the leader having stalled past
+	// its expiry
+	time.Sleep(3 * time.Second)
+	require.NoError(t, database.AcquireLock(ctx, db.LeaderLockName, "other", time.Minute))
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		leader.keepLeadership(ctx)
+	}()
+	select {
+	case <-renewed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the leader did not step down")
+	}
+	require.False(t, leader.IsLeader())
+	// The block processor is stopped
+	select {
+	case <-leader.blockProcessorStop:
+	default:
+		t.Fatal("the block processor is not stopped")
+	}
+	// The lease of the other replica is kept
+	require.NoError(t, leader.ReleaseLeadership(ctx))
+	require.True(t, db.IsLockHeldError(database.AcquireLock(ctx, db.LeaderLockName, "leader", time.Minute)))
+}
+
+func TestLeaderStepDownCancelsBlockInFlight(t *testing.T) {
+	service, database := startSlowBlockProcessor(t, time.Minute)
+
+	// The lease is no longer valid, the block in flight cannot commit
+	stepped := make(chan struct{})
+	go func() {
+		defer close(stepped)
+		service.stepDown(context.Background(), errors.New("lease taken over"), time.Now())
+	}()
+	select {
+	case <-stepped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the leader did not step down")
+	}
+	select {
+	case <-service.blockProcessorDone:
+	default:
+		t.Fatal("the block processor still runs once stepped down")
+	}
+
+	// The marker of the cancelled block is left for the next leader
+	lastProcessed, err := database.GetLastProcessedBbnBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), lastProcessed.Height)
+	require.NotNil(t, lastProcessed.ProcessingMarker)
+	require.Equal(t, uint64(1), lastProcessed.ProcessingMarker.Height)
+}
+
+func TestLeaderStepsDownWithinClockSkewMargin(t *testing.T) {
+	ctx := context.Background()
+	chaos := db.NewChaosDatabase(inmemory.New())
+	leader := newReplica(chaos, "leader")
+	leader.cfg.LeaderElection.ClockSkewMargin = time.Second
+	require.True(t, leader.awaitLeadership(ctx))
+	acquiredAt := time.Now()
+
+	// The renewals fail, the leader stepping down the margin before the
+	// lease expires
+	chaos.Inject("AcquireLock", db.Fault{Err: errors.New("mongo unreachable")})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		leader.keepLeadership(ctx)
+	}()
+	select {
+	case <-renewed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the leader did not step down")
+	}
+	require.False(t, leader.IsLeader())
+	require.Less(t, time.Since(acquiredAt), leader.cfg.LeaderElection.LeaseTTL-time.Second)
+}
+
+func TestStandbyDoesNotFlushOutbox(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.AcquireLock(ctx, db.LeaderLockName, "leader", time.Minute))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+		StakingTxHashHex: testCovenantTxHashA,
+		State:            types.StateActive,
+	}))
+	require.NoError(t, database.SaveOutboxEvent(ctx, model.NewActiveStakingOutboxEvent(
+		&model.BTCDelegationDetails{StakingTxHashHex: testCovenantTxHashA}, 100, 1,
+	)))
+	standby := newReplica(database, "standby")
+
+	// The outbox is relayed by the leader only
+	require.NoError(t, standby.FlushOutbox(ctx))
+	unsent, err := database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+	require.NoError(t, err)
+	require.Len(t, unsent, 1)
+}
+
+func TestReleasedLeaseTakenOverRightAway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	database := inmemory.New()
+	leader := newReplica(database, "leader")
+	standby := newReplica(database, "standby")
+
+	require.True(t, leader.awaitLeadership(ctx))
+	leader.startKeepingLeadership(ctx)
+	acquired := awaitLeadershipAsync(ctx, standby)
+
+	require.NoError(t, leader.ReleaseLeadership(ctx))
+	require.False(t, leader.IsLeader())
+	select {
+	case ok := <-acquired:
+		require.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("the standby did not take over the released lease")
+	}
+}
+
+func TestStandbyStopsAwaitingOnShutdown(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.AcquireLock(ctx, db.LeaderLockName, "leader", time.Minute))
+	standby := newReplica(database, "standby")
+
+	acquired := awaitLeadershipAsync(ctx, standby)
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, standby.StopBbnBlockProcessor(shutdownCtx))
+	require.NoError(t, standby.ReleaseLeadership(shutdownCtx))
+	require.False(t, <-acquired)
+}
+
+func TestStartHeightAppliedByLeaderOnly(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.UpdateLastProcessedBbnHeight(ctx, 10, "hash"))
+	require.NoError(t, database.AcquireLock(ctx, db.LeaderLockName, "leader", time.Minute))
+	standby := New(&config.Config{LeaderElection: config.LeaderElectionConfig{
+		Enabled:       true,
+		LeaseTTL:      2 * time.Second,
+		RenewInterval: 50 * time.Millisecond,
+	}}, Dependencies{
+		Db:          database,
+		Btc:         fixtures.NewBtcChain(0, 120),
+		BtcNotifier: fixtures.NewBtcChain(0, 120),
+		Bbn:         fixtures.NewBbnClient(),
+		Emitter:     consumer.NewMemoryEmitter(),
+	})
+	standby.leader.owner = "standby"
+
+	// The standby does not resync the processing of the leader
+	resyncHeight := uint64(5)
+	syncCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		standby.StartIndexerSync(syncCtx, &StartHeightOverrides{ResyncHeight: &resyncHeight})
+	}()
+	time.Sleep(200 * time.Millisecond)
+	stop()
+	<-stopped
+	lastProcessed, err := database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), lastProcessed.Height)
+
+	// The leader applies it once elected
+	standby.applyStartHeight(ctx, StartHeightOverrides{ResyncHeight: &resyncHeight})
+	lastProcessed, err = database.GetLastProcessedBbnBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, resyncHeight, lastProcessed.Height)
+}
+
+func TestOperationHoldsLeaderLease(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.AcquireLock(ctx, db.LeaderLockName, "leader", time.Minute))
+	operator := newReplica(database, "operator")
+
+	// The operation is refused while an indexer leads
+	_, _, err := operator.HoldLeaderLease(ctx, "backfill")
+	require.NotNil(t, err)
+	require.Equal(t, types.Conflict, err.ErrorCode)
+	require.NoError(t, database.ReleaseLock(ctx, db.LeaderLockName, "leader"))
+
+	// No standby takes over while it runs, past the lease TTL
+	leaseCtx, release, err := operator.HoldLeaderLease(ctx, "backfill")
+	require.Nil(t, err)
+	standby := newReplica(database, "standby")
+	time.Sleep(3 * time.Second)
+	require.True(t, db.IsLockHeldError(standby.acquireLeadership(ctx)))
+	require.NoError(t, leaseCtx.Err())
+
+	// The lease is released once it completes
+	release()
+	require.Error(t, leaseCtx.Err())
+	require.NoError(t, standby.acquireLeadership(ctx))
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	bbn "github.com/babylonlabs-io/babylon/types"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// chainBtcNetworks maps the chain ids of the public BBN chains, or their
+// prefixes ending with a dash, to the BTC network they checkpoint to
+var chainBtcNetworks = map[string]utils.SupportedBtcNetwork{
+	"bbn-1":       utils.BtcMainnet,
+	"bbn-test-":   utils.BtcSignet,
+	"bbn-devnet-": utils.BtcSignet,
+}
+
+// chainBtcNetwork returns the BTC network the BBN chain of the chain id
+// checkpoints to, if known
+func chainBtcNetwork(chainId string) (utils.SupportedBtcNetwork, bool) {
+	if network, ok := chainBtcNetworks[chainId]; ok {
+		return network, true
+	}
+	for prefix, network := range chainBtcNetworks {
+		if strings.HasSuffix(prefix, "-") && strings.HasPrefix(chainId, prefix) {
+			return network, true
+		}
+	}
+	return "", false
+}
+
+// VerifyBtcNetwork checks that the configured BTC network is the one served
+// by the BTC backend and the one the BBN chain checkpoints to, derived from
+// its chain id, and that the slashing pk scripts and covenant keys of the
+// stored params decode on it. The BBN chains of unknown chain ids are not
+// checked. Any mismatch is returned as an error naming both sides, as
+// indexing a BBN chain against the BTC blocks of another network corrupts
+// the data.
+func (s *Service) VerifyBtcNetwork(ctx context.Context) *types.Error {
+	configured := s.cfg.BTC.NetParams
+	var mismatches []string
+
+	backendNetwork, err := s.btc.GetNetwork()
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get the BTC backend network: %w", err),
+		)
+	}
+	if backendNetwork != configured {
+		mismatches = append(mismatches, fmt.Sprintf(
+			"BTC backend serves network %s, btc.netparams is %s", backendNetwork, configured,
+		))
+	}
+
+	chainId, err := s.bbn.GetChainID(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get the BBN chain id: %w", err),
+		)
+	}
+	if expected, ok := chainBtcNetwork(chainId); !ok {
+		log.Warn().
+			Str("chain_id", chainId).
+			Msg("unknown BBN chain id, the BTC network it checkpoints to is not verified")
+	} else if expected.String() != configured {
+		mismatches = append(mismatches, fmt.Sprintf(
+			"BBN chain %s checkpoints to BTC network %s, btc.netparams is %s", chainId, expected, configured,
+		))
+	}
+
+	netParams, err := utils.GetBTCParams(configured)
+	if err != nil {
+		return types.NewInternalServiceError(err)
+	}
+	storedStakingParams, err := s.db.GetAllStakingParams(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get stored staking params: %w", err),
+		)
+	}
+	versions := make([]uint32, 0, len(storedStakingParams))
+	for version := range storedStakingParams {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	for _, version := range versions {
+		for _, problem := range verifyParamsNetwork(storedStakingParams[version], netParams) {
+			mismatches = append(mismatches, fmt.Sprintf("staking params version %d %s", version, problem))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		s.alerter.Alert(ctx, alerting.SeverityCritical, "BTC network differs from the BBN chain", map[string]string{
+			"chain_id":    chainId,
+			"btc_network": configured,
+			"mismatches":  strings.Join(mismatches, "; "),
+		})
+		return types.NewInternalServiceError(
+			fmt.Errorf("%w: %s", types.ErrBtcNetworkMismatch, strings.Join(mismatches, "; ")),
+		)
+	}
+
+	log.Info().
+		Str("btc_network", configured).
+		Str("chain_id", chainId).
+		Msg("BTC network matches the BTC backend and the BBN chain")
+	return nil
+}
+
+// verifyParamsNetwork returns what of the staking params does not decode on
+// the BTC network. A pk script does not tell its network, so the address the
+// slashing pk script pays to is logged encoded for the network, for an
+// operator to recognize.
+func verifyParamsNetwork(params *bbnclient.StakingParams, netParams *chaincfg.Params) []string {
+	var problems []string
+	script, err := hex.DecodeString(params.SlashingPkScript)
+	if err == nil {
+		_, err = txscript.DisasmString(script)
+	}
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("slashing pk script %s is invalid: %v", params.SlashingPkScript, err))
+	} else if _, addrs, _, err := txscript.ExtractPkScriptAddrs(script, netParams); err == nil && len(addrs) == 1 {
+		log.Info().
+			Str("slashing_address", addrs[0].EncodeAddress()).
+			Str("btc_network", netParams.Name).
+			Msg("staking params slashing pk script address")
+	}
+
+	for _, covenantPk := range params.CovenantPks {
+		if _, err := bbn.NewBIP340PubKeyFromHex(covenantPk); err != nil {
+			problems = append(problems, fmt.Sprintf("covenant pk %s is not a BTC public key: %v", covenantPk, err))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+)
+
+func TestVerifyBtcNetwork(t *testing.T) {
+	invalidParams := fixtures.NewStakingParams()
+	invalidParams.SlashingPkScript = "not hex"
+	invalidParams.CovenantPks[1] = "0102"
+
+	tests := []struct {
+		name           string
+		netParams      string
+		backendNetwork string
+		chainId        string
+		invalidParams  bool
+		expectedErr    string
+	}{
+		{
+			name:           "signet with the BBN testnet",
+			netParams:      "signet",
+			backendNetwork: "signet",
+			chainId:        "bbn-test-5",
+		},
+		{
+			name:           "mainnet with the BBN mainnet",
+			netParams:      "mainnet",
+			backendNetwork: "mainnet",
+			chainId:        "bbn-1",
+		},
+		{
+			name:           "custom BBN chain",
+			netParams:      "regtest",
+			backendNetwork: "regtest",
+			chainId:        "chain-test",
+		},
+		{
+			name:           "signet with the BBN mainnet",
+			netParams:      "signet",
+			backendNetwork: "signet",
+			chainId:        "bbn-1",
+			expectedErr:    "BTC network mismatch: BBN chain bbn-1 checkpoints to BTC network mainnet, btc.netparams is signet",
+		},
+		{
+			name:           "backend on another network",
+			netParams:      "mainnet",
+			backendNetwork: "testnet",
+			chainId:        "bbn-1",
+			expectedErr:    "BTC network mismatch: BTC backend serves network testnet, btc.netparams is mainnet",
+		},
+		{
+			name:           "invalid stored params",
+			netParams:      "signet",
+			backendNetwork: "signet",
+			chainId:        "bbn-test-5",
+			invalidParams:  true,
+			expectedErr: "BTC network mismatch: " +
+				"staking params version 1 slashing pk script not hex is invalid: encoding/hex: invalid byte: U+006E 'n'; " +
+				"staking params version 1 covenant pk 0102 is not a BTC public key: malformed data for BIP340 public key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			database := inmemory.New()
+			require.NoError(t, database.SaveStakingParams(ctx, 0, fixtures.NewStakingParams()))
+			if tt.invalidParams {
+				require.NoError(t, database.SaveStakingParams(ctx, 1, invalidParams))
+			}
+			btcMock := mocks.NewBtcInterface(t)
+			btcMock.On("GetNetwork").Return(tt.backendNetwork, nil)
+			bbnMock := mocks.NewBbnInterface(t)
+			bbnMock.On("GetChainID", mock.Anything).Return(tt.chainId, nil)
+
+			service := NewService(&config.Config{BTC: config.BTCConfig{NetParams: tt.netParams}},
+				database, btcMock, nil, bbnMock, nil)
+			err := service.VerifyBtcNetwork(ctx)
+			if tt.expectedErr == "" {
+				require.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			require.ErrorIs(t, err, types.ErrBtcNetworkMismatch)
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/errorreporting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// outboxRelayBatchSize is the number of outbox events relayed per batch
+	outboxRelayBatchSize = 100
+	// outboxRelayMaxBackoff caps the delay between the pushes of a failing event
+	outboxRelayMaxBackoff = 10 * time.Minute
+)
+
+// outboxRelayResult summarizes a run of the outbox relay
+type outboxRelayResult struct {
+	// published is the number of events pushed to the queue per event type
+	published map[string]uint64
+	failed    uint64
+	poisoned  uint64
+}
+
+func (s *Service) StartOutboxRelay(ctx context.Context) {
+	outboxRelayPoller := s.newPoller(
+		"outbox_relay",
+		s.cfg.Poller.OutboxRelayInterval,
+		s.runOutboxRelay,
+		// the relay runs every few seconds, so that a handful of failed runs
+		// is no outage yet
+		criticalPoller(max(time.Minute, 10*s.cfg.Poller.OutboxRelayInterval)),
+	)
+	s.startPoller(ctx, outboxRelayPoller)
+}
+
+// runOutboxRelay relays the unsent outbox events unless the indexing is
+// paused, recording the relay metrics
+func (s *Service) runOutboxRelay(ctx context.Context) *types.Error {
+	err := s.pausable(func(ctx context.Context) *types.Error {
+		result, err := s.relayOutboxEvents(ctx)
+		for eventType, count := range result.published {
+			metrics.RecordOutboxEventsPublished(eventType, count)
+		}
+		for i := uint64(0); i < result.failed; i++ {
+			metrics.RecordQueueSendError()
+		}
+		return err
+	})(ctx)
+	// Recorded while the indexing is paused too, so that the unsent events
+	// keep aging
+	if statsErr := s.recordOutboxStats(ctx); statsErr != nil {
+		log.Error().Err(statsErr).Msg("failed to record the outbox stats")
+	}
+	return err
+}
+
+// relayOutboxEvents pushes the unsent outbox events to the queue in insertion
+// order and marks them sent. A failed push is retried on later runs with an
+// exponential backoff, and the later events of the same delegation wait for
+// it so that each delegation keeps the order of its events. After too many
+// attempts the event is flagged poison and no longer holds the delegation
+// back. As an event may be pushed again if marking it sent fails, consumers
+// must handle duplicates.
+func (s *Service) relayOutboxEvents(ctx context.Context) (*outboxRelayResult, *types.Error) {
+	result := &outboxRelayResult{published: make(map[string]uint64)}
+	now := time.Now()
+	// delegations whose pending events must not be pushed during this run
+	blocked := make(map[string]struct{})
+
+	// the events are paged on their creation time and id, as many events
+	// share the same creation time
+	var createdAfter int64
+	var afterId string
+	for {
+		events, err := s.db.GetUnsentOutboxEvents(ctx, createdAfter, afterId, outboxRelayBatchSize)
+		if err != nil {
+			return result, types.NewInternalServiceError(
+				fmt.Errorf("failed to get unsent outbox events: %w", err),
+			)
+		}
+		if len(events) == 0 {
+			return result, nil
+		}
+
+		for _, event := range events {
+			createdAfter, afterId = event.CreatedAt, event.Id
+			if _, ok := blocked[event.StakingTxHashHex]; ok {
+				continue
+			}
+			if event.NextAttemptAt > now.Unix() {
+				blocked[event.StakingTxHashHex] = struct{}{}
+				continue
+			}
+
+			pushStart := time.Now()
+			pushErr := pushOutboxEvent(s.queueManager, event)
+			metrics.RecordOutboxPublish(s.cfg.Emitter.GetType(), pushOutcome(pushErr), time.Since(pushStart))
+			if pushErr != nil {
+				result.failed++
+				attempts := event.Attempts + 1
+				// A push failing with a permanent error fails again on a retry
+				retryable := types.IsRetryable(pushErr)
+				poison := !retryable || attempts >= s.cfg.Poller.OutboxRelayMaxAttempts
+				if poison {
+					result.poisoned++
+					log.Error().Err(pushErr).
+						Str("id", event.Id).
+						Int("attempts", attempts).
+						Bool("retryable", retryable).
+						Msg("outbox event flagged poison")
+					s.alerter.Alert(ctx, alerting.SeverityCritical, "Outbox event flagged poison", map[string]string{
+						"id":         event.Id,
+						"staking_tx": event.StakingTxHashHex,
+						"attempts":   strconv.Itoa(attempts),
+						"last_error": pushErr.Error(),
+					})
+					errorreporting.Report(ctx, "outbox", pushErr, map[string]string{
+						logging.StakingTxHashField: event.StakingTxHashHex,
+					})
+				} else {
+					blocked[event.StakingTxHashHex] = struct{}{}
+					log.Warn().Err(pushErr).
+						Str("id", event.Id).
+						Int("attempts", attempts).
+						Msg("failed to push outbox event, will retry")
+				}
+
+				nextAttemptAt := now.Add(s.outboxRetryBackoff(attempts)).Unix()
+				if err := s.db.MarkOutboxEventFailed(
+					ctx, event.Id, pushErr.Error(), nextAttemptAt, poison,
+				); err != nil {
+					return result, types.NewInternalServiceError(
+						fmt.Errorf("failed to record the failed push of outbox event %s: %w", event.Id, err),
+					)
+				}
+				continue
+			}
+
+			if err := s.db.MarkOutboxEventSent(ctx, event.Id, now.Unix()); err != nil {
+				return result, types.NewInternalServiceError(
+					fmt.Errorf("failed to mark outbox event %s as sent: %w", event.Id, err),
+				)
+			}
+			result.published[event.EventType]++
+		}
+
+		log.Debug().Int("events", len(events)).Msg("relayed outbox events to the queue")
+	}
+}
+
+// outboxRetryBackoff returns the delay before retrying an event after the
+// given number of failed pushes
+func (s *Service) outboxRetryBackoff(attempts int) time.Duration {
+	backoff := s.cfg.Poller.OutboxRelayRetryBackoff
+	for i := 1; i < attempts && backoff < outboxRelayMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, outboxRelayMaxBackoff)
+}
+
+func (s *Service) recordOutboxStats(ctx context.Context) error {
+	stats, err := s.db.GetOutboxStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	var oldestUnsentAge time.Duration
+	if stats.Unsent > 0 {
+		oldestUnsentAge = time.Since(time.Unix(0, stats.OldestUnsentAt))
+	}
+	metrics.RecordOutboxStats(stats.Unsent, oldestUnsentAge, stats.Poison)
+	return nil
+}
+
+// ReportPoisonOutboxEvents logs the outbox events flagged poison and, if
+// requested, requeues them so that the relay pushes them again
+func (s *Service) ReportPoisonOutboxEvents(ctx context.Context, requeue bool) *types.Error {
+	events, err := s.db.GetPoisonOutboxEvents(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get poison outbox events: %w", err),
+		)
+	}
+
+	for _, event := range events {
+		log.Warn().
+			Str("id", event.Id).
+			Str("eventType", event.EventType).
+			Str("stakingTxHashHex", event.StakingTxHashHex).
+			Int("attempts", event.Attempts).
+			Str("lastError", event.LastError).
+			Msg("poison outbox event")
+	}
+	log.Info().Int("events", len(events)).Msg("found poison outbox events")
+
+	if !requeue || len(events) == 0 {
+		return nil
+	}
+
+	requeued, err := s.db.RequeuePoisonOutboxEvents(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to requeue poison outbox events: %w", err),
+		)
+	}
+	log.Info().Uint64("events", requeued).Msg("requeued poison outbox events")
+	return nil
+}
+
+// pushOutcome returns the outcome of the push of an outbox event, as
+// published by the publish metrics
+func pushOutcome(pushErr *types.Error) metrics.Outcome {
+	switch {
+	case pushErr == nil:
+		return metrics.Success
+	case errors.Is(pushErr, consumer.ErrPublishNacked):
+		return metrics.Nack
+	default:
+		return metrics.Failure
+	}
+}
+
+func pushOutboxEvent(emitter consumer.EventConsumer, event *model.OutboxEvent) *types.Error {
+	// Emitters such as the webhook one publish the events as recorded
+	if outboxEmitter, ok := emitter.(consumer.OutboxEventEmitter); ok {
+		if err := outboxEmitter.PushOutboxEvent(event); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to push outbox event %s: %w", event.Id, err),
+			)
+		}
+		return nil
+	}
+
+	delivery := schema.Delivery{
+		IdempotencyKey: event.IdempotencyKey,
+		Sequence:       event.Sequence,
+	}
+	switch event.EventType {
+	case model.OutboxEventTypeActiveStaking:
+		stakingEvent := consumer.NewActiveStakingEvent(
+			event.StakingTxHashHex,
+			event.StakerBtcPkHex,
+			event.FinalityProviderBtcPksHex,
+			event.StakingAmount,
+			event.StakingStartHeight,
+			event.ParamsVersion,
+		)
+		stakingEvent.Delivery = delivery
+		if err := emitter.PushActiveStakingEvent(&stakingEvent); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to push the staking event to the queue: %w", err),
+			)
+		}
+		return nil
+	case model.OutboxEventTypeUnbondingStaking, model.OutboxEventTypeSlashedStaking:
+		stakingEvent := consumer.NewUnbondingStakingEvent(
+			event.StakingTxHashHex,
+			event.StakerBtcPkHex,
+			event.FinalityProviderBtcPksHex,
+			event.StakingAmount,
+			event.StakingStartHeight,
+			event.ParamsVersion,
+			event.SubState,
+			event.UnbondingTxHashHex,
+			event.WithdrawableHeight,
+		)
+		stakingEvent.Delivery = delivery
+		if err := emitter.PushUnbondingStakingEvent(&stakingEvent); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to push the unbonding event to the queue: %w", err),
+			)
+		}
+		return nil
+	case model.OutboxEventTypeWithdrawable:
+		withdrawableEvent := consumer.NewWithdrawableStakingEvent(
+			event.StakingTxHashHex, event.SubState, event.BtcHeight,
+		)
+		withdrawableEvent.Delivery = delivery
+		if err := emitter.PushWithdrawableStakingEvent(&withdrawableEvent); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to push the withdrawable event to the queue: %w", err),
+			)
+		}
+		return nil
+	case model.OutboxEventTypeWithdrawn:
+		withdrawnEvent := consumer.NewWithdrawnStakingEvent(
+			event.StakingTxHashHex, event.SubState, event.BtcHeight, event.SpendingTxHashHex,
+		)
+		withdrawnEvent.Delivery = delivery
+		if err := emitter.PushWithdrawnStakingEvent(&withdrawnEvent); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to push the withdrawn event to the queue: %w", err),
+			)
+		}
+		return nil
+	case model.OutboxEventTypeSlashedFunds:
+		slashedFundsEvent := consumer.NewSlashedFundsStakingEvent(
+			event.StakingTxHashHex, event.SubState, event.BtcHeight, event.SpendingTxHashHex,
+		)
+		slashedFundsEvent.Delivery = delivery
+		if err := emitter.PushSlashedFundsStakingEvent(&slashedFundsEvent); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to push the slashed funds event to the queue: %w", err),
+			)
+		}
+		return nil
+	default:
+		return types.NewInternalServiceError(types.NewPermanentError(
+			fmt.Errorf("unknown outbox event type %s of event %s", event.EventType, event.Id),
+		))
+	}
+}
@@ -0,0 +1,693 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer"
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/dedup"
+	"github.com/babylonlabs-io/babylon-staking-indexer/consumer/schema"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"github.com/babylonlabs-io/staking-queue-client/client"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testStakingTxHash = "staking-tx"
+
+// fakeAlerter records the titles of the pushed alerts
+type fakeAlerter struct {
+	titles     []string
+	severities []alerting.Severity
+	fields     []map[string]string
+}
+
+func (a *fakeAlerter) Alert(_ context.Context, severity alerting.Severity, title string, fields map[string]string) {
+	a.titles = append(a.titles, title)
+	a.severities = append(a.severities, severity)
+	a.fields = append(a.fields, fields)
+}
+
+// outboxEventTypes are the outbox event types of the emitted events
+var outboxEventTypes = map[client.EventType]string{
+	client.ActiveStakingEventType:         model.OutboxEventTypeActiveStaking,
+	client.UnbondingStakingEventType:      model.OutboxEventTypeUnbondingStaking,
+	consumer.WithdrawableStakingEventType: model.OutboxEventTypeWithdrawable,
+	consumer.WithdrawnStakingEventType:    model.OutboxEventTypeWithdrawn,
+	consumer.SlashedFundsStakingEventType: model.OutboxEventTypeSlashedFunds,
+}
+
+// outboxTestEnv wires a service to an in-memory delegation, its timelock and
+// the outbox
+type outboxTestEnv struct {
+	service    *Service
+	emitter    *consumer.MemoryEmitter
+	alerter    *fakeAlerter
+	delegation *model.BTCDelegationDetails
+	timeLocks  []model.TimeLockDocument
+	outbox     []*model.OutboxEvent
+	// transitions are the recorded state transitions of the delegation
+	transitions []*model.DelegationStateTransition
+	// deleteFailures is the number of timelock deletions failing first
+	deleteFailures int
+	// markSentFailures is the number of outbox events failing to be marked
+	// sent first, as if the indexer crashed right after publishing them
+	markSentFailures int
+}
+
+func newOutboxTestEnv(t *testing.T) *outboxTestEnv {
+	metrics.Init()
+	env := &outboxTestEnv{
+		emitter: consumer.NewMemoryEmitter(),
+		alerter: &fakeAlerter{},
+		delegation: &model.BTCDelegationDetails{
+			StakingTxHashHex: testStakingTxHash,
+			State:            types.StateUnbonding,
+			SubState:         types.SubStateTimelock,
+		},
+		timeLocks: []model.TimeLockDocument{
+			*model.NewTimeLockDocument(testStakingTxHash, 100, types.SubStateTimelock),
+		},
+	}
+
+	btcMock := mocks.NewBtcInterface(t)
+	btcMock.On("GetTipHeight").Return(uint64(110), nil).Maybe()
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetExpiryBacklogStats", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+	dbMock.On("ForEachExpiredDelegation", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error) error {
+			for _, tlDoc := range append([]model.TimeLockDocument{}, env.timeLocks...) {
+				if err := fn(tlDoc); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	).Maybe()
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testStakingTxHash).Return(
+		func(ctx context.Context, stakingTxHash string) (*model.BTCDelegationDetails, error) {
+			delegation := *env.delegation
+			return &delegation, nil
+		},
+	).Maybe()
+	dbMock.On("GetBTCDelegationSummaryByStakingTxHash", mock.Anything, testStakingTxHash).Return(
+		func(ctx context.Context, stakingTxHash string) (*model.BTCDelegationSummary, error) {
+			return env.delegation.Summary(), nil
+		},
+	).Maybe()
+	saveTransition := func(ctx context.Context, transition *model.DelegationStateTransition) error {
+		env.transitions = append(env.transitions, transition)
+		return nil
+	}
+	saveOutboxEvent := func(ctx context.Context, event *model.OutboxEvent) error {
+		for _, existing := range env.outbox {
+			if existing.Id == event.Id {
+				return &db.DuplicateKeyError{Key: event.Id, Message: "outbox event already exists"}
+			}
+		}
+		event.Sequence = uint64(len(env.delegationEvents(event.StakingTxHashHex)) + 1)
+		env.outbox = append(env.outbox, event)
+		return nil
+	}
+	dbMock.On("ApplyBTCDelegationStateChange", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, change db.DelegationStateChange) error {
+			if !utils.Contains(change.QualifiedPreviousStates, env.delegation.State) {
+				return &db.NotFoundError{Key: change.StakingTxHash, Message: "not qualified"}
+			}
+			if change.Transition != nil {
+				change.Transition.FromState = env.delegation.State
+				_ = saveTransition(ctx, change.Transition)
+			}
+			if change.OutboxEvent != nil {
+				if err := saveOutboxEvent(ctx, change.OutboxEvent); err != nil && !db.IsDuplicateKeyError(err) {
+					return err
+				}
+			}
+			env.delegation.State = change.NewState
+			env.delegation.SubState = *change.NewSubState
+			return nil
+		},
+	).Maybe()
+	dbMock.On("SaveDelegationStateTransition", mock.Anything, mock.Anything).Return(saveTransition).Maybe()
+	dbMock.On("GetDelegationStateTransitions", mock.Anything, testStakingTxHash).Return(
+		func(ctx context.Context, stakingTxHash string) ([]*model.DelegationStateTransition, error) {
+			return env.transitions, nil
+		},
+	).Maybe()
+	dbMock.On("DeleteExpiredDelegation", mock.Anything, testStakingTxHash).Return(
+		func(ctx context.Context, stakingTxHash string) error {
+			if env.deleteFailures > 0 {
+				env.deleteFailures--
+				return errors.New("db unavailable")
+			}
+			env.timeLocks = nil
+			return nil
+		},
+	).Maybe()
+	dbMock.On("SaveOutboxEvent", mock.Anything, mock.Anything).Return(saveOutboxEvent).Maybe()
+	dbMock.On("GetDelegationOutboxEvents", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, stakingTxHash string) ([]*model.OutboxEvent, error) {
+			return env.delegationEvents(stakingTxHash), nil
+		},
+	).Maybe()
+	dbMock.On("GetOutboxSequence", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, stakingTxHash string) (uint64, error) {
+			var sequence uint64
+			for _, event := range env.delegationEvents(stakingTxHash) {
+				sequence = max(sequence, event.Sequence)
+			}
+			if sequence == 0 {
+				return 0, &db.NotFoundError{Key: stakingTxHash, Message: "outbox sequence not found"}
+			}
+			return sequence, nil
+		},
+	).Maybe()
+	dbMock.On("GetUnsentOutboxEvents", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, createdAfter int64, afterId string, limit uint64) ([]*model.OutboxEvent, error) {
+			var events []*model.OutboxEvent
+			for _, event := range env.unsentEvents() {
+				after := event.CreatedAt > createdAfter || (event.CreatedAt == createdAfter && event.Id > afterId)
+				if after && uint64(len(events)) < limit {
+					events = append(events, event)
+				}
+			}
+			return events, nil
+		},
+	).Maybe()
+	dbMock.On("MarkOutboxEventSent", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, id string, sentAt int64) error {
+			if env.markSentFailures > 0 {
+				env.markSentFailures--
+				return errors.New("db unavailable")
+			}
+			env.outboxEvent(id).SentAt = sentAt
+			return nil
+		},
+	).Maybe()
+	dbMock.On("MarkOutboxEventFailed", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool) error {
+			event := env.outboxEvent(id)
+			event.Attempts++
+			event.LastError = lastError
+			event.NextAttemptAt = nextAttemptAt
+			event.Poison = poison
+			return nil
+		},
+	).Maybe()
+
+	env.service = &Service{
+		cfg: &config.Config{
+			Poller: config.PollerConfig{
+				ExpiredDelegationsLimit: 100,
+				OutboxRelayMaxAttempts:  3,
+				// retried failed pushes are due on the next run
+				OutboxRelayRetryBackoff: time.Nanosecond,
+			},
+		},
+		db:           dbMock,
+		btc:          btcMock,
+		queueManager: env.emitter,
+		alerter:      env.alerter,
+	}
+
+	return env
+}
+
+func (env *outboxTestEnv) outboxEvent(id string) *model.OutboxEvent {
+	for _, event := range env.outbox {
+		if event.Id == id {
+			return event
+		}
+	}
+	return nil
+}
+
+// delegationEvents returns the outbox events of a delegation, in sequence order
+func (env *outboxTestEnv) delegationEvents(stakingTxHash string) []*model.OutboxEvent {
+	var events []*model.OutboxEvent
+	for _, event := range env.outbox {
+		if event.StakingTxHashHex == stakingTxHash {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// pushed returns the events pushed to the emitter as
+// "eventType:stakingTxHash", in push order
+func (env *outboxTestEnv) pushed() []string {
+	var pushed []string
+	for _, emitted := range env.emitter.Events() {
+		pushed = append(pushed, outboxEventTypes[emitted.Event.GetEventType()]+":"+emitted.Event.GetStakingTxHashHex())
+	}
+	return pushed
+}
+
+// withdrawable returns the withdrawable events pushed to the emitter
+func (env *outboxTestEnv) withdrawable() []*consumer.WithdrawalStakingEvent {
+	var events []*consumer.WithdrawalStakingEvent
+	for _, emitted := range env.emitter.Events() {
+		if emitted.Queue == consumer.WithdrawableStakingQueueName {
+			events = append(events, emitted.Event.(*consumer.WithdrawalStakingEvent))
+		}
+	}
+	return events
+}
+
+// deliveries returns the delivery identifiers of the pushed events
+func (env *outboxTestEnv) deliveries() []schema.Delivery {
+	var deliveries []schema.Delivery
+	for _, emitted := range env.emitter.Events() {
+		deliveries = append(deliveries, emitted.Delivery())
+	}
+	return deliveries
+}
+
+// unsentEvents returns the outbox events still to be pushed, in insertion order
+func (env *outboxTestEnv) unsentEvents() []*model.OutboxEvent {
+	var events []*model.OutboxEvent
+	for _, event := range env.outbox {
+		if event.SentAt == 0 && !event.Poison {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestWithdrawableEventPublishedOnceAfterPublishFailure(t *testing.T) {
+	ctx := context.Background()
+	env := newOutboxTestEnv(t)
+	env.emitter.FailPushes(1, errors.New("queue unavailable"))
+
+	require.Nil(t, env.service.checkExpiry(ctx))
+	require.Equal(t, types.StateWithdrawable, env.delegation.State)
+	require.Len(t, env.outbox, 1)
+
+	// The failed push keeps the event unsent for the next run
+	result, err := env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), result.failed)
+	require.Len(t, env.unsentEvents(), 1)
+	require.Empty(t, env.withdrawable())
+
+	require.Nil(t, env.service.checkExpiry(ctx))
+	_, err = env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	_, err = env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+
+	require.Empty(t, env.unsentEvents())
+	require.Len(t, env.withdrawable(), 1)
+	event := env.withdrawable()[0]
+	require.Equal(t, consumer.WithdrawableStakingEventType, event.EventType)
+	require.Equal(t, testStakingTxHash, event.StakingTxHashHex)
+	require.Equal(t, types.SubStateTimelock.String(), event.SubState)
+	require.Equal(t, uint32(100), event.BtcHeight)
+}
+
+func TestWithdrawableEventPublishedOnceAfterExpiryRetry(t *testing.T) {
+	ctx := context.Background()
+	env := newOutboxTestEnv(t)
+	env.deleteFailures = 1
+
+	// The transition is applied but the expiry checker fails afterwards and
+	// picks the timelock up again on its next run
+	require.NotNil(t, env.service.checkExpiry(ctx))
+	require.Equal(t, types.StateWithdrawable, env.delegation.State)
+	require.Nil(t, env.service.checkExpiry(ctx))
+
+	_, err := env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Len(t, env.withdrawable(), 1)
+	require.Empty(t, env.unsentEvents())
+
+	// The retry completes the expiry, the transition being recorded once
+	require.Empty(t, env.timeLocks)
+	require.Len(t, env.transitions, 1)
+	require.Equal(t, types.StateUnbonding, env.transitions[0].FromState)
+	require.Equal(t, types.StateWithdrawable, env.transitions[0].ToState)
+	require.Equal(t, model.StateTransitionTriggerExpiry, env.transitions[0].Trigger)
+	require.Equal(t, uint64(110), env.transitions[0].BtcHeight)
+}
+
+func TestCrashAroundPublishKeepsIdempotencyKeyAndSequence(t *testing.T) {
+	ctx := context.Background()
+	env := newOutboxTestEnv(t)
+	env.deleteFailures = 1
+	env.markSentFailures = 1
+
+	// The transition is processed twice, which records a single event
+	require.NotNil(t, env.service.checkExpiry(ctx))
+	require.Nil(t, env.service.checkExpiry(ctx))
+	require.Len(t, env.outbox, 1)
+
+	// The event is published but not marked sent, so it is published again
+	_, err := env.service.relayOutboxEvents(ctx)
+	require.NotNil(t, err)
+	_, err = env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Empty(t, env.unsentEvents())
+
+	expected := schema.Delivery{
+		IdempotencyKey: dedup.IdempotencyKey(testStakingTxHash, model.OutboxEventTypeWithdrawable, 100),
+		Sequence:       1,
+	}
+	deliveries := env.deliveries()
+	require.Equal(t, []schema.Delivery{expected, expected}, deliveries)
+
+	// Consumers tell the second delivery from a new event
+	tracker := dedup.NewTracker(nil)
+	require.Equal(t, dedup.InOrder, tracker.Check(testStakingTxHash, deliveries[0].Sequence))
+	tracker.Applied(testStakingTxHash, deliveries[0].Sequence)
+	require.Equal(t, dedup.Duplicate, tracker.Check(testStakingTxHash, deliveries[1].Sequence))
+}
+
+func TestOutboxRelayKeepsDelegationOrderOnFailure(t *testing.T) {
+	ctx := context.Background()
+	env := newOutboxTestEnv(t)
+	env.outbox = []*model.OutboxEvent{
+		testOutboxEvent(model.OutboxEventTypeWithdrawable, "tx-a", 1),
+		testOutboxEvent(model.OutboxEventTypeWithdrawable, "tx-b", 2),
+		testOutboxEvent(model.OutboxEventTypeWithdrawn, "tx-a", 3),
+	}
+	env.emitter.FailDelegationPushes("tx-a", 1, errors.New("event rejected"))
+
+	// The failing event holds back the later event of its delegation only
+	result, err := env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), result.failed)
+	require.Equal(t, uint64(1), result.published[model.OutboxEventTypeWithdrawable])
+	require.Equal(t, []string{"withdrawable_staking:tx-b"}, env.pushed())
+
+	_, err = env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"withdrawable_staking:tx-b",
+		"withdrawable_staking:tx-a",
+		"withdrawn_staking:tx-a",
+	}, env.pushed())
+	require.Empty(t, env.unsentEvents())
+}
+
+func TestOutboxRelayFlagsPoisonEvent(t *testing.T) {
+	ctx := context.Background()
+	env := newOutboxTestEnv(t)
+	env.outbox = []*model.OutboxEvent{
+		testOutboxEvent(model.OutboxEventTypeWithdrawable, "tx-a", 1),
+		testOutboxEvent(model.OutboxEventTypeWithdrawn, "tx-a", 2),
+	}
+	env.emitter.FailDelegationPushes("tx-a", 3, errors.New("event rejected"))
+
+	for i := 0; i < 2; i++ {
+		result, err := env.service.relayOutboxEvents(ctx)
+		require.Nil(t, err)
+		require.Zero(t, result.poisoned)
+		require.Empty(t, env.pushed())
+	}
+	require.Empty(t, env.alerter.titles)
+
+	// The last allowed attempt flags the event poison and lets the delegation
+	// move on
+	result, err := env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), result.poisoned)
+	require.Equal(t, []string{"withdrawn_staking:tx-a"}, env.pushed())
+
+	poison := env.outboxEvent("withdrawable_staking:tx-a")
+	require.True(t, poison.Poison)
+	require.Equal(t, 3, poison.Attempts)
+	require.Contains(t, poison.LastError, "event rejected")
+	require.Empty(t, env.unsentEvents())
+	require.Equal(t, []string{"Outbox event flagged poison"}, env.alerter.titles)
+}
+
+func TestOutboxRelayFlagsPermanentFailurePoison(t *testing.T) {
+	ctx := context.Background()
+	env := newOutboxTestEnv(t)
+	env.outbox = []*model.OutboxEvent{
+		testOutboxEvent("unknown_staking", "tx-a", 1),
+		testOutboxEvent(model.OutboxEventTypeWithdrawn, "tx-a", 2),
+	}
+
+	// The push of an event of unknown type fails again on a retry, the event
+	// is flagged poison on its first attempt
+	result, err := env.service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), result.poisoned)
+	require.Equal(t, []string{"withdrawn_staking:tx-a"}, env.pushed())
+
+	poison := env.outboxEvent("unknown_staking:tx-a")
+	require.True(t, poison.Poison)
+	require.Equal(t, 1, poison.Attempts)
+	require.Equal(t, []string{"Outbox event flagged poison"}, env.alerter.titles)
+}
+
+func testOutboxEvent(eventType, stakingTxHash string, createdAt int64) *model.OutboxEvent {
+	return &model.OutboxEvent{
+		Id:               eventType + ":" + stakingTxHash,
+		EventType:        eventType,
+		StakingTxHashHex: stakingTxHash,
+		CreatedAt:        createdAt,
+	}
+}
+
+// TestOutboxRelayPagesEventsCreatedAtOnce relays more events created at the
+// same time than fit a page, none of them being skipped
+func TestOutboxRelayPagesEventsCreatedAtOnce(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := inmemory.New()
+	for i := range outboxRelayBatchSize + 1 {
+		stakingTxHash := fmt.Sprintf("%064x", i)
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+			StakingTxHashHex: stakingTxHash,
+			State:            types.StateActive,
+		}))
+		require.NoError(t, database.SaveOutboxEvent(ctx, model.NewActiveStakingOutboxEvent(
+			&model.BTCDelegationDetails{StakingTxHashHex: stakingTxHash}, 100, 1,
+		)))
+	}
+	emitter := consumer.NewMemoryEmitter()
+	service := New(&config.Config{}, Dependencies{Db: database, Emitter: emitter, Alerter: &fakeAlerter{}})
+
+	result, err := service.relayOutboxEvents(ctx)
+	require.Nil(t, err)
+	require.Equal(t, uint64(outboxRelayBatchSize+1), result.published[model.OutboxEventTypeActiveStaking])
+	require.Len(t, emitter.Events(), outboxRelayBatchSize+1)
+	unsent, dbErr := database.GetUnsentOutboxEvents(ctx, 0, "", outboxRelayBatchSize)
+	require.NoError(t, dbErr)
+	require.Empty(t, unsent)
+}
+
+func TestPushOutcome(t *testing.T) {
+	require.Equal(t, metrics.Success, pushOutcome(nil))
+	require.Equal(t, metrics.Nack, pushOutcome(types.NewInternalServiceError(
+		fmt.Errorf("failed to push: %w", consumer.ErrPublishNacked),
+	)))
+	require.Equal(t, metrics.Failure, pushOutcome(types.NewInternalServiceError(errors.New("timeout"))))
+}
+
+func TestPushOutboxEventRoutesEventTypes(t *testing.T) {
+	tests := []struct {
+		eventType string
+		method    string
+	}{
+		{model.OutboxEventTypeActiveStaking, "PushActiveStakingEvent"},
+		{model.OutboxEventTypeUnbondingStaking, "PushUnbondingStakingEvent"},
+		{model.OutboxEventTypeSlashedStaking, "PushUnbondingStakingEvent"},
+		{model.OutboxEventTypeWithdrawable, "PushWithdrawableStakingEvent"},
+		{model.OutboxEventTypeWithdrawn, "PushWithdrawnStakingEvent"},
+		{model.OutboxEventTypeSlashedFunds, "PushSlashedFundsStakingEvent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			event := testOutboxEvent(tt.eventType, testStakingTxHash, 1)
+			event.IdempotencyKey = "idempotency-key"
+			event.Sequence = 2
+			delivery := schema.Delivery{IdempotencyKey: "idempotency-key", Sequence: 2}
+
+			emitter := mocks.NewEventConsumer(t)
+			emitter.On(tt.method, mock.MatchedBy(func(ev client.EventMessage) bool {
+				switch ev := ev.(type) {
+				case *consumer.StakingEvent:
+					return ev.StakingTxHashHex == testStakingTxHash && ev.Delivery == delivery
+				case *consumer.WithdrawalStakingEvent:
+					return ev.StakingTxHashHex == testStakingTxHash && ev.Delivery == delivery
+				default:
+					return false
+				}
+			})).Return(errors.New("queue unavailable")).Once()
+
+			err := pushOutboxEvent(emitter, event)
+			require.NotNil(t, err)
+			require.ErrorContains(t, err, "queue unavailable")
+		})
+	}
+}
+
+func TestPushOutboxEventStakingPayloads(t *testing.T) {
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex:          testStakingTxHash,
+		StakerBtcPkHex:            "staker-pk",
+		FinalityProviderBtcPksHex: []string{"fp-pk"},
+		StakingAmount:             1000,
+		StartHeight:               120,
+		ParamsVersion:             3,
+	}
+	tests := []struct {
+		name   string
+		event  *model.OutboxEvent
+		fields map[string]any
+	}{
+		{
+			name:  "active staking",
+			event: model.NewActiveStakingOutboxEvent(delegation, 120, 1),
+			fields: map[string]any{
+				"staking_amount":       float64(1000),
+				"staking_start_height": float64(120),
+				"params_version":       float64(3),
+			},
+		},
+		{
+			name: "unbonding staking",
+			event: model.NewUnbondingStakingOutboxEvent(
+				delegation, types.SubStateEarlyUnbonding, "unbonding-tx", 150, 1,
+			),
+			fields: map[string]any{
+				"staking_start_height":  float64(120),
+				"params_version":        float64(3),
+				"sub_state":             types.SubStateEarlyUnbonding.String(),
+				"unbonding_tx_hash_hex": "unbonding-tx",
+				"withdrawable_height":   float64(150),
+			},
+		},
+		{
+			name: "expired staking",
+			event: model.NewUnbondingStakingOutboxEvent(
+				delegation, types.SubStateTimelock, "", 220, 1,
+			),
+			fields: map[string]any{
+				"sub_state": types.SubStateTimelock.String(),
+				// the natural expiry has no unbonding tx
+				"unbonding_tx_hash_hex": nil,
+				"withdrawable_height":   float64(220),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The published payload carries the fields of the outbox event
+			fields := publishedPayload(t, tt.event)
+			for name, value := range tt.fields {
+				require.Equal(t, value, fields[name], name)
+			}
+		})
+	}
+}
+
+// publishedPayload returns the fields of the payload the outbox event is
+// published with
+func publishedPayload(t *testing.T, event *model.OutboxEvent) map[string]any {
+	emitter := consumer.NewMemoryEmitter()
+	require.Nil(t, pushOutboxEvent(emitter, event))
+
+	events := emitter.Events()
+	require.Len(t, events, 1)
+	payload, err := schema.Encode(events[0].Event, schema.DefaultVersion, false)
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(payload, &fields))
+	return fields
+}
+
+// TestUnbondingEventPayloadsFromBbnEvents checks the unbonding events the BBN
+// unbonding events lead to, their withdrawable height being the expire height
+// of the timelock the indexer saved
+func TestUnbondingEventPayloadsFromBbnEvents(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	d := fixtures.NewDelegation(1, 1)
+	unbondingExpireHeight := uint32(200 + fixtures.UnbondingTime)
+	stakingExpireHeight := uint32(100 + fixtures.StakingTime)
+
+	tests := []struct {
+		name   string
+		event  sdk.Msg
+		fields map[string]any
+		// expireHeight is the expire height of the saved timelock
+		expireHeight uint32
+	}{
+		{
+			name:  "unbonded early",
+			event: d.UnbondedEarlyEvent(200),
+			fields: map[string]any{
+				"sub_state":             types.SubStateEarlyUnbonding.String(),
+				"unbonding_tx_hash_hex": d.UnbondingTx.TxHash().String(),
+				"withdrawable_height":   float64(unbondingExpireHeight),
+			},
+			expireHeight: unbondingExpireHeight,
+		},
+		{
+			name: "expired",
+			event: &bbntypes.EventBTCDelegationExpired{
+				StakingTxHash: d.StakingTxHashHex(),
+				NewState:      types.SubStateTimelock.String(),
+			},
+			fields: map[string]any{
+				"sub_state":             types.SubStateTimelock.String(),
+				"unbonding_tx_hash_hex": nil,
+				"withdrawable_height":   float64(stakingExpireHeight),
+			},
+			expireHeight: stakingExpireHeight,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := inmemory.New()
+			details := fixtureDelegationDetails(t, d)
+			details.State = types.StateActive
+			details.StartHeight = 100
+			details.EndHeight = stakingExpireHeight
+			require.NoError(t, database.SaveNewBTCDelegation(ctx, details))
+			service := NewService(&config.Config{}, database, nil, nil, nil, nil)
+
+			event, err := sdk.TypedEventToEvent(tt.event)
+			require.NoError(t, err)
+			// A replayed event emits nothing more
+			for range 2 {
+				require.Nil(t, service.processEvent(ctx, NewBbnEvent(BlockCategory, abcitypes.Event(event)), 10))
+			}
+
+			outboxEvents, err := database.GetUnsentOutboxEvents(ctx, 0, "", 10)
+			require.NoError(t, err)
+			require.Len(t, outboxEvents, 1)
+			require.Equal(t, model.OutboxEventTypeUnbondingStaking, outboxEvents[0].EventType)
+			fields := publishedPayload(t, outboxEvents[0])
+			for name, value := range tt.fields {
+				require.Equal(t, value, fields[name], name)
+			}
+
+			timeLocks, err := database.GetTimeLocks(ctx, d.StakingTxHashHex())
+			require.NoError(t, err)
+			require.Len(t, timeLocks, 1)
+			require.Equal(t, tt.expireHeight, timeLocks[0].ExpireHeight)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// Statuses of a params version compared with the BBN chain
+const (
+	// ParamsStatusMatch is a stored version equal to the chain one
+	ParamsStatusMatch = "match"
+	// ParamsStatusMismatch is a stored version differing from the chain one
+	ParamsStatusMismatch = "mismatch"
+	// ParamsStatusMissing is a chain version not stored
+	ParamsStatusMissing = "missing"
+	// ParamsStatusSaved is a chain version stored by the sync
+	ParamsStatusSaved = "saved"
+	// ParamsStatusNotOnChain is a stored version unknown to the chain
+	ParamsStatusNotOnChain = "not_on_chain"
+)
+
+// ParamsVersionStatus is the state of a params version compared with the BBN
+// chain
+type ParamsVersionStatus struct {
+	// Type is the type of the params, STAKING or CHECKPOINT
+	Type    string
+	Version uint32
+	// BtcActivationHeight is the BTC height the staking params apply from,
+	// zero for the checkpoint params
+	BtcActivationHeight uint32
+	Status              string
+	// MismatchedFields are the fields of a mismatching version
+	MismatchedFields []string
+}
+
+// IsMismatch returns whether the stored version differs from the chain
+func (p ParamsVersionStatus) IsMismatch() bool {
+	return p.Status == ParamsStatusMismatch || p.Status == ParamsStatusNotOnChain
+}
+
+// SyncParams compares the stored staking and checkpoint params versions with
+// the BBN chain ones and saves the missing versions unless verifyOnly is set.
+// The mismatching versions are reported but never replaced, the stored params
+// having been used to index the delegations. The statuses are sorted by type
+// and version.
+func (s *Service) SyncParams(ctx context.Context, verifyOnly bool) ([]ParamsVersionStatus, *types.Error) {
+	statuses, err := s.compareStoredParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	missing := false
+	for _, status := range statuses {
+		missing = missing || status.Status == ParamsStatusMissing
+	}
+	if verifyOnly || !missing {
+		return statuses, nil
+	}
+
+	// Save the missing versions the way the params poller does, the stored
+	// versions being left untouched
+	if err := s.fetchAndSaveParams(ctx); err != nil {
+		return nil, err
+	}
+	for i := range statuses {
+		if statuses[i].Status == ParamsStatusMissing {
+			statuses[i].Status = ParamsStatusSaved
+		}
+	}
+	return statuses, nil
+}
+
+// compareStoredParams returns the status of every params version, stored or
+// on the BBN chain
+func (s *Service) compareStoredParams(ctx context.Context) ([]ParamsVersionStatus, *types.Error) {
+	checkpointParams, err := s.bbn.GetCheckpointParams(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get checkpoint params: %w", err),
+		)
+	}
+	storedCheckpointParams, err := s.db.GetCheckpointParams(ctx)
+	if err != nil && !db.IsNotFoundError(err) {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get stored checkpoint params: %w", err),
+		)
+	}
+
+	checkpointStatus := ParamsVersionStatus{
+		Type:    db.CHECKPOINT_PARAMS_TYPE,
+		Version: db.CHECKPOINT_PARAMS_VERSION,
+		Status:  ParamsStatusMissing,
+	}
+	if storedCheckpointParams != nil {
+		checkpointStatus.Status, checkpointStatus.MismatchedFields = diffStatus(
+			storedCheckpointParams.Diff(checkpointParams),
+		)
+	}
+	statuses := []ParamsVersionStatus{checkpointStatus}
+
+	stakingParams, err := s.bbn.GetAllStakingParams(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get staking params: %w", err),
+		)
+	}
+	storedStakingParams, err := s.db.GetAllStakingParams(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get stored staking params: %w", err),
+		)
+	}
+
+	versions := make(map[uint32]struct{}, len(stakingParams))
+	for version := range stakingParams {
+		versions[version] = struct{}{}
+	}
+	for version := range storedStakingParams {
+		versions[version] = struct{}{}
+	}
+	sortedVersions := make([]uint32, 0, len(versions))
+	for version := range versions {
+		sortedVersions = append(sortedVersions, version)
+	}
+	sort.Slice(sortedVersions, func(i, j int) bool { return sortedVersions[i] < sortedVersions[j] })
+
+	for _, version := range sortedVersions {
+		params, onChain := stakingParams[version]
+		stored, isStored := storedStakingParams[version]
+		status := ParamsVersionStatus{Type: db.STAKING_PARAMS_TYPE, Version: version}
+		switch {
+		case !onChain:
+			status.Status = ParamsStatusNotOnChain
+			status.BtcActivationHeight = stakingActivationHeight(stored)
+		case !isStored:
+			status.Status = ParamsStatusMissing
+			status.BtcActivationHeight = stakingActivationHeight(params)
+		default:
+			status.Status, status.MismatchedFields = diffStatus(stored.Diff(params))
+			status.BtcActivationHeight = stakingActivationHeight(stored)
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func diffStatus(fields []string) (string, []string) {
+	if len(fields) > 0 {
+		return ParamsStatusMismatch, fields
+	}
+	return ParamsStatusMatch, nil
+}
+
+func stakingActivationHeight(params *bbnclient.StakingParams) uint32 {
+	if params == nil {
+		return 0
+	}
+	return params.BtcActivationHeight
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncParams(t *testing.T) {
+	ctx := context.Background()
+	checkpointParams := &bbnclient.CheckpointParams{BtcConfirmationDepth: 10, CheckpointTag: "01020304"}
+	chainStakingParams := map[uint32]*bbnclient.StakingParams{
+		0: {CovenantQuorum: 3, BtcActivationHeight: 100},
+		1: {CovenantQuorum: 4, BtcActivationHeight: 200},
+		2: {CovenantQuorum: 4, BtcActivationHeight: 300},
+	}
+	storedStakingParams := map[uint32]*bbnclient.StakingParams{
+		0: {CovenantQuorum: 3, BtcActivationHeight: 100},
+		1: {CovenantQuorum: 5, BtcActivationHeight: 200},
+		3: {CovenantQuorum: 4, BtcActivationHeight: 400},
+	}
+
+	setupMocks := func(t *testing.T) (*mocks.DbInterface, *mocks.BbnInterface) {
+		dbMock := mocks.NewDbInterface(t)
+		bbnMock := mocks.NewBbnInterface(t)
+		bbnMock.On("GetCheckpointParams", ctx).Return(checkpointParams, nil)
+		bbnMock.On("GetAllStakingParams", ctx).Return(chainStakingParams, nil)
+		dbMock.On("GetCheckpointParams", ctx).Return(checkpointParams, nil).Once()
+		dbMock.On("GetAllStakingParams", ctx).Return(storedStakingParams, nil).Once()
+		return dbMock, bbnMock
+	}
+
+	expectedStatuses := func(missingStatus string) []ParamsVersionStatus {
+		return []ParamsVersionStatus{
+			{Type: db.CHECKPOINT_PARAMS_TYPE, Version: 0, Status: ParamsStatusMatch},
+			{Type: db.STAKING_PARAMS_TYPE, Version: 0, BtcActivationHeight: 100, Status: ParamsStatusMatch},
+			{
+				Type: db.STAKING_PARAMS_TYPE, Version: 1, BtcActivationHeight: 200,
+				Status: ParamsStatusMismatch, MismatchedFields: []string{"CovenantQuorum"},
+			},
+			{Type: db.STAKING_PARAMS_TYPE, Version: 2, BtcActivationHeight: 300, Status: missingStatus},
+			{Type: db.STAKING_PARAMS_TYPE, Version: 3, BtcActivationHeight: 400, Status: ParamsStatusNotOnChain},
+		}
+	}
+
+	t.Run("verify only", func(t *testing.T) {
+		dbMock, bbnMock := setupMocks(t)
+
+		service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+		statuses, err := service.SyncParams(ctx, true)
+		require.Nil(t, err)
+		require.Equal(t, expectedStatuses(ParamsStatusMissing), statuses)
+	})
+
+	t.Run("save missing", func(t *testing.T) {
+		dbMock, bbnMock := setupMocks(t)
+		dbMock.On("SaveCheckpointParams", ctx, checkpointParams).Return(nil).Once()
+		for version, params := range chainStakingParams {
+			dbMock.On("SaveStakingParams", ctx, version, params).Return(nil).Once()
+		}
+
+		service := NewService(&config.Config{}, dbMock, nil, nil, bbnMock, nil)
+		statuses, err := service.SyncParams(ctx, false)
+		require.Nil(t, err)
+		require.Equal(t, expectedStatuses(ParamsStatusSaved), statuses)
+	})
+}
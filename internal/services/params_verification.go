@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// VerifyStoredParams compares the stored staking and checkpoint params with
+// the ones of the connected BBN node, and the checkpoint tag with the one
+// expected for the configured BTC network. Any mismatch is returned as an
+// error identifying the params versions and fields, as indexing on top of
+// data from a different chain would silently corrupt it.
+func (s *Service) VerifyStoredParams(ctx context.Context) *types.Error {
+	var mismatches []string
+
+	checkpointParams, err := s.bbn.GetCheckpointParams(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get checkpoint params: %w", err),
+		)
+	}
+
+	expectedTag := s.cfg.BBN.CheckpointTag
+	if expectedTag != "" && !strings.EqualFold(expectedTag, checkpointParams.CheckpointTag) {
+		mismatches = append(mismatches, fmt.Sprintf(
+			"checkpoint tag %s differs from the expected %s for BTC network %s",
+			checkpointParams.CheckpointTag, expectedTag, s.cfg.BTC.NetParams,
+		))
+	}
+
+	storedCheckpointParams, err := s.db.GetCheckpointParams(ctx)
+	if err != nil && !db.IsNotFoundError(err) {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get stored checkpoint params: %w", err),
+		)
+	}
+	if storedCheckpointParams != nil {
+		if fields := storedCheckpointParams.Diff(checkpointParams); len(fields) > 0 {
+			mismatches = append(mismatches, fmt.Sprintf(
+				"checkpoint params differ in fields %s", strings.Join(fields, ", "),
+			))
+		}
+	}
+
+	storedStakingParams, err := s.db.GetAllStakingParams(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get stored staking params: %w", err),
+		)
+	}
+	if len(storedStakingParams) > 0 {
+		stakingParams, err := s.bbn.GetAllStakingParams(ctx)
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get staking params: %w", err),
+			)
+		}
+
+		versions := make([]uint32, 0, len(storedStakingParams))
+		for version := range storedStakingParams {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+		for _, version := range versions {
+			params, ok := stakingParams[version]
+			if !ok {
+				mismatches = append(mismatches, fmt.Sprintf(
+					"staking params version %d does not exist on the BBN chain", version,
+				))
+				continue
+			}
+			if fields := storedStakingParams[version].Diff(params); len(fields) > 0 {
+				mismatches = append(mismatches, fmt.Sprintf(
+					"staking params version %d differ in fields %s", version, strings.Join(fields, ", "),
+				))
+			}
+		}
+	}
+
+	if len(mismatches) > 0 {
+		s.alerter.Alert(ctx, alerting.SeverityCritical, "Stored params differ from the BBN chain", map[string]string{
+			"chain_id":   s.cfg.BBN.ChainId,
+			"mismatches": strings.Join(mismatches, "; "),
+		})
+		return types.NewInternalServiceError(
+			fmt.Errorf("%w with the BBN chain: %s", types.ErrParamsMismatch, strings.Join(mismatches, "; ")),
+		)
+	}
+
+	log.Info().
+		Int("stakingParamsVersions", len(storedStakingParams)).
+		Msg("stored params match the BBN chain")
+	return nil
+}
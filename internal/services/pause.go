@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// pauseGate pauses the indexing processes at the boundaries of their units of
+// work, i.e. between two BBN blocks or two poller runs, so that a pause never
+// falls between a database write and the outbox event recorded along with it
+type pauseGate struct {
+	mu sync.Mutex
+	// resumed is closed on resume, and nil while the indexing is not paused
+	resumed  chan struct{}
+	inFlight int
+	// drained is closed once the work in flight when paused completed
+	drained chan struct{}
+}
+
+func newPauseGate() *pauseGate {
+	return &pauseGate{}
+}
+
+// errPauseWaitStopped is returned by enter when stopped while the indexing is
+// paused
+var errPauseWaitStopped = errors.New("stopped while the indexing is paused")
+
+// enter waits for the indexing to be resumed if paused, then registers a unit
+// of work, which must be completed with exit. The wait ends with
+// errPauseWaitStopped once the stop channel is closed.
+func (g *pauseGate) enter(ctx context.Context, stop <-chan struct{}) error {
+	for {
+		g.mu.Lock()
+		resumed := g.resumed
+		if resumed == nil {
+			g.inFlight++
+			g.mu.Unlock()
+			return nil
+		}
+		g.mu.Unlock()
+
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stop:
+			return errPauseWaitStopped
+		}
+	}
+}
+
+// tryEnter registers a unit of work unless the indexing is paused
+func (g *pauseGate) tryEnter() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		return false
+	}
+	g.inFlight++
+	return true
+}
+
+func (g *pauseGate) exit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inFlight--
+	if g.inFlight == 0 && g.drained != nil {
+		close(g.drained)
+		g.drained = nil
+	}
+}
+
+// pause stops any new unit of work from starting and returns a channel closed
+// once the ones in flight completed
+func (g *pauseGate) pause() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		g.resumed = make(chan struct{})
+	}
+	if g.inFlight == 0 {
+		drained := make(chan struct{})
+		close(drained)
+		return drained
+	}
+	if g.drained == nil {
+		g.drained = make(chan struct{})
+	}
+	return g.drained
+}
+
+func (g *pauseGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		close(g.resumed)
+		g.resumed = nil
+	}
+}
+
+func (g *pauseGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed != nil
+}
+
+// pausable skips the runs of the poll method while the indexing is paused
+func (s *Service) pausable(
+	pollMethod func(ctx context.Context) *types.Error,
+) func(ctx context.Context) *types.Error {
+	return func(ctx context.Context) *types.Error {
+		if !s.pause.tryEnter() {
+			return nil
+		}
+		defer s.pause.exit()
+		return pollMethod(ctx)
+	}
+}
+
+// PauseIndexing pauses the BBN block processor, the expiry checker and the
+// outbox relay, and waits for the block or the runs they are processing to
+// complete. The indexing stays paused, reported not ready, until resumed. If
+// the context is done first, the pause still applies once the work in flight
+// completes.
+func (s *Service) PauseIndexing(ctx context.Context) *types.Error {
+	drained := s.pause.pause()
+	metrics.RecordIndexingPaused(true)
+	log.Info().Msg("indexing pause requested")
+
+	select {
+	case <-drained:
+		log.Info().Msg("indexing paused")
+		return nil
+	case <-ctx.Done():
+		return types.NewErrorWithMsg(
+			http.StatusRequestTimeout, types.RequestTimeout,
+			"indexing pause requested, the work in flight has not completed yet",
+		)
+	}
+}
+
+// ResumeIndexing resumes the indexing paused by PauseIndexing
+func (s *Service) ResumeIndexing(_ context.Context) *types.Error {
+	s.pause.resume()
+	metrics.RecordIndexingPaused(false)
+	log.Info().Msg("indexing resumed")
+	return nil
+}
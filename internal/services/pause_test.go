@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseGate(t *testing.T) {
+	gate := newPauseGate()
+	require.NoError(t, gate.enter(context.Background(), nil))
+
+	// the pause waits for the unit of work in flight
+	drained := gate.pause()
+	require.True(t, gate.isPaused())
+	require.False(t, gate.tryEnter())
+	select {
+	case <-drained:
+		t.Fatal("drained while a unit of work is in flight")
+	default:
+	}
+	gate.exit()
+	<-drained
+
+	// new units of work wait for the resume
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, gate.enter(ctx, nil), context.DeadlineExceeded)
+	stop := make(chan struct{})
+	close(stop)
+	require.ErrorIs(t, gate.enter(context.Background(), stop), errPauseWaitStopped)
+
+	entered := make(chan error)
+	go func() { entered <- gate.enter(context.Background(), nil) }()
+	gate.resume()
+	require.NoError(t, <-entered)
+	require.False(t, gate.isPaused())
+	gate.exit()
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *Service) StartProcessedHeightAudit(ctx context.Context) {
+	processedHeightAuditPoller := s.newPoller(
+		"processed_height_audit",
+		s.cfg.Poller.ProcessedHeightAuditInterval,
+		s.auditProcessedHeights,
+	)
+	s.startPoller(ctx, processedHeightAuditPoller)
+}
+
+// auditProcessedHeights looks for BBN heights below the last processed height
+// that were never processed and backfills them through the normal pipeline.
+// The event handlers ignore events already applied, so processing a height
+// twice is safe. Heights below the first recorded one predate the record and
+// are not audited.
+func (s *Service) auditProcessedHeights(ctx context.Context) *types.Error {
+	lastProcessedHeight, err := s.db.GetLastProcessedBbnHeight(ctx)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get last processed height: %w", err),
+		)
+	}
+
+	lowestHeight, err := s.db.GetLowestProcessedBbnHeight(ctx)
+	if err != nil {
+		if db.IsNotFoundError(err) {
+			return nil
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get lowest processed height: %w", err),
+		)
+	}
+
+	gaps, err := s.db.DetectProcessedHeightGaps(ctx, lowestHeight, lastProcessedHeight)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to detect processed height gaps: %w", err),
+		)
+	}
+
+	var missing uint64
+	for _, gap := range gaps {
+		missing += gap.Len()
+	}
+	metrics.RecordBbnProcessedHeightGaps(missing)
+
+	if missing == 0 {
+		return nil
+	}
+	log.Warn().
+		Uint64("from", lowestHeight).
+		Uint64("to", lastProcessedHeight).
+		Uint64("missing_heights", missing).
+		Msg("found unprocessed BBN heights, backfilling")
+
+	for _, gap := range gaps {
+		for height := gap.Start; height <= gap.End; height++ {
+			if err := s.processBbnBlock(withBbnBlockMode(ctx, bbnBlockBackfill), height, nil); err != nil {
+				return err
+			}
+			log.Info().Uint64("height", height).Msg("backfilled BBN height")
+		}
+	}
+
+	return nil
+}
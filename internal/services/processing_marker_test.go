@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testMarkerHeight    = 100
+	testMarkerEventsLen = 5
+)
+
+// markerTestEnv wires a service to a BBN block of finality provider edits and
+// an in-memory last processed height document, recording the applied edits.
+// The edits and markers written in a transaction are recorded once it
+// commits.
+type markerTestEnv struct {
+	service       *Service
+	blockHash     cmtbytes.HexBytes
+	lastProcessed *model.LastProcessedHeight
+	applied       map[string]int
+	// pending holds the edits and markers of the ongoing transaction
+	pendingApplied map[string]int
+	pendingEvents  []int
+	// killAt fails the edit of the event at the index to simulate a crash
+	killAt int
+	// killMarkAt fails marking the event at the index as processed, once its
+	// edit is applied, to simulate a crash in between
+	killMarkAt int
+	// failPermanentlyAt fails the edit of the event at the index with a
+	// permanent error
+	failPermanentlyAt int
+	deadLetters       []*model.BbnEventDeadLetter
+	alerter           *fakeAlerter
+}
+
+func newMarkerTestEnv(t *testing.T) *markerTestEnv {
+	env := &markerTestEnv{
+		blockHash:         cmtbytes.HexBytes{0xaa},
+		lastProcessed:     &model.LastProcessedHeight{Height: testMarkerHeight - 1},
+		applied:           make(map[string]int),
+		killAt:            -1,
+		killMarkAt:        -1,
+		failPermanentlyAt: -1,
+		alerter:           &fakeAlerter{},
+	}
+
+	var txResults []*abcitypes.ExecTxResult
+	for i := 0; i < testMarkerEventsLen; i++ {
+		event, err := sdk.TypedEventToEvent(&bbntypes.EventFinalityProviderEdited{
+			BtcPkHex: fpBtcPkForEvent(i),
+			Moniker:  "moniker",
+		})
+		require.NoError(t, err)
+		txResults = append(txResults, &abcitypes.ExecTxResult{
+			Events: []abcitypes.Event{abcitypes.Event(event)},
+		})
+	}
+
+	bbnMock := mocks.NewBbnInterface(t)
+	bbnMock.On("GetBlockResults", mock.Anything, mock.Anything).Return(
+		&ctypes.ResultBlockResults{Height: testMarkerHeight, TxsResults: txResults}, nil,
+	).Maybe()
+	bbnMock.On("GetBlock", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height *int64) (*ctypes.ResultBlock, error) {
+			return &ctypes.ResultBlock{BlockID: cmttypes.BlockID{Hash: env.blockHash}}, nil
+		},
+	).Maybe()
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("RunInTransaction", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, fn func(context.Context) error) error {
+			env.pendingApplied = make(map[string]int)
+			env.pendingEvents = nil
+			if err := fn(ctx); err != nil {
+				return err
+			}
+			for pk, count := range env.pendingApplied {
+				env.applied[pk] += count
+			}
+			marker := env.lastProcessed.ProcessingMarker
+			marker.ProcessedEvents = append(marker.ProcessedEvents, env.pendingEvents...)
+			return nil
+		},
+	).Maybe()
+	dbMock.On("UpdateFinalityProviderDetailsFromEvent", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, details *model.FinalityProviderDetails) error {
+			if details.BtcPk == fpBtcPkForEvent(env.killAt) {
+				return errors.New("killed")
+			}
+			if details.BtcPk == fpBtcPkForEvent(env.failPermanentlyAt) {
+				return types.NewPermanentError(errors.New("corrupt edit"))
+			}
+			env.pendingApplied[details.BtcPk]++
+			return nil
+		},
+	).Maybe()
+	dbMock.On("StartBbnBlockProcessing", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, marker *model.BbnProcessingMarker) error {
+			stored := *marker
+			env.lastProcessed.ProcessingMarker = &stored
+			return nil
+		},
+	).Maybe()
+	dbMock.On("MarkBbnEventProcessed", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height uint64, eventIndex int) error {
+			marker := env.lastProcessed.ProcessingMarker
+			require.NotNil(t, marker)
+			require.Equal(t, height, marker.Height)
+			if eventIndex == env.killMarkAt {
+				return errors.New("killed")
+			}
+			env.pendingEvents = append(env.pendingEvents, eventIndex)
+			return nil
+		},
+	).Maybe()
+	dbMock.On("UpdateLastProcessedBbnHeight", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, height uint64, blockHash string) error {
+			env.lastProcessed = &model.LastProcessedHeight{Height: height, BlockHash: blockHash}
+			return nil
+		},
+	).Maybe()
+	dbMock.On("MarkBbnHeightProcessed", mock.Anything, mock.Anything).Return(nil).Maybe()
+	dbMock.On("SaveBbnEventDeadLetter", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, deadLetter *model.BbnEventDeadLetter) error {
+			env.deadLetters = append(env.deadLetters, deadLetter)
+			return nil
+		},
+	).Maybe()
+
+	env.service = &Service{
+		db:      dbMock,
+		bbn:     bbnMock,
+		alerter: env.alerter,
+	}
+
+	return env
+}
+
+// expectTransactions runs the functions run in a transaction of the mocked
+// database right away, none of their writes being rolled back
+func expectTransactions(dbMock *mocks.DbInterface) {
+	dbMock.On("RunInTransaction", mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		},
+	).Maybe()
+}
+
+func fpBtcPkForEvent(index int) string {
+	return fmt.Sprintf("fp-%d", index)
+}
+
+// processBlock processes the test block the way the block processor does
+func (env *markerTestEnv) processBlock(ctx context.Context) error {
+	marker := model.NewBbnProcessingMarker(testMarkerHeight, env.blockHash.String(), 0)
+	if err := env.service.db.StartBbnBlockProcessing(ctx, marker); err != nil {
+		return err
+	}
+	if err := env.service.processMarkedBbnBlock(ctx, marker); err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestProcessingMarkerResumesAfterKill(t *testing.T) {
+	metrics.Init()
+	for killAt := 0; killAt < testMarkerEventsLen; killAt++ {
+		t.Run(fmt.Sprintf("kill before event %d", killAt), func(t *testing.T) {
+			ctx := context.Background()
+			env := newMarkerTestEnv(t)
+
+			env.killAt = killAt
+			require.Error(t, env.processBlock(ctx))
+			require.Equal(t, uint64(testMarkerHeight-1), env.lastProcessed.Height)
+			require.NotNil(t, env.lastProcessed.ProcessingMarker)
+			require.Len(t, env.lastProcessed.ProcessingMarker.ProcessedEvents, killAt)
+
+			env.killAt = -1
+			recovered, err := env.service.recoverBbnBlockProcessing(ctx, env.lastProcessed)
+			require.Nil(t, err)
+			require.Equal(t, uint64(testMarkerHeight), recovered.Height)
+			require.Equal(t, uint64(testMarkerHeight), env.lastProcessed.Height)
+			require.Nil(t, env.lastProcessed.ProcessingMarker)
+
+			// Every event is applied exactly once across the crash
+			require.Len(t, env.applied, testMarkerEventsLen)
+			for pk, count := range env.applied {
+				require.Equal(t, 1, count, pk)
+			}
+		})
+	}
+}
+
+func TestProcessingMarkerResumesAfterKillBeforeMarker(t *testing.T) {
+	metrics.Init()
+	for killAt := 0; killAt < testMarkerEventsLen; killAt++ {
+		t.Run(fmt.Sprintf("kill between event %d and its marker", killAt), func(t *testing.T) {
+			ctx := context.Background()
+			env := newMarkerTestEnv(t)
+
+			// The edit of the event is rolled back along with its marker
+			env.killMarkAt = killAt
+			require.Error(t, env.processBlock(ctx))
+			require.Len(t, env.lastProcessed.ProcessingMarker.ProcessedEvents, killAt)
+			require.Len(t, env.applied, killAt)
+			require.NotContains(t, env.applied, fpBtcPkForEvent(killAt))
+
+			env.killMarkAt = -1
+			_, err := env.service.recoverBbnBlockProcessing(ctx, env.lastProcessed)
+			require.Nil(t, err)
+			require.Nil(t, env.lastProcessed.ProcessingMarker)
+
+			require.Len(t, env.applied, testMarkerEventsLen)
+			for pk, count := range env.applied {
+				require.Equal(t, 1, count, pk)
+			}
+		})
+	}
+}
+
+func TestProcessingMarkerReprocessesChangedBlock(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	env := newMarkerTestEnv(t)
+
+	env.killAt = 2
+	require.Error(t, env.processBlock(ctx))
+
+	// The block under processing got replaced, none of its events can be skipped
+	env.killAt = -1
+	env.blockHash = cmtbytes.HexBytes{0xbb}
+	recovered, err := env.service.recoverBbnBlockProcessing(ctx, env.lastProcessed)
+	require.Nil(t, err)
+	require.Equal(t, uint64(testMarkerHeight), recovered.Height)
+	require.Equal(t, "BB", recovered.BlockHash)
+
+	require.Len(t, env.applied, testMarkerEventsLen)
+	require.Equal(t, 2, env.applied[fpBtcPkForEvent(0)])
+	require.Equal(t, 1, env.applied[fpBtcPkForEvent(2)])
+}
+
+func TestProcessingMarkerNothingToRecover(t *testing.T) {
+	env := newMarkerTestEnv(t)
+
+	lastProcessed := &model.LastProcessedHeight{Height: testMarkerHeight, BlockHash: env.blockHash.String()}
+	recovered, err := env.service.recoverBbnBlockProcessing(context.Background(), lastProcessed)
+	require.Nil(t, err)
+	require.Same(t, lastProcessed, recovered)
+	require.Empty(t, env.applied)
+}
+
+func TestPermanentlyFailedEventDeadLettered(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	env := newMarkerTestEnv(t)
+
+	// The event failing permanently is set aside, the block processing goes on
+	env.failPermanentlyAt = 2
+	require.NoError(t, env.processBlock(ctx))
+	require.Equal(t, uint64(testMarkerHeight), env.lastProcessed.Height)
+	require.Len(t, env.applied, testMarkerEventsLen-1)
+	require.NotContains(t, env.applied, fpBtcPkForEvent(2))
+
+	require.Len(t, env.deadLetters, 1)
+	deadLetter := env.deadLetters[0]
+	require.Equal(t, fmt.Sprintf("%d:2", testMarkerHeight), deadLetter.Id)
+	require.Equal(t, EventFinalityProviderEditedType.String(), deadLetter.EventType)
+	require.Contains(t, deadLetter.Error, "corrupt edit")
+	require.NotEmpty(t, deadLetter.Attributes)
+
+	require.Equal(t, []string{"BBN event dead-lettered"}, env.alerter.titles)
+	require.Equal(t, []alerting.Severity{alerting.SeverityCritical}, env.alerter.severities)
+	require.Equal(t, strconv.Itoa(testMarkerHeight), env.alerter.fields[0]["height"])
+	require.Equal(t, "2", env.alerter.fields[0]["event_index"])
+	require.Equal(t, EventFinalityProviderEditedType.String(), env.alerter.fields[0]["event_type"])
+	require.Contains(t, env.alerter.fields[0]["error"], "corrupt edit")
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Collections a prune applies to
+const (
+	// PruneCollectionDelegations moves the delegations to their archive
+	PruneCollectionDelegations = "delegations"
+	// PruneCollectionTimeLockArchive deletes the archived timelocks
+	PruneCollectionTimeLockArchive = "timelock_archive"
+)
+
+// pruneCollections lists the collections in the order they are pruned, the
+// archived timelocks of the delegations pruned first becoming prunable
+var pruneCollections = []string{PruneCollectionDelegations, PruneCollectionTimeLockArchive}
+
+// pruneLockTTL is how long the migration lock is held without renewal, the
+// prune renewing it on each batch
+const pruneLockTTL = 10 * time.Minute
+
+// PruneRequest selects the documents pruned
+type PruneRequest struct {
+	// OlderThan is how long the documents must have been in a terminal state,
+	// or archived
+	OlderThan   time.Duration
+	Collections []string
+	// DryRun counts the documents the prune would move or delete without
+	// touching them
+	DryRun    bool
+	BatchSize int64
+	// ProgressEvery is the number of documents between progress logs
+	ProgressEvery uint64
+}
+
+// PruneCounts counts the documents of a collection pruned
+type PruneCounts struct {
+	Moved   uint64 `json:"moved"`
+	Deleted uint64 `json:"deleted"`
+}
+
+// PruneSummary reports the documents pruned, by collection
+type PruneSummary struct {
+	DryRun      bool
+	Collections map[string]*PruneCounts
+}
+
+// Prune moves the delegations in a terminal state for long enough to their
+// archive, and deletes the timelocks archived long enough ago whose delegation
+// is terminal or pruned. The delegations in any other state are never pruned.
+// The migration lock is held throughout, so that a prune never runs alongside
+// a schema migration.
+func (s *Service) Prune(ctx context.Context, req PruneRequest) (*PruneSummary, *types.Error) {
+	if req.OlderThan <= 0 {
+		return nil, types.NewValidationFailedError(errors.New("the retention must be positive"))
+	}
+	if req.BatchSize <= 0 {
+		return nil, types.NewValidationFailedError(errors.New("the batch size must be positive"))
+	}
+	selected := make(map[string]bool, len(req.Collections))
+	for _, collection := range req.Collections {
+		if collection != PruneCollectionDelegations && collection != PruneCollectionTimeLockArchive {
+			return nil, types.NewValidationFailedError(fmt.Errorf("%s cannot be pruned", collection))
+		}
+		selected[collection] = true
+	}
+
+	owner := lockOwner("prune")
+	if err := s.acquireMigrationLock(ctx, owner); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := s.db.ReleaseLock(context.WithoutCancel(ctx), db.MigrationLockName, owner); err != nil {
+			log.Error().Err(err).Msg("failed to release the migration lock")
+		}
+	}()
+
+	before := time.Now().Add(-req.OlderThan).Unix()
+	summary := &PruneSummary{DryRun: req.DryRun, Collections: make(map[string]*PruneCounts)}
+	for _, collection := range pruneCollections {
+		if !selected[collection] {
+			continue
+		}
+		counts := &PruneCounts{}
+		summary.Collections[collection] = counts
+		if err := s.pruneCollection(ctx, req, owner, collection, before, counts); err != nil {
+			return nil, err
+		}
+	}
+
+	return summary, nil
+}
+
+func (s *Service) pruneCollection(
+	ctx context.Context, req PruneRequest, owner, collection string, before int64, counts *PruneCounts,
+) *types.Error {
+	if req.DryRun {
+		var count uint64
+		var err error
+		switch collection {
+		case PruneCollectionDelegations:
+			count, err = s.db.CountPrunableBTCDelegations(ctx, before)
+			counts.Moved = count
+		case PruneCollectionTimeLockArchive:
+			count, err = s.db.CountPrunableArchivedTimeLocks(ctx, before)
+			counts.Deleted = count
+		}
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to count the prunable documents of %s: %w", collection, err),
+			)
+		}
+		return nil
+	}
+
+	var processed uint64
+	for {
+		// The lock is renewed so that a long prune keeps it
+		if err := s.acquireMigrationLock(ctx, owner); err != nil {
+			return err
+		}
+
+		var count uint64
+		var err error
+		switch collection {
+		case PruneCollectionDelegations:
+			count, err = s.db.ArchivePrunableBTCDelegations(ctx, before, req.BatchSize)
+			counts.Moved += count
+		case PruneCollectionTimeLockArchive:
+			count, err = s.db.DeletePrunableArchivedTimeLocks(ctx, before, req.BatchSize)
+			counts.Deleted += count
+		}
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to prune %s: %w", collection, err),
+			)
+		}
+		if count == 0 {
+			return nil
+		}
+
+		if req.ProgressEvery > 0 && (processed+count)/req.ProgressEvery > processed/req.ProgressEvery {
+			log.Info().
+				Str("collection", collection).
+				Uint64("documents", processed+count).
+				Msg("pruning in progress")
+		}
+		processed += count
+	}
+}
+
+// acquireMigrationLock acquires or renews the migration lock, failing with a
+// conflict if a migration holds it
+func (s *Service) acquireMigrationLock(ctx context.Context, owner string) *types.Error {
+	if err := s.db.AcquireLock(ctx, db.MigrationLockName, owner, pruneLockTTL); err != nil {
+		if db.IsLockHeldError(err) {
+			return types.NewErrorWithMsg(http.StatusConflict, types.Conflict, err.Error())
+		}
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to acquire the migration lock: %w", err),
+		)
+	}
+	return nil
+}
+
+// lockOwner identifies the process running the operation as a lock owner
+func lockOwner(operation string) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s@%s:%d", operation, hostname, os.Getpid())
+}
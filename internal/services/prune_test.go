@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("AcquireLock", ctx, db.MigrationLockName, mock.Anything, pruneLockTTL).Return(nil)
+	dbMock.On("ReleaseLock", mock.Anything, db.MigrationLockName, mock.Anything).Return(nil).Once()
+
+	// The delegations are archived in batches until none is left
+	dbMock.On("ArchivePrunableBTCDelegations", ctx, mock.Anything, int64(2)).Return(uint64(2), nil).Twice()
+	dbMock.On("ArchivePrunableBTCDelegations", ctx, mock.Anything, int64(2)).Return(uint64(1), nil).Once()
+	dbMock.On("ArchivePrunableBTCDelegations", ctx, mock.Anything, int64(2)).Return(uint64(0), nil).Once()
+	dbMock.On("DeletePrunableArchivedTimeLocks", ctx, mock.Anything, int64(2)).Return(uint64(1), nil).Once()
+	dbMock.On("DeletePrunableArchivedTimeLocks", ctx, mock.Anything, int64(2)).Return(uint64(0), nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	start := time.Now()
+	summary, err := service.Prune(ctx, PruneRequest{
+		OlderThan:     365 * 24 * time.Hour,
+		Collections:   []string{PruneCollectionTimeLockArchive, PruneCollectionDelegations},
+		BatchSize:     2,
+		ProgressEvery: 2,
+	})
+	require.Nil(t, err)
+	require.Equal(t, &PruneSummary{
+		Collections: map[string]*PruneCounts{
+			PruneCollectionDelegations:     {Moved: 5},
+			PruneCollectionTimeLockArchive: {Deleted: 1},
+		},
+	}, summary)
+
+	// The retention is counted back from now
+	for _, call := range dbMock.Calls {
+		if call.Method == "ArchivePrunableBTCDelegations" {
+			require.InDelta(t, start.Add(-365*24*time.Hour).Unix(), call.Arguments.Get(1).(int64), 1)
+		}
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("AcquireLock", ctx, db.MigrationLockName, mock.Anything, pruneLockTTL).Return(nil).Once()
+	dbMock.On("ReleaseLock", mock.Anything, db.MigrationLockName, mock.Anything).Return(nil).Once()
+	dbMock.On("CountPrunableBTCDelegations", ctx, mock.Anything).Return(uint64(12), nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	summary, err := service.Prune(ctx, PruneRequest{
+		OlderThan:   24 * time.Hour,
+		Collections: []string{PruneCollectionDelegations},
+		DryRun:      true,
+		BatchSize:   100,
+	})
+	require.Nil(t, err)
+	require.Equal(t, &PruneSummary{
+		DryRun:      true,
+		Collections: map[string]*PruneCounts{PruneCollectionDelegations: {Moved: 12}},
+	}, summary)
+}
+
+func TestPruneMigrationLockHeld(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("AcquireLock", ctx, db.MigrationLockName, mock.Anything, pruneLockTTL).Return(
+		&db.LockHeldError{Name: db.MigrationLockName, Owner: "migration@host:1"},
+	).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	_, err := service.Prune(ctx, PruneRequest{
+		OlderThan:   24 * time.Hour,
+		Collections: []string{PruneCollectionDelegations},
+		BatchSize:   100,
+	})
+	require.NotNil(t, err)
+	require.Equal(t, http.StatusConflict, err.StatusCode)
+}
+
+func TestPruneInvalidCollection(t *testing.T) {
+	service := NewService(&config.Config{}, mocks.NewDbInterface(t), nil, nil, nil, nil)
+	_, err := service.Prune(context.Background(), PruneRequest{
+		OlderThan:   24 * time.Hour,
+		Collections: []string{"btc_delegation_details"},
+		BatchSize:   100,
+	})
+	require.NotNil(t, err)
+	require.Equal(t, http.StatusBadRequest, err.StatusCode)
+}
@@ -0,0 +1,491 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/errorreporting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *Service) StartReconciliation(ctx context.Context) {
+	reconciliationPoller := s.newPoller(
+		"reconciliation",
+		s.cfg.Reconciliation.Interval,
+		func(ctx context.Context) *types.Error {
+			return s.RunReconciliation(ctx, s.cfg.Reconciliation.Fix)
+		},
+	)
+	s.startPoller(ctx, reconciliationPoller)
+}
+
+// reconciliationRun holds the state of a reconciliation run in progress
+type reconciliationRun struct {
+	*model.ReconciliationRun
+	throttle *time.Ticker
+}
+
+// RunReconciliation compares the indexed delegations and finality providers
+// with the BBN chain state and reports the discrepancies in the reconciliation
+// reports collection. If fix is set, safe corrections are applied: missing
+// pending or verified delegations, missing covenant signatures and missing
+// finality providers are replayed from the chain state. An unfinished run is
+// resumed from its saved cursor.
+func (s *Service) RunReconciliation(ctx context.Context, fix bool) *types.Error {
+	ctx = audit.WithTrigger(ctx, audit.Trigger{Type: audit.TriggerReconciliation})
+	// the stored documents are compared fresh, not as cached
+	ctx = db.WithoutCache(ctx)
+	run, err := s.db.GetUnfinishedReconciliationRun(ctx)
+	if err != nil {
+		if !db.IsNotFoundError(err) {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get unfinished reconciliation run: %w", err),
+			)
+		}
+		run = model.NewReconciliationRun(fix, time.Now().Unix())
+	} else {
+		log.Info().
+			Str("run_id", run.Id.Hex()).
+			Str("phase", run.Phase).
+			Str("cursor", run.Cursor).
+			Msg("resuming reconciliation run")
+		run.Fix = fix
+	}
+
+	interval := time.Duration(float64(time.Second) / s.cfg.Reconciliation.RequestsPerSecond)
+	r := &reconciliationRun{ReconciliationRun: run, throttle: time.NewTicker(interval)}
+	defer r.throttle.Stop()
+
+	if err := s.saveReconciliationRun(ctx, r); err != nil {
+		return err
+	}
+
+	for r.Status == model.ReconciliationRunning {
+		var done bool
+		var err *types.Error
+		switch r.Phase {
+		case model.ReconciliationPhaseLocalDelegations:
+			done, err = s.reconcileLocalDelegations(ctx, r)
+		case model.ReconciliationPhaseChainDelegations:
+			done, err = s.reconcileChainDelegations(ctx, r)
+		case model.ReconciliationPhaseChainFps:
+			done, err = s.reconcileChainFinalityProviders(ctx, r)
+		default:
+			return types.NewInternalServiceError(
+				fmt.Errorf("unknown reconciliation phase %s", r.Phase),
+			)
+		}
+		if err != nil {
+			return err
+		}
+
+		if done {
+			switch r.Phase {
+			case model.ReconciliationPhaseLocalDelegations:
+				r.Phase = model.ReconciliationPhaseChainDelegations
+			case model.ReconciliationPhaseChainDelegations:
+				r.Phase = model.ReconciliationPhaseChainFps
+			default:
+				r.Status = model.ReconciliationCompleted
+				r.FinishedAt = time.Now().Unix()
+			}
+			r.Cursor = ""
+		}
+
+		// Save the cursor after each batch so that the run can be resumed
+		if err := s.saveReconciliationRun(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Str("run_id", r.Id.Hex()).
+		Uint64("checked", r.Checked).
+		Uint64("fixed", r.Fixed).
+		Interface("discrepancies", r.Discrepancies).
+		Msg("reconciliation run completed")
+
+	return nil
+}
+
+// reconcileLocalDelegations checks a batch of the indexed delegations against
+// their chain state. It returns true once all of them are checked.
+func (s *Service) reconcileLocalDelegations(
+	ctx context.Context, r *reconciliationRun,
+) (bool, *types.Error) {
+	delegations, err := s.db.GetBTCDelegationsAfter(ctx, db.BTCDelegationsFilter{}, r.Cursor, s.cfg.Reconciliation.BatchSize)
+	if err != nil {
+		return false, types.NewInternalServiceError(
+			fmt.Errorf("failed to get BTC delegations: %w", err),
+		)
+	}
+	if len(delegations) == 0 {
+		return true, nil
+	}
+
+	for _, delegation := range delegations {
+		if err := r.wait(ctx); err != nil {
+			return false, err
+		}
+
+		chainDelegation, err := s.bbn.GetBTCDelegation(ctx, delegation.StakingTxHashHex)
+		if err != nil {
+			if !errors.Is(err, bbnclient.ErrBTCDelegationNotFound) {
+				return false, types.NewInternalServiceError(err)
+			}
+			if err := s.reportDiscrepancy(
+				ctx, r, model.DiscrepancyMissingOnChain, model.ReconciliationDelegationEntity,
+				delegation.StakingTxHashHex, delegation.State.String(), "", false,
+			); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		if !utils.Contains(chainStatusesForState(delegation.State), chainDelegation.Status) {
+			if err := s.reportDiscrepancy(
+				ctx, r, model.DiscrepancyStateMismatch, model.ReconciliationDelegationEntity,
+				delegation.StakingTxHashHex, delegation.State.String(), chainDelegation.Status, false,
+			); err != nil {
+				return false, err
+			}
+		}
+
+		if err := s.reconcileCovenantSignatures(ctx, r, delegation, chainDelegation); err != nil {
+			return false, err
+		}
+	}
+
+	r.Checked += uint64(len(delegations))
+	r.Cursor = delegations[len(delegations)-1].StakingTxHashHex
+	return false, nil
+}
+
+// reconcileCovenantSignatures compares the covenant unbonding signatures of a
+// delegation with the chain and, if fixing, saves the ones missing locally.
+func (s *Service) reconcileCovenantSignatures(
+	ctx context.Context,
+	r *reconciliationRun,
+	delegation *model.BTCDelegationDetails,
+	chainDelegation *bbnclient.BTCDelegation,
+) *types.Error {
+	localCount := len(delegation.CovenantUnbondingSignatures)
+	chainCount := len(chainDelegation.CovenantUnbondingSignatures)
+	if localCount == chainCount {
+		return nil
+	}
+
+	// Only signatures missing locally can be safely added, all of them or none
+	fixed := false
+	if r.Fix && chainCount > localCount {
+		localSigs := make(map[string]struct{}, localCount)
+		for _, sig := range delegation.CovenantUnbondingSignatures {
+			localSigs[sig.CovenantBtcPkHex] = struct{}{}
+		}
+		if err := s.db.RunInTransaction(ctx, func(txCtx context.Context) error {
+			for _, sig := range chainDelegation.CovenantUnbondingSignatures {
+				if _, ok := localSigs[sig.CovenantBtcPkHex]; ok {
+					continue
+				}
+				if err := s.db.SaveBTCDelegationUnbondingCovenantSignature(
+					txCtx, delegation.StakingTxHashHex, sig.CovenantBtcPkHex, sig.SignatureHex,
+				); err != nil {
+					return fmt.Errorf("failed to save BTC delegation unbonding covenant signature: %w", err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return types.NewInternalServiceError(err)
+		}
+		fixed = true
+	}
+
+	return s.reportDiscrepancy(
+		ctx, r, model.DiscrepancyCovenantSignatureMismatch, model.ReconciliationDelegationEntity,
+		delegation.StakingTxHashHex, fmt.Sprint(localCount), fmt.Sprint(chainCount), fixed,
+	)
+}
+
+// reconcileChainDelegations checks that a page of the chain delegations is
+// indexed. It returns true once all pages are checked.
+func (s *Service) reconcileChainDelegations(
+	ctx context.Context, r *reconciliationRun,
+) (bool, *types.Error) {
+	pageKey, decodeErr := hex.DecodeString(r.Cursor)
+	if decodeErr != nil {
+		return false, types.NewInternalServiceError(
+			fmt.Errorf("invalid reconciliation cursor %s: %w", r.Cursor, decodeErr),
+		)
+	}
+
+	if err := r.wait(ctx); err != nil {
+		return false, err
+	}
+	chainDelegations, nextKey, err := s.bbn.GetBTCDelegations(ctx, pageKey, s.cfg.Reconciliation.BatchSize)
+	if err != nil {
+		return false, types.NewInternalServiceError(err)
+	}
+
+	for _, chainDelegation := range chainDelegations {
+		_, err := s.db.GetBTCDelegationByStakingTxHash(ctx, chainDelegation.StakingTxHashHex)
+		if err == nil {
+			continue
+		}
+		if !db.IsNotFoundError(err) {
+			return false, types.NewInternalServiceError(
+				fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
+			)
+		}
+
+		fixed, fixErr := s.replayMissingDelegation(ctx, r, chainDelegation)
+		if fixErr != nil {
+			return false, fixErr
+		}
+		if err := s.reportDiscrepancy(
+			ctx, r, model.DiscrepancyMissingLocally, model.ReconciliationDelegationEntity,
+			chainDelegation.StakingTxHashHex, "", chainDelegation.Status, fixed,
+		); err != nil {
+			return false, err
+		}
+	}
+
+	r.Checked += uint64(len(chainDelegations))
+	r.Cursor = hex.EncodeToString(nextKey)
+	return len(nextKey) == 0, nil
+}
+
+// replayMissingDelegation saves a delegation missing locally from its chain
+// state if fixing
+func (s *Service) replayMissingDelegation(
+	ctx context.Context, r *reconciliationRun, chainDelegation *bbnclient.BTCDelegation,
+) (bool, *types.Error) {
+	if !r.Fix {
+		return false, nil
+	}
+	return s.replayDelegationFromChain(ctx, chainDelegation)
+}
+
+// replayDelegationFromChain saves a delegation missing locally from its chain
+// state. Only pending and verified delegations are replayed, as later states
+// also require the spend notifications and the events emitted on activation.
+// It returns whether the delegation was replayed.
+func (s *Service) replayDelegationFromChain(
+	ctx context.Context, chainDelegation *bbnclient.BTCDelegation,
+) (bool, *types.Error) {
+	var state types.DelegationState
+	switch chainDelegation.Status {
+	case bbntypes.BTCDelegationStatus_PENDING.String():
+		state = types.StatePending
+	case bbntypes.BTCDelegationStatus_VERIFIED.String():
+		state = types.StateVerified
+	default:
+		return false, nil
+	}
+
+	if err := s.db.SaveNewBTCDelegation(
+		ctx, model.FromBbnBTCDelegation(chainDelegation, state),
+	); err != nil {
+		if db.IsDuplicateKeyError(err) {
+			return true, nil
+		}
+		return false, types.NewInternalServiceError(
+			fmt.Errorf("failed to save new BTC delegation: %w", err),
+		)
+	}
+
+	if err := s.recordStateTransition(ctx, &model.DelegationStateTransition{
+		StakingTxHashHex: chainDelegation.StakingTxHashHex,
+		ToState:          state,
+		Trigger:          model.StateTransitionTriggerReconciliation,
+		CreatedAt:        time.Now().Unix(),
+	}); err != nil {
+		return false, types.NewInternalServiceError(err)
+	}
+
+	return true, nil
+}
+
+// reconcileChainFinalityProviders checks a page of the chain finality providers
+// against the indexed ones. It returns true once all pages are checked.
+func (s *Service) reconcileChainFinalityProviders(
+	ctx context.Context, r *reconciliationRun,
+) (bool, *types.Error) {
+	pageKey, decodeErr := hex.DecodeString(r.Cursor)
+	if decodeErr != nil {
+		return false, types.NewInternalServiceError(
+			fmt.Errorf("invalid reconciliation cursor %s: %w", r.Cursor, decodeErr),
+		)
+	}
+
+	if err := r.wait(ctx); err != nil {
+		return false, err
+	}
+	chainFps, nextKey, err := s.bbn.GetFinalityProviders(ctx, pageKey, s.cfg.Reconciliation.BatchSize)
+	if err != nil {
+		return false, types.NewInternalServiceError(err)
+	}
+
+	for _, chainFp := range chainFps {
+		expectedState := model.FinalityProviderStateFromChain(chainFp)
+
+		fp, err := s.db.GetFinalityProviderByBtcPk(ctx, chainFp.BtcPk)
+		if err != nil {
+			if !db.IsNotFoundError(err) {
+				return false, types.NewInternalServiceError(
+					fmt.Errorf("failed to get finality provider by btc pk: %w", err),
+				)
+			}
+
+			fixed := false
+			if r.Fix {
+				if err := s.db.SaveNewFinalityProvider(
+					ctx, model.FromBbnFinalityProvider(chainFp),
+				); err != nil && !db.IsDuplicateKeyError(err) {
+					return false, types.NewInternalServiceError(
+						fmt.Errorf("failed to save new finality provider: %w", err),
+					)
+				}
+				fixed = true
+			}
+			if err := s.reportDiscrepancy(
+				ctx, r, model.DiscrepancyMissingLocally, model.ReconciliationFpEntity,
+				chainFp.BtcPk, "", expectedState, fixed,
+			); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		if !finalityProviderStateMatches(fp.State, expectedState) {
+			if err := s.reportDiscrepancy(
+				ctx, r, model.DiscrepancyStateMismatch, model.ReconciliationFpEntity,
+				chainFp.BtcPk, fp.State, expectedState, false,
+			); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	r.Checked += uint64(len(chainFps))
+	r.Cursor = hex.EncodeToString(nextKey)
+	return len(nextKey) == 0, nil
+}
+
+func (s *Service) reportDiscrepancy(
+	ctx context.Context,
+	r *reconciliationRun,
+	discrepancyType, entityType, entityId, localValue, chainValue string,
+	fixed bool,
+) *types.Error {
+	discrepancy := model.NewReconciliationDiscrepancy(
+		r.Id, discrepancyType, entityType, entityId, localValue, chainValue,
+	)
+	discrepancy.Fixed = fixed
+
+	log.Warn().
+		Str("run_id", r.Id.Hex()).
+		Str("type", discrepancyType).
+		Str("entity_type", entityType).
+		Str("entity_id", entityId).
+		Str("local_value", localValue).
+		Str("chain_value", chainValue).
+		Bool("fixed", fixed).
+		Msg("reconciliation discrepancy found")
+	tags := map[string]string{"entity_id": entityId, "fixed": strconv.FormatBool(fixed)}
+	if entityType == model.ReconciliationDelegationEntity {
+		tags[logging.StakingTxHashField] = entityId
+	}
+	errorreporting.Report(ctx, "reconciliation", fmt.Errorf(
+		"reconciliation discrepancy %s of %s", discrepancyType, entityType,
+	), tags)
+
+	if err := s.db.SaveReconciliationDiscrepancy(ctx, discrepancy); err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to save reconciliation discrepancy: %w", err),
+		)
+	}
+
+	r.Discrepancies[discrepancyType]++
+	if fixed {
+		r.Fixed++
+	}
+	return nil
+}
+
+func (s *Service) saveReconciliationRun(ctx context.Context, r *reconciliationRun) *types.Error {
+	if err := s.db.SaveReconciliationRun(ctx, r.ReconciliationRun); err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to save reconciliation run: %w", err),
+		)
+	}
+	return nil
+}
+
+// wait blocks until the rate limit allows the next BBN query
+func (r *reconciliationRun) wait(ctx context.Context) *types.Error {
+	return waitForThrottle(ctx, r.throttle, "reconciliation")
+}
+
+// waitForThrottle blocks until the throttle ticks, failing if the context of
+// the named process is cancelled first
+func waitForThrottle(ctx context.Context, throttle *time.Ticker, process string) *types.Error {
+	select {
+	case <-throttle.C:
+		return nil
+	case <-ctx.Done():
+		return types.NewInternalServiceError(
+			fmt.Errorf("context cancelled during %s: %w", process, ctx.Err()),
+		)
+	}
+}
+
+// chainStatusesForState returns the chain statuses consistent with a local
+// delegation state. The chain status of spent delegations depends on whether
+// they were unbonded early or expired, which the local state does not capture
+// for slashed delegations.
+func chainStatusesForState(state types.DelegationState) []string {
+	switch state {
+	case types.StatePending:
+		return []string{bbntypes.BTCDelegationStatus_PENDING.String()}
+	case types.StateVerified:
+		return []string{bbntypes.BTCDelegationStatus_VERIFIED.String()}
+	case types.StateActive:
+		return []string{bbntypes.BTCDelegationStatus_ACTIVE.String()}
+	case types.StateUnbonding, types.StateWithdrawable, types.StateWithdrawn:
+		return []string{
+			bbntypes.BTCDelegationStatus_UNBONDED.String(),
+			bbntypes.BTCDelegationStatus_EXPIRED.String(),
+		}
+	case types.StateSlashed:
+		return []string{
+			bbntypes.BTCDelegationStatus_ACTIVE.String(),
+			bbntypes.BTCDelegationStatus_UNBONDED.String(),
+			bbntypes.BTCDelegationStatus_EXPIRED.String(),
+		}
+	default:
+		return nil
+	}
+}
+
+// finalityProviderStateMatches returns whether the local finality provider
+// state is consistent with the one expected from the chain state, which does
+// not tell active and inactive finality providers apart.
+func finalityProviderStateMatches(localState, expectedState string) bool {
+	if expectedState == bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE.String() {
+		return localState == expectedState ||
+			localState == bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE.String()
+	}
+	return localState == expectedState
+}
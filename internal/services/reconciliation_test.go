@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+)
+
+const (
+	reconciledTxHash        = "1000000000000000000000000000000000000000000000000000000000000000"
+	stateDriftTxHash        = "2000000000000000000000000000000000000000000000000000000000000000"
+	missingSignatureTxHash  = "3000000000000000000000000000000000000000000000000000000000000000"
+	missingOnChainTxHash    = "4000000000000000000000000000000000000000000000000000000000000000"
+	missingVerifiedTxHash   = "5000000000000000000000000000000000000000000000000000000000000000"
+	missingActiveTxHash     = "6000000000000000000000000000000000000000000000000000000000000000"
+	reconciledFpBtcPk       = "fp-reconciled"
+	jailedFpBtcPk           = "fp-jailed"
+	missingLocallyFpBtcPk   = "fp-missing-locally"
+	reconciliationCovenantA = "covenant-a"
+	reconciliationCovenantB = "covenant-b"
+)
+
+// reconciliationDb records the discrepancies reported by the reconciliation
+// runs and the last saved run
+type reconciliationDb struct {
+	db.DbInterface
+	discrepancies []*model.ReconciliationDiscrepancy
+	run           *model.ReconciliationRun
+}
+
+func (d *reconciliationDb) SaveReconciliationDiscrepancy(
+	ctx context.Context, discrepancy *model.ReconciliationDiscrepancy,
+) error {
+	d.discrepancies = append(d.discrepancies, discrepancy)
+	return d.DbInterface.SaveReconciliationDiscrepancy(ctx, discrepancy)
+}
+
+func (d *reconciliationDb) SaveReconciliationRun(ctx context.Context, run *model.ReconciliationRun) error {
+	copied := *run
+	d.run = &copied
+	return d.DbInterface.SaveReconciliationRun(ctx, run)
+}
+
+// discrepancy is the outcome of a reported discrepancy
+type discrepancy struct {
+	discrepancyType, entityId, localValue, chainValue string
+	fixed                                             bool
+}
+
+func (d *reconciliationDb) reported() []discrepancy {
+	reported := make([]discrepancy, len(d.discrepancies))
+	for i, r := range d.discrepancies {
+		reported[i] = discrepancy{r.Type, r.EntityId, r.LocalValue, r.ChainValue, r.Fixed}
+	}
+	return reported
+}
+
+type reconciliationTestEnv struct {
+	service  *Service
+	database db.DbInterface
+	recorder *reconciliationDb
+}
+
+// newReconciliationTestEnv indexes delegations and finality providers
+// drifting from the chain state in every way the reconciliation detects,
+// next to ones in sync
+func newReconciliationTestEnv(t *testing.T, database db.DbInterface) *reconciliationTestEnv {
+	ctx := context.Background()
+	for _, delegation := range []*model.BTCDelegationDetails{
+		{StakingTxHashHex: reconciledTxHash, State: types.StateActive},
+		{StakingTxHashHex: stateDriftTxHash, State: types.StateActive},
+		{
+			StakingTxHashHex: missingSignatureTxHash,
+			State:            types.StateVerified,
+			CovenantUnbondingSignatures: []model.CovenantSignature{
+				{CovenantBtcPkHex: reconciliationCovenantA, SignatureHex: "sig-a"},
+			},
+		},
+		{StakingTxHashHex: missingOnChainTxHash, State: types.StatePending},
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+	for _, fp := range []*model.FinalityProviderDetails{
+		{BtcPk: reconciledFpBtcPk, State: bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE.String()},
+		{BtcPk: jailedFpBtcPk, State: bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE.String()},
+	} {
+		require.NoError(t, database.SaveNewFinalityProvider(ctx, fp))
+	}
+
+	bbnClient := fixtures.NewBbnClient()
+	for _, delegation := range []*bbnclient.BTCDelegation{
+		{StakingTxHashHex: reconciledTxHash, Status: bbntypes.BTCDelegationStatus_ACTIVE.String()},
+		{StakingTxHashHex: stateDriftTxHash, Status: bbntypes.BTCDelegationStatus_UNBONDED.String()},
+		{
+			StakingTxHashHex: missingSignatureTxHash,
+			Status:           bbntypes.BTCDelegationStatus_VERIFIED.String(),
+			CovenantUnbondingSignatures: []bbnclient.CovenantUnbondingSignature{
+				{CovenantBtcPkHex: reconciliationCovenantA, SignatureHex: "sig-a"},
+				{CovenantBtcPkHex: reconciliationCovenantB, SignatureHex: "sig-b"},
+			},
+		},
+		{StakingTxHashHex: missingVerifiedTxHash, Status: bbntypes.BTCDelegationStatus_VERIFIED.String()},
+		{StakingTxHashHex: missingActiveTxHash, Status: bbntypes.BTCDelegationStatus_ACTIVE.String()},
+	} {
+		bbnClient.SetBTCDelegation(delegation)
+	}
+	bbnClient.FinalityProviders = []*bbnclient.FinalityProvider{
+		{BtcPk: reconciledFpBtcPk},
+		{BtcPk: jailedFpBtcPk, Jailed: true},
+		{BtcPk: missingLocallyFpBtcPk, Moniker: "missing locally"},
+	}
+
+	recorder := &reconciliationDb{DbInterface: database}
+	cfg := &config.Config{Reconciliation: config.ReconciliationConfig{
+		// batches smaller than the delegations for the run to resume from
+		// its cursor
+		BatchSize:         2,
+		RequestsPerSecond: 1000,
+	}}
+	return &reconciliationTestEnv{
+		service:  NewService(cfg, recorder, nil, nil, bbnClient, nil),
+		database: database,
+		recorder: recorder,
+	}
+}
+
+func TestRunReconciliationReportsDrift(t *testing.T) {
+	ctx := context.Background()
+	env := newReconciliationTestEnv(t, inmemory.New())
+
+	require.Nil(t, env.service.RunReconciliation(ctx, false))
+
+	inactive := bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE.String()
+	require.Equal(t, []discrepancy{
+		{model.DiscrepancyStateMismatch, stateDriftTxHash, types.StateActive.String(), bbntypes.BTCDelegationStatus_UNBONDED.String(), false},
+		{model.DiscrepancyCovenantSignatureMismatch, missingSignatureTxHash, "1", "2", false},
+		{model.DiscrepancyMissingOnChain, missingOnChainTxHash, types.StatePending.String(), "", false},
+		{model.DiscrepancyMissingLocally, missingVerifiedTxHash, "", bbntypes.BTCDelegationStatus_VERIFIED.String(), false},
+		{model.DiscrepancyMissingLocally, missingActiveTxHash, "", bbntypes.BTCDelegationStatus_ACTIVE.String(), false},
+		{
+			model.DiscrepancyStateMismatch, jailedFpBtcPk,
+			bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_ACTIVE.String(),
+			bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_JAILED.String(), false,
+		},
+		{model.DiscrepancyMissingLocally, missingLocallyFpBtcPk, "", inactive, false},
+	}, env.recorder.reported())
+
+	run := env.recorder.run
+	require.Equal(t, model.ReconciliationCompleted, run.Status)
+	require.Equal(t, uint64(4+5+3), run.Checked)
+	require.Zero(t, run.Fixed)
+	require.Equal(t, map[string]uint64{
+		model.DiscrepancyStateMismatch:             2,
+		model.DiscrepancyCovenantSignatureMismatch: 1,
+		model.DiscrepancyMissingOnChain:            1,
+		model.DiscrepancyMissingLocally:            3,
+	}, run.Discrepancies)
+
+	// Nothing is repaired without fixing
+	delegation, err := env.database.GetBTCDelegationByStakingTxHash(ctx, missingSignatureTxHash)
+	require.NoError(t, err)
+	require.Len(t, delegation.CovenantUnbondingSignatures, 1)
+	_, err = env.database.GetBTCDelegationByStakingTxHash(ctx, missingVerifiedTxHash)
+	require.True(t, db.IsNotFoundError(err))
+	_, err = env.database.GetFinalityProviderByBtcPk(ctx, missingLocallyFpBtcPk)
+	require.True(t, db.IsNotFoundError(err))
+}
+
+func TestRunReconciliationRepairsDrift(t *testing.T) {
+	ctx := context.Background()
+	env := newReconciliationTestEnv(t, inmemory.New())
+
+	require.Nil(t, env.service.RunReconciliation(ctx, true))
+
+	fixed := map[string]bool{}
+	for _, d := range env.recorder.reported() {
+		fixed[d.entityId] = d.fixed
+	}
+	require.Equal(t, map[string]bool{
+		stateDriftTxHash:       false,
+		missingSignatureTxHash: true,
+		missingOnChainTxHash:   false,
+		missingVerifiedTxHash:  true,
+		// only pending and verified delegations are replayed
+		missingActiveTxHash:   false,
+		jailedFpBtcPk:         false,
+		missingLocallyFpBtcPk: true,
+	}, fixed)
+	require.Equal(t, uint64(3), env.recorder.run.Fixed)
+
+	delegation, err := env.database.GetBTCDelegationByStakingTxHash(ctx, missingSignatureTxHash)
+	require.NoError(t, err)
+	require.Equal(t, []model.CovenantSignature{
+		{CovenantBtcPkHex: reconciliationCovenantA, SignatureHex: "sig-a"},
+		{CovenantBtcPkHex: reconciliationCovenantB, SignatureHex: "sig-b"},
+	}, delegation.CovenantUnbondingSignatures)
+
+	replayed, err := env.database.GetBTCDelegationByStakingTxHash(ctx, missingVerifiedTxHash)
+	require.NoError(t, err)
+	require.Equal(t, types.StateVerified, replayed.State)
+	transitions, err := env.database.GetDelegationStateTransitions(ctx, missingVerifiedTxHash)
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	require.Equal(t, types.StateVerified, transitions[0].ToState)
+	require.Equal(t, model.StateTransitionTriggerReconciliation, transitions[0].Trigger)
+
+	_, err = env.database.GetBTCDelegationByStakingTxHash(ctx, missingActiveTxHash)
+	require.True(t, db.IsNotFoundError(err))
+
+	fp, err := env.database.GetFinalityProviderByBtcPk(ctx, missingLocallyFpBtcPk)
+	require.NoError(t, err)
+	require.Equal(t, "missing locally", fp.Description.Moniker)
+
+	// A second run finds the repaired drift in sync
+	env.recorder.discrepancies = nil
+	require.Nil(t, env.service.RunReconciliation(ctx, true))
+	for _, d := range env.recorder.reported() {
+		require.NotContains(t, []string{missingSignatureTxHash, missingVerifiedTxHash, missingLocallyFpBtcPk}, d.entityId)
+	}
+}
+
+// TestReconcileCovenantSignaturesAtomic fails the save of the second of the
+// covenant signatures missing locally, which leaves none of them saved
+func TestReconcileCovenantSignaturesAtomic(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+	chaos := db.NewChaosDatabase(database)
+	env := newReconciliationTestEnv(t, chaos)
+	require.NoError(t, database.SaveBTCDelegationUnbondingCovenantSignature(
+		ctx, missingSignatureTxHash, "covenant-c", "sig-c",
+	))
+	chainDelegation, err := env.service.bbn.GetBTCDelegation(ctx, missingSignatureTxHash)
+	require.NoError(t, err)
+	chainDelegation.CovenantUnbondingSignatures = append(
+		chainDelegation.CovenantUnbondingSignatures,
+		bbnclient.CovenantUnbondingSignature{CovenantBtcPkHex: "covenant-d", SignatureHex: "sig-d"},
+	)
+
+	saveErr := errors.New("write conflict")
+	chaos.Inject("SaveBTCDelegationUnbondingCovenantSignature", db.Fault{After: 1, Times: 1, Err: saveErr})
+
+	err = env.service.RunReconciliation(ctx, true)
+	require.ErrorIs(t, err, saveErr)
+
+	delegation, dbErr := database.GetBTCDelegationByStakingTxHash(ctx, missingSignatureTxHash)
+	require.NoError(t, dbErr)
+	require.Equal(t, []model.CovenantSignature{
+		{CovenantBtcPkHex: reconciliationCovenantA, SignatureHex: "sig-a"},
+		{CovenantBtcPkHex: "covenant-c", SignatureHex: "sig-c"},
+	}, delegation.CovenantUnbondingSignatures)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// Periods the staking report aggregates the transitions by, weeks starting on
+// Monday
+const (
+	ReportGranularityDay   = "day"
+	ReportGranularityWeek  = "week"
+	ReportGranularityMonth = "month"
+)
+
+// Formats the staking report is written in
+const (
+	ReportFormatCSV  = "csv"
+	ReportFormatJSON = "json"
+)
+
+// StakingReportRequest selects the time range, the periods and the format of
+// a staking report
+type StakingReportRequest struct {
+	// From is the start of the range, inclusive
+	From time.Time
+	// To is the end of the range, exclusive
+	To          time.Time
+	Granularity string
+	Format      string
+}
+
+// stakingReportRow is the number of transitions to a state within a period
+// and the total amount they concern, e.g. the stake activated in a week
+type stakingReportRow struct {
+	// PeriodStart is the UTC date the period starts on
+	PeriodStart     string `json:"period_start"`
+	Transition      string `json:"transition"`
+	TransitionCount uint64 `json:"transition_count"`
+	TotalSat        uint64 `json:"total_sat"`
+}
+
+var stakingReportHeader = []string{"period_start", "transition", "transition_count", "total_sat"}
+
+func newStakingReportRow(stats *model.StateTransitionPeriodStats) stakingReportRow {
+	return stakingReportRow{
+		PeriodStart:     time.Unix(stats.PeriodStart, 0).UTC().Format(time.DateOnly),
+		Transition:      stats.ToState.String(),
+		TransitionCount: stats.TransitionCount,
+		TotalSat:        stats.TotalSat,
+	}
+}
+
+// WriteStakingReport writes the number of state transitions, and the staked
+// amount they concern, per period and state reached within the time range,
+// e.g. the new, unbonded and withdrawn stake by week. The rows are written as
+// the state history is aggregated.
+func (s *Service) WriteStakingReport(
+	ctx context.Context, req StakingReportRequest, w io.Writer,
+) *types.Error {
+	if !req.From.Before(req.To) {
+		return types.NewValidationFailedError(errors.New("the report range must end after it starts"))
+	}
+	if !utils.Contains(
+		[]string{ReportGranularityDay, ReportGranularityWeek, ReportGranularityMonth}, req.Granularity,
+	) {
+		return types.NewValidationFailedError(fmt.Errorf("%s is not a valid report granularity", req.Granularity))
+	}
+
+	var writeRow func(row stakingReportRow) error
+	var complete func() error
+	switch req.Format {
+	case ReportFormatCSV:
+		csvWriter := csv.NewWriter(w)
+		if err := csvWriter.Write(stakingReportHeader); err != nil {
+			return types.NewInternalServiceError(fmt.Errorf("failed to write report: %w", err))
+		}
+		writeRow = func(row stakingReportRow) error {
+			return csvWriter.Write([]string{
+				row.PeriodStart,
+				row.Transition,
+				strconv.FormatUint(row.TransitionCount, 10),
+				strconv.FormatUint(row.TotalSat, 10),
+			})
+		}
+		complete = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	case ReportFormatJSON:
+		// The array is written element by element, so that it is not held
+		// in memory
+		separator := "[\n"
+		writeRow = func(row stakingReportRow) error {
+			content, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%s%s", separator, content)
+			separator = ",\n"
+			return err
+		}
+		complete = func() error {
+			if separator == "[\n" {
+				_, err := io.WriteString(w, "[]\n")
+				return err
+			}
+			_, err := io.WriteString(w, "\n]\n")
+			return err
+		}
+	default:
+		return types.NewValidationFailedError(fmt.Errorf("%s is not a valid report format", req.Format))
+	}
+
+	if err := s.db.AggregateStateTransitions(
+		ctx, req.From.Unix(), req.To.Unix(), req.Granularity,
+		func(stats *model.StateTransitionPeriodStats) error {
+			return writeRow(newStakingReportRow(stats))
+		},
+	); err != nil {
+		return types.NewInternalServiceError(fmt.Errorf("failed to aggregate state transitions: %w", err))
+	}
+	if err := complete(); err != nil {
+		return types.NewInternalServiceError(fmt.Errorf("failed to write report: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testReportFrom = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testReportTo   = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// mockStateTransitionStats makes the db mock pass the aggregates to the
+// visitor of the report
+func mockStateTransitionStats(dbMock *mocks.DbInterface, stats ...*model.StateTransitionPeriodStats) {
+	dbMock.On(
+		"AggregateStateTransitions", mock.Anything, testReportFrom.Unix(), testReportTo.Unix(),
+		ReportGranularityWeek, mock.Anything,
+	).Run(func(args mock.Arguments) {
+		visit := args.Get(4).(func(stats *model.StateTransitionPeriodStats) error)
+		for _, s := range stats {
+			if err := visit(s); err != nil {
+				panic(err)
+			}
+		}
+	}).Return(nil).Once()
+}
+
+func TestWriteStakingReport(t *testing.T) {
+	stats := []*model.StateTransitionPeriodStats{
+		{
+			PeriodStart:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+			ToState:         types.StateActive,
+			TransitionCount: 3,
+			TotalSat:        300000,
+		},
+		{
+			PeriodStart:     time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC).Unix(),
+			ToState:         types.StateUnbonding,
+			TransitionCount: 1,
+			TotalSat:        50000,
+		},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		dbMock := mocks.NewDbInterface(t)
+		mockStateTransitionStats(dbMock, stats...)
+
+		var out bytes.Buffer
+		service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+		err := service.WriteStakingReport(context.Background(), StakingReportRequest{
+			From: testReportFrom, To: testReportTo, Granularity: ReportGranularityWeek, Format: ReportFormatCSV,
+		}, &out)
+		require.Nil(t, err)
+		require.Equal(t, "period_start,transition,transition_count,total_sat\n"+
+			"2024-01-01,ACTIVE,3,300000\n"+
+			"2024-01-08,UNBONDING,1,50000\n", out.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		dbMock := mocks.NewDbInterface(t)
+		mockStateTransitionStats(dbMock, stats...)
+
+		var out bytes.Buffer
+		service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+		err := service.WriteStakingReport(context.Background(), StakingReportRequest{
+			From: testReportFrom, To: testReportTo, Granularity: ReportGranularityWeek, Format: ReportFormatJSON,
+		}, &out)
+		require.Nil(t, err)
+		require.JSONEq(t, `[
+			{"period_start":"2024-01-01","transition":"ACTIVE","transition_count":3,"total_sat":300000},
+			{"period_start":"2024-01-08","transition":"UNBONDING","transition_count":1,"total_sat":50000}
+		]`, out.String())
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		dbMock := mocks.NewDbInterface(t)
+		mockStateTransitionStats(dbMock)
+
+		var out bytes.Buffer
+		service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+		err := service.WriteStakingReport(context.Background(), StakingReportRequest{
+			From: testReportFrom, To: testReportTo, Granularity: ReportGranularityWeek, Format: ReportFormatJSON,
+		}, &out)
+		require.Nil(t, err)
+		require.JSONEq(t, `[]`, out.String())
+	})
+}
+
+func TestWriteStakingReportInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		req  StakingReportRequest
+	}{
+		{"empty range", StakingReportRequest{
+			From: testReportTo, To: testReportFrom, Granularity: ReportGranularityWeek, Format: ReportFormatCSV,
+		}},
+		{"unknown granularity", StakingReportRequest{
+			From: testReportFrom, To: testReportTo, Granularity: "year", Format: ReportFormatCSV,
+		}},
+		{"unknown format", StakingReportRequest{
+			From: testReportFrom, To: testReportTo, Granularity: ReportGranularityWeek, Format: "xml",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewService(&config.Config{}, mocks.NewDbInterface(t), nil, nil, nil, nil)
+			err := service.WriteStakingReport(context.Background(), tc.req, &bytes.Buffer{})
+			require.NotNil(t, err)
+			require.Equal(t, http.StatusBadRequest, err.StatusCode)
+		})
+	}
+}
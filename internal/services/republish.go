@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// RepublishRequest selects the delegations whose events are republished,
+// either a single one or the ones created in a BBN height range
+type RepublishRequest struct {
+	StakingTxHashHex types.StakingTxHash
+	FromHeight       int64
+	ToHeight         int64
+	// EventsPerSecond limits the publishing so that a large replay does not
+	// flood the consumers
+	EventsPerSecond float64
+}
+
+// stateOutboxEventTypes maps the delegation states to the event emitted when
+// entering them, which the event history of a delegation in that state must
+// contain
+var stateOutboxEventTypes = map[types.DelegationState]string{
+	types.StateActive:       model.OutboxEventTypeActiveStaking,
+	types.StateUnbonding:    model.OutboxEventTypeUnbondingStaking,
+	types.StateWithdrawable: model.OutboxEventTypeWithdrawable,
+	types.StateWithdrawn:    model.OutboxEventTypeWithdrawn,
+}
+
+// RepublishEvents publishes again the events of the requested delegations,
+// flagged as replayed, for consumers that lost them. The events are rebuilt
+// from the outbox, which keeps them once relayed, and keep their original
+// order and sequence numbers. Nothing is published if the event history of
+// any delegation is incomplete.
+func (s *Service) RepublishEvents(ctx context.Context, req RepublishRequest) *types.Error {
+	if req.EventsPerSecond <= 0 {
+		return types.NewInternalServiceError(
+			errors.New("events per second must be positive"),
+		)
+	}
+
+	delegations, err := s.getRepublishedDelegations(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	var events []*model.OutboxEvent
+	var incomplete []string
+	for _, delegation := range delegations {
+		delegationEvents, historyErr := s.getDelegationEventHistory(ctx, delegation)
+		if historyErr != nil {
+			if !errors.Is(historyErr, errIncompleteEventHistory) {
+				return types.NewInternalServiceError(historyErr)
+			}
+			incomplete = append(incomplete, historyErr.Error())
+			continue
+		}
+		events = append(events, delegationEvents...)
+	}
+	if len(incomplete) > 0 {
+		return types.NewInternalServiceError(fmt.Errorf(
+			"refusing to republish, the event history of %d delegations is incomplete: %s",
+			len(incomplete), strings.Join(incomplete, "; "),
+		))
+	}
+
+	// The events of a delegation are recorded in sequence order, so that the
+	// creation order keeps them in order while interleaving the delegations
+	// the way they were first relayed
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt < events[j].CreatedAt
+	})
+
+	throttle := time.NewTicker(time.Duration(float64(time.Second) / req.EventsPerSecond))
+	defer throttle.Stop()
+
+	emitter := s.queueManager.Replaying()
+	for _, event := range events {
+		select {
+		case <-ctx.Done():
+			return types.NewInternalServiceError(ctx.Err())
+		case <-throttle.C:
+		}
+
+		if err := pushOutboxEvent(emitter, event); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Int("delegations", len(delegations)).
+		Int("events", len(events)).
+		Msg("republished delegation events")
+	return nil
+}
+
+func (s *Service) getRepublishedDelegations(
+	ctx context.Context, req RepublishRequest,
+) ([]*model.BTCDelegationDetails, *types.Error) {
+	if req.StakingTxHashHex != "" {
+		delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, req.StakingTxHashHex.String())
+		if err != nil {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to get delegation %s: %w", req.StakingTxHashHex, err),
+			)
+		}
+		return []*model.BTCDelegationDetails{delegation}, nil
+	}
+
+	if req.FromHeight > req.ToHeight {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("invalid height range %d-%d", req.FromHeight, req.ToHeight),
+		)
+	}
+	delegations, err := s.db.GetBTCDelegationsCreatedBetween(ctx, req.FromHeight, req.ToHeight)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get the delegations created between heights %d and %d: %w",
+				req.FromHeight, req.ToHeight, err),
+		)
+	}
+	return delegations, nil
+}
+
+var errIncompleteEventHistory = errors.New("incomplete event history")
+
+// getDelegationEventHistory returns the outbox events of the delegation in
+// sequence order. The history is incomplete if a sequence number is missing,
+// e.g. for events recorded before the outbox sequences existed, or if the
+// event of the current state of the delegation was never recorded.
+func (s *Service) getDelegationEventHistory(
+	ctx context.Context, delegation *model.BTCDelegationDetails,
+) ([]*model.OutboxEvent, error) {
+	stakingTxHashHex := delegation.StakingTxHashHex
+	events, err := s.db.GetDelegationOutboxEvents(ctx, stakingTxHashHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the outbox events of delegation %s: %w", stakingTxHashHex, err)
+	}
+
+	lastSequence, err := s.db.GetOutboxSequence(ctx, stakingTxHashHex)
+	if err != nil && !db.IsNotFoundError(err) {
+		return nil, fmt.Errorf("failed to get the outbox sequence of delegation %s: %w", stakingTxHashHex, err)
+	}
+
+	if uint64(len(events)) != lastSequence {
+		return nil, fmt.Errorf("%w: %d of the %d events of delegation %s recorded",
+			errIncompleteEventHistory, len(events), lastSequence, stakingTxHashHex)
+	}
+	for i, event := range events {
+		if event.Sequence != uint64(i+1) {
+			return nil, fmt.Errorf("%w: event %d of delegation %s missing",
+				errIncompleteEventHistory, i+1, stakingTxHashHex)
+		}
+	}
+
+	if eventType, ok := stateOutboxEventTypes[delegation.State]; ok {
+		found := false
+		for _, event := range events {
+			found = found || event.EventType == eventType
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: no %s event for delegation %s in state %s",
+				errIncompleteEventHistory, eventType, stakingTxHashHex, delegation.State)
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+// newWithdrawnOutboxTestEnv sets up a withdrawn delegation whose withdrawable
+// and withdrawn events were relayed
+func newWithdrawnOutboxTestEnv(t *testing.T) *outboxTestEnv {
+	env := newOutboxTestEnv(t)
+	env.delegation.State = types.StateWithdrawn
+
+	withdrawable := model.NewWithdrawableStakingOutboxEvent(env.delegation.Summary(), types.SubStateTimelock, 100, 1)
+	withdrawable.Sequence = 1
+	withdrawable.SentAt = 1
+	withdrawn := model.NewWithdrawnStakingOutboxEvent(env.delegation, types.SubStateTimelock, "spending-tx", 110, 2)
+	withdrawn.Sequence = 2
+	withdrawn.SentAt = 2
+	env.outbox = []*model.OutboxEvent{withdrawable, withdrawn}
+
+	return env
+}
+
+func testRepublishRequest() RepublishRequest {
+	return RepublishRequest{
+		StakingTxHashHex: testStakingTxHash,
+		EventsPerSecond:  1000,
+	}
+}
+
+func TestRepublishEventsInOrderFlaggedAsReplayed(t *testing.T) {
+	env := newWithdrawnOutboxTestEnv(t)
+
+	require.Nil(t, env.service.RepublishEvents(context.Background(), testRepublishRequest()))
+
+	require.Equal(t, []string{
+		model.OutboxEventTypeWithdrawable + ":" + testStakingTxHash,
+		model.OutboxEventTypeWithdrawn + ":" + testStakingTxHash,
+	}, env.pushed())
+	for _, emitted := range env.emitter.Events() {
+		require.True(t, emitted.Replay)
+	}
+}
+
+func TestRepublishEventsRefusesMissingSequence(t *testing.T) {
+	env := newWithdrawnOutboxTestEnv(t)
+	// the withdrawable event is lost
+	env.outbox = env.outbox[1:]
+
+	require.NotNil(t, env.service.RepublishEvents(context.Background(), testRepublishRequest()))
+	require.Empty(t, env.pushed())
+}
+
+func TestRepublishEventsRefusesMissingStateEvent(t *testing.T) {
+	env := newWithdrawnOutboxTestEnv(t)
+	// the withdrawn event was never recorded
+	env.outbox = env.outbox[:1]
+
+	require.NotNil(t, env.service.RepublishEvents(context.Background(), testRepublishRequest()))
+	require.Empty(t, env.pushed())
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog/log"
 
@@ -11,12 +12,17 @@ import (
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/alerting"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/supervisor"
 	notifier "github.com/lightningnetwork/lnd/chainntnfs"
 )
 
 type Service struct {
-	wg   sync.WaitGroup
-	quit chan struct{}
+	wg       sync.WaitGroup
+	quit     chan struct{}
+	quitOnce sync.Once
+	// bbnBlockMu serializes the processing of BBN blocks
+	bbnBlockMu sync.Mutex
 
 	cfg               *config.Config
 	db                db.DbInterface
@@ -26,32 +32,116 @@ type Service struct {
 	queueManager      consumer.EventConsumer
 	bbnEventProcessor chan BbnEvent
 	latestHeightChan  chan int64
+	health            *healthState
+	pause             *pauseGate
+	bbnTip            *bbnTipCache
+	btcTip            *btcTipTracker
+	alerter           alerting.Alerter
+	eventCapture      *eventCapture
+	bbnPrefetcher     *bbnBlockPrefetcher
+	// blockAllocations adds the allocations of each BBN block to its summary
+	blockAllocations bool
+
+	// pollers are the pollers created, stopped on shutdown
+	pollersMu sync.Mutex
+	pollers   []namedPoller
+	// blockProcessorStop is closed to stop the BBN block processor between
+	// two blocks
+	blockProcessorStop     chan struct{}
+	stopBlockProcessorOnce sync.Once
+	// blockProcessorAbort is closed to cancel the block in flight of the BBN
+	// block processor
+	blockProcessorAbort     chan struct{}
+	abortBlockProcessorOnce sync.Once
+	// blockProcessorDone is closed once the BBN block processor returned
+	blockProcessorDone    chan struct{}
+	blockProcessorStarted atomic.Bool
+	// leader is the leader lease of the instance, if leader election is
+	// enabled
+	leader *leaderState
+	// supervisor runs the long-lived background components
+	supervisor *supervisor.Supervisor
 }
 
-func NewService(
-	cfg *config.Config,
-	db db.DbInterface,
-	btc btcclient.BtcInterface,
-	btcNotifier notifier.ChainNotifier,
-	bbn bbnclient.BbnInterface,
-	consumer consumer.EventConsumer,
-) *Service {
+// Dependencies are the clients and stores the service runs against, the
+// production ones in the indexer and fakes in the tests
+type Dependencies struct {
+	Db          db.DbInterface
+	Btc         btcclient.BtcInterface
+	BtcNotifier notifier.ChainNotifier
+	Bbn         bbnclient.BbnInterface
+	Emitter     consumer.EventConsumer
+	// Alerter pushes the alerts on critical conditions, which are only
+	// logged if nil
+	Alerter alerting.Alerter
+	// Supervisor runs the background components, shared with the other
+	// components of the process. A supervisor of its own is created if nil.
+	Supervisor *supervisor.Supervisor
+}
+
+// New returns a service running against the dependencies
+func New(cfg *config.Config, deps Dependencies) *Service {
+	alerter := deps.Alerter
+	if alerter == nil {
+		alerter = alerting.NewNoopAlerter()
+	}
+	sup := deps.Supervisor
+	if sup == nil {
+		sup = supervisor.New()
+	}
 	eventProcessor := make(chan BbnEvent, eventProcessorSize)
 	latestHeightChan := make(chan int64)
 	return &Service{
 		quit:              make(chan struct{}),
 		cfg:               cfg,
-		db:                db,
-		btc:               btc,
-		btcNotifier:       btcNotifier,
-		bbn:               bbn,
-		queueManager:      consumer,
+		db:                deps.Db,
+		btc:               deps.Btc,
+		btcNotifier:       deps.BtcNotifier,
+		bbn:               deps.Bbn,
+		queueManager:      deps.Emitter,
 		bbnEventProcessor: eventProcessor,
 		latestHeightChan:  latestHeightChan,
+		health:            newHealthState(),
+		pause:             newPauseGate(),
+		bbnTip:            &bbnTipCache{},
+		btcTip:            &btcTipTracker{},
+		alerter:           alerter,
+		eventCapture:      newEventCapture(&cfg.EventCapture),
+		bbnPrefetcher: newBbnBlockPrefetcher(
+			deps.Bbn, cfg.BBN.GetPrefetchConcurrency(), cfg.BBN.GetPrefetchBufferSize(),
+		),
+		blockAllocations:    cfg.Log.BlockAllocations,
+		blockProcessorStop:  make(chan struct{}),
+		blockProcessorAbort: make(chan struct{}),
+		blockProcessorDone:  make(chan struct{}),
+		leader:              newLeaderState(),
+		supervisor:          sup,
 	}
 }
 
-func (s *Service) StartIndexerSync(ctx context.Context) {
+// NewService returns a service running against the given dependencies,
+// without alerter
+func NewService(
+	cfg *config.Config,
+	db db.DbInterface,
+	btc btcclient.BtcInterface,
+	btcNotifier notifier.ChainNotifier,
+	bbn bbnclient.BbnInterface,
+	consumer consumer.EventConsumer,
+) *Service {
+	return New(cfg, Dependencies{
+		Db:          db,
+		Btc:         btc,
+		BtcNotifier: btcNotifier,
+		Bbn:         bbn,
+		Emitter:     consumer,
+	})
+}
+
+// StartIndexerSync runs the indexing until the context is done. The start
+// height overrides, if any, are applied once the leadership is acquired;
+// without them the block processing resumes after the last processed height.
+func (s *Service) StartIndexerSync(ctx context.Context, overrides *StartHeightOverrides) {
 	if err := s.bbn.Start(); err != nil {
 		log.Fatal().Err(err).Msg("failed to start BBN client")
 	}
@@ -64,18 +154,69 @@ func (s *Service) StartIndexerSync(ctx context.Context) {
 		log.Fatal().Err(err).Msg("failed to start the event consumer")
 	}
 
+	// Only the leader replica indexes, the standby ones serving the api. The
+	// processing marker left by a former leader is recovered by the block
+	// processor before it processes any block.
+	if s.cfg.LeaderElection.Enabled {
+		if !s.awaitLeadership(ctx) {
+			return
+		}
+		s.startKeepingLeadership(ctx)
+	}
+	if overrides != nil {
+		s.applyStartHeight(ctx, *overrides)
+	}
+
+	// Sync the finality providers and params of the chain before processing
+	// the BBN blocks
+	if !s.bootstrapChainState(ctx) {
+		return
+	}
 	// Sync global parameters
 	s.SyncGlobalParams(ctx)
 	// Resubscribe to missed BTC notifications
 	s.ResubscribeToMissedBtcNotifications(ctx)
 	// Start the expiry checker
 	s.StartExpiryChecker(ctx)
+	// Start the BTC reorg checker
+	s.StartBtcReorgChecker(ctx)
+	// Start tracking the BTC tip and comparing it with the BBN light client
+	s.StartBtcBackendMonitor(ctx)
+	// Start tracking the finality provider active set
+	s.StartFpActiveSetPoller(ctx)
+	// Start the scheduled reconciliation against the BBN chain state
+	s.StartReconciliation(ctx)
+	// Start the audit of the processed BBN heights
+	s.StartProcessedHeightAudit(ctx)
+	// Start the cleanup of orphaned timelocks
+	s.StartTimeLockCleanup(ctx)
+	// Start the detection of stuck delegations
+	s.StartStuckDelegationChecker(ctx)
+	// Start maintaining the global stats document
+	s.StartGlobalStatsUpdater(ctx)
+	// Start publishing the BBN processed height and lag metrics
+	s.StartBbnLagMonitor(ctx)
+	// Start publishing the delegation counts by state
+	s.StartDelegationMetricsUpdater(ctx)
+	// Start publishing the finality providers holding the most stake
+	s.StartFpStakeMetricsUpdater(ctx)
+	// Start relaying the recorded queue events, none being recorded in a dry
+	// run
+	if !s.isDryRun() {
+		s.StartOutboxRelay(ctx)
+	}
 	// Start the websocket event subscription process
 	s.SubscribeToBbnEvents(ctx)
 	// Keep processing BBN blocks in the main thread
 	s.StartBbnBlockProcessor(ctx)
 }
 
+// isDryRun returns whether the database writes are logged instead of applied
+func (s *Service) isDryRun() bool {
+	_, ok := s.db.(*db.DryRunDatabase)
+	return ok
+}
+
 func (s *Service) quitContext() (context.Context, func()) {
 	ctx, cancel := context.WithCancel(context.Background())
 	s.wg.Add(1)
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils/poller"
+)
+
+// errBbnBlockProcessorStopped is returned by the BBN block processor once
+// stopped by StopBbnBlockProcessor
+var errBbnBlockProcessorStopped = errors.New("BBN block processor stopped")
+
+// namedPoller is a poller created by newPoller, stopped on shutdown
+type namedPoller struct {
+	name string
+	*poller.Poller
+}
+
+func (s *Service) registerForShutdown(name string, p *poller.Poller) namedPoller {
+	s.pollersMu.Lock()
+	defer s.pollersMu.Unlock()
+	named := namedPoller{name: name, Poller: p}
+	s.pollers = append(s.pollers, named)
+	return named
+}
+
+// StopPollers stops the pollers and the BTC notification watchers from
+// starting new runs, and waits for the runs in flight to complete. If the
+// context is done first, the returned error names the abandoned ones.
+func (s *Service) StopPollers(ctx context.Context) error {
+	s.pollersMu.Lock()
+	pollers := append([]namedPoller{}, s.pollers...)
+	s.pollersMu.Unlock()
+
+	for _, p := range pollers {
+		p.Stop()
+	}
+	s.quitOnce.Do(func() { close(s.quit) })
+
+	var abandoned []string
+	for _, p := range pollers {
+		if !p.Wait(ctx) {
+			abandoned = append(abandoned, p.name)
+		}
+	}
+
+	watchersDone := make(chan struct{})
+	go func() {
+		defer close(watchersDone)
+		s.wg.Wait()
+	}()
+	select {
+	case <-watchersDone:
+	case <-ctx.Done():
+		abandoned = append(abandoned, "btc_notification_watchers")
+	}
+
+	if len(abandoned) > 0 {
+		return fmt.Errorf("abandoned mid run: %s", strings.Join(abandoned, ", "))
+	}
+	return nil
+}
+
+// StopBbnBlockProcessor stops the BBN block processor from starting new
+// blocks, and waits for the block in flight to be processed and committed. If
+// the context is done first, the returned error names the abandoned block,
+// which is processed again from its processing marker on the next startup.
+func (s *Service) StopBbnBlockProcessor(ctx context.Context) error {
+	s.stopBlockProcessorOnce.Do(func() { close(s.blockProcessorStop) })
+	if !s.blockProcessorStarted.Load() {
+		return nil
+	}
+
+	select {
+	case <-s.blockProcessorDone:
+		return nil
+	case <-ctx.Done():
+		if height, ok := s.health.blockInFlight(); ok {
+			return fmt.Errorf(
+				"BBN block %d abandoned mid processing, processed again from its processing marker on the next startup",
+				height,
+			)
+		}
+		return errors.New("BBN block processor abandoned between two blocks")
+	}
+}
+
+// abortBbnBlockProcessor stops the BBN block processor, cancelling the block
+// in flight, and waits for it to return. The writes of the cancelled block are
+// rolled back, the block being processed again from its processing marker.
+func (s *Service) abortBbnBlockProcessor(ctx context.Context) error {
+	s.stopBlockProcessorOnce.Do(func() { close(s.blockProcessorStop) })
+	s.abortBlockProcessorOnce.Do(func() { close(s.blockProcessorAbort) })
+	if !s.blockProcessorStarted.Load() {
+		return nil
+	}
+
+	select {
+	case <-s.blockProcessorDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FlushOutbox relays the outbox events left unsent by the last run of the
+// outbox relay, once it is stopped. Nothing is relayed in a dry run, nor by
+// an instance not holding the leader lease, the relay running on the leader.
+func (s *Service) FlushOutbox(ctx context.Context) error {
+	if s.isDryRun() || !s.IsLeader() {
+		return nil
+	}
+	if err := s.runOutboxRelay(ctx); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("outbox flush abandoned, the unsent events are relayed on the next startup: %w", err)
+		}
+		return err
+	}
+	log.Info().Msg("outbox flushed")
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+// startSlowBlockProcessor starts the block processor over two blocks, the
+// commit of each of them taking the latency, and waits for the first commit
+// to be in flight
+func startSlowBlockProcessor(t *testing.T, latency time.Duration) (*Service, db.DbInterface) {
+	metrics.Init()
+	database := inmemory.New()
+	chaos := db.NewChaosDatabase(database)
+	chaos.Inject("MarkBbnHeightProcessed", db.Fault{Latency: latency})
+	bbnClient := fixtures.NewBbnClient(
+		fixtures.MustLoadBlockResults(fixtures.FpCreated),
+		fixtures.NewBlockResults(),
+	)
+	service := NewService(&config.Config{}, chaos, nil, nil, bbnClient, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		service.StartBbnBlockProcessor(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	service.latestHeightChan <- 2
+	require.Eventually(t, func() bool {
+		return chaos.Calls("MarkBbnHeightProcessed") == 1
+	}, 10*time.Second, time.Millisecond)
+	return service, database
+}
+
+func TestStopBbnBlockProcessorCommitsBlockInFlight(t *testing.T) {
+	service, database := startSlowBlockProcessor(t, 100*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, service.StopBbnBlockProcessor(ctx))
+
+	// The block in flight is committed, the next one is not started
+	lastProcessed, err := database.GetLastProcessedBbnBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), lastProcessed.Height)
+	require.Nil(t, lastProcessed.ProcessingMarker)
+	_, err = database.GetFinalityProviderByBtcPk(context.Background(), fixtures.FpBtcPkHex)
+	require.NoError(t, err)
+}
+
+func TestStopBbnBlockProcessorAbandonsBlockPastGracePeriod(t *testing.T) {
+	service, database := startSlowBlockProcessor(t, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.EqualError(t, service.StopBbnBlockProcessor(ctx),
+		"BBN block 1 abandoned mid processing, processed again from its processing marker on the next startup")
+
+	// The marker of the abandoned block is left for the next startup
+	lastProcessed, err := database.GetLastProcessedBbnBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), lastProcessed.Height)
+	require.NotNil(t, lastProcessed.ProcessingMarker)
+	require.Equal(t, uint64(1), lastProcessed.ProcessingMarker.Height)
+}
+
+func TestStopPollersWaitsForRunInFlight(t *testing.T) {
+	metrics.Init()
+	service := NewService(&config.Config{}, inmemory.New(), nil, nil, nil, nil)
+	started := make(chan struct{}, 1)
+	completed := make(chan struct{})
+	p := service.newPoller("slow", time.Millisecond, func(ctx context.Context) *types.Error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		time.Sleep(50 * time.Millisecond)
+		select {
+		case <-completed:
+		default:
+			close(completed)
+		}
+		return nil
+	})
+	go p.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, service.StopPollers(ctx))
+	select {
+	case <-completed:
+	default:
+		t.Fatal("the run in flight did not complete")
+	}
+
+	// A never started poller does not hold the shutdown
+	service.newPoller("never_started", time.Second, func(ctx context.Context) *types.Error { return nil })
+	require.NoError(t, service.StopPollers(ctx))
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+	"github.com/btcsuite/btcd/wire"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+)
+
+// confirmSlashing follows the slashed funds of a delegation whose slashing tx
+// got confirmed on BTC: the delegation moves to SLASHING_CONFIRMED, and the
+// slashed output of the slashing tx is watched until swept
+func (s *Service) confirmSlashing(
+	ctx context.Context,
+	slashingTx *wire.MsgTx,
+	spendingHeight uint32,
+	delegation *model.BTCDelegationDetails,
+) error {
+	if err := s.advanceSlashedFundsSubState(
+		ctx, delegation.StakingTxHashHex, types.SubStateSlashingConfirmed,
+		slashingTx.TxHash().String(), spendingHeight,
+	); err != nil {
+		return err
+	}
+
+	// Create outpoint for the slashed output (index 0)
+	slashedOutpoint := wire.OutPoint{
+		Hash:  slashingTx.TxHash(),
+		Index: 0, // Slashed output is always first
+	}
+	slashedOutput, err := utils.GetTxOutput(slashingTx, slashedOutpoint.Index)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidSlashingTx, err)
+	}
+	spendEv, err := s.btcNotifier.RegisterSpendNtfn(
+		&slashedOutpoint,
+		slashedOutput.PkScript,
+		delegation.StartHeight,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register spend ntfn for slashed output: %w", err)
+	}
+
+	s.startSpendWatch(func() { s.watchForSpendSlashedOutput(spendEv, delegation) })
+
+	return nil
+}
+
+func (s *Service) watchForSpendSlashedOutput(
+	spendEvent *notifier.SpendEvent,
+	delegation *model.BTCDelegationDetails,
+) {
+	defer s.wg.Done()
+	quitCtx, cancel := s.quitContext()
+	defer cancel()
+	quitCtx = logging.WithStakingTxHash(quitCtx, delegation.StakingTxHashHex)
+
+	select {
+	case spendDetail := <-spendEvent.Spend:
+		quitCtx = withBtcSpendTrigger(quitCtx, spendDetail)
+		logging.FromContext(quitCtx).Debug().
+			Str("spending_tx", spendDetail.SpendingTx.TxHash().String()).
+			Msg("slashed output has been spent")
+		if err := s.advanceSlashedFundsSubState(
+			quitCtx, delegation.StakingTxHashHex, types.SubStateSlashedOutputSwept,
+			spendDetail.SpendingTx.TxHash().String(), uint32(spendDetail.SpendingHeight),
+		); err != nil {
+			logging.FromContext(quitCtx).Error().
+				Err(err).
+				Msg("failed to handle spending slashed output")
+			return
+		}
+
+	case <-s.quit:
+		return
+	case <-quitCtx.Done():
+		return
+	}
+}
+
+// advanceSlashedFundsSubState moves a slashed delegation to the sub state of
+// the slashed funds lifecycle reached by the BTC tx of the given height, and
+// emits its event. Nothing is done for a delegation not slashed yet, the sub
+// state being caught up once the slashing of its finality provider is
+// processed, nor for a delegation already past the sub state.
+func (s *Service) advanceSlashedFundsSubState(
+	ctx context.Context,
+	stakingTxHashHex string,
+	subState types.DelegationSubState,
+	txHashHex string,
+	btcHeight uint32,
+) error {
+	delegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, stakingTxHashHex)
+	if err != nil {
+		return fmt.Errorf("failed to get delegation state: %w", err)
+	}
+	if delegation.State != types.StateSlashed ||
+		!delegation.State.CanAdvanceSubState(delegation.SubState, subState) {
+		logging.FromContext(ctx).Debug().
+			Str("state", delegation.State.String()).
+			Str("current_sub_state", delegation.SubState.String()).
+			Str("sub_state", subState.String()).
+			Msg("skipping slashed funds sub state")
+		return nil
+	}
+
+	if err := s.recordBTCDerivedChange(
+		ctx, model.NewBTCStateChange(delegation.Summary(), uint64(btcHeight)),
+	); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Debug().
+		Str("sub_state", subState.String()).
+		Msg("updating slashed delegation sub state")
+	if err := s.db.UpdateBTCDelegationSubState(
+		ctx, stakingTxHashHex, types.StateSlashed, subState,
+	); err != nil {
+		return fmt.Errorf("failed to update slashed delegation sub state: %w", err)
+	}
+
+	if err := s.recordStateTransition(ctx, model.NewBtcStateTransition(
+		stakingTxHashHex, types.StateSlashed, types.StateSlashed, subState,
+		model.StateTransitionTriggerBtcSpend, uint64(btcHeight), time.Now().Unix(),
+	)); err != nil {
+		return err
+	}
+
+	if err := s.emitSlashedFundsDelegationEvent(
+		ctx, delegation, subState, txHashHex, btcHeight,
+	); err != nil {
+		return err
+	}
+	return nil
+}
+
+// catchUpSlashingConfirmed moves a delegation slashed along with its finality
+// provider to SLASHING_CONFIRMED if its slashing tx was seen on BTC before the
+// slashing got processed
+func (s *Service) catchUpSlashingConfirmed(
+	ctx context.Context, delegation *model.BTCDelegationDetails,
+) *types.Error {
+	slashingTxHex := delegation.SlashingTx.UnbondingSlashingTxHex
+	if slashingTxHex == "" {
+		slashingTxHex = delegation.SlashingTx.SlashingTxHex
+	}
+	if slashingTxHex == "" {
+		return nil
+	}
+
+	slashingTx, err := utils.DeserializeBtcTransactionFromHex(slashingTxHex)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to deserialize slashing tx: %w", err),
+		)
+	}
+	if err := s.advanceSlashedFundsSubState(
+		ctx, delegation.StakingTxHashHex, types.SubStateSlashingConfirmed,
+		slashingTx.TxHash().String(), delegation.SlashingTx.SpendingHeight,
+	); err != nil {
+		return types.NewInternalServiceError(err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/babylonlabs-io/babylon/testutil/datagen"
+	bbn "github.com/babylonlabs-io/babylon/types"
+	ftypes "github.com/babylonlabs-io/babylon/x/finality/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdvanceSlashedFundsSubState(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateSlashed,
+		SubState:         types.SubStateEarlyUnbonding,
+	}
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testReprocessTxHash).Return(delegation, nil)
+	// the previous sub state is restored if the block gets reorged out
+	dbMock.On("SaveBTCDerivedChange", mock.Anything, mock.MatchedBy(func(change *model.BTCDerivedChange) bool {
+		return change.BtcHeight == 120 &&
+			change.PreviousState == types.StateSlashed &&
+			change.PreviousSubState == types.SubStateEarlyUnbonding
+	})).Return(nil).Once()
+	dbMock.On(
+		"UpdateBTCDelegationSubState", mock.Anything, testReprocessTxHash,
+		types.StateSlashed, types.SubStateSlashingConfirmed,
+	).Return(nil).Once()
+	dbMock.On("SaveDelegationStateTransition", mock.Anything, mock.MatchedBy(func(transition *model.DelegationStateTransition) bool {
+		return transition.FromState == types.StateSlashed &&
+			transition.ToState == types.StateSlashed &&
+			transition.SubState == types.SubStateSlashingConfirmed &&
+			transition.Trigger == model.StateTransitionTriggerBtcSpend
+	})).Return(nil).Once()
+	dbMock.On("SaveOutboxEvent", mock.Anything, mock.MatchedBy(func(event *model.OutboxEvent) bool {
+		return event.EventType == model.OutboxEventTypeSlashedFunds &&
+			event.SubState == types.SubStateSlashingConfirmed.String() &&
+			event.SpendingTxHashHex == "slashing-tx" &&
+			event.BtcHeight == 120
+	})).Return(nil).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	require.NoError(t, service.advanceSlashedFundsSubState(
+		ctx, testReprocessTxHash, types.SubStateSlashingConfirmed, "slashing-tx", 120,
+	))
+}
+
+func TestAdvanceSlashedFundsSubStateSkipped(t *testing.T) {
+	testCases := []struct {
+		name     string
+		state    types.DelegationState
+		subState types.DelegationSubState
+	}{
+		// the sub state is caught up once the slashing is processed
+		{"not slashed yet", types.StateActive, ""},
+		{"already swept", types.StateSlashed, types.SubStateSlashedOutputSwept},
+		{"change output withdrawn", types.StateWithdrawn, types.SubStateTimelockSlashing},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// nothing is written for a delegation not at the sub state
+			dbMock := mocks.NewDbInterface(t)
+			dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testReprocessTxHash).Return(
+				&model.BTCDelegationDetails{
+					StakingTxHashHex: testReprocessTxHash,
+					State:            tc.state,
+					SubState:         tc.subState,
+				}, nil,
+			)
+
+			service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+			require.NoError(t, service.advanceSlashedFundsSubState(
+				context.Background(), testReprocessTxHash, types.SubStateSlashingConfirmed, "slashing-tx", 120,
+			))
+		})
+	}
+}
+
+// TestSlashedFinalityProviderLeavesWithdrawnDelegations slashes a finality
+// provider with a withdrawn delegation, which can no longer be slashed
+func TestSlashedFinalityProviderLeavesWithdrawnDelegations(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	fpSk, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	fpBtcPkHex := bbn.NewBIP340PubKeyFromBTCPK(fpSk.PubKey()).MarshalHex()
+	evidence, err := datagen.GenRandomEvidence(rand.New(rand.NewSource(1)), fpSk, 10)
+	require.NoError(t, err)
+
+	database := inmemory.New()
+	for stakingTxHash, state := range map[string]types.DelegationState{
+		"active":    types.StateActive,
+		"withdrawn": types.StateWithdrawn,
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+			StakingTxHashHex:          stakingTxHash,
+			State:                     state,
+			FinalityProviderBtcPksHex: []string{fpBtcPkHex},
+			StartHeight:               100,
+			EndHeight:                 1100,
+		}))
+	}
+	service := NewService(&config.Config{}, database, nil, nil, nil, nil)
+
+	event, err := sdk.TypedEventToEvent(&ftypes.EventSlashedFinalityProvider{Evidence: evidence})
+	require.NoError(t, err)
+	// The event is processed again after a restart
+	for range 2 {
+		require.Nil(t, service.processEvent(ctx, NewBbnEvent(BlockCategory, abcitypes.Event(event)), 20))
+	}
+
+	active, err := database.GetBTCDelegationByStakingTxHash(ctx, "active")
+	require.NoError(t, err)
+	require.Equal(t, types.StateSlashed, active.State)
+	transitions, err := database.GetDelegationStateTransitions(ctx, "active")
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	require.Equal(t, types.StateActive, transitions[0].FromState)
+	require.Equal(t, types.StateSlashed, transitions[0].ToState)
+	events, err := database.GetDelegationOutboxEvents(ctx, "active")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	// Nothing is recorded nor emitted for the withdrawn delegation
+	withdrawn, err := database.GetBTCDelegationByStakingTxHash(ctx, "withdrawn")
+	require.NoError(t, err)
+	require.Equal(t, types.StateWithdrawn, withdrawn.State)
+	transitions, err = database.GetDelegationStateTransitions(ctx, "withdrawn")
+	require.NoError(t, err)
+	require.Empty(t, transitions)
+	events, err = database.GetDelegationOutboxEvents(ctx, "withdrawn")
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
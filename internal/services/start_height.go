@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// Reasons of the BBN height the block processing starts from
+const (
+	// StartHeightResume resumes after the last processed height
+	StartHeightResume = "resume"
+	// StartHeightFresh starts a fresh database from the height recorded in
+	// the bootstrap document, see the StartHeightSource values
+	StartHeightFresh = "fresh"
+	// StartHeightForcedResync processes again from a configured height below
+	// the last processed one
+	StartHeightForcedResync = "forced_resync"
+	// StartHeightGap skips the heights up to a configured height above the
+	// last processed one
+	StartHeightGap = "gap"
+)
+
+// Sources of the start height of a fresh database
+const (
+	// StartHeightSourceConfigured is the configured start height
+	StartHeightSourceConfigured = "configured"
+	// StartHeightSourceActivation is the configured staking activation height
+	StartHeightSourceActivation = "staking_activation"
+	// StartHeightSourceFirstFinalityProvider is the height the first finality
+	// provider registered at, no delegation preceding it
+	StartHeightSourceFirstFinalityProvider = "first_finality_provider"
+	// StartHeightSourceFirstDelegation is the height the first delegation was
+	// created at, under the first params version
+	StartHeightSourceFirstDelegation = "first_delegation"
+	// StartHeightSourceChainTip is the chain tip, no staking having happened
+	// on the chain yet
+	StartHeightSourceChainTip = "chain_tip"
+)
+
+// StartHeightOverrides are the flags allowing the configured start height to
+// move the processing of a database with processed blocks
+type StartHeightOverrides struct {
+	// ResyncHeight resumes a halted BBN block processing after the height,
+	// if set, before the start height is resolved
+	ResyncHeight *uint64
+	// ForceResync processes again from a configured height below the last
+	// processed one
+	ForceResync bool
+	// AllowGap skips the heights up to a configured height above the last
+	// processed one
+	AllowGap bool
+}
+
+// StartHeight is the BBN height the block processing starts from
+type StartHeight struct {
+	// Height is the first BBN height processed
+	Height uint64
+	// LastProcessedHeight is the stored last processed height, zero on a
+	// fresh database
+	LastProcessedHeight uint64
+	Reason              string
+	// Source tells how the start height of a fresh database was decided
+	Source string
+}
+
+// MovesLastProcessedHeight returns whether the stored last processed height
+// must be moved for the processing to start from the height
+func (h *StartHeight) MovesLastProcessedHeight() bool {
+	return h.Height != h.LastProcessedHeight+1
+}
+
+// ResolveStartHeight returns the BBN height the block processing starts from:
+//   - with processed blocks, the processing resumes after the last processed
+//     height. A configured start height below it is ignored unless
+//     ForceResync is set, and one above it is refused unless AllowGap is set.
+//   - on a fresh database, the processing starts from the configured height,
+//     else from the height decided by an earlier startup, else from the
+//     staking activation height, configured or derived from the chain. The
+//     decision is recorded in the bootstrap document.
+//
+// The configured heights are clamped to the staking activation height.
+func (s *Service) ResolveStartHeight(
+	ctx context.Context, overrides StartHeightOverrides,
+) (*StartHeight, *types.Error) {
+	lastProcessed, err := s.db.GetLastProcessedBbnBlock(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get last processed height: %w", err),
+		)
+	}
+
+	activationHeight := max(s.cfg.BBN.StakingActivationHeight, 1)
+	configured := s.cfg.BBN.StartHeight
+	if configured != 0 && configured < activationHeight {
+		log.Warn().
+			Uint64("start_height", configured).
+			Uint64("staking_activation_height", activationHeight).
+			Msg("configured start height below the staking activation height, starting from the activation height")
+		configured = activationHeight
+	}
+
+	// A block under processing at height 1 leaves the height at zero
+	fresh := lastProcessed.Height == 0 && lastProcessed.ProcessingMarker == nil
+	if fresh {
+		return s.resolveFreshStartHeight(ctx, configured)
+	}
+
+	resume := &StartHeight{
+		Height:              lastProcessed.Height + 1,
+		LastProcessedHeight: lastProcessed.Height,
+		Reason:              StartHeightResume,
+	}
+	switch {
+	case configured == 0 || configured == resume.Height:
+		return resume, nil
+
+	case configured < resume.Height:
+		if !overrides.ForceResync {
+			log.Warn().
+				Uint64("start_height", configured).
+				Uint64("last_processed_height", lastProcessed.Height).
+				Msg("configured start height below the last processed height ignored, " +
+					"restart with --force-resync to process the blocks again from it")
+			return resume, nil
+		}
+		log.Warn().
+			Uint64("start_height", configured).
+			Uint64("last_processed_height", lastProcessed.Height).
+			Uint64("blocks", resume.Height-configured).
+			Msg("forced resync: processing the BBN blocks again from the configured start height")
+		return &StartHeight{
+			Height:              configured,
+			LastProcessedHeight: lastProcessed.Height,
+			Reason:              StartHeightForcedResync,
+		}, nil
+
+	default:
+		gap := configured - resume.Height
+		if !overrides.AllowGap {
+			return nil, types.NewValidationFailedError(fmt.Errorf(
+				"configured start height %d is above the last processed height %d, "+
+					"skipping %d blocks; restart with --allow-gap to skip them",
+				configured, lastProcessed.Height, gap,
+			))
+		}
+		log.Warn().
+			Uint64("start_height", configured).
+			Uint64("last_processed_height", lastProcessed.Height).
+			Uint64("skipped_blocks", gap).
+			Msg("GAP IN THE PROCESSED BBN HEIGHTS: skipping the blocks up to the configured start height, " +
+				"their events are never applied")
+		return &StartHeight{
+			Height:              configured,
+			LastProcessedHeight: lastProcessed.Height,
+			Reason:              StartHeightGap,
+		}, nil
+	}
+}
+
+// applyStartHeight resyncs the BBN block processing if requested, and moves
+// the last processed height for the processing to start from the resolved
+// start height. It is applied by the leader only, once elected, for a standby
+// replica not to move the height under the processing of the leader.
+func (s *Service) applyStartHeight(ctx context.Context, overrides StartHeightOverrides) {
+	if overrides.ResyncHeight != nil {
+		if err := s.db.ResyncLastProcessedBbnHeight(ctx, *overrides.ResyncHeight); err != nil {
+			log.Fatal().Err(err).Msg("error while resyncing last processed BBN height")
+		}
+		log.Info().Uint64("height", *overrides.ResyncHeight).Msg("resynced BBN block processing")
+	}
+
+	startHeight, err := s.ResolveStartHeight(ctx, overrides)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error while resolving the BBN start height")
+	}
+	if startHeight.MovesLastProcessedHeight() {
+		if err := s.db.ResyncLastProcessedBbnHeight(ctx, startHeight.Height-1); err != nil {
+			log.Fatal().Err(err).Msg("error while moving the last processed BBN height to the start height")
+		}
+	}
+	log.Info().
+		Uint64("start_height", startHeight.Height).
+		Str("reason", startHeight.Reason).
+		Str("source", startHeight.Source).
+		Msg("resolved the BBN start height")
+}
+
+// resolveFreshStartHeight decides the start height of a fresh database and
+// records it in the bootstrap document, for the restarts before the first
+// processed block not to decide differently. A configured start height
+// always wins, with a warning if it skips staking history.
+func (s *Service) resolveFreshStartHeight(ctx context.Context, configured uint64) (*StartHeight, *types.Error) {
+	bootstrap, err := s.db.GetBootstrap(ctx)
+	if err != nil {
+		if !db.IsNotFoundError(err) {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to get bootstrap progress: %w", err),
+			)
+		}
+		bootstrap = &model.Bootstrap{StartedAt: time.Now().Unix()}
+	}
+
+	start := &StartHeight{Reason: StartHeightFresh}
+	switch {
+	case configured != 0:
+		start.Height, start.Source = configured, StartHeightSourceConfigured
+		s.warnIfStartSkipsHistory(ctx, configured)
+
+	case bootstrap.StartHeight != 0:
+		start.Height, start.Source = bootstrap.StartHeight, bootstrap.StartHeightSource
+		log.Info().
+			Uint64("start_height", start.Height).
+			Str("source", start.Source).
+			Msg("starting the BBN block processing of a fresh database from the recorded start height")
+		return start, nil
+
+	case s.cfg.BBN.StakingActivationHeight != 0:
+		start.Height, start.Source = s.cfg.BBN.StakingActivationHeight, StartHeightSourceActivation
+
+	default:
+		height, source, err := s.chainStartHeight(ctx)
+		if err != nil {
+			return nil, types.NewInternalServiceError(fmt.Errorf(
+				"failed to derive the start height from the BBN chain, set bbn.start-height to start from: %w", err,
+			))
+		}
+		start.Height, start.Source = height, source
+	}
+
+	bootstrap.StartHeight, bootstrap.StartHeightSource = start.Height, start.Source
+	if err := s.saveBootstrap(ctx, bootstrap); err != nil {
+		return nil, err
+	}
+	log.Info().
+		Uint64("start_height", start.Height).
+		Str("source", start.Source).
+		Msg("decided the start height of a fresh database, recorded for the next startups")
+	return start, nil
+}
+
+// warnIfStartSkipsHistory warns if the configured start height of a fresh
+// database is above the staking activation height, configured or else
+// derived from the chain, the staking history below it being never indexed
+func (s *Service) warnIfStartSkipsHistory(ctx context.Context, configured uint64) {
+	activation, source := s.cfg.BBN.StakingActivationHeight, StartHeightSourceActivation
+	if activation == 0 {
+		var err error
+		activation, source, err = s.chainStartHeight(ctx)
+		if err != nil {
+			log.Warn().Err(err).
+				Uint64("start_height", configured).
+				Msg("failed to derive the staking activation height from the BBN chain, " +
+					"the configured start height is not checked against it")
+			return
+		}
+	}
+	if source == StartHeightSourceChainTip || configured <= activation {
+		return
+	}
+	log.Warn().
+		Uint64("start_height", configured).
+		Uint64("staking_activation_height", activation).
+		Str("activation_source", source).
+		Uint64("skipped_blocks", configured-activation).
+		Msg("configured start height above the staking activation height, " +
+			"the staking history below it is never indexed")
+}
+
+// chainStartHeight derives the staking activation height from the BBN chain:
+// the earlier of the heights the first finality provider registered at and
+// the first delegation was created at, under the first params version.
+// Without any yet, it is the chain tip, nothing below it being staking
+// history. The node must index the searched events.
+func (s *Service) chainStartHeight(ctx context.Context) (uint64, string, error) {
+	// the tip is read first, any event below it being found by the searches
+	tip, err := s.bbn.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get the latest BBN height: %w", err)
+	}
+	fpHeight, err := s.bbn.SearchFirstEventHeight(
+		ctx, fmt.Sprintf("%s.btc_pk_hex EXISTS", EventFinalityProviderCreatedType),
+	)
+	if err != nil {
+		return 0, "", err
+	}
+	delegationHeight, err := s.bbn.SearchFirstEventHeight(
+		ctx, fmt.Sprintf("%s.staking_tx_hex EXISTS", EventBTCDelegationCreated),
+	)
+	if err != nil {
+		return 0, "", err
+	}
+
+	switch {
+	case fpHeight == 0 && delegationHeight == 0:
+		return uint64(max(tip, 1)), StartHeightSourceChainTip, nil
+	case fpHeight != 0 && (delegationHeight == 0 || fpHeight <= delegationHeight):
+		return uint64(fpHeight), StartHeightSourceFirstFinalityProvider, nil
+	default:
+		return uint64(delegationHeight), StartHeightSourceFirstDelegation, nil
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+func TestResolveStartHeight(t *testing.T) {
+	tests := []struct {
+		name string
+		// lastProcessed is the stored last processed height, none if zero
+		lastProcessed    uint64
+		midBlock         bool
+		startHeight      uint64
+		activationHeight uint64
+		overrides        StartHeightOverrides
+		expected         *StartHeight
+		expectedErr      string
+	}{
+		{
+			name:     "fresh database of a chain without staking",
+			expected: &StartHeight{Height: 1, Reason: StartHeightFresh, Source: StartHeightSourceChainTip},
+		},
+		{
+			name:             "fresh database from the activation height",
+			activationHeight: 50,
+			expected:         &StartHeight{Height: 50, Reason: StartHeightFresh, Source: StartHeightSourceActivation},
+		},
+		{
+			name:             "fresh database from the configured height",
+			startHeight:      100,
+			activationHeight: 50,
+			expected:         &StartHeight{Height: 100, Reason: StartHeightFresh, Source: StartHeightSourceConfigured},
+		},
+		{
+			name:             "fresh database from a configured height below the activation",
+			startHeight:      10,
+			activationHeight: 50,
+			expected:         &StartHeight{Height: 50, Reason: StartHeightFresh, Source: StartHeightSourceConfigured},
+		},
+		{
+			name:     "database crashed in the first block",
+			midBlock: true,
+			expected: &StartHeight{Height: 1, Reason: StartHeightResume},
+		},
+		{
+			name:          "resume",
+			lastProcessed: 200,
+			expected:      &StartHeight{Height: 201, LastProcessedHeight: 200, Reason: StartHeightResume},
+		},
+		{
+			name:          "resume from the configured height",
+			lastProcessed: 200,
+			startHeight:   201,
+			expected:      &StartHeight{Height: 201, LastProcessedHeight: 200, Reason: StartHeightResume},
+		},
+		{
+			name:          "configured height below the last processed one",
+			lastProcessed: 200,
+			startHeight:   100,
+			expected:      &StartHeight{Height: 201, LastProcessedHeight: 200, Reason: StartHeightResume},
+		},
+		{
+			name:          "configured height equal to the last processed one",
+			lastProcessed: 200,
+			startHeight:   200,
+			expected:      &StartHeight{Height: 201, LastProcessedHeight: 200, Reason: StartHeightResume},
+		},
+		{
+			name:          "forced resync",
+			lastProcessed: 200,
+			startHeight:   100,
+			overrides:     StartHeightOverrides{ForceResync: true},
+			expected:      &StartHeight{Height: 100, LastProcessedHeight: 200, Reason: StartHeightForcedResync},
+		},
+		{
+			name:             "forced resync below the activation height",
+			lastProcessed:    200,
+			startHeight:      10,
+			activationHeight: 50,
+			overrides:        StartHeightOverrides{ForceResync: true},
+			expected:         &StartHeight{Height: 50, LastProcessedHeight: 200, Reason: StartHeightForcedResync},
+		},
+		{
+			name:          "configured height above the last processed one",
+			lastProcessed: 200,
+			startHeight:   300,
+			overrides:     StartHeightOverrides{ForceResync: true},
+			expectedErr:   "configured start height 300 is above the last processed height 200, skipping 99 blocks; restart with --allow-gap to skip them",
+		},
+		{
+			name:          "allowed gap",
+			lastProcessed: 200,
+			startHeight:   300,
+			overrides:     StartHeightOverrides{AllowGap: true},
+			expected:      &StartHeight{Height: 300, LastProcessedHeight: 200, Reason: StartHeightGap},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			database := inmemory.New()
+			if tt.lastProcessed != 0 {
+				require.NoError(t, database.UpdateLastProcessedBbnHeight(ctx, tt.lastProcessed, "hash"))
+			}
+			if tt.midBlock {
+				require.NoError(t, database.StartBbnBlockProcessing(ctx, model.NewBbnProcessingMarker(1, "hash", 1)))
+			}
+			service := NewService(&config.Config{BBN: config.BBNConfig{
+				StartHeight:             tt.startHeight,
+				StakingActivationHeight: tt.activationHeight,
+			}}, database, nil, nil, fixtures.NewBbnClient(), nil)
+
+			start, err := service.ResolveStartHeight(ctx, tt.overrides)
+			if tt.expectedErr != "" {
+				require.NotNil(t, err)
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, start)
+			require.Equal(t, tt.expected.Height != tt.expected.LastProcessedHeight+1, start.MovesLastProcessedHeight())
+		})
+	}
+}
+
+func TestResolveFreshStartHeightFromChain(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := inmemory.New()
+	// The first finality provider registers at height 3, the first
+	// delegation is created at height 4
+	bbnClient := fixtures.NewBbnClient(
+		fixtures.NewBlockResults(),
+		fixtures.NewBlockResults(),
+		fixtures.MustLoadBlockResults(fixtures.FpCreated),
+		fixtures.NewBlockResults(fixtures.NewDelegation(1, 1).CreatedEvent()),
+	)
+	service := NewService(&config.Config{}, database, nil, nil, bbnClient, nil)
+
+	start, err := service.ResolveStartHeight(ctx, StartHeightOverrides{})
+	require.Nil(t, err)
+	require.Equal(t, &StartHeight{Height: 3, Reason: StartHeightFresh, Source: StartHeightSourceFirstFinalityProvider}, start)
+	bootstrap, dbErr := database.GetBootstrap(ctx)
+	require.NoError(t, dbErr)
+	require.Equal(t, uint64(3), bootstrap.StartHeight)
+	require.Equal(t, StartHeightSourceFirstFinalityProvider, bootstrap.StartHeightSource)
+
+	// A restart before the first processed block reuses the recorded height,
+	// whatever the chain now tells
+	restarted := NewService(&config.Config{}, database, nil, nil, fixtures.NewBbnClient(), nil)
+	start, err = restarted.ResolveStartHeight(ctx, StartHeightOverrides{})
+	require.Nil(t, err)
+	require.Equal(t, &StartHeight{Height: 3, Reason: StartHeightFresh, Source: StartHeightSourceFirstFinalityProvider}, start)
+
+	// A configured height wins over the recorded one, even if skipping
+	// history, and is recorded in turn
+	configured := NewService(&config.Config{BBN: config.BBNConfig{StartHeight: 4}}, database, nil, nil, bbnClient, nil)
+	start, err = configured.ResolveStartHeight(ctx, StartHeightOverrides{})
+	require.Nil(t, err)
+	require.Equal(t, &StartHeight{Height: 4, Reason: StartHeightFresh, Source: StartHeightSourceConfigured}, start)
+	bootstrap, dbErr = database.GetBootstrap(ctx)
+	require.NoError(t, dbErr)
+	require.Equal(t, uint64(4), bootstrap.StartHeight)
+}
+
+func TestResolveFreshStartHeightFromFirstDelegation(t *testing.T) {
+	metrics.Init()
+	bbnClient := fixtures.NewBbnClient(
+		fixtures.NewBlockResults(),
+		fixtures.NewBlockResults(fixtures.NewDelegation(1, 1).CreatedEvent()),
+		fixtures.MustLoadBlockResults(fixtures.FpCreated),
+	)
+	service := NewService(&config.Config{}, inmemory.New(), nil, nil, bbnClient, nil)
+
+	start, err := service.ResolveStartHeight(context.Background(), StartHeightOverrides{})
+	require.Nil(t, err)
+	require.Equal(t, &StartHeight{Height: 2, Reason: StartHeightFresh, Source: StartHeightSourceFirstDelegation}, start)
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+// The race tests fire concurrent transitions at the same delegations, the
+// races being decided by the scheduling, and are meant to run with -race.
+// The races below are accepted, every order of the writes leading to a legal
+// final state and a state history whose transitions chain up:
+//   - the expiry of an early unbonding against the withdrawal of the
+//     unbonding output, the delegation being withdrawn with or without going
+//     through withdrawable
+//   - the expiry of an early unbonding against the BBN unbonding event of a
+//     block processed late, the withdrawable event being possibly emitted
+//     before the unbonding one
+//
+// They run against the in-memory database, and against the Mongo of
+// INTEGRATION_MONGO_ADDRESS if set.
+
+// raceDelegations is the number of delegations raced at once, so that the
+// writes interleave in various orders
+const raceDelegations = 20
+
+// raceTestDatabase is a database the race tests run against
+type raceTestDatabase struct {
+	name string
+	new  func(t *testing.T) db.DbInterface
+}
+
+func raceTestDatabases() []raceTestDatabase {
+	return []raceTestDatabase{
+		{name: "inmemory", new: func(t *testing.T) db.DbInterface { return inmemory.New() }},
+		{name: "mongo", new: newRaceMongoDatabase},
+	}
+}
+
+// newRaceMongoDatabase returns a database of the Mongo of
+// INTEGRATION_MONGO_ADDRESS, with its indexes and dropped once the test is
+// done, skipping the test if unset
+func newRaceMongoDatabase(t *testing.T) db.DbInterface {
+	address := os.Getenv("INTEGRATION_MONGO_ADDRESS")
+	if address == "" {
+		t.Skip("INTEGRATION_MONGO_ADDRESS is not set")
+	}
+	ctx := context.Background()
+	cfg := &config.Config{Db: config.DbConfig{
+		Address:  address,
+		Username: os.Getenv("INTEGRATION_MONGO_USERNAME"),
+		Password: os.Getenv("INTEGRATION_MONGO_PASSWORD"),
+		DbName:   "indexer-race",
+	}}
+	require.NoError(t, model.Setup(ctx, cfg))
+	database, err := db.New(ctx, cfg.Db)
+	require.NoError(t, err)
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(address).SetAuth(options.Credential{
+		Username: cfg.Db.Username,
+		Password: cfg.Db.Password,
+	}))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Database(cfg.Db.DbName).Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return database
+}
+
+// interleavingDatabase delays the state updates by a random moment, for the
+// concurrent transitions to interleave between their reads and their writes
+type interleavingDatabase struct {
+	db.DbInterface
+}
+
+func (d interleavingDatabase) UpdateBTCDelegationState(
+	ctx context.Context,
+	stakingTxHash string,
+	qualifiedPreviousStates []types.DelegationState,
+	newState types.DelegationState,
+	newSubState *types.DelegationSubState,
+) error {
+	time.Sleep(time.Duration(rand.Int63n(int64(time.Millisecond))))
+	return d.DbInterface.UpdateBTCDelegationState(
+		ctx, stakingTxHash, qualifiedPreviousStates, newState, newSubState,
+	)
+}
+
+// newRaceService returns the service of the race tests over the database,
+// the BTC tip being at the height
+func newRaceService(database db.DbInterface, btcTipHeight int32) *Service {
+	metrics.Init()
+	service := newSpendPathService()
+	service.db = interleavingDatabase{database}
+	service.btc = fixtures.NewBtcChain(0, btcTipHeight)
+	service.cfg.Poller.ExpiredDelegationsLimit = raceDelegations
+	return service
+}
+
+// saveRaceDelegations saves the fixture delegations of the race tests in the
+// state, along with their staking params
+func saveRaceDelegations(
+	t *testing.T, database db.DbInterface, state types.DelegationState, subState types.DelegationSubState,
+) []*fixtures.Delegation {
+	ctx := context.Background()
+	delegations := make([]*fixtures.Delegation, raceDelegations)
+	for i := range delegations {
+		delegations[i] = fixtures.NewDelegation(i+1, 1)
+		details := fixtureDelegationDetails(t, delegations[i])
+		details.State = state
+		details.SubState = subState
+		details.StartHeight = 100
+		details.EndHeight = 100 + uint32(fixtures.StakingTime)
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, details))
+	}
+	require.NoError(t, database.SaveStakingParams(ctx, fixtures.StakingParamsVersion, fixtures.NewStakingParams()))
+	return delegations
+}
+
+// race runs the functions at once, each of them once per delegation
+func race(delegations []*fixtures.Delegation, fns ...func(d *fixtures.Delegation)) {
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, d := range delegations {
+		for _, fn := range fns {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				fn(d)
+			}()
+		}
+	}
+	close(start)
+	wg.Wait()
+}
+
+// requireStateHistory checks that the delegation ends in the state and sub
+// state, and that its recorded transitions chain up from the state to one of
+// the legal sequences of states reached
+func requireStateHistory(
+	t *testing.T,
+	database db.DbInterface,
+	d *fixtures.Delegation,
+	from types.DelegationState,
+	state types.DelegationState,
+	subState types.DelegationSubState,
+	legalSequences ...[]types.DelegationState,
+) {
+	ctx := context.Background()
+	stored, err := database.GetBTCDelegationByStakingTxHash(ctx, d.StakingTxHashHex())
+	require.NoError(t, err)
+	require.Equal(t, state, stored.State)
+	require.Equal(t, subState, stored.SubState)
+
+	transitions, err := database.GetDelegationStateTransitions(ctx, d.StakingTxHashHex())
+	require.NoError(t, err)
+	history := make([]string, len(transitions))
+	for i, transition := range transitions {
+		history[i] = transition.FromState.String() + "->" + transition.ToState.String()
+	}
+	var reached []types.DelegationState
+	for _, transition := range transitions {
+		require.Equal(t, from, transition.FromState, "transitions not chaining up: %v", history)
+		require.True(t, transition.FromState.CanTransitionTo(transition.ToState))
+		reached = append(reached, transition.ToState)
+		from = transition.ToState
+	}
+	require.Contains(t, legalSequences, reached)
+}
+
+// TestRaceExpiryAgainstUnbondingWithdrawal races the expiry of unbonded
+// delegations with the withdrawal of their unbonding output
+func TestRaceExpiryAgainstUnbondingWithdrawal(t *testing.T) {
+	for _, database := range raceTestDatabases() {
+		t.Run(database.name, func(t *testing.T) {
+			ctx := context.Background()
+			dbClient := database.new(t)
+			delegations := saveRaceDelegations(t, dbClient, types.StateUnbonding, types.SubStateEarlyUnbonding)
+			for _, d := range delegations {
+				require.NoError(t, dbClient.SaveNewTimeLockExpire(
+					ctx, d.StakingTxHashHex(), 110, types.SubStateEarlyUnbonding,
+				))
+			}
+			service := newRaceService(dbClient, 120)
+
+			var checks sync.Mutex
+			race(delegations,
+				func(*fixtures.Delegation) {
+					// A run expires every delegation left unbonding
+					checks.Lock()
+					defer checks.Unlock()
+					assert.Nil(t, service.checkExpiry(ctx))
+				},
+				func(d *fixtures.Delegation) {
+					details, err := dbClient.GetBTCDelegationByStakingTxHash(ctx, d.StakingTxHashHex())
+					if !assert.NoError(t, err) {
+						return
+					}
+					assert.NoError(t, service.handleSpendingUnbondingTransaction(
+						ctx, d.UnbondingWithdrawalTx, 121, 0, details,
+					))
+				},
+			)
+
+			for _, d := range delegations {
+				requireStateHistory(t, dbClient, d, types.StateUnbonding,
+					types.StateWithdrawn, types.SubStateEarlyUnbonding,
+					[]types.DelegationState{types.StateWithdrawn},
+					[]types.DelegationState{types.StateWithdrawable, types.StateWithdrawn},
+				)
+			}
+		})
+	}
+}
+
+// TestRaceExpiryAgainstUnbondedEarlyEvent races the expiry checker with the
+// BBN unbonding events of active delegations, processed once their unbonding
+// timelock expired already
+func TestRaceExpiryAgainstUnbondedEarlyEvent(t *testing.T) {
+	for _, database := range raceTestDatabases() {
+		t.Run(database.name, func(t *testing.T) {
+			ctx := context.Background()
+			dbClient := database.new(t)
+			delegations := saveRaceDelegations(t, dbClient, types.StateActive, "")
+			service := newRaceService(dbClient, 300)
+
+			var checks sync.Mutex
+			race(delegations,
+				func(*fixtures.Delegation) {
+					checks.Lock()
+					defer checks.Unlock()
+					assert.Nil(t, service.checkExpiry(ctx))
+				},
+				func(d *fixtures.Delegation) {
+					event, err := sdk.TypedEventToEvent(d.UnbondedEarlyEvent(101))
+					if !assert.NoError(t, err) {
+						return
+					}
+					assert.Nil(t, service.processBTCDelegationUnbondedEarlyEvent(ctx, abcitypes.Event(event), 10))
+				},
+			)
+			// The delegations unbonded after the last run expire on the next one
+			require.Nil(t, service.checkExpiry(ctx))
+
+			for _, d := range delegations {
+				requireStateHistory(t, dbClient, d, types.StateActive,
+					types.StateWithdrawable, types.SubStateEarlyUnbonding,
+					[]types.DelegationState{types.StateUnbonding, types.StateWithdrawable},
+				)
+			}
+		})
+	}
+}
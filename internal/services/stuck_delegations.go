@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+func (s *Service) StartStuckDelegationChecker(ctx context.Context) {
+	stuckDelegationPoller := s.newPoller(
+		"stuck_delegation_checker",
+		s.cfg.Poller.StuckDelegationCheckerInterval,
+		s.checkStuckDelegations,
+	)
+	s.startPoller(ctx, stuckDelegationPoller)
+}
+
+// checkStuckDelegations looks for delegations that stayed in a state longer
+// than the threshold configured for that state, publishes their count by state
+// and saves a report listing the longest stuck ones.
+func (s *Service) checkStuckDelegations(ctx context.Context) *types.Error {
+	now := time.Now()
+	limit := s.cfg.Poller.StuckDelegationsReportLimit
+	report := &model.StuckDelegationReport{
+		CreatedAt: now.Unix(),
+		Counts:    make(map[string]uint64),
+	}
+
+	for state, threshold := range s.cfg.Poller.GetStuckDelegationThresholds() {
+		before := now.Add(-threshold).Unix()
+
+		count, err := s.db.CountStuckDelegations(ctx, state, before)
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to count stuck delegations in state %s: %w", state, err),
+			)
+		}
+		metrics.RecordStuckDelegations(state.String(), count)
+		if count == 0 {
+			continue
+		}
+		report.Counts[state.String()] = count
+
+		delegations, err := s.db.FindStuckDelegations(ctx, state, before, limit)
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to find stuck delegations in state %s: %w", state, err),
+			)
+		}
+		for _, delegation := range delegations {
+			report.Offenders = append(report.Offenders, &model.StuckDelegation{
+				StakingTxHashHex: delegation.StakingTxHashHex,
+				State:            delegation.State.String(),
+				StateSince:       delegation.StateSince(),
+				StuckForSeconds:  now.Unix() - delegation.StateSince(),
+			})
+		}
+	}
+
+	if len(report.Counts) == 0 {
+		return nil
+	}
+
+	// Keep the longest stuck delegations across all states
+	sort.Slice(report.Offenders, func(i, j int) bool {
+		return report.Offenders[i].StuckForSeconds > report.Offenders[j].StuckForSeconds
+	})
+	if uint64(len(report.Offenders)) > limit {
+		report.Offenders = report.Offenders[:limit]
+	}
+
+	log.Warn().
+		Interface("stuck_delegations", report.Counts).
+		Msg("found delegations stuck in a state longer than its threshold")
+
+	if err := s.db.SaveStuckDelegationReport(ctx, report); err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to save stuck delegation report: %w", err),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// stuckReportDb records the stuck delegation reports saved
+type stuckReportDb struct {
+	db.DbInterface
+	reports []*model.StuckDelegationReport
+}
+
+func (d *stuckReportDb) SaveStuckDelegationReport(
+	ctx context.Context, report *model.StuckDelegationReport,
+) error {
+	d.reports = append(d.reports, report)
+	return d.DbInterface.SaveStuckDelegationReport(ctx, report)
+}
+
+func TestCheckStuckDelegations(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := &stuckReportDb{DbInterface: inmemory.New()}
+
+	// The delegations are placed a few seconds around the threshold, for the
+	// check not to depend on the second it runs in
+	threshold := time.Hour
+	now := time.Now().Unix()
+	overThreshold := now - int64(threshold.Seconds()) - 5
+	underThreshold := now - int64(threshold.Seconds()) + 5
+	for _, delegation := range []*model.BTCDelegationDetails{
+		{StakingTxHashHex: "pending-stuck", State: types.StatePending, StateUpdatedAt: overThreshold},
+		{StakingTxHashHex: "pending-longest-stuck", State: types.StatePending, StateUpdatedAt: overThreshold - 60},
+		{StakingTxHashHex: "pending-not-stuck-yet", State: types.StatePending, StateUpdatedAt: underThreshold},
+		{
+			StakingTxHashHex: "verified-stuck-since-creation",
+			State:            types.StateVerified,
+			BTCDelegationCreatedBlock: model.BTCDelegationCreatedBbnBlock{
+				Timestamp: overThreshold,
+			},
+		},
+		// no threshold configured for the state
+		{StakingTxHashHex: "active", State: types.StateActive, StateUpdatedAt: overThreshold},
+	} {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, delegation))
+	}
+
+	cfg := &config.Config{Poller: config.PollerConfig{
+		StuckDelegationThresholds: map[string]time.Duration{
+			"pending":   threshold,
+			"verified":  threshold,
+			"unbonding": threshold,
+		},
+		StuckDelegationsReportLimit: 2,
+	}}
+	service := NewService(cfg, database, nil, nil, nil, nil)
+	require.Nil(t, service.checkStuckDelegations(ctx))
+
+	require.Len(t, database.reports, 1)
+	report := database.reports[0]
+	require.Equal(t, map[string]uint64{
+		types.StatePending.String():  2,
+		types.StateVerified.String(): 1,
+	}, report.Counts)
+	// Only the longest stuck delegations are listed
+	require.Len(t, report.Offenders, 2)
+	require.Equal(t, "pending-longest-stuck", report.Offenders[0].StakingTxHashHex)
+	require.Equal(t, overThreshold-60, report.Offenders[0].StateSince)
+	require.GreaterOrEqual(t, report.Offenders[0].StuckForSeconds, int64(threshold.Seconds())+65)
+	require.Contains(t,
+		[]string{"pending-stuck", "verified-stuck-since-creation"}, report.Offenders[1].StakingTxHashHex,
+	)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(), `stuck_delegations{state="PENDING"} 2`)
+	require.Contains(t, rec.Body.String(), `stuck_delegations{state="VERIFIED"} 1`)
+	require.Contains(t, rec.Body.String(), `stuck_delegations{state="UNBONDING"} 0`)
+	require.NotContains(t, rec.Body.String(), `stuck_delegations{state="ACTIVE"}`)
+}
+
+func TestCheckStuckDelegationsNoneStuck(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := &stuckReportDb{DbInterface: inmemory.New()}
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+		StakingTxHashHex: "pending", State: types.StatePending, StateUpdatedAt: time.Now().Unix(),
+	}))
+
+	cfg := &config.Config{Poller: config.PollerConfig{
+		StuckDelegationThresholds:   map[string]time.Duration{"pending": time.Hour},
+		StuckDelegationsReportLimit: 10,
+	}}
+	service := NewService(cfg, database, nil, nil, nil, nil)
+	require.Nil(t, service.checkStuckDelegations(ctx))
+
+	// No report is saved, the count is reset
+	require.Empty(t, database.reports)
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rec.Body.String(), `stuck_delegations{state="PENDING"} 0`)
+}
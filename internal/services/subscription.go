@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	ctypes "github.com/cometbft/cometbft/types"
@@ -13,54 +15,74 @@ const (
 	newBlockQuery  = "tm.event='NewBlock'"
 )
 
+// SubscribeToBbnEvents listens to the new BBN blocks under the supervisor,
+// subscribing again once the listener failed
 func (s *Service) SubscribeToBbnEvents(ctx context.Context) {
 	if !s.bbn.IsRunning() {
 		log.Fatal().Msg("BBN client is not running")
 	}
+	s.supervisor.Go(ctx, "bbn_new_blocks", listenerPolicy, s.listenBbnNewBlocks)
+}
 
+// listenBbnNewBlocks subscribes to the new BBN blocks and sends their heights
+// to the BBN block processor until the context is done, unsubscribing on
+// return
+func (s *Service) listenBbnNewBlocks(ctx context.Context) error {
 	eventChan, err := s.bbn.Subscribe(subscriberName, newBlockQuery)
 	if err != nil {
-		log.Fatal().Msgf("Failed to subscribe to events: %v", err)
+		return fmt.Errorf("failed to subscribe to events: %w", err)
 	}
+	defer func() {
+		if err := s.bbn.UnsubscribeAll(subscriberName); err != nil {
+			log.Error().Msgf("Failed to unsubscribe from events: %v", err)
+		}
+	}()
 
-	go func() {
-		for {
-			select {
-			case event := <-eventChan:
-				newBlockEvent, ok := event.Data.(ctypes.EventDataNewBlock)
-				if !ok {
-					log.Fatal().Msg("Event is not a NewBlock event")
-				}
-
-				latestHeight := newBlockEvent.Block.Height
-				if latestHeight == 0 {
-					log.Fatal().Msg("Event doesn't contain block height information")
-				}
+	for {
+		select {
+		case event, ok := <-eventChan:
+			if !ok {
+				return errors.New("BBN event subscription closed")
+			}
+			newBlockEvent, ok := event.Data.(ctypes.EventDataNewBlock)
+			if !ok {
+				return fmt.Errorf("event is not a NewBlock event: %T", event.Data)
+			}
 
-				// Send the latest height to the BBN block processor
-				s.latestHeightChan <- latestHeight
+			latestHeight := newBlockEvent.Block.Height
+			if latestHeight == 0 {
+				return errors.New("event doesn't contain block height information")
+			}
 
+			s.bbnTip.observe(latestHeight)
+			// Send the latest height to the BBN block processor
+			select {
+			case s.latestHeightChan <- latestHeight:
 			case <-ctx.Done():
-				err := s.bbn.UnsubscribeAll(subscriberName)
-				if err != nil {
-					log.Error().Msgf("Failed to unsubscribe from events: %v", err)
-				}
-				return
+				return nil
 			}
+
+		case <-ctx.Done():
+			return nil
 		}
-	}()
+	}
 }
 
-// Resubscribe to missed BTC notifications
+// Resubscribe to missed BTC notifications under the supervisor, the
+// delegations registered before a failure being skipped on restart
 func (s *Service) ResubscribeToMissedBtcNotifications(ctx context.Context) {
-	go func() {
+	registered := make(map[string]bool)
+	s.supervisor.Go(ctx, "btc_notification_resubscription", listenerPolicy, func(ctx context.Context) error {
 		log.Info().Msg("Resubscribing to missed BTC notifications")
 		delegations, err := s.db.GetBTCDelegationsByStates(ctx, []types.DelegationState{types.StateUnbonding, types.StateSlashed})
 		if err != nil {
-			log.Fatal().Msgf("Failed to get BTC delegations: %v", err)
+			return fmt.Errorf("failed to get BTC delegations: %w", err)
 		}
 
 		for _, delegation := range delegations {
+			if registered[delegation.StakingTxHashHex] {
+				continue
+			}
 			// Register spend notification
 			if err := s.registerStakingSpendNotification(
 				ctx,
@@ -69,8 +91,10 @@ func (s *Service) ResubscribeToMissedBtcNotifications(ctx context.Context) {
 				delegation.StakingOutputIdx,
 				delegation.StartHeight,
 			); err != nil {
-				log.Fatal().Msgf("Failed to register spend notification: %v", err)
+				return fmt.Errorf("failed to register spend notification: %w", err)
 			}
+			registered[delegation.StakingTxHashHex] = true
 		}
-	}()
+		return nil
+	})
 }
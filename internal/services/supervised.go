@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/supervisor"
+)
+
+var (
+	// pollerPolicy restarts a poller whose loop failed, its runs recovering
+	// their own panics
+	pollerPolicy = supervisor.WithBackoff(time.Second, time.Minute)
+	// listenerPolicy restarts the listeners of the BBN and BTC notifications,
+	// exiting the process once they keep failing, as the indexing stalls
+	// without them
+	listenerPolicy = supervisor.WithBackoff(time.Second, time.Minute).GiveUpAfter(10)
+)
+
+// startPoller runs the poller under the supervisor, until stopped or its
+// context is done
+func (s *Service) startPoller(ctx context.Context, p namedPoller) {
+	s.supervisor.Go(ctx, p.name, pollerPolicy, func(ctx context.Context) error {
+		p.Start(ctx)
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// missingDelegationState labels the timelock documents whose delegation does
+// not exist in the cleanup report
+const missingDelegationState = "MISSING"
+
+func (s *Service) StartTimeLockCleanup(ctx context.Context) {
+	timeLockCleanupPoller := s.newPoller(
+		"timelock_cleanup",
+		s.cfg.Poller.TimeLockCleanupInterval,
+		func(ctx context.Context) *types.Error {
+			_, err := s.CleanupOrphanedTimeLocks(ctx)
+			return err
+		},
+	)
+	s.startPoller(ctx, timeLockCleanupPoller)
+}
+
+// CleanupOrphanedTimeLocks deletes the timelock documents whose delegation is
+// in a terminal state or does not exist, which the expiry checker would
+// otherwise keep picking up. Delegations that can still expire keep their
+// timelock documents. It returns the number of deleted documents by
+// delegation state.
+func (s *Service) CleanupOrphanedTimeLocks(ctx context.Context) (map[string]uint64, *types.Error) {
+	deleted := make(map[string]uint64)
+	var total uint64
+
+	for {
+		timeLocks, err := s.db.FindOrphanedTimeLocks(
+			ctx, types.TerminalStatesForTimeLock(), s.cfg.Poller.ExpiredDelegationsLimit,
+		)
+		if err != nil {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to find orphaned timelocks: %w", err),
+			)
+		}
+		if len(timeLocks) == 0 {
+			break
+		}
+
+		ids := make([]primitive.ObjectID, len(timeLocks))
+		for i, timeLock := range timeLocks {
+			ids[i] = timeLock.Id
+			state := timeLock.DelegationState.String()
+			if state == "" {
+				state = missingDelegationState
+			}
+			deleted[state]++
+		}
+
+		count, err := s.db.DeleteTimeLocks(ctx, ids)
+		if err != nil {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to delete orphaned timelocks: %w", err),
+			)
+		}
+		total += count
+	}
+
+	log.Info().
+		Uint64("deleted", total).
+		Interface("deleted_by_state", deleted).
+		Msg("cleaned up orphaned timelocks")
+
+	return deleted, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+func TestCleanupOrphanedTimeLocks(t *testing.T) {
+	ctx := context.Background()
+	database := inmemory.New()
+
+	delegations := map[string]types.DelegationState{
+		"active":         types.StateActive,
+		"unbonding":      types.StateUnbonding,
+		"slashed":        types.StateSlashed,
+		"withdrawable":   types.StateWithdrawable,
+		"withdrawn":      types.StateWithdrawn,
+		"withdrawn-late": types.StateWithdrawn,
+	}
+	for stakingTxHash, state := range delegations {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, &model.BTCDelegationDetails{
+			StakingTxHashHex: stakingTxHash,
+			State:            state,
+		}))
+	}
+	for _, stakingTxHash := range []string{
+		"active", "unbonding", "slashed", "withdrawable", "withdrawn", "withdrawn-late", "missing",
+	} {
+		require.NoError(t, database.SaveNewTimeLockExpire(ctx, stakingTxHash, 100, types.SubStateTimelock))
+	}
+
+	// Orphans found in several batches
+	cfg := &config.Config{Poller: config.PollerConfig{ExpiredDelegationsLimit: 2}}
+	service := NewService(cfg, database, nil, nil, nil, nil)
+	deleted, err := service.CleanupOrphanedTimeLocks(ctx)
+	require.Nil(t, err)
+	require.Equal(t, map[string]uint64{
+		types.StateWithdrawable.String(): 1,
+		types.StateWithdrawn.String():    2,
+		missingDelegationState:           1,
+	}, deleted)
+
+	// The delegations that can still expire keep their timelock
+	for _, stakingTxHash := range []string{"active", "unbonding", "slashed"} {
+		timeLocks, err := database.GetTimeLocks(ctx, stakingTxHash)
+		require.NoError(t, err)
+		require.Len(t, timeLocks, 1, stakingTxHash)
+	}
+	for _, stakingTxHash := range []string{"withdrawable", "withdrawn", "withdrawn-late", "missing"} {
+		timeLocks, err := database.GetTimeLocks(ctx, stakingTxHash)
+		require.NoError(t, err)
+		require.Empty(t, timeLocks, stakingTxHash)
+
+		archived, err := database.GetArchivedTimeLocks(ctx, stakingTxHash)
+		require.NoError(t, err)
+		require.Len(t, archived, 1)
+		require.Equal(t, model.TimeLockArchiveReasonOrphaned, archived[0].ArchiveReason)
+	}
+
+	// Nothing is left to clean up
+	deleted, err = service.CleanupOrphanedTimeLocks(ctx)
+	require.Nil(t, err)
+	require.Empty(t, deleted)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// TimeLockRecalculationReport summarizes the recalculation of the timelock
+// expire heights of a staking params version
+type TimeLockRecalculationReport struct {
+	ParamsVersion uint32
+	// Shift is the number of blocks the expire heights moved by
+	Shift int64
+	// Updated is the number of timelocks whose expire height moved
+	Updated uint64
+	// Expired is the number of timelocks whose new expire height is already
+	// reached, which are expired right away instead of being moved
+	Expired uint64
+}
+
+// RecalculateTimeLockExpiry recomputes the expire heights of the timelocks
+// written with the stored staking params of the given version after these
+// changed on the BBN chain, and replaces the stored params with the chain
+// ones. Only the slashing change timelocks depend on the params, through the
+// unbonding time; the other expire heights come from the delegation itself.
+// As the heights are shifted relative to the stored params, the stored params
+// are only replaced once all timelocks moved.
+func (s *Service) RecalculateTimeLockExpiry(
+	ctx context.Context, paramsVersion uint32,
+) (*TimeLockRecalculationReport, *types.Error) {
+	storedParams, err := s.db.GetStakingParams(ctx, paramsVersion)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get stored staking params: %w", err),
+		)
+	}
+
+	allParams, err := s.bbn.GetAllStakingParams(ctx)
+	if err != nil {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("failed to get staking params: %w", err),
+		)
+	}
+	params, ok := allParams[paramsVersion]
+	if !ok {
+		return nil, types.NewInternalServiceError(
+			fmt.Errorf("staking params version %d does not exist on the BBN chain", paramsVersion),
+		)
+	}
+
+	report := &TimeLockRecalculationReport{
+		ParamsVersion: paramsVersion,
+		Shift:         int64(params.UnbondingTimeBlocks) - int64(storedParams.UnbondingTimeBlocks),
+	}
+
+	if report.Shift != 0 {
+		if err := s.shiftTimeLockExpireHeights(ctx, report); err != nil {
+			return nil, err
+		}
+	}
+
+	if fields := storedParams.Diff(params); len(fields) > 0 {
+		if err := s.db.ReplaceStakingParams(ctx, paramsVersion, params); err != nil {
+			return nil, types.NewInternalServiceError(
+				fmt.Errorf("failed to replace staking params: %w", err),
+			)
+		}
+		log.Info().
+			Uint32("params_version", paramsVersion).
+			Strs("fields", fields).
+			Msg("replaced stored staking params")
+	}
+
+	log.Info().
+		Uint32("params_version", report.ParamsVersion).
+		Int64("shift", report.Shift).
+		Uint64("updated", report.Updated).
+		Uint64("expired", report.Expired).
+		Msg("recalculated timelock expire heights")
+
+	return report, nil
+}
+
+func (s *Service) shiftTimeLockExpireHeights(
+	ctx context.Context, report *TimeLockRecalculationReport,
+) *types.Error {
+	timeLocks, err := s.db.FindTimeLocksByParamsVersion(
+		ctx, types.SubStatesForSlashingChange(), report.ParamsVersion,
+	)
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to find timelocks: %w", err),
+		)
+	}
+
+	btcTip, err := s.btc.GetTipHeight()
+	if err != nil {
+		return types.NewInternalServiceError(
+			fmt.Errorf("failed to get BTC tip height: %w", err),
+		)
+	}
+
+	for _, tlDoc := range timeLocks {
+		newExpireHeight := int64(tlDoc.ExpireHeight) + report.Shift
+		if newExpireHeight <= int64(btcTip) {
+			if err := s.expireTimeLock(ctx, tlDoc, btcTip); err != nil {
+				return err
+			}
+			report.Expired++
+			continue
+		}
+
+		if err := s.db.UpdateTimeLockExpireHeight(ctx, &tlDoc, uint32(newExpireHeight)); err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to update timelock expire height: %w", err),
+			)
+		}
+		report.Updated++
+	}
+
+	return nil
+}
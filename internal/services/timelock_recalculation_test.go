@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+func TestRecalculateTimeLockExpiry(t *testing.T) {
+	metrics.Init()
+	ctx := context.Background()
+	database := inmemory.New()
+	require.NoError(t, database.SaveStakingParams(ctx, 1, &bbnclient.StakingParams{UnbondingTimeBlocks: 100}))
+
+	timeLocks := []struct {
+		delegation *model.BTCDelegationDetails
+		timeLock   *model.TimeLockDocument
+	}{
+		{
+			delegation: &model.BTCDelegationDetails{StakingTxHashHex: "slashed", State: types.StateSlashed, ParamsVersion: 1},
+			timeLock:   model.NewTimeLockDocument("slashed", 500, types.SubStateTimelockSlashing),
+		},
+		{
+			delegation: &model.BTCDelegationDetails{StakingTxHashHex: "slashed-soon", State: types.StateSlashed, ParamsVersion: 1},
+			timeLock:   model.NewTimeLockDocument("slashed-soon", 220, types.SubStateEarlyUnbondingSlashing),
+		},
+		{
+			// the timelock left behind by a final delegation
+			delegation: &model.BTCDelegationDetails{StakingTxHashHex: "withdrawn", State: types.StateWithdrawn, ParamsVersion: 1},
+			timeLock:   model.NewTimeLockDocument("withdrawn", 220, types.SubStateTimelockSlashing),
+		},
+		{
+			// the staking timelock does not depend on the params
+			delegation: &model.BTCDelegationDetails{StakingTxHashHex: "active", State: types.StateActive, ParamsVersion: 1},
+			timeLock:   model.NewTimeLockDocument("active", 500, types.SubStateTimelock),
+		},
+		{
+			delegation: &model.BTCDelegationDetails{StakingTxHashHex: "other-version", State: types.StateSlashed, ParamsVersion: 2},
+			timeLock:   model.NewTimeLockDocument("other-version", 500, types.SubStateTimelockSlashing),
+		},
+	}
+	for _, tc := range timeLocks {
+		require.NoError(t, database.SaveNewBTCDelegation(ctx, tc.delegation))
+		require.NoError(t, database.SaveNewTimeLockExpire(
+			ctx, tc.timeLock.StakingTxHashHex, tc.timeLock.ExpireHeight, tc.timeLock.DelegationSubState,
+		))
+	}
+
+	// The unbonding time is shortened by 30 blocks
+	bbnClient := fixtures.NewBbnClient()
+	bbnClient.StakingParams = map[uint32]*bbnclient.StakingParams{1: {UnbondingTimeBlocks: 70}}
+	service := NewService(&config.Config{}, database, fixtures.NewBtcChain(1, 200), nil, bbnClient, nil)
+
+	report, err := service.RecalculateTimeLockExpiry(ctx, 1)
+	require.Nil(t, err)
+	require.Equal(t, &TimeLockRecalculationReport{ParamsVersion: 1, Shift: -30, Updated: 1, Expired: 1}, report)
+
+	expectedTimeLocks := map[string][]model.TimeLockDocument{
+		"slashed": {*model.NewTimeLockDocument("slashed", 470, types.SubStateTimelockSlashing)},
+		// moved below the BTC tip, expired right away
+		"slashed-soon":  nil,
+		"withdrawn":     {*model.NewTimeLockDocument("withdrawn", 220, types.SubStateTimelockSlashing)},
+		"active":        {*model.NewTimeLockDocument("active", 500, types.SubStateTimelock)},
+		"other-version": {*model.NewTimeLockDocument("other-version", 500, types.SubStateTimelockSlashing)},
+	}
+	for stakingTxHash, expected := range expectedTimeLocks {
+		timeLocks, err := database.GetTimeLocks(ctx, stakingTxHash)
+		require.NoError(t, err)
+		if expected == nil {
+			require.Empty(t, timeLocks, stakingTxHash)
+			continue
+		}
+		require.Equal(t, expected, timeLocks, stakingTxHash)
+	}
+
+	expired, dbErr := database.GetBTCDelegationByStakingTxHash(ctx, "slashed-soon")
+	require.NoError(t, dbErr)
+	require.Equal(t, types.StateWithdrawable, expired.State)
+	withdrawn, dbErr := database.GetBTCDelegationByStakingTxHash(ctx, "withdrawn")
+	require.NoError(t, dbErr)
+	require.Equal(t, types.StateWithdrawn, withdrawn.State)
+
+	// The stored params are replaced once the timelocks moved, for a later
+	// run not to shift them again
+	params, dbErr := database.GetStakingParams(ctx, 1)
+	require.NoError(t, dbErr)
+	require.Equal(t, uint32(70), params.UnbondingTimeBlocks)
+
+	report, err = service.RecalculateTimeLockExpiry(ctx, 1)
+	require.Nil(t, err)
+	require.Equal(t, &TimeLockRecalculationReport{ParamsVersion: 1}, report)
+}
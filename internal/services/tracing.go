@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/tracing"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// startBbnBlockSpan starts the root span of the processing of a BBN block,
+// the spans of its events, db operations and RPC calls being its children
+func startBbnBlockSpan(ctx context.Context, height uint64) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, "bbn.process_block", attribute.Int64("bbn.height", int64(height)))
+}
+
+// startBbnEventSpan starts the span of the handling of a BBN event, along with
+// the staking tx hash of the delegation events
+func startBbnEventSpan(
+	ctx context.Context, event BbnEvent, index int, stakingTxHash string,
+) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("bbn.event_type", event.Event.Type),
+		attribute.Int("bbn.event_index", index),
+	}
+	if stakingTxHash != "" {
+		attrs = append(attrs, attribute.String("bbn.staking_tx_hash", stakingTxHash))
+	}
+	return tracing.StartSpan(ctx, "bbn.process_event", attrs...)
+}
+
+// endSpan ends the span, recording the error if any. The nil errors are
+// passed as such, as a nil *types.Error would make a non-nil error.
+func endSpan(span trace.Span, err *types.Error) {
+	if err != nil {
+		tracing.EndSpan(span, err)
+		return
+	}
+	tracing.EndSpan(span, nil)
+}
+
+// eventStakingTxHash returns the staking tx hash of a delegation event, either
+// carried by the event or computed from its staking tx, and an empty string
+// for the other events
+func eventStakingTxHash(event abcitypes.Event) string {
+	for _, attr := range event.Attributes {
+		switch attr.Key {
+		case "staking_tx_hash":
+			return strings.Trim(attr.Value, `"`)
+		case "staking_tx_hex":
+			stakingTx, err := utils.DeserializeBtcTransactionFromHex(strings.Trim(attr.Value, `"`))
+			if err != nil {
+				return ""
+			}
+			return stakingTx.TxHash().String()
+		}
+	}
+	return ""
+}
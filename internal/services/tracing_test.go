@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestBbnBlockProcessingSpans(t *testing.T) {
+	metrics.Init()
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+
+	env := newMarkerTestEnv(t)
+	env.service.db = db.NewMetricsDatabase(env.service.db, &config.DbConfig{})
+	require.NoError(t, env.processBlock(context.Background()))
+
+	spansByName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spansByName[span.Name()] = append(spansByName[span.Name()], span)
+	}
+
+	require.Len(t, spansByName["bbn.process_block"], 1)
+	block := spansByName["bbn.process_block"][0]
+	require.Contains(t, block.Attributes(), attribute.Int64("bbn.height", testMarkerHeight))
+	require.Contains(t, block.Attributes(), attribute.Int("bbn.event_count", testMarkerEventsLen))
+
+	// every event handler and db operation of the block is traced under it
+	events := spansByName["bbn.process_event"]
+	require.Len(t, events, testMarkerEventsLen)
+	eventSpanIds := make(map[string]bool)
+	for _, event := range events {
+		require.Equal(t, block.SpanContext().SpanID(), event.Parent().SpanID())
+		eventSpanIds[event.SpanContext().SpanID().String()] = true
+	}
+	updates := spansByName["db.UpdateFinalityProviderDetailsFromEvent"]
+	require.Len(t, updates, testMarkerEventsLen)
+	for _, update := range updates {
+		require.True(t, eventSpanIds[update.Parent().SpanID().String()])
+	}
+	// the events are marked processed in the transaction of their handler
+	marks := spansByName["db.MarkBbnEventProcessed"]
+	require.Len(t, marks, testMarkerEventsLen)
+	for _, markProcessed := range marks {
+		require.True(t, eventSpanIds[markProcessed.Parent().SpanID().String()])
+	}
+}
+
+func TestBbnBlockProcessingLogScopes(t *testing.T) {
+	metrics.Init()
+	var logs bytes.Buffer
+	previousLogger := log.Logger
+	log.Logger = zerolog.New(&logs).Level(zerolog.DebugLevel)
+	t.Cleanup(func() { log.Logger = previousLogger })
+
+	env := newMarkerTestEnv(t)
+	env.killAt = 2
+	env.service.db = db.NewMetricsDatabase(env.service.db, &config.DbConfig{})
+	require.Error(t, env.processBlock(context.Background()))
+
+	// the db error is logged in the scope of the finality provider of the
+	// failed event, the event failure in the scope of the event
+	var failures []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(line, &entry))
+		require.EqualValues(t, testMarkerHeight, entry[logging.BbnHeightField])
+		if entry["level"] == zerolog.LevelErrorValue {
+			failures = append(failures, entry)
+		}
+	}
+	require.Len(t, failures, 2)
+	require.Equal(t, "db operation failed", failures[0]["message"])
+	require.Equal(t, "UpdateFinalityProviderDetailsFromEvent", failures[0]["db_method"])
+	require.Equal(t, fpBtcPkForEvent(2), failures[0][logging.FpBtcPkField])
+	require.Equal(t, "Failed to process event", failures[1]["message"])
+	for _, failure := range failures {
+		require.Equal(t, string(EventFinalityProviderEditedType), failure[logging.EventTypeField])
+	}
+}
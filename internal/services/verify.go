@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// VerifyRequest selects the delegations and finality providers a consistency
+// check compares with the BBN chain state
+type VerifyRequest struct {
+	// Sample is the number of delegations checked on each side, all of them
+	// being checked if zero
+	Sample uint64
+	// States restricts the delegations to the ones in any of the states
+	States []types.DelegationState
+	// FinalityProviderBtcPks restricts the delegations to the ones delegated
+	// to any of the finality providers, and the finality providers to them
+	FinalityProviderBtcPks []string
+	// FixSafe applies the corrections classified as unambiguous: the missing
+	// covenant signatures, pending and verified delegations and finality
+	// providers are saved from the chain state
+	FixSafe bool
+}
+
+// ConsistencyMismatch is a difference between an indexed entity and its chain
+// state, categorized by the reconciliation discrepancy types
+type ConsistencyMismatch struct {
+	EntityType string `json:"entity_type"`
+	EntityId   string `json:"entity_id"`
+	LocalValue string `json:"local_value,omitempty"`
+	ChainValue string `json:"chain_value,omitempty"`
+	Fixed      bool   `json:"fixed"`
+}
+
+// ConsistencyReport is the outcome of a consistency check, the mismatches
+// being grouped by category
+type ConsistencyReport struct {
+	CheckedLocalDelegations  uint64                           `json:"checked_local_delegations"`
+	CheckedChainDelegations  uint64                           `json:"checked_chain_delegations"`
+	CheckedFinalityProviders uint64                           `json:"checked_finality_providers"`
+	Mismatches               map[string][]ConsistencyMismatch `json:"mismatches"`
+}
+
+// MismatchCount returns the number of mismatches found, fixed or not
+func (r *ConsistencyReport) MismatchCount() int {
+	count := 0
+	for _, mismatches := range r.Mismatches {
+		count += len(mismatches)
+	}
+	return count
+}
+
+// verification holds the state of a consistency check in progress
+type verification struct {
+	req      VerifyRequest
+	report   *ConsistencyReport
+	throttle *time.Ticker
+}
+
+func (v *verification) wait(ctx context.Context) *types.Error {
+	return waitForThrottle(ctx, v.throttle, "verification")
+}
+
+func (v *verification) addMismatch(category string, mismatch ConsistencyMismatch) {
+	v.report.Mismatches[category] = append(v.report.Mismatches[category], mismatch)
+}
+
+// VerifyConsistency compares a sample of the indexed delegations, or all of
+// them, with the BBN chain state and the other way around, then the finality
+// providers, which are always all checked. Unlike the reconciliation, it keeps
+// no record and returns the mismatches found. The BBN queries are rate limited
+// like the reconciliation ones.
+func (s *Service) VerifyConsistency(ctx context.Context, req VerifyRequest) (*ConsistencyReport, *types.Error) {
+	interval := time.Duration(float64(time.Second) / s.cfg.Reconciliation.RequestsPerSecond)
+	v := &verification{
+		req:      req,
+		report:   &ConsistencyReport{Mismatches: make(map[string][]ConsistencyMismatch)},
+		throttle: time.NewTicker(interval),
+	}
+	defer v.throttle.Stop()
+
+	if err := s.verifyLocalDelegations(ctx, v); err != nil {
+		return nil, err
+	}
+	if err := s.verifyChainDelegations(ctx, v); err != nil {
+		return nil, err
+	}
+	if err := s.verifyFinalityProviders(ctx, v); err != nil {
+		return nil, err
+	}
+
+	return v.report, nil
+}
+
+// verifyLocalDelegations checks the indexed delegations against their chain
+// state
+func (s *Service) verifyLocalDelegations(ctx context.Context, v *verification) *types.Error {
+	filter := db.BTCDelegationsFilter{
+		States:                 v.req.States,
+		FinalityProviderBtcPks: v.req.FinalityProviderBtcPks,
+	}
+
+	if v.req.Sample > 0 {
+		delegations, err := s.db.SampleBTCDelegations(ctx, filter, v.req.Sample)
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to sample BTC delegations: %w", err),
+			)
+		}
+		return s.verifyLocalDelegationBatch(ctx, v, delegations)
+	}
+
+	cursor := ""
+	for {
+		delegations, err := s.db.GetBTCDelegationsAfter(ctx, filter, cursor, s.cfg.Reconciliation.BatchSize)
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get BTC delegations: %w", err),
+			)
+		}
+		if len(delegations) == 0 {
+			return nil
+		}
+		if err := s.verifyLocalDelegationBatch(ctx, v, delegations); err != nil {
+			return err
+		}
+		cursor = delegations[len(delegations)-1].StakingTxHashHex
+	}
+}
+
+func (s *Service) verifyLocalDelegationBatch(
+	ctx context.Context, v *verification, delegations []*model.BTCDelegationDetails,
+) *types.Error {
+	for _, delegation := range delegations {
+		if err := v.wait(ctx); err != nil {
+			return err
+		}
+
+		chainDelegation, err := s.bbn.GetBTCDelegation(ctx, delegation.StakingTxHashHex)
+		if err != nil {
+			if !errors.Is(err, bbnclient.ErrBTCDelegationNotFound) {
+				return types.NewInternalServiceError(err)
+			}
+			v.addMismatch(model.DiscrepancyMissingOnChain, ConsistencyMismatch{
+				EntityType: model.ReconciliationDelegationEntity,
+				EntityId:   delegation.StakingTxHashHex,
+				LocalValue: delegation.State.String(),
+			})
+			v.report.CheckedLocalDelegations++
+			continue
+		}
+
+		if !utils.Contains(chainStatusesForState(delegation.State), chainDelegation.Status) {
+			v.addMismatch(model.DiscrepancyStateMismatch, ConsistencyMismatch{
+				EntityType: model.ReconciliationDelegationEntity,
+				EntityId:   delegation.StakingTxHashHex,
+				LocalValue: delegation.State.String(),
+				ChainValue: chainDelegation.Status,
+			})
+		}
+
+		localCount := len(delegation.CovenantUnbondingSignatures)
+		chainCount := len(chainDelegation.CovenantUnbondingSignatures)
+		if localCount != chainCount {
+			// Only signatures missing locally can be safely added
+			fixed := false
+			if v.req.FixSafe && chainCount > localCount {
+				if _, err := s.saveMissingCovenantSignatures(ctx, delegation, chainDelegation); err != nil {
+					return err
+				}
+				fixed = true
+			}
+			v.addMismatch(model.DiscrepancyCovenantSignatureMismatch, ConsistencyMismatch{
+				EntityType: model.ReconciliationDelegationEntity,
+				EntityId:   delegation.StakingTxHashHex,
+				LocalValue: fmt.Sprint(localCount),
+				ChainValue: fmt.Sprint(chainCount),
+				Fixed:      fixed,
+			})
+		}
+
+		v.report.CheckedLocalDelegations++
+	}
+	return nil
+}
+
+// verifyChainDelegations checks that the chain delegations are indexed. The
+// chain serves them by staking tx hash, so that its first ones are a fair
+// sample.
+func (s *Service) verifyChainDelegations(ctx context.Context, v *verification) *types.Error {
+	var statuses []string
+	for _, state := range v.req.States {
+		statuses = append(statuses, chainStatusesForState(state)...)
+	}
+
+	var pageKey []byte
+	for {
+		if err := v.wait(ctx); err != nil {
+			return err
+		}
+		chainDelegations, nextKey, err := s.bbn.GetBTCDelegations(ctx, pageKey, s.cfg.Reconciliation.BatchSize)
+		if err != nil {
+			return types.NewInternalServiceError(err)
+		}
+
+		for _, chainDelegation := range chainDelegations {
+			if len(statuses) > 0 && !utils.Contains(statuses, chainDelegation.Status) {
+				continue
+			}
+			if !delegatedToAny(chainDelegation.FinalityProviderBtcPksHex, v.req.FinalityProviderBtcPks) {
+				continue
+			}
+
+			_, err := s.db.GetBTCDelegationByStakingTxHash(ctx, chainDelegation.StakingTxHashHex)
+			if err != nil && !db.IsNotFoundError(err) {
+				return types.NewInternalServiceError(
+					fmt.Errorf("failed to get BTC delegation by staking tx hash: %w", err),
+				)
+			}
+			if err != nil {
+				fixed := false
+				if v.req.FixSafe {
+					var fixErr *types.Error
+					if fixed, fixErr = s.replayDelegationFromChain(ctx, chainDelegation); fixErr != nil {
+						return fixErr
+					}
+				}
+				v.addMismatch(model.DiscrepancyMissingLocally, ConsistencyMismatch{
+					EntityType: model.ReconciliationDelegationEntity,
+					EntityId:   chainDelegation.StakingTxHashHex,
+					ChainValue: chainDelegation.Status,
+					Fixed:      fixed,
+				})
+			}
+
+			v.report.CheckedChainDelegations++
+			if v.req.Sample > 0 && v.report.CheckedChainDelegations >= v.req.Sample {
+				return nil
+			}
+		}
+
+		if len(nextKey) == 0 {
+			return nil
+		}
+		pageKey = nextKey
+	}
+}
+
+// verifyFinalityProviders checks the chain finality providers against the
+// indexed ones and the other way around
+func (s *Service) verifyFinalityProviders(ctx context.Context, v *verification) *types.Error {
+	chainFpPks := make(map[string]struct{})
+
+	var pageKey []byte
+	for {
+		if err := v.wait(ctx); err != nil {
+			return err
+		}
+		chainFps, nextKey, err := s.bbn.GetFinalityProviders(ctx, pageKey, s.cfg.Reconciliation.BatchSize)
+		if err != nil {
+			return types.NewInternalServiceError(err)
+		}
+
+		for _, chainFp := range chainFps {
+			chainFpPks[chainFp.BtcPk] = struct{}{}
+			if len(v.req.FinalityProviderBtcPks) > 0 &&
+				!utils.Contains(v.req.FinalityProviderBtcPks, chainFp.BtcPk) {
+				continue
+			}
+			if err := s.verifyChainFinalityProvider(ctx, v, chainFp); err != nil {
+				return err
+			}
+			v.report.CheckedFinalityProviders++
+		}
+
+		if len(nextKey) == 0 {
+			break
+		}
+		pageKey = nextKey
+	}
+
+	paginationToken := ""
+	for {
+		page, err := s.db.GetFinalityProviders(
+			ctx, db.FinalityProvidersFilter{}, paginationToken, int64(s.cfg.Reconciliation.BatchSize),
+		)
+		if err != nil {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get finality providers: %w", err),
+			)
+		}
+
+		for _, fp := range page.Data {
+			if len(v.req.FinalityProviderBtcPks) > 0 &&
+				!utils.Contains(v.req.FinalityProviderBtcPks, fp.BtcPk) {
+				continue
+			}
+			if _, ok := chainFpPks[fp.BtcPk]; !ok {
+				v.addMismatch(model.DiscrepancyMissingOnChain, ConsistencyMismatch{
+					EntityType: model.ReconciliationFpEntity,
+					EntityId:   fp.BtcPk,
+					LocalValue: fp.State,
+				})
+			}
+		}
+
+		if page.PaginationToken == "" {
+			return nil
+		}
+		paginationToken = page.PaginationToken
+	}
+}
+
+func (s *Service) verifyChainFinalityProvider(
+	ctx context.Context, v *verification, chainFp *bbnclient.FinalityProvider,
+) *types.Error {
+	expectedState := model.FinalityProviderStateFromChain(chainFp)
+
+	fp, err := s.db.GetFinalityProviderByBtcPk(ctx, chainFp.BtcPk)
+	if err != nil {
+		if !db.IsNotFoundError(err) {
+			return types.NewInternalServiceError(
+				fmt.Errorf("failed to get finality provider by btc pk: %w", err),
+			)
+		}
+
+		fixed := false
+		if v.req.FixSafe {
+			if err := s.db.SaveNewFinalityProvider(
+				ctx, model.FromBbnFinalityProvider(chainFp),
+			); err != nil && !db.IsDuplicateKeyError(err) {
+				return types.NewInternalServiceError(
+					fmt.Errorf("failed to save new finality provider: %w", err),
+				)
+			}
+			fixed = true
+		}
+		v.addMismatch(model.DiscrepancyMissingLocally, ConsistencyMismatch{
+			EntityType: model.ReconciliationFpEntity,
+			EntityId:   chainFp.BtcPk,
+			ChainValue: expectedState,
+			Fixed:      fixed,
+		})
+		return nil
+	}
+
+	if !finalityProviderStateMatches(fp.State, expectedState) {
+		v.addMismatch(model.DiscrepancyStateMismatch, ConsistencyMismatch{
+			EntityType: model.ReconciliationFpEntity,
+			EntityId:   chainFp.BtcPk,
+			LocalValue: fp.State,
+			ChainValue: expectedState,
+		})
+	}
+	return nil
+}
+
+// delegatedToAny returns whether the delegation is delegated to any of the
+// finality providers, true if there are none
+func delegatedToAny(delegationFpPks, fpPks []string) bool {
+	if len(fpPks) == 0 {
+		return true
+	}
+	for _, pk := range delegationFpPks {
+		if utils.Contains(fpPks, pk) {
+			return true
+		}
+	}
+	return false
+}
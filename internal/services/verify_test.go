@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifyTestConfig() *config.Config {
+	return &config.Config{
+		Reconciliation: config.ReconciliationConfig{BatchSize: 10, RequestsPerSecond: 1000},
+	}
+}
+
+func TestVerifyConsistency(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	bbnMock := mocks.NewBbnInterface(t)
+	active := bbntypes.BTCDelegationStatus_ACTIVE.String()
+	inactiveFp := bbntypes.FinalityProviderStatus_FINALITY_PROVIDER_STATUS_INACTIVE.String()
+
+	// Local side: a consistent delegation, one with a covenant signature
+	// missing locally and one missing on chain
+	filter := db.BTCDelegationsFilter{
+		States:                 []types.DelegationState{types.StateActive},
+		FinalityProviderBtcPks: []string{"fp"},
+	}
+	dbMock.On("SampleBTCDelegations", ctx, filter, uint64(2)).Return([]*model.BTCDelegationDetails{
+		{StakingTxHashHex: "consistent", State: types.StateActive},
+		{StakingTxHashHex: "unsigned", State: types.StateActive},
+		{StakingTxHashHex: "unknown", State: types.StateActive},
+	}, nil)
+	bbnMock.On("GetBTCDelegation", ctx, "consistent").Return(
+		&bbnclient.BTCDelegation{StakingTxHashHex: "consistent", Status: active}, nil,
+	)
+	bbnMock.On("GetBTCDelegation", ctx, "unsigned").Return(&bbnclient.BTCDelegation{
+		StakingTxHashHex: "unsigned",
+		Status:           active,
+		CovenantUnbondingSignatures: []bbnclient.CovenantUnbondingSignature{
+			{CovenantBtcPkHex: "covenant", SignatureHex: "sig"},
+		},
+	}, nil)
+	bbnMock.On("GetBTCDelegation", ctx, "unknown").Return(nil, bbnclient.ErrBTCDelegationNotFound)
+	dbMock.On(
+		"SaveBTCDelegationUnbondingCovenantSignature", ctx, "unsigned", "covenant", "sig",
+	).Return(nil).Once()
+
+	// Chain side: the delegations to other finality providers are skipped and
+	// the check stops once the sample is reached
+	bbnMock.On("GetBTCDelegations", ctx, []byte(nil), uint64(10)).Return([]*bbnclient.BTCDelegation{
+		{StakingTxHashHex: "other-fp", Status: active, FinalityProviderBtcPksHex: []string{"other"}},
+		{StakingTxHashHex: "consistent", Status: active, FinalityProviderBtcPksHex: []string{"fp"}},
+		{StakingTxHashHex: "unindexed", Status: active, FinalityProviderBtcPksHex: []string{"fp"}},
+		{StakingTxHashHex: "beyond-sample", Status: active, FinalityProviderBtcPksHex: []string{"fp"}},
+	}, []byte("next"), nil)
+	dbMock.On("GetBTCDelegationByStakingTxHash", ctx, "consistent").Return(&model.BTCDelegationDetails{}, nil)
+	dbMock.On("GetBTCDelegationByStakingTxHash", ctx, "unindexed").Return(nil, &db.NotFoundError{})
+
+	// Finality providers: an indexed one and one unknown to the chain
+	bbnMock.On("GetFinalityProviders", ctx, []byte(nil), uint64(10)).Return(
+		[]*bbnclient.FinalityProvider{{BtcPk: "fp"}, {BtcPk: "other"}}, []byte(nil), nil,
+	)
+	dbMock.On("GetFinalityProviderByBtcPk", ctx, "fp").Return(
+		&model.FinalityProviderDetails{BtcPk: "fp", State: inactiveFp}, nil,
+	)
+	dbMock.On("GetFinalityProviders", ctx, db.FinalityProvidersFilter{}, "", int64(10)).Return(
+		&db.DbResultMap[*model.FinalityProviderDetails]{Data: []*model.FinalityProviderDetails{
+			{BtcPk: "fp", State: inactiveFp},
+			{BtcPk: "other", State: inactiveFp},
+		}}, nil,
+	)
+
+	service := NewService(newVerifyTestConfig(), dbMock, nil, nil, bbnMock, nil)
+	report, err := service.VerifyConsistency(ctx, VerifyRequest{
+		Sample:                 2,
+		States:                 []types.DelegationState{types.StateActive},
+		FinalityProviderBtcPks: []string{"fp"},
+		FixSafe:                true,
+	})
+	require.Nil(t, err)
+	require.Equal(t, &ConsistencyReport{
+		CheckedLocalDelegations:  3,
+		CheckedChainDelegations:  2,
+		CheckedFinalityProviders: 1,
+		Mismatches: map[string][]ConsistencyMismatch{
+			model.DiscrepancyCovenantSignatureMismatch: {{
+				EntityType: model.ReconciliationDelegationEntity,
+				EntityId:   "unsigned",
+				LocalValue: "0",
+				ChainValue: "1",
+				Fixed:      true,
+			}},
+			model.DiscrepancyMissingOnChain: {{
+				EntityType: model.ReconciliationDelegationEntity,
+				EntityId:   "unknown",
+				LocalValue: "ACTIVE",
+			}},
+			// An active delegation is not replayed
+			model.DiscrepancyMissingLocally: {{
+				EntityType: model.ReconciliationDelegationEntity,
+				EntityId:   "unindexed",
+				ChainValue: active,
+			}},
+		},
+	}, report)
+	require.Equal(t, 3, report.MismatchCount())
+}
+
+func TestVerifyConsistencyAll(t *testing.T) {
+	ctx := context.Background()
+	dbMock := mocks.NewDbInterface(t)
+	bbnMock := mocks.NewBbnInterface(t)
+
+	// All the local delegations are paged through
+	dbMock.On("GetBTCDelegationsAfter", ctx, db.BTCDelegationsFilter{}, "", uint64(10)).Return(
+		[]*model.BTCDelegationDetails{{StakingTxHashHex: "pending", State: types.StatePending}}, nil,
+	)
+	dbMock.On("GetBTCDelegationsAfter", ctx, db.BTCDelegationsFilter{}, "pending", uint64(10)).Return(
+		[]*model.BTCDelegationDetails{}, nil,
+	)
+	bbnMock.On("GetBTCDelegation", ctx, "pending").Return(&bbnclient.BTCDelegation{
+		StakingTxHashHex: "pending",
+		Status:           bbntypes.BTCDelegationStatus_VERIFIED.String(),
+	}, nil)
+	bbnMock.On("GetBTCDelegations", ctx, []byte(nil), uint64(10)).Return(
+		[]*bbnclient.BTCDelegation{}, []byte(nil), nil,
+	)
+	bbnMock.On("GetFinalityProviders", ctx, []byte(nil), uint64(10)).Return(
+		[]*bbnclient.FinalityProvider{}, []byte(nil), nil,
+	)
+	dbMock.On("GetFinalityProviders", ctx, db.FinalityProvidersFilter{}, "", int64(10)).Return(
+		&db.DbResultMap[*model.FinalityProviderDetails]{}, nil,
+	)
+
+	service := NewService(newVerifyTestConfig(), dbMock, nil, nil, bbnMock, nil)
+	report, err := service.VerifyConsistency(ctx, VerifyRequest{})
+	require.Nil(t, err)
+	require.Equal(t, uint64(1), report.CheckedLocalDelegations)
+	require.Equal(t, map[string][]ConsistencyMismatch{
+		model.DiscrepancyStateMismatch: {{
+			EntityType: model.ReconciliationDelegationEntity,
+			EntityId:   "pending",
+			LocalValue: "PENDING",
+			ChainValue: bbntypes.BTCDelegationStatus_VERIFIED.String(),
+		}},
+	}, report.Mismatches)
+}
@@ -5,9 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/audit"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/logging"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
 	"github.com/babylonlabs-io/babylon/btcstaking"
@@ -17,9 +21,17 @@ import (
 	"github.com/btcsuite/btcd/btcutil"
 	"github.com/btcsuite/btcd/wire"
 	notifier "github.com/lightningnetwork/lnd/chainntnfs"
-	"github.com/rs/zerolog/log"
 )
 
+// withBtcSpendTrigger opens the scope of the mutations made on a BTC spend
+func withBtcSpendTrigger(ctx context.Context, spendDetail *notifier.SpendDetail) context.Context {
+	return audit.WithTrigger(ctx, audit.Trigger{
+		Type:      audit.TriggerBtcSpend,
+		BtcHeight: uint64(spendDetail.SpendingHeight),
+		TxHash:    spendDetail.SpendingTx.TxHash().String(),
+	})
+}
+
 func (s *Service) watchForSpendStakingTx(
 	spendEvent *notifier.SpendEvent,
 	stakingTxHashHex string,
@@ -27,12 +39,13 @@ func (s *Service) watchForSpendStakingTx(
 	defer s.wg.Done()
 	quitCtx, cancel := s.quitContext()
 	defer cancel()
+	quitCtx = logging.WithStakingTxHash(quitCtx, stakingTxHashHex)
 
 	// Get spending details
 	select {
 	case spendDetail := <-spendEvent.Spend:
-		log.Debug().
-			Str("staking_tx", stakingTxHashHex).
+		quitCtx = withBtcSpendTrigger(quitCtx, spendDetail)
+		logging.FromContext(quitCtx).Debug().
 			Str("spending_tx", spendDetail.SpendingTx.TxHash().String()).
 			Msg("staking tx has been spent")
 		if err := s.handleSpendingStakingTransaction(
@@ -42,9 +55,8 @@ func (s *Service) watchForSpendStakingTx(
 			uint32(spendDetail.SpendingHeight),
 			stakingTxHashHex,
 		); err != nil {
-			log.Error().
+			logging.FromContext(quitCtx).Error().
 				Err(err).
-				Str("staking_tx", stakingTxHashHex).
 				Str("spending_tx", spendDetail.SpendingTx.TxHash().String()).
 				Msg("failed to handle spending staking transaction")
 			return
@@ -65,12 +77,13 @@ func (s *Service) watchForSpendUnbondingTx(
 	defer s.wg.Done()
 	quitCtx, cancel := s.quitContext()
 	defer cancel()
+	quitCtx = logging.WithStakingTxHash(quitCtx, delegation.StakingTxHashHex)
 
 	// Get spending details
 	select {
 	case spendDetail := <-spendEvent.Spend:
-		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
+		quitCtx = withBtcSpendTrigger(quitCtx, spendDetail)
+		logging.FromContext(quitCtx).Debug().
 			Str("unbonding_tx", spendDetail.SpendingTx.TxHash().String()).
 			Msg("unbonding tx has been spent")
 		if err := s.handleSpendingUnbondingTransaction(
@@ -80,9 +93,8 @@ func (s *Service) watchForSpendUnbondingTx(
 			spendDetail.SpenderInputIndex,
 			delegation,
 		); err != nil {
-			log.Error().
+			logging.FromContext(quitCtx).Error().
 				Err(err).
-				Str("staking_tx", delegation.StakingTxHashHex).
 				Str("unbonding_tx", spendDetail.SpendingTx.TxHash().String()).
 				Msg("failed to handle spending unbonding transaction")
 			return
@@ -103,45 +115,56 @@ func (s *Service) watchForSpendSlashingChange(
 	defer s.wg.Done()
 	quitCtx, cancel := s.quitContext()
 	defer cancel()
+	quitCtx = logging.WithStakingTxHash(quitCtx, delegation.StakingTxHashHex)
 
 	select {
 	case spendDetail := <-spendEvent.Spend:
-		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
+		quitCtx = withBtcSpendTrigger(quitCtx, spendDetail)
+		logging.FromContext(quitCtx).Debug().
 			Str("spending_tx", spendDetail.SpendingTx.TxHash().String()).
 			Msg("slashing change output has been spent")
-		delegationState, err := s.db.GetBTCDelegationState(quitCtx, delegation.StakingTxHashHex)
+		currentDelegation, err := s.db.GetBTCDelegationByStakingTxHash(quitCtx, delegation.StakingTxHashHex)
 		if err != nil {
-			log.Error().
+			logging.FromContext(quitCtx).Error().
 				Err(err).
-				Str("staking_tx", delegation.StakingTxHashHex).
 				Msg("failed to get delegation state")
 			return
 		}
 
 		qualifiedStates := types.QualifiedStatesForWithdrawn()
-		if qualifiedStates == nil || !utils.Contains(qualifiedStates, *delegationState) {
-			log.Error().
-				Str("staking_tx", delegation.StakingTxHashHex).
-				Str("state", delegationState.String()).
+		if qualifiedStates == nil || !utils.Contains(qualifiedStates, currentDelegation.State) {
+			logging.FromContext(quitCtx).Error().
+				Str("state", currentDelegation.State.String()).
 				Msg("current state is not qualified for slashed withdrawn")
 			return
 		}
 
-		// Update to withdrawn state
-		delegationSubState := subState
-		if err := s.db.UpdateBTCDelegationState(
+		// Update to withdrawn state, along with its transition and event
+		if _, err := s.updateStateFrom(
 			quitCtx,
 			delegation.StakingTxHashHex,
+			currentDelegation.State,
 			types.QualifiedStatesForWithdrawn(),
 			types.StateWithdrawn,
-			&delegationSubState,
+			&subState,
+			db.DelegationStateRecords{
+				BTCDerivedChange: model.NewBTCStateChange(
+					currentDelegation.Summary(), uint64(spendDetail.SpendingHeight),
+				),
+				Transition: model.NewBtcStateTransition(
+					delegation.StakingTxHashHex, currentDelegation.State, types.StateWithdrawn, subState,
+					model.StateTransitionTriggerBtcSpend, uint64(spendDetail.SpendingHeight), time.Now().Unix(),
+				),
+				OutboxEvent: model.NewWithdrawnStakingOutboxEvent(
+					delegation, subState, spendDetail.SpendingTx.TxHash().String(),
+					uint32(spendDetail.SpendingHeight), time.Now().UnixNano(),
+				),
+			},
 		); err != nil {
-			log.Error().
+			logging.FromContext(quitCtx).Error().
 				Err(err).
-				Str("staking_tx", delegation.StakingTxHashHex).
 				Str("state", types.StateWithdrawn.String()).
-				Str("sub_state", delegationSubState.String()).
+				Str("sub_state", subState.String()).
 				Msg("failed to update delegation state to withdrawn")
 			return
 		}
@@ -170,44 +193,29 @@ func (s *Service) handleSpendingStakingTransaction(
 		return fmt.Errorf("failed to get staking params: %w", err)
 	}
 
-	// First try to validate as unbonding tx
-	isUnbonding, err := s.IsValidUnbondingTx(spendingTx, delegation, params)
+	path, err := s.classifyStakingSpend(spendingTx, spendingInputIdx, delegation, params)
 	if err != nil {
-		return fmt.Errorf("failed to validate unbonding tx: %w", err)
+		return err
 	}
-	if isUnbonding {
-		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
+	switch path {
+	case spendPathUnbonding:
+		logging.FromContext(ctx).Debug().
 			Str("unbonding_tx", spendingTx.TxHash().String()).
 			Msg("staking tx has been spent through unbonding path")
 
-		// Register unbonding spend notification
-		return s.registerUnbondingSpendNotification(ctx, delegation)
-	}
-
-	// Try to validate as withdrawal transaction
-	withdrawalErr := s.validateWithdrawalTxFromStaking(spendingTx, spendingInputIdx, delegation, params)
-	if withdrawalErr == nil {
-		// It's a valid withdrawal, process it
-		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
+		// Register unbonding spend notification, not returning a nil
+		// *types.Error as a non-nil error
+		if err := s.registerUnbondingSpendNotification(ctx, delegation); err != nil {
+			return err
+		}
+		return nil
+	case spendPathWithdrawal:
+		logging.FromContext(ctx).Debug().
 			Str("withdrawal_tx", spendingTx.TxHash().String()).
 			Msg("staking tx has been spent through withdrawal path")
-		return s.handleWithdrawal(ctx, delegation, types.SubStateTimelock)
-	}
-
-	// If it's not a valid withdrawal, check if it's a valid slashing
-	if !errors.Is(withdrawalErr, types.ErrInvalidWithdrawalTx) {
-		return fmt.Errorf("failed to validate withdrawal tx: %w", withdrawalErr)
-	}
-
-	// Try to validate as slashing transaction
-	if err := s.validateSlashingTxFromStaking(spendingTx, spendingInputIdx, delegation, params); err != nil {
-		if errors.Is(err, types.ErrInvalidSlashingTx) {
-			// Neither withdrawal nor slashing - this is an invalid spend
-			return fmt.Errorf("transaction is neither valid unbonding, withdrawal, nor slashing: %w", err)
-		}
-		return fmt.Errorf("failed to validate slashing tx: %w", err)
+		return s.handleWithdrawal(
+			ctx, delegation, types.SubStateTimelock, spendingTx.TxHash().String(), spendingHeight,
+		)
 	}
 
 	// Save slashing tx hex
@@ -216,6 +224,13 @@ func (s *Service) handleSpendingStakingTransaction(
 		return fmt.Errorf("failed to convert slashing tx to bytes: %w", err)
 	}
 	slashingTxHex := slashingTx.ToHexStr()
+	if err := s.recordBTCDerivedChange(ctx, &model.BTCDerivedChange{
+		StakingTxHashHex:   delegation.StakingTxHashHex,
+		BtcHeight:          uint64(spendingHeight),
+		PreviousSlashingTx: &delegation.SlashingTx,
+	}); err != nil {
+		return err
+	}
 	if err := s.db.SaveBTCDelegationSlashingTxHex(
 		ctx,
 		delegation.StakingTxHashHex,
@@ -224,6 +239,9 @@ func (s *Service) handleSpendingStakingTransaction(
 	); err != nil {
 		return fmt.Errorf("failed to save slashing tx hex: %w", err)
 	}
+	if err := s.confirmSlashing(ctx, spendingTx, spendingHeight, delegation); err != nil {
+		return err
+	}
 
 	// It's a valid slashing tx, watch for spending change output
 	return s.startWatchingSlashingChange(
@@ -247,29 +265,17 @@ func (s *Service) handleSpendingUnbondingTransaction(
 		return fmt.Errorf("failed to get staking params: %w", err)
 	}
 
-	// First try to validate as withdrawal transaction
-	withdrawalErr := s.validateWithdrawalTxFromUnbonding(spendingTx, delegation, spendingInputIdx, params)
-	if withdrawalErr == nil {
-		// It's a valid withdrawal, process it
-		log.Debug().
-			Str("staking_tx", delegation.StakingTxHashHex).
+	path, err := s.classifyUnbondingSpend(spendingTx, spendingInputIdx, delegation, params)
+	if err != nil {
+		return err
+	}
+	if path == spendPathWithdrawal {
+		logging.FromContext(ctx).Debug().
 			Str("unbonding_tx", spendingTx.TxHash().String()).
 			Msg("unbonding tx has been spent through withdrawal path")
-		return s.handleWithdrawal(ctx, delegation, types.SubStateEarlyUnbonding)
-	}
-
-	// If it's not a valid withdrawal, check if it's a valid slashing
-	if !errors.Is(withdrawalErr, types.ErrInvalidWithdrawalTx) {
-		return fmt.Errorf("failed to validate withdrawal tx: %w", withdrawalErr)
-	}
-
-	// Try to validate as slashing transaction
-	if err := s.validateSlashingTxFromUnbonding(spendingTx, delegation, spendingInputIdx, params); err != nil {
-		if errors.Is(err, types.ErrInvalidSlashingTx) {
-			// Neither withdrawal nor slashing - this is an invalid spend
-			return fmt.Errorf("transaction is neither valid withdrawal nor slashing: %w", err)
-		}
-		return fmt.Errorf("failed to validate slashing tx: %w", err)
+		return s.handleWithdrawal(
+			ctx, delegation, types.SubStateEarlyUnbonding, spendingTx.TxHash().String(), spendingHeight,
+		)
 	}
 
 	// Save unbonding slashing tx hex
@@ -278,6 +284,13 @@ func (s *Service) handleSpendingUnbondingTransaction(
 		return fmt.Errorf("failed to convert unbonding slashing tx to bytes: %w", err)
 	}
 	unbondingSlashingTxHex := unbondingSlashingTx.ToHexStr()
+	if err := s.recordBTCDerivedChange(ctx, &model.BTCDerivedChange{
+		StakingTxHashHex:   delegation.StakingTxHashHex,
+		BtcHeight:          uint64(spendingHeight),
+		PreviousSlashingTx: &delegation.SlashingTx,
+	}); err != nil {
+		return err
+	}
 	if err := s.db.SaveBTCDelegationUnbondingSlashingTxHex(
 		ctx, delegation.StakingTxHashHex,
 		unbondingSlashingTxHex,
@@ -285,6 +298,9 @@ func (s *Service) handleSpendingUnbondingTransaction(
 	); err != nil {
 		return fmt.Errorf("failed to save unbonding slashing tx hex: %w", err)
 	}
+	if err := s.confirmSlashing(ctx, spendingTx, spendingHeight, delegation); err != nil {
+		return err
+	}
 
 	// It's a valid slashing tx, watch for spending change output
 	return s.startWatchingSlashingChange(
@@ -300,34 +316,46 @@ func (s *Service) handleWithdrawal(
 	ctx context.Context,
 	delegation *model.BTCDelegationDetails,
 	subState types.DelegationSubState,
+	spendingTxHashHex string,
+	spendingHeight uint32,
 ) error {
-	delegationState, err := s.db.GetBTCDelegationState(ctx, delegation.StakingTxHashHex)
+	currentDelegation, err := s.db.GetBTCDelegationByStakingTxHash(ctx, delegation.StakingTxHashHex)
 	if err != nil {
 		return fmt.Errorf("failed to get delegation state: %w", err)
 	}
 
 	qualifiedStates := types.QualifiedStatesForWithdrawn()
-	if qualifiedStates == nil || !utils.Contains(qualifiedStates, *delegationState) {
-		log.Error().
-			Str("staking_tx", delegation.StakingTxHashHex).
-			Str("current_state", delegationState.String()).
+	if qualifiedStates == nil || !utils.Contains(qualifiedStates, currentDelegation.State) {
+		logging.FromContext(ctx).Error().
+			Str("current_state", currentDelegation.State.String()).
 			Msg("current state is not qualified for withdrawal")
-		return fmt.Errorf("current state %s is not qualified for withdrawal", *delegationState)
+		return fmt.Errorf("current state %s is not qualified for withdrawal", currentDelegation.State)
 	}
 
-	// Update to withdrawn state
-	log.Debug().
-		Str("staking_tx", delegation.StakingTxHashHex).
+	// Update to withdrawn state, along with its transition and event
+	logging.FromContext(ctx).Debug().
 		Str("state", types.StateWithdrawn.String()).
 		Str("sub_state", subState.String()).
 		Msg("updating delegation state to withdrawn")
-	return s.db.UpdateBTCDelegationState(
+	_, err = s.updateStateFrom(
 		ctx,
 		delegation.StakingTxHashHex,
+		currentDelegation.State,
 		types.QualifiedStatesForWithdrawn(),
 		types.StateWithdrawn,
 		&subState,
+		db.DelegationStateRecords{
+			BTCDerivedChange: model.NewBTCStateChange(currentDelegation.Summary(), uint64(spendingHeight)),
+			Transition: model.NewBtcStateTransition(
+				delegation.StakingTxHashHex, currentDelegation.State, types.StateWithdrawn, subState,
+				model.StateTransitionTriggerBtcSpend, uint64(spendingHeight), time.Now().Unix(),
+			),
+			OutboxEvent: model.NewWithdrawnStakingOutboxEvent(
+				delegation, subState, spendingTxHashHex, spendingHeight, time.Now().UnixNano(),
+			),
+		},
 	)
+	return err
 }
 
 func (s *Service) startWatchingSlashingChange(
@@ -337,8 +365,7 @@ func (s *Service) startWatchingSlashingChange(
 	delegation *model.BTCDelegationDetails,
 	subState types.DelegationSubState,
 ) error {
-	log.Debug().
-		Str("staking_tx", delegation.StakingTxHashHex).
+	logging.FromContext(ctx).Debug().
 		Str("slashing_tx", slashingTx.TxHash().String()).
 		Msg("watching for slashing change output")
 
@@ -347,11 +374,15 @@ func (s *Service) startWatchingSlashingChange(
 		Hash:  slashingTx.TxHash(),
 		Index: 1, // Change output is always second
 	}
+	changeOutput, err := utils.GetTxOutput(slashingTx, changeOutpoint.Index)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidSlashingTx, err)
+	}
 
 	// Register spend notification for the change output
 	spendEv, err := s.btcNotifier.RegisterSpendNtfn(
 		&changeOutpoint,
-		slashingTx.TxOut[1].PkScript, // Script of change output
+		changeOutput.PkScript, // Script of change output
 		delegation.StartHeight,
 	)
 	if err != nil {
@@ -364,6 +395,16 @@ func (s *Service) startWatchingSlashingChange(
 	}
 	slashingChangeTimelockExpireHeight := spendingHeight + stakingParams.UnbondingTimeBlocks
 
+	if err := s.recordBTCDerivedChange(ctx, &model.BTCDerivedChange{
+		StakingTxHashHex: delegation.StakingTxHashHex,
+		BtcHeight:        uint64(spendingHeight),
+		CreatedTimeLock: model.NewTimeLockDocument(
+			delegation.StakingTxHashHex, slashingChangeTimelockExpireHeight, subState,
+		),
+	}); err != nil {
+		return err
+	}
+
 	// Save timelock expire to mark it as Withdrawn (sub state - timelock_slashing/early_unbonding_slashing)
 	if err := s.db.SaveNewTimeLockExpire(
 		ctx,
@@ -374,12 +415,99 @@ func (s *Service) startWatchingSlashingChange(
 		return fmt.Errorf("failed to save timelock expire: %w", err)
 	}
 
-	s.wg.Add(1)
-	go s.watchForSpendSlashingChange(spendEv, delegation, subState)
+	s.startSpendWatch(func() { s.watchForSpendSlashingChange(spendEv, delegation, subState) })
 
 	return nil
 }
 
+// spendPath is the path through which a tx spends a staking or an unbonding
+// output
+type spendPath int
+
+const (
+	spendPathUnbonding spendPath = iota + 1
+	spendPathWithdrawal
+	spendPathSlashing
+)
+
+// classifyStakingSpend returns the path through which the tx spends the
+// staking output of the delegation. A tx that spends it through none of its
+// paths gets an error wrapping types.ErrInvalidSpendingTx, and one that
+// unlocks the unbonding path but is invalid an error wrapping
+// types.ErrInvalidUnbondingTx.
+func (s *Service) classifyStakingSpend(
+	tx *wire.MsgTx,
+	spendingInputIdx uint32,
+	delegation *model.BTCDelegationDetails,
+	params *bbnclient.StakingParams,
+) (spendPath, error) {
+	// First try to validate as unbonding tx
+	isUnbonding, err := s.IsValidUnbondingTx(tx, delegation, params)
+	if err != nil {
+		return 0, fmt.Errorf("failed to validate unbonding tx: %w", err)
+	}
+	if isUnbonding {
+		return spendPathUnbonding, nil
+	}
+
+	// Try to validate as withdrawal transaction
+	withdrawalErr := s.validateWithdrawalTxFromStaking(tx, spendingInputIdx, delegation, params)
+	if withdrawalErr == nil {
+		return spendPathWithdrawal, nil
+	}
+
+	// If it's not a valid withdrawal, check if it's a valid slashing
+	if !errors.Is(withdrawalErr, types.ErrInvalidWithdrawalTx) {
+		return 0, fmt.Errorf("failed to validate withdrawal tx: %w", withdrawalErr)
+	}
+
+	// Try to validate as slashing transaction
+	if err := s.validateSlashingTxFromStaking(tx, spendingInputIdx, delegation, params); err != nil {
+		if errors.Is(err, types.ErrInvalidSlashingTx) {
+			// Neither withdrawal nor slashing - this is an invalid spend
+			return 0, fmt.Errorf(
+				"%w: transaction is neither valid unbonding, withdrawal, nor slashing: %w",
+				types.ErrInvalidSpendingTx, err,
+			)
+		}
+		return 0, fmt.Errorf("failed to validate slashing tx: %w", err)
+	}
+	return spendPathSlashing, nil
+}
+
+// classifyUnbondingSpend returns the path through which the tx spends the
+// unbonding output of the delegation, an error wrapping
+// types.ErrInvalidSpendingTx if it spends it through none of its paths
+func (s *Service) classifyUnbondingSpend(
+	tx *wire.MsgTx,
+	spendingInputIdx uint32,
+	delegation *model.BTCDelegationDetails,
+	params *bbnclient.StakingParams,
+) (spendPath, error) {
+	// First try to validate as withdrawal transaction
+	withdrawalErr := s.validateWithdrawalTxFromUnbonding(tx, delegation, spendingInputIdx, params)
+	if withdrawalErr == nil {
+		return spendPathWithdrawal, nil
+	}
+
+	// If it's not a valid withdrawal, check if it's a valid slashing
+	if !errors.Is(withdrawalErr, types.ErrInvalidWithdrawalTx) {
+		return 0, fmt.Errorf("failed to validate withdrawal tx: %w", withdrawalErr)
+	}
+
+	// Try to validate as slashing transaction
+	if err := s.validateSlashingTxFromUnbonding(tx, delegation, spendingInputIdx, params); err != nil {
+		if errors.Is(err, types.ErrInvalidSlashingTx) {
+			// Neither withdrawal nor slashing - this is an invalid spend
+			return 0, fmt.Errorf(
+				"%w: transaction is neither valid withdrawal nor slashing: %w", types.ErrInvalidSpendingTx, err,
+			)
+		}
+		return 0, fmt.Errorf("failed to validate slashing tx: %w", err)
+	}
+	return spendPathSlashing, nil
+}
+
 // IsValidUnbondingTx tries to identify a tx is a valid unbonding tx
 // It returns error when (1) it fails to verify the unbonding tx due
 // to invalid parameters, and (2) the tx spends the unbonding path
@@ -391,7 +519,11 @@ func (s *Service) IsValidUnbondingTx(
 ) (bool, error) {
 	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
 	if err != nil {
-		return false, fmt.Errorf("failed to deserialize staking tx: %w", err)
+		return false, fmt.Errorf("%w: failed to deserialize staking tx: %w", types.ErrInvalidStakingTx, err)
+	}
+	stakingOutput, err := utils.GetTxOutput(stakingTx, delegation.StakingOutputIdx)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", types.ErrInvalidStakingTx, err)
 	}
 	stakingTxHash := stakingTx.TxHash()
 
@@ -436,7 +568,7 @@ func (s *Service) IsValidUnbondingTx(
 		return false, err
 	}
 
-	stakingValue := btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value)
+	stakingValue := btcutil.Amount(stakingOutput.Value)
 
 	// 3. re-build the unbonding path script and check whether the script from
 	// the witness matches
@@ -457,13 +589,12 @@ func (s *Service) IsValidUnbondingTx(
 		return false, fmt.Errorf("failed to get the unbonding path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[0].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, 0)
+	if err != nil {
+		// not unbonding tx as it does not reveal a script path
+		return false, nil
 	}
 
-	scriptFromWitness := tx.TxIn[0].Witness[len(tx.TxIn[0].Witness)-2]
-
 	if !bytes.Equal(unbondingPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		// not unbonding tx as it does not unlock the unbonding path
 		return false, nil
@@ -542,12 +673,12 @@ func (s *Service) validateWithdrawalTxFromStaking(
 		return err
 	}
 
-	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	stakingOutput, err := getStakingOutput(delegation)
 	if err != nil {
-		return fmt.Errorf("failed to deserialize staking tx: %w", err)
+		return err
 	}
 
-	stakingValue := btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value)
+	stakingValue := btcutil.Amount(stakingOutput.Value)
 
 	// 3. re-build the unbonding path script and check whether the script from
 	// the witness matches
@@ -569,13 +700,11 @@ func (s *Service) validateWithdrawalTxFromStaking(
 		return fmt.Errorf("failed to get the unbonding path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidWithdrawalTx, err)
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(timelockPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		return fmt.Errorf("%w: the tx does not unlock the time-lock path", types.ErrInvalidWithdrawalTx)
 	}
@@ -617,14 +746,14 @@ func (s *Service) validateWithdrawalTxFromUnbonding(
 		return err
 	}
 
-	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	stakingOutput, err := getStakingOutput(delegation)
 	if err != nil {
-		return fmt.Errorf("failed to deserialize staking tx: %w", err)
+		return err
 	}
 
 	// re-build the time-lock path script and check whether the script from
 	// the witness matches
-	stakingValue := btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value)
+	stakingValue := btcutil.Amount(stakingOutput.Value)
 	unbondingFee := btcutil.Amount(params.UnbondingFeeSat)
 	expectedUnbondingOutputValue := stakingValue - unbondingFee
 	unbondingInfo, err := btcstaking.BuildUnbondingInfo(
@@ -644,13 +773,11 @@ func (s *Service) validateWithdrawalTxFromUnbonding(
 		return fmt.Errorf("failed to get the unbonding path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidWithdrawalTx, err)
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(timelockPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		return fmt.Errorf("%w: the tx does not unlock the time-lock path", types.ErrInvalidWithdrawalTx)
 	}
@@ -692,12 +819,12 @@ func (s *Service) validateSlashingTxFromStaking(
 		return err
 	}
 
-	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	stakingOutput, err := getStakingOutput(delegation)
 	if err != nil {
-		return fmt.Errorf("failed to deserialize staking tx: %w", err)
+		return err
 	}
 
-	stakingValue := btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value)
+	stakingValue := btcutil.Amount(stakingOutput.Value)
 
 	// 3. re-build the unbonding path script and check whether the script from
 	// the witness matches
@@ -719,13 +846,11 @@ func (s *Service) validateSlashingTxFromStaking(
 		return fmt.Errorf("failed to get the slashing path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidSlashingTx, err)
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(slashingPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		return fmt.Errorf("%w: the tx does not unlock the slashing path", types.ErrInvalidSlashingTx)
 	}
@@ -767,14 +892,14 @@ func (s *Service) validateSlashingTxFromUnbonding(
 		return err
 	}
 
-	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	stakingOutput, err := getStakingOutput(delegation)
 	if err != nil {
-		return fmt.Errorf("failed to deserialize staking tx: %w", err)
+		return err
 	}
 
 	// re-build the time-lock path script and check whether the script from
 	// the witness matches
-	stakingValue := btcutil.Amount(stakingTx.TxOut[delegation.StakingOutputIdx].Value)
+	stakingValue := btcutil.Amount(stakingOutput.Value)
 	unbondingFee := btcutil.Amount(params.UnbondingFeeSat)
 	expectedUnbondingOutputValue := stakingValue - unbondingFee
 	unbondingInfo, err := btcstaking.BuildUnbondingInfo(
@@ -794,16 +919,41 @@ func (s *Service) validateSlashingTxFromUnbonding(
 		return fmt.Errorf("failed to get the slashing path spend info: %w", err)
 	}
 
-	witness := tx.TxIn[spendingInputIdx].Witness
-	if len(witness) < 2 {
-		panic(fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness)))
+	scriptFromWitness, err := witnessScript(tx, spendingInputIdx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrInvalidSlashingTx, err)
 	}
 
-	scriptFromWitness := tx.TxIn[spendingInputIdx].Witness[len(tx.TxIn[spendingInputIdx].Witness)-2]
-
 	if !bytes.Equal(slashingPathInfo.GetPkScriptPath(), scriptFromWitness) {
 		return fmt.Errorf("%w: the tx does not unlock the slashing path", types.ErrInvalidSlashingTx)
 	}
 
 	return nil
 }
+
+// getStakingOutput returns the staking output of the delegation, an error
+// wrapping types.ErrInvalidStakingTx if its staking tx does not have it
+func getStakingOutput(delegation *model.BTCDelegationDetails) (*wire.TxOut, error) {
+	stakingTx, err := utils.DeserializeBtcTransactionFromHex(delegation.StakingTxHex)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to deserialize staking tx: %w", types.ErrInvalidStakingTx, err)
+	}
+	stakingOutput, err := utils.GetTxOutput(stakingTx, delegation.StakingOutputIdx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", types.ErrInvalidStakingTx, err)
+	}
+	return stakingOutput, nil
+}
+
+// witnessScript returns the script revealed by the witness of the input of the
+// tx spending a taproot script path, the second to last witness element
+func witnessScript(tx *wire.MsgTx, inputIdx uint32) ([]byte, error) {
+	if uint64(inputIdx) >= uint64(len(tx.TxIn)) {
+		return nil, fmt.Errorf("spending tx has %d inputs, no input %d", len(tx.TxIn), inputIdx)
+	}
+	witness := tx.TxIn[inputIdx].Witness
+	if len(witness) < 2 {
+		return nil, fmt.Errorf("spending tx should have at least 2 elements in witness, got %d", len(witness))
+	}
+	return witness[len(witness)-2], nil
+}
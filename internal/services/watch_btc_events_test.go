@@ -0,0 +1,240 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/config"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/inmemory"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/mocks"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// spendPathFixture is a delegation with the txs spending its staking and
+// unbonding outputs through each of their paths
+type spendPathFixture struct {
+	delegation *model.BTCDelegationDetails
+	params     *bbnclient.StakingParams
+
+	// the spends of the staking output
+	unbondingTx, stakingWithdrawalTx, stakingSlashingTx *wire.MsgTx
+	// the spends of the unbonding output
+	unbondingWithdrawalTx, unbondingSlashingTx *wire.MsgTx
+}
+
+func newSpendPathFixture(t testing.TB) *spendPathFixture {
+	// deterministic keys, so that the fuzzing seeds are stable across runs
+	d := fixtures.NewDelegation(1, 1)
+	return &spendPathFixture{
+		delegation:            fixtureDelegationDetails(t, d),
+		params:                d.Params,
+		unbondingTx:           d.UnbondingTx,
+		stakingWithdrawalTx:   d.StakingWithdrawalTx,
+		stakingSlashingTx:     d.StakingSlashingTx,
+		unbondingWithdrawalTx: d.UnbondingWithdrawalTx,
+		unbondingSlashingTx:   d.UnbondingSlashingTx,
+	}
+}
+
+// fixtureDelegationDetails returns the stored details of the fixture
+// delegation, in no state
+func fixtureDelegationDetails(t testing.TB, d *fixtures.Delegation) *model.BTCDelegationDetails {
+	return &model.BTCDelegationDetails{
+		StakingTxHashHex:          d.StakingTxHashHex(),
+		StakingTxHex:              txHex(t, d.StakingTx),
+		StakingOutputIdx:          fixtures.StakingOutputIdx,
+		StakingTime:               uint32(fixtures.StakingTime),
+		UnbondingTime:             uint32(fixtures.UnbondingTime),
+		StakerBtcPkHex:            d.StakerBtcPkHex,
+		FinalityProviderBtcPksHex: []string{d.FpBtcPkHex},
+		ParamsVersion:             fixtures.StakingParamsVersion,
+		UnbondingTx:               txHex(t, d.UnbondingTx),
+	}
+}
+
+func newSpendPathService() *Service {
+	return NewService(&config.Config{BTC: config.BTCConfig{NetParams: "signet"}}, nil, nil, nil, nil, nil)
+}
+
+func serializeTx(t testing.TB, tx *wire.MsgTx) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, tx.Serialize(&buf))
+	return buf.Bytes()
+}
+
+func TestClassifyStakingSpend(t *testing.T) {
+	fixture := newSpendPathFixture(t)
+	service := newSpendPathService()
+
+	for tx, want := range map[*wire.MsgTx]spendPath{
+		fixture.unbondingTx:         spendPathUnbonding,
+		fixture.stakingWithdrawalTx: spendPathWithdrawal,
+		fixture.stakingSlashingTx:   spendPathSlashing,
+	} {
+		path, err := service.classifyStakingSpend(tx, 0, fixture.delegation, fixture.params)
+		require.NoError(t, err)
+		require.Equal(t, want, path)
+	}
+
+	// A spend of the unbonding output does not unlock any staking path
+	_, err := service.classifyStakingSpend(fixture.unbondingWithdrawalTx, 0, fixture.delegation, fixture.params)
+	require.ErrorIs(t, err, types.ErrInvalidSpendingTx)
+
+	// A key path spend, as well as an input the tx does not have, used to
+	// panic on the witness
+	keyPathSpend := fixture.stakingWithdrawalTx.Copy()
+	keyPathSpend.TxIn[0].Witness = wire.TxWitness{make([]byte, 64)}
+	_, err = service.classifyStakingSpend(keyPathSpend, 0, fixture.delegation, fixture.params)
+	require.ErrorIs(t, err, types.ErrInvalidSpendingTx)
+	_, err = service.classifyStakingSpend(fixture.stakingWithdrawalTx, 1, fixture.delegation, fixture.params)
+	require.ErrorIs(t, err, types.ErrInvalidSpendingTx)
+
+	// An unbonding tx which does not follow the params
+	unbondingTx := fixture.unbondingTx.Copy()
+	unbondingTx.LockTime = 1
+	_, err = service.classifyStakingSpend(unbondingTx, 0, fixture.delegation, fixture.params)
+	require.ErrorIs(t, err, types.ErrInvalidUnbondingTx)
+
+	// A delegation of which the staking tx does not have the staking output
+	delegation := *fixture.delegation
+	delegation.StakingOutputIdx = 2
+	_, err = service.classifyStakingSpend(fixture.stakingWithdrawalTx, 0, &delegation, fixture.params)
+	require.ErrorIs(t, err, types.ErrInvalidStakingTx)
+}
+
+func TestClassifyUnbondingSpend(t *testing.T) {
+	fixture := newSpendPathFixture(t)
+	service := newSpendPathService()
+
+	path, err := service.classifyUnbondingSpend(fixture.unbondingWithdrawalTx, 0, fixture.delegation, fixture.params)
+	require.NoError(t, err)
+	require.Equal(t, spendPathWithdrawal, path)
+	path, err = service.classifyUnbondingSpend(fixture.unbondingSlashingTx, 0, fixture.delegation, fixture.params)
+	require.NoError(t, err)
+	require.Equal(t, spendPathSlashing, path)
+
+	_, err = service.classifyUnbondingSpend(fixture.stakingWithdrawalTx, 0, fixture.delegation, fixture.params)
+	require.ErrorIs(t, err, types.ErrInvalidSpendingTx)
+	_, err = service.classifyUnbondingSpend(wire.NewMsgTx(2), 0, fixture.delegation, fixture.params)
+	require.ErrorIs(t, err, types.ErrInvalidSpendingTx)
+}
+
+// TestHandleSpendingStakingTransactionThroughUnbonding checks that an
+// unbonding spend of the staking output is handled without error
+func TestHandleSpendingStakingTransactionThroughUnbonding(t *testing.T) {
+	ctx := context.Background()
+	fixture := newSpendPathFixture(t)
+	database := inmemory.New()
+	require.NoError(t, database.SaveStakingParams(ctx, fixture.delegation.ParamsVersion, fixture.params))
+	require.NoError(t, database.SaveNewBTCDelegation(ctx, fixture.delegation))
+	service := newSpendPathService()
+	service.db = database
+
+	require.NoError(t, service.handleSpendingStakingTransaction(
+		ctx, fixture.unbondingTx, 0, 101, fixture.delegation.StakingTxHashHex,
+	))
+}
+
+// requireSpendClassification checks that a classification is one of the spend
+// paths or a typed error
+func requireSpendClassification(t *testing.T, path spendPath, err error, paths ...spendPath) {
+	if err != nil {
+		require.True(t,
+			errors.Is(err, types.ErrInvalidSpendingTx) || errors.Is(err, types.ErrInvalidUnbondingTx),
+			"untyped classification error: %v", err,
+		)
+		return
+	}
+	require.Contains(t, paths, path)
+}
+
+// FuzzClassifyStakingSpend checks that the classification of any tx spending
+// the staking output never panics
+func FuzzClassifyStakingSpend(f *testing.F) {
+	fixture := newSpendPathFixture(f)
+	service := newSpendPathService()
+
+	for _, tx := range []*wire.MsgTx{
+		fixture.unbondingTx, fixture.stakingWithdrawalTx, fixture.stakingSlashingTx, fixture.unbondingSlashingTx,
+	} {
+		f.Add(serializeTx(f, tx), uint32(0))
+	}
+	noWitnessTx := fixture.stakingSlashingTx.Copy()
+	noWitnessTx.TxIn[0].Witness = nil
+	f.Add(serializeTx(f, noWitnessTx), uint32(0))
+	f.Add(serializeTx(f, fixture.stakingWithdrawalTx), uint32(1))
+	f.Add(serializeTx(f, wire.NewMsgTx(2)), uint32(0))
+
+	f.Fuzz(func(t *testing.T, txBytes []byte, inputIdx uint32) {
+		tx := wire.NewMsgTx(2)
+		if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+			return
+		}
+		path, err := service.classifyStakingSpend(tx, inputIdx, fixture.delegation, fixture.params)
+		requireSpendClassification(t, path, err, spendPathUnbonding, spendPathWithdrawal, spendPathSlashing)
+	})
+}
+
+// FuzzClassifyUnbondingSpend checks that the classification of any tx
+// spending the unbonding output never panics
+func FuzzClassifyUnbondingSpend(f *testing.F) {
+	fixture := newSpendPathFixture(f)
+	service := newSpendPathService()
+
+	for _, tx := range []*wire.MsgTx{
+		fixture.unbondingWithdrawalTx, fixture.unbondingSlashingTx, fixture.stakingWithdrawalTx,
+	} {
+		f.Add(serializeTx(f, tx), uint32(0))
+	}
+	noWitnessTx := fixture.unbondingWithdrawalTx.Copy()
+	noWitnessTx.TxIn[0].Witness = nil
+	f.Add(serializeTx(f, noWitnessTx), uint32(0))
+	f.Add(serializeTx(f, fixture.unbondingSlashingTx), uint32(3))
+
+	f.Fuzz(func(t *testing.T, txBytes []byte, inputIdx uint32) {
+		tx := wire.NewMsgTx(2)
+		if err := tx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+			return
+		}
+		path, err := service.classifyUnbondingSpend(tx, inputIdx, fixture.delegation, fixture.params)
+		requireSpendClassification(t, path, err, spendPathWithdrawal, spendPathSlashing)
+	})
+}
+
+func TestHandleWithdrawalOutboxFailure(t *testing.T) {
+	ctx := context.Background()
+	delegation := &model.BTCDelegationDetails{
+		StakingTxHashHex: testReprocessTxHash,
+		State:            types.StateWithdrawable,
+		SubState:         types.SubStateTimelock,
+	}
+
+	dbMock := mocks.NewDbInterface(t)
+	dbMock.On("GetBTCDelegationByStakingTxHash", mock.Anything, testReprocessTxHash).Return(delegation, nil)
+	// The transition and the event are saved along with the update
+	withRecords := mock.MatchedBy(func(change db.DelegationStateChange) bool {
+		return change.NewState == types.StateWithdrawn &&
+			change.Transition != nil && change.Transition.ToState == types.StateWithdrawn &&
+			change.OutboxEvent != nil && change.OutboxEvent.EventType == model.OutboxEventTypeWithdrawn &&
+			change.OutboxEvent.SpendingTxHashHex == "spending"
+	})
+	// The outbox insert failing after the update undoes it, for the spend
+	// to be handled again
+	dbMock.On("ApplyBTCDelegationStateChange", mock.Anything, withRecords).
+		Return(errors.New("outbox insert failed")).Once()
+
+	service := NewService(&config.Config{}, dbMock, nil, nil, nil, nil)
+	require.Error(t, service.handleWithdrawal(ctx, delegation, types.SubStateTimelock, "spending", 120))
+
+	dbMock.On("ApplyBTCDelegationStateChange", mock.Anything, withRecords).Return(nil).Once()
+	require.NoError(t, service.handleWithdrawal(ctx, delegation, types.SubStateTimelock, "spending", 120))
+}
@@ -0,0 +1,72 @@
+// Package shutdown runs the stages of the graceful shutdown of the indexer in
+// order, under a single grace period.
+package shutdown
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Coordinator runs the shutdown stages in the order they were added
+type Coordinator struct {
+	gracePeriod time.Duration
+	stages      []stage
+}
+
+type stage struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// Abandoned is a stage not done within the grace period
+type Abandoned struct {
+	Stage string
+	// Err describes what the stage left undone
+	Err error
+}
+
+func New(gracePeriod time.Duration) *Coordinator {
+	return &Coordinator{gracePeriod: gracePeriod}
+}
+
+// Add appends a stage, whose stop function must return once the context is
+// done, with an error describing what it left undone
+func (c *Coordinator) Add(name string, stop func(ctx context.Context) error) {
+	c.stages = append(c.stages, stage{name: name, stop: stop})
+}
+
+// Shutdown runs the stages in order, logging the duration of each of them,
+// and returns the stages abandoned once the grace period expired. The stages
+// after an expiry still run, with the expired context, so that they release
+// what they can without waiting.
+func (c *Coordinator) Shutdown() []Abandoned {
+	ctx, cancel := context.WithTimeout(context.Background(), c.gracePeriod)
+	defer cancel()
+
+	log.Info().Dur("grace_period", c.gracePeriod).Msg("shutting down")
+	start := time.Now()
+	var abandoned []Abandoned
+	for _, stage := range c.stages {
+		stageStart := time.Now()
+		err := stage.stop(ctx)
+		logger := log.With().Str("stage", stage.name).Dur("duration", time.Since(stageStart)).Logger()
+		switch {
+		case err == nil:
+			logger.Info().Msg("shutdown stage completed")
+		case ctx.Err() != nil:
+			abandoned = append(abandoned, Abandoned{Stage: stage.name, Err: err})
+			logger.Error().Err(err).Msg("shutdown stage abandoned, the grace period expired")
+		default:
+			logger.Error().Err(err).Msg("shutdown stage failed")
+		}
+	}
+
+	event := log.Info()
+	if len(abandoned) > 0 {
+		event = log.Warn().Int("abandoned_stages", len(abandoned))
+	}
+	event.Dur("duration", time.Since(start)).Msg("shutdown completed")
+	return abandoned
+}
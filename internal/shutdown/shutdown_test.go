@@ -0,0 +1,53 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownRunsStagesInOrder(t *testing.T) {
+	var ran []string
+	c := New(time.Second)
+	for _, name := range []string{"pollers", "http", "mongo"} {
+		c.Add(name, func(context.Context) error {
+			ran = append(ran, name)
+			return nil
+		})
+	}
+
+	require.Empty(t, c.Shutdown())
+	require.Equal(t, []string{"pollers", "http", "mongo"}, ran)
+}
+
+func TestShutdownAbandonsStagesPastGracePeriod(t *testing.T) {
+	errFailed := errors.New("failed")
+	var ran []string
+	c := New(20 * time.Millisecond)
+	c.Add("failing", func(context.Context) error {
+		ran = append(ran, "failing")
+		// failed within the grace period, not abandoned
+		return errFailed
+	})
+	c.Add("block_processor", func(ctx context.Context) error {
+		ran = append(ran, "block_processor")
+		<-ctx.Done()
+		return errors.New("block 10 abandoned")
+	})
+	c.Add("mongo", func(ctx context.Context) error {
+		ran = append(ran, "mongo")
+		// later stages run with the expired context
+		return ctx.Err()
+	})
+
+	abandoned := c.Shutdown()
+	require.Equal(t, []string{"failing", "block_processor", "mongo"}, ran)
+	require.Len(t, abandoned, 2)
+	require.Equal(t, "block_processor", abandoned[0].Stage)
+	require.EqualError(t, abandoned[0].Err, "block 10 abandoned")
+	require.Equal(t, "mongo", abandoned[1].Stage)
+	require.ErrorIs(t, abandoned[1].Err, context.DeadlineExceeded)
+}
@@ -0,0 +1,257 @@
+// Package supervisor runs the long-lived background components of the
+// indexer, recovering their panics and restarting them on failure.
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+// defaultStableAfter is how long a run lasts before its failure no longer
+// counts as consecutive to the former ones
+const defaultStableAfter = time.Minute
+
+// States of a supervised component
+const (
+	StateRunning = "running"
+	// StateRestarting waits for the restart backoff after a failed run
+	StateRestarting = "restarting"
+	// StateCompleted returned without error, and is not restarted
+	StateCompleted = "completed"
+	// StateStopped returned once its context was done
+	StateStopped = "stopped"
+	// StateGaveUp failed more times in a row than its policy allows, the
+	// process exiting
+	StateGaveUp = "gave_up"
+)
+
+// Policy tells how a failed component is restarted: right away without
+// Backoff, else after the backoff, doubled on each consecutive failure up to
+// MaxBackoff
+type Policy struct {
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// MaxRestarts is the number of consecutive restarts after which the
+	// supervisor gives up and exits the process, zero for no limit
+	MaxRestarts int
+	// StableAfter is how long a run lasts before the consecutive failures
+	// are reset, a minute if zero
+	StableAfter time.Duration
+}
+
+// Immediate restarts a failed component right away, without limit
+func Immediate() Policy {
+	return Policy{}
+}
+
+// WithBackoff restarts a failed component after the backoff, doubled on each
+// consecutive failure up to the max backoff, without limit
+func WithBackoff(backoff, maxBackoff time.Duration) Policy {
+	return Policy{Backoff: backoff, MaxBackoff: maxBackoff}
+}
+
+// GiveUpAfter returns the policy exiting the process once the component
+// failed more than the given number of restarts in a row
+func (p Policy) GiveUpAfter(maxRestarts int) Policy {
+	p.MaxRestarts = maxRestarts
+	return p
+}
+
+func (p Policy) stableAfter() time.Duration {
+	if p.StableAfter == 0 {
+		return defaultStableAfter
+	}
+	return p.StableAfter
+}
+
+// nextBackoff returns the backoff following the given one
+func (p Policy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, p.MaxBackoff)
+	}
+	return backoff
+}
+
+// Status is the state of a supervised component, exposed by the health probes
+type Status struct {
+	Name     string
+	State    string
+	Restarts int
+	// LastError is the error of the last failed run, if any
+	LastError    string
+	LastFailedAt time.Time
+}
+
+// Component is a component run by the supervisor
+type Component struct {
+	name   string
+	policy Policy
+	run    func(ctx context.Context) error
+	// done is closed once the component is no longer run
+	done chan struct{}
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Done returns a channel closed once the component is no longer run, i.e.
+// completed, stopped or given up
+func (c *Component) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Component) setState(state string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.State = state
+}
+
+func (c *Component) recordFailure(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.LastError = err.Error()
+	c.status.LastFailedAt = time.Now()
+}
+
+func (c *Component) recordRestart() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status.State = StateRestarting
+	c.status.Restarts++
+}
+
+// Option customizes the supervisor
+type Option func(*Supervisor)
+
+// WithExit replaces how the process exits once a component gave up, e.g. in
+// the tests
+func WithExit(exit func(name string, err error)) Option {
+	return func(s *Supervisor) {
+		s.exit = exit
+	}
+}
+
+// Supervisor runs the components in goroutines of their own
+type Supervisor struct {
+	mu         sync.Mutex
+	components []*Component
+	exit       func(name string, err error)
+}
+
+func New(opts ...Option) *Supervisor {
+	s := &Supervisor{
+		exit: func(name string, err error) {
+			log.Fatal().Err(err).Str("component", name).Msg("supervised component gave up, exiting")
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Go runs the component in a goroutine until its context is done. A run
+// returning nil completes the component, a run failing or panicking restarts
+// it according to the policy.
+func (s *Supervisor) Go(
+	ctx context.Context, name string, policy Policy, run func(ctx context.Context) error,
+) *Component {
+	c := &Component{
+		name:   name,
+		policy: policy,
+		run:    run,
+		done:   make(chan struct{}),
+		status: Status{Name: name, State: StateRunning},
+	}
+	s.mu.Lock()
+	s.components = append(s.components, c)
+	s.mu.Unlock()
+
+	go s.supervise(ctx, c)
+	return c
+}
+
+// Statuses returns the status of the components, in the order they started
+func (s *Supervisor) Statuses() []Status {
+	s.mu.Lock()
+	components := append([]*Component{}, s.components...)
+	s.mu.Unlock()
+
+	statuses := make([]Status, len(components))
+	for i, c := range components {
+		c.mu.Lock()
+		statuses[i] = c.status
+		c.mu.Unlock()
+	}
+	return statuses
+}
+
+func (s *Supervisor) supervise(ctx context.Context, c *Component) {
+	defer close(c.done)
+	logger := log.With().Str("component", c.name).Logger()
+
+	backoff := c.policy.Backoff
+	failures := 0
+	for {
+		c.setState(StateRunning)
+		startedAt := time.Now()
+		err := runRecovered(ctx, c)
+		switch {
+		case ctx.Err() != nil:
+			c.setState(StateStopped)
+			logger.Info().Msg("supervised component stopped")
+			return
+		case err == nil:
+			c.setState(StateCompleted)
+			logger.Info().Msg("supervised component completed")
+			return
+		}
+
+		if time.Since(startedAt) >= c.policy.stableAfter() {
+			failures = 0
+			backoff = c.policy.Backoff
+		}
+		failures++
+		c.recordFailure(err)
+		if c.policy.MaxRestarts > 0 && failures > c.policy.MaxRestarts {
+			c.setState(StateGaveUp)
+			logger.Error().Err(err).Int("failures", failures).Msg("supervised component failed too many times in a row")
+			s.exit(c.name, err)
+			return
+		}
+
+		c.recordRestart()
+		metrics.RecordSupervisedRestart(c.name)
+		logger.Warn().Err(err).
+			Int("failures", failures).
+			Dur("restart_in", backoff).
+			Msg("supervised component failed, restarting")
+		select {
+		case <-ctx.Done():
+			c.setState(StateStopped)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = c.policy.nextBackoff(backoff)
+	}
+}
+
+// runRecovered runs the component once, a panic failing the run
+func runRecovered(ctx context.Context, c *Component) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("component", c.name).Interface("panic", r).
+				Bytes("stack", debug.Stack()).Msg("recovered from a supervised component panic")
+			err = fmt.Errorf("component %s panicked: %v", c.name, r)
+		}
+	}()
+	return c.run(ctx)
+}
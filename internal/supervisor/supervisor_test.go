@@ -0,0 +1,132 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/observability/metrics"
+)
+
+func TestSupervisorRestartsFailedComponent(t *testing.T) {
+	metrics.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New()
+
+	// The component fails, then panics, then runs until stopped
+	var runs atomic.Int32
+	running := make(chan struct{})
+	c := s.Go(ctx, "listener", Immediate(), func(ctx context.Context) error {
+		switch runs.Add(1) {
+		case 1:
+			return errors.New("connection lost")
+		case 2:
+			panic("unexpected event")
+		}
+		close(running)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	<-running
+	status := s.Statuses()[0]
+	require.Equal(t, "listener", status.Name)
+	require.Equal(t, StateRunning, status.State)
+	require.Equal(t, 2, status.Restarts)
+	require.Equal(t, "component listener panicked: unexpected event", status.LastError)
+
+	cancel()
+	<-c.Done()
+	require.Equal(t, StateStopped, s.Statuses()[0].State)
+}
+
+func TestSupervisorCompletesComponent(t *testing.T) {
+	s := New()
+	c := s.Go(context.Background(), "resubscribe", Immediate(), func(context.Context) error {
+		return nil
+	})
+
+	<-c.Done()
+	require.Equal(t, []Status{{Name: "resubscribe", State: StateCompleted}}, s.Statuses())
+}
+
+func TestSupervisorBacksOffRestarts(t *testing.T) {
+	metrics.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New()
+
+	var startedAt []time.Time
+	restarted := make(chan struct{})
+	s.Go(ctx, "watcher", WithBackoff(20*time.Millisecond, 30*time.Millisecond), func(ctx context.Context) error {
+		startedAt = append(startedAt, time.Now())
+		if len(startedAt) == 4 {
+			close(restarted)
+			<-ctx.Done()
+			return nil
+		}
+		return errors.New("failed")
+	})
+
+	<-restarted
+	// The backoff doubles up to its max
+	require.GreaterOrEqual(t, startedAt[1].Sub(startedAt[0]), 20*time.Millisecond)
+	require.GreaterOrEqual(t, startedAt[2].Sub(startedAt[1]), 30*time.Millisecond)
+	require.GreaterOrEqual(t, startedAt[3].Sub(startedAt[2]), 30*time.Millisecond)
+}
+
+func TestSupervisorGivesUpAfterMaxRestarts(t *testing.T) {
+	metrics.Init()
+	type exit struct {
+		name string
+		err  error
+	}
+	exited := make(chan exit, 1)
+	s := New(WithExit(func(name string, err error) {
+		exited <- exit{name, err}
+	}))
+
+	errFailed := errors.New("failed")
+	var runs atomic.Int32
+	c := s.Go(context.Background(), "api", Immediate().GiveUpAfter(2), func(context.Context) error {
+		runs.Add(1)
+		return errFailed
+	})
+
+	<-c.Done()
+	require.Equal(t, exit{"api", errFailed}, <-exited)
+	// The first run and the two restarts
+	require.Equal(t, int32(3), runs.Load())
+	status := s.Statuses()[0]
+	require.Equal(t, StateGaveUp, status.State)
+	require.Equal(t, 2, status.Restarts)
+}
+
+func TestSupervisorResetsFailuresOfStableRuns(t *testing.T) {
+	metrics.Init()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := New(WithExit(func(string, error) {
+		t.Error("gave up on a component failing after stable runs")
+	}))
+
+	policy := Immediate().GiveUpAfter(1)
+	policy.StableAfter = time.Millisecond
+	var runs atomic.Int32
+	c := s.Go(ctx, "poller", policy, func(ctx context.Context) error {
+		if runs.Add(1) == 4 {
+			return nil
+		}
+		time.Sleep(2 * time.Millisecond)
+		return errors.New("failed")
+	})
+
+	<-c.Done()
+	require.Equal(t, StateCompleted, s.Statuses()[0].State)
+	require.Equal(t, 3, s.Statuses()[0].Restarts)
+}
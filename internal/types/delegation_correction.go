@@ -0,0 +1,9 @@
+package types
+
+// DelegationCorrection is a field of an indexed delegation changed to match
+// its BBN chain state
+type DelegationCorrection struct {
+	Field string
+	From  string
+	To    string
+}
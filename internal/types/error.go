@@ -1,7 +1,9 @@
 package types
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 )
 
@@ -17,9 +19,12 @@ const (
 	ValidationError      ErrorCode = "VALIDATION_ERROR"
 	NotFound             ErrorCode = "NOT_FOUND"
 	BadRequest           ErrorCode = "BAD_REQUEST"
+	Unauthorized         ErrorCode = "UNAUTHORIZED"
 	Forbidden            ErrorCode = "FORBIDDEN"
+	Conflict             ErrorCode = "CONFLICT"
 	UnprocessableEntity  ErrorCode = "UNPROCESSABLE_ENTITY"
 	RequestTimeout       ErrorCode = "REQUEST_TIMEOUT"
+	ServiceUnavailable   ErrorCode = "SERVICE_UNAVAILABLE"
 	ClientRequestError   ErrorCode = "CLIENT_REQUEST_ERROR"
 )
 
@@ -36,6 +41,56 @@ func (e *Error) Error() string {
 	return e.Err.Error()
 }
 
+// Unwrap returns the underlying error, so that errors.Is and errors.As see
+// through the service errors
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is tells whether the target is a service error of the same error code, and
+// of the same status code unless the target leaves it uninitialized, so that
+// errors.Is(err, &Error{ErrorCode: NotFound}) matches the not found errors
+// whatever their message
+func (e *Error) Is(target error) bool {
+	targetErr, ok := target.(*Error)
+	if !ok || targetErr.ErrorCode != e.ErrorCode {
+		return false
+	}
+	return targetErr.StatusCode == UninitializedStatusCode || targetErr.StatusCode == e.StatusCode
+}
+
+// InternalErrorMessage replaces the message of the 5xx errors in the API
+// responses, not to leak their internals
+const InternalErrorMessage = "Internal service error"
+
+// ErrorEnvelope is the body of the failed API responses, its fields being
+// stable whatever the error
+type ErrorEnvelope struct {
+	ErrorCode string `json:"errorCode"`
+	Message   string `json:"message"`
+	// RequestID is the ID of the request answered with a 5xx, to look its
+	// error up in the logs
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// Envelope returns the response body of the error, with the generic message
+// in place of the one of a 5xx error
+func (e *Error) Envelope() ErrorEnvelope {
+	message := e.Error()
+	if e.StatusCode >= http.StatusInternalServerError {
+		message = InternalErrorMessage
+	}
+	return ErrorEnvelope{
+		ErrorCode: e.ErrorCode.String(),
+		Message:   message,
+	}
+}
+
+// MarshalJSON encodes the error as its envelope
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Envelope())
+}
+
 // NewError creates a new ApiError with the provided status code, error code, and underlying error.
 // If the status code is not provided (0), it defaults to http.StatusInternalServerError(500).
 // If the error code is empty, it defaults to INTERNAL_SERVICE_ERROR.
@@ -57,6 +112,16 @@ func NewErrorWithMsg(statusCode int, errorCode ErrorCode, msg string) *Error {
 	return NewError(statusCode, errorCode, errors.New(msg))
 }
 
+// Wrap creates a new ApiError with the provided status code and error code,
+// whose message prefixes the one of the wrapped error. The wrapped error stays
+// in the chain of the new one, as seen by errors.Is and errors.As.
+func Wrap(err error, statusCode int, errorCode ErrorCode, msg string) *Error {
+	if err == nil {
+		return NewErrorWithMsg(statusCode, errorCode, msg)
+	}
+	return NewError(statusCode, errorCode, fmt.Errorf("%s: %w", msg, err))
+}
+
 func NewInternalServiceError(err error) *Error {
 	return &Error{
 		StatusCode: http.StatusInternalServerError,
@@ -85,4 +150,19 @@ var (
 
 	// ErrInvalidSlashingTx the slashing transaction is invalid as it does not unlock the expected slashing path
 	ErrInvalidSlashingTx = errors.New("invalid slashing tx")
+
+	// ErrInvalidSpendingTx the transaction spends a staking or unbonding output through none of its paths
+	ErrInvalidSpendingTx = errors.New("invalid spending tx")
+
+	// ErrBtcReorgTooDeep the BTC reorg is deeper than the configured limit and is not rolled back automatically
+	ErrBtcReorgTooDeep = errors.New("BTC reorg too deep")
+
+	// ErrBbnForkDetected the BBN node serves a chain that differs from the processed one
+	ErrBbnForkDetected = errors.New("BBN fork detected")
+
+	// ErrParamsMismatch the stored params differ from the ones of the connected BBN node
+	ErrParamsMismatch = errors.New("params mismatch")
+
+	// ErrBtcNetworkMismatch the configured BTC network differs from the BTC backend or the BBN chain one
+	ErrBtcNetworkMismatch = errors.New("BTC network mismatch")
 )
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// chainedError is an error type found by errors.As through the chains
+type chainedError struct {
+	reason string
+}
+
+func (e *chainedError) Error() string {
+	return e.reason
+}
+
+func TestErrorChains(t *testing.T) {
+	// types.Error around fmt.Errorf %w around a sentinel
+	err := NewInternalServiceError(fmt.Errorf("failed to validate: %w", ErrInvalidStakingTx))
+	require.ErrorIs(t, err, ErrInvalidStakingTx)
+	require.NotErrorIs(t, err, ErrInvalidUnbondingTx)
+	require.Equal(t, "failed to validate: invalid staking tx", err.Error())
+
+	// wrapped again, by another service error and by fmt.Errorf
+	wrapped := fmt.Errorf("processing failed: %w",
+		Wrap(err, http.StatusBadRequest, ValidationError, "bad delegation"),
+	)
+	require.ErrorIs(t, wrapped, ErrInvalidStakingTx)
+	require.Equal(t, "processing failed: bad delegation: failed to validate: invalid staking tx", wrapped.Error())
+
+	var serviceErr *Error
+	require.ErrorAs(t, wrapped, &serviceErr)
+	require.Equal(t, http.StatusBadRequest, serviceErr.StatusCode)
+	require.Equal(t, ValidationError, serviceErr.ErrorCode)
+
+	var typed *chainedError
+	typedErr := Wrap(fmt.Errorf("lookup: %w", &chainedError{reason: "gone"}), http.StatusNotFound, NotFound, "delegation")
+	require.ErrorAs(t, fmt.Errorf("api: %w", typedErr), &typed)
+	require.Equal(t, "gone", typed.reason)
+	require.Equal(t, "delegation: lookup: gone", typedErr.Error())
+}
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("get delegation: %w",
+		NewErrorWithMsg(http.StatusNotFound, NotFound, "delegation not found"),
+	)
+
+	// the service errors match by error code, and by status code if set
+	require.ErrorIs(t, err, &Error{ErrorCode: NotFound})
+	require.ErrorIs(t, err, &Error{ErrorCode: NotFound, StatusCode: http.StatusNotFound})
+	require.NotErrorIs(t, err, &Error{ErrorCode: NotFound, StatusCode: http.StatusGone})
+	require.NotErrorIs(t, err, &Error{ErrorCode: InternalServiceError})
+	require.NotErrorIs(t, err, errors.New("delegation not found"))
+}
+
+func TestWrapNil(t *testing.T) {
+	err := Wrap(nil, http.StatusConflict, Conflict, "state changed")
+	require.Equal(t, "state changed", err.Error())
+	require.Equal(t, http.StatusConflict, err.StatusCode)
+	require.Nil(t, errors.Unwrap(errors.Unwrap(err)))
+}
+
+func TestErrorMarshalJSON(t *testing.T) {
+	body, err := json.Marshal(NewErrorWithMsg(http.StatusNotFound, NotFound, "delegation not found"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"errorCode":"NOT_FOUND","message":"delegation not found"}`, string(body))
+
+	// the message of the internal errors is not leaked
+	body, err = json.Marshal(NewInternalServiceError(errors.New("connection refused")))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"errorCode":"INTERNAL_SERVICE_ERROR","message":"Internal service error"}`, string(body))
+}
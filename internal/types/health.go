@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// HealthCheck is the outcome of a single check of a health probe
+type HealthCheck struct {
+	Name    string
+	Healthy bool
+	// Message details the failure, or the checked value on success
+	Message  string
+	Duration time.Duration
+}
+
+// HealthReport is the outcome of a health probe, healthy only if all its
+// checks are
+type HealthReport struct {
+	Healthy bool
+	Checks  []HealthCheck
+}
+
+func NewHealthReport(checks []HealthCheck) *HealthReport {
+	healthy := true
+	for _, check := range checks {
+		healthy = healthy && check.Healthy
+	}
+	return &HealthReport{Healthy: healthy, Checks: checks}
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Labels of the mongo errors on which the operation can be retried
+const (
+	transientTransactionErrorLabel = "TransientTransactionError"
+	retryableWriteErrorLabel       = "RetryableWriteError"
+)
+
+// Retryable is implemented by the errors telling whether the operation that
+// failed with them is worth retrying
+type Retryable interface {
+	Retryable() bool
+}
+
+// classifiedError is an error classified as transient or permanent by its
+// source, whatever the errors it wraps
+type classifiedError struct {
+	err       error
+	retryable bool
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Retryable() bool {
+	return e.retryable
+}
+
+// NewTransientError classifies the error as transient, the operation failing
+// with it being worth retrying
+func NewTransientError(err error) error {
+	return &classifiedError{err: err, retryable: true}
+}
+
+// NewPermanentError classifies the error as permanent, the operation failing
+// with it failing again whatever the retries
+func NewPermanentError(err error) error {
+	return &classifiedError{err: err, retryable: false}
+}
+
+// Retryable tells whether the operation that failed with the service error is
+// worth retrying: the client errors are not, but for the timeouts and the
+// rate limits, and the others are as their cause
+func (e *Error) Retryable() bool {
+	switch {
+	case e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests:
+		return true
+	case e.StatusCode >= 400 && e.StatusCode < 500:
+		return false
+	default:
+		return IsRetryable(e.Err)
+	}
+}
+
+// IsRetryable tells whether the operation that failed with the error is worth
+// retrying. The first error of the chain implementing Retryable decides. The
+// timeouts, the cancellations and the transient mongo errors are retryable,
+// the parse errors and the invalid txs are not. The other errors are deemed
+// retryable, not to drop data on an unexpected error.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var retryable Retryable
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+
+	var (
+		labeledErr   mongo.LabeledError
+		netErr       net.Error
+		numErr       *strconv.NumError
+		syntaxErr    *json.SyntaxError
+		typeErr      *json.UnmarshalTypeError
+		invalidByte  hex.InvalidByteError
+		invalidState *InvalidStateError
+	)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		return true
+	case mongo.IsTimeout(err) || mongo.IsNetworkError(err):
+		return true
+	case errors.As(err, &labeledErr) && (labeledErr.HasErrorLabel(transientTransactionErrorLabel) ||
+		labeledErr.HasErrorLabel(retryableWriteErrorLabel)):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	case mongo.IsDuplicateKeyError(err):
+		return false
+	case errors.As(err, &numErr) || errors.As(err, &syntaxErr) || errors.As(err, &typeErr) ||
+		errors.As(err, &invalidByte) || errors.Is(err, hex.ErrLength) || errors.As(err, &invalidState):
+		return false
+	case errors.Is(err, ErrInvalidStakingTx) || errors.Is(err, ErrInvalidUnbondingTx) ||
+		errors.Is(err, ErrInvalidWithdrawalTx) || errors.Is(err, ErrInvalidSlashingTx):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIsRetryable(t *testing.T) {
+	_, numErr := strconv.ParseUint("x", 10, 32)
+	_, hexErr := hex.DecodeString("zz")
+	jsonErr := json.Unmarshal([]byte("{"), &struct{}{})
+	_, stateErr := ParseDelegationState("UNKNOWN")
+
+	testCases := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{"nil", nil, false},
+		{"unknown", errors.New("boom"), true},
+		{"transient", NewTransientError(ErrInvalidStakingTx), true},
+		{"permanent", NewPermanentError(context.DeadlineExceeded), false},
+		{"deadline exceeded", fmt.Errorf("rpc: %w", context.DeadlineExceeded), true},
+		{"canceled", context.Canceled, true},
+		{"mongo transient label", mongo.CommandError{Labels: []string{"TransientTransactionError"}}, true},
+		{"mongo retryable write", mongo.WriteException{Labels: []string{"RetryableWriteError"}}, true},
+		{"mongo duplicate key", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, false},
+		{"number parse", numErr, false},
+		{"hex parse", hexErr, false},
+		{"json parse", jsonErr, false},
+		{"invalid state", stateErr, false},
+		{"invalid tx", fmt.Errorf("validate: %w", ErrInvalidSlashingTx), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.retryable, IsRetryable(tc.err))
+		})
+	}
+}
+
+func TestServiceErrorRetryable(t *testing.T) {
+	// the client errors are permanent, but for the timeouts and rate limits
+	require.False(t, IsRetryable(NewValidationFailedError(context.DeadlineExceeded)))
+	require.False(t, IsRetryable(NewErrorWithMsg(http.StatusConflict, Conflict, "state changed")))
+	require.True(t, IsRetryable(NewErrorWithMsg(http.StatusRequestTimeout, RequestTimeout, "timeout")))
+	require.True(t, IsRetryable(NewErrorWithMsg(http.StatusTooManyRequests, ClientRequestError, "slow down")))
+
+	// the others are as their cause, the first classification found deciding
+	require.True(t, IsRetryable(NewInternalServiceError(errors.New("db down"))))
+	require.False(t, IsRetryable(NewInternalServiceError(
+		fmt.Errorf("handle: %w", NewPermanentError(errors.New("corrupt"))),
+	)))
+	require.True(t, IsRetryable(fmt.Errorf("process: %w", NewInternalServiceError(
+		NewTransientError(NewPermanentError(errors.New("flaky"))),
+	))))
+
+	err := NewPermanentError(ErrInvalidStakingTx)
+	require.ErrorIs(t, err, ErrInvalidStakingTx)
+	require.Equal(t, ErrInvalidStakingTx.Error(), err.Error())
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// StakingTxHash is the hash of a staking tx as the delegations are stored and
+// looked up by: hex encoded lowercase, in the display order of the BTC
+// explorers, the BBN events and chainhash.Hash.String(). The staking tx hashes
+// coming from the BBN events, the BTC node or the API requests are turned into
+// one at the edge, so that the lookups do not depend on their source.
+type StakingTxHash string
+
+// NewStakingTxHash validates the hex encoded staking tx hash, in display
+// order and in any case, and returns it normalized
+func NewStakingTxHash(hexStr string) (StakingTxHash, error) {
+	txHash, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return "", fmt.Errorf("staking tx hash %q is not valid hex: %w", hexStr, err)
+	}
+	return StakingTxHashFromDisplayOrder(txHash)
+}
+
+// StakingTxHashFromDisplayOrder returns the staking tx hash of the bytes in
+// display order, as decoded from the hex of a BTC explorer
+func StakingTxHashFromDisplayOrder(txHash []byte) (StakingTxHash, error) {
+	if len(txHash) != chainhash.HashSize {
+		return "", fmt.Errorf(
+			"staking tx hash must be %d bytes long, got %d", chainhash.HashSize, len(txHash),
+		)
+	}
+	return StakingTxHash(hex.EncodeToString(txHash)), nil
+}
+
+// StakingTxHashFromInternalOrder returns the staking tx hash of the bytes in
+// the internal order of the BTC node, the reverse of the display order, as
+// found in chainhash.Hash or the serialized txs
+func StakingTxHashFromInternalOrder(txHash []byte) (StakingTxHash, error) {
+	if len(txHash) != chainhash.HashSize {
+		return "", fmt.Errorf(
+			"staking tx hash must be %d bytes long, got %d", chainhash.HashSize, len(txHash),
+		)
+	}
+	hash, err := chainhash.NewHash(txHash)
+	if err != nil {
+		return "", err
+	}
+	return StakingTxHashFromChainHash(hash), nil
+}
+
+// StakingTxHashFromChainHash returns the staking tx hash of the BTC tx hash
+func StakingTxHashFromChainHash(hash *chainhash.Hash) StakingTxHash {
+	return StakingTxHash(hash.String())
+}
+
+func (h StakingTxHash) String() string {
+	return string(h)
+}
+
+// ChainHash returns the BTC tx hash of the staking tx hash
+func (h StakingTxHash) ChainHash() (*chainhash.Hash, error) {
+	return chainhash.NewHashFromStr(string(h))
+}
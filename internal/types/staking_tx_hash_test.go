@@ -0,0 +1,104 @@
+package types
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+)
+
+const testStakingTxHashHex = "0d0a4d19e0e6f6a4d4a3b8f6e1c2b3a495867768594a3b2c1d0e0f1a2b3c4d5e"
+
+func TestNewStakingTxHash(t *testing.T) {
+	stakingTxHash, err := NewStakingTxHash(strings.ToUpper(testStakingTxHashHex))
+	require.NoError(t, err)
+	require.Equal(t, StakingTxHash(testStakingTxHashHex), stakingTxHash)
+
+	hash, err := stakingTxHash.ChainHash()
+	require.NoError(t, err)
+	require.Equal(t, testStakingTxHashHex, hash.String())
+	require.Equal(t, stakingTxHash, StakingTxHashFromChainHash(hash))
+
+	for _, invalid := range []string{"", "0d0a", testStakingTxHashHex + "00", "zz" + testStakingTxHashHex[2:], " " + testStakingTxHashHex} {
+		_, err := NewStakingTxHash(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestStakingTxHashOrders(t *testing.T) {
+	displayOrder, err := hex.DecodeString(testStakingTxHashHex)
+	require.NoError(t, err)
+	hash, err := chainhash.NewHashFromStr(testStakingTxHashHex)
+	require.NoError(t, err)
+
+	fromDisplay, err := StakingTxHashFromDisplayOrder(displayOrder)
+	require.NoError(t, err)
+	// chainhash.Hash holds the bytes in internal order
+	fromInternal, err := StakingTxHashFromInternalOrder(hash[:])
+	require.NoError(t, err)
+	require.Equal(t, StakingTxHash(testStakingTxHashHex), fromDisplay)
+	require.Equal(t, fromDisplay, fromInternal)
+
+	_, err = StakingTxHashFromInternalOrder(displayOrder[1:])
+	require.Error(t, err)
+}
+
+func FuzzNewStakingTxHash(f *testing.F) {
+	f.Add(testStakingTxHashHex, uint64(0))
+	f.Add(testStakingTxHashHex, uint64(0xffffffffffffffff))
+	f.Add("0d0a", uint64(1))
+	f.Add("not a hash", uint64(2))
+
+	f.Fuzz(func(t *testing.T, input string, casing uint64) {
+		// vary the casing of the input
+		var mixed strings.Builder
+		for i, r := range input {
+			if casing&(1<<(i%64)) != 0 {
+				r = unicode.ToUpper(r)
+			}
+			mixed.WriteRune(r)
+		}
+
+		stakingTxHash, err := NewStakingTxHash(mixed.String())
+		decoded, decodeErr := hex.DecodeString(input)
+		if decodeErr != nil || len(decoded) != chainhash.HashSize {
+			require.Error(t, err)
+			return
+		}
+
+		require.NoError(t, err)
+		require.Equal(t, StakingTxHash(strings.ToLower(input)), stakingTxHash)
+		// normalizing is idempotent
+		again, err := NewStakingTxHash(stakingTxHash.String())
+		require.NoError(t, err)
+		require.Equal(t, stakingTxHash, again)
+	})
+}
+
+func FuzzStakingTxHashOrders(f *testing.F) {
+	f.Add(make([]byte, chainhash.HashSize))
+	f.Add([]byte("0123456789abcdef0123456789abcdef"))
+	f.Add([]byte{1, 2, 3})
+
+	f.Fuzz(func(t *testing.T, txHash []byte) {
+		fromDisplay, err := StakingTxHashFromDisplayOrder(txHash)
+		if len(txHash) != chainhash.HashSize {
+			require.Error(t, err)
+			_, err = StakingTxHashFromInternalOrder(txHash)
+			require.Error(t, err)
+			return
+		}
+		require.NoError(t, err)
+
+		reversed := make([]byte, len(txHash))
+		for i := range txHash {
+			reversed[len(txHash)-1-i] = txHash[i]
+		}
+		fromInternal, err := StakingTxHashFromInternalOrder(reversed)
+		require.NoError(t, err)
+		require.Equal(t, fromDisplay, fromInternal)
+	})
+}
@@ -1,6 +1,11 @@
 package types
 
-import bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
 
 // Enum values for Delegation State
 type DelegationState string
@@ -19,36 +24,54 @@ func (s DelegationState) String() string {
 	return string(s)
 }
 
-// QualifiedStatesForCovenantQuorumReached returns the qualified current states for CovenantQuorumReached event
-func QualifiedStatesForCovenantQuorumReached(babylonState string) []DelegationState {
-	switch babylonState {
-	case bbntypes.BTCDelegationStatus_VERIFIED.String(), bbntypes.BTCDelegationStatus_ACTIVE.String():
-		return []DelegationState{StatePending}
-	default:
-		return nil
+// AllDelegationStates returns every delegation state
+func AllDelegationStates() []DelegationState {
+	return []DelegationState{
+		StatePending, StateVerified, StateActive, StateUnbonding,
+		StateWithdrawable, StateWithdrawn, StateSlashed,
 	}
 }
 
-// QualifiedStatesForInclusionProofReceived returns the qualified current states for InclusionProofReceived event
-func QualifiedStatesForInclusionProofReceived(babylonState string) []DelegationState {
-	switch babylonState {
-	case bbntypes.BTCDelegationStatus_ACTIVE.String():
-		return []DelegationState{StateVerified}
-	case bbntypes.BTCDelegationStatus_PENDING.String():
-		return []DelegationState{StatePending}
-	default:
-		return nil
-	}
+// delegationStateTransitions is the delegation state machine, the states a
+// delegation can move to from every state. The state updates it does not
+// allow are rejected by the database, whatever the qualified previous states
+// of their caller.
+var delegationStateTransitions = map[DelegationState][]DelegationState{
+	// The covenant quorum verifies a delegation, or activates it if its
+	// inclusion proof was received already
+	StatePending:  {StateVerified, StateActive, StateSlashed},
+	StateVerified: {StateActive, StateSlashed},
+	// An active delegation unbonds early or expires, and its staking output
+	// can be spent by a withdrawal tx seen before the expiry
+	StateActive:       {StateUnbonding, StateWithdrawn, StateSlashed},
+	StateUnbonding:    {StateWithdrawable, StateWithdrawn, StateSlashed},
+	StateWithdrawable: {StateWithdrawn, StateSlashed},
+	// The slashing change output is withdrawable once its timelock expires
+	StateSlashed:   {StateWithdrawable, StateWithdrawn},
+	StateWithdrawn: {},
 }
 
-// QualifiedStatesForUnbondedEarly returns the qualified current states for UnbondedEarly event
-func QualifiedStatesForUnbondedEarly() []DelegationState {
-	return []DelegationState{StateActive}
+// CanTransitionTo tells whether a delegation in the state can move to the
+// target state
+func (s DelegationState) CanTransitionTo(target DelegationState) bool {
+	for _, state := range delegationStateTransitions[s] {
+		if state == target {
+			return true
+		}
+	}
+	return false
 }
 
-// QualifiedStatesForExpired returns the qualified current states for Expired event
-func QualifiedStatesForExpired() []DelegationState {
-	return []DelegationState{StateActive}
+// QualifiedPreviousStates returns the states a delegation can move to the
+// target state from, none for the states no delegation moves to
+func QualifiedPreviousStates(target DelegationState) []DelegationState {
+	var states []DelegationState
+	for _, state := range AllDelegationStates() {
+		if state.CanTransitionTo(target) {
+			states = append(states, state)
+		}
+	}
+	return states
 }
 
 // QualifiedStatesForWithdrawn returns the qualified current states for Withdrawn event
@@ -56,12 +79,23 @@ func QualifiedStatesForWithdrawn() []DelegationState {
 	// StateActive/StateUnbonding/StateSlashed is included b/c its possible that expiry checker
 	// or babylon notifications are slow and in meanwhile the btc subscription encounters
 	// the spending/withdrawal tx
-	return []DelegationState{StateActive, StateUnbonding, StateWithdrawable, StateSlashed}
+	return QualifiedPreviousStates(StateWithdrawn)
 }
 
 // QualifiedStatesForWithdrawable returns the qualified current states for Withdrawable event
 func QualifiedStatesForWithdrawable() []DelegationState {
-	return []DelegationState{StateUnbonding, StateSlashed}
+	return QualifiedPreviousStates(StateWithdrawable)
+}
+
+// TerminalDelegationStates returns the states a delegation never leaves
+func TerminalDelegationStates() []DelegationState {
+	return []DelegationState{StateWithdrawn}
+}
+
+// TerminalStatesForTimeLock returns the states in which a delegation can no
+// longer expire, so that its timelock documents are of no use anymore
+func TerminalStatesForTimeLock() []DelegationState {
+	return []DelegationState{StateWithdrawable, StateWithdrawn}
 }
 
 type DelegationSubState string
@@ -73,8 +107,114 @@ const (
 	// Used only for Withdrawable and Withdrawn parent states
 	SubStateTimelockSlashing       DelegationSubState = "TIMELOCK_SLASHING"
 	SubStateEarlyUnbondingSlashing DelegationSubState = "EARLY_UNBONDING_SLASHING"
+
+	// Used only for the Slashed parent state, following the slashed output of
+	// the slashing tx
+	SubStateSlashingConfirmed  DelegationSubState = "SLASHING_CONFIRMED"
+	SubStateSlashedOutputSwept DelegationSubState = "SLASHED_OUTPUT_SWEPT"
 )
 
+// SubStatesForSlashingChange returns the sub states of the timelocks of
+// slashing change outputs, whose expiry depends on the staking params
+func SubStatesForSlashingChange() []DelegationSubState {
+	return []DelegationSubState{SubStateTimelockSlashing, SubStateEarlyUnbondingSlashing}
+}
+
+// TimeLockSubStates returns the sub states a timelock document can be saved
+// with
+func TimeLockSubStates() []DelegationSubState {
+	return []DelegationSubState{
+		SubStateTimelock, SubStateEarlyUnbonding,
+		SubStateTimelockSlashing, SubStateEarlyUnbondingSlashing,
+	}
+}
+
+// AllDelegationSubStates returns every delegation sub state
+func AllDelegationSubStates() []DelegationSubState {
+	return append(TimeLockSubStates(), SubStateSlashingConfirmed, SubStateSlashedOutputSwept)
+}
+
+// delegationSubStateTransitions are the sub states a delegation can move to
+// while staying in its state, by state and from every sub state. A slashed
+// delegation keeps the sub state it had before being slashed until its
+// slashing tx is seen on BTC. The slashed output can be seen spent before the
+// slashing tx if the indexer missed it.
+var delegationSubStateTransitions = map[DelegationState]map[DelegationSubState][]DelegationSubState{
+	StateSlashed: {
+		"":                        {SubStateSlashingConfirmed, SubStateSlashedOutputSwept},
+		SubStateTimelock:          {SubStateSlashingConfirmed, SubStateSlashedOutputSwept},
+		SubStateEarlyUnbonding:    {SubStateSlashingConfirmed, SubStateSlashedOutputSwept},
+		SubStateSlashingConfirmed: {SubStateSlashedOutputSwept},
+	},
+}
+
+// CanAdvanceSubState tells whether a delegation in the state can move from
+// the sub state to the target one without leaving the state
+func (s DelegationState) CanAdvanceSubState(from, target DelegationSubState) bool {
+	for _, subState := range delegationSubStateTransitions[s][from] {
+		if subState == target {
+			return true
+		}
+	}
+	return false
+}
+
+// QualifiedPreviousSubStates returns the sub states a delegation in the state
+// can move to the target sub state from, the empty one standing for a
+// delegation without sub state
+func QualifiedPreviousSubStates(state DelegationState, target DelegationSubState) []DelegationSubState {
+	var subStates []DelegationSubState
+	for _, subState := range append([]DelegationSubState{""}, AllDelegationSubStates()...) {
+		if state.CanAdvanceSubState(subState, target) {
+			subStates = append(subStates, subState)
+		}
+	}
+	return subStates
+}
+
 func (p DelegationSubState) String() string {
 	return string(p)
 }
+
+// Validate returns an InvalidStateError if the sub state is not a known one
+func (p DelegationSubState) Validate() error {
+	valid := make([]string, 0, len(AllDelegationSubStates()))
+	for _, subState := range AllDelegationSubStates() {
+		if p == subState {
+			return nil
+		}
+		valid = append(valid, subState.String())
+	}
+	return &InvalidStateError{Kind: "delegation sub state", Value: string(p), Valid: valid}
+}
+
+// MarshalBSONValue stores the sub state as its name, refusing to store an
+// unknown one. The empty sub state stands for a delegation without any.
+func (p DelegationSubState) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if p != "" {
+		if err := p.Validate(); err != nil {
+			return 0, nil, fmt.Errorf("failed to marshal the delegation sub state: %w", err)
+		}
+	}
+	return bson.MarshalValue(string(p))
+}
+
+// UnmarshalBSONValue reads a stored sub state, returning an InvalidStateError
+// on a value that is not a known sub state, e.g. stored by a former version
+func (p *DelegationSubState) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	var name string
+	if t != bson.TypeNull {
+		if err := (bson.RawValue{Type: t, Value: data}).Unmarshal(&name); err != nil {
+			return fmt.Errorf("failed to unmarshal the delegation sub state: %w", err)
+		}
+	}
+
+	subState := DelegationSubState(name)
+	if subState != "" {
+		if err := subState.Validate(); err != nil {
+			return fmt.Errorf("failed to unmarshal the delegation sub state: %w", err)
+		}
+	}
+	*p = subState
+	return nil
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+)
+
+// chainStatusPrefix prefixes the BTCDelegationStatus values in their full
+// proto enum names, which the chain emits in the event attributes since its
+// enum got renamed
+const chainStatusPrefix = "BTC_DELEGATION_STATUS_"
+
+// chainStatusStates are the delegation states the chain statuses of the
+// events lead to. An unbonded or expired delegation is unbonding until its
+// timelock expires, the two telling its sub state apart.
+var chainStatusStates = map[string]DelegationState{
+	bbntypes.BTCDelegationStatus_PENDING.String():  StatePending,
+	bbntypes.BTCDelegationStatus_VERIFIED.String(): StateVerified,
+	bbntypes.BTCDelegationStatus_ACTIVE.String():   StateActive,
+	bbntypes.BTCDelegationStatus_UNBONDED.String(): StateUnbonding,
+	bbntypes.BTCDelegationStatus_EXPIRED.String():  StateUnbonding,
+}
+
+// chainStatusSubStates are the delegation sub states the chain statuses of
+// the events lead to
+var chainStatusSubStates = map[string]DelegationSubState{
+	bbntypes.BTCDelegationStatus_UNBONDED.String(): SubStateEarlyUnbonding,
+	bbntypes.BTCDelegationStatus_EXPIRED.String():  SubStateTimelock,
+}
+
+// InvalidStateError is returned when a string is neither the name of a
+// delegation state or sub state nor a chain status leading to one
+type InvalidStateError struct {
+	// Kind is the kind of state parsed, e.g. "delegation state"
+	Kind  string
+	Value string
+	// Valid are the names accepted for the kind of state
+	Valid []string
+}
+
+func (e *InvalidStateError) Error() string {
+	return fmt.Sprintf(
+		"invalid %s %q, valid values are %s", e.Kind, e.Value, strings.Join(e.Valid, ", "),
+	)
+}
+
+// normalizeStateName returns the upper case state name, without the proto
+// enum prefix of the chain statuses
+func normalizeStateName(s string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), chainStatusPrefix)
+}
+
+// ParseDelegationState returns the delegation state of a chain status, as
+// found in the event attributes with or without its proto enum prefix, or of
+// a delegation state name in any case
+func ParseDelegationState(s string) (DelegationState, error) {
+	name := normalizeStateName(s)
+	if state, ok := chainStatusStates[name]; ok {
+		return state, nil
+	}
+
+	valid := make([]string, 0, len(AllDelegationStates()))
+	for _, state := range AllDelegationStates() {
+		if name == state.String() {
+			return state, nil
+		}
+		valid = append(valid, state.String())
+	}
+	return "", &InvalidStateError{
+		Kind:  "delegation state",
+		Value: s,
+		Valid: append(valid, bbntypes.BTCDelegationStatus_UNBONDED.String(), bbntypes.BTCDelegationStatus_EXPIRED.String()),
+	}
+}
+
+// ParseDelegationSubState returns the delegation sub state of a chain status
+// leading to one, as found in the event attributes with or without its proto
+// enum prefix, or of a delegation sub state name in any case
+func ParseDelegationSubState(s string) (DelegationSubState, error) {
+	name := normalizeStateName(s)
+	if subState, ok := chainStatusSubStates[name]; ok {
+		return subState, nil
+	}
+
+	valid := make([]string, 0, len(AllDelegationSubStates()))
+	for _, subState := range AllDelegationSubStates() {
+		if name == subState.String() {
+			return subState, nil
+		}
+		valid = append(valid, subState.String())
+	}
+	return "", &InvalidStateError{
+		Kind:  "delegation sub state",
+		Value: s,
+		Valid: append(valid, bbntypes.BTCDelegationStatus_UNBONDED.String(), bbntypes.BTCDelegationStatus_EXPIRED.String()),
+	}
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseChainStates pins the states of the chain statuses captured from
+// the event attributes of the chains before and after the enum renaming
+func TestParseChainStates(t *testing.T) {
+	data, err := os.ReadFile("testdata/chain_states.json")
+	require.NoError(t, err)
+	var fixtures map[string]map[string]struct {
+		State    DelegationState    `json:"state"`
+		SubState DelegationSubState `json:"sub_state"`
+	}
+	require.NoError(t, json.Unmarshal(data, &fixtures))
+	require.Len(t, fixtures, 2)
+
+	for chain, statuses := range fixtures {
+		for status, expected := range statuses {
+			state, err := ParseDelegationState(status)
+			require.NoError(t, err, "%s: %s", chain, status)
+			require.Equal(t, expected.State, state, "%s: %s", chain, status)
+
+			subState, err := ParseDelegationSubState(status)
+			if expected.SubState == "" {
+				require.Error(t, err, "%s: %s", chain, status)
+				continue
+			}
+			require.NoError(t, err, "%s: %s", chain, status)
+			require.Equal(t, expected.SubState, subState, "%s: %s", chain, status)
+		}
+	}
+}
+
+func TestParseStateNames(t *testing.T) {
+	for _, state := range AllDelegationStates() {
+		parsed, err := ParseDelegationState(state.String())
+		require.NoError(t, err)
+		require.Equal(t, state, parsed)
+	}
+	for _, subState := range AllDelegationSubStates() {
+		parsed, err := ParseDelegationSubState(subState.String())
+		require.NoError(t, err)
+		require.Equal(t, subState, parsed)
+	}
+
+	// the internal names are also accepted in lower case
+	state, err := ParseDelegationState(" withdrawable ")
+	require.NoError(t, err)
+	require.Equal(t, StateWithdrawable, state)
+	subState, err := ParseDelegationSubState("early_unbonding_slashing")
+	require.NoError(t, err)
+	require.Equal(t, SubStateEarlyUnbondingSlashing, subState)
+}
+
+func TestParseInvalidStates(t *testing.T) {
+	_, err := ParseDelegationState("BTC_DELEGATION_STATUS_ANY")
+	var stateErr *InvalidStateError
+	require.ErrorAs(t, err, &stateErr)
+	require.Equal(t, "delegation state", stateErr.Kind)
+	require.Equal(t,
+		`invalid delegation state "BTC_DELEGATION_STATUS_ANY", valid values are PENDING, VERIFIED, `+
+			`ACTIVE, UNBONDING, WITHDRAWABLE, WITHDRAWN, SLASHED, UNBONDED, EXPIRED`,
+		err.Error(),
+	)
+
+	_, err = ParseDelegationSubState("ACTIVE")
+	require.ErrorAs(t, err, &stateErr)
+	require.Equal(t, "delegation sub state", stateErr.Kind)
+	require.Contains(t, stateErr.Valid, SubStateSlashedOutputSwept.String())
+
+	_, err = ParseDelegationState("")
+	require.Error(t, err)
+}
@@ -0,0 +1,288 @@
+package types
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDelegationStateTransitions(t *testing.T) {
+	allowed := []struct {
+		from DelegationState
+		to   DelegationState
+	}{
+		{StatePending, StateVerified},
+		{StatePending, StateActive},
+		{StatePending, StateSlashed},
+		{StateVerified, StateActive},
+		{StateVerified, StateSlashed},
+		{StateActive, StateUnbonding},
+		{StateActive, StateWithdrawn},
+		{StateActive, StateSlashed},
+		{StateUnbonding, StateWithdrawable},
+		{StateUnbonding, StateWithdrawn},
+		{StateUnbonding, StateSlashed},
+		{StateWithdrawable, StateWithdrawn},
+		{StateWithdrawable, StateSlashed},
+		{StateSlashed, StateWithdrawable},
+		{StateSlashed, StateWithdrawn},
+	}
+	for _, tt := range allowed {
+		require.True(t, tt.from.CanTransitionTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+
+	// every other transition is disallowed
+	count := 0
+	for _, from := range AllDelegationStates() {
+		for _, to := range AllDelegationStates() {
+			if from.CanTransitionTo(to) {
+				count++
+			}
+		}
+	}
+	require.Equal(t, len(allowed), count)
+
+	disallowed := []struct {
+		from DelegationState
+		to   DelegationState
+	}{
+		{StateActive, StatePending},
+		{StateActive, StateVerified},
+		{StateVerified, StatePending},
+		{StateUnbonding, StateActive},
+		{StateWithdrawable, StateUnbonding},
+		{StateSlashed, StateActive},
+		{StateWithdrawn, StateWithdrawable},
+		{StateWithdrawn, StateSlashed},
+		{StateActive, StateActive},
+		{StateWithdrawn, StateWithdrawn},
+		{StatePending, "EXPIRED"},
+		{"EXPIRED", StateActive},
+	}
+	for _, tt := range disallowed {
+		require.False(t, tt.from.CanTransitionTo(tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func TestQualifiedPreviousStates(t *testing.T) {
+	tests := []struct {
+		target   DelegationState
+		expected []DelegationState
+	}{
+		{StatePending, nil},
+		{StateVerified, []DelegationState{StatePending}},
+		{StateActive, []DelegationState{StatePending, StateVerified}},
+		{StateUnbonding, []DelegationState{StateActive}},
+		{StateWithdrawable, []DelegationState{StateUnbonding, StateSlashed}},
+		{StateWithdrawn, []DelegationState{StateActive, StateUnbonding, StateWithdrawable, StateSlashed}},
+		{StateSlashed, []DelegationState{
+			StatePending, StateVerified, StateActive, StateUnbonding, StateWithdrawable,
+		}},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, QualifiedPreviousStates(tt.target), tt.target.String())
+	}
+}
+
+func TestDelegationSubStateTransitions(t *testing.T) {
+	tests := []struct {
+		state    DelegationState
+		from     DelegationSubState
+		to       DelegationSubState
+		expected bool
+	}{
+		{StateSlashed, "", SubStateSlashingConfirmed, true},
+		{StateSlashed, SubStateEarlyUnbonding, SubStateSlashingConfirmed, true},
+		{StateSlashed, SubStateSlashingConfirmed, SubStateSlashedOutputSwept, true},
+		{StateSlashed, "", SubStateSlashedOutputSwept, true},
+		{StateSlashed, SubStateSlashedOutputSwept, SubStateSlashingConfirmed, false},
+		{StateSlashed, SubStateSlashingConfirmed, SubStateSlashingConfirmed, false},
+		{StateSlashed, SubStateTimelockSlashing, SubStateSlashingConfirmed, false},
+		{StateSlashed, "", SubStateTimelock, false},
+		{StateActive, "", SubStateSlashingConfirmed, false},
+		{StateWithdrawn, SubStateTimelock, SubStateEarlyUnbonding, false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, tt.state.CanAdvanceSubState(tt.from, tt.to),
+			"%s: %q -> %q", tt.state, tt.from, tt.to)
+	}
+
+	require.Equal(t, []DelegationSubState{"", SubStateTimelock, SubStateEarlyUnbonding},
+		QualifiedPreviousSubStates(StateSlashed, SubStateSlashingConfirmed))
+	require.Equal(t, []DelegationSubState{"", SubStateTimelock, SubStateEarlyUnbonding, SubStateSlashingConfirmed},
+		QualifiedPreviousSubStates(StateSlashed, SubStateSlashedOutputSwept))
+	require.Empty(t, QualifiedPreviousSubStates(StateWithdrawn, SubStateTimelock))
+}
+
+func TestDelegationSubStateValidate(t *testing.T) {
+	for _, subState := range AllDelegationSubStates() {
+		require.NoError(t, subState.Validate())
+	}
+	require.NotContains(t, TimeLockSubStates(), SubStateSlashingConfirmed)
+	require.Error(t, DelegationSubState("").Validate())
+	require.Error(t, DelegationSubState("timelock").Validate())
+
+	var invalidState *InvalidStateError
+	require.ErrorAs(t, DelegationSubState("UNBONDED").Validate(), &invalidState)
+	require.Equal(t, "UNBONDED", invalidState.Value)
+}
+
+// subStateDocument holds sub states the way the db models do
+type subStateDocument struct {
+	SubState         DelegationSubState `bson:"sub_state,omitempty"`
+	TimeLockSubState DelegationSubState `bson:"delegation_sub_state"`
+}
+
+func TestDelegationSubStateBSON(t *testing.T) {
+	for _, subState := range AllDelegationSubStates() {
+		data, err := bson.Marshal(subStateDocument{SubState: subState, TimeLockSubState: subState})
+		require.NoError(t, err)
+		require.Equal(t, subState.String(), bson.Raw(data).Lookup("sub_state").StringValue())
+
+		var doc subStateDocument
+		require.NoError(t, bson.Unmarshal(data, &doc))
+		require.Equal(t, subStateDocument{SubState: subState, TimeLockSubState: subState}, doc)
+	}
+
+	// the empty sub state is still omitted
+	data, err := bson.Marshal(subStateDocument{})
+	require.NoError(t, err)
+	_, err = bson.Raw(data).LookupErr("sub_state")
+	require.Error(t, err)
+	var doc subStateDocument
+	require.NoError(t, bson.Unmarshal(data, &doc))
+	require.Empty(t, doc.SubState)
+
+	// an unknown sub state is neither stored nor read
+	_, err = bson.Marshal(subStateDocument{SubState: "UNBONDED"})
+	var invalidState *InvalidStateError
+	require.ErrorAs(t, err, &invalidState)
+
+	data, err = bson.Marshal(bson.M{"sub_state": "timelock", "delegation_sub_state": "TIMELOCK"})
+	require.NoError(t, err)
+	err = bson.Unmarshal(data, &doc)
+	require.ErrorAs(t, err, &invalidState)
+	require.Contains(t, err.Error(), `invalid delegation sub state "timelock"`)
+}
+
+// subStateFields are the fields and documents keys holding a sub state
+var subStateFields = regexp.MustCompile(`(?i)sub_?state$`)
+
+// TestDelegationSubStateLiterals makes sure that every sub state declared is
+// enumerated, and that the services and db packages only use enumerated sub
+// states, never an ad-hoc string
+func TestDelegationSubStateLiterals(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "state.go", nil, 0)
+	require.NoError(t, err)
+
+	var declared []DelegationSubState
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		if ident, ok := spec.Type.(*ast.Ident); ok && ident.Name == "DelegationSubState" {
+			for _, value := range spec.Values {
+				declared = append(declared, DelegationSubState(stringLiteral(t, value)))
+			}
+		}
+		return true
+	})
+	require.ElementsMatch(t, declared, AllDelegationSubStates())
+
+	// isSubState tells whether the expression is a sub state field, variable
+	// or document key
+	isSubState := func(expr ast.Expr) bool {
+		switch expr := expr.(type) {
+		case *ast.Ident:
+			return subStateFields.MatchString(expr.Name)
+		case *ast.SelectorExpr:
+			return subStateFields.MatchString(expr.Sel.Name)
+		case *ast.IndexExpr:
+			if lit, ok := expr.Index.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				return subStateFields.MatchString(stringLiteral(t, lit))
+			}
+		case *ast.BasicLit:
+			return expr.Kind == token.STRING && subStateFields.MatchString(stringLiteral(t, expr))
+		}
+		return false
+	}
+
+	scanned := 0
+	for _, dir := range []string{"../services", "../db"} {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return err
+			}
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			scanned++
+
+			// checkLiteral fails on a string literal used as a sub state that
+			// is not enumerated, the empty one unsetting the sub state
+			checkLiteral := func(expr ast.Expr) {
+				lit, ok := expr.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					return
+				}
+				if value := stringLiteral(t, lit); value != "" {
+					require.NoError(t, DelegationSubState(value).Validate(), fset.Position(lit.Pos()).String())
+				}
+			}
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch node := node.(type) {
+				case *ast.CallExpr:
+					if isSubState(node.Fun) {
+						for _, arg := range node.Args {
+							checkLiteral(arg)
+						}
+					}
+					if selector, ok := node.Fun.(*ast.SelectorExpr); ok && selector.Sel.Name == "DelegationSubState" {
+						checkLiteral(node.Args[0])
+					}
+				case *ast.KeyValueExpr:
+					if isSubState(node.Key) {
+						checkLiteral(node.Value)
+					}
+				case *ast.AssignStmt:
+					for i, lhs := range node.Lhs {
+						if isSubState(lhs) && i < len(node.Rhs) {
+							checkLiteral(node.Rhs[i])
+						}
+					}
+				case *ast.BinaryExpr:
+					if isSubState(node.X) {
+						checkLiteral(node.Y)
+					}
+					if isSubState(node.Y) {
+						checkLiteral(node.X)
+					}
+				}
+				return true
+			})
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	require.NotZero(t, scanned)
+}
+
+func stringLiteral(t *testing.T, expr ast.Expr) string {
+	lit, ok := expr.(*ast.BasicLit)
+	require.True(t, ok)
+	value, err := strconv.Unquote(lit.Value)
+	require.NoError(t, err)
+	return value
+}
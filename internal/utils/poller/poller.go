@@ -2,6 +2,8 @@ package poller
 
 import (
 	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
@@ -11,6 +13,9 @@ import (
 type Poller struct {
 	interval   time.Duration
 	quit       chan struct{}
+	stopOnce   sync.Once
+	started    atomic.Bool
+	done       chan struct{}
 	pollMethod func(ctx context.Context) *types.Error
 }
 
@@ -18,12 +23,17 @@ func NewPoller(interval time.Duration, pollMethod func(ctx context.Context) *typ
 	return &Poller{
 		interval:   interval,
 		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
 		pollMethod: pollMethod,
 	}
 }
 
+// Start runs the poll method on each tick until the poller is stopped or the
+// context is done. A Start that panicked may be called again.
 func (p *Poller) Start(ctx context.Context) {
+	p.started.Store(true)
 	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
 
 	for {
 		select {
@@ -34,15 +44,32 @@ func (p *Poller) Start(ctx context.Context) {
 		case <-ctx.Done():
 			// Handle context cancellation.
 			log.Info().Msg("Poller stopped due to context cancellation")
+			close(p.done)
 			return
 		case <-p.quit:
 			log.Info().Msg("Poller stopped")
-			ticker.Stop() // Stop the ticker
+			close(p.done)
 			return
 		}
 	}
 }
 
+// Stop stops the poller from starting new runs, the run in flight if any
+// completing
 func (p *Poller) Stop() {
-	close(p.quit)
+	p.stopOnce.Do(func() { close(p.quit) })
+}
+
+// Wait waits for a stopped poller to complete its run in flight, returning
+// false if the context is done first. A poller never started is done.
+func (p *Poller) Wait(ctx context.Context) bool {
+	if !p.started.Load() {
+		return true
+	}
+	select {
+	case <-p.done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
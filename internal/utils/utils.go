@@ -151,3 +151,12 @@ func DeserializeBtcTransactionFromHex(txHex string) (*wire.MsgTx, error) {
 	}
 	return tx, nil
 }
+
+// GetTxOutput returns the output of the tx at the index, an error if the tx
+// has no such output
+func GetTxOutput(tx *wire.MsgTx, idx uint32) (*wire.TxOut, error) {
+	if uint64(idx) >= uint64(len(tx.TxOut)) {
+		return nil, fmt.Errorf("tx %s has %d outputs, no output %d", tx.TxHash(), len(tx.TxOut), idx)
+	}
+	return tx.TxOut[idx], nil
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// testStakingTxHex is a signet staking tx, its staking output at index 0
+	testStakingTxHex = "02000000012bb8409b19df0505ca3f1c4b1d2bf7caa0ceab5e060e24ac09b00d645bbe76a90100000000ffffffff0220a1070000000000225120654fde41085464915536a1e87b6633e5b6d7c6016d354f58d1cb533f5f16fde787d612000000000022512000413ffe1e9b973f2394ca5db7e19cdfbae2e9059836eff2bec70237637d25fc00000000"
+	// testUnbondingTxHex is the unbonding tx of the staking tx
+	testUnbondingTxHex = "02000000011eaa41d7227c57637c9342e7f5896febebb990ffd816042b4039b6ebb684d1bc0000000000ffffffff01389d0700000000002251206942a74daaf80eaac87388536ad2f92a0bda90fad12dad76c1e6ce534db8287700000000"
+)
+
+// testWitnessTxHex returns the unbonding tx spending the staking output
+// through a script path, in the segwit encoding
+func testWitnessTxHex(t testing.TB) string {
+	tx, err := DeserializeBtcTransactionFromHex(testUnbondingTxHex)
+	require.NoError(t, err)
+	tx.TxIn[0].Witness = wire.TxWitness{make([]byte, 64), {0x51}, make([]byte, 33)}
+	txBytes, err := SerializeBtcTransaction(tx)
+	require.NoError(t, err)
+	return hex.EncodeToString(txBytes)
+}
+
+func TestGetTxOutput(t *testing.T) {
+	tx, err := DeserializeBtcTransactionFromHex(testStakingTxHex)
+	require.NoError(t, err)
+
+	output, err := GetTxOutput(tx, 1)
+	require.NoError(t, err)
+	require.Equal(t, tx.TxOut[1], output)
+
+	_, err = GetTxOutput(tx, 2)
+	require.ErrorContains(t, err, "no output 2")
+	_, err = GetTxOutput(wire.NewMsgTx(wire.TxVersion), 0)
+	require.Error(t, err)
+}
+
+// FuzzDeserializeBtcTransactionFromHex checks that any hex either decodes to
+// a tx that serializes back to its bytes or gets an error
+func FuzzDeserializeBtcTransactionFromHex(f *testing.F) {
+	f.Add(testStakingTxHex)
+	f.Add(testUnbondingTxHex)
+	f.Add(testWitnessTxHex(f))
+	f.Add(testStakingTxHex[:100])
+	f.Add("")
+	f.Add("zz")
+
+	f.Fuzz(func(t *testing.T, txHex string) {
+		tx, err := DeserializeBtcTransactionFromHex(txHex)
+		if err != nil {
+			require.Nil(t, tx)
+			return
+		}
+		txBytes, err := SerializeBtcTransaction(tx)
+		require.NoError(t, err)
+		txHash, err := GetTxHash(txBytes)
+		require.NoError(t, err)
+		require.Equal(t, tx.TxHash(), txHash)
+	})
+}
+
+// FuzzGetTxOutput checks that the output lookup of a decoded tx returns the
+// output at the index or an error, whatever the index
+func FuzzGetTxOutput(f *testing.F) {
+	f.Add(testStakingTxHex, uint32(0))
+	f.Add(testStakingTxHex, uint32(1))
+	f.Add(testStakingTxHex, uint32(2))
+	f.Add(testUnbondingTxHex, uint32(0))
+	f.Add(testWitnessTxHex(f), uint32(1))
+	f.Add(testUnbondingTxHex, ^uint32(0))
+
+	f.Fuzz(func(t *testing.T, txHex string, idx uint32) {
+		tx, err := DeserializeBtcTransactionFromHex(txHex)
+		if err != nil {
+			return
+		}
+		output, err := GetTxOutput(tx, idx)
+		if err != nil {
+			require.GreaterOrEqual(t, uint64(idx), uint64(len(tx.TxOut)))
+			return
+		}
+		require.Same(t, tx.TxOut[idx], output)
+	})
+}
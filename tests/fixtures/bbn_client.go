@@ -0,0 +1,312 @@
+package fixtures
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+)
+
+// genesisTime is the time of the first block served by the BbnClient
+var genesisTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// blockInterval is the time between two blocks served by the BbnClient
+const blockInterval = 10 * time.Second
+
+// subscriptionCapacity is the number of new block events buffered for a
+// subscriber by default
+const subscriptionCapacity = 100
+
+// BbnClient is a BBN client serving a scripted sequence of blocks, the first
+// one at height 1, each extending the previous one. The block results are
+// served as given, and the blocks have the hash and time of BlockHash and
+// BlockTime. The chain state queries serve the values set on the client, and
+// the subscribers are notified of the blocks appended.
+type BbnClient struct {
+	mu          sync.Mutex
+	blocks      []*ctypes.ResultBlockResults
+	delegations map[string]*bbnclient.BTCDelegation
+	// subscribers are notified of the blocks appended, by subscriber name
+	subscribers map[string]chan ctypes.ResultEvent
+
+	// StakingParams are the staking params versions served
+	StakingParams map[uint32]*bbnclient.StakingParams
+	// CheckpointParams are the checkpoint params served
+	CheckpointParams *bbnclient.CheckpointParams
+	// FinalityProviders are the finality providers served
+	FinalityProviders []*bbnclient.FinalityProvider
+}
+
+var _ bbnclient.BbnInterface = (*BbnClient)(nil)
+
+// NewBbnClient returns a client serving the blocks with the results, in order
+// from height 1
+func NewBbnClient(blocks ...*ctypes.ResultBlockResults) *BbnClient {
+	c := &BbnClient{
+		delegations:      make(map[string]*bbnclient.BTCDelegation),
+		subscribers:      make(map[string]chan ctypes.ResultEvent),
+		StakingParams:    make(map[uint32]*bbnclient.StakingParams),
+		CheckpointParams: &bbnclient.CheckpointParams{},
+	}
+	c.AppendBlocks(blocks...)
+	return c
+}
+
+// AppendBlocks extends the chain with blocks with the results, their height
+// set to the one they are served at, and notifies the subscribers of the new
+// tip
+func (c *BbnClient) AppendBlocks(blocks ...*ctypes.ResultBlockResults) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, block := range blocks {
+		block.Height = int64(len(c.blocks) + 1)
+		c.blocks = append(c.blocks, block)
+	}
+	if len(blocks) == 0 {
+		return
+	}
+
+	tip := ctypes.ResultEvent{Data: cmttypes.EventDataNewBlock{
+		Block: &cmttypes.Block{Header: cmttypes.Header{Height: int64(len(c.blocks))}},
+	}}
+	for _, out := range c.subscribers {
+		// A subscriber lagging behind gets the later tips only, as from a
+		// node dropping the events of a slow subscription
+		select {
+		case out <- tip:
+		default:
+		}
+	}
+}
+
+// SetBTCDelegation sets the state of the delegation on the chain, served by
+// GetBTCDelegation(s). The delegations not set are not found.
+func (c *BbnClient) SetBTCDelegation(delegation *bbnclient.BTCDelegation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.delegations[delegation.StakingTxHashHex] = delegation
+}
+
+// BlockHash returns the hash of the block served at the height
+func BlockHash(height int64) cmtbytes.HexBytes {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(height))
+	hash := sha256.Sum256(buf[:])
+	return hash[:]
+}
+
+// BlockTime returns the time of the block served at the height
+func BlockTime(height int64) time.Time {
+	return genesisTime.Add(time.Duration(height-1) * blockInterval)
+}
+
+// blockResults returns the results of the block at the height
+func (c *BbnClient) blockResults(height *int64) (*ctypes.ResultBlockResults, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if height == nil {
+		if len(c.blocks) == 0 {
+			return nil, fmt.Errorf("no block served yet")
+		}
+		return c.blocks[len(c.blocks)-1], nil
+	}
+	if *height < 1 || *height > int64(len(c.blocks)) {
+		return nil, fmt.Errorf("height %d is not available, latest height is %d", *height, len(c.blocks))
+	}
+	return c.blocks[*height-1], nil
+}
+
+func (c *BbnClient) GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error) {
+	return c.CheckpointParams, nil
+}
+
+func (c *BbnClient) GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error) {
+	return c.StakingParams, nil
+}
+
+func (c *BbnClient) GetLatestBlockNumber(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return int64(len(c.blocks)), nil
+}
+
+func (c *BbnClient) GetChainID(ctx context.Context) (string, error) {
+	return "bbn-fixtures", nil
+}
+
+func (c *BbnClient) GetBTCLightClientTipHeight(ctx context.Context) (uint64, error) {
+	return 0, nil
+}
+
+func (c *BbnClient) GetActiveFinalityProvidersAtHeight(
+	ctx context.Context, height uint64,
+) ([]*bbnclient.FinalityProviderVotingPower, error) {
+	return nil, nil
+}
+
+func (c *BbnClient) GetBTCDelegation(ctx context.Context, stakingTxHashHex string) (*bbnclient.BTCDelegation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delegation, ok := c.delegations[stakingTxHashHex]
+	if !ok {
+		return nil, bbnclient.ErrBTCDelegationNotFound
+	}
+	return delegation, nil
+}
+
+// GetBTCDelegations serves all the delegations set in a single page, ordered
+// by staking tx hash
+func (c *BbnClient) GetBTCDelegations(
+	ctx context.Context, pageKey []byte, limit uint64,
+) ([]*bbnclient.BTCDelegation, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delegations := make([]*bbnclient.BTCDelegation, 0, len(c.delegations))
+	for _, delegation := range c.delegations {
+		delegations = append(delegations, delegation)
+	}
+	sort.Slice(delegations, func(i, j int) bool {
+		return delegations[i].StakingTxHashHex < delegations[j].StakingTxHashHex
+	})
+	return delegations, nil, nil
+}
+
+// GetFinalityProviders serves the finality providers set in pages of the
+// limit, or in a single page if zero, the page key being the offset of the
+// page
+func (c *BbnClient) GetFinalityProviders(
+	ctx context.Context, pageKey []byte, limit uint64,
+) ([]*bbnclient.FinalityProvider, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var offset uint64
+	if len(pageKey) > 0 {
+		if len(pageKey) != 8 {
+			return nil, nil, fmt.Errorf("invalid page key %x", pageKey)
+		}
+		offset = binary.BigEndian.Uint64(pageKey)
+	}
+	fps := c.FinalityProviders[min(offset, uint64(len(c.FinalityProviders))):]
+	if limit == 0 || uint64(len(fps)) <= limit {
+		return fps, nil, nil
+	}
+	return fps[:limit], binary.BigEndian.AppendUint64(nil, offset+limit), nil
+}
+
+func (c *BbnClient) GetBlock(ctx context.Context, blockHeight *int64) (*ctypes.ResultBlock, error) {
+	results, err := c.blockResults(blockHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	height := results.Height
+	header := cmttypes.Header{
+		ChainID: "bbn-fixtures",
+		Height:  height,
+		Time:    BlockTime(height),
+	}
+	if height > 1 {
+		header.LastBlockID = cmttypes.BlockID{Hash: BlockHash(height - 1)}
+	}
+	return &ctypes.ResultBlock{
+		BlockID: cmttypes.BlockID{Hash: BlockHash(height)},
+		Block:   &cmttypes.Block{Header: header},
+	}, nil
+}
+
+func (c *BbnClient) GetBlockResults(ctx context.Context, blockHeight *int64) (*ctypes.ResultBlockResults, error) {
+	return c.blockResults(blockHeight)
+}
+
+// SearchEventHeights returns the heights of the blocks with an event attribute
+// matching the query, only the <type>.<key>='<value>' and <type>.<key> EXISTS
+// queries being supported
+func (c *BbnClient) SearchEventHeights(ctx context.Context, query string) ([]int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var heights []int64
+	for _, block := range c.blocks {
+		events := slices.Clone(block.FinalizeBlockEvents)
+		for _, txResult := range block.TxsResults {
+			events = append(events, txResult.Events...)
+		}
+	search:
+		for _, event := range events {
+			for _, attribute := range event.Attributes {
+				if fmt.Sprintf("%s.%s='%s'", event.Type, attribute.Key, attribute.Value) == query ||
+					fmt.Sprintf("%s.%s EXISTS", event.Type, attribute.Key) == query {
+					heights = append(heights, block.Height)
+					break search
+				}
+			}
+		}
+	}
+	return heights, nil
+}
+
+// SearchFirstEventHeight returns the height of the first block with an event
+// attribute matching the query, as SearchEventHeights, zero if none
+func (c *BbnClient) SearchFirstEventHeight(ctx context.Context, query string) (int64, error) {
+	heights, err := c.SearchEventHeights(ctx, query)
+	if err != nil || len(heights) == 0 {
+		return 0, err
+	}
+	return heights[0], nil
+}
+
+// Subscribe returns a channel on which a new block event is sent each time
+// blocks are appended, whatever the query
+func (c *BbnClient) Subscribe(subscriber, query string, outCapacity ...int) (<-chan ctypes.ResultEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	capacity := subscriptionCapacity
+	if len(outCapacity) > 0 && outCapacity[0] > 0 {
+		capacity = outCapacity[0]
+	}
+	out := make(chan ctypes.ResultEvent, capacity)
+	c.subscribers[subscriber] = out
+	return out, nil
+}
+
+// HasSubscribers returns whether a subscriber is notified of the blocks
+// appended, for the tests to append them once the indexer listens
+func (c *BbnClient) HasSubscribers() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.subscribers) > 0
+}
+
+func (c *BbnClient) UnsubscribeAll(subscriber string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.subscribers, subscriber)
+	return nil
+}
+
+func (c *BbnClient) IsRunning() bool {
+	return true
+}
+
+func (c *BbnClient) Start() error {
+	return nil
+}
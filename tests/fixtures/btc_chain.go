@@ -0,0 +1,179 @@
+package fixtures
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/btcclient"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/utils"
+)
+
+// btcGenesisTime is the time of the block at height 0 of a BtcChain
+var btcGenesisTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// btcBlockInterval is the time between two blocks of a BtcChain
+const btcBlockInterval = 10 * time.Minute
+
+// BtcChain is a BTC chain of indexed blocks from height 0, each block
+// extending the previous one and committing to its txs. The blocks mined
+// from a seed are the same on every run and differ from the ones mined from
+// another seed at the same heights, so that forks get their own hashes. The
+// chain serves its headers as a BTC client, and notifies the spends and the
+// blocks mined as a BTC notifier.
+type BtcChain struct {
+	mu     sync.Mutex
+	seed   uint32
+	blocks []*types.IndexedBlock
+
+	// ntfnID is the id of the last notification registered
+	ntfnID     uint64
+	spendNtfns map[uint64]*spendNtfn
+	epochNtfns map[uint64]chan *notifier.BlockEpoch
+}
+
+var (
+	_ btcclient.BtcInterface = (*BtcChain)(nil)
+	_ notifier.ChainNotifier = (*BtcChain)(nil)
+)
+
+// NewBtcChain returns a chain of empty blocks mined from the seed up to the
+// tip height
+func NewBtcChain(seed uint32, tipHeight int32) *BtcChain {
+	c := &BtcChain{
+		seed:       seed,
+		spendNtfns: make(map[uint64]*spendNtfn),
+		epochNtfns: make(map[uint64]chan *notifier.BlockEpoch),
+	}
+	c.Extend(tipHeight)
+	return c
+}
+
+// Extend mines empty blocks up to the tip height
+func (c *BtcChain) Extend(tipHeight int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for int32(len(c.blocks)) <= tipHeight {
+		c.mineBlock(nil)
+	}
+}
+
+// MineBlock mines a block including the txs on top of the tip and returns it
+func (c *BtcChain) MineBlock(txs ...*wire.MsgTx) *types.IndexedBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mineBlock(txs)
+}
+
+func (c *BtcChain) mineBlock(txs []*wire.MsgTx) *types.IndexedBlock {
+	height := int32(len(c.blocks))
+	prevHash := chainhash.Hash{}
+	if height > 0 {
+		prevHash = c.blocks[height-1].BlockHash()
+	}
+
+	// The coinbase tx commits to the seed and the height, making the block
+	// unique to its branch
+	coinbaseScript := binary.BigEndian.AppendUint32(nil, c.seed)
+	coinbaseScript = binary.BigEndian.AppendUint32(coinbaseScript, uint32(height))
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(
+		wire.NewOutPoint(&chainhash.Hash{}, wire.MaxPrevOutIndex), coinbaseScript, nil,
+	))
+	coinbase.AddTxOut(wire.NewTxOut(50*btcutil.SatoshiPerBitcoin, []byte{0x51}))
+
+	msgBlock := &wire.MsgBlock{Transactions: append([]*wire.MsgTx{coinbase}, txs...)}
+	blockTxs := make([]*btcutil.Tx, 0, len(msgBlock.Transactions))
+	for i, tx := range msgBlock.Transactions {
+		blockTx := btcutil.NewTx(tx)
+		blockTx.SetIndex(i)
+		blockTxs = append(blockTxs, blockTx)
+	}
+	msgBlock.Header = wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  prevHash,
+		MerkleRoot: blockchain.CalcMerkleRoot(blockTxs, false),
+		Timestamp:  btcGenesisTime.Add(time.Duration(height) * btcBlockInterval),
+		Bits:       chaincfg.RegressionNetParams.PowLimitBits,
+		Nonce:      c.seed,
+	}
+
+	block := types.NewIndexedBlockFromMsgBlock(height, msgBlock)
+	c.blocks = append(c.blocks, block)
+	c.notifyBlock(block)
+	return block
+}
+
+// Fork returns a chain sharing the blocks up to the fork height, the blocks
+// mined on it above that height being mined from the seed. The notifications
+// registered on the chain are not carried over to the fork.
+func (c *BtcChain) Fork(forkHeight int32, seed uint32) *BtcChain {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &BtcChain{
+		seed:       seed,
+		blocks:     append([]*types.IndexedBlock{}, c.blocks[:forkHeight+1]...),
+		spendNtfns: make(map[uint64]*spendNtfn),
+		epochNtfns: make(map[uint64]chan *notifier.BlockEpoch),
+	}
+}
+
+// TipHeight returns the height of the last block of the chain
+func (c *BtcChain) TipHeight() int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int32(len(c.blocks) - 1)
+}
+
+// Block returns the block at the height, nil if the chain does not reach it
+func (c *BtcChain) Block(height int32) *types.IndexedBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if height < 0 || int(height) >= len(c.blocks) {
+		return nil
+	}
+	return c.blocks[height]
+}
+
+// TxHeight returns the height of the block including the tx, false if no
+// block of the chain includes it
+func (c *BtcChain) TxHeight(txHash chainhash.Hash) (int32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, block := range c.blocks {
+		for _, tx := range block.Txs {
+			if tx.Hash().IsEqual(&txHash) {
+				return block.Height, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (c *BtcChain) GetTipHeight() (uint64, error) {
+	return uint64(c.TipHeight()), nil
+}
+
+// GetNetwork returns regtest, the network the blocks of the chain are mined
+// for
+func (c *BtcChain) GetNetwork() (string, error) {
+	return utils.BtcRegtest.String(), nil
+}
+
+func (c *BtcChain) GetBlockHeaderByHeight(height uint64) (*wire.BlockHeader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if height >= uint64(len(c.blocks)) {
+		return nil, fmt.Errorf("no BTC block at height %d, the tip is at %d", height, len(c.blocks)-1)
+	}
+	return c.blocks[height].Header, nil
+}
@@ -0,0 +1,147 @@
+package fixtures
+
+import (
+	"errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	notifier "github.com/lightningnetwork/lnd/chainntnfs"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// epochNtfnCapacity is the number of block notifications buffered for a
+// block epoch subscriber
+const epochNtfnCapacity = 100
+
+// spendNtfn is a spend notification waiting for a block spending its outpoint
+type spendNtfn struct {
+	outpoint   wire.OutPoint
+	heightHint int32
+	event      *notifier.SpendEvent
+}
+
+func (c *BtcChain) Start() error {
+	return nil
+}
+
+func (c *BtcChain) Started() bool {
+	return true
+}
+
+func (c *BtcChain) Stop() error {
+	return nil
+}
+
+// RegisterConfirmationsNtfn is not supported, the indexer only watching the
+// spends and the blocks
+func (c *BtcChain) RegisterConfirmationsNtfn(
+	txid *chainhash.Hash, pkScript []byte, numConfs, heightHint uint32, opts ...notifier.NotifierOption,
+) (*notifier.ConfirmationEvent, error) {
+	return nil, errors.New("confirmation notifications are not supported by the BTC chain fixture")
+}
+
+// RegisterSpendNtfn notifies the spend of the outpoint by the first tx of the
+// chain spending it from the height hint, as soon as the registration if the
+// tx is already mined
+func (c *BtcChain) RegisterSpendNtfn(
+	outpoint *wire.OutPoint, pkScript []byte, heightHint uint32,
+) (*notifier.SpendEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ntfnID++
+	id := c.ntfnID
+	ntfn := &spendNtfn{
+		outpoint:   *outpoint,
+		heightHint: int32(heightHint),
+		event: notifier.NewSpendEvent(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			delete(c.spendNtfns, id)
+		}),
+	}
+	for _, block := range c.blocks {
+		if ntfn.notify(block) {
+			return ntfn.event, nil
+		}
+	}
+	c.spendNtfns[id] = ntfn
+	return ntfn.event, nil
+}
+
+// RegisterBlockEpochNtfn notifies the tip and then each block mined. A
+// subscriber lagging behind by more than epochNtfnCapacity blocks misses the
+// later ones.
+func (c *BtcChain) RegisterBlockEpochNtfn(*notifier.BlockEpoch) (*notifier.BlockEpochEvent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ntfnID++
+	id := c.ntfnID
+	epochs := make(chan *notifier.BlockEpoch, epochNtfnCapacity)
+	if len(c.blocks) > 0 {
+		epochs <- blockEpoch(c.blocks[len(c.blocks)-1])
+	}
+	c.epochNtfns[id] = epochs
+	return &notifier.BlockEpochEvent{
+		Epochs: epochs,
+		Cancel: func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if _, ok := c.epochNtfns[id]; ok {
+				delete(c.epochNtfns, id)
+				close(epochs)
+			}
+		},
+	}, nil
+}
+
+// notifyBlock notifies the subscribers of the block just mined, and the
+// spends of the outpoints its txs spend
+func (c *BtcChain) notifyBlock(block *types.IndexedBlock) {
+	for id, ntfn := range c.spendNtfns {
+		if ntfn.notify(block) {
+			delete(c.spendNtfns, id)
+		}
+	}
+	for _, epochs := range c.epochNtfns {
+		select {
+		case epochs <- blockEpoch(block):
+		default:
+		}
+	}
+}
+
+// notify sends the spend of the outpoint if a tx of the block spends it, and
+// returns whether it did
+func (n *spendNtfn) notify(block *types.IndexedBlock) bool {
+	if block.Height < n.heightHint {
+		return false
+	}
+	for _, tx := range block.Txs {
+		for inputIdx, txIn := range tx.MsgTx().TxIn {
+			if txIn.PreviousOutPoint != n.outpoint {
+				continue
+			}
+			n.event.Spend <- &notifier.SpendDetail{
+				SpentOutPoint:     &n.outpoint,
+				SpenderTxHash:     tx.Hash(),
+				SpendingTx:        tx.MsgTx(),
+				SpenderInputIndex: uint32(inputIdx),
+				SpendingHeight:    block.Height,
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func blockEpoch(block *types.IndexedBlock) *notifier.BlockEpoch {
+	hash := block.BlockHash()
+	return &notifier.BlockEpoch{
+		Hash:        &hash,
+		Height:      block.Height,
+		BlockHeader: block.Header,
+	}
+}
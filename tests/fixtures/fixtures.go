@@ -0,0 +1,111 @@
+// Package fixtures holds BBN block results captured for each event type the
+// indexer handles, and a BBN client serving scripted sequences of them, for
+// the handler tests to drive the block processor without a node. It also
+// builds delegations and their events from deterministic keys, and a BTC
+// chain mining their txs, for the tests to run the indexer end to end.
+package fixtures
+
+import (
+	"embed"
+	"fmt"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	cmtjson "github.com/cometbft/cometbft/libs/json"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	proto "github.com/cosmos/gogoproto/proto"
+)
+
+// Names of the captured block results, each holding a single event of its
+// type. The event values are the ones of the constants below.
+const (
+	// FpCreated holds an EventFinalityProviderCreated of FpBtcPkHex
+	FpCreated = "fp_created"
+	// FpEdited holds an EventFinalityProviderEdited of FpBtcPkHex
+	FpEdited = "fp_edited"
+	// DelegationCreated holds an EventBTCDelegationCreated of StakingTxHashHex,
+	// delegated to FpBtcPkHex
+	DelegationCreated = "delegation_created"
+	// CovenantSignature holds an EventCovenantSignatureReceived of
+	// StakingTxHashHex
+	CovenantSignature = "covenant_signature"
+	// UnbondedEarly holds an EventBTCDelgationUnbondedEarly of
+	// StakingTxHashHex
+	UnbondedEarly = "unbonded_early"
+	// FpSlashed holds an EventSlashedFinalityProvider of FpBtcPkHex
+	FpSlashed = "fp_slashed"
+	// ParamsUpgrade holds the finalize block event of the governance proposal
+	// upgrading the staking params, which the block processor skips as the
+	// params are synced from the chain
+	ParamsUpgrade = "params_upgrade"
+)
+
+// Values of the captured events
+const (
+	FpBtcPkHex       = "05c74e3d7c224c1fa5772e0aded029f2727eb5197cca85873ea2e53602f1ed57"
+	StakingTxHashHex = "bcd184b6ebb639402b0416d8ff90b9ebeb6f89f5e742937c63577c22d741aa1e"
+)
+
+//go:embed blocks/*.json
+var blocks embed.FS
+
+// Names returns the names of all the captured block results
+func Names() []string {
+	return []string{
+		FpCreated, FpEdited, DelegationCreated, CovenantSignature, UnbondedEarly, FpSlashed, ParamsUpgrade,
+	}
+}
+
+// LoadBlockResults deserializes the captured block results of the name the
+// way the BBN client does the /block_results responses. Each call returns a
+// copy, free to be modified.
+func LoadBlockResults(name string) (*ctypes.ResultBlockResults, error) {
+	raw, err := blocks.ReadFile("blocks/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown block results fixture %s: %w", name, err)
+	}
+
+	var results ctypes.ResultBlockResults
+	if err := cmtjson.Unmarshal(raw, &results); err != nil {
+		return nil, fmt.Errorf("failed to deserialize block results fixture %s: %w", name, err)
+	}
+	return &results, nil
+}
+
+// MustLoadBlockResults is LoadBlockResults panicking on error, for the
+// fixtures known to exist
+func MustLoadBlockResults(name string) *ctypes.ResultBlockResults {
+	results, err := LoadBlockResults(name)
+	if err != nil {
+		panic(err)
+	}
+	return results
+}
+
+// NewBlockResults returns the results of a block with a tx per event, for the
+// blocks of events built from scratch. It panics if an event cannot be
+// converted, which only a message other than an event may cause.
+func NewBlockResults(events ...proto.Message) *ctypes.ResultBlockResults {
+	results := &ctypes.ResultBlockResults{}
+	for _, event := range events {
+		sdkEvent, err := sdk.TypedEventToEvent(event)
+		if err != nil {
+			panic(err)
+		}
+		results.TxsResults = append(results.TxsResults, &abcitypes.ExecTxResult{
+			Events: []abcitypes.Event{abcitypes.Event(sdkEvent)},
+		})
+	}
+	return results
+}
+
+// MergeBlockResults returns block results holding the events of all the
+// given ones in order, for a block with several events
+func MergeBlockResults(results ...*ctypes.ResultBlockResults) *ctypes.ResultBlockResults {
+	merged := &ctypes.ResultBlockResults{}
+	for _, result := range results {
+		merged.TxsResults = append(merged.TxsResults, result.TxsResults...)
+		merged.FinalizeBlockEvents = append(merged.FinalizeBlockEvents, result.FinalizeBlockEvents...)
+	}
+	return merged
+}
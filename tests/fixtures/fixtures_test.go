@@ -0,0 +1,133 @@
+package fixtures_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/stretchr/testify/require"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/tests/fixtures"
+)
+
+func TestLoadBlockResults(t *testing.T) {
+	eventTypes := map[string]string{
+		fixtures.FpCreated:         "babylon.btcstaking.v1.EventFinalityProviderCreated",
+		fixtures.FpEdited:          "babylon.btcstaking.v1.EventFinalityProviderEdited",
+		fixtures.DelegationCreated: "babylon.btcstaking.v1.EventBTCDelegationCreated",
+		fixtures.CovenantSignature: "babylon.btcstaking.v1.EventCovenantSignatureReceived",
+		fixtures.UnbondedEarly:     "babylon.btcstaking.v1.EventBTCDelgationUnbondedEarly",
+		fixtures.FpSlashed:         "babylon.finality.v1.EventSlashedFinalityProvider",
+		fixtures.ParamsUpgrade:     "active_proposal",
+	}
+	require.Len(t, fixtures.Names(), len(eventTypes))
+
+	for _, name := range fixtures.Names() {
+		results, err := fixtures.LoadBlockResults(name)
+		require.NoError(t, err, name)
+		events := results.FinalizeBlockEvents
+		for _, txResult := range results.TxsResults {
+			events = append(events, txResult.Events...)
+		}
+		require.Len(t, events, 1, name)
+		require.Equal(t, eventTypes[name], events[0].Type, name)
+	}
+
+	_, err := fixtures.LoadBlockResults("missing")
+	require.Error(t, err)
+}
+
+func TestBbnClientServesBlockSequence(t *testing.T) {
+	ctx := context.Background()
+	client := fixtures.NewBbnClient(
+		fixtures.MustLoadBlockResults(fixtures.FpCreated),
+		fixtures.MergeBlockResults(
+			fixtures.MustLoadBlockResults(fixtures.DelegationCreated),
+			fixtures.MustLoadBlockResults(fixtures.CovenantSignature),
+		),
+	)
+	latest, err := client.GetLatestBlockNumber(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), latest)
+
+	height := int64(2)
+	results, err := client.GetBlockResults(ctx, &height)
+	require.NoError(t, err)
+	require.Equal(t, height, results.Height)
+	require.Len(t, results.TxsResults, 2)
+
+	// Each block extends the previous one
+	block, err := client.GetBlock(ctx, &height)
+	require.NoError(t, err)
+	require.Equal(t, fixtures.BlockHash(2), block.BlockID.Hash)
+	require.Equal(t, fixtures.BlockHash(1), block.Block.Header.LastBlockID.Hash)
+	require.Equal(t, fixtures.BlockTime(2), block.Block.Time)
+
+	height = 3
+	_, err = client.GetBlock(ctx, &height)
+	require.Error(t, err)
+	client.AppendBlocks(fixtures.MustLoadBlockResults(fixtures.FpSlashed))
+	_, err = client.GetBlock(ctx, &height)
+	require.NoError(t, err)
+
+	heights, err := client.SearchEventHeights(
+		ctx, `babylon.btcstaking.v1.EventCovenantSignatureReceived.staking_tx_hash='"`+fixtures.StakingTxHashHex+`"'`,
+	)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2}, heights)
+}
+
+func TestBtcChainIsDeterministic(t *testing.T) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: 1}, nil, nil))
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	build := func() *fixtures.BtcChain {
+		chain := fixtures.NewBtcChain(7, 9)
+		chain.MineBlock(tx)
+		chain.Extend(12)
+		return chain
+	}
+
+	chain := build()
+	require.Equal(t, int32(12), chain.TipHeight())
+	for height := int32(0); height <= chain.TipHeight(); height++ {
+		block := chain.Block(height)
+		require.Equal(t, height, block.Height)
+		require.Equal(t, build().Block(height).BlockHash(), block.BlockHash())
+		require.Equal(t, blockchain.CalcMerkleRoot(block.Txs, false), block.Header.MerkleRoot)
+		if height > 0 {
+			require.Equal(t, chain.Block(height-1).BlockHash(), block.Header.PrevBlock)
+		}
+	}
+	txHeight, ok := chain.TxHeight(tx.TxHash())
+	require.True(t, ok)
+	require.Equal(t, int32(10), txHeight)
+	require.Nil(t, chain.Block(13))
+
+	tip, err := chain.GetTipHeight()
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), tip)
+	header, err := chain.GetBlockHeaderByHeight(10)
+	require.NoError(t, err)
+	require.Equal(t, chain.Block(10).Header, header)
+	_, err = chain.GetBlockHeaderByHeight(13)
+	require.Error(t, err)
+}
+
+func TestBtcChainFork(t *testing.T) {
+	chain := fixtures.NewBtcChain(0, 10)
+	forked := chain.Fork(7, 1)
+	forked.Extend(11)
+
+	for height := int32(0); height <= 7; height++ {
+		require.Equal(t, chain.Block(height).BlockHash(), forked.Block(height).BlockHash())
+	}
+	for height := int32(8); height <= 10; height++ {
+		require.NotEqual(t, chain.Block(height).BlockHash(), forked.Block(height).BlockHash())
+		require.Equal(t, forked.Block(height-1).BlockHash(), forked.Block(height).Header.PrevBlock)
+	}
+	// The fork leaves the original chain as it was
+	require.Equal(t, int32(10), chain.TipHeight())
+	require.Equal(t, int32(11), forked.TipHeight())
+}
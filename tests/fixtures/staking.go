@@ -0,0 +1,248 @@
+package fixtures
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/babylonlabs-io/babylon/btcstaking"
+	bbn "github.com/babylonlabs-io/babylon/types"
+	bbntypes "github.com/babylonlabs-io/babylon/x/btcstaking/types"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
+)
+
+// Staking values of the delegations built by NewDelegation, on signet
+const (
+	StakingParamsVersion uint32 = 0
+	StakingTime          uint16 = 1000
+	UnbondingTime        uint16 = 100
+	StakingAmount        int64  = 100000
+	UnbondingFee         int64  = 1000
+	// StakingOutputIdx is the index of the staking output of the staking tx
+	StakingOutputIdx uint32 = 1
+)
+
+// Delegation is a BTC delegation built from deterministic keys, with the txs
+// spending its staking and unbonding outputs through each of their script
+// paths. The spending txs carry placeholder signatures, which the indexer
+// does not check.
+type Delegation struct {
+	StakerBtcPkHex string
+	FpBtcPkHex     string
+	// Params are the staking params of version StakingParamsVersion the
+	// delegation follows
+	Params *bbnclient.StakingParams
+
+	StakingTx   *wire.MsgTx
+	UnbondingTx *wire.MsgTx
+	// the spends of the staking output, besides the unbonding tx
+	StakingWithdrawalTx, StakingSlashingTx *wire.MsgTx
+	// the spends of the unbonding output
+	UnbondingWithdrawalTx, UnbondingSlashingTx *wire.MsgTx
+}
+
+// fixtureKey returns the private key derived from the label, the same on
+// every run
+func fixtureKey(label string) *btcec.PrivateKey {
+	seed := sha256.Sum256([]byte(label))
+	key, _ := btcec.PrivKeyFromBytes(seed[:])
+	return key
+}
+
+func pkHex(key *btcec.PrivateKey) string {
+	return bbn.NewBIP340PubKeyFromBTCPK(key.PubKey()).MarshalHex()
+}
+
+// FpBtcPkHexOf returns the BTC pk of the finality provider of the seed
+func FpBtcPkHexOf(seed int) string {
+	return pkHex(fixtureKey("fp-" + strconv.Itoa(seed)))
+}
+
+// NewStakingParams returns the staking params of the delegations, whose
+// covenant committee is a quorum of 2 out of 3 members
+func NewStakingParams() *bbnclient.StakingParams {
+	params := &bbnclient.StakingParams{
+		CovenantQuorum:       2,
+		MinStakingValueSat:   10000,
+		MaxStakingValueSat:   10 * btcutil.SatoshiPerBitcoin,
+		MinStakingTimeBlocks: uint32(StakingTime),
+		MaxStakingTimeBlocks: uint32(StakingTime),
+		UnbondingTimeBlocks:  uint32(UnbondingTime),
+		UnbondingFeeSat:      UnbondingFee,
+		SlashingPkScript:     hex.EncodeToString([]byte{0x52}),
+		SlashingRate:         "0.1",
+		MinCommissionRate:    "0.03",
+	}
+	for i := 0; i < 3; i++ {
+		params.CovenantPks = append(params.CovenantPks, pkHex(fixtureKey("covenant-"+strconv.Itoa(i))))
+	}
+	return params
+}
+
+// NewDelegation returns the delegation of the staker of the seed to the
+// finality provider of the fp seed. It panics if the staking scripts cannot
+// be built, which the fixed params rule out.
+func NewDelegation(stakerSeed, fpSeed int) *Delegation {
+	stakerKey := fixtureKey("staker-" + strconv.Itoa(stakerSeed))
+	fpKey := fixtureKey("fp-" + strconv.Itoa(fpSeed))
+	params := NewStakingParams()
+	var covenantPks []*btcec.PublicKey
+	for i := range params.CovenantPks {
+		covenantPks = append(covenantPks, fixtureKey("covenant-"+strconv.Itoa(i)).PubKey())
+	}
+	fpPks := []*btcec.PublicKey{fpKey.PubKey()}
+
+	stakingInfo, err := btcstaking.BuildStakingInfo(
+		stakerKey.PubKey(), fpPks, covenantPks, params.CovenantQuorum,
+		StakingTime, btcutil.Amount(StakingAmount), &chaincfg.SigNetParams,
+	)
+	if err != nil {
+		panic(err)
+	}
+	unbondingInfo, err := btcstaking.BuildUnbondingInfo(
+		stakerKey.PubKey(), fpPks, covenantPks, params.CovenantQuorum,
+		UnbondingTime, btcutil.Amount(StakingAmount-UnbondingFee), &chaincfg.SigNetParams,
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	// The staking tx funds the staking output from an outpoint of the staker,
+	// unique to the seed
+	fundingTxHash := chainhash.Hash(sha256.Sum256([]byte("funding-" + strconv.Itoa(stakerSeed))))
+	stakingTx := wire.NewMsgTx(2)
+	stakingTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&fundingTxHash, 0), nil, nil))
+	stakingTx.AddTxOut(wire.NewTxOut(5000, []byte{0x51}))
+	stakingTx.AddTxOut(stakingInfo.StakingOutput)
+	stakingTxHash := stakingTx.TxHash()
+	stakingOutpoint := wire.NewOutPoint(&stakingTxHash, StakingOutputIdx)
+
+	unbondingTx := spendScriptPath(stakingOutpoint, mustSpendInfo(stakingInfo.UnbondingPathSpendInfo()),
+		unbondingInfo.UnbondingOutput)
+	unbondingTxHash := unbondingTx.TxHash()
+	unbondingOutpoint := wire.NewOutPoint(&unbondingTxHash, 0)
+
+	stakerOutput := func(value int64) *wire.TxOut {
+		return wire.NewTxOut(value, []byte{0x51})
+	}
+	slashingOutputs := []*wire.TxOut{
+		wire.NewTxOut(StakingAmount/10, []byte{0x52}), stakerOutput(StakingAmount - StakingAmount/10 - UnbondingFee),
+	}
+	return &Delegation{
+		StakerBtcPkHex: pkHex(stakerKey),
+		FpBtcPkHex:     pkHex(fpKey),
+		Params:         params,
+		StakingTx:      stakingTx,
+		UnbondingTx:    unbondingTx,
+		StakingWithdrawalTx: spendScriptPath(stakingOutpoint, mustSpendInfo(stakingInfo.TimeLockPathSpendInfo()),
+			stakerOutput(StakingAmount-UnbondingFee)),
+		StakingSlashingTx: spendScriptPath(stakingOutpoint, mustSpendInfo(stakingInfo.SlashingPathSpendInfo()),
+			slashingOutputs...),
+		UnbondingWithdrawalTx: spendScriptPath(unbondingOutpoint, mustSpendInfo(unbondingInfo.TimeLockPathSpendInfo()),
+			stakerOutput(StakingAmount-2*UnbondingFee)),
+		UnbondingSlashingTx: spendScriptPath(unbondingOutpoint, mustSpendInfo(unbondingInfo.SlashingPathSpendInfo()),
+			slashingOutputs...),
+	}
+}
+
+func mustSpendInfo(spendInfo *btcstaking.SpendInfo, err error) *btcstaking.SpendInfo {
+	if err != nil {
+		panic(err)
+	}
+	return spendInfo
+}
+
+// spendScriptPath returns the tx spending the outpoint through the script
+// path, with a placeholder signature
+func spendScriptPath(outpoint *wire.OutPoint, pathInfo *btcstaking.SpendInfo, outputs ...*wire.TxOut) *wire.MsgTx {
+	controlBlock, err := pathInfo.ControlBlock.ToBytes()
+	if err != nil {
+		panic(err)
+	}
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(outpoint, nil, wire.TxWitness{
+		make([]byte, 64), pathInfo.GetPkScriptPath(), controlBlock,
+	}))
+	for _, output := range outputs {
+		tx.AddTxOut(output)
+	}
+	return tx
+}
+
+// StakingTxHashHex returns the hash of the staking tx, which identifies the
+// delegation
+func (d *Delegation) StakingTxHashHex() string {
+	return d.StakingTx.TxHash().String()
+}
+
+// CreatedEvent returns the event of the creation of the delegation, pending
+// the covenant signatures
+func (d *Delegation) CreatedEvent() *bbntypes.EventBTCDelegationCreated {
+	return &bbntypes.EventBTCDelegationCreated{
+		StakingTxHex:              txHex(d.StakingTx),
+		StakingOutputIndex:        strconv.FormatUint(uint64(StakingOutputIdx), 10),
+		ParamsVersion:             strconv.FormatUint(uint64(StakingParamsVersion), 10),
+		FinalityProviderBtcPksHex: []string{d.FpBtcPkHex},
+		StakerBtcPkHex:            d.StakerBtcPkHex,
+		StakingTime:               strconv.FormatUint(uint64(StakingTime), 10),
+		UnbondingTime:             strconv.FormatUint(uint64(UnbondingTime), 10),
+		UnbondingTx:               txHex(d.UnbondingTx),
+		NewState:                  bbntypes.BTCDelegationStatus_PENDING.String(),
+	}
+}
+
+// CovenantQuorumReachedEvent returns the event of the covenant quorum on the
+// delegation, verified as its staking tx is not included yet
+func (d *Delegation) CovenantQuorumReachedEvent() *bbntypes.EventCovenantQuorumReached {
+	return &bbntypes.EventCovenantQuorumReached{
+		StakingTxHash: d.StakingTxHashHex(),
+		NewState:      bbntypes.BTCDelegationStatus_VERIFIED.String(),
+	}
+}
+
+// InclusionProofReceivedEvent returns the event of the inclusion of the
+// staking tx at the BTC height, activating the delegation
+func (d *Delegation) InclusionProofReceivedEvent(startHeight uint32) *bbntypes.EventBTCDelegationInclusionProofReceived {
+	return &bbntypes.EventBTCDelegationInclusionProofReceived{
+		StakingTxHash: d.StakingTxHashHex(),
+		StartHeight:   strconv.FormatUint(uint64(startHeight), 10),
+		EndHeight:     strconv.FormatUint(uint64(startHeight)+uint64(StakingTime), 10),
+		NewState:      bbntypes.BTCDelegationStatus_ACTIVE.String(),
+	}
+}
+
+// UnbondedEarlyEvent returns the event of the inclusion of the unbonding tx at
+// the BTC height
+func (d *Delegation) UnbondedEarlyEvent(startHeight uint32) *bbntypes.EventBTCDelgationUnbondedEarly {
+	return &bbntypes.EventBTCDelgationUnbondedEarly{
+		StakingTxHash: d.StakingTxHashHex(),
+		StartHeight:   strconv.FormatUint(uint64(startHeight), 10),
+		NewState:      bbntypes.BTCDelegationStatus_UNBONDED.String(),
+	}
+}
+
+// FpCreatedEvent returns the event of the creation of the finality provider
+// of the seed
+func FpCreatedEvent(seed int) *bbntypes.EventFinalityProviderCreated {
+	return &bbntypes.EventFinalityProviderCreated{
+		BtcPkHex:   FpBtcPkHexOf(seed),
+		Addr:       "bbn1fp" + strconv.Itoa(seed),
+		Commission: "0.050000000000000000",
+		Moniker:    "Fixture FP " + strconv.Itoa(seed),
+	}
+}
+
+func txHex(tx *wire.MsgTx) string {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
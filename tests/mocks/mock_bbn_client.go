@@ -17,6 +17,36 @@ type BbnInterface struct {
 	mock.Mock
 }
 
+// GetActiveFinalityProvidersAtHeight provides a mock function with given fields: ctx, height
+func (_m *BbnInterface) GetActiveFinalityProvidersAtHeight(ctx context.Context, height uint64) ([]*bbnclient.FinalityProviderVotingPower, error) {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetActiveFinalityProvidersAtHeight")
+	}
+
+	var r0 []*bbnclient.FinalityProviderVotingPower
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) ([]*bbnclient.FinalityProviderVotingPower, error)); ok {
+		return rf(ctx, height)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*bbnclient.FinalityProviderVotingPower); ok {
+		r0 = rf(ctx, height)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bbnclient.FinalityProviderVotingPower)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, height)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllStakingParams provides a mock function with given fields: ctx
 func (_m *BbnInterface) GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error) {
 	ret := _m.Called(ctx)
@@ -47,6 +77,103 @@ func (_m *BbnInterface) GetAllStakingParams(ctx context.Context) (map[uint32]*bb
 	return r0, r1
 }
 
+// GetBTCDelegation provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *BbnInterface) GetBTCDelegation(ctx context.Context, stakingTxHashHex string) (*bbnclient.BTCDelegation, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegation")
+	}
+
+	var r0 *bbnclient.BTCDelegation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*bbnclient.BTCDelegation, error)); ok {
+		return rf(ctx, stakingTxHashHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *bbnclient.BTCDelegation); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bbnclient.BTCDelegation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHashHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegations provides a mock function with given fields: ctx, pageKey, limit
+func (_m *BbnInterface) GetBTCDelegations(ctx context.Context, pageKey []byte, limit uint64) ([]*bbnclient.BTCDelegation, []byte, error) {
+	ret := _m.Called(ctx, pageKey, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegations")
+	}
+
+	var r0 []*bbnclient.BTCDelegation
+	var r1 []byte
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, uint64) ([]*bbnclient.BTCDelegation, []byte, error)); ok {
+		return rf(ctx, pageKey, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, uint64) []*bbnclient.BTCDelegation); ok {
+		r0 = rf(ctx, pageKey, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bbnclient.BTCDelegation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []byte, uint64) []byte); ok {
+		r1 = rf(ctx, pageKey, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, []byte, uint64) error); ok {
+		r2 = rf(ctx, pageKey, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetBTCLightClientTipHeight provides a mock function with given fields: ctx
+func (_m *BbnInterface) GetBTCLightClientTipHeight(ctx context.Context) (uint64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCLightClientTipHeight")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (uint64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlock provides a mock function with given fields: ctx, blockHeight
 func (_m *BbnInterface) GetBlock(ctx context.Context, blockHeight *int64) (*coretypes.ResultBlock, error) {
 	ret := _m.Called(ctx, blockHeight)
@@ -107,6 +234,34 @@ func (_m *BbnInterface) GetBlockResults(ctx context.Context, blockHeight *int64)
 	return r0, r1
 }
 
+// GetChainID provides a mock function with given fields: ctx
+func (_m *BbnInterface) GetChainID(ctx context.Context) (string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChainID")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) string); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCheckpointParams provides a mock function with given fields: ctx
 func (_m *BbnInterface) GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error) {
 	ret := _m.Called(ctx)
@@ -137,6 +292,45 @@ func (_m *BbnInterface) GetCheckpointParams(ctx context.Context) (*bbnclient.Che
 	return r0, r1
 }
 
+// GetFinalityProviders provides a mock function with given fields: ctx, pageKey, limit
+func (_m *BbnInterface) GetFinalityProviders(ctx context.Context, pageKey []byte, limit uint64) ([]*bbnclient.FinalityProvider, []byte, error) {
+	ret := _m.Called(ctx, pageKey, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProviders")
+	}
+
+	var r0 []*bbnclient.FinalityProvider
+	var r1 []byte
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, uint64) ([]*bbnclient.FinalityProvider, []byte, error)); ok {
+		return rf(ctx, pageKey, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []byte, uint64) []*bbnclient.FinalityProvider); ok {
+		r0 = rf(ctx, pageKey, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*bbnclient.FinalityProvider)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []byte, uint64) []byte); ok {
+		r1 = rf(ctx, pageKey, limit)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, []byte, uint64) error); ok {
+		r2 = rf(ctx, pageKey, limit)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // GetLatestBlockNumber provides a mock function with given fields: ctx
 func (_m *BbnInterface) GetLatestBlockNumber(ctx context.Context) (int64, error) {
 	ret := _m.Called(ctx)
@@ -183,6 +377,64 @@ func (_m *BbnInterface) IsRunning() bool {
 	return r0
 }
 
+// SearchEventHeights provides a mock function with given fields: ctx, query
+func (_m *BbnInterface) SearchEventHeights(ctx context.Context, query string) ([]int64, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchEventHeights")
+	}
+
+	var r0 []int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]int64, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []int64); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchFirstEventHeight provides a mock function with given fields: ctx, query
+func (_m *BbnInterface) SearchFirstEventHeight(ctx context.Context, query string) (int64, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchFirstEventHeight")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (int64, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) int64); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Start provides a mock function with given fields:
 func (_m *BbnInterface) Start() error {
 	ret := _m.Called()
@@ -2,13 +2,75 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	wire "github.com/btcsuite/btcd/wire"
+)
 
 // BtcInterface is an autogenerated mock type for the BtcInterface type
 type BtcInterface struct {
 	mock.Mock
 }
 
+// GetBlockHeaderByHeight provides a mock function with given fields: height
+func (_m *BtcInterface) GetBlockHeaderByHeight(height uint64) (*wire.BlockHeader, error) {
+	ret := _m.Called(height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBlockHeaderByHeight")
+	}
+
+	var r0 *wire.BlockHeader
+	var r1 error
+	if rf, ok := ret.Get(0).(func(uint64) (*wire.BlockHeader, error)); ok {
+		return rf(height)
+	}
+	if rf, ok := ret.Get(0).(func(uint64) *wire.BlockHeader); ok {
+		r0 = rf(height)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*wire.BlockHeader)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(uint64) error); ok {
+		r1 = rf(height)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNetwork provides a mock function with given fields:
+func (_m *BtcInterface) GetNetwork() (string, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNetwork")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTipHeight provides a mock function with given fields:
 func (_m *BtcInterface) GetTipHeight() (uint64, error) {
 	ret := _m.Called()
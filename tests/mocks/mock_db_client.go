@@ -7,59 +7,1577 @@ import (
 
 	bbnclient "github.com/babylonlabs-io/babylon-staking-indexer/internal/clients/bbnclient"
 
+	db "github.com/babylonlabs-io/babylon-staking-indexer/internal/db"
+
 	mock "github.com/stretchr/testify/mock"
 
-	model "github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+	model "github.com/babylonlabs-io/babylon-staking-indexer/internal/db/model"
+
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
+
+	time "time"
+
+	types "github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
+)
+
+// DbInterface is an autogenerated mock type for the DbInterface type
+type DbInterface struct {
+	mock.Mock
+}
+
+// AcquireLock provides a mock function with given fields: ctx, name, owner, ttl
+func (_m *DbInterface) AcquireLock(ctx context.Context, name string, owner string, ttl time.Duration) error {
+	ret := _m.Called(ctx, name, owner, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcquireLock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Duration) error); ok {
+		r0 = rf(ctx, name, owner, ttl)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AggregateStateTransitions provides a mock function with given fields: ctx, fromTime, toTime, periodUnit, visit
+func (_m *DbInterface) AggregateStateTransitions(ctx context.Context, fromTime int64, toTime int64, periodUnit string, visit func(stats *model.StateTransitionPeriodStats) error) error {
+	ret := _m.Called(ctx, fromTime, toTime, periodUnit, visit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AggregateStateTransitions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, func(stats *model.StateTransitionPeriodStats) error) error); ok {
+		r0 = rf(ctx, fromTime, toTime, periodUnit, visit)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ApplyBTCDelegationStateChange provides a mock function with given fields: ctx, change
+func (_m *DbInterface) ApplyBTCDelegationStateChange(ctx context.Context, change db.DelegationStateChange) error {
+	ret := _m.Called(ctx, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyBTCDelegationStateChange")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, db.DelegationStateChange) error); ok {
+		r0 = rf(ctx, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ArchivePrunableBTCDelegations provides a mock function with given fields: ctx, before, limit
+func (_m *DbInterface) ArchivePrunableBTCDelegations(ctx context.Context, before int64, limit int64) (uint64, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ArchivePrunableBTCDelegations")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (uint64, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) uint64); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BulkSaveCovenantSignatures provides a mock function with given fields: ctx, sigs
+func (_m *DbInterface) BulkSaveCovenantSignatures(ctx context.Context, sigs []db.CovenantSigRecord) error {
+	ret := _m.Called(ctx, sigs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BulkSaveCovenantSignatures")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []db.CovenantSigRecord) error); ok {
+		r0 = rf(ctx, sigs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BulkUpdateDelegationStates provides a mock function with given fields: ctx, updates
+func (_m *DbInterface) BulkUpdateDelegationStates(ctx context.Context, updates []db.DelegationStateUpdate) error {
+	ret := _m.Called(ctx, updates)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BulkUpdateDelegationStates")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []db.DelegationStateUpdate) error); ok {
+		r0 = rf(ctx, updates)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CountFinalityProvidersByState provides a mock function with given fields: ctx
+func (_m *DbInterface) CountFinalityProvidersByState(ctx context.Context) (map[string]uint64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountFinalityProvidersByState")
+	}
+
+	var r0 map[string]uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (map[string]uint64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) map[string]uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]uint64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountPrunableArchivedTimeLocks provides a mock function with given fields: ctx, before
+func (_m *DbInterface) CountPrunableArchivedTimeLocks(ctx context.Context, before int64) (uint64, error) {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountPrunableArchivedTimeLocks")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (uint64, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) uint64); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountPrunableBTCDelegations provides a mock function with given fields: ctx, before
+func (_m *DbInterface) CountPrunableBTCDelegations(ctx context.Context, before int64) (uint64, error) {
+	ret := _m.Called(ctx, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountPrunableBTCDelegations")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (uint64, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) uint64); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountStuckDelegations provides a mock function with given fields: ctx, state, before
+func (_m *DbInterface) CountStuckDelegations(ctx context.Context, state types.DelegationState, before int64) (uint64, error) {
+	ret := _m.Called(ctx, state, before)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountStuckDelegations")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, types.DelegationState, int64) (uint64, error)); ok {
+		return rf(ctx, state, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, types.DelegationState, int64) uint64); ok {
+		r0 = rf(ctx, state, before)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, types.DelegationState, int64) error); ok {
+		r1 = rf(ctx, state, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteBTCDelegation provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) DeleteBTCDelegation(ctx context.Context, stakingTxHashHex string) error {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBTCDelegation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteBTCDerivedChangesBelow provides a mock function with given fields: ctx, height
+func (_m *DbInterface) DeleteBTCDerivedChangesBelow(ctx context.Context, height uint64) error {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBTCDerivedChangesBelow")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, height)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteBTCHeadersAbove provides a mock function with given fields: ctx, height
+func (_m *DbInterface) DeleteBTCHeadersAbove(ctx context.Context, height uint64) error {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBTCHeadersAbove")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, height)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteBTCHeadersBelow provides a mock function with given fields: ctx, height
+func (_m *DbInterface) DeleteBTCHeadersBelow(ctx context.Context, height uint64) error {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBTCHeadersBelow")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, height)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpiredDelegation provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpiredDelegation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeletePrunableArchivedTimeLocks provides a mock function with given fields: ctx, before, limit
+func (_m *DbInterface) DeletePrunableArchivedTimeLocks(ctx context.Context, before int64, limit int64) (uint64, error) {
+	ret := _m.Called(ctx, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeletePrunableArchivedTimeLocks")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) (uint64, error)); ok {
+		return rf(ctx, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) uint64); ok {
+		r0 = rf(ctx, before, limit)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteTimeLocks provides a mock function with given fields: ctx, ids
+func (_m *DbInterface) DeleteTimeLocks(ctx context.Context, ids []primitive.ObjectID) (uint64, error) {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTimeLocks")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []primitive.ObjectID) (uint64, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []primitive.ObjectID) uint64); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []primitive.ObjectID) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DetectProcessedHeightGaps provides a mock function with given fields: ctx, from, to
+func (_m *DbInterface) DetectProcessedHeightGaps(ctx context.Context, from uint64, to uint64) ([]*model.BbnHeightRange, error) {
+	ret := _m.Called(ctx, from, to)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DetectProcessedHeightGaps")
+	}
+
+	var r0 []*model.BbnHeightRange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) ([]*model.BbnHeightRange, error)); ok {
+		return rf(ctx, from, to)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) []*model.BbnHeightRange); ok {
+		r0 = rf(ctx, from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BbnHeightRange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
+		r1 = rf(ctx, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindExpiredDelegations provides a mock function with given fields: ctx, btcTipHeight, limit
+func (_m *DbInterface) FindExpiredDelegations(ctx context.Context, btcTipHeight uint64, limit uint64) ([]model.TimeLockDocument, error) {
+	ret := _m.Called(ctx, btcTipHeight, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindExpiredDelegations")
+	}
+
+	var r0 []model.TimeLockDocument
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) ([]model.TimeLockDocument, error)); ok {
+		return rf(ctx, btcTipHeight, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) []model.TimeLockDocument); ok {
+		r0 = rf(ctx, btcTipHeight, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.TimeLockDocument)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
+		r1 = rf(ctx, btcTipHeight, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindOrphanedTimeLocks provides a mock function with given fields: ctx, terminalStates, limit
+func (_m *DbInterface) FindOrphanedTimeLocks(ctx context.Context, terminalStates []types.DelegationState, limit uint64) ([]*model.OrphanedTimeLock, error) {
+	ret := _m.Called(ctx, terminalStates, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOrphanedTimeLocks")
+	}
+
+	var r0 []*model.OrphanedTimeLock
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationState, uint64) ([]*model.OrphanedTimeLock, error)); ok {
+		return rf(ctx, terminalStates, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationState, uint64) []*model.OrphanedTimeLock); ok {
+		r0 = rf(ctx, terminalStates, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.OrphanedTimeLock)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []types.DelegationState, uint64) error); ok {
+		r1 = rf(ctx, terminalStates, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindStuckDelegations provides a mock function with given fields: ctx, state, before, limit
+func (_m *DbInterface) FindStuckDelegations(ctx context.Context, state types.DelegationState, before int64, limit uint64) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, state, before, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindStuckDelegations")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, types.DelegationState, int64, uint64) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, state, before, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, types.DelegationState, int64, uint64) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, state, before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, types.DelegationState, int64, uint64) error); ok {
+		r1 = rf(ctx, state, before, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindTimeLocksByParamsVersion provides a mock function with given fields: ctx, subStates, paramsVersion
+func (_m *DbInterface) FindTimeLocksByParamsVersion(ctx context.Context, subStates []types.DelegationSubState, paramsVersion uint32) ([]model.TimeLockDocument, error) {
+	ret := _m.Called(ctx, subStates, paramsVersion)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindTimeLocksByParamsVersion")
+	}
+
+	var r0 []model.TimeLockDocument
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationSubState, uint32) ([]model.TimeLockDocument, error)); ok {
+		return rf(ctx, subStates, paramsVersion)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationSubState, uint32) []model.TimeLockDocument); ok {
+		r0 = rf(ctx, subStates, paramsVersion)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.TimeLockDocument)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []types.DelegationSubState, uint32) error); ok {
+		r1 = rf(ctx, subStates, paramsVersion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ForEachExpiredDelegation provides a mock function with given fields: ctx, btcTipHeight, fn
+func (_m *DbInterface) ForEachExpiredDelegation(ctx context.Context, btcTipHeight uint64, fn func(model.TimeLockDocument) error) error {
+	ret := _m.Called(ctx, btcTipHeight, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ForEachExpiredDelegation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, func(model.TimeLockDocument) error) error); ok {
+		r0 = rf(ctx, btcTipHeight, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAllStakingParams provides a mock function with given fields: ctx
+func (_m *DbInterface) GetAllStakingParams(ctx context.Context) (map[uint32]*bbnclient.StakingParams, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAllStakingParams")
+	}
+
+	var r0 map[uint32]*bbnclient.StakingParams
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (map[uint32]*bbnclient.StakingParams, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) map[uint32]*bbnclient.StakingParams); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[uint32]*bbnclient.StakingParams)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetArchivedTimeLocks provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) GetArchivedTimeLocks(ctx context.Context, stakingTxHashHex string) ([]*model.ArchivedTimeLockDocument, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetArchivedTimeLocks")
+	}
+
+	var r0 []*model.ArchivedTimeLockDocument
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.ArchivedTimeLockDocument, error)); ok {
+		return rf(ctx, stakingTxHashHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.ArchivedTimeLockDocument); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.ArchivedTimeLockDocument)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHashHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegationByStakingTxHash provides a mock function with given fields: ctx, stakingTxHash
+func (_m *DbInterface) GetBTCDelegationByStakingTxHash(ctx context.Context, stakingTxHash string) (*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, stakingTxHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationByStakingTxHash")
+	}
+
+	var r0 *model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, stakingTxHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, stakingTxHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegationState provides a mock function with given fields: ctx, stakingTxHash
+func (_m *DbInterface) GetBTCDelegationState(ctx context.Context, stakingTxHash string) (*types.DelegationState, error) {
+	ret := _m.Called(ctx, stakingTxHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationState")
+	}
+
+	var r0 *types.DelegationState
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*types.DelegationState, error)); ok {
+		return rf(ctx, stakingTxHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *types.DelegationState); ok {
+		r0 = rf(ctx, stakingTxHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.DelegationState)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegationSummaryByStakingTxHash provides a mock function with given fields: ctx, stakingTxHash
+func (_m *DbInterface) GetBTCDelegationSummaryByStakingTxHash(ctx context.Context, stakingTxHash string) (*model.BTCDelegationSummary, error) {
+	ret := _m.Called(ctx, stakingTxHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationSummaryByStakingTxHash")
+	}
+
+	var r0 *model.BTCDelegationSummary
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.BTCDelegationSummary, error)); ok {
+		return rf(ctx, stakingTxHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.BTCDelegationSummary); ok {
+		r0 = rf(ctx, stakingTxHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.BTCDelegationSummary)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegationsAfter provides a mock function with given fields: ctx, filter, stakingTxHashHex, limit
+func (_m *DbInterface) GetBTCDelegationsAfter(ctx context.Context, filter db.BTCDelegationsFilter, stakingTxHashHex string, limit uint64) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, filter, stakingTxHashHex, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationsAfter")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, db.BTCDelegationsFilter, string, uint64) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, filter, stakingTxHashHex, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, db.BTCDelegationsFilter, string, uint64) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, filter, stakingTxHashHex, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, db.BTCDelegationsFilter, string, uint64) error); ok {
+		r1 = rf(ctx, filter, stakingTxHashHex, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegationsByStakingTxHashes provides a mock function with given fields: ctx, stakingTxHashes
+func (_m *DbInterface) GetBTCDelegationsByStakingTxHashes(ctx context.Context, stakingTxHashes []string) (map[string]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, stakingTxHashes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationsByStakingTxHashes")
+	}
+
+	var r0 map[string]*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, stakingTxHashes)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, stakingTxHashes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, stakingTxHashes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegationsByStates provides a mock function with given fields: ctx, states
+func (_m *DbInterface) GetBTCDelegationsByStates(ctx context.Context, states []types.DelegationState) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, states)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationsByStates")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationState) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, states)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationState) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, states)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []types.DelegationState) error); ok {
+		r1 = rf(ctx, states)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCDelegationsCreatedBetween provides a mock function with given fields: ctx, fromHeight, toHeight
+func (_m *DbInterface) GetBTCDelegationsCreatedBetween(ctx context.Context, fromHeight int64, toHeight int64) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, fromHeight, toHeight)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCDelegationsCreatedBetween")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, fromHeight, toHeight)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, fromHeight, toHeight)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, fromHeight, toHeight)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBTCHeaderByHeight provides a mock function with given fields: ctx, height
+func (_m *DbInterface) GetBTCHeaderByHeight(ctx context.Context, height uint64) (*model.BTCHeader, error) {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBTCHeaderByHeight")
+	}
+
+	var r0 *model.BTCHeader
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) (*model.BTCHeader, error)); ok {
+		return rf(ctx, height)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) *model.BTCHeader); ok {
+		r0 = rf(ctx, height)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.BTCHeader)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, height)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBootstrap provides a mock function with given fields: ctx
+func (_m *DbInterface) GetBootstrap(ctx context.Context) (*model.Bootstrap, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBootstrap")
+	}
+
+	var r0 *model.Bootstrap
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*model.Bootstrap, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *model.Bootstrap); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Bootstrap)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCheckpointParams provides a mock function with given fields: ctx
+func (_m *DbInterface) GetCheckpointParams(ctx context.Context) (*bbnclient.CheckpointParams, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCheckpointParams")
+	}
+
+	var r0 *bbnclient.CheckpointParams
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*bbnclient.CheckpointParams, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *bbnclient.CheckpointParams); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*bbnclient.CheckpointParams)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDelegationOutboxEvents provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) GetDelegationOutboxEvents(ctx context.Context, stakingTxHashHex string) ([]*model.OutboxEvent, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDelegationOutboxEvents")
+	}
+
+	var r0 []*model.OutboxEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.OutboxEvent, error)); ok {
+		return rf(ctx, stakingTxHashHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.OutboxEvent); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.OutboxEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHashHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDelegationStateTransitions provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) GetDelegationStateTransitions(ctx context.Context, stakingTxHashHex string) ([]*model.DelegationStateTransition, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDelegationStateTransitions")
+	}
+
+	var r0 []*model.DelegationStateTransition
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.DelegationStateTransition, error)); ok {
+		return rf(ctx, stakingTxHashHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.DelegationStateTransition); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.DelegationStateTransition)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHashHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDelegationStatsByState provides a mock function with given fields: ctx
+func (_m *DbInterface) GetDelegationStatsByState(ctx context.Context) ([]*model.DelegationStateStats, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDelegationStatsByState")
+	}
+
+	var r0 []*model.DelegationStateStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*model.DelegationStateStats, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*model.DelegationStateStats); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.DelegationStateStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDelegationsByFinalityProvider provides a mock function with given fields: ctx, fpBtcPkHex
+func (_m *DbInterface) GetDelegationsByFinalityProvider(ctx context.Context, fpBtcPkHex string) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, fpBtcPkHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetDelegationsByFinalityProvider")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, fpBtcPkHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, fpBtcPkHex)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fpBtcPkHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetExpiryBacklogStats provides a mock function with given fields: ctx, btcTipHeight
+func (_m *DbInterface) GetExpiryBacklogStats(ctx context.Context, btcTipHeight uint64) ([]*model.ExpiryBacklogStats, error) {
+	ret := _m.Called(ctx, btcTipHeight)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetExpiryBacklogStats")
+	}
+
+	var r0 []*model.ExpiryBacklogStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) ([]*model.ExpiryBacklogStats, error)); ok {
+		return rf(ctx, btcTipHeight)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*model.ExpiryBacklogStats); ok {
+		r0 = rf(ctx, btcTipHeight)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.ExpiryBacklogStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, btcTipHeight)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinalityProviderActivationPeriods provides a mock function with given fields: ctx, fpBtcPk
+func (_m *DbInterface) GetFinalityProviderActivationPeriods(ctx context.Context, fpBtcPk string) ([]*model.FinalityProviderActivationPeriod, error) {
+	ret := _m.Called(ctx, fpBtcPk)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProviderActivationPeriods")
+	}
+
+	var r0 []*model.FinalityProviderActivationPeriod
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.FinalityProviderActivationPeriod, error)); ok {
+		return rf(ctx, fpBtcPk)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.FinalityProviderActivationPeriod); ok {
+		r0 = rf(ctx, fpBtcPk)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.FinalityProviderActivationPeriod)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, fpBtcPk)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinalityProviderByBtcPk provides a mock function with given fields: ctx, btcPk
+func (_m *DbInterface) GetFinalityProviderByBtcPk(ctx context.Context, btcPk string) (*model.FinalityProviderDetails, error) {
+	ret := _m.Called(ctx, btcPk)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProviderByBtcPk")
+	}
+
+	var r0 *model.FinalityProviderDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.FinalityProviderDetails, error)); ok {
+		return rf(ctx, btcPk)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.FinalityProviderDetails); ok {
+		r0 = rf(ctx, btcPk)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.FinalityProviderDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, btcPk)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinalityProviderStakeDistribution provides a mock function with given fields: ctx, limit
+func (_m *DbInterface) GetFinalityProviderStakeDistribution(ctx context.Context, limit int64) (*model.FinalityProviderStakeDistribution, error) {
+	ret := _m.Called(ctx, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProviderStakeDistribution")
+	}
+
+	var r0 *model.FinalityProviderStakeDistribution
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*model.FinalityProviderStakeDistribution, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *model.FinalityProviderStakeDistribution); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.FinalityProviderStakeDistribution)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinalityProviderStats provides a mock function with given fields: ctx, btcPk
+func (_m *DbInterface) GetFinalityProviderStats(ctx context.Context, btcPk string) (*model.FinalityProviderStats, error) {
+	ret := _m.Called(ctx, btcPk)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProviderStats")
+	}
+
+	var r0 *model.FinalityProviderStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.FinalityProviderStats, error)); ok {
+		return rf(ctx, btcPk)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.FinalityProviderStats); ok {
+		r0 = rf(ctx, btcPk)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.FinalityProviderStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, btcPk)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinalityProviders provides a mock function with given fields: ctx, filter, paginationToken, limit
+func (_m *DbInterface) GetFinalityProviders(ctx context.Context, filter db.FinalityProvidersFilter, paginationToken string, limit int64) (*db.DbResultMap[*model.FinalityProviderDetails], error) {
+	ret := _m.Called(ctx, filter, paginationToken, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProviders")
+	}
+
+	var r0 *db.DbResultMap[*model.FinalityProviderDetails]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, db.FinalityProvidersFilter, string, int64) (*db.DbResultMap[*model.FinalityProviderDetails], error)); ok {
+		return rf(ctx, filter, paginationToken, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, db.FinalityProvidersFilter, string, int64) *db.DbResultMap[*model.FinalityProviderDetails]); ok {
+		r0 = rf(ctx, filter, paginationToken, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.DbResultMap[*model.FinalityProviderDetails])
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, db.FinalityProvidersFilter, string, int64) error); ok {
+		r1 = rf(ctx, filter, paginationToken, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFinalityProvidersByBsnId provides a mock function with given fields: ctx, bsnId
+func (_m *DbInterface) GetFinalityProvidersByBsnId(ctx context.Context, bsnId string) ([]*model.FinalityProviderDetails, error) {
+	ret := _m.Called(ctx, bsnId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFinalityProvidersByBsnId")
+	}
+
+	var r0 []*model.FinalityProviderDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.FinalityProviderDetails, error)); ok {
+		return rf(ctx, bsnId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.FinalityProviderDetails); ok {
+		r0 = rf(ctx, bsnId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.FinalityProviderDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, bsnId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetGlobalStats provides a mock function with given fields: ctx
+func (_m *DbInterface) GetGlobalStats(ctx context.Context) (*model.GlobalStats, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetGlobalStats")
+	}
+
+	var r0 *model.GlobalStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*model.GlobalStats, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *model.GlobalStats); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.GlobalStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLastProcessedBbnBlock provides a mock function with given fields: ctx
+func (_m *DbInterface) GetLastProcessedBbnBlock(ctx context.Context) (*model.LastProcessedHeight, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLastProcessedBbnBlock")
+	}
+
+	var r0 *model.LastProcessedHeight
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*model.LastProcessedHeight, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *model.LastProcessedHeight); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.LastProcessedHeight)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLastProcessedBbnHeight provides a mock function with given fields: ctx
+func (_m *DbInterface) GetLastProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLastProcessedBbnHeight")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (uint64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLatestBTCHeader provides a mock function with given fields: ctx
+func (_m *DbInterface) GetLatestBTCHeader(ctx context.Context) (*model.BTCHeader, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLatestBTCHeader")
+	}
+
+	var r0 *model.BTCHeader
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*model.BTCHeader, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *model.BTCHeader); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.BTCHeader)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLatestFinalityProviderVotingPowerChanges provides a mock function with given fields: ctx, fpBtcPks
+func (_m *DbInterface) GetLatestFinalityProviderVotingPowerChanges(ctx context.Context, fpBtcPks []string) (map[string]*model.FinalityProviderVotingPowerChange, error) {
+	ret := _m.Called(ctx, fpBtcPks)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLatestFinalityProviderVotingPowerChanges")
+	}
+
+	var r0 map[string]*model.FinalityProviderVotingPowerChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) (map[string]*model.FinalityProviderVotingPowerChange, error)); ok {
+		return rf(ctx, fpBtcPks)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]*model.FinalityProviderVotingPowerChange); ok {
+		r0 = rf(ctx, fpBtcPks)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]*model.FinalityProviderVotingPowerChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, fpBtcPks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLowestProcessedBbnHeight provides a mock function with given fields: ctx
+func (_m *DbInterface) GetLowestProcessedBbnHeight(ctx context.Context) (uint64, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLowestProcessedBbnHeight")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (uint64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) uint64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOutboxSequence provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) GetOutboxSequence(ctx context.Context, stakingTxHashHex string) (uint64, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOutboxSequence")
+	}
+
+	var r0 uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (uint64, error)); ok {
+		return rf(ctx, stakingTxHashHex)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) uint64); ok {
+		r0 = rf(ctx, stakingTxHashHex)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, stakingTxHashHex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOutboxStats provides a mock function with given fields: ctx
+func (_m *DbInterface) GetOutboxStats(ctx context.Context) (*model.OutboxStats, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOutboxStats")
+	}
+
+	var r0 *model.OutboxStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*model.OutboxStats, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *model.OutboxStats); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.OutboxStats)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPoisonOutboxEvents provides a mock function with given fields: ctx
+func (_m *DbInterface) GetPoisonOutboxEvents(ctx context.Context) ([]*model.OutboxEvent, error) {
+	ret := _m.Called(ctx)
 
-	types "github.com/babylonlabs-io/babylon-staking-indexer/internal/types"
-)
+	if len(ret) == 0 {
+		panic("no return value specified for GetPoisonOutboxEvents")
+	}
 
-// DbInterface is an autogenerated mock type for the DbInterface type
-type DbInterface struct {
-	mock.Mock
+	var r0 []*model.OutboxEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*model.OutboxEvent, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*model.OutboxEvent); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.OutboxEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// DeleteExpiredDelegation provides a mock function with given fields: ctx, stakingTxHashHex
-func (_m *DbInterface) DeleteExpiredDelegation(ctx context.Context, stakingTxHashHex string) error {
-	ret := _m.Called(ctx, stakingTxHashHex)
+// GetStakerDelegationSummaries provides a mock function with given fields: ctx, filter, paginationToken, limit
+func (_m *DbInterface) GetStakerDelegationSummaries(ctx context.Context, filter db.StakerDelegationsFilter, paginationToken string, limit int64) (*db.DbResultMap[*model.BTCDelegationSummary], error) {
+	ret := _m.Called(ctx, filter, paginationToken, limit)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteExpiredDelegation")
+		panic("no return value specified for GetStakerDelegationSummaries")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, stakingTxHashHex)
+	var r0 *db.DbResultMap[*model.BTCDelegationSummary]
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, db.StakerDelegationsFilter, string, int64) (*db.DbResultMap[*model.BTCDelegationSummary], error)); ok {
+		return rf(ctx, filter, paginationToken, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, db.StakerDelegationsFilter, string, int64) *db.DbResultMap[*model.BTCDelegationSummary]); ok {
+		r0 = rf(ctx, filter, paginationToken, limit)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.DbResultMap[*model.BTCDelegationSummary])
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, db.StakerDelegationsFilter, string, int64) error); ok {
+		r1 = rf(ctx, filter, paginationToken, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// FindExpiredDelegations provides a mock function with given fields: ctx, btcTipHeight, limit
-func (_m *DbInterface) FindExpiredDelegations(ctx context.Context, btcTipHeight uint64, limit uint64) ([]model.TimeLockDocument, error) {
-	ret := _m.Called(ctx, btcTipHeight, limit)
+// GetStakerDelegations provides a mock function with given fields: ctx, filter, paginationToken, limit
+func (_m *DbInterface) GetStakerDelegations(ctx context.Context, filter db.StakerDelegationsFilter, paginationToken string, limit int64) (*db.DbResultMap[*model.BTCDelegationDetails], error) {
+	ret := _m.Called(ctx, filter, paginationToken, limit)
 
 	if len(ret) == 0 {
-		panic("no return value specified for FindExpiredDelegations")
+		panic("no return value specified for GetStakerDelegations")
 	}
 
-	var r0 []model.TimeLockDocument
+	var r0 *db.DbResultMap[*model.BTCDelegationDetails]
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) ([]model.TimeLockDocument, error)); ok {
-		return rf(ctx, btcTipHeight, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, db.StakerDelegationsFilter, string, int64) (*db.DbResultMap[*model.BTCDelegationDetails], error)); ok {
+		return rf(ctx, filter, paginationToken, limit)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) []model.TimeLockDocument); ok {
-		r0 = rf(ctx, btcTipHeight, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, db.StakerDelegationsFilter, string, int64) *db.DbResultMap[*model.BTCDelegationDetails]); ok {
+		r0 = rf(ctx, filter, paginationToken, limit)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.TimeLockDocument)
+			r0 = ret.Get(0).(*db.DbResultMap[*model.BTCDelegationDetails])
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
-		r1 = rf(ctx, btcTipHeight, limit)
+	if rf, ok := ret.Get(1).(func(context.Context, db.StakerDelegationsFilter, string, int64) error); ok {
+		r1 = rf(ctx, filter, paginationToken, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -67,29 +1585,29 @@ func (_m *DbInterface) FindExpiredDelegations(ctx context.Context, btcTipHeight
 	return r0, r1
 }
 
-// GetBTCDelegationByStakingTxHash provides a mock function with given fields: ctx, stakingTxHash
-func (_m *DbInterface) GetBTCDelegationByStakingTxHash(ctx context.Context, stakingTxHash string) (*model.BTCDelegationDetails, error) {
-	ret := _m.Called(ctx, stakingTxHash)
+// GetStakingParams provides a mock function with given fields: ctx, version
+func (_m *DbInterface) GetStakingParams(ctx context.Context, version uint32) (*bbnclient.StakingParams, error) {
+	ret := _m.Called(ctx, version)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetBTCDelegationByStakingTxHash")
+		panic("no return value specified for GetStakingParams")
 	}
 
-	var r0 *model.BTCDelegationDetails
+	var r0 *bbnclient.StakingParams
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.BTCDelegationDetails, error)); ok {
-		return rf(ctx, stakingTxHash)
+	if rf, ok := ret.Get(0).(func(context.Context, uint32) (*bbnclient.StakingParams, error)); ok {
+		return rf(ctx, version)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.BTCDelegationDetails); ok {
-		r0 = rf(ctx, stakingTxHash)
+	if rf, ok := ret.Get(0).(func(context.Context, uint32) *bbnclient.StakingParams); ok {
+		r0 = rf(ctx, version)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.BTCDelegationDetails)
+			r0 = ret.Get(0).(*bbnclient.StakingParams)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, stakingTxHash)
+	if rf, ok := ret.Get(1).(func(context.Context, uint32) error); ok {
+		r1 = rf(ctx, version)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -97,29 +1615,29 @@ func (_m *DbInterface) GetBTCDelegationByStakingTxHash(ctx context.Context, stak
 	return r0, r1
 }
 
-// GetBTCDelegationState provides a mock function with given fields: ctx, stakingTxHash
-func (_m *DbInterface) GetBTCDelegationState(ctx context.Context, stakingTxHash string) (*types.DelegationState, error) {
-	ret := _m.Called(ctx, stakingTxHash)
+// GetTimeLocks provides a mock function with given fields: ctx, stakingTxHashHex
+func (_m *DbInterface) GetTimeLocks(ctx context.Context, stakingTxHashHex string) ([]model.TimeLockDocument, error) {
+	ret := _m.Called(ctx, stakingTxHashHex)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetBTCDelegationState")
+		panic("no return value specified for GetTimeLocks")
 	}
 
-	var r0 *types.DelegationState
+	var r0 []model.TimeLockDocument
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*types.DelegationState, error)); ok {
-		return rf(ctx, stakingTxHash)
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]model.TimeLockDocument, error)); ok {
+		return rf(ctx, stakingTxHashHex)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *types.DelegationState); ok {
-		r0 = rf(ctx, stakingTxHash)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []model.TimeLockDocument); ok {
+		r0 = rf(ctx, stakingTxHashHex)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*types.DelegationState)
+			r0 = ret.Get(0).([]model.TimeLockDocument)
 		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, stakingTxHash)
+		r1 = rf(ctx, stakingTxHashHex)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -127,29 +1645,29 @@ func (_m *DbInterface) GetBTCDelegationState(ctx context.Context, stakingTxHash
 	return r0, r1
 }
 
-// GetBTCDelegationsByStates provides a mock function with given fields: ctx, states
-func (_m *DbInterface) GetBTCDelegationsByStates(ctx context.Context, states []types.DelegationState) ([]*model.BTCDelegationDetails, error) {
-	ret := _m.Called(ctx, states)
+// GetTimeLocksExpiringBetween provides a mock function with given fields: ctx, fromHeight, toHeight, paginationToken, limit
+func (_m *DbInterface) GetTimeLocksExpiringBetween(ctx context.Context, fromHeight uint32, toHeight uint32, paginationToken string, limit int64) (*db.DbResultMap[*model.ExpiringTimeLock], error) {
+	ret := _m.Called(ctx, fromHeight, toHeight, paginationToken, limit)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetBTCDelegationsByStates")
+		panic("no return value specified for GetTimeLocksExpiringBetween")
 	}
 
-	var r0 []*model.BTCDelegationDetails
+	var r0 *db.DbResultMap[*model.ExpiringTimeLock]
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationState) ([]*model.BTCDelegationDetails, error)); ok {
-		return rf(ctx, states)
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, string, int64) (*db.DbResultMap[*model.ExpiringTimeLock], error)); ok {
+		return rf(ctx, fromHeight, toHeight, paginationToken, limit)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []types.DelegationState) []*model.BTCDelegationDetails); ok {
-		r0 = rf(ctx, states)
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, uint32, string, int64) *db.DbResultMap[*model.ExpiringTimeLock]); ok {
+		r0 = rf(ctx, fromHeight, toHeight, paginationToken, limit)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+			r0 = ret.Get(0).(*db.DbResultMap[*model.ExpiringTimeLock])
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []types.DelegationState) error); ok {
-		r1 = rf(ctx, states)
+	if rf, ok := ret.Get(1).(func(context.Context, uint32, uint32, string, int64) error); ok {
+		r1 = rf(ctx, fromHeight, toHeight, paginationToken, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -157,29 +1675,29 @@ func (_m *DbInterface) GetBTCDelegationsByStates(ctx context.Context, states []t
 	return r0, r1
 }
 
-// GetDelegationsByFinalityProvider provides a mock function with given fields: ctx, fpBtcPkHex
-func (_m *DbInterface) GetDelegationsByFinalityProvider(ctx context.Context, fpBtcPkHex string) ([]*model.BTCDelegationDetails, error) {
-	ret := _m.Called(ctx, fpBtcPkHex)
+// GetUnfinishedReconciliationRun provides a mock function with given fields: ctx
+func (_m *DbInterface) GetUnfinishedReconciliationRun(ctx context.Context) (*model.ReconciliationRun, error) {
+	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetDelegationsByFinalityProvider")
+		panic("no return value specified for GetUnfinishedReconciliationRun")
 	}
 
-	var r0 []*model.BTCDelegationDetails
+	var r0 *model.ReconciliationRun
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.BTCDelegationDetails, error)); ok {
-		return rf(ctx, fpBtcPkHex)
+	if rf, ok := ret.Get(0).(func(context.Context) (*model.ReconciliationRun, error)); ok {
+		return rf(ctx)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.BTCDelegationDetails); ok {
-		r0 = rf(ctx, fpBtcPkHex)
+	if rf, ok := ret.Get(0).(func(context.Context) *model.ReconciliationRun); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+			r0 = ret.Get(0).(*model.ReconciliationRun)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, fpBtcPkHex)
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -187,42 +1705,204 @@ func (_m *DbInterface) GetDelegationsByFinalityProvider(ctx context.Context, fpB
 	return r0, r1
 }
 
-// GetFinalityProviderByBtcPk provides a mock function with given fields: ctx, btcPk
-func (_m *DbInterface) GetFinalityProviderByBtcPk(ctx context.Context, btcPk string) (*model.FinalityProviderDetails, error) {
-	ret := _m.Called(ctx, btcPk)
+// GetUnsentOutboxEvents provides a mock function with given fields: ctx, createdAfter, afterId, limit
+func (_m *DbInterface) GetUnsentOutboxEvents(ctx context.Context, createdAfter int64, afterId string, limit uint64) ([]*model.OutboxEvent, error) {
+	ret := _m.Called(ctx, createdAfter, afterId, limit)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetFinalityProviderByBtcPk")
+		panic("no return value specified for GetUnsentOutboxEvents")
 	}
 
-	var r0 *model.FinalityProviderDetails
+	var r0 []*model.OutboxEvent
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.FinalityProviderDetails, error)); ok {
-		return rf(ctx, btcPk)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, uint64) ([]*model.OutboxEvent, error)); ok {
+		return rf(ctx, createdAfter, afterId, limit)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.FinalityProviderDetails); ok {
-		r0 = rf(ctx, btcPk)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, uint64) []*model.OutboxEvent); ok {
+		r0 = rf(ctx, createdAfter, afterId, limit)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.FinalityProviderDetails)
+			r0 = ret.Get(0).([]*model.OutboxEvent)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, btcPk)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, uint64) error); ok {
+		r1 = rf(ctx, createdAfter, afterId, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HaltBbnProcessing provides a mock function with given fields: ctx, reason
+func (_m *DbInterface) HaltBbnProcessing(ctx context.Context, reason string) error {
+	ret := _m.Called(ctx, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HaltBbnProcessing")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkBbnEventProcessed provides a mock function with given fields: ctx, height, eventIndex
+func (_m *DbInterface) MarkBbnEventProcessed(ctx context.Context, height uint64, eventIndex int) error {
+	ret := _m.Called(ctx, height, eventIndex)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkBbnEventProcessed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int) error); ok {
+		r0 = rf(ctx, height, eventIndex)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkBbnHeightProcessed provides a mock function with given fields: ctx, height
+func (_m *DbInterface) MarkBbnHeightProcessed(ctx context.Context, height uint64) error {
+	ret := _m.Called(ctx, height)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkBbnHeightProcessed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, height)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkOutboxEventFailed provides a mock function with given fields: ctx, id, lastError, nextAttemptAt, poison
+func (_m *DbInterface) MarkOutboxEventFailed(ctx context.Context, id string, lastError string, nextAttemptAt int64, poison bool) error {
+	ret := _m.Called(ctx, id, lastError, nextAttemptAt, poison)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOutboxEventFailed")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, bool) error); ok {
+		r0 = rf(ctx, id, lastError, nextAttemptAt, poison)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkOutboxEventSent provides a mock function with given fields: ctx, id, sentAt
+func (_m *DbInterface) MarkOutboxEventSent(ctx context.Context, id string, sentAt int64) error {
+	ret := _m.Called(ctx, id, sentAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkOutboxEventSent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, id, sentAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OverrideBTCDelegationState provides a mock function with given fields: ctx, stakingTxHash, currentState, newState, newSubState
+func (_m *DbInterface) OverrideBTCDelegationState(ctx context.Context, stakingTxHash string, currentState types.DelegationState, newState types.DelegationState, newSubState *types.DelegationSubState) error {
+	ret := _m.Called(ctx, stakingTxHash, currentState, newState, newSubState)
+
+	if len(ret) == 0 {
+		panic("no return value specified for OverrideBTCDelegationState")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, types.DelegationState, types.DelegationState, *types.DelegationSubState) error); ok {
+		r0 = rf(ctx, stakingTxHash, currentState, newState, newSubState)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *DbInterface) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Ping")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReleaseLock provides a mock function with given fields: ctx, name, owner
+func (_m *DbInterface) ReleaseLock(ctx context.Context, name string, owner string) error {
+	ret := _m.Called(ctx, name, owner)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseLock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, name, owner)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReplaceStakingParams provides a mock function with given fields: ctx, version, params
+func (_m *DbInterface) ReplaceStakingParams(ctx context.Context, version uint32, params *bbnclient.StakingParams) error {
+	ret := _m.Called(ctx, version, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReplaceStakingParams")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint32, *bbnclient.StakingParams) error); ok {
+		r0 = rf(ctx, version, params)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
-// GetLastProcessedBbnHeight provides a mock function with given fields: ctx
-func (_m *DbInterface) GetLastProcessedBbnHeight(ctx context.Context) (uint64, error) {
+// RequeuePoisonOutboxEvents provides a mock function with given fields: ctx
+func (_m *DbInterface) RequeuePoisonOutboxEvents(ctx context.Context) (uint64, error) {
 	ret := _m.Called(ctx)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetLastProcessedBbnHeight")
+		panic("no return value specified for RequeuePoisonOutboxEvents")
 	}
 
 	var r0 uint64
@@ -245,29 +1925,47 @@ func (_m *DbInterface) GetLastProcessedBbnHeight(ctx context.Context) (uint64, e
 	return r0, r1
 }
 
-// GetStakingParams provides a mock function with given fields: ctx, version
-func (_m *DbInterface) GetStakingParams(ctx context.Context, version uint32) (*bbnclient.StakingParams, error) {
-	ret := _m.Called(ctx, version)
+// ResyncLastProcessedBbnHeight provides a mock function with given fields: ctx, height
+func (_m *DbInterface) ResyncLastProcessedBbnHeight(ctx context.Context, height uint64) error {
+	ret := _m.Called(ctx, height)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetStakingParams")
+		panic("no return value specified for ResyncLastProcessedBbnHeight")
 	}
 
-	var r0 *bbnclient.StakingParams
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, height)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RollbackBTCDerivedChanges provides a mock function with given fields: ctx, forkHeight
+func (_m *DbInterface) RollbackBTCDerivedChanges(ctx context.Context, forkHeight uint64) ([]*model.BTCDerivedChange, error) {
+	ret := _m.Called(ctx, forkHeight)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RollbackBTCDerivedChanges")
+	}
+
+	var r0 []*model.BTCDerivedChange
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint32) (*bbnclient.StakingParams, error)); ok {
-		return rf(ctx, version)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) ([]*model.BTCDerivedChange, error)); ok {
+		return rf(ctx, forkHeight)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, uint32) *bbnclient.StakingParams); ok {
-		r0 = rf(ctx, version)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*model.BTCDerivedChange); ok {
+		r0 = rf(ctx, forkHeight)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*bbnclient.StakingParams)
+			r0 = ret.Get(0).([]*model.BTCDerivedChange)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, uint32) error); ok {
-		r1 = rf(ctx, version)
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, forkHeight)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -275,17 +1973,17 @@ func (_m *DbInterface) GetStakingParams(ctx context.Context, version uint32) (*b
 	return r0, r1
 }
 
-// Ping provides a mock function with given fields: ctx
-func (_m *DbInterface) Ping(ctx context.Context) error {
-	ret := _m.Called(ctx)
+// RunInTransaction provides a mock function with given fields: ctx, fn
+func (_m *DbInterface) RunInTransaction(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Ping")
+		panic("no return value specified for RunInTransaction")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -293,6 +1991,36 @@ func (_m *DbInterface) Ping(ctx context.Context) error {
 	return r0
 }
 
+// SampleBTCDelegations provides a mock function with given fields: ctx, filter, size
+func (_m *DbInterface) SampleBTCDelegations(ctx context.Context, filter db.BTCDelegationsFilter, size uint64) ([]*model.BTCDelegationDetails, error) {
+	ret := _m.Called(ctx, filter, size)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SampleBTCDelegations")
+	}
+
+	var r0 []*model.BTCDelegationDetails
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, db.BTCDelegationsFilter, uint64) ([]*model.BTCDelegationDetails, error)); ok {
+		return rf(ctx, filter, size)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, db.BTCDelegationsFilter, uint64) []*model.BTCDelegationDetails); ok {
+		r0 = rf(ctx, filter, size)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BTCDelegationDetails)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, db.BTCDelegationsFilter, uint64) error); ok {
+		r1 = rf(ctx, filter, size)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveBTCDelegationSlashingTxHex provides a mock function with given fields: ctx, stakingTxHashHex, slashingTxHex, spendingHeight
 func (_m *DbInterface) SaveBTCDelegationSlashingTxHex(ctx context.Context, stakingTxHashHex string, slashingTxHex string, spendingHeight uint32) error {
 	ret := _m.Called(ctx, stakingTxHashHex, slashingTxHex, spendingHeight)
@@ -347,6 +2075,78 @@ func (_m *DbInterface) SaveBTCDelegationUnbondingSlashingTxHex(ctx context.Conte
 	return r0
 }
 
+// SaveBTCDerivedChange provides a mock function with given fields: ctx, change
+func (_m *DbInterface) SaveBTCDerivedChange(ctx context.Context, change *model.BTCDerivedChange) error {
+	ret := _m.Called(ctx, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveBTCDerivedChange")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.BTCDerivedChange) error); ok {
+		r0 = rf(ctx, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveBTCHeader provides a mock function with given fields: ctx, header
+func (_m *DbInterface) SaveBTCHeader(ctx context.Context, header *model.BTCHeader) error {
+	ret := _m.Called(ctx, header)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveBTCHeader")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.BTCHeader) error); ok {
+		r0 = rf(ctx, header)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveBbnEventDeadLetter provides a mock function with given fields: ctx, deadLetter
+func (_m *DbInterface) SaveBbnEventDeadLetter(ctx context.Context, deadLetter *model.BbnEventDeadLetter) error {
+	ret := _m.Called(ctx, deadLetter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveBbnEventDeadLetter")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.BbnEventDeadLetter) error); ok {
+		r0 = rf(ctx, deadLetter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveBootstrap provides a mock function with given fields: ctx, bootstrap
+func (_m *DbInterface) SaveBootstrap(ctx context.Context, bootstrap *model.Bootstrap) error {
+	ret := _m.Called(ctx, bootstrap)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveBootstrap")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.Bootstrap) error); ok {
+		r0 = rf(ctx, bootstrap)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveCheckpointParams provides a mock function with given fields: ctx, params
 func (_m *DbInterface) SaveCheckpointParams(ctx context.Context, params *bbnclient.CheckpointParams) error {
 	ret := _m.Called(ctx, params)
@@ -365,6 +2165,60 @@ func (_m *DbInterface) SaveCheckpointParams(ctx context.Context, params *bbnclie
 	return r0
 }
 
+// SaveDelegationStateTransition provides a mock function with given fields: ctx, transition
+func (_m *DbInterface) SaveDelegationStateTransition(ctx context.Context, transition *model.DelegationStateTransition) error {
+	ret := _m.Called(ctx, transition)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveDelegationStateTransition")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.DelegationStateTransition) error); ok {
+		r0 = rf(ctx, transition)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveFinalityProviderVotingPowerChange provides a mock function with given fields: ctx, change
+func (_m *DbInterface) SaveFinalityProviderVotingPowerChange(ctx context.Context, change *model.FinalityProviderVotingPowerChange) error {
+	ret := _m.Called(ctx, change)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveFinalityProviderVotingPowerChange")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.FinalityProviderVotingPowerChange) error); ok {
+		r0 = rf(ctx, change)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveGlobalStats provides a mock function with given fields: ctx, stats
+func (_m *DbInterface) SaveGlobalStats(ctx context.Context, stats *model.GlobalStats) error {
+	ret := _m.Called(ctx, stats)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveGlobalStats")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.GlobalStats) error); ok {
+		r0 = rf(ctx, stats)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveNewBTCDelegation provides a mock function with given fields: ctx, delegationDoc
 func (_m *DbInterface) SaveNewBTCDelegation(ctx context.Context, delegationDoc *model.BTCDelegationDetails) error {
 	ret := _m.Called(ctx, delegationDoc)
@@ -419,6 +2273,78 @@ func (_m *DbInterface) SaveNewTimeLockExpire(ctx context.Context, stakingTxHashH
 	return r0
 }
 
+// SaveOutboxEvent provides a mock function with given fields: ctx, event
+func (_m *DbInterface) SaveOutboxEvent(ctx context.Context, event *model.OutboxEvent) error {
+	ret := _m.Called(ctx, event)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveOutboxEvent")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.OutboxEvent) error); ok {
+		r0 = rf(ctx, event)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveRawEventCapture provides a mock function with given fields: ctx, capture
+func (_m *DbInterface) SaveRawEventCapture(ctx context.Context, capture *model.RawEventCapture) error {
+	ret := _m.Called(ctx, capture)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveRawEventCapture")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.RawEventCapture) error); ok {
+		r0 = rf(ctx, capture)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveReconciliationDiscrepancy provides a mock function with given fields: ctx, discrepancy
+func (_m *DbInterface) SaveReconciliationDiscrepancy(ctx context.Context, discrepancy *model.ReconciliationDiscrepancy) error {
+	ret := _m.Called(ctx, discrepancy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveReconciliationDiscrepancy")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.ReconciliationDiscrepancy) error); ok {
+		r0 = rf(ctx, discrepancy)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveReconciliationRun provides a mock function with given fields: ctx, run
+func (_m *DbInterface) SaveReconciliationRun(ctx context.Context, run *model.ReconciliationRun) error {
+	ret := _m.Called(ctx, run)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveReconciliationRun")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.ReconciliationRun) error); ok {
+		r0 = rf(ctx, run)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveStakingParams provides a mock function with given fields: ctx, version, params
 func (_m *DbInterface) SaveStakingParams(ctx context.Context, version uint32, params *bbnclient.StakingParams) error {
 	ret := _m.Called(ctx, version, params)
@@ -437,6 +2363,60 @@ func (_m *DbInterface) SaveStakingParams(ctx context.Context, version uint32, pa
 	return r0
 }
 
+// SaveStuckDelegationReport provides a mock function with given fields: ctx, report
+func (_m *DbInterface) SaveStuckDelegationReport(ctx context.Context, report *model.StuckDelegationReport) error {
+	ret := _m.Called(ctx, report)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveStuckDelegationReport")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.StuckDelegationReport) error); ok {
+		r0 = rf(ctx, report)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetCovenantSignatureVerified provides a mock function with given fields: ctx, stakingTxHash, covenantBtcPkHex, verified
+func (_m *DbInterface) SetCovenantSignatureVerified(ctx context.Context, stakingTxHash string, covenantBtcPkHex string, verified bool) error {
+	ret := _m.Called(ctx, stakingTxHash, covenantBtcPkHex, verified)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetCovenantSignatureVerified")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
+		r0 = rf(ctx, stakingTxHash, covenantBtcPkHex, verified)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StartBbnBlockProcessing provides a mock function with given fields: ctx, marker
+func (_m *DbInterface) StartBbnBlockProcessing(ctx context.Context, marker *model.BbnProcessingMarker) error {
+	ret := _m.Called(ctx, marker)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StartBbnBlockProcessing")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.BbnProcessingMarker) error); ok {
+		r0 = rf(ctx, marker)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateBTCDelegationDetails provides a mock function with given fields: ctx, stakingTxHash, details
 func (_m *DbInterface) UpdateBTCDelegationDetails(ctx context.Context, stakingTxHash string, details *model.BTCDelegationDetails) error {
 	ret := _m.Called(ctx, stakingTxHash, details)
@@ -473,6 +2453,24 @@ func (_m *DbInterface) UpdateBTCDelegationState(ctx context.Context, stakingTxHa
 	return r0
 }
 
+// UpdateBTCDelegationSubState provides a mock function with given fields: ctx, stakingTxHash, state, newSubState
+func (_m *DbInterface) UpdateBTCDelegationSubState(ctx context.Context, stakingTxHash string, state types.DelegationState, newSubState types.DelegationSubState) error {
+	ret := _m.Called(ctx, stakingTxHash, state, newSubState)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateBTCDelegationSubState")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, types.DelegationState, types.DelegationSubState) error); ok {
+		r0 = rf(ctx, stakingTxHash, state, newSubState)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateDelegationsStateByFinalityProvider provides a mock function with given fields: ctx, fpBtcPkHex, newState
 func (_m *DbInterface) UpdateDelegationsStateByFinalityProvider(ctx context.Context, fpBtcPkHex string, newState types.DelegationState) error {
 	ret := _m.Called(ctx, fpBtcPkHex, newState)
@@ -527,17 +2525,35 @@ func (_m *DbInterface) UpdateFinalityProviderState(ctx context.Context, btcPk st
 	return r0
 }
 
-// UpdateLastProcessedBbnHeight provides a mock function with given fields: ctx, height
-func (_m *DbInterface) UpdateLastProcessedBbnHeight(ctx context.Context, height uint64) error {
-	ret := _m.Called(ctx, height)
+// UpdateLastProcessedBbnHeight provides a mock function with given fields: ctx, height, blockHash
+func (_m *DbInterface) UpdateLastProcessedBbnHeight(ctx context.Context, height uint64, blockHash string) error {
+	ret := _m.Called(ctx, height, blockHash)
 
 	if len(ret) == 0 {
 		panic("no return value specified for UpdateLastProcessedBbnHeight")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
-		r0 = rf(ctx, height)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string) error); ok {
+		r0 = rf(ctx, height, blockHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateTimeLockExpireHeight provides a mock function with given fields: ctx, timeLock, newExpireHeight
+func (_m *DbInterface) UpdateTimeLockExpireHeight(ctx context.Context, timeLock *model.TimeLockDocument, newExpireHeight uint32) error {
+	ret := _m.Called(ctx, timeLock, newExpireHeight)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateTimeLockExpireHeight")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *model.TimeLockDocument, uint32) error); ok {
+		r0 = rf(ctx, timeLock, newExpireHeight)
 	} else {
 		r0 = ret.Error(0)
 	}